@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/burcev/api/internal/seed"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+)
+
+// Exit codes of the server subcommands
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+const commandUsage = `usage: server [migrate up|migrate status|seed [-seed N] [-days N]|
+              events replay CONSUMER [-since YYYY-MM-DD]]
+
+  migrate up      apply pending migrations and exit
+  migrate status  list migrations; exits 1 if an applied file was modified
+  seed            create or update demo accounts with generated data
+                  (not in production); the same -seed gives the same data
+  events replay   hand the logged domain events to a consumer again, all
+                  of them or those since a day (UTC), to rebuild what it
+                  derives from them; consumers: webhooks`
+
+// schemaMigrator is the part of database.Migrator the migrate command uses
+type schemaMigrator interface {
+	Run(ctx context.Context, baseline int) error
+	Status(ctx context.Context) ([]database.MigrationStatus, error)
+}
+
+// demoSeeder is the part of seed.Seeder the seed command uses
+type demoSeeder interface {
+	Seed(ctx context.Context, opts seed.Options) (*seed.Result, error)
+}
+
+// eventReplayer is the part of events.Log the events command uses
+type eventReplayer interface {
+	Replay(ctx context.Context, consumer string, since time.Time) (int, error)
+}
+
+// commands holds what the subcommands work with
+type commands struct {
+	migrator schemaMigrator
+	baseline int
+	seeder   demoSeeder
+	events   eventReplayer
+}
+
+// runCommand runs the subcommand in args, writing its output to out, and
+// returns the process exit code. Without arguments the server starts
+// instead, so args is never empty here.
+func runCommand(ctx context.Context, args []string, out io.Writer, cmds commands) int {
+	switch {
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "up":
+		if err := cmds.migrator.Run(ctx, cmds.baseline); err != nil {
+			fmt.Fprintf(out, "migrate up: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintln(out, "migrations are up to date")
+		return exitOK
+	case len(args) == 2 && args[0] == "migrate" && args[1] == "status":
+		return printMigrationStatus(ctx, out, cmds.migrator)
+	case len(args) >= 1 && args[0] == "seed":
+		return runSeed(ctx, args[1:], out, cmds.seeder)
+	case len(args) >= 3 && args[0] == "events" && args[1] == "replay":
+		return runReplay(ctx, args[2], args[3:], out, cmds.events)
+	default:
+		fmt.Fprintln(out, commandUsage)
+		return exitUsage
+	}
+}
+
+// runSeed seeds the demo data and prints the accounts' credentials
+func runSeed(ctx context.Context, args []string, out io.Writer, seeder demoSeeder) int {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	flags.SetOutput(out)
+	seedValue := flags.Uint64("seed", 1, "random seed of the generated data and passwords")
+	days := flags.Int("days", seed.DefaultDays, "days of data per client, ending today")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 || *days < 1 {
+		fmt.Fprintln(out, commandUsage)
+		return exitUsage
+	}
+
+	result, err := seeder.Seed(ctx, seed.Options{Seed: *seedValue, Days: *days, Today: time.Now().UTC()})
+	if errors.Is(err, seed.ErrProduction) {
+		fmt.Fprintf(out, "seed: %v\n", err)
+		return exitError
+	}
+	if err != nil {
+		fmt.Fprintf(out, "seed: %v (are migrations applied? run \"migrate up\")\n", err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ROLE\tEMAIL\tPASSWORD\tNAME")
+	for _, a := range result.Accounts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Role, a.Email, a.Password, a.Name)
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(out, "\nseed %d, %d days: %d nutrition entries, %d weights, %d measurements\n",
+		*seedValue, *days, result.Entries, result.Weights, result.Measurements)
+	return exitOK
+}
+
+// runReplay replays the logged events to consumer
+func runReplay(ctx context.Context, consumer string, args []string, out io.Writer, replayer eventReplayer) int {
+	flags := flag.NewFlagSet("events replay", flag.ContinueOnError)
+	flags.SetOutput(out)
+	sinceValue := flags.String("since", "", "replay events that occurred on or after this day")
+	if err := flags.Parse(args); err != nil || flags.NArg() > 0 {
+		fmt.Fprintln(out, commandUsage)
+		return exitUsage
+	}
+	var since time.Time
+	if *sinceValue != "" {
+		day, err := time.Parse("2006-01-02", *sinceValue)
+		if err != nil {
+			fmt.Fprintln(out, commandUsage)
+			return exitUsage
+		}
+		since = day
+	}
+
+	replayed, err := replayer.Replay(ctx, consumer, since)
+	if errors.Is(err, events.ErrUnknownConsumer) {
+		fmt.Fprintf(out, "events replay: %v\n", err)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintf(out, "events replay: %v after %d events\n", err, replayed)
+		return exitError
+	}
+
+	fmt.Fprintf(out, "replayed %d events to %s\n", replayed, consumer)
+	return exitOK
+}
+
+// printMigrationStatus prints one line per migration file
+func printMigrationStatus(ctx context.Context, out io.Writer, migrator schemaMigrator) int {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		fmt.Fprintf(out, "migrate status: %v\n", err)
+		return exitError
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+
+	code, pending := exitOK, 0
+	for _, st := range statuses {
+		state, appliedAt := "pending", ""
+		if st.Applied {
+			state = "applied"
+			appliedAt = st.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+		} else {
+			pending++
+		}
+		if st.Modified {
+			state = "MODIFIED"
+			code = exitError
+		}
+		fmt.Fprintf(w, "%03d\t%s\t%s\t%s\n", st.Version, st.Name, state, appliedAt)
+	}
+	_ = w.Flush()
+
+	fmt.Fprintf(out, "\n%d migrations, %d pending\n", len(statuses), pending)
+	return code
+}
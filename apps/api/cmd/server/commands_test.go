@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/seed"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMigrator struct {
+	ran      bool
+	baseline int
+	err      error
+	statuses []database.MigrationStatus
+}
+
+func (f *fakeMigrator) Run(ctx context.Context, baseline int) error {
+	f.ran, f.baseline = true, baseline
+	return f.err
+}
+
+func (f *fakeMigrator) Status(ctx context.Context) ([]database.MigrationStatus, error) {
+	return f.statuses, f.err
+}
+
+type fakeSeeder struct {
+	ran  bool
+	opts seed.Options
+	err  error
+}
+
+func (f *fakeSeeder) Seed(ctx context.Context, opts seed.Options) (*seed.Result, error) {
+	f.ran, f.opts = true, opts
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &seed.Result{
+		Accounts: []seed.Account{{Role: "super_admin", Email: "demo-admin@example.com", Password: "Demo-abcdef12!", Name: "Демо"}},
+		Entries:  120,
+	}, nil
+}
+
+type fakeReplayer struct {
+	consumer string
+	since    time.Time
+	replayed int
+	err      error
+}
+
+func (f *fakeReplayer) Replay(ctx context.Context, consumer string, since time.Time) (int, error) {
+	f.consumer, f.since = consumer, since
+	return f.replayed, f.err
+}
+
+func TestRunCommand_MigrateUp(t *testing.T) {
+	var out bytes.Buffer
+	migrator := &fakeMigrator{}
+
+	code := runCommand(context.Background(), []string{"migrate", "up"}, &out, commands{migrator: migrator, baseline: 42})
+
+	assert.Equal(t, exitOK, code)
+	assert.True(t, migrator.ran)
+	assert.Equal(t, 42, migrator.baseline)
+
+	out.Reset()
+	code = runCommand(context.Background(), []string{"migrate", "up"}, &out, commands{migrator: &fakeMigrator{err: errors.New("checksum mismatch")}})
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, out.String(), "checksum mismatch")
+}
+
+func TestRunCommand_MigrateStatus(t *testing.T) {
+	appliedAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	statuses := []database.MigrationStatus{
+		{Version: 1, Name: "init", Applied: true, AppliedAt: &appliedAt},
+		{Version: 2, Name: "add_users"},
+	}
+
+	var out bytes.Buffer
+	code := runCommand(context.Background(), []string{"migrate", "status"}, &out, commands{migrator: &fakeMigrator{statuses: statuses}})
+
+	assert.Equal(t, exitOK, code)
+	assert.Regexp(t, `001\s+init\s+applied\s+2026-10-01 09:00:00`, out.String())
+	assert.Regexp(t, `002\s+add_users\s+pending`, out.String())
+	assert.Contains(t, out.String(), "2 migrations, 1 pending")
+
+	statuses[0].Modified = true
+	out.Reset()
+	code = runCommand(context.Background(), []string{"migrate", "status"}, &out, commands{migrator: &fakeMigrator{statuses: statuses}})
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, out.String(), "MODIFIED")
+}
+
+func TestRunCommand_Usage(t *testing.T) {
+	for _, args := range [][]string{{"migrate"}, {"migrate", "down"}, {"serve"}, {"seed", "-days", "0"}, {"seed", "extra"},
+		{"events", "replay"}, {"events", "replay", "webhooks", "-since", "01.10.2026"}, {"events", "replay", "webhooks", "extra"}} {
+		var out bytes.Buffer
+		migrator, seeder := &fakeMigrator{}, &fakeSeeder{}
+
+		code := runCommand(context.Background(), args, &out, commands{migrator: migrator, seeder: seeder})
+
+		assert.Equal(t, exitUsage, code)
+		assert.False(t, migrator.ran)
+		assert.False(t, seeder.ran)
+		assert.Contains(t, out.String(), "usage:")
+	}
+}
+
+func TestRunCommand_Seed(t *testing.T) {
+	var out bytes.Buffer
+	seeder := &fakeSeeder{}
+
+	code := runCommand(context.Background(), []string{"seed", "-seed", "7", "-days", "14"}, &out, commands{seeder: seeder})
+
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, uint64(7), seeder.opts.Seed)
+	assert.Equal(t, 14, seeder.opts.Days)
+	assert.Regexp(t, `super_admin\s+demo-admin@example.com\s+Demo-abcdef12!`, out.String())
+	assert.Contains(t, out.String(), "120 nutrition entries")
+
+	out.Reset()
+	seeder = &fakeSeeder{}
+	runCommand(context.Background(), []string{"seed"}, &out, commands{seeder: seeder})
+	assert.Equal(t, seed.Options{Seed: 1, Days: seed.DefaultDays, Today: seeder.opts.Today}, seeder.opts)
+
+	out.Reset()
+	code = runCommand(context.Background(), []string{"seed"}, &out, commands{seeder: &fakeSeeder{err: seed.ErrProduction}})
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, out.String(), "production")
+}
+
+func TestRunCommand_EventsReplay(t *testing.T) {
+	var out bytes.Buffer
+	replayer := &fakeReplayer{replayed: 42}
+
+	code := runCommand(context.Background(), []string{"events", "replay", "webhooks", "-since", "2026-10-01"}, &out, commands{events: replayer})
+
+	assert.Equal(t, exitOK, code)
+	assert.Equal(t, "webhooks", replayer.consumer)
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), replayer.since)
+	assert.Contains(t, out.String(), "replayed 42 events to webhooks")
+
+	out.Reset()
+	replayer = &fakeReplayer{}
+	runCommand(context.Background(), []string{"events", "replay", "webhooks"}, &out, commands{events: replayer})
+	assert.True(t, replayer.since.IsZero(), "the whole log by default")
+
+	out.Reset()
+	code = runCommand(context.Background(), []string{"events", "replay", "search"}, &out,
+		commands{events: &fakeReplayer{err: fmt.Errorf("%w: %q", events.ErrUnknownConsumer, "search")}})
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, out.String(), `unknown event consumer: "search"`)
+
+	out.Reset()
+	code = runCommand(context.Background(), []string{"events", "replay", "webhooks"}, &out,
+		commands{events: &fakeReplayer{replayed: 7, err: errors.New("connection reset")}})
+	assert.Equal(t, exitError, code)
+	assert.Contains(t, out.String(), "connection reset after 7 events")
+}
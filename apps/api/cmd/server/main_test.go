@@ -5,9 +5,16 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/modules/logs"
+	"github.com/burcev/api/internal/modules/nutrition"
+	"github.com/burcev/api/internal/modules/users"
+	"github.com/burcev/api/internal/openapi"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -290,3 +297,47 @@ func TestGinModeConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAPIDocument(t *testing.T) {
+	router := gin.New()
+	noop := func(c *gin.Context) {}
+	apiDocs := openapi.New("BURCEV API", "1.0")
+
+	// Register the documented module routes the way main does
+	v1 := router.Group("/api/v1")
+	v1.GET("/openapi.json", apiDocs.Handler(router))
+	for _, m := range []struct {
+		path      string
+		endpoints []openapi.Endpoint
+	}{
+		{"/auth", auth.Endpoints()},
+		{"/users", users.Endpoints()},
+		{"/nutrition", nutrition.Endpoints()},
+		{"/logs", logs.Endpoints()},
+	} {
+		group := v1.Group(m.path)
+		for _, e := range m.endpoints {
+			group.Handle(e.Method, e.Path, noop)
+		}
+		apiDocs.Add(group.BasePath(), m.path[1:], m.endpoints...)
+	}
+	// and one route without a descriptor
+	v1.GET("/food-tracker/entries/:id", noop)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	require.NoError(t, openapi.Validate(doc))
+
+	paths := doc["paths"].(map[string]interface{})
+	for _, r := range router.Routes() {
+		path := regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`).ReplaceAllString(r.Path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		require.True(t, ok, "path %s is missing", path)
+		assert.Contains(t, item, strings.ToLower(r.Method), "%s %s is missing", r.Method, r.Path)
+	}
+}
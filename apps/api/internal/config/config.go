@@ -1,18 +1,139 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/password"
 	"github.com/joho/godotenv"
 )
 
+// Defaults for settings that have a typed or security-sensitive value
+const (
+	// DefaultJWTSecret is only acceptable outside production
+	DefaultJWTSecret = "dev-secret-key"
+	// MinJWTSecretLength is the shortest JWT secret accepted in production
+	MinJWTSecretLength = 32
+
+	DefaultReadTimeout     = 15 * time.Second
+	DefaultWriteTimeout    = 15 * time.Second
+	DefaultIdleTimeout     = 60 * time.Second
+	DefaultShutdownTimeout = 15 * time.Second
+
+	// DefaultDBSlowQueryThreshold is how long a query may take before it is
+	// logged as slow
+	DefaultDBSlowQueryThreshold = 500 * time.Millisecond
+
+	// DefaultTrustedProxies are the private networks the Docker proxies run
+	// on. Outside production they are trusted when TRUSTED_PROXIES is unset;
+	// production has to list its proxies.
+	DefaultTrustedProxies = "10.0.0.0/8,172.16.0.0/12,192.168.0.0/16"
+
+	// DefaultGzipMinSize is the smallest response body compressed, in bytes
+	DefaultGzipMinSize = 1024
+
+	// Yandex throttles clients that open a connection per message
+	DefaultSMTPPoolSize    = 3
+	DefaultSMTPIdleTimeout = 30 * time.Second
+
+	DefaultAccessTokenTTL            = 15 * time.Minute
+	DefaultRefreshTokenTTL           = 24 * time.Hour
+	DefaultRememberMeRefreshTokenTTL = 30 * 24 * time.Hour
+	DefaultResetTokenTTL             = time.Hour
+
+	// Reset tokens must stay valid long enough for the email to arrive but
+	// not so long that a leaked inbox keeps them usable
+	MinResetTokenTTL = 10 * time.Minute
+	MaxResetTokenTTL = 24 * time.Hour
+
+	// DefaultResetTokenBytes is the entropy of a reset token (256 bits)
+	DefaultResetTokenBytes = 32
+	MinResetTokenBytes     = 16
+	MaxResetTokenBytes     = 64
+
+	// DefaultResetMaxActiveTokens of 1 invalidates earlier reset links
+	// whenever a new one is sent
+	DefaultResetMaxActiveTokens = 1
+
+	// ResetURLTokenPlaceholder in RESET_PASSWORD_URL is replaced with the
+	// token; without it the token is appended as the token query parameter
+	ResetURLTokenPlaceholder = "{token}"
+
+	DefaultResetEmailLimit  = 3
+	DefaultResetIPLimit     = 10
+	DefaultResetLimitWindow = time.Hour
+	// DefaultResetLimitFailurePolicy rejects password reset requests while
+	// the rate limit cannot be checked against the database
+	DefaultResetLimitFailurePolicy = "closed"
+
+	// DefaultDuplicateEntryWindow is how recently an identical nutrition
+	// entry must have been logged for a new one to look like a double submit
+	DefaultDuplicateEntryWindow = 2 * time.Minute
+
+	// DefaultLogRedactKeys are the query parameters and headers whose values
+	// request logs never contain: reset and refresh tokens, passwords,
+	// bearer tokens, session cookies and API keys
+	DefaultLogRedactKeys = "token,authorization,password,cookie,api-key"
+	// DefaultLogSlowRequest is how long a request may take before it is
+	// logged whatever LOG_SAMPLE_RATE says
+	DefaultLogSlowRequest = time.Second
+
+	// DefaultNutritionExcludedDayFlags leaves every flagged day out of
+	// adherence statistics and recommendations
+	DefaultNutritionExcludedDayFlags = "refeed,sick,travel"
+	// DefaultNutritionSodiumWarningMg is the daily sodium above which
+	// nutrition reports warn about a day (the usual 2300 mg guideline)
+	DefaultNutritionSodiumWarningMg = 2300
+
+	// Per-user quotas, generous for any real use: entries of one date,
+	// total size of progress photos (1 GiB) and active webhooks
+	DefaultQuotaEntriesPerDay = 200
+	DefaultQuotaPhotoBytes    = 1 << 30
+	DefaultQuotaWebhooks      = 5
+)
+
+// nutritionDayFlagTypes are the day flags NUTRITION_EXCLUDED_DAY_FLAGS may list
+var nutritionDayFlagTypes = []string{"refeed", "sick", "travel"}
+
 // Config holds application configuration
 type Config struct {
 	Env  string
 	Port int
 
+	// HTTP server timeouts
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// CORSOrigins are the browser origins allowed to call the API, e.g.
+	// https://burcev.team or https://*.burcev.team. Empty allows any origin.
+	CORSOrigins []string
+
+	// TrustedProxies are the addresses (IPs or CIDRs) of the reverse proxies
+	// whose X-Forwarded-For and X-Real-IP headers name the client. Requests
+	// from anywhere else are attributed to their peer address, so clients
+	// cannot pick their own IP. Empty trusts no proxy.
+	TrustedProxies []string
+
+	// GzipMinSize is the smallest response body that is gzipped for clients
+	// accepting it; smaller bodies are not worth the CPU
+	GzipMinSize int
+
+	// CompatResponses keeps the keys list responses wrapped their items in
+	// before the items/pagination envelope ("entries", "keys"...) next to
+	// the new ones, for clients that have not moved over yet
+	CompatResponses bool
+
 	// PostgreSQL
 	DatabaseURL      string
 	DatabaseHost     string
@@ -24,6 +145,8 @@ type Config struct {
 	MaxOpenConns     int
 	MaxIdleConns     int
 
+	DBSlowQueryThreshold time.Duration
+
 	// Supabase (optional, for migration compatibility)
 	SupabaseURL        string
 	SupabaseServiceKey string
@@ -31,6 +154,44 @@ type Config struct {
 	// JWT
 	JWTSecret string
 
+	// Token lifetimes
+	AccessTokenTTL            time.Duration
+	RefreshTokenTTL           time.Duration
+	RememberMeRefreshTokenTTL time.Duration
+	ResetTokenTTL             time.Duration
+
+	// RefreshCookie delivers refresh tokens to every login in an HttpOnly
+	// cookie instead of the response body; clients can also opt in per login
+	RefreshCookie bool
+
+	// ResetTokenBytes is the number of random bytes in a reset token
+	ResetTokenBytes int
+	// ResetMaxActiveTokens is how many unused reset links a user may hold;
+	// the oldest are invalidated when a new one is sent
+	ResetMaxActiveTokens int
+
+	// BcryptCost is the bcrypt cost of new password hashes; older hashes
+	// are upgraded on login
+	BcryptCost int
+
+	// PasswordBreachCheck rejects new passwords found by the Have I Been
+	// Pwned range API; off for offline deployments
+	PasswordBreachCheck bool
+
+	// Password reset rate limits: attempts allowed per email and per IP within the window
+	ResetEmailLimit  int
+	ResetIPLimit     int
+	ResetLimitWindow time.Duration
+	// What the reset limiter does when the database check fails and the
+	// in-process counters are under the limit: open admits, closed rejects
+	ResetLimitFailurePolicy string
+
+	// Email transport: smtp (default), log or memory
+	EmailDriver string
+	// EmailTemplateDir optionally holds {name}.html and {name}.txt files
+	// that replace the built-in email templates
+	EmailTemplateDir string
+
 	// SMTP Configuration (Yandex Mail)
 	SMTPHost        string
 	SMTPPort        int
@@ -38,10 +199,29 @@ type Config struct {
 	SMTPPassword    string
 	SMTPFromAddress string
 	SMTPFromName    string
+	// SMTPPoolSize caps the SMTP connections kept open and reused across
+	// sends; SMTPIdleTimeout is how long an unused one stays open
+	SMTPPoolSize    int
+	SMTPIdleTimeout time.Duration
 
-	// Password Reset
+	// Password Reset page; may contain ResetURLTokenPlaceholder
 	ResetPasswordURL string
 
+	// Public endpoint linked from emails for one-click unsubscribe
+	UnsubscribeURL string
+
+	// Public endpoint linked from organization join requests
+	OrganizationJoinURL string
+
+	// Frontend page that downloads a data export; emailed links point to it
+	DataExportURL string
+
+	// Frontend page showing a public progress share; share links point to it
+	ShareURL string
+
+	// Public endpoint linked from email change confirmations
+	EmailChangeConfirmURL string
+
 	// Weekly Photos S3 (Object Storage)
 	WeeklyPhotosS3AccessKeyID     string
 	WeeklyPhotosS3SecretAccessKey string
@@ -77,6 +257,52 @@ type Config struct {
 	// S3 Path Prefix (dev/ or prod/ — applied to all S3 clients except content)
 	S3PathPrefix string
 
+	// Data residency: the default storage region is served by the weekly
+	// photos bucket, additional named regions come from STORAGE_REGIONS.
+	StorageDefaultRegion string
+	StorageRegions       []StorageRegionConfig
+
+	// Progress photos (local disk storage root)
+	PhotosStorageDir string
+
+	// Resumable uploads (local disk storage root for chunks and assembled files)
+	UploadsStorageDir string
+
+	// Data exports (local disk storage root for finished archives)
+	ExportsStorageDir string
+
+	// MaintenanceMode keeps the API in maintenance mode (503 outside the
+	// health checks) whatever the admin toggle says; MaintenanceMessage is
+	// shown to clients. MaintenanceModePersist stores the admin toggle in the
+	// database so every replica follows it.
+	MaintenanceMode        bool
+	MaintenanceMessage     string
+	MaintenanceModePersist bool
+
+	// DuplicateEntryCheck warns when a new nutrition entry matches one
+	// logged within DuplicateEntryWindow (same date, meal, food and calories)
+	DuplicateEntryCheck  bool
+	DuplicateEntryWindow time.Duration
+
+	// NutritionExcludedDayFlags are the day flag types (refeed, sick,
+	// travel) whose days are left out of adherence statistics and
+	// recommendations
+	NutritionExcludedDayFlags []string
+	// NutritionSodiumWarningMg is the daily sodium, in mg, above which
+	// nutrition reports warn about a day; 0 turns the warnings off
+	NutritionSodiumWarningMg int
+
+	// Per-user quotas: nutrition entries per date, total bytes of progress
+	// photos and active webhooks. Admins can override them per user.
+	QuotaEntriesPerDay int
+	QuotaPhotoBytes    int
+	QuotaWebhooks      int
+
+	// Body fat estimation vision API (disabled when the URL is empty)
+	BodyFatAnalyzerURL            string
+	BodyFatAnalyzerAPIKey         string
+	BodyFatAnalyzerTimeoutSeconds int
+
 	// Food Photos S3 — falls back to generic S3_* vars
 	FoodPhotosS3AccessKeyID     string
 	FoodPhotosS3SecretAccessKey string
@@ -91,9 +317,35 @@ type Config struct {
 
 	// Migrations
 	MigrationBaseline int
+	// MigrateOnStart applies pending migrations when the server starts;
+	// otherwise they are applied with "server migrate up"
+	MigrateOnStart bool
+
+	// Logging. Request logs redact the values of query parameters and
+	// headers whose name contains one of LogRedactKeys, and log 1 in
+	// LogSampleRate successful requests; errors and requests slower than
+	// LogSlowRequest are always logged.
+	LogLevel       string
+	LogRedactKeys  []string
+	LogSampleRate  int
+	LogSlowRequest time.Duration
+
+	// Cache of computed values such as nutrition report days: CacheDriver
+	// is empty (off), memory (per replica, at most CacheMemoryEntries
+	// values) or redis (shared, at RedisURL)
+	CacheDriver        string
+	CacheMemoryEntries int
+	RedisURL           string
+}
 
-	// Logging
-	LogLevel string
+// StorageRegionConfig describes an additional named storage region (bucket)
+type StorageRegionConfig struct {
+	Name            string
+	AccessKeyID     string
+	SecretAccessKey string
+	Bucket          string
+	Region          string
+	Endpoint        string
 }
 
 // Load loads configuration from environment variables
@@ -103,37 +355,85 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()             // ./env
 	_ = godotenv.Load("../../.env") // project root when running from apps/api
 
+	env := &envReader{}
 	cfg := &Config{
 		Env:  getEnv("NODE_ENV", "development"),
-		Port: getEnvAsInt("PORT", 4000),
+		Port: env.int("PORT", 4000),
+
+		ReadTimeout:     env.duration("HTTP_READ_TIMEOUT", DefaultReadTimeout),
+		WriteTimeout:    env.duration("HTTP_WRITE_TIMEOUT", DefaultWriteTimeout),
+		IdleTimeout:     env.duration("HTTP_IDLE_TIMEOUT", DefaultIdleTimeout),
+		ShutdownTimeout: env.duration("SHUTDOWN_TIMEOUT", DefaultShutdownTimeout),
+
+		CORSOrigins: getCORSOrigins(),
+
+		GzipMinSize: env.int("GZIP_MIN_SIZE", DefaultGzipMinSize),
+
+		CompatResponses: env.bool("COMPAT_RESPONSES", true),
 
 		// PostgreSQL configuration
 		DatabaseURL:      getEnv("DATABASE_URL", ""),
 		DatabaseHost:     getEnv("DB_HOST", "localhost"),
-		DatabasePort:     getEnvAsInt("DB_PORT", 5432),
+		DatabasePort:     env.int("DB_PORT", 5432),
 		DatabaseName:     getEnv("DB_NAME", "web-app-db"),
 		DatabaseUser:     getEnv("DB_USER", "web-app-user"),
 		DatabasePassword: getEnv("DB_PASSWORD", ""),
 		DatabaseSSLMode:  getEnv("DB_SSL_MODE", "require"),
-		MaxOpenConns:     getEnvAsInt("DB_MAX_OPEN_CONNS", 10),
-		MaxIdleConns:     getEnvAsInt("DB_MAX_IDLE_CONNS", 3),
+		MaxOpenConns:     env.int("DB_MAX_OPEN_CONNS", 10),
+		MaxIdleConns:     env.int("DB_MAX_IDLE_CONNS", 3),
+
+		DBSlowQueryThreshold: env.duration("DB_SLOW_QUERY_THRESHOLD", DefaultDBSlowQueryThreshold),
 
 		// Supabase (optional)
 		SupabaseURL:        getEnv("SUPABASE_URL", ""),
 		SupabaseServiceKey: getEnv("SUPABASE_SERVICE_KEY", ""),
 
-		JWTSecret: getEnv("JWT_SECRET", "dev-secret-key"),
+		JWTSecret: getEnv("JWT_SECRET", DefaultJWTSecret),
+
+		AccessTokenTTL:            env.duration("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL),
+		RefreshTokenTTL:           env.duration("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL),
+		RememberMeRefreshTokenTTL: env.duration("REFRESH_TOKEN_REMEMBER_ME_TTL", DefaultRememberMeRefreshTokenTTL),
+		ResetTokenTTL:             env.duration("RESET_TOKEN_TTL", DefaultResetTokenTTL),
+
+		RefreshCookie: env.bool("AUTH_REFRESH_COOKIE", false),
+
+		ResetTokenBytes:      env.int("RESET_TOKEN_BYTES", DefaultResetTokenBytes),
+		ResetMaxActiveTokens: env.int("RESET_MAX_ACTIVE_TOKENS", DefaultResetMaxActiveTokens),
+
+		BcryptCost:          env.int("BCRYPT_COST", password.DefaultCost),
+		PasswordBreachCheck: env.bool("PASSWORD_BREACH_CHECK", false),
+
+		ResetEmailLimit:  env.int("RESET_RATE_LIMIT_EMAIL", DefaultResetEmailLimit),
+		ResetIPLimit:     env.int("RESET_RATE_LIMIT_IP", DefaultResetIPLimit),
+		ResetLimitWindow: env.duration("RESET_RATE_LIMIT_WINDOW", DefaultResetLimitWindow),
+
+		ResetLimitFailurePolicy: getEnv("RESET_RATE_LIMIT_FAILURE_POLICY", DefaultResetLimitFailurePolicy),
+
+		EmailDriver:      getEnv("EMAIL_DRIVER", "smtp"),
+		EmailTemplateDir: getEnv("EMAIL_TEMPLATE_DIR", ""),
 
 		// SMTP Configuration (Yandex Mail)
 		SMTPHost:        getEnv("SMTP_HOST", "smtp.yandex.ru"),
-		SMTPPort:        getEnvAsInt("SMTP_PORT", 465),
+		SMTPPort:        env.int("SMTP_PORT", 465),
 		SMTPUsername:    getEnv("SMTP_USERNAME", ""),
 		SMTPPassword:    getEnv("SMTP_PASSWORD", ""),
 		SMTPFromAddress: getEnv("SMTP_FROM_ADDRESS", ""),
 		SMTPFromName:    getEnv("SMTP_FROM_NAME", "BURCEV"),
+		SMTPPoolSize:    env.int("SMTP_POOL_SIZE", DefaultSMTPPoolSize),
+		SMTPIdleTimeout: env.duration("SMTP_IDLE_TIMEOUT", DefaultSMTPIdleTimeout),
 
 		// Password Reset
-		ResetPasswordURL: getResetPasswordURL(),
+		ResetPasswordURL: getEnv("RESET_PASSWORD_URL", getResetPasswordURL()),
+
+		UnsubscribeURL: getUnsubscribeURL(),
+
+		OrganizationJoinURL: getOrganizationJoinURL(),
+
+		DataExportURL: getDataExportURL(),
+
+		ShareURL: getShareURL(),
+
+		EmailChangeConfirmURL: getEmailChangeConfirmURL(),
 
 		// Weekly Photos S3 (Object Storage) — falls back to generic S3_* vars
 		WeeklyPhotosS3AccessKeyID:     getEnvWithFallback("WEEKLY_PHOTOS_S3_ACCESS_KEY_ID", "S3_ACCESS_KEY_ID", ""),
@@ -167,6 +467,33 @@ func Load() (*Config, error) {
 
 		S3PathPrefix: getEnv("S3_PATH_PREFIX", ""),
 
+		StorageDefaultRegion: getEnv("STORAGE_DEFAULT_REGION", "default"),
+		StorageRegions:       getStorageRegions(),
+
+		PhotosStorageDir: getEnv("PHOTOS_STORAGE_DIR", "./data/photos"),
+
+		UploadsStorageDir: getEnv("UPLOADS_STORAGE_DIR", "./data/uploads"),
+
+		ExportsStorageDir: getEnv("EXPORTS_STORAGE_DIR", "./data/exports"),
+
+		MaintenanceMode:        env.bool("MAINTENANCE_MODE", false),
+		MaintenanceMessage:     getEnv("MAINTENANCE_MESSAGE", ""),
+		MaintenanceModePersist: env.bool("MAINTENANCE_MODE_PERSIST", true),
+
+		DuplicateEntryCheck:  env.bool("NUTRITION_DUPLICATE_CHECK", true),
+		DuplicateEntryWindow: env.duration("NUTRITION_DUPLICATE_WINDOW", DefaultDuplicateEntryWindow),
+
+		NutritionExcludedDayFlags: getNutritionExcludedDayFlags(),
+		NutritionSodiumWarningMg:  env.int("NUTRITION_SODIUM_WARNING_MG", DefaultNutritionSodiumWarningMg),
+
+		QuotaEntriesPerDay: env.int("QUOTA_ENTRIES_PER_DAY", DefaultQuotaEntriesPerDay),
+		QuotaPhotoBytes:    env.int("QUOTA_PHOTO_BYTES", DefaultQuotaPhotoBytes),
+		QuotaWebhooks:      env.int("QUOTA_WEBHOOKS", DefaultQuotaWebhooks),
+
+		BodyFatAnalyzerURL:            getEnv("BODY_FAT_ANALYZER_URL", ""),
+		BodyFatAnalyzerAPIKey:         getEnv("BODY_FAT_ANALYZER_API_KEY", ""),
+		BodyFatAnalyzerTimeoutSeconds: env.int("BODY_FAT_ANALYZER_TIMEOUT_SECONDS", 60),
+
 		// Food Photos S3 — falls back to generic S3_* vars
 		FoodPhotosS3AccessKeyID:     getEnvWithFallback("FOOD_PHOTOS_S3_ACCESS_KEY_ID", "S3_ACCESS_KEY_ID", ""),
 		FoodPhotosS3SecretAccessKey: getEnvWithFallback("FOOD_PHOTOS_S3_SECRET_ACCESS_KEY", "S3_SECRET_ACCESS_KEY", ""),
@@ -177,21 +504,182 @@ func Load() (*Config, error) {
 		// OpenRouter (AI food recognition)
 		OpenRouterAPIKey:          getEnv("OPENROUTER_API_KEY", ""),
 		OpenRouterModel:           getEnv("OPENROUTER_MODEL", "anthropic/claude-sonnet-4"),
-		FoodRecognitionDailyLimit: getEnvAsInt("FOOD_RECOGNITION_DAILY_LIMIT", 3),
+		FoodRecognitionDailyLimit: env.int("FOOD_RECOGNITION_DAILY_LIMIT", 3),
+
+		MigrationBaseline: env.int("DB_MIGRATION_BASELINE", 0),
+		MigrateOnStart:    env.bool("MIGRATE_ON_START", true),
 
-		MigrationBaseline: getEnvAsInt("DB_MIGRATION_BASELINE", 0),
+		LogLevel:       getEnv("LOG_LEVEL", "info"),
+		LogRedactKeys:  getLogRedactKeys(),
+		LogSampleRate:  env.int("LOG_SAMPLE_RATE", 1),
+		LogSlowRequest: env.duration("LOG_SLOW_REQUEST", DefaultLogSlowRequest),
 
-		LogLevel: getEnv("LOG_LEVEL", "info"),
+		CacheDriver:        getEnv("CACHE_DRIVER", ""),
+		CacheMemoryEntries: env.int("CACHE_MEMORY_ENTRIES", cache.DefaultMemoryEntries),
+		RedisURL:           getEnv("REDIS_URL", ""),
 	}
 
-	// Validate required configuration
-	if cfg.DatabaseURL == "" && cfg.DatabasePassword == "" {
-		return nil, fmt.Errorf("DATABASE_URL or DB_PASSWORD is required")
+	cfg.TrustedProxies = getTrustedProxies(cfg.Env)
+
+	if err := errors.Join(env.errs...); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
 	return cfg, nil
 }
 
+// Validate checks the configuration. Basic sanity rules always apply; in
+// production development defaults and insecure settings are rejected too.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.DatabaseURL == "" && c.DatabasePassword == "" {
+		errs = append(errs, errors.New("DATABASE_URL or DB_PASSWORD is required"))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", c.Port))
+	}
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", c.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.IdleTimeout},
+		{"SHUTDOWN_TIMEOUT", c.ShutdownTimeout},
+		{"DB_SLOW_QUERY_THRESHOLD", c.DBSlowQueryThreshold},
+		{"ACCESS_TOKEN_TTL", c.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", c.RefreshTokenTTL},
+		{"REFRESH_TOKEN_REMEMBER_ME_TTL", c.RememberMeRefreshTokenTTL},
+		{"RESET_RATE_LIMIT_WINDOW", c.ResetLimitWindow},
+		{"SMTP_IDLE_TIMEOUT", c.SMTPIdleTimeout},
+		{"NUTRITION_DUPLICATE_WINDOW", c.DuplicateEntryWindow},
+		{"LOG_SLOW_REQUEST", c.LogSlowRequest},
+	} {
+		if d.value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", d.key, d.value))
+		}
+	}
+	for _, origin := range c.CORSOrigins {
+		if err := validateOrigin(origin); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, proxy := range c.TrustedProxies {
+		if err := validateTrustedProxy(proxy); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if c.SMTPPoolSize < 1 {
+		errs = append(errs, fmt.Errorf("SMTP_POOL_SIZE must be at least 1, got %d", c.SMTPPoolSize))
+	}
+	if c.GzipMinSize < 0 {
+		errs = append(errs, fmt.Errorf("GZIP_MIN_SIZE must not be negative, got %d", c.GzipMinSize))
+	}
+	if c.ResetTokenTTL < MinResetTokenTTL || c.ResetTokenTTL > MaxResetTokenTTL {
+		errs = append(errs, fmt.Errorf("RESET_TOKEN_TTL must be between %s and %s, got %s", MinResetTokenTTL, MaxResetTokenTTL, c.ResetTokenTTL))
+	}
+	if c.ResetTokenBytes < MinResetTokenBytes || c.ResetTokenBytes > MaxResetTokenBytes {
+		errs = append(errs, fmt.Errorf("RESET_TOKEN_BYTES must be between %d and %d, got %d", MinResetTokenBytes, MaxResetTokenBytes, c.ResetTokenBytes))
+	}
+	if c.BcryptCost < password.MinCost || c.BcryptCost > password.MaxCost {
+		errs = append(errs, fmt.Errorf("BCRYPT_COST must be between %d and %d, got %d", password.MinCost, password.MaxCost, c.BcryptCost))
+	}
+	if c.ResetMaxActiveTokens < 1 {
+		errs = append(errs, fmt.Errorf("RESET_MAX_ACTIVE_TOKENS must be at least 1, got %d", c.ResetMaxActiveTokens))
+	}
+	if err := validateResetPasswordURL(c.ResetPasswordURL); err != nil {
+		errs = append(errs, err)
+	}
+	if c.ResetEmailLimit < 1 {
+		errs = append(errs, fmt.Errorf("RESET_RATE_LIMIT_EMAIL must be at least 1, got %d", c.ResetEmailLimit))
+	}
+	if c.ResetIPLimit < 1 {
+		errs = append(errs, fmt.Errorf("RESET_RATE_LIMIT_IP must be at least 1, got %d", c.ResetIPLimit))
+	}
+	if c.ResetLimitFailurePolicy != "open" && c.ResetLimitFailurePolicy != "closed" {
+		errs = append(errs, fmt.Errorf("RESET_RATE_LIMIT_FAILURE_POLICY must be open or closed, got %q", c.ResetLimitFailurePolicy))
+	}
+	for _, flag := range c.NutritionExcludedDayFlags {
+		if !slices.Contains(nutritionDayFlagTypes, flag) {
+			errs = append(errs, fmt.Errorf("NUTRITION_EXCLUDED_DAY_FLAGS must list refeed, sick or travel, got %q", flag))
+		}
+	}
+	if c.NutritionSodiumWarningMg < 0 {
+		errs = append(errs, fmt.Errorf("NUTRITION_SODIUM_WARNING_MG must not be negative, got %d", c.NutritionSodiumWarningMg))
+	}
+	for _, q := range []struct {
+		key   string
+		value int
+	}{
+		{"QUOTA_ENTRIES_PER_DAY", c.QuotaEntriesPerDay},
+		{"QUOTA_PHOTO_BYTES", c.QuotaPhotoBytes},
+		{"QUOTA_WEBHOOKS", c.QuotaWebhooks},
+	} {
+		if q.value < 1 {
+			errs = append(errs, fmt.Errorf("%s must be at least 1, got %d", q.key, q.value))
+		}
+	}
+	if _, err := logger.ParseLevel(c.LogLevel); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL must be debug, info, warn, error or fatal, got %q", c.LogLevel))
+	}
+	if c.LogSampleRate < 1 {
+		errs = append(errs, fmt.Errorf("LOG_SAMPLE_RATE must be at least 1, got %d", c.LogSampleRate))
+	}
+	switch c.CacheDriver {
+	case "":
+	case cache.DriverMemory:
+		if c.CacheMemoryEntries < 1 {
+			errs = append(errs, fmt.Errorf("CACHE_MEMORY_ENTRIES must be at least 1, got %d", c.CacheMemoryEntries))
+		}
+	case cache.DriverRedis:
+		if c.RedisURL == "" {
+			errs = append(errs, errors.New("REDIS_URL is required when CACHE_DRIVER is redis"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("CACHE_DRIVER must be empty, memory or redis, got %q", c.CacheDriver))
+	}
+
+	if c.Env == "production" {
+		switch {
+		case c.JWTSecret == "" || c.JWTSecret == DefaultJWTSecret:
+			errs = append(errs, errors.New("JWT_SECRET must be set in production"))
+		case len(c.JWTSecret) < MinJWTSecretLength:
+			errs = append(errs, fmt.Errorf("JWT_SECRET must be at least %d characters in production", MinJWTSecretLength))
+		}
+
+		// Without the list every client could pass for any IP; "none" is
+		// the explicit choice of serving clients directly
+		if c.TrustedProxies == nil {
+			errs = append(errs, errors.New(`TRUSTED_PROXIES must be set in production; use "none" when no proxy is in front`))
+		}
+
+		if c.DatabaseSSLMode == "disable" || strings.Contains(c.DatabaseURL, "sslmode=disable") {
+			errs = append(errs, errors.New("database SSL must not be disabled in production"))
+		}
+
+		// The log and memory drivers do not deliver mail, so only smtp needs credentials
+		if c.EmailDriver == "" || c.EmailDriver == "smtp" {
+			for _, f := range []struct{ key, value string }{
+				{"SMTP_HOST", c.SMTPHost},
+				{"SMTP_USERNAME", c.SMTPUsername},
+				{"SMTP_PASSWORD", c.SMTPPassword},
+				{"SMTP_FROM_ADDRESS", c.SMTPFromAddress},
+			} {
+				if f.value == "" {
+					errs = append(errs, fmt.Errorf("%s is required in production when EMAIL_DRIVER is smtp", f.key))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -209,6 +697,120 @@ func getEnvWithFallback(key, fallbackKey, defaultValue string) string {
 	return defaultValue
 }
 
+// getCORSOrigins reads the comma-separated CORS_ORIGINS list, falling back to
+// the single-value CORS_ORIGIN
+func getCORSOrigins() []string {
+	var origins []string
+	for _, origin := range strings.Split(getEnvWithFallback("CORS_ORIGINS", "CORS_ORIGIN", ""), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// validateOrigin checks a CORS origin: an absolute http(s) URL with no path,
+// query or trailing slash. The host may start with a "*." wildcard label.
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("CORS origin %q must be an absolute http(s) URL", origin)
+	}
+	if u.Path != "" || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("CORS origin %q must not have a path, query or trailing slash", origin)
+	}
+	if strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+		return fmt.Errorf("CORS origin %q may only use a wildcard as the first subdomain label", origin)
+	}
+	return nil
+}
+
+// getTrustedProxies reads the comma-separated TRUSTED_PROXIES list; "none"
+// trusts no proxy. When unset it is DefaultTrustedProxies outside
+// production and nil in production, which Validate rejects.
+func getTrustedProxies(env string) []string {
+	value := os.Getenv("TRUSTED_PROXIES")
+	switch {
+	case strings.TrimSpace(value) == "none":
+		return []string{}
+	case value == "" && env == "production":
+		return nil
+	case value == "":
+		value = DefaultTrustedProxies
+	}
+	proxies := []string{}
+	for _, proxy := range strings.Split(value, ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// validateTrustedProxy checks a trusted proxy: an IP address or a CIDR
+func validateTrustedProxy(proxy string) error {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return nil
+	}
+	if net.ParseIP(proxy) != nil {
+		return nil
+	}
+	return fmt.Errorf("TRUSTED_PROXIES entry %q must be an IP address or CIDR", proxy)
+}
+
+// getNutritionExcludedDayFlags reads the comma-separated
+// NUTRITION_EXCLUDED_DAY_FLAGS list; "none" excludes no flagged days
+func getNutritionExcludedDayFlags() []string {
+	value := getEnv("NUTRITION_EXCLUDED_DAY_FLAGS", DefaultNutritionExcludedDayFlags)
+	if strings.TrimSpace(value) == "none" {
+		return []string{}
+	}
+	var flags []string
+	for _, flag := range strings.Split(value, ",") {
+		if flag = strings.TrimSpace(strings.ToLower(flag)); flag != "" {
+			flags = append(flags, flag)
+		}
+	}
+	return flags
+}
+
+// getLogRedactKeys reads the comma-separated LOG_REDACT_KEYS list
+func getLogRedactKeys() []string {
+	var keys []string
+	for _, key := range strings.Split(getEnv("LOG_REDACT_KEYS", DefaultLogRedactKeys), ",") {
+		if key = strings.TrimSpace(strings.ToLower(key)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// getStorageRegions reads the named storage regions listed in STORAGE_REGIONS
+// (comma-separated, e.g. "eu,kz"). Each region is configured through
+// STORAGE_REGION_<NAME>_* variables; credentials fall back to S3_*.
+func getStorageRegions() []StorageRegionConfig {
+	var regions []StorageRegionConfig
+	for _, name := range strings.Split(getEnv("STORAGE_REGIONS", ""), ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		prefix := "STORAGE_REGION_" + strings.ToUpper(name) + "_"
+		regions = append(regions, StorageRegionConfig{
+			Name:            name,
+			AccessKeyID:     getEnvWithFallback(prefix+"ACCESS_KEY_ID", "S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnvWithFallback(prefix+"SECRET_ACCESS_KEY", "S3_SECRET_ACCESS_KEY", ""),
+			Bucket:          getEnv(prefix+"BUCKET", ""),
+			Region:          getEnv(prefix+"REGION", ""),
+			Endpoint:        getEnvWithFallback(prefix+"ENDPOINT", "S3_ENDPOINT", "https://storage.yandexcloud.net"),
+		})
+	}
+	return regions
+}
+
 func getResetPasswordURL() string {
 	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
 		return "https://" + domain + "/reset-password"
@@ -216,10 +818,93 @@ func getResetPasswordURL() string {
 	return "http://localhost:3069/reset-password"
 }
 
-func getEnvAsInt(key string, defaultValue int) int {
+// validateResetPasswordURL checks that reset links are absolute http(s)
+// URLs once the token placeholder is filled in
+func validateResetPasswordURL(resetURL string) error {
+	u, err := url.Parse(strings.ReplaceAll(resetURL, ResetURLTokenPlaceholder, "token"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("RESET_PASSWORD_URL must be an absolute http(s) URL, got %q", resetURL)
+	}
+	return nil
+}
+
+func getUnsubscribeURL() string {
+	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
+		return "https://" + domain + "/api/v1/notifications/unsubscribe"
+	}
+	return "http://localhost:4000/api/v1/notifications/unsubscribe"
+}
+
+func getOrganizationJoinURL() string {
+	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
+		return "https://" + domain + "/api/v1/organizations/join"
+	}
+	return "http://localhost:4000/api/v1/organizations/join"
+}
+
+func getDataExportURL() string {
+	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
+		return "https://" + domain + "/data-export"
+	}
+	return "http://localhost:3069/data-export"
+}
+
+func getShareURL() string {
+	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
+		return "https://" + domain + "/share"
+	}
+	return "http://localhost:3069/share"
+}
+
+func getEmailChangeConfirmURL() string {
+	if domain := os.Getenv("APP_DOMAIN"); domain != "" {
+		return "https://" + domain + "/api/v1/users/confirm-email"
+	}
+	return "http://localhost:4000/api/v1/users/confirm-email"
+}
+
+// envReader reads typed environment variables and collects the values it
+// could not parse, so Load reports them instead of silently using defaults
+type envReader struct {
+	errs []error
+}
+
+func (r *envReader) int(key string, defaultValue int) int {
 	valueStr := getEnv(key, "")
-	if value, err := strconv.Atoi(valueStr); err == nil {
-		return value
+	if valueStr == "" {
+		return defaultValue
 	}
-	return defaultValue
+	value, err := strconv.Atoi(valueStr)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: invalid integer %q", key, valueStr))
+		return defaultValue
+	}
+	return value
+}
+
+func (r *envReader) bool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: invalid boolean %q", key, valueStr))
+		return defaultValue
+	}
+	return value
+}
+
+// duration parses Go durations such as "15s" or "1h30m"
+func (r *envReader) duration(key string, defaultValue time.Duration) time.Duration {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := time.ParseDuration(valueStr)
+	if err != nil {
+		r.errs = append(r.errs, fmt.Errorf("%s: invalid duration %q (expected e.g. \"15s\")", key, valueStr))
+		return defaultValue
+	}
+	return value
 }
@@ -0,0 +1,48 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClientIPBehindProxies checks which address the reset rate limits
+// count a request against. httptest requests come from 192.0.2.1.
+func TestClientIPBehindProxies(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies string
+		forwardedFor   string
+		wantIP         string
+	}{
+		{"header from an untrusted peer is ignored", "", "198.51.100.9", "192.0.2.1"},
+		{"no trusted proxies", "none", "198.51.100.9", "192.0.2.1"},
+		{"header from a trusted proxy names the client", "192.0.2.0/24", "198.51.100.9", "198.51.100.9"},
+		{"addresses the client prepended are ignored", "192.0.2.0/24,10.0.0.0/8", "203.0.113.66, 198.51.100.9, 10.1.2.3", "198.51.100.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUSTED_PROXIES", tt.trustedProxies)
+			s := New(t)
+
+			s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+				WithArgs(userEmail, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+				WithArgs(tt.wantIP, sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			s.DB.ExpectExec("INSERT INTO password_reset_attempts").
+				WithArgs(userEmail, tt.wantIP).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+			s.DB.ExpectExec("INSERT INTO email_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+
+			resp := s.Client().
+				WithHeader("X-Forwarded-For", tt.forwardedFor).
+				Post("/api/v1/auth/forgot-password", map[string]any{"email": userEmail})
+			require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+		})
+	}
+}
@@ -0,0 +1,251 @@
+// Package integration runs end-to-end scenarios against the full router:
+// every route, middleware and service as the server wires them, over a
+// sqlmock database and an in-memory email sender. Handler tests miss route
+// wiring and middleware interactions; these scenarios catch them. It is
+// test support only and nothing in the server imports it.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/server"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Suite is the full router over a mock database. Scenarios set up DB
+// expectations for each request before sending it; unmet expectations fail
+// the test when it ends.
+type Suite struct {
+	t      *testing.T
+	Config *config.Config
+	Router *gin.Engine
+	DB     sqlmock.Sqlmock
+	Mail   *email.MemorySender
+
+	outbox *email.Outbox
+}
+
+// New builds the production router for t. Configuration comes from the
+// environment as in production, with storage in temporary directories and
+// the cheapest bcrypt cost.
+func New(t *testing.T) *Suite {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ENV", "test")
+	t.Setenv("DATABASE_URL", "postgres://test@localhost/test")
+	t.Setenv("BCRYPT_COST", strconv.Itoa(password.MinCost))
+	t.Setenv("PHOTOS_STORAGE_DIR", t.TempDir())
+	t.Setenv("UPLOADS_STORAGE_DIR", t.TempDir())
+	t.Setenv("EXPORTS_STORAGE_DIR", t.TempDir())
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	require.Equal(t, password.MinCost, cfg.BcryptCost)
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		db.Close()
+	})
+
+	log := logger.New()
+	mail := email.NewMemorySender()
+	emailService, err := email.NewServiceWithSender(mail, log)
+	require.NoError(t, err)
+
+	deps := server.NewDeps(cfg, log, &database.DB{DB: db}, emailService)
+	return &Suite{
+		t:      t,
+		Config: cfg,
+		Router: server.NewRouter(cfg, deps),
+		DB:     mock,
+		Mail:   mail,
+		outbox: deps.EmailOutbox(),
+	}
+}
+
+// DeliverQueuedEmails runs one pass of the email outbox worker and returns
+// the number of emails it processed. The scenario sets up the claim and
+// delivery expectations.
+func (s *Suite) DeliverQueuedEmails() int {
+	s.t.Helper()
+	n, err := s.outbox.ProcessPending(context.Background())
+	require.NoError(s.t, err)
+	return n
+}
+
+// Yesterday is the day before today as YYYY-MM-DD. The router validates
+// dates against the real clock, so scenarios use days derived from it: a
+// fixed date would fall out of the accepted window as time passes.
+func Yesterday() string {
+	return types.DateOf(time.Now()).AddDays(-1).String()
+}
+
+// Client sends requests without credentials
+func (s *Suite) Client() *Client {
+	return &Client{suite: s}
+}
+
+// ClientAs sends requests with an access token of userID, as if the user
+// had logged in
+func (s *Suite) ClientAs(userID int64, email, role string) *Client {
+	return s.Client().WithToken(s.Token(userID, email, role))
+}
+
+// Token signs an access token of userID the way login does, with token
+// version 0 and a new session
+func (s *Suite) Token(userID int64, email, role string) string {
+	s.t.Helper()
+	now := time.Now()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: uuid.NewString(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.Config.AccessTokenTTL)),
+		},
+	}).SignedString([]byte(s.Config.JWTSecret))
+	require.NoError(s.t, err)
+	return token
+}
+
+// Client sends requests to the suite's router and checks what every
+// response must carry
+type Client struct {
+	suite  *Suite
+	token  string
+	header http.Header
+}
+
+// WithToken returns a client sending token as the bearer token
+func (c *Client) WithToken(token string) *Client {
+	return &Client{suite: c.suite, token: token, header: c.header}
+}
+
+// WithHeader returns a client that also sends the header key
+func (c *Client) WithHeader(key, value string) *Client {
+	header := c.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(key, value)
+	return &Client{suite: c.suite, token: c.token, header: header}
+}
+
+// Do sends a request. body is sent as JSON; a string is sent as is.
+func (c *Client) Do(method, path string, body any) *Response {
+	t := c.suite.t
+	t.Helper()
+
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		require.NoError(t, err)
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	if reader != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	w := httptest.NewRecorder()
+	c.suite.Router.ServeHTTP(w, req)
+
+	resp := &Response{Status: w.Code, Header: w.Header(), Body: w.Body.Bytes()}
+	checkResponse(t, method+" "+path, resp)
+	return resp
+}
+
+// Get sends a GET request
+func (c *Client) Get(path string) *Response {
+	c.suite.t.Helper()
+	return c.Do(http.MethodGet, path, nil)
+}
+
+// Post sends a POST request with a JSON body
+func (c *Client) Post(path string, body any) *Response {
+	c.suite.t.Helper()
+	return c.Do(http.MethodPost, path, body)
+}
+
+// Envelope is the JSON body every API response is wrapped in
+type Envelope struct {
+	Status    string          `json:"status"`
+	Data      json.RawMessage `json:"data"`
+	Message   string          `json:"message"`
+	Code      string          `json:"code"`
+	Details   json.RawMessage `json:"details"`
+	RequestID string          `json:"request_id"`
+}
+
+// Response is a recorded response
+type Response struct {
+	Status   int
+	Header   http.Header
+	Body     []byte
+	Envelope Envelope
+}
+
+// Decode decodes the data of the envelope into v
+func (r *Response) Decode(t *testing.T, v any) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal(r.Envelope.Data, v), "data: %s", r.Envelope.Data)
+}
+
+// checkResponse asserts what every API response carries: the headers that
+// keep it out of caches, the browser security headers, a request id and the
+// response envelope. HSTS and CSP are left to nginx.
+func checkResponse(t *testing.T, request string, r *Response) {
+	t.Helper()
+	assert.Contains(t, r.Header.Get("Cache-Control"), "no-store", "%s: Cache-Control", request)
+	assert.Equal(t, "no-cache", r.Header.Get("Pragma"), "%s: Pragma", request)
+	assert.Equal(t, "nosniff", r.Header.Get("X-Content-Type-Options"), "%s: X-Content-Type-Options", request)
+	assert.Equal(t, "SAMEORIGIN", r.Header.Get("X-Frame-Options"), "%s: X-Frame-Options", request)
+	assert.Equal(t, "strict-origin-when-cross-origin", r.Header.Get("Referrer-Policy"), "%s: Referrer-Policy", request)
+	assert.NotEmpty(t, r.Header.Get("X-Request-Id"), "%s: X-Request-Id", request)
+
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		return
+	}
+	require.NoError(t, json.Unmarshal(r.Body, &r.Envelope), "%s: body %s", request, r.Body)
+	if r.Status < http.StatusBadRequest {
+		assert.Equal(t, "success", r.Envelope.Status, "%s: envelope status, body %s", request, r.Body)
+		return
+	}
+	assert.Equal(t, "error", r.Envelope.Status, "%s: envelope status, body %s", request, r.Body)
+	assert.NotEmpty(t, r.Envelope.Message, "%s: error message", request)
+	assert.Equal(t, r.Header.Get("X-Request-Id"), r.Envelope.RequestID, "%s: error request id", request)
+}
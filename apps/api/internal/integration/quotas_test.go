@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestEntryQuota logs an entry over the daily quota, then reads the usage
+// the user is pointed to
+func TestEntryQuota(t *testing.T) {
+	const userID int64 = 7
+	s := New(t)
+	client := s.ClientAs(userID, userEmail, "client")
+	limit := s.Config.QuotaEntriesPerDay
+	day := Yesterday()
+
+	s.DB.ExpectBegin()
+	s.DB.ExpectQuery("INSERT INTO user_daily_entries").
+		WithArgs(userID, day, int64(limit)).
+		WillReturnRows(sqlmock.NewRows([]string{"entries", "limit"}).AddRow(limit+1, limit))
+	s.DB.ExpectRollback()
+
+	resp := client.Post("/api/v1/nutrition/entries", map[string]any{
+		"date": day, "meal": "snack", "food": "Яблоко", "calories": 80,
+	})
+	require.Equal(t, http.StatusForbidden, resp.Status, "%s", resp.Body)
+	assert.Equal(t, "QUOTA_EXCEEDED", resp.Envelope.Code)
+	var details map[string]any
+	require.NoError(t, json.Unmarshal(resp.Envelope.Details, &details))
+	assert.Equal(t, map[string]any{"resource": "entries_per_day", "used": float64(limit), "limit": float64(limit)}, details)
+
+	s.DB.ExpectQuery("SELECT (.+) FROM users u").
+		WithArgs(userID, day).
+		WillReturnRows(sqlmock.NewRows([]string{"entries", "photo_bytes", "webhooks", "e", "p", "w"}).
+			AddRow(limit, 0, 0, nil, nil, nil))
+
+	resp = client.Get("/api/v1/users/me/usage?date=" + day)
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+	var usage struct {
+		EntriesPerDay struct {
+			Used  int `json:"used"`
+			Limit int `json:"limit"`
+		} `json:"entries_per_day"`
+	}
+	resp.Decode(t, &usage)
+	assert.Equal(t, limit, usage.EntriesPerDay.Used)
+	assert.Equal(t, limit, usage.EntriesPerDay.Limit)
+}
+
+// TestQuotaOverridesAreAdminOnly checks the override route sits behind the
+// admin role
+func TestQuotaOverridesAreAdminOnly(t *testing.T) {
+	s := New(t)
+	s.DB.ExpectQuery("SELECT token_version FROM users").
+		WillReturnRows(sqlmock.NewRows([]string{"token_version"}).AddRow(0))
+
+	resp := s.ClientAs(7, userEmail, "client").Do(http.MethodPut, "/api/v1/admin/users/8/quotas", map[string]any{"entries_per_day": 1000})
+
+	assert.Equal(t, http.StatusForbidden, resp.Status, "%s", resp.Body)
+}
@@ -0,0 +1,337 @@
+package integration
+
+import (
+	"database/sql/driver"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	userEmail    = "anna@example.com"
+	userPassword = "Kettlebell#Row42"
+)
+
+var userColumns = []string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at"}
+
+var entryColumns = []string{
+	"id", "user_id", "date", "meal", "food", "calories", "protein", "carbs", "fat",
+	"created_at", "updated_at", "recipe_id", "portion_grams", "version",
+	"fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g",
+}
+
+var reportDayColumns = []string{
+	"date", "entries", "calories", "protein", "carbs", "fat",
+	"target_calories", "target_protein", "target_carbs", "target_fat", "flag",
+	"fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g",
+}
+
+// captured is an argument matcher that accepts any value of type T and
+// stores it, for queries whose arguments later steps need
+type captured[T any] struct {
+	dst *T
+}
+
+func capture[T any](dst *T) sqlmock.Argument {
+	return captured[T]{dst: dst}
+}
+
+func (c captured[T]) Match(v driver.Value) bool {
+	value, ok := v.(T)
+	if ok {
+		*c.dst = value
+	}
+	return ok
+}
+
+type authResult struct {
+	User struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+	} `json:"user"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// expectLogin sets up the queries of a successful login of userID with
+// password
+func expectLogin(t *testing.T, s *Suite, userID int64, password string) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.Config.BcryptCost)
+	require.NoError(t, err)
+
+	s.DB.ExpectQuery("SELECT (.+) FROM users WHERE LOWER\\(email\\) = \\$1").
+		WithArgs(userEmail).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "email", "name", "password", "role", "email_verified",
+			"onboarding_completed", "created_at", "purge_after", "token_version",
+		}).AddRow(userID, userEmail, "Анна", string(hash), "client", true, true, time.Now(), nil, 0))
+	s.DB.ExpectExec("INSERT INTO refresh_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestRegisterLoginLogAndReport(t *testing.T) {
+	s := New(t)
+	const userID = int64(42)
+
+	// Register
+	s.DB.ExpectQuery("INSERT INTO users").
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userID, userEmail, "Анна", "client", false, false, time.Now()))
+	s.DB.ExpectExec("INSERT INTO user_settings").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.DB.ExpectQuery("SELECT u.id FROM users").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	s.DB.ExpectExec("INSERT INTO refresh_tokens").WillReturnResult(sqlmock.NewResult(1, 1))
+	s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM email_verification_codes").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.DB.ExpectExec("INSERT INTO email_verification_codes").WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp := s.Client().Post("/api/v1/auth/register", map[string]any{
+		"email":    userEmail,
+		"password": userPassword,
+		"name":     "Анна",
+	})
+	require.Equal(t, http.StatusCreated, resp.Status, "%s", resp.Body)
+	var registered authResult
+	resp.Decode(t, &registered)
+	assert.Equal(t, userID, registered.User.ID)
+	assert.NotEmpty(t, registered.Token)
+	assert.NotEmpty(t, registered.RefreshToken)
+	require.Len(t, s.Mail.Messages(), 1, "verification email")
+	assert.Equal(t, userEmail, s.Mail.Messages()[0].To)
+
+	// Log in with the same credentials
+	expectLogin(t, s, userID, userPassword)
+	resp = s.Client().Post("/api/v1/auth/login", map[string]any{"email": userEmail, "password": userPassword})
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+	var loggedIn authResult
+	resp.Decode(t, &loggedIn)
+	require.NotEmpty(t, loggedIn.Token)
+	client := s.Client().WithToken(loggedIn.Token)
+
+	// Log two meals
+	now := time.Now()
+	day := Yesterday()
+	type loggedEntry struct {
+		meal                          string
+		food                          string
+		calories, protein, carbs, fat float64
+	}
+	entries := []loggedEntry{
+		{"breakfast", "Овсянка", 350, 12, 60, 7},
+		{"lunch", "Гречка с курицей", 650, 45, 70, 20},
+	}
+	// total is what the day's summaries must add up to
+	var total loggedEntry
+	for i, entry := range entries {
+		total.calories += entry.calories
+		total.protein += entry.protein
+		total.carbs += entry.carbs
+		total.fat += entry.fat
+
+		// Each entry is counted against the daily quota in its transaction
+		s.DB.ExpectBegin()
+		s.DB.ExpectQuery("INSERT INTO user_daily_entries").
+			WithArgs(userID, day, int64(s.Config.QuotaEntriesPerDay)).
+			WillReturnRows(sqlmock.NewRows([]string{"entries", "limit"}).AddRow(i+1, s.Config.QuotaEntriesPerDay))
+		s.DB.ExpectQuery("INSERT INTO nutrition_entries").
+			WithArgs(sqlmock.AnyArg(), userID, day, entry.meal, entry.food, entry.calories, entry.protein, entry.carbs, entry.fat,
+				nil, nil, sqlmock.AnyArg(), nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(
+				int64(i+1), userID, day, entry.meal, entry.food, entry.calories, entry.protein, entry.carbs, entry.fat,
+				now, now, nil, nil, 1, nil, nil, nil, nil,
+			))
+		s.DB.ExpectExec("INSERT INTO nutrition_daily_rollups").
+			WithArgs(userID, day, 1, entry.calories, entry.protein, entry.carbs, entry.fat, 0.0, 0, 0.0, 0, 0.0, 0, 0.0, 0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// The entry's event is logged with it for the webhooks
+		s.DB.ExpectExec("INSERT INTO domain_events").
+			WithArgs(sqlmock.AnyArg(), "nutrition.entry.created", userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		s.DB.ExpectCommit()
+		s.DB.ExpectQuery("SELECT id, created_at\\s+FROM nutrition_entries").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}))
+
+		resp = client.Post("/api/v1/nutrition/entries", map[string]any{
+			"date":     day,
+			"meal":     entry.meal,
+			"food":     entry.food,
+			"calories": entry.calories,
+			"protein":  entry.protein,
+			"carbs":    entry.carbs,
+			"fat":      entry.fat,
+		})
+		require.Equal(t, http.StatusCreated, resp.Status, "%s", resp.Body)
+	}
+
+	// The report of the day reads both meals from the day's rollup, which
+	// the inserts above added the entries to
+	s.DB.ExpectQuery("FROM generate_series").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns).AddRow(
+			day, len(entries), total.calories, total.protein, total.carbs, total.fat, nil, nil, nil, nil, nil, nil, nil, nil, nil,
+		))
+	top := sqlmock.NewRows(entryColumns)
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		top.AddRow(int64(i+1), userID, day, e.meal, e.food, e.calories, e.protein, e.carbs, e.fat, now, now, nil, nil, 1, nil, nil, nil, nil)
+	}
+	s.DB.ExpectQuery("ORDER BY calories DESC").WillReturnRows(top)
+
+	resp = client.Get("/api/v1/nutrition/report?from=" + day + "&to=" + day)
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+	var report struct {
+		DaysLogged int `json:"days_logged"`
+		Average    struct {
+			Calories float64 `json:"calories"`
+			Protein  float64 `json:"protein"`
+		} `json:"average"`
+		TopEntries []struct {
+			Food string `json:"food"`
+		} `json:"top_entries"`
+	}
+	resp.Decode(t, &report)
+	assert.Equal(t, 1, report.DaysLogged)
+	assert.Equal(t, 1000.0, report.Average.Calories)
+	assert.Equal(t, 57.0, report.Average.Protein)
+	require.Len(t, report.TopEntries, 2)
+	assert.Equal(t, "Гречка с курицей", report.TopEntries[0].Food)
+
+	// The dashboard summary of the day, read in the user's time zone. Its
+	// macros come from the day's daily_metrics row, which the food tracker
+	// keeps equal to the sum of the day's entries in the database; sqlmock
+	// cannot run that, so the row below is that sum of the entries posted
+	// above rather than a value read back from them.
+	s.DB.ExpectQuery("SELECT COALESCE\\(timezone, 'Europe/Moscow'\\) FROM user_settings").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Europe/Moscow"))
+	s.DB.ExpectQuery("FROM daily_metrics").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "date", "calories", "protein", "fat", "carbs", "weight", "steps",
+			"workout_completed", "workout_type", "workout_duration", "created_at", "updated_at"}).
+			AddRow(uuid.NewString(), userID, now, int(total.calories), int(total.protein), int(total.fat), int(total.carbs), nil, 8000, false, nil, nil, now, now))
+	s.DB.ExpectQuery("FROM water_intake_events").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}).AddRow(day, 1500))
+	s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM curator_comments").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.DB.ExpectQuery("FROM nutrition_day_flags").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "type", "note", "created_at", "updated_at"}))
+	s.DB.ExpectQuery("FROM activities").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+	s.DB.ExpectQuery("FROM meal_plans").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	// None of the entries recorded micronutrients
+	s.DB.ExpectQuery("FROM nutrition_daily_rollups").
+		WithArgs(userID, day, day).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}).
+			AddRow(day, nil, nil, nil, nil))
+
+	resp = client.Get("/api/v1/dashboard/daily/" + day)
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+	var summary struct {
+		Calories       int     `json:"calories"`
+		Protein        int     `json:"protein"`
+		Carbs          int     `json:"carbs"`
+		Fat            int     `json:"fat"`
+		NetCalories    float64 `json:"net_calories"`
+		Steps          int     `json:"steps"`
+		WaterML        int     `json:"water_ml"`
+		Micronutrients struct {
+			SodiumMg *float64 `json:"sodium_mg"`
+		} `json:"micronutrients"`
+	}
+	resp.Decode(t, &summary)
+	assert.Equal(t, 1000, summary.Calories)
+	assert.Equal(t, 57, summary.Protein)
+	assert.Equal(t, 130, summary.Carbs)
+	assert.Equal(t, 27, summary.Fat)
+	assert.Equal(t, 1000.0, summary.NetCalories)
+	assert.Nil(t, summary.Micronutrients.SodiumMg)
+	assert.Equal(t, 8000, summary.Steps)
+	assert.Equal(t, 1500, summary.WaterML)
+}
+
+func TestPasswordResetRoundTrip(t *testing.T) {
+	s := New(t)
+	const userID = int64(42)
+
+	// Request a reset; the email is queued for the outbox worker
+	s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.DB.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	s.DB.ExpectExec("INSERT INTO password_reset_attempts").WillReturnResult(sqlmock.NewResult(1, 1))
+	var queued []byte
+	s.DB.ExpectExec("INSERT INTO email_outbox").
+		WithArgs("password_reset", capture(&queued)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp := s.Client().Post("/api/v1/auth/forgot-password", map[string]any{"email": userEmail})
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+	assert.Empty(t, s.Mail.Messages(), "email is sent by the worker")
+
+	// The worker issues a token and emails the link
+	s.DB.ExpectQuery("UPDATE email_outbox").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "payload", "attempts"}).AddRow(int64(1), queued, 1))
+	s.DB.ExpectQuery("SELECT u.id, u.email").
+		WithArgs(userEmail).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "language"}).AddRow(userID, userEmail, ""))
+	s.DB.ExpectExec("DELETE FROM reset_tokens").WillReturnResult(sqlmock.NewResult(0, 0))
+	var tokenHash string
+	s.DB.ExpectQuery("INSERT INTO reset_tokens").
+		WithArgs(userID, capture(&tokenHash), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	s.DB.ExpectExec("DELETE FROM email_outbox").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.Equal(t, 1, s.DeliverQueuedEmails())
+	require.Len(t, s.Mail.Messages(), 1, "reset email")
+	token := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(s.Mail.Messages()[0].TextBody)
+	require.Len(t, token, 2, "reset link in %s", s.Mail.Messages()[0].TextBody)
+
+	expectResetToken := func() {
+		s.DB.ExpectQuery("SELECT (.+) FROM reset_tokens").
+			WithArgs(tokenHash).
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "user_id", "token_hash", "created_at", "expires_at", "used_at", "ip_address", "user_agent",
+			}).AddRow(int64(7), userID, tokenHash, time.Now(), time.Now().Add(time.Hour), nil, "192.0.2.1", "test"))
+	}
+
+	// The link's token is valid
+	expectResetToken()
+	resp = s.Client().Get("/api/v1/auth/validate-reset-token?token=" + token[1])
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+
+	// Set the new password
+	const newPassword = "Deadlift#Press77"
+	expectResetToken()
+	s.DB.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow(userEmail, true, ""))
+	s.DB.ExpectBegin()
+	s.DB.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	s.DB.ExpectExec("UPDATE reset_tokens").WithArgs(int64(7)).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.DB.ExpectCommit()
+	s.DB.ExpectExec("INSERT INTO audit_log").
+		WithArgs(userID, sqlmock.AnyArg(), "password_reset_completed", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp = s.Client().Post("/api/v1/auth/reset-password", map[string]any{"token": token[1], "password": newPassword})
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+
+	// Log in with the new password
+	expectLogin(t, s, userID, newPassword)
+	resp = s.Client().Post("/api/v1/auth/login", map[string]any{"email": userEmail, "password": newPassword})
+	require.Equal(t, http.StatusOK, resp.Status, "%s", resp.Body)
+}
+
+func TestProtectedRouteWithoutToken(t *testing.T) {
+	s := New(t)
+
+	resp := s.Client().Get("/api/v1/nutrition/entries")
+	assert.Equal(t, http.StatusUnauthorized, resp.Status)
+
+	resp = s.Client().WithToken("not-a-token").Get("/api/v1/nutrition/entries")
+	assert.Equal(t, http.StatusUnauthorized, resp.Status)
+}
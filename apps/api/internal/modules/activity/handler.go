@@ -0,0 +1,105 @@
+package activity
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles activity requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new activity handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateActivity handles POST /api/v1/activity
+// calories_burned may be omitted for the types in the MET table; they are
+// then estimated from the user's weight.
+func (h *Handler) CreateActivity(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateActivityRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	activity, err := h.service.CreateActivity(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, activity)
+}
+
+// ListActivities handles GET /api/v1/activity?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) ListActivities(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	activities, err := h.service.ListActivities(c.Request.Context(), userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"activities": activities})
+}
+
+func (h *Handler) respondError(c *gin.Context, err error, userID int64) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		validation.Respond(c, err)
+	case errors.Is(err, ErrCaloriesRequired):
+		caloriesRequired(c, "Для этого вида активности укажите сожжённые калории")
+	case errors.Is(err, ErrWeightUnknown):
+		caloriesRequired(c, "Укажите сожжённые калории или внесите свой вес")
+	default:
+		h.log.Error("Failed to process activity request", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось обработать запрос")
+	}
+}
+
+// caloriesRequired sends 422 VALIDATION_FAILED on calories_burned
+func caloriesRequired(c *gin.Context, message string) {
+	response.ErrorCode(c, http.StatusUnprocessableEntity, response.CodeValidationFailed, message, response.ValidationDetails{
+		Fields: map[string]string{"calories_burned": message},
+	})
+}
@@ -0,0 +1,91 @@
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err error
+}
+
+func (m *mockService) CreateActivity(ctx context.Context, userID int64, req *CreateActivityRequest) (*Activity, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Activity{ID: testActivityID, UserID: userID, Date: req.Date, Type: req.Type, DurationMin: req.DurationMin}, nil
+}
+
+func (m *mockService) ListActivities(ctx context.Context, userID int64, from, to string) ([]Activity, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Activity{{ID: testActivityID, UserID: userID, Date: from}}, nil
+}
+
+func newTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Set("user_id", testUserID)
+	return c, w
+}
+
+func TestHandlerCreateActivity(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", `{"date":"2026-10-16","type":"running","duration_min":30}`, nil, http.StatusCreated},
+		{"missing duration", `{"date":"2026-10-16","type":"running"}`, nil, http.StatusBadRequest},
+		{"negative calories", `{"date":"2026-10-16","type":"running","duration_min":30,"calories_burned":-5}`, nil, http.StatusBadRequest},
+		{"unknown type without calories", `{"date":"2026-10-16","type":"climbing","duration_min":60}`, ErrCaloriesRequired, http.StatusUnprocessableEntity},
+		{"no weight", `{"date":"2026-10-16","type":"running","duration_min":30}`, ErrWeightUnknown, http.StatusUnprocessableEntity},
+		{"internal", `{"date":"2026-10-16","type":"running","duration_min":30}`, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&config.Config{}, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/api/v1/activity", tt.body)
+
+			handler.CreateActivity(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusUnprocessableEntity {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, response.CodeValidationFailed, resp.Code)
+				assert.Contains(t, resp.Details.(map[string]any)["fields"], "calories_burned")
+			}
+		})
+	}
+}
+
+func TestHandlerListActivities(t *testing.T) {
+	handler := NewHandler(&config.Config{}, logger.New(), &mockService{})
+	c, w := newTestContext(http.MethodGet, "/api/v1/activity?from=2026-10-01&to=2026-10-16", "")
+
+	handler.ListActivities(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	activities := resp["data"].(map[string]any)["activities"].([]any)
+	assert.Equal(t, "2026-10-01", activities[0].(map[string]any)["date"])
+}
@@ -0,0 +1,40 @@
+package activity
+
+import "math"
+
+// METs maps the activity types the calories can be estimated for to their
+// metabolic equivalent: the energy spent relative to sitting at rest. The
+// values are moderate-effort ones from the Compendium of Physical
+// Activities.
+var METs = map[string]float64{
+	"walking":    3.5,
+	"hiking":     6.0,
+	"running":    9.8,
+	"cycling":    7.5,
+	"swimming":   6.0,
+	"rowing":     7.0,
+	"elliptical": 5.0,
+	"strength":   5.0,
+	"hiit":       8.0,
+	"boxing":     7.8,
+	"football":   7.0,
+	"basketball": 6.5,
+	"tennis":     7.3,
+	"skiing":     7.0,
+	"dancing":    5.0,
+	"pilates":    3.0,
+	"yoga":       2.5,
+	"stretching": 2.3,
+}
+
+// CaloriesBurned estimates the calories an activity of the given type and
+// duration burns for a person of weightKg: MET × weight × hours, rounded to
+// one decimal place. It returns false for a type missing from METs.
+func CaloriesBurned(activityType string, durationMin int, weightKg float64) (float64, bool) {
+	met, ok := METs[activityType]
+	if !ok {
+		return 0, false
+	}
+	kcal := met * weightKg * float64(durationMin) / 60
+	return math.Round(kcal*10) / 10, true
+}
@@ -0,0 +1,49 @@
+package activity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaloriesBurned(t *testing.T) {
+	tests := []struct {
+		name         string
+		activityType string
+		durationMin  int
+		weightKg     float64
+		want         float64
+	}{
+		{"hour of running at 70 kg", "running", 60, 70, 686},
+		{"half hour of running at 70 kg", "running", 30, 70, 343},
+		{"running at 90 kg", "running", 60, 90, 882},
+		{"walking", "walking", 45, 62.5, 164.1},
+		{"yoga", "yoga", 90, 55, 206.3},
+		{"strength", "strength", 50, 80, 333.3},
+		{"cycling", "cycling", 1, 70, 8.8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := CaloriesBurned(tt.activityType, tt.durationMin, tt.weightKg)
+
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("heavier people burn more", func(t *testing.T) {
+		for activityType := range METs {
+			light, _ := CaloriesBurned(activityType, 60, 50)
+			heavy, _ := CaloriesBurned(activityType, 60, 100)
+			assert.Greater(t, heavy, light, activityType)
+		}
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		got, ok := CaloriesBurned("quidditch", 60, 70)
+
+		assert.False(t, ok)
+		assert.Zero(t, got)
+	})
+}
@@ -0,0 +1,189 @@
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// ServiceInterface defines the activity operations the handler uses
+type ServiceInterface interface {
+	CreateActivity(ctx context.Context, userID int64, req *CreateActivityRequest) (*Activity, error)
+	ListActivities(ctx context.Context, userID int64, from, to string) ([]Activity, error)
+}
+
+// Service logs activities and the calories they burned
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new activity service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{db: db, log: log}
+}
+
+const activityColumns = `id, user_id, date::text, type, duration_min, calories_burned, calories_computed, created_at`
+
+func scanActivity(row interface{ Scan(dest ...any) error }) (*Activity, error) {
+	var a Activity
+	err := row.Scan(&a.ID, &a.UserID, &a.Date, &a.Type, &a.DurationMin, &a.CaloriesBurned, &a.CaloriesComputed, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// validateActivity checks an activity request and normalizes its type.
+// Invalid input is reported as validation.Errors.
+func validateActivity(req *CreateActivityRequest) error {
+	errs := validation.Errors{}
+
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if req.Type == "" {
+		errs["type"] = "Обязательное поле"
+	}
+	if _, err := time.Parse("2006-01-02", req.Date); err != nil {
+		errs["date"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CreateActivity logs an activity. Without calories_burned the calories are
+// estimated from the MET table and the user's weight on the activity date;
+// ErrCaloriesRequired and ErrWeightUnknown report when that is impossible.
+func (s *Service) CreateActivity(ctx context.Context, userID int64, req *CreateActivityRequest) (*Activity, error) {
+	if err := validateActivity(req); err != nil {
+		return nil, err
+	}
+
+	calories, computed := 0.0, false
+	if req.CaloriesBurned != nil {
+		calories = *req.CaloriesBurned
+	} else {
+		if _, ok := METs[req.Type]; !ok {
+			return nil, ErrCaloriesRequired
+		}
+		weight, err := s.latestWeight(ctx, userID, req.Date)
+		if err != nil {
+			return nil, err
+		}
+		if weight == 0 {
+			return nil, ErrWeightUnknown
+		}
+		calories, computed = CaloriesBurned(req.Type, req.DurationMin, weight)
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO activities (user_id, date, type, duration_min, calories_burned, calories_computed)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + activityColumns
+
+	activity, err := scanActivity(s.db.QueryRowContext(ctx, query,
+		userID, req.Date, req.Type, req.DurationMin, calories, computed))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create activity: %w", err)
+	}
+
+	s.log.LogBusinessEvent("activity_logged", map[string]interface{}{
+		"activity_id": activity.ID,
+		"user_id":     userID,
+		"type":        activity.Type,
+		"computed":    computed,
+	})
+
+	return activity, nil
+}
+
+// latestWeight returns the user's most recent weight on or before date, 0
+// when none was logged
+func (s *Service) latestWeight(ctx context.Context, userID int64, date string) (float64, error) {
+	query := `
+		SELECT weight FROM daily_metrics
+		WHERE user_id = $1 AND date <= $2 AND weight IS NOT NULL
+		ORDER BY date DESC LIMIT 1`
+
+	var weight float64
+	err := s.db.QueryRowContext(ctx, query, userID, date).Scan(&weight)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest weight: %w", err)
+	}
+	return weight, nil
+}
+
+// ListActivities returns the user's activities from from to to (inclusive,
+// YYYY-MM-DD) in date order. An invalid range is reported as
+// validation.Errors.
+func (s *Service) ListActivities(ctx context.Context, userID int64, from, to string) ([]Activity, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT ` + activityColumns + `
+		FROM activities
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3
+		ORDER BY date, created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query activities: %w", err)
+	}
+	defer rows.Close()
+
+	activities := make([]Activity, 0)
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity: %w", err)
+		}
+		activities = append(activities, *activity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating activities: %w", err)
+	}
+	return activities, nil
+}
+
+// validateRange checks a from..to range of at most MaxRangeDays days
+func validateRange(from, to string) error {
+	fromDate, fromErr := time.Parse("2006-01-02", from)
+	toDate, toErr := time.Parse("2006-01-02", to)
+	errs := validation.Errors{}
+	if fromErr != nil {
+		errs["from"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	}
+	if toErr != nil {
+		errs["to"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	}
+	if len(errs) == 0 {
+		switch {
+		case toDate.Before(fromDate):
+			errs["to"] = "Дата окончания раньше даты начала"
+		case int(toDate.Sub(fromDate).Hours()/24)+1 > MaxRangeDays:
+			errs["to"] = fmt.Sprintf("Период не может быть длиннее %d дней", MaxRangeDays)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
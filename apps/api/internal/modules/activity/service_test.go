@@ -0,0 +1,143 @@
+package activity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserID     int64 = 5
+	testActivityID       = "6c1d2e3f-4a5b-4c6d-8e7f-9a0b1c2d3e4f"
+)
+
+var testNow = time.Date(2026, 10, 16, 18, 0, 0, 0, time.UTC)
+
+var activityColumnNames = []string{"id", "user_id", "date", "type", "duration_min", "calories_burned", "calories_computed", "created_at"}
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestService_CreateActivity(t *testing.T) {
+	t.Run("explicit calories", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("INSERT INTO activities").
+			WithArgs(testUserID, "2026-10-16", "climbing", 60, 550.0, false).
+			WillReturnRows(sqlmock.NewRows(activityColumnNames).
+				AddRow(testActivityID, testUserID, "2026-10-16", "climbing", 60, 550.0, false, testNow))
+
+		activity, err := service.CreateActivity(context.Background(), testUserID, &CreateActivityRequest{
+			Date: "2026-10-16", Type: " Climbing ", DurationMin: 60, CaloriesBurned: floatPtr(550),
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 550.0, activity.CaloriesBurned)
+		assert.False(t, activity.CaloriesComputed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("calories from the MET table", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT weight FROM daily_metrics").
+			WithArgs(testUserID, "2026-10-16").
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(70.0))
+		mock.ExpectQuery("INSERT INTO activities").
+			WithArgs(testUserID, "2026-10-16", "running", 30, 343.0, true).
+			WillReturnRows(sqlmock.NewRows(activityColumnNames).
+				AddRow(testActivityID, testUserID, "2026-10-16", "running", 30, 343.0, true, testNow))
+
+		activity, err := service.CreateActivity(context.Background(), testUserID, &CreateActivityRequest{
+			Date: "2026-10-16", Type: "running", DurationMin: 30,
+		})
+
+		require.NoError(t, err)
+		assert.True(t, activity.CaloriesComputed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown type without calories", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		_, err := service.CreateActivity(context.Background(), testUserID, &CreateActivityRequest{
+			Date: "2026-10-16", Type: "climbing", DurationMin: 60,
+		})
+
+		assert.ErrorIs(t, err, ErrCaloriesRequired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no weight logged", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT weight FROM daily_metrics").WillReturnRows(sqlmock.NewRows([]string{"weight"}))
+
+		_, err := service.CreateActivity(context.Background(), testUserID, &CreateActivityRequest{
+			Date: "2026-10-16", Type: "walking", DurationMin: 40,
+		})
+
+		assert.ErrorIs(t, err, ErrWeightUnknown)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		_, err := service.CreateActivity(context.Background(), testUserID, &CreateActivityRequest{
+			Date: "16.10.2026", Type: "walking", DurationMin: 40,
+		})
+
+		var fieldErrs validation.Errors
+		require.ErrorAs(t, err, &fieldErrs)
+		assert.Contains(t, fieldErrs, "date")
+	})
+}
+
+func TestService_ListActivities(t *testing.T) {
+	t.Run("range", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM activities\\s+WHERE user_id = \\$1 AND date BETWEEN \\$2 AND \\$3").
+			WithArgs(testUserID, "2026-10-01", "2026-10-16").
+			WillReturnRows(sqlmock.NewRows(activityColumnNames).
+				AddRow(testActivityID, testUserID, "2026-10-02", "yoga", 60, 137.5, true, testNow))
+
+		activities, err := service.ListActivities(context.Background(), testUserID, "2026-10-01", "2026-10-16")
+
+		require.NoError(t, err)
+		require.Len(t, activities, 1)
+		assert.Equal(t, "yoga", activities[0].Type)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid ranges", func(t *testing.T) {
+		service, _ := setupTestService(t)
+		tests := []struct {
+			from, to string
+			field    string
+		}{
+			{"", "2026-10-16", "from"},
+			{"2026-10-16", "2026-10-01", "to"},
+			{"2025-01-01", "2026-10-16", "to"},
+		}
+		for _, tt := range tests {
+			_, err := service.ListActivities(context.Background(), testUserID, tt.from, tt.to)
+
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Contains(t, fieldErrs, tt.field)
+		}
+	})
+}
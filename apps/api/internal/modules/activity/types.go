@@ -0,0 +1,40 @@
+package activity
+
+import (
+	"errors"
+	"time"
+)
+
+// MaxRangeDays caps the from..to range of an activity listing
+const MaxRangeDays = 366
+
+var (
+	// ErrCaloriesRequired is returned when calories_burned is omitted for
+	// an activity type missing from the MET table
+	ErrCaloriesRequired = errors.New("calories burned are required for this activity type")
+	// ErrWeightUnknown is returned when calories_burned is omitted and the
+	// user has never logged their weight, so the MET table cannot be applied
+	ErrWeightUnknown = errors.New("calories burned are required without a logged weight")
+)
+
+// CreateActivityRequest represents an activity to log. CaloriesBurned may
+// be omitted for the types in the MET table.
+type CreateActivityRequest struct {
+	Date           string   `json:"date" binding:"required"`
+	Type           string   `json:"type" binding:"required,max=50"`
+	DurationMin    int      `json:"duration_min" binding:"required,min=1,max=1440"`
+	CaloriesBurned *float64 `json:"calories_burned" binding:"omitempty,min=0,max=20000"`
+}
+
+// Activity is a logged workout or other activity. CaloriesComputed means
+// the calories were estimated from the MET table and the user's weight.
+type Activity struct {
+	ID               string    `json:"id"`
+	UserID           int64     `json:"user_id"`
+	Date             string    `json:"date"`
+	Type             string    `json:"type"`
+	DurationMin      int       `json:"duration_min"`
+	CaloriesBurned   float64   `json:"calories_burned"`
+	CaloriesComputed bool      `json:"calories_computed"`
+	CreatedAt        time.Time `json:"created_at"`
+}
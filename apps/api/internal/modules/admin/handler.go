@@ -1,35 +1,80 @@
 package admin
 
 import (
+	"errors"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/cache"
 	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/securityevents"
 	"github.com/gin-gonic/gin"
 )
 
+// EmailPreviewer renders email templates with sample data
+type EmailPreviewer interface {
+	Preview(name string) (html, text string, err error)
+}
+
+// SecurityEventLog keeps the recent security events and their counts
+type SecurityEventLog interface {
+	Recent() []logger.SecurityEvent
+	Counts() []securityevents.Count
+}
+
 // Handler handles admin panel requests
 type Handler struct {
 	cfg     *config.Config
 	log     *logger.Logger
 	service ServiceInterface
+	stats   *StatsService
+	emails  EmailPreviewer
+	events  SecurityEventLog
 }
 
-// NewHandler creates a new admin handler
-func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB) *Handler {
+// NewHandler creates a new admin handler. Dashboard stats are cached in
+// statsCache, which may be nil; security events are read from events.
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, emails EmailPreviewer, statsCache cache.Cache, events SecurityEventLog) *Handler {
 	return &Handler{
 		cfg:     cfg,
 		log:     log,
 		service: NewService(db, log),
+		stats:   NewStatsService(db, log, statsCache),
+		emails:  emails,
+		events:  events,
 	}
 }
 
-// GetUsers handles GET /api/v1/admin/users
+// GetUsers handles GET /api/v1/admin/users?search=&role=&limit=&offset=
 func (h *Handler) GetUsers(c *gin.Context) {
-	users, err := h.service.GetUsers(c.Request.Context())
+	filter := UserFilter{
+		Search: strings.TrimSpace(c.Query("search")),
+		Role:   c.Query("role"),
+		Limit:  DefaultPageSize,
+	}
+	if filter.Role != "" && !slices.Contains(roles, filter.Role) {
+		response.Error(c, http.StatusBadRequest, "Неверная роль: допустимы client, coordinator и super_admin")
+		return
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit > 0 {
+			filter.Limit = min(limit, MaxPageSize)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err := strconv.Atoi(v); err == nil && offset >= 0 {
+			filter.Offset = offset
+		}
+	}
+
+	users, err := h.service.GetUsers(c.Request.Context(), filter)
 	if err != nil {
 		h.log.Error("Failed to get users", "error", err)
 		response.InternalError(c, "Не удалось загрузить пользователей")
@@ -51,8 +96,19 @@ func (h *Handler) GetCurators(c *gin.Context) {
 	response.Success(c, http.StatusOK, curators)
 }
 
-// ChangeRole handles POST /api/v1/admin/users/:id/role
+// ChangeRole handles PUT (and the older POST) /api/v1/admin/users/:id/role
 func (h *Handler) ChangeRole(c *gin.Context) {
+	actorID, ok := c.Get("user_id")
+	if !ok {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	adminID, ok := actorID.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
 	userIDStr := c.Param("id")
 	userID, err := strconv.ParseInt(userIDStr, 10, 64)
 	if err != nil {
@@ -62,24 +118,22 @@ func (h *Handler) ChangeRole(c *gin.Context) {
 
 	var req ChangeRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные: роль должна быть coordinator или client")
+		response.Error(c, http.StatusBadRequest, "Неверные данные: роль должна быть client, coordinator или super_admin")
 		return
 	}
 
-	if err := h.service.ChangeRole(c.Request.Context(), userID, req.Role); err != nil {
+	if err := h.service.ChangeRole(c.Request.Context(), adminID, userID, req.Role); err != nil {
 		h.log.Error("Failed to change role", "error", err, "user_id", userID, "new_role", req.Role)
 
-		switch err.Error() {
-		case "user not found":
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
 			response.NotFound(c, "Пользователь не найден")
-		case "cannot change super_admin role":
-			response.Forbidden(c, "Нельзя изменить роль супер-администратора")
+		case errors.Is(err, ErrLastSuperAdmin):
+			response.Error(c, http.StatusConflict, "Нельзя понизить последнего супер-администратора")
+		case errors.Is(err, ErrNoRemainingCurators):
+			response.Error(c, http.StatusConflict, "Нельзя понизить куратора: некому передать его клиентов")
 		default:
-			if len(err.Error()) > 15 && err.Error()[:15] == "cannot demote: " {
-				response.Error(c, http.StatusConflict, err.Error())
-			} else {
-				response.InternalError(c, "Не удалось изменить роль")
-			}
+			response.InternalError(c, "Не удалось изменить роль")
 		}
 		return
 	}
@@ -148,3 +202,47 @@ func (h *Handler) GetConversationMessages(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, messages)
 }
+
+// GetStats handles GET /api/v1/admin/stats. It answers 200 even when some
+// metrics failed; those carry an error instead of a value.
+func (h *Handler) GetStats(c *gin.Context) {
+	response.Success(c, http.StatusOK, h.stats.GetStats(c.Request.Context()))
+}
+
+// GetSecurityEvents handles GET /api/v1/admin/security-events. It returns
+// the latest security events, newest first, and the counts per event and
+// severity since startup, for triage without log access.
+func (h *Handler) GetSecurityEvents(c *gin.Context) {
+	response.Success(c, http.StatusOK, gin.H{
+		"events": h.events.Recent(),
+		"counts": h.events.Counts(),
+	})
+}
+
+// PreviewEmail handles GET /api/v1/admin/email-preview/:template?format=html|text.
+// It renders the template, including any override from EMAIL_TEMPLATE_DIR,
+// with sample data and returns the page itself rather than JSON.
+func (h *Handler) PreviewEmail(c *gin.Context) {
+	format := c.DefaultQuery("format", "html")
+	if format != "html" && format != "text" {
+		response.Error(c, http.StatusBadRequest, "Неверный формат: допустимы html и text")
+		return
+	}
+
+	html, text, err := h.emails.Preview(c.Param("template"))
+	if err != nil {
+		if errors.Is(err, email.ErrUnknownTemplate) {
+			response.NotFound(c, "Шаблон письма не найден")
+			return
+		}
+		h.log.Error("Failed to render email preview", "error", err, "template", c.Param("template"))
+		response.InternalError(c, "Не удалось отрисовать шаблон письма")
+		return
+	}
+
+	if format == "text" {
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+		return
+	}
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
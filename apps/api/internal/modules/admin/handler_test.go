@@ -10,7 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/email"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/securityevents"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -18,24 +21,24 @@ import (
 
 // mockService implements ServiceInterface for handler tests
 type mockService struct {
-	getUsersFunc            func(ctx context.Context) ([]AdminUser, error)
+	getUsersFunc            func(ctx context.Context, filter UserFilter) (*UserListResponse, error)
 	getCuratorsFunc         func(ctx context.Context) ([]CuratorLoad, error)
-	changeRoleFunc          func(ctx context.Context, userID int64, newRole string) error
+	changeRoleFunc          func(ctx context.Context, actorID, userID int64, newRole string) error
 	assignCuratorFunc       func(ctx context.Context, clientID, curatorID int64) error
 	getConversationsFunc    func(ctx context.Context) ([]AdminConversation, error)
 	getConversationMsgsFunc func(ctx context.Context, conversationID string, cursor string, limit int) ([]AdminMessage, error)
 }
 
-func (m *mockService) GetUsers(ctx context.Context) ([]AdminUser, error) {
-	return m.getUsersFunc(ctx)
+func (m *mockService) GetUsers(ctx context.Context, filter UserFilter) (*UserListResponse, error) {
+	return m.getUsersFunc(ctx, filter)
 }
 
 func (m *mockService) GetCurators(ctx context.Context) ([]CuratorLoad, error) {
 	return m.getCuratorsFunc(ctx)
 }
 
-func (m *mockService) ChangeRole(ctx context.Context, userID int64, newRole string) error {
-	return m.changeRoleFunc(ctx, userID, newRole)
+func (m *mockService) ChangeRole(ctx context.Context, actorID, userID int64, newRole string) error {
+	return m.changeRoleFunc(ctx, actorID, userID, newRole)
 }
 
 func (m *mockService) AssignCurator(ctx context.Context, clientID, curatorID int64) error {
@@ -62,15 +65,19 @@ func setupTestHandler(t *testing.T) (*Handler, *mockService) {
 }
 
 func TestHandlerGetUsers(t *testing.T) {
-	t.Run("returns user list", func(t *testing.T) {
+	t.Run("returns user page", func(t *testing.T) {
 		handler, mock := setupTestHandler(t)
 
 		now := time.Now()
 		curatorName := "Curator"
-		mock.getUsersFunc = func(ctx context.Context) ([]AdminUser, error) {
-			return []AdminUser{
-				{ID: 1, Email: "user@example.com", Name: "User", Role: "client", CuratorName: &curatorName, CreatedAt: now},
-				{ID: 2, Email: "curator@example.com", Name: "Curator", Role: "coordinator", ClientCount: 3, CreatedAt: now},
+		mock.getUsersFunc = func(ctx context.Context, filter UserFilter) (*UserListResponse, error) {
+			assert.Equal(t, UserFilter{Limit: DefaultPageSize}, filter)
+			return &UserListResponse{
+				Users: []AdminUser{
+					{ID: 1, Email: "user@example.com", Name: "User", Role: "client", CuratorName: &curatorName, CreatedAt: now},
+					{ID: 2, Email: "curator@example.com", Name: "Curator", Role: "coordinator", ClientCount: 3, CreatedAt: now},
+				},
+				Total: 2,
 			}, nil
 		}
 
@@ -87,14 +94,46 @@ func TestHandlerGetUsers(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, "success", resp["status"])
 
-		data := resp["data"].([]interface{})
-		assert.Len(t, data, 2)
+		data := resp["data"].(map[string]interface{})
+		assert.Len(t, data["users"], 2)
+		assert.Equal(t, float64(2), data["total"])
+	})
+
+	t.Run("passes search, role and page", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		var got UserFilter
+		mock.getUsersFunc = func(ctx context.Context, filter UserFilter) (*UserListResponse, error) {
+			got = filter
+			return &UserListResponse{Users: []AdminUser{}}, nil
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/users?search=+Anna+&role=coordinator&limit=1000&offset=20", nil)
+
+		handler.GetUsers(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, UserFilter{Search: "Anna", Role: "coordinator", Limit: MaxPageSize, Offset: 20}, got)
+	})
+
+	t.Run("rejects unknown role", func(t *testing.T) {
+		handler, _ := setupTestHandler(t)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/users?role=admin", nil)
+
+		handler.GetUsers(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
 	t.Run("returns 500 on service error", func(t *testing.T) {
 		handler, mock := setupTestHandler(t)
 
-		mock.getUsersFunc = func(ctx context.Context) ([]AdminUser, error) {
+		mock.getUsersFunc = func(ctx context.Context, filter UserFilter) (*UserListResponse, error) {
 			return nil, fmt.Errorf("db error")
 		}
 
@@ -150,23 +189,29 @@ func TestHandlerGetCurators(t *testing.T) {
 }
 
 func TestHandlerChangeRole(t *testing.T) {
+	changeRole := func(handler *Handler, userID, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPut, "/admin/users/"+userID+"/role", strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{{Key: "id", Value: userID}}
+		c.Set("user_id", int64(42))
+
+		handler.ChangeRole(c)
+		return w
+	}
+
 	t.Run("successful role change", func(t *testing.T) {
 		handler, mock := setupTestHandler(t)
 
-		mock.changeRoleFunc = func(ctx context.Context, userID int64, newRole string) error {
+		mock.changeRoleFunc = func(ctx context.Context, actorID, userID int64, newRole string) error {
+			assert.Equal(t, int64(42), actorID)
 			assert.Equal(t, int64(1), userID)
-			assert.Equal(t, "coordinator", newRole)
+			assert.Equal(t, "super_admin", newRole)
 			return nil
 		}
 
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/1/role",
-			strings.NewReader(`{"role":"coordinator"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-		c.Params = gin.Params{{Key: "id", Value: "1"}}
-
-		handler.ChangeRole(c)
+		w := changeRole(handler, "1", `{"role":"super_admin"}`)
 
 		assert.Equal(t, http.StatusOK, w.Code)
 
@@ -179,14 +224,7 @@ func TestHandlerChangeRole(t *testing.T) {
 	t.Run("invalid user ID", func(t *testing.T) {
 		handler, _ := setupTestHandler(t)
 
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/abc/role",
-			strings.NewReader(`{"role":"coordinator"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-		c.Params = gin.Params{{Key: "id", Value: "abc"}}
-
-		handler.ChangeRole(c)
+		w := changeRole(handler, "abc", `{"role":"coordinator"}`)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
@@ -194,74 +232,46 @@ func TestHandlerChangeRole(t *testing.T) {
 	t.Run("invalid role", func(t *testing.T) {
 		handler, _ := setupTestHandler(t)
 
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/1/role",
-			strings.NewReader(`{"role":"admin"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-		c.Params = gin.Params{{Key: "id", Value: "1"}}
-
-		handler.ChangeRole(c)
+		w := changeRole(handler, "1", `{"role":"admin"}`)
 
 		assert.Equal(t, http.StatusBadRequest, w.Code)
 	})
 
-	t.Run("user not found", func(t *testing.T) {
-		handler, mock := setupTestHandler(t)
-
-		mock.changeRoleFunc = func(ctx context.Context, userID int64, newRole string) error {
-			return fmt.Errorf("user not found")
-		}
-
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/999/role",
-			strings.NewReader(`{"role":"coordinator"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-		c.Params = gin.Params{{Key: "id", Value: "999"}}
-
-		handler.ChangeRole(c)
-
-		assert.Equal(t, http.StatusNotFound, w.Code)
-	})
-
-	t.Run("cannot change super_admin", func(t *testing.T) {
-		handler, mock := setupTestHandler(t)
-
-		mock.changeRoleFunc = func(ctx context.Context, userID int64, newRole string) error {
-			return fmt.Errorf("cannot change super_admin role")
-		}
+	t.Run("unauthenticated", func(t *testing.T) {
+		handler, _ := setupTestHandler(t)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/1/role",
-			strings.NewReader(`{"role":"client"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
+		c.Request = httptest.NewRequest(http.MethodPut, "/admin/users/1/role", strings.NewReader(`{"role":"client"}`))
 		c.Params = gin.Params{{Key: "id", Value: "1"}}
 
 		handler.ChangeRole(c)
 
-		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 
-	t.Run("cannot demote conflict", func(t *testing.T) {
-		handler, mock := setupTestHandler(t)
-
-		mock.changeRoleFunc = func(ctx context.Context, userID int64, newRole string) error {
-			return fmt.Errorf("cannot demote: no remaining curators to reassign 3 clients")
-		}
-
-		w := httptest.NewRecorder()
-		c, _ := gin.CreateTestContext(w)
-		c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/1/role",
-			strings.NewReader(`{"role":"client"}`))
-		c.Request.Header.Set("Content-Type", "application/json")
-		c.Params = gin.Params{{Key: "id", Value: "1"}}
+	errorCases := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"user not found", fmt.Errorf("ChangeRole: %w", apperrors.ErrNotFound), http.StatusNotFound},
+		{"last super_admin", ErrLastSuperAdmin, http.StatusConflict},
+		{"no remaining curators", fmt.Errorf("%w: 3 clients need reassignment", ErrNoRemainingCurators), http.StatusConflict},
+		{"unexpected error", fmt.Errorf("db error"), http.StatusInternalServerError},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mock := setupTestHandler(t)
+			mock.changeRoleFunc = func(ctx context.Context, actorID, userID int64, newRole string) error {
+				return tc.err
+			}
 
-		handler.ChangeRole(c)
+			w := changeRole(handler, "1", `{"role":"client"}`)
 
-		assert.Equal(t, http.StatusConflict, w.Code)
-	})
+			assert.Equal(t, tc.want, w.Code)
+		})
+	}
 }
 
 func TestHandlerAssignCurator(t *testing.T) {
@@ -488,3 +498,72 @@ func TestHandlerGetConversationMessages(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func TestHandlerPreviewEmail(t *testing.T) {
+	emails, err := email.NewServiceWithSender(email.NewMemorySender(), logger.Nop())
+	require.NoError(t, err)
+
+	preview := func(template, query string) *httptest.ResponseRecorder {
+		handler, _ := setupTestHandler(t)
+		handler.emails = emails
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/admin/email-preview/"+template+query, nil)
+		c.Params = gin.Params{{Key: "template", Value: template}}
+
+		handler.PreviewEmail(c)
+		return w
+	}
+
+	t.Run("renders HTML with sample data", func(t *testing.T) {
+		w := preview("password_reset", "")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "<!DOCTYPE html>")
+		assert.Contains(t, w.Body.String(), "https://burcev.team/reset-password?token=sample")
+	})
+
+	t.Run("renders the text version", func(t *testing.T) {
+		w := preview("weekly_summary", "?format=text")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/plain; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), "Ваша неделя в цифрах")
+		assert.NotContains(t, w.Body.String(), "<")
+	})
+
+	t.Run("returns 404 for unknown template", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, preview("unknown", "").Code)
+	})
+
+	t.Run("returns 400 for unknown format", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, preview("password_reset", "?format=pdf").Code)
+	})
+}
+
+func TestHandlerGetSecurityEvents(t *testing.T) {
+	handler, _ := setupTestHandler(t)
+	registry := securityevents.NewRegistry(10)
+	handler.events = registry
+	registry.RecordSecurityEvent(logger.SecurityEvent{Event: "login_lockout", Severity: "high"})
+	registry.RecordSecurityEvent(logger.SecurityEvent{Event: "password_reset_invalid_token", Severity: "medium"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/security-events", nil)
+	handler.GetSecurityEvents(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data struct {
+			Events []logger.SecurityEvent `json:"events"`
+			Counts []securityevents.Count `json:"counts"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Data.Events, 2)
+	assert.Equal(t, "password_reset_invalid_token", body.Data.Events[0].Event, "newest first")
+	assert.Len(t, body.Data.Counts, 2)
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/burcev/api/internal/modules/audit"
 	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
@@ -13,9 +15,9 @@ import (
 
 // ServiceInterface defines the contract for the admin service
 type ServiceInterface interface {
-	GetUsers(ctx context.Context) ([]AdminUser, error)
+	GetUsers(ctx context.Context, filter UserFilter) (*UserListResponse, error)
 	GetCurators(ctx context.Context) ([]CuratorLoad, error)
-	ChangeRole(ctx context.Context, userID int64, newRole string) error
+	ChangeRole(ctx context.Context, actorID, userID int64, newRole string) error
 	AssignCurator(ctx context.Context, clientID, curatorID int64) error
 	GetConversations(ctx context.Context) ([]AdminConversation, error)
 	GetConversationMessages(ctx context.Context, conversationID string, cursor string, limit int) ([]AdminMessage, error)
@@ -23,23 +25,48 @@ type ServiceInterface interface {
 
 // Service handles admin business logic
 type Service struct {
-	db  *database.DB
-	log *logger.Logger
+	db    *database.DB
+	log   *logger.Logger
+	audit audit.ServiceInterface
 }
 
 // NewService creates a new admin service
 func NewService(db *database.DB, log *logger.Logger) *Service {
 	return &Service{
-		db:  db,
-		log: log,
+		db:    db,
+		log:   log,
+		audit: audit.NewService(db.DB, log),
 	}
 }
 
-// GetUsers returns all users with role, curator assignment, and client count
-func (s *Service) GetUsers(ctx context.Context) ([]AdminUser, error) {
+// GetUsers returns a page of users matching the filter, newest first, with
+// role, curator assignment and client count
+func (s *Service) GetUsers(ctx context.Context, filter UserFilter) (*UserListResponse, error) {
 	startTime := time.Now()
 
-	query := `
+	var conditions []string
+	var args []interface{}
+	if filter.Search != "" {
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(filter.Search))+"%")
+		conditions = append(conditions, fmt.Sprintf("LOWER(u.email) LIKE $%d", len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("u.role = $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM users u ` + where
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		s.log.LogDatabaseQuery(countQuery, time.Since(startTime), err, nil)
+		return nil, fmt.Errorf("failed to count users: %w", err)
+	}
+
+	query := fmt.Sprintf(`
 		SELECT u.id, u.email, COALESCE(u.name, '') AS name, u.role,
 		       COALESCE(u.avatar_url, '') AS avatar_url,
 		       COALESCE(curator.name, '') AS curator_name,
@@ -63,17 +90,20 @@ func (s *Service) GetUsers(ctx context.Context) ([]AdminUser, error) {
 			FROM refresh_tokens
 			GROUP BY user_id
 		) last_tokens ON last_tokens.user_id = u.id
-		ORDER BY u.created_at DESC
-	`
+		%s
+		ORDER BY u.created_at DESC, u.id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
 
-	rows, err := s.db.QueryContext(ctx, query)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
 		return nil, fmt.Errorf("failed to query users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []AdminUser
+	users := []AdminUser{}
 	for rows.Next() {
 		var u AdminUser
 		var curatorName sql.NullString
@@ -107,16 +137,22 @@ func (s *Service) GetUsers(ctx context.Context) ([]AdminUser, error) {
 	}
 
 	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
-		"count": len(users),
+		"count":  len(users),
+		"role":   filter.Role,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
 	})
 
-	if users == nil {
-		users = []AdminUser{}
-	}
-
-	return users, nil
+	return &UserListResponse{
+		Users:   users,
+		Total:   total,
+		HasMore: filter.Offset+len(users) < total,
+	}, nil
 }
 
+// likeEscaper escapes LIKE wildcards in user-supplied search text
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // GetCurators returns all coordinators with their active client counts
 func (s *Service) GetCurators(ctx context.Context) ([]CuratorLoad, error) {
 	startTime := time.Now()
@@ -165,14 +201,23 @@ func (s *Service) GetCurators(ctx context.Context) ([]CuratorLoad, error) {
 	return curators, nil
 }
 
-// ChangeRole changes a user's role. When demoting a curator (coordinator -> client),
-// it reassigns all their active clients to the least-loaded remaining curator within a transaction.
-func (s *Service) ChangeRole(ctx context.Context, userID int64, newRole string) error {
+// ChangeRole changes a user's role on behalf of actorID and bumps the
+// user's token version so access tokens with the old role stop working.
+// Demoting the last super_admin fails with ErrLastSuperAdmin. A coordinator
+// leaving the role has their active clients reassigned to the least-loaded
+// remaining curator within the same transaction.
+func (s *Service) ChangeRole(ctx context.Context, actorID, userID int64, newRole string) error {
 	startTime := time.Now()
 
-	// Get current role
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the user so concurrent role changes to the same account serialize
 	var currentRole string
-	err := s.db.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1`, userID).Scan(&currentRole)
+	err = tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&currentRole)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("ChangeRole: %w", apperrors.ErrNotFound)
@@ -184,54 +229,82 @@ func (s *Service) ChangeRole(ctx context.Context, userID int64, newRole string)
 		return nil // no-op
 	}
 
-	// Cannot change super_admin role
-	if currentRole == "super_admin" {
-		return fmt.Errorf("cannot change super_admin role: %w", apperrors.ErrForbidden)
+	if currentRole == RoleSuperAdmin {
+		// Locking every admin row serializes concurrent demotions, so two
+		// admins demoting each other cannot leave none
+		var admins int
+		err = tx.QueryRowContext(ctx, `
+			SELECT COUNT(*) FROM (
+				SELECT id FROM users WHERE role = 'super_admin' AND deactivated_at IS NULL FOR UPDATE
+			) admins
+		`).Scan(&admins)
+		if err != nil {
+			return fmt.Errorf("failed to count super_admins: %w", err)
+		}
+		if admins <= 1 {
+			return ErrLastSuperAdmin
+		}
 	}
 
-	// If demoting coordinator -> client, handle client reassignment in a transaction
-	if currentRole == "coordinator" && newRole == "client" {
-		return s.demoteCurator(ctx, userID)
+	reassigned := 0
+	if currentRole == RoleCoordinator {
+		if reassigned, err = s.reassignClients(ctx, tx, userID); err != nil {
+			return err
+		}
 	}
 
-	// Simple role change (client -> coordinator)
-	_, err = s.db.ExecContext(ctx, `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2`, newRole, userID)
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users SET role = $1, token_version = token_version + 1, updated_at = NOW() WHERE id = $2
+	`, newRole, userID)
 	if err != nil {
 		return fmt.Errorf("failed to update role: %w", err)
 	}
 
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	s.log.LogBusinessEvent("role_changed", map[string]interface{}{
-		"user_id":  userID,
-		"old_role": currentRole,
-		"new_role": newRole,
-		"duration": time.Since(startTime).String(),
+		"user_id":            userID,
+		"changed_by":         actorID,
+		"old_role":           currentRole,
+		"new_role":           newRole,
+		"reassigned_clients": reassigned,
+		"duration":           time.Since(startTime).String(),
 	})
+	s.recordRoleChange(ctx, actorID, userID, currentRole, newRole)
 
 	return nil
 }
 
-// demoteCurator handles the complex case of demoting a coordinator to client:
+// recordRoleChange writes the role change to the audit log
+func (s *Service) recordRoleChange(ctx context.Context, actorID, userID int64, oldRole, newRole string) {
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &userID,
+		Action: audit.ActionRoleChanged,
+		Metadata: map[string]any{
+			"old_role":   oldRole,
+			"new_role":   newRole,
+			"changed_by": actorID,
+		},
+	})
+}
+
+// reassignClients moves the active clients of a coordinator who is leaving
+// the role to the least-loaded remaining curators and returns how many were
+// moved:
 // 1. Get all active clients of the curator
 // 2. Mark all their relationships as inactive
-// 3. Change role to client
-// 4. Reassign each orphaned client to the least-loaded remaining curator
-// 5. Create new conversations for reassigned clients
-func (s *Service) demoteCurator(ctx context.Context, curatorID int64) error {
-	startTime := time.Now()
-
-	tx, err := s.db.BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
+// 3. Reassign each orphaned client to the least-loaded remaining curator
+// 4. Create new conversations for reassigned clients
+func (s *Service) reassignClients(ctx context.Context, tx *sql.Tx, curatorID int64) (int, error) {
 	// 1. Get active clients of this curator
 	clientRows, err := tx.QueryContext(ctx, `
 		SELECT client_id FROM curator_client_relationships
 		WHERE curator_id = $1 AND status = 'active'
 	`, curatorID)
 	if err != nil {
-		return fmt.Errorf("failed to get curator clients: %w", err)
+		return 0, fmt.Errorf("failed to get curator clients: %w", err)
 	}
 
 	var orphanedClients []int64
@@ -239,14 +312,14 @@ func (s *Service) demoteCurator(ctx context.Context, curatorID int64) error {
 		var clientID int64
 		if err := clientRows.Scan(&clientID); err != nil {
 			clientRows.Close()
-			return fmt.Errorf("failed to scan client: %w", err)
+			return 0, fmt.Errorf("failed to scan client: %w", err)
 		}
 		orphanedClients = append(orphanedClients, clientID)
 	}
 	clientRows.Close()
 
 	if err := clientRows.Err(); err != nil {
-		return fmt.Errorf("error iterating clients: %w", err)
+		return 0, fmt.Errorf("error iterating clients: %w", err)
 	}
 
 	// 2. Deactivate all relationships for this curator
@@ -255,82 +328,65 @@ func (s *Service) demoteCurator(ctx context.Context, curatorID int64) error {
 		WHERE curator_id = $1 AND status = 'active'
 	`, curatorID)
 	if err != nil {
-		return fmt.Errorf("failed to deactivate relationships: %w", err)
+		return 0, fmt.Errorf("failed to deactivate relationships: %w", err)
 	}
 
-	// 3. Change role to client
-	_, err = tx.ExecContext(ctx, `
-		UPDATE users SET role = 'client', updated_at = NOW() WHERE id = $1
-	`, curatorID)
+	if len(orphanedClients) == 0 {
+		return 0, nil
+	}
+
+	// 3. Reassign orphaned clients, if any curator remains
+	var remainingCount int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM users WHERE role = 'coordinator' AND id != $1
+	`, curatorID).Scan(&remainingCount)
 	if err != nil {
-		return fmt.Errorf("failed to update role: %w", err)
+		return 0, fmt.Errorf("failed to count remaining curators: %w", err)
+	}
+
+	if remainingCount == 0 {
+		return 0, fmt.Errorf("%w: %d clients need reassignment", ErrNoRemainingCurators, len(orphanedClients))
 	}
 
-	// 4. Reassign orphaned clients
-	if len(orphanedClients) > 0 {
-		// Check if there are remaining curators
-		var remainingCount int
+	for _, clientID := range orphanedClients {
+		// Find least-loaded curator (excluding the one being demoted)
+		var newCuratorID int64
 		err = tx.QueryRowContext(ctx, `
-			SELECT COUNT(*) FROM users WHERE role = 'coordinator' AND id != $1
-		`, curatorID).Scan(&remainingCount)
+			SELECT u.id
+			FROM users u
+			LEFT JOIN curator_client_relationships ccr
+				ON ccr.curator_id = u.id AND ccr.status = 'active'
+			WHERE u.role = 'coordinator' AND u.id != $1
+			GROUP BY u.id
+			ORDER BY COUNT(ccr.client_id) ASC
+			LIMIT 1
+		`, curatorID).Scan(&newCuratorID)
 		if err != nil {
-			return fmt.Errorf("failed to count remaining curators: %w", err)
+			return 0, fmt.Errorf("failed to find curator for client %d: %w", clientID, err)
 		}
 
-		if remainingCount == 0 {
-			return fmt.Errorf("cannot demote: no remaining curators to reassign %d clients: %w", len(orphanedClients), apperrors.ErrForbidden)
+		// Create new relationship
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO curator_client_relationships (curator_id, client_id, status)
+			VALUES ($1, $2, 'active')
+			ON CONFLICT (curator_id, client_id) DO UPDATE SET status = 'active'
+		`, newCuratorID, clientID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create relationship for client %d: %w", clientID, err)
 		}
 
-		for _, clientID := range orphanedClients {
-			// Find least-loaded curator (excluding the one being demoted)
-			var newCuratorID int64
-			err = tx.QueryRowContext(ctx, `
-				SELECT u.id
-				FROM users u
-				LEFT JOIN curator_client_relationships ccr
-					ON ccr.curator_id = u.id AND ccr.status = 'active'
-				WHERE u.role = 'coordinator' AND u.id != $1
-				GROUP BY u.id
-				ORDER BY COUNT(ccr.client_id) ASC
-				LIMIT 1
-			`, curatorID).Scan(&newCuratorID)
-			if err != nil {
-				return fmt.Errorf("failed to find curator for client %d: %w", clientID, err)
-			}
-
-			// Create new relationship
-			_, err = tx.ExecContext(ctx, `
-				INSERT INTO curator_client_relationships (curator_id, client_id, status)
-				VALUES ($1, $2, 'active')
-				ON CONFLICT (curator_id, client_id) DO UPDATE SET status = 'active'
-			`, newCuratorID, clientID)
-			if err != nil {
-				return fmt.Errorf("failed to create relationship for client %d: %w", clientID, err)
-			}
-
-			// Create conversation for the new pair
-			_, err = tx.ExecContext(ctx, `
-				INSERT INTO conversations (client_id, curator_id)
-				VALUES ($1, $2)
-				ON CONFLICT (client_id, curator_id) DO NOTHING
-			`, clientID, newCuratorID)
-			if err != nil {
-				return fmt.Errorf("failed to create conversation for client %d: %w", clientID, err)
-			}
+		// 4. Create conversation for the new pair
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO conversations (client_id, curator_id)
+			VALUES ($1, $2)
+			ON CONFLICT (client_id, curator_id) DO NOTHING
+		`, clientID, newCuratorID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create conversation for client %d: %w", clientID, err)
 		}
 	}
 
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	s.log.LogBusinessEvent("curator_demoted", map[string]interface{}{
-		"curator_id":       curatorID,
-		"orphaned_clients": len(orphanedClients),
-		"duration":         time.Since(startTime).String(),
-	})
-
-	return nil
+	return len(orphanedClients), nil
 }
 
 // AssignCurator creates a new curator-client relationship and conversation
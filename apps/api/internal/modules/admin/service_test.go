@@ -28,7 +28,13 @@ func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, func()) {
 }
 
 func TestGetUsers(t *testing.T) {
-	t.Run("returns user list", func(t *testing.T) {
+	userColumns := []string{
+		"id", "email", "name", "role", "avatar_url",
+		"curator_name", "curator_id", "client_count",
+		"created_at", "last_login",
+	}
+
+	t.Run("returns user page", func(t *testing.T) {
 		service, mock, cleanup := setupTestService(t)
 		defer cleanup()
 		ctx := context.Background()
@@ -38,11 +44,7 @@ func TestGetUsers(t *testing.T) {
 		curatorName := "Curator One"
 		curatorID := int64(10)
 
-		rows := sqlmock.NewRows([]string{
-			"id", "email", "name", "role", "avatar_url",
-			"curator_name", "curator_id", "client_count",
-			"created_at", "last_login",
-		}).
+		rows := sqlmock.NewRows(userColumns).
 			AddRow(1, "user@example.com", "User One", "client", "",
 				curatorName, curatorID, 0,
 				now, lastLogin).
@@ -50,11 +52,18 @@ func TestGetUsers(t *testing.T) {
 				nil, nil, 5,
 				now, nil)
 
-		mock.ExpectQuery("SELECT u.id").WillReturnRows(rows)
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users u$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("SELECT u.id").
+			WithArgs(2, 0).
+			WillReturnRows(rows)
 
-		users, err := service.GetUsers(ctx)
-		assert.NoError(t, err)
+		page, err := service.GetUsers(ctx, UserFilter{Limit: 2})
+		require.NoError(t, err)
+		users := page.Users
 		require.Len(t, users, 2)
+		assert.Equal(t, 3, page.Total)
+		assert.True(t, page.HasMore)
 
 		assert.Equal(t, int64(1), users[0].ID)
 		assert.Equal(t, "user@example.com", users[0].Email)
@@ -72,25 +81,27 @@ func TestGetUsers(t *testing.T) {
 		assert.Nil(t, users[1].CuratorName)
 		assert.Nil(t, users[1].CuratorID)
 		assert.Nil(t, users[1].LastLoginAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("returns empty list when no users", func(t *testing.T) {
+	t.Run("filters by email and role", func(t *testing.T) {
 		service, mock, cleanup := setupTestService(t)
 		defer cleanup()
 		ctx := context.Background()
 
-		rows := sqlmock.NewRows([]string{
-			"id", "email", "name", "role", "avatar_url",
-			"curator_name", "curator_id", "client_count",
-			"created_at", "last_login",
-		})
-
-		mock.ExpectQuery("SELECT u.id").WillReturnRows(rows)
-
-		users, err := service.GetUsers(ctx)
-		assert.NoError(t, err)
-		assert.NotNil(t, users)
-		assert.Len(t, users, 0)
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users u WHERE LOWER\(u.email\) LIKE \$1 AND u.role = \$2`).
+			WithArgs(`%ann\_a%`, "coordinator").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`WHERE LOWER\(u.email\) LIKE \$1 AND u.role = \$2\s+ORDER BY .+ LIMIT \$3 OFFSET \$4`).
+			WithArgs(`%ann\_a%`, "coordinator", DefaultPageSize, 50).
+			WillReturnRows(sqlmock.NewRows(userColumns))
+
+		page, err := service.GetUsers(ctx, UserFilter{Search: "Ann_A", Role: "coordinator", Limit: DefaultPageSize, Offset: 50})
+		require.NoError(t, err)
+		assert.NotNil(t, page.Users)
+		assert.Empty(t, page.Users)
+		assert.False(t, page.HasMore)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("returns error on query failure", func(t *testing.T) {
@@ -98,11 +109,12 @@ func TestGetUsers(t *testing.T) {
 		defer cleanup()
 		ctx := context.Background()
 
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 		mock.ExpectQuery("SELECT u.id").WillReturnError(fmt.Errorf("db error"))
 
-		users, err := service.GetUsers(ctx)
+		page, err := service.GetUsers(ctx, UserFilter{Limit: DefaultPageSize})
 		assert.Error(t, err)
-		assert.Nil(t, users)
+		assert.Nil(t, page)
 		assert.Contains(t, err.Error(), "failed to query users")
 	})
 }
@@ -161,21 +173,32 @@ func TestGetCurators(t *testing.T) {
 }
 
 func TestChangeRole(t *testing.T) {
-	t.Run("promotes client to coordinator", func(t *testing.T) {
+	const actorID = int64(42)
+
+	expectRoleLookup := func(mock sqlmock.Sqlmock, userID int64, role string) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(`SELECT role FROM users WHERE id = \$1 FOR UPDATE`).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+	}
+
+	t.Run("promotes client to coordinator and bumps token version", func(t *testing.T) {
 		service, mock, cleanup := setupTestService(t)
 		defer cleanup()
 		ctx := context.Background()
 
-		mock.ExpectQuery("SELECT role FROM users WHERE id").
-			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("client"))
-
-		mock.ExpectExec("UPDATE users SET role").
+		expectRoleLookup(mock, 1, "client")
+		mock.ExpectExec(`UPDATE users SET role = \$1, token_version = token_version \+ 1`).
 			WithArgs("coordinator", int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(1), sqlmock.AnyArg(), "role_changed", []byte(`{"changed_by":42,"new_role":"coordinator","old_role":"client"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := service.ChangeRole(ctx, 1, "coordinator")
+		err := service.ChangeRole(ctx, actorID, 1, "coordinator")
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("no-op when role is the same", func(t *testing.T) {
@@ -183,26 +206,47 @@ func TestChangeRole(t *testing.T) {
 		defer cleanup()
 		ctx := context.Background()
 
-		mock.ExpectQuery("SELECT role FROM users WHERE id").
-			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("client"))
+		expectRoleLookup(mock, 1, "client")
+		mock.ExpectRollback()
 
-		err := service.ChangeRole(ctx, 1, "client")
+		err := service.ChangeRole(ctx, actorID, 1, "client")
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	t.Run("returns error for super_admin", func(t *testing.T) {
+	t.Run("demotes a super_admin while another remains", func(t *testing.T) {
 		service, mock, cleanup := setupTestService(t)
 		defer cleanup()
 		ctx := context.Background()
 
-		mock.ExpectQuery("SELECT role FROM users WHERE id").
-			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("super_admin"))
+		expectRoleLookup(mock, 7, "super_admin")
+		mock.ExpectQuery(`SELECT id FROM users WHERE role = 'super_admin' AND deactivated_at IS NULL FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectExec(`UPDATE users SET role = \$1, token_version = token_version \+ 1`).
+			WithArgs("client", int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := service.ChangeRole(ctx, 1, "client")
-		assert.Error(t, err)
-		assert.True(t, errors.Is(err, apperrors.ErrForbidden))
+		err := service.ChangeRole(ctx, actorID, 7, "client")
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses to demote the last super_admin", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		ctx := context.Background()
+
+		// The last admin demoting themselves
+		expectRoleLookup(mock, actorID, "super_admin")
+		mock.ExpectQuery(`SELECT id FROM users WHERE role = 'super_admin' AND deactivated_at IS NULL FOR UPDATE`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+
+		err := service.ChangeRole(ctx, actorID, actorID, "coordinator")
+		assert.ErrorIs(t, err, ErrLastSuperAdmin)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("returns error when user not found", func(t *testing.T) {
@@ -210,11 +254,13 @@ func TestChangeRole(t *testing.T) {
 		defer cleanup()
 		ctx := context.Background()
 
+		mock.ExpectBegin()
 		mock.ExpectQuery("SELECT role FROM users WHERE id").
 			WithArgs(int64(999)).
 			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
 
-		err := service.ChangeRole(ctx, 999, "coordinator")
+		err := service.ChangeRole(ctx, actorID, 999, "coordinator")
 		assert.Error(t, err)
 		assert.True(t, errors.Is(err, apperrors.ErrNotFound))
 	})
@@ -224,12 +270,7 @@ func TestChangeRole(t *testing.T) {
 		defer cleanup()
 		ctx := context.Background()
 
-		mock.ExpectQuery("SELECT role FROM users WHERE id").
-			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coordinator"))
-
-		// demoteCurator: begin tx
-		mock.ExpectBegin()
+		expectRoleLookup(mock, 1, "coordinator")
 
 		// Get active clients - none
 		mock.ExpectQuery("SELECT client_id FROM curator_client_relationships").
@@ -243,13 +284,18 @@ func TestChangeRole(t *testing.T) {
 
 		// Change role to client
 		mock.ExpectExec("UPDATE users SET role").
-			WithArgs(int64(1)).
+			WithArgs("client", int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
 		mock.ExpectCommit()
 
-		err := service.ChangeRole(ctx, 1, "client")
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(1), sqlmock.AnyArg(), "role_changed", []byte(`{"changed_by":42,"new_role":"client","old_role":"coordinator"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := service.ChangeRole(ctx, actorID, 1, "client")
 		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("demotes coordinator fails when no remaining curators", func(t *testing.T) {
@@ -257,11 +303,7 @@ func TestChangeRole(t *testing.T) {
 		defer cleanup()
 		ctx := context.Background()
 
-		mock.ExpectQuery("SELECT role FROM users WHERE id").
-			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coordinator"))
-
-		mock.ExpectBegin()
+		expectRoleLookup(mock, 1, "coordinator")
 
 		// Has active clients
 		mock.ExpectQuery("SELECT client_id FROM curator_client_relationships").
@@ -273,11 +315,6 @@ func TestChangeRole(t *testing.T) {
 			WithArgs(int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		// Change role
-		mock.ExpectExec("UPDATE users SET role").
-			WithArgs(int64(1)).
-			WillReturnResult(sqlmock.NewResult(0, 1))
-
 		// No remaining curators
 		mock.ExpectQuery("SELECT COUNT").
 			WithArgs(int64(1)).
@@ -285,9 +322,9 @@ func TestChangeRole(t *testing.T) {
 
 		mock.ExpectRollback()
 
-		err := service.ChangeRole(ctx, 1, "client")
-		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "cannot demote")
+		err := service.ChangeRole(ctx, actorID, 1, "client")
+		assert.ErrorIs(t, err, ErrNoRemainingCurators)
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
 
@@ -0,0 +1,265 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// StatsQueryTimeout bounds each stats aggregate, so one slow query
+	// only costs its own metric
+	StatsQueryTimeout = 5 * time.Second
+	// StatsCacheTTL is how long complete stats are served from the cache
+	StatsCacheTTL = 5 * time.Minute
+	// StatsWeeks is how many weeks of signups the stats cover, the current
+	// one included
+	StatsWeeks = 8
+)
+
+// Metric errors reported in place of a value
+const (
+	MetricErrorTimeout = "timeout"
+	MetricErrorFailed  = "failed"
+)
+
+// statsCacheKey is the cache key of the latest complete stats
+const statsCacheKey = "admin:stats"
+
+// Metric is one stats value, or the reason it is missing
+type Metric struct {
+	Value any    `json:"value"`
+	Error string `json:"error,omitempty"`
+}
+
+// WeekSignups is the number of users who signed up in an ISO week
+type WeekSignups struct {
+	Week  string `json:"week"`
+	Users int64  `json:"users"`
+}
+
+// ActiveUsers counts users who logged nutrition entries in the last day
+// and the last 7 days
+type ActiveUsers struct {
+	DAU int64 `json:"dau"`
+	WAU int64 `json:"wau"`
+}
+
+// OutboxBacklog counts emails waiting for delivery and emails that ran out
+// of attempts
+type OutboxBacklog struct {
+	Pending int64 `json:"pending"`
+	Failed  int64 `json:"failed"`
+}
+
+// Stats are aggregate usage metrics for the admin dashboard. A metric whose
+// query failed carries an error instead of a value.
+type Stats struct {
+	TotalUsers      Metric `json:"total_users"`
+	NewUsersPerWeek Metric `json:"new_users_per_week"`
+	ActiveUsers     Metric `json:"active_users"`
+	TotalEntries    Metric `json:"total_entries"`
+	// MedianEntriesPerActiveUser is over the users active in the last 7 days
+	MedianEntriesPerActiveUser Metric    `json:"median_entries_per_active_user"`
+	EmailOutboxBacklog         Metric    `json:"email_outbox_backlog"`
+	GeneratedAt                time.Time `json:"generated_at"`
+}
+
+// StatsService computes the admin dashboard metrics
+type StatsService struct {
+	db      *database.DB
+	log     *logger.Logger
+	cache   cache.Cache
+	timeout time.Duration
+	now     func() time.Time
+}
+
+// NewStatsService creates a new stats service. Complete stats are kept in
+// statsCache, which may be nil, for StatsCacheTTL.
+func NewStatsService(db *database.DB, log *logger.Logger, statsCache cache.Cache) *StatsService {
+	return &StatsService{
+		db:      db,
+		log:     log,
+		cache:   statsCache,
+		timeout: StatsQueryTimeout,
+		now:     time.Now,
+	}
+}
+
+// statsMetric is one aggregate of the stats
+type statsMetric struct {
+	name  string
+	dst   *Metric
+	query func(ctx context.Context) (any, error)
+}
+
+// GetStats returns the usage metrics. The aggregates run concurrently,
+// each within StatsQueryTimeout; failed ones are reported per metric and
+// keep the stats out of the cache.
+func (s *StatsService) GetStats(ctx context.Context) *Stats {
+	var cached Stats
+	ok, err := cache.GetJSON(ctx, s.cache, statsCacheKey, &cached)
+	if err != nil {
+		s.log.Warn("Failed to read cached admin stats", "error", err)
+	}
+	if ok {
+		return &cached
+	}
+
+	stats := &Stats{GeneratedAt: s.now().UTC()}
+	metrics := []statsMetric{
+		{"total_users", &stats.TotalUsers, s.totalUsers},
+		{"new_users_per_week", &stats.NewUsersPerWeek, s.newUsersPerWeek},
+		{"active_users", &stats.ActiveUsers, s.activeUsers},
+		{"total_entries", &stats.TotalEntries, s.totalEntries},
+		{"median_entries_per_active_user", &stats.MedianEntriesPerActiveUser, s.medianEntriesPerActiveUser},
+		{"email_outbox_backlog", &stats.EmailOutboxBacklog, s.emailOutboxBacklog},
+	}
+
+	var wg sync.WaitGroup
+	for _, m := range metrics {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			*m.dst = s.runMetric(ctx, m)
+		}()
+	}
+	wg.Wait()
+
+	for _, m := range metrics {
+		if m.dst.Error != "" {
+			return stats
+		}
+	}
+	if err := cache.SetJSON(ctx, s.cache, statsCacheKey, stats, StatsCacheTTL); err != nil {
+		s.log.Warn("Failed to cache admin stats", "error", err)
+	}
+	return stats
+}
+
+// runMetric runs one aggregate within the timeout. Errors are logged; the
+// client only learns whether the query timed out.
+func (s *StatsService) runMetric(ctx context.Context, m statsMetric) Metric {
+	queryCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	value, err := m.query(queryCtx)
+	if err == nil {
+		return Metric{Value: value}
+	}
+	if errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		s.log.Warn("Admin stats metric timed out", "metric", m.name, "timeout", s.timeout)
+		return Metric{Error: MetricErrorTimeout}
+	}
+	s.log.Error("Failed to compute admin stats metric", "metric", m.name, "error", err)
+	return Metric{Error: MetricErrorFailed}
+}
+
+// count runs a single-value COUNT query
+func (s *StatsService) count(ctx context.Context, query string) (int64, error) {
+	startTime := time.Now()
+	var n int64
+	err := s.db.QueryRowContext(ctx, query).Scan(&n)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	return n, err
+}
+
+func (s *StatsService) totalUsers(ctx context.Context) (any, error) {
+	return s.count(ctx, `SELECT COUNT(*) FROM users`)
+}
+
+func (s *StatsService) totalEntries(ctx context.Context) (any, error) {
+	return s.count(ctx, `SELECT COUNT(*) FROM nutrition_entries`)
+}
+
+// newUsersPerWeek returns the signups of the last StatsWeeks ISO weeks,
+// oldest first, weeks without signups included
+func (s *StatsService) newUsersPerWeek(ctx context.Context) (any, error) {
+	query := `
+		SELECT to_char(w.week, 'IYYY-"W"IW'), COUNT(u.id)
+		FROM generate_series(date_trunc('week', NOW()) - make_interval(weeks => $1 - 1), date_trunc('week', NOW()), '1 week') AS w(week)
+		LEFT JOIN users u ON u.created_at >= w.week AND u.created_at < w.week + INTERVAL '1 week'
+		GROUP BY w.week
+		ORDER BY w.week
+	`
+	startTime := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, StatsWeeks)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	weeks := make([]WeekSignups, 0, StatsWeeks)
+	for rows.Next() {
+		var w WeekSignups
+		if err := rows.Scan(&w.Week, &w.Users); err != nil {
+			return nil, fmt.Errorf("failed to scan week: %w", err)
+		}
+		weeks = append(weeks, w)
+	}
+	return weeks, rows.Err()
+}
+
+// activeUsers counts the users who logged entries in the last day and week
+func (s *StatsService) activeUsers(ctx context.Context) (any, error) {
+	query := `
+		SELECT COUNT(DISTINCT user_id) FILTER (WHERE created_at >= NOW() - INTERVAL '1 day'),
+		       COUNT(DISTINCT user_id)
+		FROM nutrition_entries
+		WHERE created_at >= NOW() - INTERVAL '7 days'
+	`
+	startTime := time.Now()
+	var active ActiveUsers
+	err := s.db.QueryRowContext(ctx, query).Scan(&active.DAU, &active.WAU)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return active, nil
+}
+
+// medianEntriesPerActiveUser is the median number of entries logged in the
+// last 7 days by the users who logged any; 0 without active users
+func (s *StatsService) medianEntriesPerActiveUser(ctx context.Context) (any, error) {
+	query := `
+		SELECT COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY entries), 0)
+		FROM (
+			SELECT COUNT(*) AS entries
+			FROM nutrition_entries
+			WHERE created_at >= NOW() - INTERVAL '7 days'
+			GROUP BY user_id
+		) active
+	`
+	startTime := time.Now()
+	var median float64
+	err := s.db.QueryRowContext(ctx, query).Scan(&median)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return median, nil
+}
+
+// emailOutboxBacklog counts the undelivered emails of the outbox
+func (s *StatsService) emailOutboxBacklog(ctx context.Context) (any, error) {
+	query := `
+		SELECT COUNT(*) FILTER (WHERE status IN ('pending', 'processing')),
+		       COUNT(*) FILTER (WHERE status = 'failed')
+		FROM email_outbox
+	`
+	startTime := time.Now()
+	var backlog OutboxBacklog
+	err := s.db.QueryRowContext(ctx, query).Scan(&backlog.Pending, &backlog.Failed)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, err
+	}
+	return backlog, nil
+}
@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStatsService(t *testing.T, statsCache cache.Cache) (*StatsService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	// The metrics are queried concurrently
+	mock.MatchExpectationsInOrder(false)
+
+	service := NewStatsService(&database.DB{DB: db}, logger.New(), statsCache)
+	service.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	return service, mock
+}
+
+// statsQueries maps each metric to a pattern matching only its query
+var statsQueries = map[string]string{
+	"total_users":                    "SELECT COUNT\\(\\*\\) FROM users",
+	"new_users_per_week":             "generate_series",
+	"active_users":                   "COUNT\\(DISTINCT user_id\\)",
+	"total_entries":                  "SELECT COUNT\\(\\*\\) FROM nutrition_entries",
+	"median_entries_per_active_user": "percentile_cont",
+	"email_outbox_backlog":           "FROM email_outbox",
+}
+
+// expectStats expects every metric query, answering the ones in override
+// with their expectation instead of a result
+func expectStats(mock sqlmock.Sqlmock, override map[string]func(*sqlmock.ExpectedQuery)) {
+	results := map[string]*sqlmock.Rows{
+		"total_users": sqlmock.NewRows([]string{"count"}).AddRow(120),
+		"new_users_per_week": sqlmock.NewRows([]string{"week", "users"}).
+			AddRow("2026-W41", 7).AddRow("2026-W42", 3),
+		"active_users":                   sqlmock.NewRows([]string{"dau", "wau"}).AddRow(15, 40),
+		"total_entries":                  sqlmock.NewRows([]string{"count"}).AddRow(5230),
+		"median_entries_per_active_user": sqlmock.NewRows([]string{"median"}).AddRow(9.5),
+		"email_outbox_backlog":           sqlmock.NewRows([]string{"pending", "failed"}).AddRow(4, 1),
+	}
+	for name, pattern := range statsQueries {
+		expectation := mock.ExpectQuery(pattern)
+		if f, ok := override[name]; ok {
+			f(expectation)
+			continue
+		}
+		expectation.WillReturnRows(results[name])
+	}
+}
+
+func TestStatsService_GetStats(t *testing.T) {
+	t.Run("all metrics", func(t *testing.T) {
+		service, mock := setupStatsService(t, nil)
+		expectStats(mock, nil)
+
+		stats := service.GetStats(context.Background())
+
+		assert.Equal(t, Metric{Value: int64(120)}, stats.TotalUsers)
+		assert.Equal(t, Metric{Value: []WeekSignups{{"2026-W41", 7}, {"2026-W42", 3}}}, stats.NewUsersPerWeek)
+		assert.Equal(t, Metric{Value: ActiveUsers{DAU: 15, WAU: 40}}, stats.ActiveUsers)
+		assert.Equal(t, Metric{Value: int64(5230)}, stats.TotalEntries)
+		assert.Equal(t, Metric{Value: 9.5}, stats.MedianEntriesPerActiveUser)
+		assert.Equal(t, Metric{Value: OutboxBacklog{Pending: 4, Failed: 1}}, stats.EmailOutboxBacklog)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("partial failure", func(t *testing.T) {
+		statsCache := cache.NewMemory(10)
+		service, mock := setupStatsService(t, statsCache)
+		service.timeout = 50 * time.Millisecond
+		expectStats(mock, map[string]func(*sqlmock.ExpectedQuery){
+			"active_users": func(q *sqlmock.ExpectedQuery) {
+				q.WillReturnError(errors.New("relation does not exist"))
+			},
+			"median_entries_per_active_user": func(q *sqlmock.ExpectedQuery) {
+				q.WillDelayFor(time.Second).WillReturnRows(sqlmock.NewRows([]string{"median"}).AddRow(1))
+			},
+		})
+
+		stats := service.GetStats(context.Background())
+
+		assert.Equal(t, Metric{Error: MetricErrorFailed}, stats.ActiveUsers)
+		assert.Equal(t, Metric{Error: MetricErrorTimeout}, stats.MedianEntriesPerActiveUser)
+		assert.Equal(t, Metric{Value: int64(120)}, stats.TotalUsers)
+		assert.Equal(t, Metric{Value: OutboxBacklog{Pending: 4, Failed: 1}}, stats.EmailOutboxBacklog)
+		assert.Zero(t, statsCache.Len(), "partial stats are not cached")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("served from the cache", func(t *testing.T) {
+		statsCache := cache.NewMemory(10)
+		service, mock := setupStatsService(t, statsCache)
+		expectStats(mock, nil)
+
+		first := service.GetStats(context.Background())
+		require.NoError(t, mock.ExpectationsWereMet())
+		second := service.GetStats(context.Background())
+
+		// Cached stats come back decoded from JSON
+		assert.Equal(t, first.GeneratedAt, second.GeneratedAt)
+		assert.Equal(t, float64(120), second.TotalUsers.Value)
+		assert.Equal(t, float64(9.5), second.MedianEntriesPerActiveUser.Value)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestHandlerGetStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service, mock := setupStatsService(t, nil)
+	expectStats(mock, map[string]func(*sqlmock.ExpectedQuery){
+		"email_outbox_backlog": func(q *sqlmock.ExpectedQuery) {
+			q.WillReturnError(errors.New("connection reset"))
+		},
+	})
+	handler := &Handler{log: logger.New(), stats: service}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/admin/stats", nil)
+	handler.GetStats(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.JSONEq(t, `{"value":120}`, string(body.Data["total_users"]))
+	assert.JSONEq(t, `{"value":null,"error":"failed"}`, string(body.Data["email_outbox_backlog"]))
+	assert.NotContains(t, w.Body.String(), "connection reset")
+}
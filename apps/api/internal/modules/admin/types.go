@@ -1,6 +1,33 @@
 package admin
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// Roles an admin can assign
+const (
+	RoleClient      = "client"
+	RoleCoordinator = "coordinator"
+	RoleSuperAdmin  = "super_admin"
+)
+
+var roles = []string{RoleClient, RoleCoordinator, RoleSuperAdmin}
+
+const (
+	// DefaultPageSize is the page size of the admin user listing
+	DefaultPageSize = 50
+	// MaxPageSize caps the limit query parameter
+	MaxPageSize = 200
+)
+
+var (
+	// ErrLastSuperAdmin is returned when a role change would leave no super_admin
+	ErrLastSuperAdmin = errors.New("cannot demote the last super_admin")
+	// ErrNoRemainingCurators is returned when a coordinator with active
+	// clients is demoted and no other curator can take them
+	ErrNoRemainingCurators = errors.New("cannot demote: no remaining curators")
+)
 
 // AdminUser represents a user as seen in the admin panel
 type AdminUser struct {
@@ -16,6 +43,22 @@ type AdminUser struct {
 	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
 }
 
+// UserFilter narrows the admin user listing; zero values mean "any".
+// Search matches part of the email, case-insensitively.
+type UserFilter struct {
+	Search string
+	Role   string
+	Limit  int
+	Offset int
+}
+
+// UserListResponse is a page of users
+type UserListResponse struct {
+	Users   []AdminUser `json:"users"`
+	Total   int         `json:"total"`
+	HasMore bool        `json:"hasMore"`
+}
+
 // CuratorLoad represents a curator with their client load
 type CuratorLoad struct {
 	ID          int64  `json:"id"`
@@ -48,7 +91,7 @@ type AdminMessage struct {
 
 // ChangeRoleRequest is the request body for changing a user's role
 type ChangeRoleRequest struct {
-	Role string `json:"role" binding:"required,oneof=coordinator client"`
+	Role string `json:"role" binding:"required,oneof=client coordinator super_admin"`
 }
 
 // AssignCuratorRequest is the request body for assigning a curator to a client
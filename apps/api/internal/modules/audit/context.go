@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+// actorIPKey is the request context key holding the client IP
+type actorIPKey struct{}
+
+// remoteAddrKey is the request context key holding the peer address
+type remoteAddrKey struct{}
+
+// WithActorIP returns a context carrying the client IP for audit records
+func WithActorIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, actorIPKey{}, ip)
+}
+
+// ActorIPFromContext returns the client IP stored by WithActorIP, if any
+func ActorIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(actorIPKey{}).(string)
+	return ip
+}
+
+// WithRemoteAddr returns a context carrying the address of the connection's
+// peer, which is the proxy when the request came through one
+func WithRemoteAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, addr)
+}
+
+// RemoteAddrFromContext returns the peer address stored by WithRemoteAddr,
+// if any. Security events log it next to the client IP, which comes from
+// proxy headers, so a spoofed header can be told apart afterwards.
+func RemoteAddrFromContext(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// CaptureActorIP stores the client IP and the peer address in the request
+// context, so services deep below the handlers can attribute audit records
+// and security events without a gin context
+func CaptureActorIP() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := WithActorIP(c.Request.Context(), c.ClientIP())
+		c.Request = c.Request.WithContext(WithRemoteAddr(ctx, c.RemoteIP()))
+		c.Next()
+	}
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the admin audit log
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new audit handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// List handles GET /api/v1/admin/audit?user_id=&action=&from=&to=&limit=&offset=
+func (h *Handler) List(c *gin.Context) {
+	filter, err := parseFilter(c)
+	if err != nil {
+		switch err {
+		case ErrInvalidUserID:
+			response.Error(c, http.StatusBadRequest, "Неверный идентификатор пользователя")
+		default:
+			response.Error(c, http.StatusBadRequest, "Неверный период: используйте формат ГГГГ-ММ-ДД или RFC 3339")
+		}
+		return
+	}
+
+	result, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		h.log.Error("Failed to list audit log", "error", err)
+		response.InternalError(c, "Не удалось загрузить журнал аудита")
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+// parseFilter reads the listing filter from query parameters. Dates may be
+// given as YYYY-MM-DD (a date-only "to" includes that whole day) or RFC 3339.
+func parseFilter(c *gin.Context) (Filter, error) {
+	filter := Filter{
+		Action: c.Query("action"),
+		Limit:  DefaultPageSize,
+	}
+
+	if v := c.Query("user_id"); v != "" {
+		userID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || userID <= 0 {
+			return filter, ErrInvalidUserID
+		}
+		filter.UserID = &userID
+	}
+
+	if v := c.Query("from"); v != "" {
+		from, _, err := parseTime(v)
+		if err != nil {
+			return filter, ErrInvalidRange
+		}
+		filter.From = &from
+	}
+
+	if v := c.Query("to"); v != "" {
+		to, dateOnly, err := parseTime(v)
+		if err != nil {
+			return filter, ErrInvalidRange
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	if filter.From != nil && filter.To != nil && !filter.From.Before(*filter.To) {
+		return filter, ErrInvalidRange
+	}
+
+	if v := c.Query("limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit > 0 {
+			filter.Limit = min(limit, MaxPageSize)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err := strconv.Atoi(v); err == nil && offset >= 0 {
+			filter.Offset = offset
+		}
+	}
+
+	return filter, nil
+}
+
+// parseTime accepts a date (UTC midnight) or an RFC 3339 timestamp and
+// reports whether the value was date-only
+func parseTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
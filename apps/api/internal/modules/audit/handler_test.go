@@ -0,0 +1,88 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAuditService struct {
+	filter *Filter
+	err    error
+}
+
+func (m *mockAuditService) Record(ctx context.Context, entry Entry) {}
+
+func (m *mockAuditService) List(ctx context.Context, filter Filter) (*ListResponse, error) {
+	m.filter = &filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &ListResponse{Entries: []Record{}}, nil
+}
+
+func get(service ServiceInterface, url string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(&config.Config{}, logger.New(), service)
+	router := gin.New()
+	router.GET("/admin/audit", handler.List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+	return w
+}
+
+func TestListHandler(t *testing.T) {
+	t.Run("parses filters and makes a date-only to inclusive", func(t *testing.T) {
+		service := &mockAuditService{}
+
+		w := get(service, "/admin/audit?user_id=42&action=role_changed&from=2026-10-01&to=2026-10-15&limit=500&offset=50")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, service.filter)
+		assert.Equal(t, int64(42), *service.filter.UserID)
+		assert.Equal(t, "role_changed", service.filter.Action)
+		assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), *service.filter.From)
+		assert.Equal(t, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC), *service.filter.To)
+		assert.Equal(t, MaxPageSize, service.filter.Limit)
+		assert.Equal(t, 50, service.filter.Offset)
+	})
+
+	t.Run("defaults without parameters", func(t *testing.T) {
+		service := &mockAuditService{}
+
+		w := get(service, "/admin/audit")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Nil(t, service.filter.UserID)
+		assert.Nil(t, service.filter.From)
+		assert.Equal(t, DefaultPageSize, service.filter.Limit)
+	})
+
+	t.Run("rejects bad parameters", func(t *testing.T) {
+		for _, url := range []string{
+			"/admin/audit?user_id=abc",
+			"/admin/audit?from=yesterday",
+			"/admin/audit?from=2026-10-15&to=2026-10-01",
+		} {
+			service := &mockAuditService{}
+			w := get(service, url)
+			assert.Equal(t, http.StatusBadRequest, w.Code, url)
+			assert.Nil(t, service.filter, url)
+		}
+	})
+
+	t.Run("service error is a 500", func(t *testing.T) {
+		w := get(&mockAuditService{err: errors.New("db down")}, "/admin/audit")
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"regexp"
+	"strings"
+)
+
+// redacted replaces sensitive metadata values
+const redacted = "[REDACTED]"
+
+// sensitiveKeyParts mark metadata keys whose values must never be stored
+var sensitiveKeyParts = []string{
+	"password", "passwd", "token", "secret", "authorization", "api_key", "apikey", "cookie", "hash",
+}
+
+// jwtPattern matches a JSON Web Token anywhere in a string value
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// isSensitiveKey reports whether a metadata key names a secret
+func isSensitiveKey(key string) bool {
+	lower := strings.ToLower(key)
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// Sanitize returns a copy of metadata that is safe to store: values under
+// secret-looking keys are redacted at any depth, and bearer tokens or JWTs
+// embedded in other string values are masked. The input is not modified.
+func Sanitize(metadata map[string]any) map[string]any {
+	if metadata == nil {
+		return map[string]any{}
+	}
+	clean := make(map[string]any, len(metadata))
+	for key, value := range metadata {
+		if isSensitiveKey(key) {
+			clean[key] = redacted
+			continue
+		}
+		clean[key] = sanitizeValue(value)
+	}
+	return clean
+}
+
+// sanitizeValue sanitizes a single metadata value
+func sanitizeValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		return Sanitize(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = sanitizeValue(item)
+		}
+		return out
+	case string:
+		return sanitizeString(v)
+	default:
+		return v
+	}
+}
+
+// sanitizeString masks credentials that leaked into free text
+func sanitizeString(s string) string {
+	if strings.HasPrefix(strings.ToLower(s), "bearer ") {
+		return redacted
+	}
+	return jwtPattern.ReplaceAllString(s, redacted)
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// ServiceInterface defines the contract for the audit service
+type ServiceInterface interface {
+	Record(ctx context.Context, entry Entry)
+	List(ctx context.Context, filter Filter) (*ListResponse, error)
+}
+
+// Service writes and reads the audit log
+type Service struct {
+	db  *sql.DB
+	log *logger.Logger
+}
+
+// NewService creates a new audit service
+func NewService(db *sql.DB, log *logger.Logger) *Service {
+	return &Service{
+		db:  db,
+		log: log,
+	}
+}
+
+// Record writes an audit row. It is best-effort: failures are logged and
+// never returned, so auditing cannot break the operation being audited.
+// Metadata is sanitized before it is stored.
+func (s *Service) Record(ctx context.Context, entry Entry) {
+	startTime := time.Now()
+
+	actorIP := entry.ActorIP
+	if actorIP == "" {
+		actorIP = ActorIPFromContext(ctx)
+	}
+	var ip sql.NullString
+	if actorIP != "" {
+		ip = sql.NullString{String: actorIP, Valid: true}
+	}
+
+	metadata, err := json.Marshal(Sanitize(entry.Metadata))
+	if err != nil {
+		s.log.Error("Failed to encode audit metadata", "action", entry.Action, "error", err)
+		metadata = []byte("{}")
+	}
+
+	query := `
+		INSERT INTO audit_log (user_id, actor_ip, action, metadata)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, err = s.db.ExecContext(ctx, query, entry.UserID, ip, entry.Action, metadata)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"action": entry.Action,
+	})
+	if err != nil {
+		s.log.Error("Failed to write audit log", "action", entry.Action, "error", err)
+	}
+}
+
+// List returns audit records matching the filter, newest first
+func (s *Service) List(ctx context.Context, filter Filter) (*ListResponse, error) {
+	startTime := time.Now()
+
+	var conditions []string
+	var args []interface{}
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM audit_log ` + where
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		s.log.LogDatabaseQuery(countQuery, time.Since(startTime), err, nil)
+		return nil, fmt.Errorf("failed to count audit records: %w", err)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT id, user_id, actor_ip, action, metadata, created_at
+		FROM audit_log
+		%s
+		ORDER BY created_at DESC, id DESC
+		LIMIT $%d OFFSET $%d
+	`, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"action": filter.Action,
+		"limit":  filter.Limit,
+		"offset": filter.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Record{}
+	for rows.Next() {
+		var r Record
+		var userID sql.NullInt64
+		var actorIP sql.NullString
+		var metadata []byte
+		if err := rows.Scan(&r.ID, &userID, &actorIP, &r.Action, &metadata, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan audit record: %w", err)
+		}
+		if userID.Valid {
+			r.UserID = &userID.Int64
+		}
+		if actorIP.Valid {
+			r.ActorIP = &actorIP.String
+		}
+		r.Metadata = json.RawMessage(metadata)
+		entries = append(entries, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating audit records: %w", err)
+	}
+
+	return &ListResponse{
+		Entries: entries,
+		Total:   total,
+		HasMore: filter.Offset+len(entries) < total,
+	}, nil
+}
@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, func()) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	service := NewService(db, logger.New())
+
+	return service, mock, func() { db.Close() }
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("writes sanitized metadata with the context IP", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		ctx := WithActorIP(context.Background(), "198.51.100.4")
+		userID := int64(42)
+
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(userID, "198.51.100.4", ActionPasswordChanged, []byte(`{"password":"[REDACTED]","via":"settings"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		service.Record(ctx, Entry{
+			UserID:   &userID,
+			Action:   ActionPasswordChanged,
+			Metadata: map[string]any{"password": "NewPass456!@#", "via": "settings"},
+		})
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("explicit IP wins and missing user is stored as NULL", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		ctx := WithActorIP(context.Background(), "198.51.100.4")
+
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(nil, "203.0.113.9", ActionLoginLockout, []byte("{}")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		service.Record(ctx, Entry{ActorIP: "203.0.113.9", Action: ActionLoginLockout})
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("write failures are swallowed", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectExec("INSERT INTO audit_log").
+			WillReturnError(errors.New("connection reset by peer"))
+
+		assert.NotPanics(t, func() {
+			service.Record(context.Background(), Entry{Action: ActionRoleChanged})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestList(t *testing.T) {
+	columns := []string{"id", "user_id", "actor_ip", "action", "metadata", "created_at"}
+	createdAt := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+
+	t.Run("applies filters and pagination", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		userID := int64(42)
+		from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_log WHERE user_id = \$1 AND action = \$2 AND created_at >= \$3 AND created_at < \$4`).
+			WithArgs(userID, ActionRoleChanged, from, to).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery(`LIMIT \$5 OFFSET \$6`).
+			WithArgs(userID, ActionRoleChanged, from, to, 2, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(int64(9), userID, "10.0.0.1", ActionRoleChanged, []byte(`{"new_role":"client"}`), createdAt).
+				AddRow(int64(8), userID, nil, ActionRoleChanged, []byte(`{}`), createdAt.Add(-time.Hour)))
+
+		result, err := service.List(context.Background(), Filter{
+			UserID: &userID, Action: ActionRoleChanged, From: &from, To: &to, Limit: 2,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, result.Total)
+		assert.True(t, result.HasMore)
+		require.Len(t, result.Entries, 2)
+		assert.Equal(t, "10.0.0.1", *result.Entries[0].ActorIP)
+		assert.JSONEq(t, `{"new_role":"client"}`, string(result.Entries[0].Metadata))
+		assert.Nil(t, result.Entries[1].ActorIP)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no filters lists everything", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery(`SELECT COUNT\(\*\) FROM audit_log\s*$`).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(`LIMIT \$1 OFFSET \$2`).
+			WithArgs(DefaultPageSize, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		result, err := service.List(context.Background(), Filter{Limit: DefaultPageSize})
+
+		require.NoError(t, err)
+		assert.Empty(t, result.Entries)
+		assert.NotNil(t, result.Entries)
+		assert.False(t, result.HasMore)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultPageSize is the page size of the admin audit listing
+	DefaultPageSize = 50
+	// MaxPageSize caps the limit query parameter
+	MaxPageSize = 200
+)
+
+// Audited actions
+const (
+	ActionPasswordResetCompleted = "password_reset_completed"
+	ActionPasswordChanged        = "password_changed"
+	ActionRoleChanged            = "role_changed"
+	ActionCoachDataAccess        = "coach_data_access"
+	ActionCoachDataAccessDenied  = "coach_data_access_denied"
+	ActionLoginLockout           = "login_lockout"
+	ActionMembersImported        = "organization_members_imported"
+	ActionOrganizationJoined     = "organization_joined"
+	ActionAccountDeleted         = "account_deleted"
+	ActionAccountReactivated     = "account_reactivated"
+	ActionAccountPurged          = "account_purged"
+	ActionDataExportRequested    = "data_export_requested"
+	ActionDataExportDownloaded   = "data_export_downloaded"
+	ActionMaintenanceModeChanged = "maintenance_mode_changed"
+	ActionEmailChangeRequested   = "email_change_requested"
+	ActionEmailChanged           = "email_changed"
+	ActionQuotaLimitsChanged     = "quota_limits_changed"
+	ActionFeatureFlagChanged     = "feature_flag_changed"
+)
+
+// Entry is an audit event to record. UserID is the account the action
+// concerns and may be nil when it is not known (e.g. a lockout by IP).
+// ActorIP defaults to the IP stored in the request context.
+type Entry struct {
+	UserID   *int64
+	ActorIP  string
+	Action   string
+	Metadata map[string]any
+}
+
+// Record is a stored audit row as returned to admins
+type Record struct {
+	ID        int64           `json:"id"`
+	UserID    *int64          `json:"user_id"`
+	ActorIP   *string         `json:"actor_ip"`
+	Action    string          `json:"action"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// Filter narrows the admin audit listing; zero values mean "any".
+// From is inclusive, To is exclusive.
+type Filter struct {
+	UserID *int64
+	Action string
+	From   *time.Time
+	To     *time.Time
+	Limit  int
+	Offset int
+}
+
+// ListResponse is a page of audit records
+type ListResponse struct {
+	Entries []Record `json:"entries"`
+	Total   int      `json:"total"`
+	HasMore bool     `json:"hasMore"`
+}
+
+var (
+	ErrInvalidUserID = errors.New("invalid user_id")
+	ErrInvalidRange  = errors.New("invalid date range")
+)
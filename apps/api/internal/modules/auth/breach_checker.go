@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// HIBPRangeURL is the Have I Been Pwned range endpoint for password hashes
+	HIBPRangeURL = "https://api.pwnedpasswords.com/range/"
+	// hibpTimeout bounds how long a password change waits for the breach check
+	hibpTimeout = 3 * time.Second
+)
+
+// BreachChecker reports whether a password appeared in a known data breach
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// noopBreachChecker treats every password as not breached, for tests and
+// offline deployments
+type noopBreachChecker struct{}
+
+func (noopBreachChecker) IsBreached(string) (bool, error) {
+	return false, nil
+}
+
+// HIBPChecker checks passwords against the Have I Been Pwned range API.
+// Only the first five characters of the password's SHA-1 hash are sent
+// (k-anonymity); the matching suffixes are compared locally.
+type HIBPChecker struct {
+	httpClient *http.Client
+	baseURL    string
+	log        *logger.Logger
+}
+
+// NewHIBPChecker creates a checker querying rangeURL, normally HIBPRangeURL
+func NewHIBPChecker(rangeURL string, log *logger.Logger) *HIBPChecker {
+	return &HIBPChecker{
+		httpClient: &http.Client{Timeout: hibpTimeout},
+		baseURL:    rangeURL,
+		log:        log,
+	}
+}
+
+// newPasswordValidator returns the password policy, checking Have I Been
+// Pwned when PASSWORD_BREACH_CHECK is enabled
+func newPasswordValidator(cfg *config.Config, log *logger.Logger) *PasswordValidator {
+	if cfg.PasswordBreachCheck {
+		return NewPasswordValidator(WithBreachChecker(NewHIBPChecker(HIBPRangeURL, log)))
+	}
+	return NewPasswordValidator()
+}
+
+// IsBreached reports whether the password's hash is in the breach corpus.
+// Failures are logged and returned; the caller decides whether to go on.
+func (c *HIBPChecker) IsBreached(password string) (bool, error) {
+	breached, err := c.lookup(password)
+	if err != nil {
+		c.log.Warn("Password breach check failed", "error", err)
+	}
+	return breached, err
+}
+
+func (c *HIBPChecker) lookup(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+prefix, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Padded responses hide the number of suffixes sharing the prefix
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+
+	// Each line is SUFFIX:COUNT; padding entries have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		lineSuffix, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && lineSuffix == suffix && count != "0" {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read response: %w", err)
+	}
+	return false, nil
+}
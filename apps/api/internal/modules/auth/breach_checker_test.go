@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHIBPChecker(t *testing.T) {
+	const password = "Kettlebell#Row42"
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+	other := strings.Repeat("0", len(suffix))
+
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		breached bool
+		wantErr  bool
+	}{
+		{name: "suffix listed", status: http.StatusOK, body: other + ":3\r\n" + suffix + ":42\r\n", breached: true},
+		{name: "suffix absent", status: http.StatusOK, body: other + ":3\r\n"},
+		{name: "padding entry", status: http.StatusOK, body: suffix + ":0\r\n"},
+		{name: "service error", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotPadding string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotPadding = r.Header.Get("Add-Padding")
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+
+			breached, err := NewHIBPChecker(srv.URL+"/range/", logger.New()).IsBreached(password)
+
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tt.breached, breached)
+			// Only the hash prefix leaves the server
+			assert.Equal(t, "/range/"+prefix, gotPath)
+			assert.Equal(t, "true", gotPadding)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// RefreshCookieName holds the refresh token for web clients in cookie mode
+	RefreshCookieName = "refresh_token"
+	// RefreshCookiePath limits the cookie to the auth endpoints that read it
+	RefreshCookiePath = "/api/v1/auth"
+)
+
+// setRefreshCookie stores the refresh token in an HttpOnly cookie that lives
+// as long as the token itself
+func setRefreshCookie(c *gin.Context, token string, ttl time.Duration) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    token,
+		Path:     RefreshCookiePath,
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// clearRefreshCookie tells the browser to drop the refresh cookie
+func clearRefreshCookie(c *gin.Context) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     RefreshCookieName,
+		Value:    "",
+		Path:     RefreshCookiePath,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// refreshCookie returns the refresh token sent in the cookie, if any
+func refreshCookie(c *gin.Context) string {
+	token, err := c.Cookie(RefreshCookieName)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// respondWithRefreshCookie moves the refresh token from the response body
+// into the cookie
+func (h *Handler) respondWithRefreshCookie(c *gin.Context, status int, result *LoginResult) {
+	setRefreshCookie(c, result.RefreshToken, refreshTokenTTL(h.cfg, result.rememberMe))
+	result.RefreshToken = ""
+	response.Success(c, status, result)
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newCookieTestServer serves the auth routes over TLS, since the refresh
+// cookie is Secure, and returns a client that keeps cookies between calls
+func newCookieTestServer(t *testing.T, handler *Handler) (*httptest.Server, *http.Client) {
+	router := gin.New()
+	group := router.Group(RefreshCookiePath)
+	group.POST("/login", handler.Login)
+	group.POST("/refresh", middleware.RequireRequestedWith(RefreshCookieName), handler.Refresh)
+	group.POST("/logout", middleware.RequireRequestedWith(RefreshCookieName), handler.Logout)
+
+	srv := httptest.NewTLSServer(router)
+	t.Cleanup(srv.Close)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := srv.Client()
+	client.Jar = jar
+	return srv, client
+}
+
+func postAuth(t *testing.T, client *http.Client, url string, body any, requestedWith bool) (*http.Response, map[string]any) {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(http.MethodPost, url, &buf)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if requestedWith {
+		req.Header.Set(middleware.RequestedWithHeader, "XMLHttpRequest")
+	}
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var decoded map[string]any
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+	return resp, decoded
+}
+
+func findRefreshCookie(resp *http.Response) *http.Cookie {
+	for _, c := range resp.Cookies() {
+		if c.Name == RefreshCookieName {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestRefreshCookieRoundTrip(t *testing.T) {
+	handler, mock, cleanup := setupTestHandler(t)
+	defer cleanup()
+	srv, client := newCookieTestServer(t, handler)
+	base := srv.URL + RefreshCookiePath
+
+	// Login with use_cookie: the refresh token moves into the cookie
+	hashedPw, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mock.ExpectQuery("SELECT id, email").
+		WithArgs("test@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+			AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp, body := postAuth(t, client, base+"/login", map[string]any{
+		"email": "test@example.com", "password": "password123", "use_cookie": true,
+	}, false)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data := body["data"].(map[string]any)
+	assert.NotEmpty(t, data["token"])
+	assert.NotContains(t, data, "refresh_token")
+	loginCookie := findRefreshCookie(resp)
+	require.NotNil(t, loginCookie)
+	assert.NotEmpty(t, loginCookie.Value)
+	assert.True(t, loginCookie.HttpOnly)
+	assert.True(t, loginCookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, loginCookie.SameSite)
+	assert.Equal(t, RefreshCookiePath, loginCookie.Path)
+	assert.Equal(t, int(handler.cfg.RefreshTokenTTL.Seconds()), loginCookie.MaxAge)
+
+	// The cookie alone is not enough: the CSRF header is required
+	resp, _ = postAuth(t, client, base+"/refresh", nil, false)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Refresh reads the cookie and rotates it
+	mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
+		WithArgs(handler.service.(*Service).tokens.HashToken(loginCookie.Value)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me", "family_id"}).
+			AddRow(1, 1, time.Now().Add(time.Hour), nil, false, testSessionID))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refresh_tokens").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT id, email").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at", "token_version"}).
+			AddRow(1, "test@example.com", "Test User", "client", false, false, time.Now(), 0))
+
+	resp, body = postAuth(t, client, base+"/refresh", nil, true)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	data = body["data"].(map[string]any)
+	assert.NotEmpty(t, data["token"])
+	assert.NotContains(t, data, "refresh_token")
+	rotated := findRefreshCookie(resp)
+	require.NotNil(t, rotated)
+	assert.NotEqual(t, loginCookie.Value, rotated.Value)
+
+	// Logout revokes the rotated token and clears the cookie
+	mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
+		WithArgs(handler.service.(*Service).tokens.HashToken(rotated.Value)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	resp, _ = postAuth(t, client, base+"/logout", nil, true)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	cleared := findRefreshCookie(resp)
+	require.NotNil(t, cleared)
+	assert.Negative(t, cleared.MaxAge)
+	u, _ := url.Parse(base + "/refresh")
+	assert.Empty(t, client.Jar.Cookies(u))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLogin_RefreshCookieConfig(t *testing.T) {
+	handler, mock, cleanup := setupTestHandler(t)
+	defer cleanup()
+	handler.cfg.RefreshCookie = true
+	srv, client := newCookieTestServer(t, handler)
+
+	hashedPw, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	mock.ExpectQuery("SELECT id, email").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+			AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), true, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	resp, body := postAuth(t, client, srv.URL+RefreshCookiePath+"/login", map[string]any{
+		"email": "test@example.com", "password": "password123", "remember_me": true,
+	}, false)
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, body["data"].(map[string]any), "refresh_token")
+	cookie := findRefreshCookie(resp)
+	require.NotNil(t, cookie)
+	assert.Equal(t, int(handler.cfg.RememberMeRefreshTokenTTL.Seconds()), cookie.MaxAge)
+}
+
+func TestRefresh_BodyTokenStaysInBody(t *testing.T) {
+	handler, mock, cleanup := setupTestHandler(t)
+	defer cleanup()
+	srv, client := newCookieTestServer(t, handler)
+
+	mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
+		WillReturnError(sqlmock.ErrCancelled)
+
+	resp, _ := postAuth(t, client, srv.URL+RefreshCookiePath+"/refresh", RefreshRequest{RefreshToken: "body-token"}, false)
+
+	// Mobile clients need neither the cookie nor the CSRF header
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Nil(t, findRefreshCookie(resp))
+}
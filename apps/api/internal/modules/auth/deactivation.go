@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// DeletionGracePeriod is how long a deleted account can be reactivated
+// before its data is purged
+const DeletionGracePeriod = 14 * 24 * time.Hour
+
+var (
+	// ErrAccountDeactivated matches DeactivatedError
+	ErrAccountDeactivated = errors.New("account deactivated")
+	// ErrNotDeactivated is returned when reactivating an active account
+	ErrNotDeactivated = errors.New("account is not deactivated")
+)
+
+// DeactivatedError is returned by Login for an account its owner deleted
+// that can still be reactivated until PurgeAfter
+type DeactivatedError struct {
+	PurgeAfter time.Time
+}
+
+func (e *DeactivatedError) Error() string {
+	return ErrAccountDeactivated.Error() + ", purged after " + e.PurgeAfter.Format(time.RFC3339)
+}
+
+func (e *DeactivatedError) Is(target error) bool {
+	return target == ErrAccountDeactivated
+}
+
+// Reactivate restores an account deleted by its owner while the grace
+// period lasts and logs the user in. Unknown accounts, wrong passwords and
+// accounts past the grace period all fail with ErrInvalidCredentials.
+func (s *Service) Reactivate(ctx context.Context, email, plainPassword, ip, ua string) (*LoginResult, error) {
+	email = validation.NormalizeEmail(email)
+	query := `
+		SELECT id, email, COALESCE(name, ''), password, role, email_verified, COALESCE(onboarding_completed, false), created_at, purge_after, token_version
+		FROM users
+		WHERE LOWER(email) = $1
+	`
+
+	var user User
+	var hashedPassword string
+	var purgeAfter sql.NullTime
+	startTime := time.Now()
+	err := s.db.QueryRowContext(ctx, query, email).Scan(
+		&user.ID, &user.Email, &user.Name, &hashedPassword, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt, &purgeAfter, &user.TokenVersion,
+	)
+	s.log.LogDatabaseQuery("Reactivate.LookupUser", time.Since(startTime), err, map[string]any{"email": email})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("Reactivate.LookupUser: %w", apperrors.ErrInvalidCredentials)
+		}
+		return nil, fmt.Errorf("ошибка при восстановлении аккаунта: %w", err)
+	}
+
+	if err := password.Compare(hashedPassword, plainPassword); err != nil {
+		return nil, fmt.Errorf("Reactivate.VerifyPassword: %w", apperrors.ErrInvalidCredentials)
+	}
+	if !purgeAfter.Valid {
+		return nil, ErrNotDeactivated
+	}
+
+	// The purge_after condition keeps a reactivation racing the purge job
+	// from reviving an account whose data is already being removed
+	startTime = time.Now()
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE users SET deactivated_at = NULL, purge_after = NULL, updated_at = NOW()
+		 WHERE id = $1 AND purge_after > NOW()`,
+		user.ID,
+	)
+	s.log.LogDatabaseQuery("Reactivate.Restore", time.Since(startTime), err, map[string]any{"user_id": user.ID})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при восстановлении аккаунта: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return nil, fmt.Errorf("Reactivate.GracePeriodOver: %w", apperrors.ErrInvalidCredentials)
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &user.ID,
+		Action: audit.ActionAccountReactivated,
+	})
+	s.log.LogBusinessEvent("account_reactivated", map[string]any{"user_id": user.ID})
+
+	sessionID := uuid.NewString()
+	token, err := s.generateToken(&user, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	refreshToken, err := s.createRefreshToken(ctx, user.ID, sessionID, ip, ua, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return &LoginResult{
+		User:         &user,
+		Token:        token,
+		RefreshToken: refreshToken,
+	}, nil
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var loginColumns = []string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}
+
+// deactivatedRows is a user who deleted their account, purged at purgeAfter
+func deactivatedRows(t *testing.T, password string, purgeAfter time.Time) *sqlmock.Rows {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+	return sqlmock.NewRows(loginColumns).
+		AddRow(7, "gone@example.com", "Gone", string(hash), "client", true, true, time.Now(), purgeAfter, 0)
+}
+
+func TestLogin_DeactivatedAccount(t *testing.T) {
+	t.Run("within the grace period", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		purgeAfter := time.Now().Add(10 * 24 * time.Hour)
+
+		mock.ExpectQuery("SELECT id, email").
+			WithArgs("gone@example.com").
+			WillReturnRows(deactivatedRows(t, "password123", purgeAfter))
+
+		result, err := service.Login(context.Background(), "gone@example.com", "password123", "", "", false)
+
+		assert.Nil(t, result)
+		var deactivated *DeactivatedError
+		require.ErrorAs(t, err, &deactivated)
+		assert.True(t, errors.Is(err, ErrAccountDeactivated))
+		assert.WithinDuration(t, purgeAfter, deactivated.PurgeAfter, time.Second)
+		// No refresh token was issued
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong password does not reveal the deactivation", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, email").
+			WillReturnRows(deactivatedRows(t, "password123", time.Now().Add(time.Hour)))
+
+		_, err := service.Login(context.Background(), "gone@example.com", "wrong", "", "", false)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		assert.NotErrorIs(t, err, ErrAccountDeactivated)
+	})
+
+	t.Run("after the grace period", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, email").
+			WillReturnRows(deactivatedRows(t, "password123", time.Now().Add(-time.Minute)))
+
+		_, err := service.Login(context.Background(), "gone@example.com", "password123", "", "", false)
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+	})
+}
+
+func TestLoginHandler_DeactivatedAccount(t *testing.T) {
+	handler, mock, cleanup := setupTestHandler(t)
+	defer cleanup()
+	purgeAfter := time.Now().Add(5 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	mock.ExpectQuery("SELECT id, email").
+		WillReturnRows(deactivatedRows(t, "password123", purgeAfter))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	body, _ := json.Marshal(LoginRequest{Email: "gone@example.com", Password: "password123"})
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.Login(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	var resp struct {
+		Code    string             `json:"code"`
+		Message string             `json:"message"`
+		Details DeactivatedDetails `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, response.CodeAccountDeactivated, resp.Code)
+	assert.Contains(t, resp.Message, purgeAfter.Format("02.01.2006"))
+	assert.Equal(t, "/api/v1/auth/reactivate", resp.Details.ReactivateURL)
+	assert.True(t, purgeAfter.Equal(resp.Details.PurgeAfter))
+}
+
+func TestReactivate(t *testing.T) {
+	t.Run("restores the account and logs in", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, email").
+			WithArgs("gone@example.com").
+			WillReturnRows(deactivatedRows(t, "password123", time.Now().Add(time.Hour)))
+		mock.ExpectExec("UPDATE users SET deactivated_at = NULL, purge_after = NULL").
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(7), sqlmock.AnyArg(), audit.ActionAccountReactivated, []byte("{}")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO refresh_tokens").
+			WithArgs(int64(7), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		result, err := service.Reactivate(context.Background(), "gone@example.com", "password123", "127.0.0.1", "TestAgent")
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, result.Token)
+		assert.NotEmpty(t, result.RefreshToken)
+		assert.Equal(t, int64(7), result.User.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, email").
+			WillReturnRows(deactivatedRows(t, "password123", time.Now().Add(time.Hour)))
+
+		_, err := service.Reactivate(context.Background(), "gone@example.com", "wrong", "", "")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("active account", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+		mock.ExpectQuery("SELECT id, email").
+			WillReturnRows(sqlmock.NewRows(loginColumns).
+				AddRow(7, "active@example.com", "", string(hash), "client", true, true, time.Now(), nil, 0))
+
+		_, err := service.Reactivate(context.Background(), "active@example.com", "password123", "", "")
+
+		assert.ErrorIs(t, err, ErrNotDeactivated)
+	})
+
+	t.Run("grace period over", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, email").
+			WillReturnRows(deactivatedRows(t, "password123", time.Now().Add(-time.Minute)))
+		mock.ExpectExec("UPDATE users SET deactivated_at = NULL").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := service.Reactivate(context.Background(), "gone@example.com", "password123", "", "")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestReactivateHandler(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		rows       func(t *testing.T) *sqlmock.Rows
+		wantStatus int
+	}{
+		{"unknown account", func(t *testing.T) *sqlmock.Rows { return sqlmock.NewRows(loginColumns) }, http.StatusUnauthorized},
+		{"active account", func(t *testing.T) *sqlmock.Rows {
+			hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+			return sqlmock.NewRows(loginColumns).AddRow(7, "gone@example.com", "", string(hash), "client", true, true, time.Now(), nil, 0)
+		}, http.StatusConflict},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mock, cleanup := setupTestHandler(t)
+			defer cleanup()
+			mock.ExpectQuery("SELECT id, email").WillReturnRows(tc.rows(t))
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(ReactivateRequest{Email: "gone@example.com", Password: "password123"})
+			c.Request = httptest.NewRequest(http.MethodPost, "/auth/reactivate", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.Reactivate(c)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+}
@@ -1,15 +1,16 @@
 package auth
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/burcev/api/internal/config"
 	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,16 +18,17 @@ import (
 type Handler struct {
 	cfg                 *config.Config
 	log                 *logger.Logger
-	service             *Service
+	service             ServiceInterface
 	verificationService *VerificationService
 }
 
-// NewHandler creates a new auth handler
-func NewHandler(db *sql.DB, cfg *config.Config, log *logger.Logger, vs *VerificationService) *Handler {
+// NewHandler creates a new auth handler. vs may be nil, then no
+// verification codes are sent.
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface, vs *VerificationService) *Handler {
 	return &Handler{
 		cfg:                 cfg,
 		log:                 log,
-		service:             NewService(db, cfg, log),
+		service:             service,
 		verificationService: vs,
 	}
 }
@@ -52,11 +54,15 @@ type LoginRequest struct {
 	Email      string `json:"email" binding:"required,email"`
 	Password   string `json:"password" binding:"required"`
 	RememberMe bool   `json:"remember_me"`
+	// UseCookie returns the refresh token in an HttpOnly cookie instead of
+	// the body; AUTH_REFRESH_COOKIE turns this on for every login
+	UseCookie bool `json:"use_cookie"`
 }
 
-// RefreshRequest represents token refresh request
+// RefreshRequest represents token refresh request. Web clients in cookie
+// mode send no body and the token is read from the refresh cookie.
 type RefreshRequest struct {
-	RefreshToken string `json:"refresh_token" binding:"required"`
+	RefreshToken string `json:"refresh_token"`
 }
 
 // LogoutRequest represents logout request
@@ -67,12 +73,18 @@ type LogoutRequest struct {
 // Register handles user registration
 func (h *Handler) Register(c *gin.Context) {
 	var req RegisterRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
 	result, err := h.service.Register(c.Request.Context(), req.Email, req.Password, req.Name, c.ClientIP(), c.Request.UserAgent(), req.Consents)
+	var conflict *apperrors.ConflictError
+	if errors.As(err, &conflict) {
+		h.log.Warnw("Registration conflict", "error", err, "email", req.Email)
+		response.Conflict(c, "Пользователь с таким email уже существует", conflict.Field)
+		return
+	}
 	if err != nil {
 		h.log.Errorw("Registration failed", "error", err, "email", req.Email)
 		response.Error(c, http.StatusBadRequest, err.Error())
@@ -92,67 +104,137 @@ func (h *Handler) Register(c *gin.Context) {
 // Login handles user login
 func (h *Handler) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
 	result, err := h.service.Login(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.Request.UserAgent(), req.RememberMe)
+	var deactivated *DeactivatedError
+	if errors.As(err, &deactivated) {
+		respondDeactivated(c, deactivated)
+		return
+	}
 	if err != nil {
 		h.log.Errorw("Login failed", "error", err, "email", req.Email)
-		response.Error(c, http.StatusUnauthorized, "Неверные учетные данные")
+		response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthInvalidCredentials, "Неверные учетные данные", nil)
+		return
+	}
+
+	if req.UseCookie || h.cfg.RefreshCookie {
+		h.respondWithRefreshCookie(c, http.StatusOK, result)
+		return
+	}
+	response.Success(c, http.StatusOK, result)
+}
+
+// DeactivatedDetails tells the owner of a deleted account how to get it back
+type DeactivatedDetails struct {
+	PurgeAfter    time.Time `json:"purge_after"`
+	ReactivateURL string    `json:"reactivate_url"`
+}
+
+// respondDeactivated sends the 403 for logins to a deactivated account
+func respondDeactivated(c *gin.Context, err *DeactivatedError) {
+	response.ErrorCode(c, http.StatusForbidden, response.CodeAccountDeactivated,
+		"Аккаунт удалён. Его можно восстановить с тем же email и паролем до "+err.PurgeAfter.Format("02.01.2006"),
+		DeactivatedDetails{PurgeAfter: err.PurgeAfter, ReactivateURL: "/api/v1/auth/reactivate"})
+}
+
+// ReactivateRequest represents a request to restore a deleted account
+type ReactivateRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Reactivate restores a deleted account within the grace period and logs
+// the user in
+func (h *Handler) Reactivate(c *gin.Context) {
+	var req ReactivateRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	result, err := h.service.Reactivate(c.Request.Context(), req.Email, req.Password, c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidCredentials):
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthInvalidCredentials, "Неверные учетные данные", nil)
+		case errors.Is(err, ErrNotDeactivated):
+			response.Error(c, http.StatusConflict, "Аккаунт активен, восстанавливать нечего")
+		default:
+			h.log.Errorw("Reactivation failed", "error", err, "email", req.Email)
+			response.InternalError(c, "Не удалось восстановить аккаунт")
+		}
 		return
 	}
 
 	response.Success(c, http.StatusOK, result)
 }
 
-// Refresh handles token refresh
+// Refresh handles token refresh. The token comes from the body or, when the
+// body has none, from the refresh cookie; cookie clients get the rotated
+// token back in the cookie.
 func (h *Handler) Refresh(c *gin.Context) {
 	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	// Best-effort parse — cookie clients send an empty body
+	_ = c.ShouldBindJSON(&req)
+
+	fromCookie := false
+	if req.RefreshToken == "" {
+		req.RefreshToken = refreshCookie(c)
+		fromCookie = req.RefreshToken != ""
+	}
+	if req.RefreshToken == "" {
+		response.ErrorCode(c, http.StatusBadRequest, response.CodeValidationFailed, "Не указан refresh-токен", nil)
 		return
 	}
 
 	result, err := h.service.RefreshTokens(c.Request.Context(), req.RefreshToken, c.ClientIP(), c.Request.UserAgent())
 	if err != nil {
 		h.log.Errorw("Token refresh failed", "error", err)
-		response.Error(c, http.StatusUnauthorized, "Invalid or expired refresh token")
+		if fromCookie {
+			clearRefreshCookie(c)
+		}
+		response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthRefreshInvalid, "Invalid or expired refresh token", nil)
 		return
 	}
 
+	if fromCookie {
+		h.respondWithRefreshCookie(c, http.StatusOK, result)
+		return
+	}
 	response.Success(c, http.StatusOK, result)
 }
 
-// Logout handles user logout
+// Logout handles user logout, revoking the refresh token from the body or
+// the cookie and clearing the cookie
 func (h *Handler) Logout(c *gin.Context) {
 	var req LogoutRequest
 	// Best-effort parse — body may be empty for legacy clients
 	_ = c.ShouldBindJSON(&req)
 
+	if req.RefreshToken == "" {
+		req.RefreshToken = refreshCookie(c)
+	}
 	if req.RefreshToken != "" {
 		if err := h.service.RevokeRefreshToken(c.Request.Context(), req.RefreshToken); err != nil {
 			h.log.Errorw("Failed to revoke refresh token on logout", "error", err)
 		}
 	}
 
+	clearRefreshCookie(c)
 	response.SuccessWithMessage(c, http.StatusOK, "Logged out successfully", nil)
 }
 
 // GetCurrentUser returns current authenticated user
 func (h *Handler) GetCurrentUser(c *gin.Context) {
-	userID, _ := c.Get("user_id")
-	email, _ := c.Get("user_email")
-	role, _ := c.Get("user_role")
-
-	response.Success(c, http.StatusOK, gin.H{
-		"user": gin.H{
-			"id":    userID,
-			"email": email,
-			"role":  role,
-		},
-	})
+	response.Success(c, http.StatusOK, CurrentUserResponse{User: CurrentUser{
+		ID:    c.GetInt64("user_id"),
+		Email: c.GetString("user_email"),
+		Role:  c.GetString("user_role"),
+	}})
 }
 
 // ChangePasswordRequest represents a password change request
@@ -170,22 +252,26 @@ func (h *Handler) ChangePassword(c *gin.Context) {
 	}
 
 	var req ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
 	if err := h.service.ChangePassword(c.Request.Context(), userID.(int64), req.CurrentPassword, req.NewPassword); err != nil {
 		switch {
 		case errors.Is(err, apperrors.ErrInvalidCredentials):
-			response.Error(c, http.StatusUnauthorized, "Неверный текущий пароль")
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthWrongPassword, "Неверный текущий пароль", nil)
 		case strings.HasPrefix(err.Error(), "новый пароль должен отличаться"):
-			response.Error(c, http.StatusUnprocessableEntity, err.Error())
+			response.ErrorCode(c, http.StatusUnprocessableEntity, response.CodeValidationFailed, err.Error(), response.ValidationDetails{
+				Fields: map[string]string{"new_password": err.Error()},
+			})
 		case strings.HasPrefix(err.Error(), "пароль не соответствует требованиям"):
-			response.Error(c, http.StatusUnprocessableEntity, err.Error())
+			response.ErrorCode(c, http.StatusUnprocessableEntity, response.CodePasswordTooWeak, err.Error(), response.ValidationDetails{
+				Fields: map[string]string{"new_password": err.Error()},
+			})
 		default:
 			h.log.Errorw("Password change failed", "error", err, "user_id", userID)
-			response.Error(c, http.StatusInternalServerError, "Не удалось изменить пароль")
+			response.ErrorCode(c, http.StatusInternalServerError, response.CodeInternal, "Не удалось изменить пароль", nil)
 		}
 		return
 	}
@@ -203,8 +289,8 @@ func (h *Handler) VerifyEmail(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 
 	var req VerifyEmailRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
@@ -212,11 +298,11 @@ func (h *Handler) VerifyEmail(c *gin.Context) {
 	if err != nil {
 		switch {
 		case errors.Is(err, apperrors.ErrTooManyAttempts):
-			response.Error(c, http.StatusTooManyRequests, "Слишком много попыток. Запросите новый код.")
+			response.ErrorCode(c, http.StatusTooManyRequests, response.CodeVerificationAttemptsExceeded, "Слишком много попыток. Запросите новый код.", nil)
 		case errors.Is(err, apperrors.ErrCodeExpired):
-			response.Error(c, http.StatusBadRequest, "Код истёк. Запросите новый.")
+			response.ErrorCode(c, http.StatusBadRequest, response.CodeVerificationCodeExpired, "Код истёк. Запросите новый.", nil)
 		default:
-			response.Error(c, http.StatusBadRequest, "Неверный код")
+			response.ErrorCode(c, http.StatusBadRequest, response.CodeVerificationCodeInvalid, "Неверный код", nil)
 		}
 		return
 	}
@@ -238,7 +324,7 @@ func (h *Handler) ResendVerification(c *gin.Context) {
 	)
 	if err != nil {
 		if errors.Is(err, apperrors.ErrTooManyAttempts) {
-			response.Error(c, http.StatusTooManyRequests, "Слишком много запросов. Попробуйте позже.")
+			response.RateLimited(c, "Слишком много запросов. Попробуйте позже.", resendWindowDuration)
 			return
 		}
 		h.log.Errorw("Failed to resend verification code", "error", err, "user_id", userID)
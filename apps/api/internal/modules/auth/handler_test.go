@@ -2,7 +2,10 @@ package auth
 
 import (
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,8 +13,11 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
@@ -23,10 +29,13 @@ func setupTestHandler(t *testing.T) (*Handler, sqlmock.Sqlmock, func()) {
 	require.NoError(t, err)
 
 	cfg := &config.Config{
-		JWTSecret: "test-secret",
+		JWTSecret:                 "test-secret",
+		AccessTokenTTL:            config.DefaultAccessTokenTTL,
+		RefreshTokenTTL:           config.DefaultRefreshTokenTTL,
+		RememberMeRefreshTokenTTL: config.DefaultRememberMeRefreshTokenTTL,
 	}
 	log := logger.New()
-	handler := NewHandler(db, cfg, log, nil)
+	handler := NewHandler(cfg, log, NewService(db, cfg, log), nil)
 
 	cleanup := func() {
 		db.Close()
@@ -54,14 +63,14 @@ func TestRegister(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
 		body, _ := json.Marshal(RegisterRequest{
 			Email:    "test@example.com",
-			Password: "Test123!@#",
+			Password: "Orbit#Lantern7",
 			Name:     "Test User",
 		})
 		c.Request = httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
@@ -120,6 +129,45 @@ func TestRegister(t *testing.T) {
 	})
 }
 
+func TestRegister_Conflict(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		// The NOT EXISTS guard saw the email
+		{"existing email", sql.ErrNoRows},
+		// A concurrent registration inserted it first
+		{"unique violation", &pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock, cleanup := setupTestHandler(t)
+			defer cleanup()
+
+			mock.ExpectQuery("INSERT INTO users").
+				WithArgs("test@example.com", sqlmock.AnyArg(), "").
+				WillReturnError(tt.err)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			body, _ := json.Marshal(RegisterRequest{Email: "test@example.com", Password: "Orbit#Lantern7"})
+			c.Request = httptest.NewRequest(http.MethodPost, "/auth/register", bytes.NewBuffer(body))
+			c.Request.Header.Set("Content-Type", "application/json")
+
+			handler.Register(c)
+
+			assert.Equal(t, http.StatusConflict, w.Code)
+			assert.JSONEq(t, `{
+				"status": "error",
+				"code": "CONFLICT",
+				"message": "Пользователь с таким email уже существует",
+				"details": {"field": "email"}
+			}`, w.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
 func TestLogin(t *testing.T) {
 	t.Run("successful login returns tokens", func(t *testing.T) {
 		handler, mock, cleanup := setupTestHandler(t)
@@ -129,11 +177,11 @@ func TestLogin(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		w := httptest.NewRecorder()
@@ -186,8 +234,8 @@ func TestLogin(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
@@ -213,11 +261,11 @@ func TestLoginWithRememberMe(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), true).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), true, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		w := httptest.NewRecorder()
@@ -251,11 +299,11 @@ func TestLoginWithRememberMe(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		w := httptest.NewRecorder()
@@ -354,7 +402,7 @@ func TestGetCurrentUser(t *testing.T) {
 	c.Request = httptest.NewRequest(http.MethodGet, "/auth/me", nil)
 
 	// Set user context (simulating middleware)
-	c.Set("user_id", "user-123")
+	c.Set("user_id", int64(123))
 	c.Set("user_email", "test@example.com")
 	c.Set("user_role", "client")
 
@@ -369,7 +417,89 @@ func TestGetCurrentUser(t *testing.T) {
 	assert.Equal(t, "success", response["status"])
 	data := response["data"].(map[string]interface{})
 	user := data["user"].(map[string]interface{})
-	assert.Equal(t, "user-123", user["id"])
+	assert.Equal(t, float64(123), user["id"])
 	assert.Equal(t, "test@example.com", user["email"])
 	assert.Equal(t, "client", user["role"])
 }
+
+// mockService implements ServiceInterface for handler tests that only care
+// how the handler maps service results to responses
+type mockService struct {
+	result *LoginResult
+	err    error
+}
+
+func (m *mockService) Register(ctx context.Context, email, password, name, ip, ua string, consents *ConsentsInput) (*LoginResult, error) {
+	return m.result, m.err
+}
+
+func (m *mockService) Login(ctx context.Context, email, password, ip, ua string, rememberMe bool) (*LoginResult, error) {
+	return m.result, m.err
+}
+
+func (m *mockService) Reactivate(ctx context.Context, email, password, ip, ua string) (*LoginResult, error) {
+	return m.result, m.err
+}
+
+func (m *mockService) RefreshTokens(ctx context.Context, plainToken, ip, ua string) (*LoginResult, error) {
+	return m.result, m.err
+}
+
+func (m *mockService) RevokeRefreshToken(ctx context.Context, plainToken string) error {
+	return m.err
+}
+
+func (m *mockService) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
+	return m.err
+}
+
+// serveMock runs one JSON request through handle of a handler over service
+func serveMock(t *testing.T, service ServiceInterface, handle func(*Handler) gin.HandlerFunc, body any) (int, map[string]interface{}) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(&config.Config{}, logger.New(), service, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	encoded, _ := json.Marshal(body)
+	c.Request = httptest.NewRequest(http.MethodPost, "/auth", bytes.NewBuffer(encoded))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handle(handler)(c)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestHandler_ServiceErrors(t *testing.T) {
+	credentials := LoginRequest{Email: "test@example.com", Password: "password123"}
+
+	tests := []struct {
+		name   string
+		handle func(*Handler) gin.HandlerFunc
+		body   any
+		err    error
+		status int
+		code   string
+	}{
+		{"register rejected", func(h *Handler) gin.HandlerFunc { return h.Register },
+			RegisterRequest{Email: "test@example.com", Password: "password123"}, errors.New("email already registered"), http.StatusBadRequest, ""},
+		{"login failed", func(h *Handler) gin.HandlerFunc { return h.Login },
+			credentials, errors.New("db is down"), http.StatusUnauthorized, response.CodeAuthInvalidCredentials},
+		{"refresh token rejected", func(h *Handler) gin.HandlerFunc { return h.Refresh },
+			RefreshRequest{RefreshToken: "stale"}, apperrors.ErrTokenExpired, http.StatusUnauthorized, response.CodeAuthRefreshInvalid},
+		{"reactivation failed", func(h *Handler) gin.HandlerFunc { return h.Reactivate },
+			ReactivateRequest{Email: credentials.Email, Password: credentials.Password}, errors.New("db is down"), http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := serveMock(t, &mockService{err: tt.err}, tt.handle, tt.body)
+
+			assert.Equal(t, tt.status, status)
+			if tt.code != "" {
+				assert.Equal(t, tt.code, resp["code"])
+			}
+		})
+	}
+}
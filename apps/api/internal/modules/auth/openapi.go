@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+)
+
+// Endpoints describes the /auth routes for the OpenAPI document
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodPost, Path: "/register", Summary: "Регистрация", Request: RegisterRequest{}, Response: LoginResult{}, Status: http.StatusCreated},
+		{Method: http.MethodPost, Path: "/login", Summary: "Вход по email и паролю; с use_cookie=true refresh-токен приходит в HttpOnly-cookie; 403 ACCOUNT_DEACTIVATED для удалённого аккаунта", Request: LoginRequest{}, Response: LoginResult{}},
+		{Method: http.MethodPost, Path: "/refresh", Summary: "Обновление токенов; без тела токен берётся из cookie (нужен заголовок X-Requested-With)", Request: RefreshRequest{}, Response: LoginResult{}},
+		{Method: http.MethodPost, Path: "/logout", Summary: "Выход, отзыв refresh-токена и удаление cookie", Request: LogoutRequest{}},
+		{Method: http.MethodPost, Path: "/reactivate", Summary: "Восстановление удалённого аккаунта до истечения 14 дней", Request: ReactivateRequest{}, Response: LoginResult{}},
+		{Method: http.MethodGet, Path: "/me", Summary: "Текущий пользователь", Auth: openapi.Bearer, Response: CurrentUserResponse{}},
+		{Method: http.MethodPost, Path: "/verify-email", Summary: "Подтверждение email кодом", Auth: openapi.Bearer, Request: VerifyEmailRequest{}},
+		{Method: http.MethodPost, Path: "/resend-verification", Summary: "Повторная отправка кода", Auth: openapi.Bearer},
+		{Method: http.MethodPost, Path: "/forgot-password", Summary: "Запрос ссылки для сброса пароля", Request: ForgotPasswordRequest{}},
+		{Method: http.MethodPost, Path: "/reset-password", Summary: "Сброс пароля по ссылке", Request: ResetPasswordRequest{}},
+		{Method: http.MethodGet, Path: "/validate-reset-token", Summary: "Проверка ссылки для сброса", Query: ValidateTokenRequest{}, Response: ResetTokenResponse{}},
+	}
+}
+
+// SessionEndpoints describes the session routes, which live under /users
+func SessionEndpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/sessions", Summary: "Активные сеансы пользователя; current отмечает текущий", Auth: openapi.Bearer, Response: SessionsResponse{}},
+		{Method: http.MethodDelete, Path: "/sessions", Summary: "Завершение всех сеансов, кроме текущего", Auth: openapi.Bearer, Response: revokedSessionsResponse{}},
+		{Method: http.MethodDelete, Path: "/sessions/:id", Summary: "Завершение сеанса; его access-токены перестают действовать сразу", Auth: openapi.Bearer},
+	}
+}
@@ -1,13 +1,30 @@
 package auth
 
 import (
+	_ "embed"
+	"fmt"
+	"strings"
 	"unicode"
+
+	"github.com/burcev/api/internal/shared/i18n"
 )
 
+// minEmailLocalPartLength is the shortest email local part a password is
+// checked against; shorter ones match too many unrelated passwords
+const minEmailLocalPartLength = 3
+
+//go:embed common_passwords.txt
+var commonPasswordsFile string
+
+// commonPasswords is the set of the embedded most common passwords
+var commonPasswords = parseCommonPasswords(commonPasswordsFile)
+
 // PasswordValidator validates passwords against security requirements.
 // It checks for minimum length, character type requirements (uppercase,
 // lowercase, numbers, special characters), and returns detailed validation
-// errors to help users create secure passwords.
+// errors to help users create secure passwords. Passwords meeting them are
+// then checked against common passwords, the user's email and, when a
+// BreachChecker is configured, known data breaches.
 type PasswordValidator struct {
 	minLength      int
 	maxLength      int // UX ceiling; bcrypt truncates at 72 bytes — 128 chars is well above that
@@ -15,14 +32,64 @@ type PasswordValidator struct {
 	requireLower   bool
 	requireNumber  bool
 	requireSpecial bool
+	breaches       BreachChecker
+}
+
+// PasswordOption configures a PasswordValidator
+type PasswordOption func(*PasswordValidator)
+
+// WithBreachChecker makes the validator reject passwords found in data
+// breaches by checker
+func WithBreachChecker(checker BreachChecker) PasswordOption {
+	return func(pv *PasswordValidator) {
+		pv.breaches = checker
+	}
+}
+
+// UserContext is what is known about the account a password is set for.
+// Empty fields skip the checks that need them.
+type UserContext struct {
+	Email string
 }
 
 // ValidationResult contains the result of password validation.
 // It includes a boolean indicating overall validity and a slice
 // of specific error messages for each failed requirement.
 type ValidationResult struct {
-	Valid  bool     // True if password meets all requirements
-	Errors []string // List of specific validation errors
+	Valid    bool           // True if password meets all requirements
+	Errors   []string       // List of specific validation errors, in Russian
+	Problems []i18n.Message // The same errors, translatable
+}
+
+// Err returns a *WeakPasswordError for an invalid result and nil otherwise
+func (r ValidationResult) Err() error {
+	if r.Valid {
+		return nil
+	}
+	return &WeakPasswordError{Problems: r.Problems}
+}
+
+// WeakPasswordError is returned when a new password does not meet the
+// requirements. Its Error text is Russian; Message translates it.
+type WeakPasswordError struct {
+	Problems []i18n.Message
+}
+
+func (e *WeakPasswordError) Error() string {
+	return fmt.Sprintf("пароль не соответствует требованиям: %v", messagesIn(i18n.RU, e.Problems))
+}
+
+// Message returns the error and every problem in locale, for the client
+func (e *WeakPasswordError) Message(locale i18n.Locale) string {
+	return i18n.T(locale, i18n.PasswordTooWeak) + ": " + strings.Join(messagesIn(locale, e.Problems), "; ")
+}
+
+func messagesIn(locale i18n.Locale, messages []i18n.Message) []string {
+	texts := make([]string, len(messages))
+	for i, m := range messages {
+		texts[i] = m.In(locale)
+	}
+	return texts
 }
 
 // NewPasswordValidator creates a new PasswordValidator with default settings.
@@ -34,23 +101,34 @@ type ValidationResult struct {
 //   - At least one special character
 //
 // These defaults align with OWASP password security recommendations.
-func NewPasswordValidator() *PasswordValidator {
-	return &PasswordValidator{
+// Breached passwords are only rejected with WithBreachChecker.
+func NewPasswordValidator(opts ...PasswordOption) *PasswordValidator {
+	pv := &PasswordValidator{
 		minLength:      8,
 		maxLength:      128,
 		requireUpper:   true,
 		requireLower:   true,
 		requireNumber:  true,
 		requireSpecial: true,
+		breaches:       noopBreachChecker{},
+	}
+	for _, opt := range opts {
+		opt(pv)
 	}
+	return pv
 }
 
 // Validate checks if a password meets all security requirements.
 // It returns a ValidationResult containing the overall validity status
 // and detailed error messages for each failed requirement.
 //
+// The common password, email and breach checks only run for passwords
+// that meet the character requirements, so a weak password is not sent to
+// the breach service.
+//
 // Parameters:
 //   - password: The password string to validate
+//   - user: The account the password is for
 //
 // Returns:
 //   - ValidationResult with Valid=true if all requirements are met,
@@ -59,49 +137,118 @@ func NewPasswordValidator() *PasswordValidator {
 // Example:
 //
 //	pv := NewPasswordValidator()
-//	result := pv.Validate("weak")
+//	result := pv.Validate("weak", UserContext{Email: "user@example.com"})
 //	if !result.Valid {
 //	    for _, err := range result.Errors {
 //	        fmt.Println(err)
 //	    }
 //	}
-func (pv *PasswordValidator) Validate(password string) ValidationResult {
-	var errors []string
+func (pv *PasswordValidator) Validate(password string, user UserContext) ValidationResult {
+	var problems []i18n.Message
 
 	// Check minimum length
 	if len(password) < pv.minLength {
-		errors = append(errors, "Пароль должен содержать минимум 8 символов")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordTooShort, Args: []any{pv.minLength}})
 	}
 
 	// Check maximum length
 	if len(password) > pv.maxLength {
-		errors = append(errors, "Пароль не должен превышать 128 символов")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordTooLong, Args: []any{pv.maxLength}})
 	}
 
 	// Check for uppercase letter
 	if pv.requireUpper && !containsUppercase(password) {
-		errors = append(errors, "Пароль должен содержать хотя бы одну заглавную букву")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordNoUppercase})
 	}
 
 	// Check for lowercase letter
 	if pv.requireLower && !containsLowercase(password) {
-		errors = append(errors, "Пароль должен содержать хотя бы одну строчную букву")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordNoLowercase})
 	}
 
 	// Check for number
 	if pv.requireNumber && !containsNumber(password) {
-		errors = append(errors, "Пароль должен содержать хотя бы одну цифру")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordNoDigit})
 	}
 
 	// Check for special character
 	if pv.requireSpecial && !containsSpecialChar(password) {
-		errors = append(errors, "Пароль должен содержать хотя бы один специальный символ")
+		problems = append(problems, i18n.Message{Key: i18n.PasswordNoSpecial})
+	}
+
+	if len(problems) == 0 {
+		problems = pv.checkGuessable(password, user)
+	}
+
+	result := ValidationResult{Valid: len(problems) == 0}
+	if !result.Valid {
+		result.Problems = problems
+		result.Errors = messagesIn(i18n.RU, problems)
+	}
+	return result
+}
+
+// checkGuessable returns the problems of a password that meets the
+// character requirements but is still easy to guess
+func (pv *PasswordValidator) checkGuessable(password string, user UserContext) []i18n.Message {
+	if isCommonPassword(password) {
+		return []i18n.Message{{Key: i18n.PasswordCommon}}
+	}
+	if containsEmailLocalPart(password, user.Email) {
+		return []i18n.Message{{Key: i18n.PasswordContainsEmail}}
 	}
+	// An unavailable breach service must not block password changes; the
+	// checker reports its own failures
+	if breached, err := pv.breaches.IsBreached(password); err == nil && breached {
+		return []i18n.Message{{Key: i18n.PasswordFoundInBreaches}}
+	}
+	return nil
+}
 
-	return ValidationResult{
-		Valid:  len(errors) == 0,
-		Errors: errors,
+// isCommonPassword reports whether password, ignoring case and trailing
+// digits and symbols, is one of the embedded common passwords. This catches
+// "Password1!" as well as "Qwerty2024#".
+func isCommonPassword(password string) bool {
+	lower := strings.ToLower(password)
+	candidates := []string{
+		lower,
+		strings.TrimRightFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) }),
+		strings.TrimRightFunc(lower, func(r rune) bool { return !unicode.IsLetter(r) }),
+	}
+	for _, c := range candidates {
+		if _, ok := commonPasswords[c]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsEmailLocalPart reports whether password contains the local part
+// of email, ignoring case and any +tag
+func containsEmailLocalPart(password, email string) bool {
+	local, _, found := strings.Cut(strings.ToLower(email), "@")
+	if !found {
+		return false
+	}
+	local, _, _ = strings.Cut(local, "+")
+	if len([]rune(local)) < minEmailLocalPartLength {
+		return false
+	}
+	return strings.Contains(strings.ToLower(password), local)
+}
+
+// parseCommonPasswords reads one password per line, skipping blank lines
+// and # comments
+func parseCommonPasswords(data string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		set[line] = struct{}{}
 	}
+	return set
 }
 
 // containsUppercase checks if the string contains at least one uppercase letter.
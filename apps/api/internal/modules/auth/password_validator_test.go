@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"errors"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestNewPasswordValidator(t *testing.T) {
@@ -166,7 +169,7 @@ func TestPasswordValidator_Validate(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := pv.Validate(tt.password)
+			result := pv.Validate(tt.password, UserContext{})
 
 			if result.Valid != tt.expectValid {
 				t.Errorf("Expected Valid=%v, got %v", tt.expectValid, result.Valid)
@@ -195,6 +198,131 @@ func TestPasswordValidator_Validate(t *testing.T) {
 	}
 }
 
+// fakeBreachChecker reports the passwords in breached as breached
+type fakeBreachChecker struct {
+	breached map[string]bool
+	err      error
+	calls    int
+}
+
+func (f *fakeBreachChecker) IsBreached(password string) (bool, error) {
+	f.calls++
+	return f.breached[password], f.err
+}
+
+func TestPasswordValidator_CommonPasswords(t *testing.T) {
+	pv := NewPasswordValidator()
+	commonErr := []string{"Пароль слишком распространён, придумайте другой"}
+
+	tests := []struct {
+		name     string
+		password string
+		errors   []string
+	}{
+		{name: "common word with digit and symbol", password: "Password1!", errors: commonErr},
+		{name: "common word mixed case", password: "pASSWORD1!", errors: commonErr},
+		{name: "common keyboard pattern", password: "Qwerty2024#", errors: commonErr},
+		{name: "trailing symbols only", password: "Trustno1!!$", errors: commonErr},
+		{name: "common numeric base", password: "Abc123!!", errors: commonErr},
+		{name: "common word inside a longer password", password: "MyPassword1!", errors: nil},
+		{name: "uncommon password", password: "Kettlebell#Row42", errors: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pv.Validate(tt.password, UserContext{})
+			assert.Equal(t, tt.errors, result.Errors)
+			assert.Equal(t, tt.errors == nil, result.Valid)
+		})
+	}
+}
+
+// TestCommonPasswordsCount keeps the embedded list from shrinking unnoticed
+func TestCommonPasswordsCount(t *testing.T) {
+	assert.Len(t, parseCommonPasswords(commonPasswordsFile), 7141)
+}
+
+func TestPasswordValidator_EmailLocalPart(t *testing.T) {
+	pv := NewPasswordValidator()
+	emailErr := []string{"Пароль не должен содержать имя из вашего email"}
+
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		errors   []string
+	}{
+		{name: "contains local part", password: "Ivan.Petrov#92", email: "ivan.petrov@example.com", errors: emailErr},
+		{name: "contains local part ignoring case", password: "xX-IVANOV-Xx1", email: "ivanov@example.com", errors: emailErr},
+		{name: "plus tag is ignored", password: "Kettle!Ivanov7", email: "ivanov+fit@example.com", errors: emailErr},
+		{name: "domain is allowed", password: "Example#Kettle7", email: "ivanov@example.com", errors: nil},
+		{name: "short local part is not checked", password: "Kettlebell#Row42", email: "ow@example.com", errors: nil},
+		{name: "no email", password: "Kettlebell#Row42", email: "", errors: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pv.Validate(tt.password, UserContext{Email: tt.email})
+			assert.Equal(t, tt.errors, result.Errors)
+		})
+	}
+}
+
+func TestPasswordValidator_BreachChecker(t *testing.T) {
+	breachErr := []string{"Этот пароль встречался в утечках данных, придумайте другой"}
+
+	tests := []struct {
+		name      string
+		password  string
+		checker   *fakeBreachChecker
+		errors    []string
+		wantCalls int
+	}{
+		{
+			name:      "breached password",
+			password:  "Kettlebell#Row42",
+			checker:   &fakeBreachChecker{breached: map[string]bool{"Kettlebell#Row42": true}},
+			errors:    breachErr,
+			wantCalls: 1,
+		},
+		{
+			name:      "clean password",
+			password:  "Kettlebell#Row42",
+			checker:   &fakeBreachChecker{},
+			wantCalls: 1,
+		},
+		{
+			name:      "unavailable checker allows the password",
+			password:  "Kettlebell#Row42",
+			checker:   &fakeBreachChecker{breached: map[string]bool{"Kettlebell#Row42": true}, err: errors.New("timeout")},
+			wantCalls: 1,
+		},
+		{
+			name:      "weak password is not sent",
+			password:  "weak",
+			checker:   &fakeBreachChecker{},
+			errors:    NewPasswordValidator().Validate("weak", UserContext{}).Errors,
+			wantCalls: 0,
+		},
+		{
+			name:      "common password is not sent",
+			password:  "Password1!",
+			checker:   &fakeBreachChecker{},
+			errors:    []string{"Пароль слишком распространён, придумайте другой"},
+			wantCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := NewPasswordValidator(WithBreachChecker(tt.checker))
+			result := pv.Validate(tt.password, UserContext{Email: "user@example.com"})
+			assert.Equal(t, tt.errors, result.Errors)
+			assert.Equal(t, tt.wantCalls, tt.checker.calls)
+		})
+	}
+}
+
 func TestContainsUppercase(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -306,7 +434,7 @@ func BenchmarkPasswordValidator_Validate(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pv.Validate(password)
+		pv.Validate(password, UserContext{Email: "user@example.com"})
 	}
 }
 
@@ -316,6 +444,6 @@ func BenchmarkPasswordValidator_ValidateComplex(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		pv.Validate(password)
+		pv.Validate(password, UserContext{Email: "user@example.com"})
 	}
 }
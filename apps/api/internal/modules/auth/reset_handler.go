@@ -3,12 +3,14 @@ package auth
 import (
 	"errors"
 	"net/http"
-	"strings"
+	"time"
 
 	"github.com/burcev/api/internal/config"
 	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -48,12 +50,12 @@ type ValidateTokenRequest struct {
 // POST /api/auth/forgot-password
 func (h *ResetHandler) ForgotPassword(c *gin.Context) {
 	var req ForgotPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := validation.BindJSON(c, &req); err != nil {
 		h.log.Warn("Invalid forgot password request",
 			"error", err,
 			"ip", c.ClientIP(),
 		)
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+		validation.Respond(c, err)
 		return
 	}
 
@@ -63,13 +65,14 @@ func (h *ResetHandler) ForgotPassword(c *gin.Context) {
 
 	// Log the attempt
 	h.log.LogSecurityEvent("password_reset_requested", "info", map[string]interface{}{
-		"email":      req.Email,
-		"ip_address": ipAddress,
-		"user_agent": userAgent,
+		"email":       req.Email,
+		"ip_address":  ipAddress,
+		"remote_addr": c.RemoteIP(),
+		"user_agent":  userAgent,
 	})
 
 	// Process reset request
-	err := h.service.RequestPasswordReset(c.Request.Context(), req.Email, ipAddress, userAgent)
+	err := h.service.RequestPasswordReset(c.Request.Context(), req.Email, ipAddress, userAgent, i18n.Of(c))
 
 	if err != nil {
 		// Check if it's a rate limit error
@@ -78,7 +81,7 @@ func (h *ResetHandler) ForgotPassword(c *gin.Context) {
 				"email", req.Email,
 				"ip", ipAddress,
 			)
-			response.Error(c, http.StatusTooManyRequests, "Слишком много запросов. Попробуйте позже.")
+			response.RateLimited(c, response.T(c, i18n.ResetRateLimited), h.retryAfter(err))
 			return
 		}
 
@@ -89,30 +92,24 @@ func (h *ResetHandler) ForgotPassword(c *gin.Context) {
 		)
 
 		// Return generic success to prevent information leakage
-		response.SuccessWithMessage(c, http.StatusOK,
-			"Если аккаунт с этим email существует, вы получите инструкции по сбросу пароля.",
-			nil,
-		)
+		response.LocalizedSuccess(c, http.StatusOK, i18n.ResetRequested, nil)
 		return
 	}
 
 	// Always return generic success message (prevent email enumeration)
-	response.SuccessWithMessage(c, http.StatusOK,
-		"Если аккаунт с этим email существует, вы получите инструкции по сбросу пароля.",
-		nil,
-	)
+	response.LocalizedSuccess(c, http.StatusOK, i18n.ResetRequested, nil)
 }
 
 // ResetPassword handles password reset with token
 // POST /api/auth/reset-password
 func (h *ResetHandler) ResetPassword(c *gin.Context) {
 	var req ResetPasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := validation.BindJSON(c, &req); err != nil {
 		h.log.Warn("Invalid reset password request",
 			"error", err,
 			"ip", c.ClientIP(),
 		)
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+		validation.Respond(c, err)
 		return
 	}
 
@@ -121,11 +118,12 @@ func (h *ResetHandler) ResetPassword(c *gin.Context) {
 
 	// Log the attempt
 	h.log.LogSecurityEvent("password_reset_attempted", "info", map[string]interface{}{
-		"ip_address": ipAddress,
+		"ip_address":  ipAddress,
+		"remote_addr": c.RemoteIP(),
 	})
 
 	// Reset password
-	err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password, ipAddress)
+	err := h.service.ResetPassword(c.Request.Context(), req.Token, req.Password, ipAddress, i18n.Of(c))
 
 	if err != nil {
 		h.log.WithError(err).Warn("Password reset failed",
@@ -134,23 +132,27 @@ func (h *ResetHandler) ResetPassword(c *gin.Context) {
 
 		// Return appropriate error message
 		if errors.Is(err, apperrors.ErrTokenInvalid) {
-			response.Error(c, http.StatusBadRequest, "Неверная или истекшая ссылка для сброса. Запросите новую.")
+			response.LocalizedErrorCode(c, http.StatusBadRequest, response.CodeResetTokenInvalid, i18n.ResetTokenInvalid, nil)
 			return
 		}
 
 		if errors.Is(err, apperrors.ErrTokenExpired) {
-			response.Error(c, http.StatusBadRequest, "Срок действия ссылки истек. Запросите новую.")
+			response.LocalizedErrorCode(c, http.StatusBadRequest, response.CodeResetTokenExpired, i18n.ResetTokenExpired, nil)
 			return
 		}
 
 		// Check if it's a password validation error
-		if strings.HasPrefix(err.Error(), "пароль не соответствует требованиям") {
-			response.Error(c, http.StatusBadRequest, err.Error())
+		var weak *WeakPasswordError
+		if errors.As(err, &weak) {
+			message := weak.Message(i18n.Of(c))
+			response.ErrorCode(c, http.StatusBadRequest, response.CodePasswordTooWeak, message, response.ValidationDetails{
+				Fields: map[string]string{"password": message},
+			})
 			return
 		}
 
 		// Generic error for other cases
-		response.Error(c, http.StatusInternalServerError, "Не удалось сбросить пароль. Попробуйте снова.")
+		response.LocalizedErrorCode(c, http.StatusInternalServerError, response.CodeInternal, i18n.ResetFailed, nil)
 		return
 	}
 
@@ -158,10 +160,7 @@ func (h *ResetHandler) ResetPassword(c *gin.Context) {
 		"ip", ipAddress,
 	)
 
-	response.SuccessWithMessage(c, http.StatusOK,
-		"Пароль успешно изменен. Теперь вы можете войти с новым паролем.",
-		nil,
-	)
+	response.LocalizedSuccess(c, http.StatusOK, i18n.ResetCompleted, nil)
 }
 
 // ValidateResetToken validates a reset token
@@ -173,7 +172,7 @@ func (h *ResetHandler) ValidateResetToken(c *gin.Context) {
 			"error", err,
 			"ip", c.ClientIP(),
 		)
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+		validation.Respond(c, err)
 		return
 	}
 
@@ -187,22 +186,33 @@ func (h *ResetHandler) ValidateResetToken(c *gin.Context) {
 		)
 
 		if errors.Is(err, apperrors.ErrTokenInvalid) {
-			response.Error(c, http.StatusBadRequest, "Неверная ссылка для сброса.")
+			response.LocalizedErrorCode(c, http.StatusBadRequest, response.CodeResetTokenInvalid, i18n.ResetLinkInvalid, nil)
 			return
 		}
 
 		if errors.Is(err, apperrors.ErrTokenExpired) {
-			response.Error(c, http.StatusBadRequest, "Срок действия ссылки истек.")
+			response.LocalizedErrorCode(c, http.StatusBadRequest, response.CodeResetTokenExpired, i18n.ResetLinkExpired, nil)
 			return
 		}
 
-		response.Error(c, http.StatusInternalServerError, "Не удалось проверить токен.")
+		response.LocalizedErrorCode(c, http.StatusInternalServerError, response.CodeInternal, i18n.ResetLinkCheckFailed, nil)
 		return
 	}
 
 	// Return success with minimal information
-	response.Success(c, http.StatusOK, gin.H{
-		"valid":      true,
-		"expires_at": tokenData.ExpiresAt,
-	})
+	response.Success(c, http.StatusOK, ResetTokenResponse{Valid: true, ExpiresAt: tokenData.ExpiresAt})
+}
+
+// retryAfter is how long a rate-limited client has to wait: until the
+// oldest attempt leaves the window when the limiter knows it, otherwise the
+// longest it may take, the window itself
+func (h *ResetHandler) retryAfter(err error) time.Duration {
+	var rateErr *apperrors.RateLimitError
+	if errors.As(err, &rateErr) {
+		return rateErr.RetryAfter
+	}
+	if h.cfg == nil {
+		return 0
+	}
+	return h.cfg.ResetLimitWindow
 }
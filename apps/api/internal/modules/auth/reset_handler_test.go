@@ -11,9 +11,11 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/email"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,25 +30,19 @@ func setupResetHandlerTest(t *testing.T) (*ResetHandler, sqlmock.Sqlmock, *gin.E
 	log := logger.New()
 	cfg := &config.Config{
 		ResetPasswordURL: "http://localhost:3000/reset-password",
+		ResetTokenTTL:    config.DefaultResetTokenTTL,
+		ResetLimitWindow: config.DefaultResetLimitWindow,
 	}
 
-	emailCfg := email.Config{
-		SMTPHost:     "smtp.test.com",
-		SMTPPort:     465,
-		SMTPUsername: "test@test.com",
-		SMTPPassword: "password",
-		FromAddress:  "noreply@test.com",
-		FromName:     "Test",
-	}
-
-	emailService, err := email.NewService(emailCfg, log)
+	emailService, err := email.NewService(email.Config{Driver: email.DriverMemory}, log)
 	require.NoError(t, err)
 
-	rateLimiter := middleware.NewRateLimiter(db, log)
-	resetService := NewResetService(db, cfg, log, emailService, rateLimiter)
+	rateLimiter := middleware.NewRateLimiter(&database.DB{DB: db}, log, middleware.DefaultRateLimitConfig())
+	resetService := NewResetService(&database.DB{DB: db}, cfg, log, emailService, rateLimiter, email.NewOutbox(db, log))
 	handler := NewResetHandler(cfg, log, resetService)
 
 	router := gin.New()
+	router.Use(middleware.Locale(nil))
 
 	cleanup := func() {
 		db.Close()
@@ -55,6 +51,15 @@ func setupResetHandlerTest(t *testing.T) (*ResetHandler, sqlmock.Sqlmock, *gin.E
 	return handler, mock, router, cleanup
 }
 
+// decodeErrorResponse decodes an error body, including the machine-readable code
+func decodeErrorResponse(t *testing.T, w *httptest.ResponseRecorder) response.Response {
+	t.Helper()
+	var resp response.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "error", resp.Status)
+	return resp
+}
+
 func TestForgotPassword_Success(t *testing.T) {
 	handler, mock, router, cleanup := setupResetHandlerTest(t)
 	defer cleanup()
@@ -77,9 +82,10 @@ func TestForgotPassword_Success(t *testing.T) {
 	mock.ExpectExec("INSERT INTO password_reset_attempts").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// User not found - should still return success
-	mock.ExpectQuery("SELECT id, email FROM users").
-		WillReturnError(sql.ErrNoRows)
+	// The email is queued whether or not the account exists
+	mock.ExpectExec("INSERT INTO email_outbox").
+		WithArgs(OutboxPasswordReset, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	req := httptest.NewRequest(http.MethodPost, "/forgot-password", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -113,6 +119,11 @@ func TestForgotPassword_InvalidEmail(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	resp := decodeErrorResponse(t, w)
+	assert.Equal(t, response.CodeValidationFailed, resp.Code)
+	assert.Equal(t, map[string]interface{}{
+		"fields": map[string]interface{}{"email": "Некорректный email"},
+	}, resp.Details)
 }
 
 func TestForgotPassword_MissingEmail(t *testing.T) {
@@ -147,6 +158,9 @@ func TestForgotPassword_RateLimitExceeded(t *testing.T) {
 	// Rate limit exceeded
 	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	// The window frees up when the oldest attempt, 20 minutes old, leaves it
+	mock.ExpectQuery("SELECT MIN\\(attempted_at\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now().Add(-20 * time.Minute)))
 
 	req := httptest.NewRequest(http.MethodPost, "/forgot-password", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
@@ -155,6 +169,11 @@ func TestForgotPassword_RateLimitExceeded(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2400", w.Header().Get("Retry-After"))
+	resp := decodeErrorResponse(t, w)
+	assert.Equal(t, response.CodeRateLimited, resp.Code)
+	assert.Equal(t, map[string]interface{}{"retry_after_seconds": float64(2400)}, resp.Details)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestResetPasswordHandler_Success(t *testing.T) {
@@ -169,7 +188,7 @@ func TestResetPasswordHandler_Success(t *testing.T) {
 
 	requestBody := map[string]string{
 		"token":    plainToken,
-		"password": "NewPass123!@#",
+		"password": "Kettlebell#Row42",
 	}
 	body, _ := json.Marshal(requestBody)
 
@@ -185,6 +204,11 @@ func TestResetPasswordHandler_Success(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -201,19 +225,13 @@ func TestResetPasswordHandler_Success(t *testing.T) {
 	// Commit transaction
 	mock.ExpectCommit()
 
-	// Get user email for confirmation
-	mock.ExpectQuery("SELECT email FROM users").
-		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
-
 	req := httptest.NewRequest(http.MethodPost, "/reset-password", bytes.NewBuffer(body))
 	req.Header.Set("Content-Type", "application/json")
 	w := httptest.NewRecorder()
 
 	router.ServeHTTP(w, req)
 
-	// Will return 500 because email sending fails, but that's expected in test
-	// The important part is that the database operations succeeded
+	assert.Equal(t, http.StatusOK, w.Code)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -228,7 +246,7 @@ func TestResetPasswordHandler_InvalidToken(t *testing.T) {
 
 	requestBody := map[string]string{
 		"token":    plainToken,
-		"password": "NewPass123!@#",
+		"password": "Kettlebell#Row42",
 	}
 	body, _ := json.Marshal(requestBody)
 
@@ -244,6 +262,7 @@ func TestResetPasswordHandler_InvalidToken(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, response.CodeResetTokenInvalid, decodeErrorResponse(t, w).Code)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -270,6 +289,65 @@ func TestResetPasswordHandler_WeakPassword(t *testing.T) {
 
 	// Should fail at validation level
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, response.CodeValidationFailed, decodeErrorResponse(t, w).Code)
+}
+
+func TestResetPasswordHandler_Localized(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		password       string
+		wantCode       string
+		wantMessage    string
+	}{
+		{"invalid token in English", "en-US,en;q=0.9", "Kettlebell#Row42", response.CodeResetTokenInvalid,
+			"The reset link is invalid or has expired. Please request a new one."},
+		{"invalid token falls back to Russian", "de-DE,de;q=0.9", "Kettlebell#Row42", response.CodeResetTokenInvalid,
+			"Неверная или истекшая ссылка для сброса. Запросите новую."},
+		{"weak password in English", "en", "kettlebell#row42", response.CodePasswordTooWeak,
+			"The password does not meet the requirements: The password must contain at least one uppercase letter"},
+		{"weak password in Russian", "", "kettlebell#row42", response.CodePasswordTooWeak,
+			"Пароль не соответствует требованиям: Пароль должен содержать хотя бы одну заглавную букву"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock, router, cleanup := setupResetHandlerTest(t)
+			defer cleanup()
+
+			router.POST("/reset-password", handler.ResetPassword)
+
+			plainToken, hashedToken, _ := NewTokenGenerator().GenerateToken()
+			body, _ := json.Marshal(map[string]string{"token": plainToken, "password": tt.password})
+
+			if tt.wantCode == response.CodeResetTokenInvalid {
+				mock.ExpectQuery("SELECT (.+) FROM reset_tokens").
+					WithArgs(hashedToken).
+					WillReturnError(sql.ErrNoRows)
+			} else {
+				mock.ExpectQuery("SELECT (.+) FROM reset_tokens").
+					WithArgs(hashedToken).
+					WillReturnRows(sqlmock.NewRows([]string{
+						"id", "user_id", "token_hash", "created_at", "expires_at", "used_at", "ip_address", "user_agent",
+					}).AddRow(1, int64(123), hashedToken, time.Now(), time.Now().Add(time.Hour), nil, "192.168.1.1", "test-agent"))
+				mock.ExpectQuery("SELECT u.email").
+					WithArgs(int64(123)).
+					WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/reset-password", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept-Language", tt.acceptLanguage)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			resp := decodeErrorResponse(t, w)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, tt.wantMessage, resp.Message)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
 }
 
 func TestResetPasswordHandler_MissingFields(t *testing.T) {
@@ -285,7 +363,7 @@ func TestResetPasswordHandler_MissingFields(t *testing.T) {
 		{
 			name: "Missing token",
 			body: map[string]string{
-				"password": "NewPass123!@#",
+				"password": "Kettlebell#Row42",
 			},
 		},
 		{
@@ -366,6 +444,7 @@ func TestValidateResetToken_Invalid(t *testing.T) {
 
 	// Handler returns 400 for invalid tokens
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, response.CodeResetTokenInvalid, decodeErrorResponse(t, w).Code)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -381,6 +460,7 @@ func TestValidateResetToken_MissingToken(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, response.CodeValidationFailed, decodeErrorResponse(t, w).Code)
 }
 
 func TestValidateResetToken_Expired(t *testing.T) {
@@ -416,5 +496,6 @@ func TestValidateResetToken_Expired(t *testing.T) {
 
 	// Handler returns 400 for expired tokens
 	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, response.CodeResetTokenExpired, decodeErrorResponse(t, w).Code)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -3,26 +3,36 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
 	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/middleware"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/validation"
 )
 
 // ResetService handles password reset operations
 type ResetService struct {
-	db           *sql.DB
+	db           *database.DB
 	cfg          *config.Config
 	log          *logger.Logger
 	emailService *email.Service
 	rateLimiter  *middleware.RateLimiter
+	outbox       *email.Outbox
 	tokenGen     *TokenGenerator
 	passwordVal  *PasswordValidator
+	audit        audit.ServiceInterface
 }
 
 // ResetTokenData represents a reset token record
@@ -37,45 +47,75 @@ type ResetTokenData struct {
 	UserAgent string
 }
 
-// NewResetService creates a new password reset service
+// OutboxPasswordReset is the email outbox kind of reset emails
+const OutboxPasswordReset = "password_reset"
+
+// resetRequest is the outbox payload of a password reset request
+type resetRequest struct {
+	Email     string      `json:"email"`
+	IPAddress string      `json:"ip_address"`
+	UserAgent string      `json:"user_agent"`
+	Locale    i18n.Locale `json:"locale,omitempty"`
+}
+
+// NewResetService creates a new password reset service and registers its
+// reset email handler with the outbox
 func NewResetService(
-	db *sql.DB,
+	db *database.DB,
 	cfg *config.Config,
 	log *logger.Logger,
 	emailService *email.Service,
 	rateLimiter *middleware.RateLimiter,
+	outbox *email.Outbox,
 ) *ResetService {
-	return &ResetService{
+	rs := &ResetService{
 		db:           db,
 		cfg:          cfg,
 		log:          log,
 		emailService: emailService,
 		rateLimiter:  rateLimiter,
-		tokenGen:     NewTokenGenerator(),
-		passwordVal:  NewPasswordValidator(),
+		outbox:       outbox,
+		tokenGen:     NewTokenGenerator(WithTokenBytes(cfg.ResetTokenBytes)),
+		passwordVal:  newPasswordValidator(cfg, log),
+		audit:        audit.NewService(db.DB, log),
 	}
+	outbox.Handle(OutboxPasswordReset, rs.handleResetEmail)
+	return rs
 }
 
 // RequestPasswordReset initiates a password reset request
-// Returns generic response regardless of email existence (security)
-func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail string, ipAddress string, userAgent string) error {
+// Returns generic response regardless of email existence (security).
+// Whether the account exists is only checked by the outbox worker, so the
+// request does the same work, and takes the same time, in both cases.
+func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail string, ipAddress string, userAgent string, locale i18n.Locale) error {
+	userEmail = validation.NormalizeEmail(userEmail)
+
 	// Check rate limits first
 	if err := rs.rateLimiter.CheckEmailRateLimit(ctx, userEmail); err != nil {
+		// The limiter could not decide and its policy is to fail closed
+		if !errors.Is(err, apperrors.ErrRateLimited) {
+			return fmt.Errorf("email rate limit: %w", err)
+		}
 		rs.log.LogSecurityEvent("password_reset_rate_limit", "high", map[string]any{
-			"email":      userEmail,
-			"ip_address": ipAddress,
-			"reason":     "email_rate_limit",
+			"email":       userEmail,
+			"ip_address":  ipAddress,
+			"remote_addr": audit.RemoteAddrFromContext(ctx),
+			"reason":      "email_rate_limit",
 		})
-		return fmt.Errorf("email rate limit: %w", apperrors.ErrTooManyAttempts)
+		return fmt.Errorf("email rate limit: %w: %w", apperrors.ErrTooManyAttempts, err)
 	}
 
 	if err := rs.rateLimiter.CheckIPRateLimit(ctx, ipAddress); err != nil {
+		if !errors.Is(err, apperrors.ErrRateLimited) {
+			return fmt.Errorf("ip rate limit: %w", err)
+		}
 		rs.log.LogSecurityEvent("password_reset_rate_limit", "high", map[string]any{
-			"email":      userEmail,
-			"ip_address": ipAddress,
-			"reason":     "ip_rate_limit",
+			"email":       userEmail,
+			"ip_address":  ipAddress,
+			"remote_addr": audit.RemoteAddrFromContext(ctx),
+			"reason":      "ip_rate_limit",
 		})
-		return fmt.Errorf("ip rate limit: %w", apperrors.ErrTooManyAttempts)
+		return fmt.Errorf("ip rate limit: %w: %w", apperrors.ErrTooManyAttempts, err)
 	}
 
 	// Record the attempt
@@ -84,31 +124,88 @@ func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail stri
 		// Continue anyway - don't fail the request
 	}
 
-	// Check if user exists
+	return rs.enqueueResetEmail(ctx, resetRequest{
+		Email:     userEmail,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+		Locale:    locale,
+	})
+}
+
+// ResetURL returns the reset link for token. The token replaces
+// config.ResetURLTokenPlaceholder in base, or is appended as the token
+// query parameter when base has no placeholder.
+func ResetURL(base, token string) string {
+	if strings.Contains(base, config.ResetURLTokenPlaceholder) {
+		return strings.ReplaceAll(base, config.ResetURLTokenPlaceholder, url.PathEscape(token))
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + url.Values{"token": {token}}.Encode()
+}
+
+// recipientLocale is the language of an email: the one saved in the
+// recipient's profile, or else the one of the request that triggered it
+func recipientLocale(profileLanguage string, requested i18n.Locale) i18n.Locale {
+	if locale, ok := i18n.Match(profileLanguage); ok {
+		return locale
+	}
+	return i18n.Parse(string(requested))
+}
+
+// enqueueResetEmail queues the reset email of a request that passed the
+// rate limits
+func (rs *ResetService) enqueueResetEmail(ctx context.Context, req resetRequest) error {
+	if err := rs.outbox.Enqueue(ctx, OutboxPasswordReset, req); err != nil {
+		rs.log.WithError(err).Error("Failed to queue reset email",
+			"email", req.Email,
+		)
+		return fmt.Errorf("failed to process request")
+	}
+	return nil
+}
+
+// handleResetEmail delivers a reset email queued in the outbox
+func (rs *ResetService) handleResetEmail(ctx context.Context, payload json.RawMessage) error {
+	var req resetRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return fmt.Errorf("failed to decode reset request: %w", err)
+	}
+	return rs.sendResetEmail(ctx, req)
+}
+
+// sendResetEmail issues a reset token for the account with the request's
+// email and emails the link. Unknown emails are skipped without an error.
+func (rs *ResetService) sendResetEmail(ctx context.Context, req resetRequest) error {
+	// Check if user exists; LOWER(email) is served by idx_users_email_lower
 	var userID int64
-	var existingEmail string
-	query := `SELECT id, email FROM users WHERE email = $1`
-	err := rs.db.QueryRowContext(ctx, query, userEmail).Scan(&userID, &existingEmail)
+	var existingEmail, language string
+	query := `
+		SELECT u.id, u.email, COALESCE(s.language, '')
+		FROM users u
+		LEFT JOIN user_settings s ON s.user_id = u.id
+		WHERE LOWER(u.email) = $1
+	`
+	err := rs.db.QueryRowCtx(ctx, query, req.Email).Scan(&userID, &existingEmail, &language)
 
 	if err == sql.ErrNoRows {
-		// User doesn't exist - return success anyway (prevent email enumeration)
 		rs.log.Info("Password reset requested for non-existent email",
-			"email", userEmail,
-			"ip_address", ipAddress,
+			"email", req.Email,
+			"ip_address", req.IPAddress,
 		)
-		// Sleep to make timing consistent
-		time.Sleep(100 * time.Millisecond)
 		return nil
 	}
 
 	if err != nil {
 		rs.log.WithError(err).Error("Failed to query user",
-			"email", userEmail,
+			"email", req.Email,
 		)
-		return fmt.Errorf("failed to process request")
+		return fmt.Errorf("failed to look up user: %w", err)
 	}
 
-	// Invalidate all previous tokens for this user
+	// Make room for the new token among the user's unused ones
 	if err := rs.invalidateUserTokens(ctx, userID); err != nil {
 		rs.log.WithError(err).Error("Failed to invalidate previous tokens",
 			"user_id", userID,
@@ -122,11 +219,11 @@ func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail stri
 		rs.log.WithError(err).Error("Failed to generate reset token",
 			"user_id", userID,
 		)
-		return fmt.Errorf("failed to generate token")
+		return fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	// Store token in database
-	expiresAt := time.Now().Add(1 * time.Hour)
+	expiresAt := time.Now().Add(rs.cfg.ResetTokenTTL)
 	insertQuery := `
 		INSERT INTO reset_tokens (user_id, token_hash, created_at, expires_at, ip_address, user_agent)
 		VALUES ($1, $2, NOW(), $3, $4, $5)
@@ -134,16 +231,15 @@ func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail stri
 	`
 
 	var tokenID int64
-	err = rs.db.QueryRowContext(ctx, insertQuery, userID, hashedToken, expiresAt, ipAddress, userAgent).Scan(&tokenID)
+	err = rs.db.QueryRowCtx(ctx, insertQuery, userID, hashedToken, expiresAt, req.IPAddress, req.UserAgent).Scan(&tokenID)
 	if err != nil {
 		rs.log.WithError(err).Error("Failed to store reset token",
 			"user_id", userID,
 		)
-		return fmt.Errorf("failed to store token")
+		return fmt.Errorf("failed to store token: %w", err)
 	}
 
-	// Build reset URL
-	resetURL := fmt.Sprintf("%s?token=%s", rs.cfg.ResetPasswordURL, plainToken)
+	resetURL := ResetURL(rs.cfg.ResetPasswordURL, plainToken)
 
 	// Send email
 	emailData := email.ResetEmailData{
@@ -151,6 +247,7 @@ func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail stri
 		ResetURL:       resetURL,
 		ExpirationTime: expiresAt,
 		SupportEmail:   "support@burcev.team",
+		Locale:         recipientLocale(language, req.Locale),
 	}
 
 	err = rs.emailService.SendPasswordResetEmail(ctx, emailData)
@@ -162,19 +259,19 @@ func (rs *ResetService) RequestPasswordReset(ctx context.Context, userEmail stri
 
 		// Invalidate the token since email failed
 		deleteQuery := `DELETE FROM reset_tokens WHERE id = $1`
-		if _, delErr := rs.db.ExecContext(ctx, deleteQuery, tokenID); delErr != nil {
+		if _, delErr := rs.db.ExecCtx(ctx, deleteQuery, tokenID); delErr != nil {
 			rs.log.WithError(delErr).Error("Failed to delete token after email failure",
 				"token_id", tokenID,
 			)
 		}
 
-		return fmt.Errorf("failed to send email")
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
 	rs.log.Info("Password reset email sent successfully",
 		"user_id", userID,
 		"email", existingEmail,
-		"ip_address", ipAddress,
+		"ip_address", req.IPAddress,
 	)
 
 	return nil
@@ -195,7 +292,7 @@ func (rs *ResetService) ValidateResetToken(ctx context.Context, plainToken strin
 	var tokenData ResetTokenData
 	var usedAt sql.NullTime
 
-	err := rs.db.QueryRowContext(ctx, query, hashedToken).Scan(
+	err := rs.db.QueryRowCtx(ctx, query, hashedToken).Scan(
 		&tokenData.ID,
 		&tokenData.UserID,
 		&tokenData.TokenHash,
@@ -210,6 +307,9 @@ func (rs *ResetService) ValidateResetToken(ctx context.Context, plainToken strin
 		rs.log.Warn("Invalid reset token attempted",
 			"token_hash", hashedToken[:10]+"...",
 		)
+		rs.log.LogSecurityEvent("password_reset_invalid_token", "medium", map[string]any{
+			"reason": "unknown_token",
+		})
 		return nil, fmt.Errorf("lookup: %w", apperrors.ErrTokenInvalid)
 	}
 
@@ -228,6 +328,10 @@ func (rs *ResetService) ValidateResetToken(ctx context.Context, plainToken strin
 			"token_id", tokenData.ID,
 			"user_id", tokenData.UserID,
 		)
+		rs.log.LogSecurityEvent("password_reset_invalid_token", "medium", map[string]any{
+			"reason":  "used_token",
+			"user_id": tokenData.UserID,
+		})
 		return nil, fmt.Errorf("already used: %w", apperrors.ErrTokenInvalid)
 	}
 
@@ -241,7 +345,7 @@ func (rs *ResetService) ValidateResetToken(ctx context.Context, plainToken strin
 
 		// Clean up expired token
 		deleteQuery := `DELETE FROM reset_tokens WHERE id = $1`
-		if _, err := rs.db.ExecContext(ctx, deleteQuery, tokenData.ID); err != nil {
+		if _, err := rs.db.ExecCtx(ctx, deleteQuery, tokenData.ID); err != nil {
 			rs.log.WithError(err).Error("Failed to delete expired token",
 				"token_id", tokenData.ID,
 			)
@@ -254,25 +358,45 @@ func (rs *ResetService) ValidateResetToken(ctx context.Context, plainToken strin
 }
 
 // ResetPassword resets a user's password using a valid token
-func (rs *ResetService) ResetPassword(ctx context.Context, plainToken string, newPassword string, ipAddress string) error {
+func (rs *ResetService) ResetPassword(ctx context.Context, plainToken string, newPassword string, ipAddress string, locale i18n.Locale) error {
 	// Validate token
 	tokenData, err := rs.ValidateResetToken(ctx, plainToken)
 	if err != nil {
 		return err
 	}
 
+	// Get user email, checked against the password, and the opt-in flag
+	// and language for the confirmation
+	var userEmail, language string
+	var notifyEnabled bool
+	emailQuery := `
+		SELECT u.email, COALESCE(ep.password_changed_email, TRUE), COALESCE(s.language, '')
+		FROM users u
+		LEFT JOIN email_preferences ep ON ep.user_id = u.id
+		LEFT JOIN user_settings s ON s.user_id = u.id
+		WHERE u.id = $1
+	`
+	emailErr := rs.db.QueryRowCtx(ctx, emailQuery, tokenData.UserID).Scan(&userEmail, &notifyEnabled, &language)
+	if emailErr != nil {
+		rs.log.WithError(emailErr).Error("Failed to get user email for confirmation",
+			"user_id", tokenData.UserID,
+		)
+		// Don't fail the request - the email only adds a password check
+		// and the confirmation
+	}
+
 	// Validate password
-	validationResult := rs.passwordVal.Validate(newPassword)
+	validationResult := rs.passwordVal.Validate(newPassword, UserContext{Email: userEmail})
 	if !validationResult.Valid {
 		rs.log.Warn("Invalid password provided for reset",
 			"user_id", tokenData.UserID,
 			"errors", validationResult.Errors,
 		)
-		return fmt.Errorf("password does not meet requirements: %v", validationResult.Errors)
+		return validationResult.Err()
 	}
 
 	// Hash password with bcrypt
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := password.Hash(newPassword, rs.cfg.BcryptCost)
 	if err != nil {
 		rs.log.WithError(err).Error("Failed to hash password",
 			"user_id", tokenData.UserID,
@@ -280,58 +404,44 @@ func (rs *ResetService) ResetPassword(ctx context.Context, plainToken string, ne
 		return fmt.Errorf("failed to hash password")
 	}
 
-	// Start transaction
-	tx, err := rs.db.BeginTx(ctx, nil)
-	if err != nil {
-		rs.log.WithError(err).Error("Failed to start transaction")
-		return fmt.Errorf("failed to start transaction")
-	}
-	defer tx.Rollback()
-
-	// Update password
-	updateQuery := `
-		UPDATE users
-		SET password = $1, password_changed_at = NOW()
-		WHERE id = $2
-	`
-
-	result, err := tx.ExecContext(ctx, updateQuery, string(hashedPassword), tokenData.UserID)
-	if err != nil {
-		rs.log.WithError(err).Error("Failed to update password",
-			"user_id", tokenData.UserID,
-		)
-		return fmt.Errorf("failed to update password")
-	}
+	// Update the password and spend the token together
+	err = database.WithTx(ctx, rs.db, func(tx *sql.Tx) error {
+		updateQuery := `
+			UPDATE users
+			SET password = $1, password_changed_at = NOW()
+			WHERE id = $2
+		`
+
+		result, err := tx.ExecContext(ctx, updateQuery, hashedPassword, tokenData.UserID)
+		if err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil || rowsAffected == 0 {
-		rs.log.Error("No rows affected when updating password",
-			"user_id", tokenData.UserID,
-		)
-		return fmt.Errorf("failed to update password")
-	}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("failed to update password: no rows affected")
+		}
 
-	// Mark token as used
-	markUsedQuery := `
-		UPDATE reset_tokens
-		SET used_at = NOW()
-		WHERE id = $1
-	`
+		markUsedQuery := `
+			UPDATE reset_tokens
+			SET used_at = NOW()
+			WHERE id = $1
+		`
 
-	_, err = tx.ExecContext(ctx, markUsedQuery, tokenData.ID)
+		if _, err := tx.ExecContext(ctx, markUsedQuery, tokenData.ID); err != nil {
+			return fmt.Errorf("failed to mark token as used: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		rs.log.WithError(err).Error("Failed to mark token as used",
-			"token_id", tokenData.ID,
-		)
-		return fmt.Errorf("failed to mark token as used")
-	}
-
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		rs.log.WithError(err).Error("Failed to commit transaction",
+		rs.log.WithError(err).Error("Failed to reset password",
 			"user_id", tokenData.UserID,
+			"token_id", tokenData.ID,
 		)
-		return fmt.Errorf("failed to commit transaction")
+		return err
 	}
 
 	// Invalidate all user sessions (JWT tokens)
@@ -341,22 +451,21 @@ func (rs *ResetService) ResetPassword(ctx context.Context, plainToken string, ne
 		"user_id", tokenData.UserID,
 	)
 
-	// Get user email for confirmation
-	var userEmail string
-	emailQuery := `SELECT email FROM users WHERE id = $1`
-	err = rs.db.QueryRowContext(ctx, emailQuery, tokenData.UserID).Scan(&userEmail)
-	if err != nil {
-		rs.log.WithError(err).Error("Failed to get user email for confirmation",
+	switch {
+	case emailErr != nil:
+		// Already logged; no address to confirm to
+	case !notifyEnabled:
+		rs.log.Info("Password changed email skipped - user opted out",
 			"user_id", tokenData.UserID,
 		)
-		// Don't fail the request - password was already changed
-	} else {
+	default:
 		// Send confirmation email
 		emailData := email.PasswordChangedEmailData{
 			UserEmail:    userEmail,
 			ChangedAt:    time.Now(),
 			IPAddress:    ipAddress,
 			SupportEmail: "support@burcev.team",
+			Locale:       recipientLocale(language, locale),
 		}
 
 		if err := rs.emailService.SendPasswordChangedEmail(ctx, emailData); err != nil {
@@ -368,8 +477,14 @@ func (rs *ResetService) ResetPassword(ctx context.Context, plainToken string, ne
 	}
 
 	rs.log.LogSecurityEvent("password_reset_completed", "info", map[string]any{
-		"user_id":    tokenData.UserID,
-		"ip_address": ipAddress,
+		"user_id":     tokenData.UserID,
+		"ip_address":  ipAddress,
+		"remote_addr": audit.RemoteAddrFromContext(ctx),
+	})
+	rs.audit.Record(ctx, audit.Entry{
+		UserID:  &tokenData.UserID,
+		ActorIP: ipAddress,
+		Action:  audit.ActionPasswordResetCompleted,
 	})
 
 	return nil
@@ -393,13 +508,26 @@ func (rs *ResetService) InvalidateUserSessions(ctx context.Context, userID int64
 
 // invalidateUserTokens invalidates all previous reset tokens for a user
 func (rs *ResetService) invalidateUserTokens(ctx context.Context, userID int64) error {
+	// Keep the newest unused tokens so that, with the new one, at most
+	// ResetMaxActiveTokens links work; the default of 1 keeps none
+	keep := rs.cfg.ResetMaxActiveTokens - 1
+	if keep < 0 {
+		keep = 0
+	}
+
 	query := `
 		DELETE FROM reset_tokens
 		WHERE user_id = $1
 		AND used_at IS NULL
+		AND id NOT IN (
+			SELECT id FROM reset_tokens
+			WHERE user_id = $1 AND used_at IS NULL AND expires_at > NOW()
+			ORDER BY created_at DESC
+			LIMIT $2
+		)
 	`
 
-	result, err := rs.db.ExecContext(ctx, query, userID)
+	result, err := rs.db.ExecCtx(ctx, query, userID, keep)
 	if err != nil {
 		return err
 	}
@@ -423,7 +551,7 @@ func (rs *ResetService) CleanupExpiredTokens(ctx context.Context) (int, error) {
 		AND used_at IS NULL
 	`
 
-	result, err := rs.db.ExecContext(ctx, query)
+	result, err := rs.db.ExecCtx(ctx, query)
 	if err != nil {
 		rs.log.WithError(err).Error("Failed to cleanup expired tokens")
 		return 0, err
@@ -10,7 +10,10 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/middleware"
 	"github.com/stretchr/testify/assert"
@@ -24,23 +27,15 @@ func setupResetServiceTest(t *testing.T) (*ResetService, sqlmock.Sqlmock, func()
 	log := logger.New()
 	cfg := &config.Config{
 		ResetPasswordURL: "http://localhost:3000/reset-password",
+		ResetTokenTTL:    config.DefaultResetTokenTTL,
 	}
 
-	emailCfg := email.Config{
-		SMTPHost:     "smtp.test.com",
-		SMTPPort:     465,
-		SMTPUsername: "test@test.com",
-		SMTPPassword: "password",
-		FromAddress:  "noreply@test.com",
-		FromName:     "Test",
-	}
-
-	emailService, err := email.NewService(emailCfg, log)
+	emailService, err := email.NewService(email.Config{Driver: email.DriverMemory}, log)
 	require.NoError(t, err)
 
-	rateLimiter := middleware.NewRateLimiter(db, log)
+	rateLimiter := middleware.NewRateLimiter(&database.DB{DB: db}, log, middleware.DefaultRateLimitConfig())
 
-	service := NewResetService(db, cfg, log, emailService, rateLimiter)
+	service := NewResetService(&database.DB{DB: db}, cfg, log, emailService, rateLimiter, email.NewOutbox(db, log))
 
 	cleanup := func() {
 		db.Close()
@@ -49,6 +44,14 @@ func setupResetServiceTest(t *testing.T) (*ResetService, sqlmock.Sqlmock, func()
 	return service, mock, cleanup
 }
 
+// sentEmails returns the in-memory sender backing the test reset service
+func sentEmails(t *testing.T, service *ResetService) *email.MemorySender {
+	t.Helper()
+	sender, ok := service.emailService.Sender().(*email.MemorySender)
+	require.True(t, ok, "reset service tests must use the memory email driver")
+	return sender
+}
+
 func TestNewResetService(t *testing.T) {
 	service, _, cleanup := setupResetServiceTest(t)
 	defer cleanup()
@@ -60,10 +63,11 @@ func TestNewResetService(t *testing.T) {
 
 func TestValidateResetToken(t *testing.T) {
 	tests := []struct {
-		name          string
-		setupMock     func(sqlmock.Sqlmock, string)
-		expectError   bool
-		errorContains string
+		name           string
+		setupMock      func(sqlmock.Sqlmock, string)
+		expectError    bool
+		errorContains  string
+		securityEvents []string
 	}{
 		{
 			name: "Valid unused token",
@@ -86,8 +90,9 @@ func TestValidateResetToken(t *testing.T) {
 					WithArgs(hashedToken).
 					WillReturnError(sql.ErrNoRows)
 			},
-			expectError:   true,
-			errorContains: "invalid token",
+			expectError:    true,
+			errorContains:  "invalid token",
+			securityEvents: []string{"password_reset_invalid_token"},
 		},
 		{
 			name: "Token already used",
@@ -102,8 +107,9 @@ func TestValidateResetToken(t *testing.T) {
 					WithArgs(hashedToken).
 					WillReturnRows(rows)
 			},
-			expectError:   true,
-			errorContains: "invalid token",
+			expectError:    true,
+			errorContains:  "invalid token",
+			securityEvents: []string{"password_reset_invalid_token"},
 		},
 		{
 			name: "Token expired",
@@ -130,6 +136,9 @@ func TestValidateResetToken(t *testing.T) {
 			service, mock, cleanup := setupResetServiceTest(t)
 			defer cleanup()
 
+			recorder := &logger.SecurityRecorder{}
+			service.log.AddSecuritySink(recorder)
+
 			plainToken := "test-token-123"
 			hashedToken := service.tokenGen.HashToken(plainToken)
 
@@ -146,6 +155,8 @@ func TestValidateResetToken(t *testing.T) {
 				assert.NotNil(t, tokenData)
 				assert.Equal(t, int64(123), tokenData.UserID)
 			}
+			// Expired links are not attacks
+			assert.Equal(t, tt.securityEvents, recorder.Names())
 
 			assert.NoError(t, mock.ExpectationsWereMet())
 		})
@@ -159,7 +170,7 @@ func TestInvalidateUserTokens(t *testing.T) {
 	userID := int64(123)
 
 	mock.ExpectExec("DELETE FROM reset_tokens").
-		WithArgs(userID).
+		WithArgs(userID, 0).
 		WillReturnResult(sqlmock.NewResult(0, 2))
 
 	err := service.invalidateUserTokens(context.Background(), userID)
@@ -168,6 +179,41 @@ func TestInvalidateUserTokens(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestInvalidateUserTokens_KeepsConcurrentTokens(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+	service.cfg.ResetMaxActiveTokens = 3
+
+	// Two older links stay usable next to the one about to be sent
+	mock.ExpectExec("DELETE FROM reset_tokens .+ ORDER BY created_at DESC\\s+LIMIT \\$2").
+		WithArgs(int64(123), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.invalidateUserTokens(context.Background(), 123)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetURL(t *testing.T) {
+	tests := []struct {
+		name string
+		base string
+		want string
+	}{
+		{name: "appends query parameter", base: "https://burcev.team/reset-password", want: "https://burcev.team/reset-password?token=abc123"},
+		{name: "extends existing query", base: "https://burcev.team/auth?step=reset", want: "https://burcev.team/auth?step=reset&token=abc123"},
+		{name: "path placeholder", base: "https://burcev.team/reset/{token}", want: "https://burcev.team/reset/abc123"},
+		{name: "query placeholder", base: "https://burcev.team/reset?t={token}&src=email", want: "https://burcev.team/reset?t=abc123&src=email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ResetURL(tt.base, "abc123"))
+		})
+	}
+}
+
 func TestCleanupExpiredTokens(t *testing.T) {
 	service, mock, cleanup := setupResetServiceTest(t)
 	defer cleanup()
@@ -182,55 +228,121 @@ func TestCleanupExpiredTokens(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestRequestPasswordReset_NonExistentUser(t *testing.T) {
+func TestRequestPasswordReset_QueuesEmail(t *testing.T) {
 	service, mock, cleanup := setupResetServiceTest(t)
 	defer cleanup()
 
-	email := "nonexistent@example.com"
+	email := "user@example.com"
 	ipAddress := "192.168.1.1"
 
-	// Rate limit checks
+	// Limits and the queued request use the normalized email
 	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-		WithArgs(email).
+		WithArgs(email, float64(3600)).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
 	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-		WithArgs(ipAddress).
+		WithArgs(ipAddress, float64(3600)).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
 
-	// Record attempt
 	mock.ExpectExec("INSERT INTO password_reset_attempts").
 		WithArgs(email, ipAddress).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// User lookup - not found
-	mock.ExpectQuery("SELECT id, email FROM users").
+	// Nothing reveals whether the account exists: no lookup, only the outbox
+	mock.ExpectExec("INSERT INTO email_outbox").
+		WithArgs(OutboxPasswordReset, []byte(`{"email":"user@example.com","ip_address":"192.168.1.1","user_agent":"test-agent","locale":"en"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := service.RequestPasswordReset(context.Background(), "  User@Example.COM ", ipAddress, "test-agent", i18n.EN)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, sentEmails(t, service).Messages())
+}
+
+func TestRequestPasswordReset_QueueFailure(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec("INSERT INTO password_reset_attempts").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO email_outbox").
+		WillReturnError(sql.ErrConnDone)
+
+	err := service.RequestPasswordReset(context.Background(), "user@example.com", "192.168.1.1", "test-agent", i18n.RU)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSendResetEmail_NonExistentUser(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	email := "nonexistent@example.com"
+
+	mock.ExpectQuery("SELECT u.id, u.email, .+ WHERE LOWER\\(u.email\\) = \\$1").
 		WithArgs(email).
 		WillReturnError(sql.ErrNoRows)
 
-	err := service.RequestPasswordReset(context.Background(), email, ipAddress, "test-agent")
+	err := service.handleResetEmail(context.Background(),
+		[]byte(`{"email":"nonexistent@example.com","ip_address":"192.168.1.1","user_agent":"test-agent"}`))
 
-	// Should succeed (no error) to prevent email enumeration
+	// Nothing to send; not a failure the outbox should retry
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, sentEmails(t, service).Messages())
 }
 
 func TestRequestPasswordReset_RateLimitExceeded(t *testing.T) {
 	service, mock, cleanup := setupResetServiceTest(t)
 	defer cleanup()
+	recorder := &logger.SecurityRecorder{}
+	service.log.AddSecuritySink(recorder)
 
 	email := "user@example.com"
 	ipAddress := "192.168.1.1"
 
 	// Email rate limit exceeded
 	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-		WithArgs(email).
+		WithArgs(email, float64(3600)).
 		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT MIN\\(attempted_at\\) FROM password_reset_attempts").
+		WithArgs(email, float64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now().Add(-15 * time.Minute)))
 
-	err := service.RequestPasswordReset(context.Background(), email, ipAddress, "test-agent")
+	err := service.RequestPasswordReset(context.Background(), email, ipAddress, "test-agent", i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "too many attempts")
+	// The limiter's retry time reaches the handler
+	var rateErr *apperrors.RateLimitError
+	require.ErrorAs(t, err, &rateErr)
+	assert.InDelta(t, 45*time.Minute, rateErr.RetryAfter, float64(time.Second))
+	// Reported by the limiter and by the reset service
+	assert.Equal(t, []string{"email_rate_limit_exceeded", "password_reset_rate_limit"}, recorder.Names())
+	assert.Equal(t, "email_rate_limit", recorder.Events()[1].Fields["reason"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRequestPasswordReset_RateLimitUnavailable(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	// The default policy fails closed: no email goes out, and the failure
+	// is not reported as the user exceeding the limit
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnError(sql.ErrConnDone)
+
+	err := service.RequestPasswordReset(context.Background(), "user@example.com", "192.168.1.1", "test-agent", i18n.RU)
+
+	assert.ErrorIs(t, err, middleware.ErrRateLimitUnavailable)
+	assert.NotErrorIs(t, err, apperrors.ErrTooManyAttempts)
+	assert.Empty(t, sentEmails(t, service).Messages())
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -245,7 +357,7 @@ func TestPasswordValidation(t *testing.T) {
 	}{
 		{
 			name:     "Valid password",
-			password: "Test123!@#",
+			password: "Orbit#Lantern7",
 			valid:    true,
 		},
 		{
@@ -282,54 +394,113 @@ func TestPasswordValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := service.passwordVal.Validate(tt.password)
+			result := service.passwordVal.Validate(tt.password, UserContext{})
 			assert.Equal(t, tt.valid, result.Valid)
 		})
 	}
 }
 
-func TestRequestPasswordReset_Success(t *testing.T) {
+func TestSendResetEmail_Success(t *testing.T) {
 	service, mock, cleanup := setupResetServiceTest(t)
 	defer cleanup()
 
-	userEmail := "user@example.com"
-	ipAddress := "192.168.1.1"
-	userAgent := "Mozilla/5.0"
+	// Accounts from before normalization keep their stored casing
+	storedEmail := "User@Example.com"
+	req := resetRequest{Email: "user@example.com", IPAddress: "192.168.1.1", UserAgent: "Mozilla/5.0"}
 
-	// Rate limit checks
-	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-		WithArgs(userEmail).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
-
-	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-		WithArgs(ipAddress).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
-
-	// Record attempt
-	mock.ExpectExec("INSERT INTO password_reset_attempts").
-		WithArgs(userEmail, ipAddress).
-		WillReturnResult(sqlmock.NewResult(1, 1))
-
-	// User lookup - found
-	mock.ExpectQuery("SELECT id, email FROM users").
-		WithArgs(userEmail).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(123, userEmail))
+	mock.ExpectQuery("SELECT u.id, u.email, .+ WHERE LOWER\\(u.email\\) = \\$1").
+		WithArgs(req.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "language"}).AddRow(123, storedEmail, ""))
 
 	// Invalidate old tokens
 	mock.ExpectExec("DELETE FROM reset_tokens").
-		WithArgs(int64(123)).
+		WithArgs(int64(123), 0).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
 	// Insert new token
 	mock.ExpectQuery("INSERT INTO reset_tokens").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	err := service.RequestPasswordReset(context.Background(), userEmail, ipAddress, userAgent)
+	err := service.sendResetEmail(context.Background(), req)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	messages := sentEmails(t, service).Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, storedEmail, messages[0].To)
+	assert.Equal(t, "Запрос на сброс пароля - BURCEV", messages[0].Subject)
+	assert.Contains(t, messages[0].HTMLBody, "http://localhost:3000/reset-password?token=")
+	assert.Contains(t, messages[0].HTMLBody, storedEmail)
+}
+
+func TestSendResetEmail_Locale(t *testing.T) {
+	tests := []struct {
+		name        string
+		profile     string
+		requested   i18n.Locale
+		wantSubject string
+		wantBody    string
+	}{
+		{"requested locale", "", i18n.EN, "Password reset request - BURCEV", "Reset password"},
+		{"profile wins over the request", "ru", i18n.EN, "Запрос на сброс пароля - BURCEV", "Сбросить пароль"},
+		{"unsupported profile language", "de", i18n.EN, "Password reset request - BURCEV", "Reset password"},
+		{"unsupported request", "", i18n.Locale("de"), "Запрос на сброс пароля - BURCEV", "Сбросить пароль"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock, cleanup := setupResetServiceTest(t)
+			defer cleanup()
+
+			req := resetRequest{Email: "user@example.com", IPAddress: "192.168.1.1", UserAgent: "Mozilla/5.0", Locale: tt.requested}
+
+			mock.ExpectQuery("SELECT u.id, u.email").
+				WithArgs(req.Email).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "language"}).AddRow(123, req.Email, tt.profile))
+			mock.ExpectExec("DELETE FROM reset_tokens").
+				WithArgs(int64(123), 0).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("INSERT INTO reset_tokens").
+				WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+			require.NoError(t, service.sendResetEmail(context.Background(), req))
+
+			messages := sentEmails(t, service).Messages()
+			require.Len(t, messages, 1)
+			assert.Equal(t, tt.wantSubject, messages[0].Subject)
+			assert.Contains(t, messages[0].HTMLBody, tt.wantBody)
+		})
+	}
+}
+
+func TestSendResetEmail_EmailFailureDeletesToken(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	sentEmails(t, service).FailWith(fmt.Errorf("smtp unavailable"))
+
+	userEmail := "user@example.com"
+
+	mock.ExpectQuery("SELECT u.id, u.email").
+		WithArgs(userEmail).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "language"}).AddRow(123, userEmail, ""))
+	mock.ExpectExec("DELETE FROM reset_tokens").
+		WithArgs(int64(123), 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("INSERT INTO reset_tokens").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
 
-	// Should fail because email service is not configured for real sending
-	// But we can verify the database operations were attempted
-	assert.Error(t, err) // Email sending will fail in test
+	// Token is removed so an undeliverable link can never be used
+	mock.ExpectExec("DELETE FROM reset_tokens WHERE id").
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.sendResetEmail(context.Background(), resetRequest{Email: userEmail, IPAddress: "192.168.1.1", UserAgent: "Mozilla/5.0"})
+
+	// Returned so the outbox retries the email
+	assert.Error(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, sentEmails(t, service).Messages())
 }
 
 func TestResetPassword_Success(t *testing.T) {
@@ -338,7 +509,7 @@ func TestResetPassword_Success(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -352,6 +523,11 @@ func TestResetPassword_Success(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -368,17 +544,60 @@ func TestResetPassword_Success(t *testing.T) {
 	// Commit transaction
 	mock.ExpectCommit()
 
-	// Get user email for confirmation
-	mock.ExpectQuery("SELECT email FROM users").
-		WithArgs(userID).
-		WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+	// Audit record
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(userID, ipAddress, "password_reset_completed", []byte("{}")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
-	// Should succeed even if email fails (password was already changed)
-	// Email failure is logged but doesn't fail the operation
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
+
+	messages := sentEmails(t, service).Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "user@example.com", messages[0].To)
+	assert.Equal(t, "Пароль изменен - BURCEV", messages[0].Subject)
+	assert.Contains(t, messages[0].HTMLBody, ipAddress)
+}
+
+func TestResetPassword_PasswordChangedEmailOptedOut(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	plainToken := "test-token-123"
+	hashedToken := service.tokenGen.HashToken(plainToken)
+	userID := int64(123)
+
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "token_hash", "created_at", "expires_at", "used_at", "ip_address", "user_agent",
+	}).AddRow(
+		1, userID, hashedToken, time.Now(), time.Now().Add(1*time.Hour), nil, "192.168.1.1", "test-agent",
+	)
+	mock.ExpectQuery("SELECT (.+) FROM reset_tokens").
+		WithArgs(hashedToken).
+		WillReturnRows(rows)
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", false, ""))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").
+		WithArgs(sqlmock.AnyArg(), userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE reset_tokens").
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("INSERT INTO audit_log").
+		WillReturnError(fmt.Errorf("relation \"audit_log\" does not exist"))
+
+	err := service.ResetPassword(context.Background(), plainToken, "Kettlebell#Row42", "192.168.1.1", i18n.RU)
+
+	assert.NoError(t, err, "audit failures must not fail the reset")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Empty(t, sentEmails(t, service).Messages())
 }
 
 func TestResetPassword_TransactionFailure(t *testing.T) {
@@ -387,7 +606,7 @@ func TestResetPassword_TransactionFailure(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -402,12 +621,17 @@ func TestResetPassword_TransactionFailure(t *testing.T) {
 		WillReturnRows(rows)
 
 	// Begin transaction fails
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	mock.ExpectBegin().WillReturnError(fmt.Errorf("transaction error"))
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to start transaction")
+	assert.Contains(t, err.Error(), "failed to begin transaction")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -417,7 +641,7 @@ func TestResetPassword_UpdatePasswordFailure(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -431,6 +655,11 @@ func TestResetPassword_UpdatePasswordFailure(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -442,7 +671,7 @@ func TestResetPassword_UpdatePasswordFailure(t *testing.T) {
 	// Rollback
 	mock.ExpectRollback()
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to update password")
@@ -455,7 +684,7 @@ func TestResetPassword_NoRowsAffected(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -469,6 +698,11 @@ func TestResetPassword_NoRowsAffected(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -480,7 +714,7 @@ func TestResetPassword_NoRowsAffected(t *testing.T) {
 	// Rollback
 	mock.ExpectRollback()
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to update password")
@@ -493,7 +727,7 @@ func TestResetPassword_MarkTokenUsedFailure(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -507,6 +741,11 @@ func TestResetPassword_MarkTokenUsedFailure(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -523,7 +762,7 @@ func TestResetPassword_MarkTokenUsedFailure(t *testing.T) {
 	// Rollback
 	mock.ExpectRollback()
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to mark token as used")
@@ -536,7 +775,7 @@ func TestResetPassword_CommitFailure(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -550,6 +789,11 @@ func TestResetPassword_CommitFailure(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -566,7 +810,7 @@ func TestResetPassword_CommitFailure(t *testing.T) {
 	// Commit fails
 	mock.ExpectCommit().WillReturnError(fmt.Errorf("commit error"))
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to commit transaction")
@@ -579,7 +823,7 @@ func TestResetPassword_EmailLookupFailure(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -593,6 +837,11 @@ func TestResetPassword_EmailLookupFailure(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnError(fmt.Errorf("database error"))
+
 	// Begin transaction
 	mock.ExpectBegin()
 
@@ -609,12 +858,7 @@ func TestResetPassword_EmailLookupFailure(t *testing.T) {
 	// Commit transaction
 	mock.ExpectCommit()
 
-	// Get user email fails
-	mock.ExpectQuery("SELECT email FROM users").
-		WithArgs(userID).
-		WillReturnError(fmt.Errorf("database error"))
-
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	// Should succeed even if email lookup fails (password was already changed)
 	assert.NoError(t, err)
@@ -627,7 +871,7 @@ func TestResetPassword_InvalidToken(t *testing.T) {
 
 	plainToken := "invalid-token"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 
 	// Token not found
@@ -635,7 +879,7 @@ func TestResetPassword_InvalidToken(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnError(sql.ErrNoRows)
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid token")
@@ -662,10 +906,43 @@ func TestResetPassword_WeakPassword(t *testing.T) {
 		WithArgs(hashedToken).
 		WillReturnRows(rows)
 
-	err := service.ResetPassword(context.Background(), plainToken, weakPassword, ipAddress)
+	// User email for the password check and the confirmation
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("user@example.com", true, ""))
+
+	err := service.ResetPassword(context.Background(), plainToken, weakPassword, ipAddress, i18n.RU)
+
+	var weak *WeakPasswordError
+	require.ErrorAs(t, err, &weak)
+	assert.Contains(t, weak.Message(i18n.EN), "The password does not meet the requirements")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_PasswordContainsEmail(t *testing.T) {
+	service, mock, cleanup := setupResetServiceTest(t)
+	defer cleanup()
+
+	plainToken := "test-token-123"
+	hashedToken := service.tokenGen.HashToken(plainToken)
+	userID := int64(123)
+
+	rows := sqlmock.NewRows([]string{
+		"id", "user_id", "token_hash", "created_at", "expires_at", "used_at", "ip_address", "user_agent",
+	}).AddRow(
+		1, userID, hashedToken, time.Now(), time.Now().Add(1*time.Hour), nil, "192.168.1.1", "test-agent",
+	)
+	mock.ExpectQuery("SELECT (.+) FROM reset_tokens").
+		WithArgs(hashedToken).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT u.email").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "password_changed_email", "language"}).AddRow("kettlebell@example.com", true, ""))
+
+	err := service.ResetPassword(context.Background(), plainToken, "Kettlebell#Row42", "192.168.1.1", i18n.RU)
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "password does not meet requirements")
+	assert.Contains(t, err.Error(), "Пароль не должен содержать имя из вашего email")
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -675,7 +952,7 @@ func TestResetPassword_ExpiredToken(t *testing.T) {
 
 	plainToken := "test-token-123"
 	hashedToken := service.tokenGen.HashToken(plainToken)
-	newPassword := "NewPass123!@#"
+	newPassword := "Kettlebell#Row42"
 	ipAddress := "192.168.1.1"
 	userID := int64(123)
 
@@ -694,7 +971,7 @@ func TestResetPassword_ExpiredToken(t *testing.T) {
 		WithArgs(1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress)
+	err := service.ResetPassword(context.Background(), plainToken, newPassword, ipAddress, i18n.RU)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "token expired")
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/burcev/api/internal/shared/response"
+)
+
+// CurrentUser is the authenticated user as the access token names them
+type CurrentUser struct {
+	ID    int64  `json:"id"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// CurrentUserResponse is what GET /auth/me returns
+type CurrentUserResponse struct {
+	User CurrentUser `json:"user"`
+}
+
+// ResetTokenResponse is what GET /auth/validate-reset-token returns
+type ResetTokenResponse struct {
+	Valid     bool      `json:"valid"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionsResponse is what GET /users/sessions returns
+type SessionsResponse struct {
+	response.ListData[Session]
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RouteDeps are the handlers and middleware the /auth routes are built from
+type RouteDeps struct {
+	Handler     *Handler
+	Reset       *ResetHandler
+	RateLimiter *middleware.AuthRateLimiter
+}
+
+// RegisterRoutes registers the /auth routes on r. Login and registration
+// are public and rate limited; refresh and logout read the refresh cookie
+// and require the CSRF header; the rest needs a token.
+func RegisterRoutes(r *gin.RouterGroup, deps RouteDeps) {
+	h := deps.Handler
+	requireAuth := middleware.RequireAuth(h.cfg)
+
+	r.POST("/register", deps.RateLimiter.Limit("register"), h.Register)
+	r.POST("/login", deps.RateLimiter.Limit("login"), h.Login)
+	r.POST("/refresh", middleware.RequireRequestedWith(RefreshCookieName), h.Refresh)
+	r.POST("/logout", middleware.RequireRequestedWith(RefreshCookieName), h.Logout)
+	r.POST("/reactivate", deps.RateLimiter.Limit("login"), h.Reactivate)
+	r.GET("/me", requireAuth, h.GetCurrentUser)
+	r.POST("/verify-email", requireAuth, h.VerifyEmail)
+	r.POST("/resend-verification", requireAuth, h.ResendVerification)
+
+	// Password reset routes
+	r.POST("/forgot-password", deps.Reset.ForgotPassword)
+	r.POST("/reset-password", deps.Reset.ResetPassword)
+	r.GET("/validate-reset-token", deps.Reset.ValidateResetToken)
+}
+
+// RegisterSessionRoutes registers the session routes, which live under
+// /users, on r
+func RegisterSessionRoutes(r *gin.RouterGroup, h *SessionHandler) {
+	r.GET("/sessions", h.ListSessions)
+	r.DELETE("/sessions", h.RevokeOtherSessions)
+	r.DELETE("/sessions/:id", h.RevokeSession)
+}
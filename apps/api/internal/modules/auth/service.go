@@ -3,21 +3,25 @@ package auth
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
 	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/golang-jwt/jwt/v5"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/google/uuid"
 )
 
-const (
-	RefreshTokenTTLDefault    = 24 * time.Hour
-	RefreshTokenTTLRememberMe = 30 * 24 * time.Hour
-)
+// rehashTimeout bounds the background upgrade of a password hash after login
+const rehashTimeout = 10 * time.Second
 
 // Default display names for users who register without a name.
 // Format: "Цвет Животное" — deterministic by user ID.
@@ -47,6 +51,16 @@ func generateDefaultIdentity(userID int64) (name, avatarURL string) {
 	return name, avatarURL
 }
 
+// ServiceInterface defines the auth operations the handler uses
+type ServiceInterface interface {
+	Register(ctx context.Context, email, password, name, ip, ua string, consents *ConsentsInput) (*LoginResult, error)
+	Login(ctx context.Context, email, password, ip, ua string, rememberMe bool) (*LoginResult, error)
+	Reactivate(ctx context.Context, email, password, ip, ua string) (*LoginResult, error)
+	RefreshTokens(ctx context.Context, plainToken, ip, ua string) (*LoginResult, error)
+	RevokeRefreshToken(ctx context.Context, plainToken string) error
+	ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error
+}
+
 // Service handles auth business logic
 type Service struct {
 	db          *sql.DB
@@ -54,6 +68,10 @@ type Service struct {
 	log         *logger.Logger
 	tokens      *TokenGenerator
 	passwordVal *PasswordValidator
+	audit       audit.ServiceInterface
+
+	// rehashes tracks background hash upgrades, so tests can wait for them
+	rehashes sync.WaitGroup
 }
 
 // NewService creates a new auth service
@@ -63,7 +81,8 @@ func NewService(db *sql.DB, cfg *config.Config, log *logger.Logger) *Service {
 		cfg:         cfg,
 		log:         log,
 		tokens:      NewTokenGenerator(),
-		passwordVal: NewPasswordValidator(),
+		passwordVal: newPasswordValidator(cfg, log),
+		audit:       audit.NewService(db, log),
 	}
 }
 
@@ -76,34 +95,45 @@ type User struct {
 	EmailVerified       bool      `json:"email_verified"`
 	OnboardingCompleted bool      `json:"onboarding_completed"`
 	CreatedAt           time.Time `json:"created_at"`
+
+	// TokenVersion is embedded in access tokens; bumping it in the database
+	// invalidates tokens issued earlier
+	TokenVersion int `json:"-"`
 }
 
 // LoginResult represents login response
 type LoginResult struct {
-	User         *User  `json:"user"`
-	Token        string `json:"token"`
-	RefreshToken string `json:"refresh_token"`
+	User  *User  `json:"user"`
+	Token string `json:"token"`
+	// RefreshToken is omitted when it is delivered in the refresh cookie
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// rememberMe tells the handler how long the refresh cookie should live
+	rememberMe bool
 }
 
 // Register registers a new user and returns login result with tokens
-func (s *Service) Register(ctx context.Context, email, password, name, ip, ua string, consents *ConsentsInput) (*LoginResult, error) {
+func (s *Service) Register(ctx context.Context, email, plainPassword, name, ip, ua string, consents *ConsentsInput) (*LoginResult, error) {
+	email = validation.NormalizeEmail(email)
 	s.log.Infow("User registration", "email", email)
 
 	// Validate password policy
-	if result := s.passwordVal.Validate(password); !result.Valid {
-		return nil, fmt.Errorf("пароль не соответствует требованиям: %v", result.Errors)
+	if result := s.passwordVal.Validate(plainPassword, UserContext{Email: email}); !result.Valid {
+		return nil, result.Err()
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := password.Hash(plainPassword, s.cfg.BcryptCost)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка при хешировании пароля: %w", err)
 	}
 
-	// Insert user into database
+	// Insert user into database. Accounts from before email normalization
+	// may differ only in case, which the unique constraint does not catch.
 	query := `
 		INSERT INTO users (email, password, name, role, created_at, updated_at)
-		VALUES ($1, $2, $3, 'client', NOW(), NOW())
+		SELECT $1, $2, $3, 'client', NOW(), NOW()
+		WHERE NOT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = $1)
 		RETURNING id, email, COALESCE(name, ''), role, email_verified, COALESCE(onboarding_completed, false), created_at
 	`
 
@@ -113,8 +143,12 @@ func (s *Service) Register(ctx context.Context, email, password, name, ip, ua st
 		&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt,
 	)
 	s.log.LogDatabaseQuery("Register.InsertUser", time.Since(startTime), err, map[string]any{"email": email})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, &apperrors.ConflictError{Constraint: "users_email_key", Field: "email"}
+	}
 	if err != nil {
-		return nil, fmt.Errorf("ошибка при регистрации: %w", err)
+		// A concurrent registration of the same email trips the constraint
+		return nil, fmt.Errorf("ошибка при регистрации: %w", database.ConstraintError(err))
 	}
 
 	// Assign default name and avatar when user registered without a name
@@ -160,14 +194,15 @@ func (s *Service) Register(ctx context.Context, email, password, name, ip, ua st
 	// Auto-assign curator (coordinator with fewest active clients)
 	s.assignCurator(ctx, user.ID)
 
-	// Generate JWT token
-	token, err := s.generateToken(&user)
+	// Generate JWT token for a new session
+	sessionID := uuid.NewString()
+	token, err := s.generateToken(&user, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	// Generate refresh token
-	refreshToken, err := s.createRefreshToken(ctx, user.ID, ip, ua, false)
+	refreshToken, err := s.createRefreshToken(ctx, user.ID, sessionID, ip, ua, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
@@ -180,21 +215,23 @@ func (s *Service) Register(ctx context.Context, email, password, name, ip, ua st
 }
 
 // Login authenticates a user
-func (s *Service) Login(ctx context.Context, email, password, ip, ua string, rememberMe bool) (*LoginResult, error) {
+func (s *Service) Login(ctx context.Context, email, plainPassword, ip, ua string, rememberMe bool) (*LoginResult, error) {
+	email = validation.NormalizeEmail(email)
 	s.log.Infow("User login", "email", email)
 
-	// Look up user by email
+	// Look up user by email; LOWER(email) is served by idx_users_email_lower
 	query := `
-		SELECT id, email, COALESCE(name, ''), password, role, email_verified, COALESCE(onboarding_completed, false), created_at
+		SELECT id, email, COALESCE(name, ''), password, role, email_verified, COALESCE(onboarding_completed, false), created_at, purge_after, token_version
 		FROM users
-		WHERE email = $1
+		WHERE LOWER(email) = $1
 	`
 
 	var user User
 	var hashedPassword string
+	var purgeAfter sql.NullTime
 	startTime := time.Now()
 	err := s.db.QueryRowContext(ctx, query, email).Scan(
-		&user.ID, &user.Email, &user.Name, &hashedPassword, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt,
+		&user.ID, &user.Email, &user.Name, &hashedPassword, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt, &purgeAfter, &user.TokenVersion,
 	)
 	s.log.LogDatabaseQuery("Login.LookupUser", time.Since(startTime), err, map[string]any{"email": email})
 	if err != nil {
@@ -205,31 +242,43 @@ func (s *Service) Login(ctx context.Context, email, password, ip, ua string, rem
 	}
 
 	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+	if err := password.Compare(hashedPassword, plainPassword); err != nil {
 		// If stored password is not a bcrypt hash, try plaintext comparison
 		// and migrate to bcrypt on success
 		if strings.HasPrefix(hashedPassword, "$2") {
 			return nil, fmt.Errorf("Login.VerifyPassword: %w", apperrors.ErrInvalidCredentials)
 		}
-		if hashedPassword != password {
+		if hashedPassword != plainPassword {
 			return nil, fmt.Errorf("Login.VerifyPassword: %w", apperrors.ErrInvalidCredentials)
 		}
 		// Migrate plaintext password to bcrypt
-		newHash, hashErr := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		newHash, hashErr := password.Hash(plainPassword, s.cfg.BcryptCost)
 		if hashErr == nil {
-			_, _ = s.db.ExecContext(ctx, "UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2", string(newHash), user.ID)
+			_, _ = s.db.ExecContext(ctx, "UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2", newHash, user.ID)
 			s.log.Infow("Migrated plaintext password to bcrypt", "user_id", user.ID)
 		}
+	} else if password.NeedsRehash(hashedPassword, s.cfg.BcryptCost) {
+		s.rehashPassword(user.ID, hashedPassword, plainPassword)
 	}
 
-	// Generate JWT token
-	token, err := s.generateToken(&user)
+	// Only the owner, who knows the password, learns that the account is
+	// deactivated; once the grace period is over it is as good as gone
+	if purgeAfter.Valid {
+		if !time.Now().Before(purgeAfter.Time) {
+			return nil, fmt.Errorf("Login.Deactivated: %w", apperrors.ErrInvalidCredentials)
+		}
+		return nil, &DeactivatedError{PurgeAfter: purgeAfter.Time}
+	}
+
+	// Generate JWT token for a new session
+	sessionID := uuid.NewString()
+	token, err := s.generateToken(&user, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate token: %w", err)
 	}
 
 	// Generate refresh token
-	refreshToken, err := s.createRefreshToken(ctx, user.ID, ip, ua, rememberMe)
+	refreshToken, err := s.createRefreshToken(ctx, user.ID, sessionID, ip, ua, rememberMe)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create refresh token: %w", err)
 	}
@@ -238,9 +287,40 @@ func (s *Service) Login(ctx context.Context, email, password, ip, ua string, rem
 		User:         &user,
 		Token:        token,
 		RefreshToken: refreshToken,
+		rememberMe:   rememberMe,
 	}, nil
 }
 
+// rehashPassword upgrades a password hash made at a lower cost than
+// BCRYPT_COST. It runs in the background with its own context, so the login
+// neither waits for the extra hashing nor cancels it; a failure is logged
+// and the upgrade retried on the next login.
+func (s *Service) rehashPassword(userID int64, oldHash, plainPassword string) {
+	s.rehashes.Add(1)
+	go func() {
+		defer s.rehashes.Done()
+		ctx, cancel := context.WithTimeout(context.Background(), rehashTimeout)
+		defer cancel()
+
+		newHash, err := password.Hash(plainPassword, s.cfg.BcryptCost)
+		if err != nil {
+			s.log.Errorw("Failed to rehash password", "error", err, "user_id", userID)
+			return
+		}
+
+		// A password changed in the meantime is left alone
+		startTime := time.Now()
+		_, err = s.db.ExecContext(ctx,
+			`UPDATE users SET password = $1 WHERE id = $2 AND password = $3`,
+			newHash, userID, oldHash,
+		)
+		s.log.LogDatabaseQuery("Login.RehashPassword", time.Since(startTime), err, map[string]any{"user_id": userID})
+		if err == nil {
+			s.log.Infow("Upgraded password hash", "user_id", userID, "cost", s.cfg.BcryptCost)
+		}
+	}()
+}
+
 // RefreshTokens validates a refresh token, rotates it, and returns new tokens
 func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string) (*LoginResult, error) {
 	tokenHash := s.tokens.HashToken(plainToken)
@@ -250,12 +330,13 @@ func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string)
 	var expiresAt time.Time
 	var revokedAt sql.NullTime
 	var rememberMe bool
+	var sessionID string
 
 	startTime := time.Now()
 	err := s.db.QueryRowContext(ctx,
-		`SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens WHERE token_hash = $1`,
+		`SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens WHERE token_hash = $1`,
 		tokenHash,
-	).Scan(&id, &userID, &expiresAt, &revokedAt, &rememberMe)
+	).Scan(&id, &userID, &expiresAt, &revokedAt, &rememberMe, &sessionID)
 	s.log.LogDatabaseQuery("Refresh.LookupToken", time.Since(startTime), err, nil)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -273,7 +354,7 @@ func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string)
 			// Look up the replacement token's result instead of revoking everything.
 			s.log.Infow("Refresh token reuse within grace period, looking up replacement",
 				"user_id", userID, "revoked_ago_ms", time.Since(revokedAt.Time).Milliseconds())
-			return s.handleGracefulReuse(ctx, id, userID, ip, ua, rememberMe)
+			return s.handleGracefulReuse(ctx, userID, sessionID, ip, ua, rememberMe)
 		}
 		s.log.Warnw("Refresh token reuse detected, revoking all tokens for user", "user_id", userID)
 		s.revokeAllUserRefreshTokens(ctx, userID)
@@ -314,16 +395,13 @@ func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string)
 		return nil, fmt.Errorf("failed to revoke old refresh token: %w", err)
 	}
 
-	// Insert new refresh token
-	ttl := RefreshTokenTTLDefault
-	if rememberMe {
-		ttl = RefreshTokenTTLRememberMe
-	}
+	// Insert new refresh token in the same session
+	ttl := refreshTokenTTL(s.cfg, rememberMe)
 	expiresAtNew := time.Now().Add(ttl)
 	_, err = tx.ExecContext(dbCtx,
-		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at, ip_address, user_agent, remember_me, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
-		userID, newHash, expiresAtNew, ip, ua, rememberMe,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at, ip_address, user_agent, remember_me, created_at, family_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7)`,
+		userID, newHash, expiresAtNew, ip, ua, rememberMe, sessionID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert new refresh token: %w", err)
@@ -336,15 +414,15 @@ func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string)
 	// Look up user for JWT claims
 	var user User
 	err = s.db.QueryRowContext(dbCtx,
-		`SELECT id, email, COALESCE(name, ''), role, email_verified, COALESCE(onboarding_completed, false), created_at
-		 FROM users WHERE id = $1`, userID,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt)
+		`SELECT id, email, COALESCE(name, ''), role, email_verified, COALESCE(onboarding_completed, false), created_at, token_version
+		 FROM users WHERE id = $1 AND deactivated_at IS NULL`, userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt, &user.TokenVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to look up user: %w", err)
 	}
 
 	// Generate new JWT
-	accessToken, err := s.generateToken(&user)
+	accessToken, err := s.generateToken(&user, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -353,15 +431,16 @@ func (s *Service) RefreshTokens(ctx context.Context, plainToken, ip, ua string)
 		User:         &user,
 		Token:        accessToken,
 		RefreshToken: newPlain,
+		rememberMe:   rememberMe,
 	}, nil
 }
 
 // handleGracefulReuse handles the case where a refresh token was recently rotated
 // (e.g., by another browser tab). Instead of revoking all tokens, it issues new
-// tokens for the user, treating it as a benign race condition.
-func (s *Service) handleGracefulReuse(ctx context.Context, oldTokenID, userID int64, ip, ua string, rememberMe bool) (*LoginResult, error) {
+// tokens for the user in the same session, treating it as a benign race condition.
+func (s *Service) handleGracefulReuse(ctx context.Context, userID int64, sessionID, ip, ua string, rememberMe bool) (*LoginResult, error) {
 	// Issue a brand new refresh token for this client
-	newPlain, err := s.createRefreshToken(ctx, userID, ip, ua, rememberMe)
+	newPlain, err := s.createRefreshToken(ctx, userID, sessionID, ip, ua, rememberMe)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create replacement refresh token: %w", err)
 	}
@@ -369,14 +448,14 @@ func (s *Service) handleGracefulReuse(ctx context.Context, oldTokenID, userID in
 	// Look up user for JWT claims
 	var user User
 	err = s.db.QueryRowContext(ctx,
-		`SELECT id, email, COALESCE(name, ''), role, email_verified, COALESCE(onboarding_completed, false), created_at
-		 FROM users WHERE id = $1`, userID,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt)
+		`SELECT id, email, COALESCE(name, ''), role, email_verified, COALESCE(onboarding_completed, false), created_at, token_version
+		 FROM users WHERE id = $1 AND deactivated_at IS NULL`, userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.EmailVerified, &user.OnboardingCompleted, &user.CreatedAt, &user.TokenVersion)
 	if err != nil {
 		return nil, fmt.Errorf("failed to look up user: %w", err)
 	}
 
-	accessToken, err := s.generateToken(&user)
+	accessToken, err := s.generateToken(&user, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
@@ -385,6 +464,7 @@ func (s *Service) handleGracefulReuse(ctx context.Context, oldTokenID, userID in
 		User:         &user,
 		Token:        accessToken,
 		RefreshToken: newPlain,
+		rememberMe:   rememberMe,
 	}, nil
 }
 
@@ -392,30 +472,30 @@ func (s *Service) handleGracefulReuse(ctx context.Context, oldTokenID, userID in
 // It verifies the current password, validates the new one against policy,
 // and updates the stored bcrypt hash.
 func (s *Service) ChangePassword(ctx context.Context, userID int64, currentPassword, newPassword string) error {
-	var storedHash string
+	var storedHash, email string
 	startTime := time.Now()
 	err := s.db.QueryRowContext(ctx,
-		`SELECT password FROM users WHERE id = $1`,
+		`SELECT password, email FROM users WHERE id = $1`,
 		userID,
-	).Scan(&storedHash)
+	).Scan(&storedHash, &email)
 	s.log.LogDatabaseQuery("ChangePassword.GetHash", time.Since(startTime), err, map[string]any{"user_id": userID})
 	if err != nil {
 		return fmt.Errorf("ошибка при получении данных пользователя: %w", err)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(currentPassword)); err != nil {
+	if err := password.Compare(storedHash, currentPassword); err != nil {
 		return fmt.Errorf("ChangePassword.verify: %w", apperrors.ErrInvalidCredentials)
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(storedHash), []byte(newPassword)); err == nil {
+	if err := password.Compare(storedHash, newPassword); err == nil {
 		return fmt.Errorf("новый пароль должен отличаться от текущего")
 	}
 
-	if result := s.passwordVal.Validate(newPassword); !result.Valid {
-		return fmt.Errorf("пароль не соответствует требованиям: %v", result.Errors)
+	if result := s.passwordVal.Validate(newPassword, UserContext{Email: email}); !result.Valid {
+		return result.Err()
 	}
 
-	newHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	newHash, err := password.Hash(newPassword, s.cfg.BcryptCost)
 	if err != nil {
 		return fmt.Errorf("ошибка при хешировании пароля: %w", err)
 	}
@@ -423,13 +503,58 @@ func (s *Service) ChangePassword(ctx context.Context, userID int64, currentPassw
 	startTime2 := time.Now()
 	_, err = s.db.ExecContext(ctx,
 		`UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`,
-		string(newHash), userID,
+		newHash, userID,
 	)
 	s.log.LogDatabaseQuery("ChangePassword.UpdateHash", time.Since(startTime2), err, map[string]any{"user_id": userID})
 	if err != nil {
 		return fmt.Errorf("ошибка при обновлении пароля: %w", err)
 	}
 
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &userID,
+		Action: audit.ActionPasswordChanged,
+	})
+
+	return nil
+}
+
+// SetPassword replaces a user's password without asking for the current
+// one, for tooling such as the development seed. The password policy still
+// applies, and the user's refresh tokens are revoked.
+func (s *Service) SetPassword(ctx context.Context, userID int64, plainPassword string) error {
+	var email string
+	err := s.db.QueryRowContext(ctx, `SELECT email FROM users WHERE id = $1`, userID).Scan(&email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("SetPassword: %w", apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка при получении данных пользователя: %w", err)
+	}
+
+	if result := s.passwordVal.Validate(plainPassword, UserContext{Email: email}); !result.Valid {
+		return result.Err()
+	}
+
+	hash, err := password.Hash(plainPassword, s.cfg.BcryptCost)
+	if err != nil {
+		return fmt.Errorf("ошибка при хешировании пароля: %w", err)
+	}
+
+	startTime := time.Now()
+	_, err = s.db.ExecContext(ctx,
+		`UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`,
+		hash, userID,
+	)
+	s.log.LogDatabaseQuery("SetPassword.UpdateHash", time.Since(startTime), err, map[string]any{"user_id": userID})
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении пароля: %w", err)
+	}
+
+	s.revokeAllUserRefreshTokens(ctx, userID)
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &userID,
+		Action: audit.ActionPasswordChanged,
+	})
 	return nil
 }
 
@@ -445,24 +570,21 @@ func (s *Service) RevokeRefreshToken(ctx context.Context, plainToken string) err
 	return err
 }
 
-// createRefreshToken generates and stores a new refresh token
-func (s *Service) createRefreshToken(ctx context.Context, userID int64, ip, ua string, rememberMe bool) (string, error) {
+// createRefreshToken generates and stores a new refresh token of the session
+func (s *Service) createRefreshToken(ctx context.Context, userID int64, sessionID, ip, ua string, rememberMe bool) (string, error) {
 	plainToken, hashedToken, err := s.tokens.GenerateToken()
 	if err != nil {
 		return "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	ttl := RefreshTokenTTLDefault
-	if rememberMe {
-		ttl = RefreshTokenTTLRememberMe
-	}
+	ttl := refreshTokenTTL(s.cfg, rememberMe)
 	expiresAt := time.Now().Add(ttl)
 
 	startTime := time.Now()
 	_, err = s.db.ExecContext(ctx,
-		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at, ip_address, user_agent, remember_me, created_at)
-		 VALUES ($1, $2, $3, $4, $5, $6, NOW())`,
-		userID, hashedToken, expiresAt, ip, ua, rememberMe,
+		`INSERT INTO refresh_tokens (user_id, token_hash, expires_at, ip_address, user_agent, remember_me, created_at, family_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW(), $7)`,
+		userID, hashedToken, expiresAt, ip, ua, rememberMe, sessionID,
 	)
 	s.log.LogDatabaseQuery("RefreshToken.Insert", time.Since(startTime), err, map[string]any{"user_id": userID})
 	if err != nil {
@@ -529,14 +651,27 @@ func (s *Service) assignCurator(ctx context.Context, clientID int64) {
 	s.log.Infow("Auto-assigned curator to new client", "curator_id", curatorID, "client_id", clientID)
 }
 
-// generateToken generates JWT token for user (15 min expiry)
-func (s *Service) generateToken(user *User) (string, error) {
+// refreshTokenTTL returns the configured refresh token lifetime
+func refreshTokenTTL(cfg *config.Config, rememberMe bool) time.Duration {
+	if rememberMe {
+		return cfg.RememberMeRefreshTokenTTL
+	}
+	return cfg.RefreshTokenTTL
+}
+
+// generateToken generates JWT token for user in the refresh token session
+// (expires after cfg.AccessTokenTTL)
+func (s *Service) generateToken(user *User, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
 		"email":   user.Email,
 		"role":    user.Role,
-		"exp":     time.Now().Add(15 * time.Minute).Unix(),
-		"iat":     time.Now().Unix(),
+		// Checked by middleware.RequireTokenVersion on privileged routes
+		"token_version": user.TokenVersion,
+		"exp":           time.Now().Add(s.cfg.AccessTokenTTL).Unix(),
+		"iat":           time.Now().Unix(),
+		// Checked by middleware.RejectRevokedSessions; marks the current session
+		"sid": sessionID,
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
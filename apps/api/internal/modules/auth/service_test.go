@@ -10,7 +10,10 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/password"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -34,7 +37,10 @@ func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, func()) {
 	require.NoError(t, err)
 
 	cfg := &config.Config{
-		JWTSecret: "test-secret-key",
+		JWTSecret:                 "test-secret-key",
+		AccessTokenTTL:            config.DefaultAccessTokenTTL,
+		RefreshTokenTTL:           config.DefaultRefreshTokenTTL,
+		RememberMeRefreshTokenTTL: config.DefaultRememberMeRefreshTokenTTL,
 	}
 	log := logger.New()
 	service := NewService(db, cfg, log)
@@ -63,10 +69,10 @@ func TestRegisterService(t *testing.T) {
 
 		// Expect refresh token insertion (6 args: userID, hash, expiresAt, ip, ua, rememberMe)
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		result, err := service.Register(ctx, "test@example.com", "Password1!", "Test User", "127.0.0.1", "TestAgent", nil)
+		result, err := service.Register(ctx, "test@example.com", "Orbit#Lantern7", "Test User", "127.0.0.1", "TestAgent", nil)
 		require.NoError(t, err)
 		require.NotNil(t, result)
 		assert.NotNil(t, result.User)
@@ -97,16 +103,51 @@ func TestRegisterService(t *testing.T) {
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", false).
+			WithArgs(int64(2), sqlmock.AnyArg(), sqlmock.AnyArg(), "", "", false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		result, err := service.Register(ctx, "test2@example.com", "Password1!", "", "", "", nil)
+		result, err := service.Register(ctx, "test2@example.com", "Orbit#Lantern7", "", "", "", nil)
 		require.NoError(t, err)
 		require.NotNil(t, result)
 		assert.Equal(t, "test2@example.com", result.User.Email)
 		assert.NotEmpty(t, result.User.Name, "should have a default name")
 		assert.Contains(t, result.User.Name, " ", "default name should be 'Color Animal' format")
 	})
+
+	t.Run("email is normalized", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs("test@example.com", sqlmock.AnyArg(), "Test User").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at"}).
+				AddRow(1, "test@example.com", "Test User", "client", false, false, time.Now()))
+		mock.ExpectExec("INSERT INTO user_settings").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO refresh_tokens").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		result, err := service.Register(context.Background(), " Test@Example.COM ", "Orbit#Lantern7", "Test User", "", "", nil)
+		require.NoError(t, err)
+		assert.Equal(t, "test@example.com", result.User.Email)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("email taken in another case", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		// The NOT EXISTS guard matches LOWER(email) and inserts nothing
+		mock.ExpectQuery("INSERT INTO users").
+			WithArgs("test@example.com", sqlmock.AnyArg(), "").
+			WillReturnError(sql.ErrNoRows)
+
+		result, err := service.Register(context.Background(), "TEST@example.com", "Orbit#Lantern7", "", "", "", nil)
+		assert.Nil(t, result)
+		var conflict *apperrors.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "email", conflict.Field)
+	})
 }
 
 func TestLoginService(t *testing.T) {
@@ -119,11 +160,11 @@ func TestLoginService(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false).
+			WithArgs(int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false, sqlmock.AnyArg()).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		result, err := service.Login(ctx, "test@example.com", "password123", "127.0.0.1", "TestAgent", false)
@@ -144,13 +185,74 @@ func TestLoginService(t *testing.T) {
 
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs("test@example.com").
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 		result, err := service.Login(ctx, "test@example.com", "wrongpassword", "", "", false)
 		assert.Error(t, err)
 		assert.Nil(t, result)
 	})
+
+	t.Run("email matches case-insensitively", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		hashedPw, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+
+		// Stored before normalization; found through LOWER(email)
+		mock.ExpectQuery("SELECT id, email, .+ WHERE LOWER\\(email\\) = \\$1").
+			WithArgs("test@example.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+				AddRow(1, "Test@Example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
+		mock.ExpectExec("INSERT INTO refresh_tokens").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		result, err := service.Login(context.Background(), "TEST@EXAMPLE.COM", "password123", "", "", false)
+		require.NoError(t, err)
+		assert.Equal(t, "Test@Example.com", result.User.Email)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// hashCost matches a bcrypt hash argument made at cost
+type hashCost int
+
+func (c hashCost) Match(v driver.Value) bool {
+	hash, ok := v.(string)
+	if !ok {
+		return false
+	}
+	cost, err := password.Cost(hash)
+	return err == nil && cost == int(c) && password.Compare(hash, "password123") == nil
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+	// The background update races the login's own queries
+	mock.MatchExpectationsInOrder(false)
+	service.cfg.BcryptCost = password.MinCost
+
+	// A fixture hash below the configured cost
+	lowCost, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id, email").
+		WithArgs("test@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+			AddRow(1, "test@example.com", "Test User", string(lowCost), "client", false, false, time.Now(), nil, 0))
+	mock.ExpectExec("INSERT INTO refresh_tokens").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE users SET password = \\$1 WHERE id = \\$2 AND password = \\$3").
+		WithArgs(hashCost(password.MinCost), int64(1), string(lowCost)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	result, err := service.Login(context.Background(), "test@example.com", "password123", "", "", false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+
+	service.rehashes.Wait()
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestRefreshTokens(t *testing.T) {
@@ -164,10 +266,10 @@ func TestRefreshTokens(t *testing.T) {
 		expiresAt := time.Now().Add(24 * time.Hour)
 
 		// Lookup refresh token
-		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens").
+		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
 			WithArgs(tokenHash).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me"}).
-				AddRow(1, int64(42), expiresAt, nil, false))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me", "family_id"}).
+				AddRow(1, int64(42), expiresAt, nil, false, testSessionID))
 
 		// Begin transaction
 		mock.ExpectBegin()
@@ -177,9 +279,9 @@ func TestRefreshTokens(t *testing.T) {
 			WithArgs(sqlmock.AnyArg(), int64(1)).
 			WillReturnResult(sqlmock.NewResult(0, 1))
 
-		// Insert new token
+		// Insert new token in the same session
 		mock.ExpectExec("INSERT INTO refresh_tokens").
-			WithArgs(int64(42), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false).
+			WithArgs(int64(42), sqlmock.AnyArg(), sqlmock.AnyArg(), "127.0.0.1", "TestAgent", false, testSessionID).
 			WillReturnResult(sqlmock.NewResult(2, 1))
 
 		// Commit transaction
@@ -188,8 +290,8 @@ func TestRefreshTokens(t *testing.T) {
 		// Look up user
 		mock.ExpectQuery("SELECT id, email").
 			WithArgs(int64(42)).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at"}).
-				AddRow(42, "user@example.com", "User", "client", true, true, time.Now()))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at", "token_version"}).
+				AddRow(42, "user@example.com", "User", "client", true, true, time.Now(), 0))
 
 		result, err := service.RefreshTokens(ctx, plainToken, "127.0.0.1", "TestAgent")
 		assert.NoError(t, err)
@@ -209,10 +311,10 @@ func TestRefreshTokens(t *testing.T) {
 		tokenHash := service.tokens.HashToken(plainToken)
 		expiredAt := time.Now().Add(-1 * time.Hour)
 
-		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens").
+		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
 			WithArgs(tokenHash).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me"}).
-				AddRow(1, int64(42), expiredAt, nil, false))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me", "family_id"}).
+				AddRow(1, int64(42), expiredAt, nil, false, testSessionID))
 
 		result, err := service.RefreshTokens(ctx, plainToken, "", "")
 		assert.Error(t, err)
@@ -229,10 +331,10 @@ func TestRefreshTokens(t *testing.T) {
 		tokenHash := service.tokens.HashToken(plainToken)
 		revokedAt := sql.NullTime{Time: time.Now().Add(-1 * time.Hour), Valid: true}
 
-		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens").
+		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
 			WithArgs(tokenHash).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me"}).
-				AddRow(1, int64(42), time.Now().Add(24*time.Hour), revokedAt, false))
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me", "family_id"}).
+				AddRow(1, int64(42), time.Now().Add(24*time.Hour), revokedAt, false, testSessionID))
 
 		// Expect all tokens to be revoked
 		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
@@ -253,7 +355,7 @@ func TestRefreshTokens(t *testing.T) {
 		plainToken := "unknown-token"
 		tokenHash := service.tokens.HashToken(plainToken)
 
-		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens").
+		mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
 			WithArgs(tokenHash).
 			WillReturnError(sql.ErrNoRows)
 
@@ -280,6 +382,65 @@ func TestRevokeRefreshToken(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestChangePasswordRecordsAudit(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+	ctx := audit.WithActorIP(context.Background(), "203.0.113.7")
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("OldPass123!@#"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT password, email FROM users").
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"password", "email"}).AddRow(string(hash), "user@example.com"))
+	mock.ExpectExec("UPDATE users SET password").
+		WithArgs(sqlmock.AnyArg(), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(int64(42), "203.0.113.7", audit.ActionPasswordChanged, []byte("{}")).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err = service.ChangePassword(ctx, 42, "OldPass123!@#", "Kettlebell#Row42")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetPassword(t *testing.T) {
+	t.Run("replaces the password and revokes sessions", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT email FROM users WHERE id = \\$1").
+			WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+		mock.ExpectExec("UPDATE users SET password").
+			WithArgs(sqlmock.AnyArg(), int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = NOW\\(\\) WHERE user_id = \\$1").
+			WithArgs(int64(42)).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := service.SetPassword(context.Background(), 42, "Kettlebell#Row42")
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("policy still applies", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT email FROM users WHERE id = \\$1").
+			WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+
+		err := service.SetPassword(context.Background(), 42, "short")
+		assert.ErrorContains(t, err, "пароль не соответствует требованиям")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
 func TestGenerateJWTToken(t *testing.T) {
 	service, _, cleanup := setupTestService(t)
 	defer cleanup()
@@ -290,7 +451,7 @@ func TestGenerateJWTToken(t *testing.T) {
 		Role:  "client",
 	}
 
-	token, err := service.generateToken(user)
+	token, err := service.generateToken(user, testSessionID)
 	require.NoError(t, err)
 	assert.NotEmpty(t, token)
 
@@ -307,6 +468,7 @@ func TestGenerateJWTToken(t *testing.T) {
 	assert.Equal(t, float64(user.ID), claims["user_id"])
 	assert.Equal(t, user.Email, claims["email"])
 	assert.Equal(t, user.Role, claims["role"])
+	assert.Equal(t, testSessionID, claims["sid"])
 
 	// Verify 15 min expiry (not 7 days)
 	exp := int64(claims["exp"].(float64))
@@ -320,8 +482,8 @@ func TestLoginRememberMe(t *testing.T) {
 		rememberMe  bool
 		expectedTTL time.Duration
 	}{
-		{"rememberMe=false uses default TTL", false, RefreshTokenTTLDefault},
-		{"rememberMe=true uses extended TTL", true, RefreshTokenTTLRememberMe},
+		{"rememberMe=false uses default TTL", false, config.DefaultRefreshTokenTTL},
+		{"rememberMe=true uses extended TTL", true, config.DefaultRememberMeRefreshTokenTTL},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			service, mock, cleanup := setupTestService(t)
@@ -332,11 +494,11 @@ func TestLoginRememberMe(t *testing.T) {
 
 			mock.ExpectQuery("SELECT id, email").
 				WithArgs("test@example.com").
-				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at"}).
-					AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now()))
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "password", "role", "email_verified", "onboarding_completed", "created_at", "purge_after", "token_version"}).
+					AddRow(1, "test@example.com", "Test User", string(hashedPw), "client", false, false, time.Now(), nil, 0))
 
 			mock.ExpectExec("INSERT INTO refresh_tokens").
-				WithArgs(int64(1), sqlmock.AnyArg(), expiresAtMatcher{tc.expectedTTL}, "127.0.0.1", "TestAgent", tc.rememberMe).
+				WithArgs(int64(1), sqlmock.AnyArg(), expiresAtMatcher{tc.expectedTTL}, "127.0.0.1", "TestAgent", tc.rememberMe, sqlmock.AnyArg()).
 				WillReturnResult(sqlmock.NewResult(1, 1))
 
 			result, err := service.Login(ctx, "test@example.com", "password123", "127.0.0.1", "TestAgent", tc.rememberMe)
@@ -354,8 +516,8 @@ func TestRefreshTokensInheritsRememberMe(t *testing.T) {
 		rememberMe  bool
 		expectedTTL time.Duration
 	}{
-		{"inherits rememberMe=false", false, RefreshTokenTTLDefault},
-		{"inherits rememberMe=true", true, RefreshTokenTTLRememberMe},
+		{"inherits rememberMe=false", false, config.DefaultRefreshTokenTTL},
+		{"inherits rememberMe=true", true, config.DefaultRememberMeRefreshTokenTTL},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			service, mock, cleanup := setupTestService(t)
@@ -366,10 +528,10 @@ func TestRefreshTokensInheritsRememberMe(t *testing.T) {
 			tokenHash := service.tokens.HashToken(plainToken)
 			expiresAt := time.Now().Add(24 * time.Hour)
 
-			mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me FROM refresh_tokens").
+			mock.ExpectQuery("SELECT id, user_id, expires_at, revoked_at, remember_me, family_id FROM refresh_tokens").
 				WithArgs(tokenHash).
-				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me"}).
-					AddRow(1, int64(42), expiresAt, nil, tc.rememberMe))
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at", "remember_me", "family_id"}).
+					AddRow(1, int64(42), expiresAt, nil, tc.rememberMe, testSessionID))
 
 			mock.ExpectBegin()
 
@@ -378,15 +540,15 @@ func TestRefreshTokensInheritsRememberMe(t *testing.T) {
 				WillReturnResult(sqlmock.NewResult(0, 1))
 
 			mock.ExpectExec("INSERT INTO refresh_tokens").
-				WithArgs(int64(42), sqlmock.AnyArg(), expiresAtMatcher{tc.expectedTTL}, "127.0.0.1", "TestAgent", tc.rememberMe).
+				WithArgs(int64(42), sqlmock.AnyArg(), expiresAtMatcher{tc.expectedTTL}, "127.0.0.1", "TestAgent", tc.rememberMe, testSessionID).
 				WillReturnResult(sqlmock.NewResult(2, 1))
 
 			mock.ExpectCommit()
 
 			mock.ExpectQuery("SELECT id, email").
 				WithArgs(int64(42)).
-				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at"}).
-					AddRow(42, "user@example.com", "User", "client", true, true, time.Now()))
+				WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "role", "email_verified", "onboarding_completed", "created_at", "token_version"}).
+					AddRow(42, "user@example.com", "User", "client", true, true, time.Now(), 0))
 
 			result, err := service.RefreshTokens(ctx, plainToken, "127.0.0.1", "TestAgent")
 			assert.NoError(t, err)
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler handles the user's session list
+type SessionHandler struct {
+	log     *logger.Logger
+	service *SessionService
+}
+
+// NewSessionHandler creates a session handler
+func NewSessionHandler(log *logger.Logger, service *SessionService) *SessionHandler {
+	return &SessionHandler{log: log, service: service}
+}
+
+// revokedSessionsResponse is what DELETE /users/sessions returns
+type revokedSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// ListSessions returns the user's active sessions
+func (h *SessionHandler) ListSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	sessions, err := h.service.ListSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		h.log.Errorw("Failed to list sessions", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить список сеансов")
+		return
+	}
+
+	response.List(c, http.StatusOK, SessionsResponse{response.NewList(sessions, 0, 0)}, "sessions")
+}
+
+// RevokeSession signs the user out of one session
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	if err := h.service.RevokeSession(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Сеанс не найден")
+			return
+		}
+		h.log.Errorw("Failed to revoke session", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось завершить сеанс")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Сеанс завершён", nil)
+}
+
+// RevokeOtherSessions signs the user out of every session but the current one
+func (h *SessionHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	revoked, err := h.service.RevokeOtherSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		h.log.Errorw("Failed to revoke sessions", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось завершить сеансы")
+		return
+	}
+
+	response.Success(c, http.StatusOK, revokedSessionsResponse{Revoked: revoked})
+}
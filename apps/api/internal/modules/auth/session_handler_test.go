@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHandler_ListSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+
+	serve := func(t *testing.T, compat bool) string {
+		t.Helper()
+		service, _, mock := setupSessionService(t, logger.New())
+		mock.ExpectQuery(`FROM refresh_tokens rt\s+JOIN`).
+			WithArgs(int64(42)).
+			WillReturnRows(sqlmock.NewRows([]string{"family_id", "user_agent", "ip_address", "started_at", "created_at"}).
+				AddRow(testSessionID, chromeWindowsUA, "10.0.0.1", started, started))
+
+		router := gin.New()
+		router.GET("/users/sessions", response.Compat(compat), func(c *gin.Context) {
+			c.Set("user_id", int64(42))
+			c.Set("session_id", testSessionID)
+			NewSessionHandler(logger.New(), service).ListSessions(c)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/sessions", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, mock.ExpectationsWereMet())
+		return w.Body.String()
+	}
+
+	session := `{"id":"` + testSessionID + `","device":"Chrome на Windows","ip_address":"10.0.0.1","created_at":"2026-10-01T09:00:00Z","last_used_at":"2026-10-01T09:00:00Z","current":true}`
+	items := `"items":[` + session + `],"pagination":{"limit":0,"offset":0,"count":1}`
+
+	t.Run("sessions are sent in the list envelope", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"success","data":{`+items+`}}`, serve(t, false))
+	})
+
+	t.Run("compatible responses add the sessions key", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"success","data":{`+items+`,"sessions":[`+session+`]}}`, serve(t, true))
+	})
+}
@@ -0,0 +1,273 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/google/uuid"
+)
+
+// Session is a signed-in device: a login and the refresh tokens rotated
+// from it
+type Session struct {
+	ID     string `json:"id"`
+	Device string `json:"device"`
+	IP     string `json:"ip_address"`
+	// CreatedAt is when the user signed in
+	CreatedAt time.Time `json:"created_at"`
+	// LastUsedAt is when the session last refreshed its tokens
+	LastUsedAt time.Time `json:"last_used_at"`
+	// Current marks the session of the request's access token
+	Current bool `json:"current"`
+}
+
+// SessionService lists and revokes the user's sessions
+type SessionService struct {
+	db        *sql.DB
+	log       *logger.Logger
+	blacklist *SessionBlacklist
+}
+
+// NewSessionService creates a session service. Revoked sessions are added
+// to blacklist so their access tokens stop working at once.
+func NewSessionService(db *sql.DB, log *logger.Logger, blacklist *SessionBlacklist) *SessionService {
+	return &SessionService{db: db, log: log, blacklist: blacklist}
+}
+
+// ListSessions returns the user's active sessions, most recently used
+// first. currentID is the session of the request, empty when unknown.
+func (s *SessionService) ListSessions(ctx context.Context, userID int64, currentID string) ([]Session, error) {
+	query := `
+		SELECT rt.family_id, COALESCE(rt.user_agent, ''), COALESCE(rt.ip_address, ''), f.started_at, rt.created_at
+		FROM refresh_tokens rt
+		JOIN (
+			SELECT family_id, MIN(created_at) AS started_at
+			FROM refresh_tokens
+			WHERE user_id = $1
+			GROUP BY family_id
+		) f ON f.family_id = rt.family_id
+		WHERE rt.user_id = $1 AND rt.revoked_at IS NULL AND rt.expires_at > NOW()
+		ORDER BY rt.created_at DESC
+	`
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(start), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	// Two tabs refreshing at once leave a session with two active tokens;
+	// the latest one describes it
+	sessions := []Session{}
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var session Session
+		var ua string
+		if err := rows.Scan(&session.ID, &ua, &session.IP, &session.CreatedAt, &session.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if seen[session.ID] {
+			continue
+		}
+		seen[session.ID] = true
+		session.Device = describeUserAgent(ua)
+		session.Current = session.ID == currentID
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs the user out of one session
+func (s *SessionService) RevokeSession(ctx context.Context, userID int64, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return fmt.Errorf("RevokeSession: %w", apperrors.ErrNotFound)
+	}
+
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx, query, userID, sessionID)
+	s.log.LogDatabaseQuery(query, time.Since(start), err, map[string]interface{}{"user_id": userID, "session_id": sessionID})
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("RevokeSession: %w", apperrors.ErrNotFound)
+	}
+
+	s.blacklist.Revoke(sessionID)
+	s.log.LogSecurityEvent("session_revoked", "info", map[string]interface{}{
+		"user_id":    userID,
+		"session_id": sessionID,
+	})
+	return nil
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current
+// session and returns how many sessions were ended. Without a current
+// session (a token from before sessions were tracked) every session ends.
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userID int64, currentID string) (int, error) {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL AND family_id IS DISTINCT FROM NULLIF($2, '')::uuid
+		RETURNING family_id
+	`
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, userID, currentID)
+	s.log.LogDatabaseQuery(query, time.Since(start), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var revoked []string
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("failed to scan session: %w", err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			revoked = append(revoked, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	s.blacklist.Revoke(revoked...)
+	s.log.LogSecurityEvent("sessions_revoked", "medium", map[string]interface{}{
+		"user_id":         userID,
+		"current_session": currentID,
+		"revoked":         len(revoked),
+	})
+	return len(revoked), nil
+}
+
+// SessionBlacklist holds the sessions revoked within the last access token
+// lifetime, whose access tokens must no longer be accepted. Sessions
+// revoked by this replica are added at once; Refresh picks up the ones
+// revoked elsewhere, logouts and reuse detection included.
+type SessionBlacklist struct {
+	db  *sql.DB
+	log *logger.Logger
+	// ttl is the access token lifetime: after it no token of a revoked
+	// session can still be valid
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.RWMutex
+	revoked map[string]time.Time // session id -> when it can be forgotten
+}
+
+// NewSessionBlacklist creates an empty blacklist for access tokens living
+// accessTokenTTL
+func NewSessionBlacklist(db *sql.DB, log *logger.Logger, accessTokenTTL time.Duration) *SessionBlacklist {
+	return &SessionBlacklist{
+		db:      db,
+		log:     log,
+		ttl:     accessTokenTTL,
+		now:     time.Now,
+		revoked: make(map[string]time.Time),
+	}
+}
+
+// Revoke blacklists the sessions
+func (b *SessionBlacklist) Revoke(sessionIDs ...string) {
+	forgetAt := b.now().Add(b.ttl)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, id := range sessionIDs {
+		b.revoked[id] = forgetAt
+	}
+}
+
+// IsRevoked reports whether access tokens of the session must be rejected.
+// It implements middleware.RevokedSessions.
+func (b *SessionBlacklist) IsRevoked(sessionID string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	forgetAt, ok := b.revoked[sessionID]
+	return ok && b.now().Before(forgetAt)
+}
+
+// Refresh reloads the sessions ended within the access token lifetime and
+// forgets older ones. A session has ended when a token of it was revoked
+// without being rotated.
+func (b *SessionBlacklist) Refresh(ctx context.Context) error {
+	query := `
+		SELECT DISTINCT family_id FROM refresh_tokens
+		WHERE revoked_at > NOW() - make_interval(secs => $1) AND replaced_by_hash IS NULL
+	`
+	start := time.Now()
+	rows, err := b.db.QueryContext(ctx, query, b.ttl.Seconds())
+	b.log.LogDatabaseQuery(query, time.Since(start), err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load revoked sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan revoked session: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load revoked sessions: %w", err)
+	}
+
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, forgetAt := range b.revoked {
+		if !now.Before(forgetAt) {
+			delete(b.revoked, id)
+		}
+	}
+	for _, id := range ids {
+		if _, ok := b.revoked[id]; !ok {
+			b.revoked[id] = now.Add(b.ttl)
+		}
+	}
+	return nil
+}
+
+// Run refreshes the blacklist every 15 seconds until ctx is cancelled
+func (b *SessionBlacklist) Run(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	b.log.Info("Session blacklist refresh started")
+
+	if err := b.Refresh(ctx); err != nil {
+		b.log.Error("Failed to refresh session blacklist", "error", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := b.Refresh(ctx); err != nil {
+				b.log.Error("Failed to refresh session blacklist", "error", err)
+			}
+		case <-ctx.Done():
+			b.log.Info("Session blacklist refresh stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSessionID  = "6f1c2b1e-3d4a-4c5b-8e9f-0a1b2c3d4e5f"
+	otherSessionID = "9a8b7c6d-5e4f-4a3b-9c2d-1e0f9a8b7c6d"
+
+	chromeWindowsUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36"
+	safariIPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+)
+
+func setupSessionService(t *testing.T, log *logger.Logger) (*SessionService, *SessionBlacklist, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	blacklist := NewSessionBlacklist(db, log, 15*time.Minute)
+	return NewSessionService(db, log, blacklist), blacklist, mock
+}
+
+func TestSessionService_ListSessions(t *testing.T) {
+	service, _, mock := setupSessionService(t, logger.New())
+	started := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	latest := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// The current session has two active tokens after a concurrent refresh
+	mock.ExpectQuery(`FROM refresh_tokens rt\s+JOIN`).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id", "user_agent", "ip_address", "started_at", "created_at"}).
+			AddRow(testSessionID, chromeWindowsUA, "10.0.0.1", started, latest).
+			AddRow(testSessionID, chromeWindowsUA, "10.0.0.2", started, latest.Add(-time.Second)).
+			AddRow(otherSessionID, safariIPhoneUA, "10.0.0.3", started, latest.Add(-time.Hour)))
+
+	sessions, err := service.ListSessions(context.Background(), 42, testSessionID)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Session{
+		{ID: testSessionID, Device: "Chrome на Windows", IP: "10.0.0.1", CreatedAt: started, LastUsedAt: latest, Current: true},
+		{ID: otherSessionID, Device: "Safari на iPhone", IP: "10.0.0.3", CreatedAt: started, LastUsedAt: latest.Add(-time.Hour)},
+	}, sessions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionService_RevokeSession(t *testing.T) {
+	t.Run("blacklists the session", func(t *testing.T) {
+		service, blacklist, mock := setupSessionService(t, logger.New())
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
+			WithArgs(int64(42), otherSessionID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, service.RevokeSession(context.Background(), 42, otherSessionID))
+
+		assert.True(t, blacklist.IsRevoked(otherSessionID))
+		assert.False(t, blacklist.IsRevoked(testSessionID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("session of another user", func(t *testing.T) {
+		service, blacklist, mock := setupSessionService(t, logger.New())
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at").
+			WithArgs(int64(42), otherSessionID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := service.RevokeSession(context.Background(), 42, otherSessionID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.False(t, blacklist.IsRevoked(otherSessionID))
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, _, mock := setupSessionService(t, logger.New())
+
+		err := service.RevokeSession(context.Background(), 42, "not-a-uuid")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSessionService_RevokeOtherSessions(t *testing.T) {
+	var buf bytes.Buffer
+	service, blacklist, mock := setupSessionService(t, logger.New(logger.WithOutput(&buf), logger.WithEncoding(logger.EncodingJSON)))
+	mock.ExpectQuery(`UPDATE refresh_tokens SET revoked_at = NOW\(\)\s+WHERE user_id = \$1 AND revoked_at IS NULL AND family_id IS DISTINCT FROM`).
+		WithArgs(int64(42), testSessionID).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}).
+			AddRow(otherSessionID).
+			AddRow(otherSessionID))
+
+	revoked, err := service.RevokeOtherSessions(context.Background(), 42, testSessionID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, revoked)
+	assert.True(t, blacklist.IsRevoked(otherSessionID))
+	assert.False(t, blacklist.IsRevoked(testSessionID))
+	assert.Contains(t, buf.String(), `"event":"sessions_revoked"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionBlacklist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	blacklist := NewSessionBlacklist(db, logger.New(), 15*time.Minute)
+	blacklist.now = func() time.Time { return now }
+
+	blacklist.Revoke(testSessionID)
+	assert.True(t, blacklist.IsRevoked(testSessionID))
+
+	// Sessions ended on another replica are loaded
+	mock.ExpectQuery(`SELECT DISTINCT family_id FROM refresh_tokens`).
+		WithArgs(float64(15 * 60)).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}).AddRow(otherSessionID))
+	require.NoError(t, blacklist.Refresh(context.Background()))
+	assert.True(t, blacklist.IsRevoked(otherSessionID))
+	assert.True(t, blacklist.IsRevoked(testSessionID))
+
+	// Once every access token of the sessions has expired they are forgotten
+	now = now.Add(16 * time.Minute)
+	assert.False(t, blacklist.IsRevoked(testSessionID))
+	mock.ExpectQuery(`SELECT DISTINCT family_id FROM refresh_tokens`).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}))
+	require.NoError(t, blacklist.Refresh(context.Background()))
+	assert.Empty(t, blacklist.revoked)
+
+	mock.ExpectQuery(`SELECT DISTINCT family_id FROM refresh_tokens`).WillReturnError(sql.ErrConnDone)
+	assert.Error(t, blacklist.Refresh(context.Background()))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -7,19 +7,39 @@ import (
 	"fmt"
 )
 
+// DefaultTokenBytes is the default token length in bytes (256 bits)
+const DefaultTokenBytes = 32
+
 // TokenGenerator generates and validates cryptographically secure tokens
 // for password reset functionality. Tokens are generated with 256 bits of
-// entropy and stored as SHA-256 hashes for security.
+// entropy by default and stored as SHA-256 hashes for security.
 type TokenGenerator struct {
 	tokenLength int // Length in bytes (32 bytes = 256 bits)
 }
 
-// NewTokenGenerator creates a new TokenGenerator with default settings.
-// The default token length is 32 bytes (256 bits) for cryptographic security.
-func NewTokenGenerator() *TokenGenerator {
-	return &TokenGenerator{
-		tokenLength: 32, // 32 bytes = 256 bits of entropy
+// TokenOption configures a TokenGenerator
+type TokenOption func(*TokenGenerator)
+
+// WithTokenBytes sets the number of random bytes in generated tokens.
+// Non-positive values keep the default.
+func WithTokenBytes(n int) TokenOption {
+	return func(tg *TokenGenerator) {
+		if n > 0 {
+			tg.tokenLength = n
+		}
+	}
+}
+
+// NewTokenGenerator creates a new TokenGenerator. Without options tokens
+// are DefaultTokenBytes long for cryptographic security.
+func NewTokenGenerator(opts ...TokenOption) *TokenGenerator {
+	tg := &TokenGenerator{
+		tokenLength: DefaultTokenBytes,
+	}
+	for _, opt := range opts {
+		opt(tg)
 	}
+	return tg
 }
 
 // GenerateToken generates a cryptographically secure random token.
@@ -27,7 +47,7 @@ func NewTokenGenerator() *TokenGenerator {
 // token (to be stored in the database).
 //
 // The plain token is a hex-encoded string of random bytes, providing
-// 256 bits of entropy for security. The hashed token is a SHA-256 hash
+// 256 bits of entropy by default. The hashed token is a SHA-256 hash
 // of the plain token, ensuring tokens are never stored in plain text.
 //
 // Returns:
@@ -17,6 +17,22 @@ func TestNewTokenGenerator(t *testing.T) {
 	}
 }
 
+func TestWithTokenBytes(t *testing.T) {
+	tg := NewTokenGenerator(WithTokenBytes(48))
+
+	plain, _, err := tg.GenerateToken()
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+	if len(plain) != 96 {
+		t.Errorf("Expected plain token length 96, got %d", len(plain))
+	}
+
+	if tg := NewTokenGenerator(WithTokenBytes(0)); tg.tokenLength != DefaultTokenBytes {
+		t.Errorf("Expected non-positive length to keep the default, got %d", tg.tokenLength)
+	}
+}
+
 func TestGenerateToken(t *testing.T) {
 	tg := NewTokenGenerator()
 
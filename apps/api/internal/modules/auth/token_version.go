@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+)
+
+// TokenVersions reads the access token versions checked by
+// middleware.RequireTokenVersion
+type TokenVersions struct {
+	db *sql.DB
+}
+
+// NewTokenVersions creates a token version source
+func NewTokenVersions(db *sql.DB) *TokenVersions {
+	return &TokenVersions{db: db}
+}
+
+// TokenVersion returns the current token version of an active user
+func (v *TokenVersions) TokenVersion(ctx context.Context, userID int64) (int, error) {
+	var version int
+	err := v.db.QueryRowContext(ctx,
+		`SELECT token_version FROM users WHERE id = $1 AND deactivated_at IS NULL`, userID,
+	).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("TokenVersion: %w", apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read token version: %w", err)
+	}
+	return version, nil
+}
@@ -0,0 +1,66 @@
+package auth
+
+import "strings"
+
+// unknownDevice labels sessions whose user agent is missing or unrecognised
+const unknownDevice = "Неизвестное устройство"
+
+// userAgentRule maps a user agent substring to a label. Rules are checked
+// in order, so more specific tokens come first: Edge and Opera also send
+// "Chrome", and Chrome also sends "Safari".
+type userAgentRule struct {
+	token, label string
+}
+
+var browserRules = []userAgentRule{
+	{"YaBrowser/", "Яндекс Браузер"},
+	{"Edg/", "Edge"},
+	{"EdgA/", "Edge"},
+	{"EdgiOS/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"FxiOS/", "Firefox"},
+	{"CriOS/", "Chrome"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"okhttp/", "Приложение"},
+	{"CFNetwork/", "Приложение"},
+}
+
+var osRules = []userAgentRule{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"Macintosh", "macOS"},
+	{"Darwin", "iOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// describeUserAgent turns a User-Agent header into a short label for the
+// session list, such as "Chrome на Windows"
+func describeUserAgent(ua string) string {
+	browser := matchUserAgent(ua, browserRules)
+	os := matchUserAgent(ua, osRules)
+	switch {
+	case browser != "" && os != "":
+		return browser + " на " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return unknownDevice
+	}
+}
+
+func matchUserAgent(ua string, rules []userAgentRule) string {
+	for _, rule := range rules {
+		if strings.Contains(ua, rule.token) {
+			return rule.label
+		}
+	}
+	return ""
+}
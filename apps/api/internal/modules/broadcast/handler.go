@@ -0,0 +1,105 @@
+package broadcast
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles curator broadcast requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new broadcast handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateBroadcast handles POST /api/v1/curator/broadcast
+// Records the broadcast and returns 202; delivery happens in the background worker.
+func (h *Handler) CreateBroadcast(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateBroadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуются message и channels")
+		return
+	}
+
+	b, err := h.service.CreateBroadcast(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrEmptyMessage):
+			response.Error(c, http.StatusBadRequest, "Сообщение не может быть пустым")
+		case errors.Is(err, ErrMessageTooLong):
+			response.Error(c, http.StatusBadRequest, "Сообщение слишком длинное")
+		case errors.Is(err, ErrInvalidChannel), errors.Is(err, ErrNoChannels):
+			response.Error(c, http.StatusBadRequest, "Неверный канал: допустимы in_app, email, push")
+		case errors.Is(err, ErrNoRecipients):
+			response.Error(c, http.StatusUnprocessableEntity, "У вас нет активных клиентов")
+		default:
+			h.log.Error("Failed to create broadcast", "error", err, "curator_id", userID)
+			response.InternalError(c, "Не удалось отправить рассылку")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, b)
+}
+
+// ListBroadcasts handles GET /api/v1/curator/broadcasts?limit=50
+func (h *Handler) ListBroadcasts(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if l := c.Query("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed < 1 || parsed > 100 {
+			response.Error(c, http.StatusBadRequest, "Неверный параметр limit")
+			return
+		}
+		limit = parsed
+	}
+
+	broadcasts, err := h.service.ListBroadcasts(c.Request.Context(), userID, limit)
+	if err != nil {
+		h.log.Error("Failed to list broadcasts", "error", err, "curator_id", userID)
+		response.InternalError(c, "Не удалось загрузить рассылки")
+		return
+	}
+
+	response.Success(c, http.StatusOK, broadcasts)
+}
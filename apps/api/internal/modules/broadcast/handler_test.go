@@ -0,0 +1,140 @@
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	createFunc func(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error)
+	listFunc   func(ctx context.Context, curatorID int64, limit int) ([]Broadcast, error)
+}
+
+func (m *mockService) CreateBroadcast(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error) {
+	return m.createFunc(ctx, curatorID, req)
+}
+
+func (m *mockService) ListBroadcasts(ctx context.Context, curatorID int64, limit int) ([]Broadcast, error) {
+	return m.listFunc(ctx, curatorID, limit)
+}
+
+func setupTestHandler() (*Handler, *mockService) {
+	gin.SetMode(gin.TestMode)
+	mock := &mockService{}
+	return NewHandler(nil, logger.New(), mock), mock
+}
+
+func newContext(method, target string, body []byte, userID interface{}) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if userID != nil {
+		c.Set("user_id", userID)
+	}
+	return c, w
+}
+
+func TestHandlerCreateBroadcast(t *testing.T) {
+	t.Run("accepts broadcast for background delivery", func(t *testing.T) {
+		handler, mock := setupTestHandler()
+		mock.createFunc = func(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error) {
+			assert.Equal(t, int64(7), curatorID)
+			assert.Equal(t, []string{"in_app", "push"}, req.Channels)
+			return &Broadcast{ID: "b-1", Status: StatusPending, Recipients: 2}, nil
+		}
+
+		body, _ := json.Marshal(CreateBroadcastRequest{Message: "hello", Channels: []string{"in_app", "push"}})
+		c, w := newContext(http.MethodPost, "/curator/broadcast", body, int64(7))
+		handler.CreateBroadcast(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "b-1", resp["data"].(map[string]interface{})["id"])
+	})
+
+	errorCases := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"invalid channel", fmt.Errorf("%w: sms", ErrInvalidChannel), http.StatusBadRequest},
+		{"message too long", ErrMessageTooLong, http.StatusBadRequest},
+		{"no recipients", ErrNoRecipients, http.StatusUnprocessableEntity},
+		{"database error", fmt.Errorf("boom"), http.StatusInternalServerError},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, mock := setupTestHandler()
+			mock.createFunc = func(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error) {
+				return nil, tc.err
+			}
+
+			body, _ := json.Marshal(CreateBroadcastRequest{Message: "hello", Channels: []string{"in_app"}})
+			c, w := newContext(http.MethodPost, "/curator/broadcast", body, int64(7))
+			handler.CreateBroadcast(c)
+
+			assert.Equal(t, tc.status, w.Code)
+		})
+	}
+
+	t.Run("rejects missing channels", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+
+		c, w := newContext(http.MethodPost, "/curator/broadcast", []byte(`{"message":"hi"}`), int64(7))
+		handler.CreateBroadcast(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("requires authentication", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+
+		c, w := newContext(http.MethodPost, "/curator/broadcast", []byte(`{}`), nil)
+		handler.CreateBroadcast(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestHandlerListBroadcasts(t *testing.T) {
+	t.Run("returns broadcasts with stats", func(t *testing.T) {
+		handler, mock := setupTestHandler()
+		mock.listFunc = func(ctx context.Context, curatorID int64, limit int) ([]Broadcast, error) {
+			assert.Equal(t, 10, limit)
+			return []Broadcast{{ID: "b-1", Stats: DeliveryStats{Sent: 2, Failed: 1}}}, nil
+		}
+
+		c, w := newContext(http.MethodGet, "/curator/broadcasts?limit=10", nil, int64(7))
+		handler.ListBroadcasts(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		items := resp["data"].([]interface{})
+		require.Len(t, items, 1)
+		stats := items[0].(map[string]interface{})["stats"].(map[string]interface{})
+		assert.Equal(t, float64(1), stats["failed"])
+	})
+
+	t.Run("rejects invalid limit", func(t *testing.T) {
+		handler, _ := setupTestHandler()
+
+		c, w := newContext(http.MethodGet, "/curator/broadcasts?limit=abc", nil, int64(7))
+		handler.ListBroadcasts(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
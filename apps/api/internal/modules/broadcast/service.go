@@ -0,0 +1,463 @@
+package broadcast
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/ws"
+)
+
+// defaultBatchSize is the number of deliveries claimed per worker iteration
+const defaultBatchSize = 100
+
+// staleProcessingAfter is how long a claimed delivery may stay in "processing"
+// before another worker run picks it up again (e.g. after a crash mid-batch)
+const staleProcessingAfter = 10 * time.Minute
+
+// Notifier creates in-app notifications and exposes per-user channel mutes,
+// email preferences and quiet hours
+type Notifier interface {
+	CreateNotification(ctx context.Context, notification *notifications.Notification) error
+	GetMutedChannels(ctx context.Context, userIDs []int64) (map[int64]map[notifications.NotificationChannel]bool, error)
+	EmailAllowed(ctx context.Context, userID int64, kind notifications.EmailKind) (bool, error)
+	InQuietHours(ctx context.Context, userID int64) (bool, error)
+	CreateUnsubscribeToken(ctx context.Context, userID int64, kind notifications.EmailKind) (string, error)
+}
+
+// Mailer sends broadcast emails
+type Mailer interface {
+	SendCuratorBroadcastEmail(ctx context.Context, data email.CuratorBroadcastEmailData) error
+}
+
+// Pusher pushes real-time events to connected clients
+type Pusher interface {
+	SendToUser(userID int64, event ws.OutgoingEvent) bool
+}
+
+// ServiceInterface defines the interface for broadcast service operations
+type ServiceInterface interface {
+	CreateBroadcast(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error)
+	ListBroadcasts(ctx context.Context, curatorID int64, limit int) ([]Broadcast, error)
+}
+
+// Service handles curator broadcasts and their background fan-out
+type Service struct {
+	db        *database.DB
+	log       *logger.Logger
+	notifier  Notifier
+	mailer    Mailer
+	pusher    Pusher
+	batchSize int
+}
+
+// NewService creates a new broadcast service.
+// mailer and pusher may be nil; deliveries on those channels are then recorded as failed.
+func NewService(db *database.DB, log *logger.Logger, notifier Notifier, mailer Mailer, pusher Pusher) *Service {
+	return &Service{
+		db:        db,
+		log:       log,
+		notifier:  notifier,
+		mailer:    mailer,
+		pusher:    pusher,
+		batchSize: defaultBatchSize,
+	}
+}
+
+// normalizeChannels validates and de-duplicates requested channels, preserving order
+func normalizeChannels(channels []string) ([]string, error) {
+	if len(channels) == 0 {
+		return nil, ErrNoChannels
+	}
+	seen := make(map[string]bool, len(channels))
+	result := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		if !notifications.NotificationChannel(ch).IsValid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidChannel, ch)
+		}
+		if seen[ch] {
+			continue
+		}
+		seen[ch] = true
+		result = append(result, ch)
+	}
+	return result, nil
+}
+
+// CreateBroadcast records a broadcast and one pending delivery per active client and channel.
+// Actual delivery happens asynchronously in RunWorker.
+func (s *Service) CreateBroadcast(ctx context.Context, curatorID int64, req CreateBroadcastRequest) (*Broadcast, error) {
+	startTime := time.Now()
+
+	message := strings.TrimSpace(req.Message)
+	if message == "" {
+		return nil, ErrEmptyMessage
+	}
+	if utf8.RuneCountInString(message) > MaxMessageLength {
+		return nil, ErrMessageTooLong
+	}
+
+	channels, err := normalizeChannels(req.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	b := &Broadcast{
+		Message:  message,
+		Channels: channels,
+		Status:   StatusPending,
+	}
+
+	insertQuery := `
+		INSERT INTO curator_broadcasts (curator_id, message, channels)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	if err := tx.QueryRowContext(ctx, insertQuery, curatorID, message, strings.Join(channels, ",")).Scan(&b.ID, &b.CreatedAt); err != nil {
+		s.log.LogDatabaseQuery(insertQuery, time.Since(startTime), err, map[string]interface{}{
+			"curator_id": curatorID,
+		})
+		return nil, fmt.Errorf("failed to insert broadcast: %w", err)
+	}
+
+	// Snapshot recipients now so clients linked later don't receive an old announcement
+	deliveriesQuery := `
+		INSERT INTO curator_broadcast_deliveries (broadcast_id, client_id, channel)
+		SELECT $1, client_id, $2
+		FROM curator_client_relationships
+		WHERE curator_id = $3 AND status = 'active'
+	`
+	for _, ch := range channels {
+		res, err := tx.ExecContext(ctx, deliveriesQuery, b.ID, ch, curatorID)
+		if err != nil {
+			s.log.LogDatabaseQuery(deliveriesQuery, time.Since(startTime), err, map[string]interface{}{
+				"curator_id": curatorID,
+				"channel":    ch,
+			})
+			return nil, fmt.Errorf("failed to insert deliveries: %w", err)
+		}
+		affected, _ := res.RowsAffected()
+		b.Recipients = int(affected)
+		b.Stats.Pending += int(affected)
+	}
+
+	if b.Recipients == 0 {
+		return nil, ErrNoRecipients
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.LogBusinessEvent("curator_broadcast_created", map[string]interface{}{
+		"broadcast_id": b.ID,
+		"curator_id":   curatorID,
+		"recipients":   b.Recipients,
+		"channels":     channels,
+	})
+
+	return b, nil
+}
+
+// ListBroadcasts returns the curator's most recent broadcasts with delivery stats
+func (s *Service) ListBroadcasts(ctx context.Context, curatorID int64, limit int) ([]Broadcast, error) {
+	startTime := time.Now()
+
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	query := `
+		SELECT b.id, b.message, b.channels, b.status, b.created_at, b.completed_at,
+		       COUNT(DISTINCT d.client_id) AS recipients,
+		       COUNT(d.id) FILTER (WHERE d.status IN ('pending', 'processing')) AS pending,
+		       COUNT(d.id) FILTER (WHERE d.status = 'sent') AS sent,
+		       COUNT(d.id) FILTER (WHERE d.status = 'skipped') AS skipped,
+		       COUNT(d.id) FILTER (WHERE d.status = 'failed') AS failed
+		FROM curator_broadcasts b
+		LEFT JOIN curator_broadcast_deliveries d ON d.broadcast_id = b.id
+		WHERE b.curator_id = $1
+		GROUP BY b.id
+		ORDER BY b.created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, curatorID, limit)
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"curator_id": curatorID,
+		})
+		return nil, fmt.Errorf("failed to query broadcasts: %w", err)
+	}
+	defer rows.Close()
+
+	broadcasts := []Broadcast{}
+	for rows.Next() {
+		var b Broadcast
+		var channels string
+		var completedAt sql.NullTime
+		if err := rows.Scan(
+			&b.ID, &b.Message, &channels, &b.Status, &b.CreatedAt, &completedAt,
+			&b.Recipients, &b.Stats.Pending, &b.Stats.Sent, &b.Stats.Skipped, &b.Stats.Failed,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan broadcast: %w", err)
+		}
+		b.Channels = strings.Split(channels, ",")
+		if completedAt.Valid {
+			b.CompletedAt = &completedAt.Time
+		}
+		broadcasts = append(broadcasts, b)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating broadcasts: %w", err)
+	}
+
+	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+		"curator_id": curatorID,
+		"count":      len(broadcasts),
+	})
+
+	return broadcasts, nil
+}
+
+// ProcessPending claims one batch of pending deliveries, delivers them respecting
+// recipients' channel mutes, and records the per-recipient outcome.
+// Returns the number of deliveries processed.
+func (s *Service) ProcessPending(ctx context.Context) (int, error) {
+	startTime := time.Now()
+
+	deliveries, err := s.claimBatch(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(deliveries) == 0 {
+		return 0, nil
+	}
+
+	clientIDs := make([]int64, 0, len(deliveries))
+	seen := make(map[int64]bool)
+	for _, d := range deliveries {
+		if !seen[d.clientID] {
+			seen[d.clientID] = true
+			clientIDs = append(clientIDs, d.clientID)
+		}
+	}
+
+	// Claimed rows stay in "processing" on error and are retried once stale
+	muted, err := s.notifier.GetMutedChannels(ctx, clientIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load channel preferences: %w", err)
+	}
+
+	var stats DeliveryStats
+	for _, d := range deliveries {
+		status, errMsg := DeliverySent, ""
+		if muted[d.clientID][notifications.NotificationChannel(d.channel)] {
+			status, errMsg = DeliverySkipped, "channel muted by recipient"
+		} else if reason, err := s.skipReason(ctx, d); err != nil {
+			status, errMsg = DeliveryFailed, err.Error()
+		} else if reason != "" {
+			status, errMsg = DeliverySkipped, reason
+		} else if err := s.deliver(ctx, d); err != nil {
+			status, errMsg = DeliveryFailed, err.Error()
+			s.log.Warn("Broadcast delivery failed",
+				"broadcast_id", d.broadcastID,
+				"client_id", d.clientID,
+				"channel", d.channel,
+				"error", err,
+			)
+		}
+
+		if err := s.markDelivery(ctx, d.id, status, errMsg); err != nil {
+			return 0, err
+		}
+
+		switch status {
+		case DeliverySent:
+			stats.Sent++
+		case DeliverySkipped:
+			stats.Skipped++
+		case DeliveryFailed:
+			stats.Failed++
+		}
+	}
+
+	if err := s.completeFinishedBroadcasts(ctx); err != nil {
+		return 0, err
+	}
+
+	s.log.LogBusinessEvent("curator_broadcast_batch_processed", map[string]interface{}{
+		"processed":   len(deliveries),
+		"sent":        stats.Sent,
+		"skipped":     stats.Skipped,
+		"failed":      stats.Failed,
+		"duration_ms": time.Since(startTime).Milliseconds(),
+	})
+
+	return len(deliveries), nil
+}
+
+// claimBatch atomically marks a batch of pending (or stale processing) deliveries
+// as processing and returns them together with the data needed to send
+func (s *Service) claimBatch(ctx context.Context) ([]delivery, error) {
+	startTime := time.Now()
+
+	query := `
+		WITH claimed AS (
+			UPDATE curator_broadcast_deliveries
+			SET status = 'processing', attempted_at = NOW()
+			WHERE id IN (
+				SELECT id FROM curator_broadcast_deliveries
+				WHERE status = 'pending'
+				   OR (status = 'processing' AND attempted_at < $2)
+				ORDER BY created_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, broadcast_id, client_id, channel
+		)
+		SELECT c.id, c.broadcast_id, c.client_id, c.channel, b.message,
+		       COALESCE(cu.name, ''), u.email
+		FROM claimed c
+		JOIN curator_broadcasts b ON b.id = c.broadcast_id
+		JOIN users cu ON cu.id = b.curator_id
+		JOIN users u ON u.id = c.client_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, s.batchSize, time.Now().Add(-staleProcessingAfter))
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+		return nil, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []delivery
+	for rows.Next() {
+		var d delivery
+		if err := rows.Scan(&d.id, &d.broadcastID, &d.clientID, &d.channel, &d.message, &d.curatorName, &d.clientEmail); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// skipReason returns why a delivery should be skipped based on the
+// recipient's preferences, or "" to send it. In-app copies are never skipped
+// here: during quiet hours the notifications service holds them for the digest,
+// while real-time pushes are dropped.
+func (s *Service) skipReason(ctx context.Context, d delivery) (string, error) {
+	switch notifications.NotificationChannel(d.channel) {
+	case notifications.ChannelEmail:
+		allowed, err := s.notifier.EmailAllowed(ctx, d.clientID, notifications.EmailCoachActivity)
+		if err != nil {
+			return "", err
+		}
+		if !allowed {
+			return "email opted out by recipient", nil
+		}
+	case notifications.ChannelPush:
+		quiet, err := s.notifier.InQuietHours(ctx, d.clientID)
+		if err != nil {
+			return "", err
+		}
+		if quiet {
+			return "recipient in quiet hours", nil
+		}
+	}
+	return "", nil
+}
+
+// deliver sends a single delivery over its channel
+func (s *Service) deliver(ctx context.Context, d delivery) error {
+	switch notifications.NotificationChannel(d.channel) {
+	case notifications.ChannelInApp:
+		return s.notifier.CreateNotification(ctx, &notifications.Notification{
+			UserID:   d.clientID,
+			Category: notifications.CategoryMain,
+			Type:     notifications.TypeCuratorBroadcast,
+			Title:    "Сообщение от куратора",
+			Content:  d.message,
+		})
+	case notifications.ChannelEmail:
+		if s.mailer == nil {
+			return errChannelDisabled
+		}
+		token, err := s.notifier.CreateUnsubscribeToken(ctx, d.clientID, notifications.EmailCoachActivity)
+		if err != nil {
+			return err
+		}
+		return s.mailer.SendCuratorBroadcastEmail(ctx, email.CuratorBroadcastEmailData{
+			UserEmail:        d.clientEmail,
+			CuratorName:      d.curatorName,
+			Message:          d.message,
+			UnsubscribeToken: token,
+		})
+	case notifications.ChannelPush:
+		if s.pusher == nil {
+			return errChannelDisabled
+		}
+		sent := s.pusher.SendToUser(d.clientID, ws.OutgoingEvent{
+			Type: ws.EventCuratorBroadcast,
+			Data: map[string]interface{}{
+				"broadcast_id": d.broadcastID,
+				"curator_name": d.curatorName,
+				"message":      d.message,
+			},
+		})
+		if !sent {
+			return errRecipientOffline
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalidChannel, d.channel)
+}
+
+// markDelivery records the outcome of a delivery attempt
+func (s *Service) markDelivery(ctx context.Context, id, status, errMsg string) error {
+	query := `
+		UPDATE curator_broadcast_deliveries
+		SET status = $2, error = NULLIF($3, '')
+		WHERE id = $1
+	`
+	if _, err := s.db.ExecContext(ctx, query, id, status, errMsg); err != nil {
+		return fmt.Errorf("failed to update delivery %s: %w", id, err)
+	}
+	return nil
+}
+
+// completeFinishedBroadcasts marks broadcasts without outstanding deliveries as completed
+func (s *Service) completeFinishedBroadcasts(ctx context.Context) error {
+	query := `
+		UPDATE curator_broadcasts b
+		SET status = 'completed', completed_at = NOW()
+		WHERE b.status = 'pending'
+		  AND NOT EXISTS (
+			SELECT 1 FROM curator_broadcast_deliveries d
+			WHERE d.broadcast_id = b.id AND d.status IN ('pending', 'processing')
+		  )
+	`
+	if _, err := s.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to complete broadcasts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,373 @@
+package broadcast
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/ws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotifier records in-app notifications and serves fixed channel mutes,
+// email opt-outs and quiet hours
+type fakeNotifier struct {
+	muted     map[int64]map[notifications.NotificationChannel]bool
+	mutedErr  error
+	failFor   map[int64]bool
+	delivered []int64
+	optedOut  map[int64]bool
+	quiet     map[int64]bool
+}
+
+func (f *fakeNotifier) CreateNotification(ctx context.Context, n *notifications.Notification) error {
+	if f.failFor[n.UserID] {
+		return errors.New("insert failed")
+	}
+	f.delivered = append(f.delivered, n.UserID)
+	return nil
+}
+
+func (f *fakeNotifier) GetMutedChannels(ctx context.Context, userIDs []int64) (map[int64]map[notifications.NotificationChannel]bool, error) {
+	if f.mutedErr != nil {
+		return nil, f.mutedErr
+	}
+	if f.muted == nil {
+		return map[int64]map[notifications.NotificationChannel]bool{}, nil
+	}
+	return f.muted, nil
+}
+
+func (f *fakeNotifier) EmailAllowed(ctx context.Context, userID int64, kind notifications.EmailKind) (bool, error) {
+	return !f.optedOut[userID], nil
+}
+
+func (f *fakeNotifier) InQuietHours(ctx context.Context, userID int64) (bool, error) {
+	return f.quiet[userID], nil
+}
+
+func (f *fakeNotifier) CreateUnsubscribeToken(ctx context.Context, userID int64, kind notifications.EmailKind) (string, error) {
+	return fmt.Sprintf("token-%d", userID), nil
+}
+
+// fakeMailer records sent emails and fails for configured addresses
+type fakeMailer struct {
+	failFor map[string]bool
+	sent    []email.CuratorBroadcastEmailData
+}
+
+func (f *fakeMailer) SendCuratorBroadcastEmail(ctx context.Context, data email.CuratorBroadcastEmailData) error {
+	if f.failFor[data.UserEmail] {
+		return errors.New("smtp: connection refused")
+	}
+	f.sent = append(f.sent, data)
+	return nil
+}
+
+// fakePusher reports only the configured users as online
+type fakePusher struct {
+	online map[int64]bool
+}
+
+func (f *fakePusher) SendToUser(userID int64, event ws.OutgoingEvent) bool {
+	return f.online[userID]
+}
+
+func setupTestService(t *testing.T, notifier Notifier, mailer Mailer, pusher Pusher) (*Service, sqlmock.Sqlmock, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	db := &database.DB{DB: mockDB}
+	log := logger.New()
+
+	service := NewService(db, log, notifier, mailer, pusher)
+
+	return service, mock, func() { mockDB.Close() }
+}
+
+var claimColumns = []string{"id", "broadcast_id", "client_id", "channel", "message", "curator_name", "email"}
+
+func TestCreateBroadcast(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("creates broadcast with one delivery per client and channel", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+		defer cleanup()
+
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO curator_broadcasts").
+			WithArgs(int64(1), "В отпуске до понедельника", "in_app,email").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("b-1", now))
+		mock.ExpectExec("INSERT INTO curator_broadcast_deliveries").
+			WithArgs("b-1", "in_app", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("INSERT INTO curator_broadcast_deliveries").
+			WithArgs("b-1", "email", int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		b, err := service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{
+			Message:  "  В отпуске до понедельника ",
+			Channels: []string{"in_app", "email", "in_app"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, "b-1", b.ID)
+		assert.Equal(t, []string{"in_app", "email"}, b.Channels)
+		assert.Equal(t, 3, b.Recipients)
+		assert.Equal(t, 6, b.Stats.Pending)
+		assert.Equal(t, StatusPending, b.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back when curator has no active clients", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO curator_broadcasts").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("b-1", time.Now()))
+		mock.ExpectExec("INSERT INTO curator_broadcast_deliveries").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{Message: "hi", Channels: []string{"in_app"}})
+
+		assert.ErrorIs(t, err, ErrNoRecipients)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("validates input before touching the database", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+		defer cleanup()
+
+		_, err := service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{Message: "   ", Channels: []string{"in_app"}})
+		assert.ErrorIs(t, err, ErrEmptyMessage)
+
+		_, err = service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{Message: strings.Repeat("я", MaxMessageLength+1), Channels: []string{"in_app"}})
+		assert.ErrorIs(t, err, ErrMessageTooLong)
+
+		_, err = service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{Message: "hi", Channels: []string{"sms"}})
+		assert.ErrorIs(t, err, ErrInvalidChannel)
+
+		_, err = service.CreateBroadcast(ctx, 1, CreateBroadcastRequest{Message: "hi"})
+		assert.ErrorIs(t, err, ErrNoChannels)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListBroadcasts(t *testing.T) {
+	service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+	defer cleanup()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "message", "channels", "status", "created_at", "completed_at",
+		"recipients", "pending", "sent", "skipped", "failed",
+	}).
+		AddRow("b-2", "second", "push", StatusPending, now, nil, 2, 1, 1, 0, 0).
+		AddRow("b-1", "first", "in_app,email", StatusCompleted, now.Add(-time.Hour), now, 3, 0, 4, 1, 1)
+
+	mock.ExpectQuery("FROM curator_broadcasts b").
+		WithArgs(int64(1), 50).
+		WillReturnRows(rows)
+
+	broadcasts, err := service.ListBroadcasts(context.Background(), 1, 0)
+
+	require.NoError(t, err)
+	require.Len(t, broadcasts, 2)
+	assert.Nil(t, broadcasts[0].CompletedAt)
+	assert.Equal(t, []string{"in_app", "email"}, broadcasts[1].Channels)
+	assert.Equal(t, DeliveryStats{Pending: 0, Sent: 4, Skipped: 1, Failed: 1}, broadcasts[1].Stats)
+	require.NotNil(t, broadcasts[1].CompletedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestProcessPending(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("skips muted channels and sends the rest", func(t *testing.T) {
+		notifier := &fakeNotifier{
+			muted: map[int64]map[notifications.NotificationChannel]bool{
+				11: {notifications.ChannelEmail: true},
+			},
+		}
+		mailer := &fakeMailer{}
+		service, mock, cleanup := setupTestService(t, notifier, mailer, nil)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WithArgs(defaultBatchSize, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "in_app", "msg", "Coach", "a@example.com").
+				AddRow("d-2", "b-1", int64(11), "email", "msg", "Coach", "a@example.com").
+				AddRow("d-3", "b-1", int64(12), "email", "msg", "Coach", "b@example.com"))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-1", DeliverySent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-2", DeliverySkipped, "channel muted by recipient").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-3", DeliverySent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcasts b").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, processed)
+		assert.Equal(t, []int64{11}, notifier.delivered)
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "b@example.com", mailer.sent[0].UserEmail)
+		assert.Equal(t, "Coach", mailer.sent[0].CuratorName)
+		assert.Equal(t, "token-12", mailer.sent[0].UnsubscribeToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("skips email for recipients who opted out of curator emails", func(t *testing.T) {
+		notifier := &fakeNotifier{optedOut: map[int64]bool{11: true}}
+		mailer := &fakeMailer{}
+		service, mock, cleanup := setupTestService(t, notifier, mailer, nil)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "in_app", "msg", "Coach", "a@example.com").
+				AddRow("d-2", "b-1", int64(11), "email", "msg", "Coach", "a@example.com"))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-1", DeliverySent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-2", DeliverySkipped, "email opted out by recipient").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcasts b").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{11}, notifier.delivered)
+		assert.Empty(t, mailer.sent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("drops pushes to recipients in quiet hours", func(t *testing.T) {
+		notifier := &fakeNotifier{quiet: map[int64]bool{11: true}}
+		pusher := &fakePusher{online: map[int64]bool{11: true, 12: true}}
+		service, mock, cleanup := setupTestService(t, notifier, nil, pusher)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "push", "msg", "Coach", "a@example.com").
+				AddRow("d-2", "b-1", int64(12), "push", "msg", "Coach", "b@example.com"))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-1", DeliverySkipped, "recipient in quiet hours").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-2", DeliverySent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcasts b").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("records partial failures per recipient and keeps going", func(t *testing.T) {
+		notifier := &fakeNotifier{failFor: map[int64]bool{12: true}}
+		mailer := &fakeMailer{failFor: map[string]bool{"a@example.com": true}}
+		pusher := &fakePusher{online: map[int64]bool{12: true}}
+		service, mock, cleanup := setupTestService(t, notifier, mailer, pusher)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "email", "msg", "Coach", "a@example.com").
+				AddRow("d-2", "b-1", int64(12), "in_app", "msg", "Coach", "b@example.com").
+				AddRow("d-3", "b-1", int64(11), "push", "msg", "Coach", "a@example.com").
+				AddRow("d-4", "b-1", int64(12), "push", "msg", "Coach", "b@example.com"))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-1", DeliveryFailed, "smtp: connection refused").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-2", DeliveryFailed, "insert failed").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-3", DeliveryFailed, errRecipientOffline.Error()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-4", DeliverySent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcasts b").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 4, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("email channel fails when no mailer is configured", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "email", "msg", "Coach", "a@example.com"))
+		mock.ExpectExec("UPDATE curator_broadcast_deliveries").
+			WithArgs("d-1", DeliveryFailed, errChannelDisabled.Error()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_broadcasts b").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing to do", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{}, nil, nil)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns))
+
+		processed, err := service.ProcessPending(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 0, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("leaves batch claimed when preferences cannot be loaded", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeNotifier{mutedErr: errors.New("db down")}, nil, nil)
+		defer cleanup()
+
+		mock.ExpectQuery("WITH claimed AS").
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow("d-1", "b-1", int64(11), "in_app", "msg", "Coach", "a@example.com"))
+
+		_, err := service.ProcessPending(ctx)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
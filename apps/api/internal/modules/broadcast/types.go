@@ -0,0 +1,71 @@
+package broadcast
+
+import (
+	"errors"
+	"time"
+)
+
+// Broadcast statuses
+const (
+	StatusPending   = "pending"
+	StatusCompleted = "completed"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending    = "pending"
+	DeliveryProcessing = "processing"
+	DeliverySent       = "sent"
+	DeliverySkipped    = "skipped"
+	DeliveryFailed     = "failed"
+)
+
+// MaxMessageLength is the maximum broadcast message length in characters
+const MaxMessageLength = 2000
+
+var (
+	ErrEmptyMessage     = errors.New("message is required")
+	ErrMessageTooLong   = errors.New("message is too long")
+	ErrInvalidChannel   = errors.New("invalid channel")
+	ErrNoChannels       = errors.New("at least one channel is required")
+	ErrNoRecipients     = errors.New("no active clients")
+	errChannelDisabled  = errors.New("channel is not configured")
+	errRecipientOffline = errors.New("recipient is offline")
+)
+
+// CreateBroadcastRequest is the request body for POST /api/v1/curator/broadcast
+type CreateBroadcastRequest struct {
+	Message  string   `json:"message" binding:"required"`
+	Channels []string `json:"channels" binding:"required,min=1"`
+}
+
+// DeliveryStats aggregates per-recipient delivery results of a broadcast
+type DeliveryStats struct {
+	Pending int `json:"pending"`
+	Sent    int `json:"sent"`
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// Broadcast is a curator announcement sent to all active clients
+type Broadcast struct {
+	ID          string        `json:"id"`
+	Message     string        `json:"message"`
+	Channels    []string      `json:"channels"`
+	Status      string        `json:"status"`
+	Recipients  int           `json:"recipients"`
+	Stats       DeliveryStats `json:"stats"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+}
+
+// delivery is a claimed per-recipient, per-channel delivery row
+type delivery struct {
+	id          string
+	broadcastID string
+	clientID    int64
+	channel     string
+	message     string
+	curatorName string
+	clientEmail string
+}
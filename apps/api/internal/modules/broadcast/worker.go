@@ -0,0 +1,34 @@
+package broadcast
+
+import (
+	"context"
+	"time"
+)
+
+// RunWorker starts the broadcast fan-out loop. Every tick it drains pending
+// deliveries batch by batch. It blocks until the provided context is cancelled.
+func (s *Service) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("Broadcast worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessPending(ctx)
+				if err != nil {
+					s.log.Error("Failed to process broadcast deliveries", "error", err)
+					break
+				}
+				if processed < s.batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Broadcast worker stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package comments
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles curator comment requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new comments handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateComment handles POST /api/v1/curator/clients/:id/comments
+func (h *Handler) CreateComment(c *gin.Context) {
+	curatorID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	clientID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор клиента")
+		return
+	}
+
+	var req CreateCommentRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	comment, err := h.service.CreateComment(c.Request.Context(), curatorID, clientID, &req)
+	if err != nil {
+		h.respondError(c, err, curatorID)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, comment)
+}
+
+// ListComments handles GET /api/v1/nutrition/comments?from=&to=
+// Returns the curators' comments on the user's days in the range.
+func (h *Handler) ListComments(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	comments, err := h.service.ListComments(c.Request.Context(), userID, c.Query("from"), c.Query("to"))
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"comments": comments})
+}
+
+// MarkRead handles POST /api/v1/nutrition/comments/:id/read
+func (h *Handler) MarkRead(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.MarkRead(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Комментарий прочитан", nil)
+}
+
+func (h *Handler) respondError(c *gin.Context, err error, userID int64) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		validation.Respond(c, err)
+	case errors.Is(err, apperrors.ErrForbidden):
+		response.Forbidden(c, "Нет активной связи с данным клиентом")
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, "Комментарий не найден")
+	default:
+		h.log.Error("Failed to process comment request", "error", err, "user_id", userID, "comment_id", c.Param("id"))
+		response.InternalError(c, "Не удалось обработать запрос")
+	}
+}
@@ -0,0 +1,128 @@
+package comments
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err      error
+	userID   int64
+	clientID int64
+}
+
+func (m *mockService) CreateComment(ctx context.Context, curatorID, clientID int64, req *CreateCommentRequest) (*Comment, error) {
+	m.userID, m.clientID = curatorID, clientID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Comment{ID: testCommentID, CuratorID: curatorID, ClientID: clientID, Text: req.Text}, nil
+}
+
+func (m *mockService) ListComments(ctx context.Context, clientID int64, from, to string) ([]Comment, error) {
+	m.userID = clientID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Comment{}, nil
+}
+
+func (m *mockService) MarkRead(ctx context.Context, clientID int64, commentID string) error {
+	m.userID = clientID
+	return m.err
+}
+
+func newTestContext(method, path, body string, params gin.Params) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Params = params
+	return c, w
+}
+
+func TestHandlerCreateComment(t *testing.T) {
+	body := `{"date":"2026-03-01","text":"Маловато белка"}`
+
+	tests := []struct {
+		name     string
+		clientID string
+		body     string
+		err      error
+		code     int
+	}{
+		{"linked curator", "42", body, nil, http.StatusCreated},
+		{"unlinked curator", "42", body, apperrors.ErrForbidden, http.StatusForbidden},
+		{"bad client id", "abc", body, nil, http.StatusBadRequest},
+		{"missing text", "42", `{"date":"2026-03-01"}`, nil, http.StatusBadRequest},
+		{"invalid comment", "42", body, validation.Errors{"text": "Не более 2000 символов"}, http.StatusBadRequest},
+		{"internal", "42", body, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{err: tt.err}
+			handler := NewHandler(nil, logger.New(), svc)
+			c, w := newTestContext(http.MethodPost, "/curator/clients/"+tt.clientID+"/comments", tt.body,
+				gin.Params{{Key: "id", Value: tt.clientID}})
+			c.Set("user_id", testCuratorID)
+
+			handler.CreateComment(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusCreated {
+				assert.Equal(t, testCuratorID, svc.userID)
+				assert.Equal(t, testClientID, svc.clientID)
+			}
+		})
+	}
+}
+
+func TestHandlerClientEndpoints(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle func(*Handler) gin.HandlerFunc
+		err    error
+		code   int
+	}{
+		{"list", func(h *Handler) gin.HandlerFunc { return h.ListComments }, nil, http.StatusOK},
+		{"list bad range", func(h *Handler) gin.HandlerFunc { return h.ListComments }, validation.Errors{"to": "Неверный формат даты"}, http.StatusBadRequest},
+		{"mark read", func(h *Handler) gin.HandlerFunc { return h.MarkRead }, nil, http.StatusOK},
+		{"mark read of another client's comment", func(h *Handler) gin.HandlerFunc { return h.MarkRead }, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{err: tt.err}
+			handler := NewHandler(nil, logger.New(), svc)
+			c, w := newTestContext(http.MethodGet, "/nutrition/comments?from=2026-02-01&to=2026-03-01", "",
+				gin.Params{{Key: "id", Value: testCommentID}})
+			c.Set("user_id", testClientID)
+
+			tt.handle(handler)(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			// The client is always the authenticated user, never a parameter
+			assert.Equal(t, testClientID, svc.userID)
+		})
+	}
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{})
+		c, w := newTestContext(http.MethodGet, "/nutrition/comments", "", nil)
+
+		handler.ListComments(c)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
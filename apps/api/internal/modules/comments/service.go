@@ -0,0 +1,282 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// ServiceInterface defines the comments operations used by the handler
+type ServiceInterface interface {
+	CreateComment(ctx context.Context, curatorID, clientID int64, req *CreateCommentRequest) (*Comment, error)
+	ListComments(ctx context.Context, clientID int64, from, to string) ([]Comment, error)
+	MarkRead(ctx context.Context, clientID int64, commentID string) error
+}
+
+// Service handles curator comments. Every operation requires an active
+// curator-client relationship: a curator only comments on their clients and
+// a client only sees comments of their current curators.
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new comments service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{db: db, log: log}
+}
+
+// activeLink is the condition of a comment row c whose curator and client
+// are still linked
+const activeLink = `EXISTS (SELECT 1 FROM curator_client_relationships ccr
+	WHERE ccr.curator_id = c.curator_id AND ccr.client_id = c.client_id AND ccr.status = 'active')`
+
+const commentColumns = `c.id, c.curator_id, COALESCE(u.name, ''), c.client_id, c.entry_id, c.date::text, c.text, c.read_at, c.created_at`
+
+func scanComment(row interface{ Scan(...any) error }) (*Comment, error) {
+	var cm Comment
+	var entryID sql.NullString
+	if err := row.Scan(&cm.ID, &cm.CuratorID, &cm.CuratorName, &cm.ClientID, &entryID,
+		&cm.Date, &cm.Text, &cm.ReadAt, &cm.CreatedAt); err != nil {
+		return nil, err
+	}
+	if entryID.Valid {
+		cm.EntryID = &entryID.String
+	}
+	cm.Read = cm.ReadAt != nil
+	return &cm, nil
+}
+
+// validateComment checks a comment request and trims its text. Invalid
+// input is reported as validation.Errors.
+func validateComment(req *CreateCommentRequest) error {
+	errs := validation.Errors{}
+
+	req.Text = strings.TrimSpace(req.Text)
+	switch n := utf8.RuneCountInString(req.Text); {
+	case n == 0:
+		errs["text"] = "Обязательное поле"
+	case n > MaxTextLength:
+		errs["text"] = fmt.Sprintf("Не более %d символов", MaxTextLength)
+	}
+
+	switch {
+	case req.EntryID != nil && req.Date != nil:
+		errs["entry_id"] = "Укажите либо запись, либо дату"
+	case req.EntryID != nil:
+		if _, err := uuid.Parse(*req.EntryID); err != nil {
+			errs["entry_id"] = "Запись не найдена"
+		}
+	case req.Date != nil:
+		if _, err := time.Parse("2006-01-02", *req.Date); err != nil {
+			errs["date"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+		}
+	default:
+		errs["entry_id"] = "Укажите либо запись, либо дату"
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// verifyLink returns apperrors.ErrForbidden unless curatorID actively curates clientID
+func (s *Service) verifyLink(ctx context.Context, curatorID, clientID int64) error {
+	var linked bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM curator_client_relationships WHERE curator_id = $1 AND client_id = $2 AND status = 'active')`,
+		curatorID, clientID,
+	).Scan(&linked)
+	if err != nil {
+		return fmt.Errorf("failed to verify relationship: %w", err)
+	}
+	if !linked {
+		return apperrors.ErrForbidden
+	}
+	return nil
+}
+
+// CreateComment stores a curator's comment for a client. An entry comment
+// takes the entry's date; an entry of another user is reported as a
+// validation error like a missing one.
+func (s *Service) CreateComment(ctx context.Context, curatorID, clientID int64, req *CreateCommentRequest) (*Comment, error) {
+	if err := validateComment(req); err != nil {
+		return nil, err
+	}
+	if err := s.verifyLink(ctx, curatorID, clientID); err != nil {
+		return nil, err
+	}
+
+	date := ""
+	if req.EntryID != nil {
+		err := s.db.QueryRowContext(ctx,
+			`SELECT date::text FROM nutrition_entries WHERE id = $1 AND user_id = $2`,
+			*req.EntryID, clientID,
+		).Scan(&date)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, validation.Errors{"entry_id": "Запись не найдена"}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load entry: %w", err)
+		}
+	} else {
+		date = *req.Date
+	}
+
+	startTime := time.Now()
+	query := `
+		WITH c AS (
+			INSERT INTO curator_comments (curator_id, client_id, entry_id, date, text)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		)
+		SELECT ` + commentColumns + `
+		FROM c JOIN users u ON u.id = c.curator_id`
+
+	comment, err := scanComment(s.db.QueryRowContext(ctx, query, curatorID, clientID, req.EntryID, date, req.Text))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	s.log.LogBusinessEvent("curator_comment_created", map[string]interface{}{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+		"comment_id": comment.ID,
+	})
+	return comment, nil
+}
+
+// ListComments returns the comments on the client's days from from to to
+// (inclusive, YYYY-MM-DD), oldest first. Comments of curators the client is
+// no longer linked with and comments on deleted entries are left out.
+func (s *Service) ListComments(ctx context.Context, clientID int64, from, to string) ([]Comment, error) {
+	fromDate, fromErr := time.Parse("2006-01-02", from)
+	toDate, toErr := time.Parse("2006-01-02", to)
+	errs := validation.Errors{}
+	if fromErr != nil {
+		errs["from"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	}
+	if toErr != nil {
+		errs["to"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	}
+	if len(errs) == 0 {
+		switch {
+		case toDate.Before(fromDate):
+			errs["to"] = "Дата окончания раньше даты начала"
+		case toDate.Sub(fromDate) > MaxRangeDays*24*time.Hour:
+			errs["to"] = fmt.Sprintf("Период не может быть длиннее %d дней", MaxRangeDays)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT ` + commentColumns + `
+		FROM curator_comments c
+		JOIN users u ON u.id = c.curator_id
+		WHERE c.client_id = $1 AND c.date >= $2 AND c.date <= $3
+		  AND c.deleted_at IS NULL AND ` + activeLink + `
+		ORDER BY c.date, c.created_at, c.id`
+
+	rows, err := s.db.QueryContext(ctx, query, clientID, from, to)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"client_id": clientID,
+		"from":      from,
+		"to":        to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]Comment, 0)
+	for rows.Next() {
+		comment, err := scanComment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, *comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating comments: %w", err)
+	}
+	return comments, nil
+}
+
+// MarkRead marks a comment addressed to the client as read. Marking it again
+// keeps the first read time. Comments of other clients, of unlinked curators
+// and deleted ones are reported as apperrors.ErrNotFound.
+func (s *Service) MarkRead(ctx context.Context, clientID int64, commentID string) error {
+	if _, err := uuid.Parse(commentID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `
+		UPDATE curator_comments c SET read_at = COALESCE(c.read_at, NOW())
+		WHERE c.id = $1 AND c.client_id = $2 AND c.deleted_at IS NULL AND ` + activeLink
+
+	result, err := s.db.ExecContext(ctx, query, commentID, clientID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"client_id":  clientID,
+		"comment_id": commentID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark comment read: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to mark comment read: %w", err)
+	} else if n == 0 {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// UnreadCount returns how many comments on date (YYYY-MM-DD) the client has
+// not read yet, counting only comments they can list
+func (s *Service) UnreadCount(ctx context.Context, clientID int64, date string) (int, error) {
+	startTime := time.Now()
+	query := `
+		SELECT COUNT(*) FROM curator_comments c
+		WHERE c.client_id = $1 AND c.date = $2 AND c.read_at IS NULL
+		  AND c.deleted_at IS NULL AND ` + activeLink
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, clientID, date).Scan(&count)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"client_id": clientID,
+		"date":      date,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread comments: %w", err)
+	}
+	return count, nil
+}
+
+// DeleteForEntry soft-deletes the comments on an entry. It runs in the
+// transaction deleting the entry.
+func DeleteForEntry(ctx context.Context, tx *sql.Tx, entryID string) error {
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE curator_comments SET deleted_at = NOW() WHERE entry_id = $1 AND deleted_at IS NULL`,
+		entryID); err != nil {
+		return fmt.Errorf("failed to delete entry comments: %w", err)
+	}
+	return nil
+}
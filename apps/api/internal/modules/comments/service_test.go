@@ -0,0 +1,224 @@
+package comments
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testCommentID = "5a6b7c8d-9e0f-4a1b-8c2d-3e4f5a6b7c8d"
+	testEntryID   = "1f2e3d4c-5b6a-4978-8695-a4b3c2d1e0f9"
+	testCuratorID = int64(7)
+	testClientID  = int64(42)
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+const linkRe = "SELECT EXISTS \\(SELECT 1 FROM curator_client_relationships WHERE curator_id = \\$1 AND client_id = \\$2 AND status = 'active'\\)"
+
+func strPtr(v string) *string { return &v }
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+func commentRows(entryID *string, date string, readAt *time.Time) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "curator_id", "name", "client_id", "entry_id", "date", "text", "read_at", "created_at"}).
+		AddRow(testCommentID, testCuratorID, "Анна", testClientID, entryID, date, "Маловато белка", readAt, testNow)
+}
+
+func expectLink(mock sqlmock.Sqlmock, linked bool) {
+	mock.ExpectQuery(linkRe).
+		WithArgs(testCuratorID, testClientID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(linked))
+}
+
+func TestValidateComment(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    CreateCommentRequest
+		fields []string
+	}{
+		{"entry comment", CreateCommentRequest{EntryID: strPtr(testEntryID), Text: "Отлично"}, nil},
+		{"day comment", CreateCommentRequest{Date: strPtr("2026-03-01"), Text: "Отлично"}, nil},
+		{"longest text", CreateCommentRequest{Date: strPtr("2026-03-01"), Text: strings.Repeat("я", MaxTextLength)}, nil},
+		{"text too long", CreateCommentRequest{Date: strPtr("2026-03-01"), Text: strings.Repeat("я", MaxTextLength+1)}, []string{"text"}},
+		{"blank text", CreateCommentRequest{Date: strPtr("2026-03-01"), Text: "  "}, []string{"text"}},
+		{"neither entry nor date", CreateCommentRequest{Text: "Отлично"}, []string{"entry_id"}},
+		{"both entry and date", CreateCommentRequest{EntryID: strPtr(testEntryID), Date: strPtr("2026-03-01"), Text: "Отлично"}, []string{"entry_id"}},
+		{"bad entry id", CreateCommentRequest{EntryID: strPtr("42"), Text: "Отлично"}, []string{"entry_id"}},
+		{"bad date", CreateCommentRequest{Date: strPtr("01.03.2026"), Text: "Отлично"}, []string{"date"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateComment(&tt.req)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+}
+
+func TestService_CreateComment(t *testing.T) {
+	t.Run("entry comment takes the entry's date", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLink(mock, true)
+		mock.ExpectQuery("SELECT date::text FROM nutrition_entries WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(testEntryID, testClientID).
+			WillReturnRows(sqlmock.NewRows([]string{"date"}).AddRow("2026-02-28"))
+		mock.ExpectQuery("INSERT INTO curator_comments").
+			WithArgs(testCuratorID, testClientID, strPtr(testEntryID), "2026-02-28", "Маловато белка").
+			WillReturnRows(commentRows(strPtr(testEntryID), "2026-02-28", nil))
+
+		comment, err := service.CreateComment(context.Background(), testCuratorID, testClientID,
+			&CreateCommentRequest{EntryID: strPtr(testEntryID), Text: " Маловато белка "})
+
+		require.NoError(t, err)
+		assert.Equal(t, "2026-02-28", comment.Date)
+		assert.Equal(t, "Анна", comment.CuratorName)
+		assert.False(t, comment.Read)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("day comment", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLink(mock, true)
+		mock.ExpectQuery("INSERT INTO curator_comments").
+			WithArgs(testCuratorID, testClientID, nil, "2026-03-01", "Маловато белка").
+			WillReturnRows(commentRows(nil, "2026-03-01", nil))
+
+		comment, err := service.CreateComment(context.Background(), testCuratorID, testClientID,
+			&CreateCommentRequest{Date: strPtr("2026-03-01"), Text: "Маловато белка"})
+
+		require.NoError(t, err)
+		assert.Nil(t, comment.EntryID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("curator without an active link", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLink(mock, false)
+
+		_, err := service.CreateComment(context.Background(), testCuratorID, testClientID,
+			&CreateCommentRequest{Date: strPtr("2026-03-01"), Text: "Маловато белка"})
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("entry of someone else", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLink(mock, true)
+		mock.ExpectQuery("SELECT date::text FROM nutrition_entries").
+			WithArgs(testEntryID, testClientID).
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := service.CreateComment(context.Background(), testCuratorID, testClientID,
+			&CreateCommentRequest{EntryID: strPtr(testEntryID), Text: "Маловато белка"})
+
+		assert.Equal(t, validation.Errors{"entry_id": "Запись не найдена"}, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_ListComments(t *testing.T) {
+	t.Run("only linked curators, not deleted", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		readAt := testNow.Add(time.Hour)
+		mock.ExpectQuery("FROM curator_comments c (.+) WHERE c.client_id = \\$1 AND c.date >= \\$2 AND c.date <= \\$3\\s+AND c.deleted_at IS NULL AND EXISTS \\(SELECT 1 FROM curator_client_relationships").
+			WithArgs(testClientID, "2026-02-01", "2026-03-01").
+			WillReturnRows(commentRows(nil, "2026-03-01", &readAt))
+
+		comments, err := service.ListComments(context.Background(), testClientID, "2026-02-01", "2026-03-01")
+
+		require.NoError(t, err)
+		require.Len(t, comments, 1)
+		assert.True(t, comments[0].Read)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid range", func(t *testing.T) {
+		service, _ := setupTestService(t)
+
+		_, err := service.ListComments(context.Background(), testClientID, "2026-03-01", "2026-02-01")
+		assert.Equal(t, validation.Errors{"to": "Дата окончания раньше даты начала"}, err)
+
+		_, err = service.ListComments(context.Background(), testClientID, "", "2026-02-01")
+		assert.Contains(t, err, "from")
+
+		_, err = service.ListComments(context.Background(), testClientID, "2024-01-01", "2026-02-01")
+		assert.Contains(t, err, "to")
+	})
+}
+
+func TestService_MarkRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      string
+		updated int64
+		wantErr error
+	}{
+		{"own comment", testCommentID, 1, nil},
+		// another client's, an unlinked curator's or a deleted comment
+		{"not visible to the client", testCommentID, 0, apperrors.ErrNotFound},
+		{"malformed id", "abc", -1, apperrors.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock := setupTestService(t)
+			if tt.updated >= 0 {
+				mock.ExpectExec("UPDATE curator_comments c SET read_at = COALESCE\\(c.read_at, NOW\\(\\)\\)\\s+WHERE c.id = \\$1 AND c.client_id = \\$2 AND c.deleted_at IS NULL AND EXISTS").
+					WithArgs(tt.id, testClientID).
+					WillReturnResult(sqlmock.NewResult(0, tt.updated))
+			}
+
+			err := service.MarkRead(context.Background(), testClientID, tt.id)
+
+			if tt.wantErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestService_UnreadCount(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM curator_comments c\\s+WHERE c.client_id = \\$1 AND c.date = \\$2 AND c.read_at IS NULL").
+		WithArgs(testClientID, "2026-03-01").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := service.UnreadCount(context.Background(), testClientID, "2026-03-01")
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
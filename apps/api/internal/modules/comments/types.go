@@ -0,0 +1,34 @@
+package comments
+
+import "time"
+
+// Comment limits
+const (
+	MaxTextLength = 2000
+	// MaxRangeDays bounds the from..to range of a listing
+	MaxRangeDays = 366
+)
+
+// CreateCommentRequest represents a curator's comment on a client's
+// nutrition entry or, when only date is set, on the whole day. Exactly one
+// of entry_id and date must be given.
+type CreateCommentRequest struct {
+	EntryID *string `json:"entry_id"`
+	Date    *string `json:"date"`
+	Text    string  `json:"text" binding:"required"`
+}
+
+// Comment is a curator's comment as shown to the client. EntryID is nil for
+// comments on a whole day.
+type Comment struct {
+	ID          string     `json:"id"`
+	CuratorID   int64      `json:"curator_id"`
+	CuratorName string     `json:"curator_name"`
+	ClientID    int64      `json:"client_id"`
+	EntryID     *string    `json:"entry_id,omitempty"`
+	Date        string     `json:"date"`
+	Text        string     `json:"text"`
+	Read        bool       `json:"read"`
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -1,12 +1,15 @@
 package curator
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/burcev/api/internal/config"
 	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
@@ -631,3 +634,121 @@ func (h *Handler) GetWeeklyPlans(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, plans)
 }
+
+// CreateInvite handles POST /api/v1/curator/invites
+// Generates a one-time code the curator passes to a client to link accounts.
+func (h *Handler) CreateInvite(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	invite, err := h.service.CreateInvite(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to create invite", "error", err, "curator_id", userID)
+		response.InternalError(c, "Не удалось создать приглашение")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, invite)
+}
+
+// GetClientNutrition handles GET /api/v1/curator/clients/:id/nutrition?from=YYYY-MM-DD&to=YYYY-MM-DD
+// Returns the client's food entries read-only. Defaults to the last 7 days; the range is capped at 31 days.
+func (h *Handler) GetClientNutrition(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	clientID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор клиента")
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			response.Error(c, http.StatusBadRequest, "Неверный формат даты, ожидается YYYY-MM-DD")
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -6)
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			response.Error(c, http.StatusBadRequest, "Неверный формат даты, ожидается YYYY-MM-DD")
+			return
+		}
+	}
+	if from.After(to) || to.Sub(from) >= MaxNutritionRangeDays*24*time.Hour {
+		response.Error(c, http.StatusBadRequest, "Период должен быть от 1 до 31 дня")
+		return
+	}
+
+	nutrition, err := h.service.GetClientNutrition(c.Request.Context(), userID, clientID,
+		from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		if errors.Is(err, apperrors.ErrForbidden) {
+			response.Forbidden(c, "Нет активной связи с данным клиентом")
+			return
+		}
+		h.log.Error("Failed to get client nutrition", "error", err, "curator_id", userID, "client_id", clientID)
+		response.InternalError(c, "Не удалось загрузить питание клиента")
+		return
+	}
+
+	response.Success(c, http.StatusOK, nutrition)
+}
+
+// LinkCurator handles POST /api/v1/users/link-curator
+// Body: {"code": "ABCD2345"}. Links the client to the curator who issued the invite.
+func (h *Handler) LinkCurator(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req RedeemInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Укажите код приглашения")
+		return
+	}
+
+	link, err := h.service.RedeemInvite(c.Request.Context(), userID, strings.ToUpper(strings.TrimSpace(req.Code)))
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInviteInvalid):
+			response.Error(c, http.StatusBadRequest, "Код приглашения недействителен или истёк")
+		case errors.Is(err, ErrNotClient):
+			response.Forbidden(c, "Привязаться к куратору может только клиент")
+		default:
+			h.log.Error("Failed to redeem invite", "error", err, "user_id", userID)
+			response.InternalError(c, "Не удалось привязаться к куратору")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, link)
+}
+
+// UnlinkCurator handles DELETE /api/v1/users/link-curator
+// Ends the client's relationship with their curator.
+func (h *Handler) UnlinkCurator(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnlinkCurator(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Куратор не назначен")
+			return
+		}
+		h.log.Error("Failed to unlink curator", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось отвязаться от куратора")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"message": "Куратор отвязан"})
+}
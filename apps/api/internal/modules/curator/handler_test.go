@@ -5,11 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -37,6 +39,10 @@ type mockCuratorService struct {
 	getAnalyticsHistoryFunc  func(ctx context.Context, curatorID int64, period string, count int) (any, error)
 	getBenchmarkFunc         func(ctx context.Context, curatorID int64, weeks int) (*BenchmarkData, error)
 	collectDailySnapshotFunc func(ctx context.Context, curatorID int64) error
+	createInviteFunc         func(ctx context.Context, curatorID int64) (*Invite, error)
+	redeemInviteFunc         func(ctx context.Context, clientID int64, code string) (*CuratorLink, error)
+	unlinkCuratorFunc        func(ctx context.Context, clientID int64) error
+	getClientNutritionFunc   func(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error)
 }
 
 func (m *mockCuratorService) GetClients(ctx context.Context, curatorID int64) ([]ClientCard, error) {
@@ -172,6 +178,34 @@ func (m *mockCuratorService) CollectDailySnapshot(ctx context.Context, curatorID
 	return nil
 }
 
+func (m *mockCuratorService) CreateInvite(ctx context.Context, curatorID int64) (*Invite, error) {
+	if m.createInviteFunc != nil {
+		return m.createInviteFunc(ctx, curatorID)
+	}
+	return &Invite{}, nil
+}
+
+func (m *mockCuratorService) RedeemInvite(ctx context.Context, clientID int64, code string) (*CuratorLink, error) {
+	if m.redeemInviteFunc != nil {
+		return m.redeemInviteFunc(ctx, clientID, code)
+	}
+	return &CuratorLink{}, nil
+}
+
+func (m *mockCuratorService) UnlinkCurator(ctx context.Context, clientID int64) error {
+	if m.unlinkCuratorFunc != nil {
+		return m.unlinkCuratorFunc(ctx, clientID)
+	}
+	return nil
+}
+
+func (m *mockCuratorService) GetClientNutrition(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error) {
+	if m.getClientNutritionFunc != nil {
+		return m.getClientNutritionFunc(ctx, curatorID, clientID, from, to)
+	}
+	return &ClientNutrition{}, nil
+}
+
 func setupCuratorTestHandler() (*Handler, *mockCuratorService) {
 	gin.SetMode(gin.TestMode)
 	cfg := &config.Config{}
@@ -1771,3 +1805,137 @@ func TestHandler_GetBenchmark(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
+
+func TestHandler_CreateInvite(t *testing.T) {
+	handler, mock := setupCuratorTestHandler()
+	mock.createInviteFunc = func(ctx context.Context, curatorID int64) (*Invite, error) {
+		assert.Equal(t, int64(1), curatorID)
+		return &Invite{Code: "ABCD2345"}, nil
+	}
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/curator/invites", nil)
+	c.Set("user_id", int64(1))
+
+	handler.CreateInvite(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), "ABCD2345")
+}
+
+func TestHandler_GetClientNutrition(t *testing.T) {
+	newContext := func(query string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/curator/clients/10/nutrition"+query, nil)
+		c.Params = gin.Params{{Key: "id", Value: "10"}}
+		c.Set("user_id", int64(1))
+		return c, w
+	}
+
+	t.Run("passes the date range", func(t *testing.T) {
+		handler, mock := setupCuratorTestHandler()
+		var gotFrom, gotTo string
+		mock.getClientNutritionFunc = func(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error) {
+			gotFrom, gotTo = from, to
+			return &ClientNutrition{ClientID: clientID}, nil
+		}
+
+		c, w := newContext("?to=2026-10-15")
+		handler.GetClientNutrition(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2026-10-09", gotFrom)
+		assert.Equal(t, "2026-10-15", gotTo)
+	})
+
+	t.Run("range too long returns 400", func(t *testing.T) {
+		handler, _ := setupCuratorTestHandler()
+
+		c, w := newContext("?from=2026-09-01&to=2026-10-15")
+		handler.GetClientNutrition(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("no relationship returns 403", func(t *testing.T) {
+		handler, mock := setupCuratorTestHandler()
+		mock.getClientNutritionFunc = func(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error) {
+			return nil, fmt.Errorf("verifyRelationship: %w", apperrors.ErrForbidden)
+		}
+
+		c, w := newContext("")
+		handler.GetClientNutrition(c)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+}
+
+func TestHandler_LinkCurator(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"success", `{"code":" abcd2345 "}`, nil, http.StatusOK},
+		{"missing code", `{}`, nil, http.StatusBadRequest},
+		{"invalid code", `{"code":"ABCD2345"}`, ErrInviteInvalid, http.StatusBadRequest},
+		{"not a client", `{"code":"ABCD2345"}`, ErrNotClient, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock := setupCuratorTestHandler()
+			mock.redeemInviteFunc = func(ctx context.Context, clientID int64, code string) (*CuratorLink, error) {
+				assert.Equal(t, "ABCD2345", code)
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &CuratorLink{CuratorID: 1}, nil
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/users/link-curator", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", int64(10))
+
+			handler.LinkCurator(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestHandler_UnlinkCurator(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		handler, _ := setupCuratorTestHandler()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/users/link-curator", nil)
+		c.Set("user_id", int64(10))
+
+		handler.UnlinkCurator(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("no curator returns 404", func(t *testing.T) {
+		handler, mock := setupCuratorTestHandler()
+		mock.unlinkCuratorFunc = func(ctx context.Context, clientID int64) error {
+			return fmt.Errorf("UnlinkCurator: %w", apperrors.ErrNotFound)
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/users/link-curator", nil)
+		c.Set("user_id", int64(10))
+
+		handler.UnlinkCurator(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,279 @@
+package curator
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
+)
+
+// inviteAlphabet omits characters that are easy to confuse when typed (0/O, 1/I/L)
+const inviteAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+const inviteCodeLength = 8
+
+// generateInviteCode returns a random invite code
+func generateInviteCode() (string, error) {
+	code := make([]byte, inviteCodeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(inviteAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		code[i] = inviteAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// CreateInvite generates a one-time invite code for the curator
+func (s *Service) CreateInvite(ctx context.Context, curatorID int64) (*Invite, error) {
+	startTime := time.Now()
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %w", err)
+	}
+
+	query := `
+		INSERT INTO curator_invites (code, curator_id, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING expires_at, created_at
+	`
+
+	invite := &Invite{Code: code}
+	err = s.db.QueryRowContext(ctx, query, code, curatorID, time.Now().Add(InviteTTL)).
+		Scan(&invite.ExpiresAt, &invite.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]any{
+		"curator_id": curatorID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	s.log.LogBusinessEvent("curator_invite_created", map[string]any{
+		"curator_id": curatorID,
+	})
+
+	return invite, nil
+}
+
+// RedeemInvite links the client to the curator who issued the code, replacing
+// any existing curator. Each code can be redeemed once.
+func (s *Service) RedeemInvite(ctx context.Context, clientID int64, code string) (*CuratorLink, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id = $1`, clientID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("RedeemInvite.client: %w", apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user role: %w", err)
+	}
+	if role != "client" {
+		return nil, ErrNotClient
+	}
+
+	var inviteID string
+	var link CuratorLink
+	err = tx.QueryRowContext(ctx, `
+		SELECT ci.id, ci.curator_id, COALESCE(u.name, '')
+		FROM curator_invites ci
+		JOIN users u ON u.id = ci.curator_id AND u.role = 'coordinator'
+		WHERE ci.code = $1 AND ci.redeemed_at IS NULL AND ci.expires_at > NOW()
+		FOR UPDATE OF ci
+	`, code).Scan(&inviteID, &link.CuratorID, &link.CuratorName)
+	if err == sql.ErrNoRows {
+		s.log.LogSecurityEvent("curator_invite_rejected", "low", map[string]any{
+			"client_id": clientID,
+		})
+		return nil, ErrInviteInvalid
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	// A client has a single active curator
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE curator_client_relationships SET status = 'inactive', updated_at = NOW()
+		WHERE client_id = $1 AND curator_id != $2 AND status = 'active'
+	`, clientID, link.CuratorID); err != nil {
+		return nil, fmt.Errorf("failed to deactivate old relationship: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO curator_client_relationships (curator_id, client_id, status)
+		VALUES ($1, $2, 'active')
+		ON CONFLICT (curator_id, client_id) DO UPDATE SET status = 'active', updated_at = NOW()
+	`, link.CuratorID, clientID); err != nil {
+		return nil, fmt.Errorf("failed to create relationship: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO conversations (client_id, curator_id)
+		VALUES ($1, $2)
+		ON CONFLICT (client_id, curator_id) DO NOTHING
+	`, clientID, link.CuratorID); err != nil {
+		return nil, fmt.Errorf("failed to create conversation: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE curator_invites SET redeemed_by = $2, redeemed_at = NOW() WHERE id = $1
+	`, inviteID, clientID); err != nil {
+		return nil, fmt.Errorf("failed to mark invite redeemed: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.LogBusinessEvent("curator_invite_redeemed", map[string]any{
+		"curator_id": link.CuratorID,
+		"client_id":  clientID,
+	})
+
+	return &link, nil
+}
+
+// UnlinkCurator ends the client's active curator relationship. The curator
+// loses access to the client's data immediately.
+func (s *Service) UnlinkCurator(ctx context.Context, clientID int64) error {
+	startTime := time.Now()
+
+	query := `
+		UPDATE curator_client_relationships SET status = 'inactive', updated_at = NOW()
+		WHERE client_id = $1 AND status = 'active'
+		RETURNING curator_id
+	`
+
+	var curatorID int64
+	err := s.db.QueryRowContext(ctx, query, clientID).Scan(&curatorID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]any{
+		"client_id": clientID,
+	})
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("UnlinkCurator: %w", apperrors.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to unlink curator: %w", err)
+	}
+
+	s.log.LogBusinessEvent("curator_unlinked", map[string]any{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+	})
+
+	return nil
+}
+
+// GetClientNutrition returns the client's food entries for a date range.
+// The relationship is verified here rather than trusting the role middleware,
+// and every access attempt is recorded as a security event.
+func (s *Service) GetClientNutrition(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error) {
+	if err := s.verifyRelationship(ctx, curatorID, clientID); err != nil {
+		if errors.Is(err, apperrors.ErrForbidden) {
+			s.log.LogSecurityEvent("curator_client_access_denied", "medium", map[string]any{
+				"curator_id": curatorID,
+				"client_id":  clientID,
+				"resource":   "nutrition",
+			})
+			s.audit.Record(ctx, audit.Entry{
+				UserID: &clientID,
+				Action: audit.ActionCoachDataAccessDenied,
+				Metadata: map[string]any{
+					"curator_id": curatorID,
+					"resource":   "nutrition",
+				},
+			})
+		}
+		return nil, err
+	}
+
+	s.log.LogSecurityEvent("curator_client_data_access", "low", map[string]any{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+		"resource":   "nutrition",
+		"from":       from,
+		"to":         to,
+	})
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &clientID,
+		Action: audit.ActionCoachDataAccess,
+		Metadata: map[string]any{
+			"curator_id": curatorID,
+			"resource":   "nutrition",
+			"from":       from,
+			"to":         to,
+		},
+	})
+
+	startTime := time.Now()
+	query := `
+		SELECT id, food_name, meal_type, calories, protein, fat, carbs, portion_amount, created_by, created_at, date
+		FROM food_entries
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		ORDER BY date DESC, created_at ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, clientID, from, to)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]any{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food entries: %w", err)
+	}
+	defer rows.Close()
+
+	result := &ClientNutrition{
+		ClientID: clientID,
+		From:     from,
+		To:       to,
+		Days:     make([]NutritionDayView, 0),
+	}
+	for rows.Next() {
+		var entry FoodEntryView
+		var createdBy sql.NullInt64
+		var createdAt, entryDate time.Time
+
+		if err := rows.Scan(
+			&entry.ID, &entry.FoodName, &entry.MealType,
+			&entry.Calories, &entry.Protein, &entry.Fat, &entry.Carbs,
+			&entry.Weight, &createdBy, &createdAt, &entryDate,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan food entry: %w", err)
+		}
+		if createdBy.Valid {
+			v := createdBy.Int64
+			entry.CreatedBy = &v
+		}
+		entry.Time = createdAt.Format("15:04")
+
+		// Rows are ordered by date, so a new date always starts a new day
+		dateKey := entryDate.Format("2006-01-02")
+		if n := len(result.Days); n == 0 || result.Days[n-1].Date != dateKey {
+			result.Days = append(result.Days, NutritionDayView{Date: dateKey, Entries: make([]FoodEntryView, 0)})
+		}
+		day := &result.Days[len(result.Days)-1]
+		day.Entries = append(day.Entries, entry)
+		day.Totals.Calories += entry.Calories
+		day.Totals.Protein += entry.Protein
+		day.Totals.Fat += entry.Fat
+		day.Totals.Carbs += entry.Carbs
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating food entries: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,189 @@
+package curator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateInviteCode(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		code, err := generateInviteCode()
+		require.NoError(t, err)
+		assert.Len(t, code, inviteCodeLength)
+		for _, r := range code {
+			assert.Contains(t, inviteAlphabet, string(r))
+		}
+		seen[code] = true
+	}
+	assert.Len(t, seen, 100)
+}
+
+func TestCreateInvite(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	expires := time.Now().Add(InviteTTL)
+	mock.ExpectQuery("INSERT INTO curator_invites").
+		WithArgs(sqlmock.AnyArg(), int64(1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"expires_at", "created_at"}).AddRow(expires, time.Now()))
+
+	invite, err := service.CreateInvite(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Len(t, invite.Code, inviteCodeLength)
+	assert.Equal(t, expires, invite.ExpiresAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRedeemInvite(t *testing.T) {
+	t.Run("links the client to the curator", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM users").
+			WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("client"))
+		mock.ExpectQuery("FROM curator_invites ci").
+			WithArgs("ABCD2345").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "curator_id", "name"}).AddRow("invite-1", int64(1), "Curator"))
+		mock.ExpectExec("UPDATE curator_client_relationships SET status = 'inactive'").
+			WithArgs(int64(10), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO curator_client_relationships").
+			WithArgs(int64(1), int64(10)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO conversations").
+			WithArgs(int64(10), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_invites SET redeemed_by").
+			WithArgs("invite-1", int64(10)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		link, err := service.RedeemInvite(context.Background(), 10, "ABCD2345")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), link.CuratorID)
+		assert.Equal(t, "Curator", link.CuratorName)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects expired or redeemed codes", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("client"))
+		mock.ExpectQuery("FROM curator_invites ci").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "curator_id", "name"}))
+		mock.ExpectRollback()
+
+		_, err := service.RedeemInvite(context.Background(), 10, "ABCD2345")
+
+		assert.ErrorIs(t, err, ErrInviteInvalid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects non-clients", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT role FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("coordinator"))
+		mock.ExpectRollback()
+
+		_, err := service.RedeemInvite(context.Background(), 1, "ABCD2345")
+
+		assert.ErrorIs(t, err, ErrNotClient)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUnlinkCurator(t *testing.T) {
+	t.Run("deactivates the relationship", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("UPDATE curator_client_relationships SET status = 'inactive'").
+			WithArgs(int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"curator_id"}).AddRow(int64(1)))
+
+		require.NoError(t, service.UnlinkCurator(context.Background(), 10))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no active curator", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("UPDATE curator_client_relationships SET status = 'inactive'").
+			WillReturnRows(sqlmock.NewRows([]string{"curator_id"}))
+
+		err := service.UnlinkCurator(context.Background(), 10)
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestGetClientNutrition(t *testing.T) {
+	t.Run("groups entries by day", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(int64(1), int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(10), sqlmock.AnyArg(), "coach_data_access",
+				[]byte(`{"curator_id":1,"from":"2026-10-09","resource":"nutrition","to":"2026-10-15"}`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		day1 := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+		day2 := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+		columns := []string{"id", "food_name", "meal_type", "calories", "protein", "fat", "carbs",
+			"portion_amount", "created_by", "created_at", "date"}
+		mock.ExpectQuery("FROM food_entries").
+			WithArgs(int64(10), "2026-10-09", "2026-10-15").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("e1", "Овсянка", "breakfast", 300.0, 10.0, 5.0, 50.0, 80.0, nil, day1.Add(8*time.Hour), day1).
+				AddRow("e2", "Курица", "lunch", 400.0, 40.0, 10.0, 0.0, 200.0, int64(1), day1.Add(13*time.Hour), day1).
+				AddRow("e3", "Яблоко", "snack", 80.0, 0.0, 0.0, 20.0, 150.0, nil, day2.Add(16*time.Hour), day2))
+
+		nutrition, err := service.GetClientNutrition(context.Background(), 1, 10, "2026-10-09", "2026-10-15")
+
+		require.NoError(t, err)
+		require.Len(t, nutrition.Days, 2)
+		assert.Equal(t, "2026-10-15", nutrition.Days[0].Date)
+		assert.Len(t, nutrition.Days[0].Entries, 2)
+		assert.Equal(t, 700.0, nutrition.Days[0].Totals.Calories)
+		assert.Equal(t, int64(1), *nutrition.Days[0].Entries[1].CreatedBy)
+		assert.Equal(t, 80.0, nutrition.Days[1].Totals.Calories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("forbidden without an active relationship", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(int64(2), int64(10)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(10), sqlmock.AnyArg(), "coach_data_access_denied", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		_, err := service.GetClientNutrition(context.Background(), 2, 10, "2026-10-09", "2026-10-15")
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -10,8 +10,10 @@ import (
 	"strings"
 	"time"
 
+	"github.com/burcev/api/internal/modules/audit"
 	"github.com/burcev/api/internal/modules/notifications"
 	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/clock"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"golang.org/x/sync/errgroup"
@@ -67,6 +69,10 @@ type ServiceInterface interface {
 	GetAnalyticsHistory(ctx context.Context, curatorID int64, period string, count int) (any, error)
 	GetBenchmark(ctx context.Context, curatorID int64, weeks int) (*BenchmarkData, error)
 	CollectDailySnapshot(ctx context.Context, curatorID int64) error
+	CreateInvite(ctx context.Context, curatorID int64) (*Invite, error)
+	RedeemInvite(ctx context.Context, clientID int64, code string) (*CuratorLink, error)
+	UnlinkCurator(ctx context.Context, clientID int64) error
+	GetClientNutrition(ctx context.Context, curatorID, clientID int64, from, to string) (*ClientNutrition, error)
 }
 
 // Service handles curator business logic
@@ -74,6 +80,7 @@ type Service struct {
 	db               *database.DB
 	log              *logger.Logger
 	notificationsSvc *notifications.Service
+	audit            audit.ServiceInterface
 }
 
 // NewService creates a new curator service
@@ -82,6 +89,7 @@ func NewService(db *database.DB, log *logger.Logger, notificationsSvc *notificat
 		db:               db,
 		log:              log,
 		notificationsSvc: notificationsSvc,
+		audit:            audit.NewService(db.DB, log),
 	}
 }
 
@@ -1855,7 +1863,8 @@ func (s *Service) getLastActivityDates(ctx context.Context, clientIDs []int64) m
 	return result
 }
 
-// getStreakDays returns consecutive days of food entries ending today per client
+// getStreakDays returns consecutive days of food entries ending today per
+// client, where today is the client's local day
 func (s *Service) getStreakDays(ctx context.Context, clientIDs []int64) map[int64]int {
 	result := make(map[int64]int)
 	if len(clientIDs) == 0 {
@@ -1863,12 +1872,15 @@ func (s *Service) getStreakDays(ctx context.Context, clientIDs []int64) map[int6
 	}
 
 	inClause, args := buildPlaceholders(clientIDs, 0)
-	// Get distinct dates per client for the last 60 days
+	// Get distinct dates per client for the last 60 days. The upper bound
+	// allows for clients whose local day is already ahead of the server's.
 	query := fmt.Sprintf(`
-		SELECT user_id, date FROM food_entries
-		WHERE user_id IN %s AND date >= CURRENT_DATE - INTERVAL '60 days' AND date <= CURRENT_DATE
-		GROUP BY user_id, date
-		ORDER BY user_id, date DESC
+		SELECT f.user_id, f.date, COALESCE(us.timezone, '')
+		FROM food_entries f
+		LEFT JOIN user_settings us ON us.user_id = f.user_id
+		WHERE f.user_id IN %s AND f.date >= CURRENT_DATE - INTERVAL '60 days' AND f.date <= CURRENT_DATE + 1
+		GROUP BY f.user_id, f.date, us.timezone
+		ORDER BY f.user_id, f.date DESC
 	`, inClause)
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
@@ -1880,33 +1892,41 @@ func (s *Service) getStreakDays(ctx context.Context, clientIDs []int64) map[int6
 
 	// Build per-client date lists
 	clientDates := make(map[int64][]time.Time)
+	clientTZ := make(map[int64]string)
 	for rows.Next() {
 		var userID int64
 		var d time.Time
-		if err := rows.Scan(&userID, &d); err != nil {
+		var tz string
+		if err := rows.Scan(&userID, &d, &tz); err != nil {
 			continue
 		}
-		clientDates[userID] = append(clientDates[userID], d.Truncate(24*time.Hour))
+		clientDates[userID] = append(clientDates[userID], clock.Day(d))
+		clientTZ[userID] = tz
 	}
 
-	today := time.Now().UTC().Truncate(24 * time.Hour)
+	now := time.Now()
 	for userID, dates := range clientDates {
-		// dates are sorted desc; count consecutive from today
-		streak := 0
-		expected := today
-		for _, d := range dates {
-			if d.Equal(expected) {
-				streak++
-				expected = expected.AddDate(0, 0, -1)
-			} else if d.Before(expected) {
-				break
-			}
-		}
-		result[userID] = streak
+		result[userID] = streakDays(dates, clock.UserDay(clientTZ[userID], now))
 	}
 	return result
 }
 
+// streakDays counts consecutive days ending on today in dates, which are
+// sorted newest first
+func streakDays(dates []time.Time, today time.Time) int {
+	streak := 0
+	expected := today
+	for _, d := range dates {
+		if d.Equal(expected) {
+			streak++
+			expected = expected.AddDate(0, 0, -1)
+		} else if d.Before(expected) {
+			break
+		}
+	}
+	return streak
+}
+
 // GetAnalytics returns aggregate analytics summary for a curator
 func (s *Service) GetAnalytics(ctx context.Context, curatorID int64) (*AnalyticsSummary, error) {
 	startTime := time.Now()
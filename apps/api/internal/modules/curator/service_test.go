@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/clock"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/stretchr/testify/assert"
@@ -125,9 +126,9 @@ func TestGetClients(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"user_id", "max"}))
 
 		// Streak days (getStreakDays)
-		mock.ExpectQuery(`SELECT user_id, date FROM food_entries`).
+		mock.ExpectQuery(`SELECT f.user_id, f.date, COALESCE\(us.timezone`).
 			WithArgs(int64(1), int64(2), int64(3)).
-			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date"}))
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date", "timezone"}))
 
 		clients, err := service.GetClients(ctx, curatorID)
 
@@ -228,9 +229,9 @@ func TestGetClients(t *testing.T) {
 			WillReturnRows(sqlmock.NewRows([]string{"user_id", "max"}))
 
 		// Streak days (getStreakDays)
-		mock.ExpectQuery(`SELECT user_id, date FROM food_entries`).
+		mock.ExpectQuery(`SELECT f.user_id, f.date, COALESCE\(us.timezone`).
 			WithArgs(int64(1)).
-			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date"}))
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date", "timezone"}))
 
 		clients, err := service.GetClients(ctx, curatorID)
 
@@ -1680,9 +1681,9 @@ func TestCollectDailySnapshot(t *testing.T) {
 				AddRow(int64(1)).AddRow(int64(2)).AddRow(int64(3)))
 
 		// Streak query
-		mock.ExpectQuery(`SELECT user_id, date FROM food_entries`).
+		mock.ExpectQuery(`SELECT f.user_id, f.date, COALESCE\(us.timezone`).
 			WithArgs(int64(1), int64(2), int64(3)).
-			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date"}))
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "date", "timezone"}))
 
 		// INSERT ... ON CONFLICT
 		mock.ExpectExec(`INSERT INTO curator_daily_snapshots`).
@@ -1725,3 +1726,19 @@ func TestCollectDailySnapshot(t *testing.T) {
 
 // Ensure sql package is used (for sql.NullFloat64 in service)
 var _ = sql.ErrNoRows
+
+func TestStreakDays(t *testing.T) {
+	d := func(s string) time.Time {
+		v, _ := time.Parse("2006-01-02", s)
+		return v
+	}
+	dates := []time.Time{d("2026-10-17"), d("2026-10-16"), d("2026-10-15"), d("2026-10-13")}
+
+	// 13:00 UTC on the 16th is already the 17th in Kamchatka (UTC+12)
+	now := time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3, streakDays(dates, clock.UserDay("Asia/Kamchatka", now)))
+	// In Moscow it is still the 16th; the entry dated tomorrow does not count
+	assert.Equal(t, 2, streakDays(dates, clock.UserDay("Europe/Moscow", now)))
+	// Nothing logged today yet
+	assert.Equal(t, 0, streakDays(dates, d("2026-10-18")))
+}
@@ -1,6 +1,10 @@
 package curator
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
 
 // ClientCard represents a summary view of a client for the curator dashboard
 type ClientCard struct {
@@ -289,3 +293,49 @@ type WeeklyPlanView struct {
 	IsActive  bool    `json:"is_active"`
 	CreatedAt string  `json:"created_at"`
 }
+
+// InviteTTL is how long a curator invite code can be redeemed
+const InviteTTL = 7 * 24 * time.Hour
+
+// MaxNutritionRangeDays caps the client nutrition date range
+const MaxNutritionRangeDays = 31
+
+var (
+	// ErrInviteInvalid is returned for unknown, expired or already redeemed invite codes
+	ErrInviteInvalid = errors.New("invite code is invalid or expired")
+	// ErrNotClient is returned when a non-client tries to link to a curator
+	ErrNotClient = errors.New("only clients can link to a curator")
+)
+
+// Invite is a one-time code a curator gives to a client to link accounts
+type Invite struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// RedeemInviteRequest is the body of POST /api/v1/users/link-curator
+type RedeemInviteRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// CuratorLink is the client's view of the curator they are linked to
+type CuratorLink struct {
+	CuratorID   int64  `json:"curator_id"`
+	CuratorName string `json:"curator_name"`
+}
+
+// NutritionDayView is one day of a client's food entries with totals
+type NutritionDayView struct {
+	Date    string          `json:"date"`
+	Totals  DailyKBZHU      `json:"totals"`
+	Entries []FoodEntryView `json:"entries"`
+}
+
+// ClientNutrition is the read-only view of a client's food diary for a curator
+type ClientNutrition struct {
+	ClientID int64              `json:"client_id"`
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Days     []NutritionDayView `json:"days"`
+}
@@ -8,9 +8,12 @@ import (
 	"time"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/maintenance"
 	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/modules/nutrition"
 	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
 	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/middleware"
 	"github.com/burcev/api/internal/shared/response"
@@ -50,12 +53,12 @@ type Handler struct {
 }
 
 // NewHandler creates a new dashboard handler
-func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, s3Client *storage.S3Client, notificationsSvc *notifications.Service, nutritionCalcSvc *nutritioncalc.Service) *Handler {
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, regions *storage.Regions, notificationsSvc *notifications.Service, nutritionCalcSvc *nutritioncalc.Service, bus *events.Bus, dayFlags *nutrition.FlagService) *Handler {
 	return &Handler{
 		cfg:              cfg,
 		log:              log,
 		db:               db,
-		service:          NewService(db, log, s3Client, notificationsSvc),
+		service:          NewService(db, log, regions, notificationsSvc, bus, dayFlags, float64(cfg.NutritionSodiumWarningMg)),
 		nutritionCalcSvc: nutritionCalcSvc,
 	}
 }
@@ -78,7 +81,7 @@ type GetWeekMetricsRequest struct {
 }
 
 // GetDailyMetrics handles GET /api/dashboard/daily/:date
-// Retrieves daily metrics for a specific date
+// Retrieves daily metrics for a specific date; includes a notice before scheduled maintenance
 func (h *Handler) GetDailyMetrics(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userIDInterface, exists := c.Get("user_id")
@@ -118,6 +121,10 @@ func (h *Handler) GetDailyMetrics(c *gin.Context) {
 		return
 	}
 
+	if notice, ok := maintenance.NoticeFromContext(c); ok {
+		response.SuccessWithNotice(c, http.StatusOK, metrics, notice)
+		return
+	}
 	response.Success(c, http.StatusOK, metrics)
 }
 
@@ -661,8 +668,12 @@ func (h *Handler) CompleteTaskForDate(c *gin.Context) {
 		WorkoutDuration *int    `json:"workout_duration,omitempty"`
 	}
 	_ = c.ShouldBindJSON(&req)
+	userLoc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
 	if req.Date == "" {
-		req.Date = time.Now().Format("2006-01-02")
+		req.Date = time.Now().In(userLoc).Format("2006-01-02")
 	}
 
 	task, err := h.service.CompleteTaskForDate(c.Request.Context(), userID, taskID, req.Date)
@@ -675,7 +686,6 @@ func (h *Handler) CompleteTaskForDate(c *gin.Context) {
 	// If this is a workout task with workout data, save the metric too
 	metricSynced := false
 	if task.Type == "workout" && req.WorkoutType != nil {
-		userLoc := middleware.GetUserTimezone(c.Request.Context(), h.db, userID)
 		date, parseErr := time.ParseInLocation("2006-01-02", req.Date, userLoc)
 		if parseErr == nil {
 			// SaveMetric expects map[string]interface{} (from JSON unmarshaling),
@@ -28,14 +28,14 @@ func TestNotificationIntegration(t *testing.T) {
 		// Create a mock database (nil is ok for this test)
 		var db *database.DB
 
-		// Create mock S3 client (nil is ok for this test)
-		var s3Client *storage.S3Client
+		// Create storage regions (nil is ok for this test)
+		var regions *storage.Regions
 
 		// Create mock notifications service (nil is ok for this test)
 		var notificationsSvc *notifications.Service
 
 		// Create service - this should not panic
-		service := NewService(db, log, s3Client, notificationsSvc)
+		service := NewService(db, log, regions, notificationsSvc, nil, nil, 0)
 
 		// Verify service was created
 		assert.NotNil(t, service)
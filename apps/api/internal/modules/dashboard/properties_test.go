@@ -1,20 +1,31 @@
 package dashboard
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	mealplans "github.com/burcev/api/internal/modules/meal-plans"
 	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/modules/nutrition"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/types"
 	"github.com/google/uuid"
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/gen"
 	"github.com/leanovate/gopter/prop"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 // Helper function to create a test service with mock database
@@ -27,7 +38,7 @@ func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, func()) {
 	db := &database.DB{DB: mockDB}
 	log := logger.New()
 
-	service := NewService(db, log, nil, nil) // nil for S3Client and NotificationsService in tests
+	service := NewService(db, log, nil, nil, nil, nil, config.DefaultNutritionSodiumWarningMg) // nil for S3Client and NotificationsService in tests
 
 	cleanup := func() {
 		mockDB.Close()
@@ -2305,3 +2316,468 @@ func TestCuratorClientNotificationProperty(t *testing.T) {
 
 	properties.TestingRun(t)
 }
+
+func TestGetWeekMetrics_WaterTotals(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	sunday := monday.AddDate(0, 0, 6)
+	columns := []string{
+		"id", "user_id", "date", "calories", "protein", "fat", "carbs", "weight", "steps",
+		"workout_completed", "workout_type", "workout_duration", "created_at", "updated_at",
+	}
+	mock.ExpectQuery("FROM daily_metrics").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(uuid.New().String(), int64(1), monday, 2000, 150, 60, 200, nil, 8000, false, nil, nil, time.Now(), time.Now()).
+			AddRow(uuid.New().String(), int64(1), monday.AddDate(0, 0, 1), 1800, 140, 55, 190, nil, 9000, false, nil, nil, time.Now(), time.Now()))
+	mock.ExpectQuery("FROM water_intake_events").
+		WithArgs(int64(1), "2026-10-12", "2026-10-18").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}).AddRow("2026-10-12", 1750))
+
+	metrics, err := service.GetWeekMetrics(context.Background(), 1, monday, sunday)
+
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	assert.Equal(t, 1750, metrics[0].WaterML)
+	assert.Zero(t, metrics[1].WaterML)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDailyMetrics_WaterFromBothSources(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	// 500 ml of intake events plus 3 glasses of 250 ml from the food tracker
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM daily_metrics").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`(?s)FROM water_intake_events.*UNION ALL.*glasses \* glass_size\s+FROM water_logs`).
+		WithArgs(int64(1), "2026-10-12", "2026-10-12").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}).AddRow("2026-10-12", 1250))
+	mock.ExpectQuery("FROM curator_comments").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1250, metrics.WaterML)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDailyMetrics_UnreadComments(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM daily_metrics").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("FROM water_intake_events").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM curator_comments").
+		WithArgs(int64(1), "2026-10-12").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, metrics.UnreadComments)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDailyMetrics_DayFlag(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+	service.dayFlags = nutrition.NewFlagService(service.db, service.log, []string{nutrition.DayFlagRefeed}, nil)
+
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM daily_metrics").WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery("FROM water_intake_events").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+	mock.ExpectQuery("FROM curator_comments").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("FROM nutrition_day_flags").
+		WithArgs(int64(1), "2026-10-12", "2026-10-12").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "type", "note", "created_at", "updated_at"}).
+			AddRow("2026-10-12", nutrition.DayFlagRefeed, "", time.Now(), time.Now()))
+
+	metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+	require.NoError(t, err)
+	require.NotNil(t, metrics.DayFlag)
+	assert.Equal(t, nutrition.DayFlagRefeed, metrics.DayFlag.Type)
+	assert.True(t, metrics.DayFlag.Excluded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetDailyMetrics_CaloriesBurned(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM daily_metrics").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "date", "calories", "protein", "fat", "carbs", "weight", "steps",
+			"workout_completed", "workout_type", "workout_duration", "created_at", "updated_at",
+		}).AddRow(uuid.New().String(), int64(1), day, 1850, 120, 60, 200, nil, 8000, false, nil, nil, time.Now(), time.Now()))
+	mock.ExpectQuery("FROM water_intake_events").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+	mock.ExpectQuery("FROM curator_comments").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(calories_burned\), 0\) FROM activities`).
+		WithArgs(int64(1), "2026-10-12").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(342.5))
+
+	metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+	require.NoError(t, err)
+	assert.Equal(t, 342.5, metrics.CaloriesBurned)
+	assert.Equal(t, 1507.5, metrics.NetCalories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	body, err := json.Marshal(metrics)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"calories_burned":342.5`)
+	assert.Contains(t, string(body), `"net_calories":1507.5`)
+}
+
+// stubPlans implements MealPlans with a fixed plan
+type stubPlans struct {
+	plan *mealplans.Plan
+	err  error
+	day  types.Date
+}
+
+func (s *stubPlans) PlanFor(ctx context.Context, userID int64, day types.Date) (*mealplans.Plan, error) {
+	s.day = day
+	return s.plan, s.err
+}
+
+func TestGetDailyMetrics_MealTargets(t *testing.T) {
+	// The user's day starts at midnight in their timezone, which is the
+	// previous day in UTC for Vladivostok
+	day := time.Date(2026, 10, 16, 0, 0, 0, 0, time.FixedZone("UTC+10", 10*60*60))
+
+	expectDay := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("FROM daily_metrics").WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("FROM water_intake_events").
+			WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+		mock.ExpectQuery("FROM curator_comments").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("FROM activities").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+	}
+
+	t.Run("target, actual and delta per planned meal", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		plans := &stubPlans{plan: &mealplans.Plan{Meals: map[string]mealplans.Target{
+			mealplans.MealBreakfast: {Calories: 500, Protein: 40, Carbs: 50, Fat: 15},
+			mealplans.MealDinner:    {Calories: 700, Protein: 50, Carbs: 60, Fat: 25},
+		}}}
+		service.plans = plans
+		expectDay(mock)
+		mock.ExpectQuery("FROM nutrition_entries").
+			WithArgs(int64(1), "2026-10-16").
+			WillReturnRows(sqlmock.NewRows([]string{"meal", "calories", "protein", "carbs", "fat"}).
+				AddRow("breakfast", 550.0, 29.5, 44.3, 28.0).
+				AddRow("lunch", 180.0, 9.0, 20.0, 6.0))
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Equal(t, "2026-10-16", plans.day.String())
+		assert.Equal(t, map[string]MealTargetProgress{
+			mealplans.MealBreakfast: {
+				Target: nutrition.Macros{Calories: 500, Protein: 40, Carbs: 50, Fat: 15},
+				Actual: nutrition.Macros{Calories: 550, Protein: 29.5, Carbs: 44.3, Fat: 28},
+				Delta:  nutrition.Macros{Calories: 50, Protein: -10.5, Carbs: -5.7, Fat: 13},
+			},
+			// Nothing logged for dinner yet
+			mealplans.MealDinner: {
+				Target: nutrition.Macros{Calories: 700, Protein: 50, Carbs: 60, Fat: 25},
+				Actual: nutrition.Macros{},
+				Delta:  nutrition.Macros{Calories: -700, Protein: -50, Carbs: -60, Fat: -25},
+			},
+		}, metrics.MealTargets, "lunch has no target")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{}
+		expectDay(mock)
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Nil(t, metrics.MealTargets)
+		body, err := json.Marshal(metrics)
+		require.NoError(t, err)
+		assert.NotContains(t, string(body), "meal_targets")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed plan lookup leaves the day without targets", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{err: errors.New("connection reset")}
+		expectDay(mock)
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Nil(t, metrics.MealTargets)
+	})
+}
+
+func TestGetDailyMetrics_Micronutrients(t *testing.T) {
+	day := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	micronutrientColumns := []string{"date", "fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}
+
+	expectDay := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("FROM daily_metrics").WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery("FROM water_intake_events").
+			WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+		mock.ExpectQuery("FROM curator_comments").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("FROM activities").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(0.0))
+	}
+
+	t.Run("totals and a sodium warning over the limit", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{}
+		expectDay(mock)
+		mock.ExpectQuery("FROM nutrition_daily_rollups").
+			WithArgs(int64(1), "2026-10-12", "2026-10-12").
+			WillReturnRows(sqlmock.NewRows(micronutrientColumns).
+				AddRow("2026-10-12", 24.5, nil, 2750.25, 18.0))
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		require.NotNil(t, metrics.Micronutrients.FiberG)
+		assert.Equal(t, 24.5, *metrics.Micronutrients.FiberG)
+		assert.Nil(t, metrics.Micronutrients.SugarG, "no entry recorded sugar")
+		assert.Equal(t, &nutrition.SodiumWarning{Date: "2026-10-12", SodiumMg: 2750.3, LimitMg: 2300}, metrics.SodiumWarning)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		body, err := json.Marshal(metrics)
+		require.NoError(t, err)
+		assert.Contains(t, string(body), `"sugar_g":null`)
+		assert.Contains(t, string(body), `"sodium_warning":{"date":"2026-10-12","sodium_mg":2750.3,"limit_mg":2300}`)
+	})
+
+	t.Run("no warning at or under the limit", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{}
+		expectDay(mock)
+		mock.ExpectQuery("FROM nutrition_daily_rollups").
+			WillReturnRows(sqlmock.NewRows(micronutrientColumns).
+				AddRow("2026-10-12", nil, nil, 2300.0, nil))
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Nil(t, metrics.SodiumWarning)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a zero limit turns the warning off", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{}
+		service.sodiumLimitMg = 0
+		expectDay(mock)
+		mock.ExpectQuery("FROM nutrition_daily_rollups").
+			WillReturnRows(sqlmock.NewRows(micronutrientColumns).
+				AddRow("2026-10-12", nil, nil, 9000.0, nil))
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Nil(t, metrics.SodiumWarning)
+	})
+
+	t.Run("a day without entries has every nutrient nil", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.plans = &stubPlans{}
+		expectDay(mock)
+		mock.ExpectQuery("FROM nutrition_daily_rollups").
+			WillReturnRows(sqlmock.NewRows(micronutrientColumns))
+
+		metrics, err := service.GetDailyMetrics(context.Background(), 1, day)
+
+		require.NoError(t, err)
+		assert.Equal(t, nutrition.Micronutrients{}, metrics.Micronutrients)
+		assert.Nil(t, metrics.SodiumWarning)
+	})
+}
+
+func TestGetWeekMetrics_Micronutrients(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	monday := time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+	sunday := monday.AddDate(0, 0, 6)
+	columns := []string{
+		"id", "user_id", "date", "calories", "protein", "fat", "carbs", "weight", "steps",
+		"workout_completed", "workout_type", "workout_duration", "created_at", "updated_at",
+	}
+	mock.ExpectQuery("FROM daily_metrics").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(uuid.New().String(), int64(1), monday, 2000, 150, 60, 200, nil, 8000, false, nil, nil, time.Now(), time.Now()).
+			AddRow(uuid.New().String(), int64(1), monday.AddDate(0, 0, 1), 1800, 140, 55, 190, nil, 9000, false, nil, nil, time.Now(), time.Now()))
+	mock.ExpectQuery("FROM water_intake_events").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "sum"}))
+	mock.ExpectQuery("FROM nutrition_daily_rollups").
+		WithArgs(int64(1), "2026-10-12", "2026-10-18").
+		WillReturnRows(sqlmock.NewRows([]string{"date", "fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}).
+			AddRow("2026-10-12", 30.0, 45.0, 3100.0, nil).
+			AddRow("2026-10-13", nil, 20.0, 1800.0, 12.0))
+
+	metrics, err := service.GetWeekMetrics(context.Background(), 1, monday, sunday)
+
+	require.NoError(t, err)
+	require.Len(t, metrics, 2)
+	require.NotNil(t, metrics[0].Micronutrients.SodiumMg)
+	assert.Equal(t, 3100.0, *metrics[0].Micronutrients.SodiumMg)
+	assert.Equal(t, &nutrition.SodiumWarning{Date: "2026-10-12", SodiumMg: 3100, LimitMg: 2300}, metrics[0].SodiumWarning)
+	assert.Nil(t, metrics[1].Micronutrients.FiberG)
+	require.NotNil(t, metrics[1].Micronutrients.SaturatedFatG)
+	assert.Equal(t, 12.0, *metrics[1].Micronutrients.SaturatedFatG)
+	assert.Nil(t, metrics[1].SodiumWarning)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakeRegionStore is an in-memory storage.ObjectStore of one region
+type fakeRegionStore struct {
+	name    string
+	objects map[string][]byte
+}
+
+func newFakeRegionStore(name string) *fakeRegionStore {
+	return &fakeRegionStore{name: name, objects: make(map[string][]byte)}
+}
+
+func (f *fakeRegionStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	f.objects[key] = b
+	return "https://storage.example/" + f.name + "/" + key, nil
+}
+
+func (f *fakeRegionStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return f.objects[key], nil
+}
+
+func (f *fakeRegionStore) DeleteFile(ctx context.Context, key string) error {
+	delete(f.objects, key)
+	return nil
+}
+
+func (f *fakeRegionStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + f.name + "/" + key + "?signed", nil
+}
+
+func setupRegionService(t *testing.T) (*Service, sqlmock.Sqlmock, *fakeRegionStore, *fakeRegionStore) {
+	t.Helper()
+	service, mock, cleanup := setupTestService(t)
+	t.Cleanup(cleanup)
+	ru, eu := newFakeRegionStore("photos-ru"), newFakeRegionStore("photos-eu")
+	service.regions = storage.NewRegions(storage.DefaultRegion)
+	service.regions.Register(storage.DefaultRegion, ru)
+	service.regions.Register("eu", eu)
+	return service, mock, ru, eu
+}
+
+func expectOrgRegion(mock sqlmock.Sqlmock, region string) {
+	mock.ExpectQuery("SELECT COALESCE\\(o.storage_region, ''\\)").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow(region))
+}
+
+func TestUploadPhoto_StorageRegion(t *testing.T) {
+	jpeg := []byte("\xff\xd8\xff\xe0jpeg")
+
+	t.Run("users without an organization use the default region", func(t *testing.T) {
+		service, mock, ru, eu := setupRegionService(t)
+		expectOrgRegion(mock, "")
+		mock.ExpectQuery("INSERT INTO weekly_photos").
+			WithArgs(sqlmock.AnyArg(), int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "2026-W42", sqlmock.AnyArg(),
+				len(jpeg), "image/jpeg", sqlmock.AnyArg(), storage.DefaultRegion, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "week_start", "week_end", "week_identifier", "photo_url",
+				"file_size", "mime_type", "uploaded_at", "created_at"}).
+				AddRow(uuid.New().String(), int64(1), time.Now(), time.Now(), "2026-W42", "url", len(jpeg), "image/jpeg", time.Now(), time.Now()))
+
+		_, err := service.UploadPhoto(context.Background(), 1, "2026-W42", bytes.NewReader(jpeg), len(jpeg), "image/jpeg")
+
+		require.NoError(t, err)
+		assert.Len(t, ru.objects, 1)
+		assert.Empty(t, eu.objects)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("organizations upload to their region", func(t *testing.T) {
+		service, mock, ru, eu := setupRegionService(t)
+		expectOrgRegion(mock, "eu")
+		mock.ExpectQuery("INSERT INTO weekly_photos").
+			WithArgs(sqlmock.AnyArg(), int64(1), sqlmock.AnyArg(), sqlmock.AnyArg(), "2026-W42", sqlmock.AnyArg(),
+				len(jpeg), "image/jpeg", sqlmock.AnyArg(), "eu", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "week_start", "week_end", "week_identifier", "photo_url",
+				"file_size", "mime_type", "uploaded_at", "created_at"}).
+				AddRow(uuid.New().String(), int64(1), time.Now(), time.Now(), "2026-W42", "url", len(jpeg), "image/jpeg", time.Now(), time.Now()))
+
+		_, err := service.UploadPhoto(context.Background(), 1, "2026-W42", bytes.NewReader(jpeg), len(jpeg), "image/jpeg")
+
+		require.NoError(t, err)
+		assert.Empty(t, ru.objects)
+		assert.Len(t, eu.objects, 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an unconfigured organization region fails instead of using the default", func(t *testing.T) {
+		service, mock, ru, _ := setupRegionService(t)
+		expectOrgRegion(mock, "us")
+
+		_, err := service.UploadPhoto(context.Background(), 1, "2026-W42", bytes.NewReader(jpeg), len(jpeg), "image/jpeg")
+
+		assert.ErrorIs(t, err, storage.ErrRegionUnavailable)
+		assert.Empty(t, ru.objects)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetPhotoSignedURL_StorageRegion(t *testing.T) {
+	photoID := uuid.New().String()
+	photoRow := func(region string) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"photo_url", "storage_region", "storage_key"}).
+			AddRow("https://storage.example/weekly-photos/1/2026-W42/a.jpg", region, "weekly-photos/1/2026-W42/a.jpg")
+	}
+
+	t.Run("signs in the region holding the photo", func(t *testing.T) {
+		service, mock, _, _ := setupRegionService(t)
+		mock.ExpectQuery("SELECT photo_url, storage_region, storage_key FROM weekly_photos").
+			WithArgs(photoID, int64(1)).WillReturnRows(photoRow("eu"))
+
+		url, err := service.GetPhotoSignedURL(context.Background(), photoID, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, "https://storage.example/photos-eu/weekly-photos/1/2026-W42/a.jpg?signed", url)
+	})
+
+	t.Run("an unconfigured region is an error, not the default bucket", func(t *testing.T) {
+		service, mock, _, _ := setupRegionService(t)
+		mock.ExpectQuery("SELECT photo_url, storage_region, storage_key FROM weekly_photos").
+			WithArgs(photoID, int64(1)).WillReturnRows(photoRow("us"))
+
+		_, err := service.GetPhotoSignedURL(context.Background(), photoID, 1)
+
+		assert.ErrorIs(t, err, storage.ErrRegionUnavailable)
+	})
+}
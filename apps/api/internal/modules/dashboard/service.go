@@ -6,15 +6,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/burcev/api/internal/modules/comments"
+	mealplans "github.com/burcev/api/internal/modules/meal-plans"
 	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/modules/nutrition"
 	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/types"
 	"github.com/google/uuid"
 )
 
@@ -66,21 +72,42 @@ func parseIntArray(s string) []int {
 	return result
 }
 
+// MealPlans resolves the curator's meal plan in effect for a user on a
+// day. It returns nil, nil when no plan is in effect.
+type MealPlans interface {
+	PlanFor(ctx context.Context, userID int64, day types.Date) (*mealplans.Plan, error)
+}
+
 // Service handles dashboard business logic
 type Service struct {
 	db               *database.DB
 	log              *logger.Logger
-	s3Client         *storage.S3Client
+	regions          *storage.Regions
 	notificationsSvc *notifications.Service
+	events           *events.Bus
+	comments         *comments.Service
+	dayFlags         *nutrition.FlagService
+	plans            MealPlans
+	// sodiumLimitMg is the daily sodium above which a day gets a warning
+	sodiumLimitMg float64
 }
 
-// NewService creates a new dashboard service
-func NewService(db *database.DB, log *logger.Logger, s3Client *storage.S3Client, notificationsSvc *notifications.Service) *Service {
+// NewService creates a new dashboard service. Photo storage is routed through
+// regions according to the user's organization (data residency). Weight
+// measurements are published on bus, which may be nil. dayFlags may be nil
+// to show days without their flag. Days with more than sodiumLimitMg of
+// sodium get a warning; 0 turns the warnings off.
+func NewService(db *database.DB, log *logger.Logger, regions *storage.Regions, notificationsSvc *notifications.Service, bus *events.Bus, dayFlags *nutrition.FlagService, sodiumLimitMg float64) *Service {
 	return &Service{
 		db:               db,
 		log:              log,
-		s3Client:         s3Client,
+		regions:          regions,
 		notificationsSvc: notificationsSvc,
+		events:           bus,
+		comments:         comments.NewService(db, log),
+		dayFlags:         dayFlags,
+		plans:            mealplans.NewService(db, log),
+		sodiumLimitMg:    sodiumLimitMg,
 	}
 }
 
@@ -116,46 +143,251 @@ func (s *Service) GetDailyMetrics(ctx context.Context, userID int64, date time.T
 		&metrics.UpdatedAt,
 	)
 
-	if err != nil {
-		if err == sql.ErrNoRows {
-			// Return empty metrics for the date
-			s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
-				"user_id": userID,
-				"date":    date,
-				"found":   false,
-			})
-			return &DailyMetrics{
-				ID:               uuid.New().String(),
-				UserID:           userID,
-				Date:             date,
-				Calories:         0,
-				Protein:          0,
-				Fat:              0,
-				Carbs:            0,
-				Weight:           nil,
-				Steps:            0,
-				WorkoutCompleted: false,
-				WorkoutType:      nil,
-				WorkoutDuration:  nil,
-				CreatedAt:        time.Now(),
-				UpdatedAt:        time.Now(),
-			}, nil
+	if err == sql.ErrNoRows {
+		// Empty metrics for the date
+		s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+			"user_id": userID,
+			"date":    date,
+			"found":   false,
+		})
+		metrics = DailyMetrics{
+			ID:        uuid.New().String(),
+			UserID:    userID,
+			Date:      date,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
 		}
+	} else if err != nil {
 		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
 			"user_id": userID,
 			"date":    date,
 		})
 		return nil, fmt.Errorf("failed to query daily metrics: %w", err)
+	} else {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+			"user_id": userID,
+			"date":    date,
+			"found":   true,
+		})
+		populateWorkoutTypes(&metrics)
+	}
+
+	metrics.WaterML = s.waterTotals(ctx, userID, date, date)[date.Format("2006-01-02")]
+	metrics.UnreadComments = s.unreadComments(ctx, userID, date)
+	metrics.DayFlag = s.dayFlag(ctx, userID, date)
+	metrics.CaloriesBurned = s.caloriesBurned(ctx, userID, date)
+	metrics.NetCalories = roundToOneDecimal(float64(metrics.Calories) - metrics.CaloriesBurned)
+	metrics.MealTargets = s.mealTargets(ctx, userID, date)
+	day := date.Format("2006-01-02")
+	metrics.Micronutrients = s.micronutrientTotals(ctx, userID, date, date)[day]
+	metrics.SodiumWarning = s.sodiumWarning(day, metrics.Micronutrients)
+	return &metrics, nil
+}
+
+// roundToOneDecimal rounds value to one decimal place
+func roundToOneDecimal(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+// caloriesBurned sums the calories of the user's activities on date. Like
+// water, a failed query is logged and reported as none.
+func (s *Service) caloriesBurned(ctx context.Context, userID int64, date time.Time) float64 {
+	var burned float64
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COALESCE(SUM(calories_burned), 0) FROM activities WHERE user_id = $1 AND date = $2`,
+		userID, date.Format("2006-01-02"),
+	).Scan(&burned)
+	if err != nil {
+		s.log.Warn("Failed to sum calories burned", "error", err, "user_id", userID)
+		return 0
 	}
+	return burned
+}
 
-	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+// mealTargets compares the intake of each meal the user's meal plan sets a
+// target for with that target, nil without a plan. The intake is summed
+// from the nutrition entries. Like water, a failed query is logged and
+// reported as no targets.
+func (s *Service) mealTargets(ctx context.Context, userID int64, date time.Time) map[string]MealTargetProgress {
+	plan, err := s.plans.PlanFor(ctx, userID, types.DateOf(date))
+	if err != nil {
+		s.log.Warn("Failed to load meal plan", "error", err, "user_id", userID)
+		return nil
+	}
+	if plan == nil {
+		return nil
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT meal, SUM(calories), SUM(protein), SUM(carbs), SUM(fat)
+		FROM nutrition_entries
+		WHERE user_id = $1 AND date = $2
+		GROUP BY meal
+	`
+	rows, err := s.db.QueryContext(ctx, query, userID, date.Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
 		"user_id": userID,
-		"date":    date,
-		"found":   true,
 	})
+	if err != nil {
+		s.log.Warn("Failed to sum meal intake", "error", err, "user_id", userID)
+		return nil
+	}
+	defer rows.Close()
 
-	populateWorkoutTypes(&metrics)
-	return &metrics, nil
+	actual := make(map[string]nutrition.Macros)
+	for rows.Next() {
+		var meal string
+		var m nutrition.Macros
+		if err := rows.Scan(&meal, &m.Calories, &m.Protein, &m.Carbs, &m.Fat); err != nil {
+			s.log.Warn("Failed to scan meal intake", "error", err, "user_id", userID)
+			return nil
+		}
+		actual[meal] = m
+	}
+	if err := rows.Err(); err != nil {
+		s.log.Warn("Failed to sum meal intake", "error", err, "user_id", userID)
+		return nil
+	}
+
+	progress := make(map[string]MealTargetProgress, len(plan.Meals))
+	for meal, target := range plan.Meals {
+		planned := nutrition.Macros{Calories: target.Calories, Protein: target.Protein, Carbs: target.Carbs, Fat: target.Fat}
+		eaten := actual[meal]
+		progress[meal] = MealTargetProgress{
+			Target: planned,
+			Actual: eaten,
+			Delta: nutrition.Macros{
+				Calories: roundToOneDecimal(eaten.Calories - planned.Calories),
+				Protein:  roundToOneDecimal(eaten.Protein - planned.Protein),
+				Carbs:    roundToOneDecimal(eaten.Carbs - planned.Carbs),
+				Fat:      roundToOneDecimal(eaten.Fat - planned.Fat),
+			},
+		}
+	}
+	return progress
+}
+
+// dayFlag returns the refeed, sick or travel flag of date, nil when there is
+// none. Like water, a failed query is logged and reported as no flag.
+func (s *Service) dayFlag(ctx context.Context, userID int64, date time.Time) *nutrition.DayFlag {
+	if s.dayFlags == nil {
+		return nil
+	}
+	flag, err := s.dayFlags.GetFlag(ctx, userID, date.Format("2006-01-02"))
+	if err != nil {
+		s.log.Warn("Failed to load day flag", "error", err, "user_id", userID)
+		return nil
+	}
+	return flag
+}
+
+// unreadComments returns how many curator comments on date the user has not
+// read. Like water, a failed query is logged and reported as none.
+func (s *Service) unreadComments(ctx context.Context, userID int64, date time.Time) int {
+	count, err := s.comments.UnreadCount(ctx, userID, date.Format("2006-01-02"))
+	if err != nil {
+		s.log.Warn("Failed to count unread comments", "error", err, "user_id", userID)
+		return 0
+	}
+	return count
+}
+
+// waterTotals returns the water intake in ml per day (YYYY-MM-DD) from from
+// to to: the logged intake events plus the glasses counted through the food
+// tracker. Water is secondary to the metrics it is shown with, so a failed
+// query is logged and reported as no water.
+func (s *Service) waterTotals(ctx context.Context, userID int64, from, to time.Time) map[string]int {
+	startTime := time.Now()
+
+	query := `
+		SELECT date::text, SUM(amount_ml)
+		FROM (
+			SELECT date, amount_ml
+			FROM water_intake_events
+			WHERE user_id = $1 AND date >= $2 AND date <= $3
+			UNION ALL
+			SELECT date, glasses * glass_size
+			FROM water_logs
+			WHERE user_id = $1 AND date >= $2 AND date <= $3
+		) water
+		GROUP BY date
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		s.log.Warn("Failed to load water totals", "error", err, "user_id", userID)
+		return nil
+	}
+	defer rows.Close()
+
+	totals := make(map[string]int)
+	for rows.Next() {
+		var date string
+		var total int
+		if err := rows.Scan(&date, &total); err != nil {
+			s.log.Warn("Failed to scan water total", "error", err, "user_id", userID)
+			return nil
+		}
+		totals[date] = total
+	}
+	return totals
+}
+
+// micronutrientTotals returns the user's micronutrient totals per day
+// (YYYY-MM-DD) from from to to, inclusive. A nutrient stays nil on a day
+// where no entry recorded it. Like water, a failed query is logged and
+// reported as none.
+func (s *Service) micronutrientTotals(ctx context.Context, userID int64, from, to time.Time) map[string]nutrition.Micronutrients {
+	startTime := time.Now()
+
+	query := `
+		SELECT date::text,
+		       CASE WHEN fiber_g_count > 0 THEN fiber_g END,
+		       CASE WHEN sugar_g_count > 0 THEN sugar_g END,
+		       CASE WHEN sodium_mg_count > 0 THEN sodium_mg END,
+		       CASE WHEN saturated_fat_g_count > 0 THEN saturated_fat_g END
+		FROM nutrition_daily_rollups
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		s.log.Warn("Failed to load micronutrient totals", "error", err, "user_id", userID)
+		return nil
+	}
+	defer rows.Close()
+
+	totals := make(map[string]nutrition.Micronutrients)
+	for rows.Next() {
+		var date string
+		var m nutrition.Micronutrients
+		if err := rows.Scan(&date, &m.FiberG, &m.SugarG, &m.SodiumMg, &m.SaturatedFatG); err != nil {
+			s.log.Warn("Failed to scan micronutrient totals", "error", err, "user_id", userID)
+			return nil
+		}
+		totals[date] = m
+	}
+	return totals
+}
+
+// sodiumWarning returns the warning for a day whose recorded sodium is over
+// the configured limit, nil otherwise
+func (s *Service) sodiumWarning(date string, m nutrition.Micronutrients) *nutrition.SodiumWarning {
+	if s.sodiumLimitMg <= 0 || m.SodiumMg == nil || *m.SodiumMg <= s.sodiumLimitMg {
+		return nil
+	}
+	return &nutrition.SodiumWarning{
+		Date:     date,
+		SodiumMg: roundToOneDecimal(*m.SodiumMg),
+		LimitMg:  s.sodiumLimitMg,
+	}
 }
 
 // SaveMetric creates or updates a daily metric
@@ -354,6 +586,20 @@ func (s *Service) SaveMetric(ctx context.Context, userID int64, date time.Time,
 		"metric_type": metricUpdate.Type,
 	})
 
+	if metricUpdate.Type == MetricUpdateTypeWeight && result.Weight != nil {
+		// The metric is saved in its own statement; an event lost here
+		// does not fail the save
+		err := s.events.Publish(ctx, nil, userID, MeasurementCreated{
+			Type:  "weight",
+			Date:  result.Date.Format("2006-01-02"),
+			Value: *result.Weight,
+			Unit:  "kg",
+		})
+		if err != nil {
+			s.log.Error("Failed to publish measurement", "error", err, "user_id", userID)
+		}
+	}
+
 	populateWorkoutTypes(&result)
 	return &result, nil
 }
@@ -416,6 +662,15 @@ func (s *Service) GetWeekMetrics(ctx context.Context, userID int64, startDate, e
 		return nil, fmt.Errorf("error iterating metrics: %w", err)
 	}
 
+	water := s.waterTotals(ctx, userID, startDate, endDate)
+	micros := s.micronutrientTotals(ctx, userID, startDate, endDate)
+	for i := range metrics {
+		day := metrics[i].Date.Format("2006-01-02")
+		metrics[i].WaterML = water[day]
+		metrics[i].Micronutrients = micros[day]
+		metrics[i].SodiumWarning = s.sodiumWarning(day, micros[day])
+	}
+
 	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
 		"user_id":    userID,
 		"start_date": startDate,
@@ -1811,14 +2066,21 @@ func (s *Service) UploadPhoto(ctx context.Context, userID int64, weekIdentifier
 	// Generate S3 key: weekly-photos/{userID}/{weekIdentifier}/{filename}
 	s3Key := fmt.Sprintf("weekly-photos/%d/%s/%s", userID, weekIdentifier, filename)
 
+	// Pick the bucket required by the user's organization
+	region, store, err := s.photoStoreForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Upload to S3
-	photoURL, err := s.s3Client.UploadFile(ctx, s3Key, fileData, mimeType, int64(fileSize))
+	photoURL, err := store.UploadFile(ctx, s3Key, fileData, mimeType, int64(fileSize))
 	if err != nil {
 		s.log.Error("Failed to upload photo to S3",
 			"error", err,
 			"user_id", userID,
 			"week_identifier", weekIdentifier,
 			"s3_key", s3Key,
+			"storage_region", region,
 		)
 		return nil, fmt.Errorf("failed to upload photo to S3: %w", err)
 	}
@@ -1841,14 +2103,16 @@ func (s *Service) UploadPhoto(ctx context.Context, userID int64, weekIdentifier
 	query := `
 		INSERT INTO weekly_photos (
 			id, user_id, week_start, week_end, week_identifier, photo_url, file_size, mime_type,
-			uploaded_at, created_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+			uploaded_at, storage_region, storage_key, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NOW())
 		ON CONFLICT (user_id, week_identifier)
 		DO UPDATE SET
 			photo_url = EXCLUDED.photo_url,
 			file_size = EXCLUDED.file_size,
 			mime_type = EXCLUDED.mime_type,
-			uploaded_at = EXCLUDED.uploaded_at
+			uploaded_at = EXCLUDED.uploaded_at,
+			storage_region = EXCLUDED.storage_region,
+			storage_key = EXCLUDED.storage_key
 		RETURNING id, user_id, week_start, week_end, week_identifier, photo_url, file_size, mime_type,
 		          uploaded_at, created_at
 	`
@@ -1865,6 +2129,8 @@ func (s *Service) UploadPhoto(ctx context.Context, userID int64, weekIdentifier
 		photo.FileSize,
 		photo.MimeType,
 		photo.UploadedAt,
+		region,
+		s3Key,
 	).Scan(
 		&photo.ID,
 		&photo.UserID,
@@ -1880,7 +2146,7 @@ func (s *Service) UploadPhoto(ctx context.Context, userID int64, weekIdentifier
 
 	if err != nil {
 		// If database insert fails, try to delete the uploaded file from S3
-		deleteErr := s.s3Client.DeleteFile(ctx, s3Key)
+		deleteErr := store.DeleteFile(ctx, s3Key)
 		if deleteErr != nil {
 			s.log.Error("Failed to cleanup S3 file after database error",
 				"error", deleteErr,
@@ -1913,6 +2179,42 @@ func (s *Service) UploadPhoto(ctx context.Context, userID int64, weekIdentifier
 	return photo, nil
 }
 
+// photoStoreForUser resolves the storage region of the user's organization.
+// Users without an organization use the default region; an organization
+// pinned to a region that is not configured cannot upload, since writing to
+// another region would break its data residency.
+func (s *Service) photoStoreForUser(ctx context.Context, userID int64) (string, storage.ObjectStore, error) {
+	query := `
+		SELECT COALESCE(o.storage_region, '')
+		FROM users u
+		LEFT JOIN organizations o ON o.id = u.organization_id
+		WHERE u.id = $1
+	`
+
+	var orgRegion string
+	if err := s.db.QueryRowContext(ctx, query, userID).Scan(&orgRegion); err != nil && err != sql.ErrNoRows {
+		return "", nil, fmt.Errorf("failed to get storage region: %w", err)
+	}
+
+	if orgRegion == "" {
+		region, store := s.regions.Resolve("")
+		if store == nil {
+			return "", nil, fmt.Errorf("photo storage is not configured")
+		}
+		return region, store, nil
+	}
+
+	store, ok := s.regions.Store(orgRegion)
+	if !ok {
+		s.log.Error("Organization storage region is not configured",
+			"user_id", userID,
+			"storage_region", orgRegion,
+		)
+		return "", nil, fmt.Errorf("%w: %s", storage.ErrRegionUnavailable, orgRegion)
+	}
+	return orgRegion, store, nil
+}
+
 // getFileExtension returns file extension based on MIME type
 func getFileExtension(mimeType string) string {
 	switch mimeType {
@@ -1931,12 +2233,12 @@ func getFileExtension(mimeType string) string {
 func (s *Service) GetPhotoSignedURL(ctx context.Context, photoID string, userID int64) (string, error) {
 	// Get photo metadata from database
 	query := `
-		SELECT photo_url FROM weekly_photos
+		SELECT photo_url, storage_region, storage_key FROM weekly_photos
 		WHERE id = $1 AND user_id = $2
 	`
 
-	var photoURL string
-	err := s.db.QueryRowContext(ctx, query, photoID, userID).Scan(&photoURL)
+	var photoURL, region, s3Key string
+	err := s.db.QueryRowContext(ctx, query, photoID, userID).Scan(&photoURL, &region, &s3Key)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return "", fmt.Errorf("photo not found")
@@ -1944,13 +2246,20 @@ func (s *Service) GetPhotoSignedURL(ctx context.Context, photoID string, userID
 		return "", fmt.Errorf("failed to get photo: %w", err)
 	}
 
-	// Extract S3 key from URL
-	// URL format: https://storage.yandexcloud.net/bucket/key
-	// We need to extract the key part
-	s3Key := extractS3KeyFromURL(photoURL)
+	// Rows created before storage keys were recorded only have the URL
+	if s3Key == "" {
+		s3Key = extractS3KeyFromURL(photoURL)
+	}
+
+	// Sign against the bucket the object currently lives in; another bucket
+	// would only hand out a URL to a missing object
+	store, ok := s.regions.Store(region)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", storage.ErrRegionUnavailable, region)
+	}
 
 	// Generate signed URL (valid for 15 minutes)
-	signedURL, err := s.s3Client.GetSignedURL(ctx, s3Key, 15*time.Minute)
+	signedURL, err := store.GetSignedURL(ctx, s3Key, 15*time.Minute)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate signed URL: %w", err)
 	}
@@ -3,26 +3,45 @@ package dashboard
 import (
 	"fmt"
 	"time"
+
+	"github.com/burcev/api/internal/modules/nutrition"
+	"github.com/burcev/api/internal/shared/events"
 )
 
 // DailyMetrics represents daily tracking metrics for a user
 type DailyMetrics struct {
-	ID                   string         `json:"id" db:"id"`
-	UserID               int64          `json:"user_id" db:"user_id"`
-	Date                 time.Time      `json:"date" db:"date"`
-	Calories             int            `json:"calories" db:"calories"`
-	Protein              int            `json:"protein" db:"protein"`
-	Fat                  int            `json:"fat" db:"fat"`
-	Carbs                int            `json:"carbs" db:"carbs"`
-	Weight               *float64       `json:"weight,omitempty" db:"weight"`
-	Steps                int            `json:"steps" db:"steps"`
-	WorkoutCompleted     bool           `json:"workout_completed" db:"workout_completed"`
-	WorkoutType          *string        `json:"workout_type,omitempty" db:"workout_type"`
-	WorkoutTypes         []string       `json:"workout_types,omitempty"`          // derived; not a DB column
-	WorkoutTypeDurations map[string]int `json:"workout_type_durations,omitempty"` // derived; not a DB column
-	WorkoutDuration      *int           `json:"workout_duration,omitempty" db:"workout_duration"`
-	CreatedAt            time.Time      `json:"created_at" db:"created_at"`
-	UpdatedAt            time.Time      `json:"updated_at" db:"updated_at"`
+	ID                   string                        `json:"id" db:"id"`
+	UserID               int64                         `json:"user_id" db:"user_id"`
+	Date                 time.Time                     `json:"date" db:"date"`
+	Calories             int                           `json:"calories" db:"calories"`
+	Protein              int                           `json:"protein" db:"protein"`
+	Fat                  int                           `json:"fat" db:"fat"`
+	Carbs                int                           `json:"carbs" db:"carbs"`
+	Weight               *float64                      `json:"weight,omitempty" db:"weight"`
+	Steps                int                           `json:"steps" db:"steps"`
+	WorkoutCompleted     bool                          `json:"workout_completed" db:"workout_completed"`
+	WorkoutType          *string                       `json:"workout_type,omitempty" db:"workout_type"`
+	WorkoutTypes         []string                      `json:"workout_types,omitempty"`          // derived; not a DB column
+	WorkoutTypeDurations map[string]int                `json:"workout_type_durations,omitempty"` // derived; not a DB column
+	WorkoutDuration      *int                          `json:"workout_duration,omitempty" db:"workout_duration"`
+	WaterML              int                           `json:"water_ml"`                 // derived from water_intake_events; not a DB column
+	UnreadComments       int                           `json:"unread_comments"`          // derived from curator_comments; not a DB column
+	DayFlag              *nutrition.DayFlag            `json:"day_flag,omitempty"`       // derived from nutrition_day_flags; not a DB column
+	CaloriesBurned       float64                       `json:"calories_burned"`          // derived from activities; not a DB column
+	NetCalories          float64                       `json:"net_calories"`             // Calories less CaloriesBurned; not a DB column
+	MealTargets          map[string]MealTargetProgress `json:"meal_targets,omitempty"`   // derived from meal_plans and nutrition_entries; not a DB column
+	Micronutrients       nutrition.Micronutrients      `json:"micronutrients"`           // derived from nutrition_daily_rollups; not a DB column
+	SodiumWarning        *nutrition.SodiumWarning      `json:"sodium_warning,omitempty"` // set when sodium is over the configured limit; not a DB column
+	CreatedAt            time.Time                     `json:"created_at" db:"created_at"`
+	UpdatedAt            time.Time                     `json:"updated_at" db:"updated_at"`
+}
+
+// MealTargetProgress compares a meal's intake with its meal plan target
+type MealTargetProgress struct {
+	Target nutrition.Macros `json:"target"`
+	Actual nutrition.Macros `json:"actual"`
+	// Delta is Actual less Target: positive above the target, negative below
+	Delta nutrition.Macros `json:"delta"`
 }
 
 // Validate validates the daily metrics fields
@@ -336,6 +355,17 @@ type MetricUpdate struct {
 	Data interface{}      `json:"data" binding:"required"`
 }
 
+// MeasurementCreated is the event published for a saved weight
+type MeasurementCreated struct {
+	Type  string  `json:"type"`
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// EventType implements events.Payload
+func (MeasurementCreated) EventType() string { return events.MeasurementCreated }
+
 // NutritionData represents nutrition metric data
 type NutritionData struct {
 	Calories int `json:"calories" binding:"required,min=0"`
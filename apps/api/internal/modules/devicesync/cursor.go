@@ -0,0 +1,52 @@
+package devicesync
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// position is the last change a client has of a collection: its change
+// time and row id
+type position struct {
+	At time.Time `json:"at"`
+	ID string    `json:"id,omitempty"`
+}
+
+func positionOf(c Change) position {
+	return position{At: c.ChangedAt, ID: c.ID}
+}
+
+func (p position) before(q position) bool {
+	if !p.At.Equal(q.At) {
+		return p.At.Before(q.At)
+	}
+	return p.ID < q.ID
+}
+
+// cursor carries a paged sync over to its next call: the server time of
+// its first call and the position reached in each collection
+type cursor struct {
+	ServerTime time.Time           `json:"server_time"`
+	Positions  map[string]position `json:"positions"`
+}
+
+// encode makes the opaque cursor token handed to the client
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string) (cursor, error) {
+	var c cursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil || c.ServerTime.IsZero() || c.Positions == nil {
+		return cursor{}, validation.Errors{"cursor": "Неверный курсор синхронизации"}
+	}
+	return c, nil
+}
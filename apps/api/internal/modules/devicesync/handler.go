@@ -0,0 +1,54 @@
+package devicesync
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles sync requests
+type Handler struct {
+	log     *logger.Logger
+	service *Service
+}
+
+// NewHandler creates a new sync handler
+func NewHandler(log *logger.Logger, service *Service) *Handler {
+	return &Handler{log: log, service: service}
+}
+
+// Sync handles GET /api/v1/sync?since=<RFC3339>. It returns the nutrition
+// entries, body measurements and day flags changed or deleted since then.
+// When a collection has more than PageSize changes, the response carries a
+// cursor; GET /api/v1/sync?cursor=<cursor> returns the next page.
+func (h *Handler) Sync(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var changes *Changes
+	var err error
+	if token := c.Query("cursor"); token != "" {
+		changes, err = h.service.Continue(c.Request.Context(), userID, token)
+	} else {
+		since, parseErr := time.Parse(time.RFC3339, c.Query("since"))
+		if parseErr != nil {
+			validation.Respond(c, validation.Errors{"since": "Ожидается время в формате RFC 3339"})
+			return
+		}
+		changes, err = h.service.Changes(c.Request.Context(), userID, since)
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, changes)
+}
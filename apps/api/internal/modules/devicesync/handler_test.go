@@ -0,0 +1,76 @@
+package devicesync
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandlerSync(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(t *testing.T, service *Service, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		router := gin.New()
+		router.Use(middleware.ErrorHandler(logger.New()))
+		router.GET("/sync", func(c *gin.Context) {
+			c.Set("user_id", testUserID)
+			NewHandler(logger.New(), service).Sync(c)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sync"+query, nil))
+		return w
+	}
+
+	t.Run("since", func(t *testing.T) {
+		service, mock := setupService(t)
+		expectEmpty(mock, "nutrition_entries", entryColumns)
+		mock.ExpectQuery("FROM sync_tombstones").
+			WillReturnRows(sqlmock.NewRows(tombstoneColumns).AddRow("entry-b", at(2)))
+		for _, table := range []string{"body_measurements", "sync_tombstones", "nutrition_day_flags", "sync_tombstones"} {
+			mock.ExpectQuery("FROM " + table).WillReturnRows(sqlmock.NewRows(tombstoneColumns))
+		}
+
+		w := serve(t, service, "?since=2026-10-01T03:00:00%2B03:00")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{
+			"status": "success",
+			"data": {
+				"server_time": "2026-10-16T11:59:50Z",
+				"nutrition_entries": {"changes": [{"id": "entry-b", "changed_at": "2026-10-01T00:02:00Z", "deleted": true}], "has_more": false},
+				"measurements": {"changes": [], "has_more": false},
+				"day_flags": {"changes": [], "has_more": false}
+			}
+		}`, w.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing or malformed since", func(t *testing.T) {
+		service, mock := setupService(t)
+
+		for _, query := range []string{"", "?since=yesterday"} {
+			w := serve(t, service, query)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), `"since"`)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed cursor", func(t *testing.T) {
+		service, mock := setupService(t)
+
+		w := serve(t, service, "?cursor=garbage")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"cursor"`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
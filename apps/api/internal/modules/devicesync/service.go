@@ -0,0 +1,255 @@
+package devicesync
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/modules/measurements"
+	"github.com/burcev/api/internal/modules/nutrition"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/tombstones"
+)
+
+const (
+	// PageSize caps the changes returned per collection and call; the rest
+	// are fetched with the cursor
+	PageSize = 500
+	// ServerTimeLag is how far server_time trails the clock, so changes of
+	// transactions still running during a sync are picked up by the next
+	// one. Clients may see such changes twice.
+	ServerTimeLag = 10 * time.Second
+)
+
+// Change is a created or updated row with its current values in Data, or
+// a deleted one
+type Change struct {
+	ID        string    `json:"id"`
+	ChangedAt time.Time `json:"changed_at"`
+	Deleted   bool      `json:"deleted"`
+	Data      any       `json:"data,omitempty"`
+}
+
+// CollectionChanges are the changes of a collection in change order.
+// HasMore means the collection has more changes than PageSize; the cursor
+// fetches them.
+type CollectionChanges struct {
+	Changes []Change `json:"changes"`
+	HasMore bool     `json:"has_more"`
+}
+
+// Changes is the response of GET /api/v1/sync. The client stores
+// ServerTime and passes it as since on the next sync, after following
+// Cursor until it is empty.
+type Changes struct {
+	ServerTime       time.Time         `json:"server_time"`
+	Cursor           string            `json:"cursor,omitempty"`
+	NutritionEntries CollectionChanges `json:"nutrition_entries"`
+	Measurements     CollectionChanges `json:"measurements"`
+	DayFlags         CollectionChanges `json:"day_flags"`
+}
+
+// Service reads the changes of the synced collections
+type Service struct {
+	db    *database.DB
+	log   *logger.Logger
+	flags *nutrition.FlagService
+	now   func() time.Time
+}
+
+// NewService creates a new sync service. flags decides which day flags are
+// reported as excluded.
+func NewService(db *database.DB, log *logger.Logger, flags *nutrition.FlagService) *Service {
+	return &Service{db: db, log: log, flags: flags, now: time.Now}
+}
+
+// collection is a synced table; live returns up to limit of the user's
+// rows changed after the position, in change order
+type collection struct {
+	name string
+	dst  *CollectionChanges
+	live func(ctx context.Context, userID int64, after position, limit int) ([]Change, error)
+}
+
+// Changes returns what changed since the given time, deletions included
+func (s *Service) Changes(ctx context.Context, userID int64, since time.Time) (*Changes, error) {
+	c := cursor{ServerTime: s.now().Add(-ServerTimeLag).UTC(), Positions: map[string]position{}}
+	for _, name := range []string{tombstones.NutritionEntries, tombstones.Measurements, tombstones.DayFlags} {
+		c.Positions[name] = position{At: since}
+	}
+	return s.collect(ctx, userID, c)
+}
+
+// Continue returns the next page of a sync from its cursor. A malformed
+// cursor is reported as validation.Errors.
+func (s *Service) Continue(ctx context.Context, userID int64, token string) (*Changes, error) {
+	c, err := decodeCursor(token)
+	if err != nil {
+		return nil, err
+	}
+	return s.collect(ctx, userID, c)
+}
+
+func (s *Service) collect(ctx context.Context, userID int64, c cursor) (*Changes, error) {
+	changes := &Changes{ServerTime: c.ServerTime}
+	collections := []collection{
+		{tombstones.NutritionEntries, &changes.NutritionEntries, s.entryChanges},
+		{tombstones.Measurements, &changes.Measurements, s.measurementChanges},
+		{tombstones.DayFlags, &changes.DayFlags, s.dayFlagChanges},
+	}
+
+	next := cursor{ServerTime: c.ServerTime, Positions: map[string]position{}}
+	hasMore := false
+	for _, col := range collections {
+		after := c.Positions[col.name]
+		page, err := s.page(ctx, userID, col, after)
+		if err != nil {
+			return nil, err
+		}
+		*col.dst = page
+
+		if n := len(page.Changes); n > 0 {
+			after = positionOf(page.Changes[n-1])
+		}
+		next.Positions[col.name] = after
+		hasMore = hasMore || page.HasMore
+	}
+
+	if hasMore {
+		changes.Cursor = next.encode()
+	}
+	return changes, nil
+}
+
+// page merges the changed rows and the tombstones of a collection after
+// the position. Reading one more of each than fits tells whether the
+// collection has more.
+func (s *Service) page(ctx context.Context, userID int64, col collection, after position) (CollectionChanges, error) {
+	live, err := col.live(ctx, userID, after, PageSize+1)
+	if err != nil {
+		return CollectionChanges{}, err
+	}
+	deleted, err := s.deletions(ctx, userID, col.name, after, PageSize+1)
+	if err != nil {
+		return CollectionChanges{}, err
+	}
+
+	merged := mergeChanges(live, deleted)
+	if len(merged) > PageSize {
+		return CollectionChanges{Changes: merged[:PageSize], HasMore: true}, nil
+	}
+	return CollectionChanges{Changes: merged}, nil
+}
+
+// mergeChanges merges two lists in change order into one
+func mergeChanges(a, b []Change) []Change {
+	merged := make([]Change, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if positionOf(b[0]).before(positionOf(a[0])) {
+			merged, b = append(merged, b[0]), b[1:]
+		} else {
+			merged, a = append(merged, a[0]), a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// afterPosition is the condition selecting rows changed after the position
+// ($2, $3) by the time column and text id; ids compare bytewise, as in Go
+func afterPosition(timeColumn, idColumn string) string {
+	return fmt.Sprintf(`(%[1]s > $2 OR (%[1]s = $2 AND %[2]s::text COLLATE "C" > $3))`, timeColumn, idColumn)
+}
+
+// query runs a change query and scans each row into a Change
+func (s *Service) query(ctx context.Context, name, query string, userID int64, after position, limit int, extra []any, scan func(*sql.Rows) (Change, error)) ([]Change, error) {
+	startTime := time.Now()
+	args := append([]any{userID, after.At, after.ID, limit}, extra...)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":    userID,
+		"collection": name,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s changes: %w", name, err)
+	}
+	defer rows.Close()
+
+	changes := make([]Change, 0)
+	for rows.Next() {
+		change, err := scan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s change: %w", name, err)
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s changes: %w", name, err)
+	}
+	return changes, nil
+}
+
+// deletions are the tombstones of a collection
+func (s *Service) deletions(ctx context.Context, userID int64, name string, after position, limit int) ([]Change, error) {
+	query := `
+		SELECT item_id, deleted_at
+		FROM sync_tombstones
+		WHERE user_id = $1 AND collection = $5 AND ` + afterPosition("deleted_at", "item_id") + `
+		ORDER BY deleted_at, item_id COLLATE "C"
+		LIMIT $4`
+	return s.query(ctx, name+" tombstones", query, userID, after, limit, []any{name}, func(rows *sql.Rows) (Change, error) {
+		change := Change{Deleted: true}
+		err := rows.Scan(&change.ID, &change.ChangedAt)
+		return change, err
+	})
+}
+
+func (s *Service) entryChanges(ctx context.Context, userID int64, after position, limit int) ([]Change, error) {
+	query := `
+		SELECT id, date::text, meal, food, calories, protein, carbs, fat, created_at, updated_at, recipe_id, portion_grams, version,
+		       fiber_g, sugar_g, sodium_mg, saturated_fat_g
+		FROM nutrition_entries
+		WHERE user_id = $1 AND ` + afterPosition("updated_at", "id") + `
+		ORDER BY updated_at, id::text COLLATE "C"
+		LIMIT $4`
+	return s.query(ctx, tombstones.NutritionEntries, query, userID, after, limit, nil, func(rows *sql.Rows) (Change, error) {
+		e := nutrition.Entry{UserID: userID}
+		err := rows.Scan(&e.ID, &e.Date, &e.Meal, &e.Food, &e.Calories, &e.Protein, &e.Carbs, &e.Fat,
+			&e.CreatedAt, &e.UpdatedAt, &e.RecipeID, &e.PortionGrams, &e.Version,
+			&e.FiberG, &e.SugarG, &e.SodiumMg, &e.SaturatedFatG)
+		return Change{ID: e.ID, ChangedAt: e.UpdatedAt, Data: &e}, err
+	})
+}
+
+func (s *Service) measurementChanges(ctx context.Context, userID int64, after position, limit int) ([]Change, error) {
+	query := `
+		SELECT id, date::text, waist_cm, chest_cm, hips_cm, thigh_cm, arm_cm, neck_cm, created_at, updated_at
+		FROM body_measurements
+		WHERE user_id = $1 AND ` + afterPosition("updated_at", "id") + `
+		ORDER BY updated_at, id::text COLLATE "C"
+		LIMIT $4`
+	return s.query(ctx, tombstones.Measurements, query, userID, after, limit, nil, func(rows *sql.Rows) (Change, error) {
+		var m measurements.Measurement
+		var changedAt time.Time
+		err := rows.Scan(&m.ID, &m.Date, &m.Waist, &m.Chest, &m.Hips, &m.Thigh, &m.Arm, &m.Neck, &m.CreatedAt, &changedAt)
+		return Change{ID: m.ID, ChangedAt: changedAt, Data: &m}, err
+	})
+}
+
+// dayFlagChanges are keyed by the flag date
+func (s *Service) dayFlagChanges(ctx context.Context, userID int64, after position, limit int) ([]Change, error) {
+	query := `
+		SELECT date::text, type, note, created_at, updated_at
+		FROM nutrition_day_flags
+		WHERE user_id = $1 AND ` + afterPosition("updated_at", "date") + `
+		ORDER BY updated_at, date
+		LIMIT $4`
+	return s.query(ctx, tombstones.DayFlags, query, userID, after, limit, nil, func(rows *sql.Rows) (Change, error) {
+		var f nutrition.DayFlag
+		err := rows.Scan(&f.Date, &f.Type, &f.Note, &f.CreatedAt, &f.UpdatedAt)
+		f.Excluded = s.flags.Excludes(f.Type)
+		return Change{ID: f.Date, ChangedAt: f.UpdatedAt, Data: &f}, err
+	})
+}
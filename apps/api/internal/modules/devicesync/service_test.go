@@ -0,0 +1,195 @@
+package devicesync
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/nutrition"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/tombstones"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserID int64 = 42
+
+var (
+	testNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	since   = time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+)
+
+var (
+	entryColumns       = []string{"id", "date", "meal", "food", "calories", "protein", "carbs", "fat", "created_at", "updated_at", "recipe_id", "portion_grams", "version", "fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}
+	measurementColumns = []string{"id", "date", "waist_cm", "chest_cm", "hips_cm", "thigh_cm", "arm_cm", "neck_cm", "created_at", "updated_at"}
+	flagColumns        = []string{"date", "type", "note", "created_at", "updated_at"}
+	tombstoneColumns   = []string{"item_id", "deleted_at"}
+)
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	log := logger.New()
+	flags := nutrition.NewFlagService(&database.DB{DB: db}, log, []string{nutrition.DayFlagSick}, nil)
+	service := NewService(&database.DB{DB: db}, log, flags)
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+// at is a change time minutes after since
+func at(minutes int) time.Time {
+	return since.Add(time.Duration(minutes) * time.Minute)
+}
+
+// expectEmpty expects a collection query without changes
+func expectEmpty(mock sqlmock.Sqlmock, table string, columns []string) {
+	mock.ExpectQuery("FROM " + table).WillReturnRows(sqlmock.NewRows(columns))
+}
+
+func TestService_Changes(t *testing.T) {
+	t.Run("deletions merge with changes in change order", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM nutrition_entries").
+			WithArgs(testUserID, since, "", PageSize+1).
+			WillReturnRows(sqlmock.NewRows(entryColumns).
+				AddRow("entry-a", "2026-10-02", "lunch", "Суп", 200.0, 8.0, 20.0, 9.0, at(1), at(1), nil, nil, 1, nil, nil, nil, nil).
+				AddRow("entry-c", "2026-10-03", "dinner", "Рис", 300.0, 6.0, 60.0, 2.0, at(3), at(5), nil, nil, 1, nil, nil, nil, nil))
+		mock.ExpectQuery("FROM sync_tombstones").
+			WithArgs(testUserID, since, "", PageSize+1, tombstones.NutritionEntries).
+			WillReturnRows(sqlmock.NewRows(tombstoneColumns).AddRow("entry-b", at(2)))
+		expectEmpty(mock, "body_measurements", measurementColumns)
+		expectEmpty(mock, "sync_tombstones", tombstoneColumns)
+		mock.ExpectQuery("FROM nutrition_day_flags").
+			WillReturnRows(sqlmock.NewRows(flagColumns).AddRow("2026-10-04", nutrition.DayFlagSick, "", at(4), at(4)))
+		// The same day was unflagged before it was flagged again
+		mock.ExpectQuery("FROM sync_tombstones").
+			WithArgs(testUserID, since, "", PageSize+1, tombstones.DayFlags).
+			WillReturnRows(sqlmock.NewRows(tombstoneColumns).AddRow("2026-10-04", at(2)))
+
+		changes, err := service.Changes(context.Background(), testUserID, since)
+
+		require.NoError(t, err)
+		assert.Equal(t, testNow.Add(-ServerTimeLag), changes.ServerTime)
+		assert.Empty(t, changes.Cursor)
+
+		entries := changes.NutritionEntries
+		assert.False(t, entries.HasMore)
+		require.Len(t, entries.Changes, 3)
+		assert.Equal(t, []string{"entry-a", "entry-b", "entry-c"}, changeIDs(entries.Changes))
+		assert.Equal(t, Change{ID: "entry-b", ChangedAt: at(2), Deleted: true}, entries.Changes[1])
+		entry := entries.Changes[2].Data.(*nutrition.Entry)
+		assert.Equal(t, "Рис", entry.Food)
+		assert.Equal(t, testUserID, entry.UserID)
+		assert.Equal(t, at(5), entries.Changes[2].ChangedAt, "changes are timed by updated_at")
+
+		assert.Empty(t, changes.Measurements.Changes)
+
+		flags := changes.DayFlags.Changes
+		require.Len(t, flags, 2)
+		assert.True(t, flags[0].Deleted)
+		assert.Equal(t, "2026-10-04", flags[1].ID)
+		assert.True(t, flags[1].Data.(*nutrition.DayFlag).Excluded)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query failure", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM nutrition_entries").WillReturnError(fmt.Errorf("connection reset"))
+
+		_, err := service.Changes(context.Background(), testUserID, since)
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_Pagination(t *testing.T) {
+	service, mock := setupService(t)
+
+	// One entry more than fits a page, so the entries are cut at PageSize
+	entries := sqlmock.NewRows(entryColumns)
+	for i := 0; i <= PageSize; i++ {
+		entries.AddRow(fmt.Sprintf("entry-%04d", i), "2026-10-02", "snack", "Яблоко", 50.0, 0.0, 12.0, 0.0, at(i), at(i), nil, nil, 1, nil, nil, nil, nil)
+	}
+	mock.ExpectQuery("FROM nutrition_entries").WillReturnRows(entries)
+	expectEmpty(mock, "sync_tombstones", tombstoneColumns)
+	mock.ExpectQuery("FROM body_measurements").
+		WillReturnRows(sqlmock.NewRows(measurementColumns).AddRow("m-1", "2026-10-05", 80.5, nil, nil, nil, nil, nil, at(7), at(7)))
+	expectEmpty(mock, "sync_tombstones", tombstoneColumns)
+	expectEmpty(mock, "nutrition_day_flags", flagColumns)
+	mock.ExpectQuery("FROM sync_tombstones").
+		WillReturnRows(sqlmock.NewRows(tombstoneColumns).AddRow("2026-10-06", at(9)))
+
+	first, err := service.Changes(context.Background(), testUserID, since)
+
+	require.NoError(t, err)
+	assert.True(t, first.NutritionEntries.HasMore)
+	assert.Len(t, first.NutritionEntries.Changes, PageSize)
+	assert.False(t, first.Measurements.HasMore)
+	assert.False(t, first.DayFlags.HasMore)
+	require.NotEmpty(t, first.Cursor)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The next page picks up every collection where the first one ended
+	service.now = func() time.Time { return testNow.Add(time.Minute) }
+	mock.ExpectQuery("FROM nutrition_entries").
+		WithArgs(testUserID, at(PageSize-1), fmt.Sprintf("entry-%04d", PageSize-1), PageSize+1).
+		WillReturnRows(sqlmock.NewRows(entryColumns).
+			AddRow(fmt.Sprintf("entry-%04d", PageSize), "2026-10-02", "snack", "Яблоко", 50.0, 0.0, 12.0, 0.0, at(PageSize), at(PageSize), nil, nil, 1, nil, nil, nil, nil))
+	mock.ExpectQuery("FROM sync_tombstones").
+		WithArgs(testUserID, at(PageSize-1), fmt.Sprintf("entry-%04d", PageSize-1), PageSize+1, tombstones.NutritionEntries).
+		WillReturnRows(sqlmock.NewRows(tombstoneColumns))
+	mock.ExpectQuery("FROM body_measurements").
+		WithArgs(testUserID, at(7), "m-1", PageSize+1).
+		WillReturnRows(sqlmock.NewRows(measurementColumns))
+	expectEmpty(mock, "sync_tombstones", tombstoneColumns)
+	mock.ExpectQuery("FROM nutrition_day_flags").
+		WithArgs(testUserID, at(9), "2026-10-06", PageSize+1).
+		WillReturnRows(sqlmock.NewRows(flagColumns))
+	expectEmpty(mock, "sync_tombstones", tombstoneColumns)
+
+	second, err := service.Continue(context.Background(), testUserID, first.Cursor)
+
+	require.NoError(t, err)
+	assert.Equal(t, first.ServerTime, second.ServerTime, "a paged sync keeps the time of its first page")
+	assert.Empty(t, second.Cursor)
+	assert.False(t, second.NutritionEntries.HasMore)
+	assert.Equal(t, []string{fmt.Sprintf("entry-%04d", PageSize)}, changeIDs(second.NutritionEntries.Changes))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_ContinueInvalidCursor(t *testing.T) {
+	service, mock := setupService(t)
+
+	for _, token := range []string{"not base64!", "bm90IGpzb24", cursor{}.encode()} {
+		_, err := service.Continue(context.Background(), testUserID, token)
+
+		var fieldErrs validation.Errors
+		require.ErrorAs(t, err, &fieldErrs, token)
+		assert.Contains(t, fieldErrs, "cursor")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMergeChanges(t *testing.T) {
+	a := []Change{{ID: "a", ChangedAt: at(1)}, {ID: "c", ChangedAt: at(2)}}
+	b := []Change{{ID: "b", ChangedAt: at(2)}, {ID: "d", ChangedAt: at(3)}}
+
+	assert.Equal(t, []string{"a", "b", "c", "d"}, changeIDs(mergeChanges(a, b)), "ties are ordered by id")
+	assert.Empty(t, mergeChanges(nil, nil))
+}
+
+func changeIDs(changes []Change) []string {
+	ids := make([]string, len(changes))
+	for i, c := range changes {
+		ids[i] = c.ID
+	}
+	return ids
+}
@@ -0,0 +1,80 @@
+package features
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the feature flag admin routes
+type Handler struct {
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new feature flag handler
+func NewHandler(log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		log:     log,
+		service: service,
+	}
+}
+
+// List handles GET /api/v1/admin/flags
+func (h *Handler) List(c *gin.Context) {
+	flags, err := h.service.List(c.Request.Context())
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.List(c, http.StatusOK, FlagsResponse{response.NewList(flags, 0, 0)}, "flags")
+}
+
+// Create handles POST /api/v1/admin/flags
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateFlagRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	flag, err := h.service.Create(c.Request.Context(), c.GetInt64("user_id"), &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, FlagResponse{Flag: flag})
+}
+
+// Update handles PUT /api/v1/admin/flags/:flag
+// The body replaces all of the flag's settings.
+func (h *Handler) Update(c *gin.Context) {
+	var req Settings
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	flag, err := h.service.Update(c.Request.Context(), c.GetInt64("user_id"), c.Param("flag"), &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, FlagResponse{Flag: flag})
+}
+
+// Delete handles DELETE /api/v1/admin/flags/:flag
+func (h *Handler) Delete(c *gin.Context) {
+	if err := h.service.Delete(c.Request.Context(), c.GetInt64("user_id"), c.Param("flag")); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Флаг удалён", nil)
+}
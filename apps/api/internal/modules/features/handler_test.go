@@ -0,0 +1,140 @@
+package features
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err      error
+	adminID  int64
+	name     string
+	created  *CreateFlagRequest
+	settings *Settings
+}
+
+func (m *mockService) List(ctx context.Context) ([]Flag, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Flag{{Flag: AIAnalysis, Settings: Settings{Enabled: true, RolloutPercent: 10, Allowlist: []int64{}}}}, nil
+}
+
+func (m *mockService) Create(ctx context.Context, adminID int64, req *CreateFlagRequest) (*Flag, error) {
+	m.adminID, m.created = adminID, req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Flag{Flag: req.Flag, Settings: req.Settings}, nil
+}
+
+func (m *mockService) Update(ctx context.Context, adminID int64, name string, settings *Settings) (*Flag, error) {
+	m.adminID, m.name, m.settings = adminID, name, settings
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Flag{Flag: name, Settings: *settings}, nil
+}
+
+func (m *mockService) Delete(ctx context.Context, adminID int64, name string) error {
+	m.adminID, m.name = adminID, name
+	return m.err
+}
+
+func serve(service ServiceInterface, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	admin := router.Group("/admin", func(c *gin.Context) { c.Set("user_id", testAdminID) })
+	RegisterAdminRoutes(admin, NewHandler(logger.New(), service))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandler_List(t *testing.T) {
+	w := serve(&mockService{}, http.MethodGet, "/admin/flags", "")
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Items []Flag `json:"items"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Items, 1)
+	assert.Equal(t, 10, resp.Data.Items[0].RolloutPercent)
+}
+
+func TestHandler_Create(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", `{"flag":"dark_mode","enabled":true,"rollout_percent":25,"allowlist":[7]}`, nil, http.StatusCreated},
+		{"no name", `{"enabled":true}`, nil, http.StatusBadRequest},
+		{"rollout over 100", `{"flag":"dark_mode","rollout_percent":101}`, nil, http.StatusBadRequest},
+		{"invalid user in the allowlist", `{"flag":"dark_mode","allowlist":[0]}`, nil, http.StatusBadRequest},
+		{"taken name", `{"flag":"ai_analysis"}`, &apperrors.ConflictError{Constraint: "feature_flags_pkey", Field: "flag"}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockService{err: tt.err}
+
+			w := serve(service, http.MethodPost, "/admin/flags", tt.body)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+			if tt.code == http.StatusCreated {
+				assert.Equal(t, testAdminID, service.adminID)
+				assert.Equal(t, 25, service.created.RolloutPercent)
+				assert.Contains(t, w.Body.String(), `"flag":"dark_mode"`)
+			}
+		})
+	}
+}
+
+func TestHandler_Update(t *testing.T) {
+	t.Run("settings are passed on", func(t *testing.T) {
+		service := &mockService{}
+
+		w := serve(service, http.MethodPut, "/admin/flags/ai_analysis", `{"enabled":true,"rollout_percent":5}`)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, AIAnalysis, service.name)
+		assert.Equal(t, &Settings{Enabled: true, RolloutPercent: 5}, service.settings)
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		w := serve(&mockService{err: apperrors.ErrNotFound}, http.MethodPut, "/admin/flags/unknown", `{}`)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_Delete(t *testing.T) {
+	service := &mockService{}
+
+	w := serve(service, http.MethodDelete, "/admin/flags/ai_analysis", "")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, AIAnalysis, service.name)
+
+	w = serve(&mockService{err: assert.AnError}, http.MethodDelete, "/admin/flags/ai_analysis", "")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
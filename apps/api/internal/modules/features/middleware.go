@@ -0,0 +1,47 @@
+package features
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// contextKey is where Middleware puts the service for Enabled
+const contextKey = "features"
+
+// notFoundBody is what Gin answers for a route it does not have
+const notFoundBody = "404 page not found"
+
+// Middleware makes s available to Enabled and Require on every route
+func Middleware(s *Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(contextKey, s)
+		c.Next()
+	}
+}
+
+// Enabled reports whether flag is on for the user of the request. Flags
+// are off without Middleware; before authentication only flags rolled out
+// to everyone are on.
+func Enabled(c *gin.Context, flag string) bool {
+	s, ok := c.Value(contextKey).(*Service)
+	if !ok {
+		return false
+	}
+	return s.Enabled(c.Request.Context(), flag, c.GetInt64("user_id"))
+}
+
+// Require lets the request through when flag is on for its user. Otherwise
+// it answers exactly as for a route that does not exist, not 403, so an
+// unreleased feature cannot be discovered. Register it after the
+// authentication middleware.
+func Require(flag string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !Enabled(c, flag) {
+			c.Data(http.StatusNotFound, "text/plain", []byte(notFoundBody))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package features
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedRouter serves /gated behind Require(AIAnalysis) for userID
+func gatedRouter(service *Service, userID int64) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	if service != nil {
+		router.Use(Middleware(service))
+	}
+	authenticated := func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+	}
+	router.POST("/gated", authenticated, Require(AIAnalysis), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+func post(router http.Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	return w
+}
+
+func TestRequire(t *testing.T) {
+	setup := func(t *testing.T, percent int, allowlist string) *Service {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM feature_flags").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", true, percent, allowlist, testNow, testNow))
+		require.NoError(t, service.Refresh(context.Background()))
+		return service
+	}
+
+	t.Run("flag on for the user", func(t *testing.T) {
+		w := post(gatedRouter(setup(t, 0, "{42}"), testUserID), "/gated")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("flag off looks like a missing route", func(t *testing.T) {
+		router := gatedRouter(setup(t, 0, "{}"), testUserID)
+
+		gated := post(router, "/gated")
+		missing := post(router, "/missing")
+
+		assert.Equal(t, http.StatusNotFound, gated.Code)
+		assert.Equal(t, missing.Code, gated.Code)
+		assert.Equal(t, missing.Body.String(), gated.Body.String())
+		assert.Equal(t, missing.Header().Get("Content-Type"), gated.Header().Get("Content-Type"))
+	})
+
+	t.Run("without the middleware every flag is off", func(t *testing.T) {
+		w := post(gatedRouter(nil, testUserID), "/gated")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("signed out caller", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, post(gatedRouter(setup(t, 50, "{}"), 0), "/gated").Code)
+		assert.Equal(t, http.StatusOK, post(gatedRouter(setup(t, 100, "{}"), 0), "/gated").Code)
+	})
+}
@@ -0,0 +1,42 @@
+package features
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"slices"
+)
+
+// rolloutBuckets is the number of buckets users are spread over; one
+// bucket is one percent of the rollout
+const rolloutBuckets = 100
+
+// bucket places userID in one of the rolloutBuckets buckets of flag. It
+// depends on nothing but its inputs, so a user gets the same bucket on
+// every instance and after restarts. The flag is hashed too, so each flag
+// rolls out to a different share of the users.
+func bucket(flag string, userID int64) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(flag))
+	var id [9]byte
+	binary.BigEndian.PutUint64(id[1:], uint64(userID))
+	_, _ = h.Write(id[:])
+	return int(h.Sum64() % rolloutBuckets)
+}
+
+// EnabledFor reports whether the flag is on for userID. Users in the
+// first RolloutPercent buckets get it, so raising the percentage only adds
+// users. userID 0, a caller who is not signed in, only gets flags rolled
+// out to everyone.
+func (f *Flag) EnabledFor(userID int64) bool {
+	switch {
+	case !f.Enabled:
+		return false
+	case f.RolloutPercent >= rolloutBuckets:
+		return true
+	case userID == 0:
+		return false
+	case slices.Contains(f.Allowlist, userID):
+		return true
+	}
+	return bucket(f.Flag, userID) < f.RolloutPercent
+}
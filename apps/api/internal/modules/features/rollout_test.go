@@ -0,0 +1,108 @@
+package features
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucket(t *testing.T) {
+	t.Run("same user always gets the same bucket", func(t *testing.T) {
+		for userID := int64(1); userID <= 1000; userID++ {
+			first := bucket(AIAnalysis, userID)
+			for range 3 {
+				assert.Equal(t, first, bucket(AIAnalysis, userID))
+			}
+			assert.GreaterOrEqual(t, first, 0)
+			assert.Less(t, first, rolloutBuckets)
+		}
+	})
+
+	t.Run("known buckets stay put", func(t *testing.T) {
+		// Changing the hash would move users in and out of every rollout
+		assert.Equal(t, map[int64]int{1: 73, 42: 76, 100500: 45}, map[int64]int{
+			1:      bucket(AIAnalysis, 1),
+			42:     bucket(AIAnalysis, 42),
+			100500: bucket(AIAnalysis, 100500),
+		})
+	})
+
+	t.Run("sequential ids spread evenly", func(t *testing.T) {
+		const users = 100_000
+		counts := make([]int, rolloutBuckets)
+		for userID := int64(1); userID <= users; userID++ {
+			counts[bucket(Recommendations, userID)]++
+		}
+		for b, n := range counts {
+			// 1000 expected in each bucket
+			assert.InDelta(t, users/rolloutBuckets, n, 150, "bucket %d", b)
+		}
+	})
+
+	t.Run("flags pick different users", func(t *testing.T) {
+		same := 0
+		for userID := int64(1); userID <= 1000; userID++ {
+			if bucket(AIAnalysis, userID) == bucket(Recommendations, userID) {
+				same++
+			}
+		}
+		assert.Less(t, same, 50)
+	})
+}
+
+func TestFlag_EnabledFor(t *testing.T) {
+	rollout := func(percent int) *Flag {
+		return &Flag{Flag: AIAnalysis, Settings: Settings{Enabled: true, RolloutPercent: percent}}
+	}
+	share := func(f *Flag) float64 {
+		on := 0
+		for userID := int64(1); userID <= 10_000; userID++ {
+			if f.EnabledFor(userID) {
+				on++
+			}
+		}
+		return float64(on) / 10_000
+	}
+
+	t.Run("percent of the users", func(t *testing.T) {
+		assert.Equal(t, 0.0, share(rollout(0)))
+		assert.InDelta(t, 0.10, share(rollout(10)), 0.01)
+		assert.InDelta(t, 0.50, share(rollout(50)), 0.02)
+		assert.Equal(t, 1.0, share(rollout(100)))
+	})
+
+	t.Run("raising the percent keeps everyone who had the feature", func(t *testing.T) {
+		for userID := int64(1); userID <= 10_000; userID++ {
+			had := false
+			for percent := 0; percent <= 100; percent += 5 {
+				on := rollout(percent).EnabledFor(userID)
+				if had {
+					assert.True(t, on, "user %d lost the feature at %d%%", userID, percent)
+				}
+				had = on
+			}
+		}
+	})
+
+	t.Run("allowlist outside the rollout", func(t *testing.T) {
+		f := rollout(0)
+		f.Allowlist = []int64{7, 42}
+
+		assert.True(t, f.EnabledFor(42))
+		assert.False(t, f.EnabledFor(43))
+	})
+
+	t.Run("disabled flag is off for everyone", func(t *testing.T) {
+		f := rollout(100)
+		f.Enabled = false
+		f.Allowlist = []int64{42}
+
+		assert.Equal(t, 0.0, share(f))
+		assert.False(t, f.EnabledFor(42))
+	})
+
+	t.Run("signed out callers only get full rollouts", func(t *testing.T) {
+		assert.False(t, rollout(99).EnabledFor(0))
+		assert.True(t, rollout(100).EnabledFor(0))
+	})
+}
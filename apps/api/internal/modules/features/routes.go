@@ -0,0 +1,12 @@
+package features
+
+import "github.com/gin-gonic/gin"
+
+// RegisterAdminRoutes registers the flag management routes on the admin
+// group r
+func RegisterAdminRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/flags", h.List)
+	r.POST("/flags", h.Create)
+	r.PUT("/flags/:flag", h.Update)
+	r.DELETE("/flags/:flag", h.Delete)
+}
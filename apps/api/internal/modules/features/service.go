@@ -0,0 +1,304 @@
+// Package features turns features on per user with flags kept in the
+// database: a flag is off, on for a share of the users, or on for listed
+// users. Each instance checks flags against an in-memory copy it reloads
+// every RefreshInterval, so a check costs no query.
+package features
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// ServiceInterface defines the flag operations the admin handler uses
+type ServiceInterface interface {
+	List(ctx context.Context) ([]Flag, error)
+	Create(ctx context.Context, adminID int64, req *CreateFlagRequest) (*Flag, error)
+	Update(ctx context.Context, adminID int64, name string, settings *Settings) (*Flag, error)
+	Delete(ctx context.Context, adminID int64, name string) error
+}
+
+// Service stores the flags and evaluates them
+type Service struct {
+	db    *database.DB
+	log   *logger.Logger
+	audit *audit.Service
+
+	mu sync.RWMutex
+	// flags is the in-memory copy by name; nil until first loaded
+	flags map[string]Flag
+	// loadAttemptAt is when a check last tried to load the flags
+	loadAttemptAt time.Time
+	now           func() time.Time
+}
+
+// NewService creates a new feature flag service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{
+		db:    db,
+		log:   log,
+		audit: audit.NewService(db.DB, log),
+		now:   time.Now,
+	}
+}
+
+// flagName is what a flag may be called
+var flagName = regexp.MustCompile(`^[a-z][a-z0-9_]{1,62}$`)
+
+const flagColumns = `flag, description, enabled, rollout_percent, allowlist::text, created_at, updated_at`
+
+func scanFlag(row interface{ Scan(dest ...any) error }) (*Flag, error) {
+	var f Flag
+	var allowlist string
+	if err := row.Scan(&f.Flag, &f.Description, &f.Enabled, &f.RolloutPercent, &allowlist, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		return nil, err
+	}
+	f.Allowlist = parseIDArray(allowlist)
+	return &f, nil
+}
+
+// Enabled reports whether flag is on for userID. An unknown flag is off.
+// Checks made before the first refresh load the flags themselves, at most
+// once per loadRetryInterval; while they cannot be loaded every flag is off.
+func (s *Service) Enabled(ctx context.Context, flag string, userID int64) bool {
+	s.mu.RLock()
+	loaded := s.flags != nil
+	s.mu.RUnlock()
+	if !loaded && s.startLoad() {
+		if err := s.Refresh(ctx); err != nil {
+			s.log.Error("Failed to load feature flags", "error", err)
+			return false
+		}
+	}
+
+	s.mu.RLock()
+	f, ok := s.flags[flag]
+	s.mu.RUnlock()
+	return ok && f.EnabledFor(userID)
+}
+
+// startLoad reports whether a check may try to load the flags now, and if
+// so records the attempt
+func (s *Service) startLoad() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if s.flags != nil || now.Sub(s.loadAttemptAt) < loadRetryInterval {
+		return false
+	}
+	s.loadAttemptAt = now
+	return true
+}
+
+// Refresh replaces the in-memory copy with the flags in the database
+func (s *Service) Refresh(ctx context.Context) error {
+	list, err := s.List(ctx)
+	if err != nil {
+		return err
+	}
+	flags := make(map[string]Flag, len(list))
+	for _, f := range list {
+		flags[f.Flag] = f
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flags = flags
+	return nil
+}
+
+// RunRefresh reloads the flags at start and then every RefreshInterval
+// until ctx is cancelled. A failed reload keeps the previous copy.
+func (s *Service) RunRefresh(ctx context.Context) {
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Feature flag refresh started")
+
+	run := func() {
+		if err := s.Refresh(ctx); err != nil {
+			s.log.Warn("Failed to refresh feature flags", "error", err)
+		}
+	}
+
+	run()
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case <-ctx.Done():
+			s.log.Info("Feature flag refresh stopped")
+			return
+		}
+	}
+}
+
+// List returns every flag from the database, ordered by name
+func (s *Service) List(ctx context.Context) ([]Flag, error) {
+	startTime := time.Now()
+	query := `SELECT ` + flagColumns + ` FROM feature_flags ORDER BY flag`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]Flag, 0)
+	for rows.Next() {
+		f, err := scanFlag(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feature flag: %w", err)
+		}
+		flags = append(flags, *f)
+	}
+	return flags, rows.Err()
+}
+
+// Create adds a flag, set by adminID. A name that is taken is reported as
+// *apperrors.ConflictError, an invalid one as validation.Errors.
+func (s *Service) Create(ctx context.Context, adminID int64, req *CreateFlagRequest) (*Flag, error) {
+	if !flagName.MatchString(req.Flag) {
+		return nil, validation.Errors{"flag": "Имя флага: от 2 до 63 строчных латинских букв, цифр и _, начиная с буквы"}
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO feature_flags (flag, description, enabled, rollout_percent, allowlist)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + flagColumns
+
+	settings := normalize(req.Settings)
+	f, err := scanFlag(s.db.QueryRowContext(ctx, query, req.Flag, settings.Description, settings.Enabled,
+		settings.RolloutPercent, idArrayParam(settings.Allowlist)))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"flag": req.Flag})
+	if err != nil {
+		if err := database.ConstraintError(err); errors.Is(err, apperrors.ErrConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to create feature flag: %w", err)
+	}
+
+	s.changed(ctx, adminID, f, false)
+	return f, nil
+}
+
+// Update replaces the settings of the flag called name, set by adminID
+func (s *Service) Update(ctx context.Context, adminID int64, name string, settings *Settings) (*Flag, error) {
+	startTime := time.Now()
+	query := `
+		UPDATE feature_flags
+		SET description = $2, enabled = $3, rollout_percent = $4, allowlist = $5, updated_at = NOW()
+		WHERE flag = $1
+		RETURNING ` + flagColumns
+
+	normalized := normalize(*settings)
+	f, err := scanFlag(s.db.QueryRowContext(ctx, query, name, normalized.Description, normalized.Enabled,
+		normalized.RolloutPercent, idArrayParam(normalized.Allowlist)))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"flag": name})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+
+	s.changed(ctx, adminID, f, false)
+	return f, nil
+}
+
+// Delete removes the flag called name, deleted by adminID. The features it
+// gated are off until it is created again.
+func (s *Service) Delete(ctx context.Context, adminID int64, name string) error {
+	startTime := time.Now()
+	query := `DELETE FROM feature_flags WHERE flag = $1`
+
+	result, err := s.db.ExecContext(ctx, query, name)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"flag": name})
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if deleted == 0 {
+		return apperrors.ErrNotFound
+	}
+
+	s.changed(ctx, adminID, &Flag{Flag: name}, true)
+	return nil
+}
+
+// changed applies a flag change to this instance's copy right away, the
+// others pick it up on their next refresh, and audits it
+func (s *Service) changed(ctx context.Context, adminID int64, f *Flag, deleted bool) {
+	s.mu.Lock()
+	if s.flags != nil {
+		if deleted {
+			delete(s.flags, f.Flag)
+		} else {
+			s.flags[f.Flag] = *f
+		}
+	}
+	s.mu.Unlock()
+
+	metadata := map[string]any{"flag": f.Flag}
+	if deleted {
+		metadata["deleted"] = true
+	} else {
+		metadata["enabled"] = f.Enabled
+		metadata["rollout_percent"] = f.RolloutPercent
+		metadata["allowlist"] = f.Allowlist
+	}
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &adminID,
+		Action:   audit.ActionFeatureFlagChanged,
+		Metadata: metadata,
+	})
+}
+
+// normalize sorts the allowlist and drops repeated users
+func normalize(settings Settings) Settings {
+	settings.Allowlist = slices.Compact(slices.Sorted(slices.Values(settings.Allowlist)))
+	return settings
+}
+
+// idArrayParam formats ids as a PostgreSQL array literal
+func idArrayParam(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// parseIDArray parses a PostgreSQL array literal like "{1,3,5}"
+func parseIDArray(s string) []int64 {
+	s = strings.Trim(strings.TrimSpace(s), "{}")
+	if s == "" {
+		return []int64{}
+	}
+	parts := strings.Split(s, ",")
+	ids := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		if id, err := strconv.ParseInt(strings.TrimSpace(p), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,193 @@
+package features
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserID  int64 = 42
+	testAdminID int64 = 1
+)
+
+var (
+	testNow         = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	flagColumnNames = []string{"flag", "description", "enabled", "rollout_percent", "allowlist", "created_at", "updated_at"}
+)
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewService(&database.DB{DB: db}, logger.New()), mock
+}
+
+func flagRows() *sqlmock.Rows {
+	return sqlmock.NewRows(flagColumnNames)
+}
+
+// expectAudit expects a flag change to be audited
+func expectAudit(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(testAdminID, sqlmock.AnyArg(), "feature_flag_changed", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func TestService_Enabled(t *testing.T) {
+	t.Run("flags are loaded once and then read from memory", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("SELECT (.+) FROM feature_flags ORDER BY flag").
+			WillReturnRows(flagRows().
+				AddRow(AIAnalysis, "", true, 0, "{42}", testNow, testNow).
+				AddRow(Recommendations, "", false, 100, "{}", testNow, testNow))
+
+		ctx := context.Background()
+		assert.True(t, service.Enabled(ctx, AIAnalysis, testUserID), "allowlisted")
+		assert.False(t, service.Enabled(ctx, AIAnalysis, testUserID+1))
+		assert.False(t, service.Enabled(ctx, Recommendations, testUserID), "disabled")
+		assert.False(t, service.Enabled(ctx, "unknown", testUserID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("flags are off while they cannot be loaded", func(t *testing.T) {
+		service, mock := setupService(t)
+		now := testNow
+		service.now = func() time.Time { return now }
+		mock.ExpectQuery("FROM feature_flags").WillReturnError(errors.New("connection refused"))
+		mock.ExpectQuery("FROM feature_flags").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", true, 100, "{}", testNow, testNow))
+
+		assert.False(t, service.Enabled(context.Background(), AIAnalysis, testUserID))
+		now = now.Add(loadRetryInterval - time.Second)
+		assert.False(t, service.Enabled(context.Background(), AIAnalysis, testUserID), "no query before the retry interval")
+		now = now.Add(time.Second)
+		assert.True(t, service.Enabled(context.Background(), AIAnalysis, testUserID), "the next attempt loads them")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refresh replaces the copy", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM feature_flags").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", true, 100, "{}", testNow, testNow))
+		mock.ExpectQuery("FROM feature_flags").WillReturnRows(flagRows())
+
+		require.NoError(t, service.Refresh(context.Background()))
+		assert.True(t, service.Enabled(context.Background(), AIAnalysis, testUserID))
+		require.NoError(t, service.Refresh(context.Background()))
+		assert.False(t, service.Enabled(context.Background(), AIAnalysis, testUserID), "deleted on another instance")
+	})
+}
+
+func TestService_Create(t *testing.T) {
+	t.Run("created flag applies here at once", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM feature_flags").WillReturnRows(flagRows())
+		require.NoError(t, service.Refresh(context.Background()))
+		mock.ExpectQuery("INSERT INTO feature_flags").
+			WithArgs("dark_mode", "Тёмная тема", true, 25, "{7,42}").
+			WillReturnRows(flagRows().AddRow("dark_mode", "Тёмная тема", true, 25, "{7,42}", testNow, testNow))
+		expectAudit(mock)
+
+		flag, err := service.Create(context.Background(), testAdminID, &CreateFlagRequest{
+			Flag:     "dark_mode",
+			Settings: Settings{Description: "Тёмная тема", Enabled: true, RolloutPercent: 25, Allowlist: []int64{42, 7, 42}},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, []int64{7, 42}, flag.Allowlist)
+		assert.True(t, service.Enabled(context.Background(), "dark_mode", 42))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid name", func(t *testing.T) {
+		for _, name := range []string{"", "a", "Dark_Mode", "1st", "dark-mode", "ai analysis"} {
+			service, mock := setupService(t)
+
+			_, err := service.Create(context.Background(), testAdminID, &CreateFlagRequest{Flag: name})
+
+			var fields validation.Errors
+			require.ErrorAs(t, err, &fields, name)
+			assert.Contains(t, fields, "flag")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("taken name", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO feature_flags").
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "feature_flags_pkey"})
+
+		_, err := service.Create(context.Background(), testAdminID, &CreateFlagRequest{Flag: AIAnalysis})
+
+		var conflict *apperrors.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "flag", conflict.Field)
+	})
+}
+
+func TestService_Update(t *testing.T) {
+	t.Run("settings are replaced", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM feature_flags").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", true, 100, "{}", testNow, testNow))
+		require.NoError(t, service.Refresh(context.Background()))
+		mock.ExpectQuery("UPDATE feature_flags").
+			WithArgs(AIAnalysis, "", false, 100, "{}").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", false, 100, "{}", testNow, testNow))
+		expectAudit(mock)
+
+		_, err := service.Update(context.Background(), testAdminID, AIAnalysis, &Settings{RolloutPercent: 100})
+
+		require.NoError(t, err)
+		assert.False(t, service.Enabled(context.Background(), AIAnalysis, testUserID), "switched off here at once")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("UPDATE feature_flags").WillReturnError(sql.ErrNoRows)
+
+		_, err := service.Update(context.Background(), testAdminID, "unknown", &Settings{})
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestService_Delete(t *testing.T) {
+	t.Run("deleted flag is off", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM feature_flags").
+			WillReturnRows(flagRows().AddRow(AIAnalysis, "", true, 100, "{}", testNow, testNow))
+		require.NoError(t, service.Refresh(context.Background()))
+		mock.ExpectExec("DELETE FROM feature_flags").WithArgs(AIAnalysis).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock)
+
+		require.NoError(t, service.Delete(context.Background(), testAdminID, AIAnalysis))
+
+		assert.False(t, service.Enabled(context.Background(), AIAnalysis, testUserID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown flag", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectExec("DELETE FROM feature_flags").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := service.Delete(context.Background(), testAdminID, "unknown")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
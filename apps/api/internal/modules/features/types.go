@@ -0,0 +1,64 @@
+package features
+
+import (
+	"time"
+
+	"github.com/burcev/api/internal/shared/response"
+)
+
+// Flags of the features being rolled out
+const (
+	// AIAnalysis gates the body fat analysis of progress photos
+	AIAnalysis = "ai_analysis"
+	// Recommendations gates the personal nutrition recommendations
+	Recommendations = "recommendations"
+)
+
+const (
+	// RefreshInterval is how often each instance reloads the flags, and so
+	// how long a change takes to reach every instance
+	RefreshInterval = 30 * time.Second
+	// loadRetryInterval is how long checks wait between attempts to load
+	// the flags the first time, so a database outage does not cost a query
+	// per check
+	loadRetryInterval = 5 * time.Second
+	// MaxAllowlist caps the users a flag can list
+	MaxAllowlist = 1000
+)
+
+// Flag is a feature flag. A disabled flag is off for everyone; an enabled
+// one is on for the users in Allowlist and for RolloutPercent percent of
+// the other users.
+type Flag struct {
+	Flag        string    `json:"flag"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	Settings
+}
+
+// Settings are what an admin sets on a flag
+type Settings struct {
+	Description    string  `json:"description" binding:"max=500"`
+	Enabled        bool    `json:"enabled"`
+	RolloutPercent int     `json:"rollout_percent" binding:"min=0,max=100"`
+	Allowlist      []int64 `json:"allowlist" binding:"max=1000,dive,min=1"`
+}
+
+// CreateFlagRequest creates a flag
+type CreateFlagRequest struct {
+	Flag string `json:"flag" binding:"required"`
+
+	Settings
+}
+
+// FlagResponse is one flag
+type FlagResponse struct {
+	Flag *Flag `json:"flag"`
+}
+
+// FlagsResponse is every flag, ordered by name
+type FlagsResponse struct {
+	response.ListData[Flag]
+}
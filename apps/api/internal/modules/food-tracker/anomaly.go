@@ -0,0 +1,198 @@
+package foodtracker
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Absolute sanity bounds for a single entry
+const (
+	// MaxEntryCalories is more than any realistic single serving
+	MaxEntryCalories = 5000.0
+	// MaxEntryMacroGrams caps protein, fat and carbs of a single entry
+	MaxEntryMacroGrams = 500.0
+	// MaxCaloriesPer100 is just above pure fat (~900 kcal/100 g)
+	MaxCaloriesPer100 = 950.0
+)
+
+// Thresholds for comparing an entry with the user's history of the same food
+const (
+	// MinHistoryEntries is how many past entries are needed before comparing
+	MinHistoryEntries = 3
+	// HistoryLimit is how many recent entries of the same food are compared
+	HistoryLimit = 50
+	// outlierZScore is the robust (median/MAD) z-score above which a value is unusual
+	outlierZScore = 3.5
+	// outlierRatio is how many times above or below the median a value must also be,
+	// so tight histories do not flag ordinary variation
+	outlierRatio = 3.0
+	// macroMismatchRatio flags calories that disagree with 4/4/9 macro energy
+	macroMismatchRatio = 2.0
+	// macroMismatchMinCalories ignores the macro check for small entries
+	macroMismatchMinCalories = 200.0
+)
+
+// Warning codes returned with flagged entries
+const (
+	WarningCaloriesTooHigh    = "calories_too_high"
+	WarningMacrosTooHigh      = "macros_too_high"
+	WarningImplausibleDensity = "implausible_density"
+	WarningMacroMismatch      = "macro_mismatch"
+	WarningUnusualCalories    = "unusual_calories"
+	WarningUnusualDensity     = "unusual_density"
+)
+
+// EntryWarning explains why an entry was flagged for confirmation
+type EntryWarning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// EntryCandidate is the nutrition of an entry about to be saved
+type EntryCandidate struct {
+	Nutrition     KBZHU
+	PortionType   PortionType
+	PortionAmount float64
+}
+
+// HistoricalEntry is a past entry of the same food by the same user
+type HistoricalEntry struct {
+	Calories      float64
+	PortionType   PortionType
+	PortionAmount float64
+}
+
+// DetectAnomalies checks an entry against absolute sanity bounds and against
+// the user's own history for the same food. It returns nil when the entry
+// looks plausible.
+func DetectAnomalies(candidate EntryCandidate, history []HistoricalEntry) []EntryWarning {
+	var warnings []EntryWarning
+	n := candidate.Nutrition
+
+	if n.Calories > MaxEntryCalories {
+		warnings = append(warnings, EntryWarning{
+			Code:    WarningCaloriesTooHigh,
+			Message: fmt.Sprintf("%.0f ккал в одной записи — проверьте, нет ли опечатки", n.Calories),
+		})
+	}
+
+	if n.Protein > MaxEntryMacroGrams || n.Fat > MaxEntryMacroGrams || n.Carbs > MaxEntryMacroGrams {
+		warnings = append(warnings, EntryWarning{
+			Code:    WarningMacrosTooHigh,
+			Message: "Слишком большое количество белков, жиров или углеводов в одной записи",
+		})
+	}
+
+	if density, ok := caloriesPer100(n.Calories, candidate.PortionType, candidate.PortionAmount); ok && density > MaxCaloriesPer100 {
+		warnings = append(warnings, EntryWarning{
+			Code:    WarningImplausibleDensity,
+			Message: fmt.Sprintf("%.0f ккал на 100 г — такого не бывает даже у чистого жира", density),
+		})
+	}
+
+	macroCalories := 4*n.Protein + 4*n.Carbs + 9*n.Fat
+	if macroCalories > 0 && math.Max(n.Calories, macroCalories) >= macroMismatchMinCalories &&
+		outsideRatio(n.Calories, macroCalories, macroMismatchRatio) {
+		warnings = append(warnings, EntryWarning{
+			Code:    WarningMacroMismatch,
+			Message: fmt.Sprintf("Калорийность (%.0f ккал) не сходится с БЖУ (≈%.0f ккал)", n.Calories, macroCalories),
+		})
+	}
+
+	if len(history) < MinHistoryEntries {
+		return warnings
+	}
+
+	totals := make([]float64, 0, len(history))
+	var densities []float64
+	for _, h := range history {
+		totals = append(totals, h.Calories)
+		if h.PortionType == candidate.PortionType {
+			if d, ok := perUnit(h.Calories, h.PortionAmount); ok {
+				densities = append(densities, d)
+			}
+		}
+	}
+
+	if isOutlier(n.Calories, totals) {
+		warnings = append(warnings, EntryWarning{
+			Code:    WarningUnusualCalories,
+			Message: fmt.Sprintf("Обычно вы записываете этот продукт примерно на %.0f ккал", median(totals)),
+		})
+	}
+
+	if len(densities) >= MinHistoryEntries {
+		if d, ok := perUnit(n.Calories, candidate.PortionAmount); ok && isOutlier(d, densities) {
+			warnings = append(warnings, EntryWarning{
+				Code:    WarningUnusualDensity,
+				Message: "Калорийность на единицу порции сильно отличается от ваших прошлых записей этого продукта",
+			})
+		}
+	}
+
+	return warnings
+}
+
+// caloriesPer100 returns kcal per 100 g/ml for weighed portions
+func caloriesPer100(calories float64, portionType PortionType, amount float64) (float64, bool) {
+	if portionType == PortionPortion || amount <= 0 {
+		return 0, false
+	}
+	return calories * 100 / amount, true
+}
+
+// perUnit returns calories per portion unit
+func perUnit(calories, amount float64) (float64, bool) {
+	if amount <= 0 {
+		return 0, false
+	}
+	return calories / amount, true
+}
+
+// isOutlier reports whether value is far from the sample using a robust
+// z-score (median and median absolute deviation), and is also at least
+// outlierRatio times away from the median. A zero MAD (all samples equal)
+// falls back to the ratio alone.
+func isOutlier(value float64, sample []float64) bool {
+	med := median(sample)
+	if med <= 0 {
+		return false
+	}
+	if !outsideRatio(value, med, outlierRatio) {
+		return false
+	}
+
+	deviations := make([]float64, len(sample))
+	for i, v := range sample {
+		deviations[i] = math.Abs(v - med)
+	}
+	mad := median(deviations)
+	if mad == 0 {
+		return true
+	}
+	z := 0.6745 * math.Abs(value-med) / mad
+	return z > outlierZScore
+}
+
+// outsideRatio reports whether a and b differ by more than ratio times
+func outsideRatio(a, b, ratio float64) bool {
+	if a <= 0 || b <= 0 {
+		return a != b
+	}
+	return a/b > ratio || b/a > ratio
+}
+
+// median returns the median of values (0 for an empty slice)
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
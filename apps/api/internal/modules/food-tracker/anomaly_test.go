@@ -0,0 +1,134 @@
+package foodtracker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// bananaHistory is a user's synthetic history of logging bananas by weight
+func bananaHistory() []HistoricalEntry {
+	amounts := []float64{110, 120, 125, 118, 130, 105, 240}
+	history := make([]HistoricalEntry, len(amounts))
+	for i, a := range amounts {
+		history[i] = HistoricalEntry{Calories: a * 0.89, PortionType: PortionGrams, PortionAmount: a}
+	}
+	return history
+}
+
+func codes(warnings []EntryWarning) []string {
+	result := make([]string, len(warnings))
+	for i, w := range warnings {
+		result[i] = w.Code
+	}
+	return result
+}
+
+func banana(grams, calories float64) EntryCandidate {
+	return EntryCandidate{
+		Nutrition:     KBZHU{Calories: calories, Protein: grams * 0.011, Fat: grams * 0.003, Carbs: grams * 0.23},
+		PortionType:   PortionGrams,
+		PortionAmount: grams,
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	t.Run("typical entry is not flagged", func(t *testing.T) {
+		assert.Empty(t, DetectAnomalies(banana(120, 107), bananaHistory()))
+	})
+
+	t.Run("a large but plausible portion is not flagged", func(t *testing.T) {
+		assert.Empty(t, DetectAnomalies(banana(250, 222), bananaHistory()))
+	})
+
+	t.Run("extra zeros in calories are flagged", func(t *testing.T) {
+		warnings := DetectAnomalies(banana(120, 15000), bananaHistory())
+
+		assert.Contains(t, codes(warnings), WarningCaloriesTooHigh)
+		assert.Contains(t, codes(warnings), WarningImplausibleDensity)
+		assert.Contains(t, codes(warnings), WarningMacroMismatch)
+		assert.Contains(t, codes(warnings), WarningUnusualCalories)
+		assert.Contains(t, codes(warnings), WarningUnusualDensity)
+	})
+
+	t.Run("an extra zero in the portion stands out against history", func(t *testing.T) {
+		warnings := DetectAnomalies(banana(1200, 1068), bananaHistory())
+
+		assert.Equal(t, []string{WarningUnusualCalories}, codes(warnings))
+	})
+
+	t.Run("a dropped digit is flagged too", func(t *testing.T) {
+		warnings := DetectAnomalies(banana(120, 10.7), bananaHistory())
+
+		assert.Contains(t, codes(warnings), WarningUnusualDensity)
+	})
+
+	t.Run("short history only applies absolute bounds", func(t *testing.T) {
+		history := bananaHistory()[:MinHistoryEntries-1]
+
+		assert.Empty(t, DetectAnomalies(banana(1200, 1068), history))
+		assert.Equal(t, []string{WarningCaloriesTooHigh, WarningImplausibleDensity},
+			codes(DetectAnomalies(EntryCandidate{
+				Nutrition:     KBZHU{Calories: 6000, Fat: 500, Protein: 100, Carbs: 100},
+				PortionType:   PortionGrams,
+				PortionAmount: 500,
+			}, history)))
+	})
+
+	t.Run("identical history falls back to the ratio", func(t *testing.T) {
+		history := []HistoricalEntry{
+			{Calories: 250, PortionType: PortionPortion, PortionAmount: 1},
+			{Calories: 250, PortionType: PortionPortion, PortionAmount: 1},
+			{Calories: 250, PortionType: PortionPortion, PortionAmount: 1},
+		}
+		same := EntryCandidate{Nutrition: KBZHU{Calories: 260, Protein: 10, Fat: 10, Carbs: 30}, PortionType: PortionPortion, PortionAmount: 1}
+		off := EntryCandidate{Nutrition: KBZHU{Calories: 2500, Protein: 100, Fat: 100, Carbs: 300}, PortionType: PortionPortion, PortionAmount: 1}
+
+		assert.Empty(t, DetectAnomalies(same, history))
+		assert.Contains(t, codes(DetectAnomalies(off, history)), WarningUnusualCalories)
+	})
+
+	t.Run("macros that do not add up are flagged", func(t *testing.T) {
+		candidate := EntryCandidate{
+			Nutrition:     KBZHU{Calories: 900, Protein: 5, Fat: 2, Carbs: 20},
+			PortionType:   PortionPortion,
+			PortionAmount: 1,
+		}
+
+		assert.Equal(t, []string{WarningMacroMismatch}, codes(DetectAnomalies(candidate, nil)))
+	})
+
+	t.Run("small entries skip the macro check", func(t *testing.T) {
+		candidate := EntryCandidate{
+			Nutrition:     KBZHU{Calories: 5, Protein: 0.1, Carbs: 0.2},
+			PortionType:   PortionMilliliters,
+			PortionAmount: 250,
+		}
+
+		assert.Empty(t, DetectAnomalies(candidate, nil))
+	})
+
+	t.Run("absurd macros are flagged", func(t *testing.T) {
+		candidate := EntryCandidate{
+			Nutrition:     KBZHU{Calories: 4000, Protein: 900, Fat: 20, Carbs: 50},
+			PortionType:   PortionPortion,
+			PortionAmount: 1,
+		}
+
+		assert.Equal(t, []string{WarningMacrosTooHigh}, codes(DetectAnomalies(candidate, nil)))
+	})
+}
+
+func TestWithoutUnconfirmed(t *testing.T) {
+	entries := map[MealType][]FoodEntry{
+		MealBreakfast: {{ID: "a", Calories: 300}, {ID: "b", Calories: 15000, NeedsConfirmation: true}},
+		MealLunch:     {{ID: "c", Calories: 500}},
+	}
+
+	kept, excluded := withoutUnconfirmed(entries)
+
+	assert.Equal(t, 1, excluded)
+	assert.Len(t, kept[MealBreakfast], 1)
+	assert.Len(t, kept[MealLunch], 1)
+	assert.Len(t, entries[MealBreakfast], 2, "input is not modified")
+}
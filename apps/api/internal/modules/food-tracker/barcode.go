@@ -0,0 +1,125 @@
+package foodtracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/openfoodfacts"
+)
+
+const (
+	// lookupTimeout bounds a single upstream barcode lookup
+	lookupTimeout = 5 * time.Second
+	// missCacheTTL is how long an unknown barcode is answered from memory
+	missCacheTTL = time.Hour
+	// missCacheMaxEntries caps the negative cache between clean-ups
+	missCacheMaxEntries = 10000
+)
+
+var (
+	// ErrProductNotFound is returned when neither the local database nor
+	// the upstream source knows the barcode
+	ErrProductNotFound = errors.New("продукт не найден")
+	// ErrLookupFailed is returned when the upstream source is unavailable
+	ErrLookupFailed = errors.New("внешний сервис поиска по штрих-коду недоступен")
+
+	// errBarcodeNotFound marks a local miss in getFoodByBarcode
+	errBarcodeNotFound = errors.New("продукт не найден в локальной базе")
+)
+
+// FoodLookup finds products by barcode in an external catalogue. It returns
+// nil, nil when the barcode is unknown and macros normalised to 100 g
+// otherwise.
+type FoodLookup interface {
+	LookupBarcode(ctx context.Context, barcode string) (*openfoodfacts.Product, error)
+}
+
+// missCache remembers barcodes the upstream source did not know, so repeated
+// scans of the same unknown product do not hit it again
+type missCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // barcode → expiry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+func newMissCache(ttl time.Duration) *missCache {
+	return &missCache{
+		entries: make(map[string]time.Time),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// contains reports whether barcode was recorded as a miss and has not expired
+func (c *missCache) contains(barcode string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiry, ok := c.entries[barcode]
+	if !ok {
+		return false
+	}
+	if !c.now().Before(expiry) {
+		delete(c.entries, barcode)
+		return false
+	}
+	return true
+}
+
+// add records barcode as a miss. When the cache is full, expired entries are
+// dropped first and everything if that is not enough.
+func (c *missCache) add(barcode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if len(c.entries) >= missCacheMaxEntries {
+		for code, expiry := range c.entries {
+			if !now.Before(expiry) {
+				delete(c.entries, code)
+			}
+		}
+		if len(c.entries) >= missCacheMaxEntries {
+			c.entries = make(map[string]time.Time)
+		}
+	}
+	c.entries[barcode] = now.Add(c.ttl)
+}
+
+// FindByBarcode returns the food for a barcode: the local database first,
+// then the external catalogue. Products found upstream are stored in
+// food_items so the next scan is served locally. Returns ErrProductNotFound
+// for unknown barcodes and ErrLookupFailed when the catalogue cannot be
+// reached.
+func (s *Service) FindByBarcode(ctx context.Context, barcode string) (*FoodItem, error) {
+	food, err := s.getFoodByBarcode(ctx, barcode)
+	if err == nil {
+		return food, nil
+	}
+	if !errors.Is(err, errBarcodeNotFound) {
+		return nil, err
+	}
+
+	if s.misses.contains(barcode) {
+		return nil, ErrProductNotFound
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+
+	product, err := s.offClient.LookupBarcode(lookupCtx, barcode)
+	if err != nil {
+		s.log.Warn("OpenFoodFacts lookup failed", "error", err, "barcode", barcode)
+		return nil, fmt.Errorf("%w: %v", ErrLookupFailed, err)
+	}
+	if product == nil {
+		s.misses.add(barcode)
+		return nil, ErrProductNotFound
+	}
+
+	return s.saveOFFProduct(ctx, barcode, product)
+}
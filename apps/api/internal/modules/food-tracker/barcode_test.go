@@ -0,0 +1,212 @@
+package foodtracker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/openfoodfacts"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// stubLookup implements FoodLookup; the zero value knows no barcodes
+type stubLookup struct {
+	product *openfoodfacts.Product
+	err     error
+	calls   int
+}
+
+func (s *stubLookup) LookupBarcode(ctx context.Context, barcode string) (*openfoodfacts.Product, error) {
+	s.calls++
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, assert.AnError
+	}
+	return s.product, s.err
+}
+
+var foodItemColumns = []string{
+	"id", "name", "brand", "category", "serving_size", "serving_unit",
+	"calories_per_100", "protein_per_100", "fat_per_100", "carbs_per_100",
+	"fiber_per_100", "sugar_per_100", "sodium_per_100", "barcode", "source", "verified",
+	"created_at", "updated_at",
+}
+
+// expectLocalMiss expects the food_items and products lookups to find nothing
+func expectLocalMiss(mock sqlmock.Sqlmock, barcode string) {
+	mock.ExpectQuery(`SELECT id, name, brand, category, serving_size, serving_unit`).
+		WithArgs(barcode).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT id::text, name, brand`).
+		WithArgs(barcode).
+		WillReturnError(sql.ErrNoRows)
+}
+
+func TestFindByBarcode(t *testing.T) {
+	ctx := context.Background()
+	barcode := "4600000000017"
+
+	t.Run("local hit does not call upstream", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		lookup := &stubLookup{}
+		service.offClient = lookup
+
+		now := time.Now()
+		mock.ExpectQuery(`SELECT id, name, brand, category, serving_size, serving_unit`).
+			WithArgs(barcode).
+			WillReturnRows(sqlmock.NewRows(foodItemColumns).
+				AddRow("food-1", "Кефир 1%", nil, "молочные", 100.0, "мл", 40.0, 3.0, 1.0, 4.0, nil, nil, nil, &barcode, "database", true, now, now))
+
+		food, err := service.FindByBarcode(ctx, barcode)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Кефир 1%", food.Name)
+		assert.Zero(t, lookup.calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upstream hit is stored in food_items", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.offClient = &stubLookup{product: &openfoodfacts.Product{
+			Code: barcode, Name: "Кефир", Brand: "Савушкин", Calories: 40, Protein: 3, Fat: 1, Carbs: 4,
+		}}
+
+		now := time.Now()
+		expectLocalMiss(mock, barcode)
+		mock.ExpectQuery(`INSERT INTO food_items (.+) 'openfoodfacts'`).
+			WithArgs(sqlmock.AnyArg(), "Кефир", "Савушкин", 40.0, 3.0, 1.0, 4.0, barcode).
+			WillReturnRows(sqlmock.NewRows(foodItemColumns).
+				AddRow("food-2", "Кефир", stringPtr("Савушкин"), "imported", 100.0, "г", 40.0, 3.0, 1.0, 4.0, nil, nil, nil, &barcode, "openfoodfacts", false, now, now))
+
+		food, err := service.FindByBarcode(ctx, barcode)
+
+		require.NoError(t, err)
+		assert.Equal(t, "food-2", food.ID)
+		assert.Equal(t, 40.0, food.NutritionPer100.Calories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown barcode is cached", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		lookup := &stubLookup{}
+		service.offClient = lookup
+
+		expectLocalMiss(mock, barcode)
+		expectLocalMiss(mock, barcode)
+
+		_, err := service.FindByBarcode(ctx, barcode)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+		_, err = service.FindByBarcode(ctx, barcode)
+		assert.ErrorIs(t, err, ErrProductNotFound)
+
+		assert.Equal(t, 1, lookup.calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("upstream failure is not cached", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		lookup := &stubLookup{err: context.DeadlineExceeded}
+		service.offClient = lookup
+
+		expectLocalMiss(mock, barcode)
+		expectLocalMiss(mock, barcode)
+
+		_, err := service.FindByBarcode(ctx, barcode)
+		assert.ErrorIs(t, err, ErrLookupFailed)
+		_, err = service.FindByBarcode(ctx, barcode)
+		assert.ErrorIs(t, err, ErrLookupFailed)
+
+		assert.Equal(t, 2, lookup.calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		lookup := &stubLookup{}
+		service.offClient = lookup
+
+		mock.ExpectQuery(`SELECT id, name, brand, category, serving_size, serving_unit`).
+			WithArgs(barcode).
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := service.FindByBarcode(ctx, barcode)
+
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.Zero(t, lookup.calls)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMissCache(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	cache := newMissCache(time.Hour)
+	cache.now = func() time.Time { return now }
+
+	cache.add("4600000000017")
+	assert.True(t, cache.contains("4600000000017"))
+	assert.False(t, cache.contains("4600000000024"))
+
+	now = now.Add(59 * time.Minute)
+	assert.True(t, cache.contains("4600000000017"))
+
+	now = now.Add(time.Minute)
+	assert.False(t, cache.contains("4600000000017"))
+	assert.Empty(t, cache.entries)
+}
+
+func TestGetFoodByBarcodeHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		ean    string
+		err    error
+		status int
+		code   string
+	}{
+		{"found", "4600000000017", nil, http.StatusOK, ""},
+		{"not found", "4600000000017", ErrProductNotFound, http.StatusNotFound, response.CodeNotFound},
+		{"upstream unavailable", "46000000", ErrLookupFailed, http.StatusBadGateway, response.CodeUpstreamUnavailable},
+		{"internal", "4600000000017", sql.ErrConnDone, http.StatusInternalServerError, ""},
+		{"too short", "4600000", nil, http.StatusBadRequest, response.CodeValidationFailed},
+		{"not digits", "46000000abc", nil, http.StatusBadRequest, response.CodeValidationFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mockSvc := setupTestHandlerWithMock()
+			if tt.status != http.StatusBadRequest {
+				var food *FoodItem
+				if tt.err == nil {
+					food = &FoodItem{ID: "food-1", Name: "Кефир"}
+				}
+				mockSvc.On("FindByBarcode", mock.Anything, tt.ean).Return(food, tt.err)
+			}
+
+			router := gin.New()
+			router.GET("/foods/barcode/:ean", handler.GetFoodByBarcode)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/foods/barcode/"+tt.ean, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			var resp map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if tt.code != "" {
+				assert.Equal(t, tt.code, resp["code"])
+			}
+			mockSvc.AssertExpectations(t)
+		})
+	}
+}
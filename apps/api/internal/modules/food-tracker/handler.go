@@ -3,6 +3,7 @@ package foodtracker
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +12,7 @@ import (
 	"time"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/middleware"
@@ -23,9 +25,10 @@ import (
 
 // FoodEntriesService handles daily food entry CRUD.
 type FoodEntriesService interface {
-	GetEntriesByDate(ctx context.Context, userID int64, date time.Time) (*GetEntriesResponse, error)
+	GetEntriesByDate(ctx context.Context, userID int64, date time.Time, excludeUnconfirmed bool) (*GetEntriesResponse, error)
 	CreateEntry(ctx context.Context, userID int64, req *CreateEntryRequest) (*FoodEntry, error)
 	UpdateEntry(ctx context.Context, userID int64, entryID string, req *UpdateEntryRequest) (*FoodEntry, error)
+	ConfirmEntry(ctx context.Context, userID int64, entryID string) (*FoodEntry, error)
 	DeleteEntry(ctx context.Context, userID int64, entryID string) error
 }
 
@@ -33,6 +36,7 @@ type FoodEntriesService interface {
 type FoodSearchService interface {
 	SearchFoods(ctx context.Context, userID int64, query string, limit int, offset int) (*SearchFoodsResponse, error)
 	LookupBarcode(ctx context.Context, barcode string) (*BarcodeResponse, error)
+	FindByBarcode(ctx context.Context, barcode string) (*FoodItem, error)
 	GetRecentFoods(ctx context.Context, userID int64, limit int) (*GetRecentFoodsResponse, error)
 	GetFavoriteFoods(ctx context.Context, userID int64, limit int) (*GetFavoriteFoodsResponse, error)
 	AddToFavorites(ctx context.Context, userID int64, foodID string) error
@@ -116,6 +120,7 @@ func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
 // Retrieves food entries for the authenticated user on a specific date
 // Query parameters:
 //   - date: required, format YYYY-MM-DD
+//   - exclude_unconfirmed: optional, leave flagged entries out of daily totals
 func (h *Handler) GetEntries(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, ok := h.getUserID(c)
@@ -147,7 +152,7 @@ func (h *Handler) GetEntries(c *gin.Context) {
 	}
 
 	// Call service to get entries
-	result, err := h.entries.GetEntriesByDate(c.Request.Context(), userID, date)
+	result, err := h.entries.GetEntriesByDate(c.Request.Context(), userID, date, req.ExcludeUnconfirmed)
 	if err != nil {
 		h.log.Errorw("Не удалось получить записи", "error", err, "user_id", userID, "date", req.Date)
 		response.InternalError(c, "Не удалось получить записи о питании")
@@ -189,6 +194,12 @@ func (h *Handler) CreateEntry(c *gin.Context) {
 
 	// Call service to create entry
 	entry, err := h.entries.CreateEntry(c.Request.Context(), userID, &req)
+	var conflict *apperrors.ConflictError
+	if errors.As(err, &conflict) {
+		h.log.Warnw("Food entry conflict", "error", err, "user_id", userID, "food_id", req.FoodID)
+		response.Conflict(c, "Продукт с таким штрих-кодом уже существует", conflict.Field)
+		return
+	}
 	if err != nil {
 		h.log.Errorw("Не удалось создать запись", "error", err, "user_id", userID, "food_id", req.FoodID)
 
@@ -291,6 +302,43 @@ func (h *Handler) UpdateEntry(c *gin.Context) {
 	response.Success(c, http.StatusOK, entry)
 }
 
+// ConfirmEntry handles POST /api/food-tracker/entries/:id/confirm
+// Confirms an entry that was flagged as anomalous, so it counts in totals again
+// URL parameters:
+//   - id: required, UUID of the entry to confirm
+func (h *Handler) ConfirmEntry(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	entryID := c.Param("id")
+	if entryID == "" {
+		response.Error(c, http.StatusBadRequest, "Идентификатор записи обязателен")
+		return
+	}
+
+	entry, err := h.entries.ConfirmEntry(c.Request.Context(), userID, entryID)
+	if err != nil {
+		h.log.Errorw("Не удалось подтвердить запись", "error", err, "user_id", userID, "entry_id", entryID)
+
+		errMsg := err.Error()
+		if strings.Contains(errMsg, "запись не найдена") {
+			response.NotFound(c, "Запись не найдена")
+			return
+		}
+		if strings.Contains(errMsg, "неверный формат") {
+			response.Error(c, http.StatusBadRequest, errMsg)
+			return
+		}
+
+		response.InternalError(c, "Не удалось подтвердить запись о питании")
+		return
+	}
+
+	response.Success(c, http.StatusOK, entry)
+}
+
 // DeleteEntry handles DELETE /api/food-tracker/entries/:id
 // Deletes a food entry for the authenticated user
 // URL parameters:
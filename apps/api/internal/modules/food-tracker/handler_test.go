@@ -12,11 +12,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/openrouter"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -31,8 +33,8 @@ type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) GetEntriesByDate(ctx context.Context, userID int64, date time.Time) (*GetEntriesResponse, error) {
-	args := m.Called(ctx, userID, date)
+func (m *MockService) GetEntriesByDate(ctx context.Context, userID int64, date time.Time, excludeUnconfirmed bool) (*GetEntriesResponse, error) {
+	args := m.Called(ctx, userID, date, excludeUnconfirmed)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -55,6 +57,14 @@ func (m *MockService) UpdateEntry(ctx context.Context, userID int64, entryID str
 	return args.Get(0).(*FoodEntry), args.Error(1)
 }
 
+func (m *MockService) ConfirmEntry(ctx context.Context, userID int64, entryID string) (*FoodEntry, error) {
+	args := m.Called(ctx, userID, entryID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FoodEntry), args.Error(1)
+}
+
 func (m *MockService) DeleteEntry(ctx context.Context, userID int64, entryID string) error {
 	args := m.Called(ctx, userID, entryID)
 	return args.Error(0)
@@ -76,6 +86,14 @@ func (m *MockService) LookupBarcode(ctx context.Context, barcode string) (*Barco
 	return args.Get(0).(*BarcodeResponse), args.Error(1)
 }
 
+func (m *MockService) FindByBarcode(ctx context.Context, barcode string) (*FoodItem, error) {
+	args := m.Called(ctx, barcode)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*FoodItem), args.Error(1)
+}
+
 func (m *MockService) GetRecentFoods(ctx context.Context, userID int64, limit int) (*GetRecentFoodsResponse, error) {
 	args := m.Called(ctx, userID, limit)
 	if args.Get(0) == nil {
@@ -269,7 +287,7 @@ func TestGetEntries_Success(t *testing.T) {
 
 	mockService.On("GetEntriesByDate", mock.Anything, int64(1), mock.MatchedBy(func(d time.Time) bool {
 		return d.Year() == 2024 && d.Month() == 1 && d.Day() == 15
-	})).Return(response, nil)
+	}), false).Return(response, nil)
 
 	router := gin.New()
 	router.GET("/entries", func(c *gin.Context) {
@@ -319,6 +337,85 @@ func TestGetEntries_MissingDate(t *testing.T) {
 	assert.Equal(t, "Неверные параметры запроса", resp["message"])
 }
 
+func TestGetEntries_ExcludeUnconfirmed(t *testing.T) {
+	handler, mockService := setupTestHandlerWithMock()
+
+	response := &GetEntriesResponse{
+		Entries:         map[MealType][]FoodEntry{},
+		ExcludedEntries: 1,
+	}
+
+	mockService.On("GetEntriesByDate", mock.Anything, int64(1), mock.Anything, true).Return(response, nil)
+
+	router := gin.New()
+	router.GET("/entries", func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		handler.GetEntries(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/entries?date=2024-01-15&exclude_unconfirmed=true", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp map[string]any
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	require.NoError(t, err)
+
+	data := resp["data"].(map[string]any)
+	assert.Equal(t, float64(1), data["excludedEntries"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestConfirmEntry(t *testing.T) {
+	entryID := uuid.New().String()
+
+	t.Run("clears the flag", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+		mockService.On("ConfirmEntry", mock.Anything, int64(1), entryID).
+			Return(&FoodEntry{ID: entryID, UserID: 1, NeedsConfirmation: false}, nil)
+
+		router := gin.New()
+		router.POST("/entries/:id/confirm", func(c *gin.Context) {
+			c.Set("user_id", int64(1))
+			handler.ConfirmEntry(c)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/entries/"+entryID+"/confirm", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var resp map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		data := resp["data"].(map[string]any)
+		assert.Equal(t, false, data["needsConfirmation"])
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown entry is a 404", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+		mockService.On("ConfirmEntry", mock.Anything, int64(1), entryID).
+			Return(nil, fmt.Errorf("запись не найдена"))
+
+		router := gin.New()
+		router.POST("/entries/:id/confirm", func(c *gin.Context) {
+			c.Set("user_id", int64(1))
+			handler.ConfirmEntry(c)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/entries/"+entryID+"/confirm", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
 // ============================================================================
 // User Foods Handler Tests
 // ============================================================================
@@ -638,3 +735,48 @@ func TestSearchFoodsHandler_ContextCanceled(t *testing.T) {
 	assert.Equal(t, 499, w.Code, "handler should return 499 for canceled requests, not 500")
 	mockSvc.AssertExpectations(t)
 }
+
+func TestCreateEntry_BarcodeConflict(t *testing.T) {
+	service, sqlMock, cleanup := setupTestService(t)
+	defer cleanup()
+	handler, _ := setupTestHandlerWithMock()
+	handler.entries = service
+
+	now := time.Now()
+	sqlMock.ExpectQuery("FROM products").
+		WithArgs("12345").
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "brand", "category", "serving_size", "serving_unit",
+			"calories", "protein", "fat", "carbs", "fiber", "sugar", "sodium",
+			"barcode", "source", "verified", "created_at", "updated_at",
+		}).AddRow("12345", "Кефир", nil, "", 100.0, "г", 50.0, 3.0, 2.5, 4.0, nil, nil, nil,
+			"4600000000001", "database", false, now, now))
+	// Another food item already holds the product's barcode
+	sqlMock.ExpectExec("INSERT INTO food_items").
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_food_items_barcode_unique"})
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", int64(1))
+	body, _ := json.Marshal(CreateEntryRequest{
+		FoodID:        "12345",
+		MealType:      MealBreakfast,
+		PortionType:   PortionGrams,
+		PortionAmount: 200,
+		Time:          "08:30",
+		Date:          "2026-10-16",
+	})
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/v1/food-tracker/entries", bytes.NewBuffer(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	handler.CreateEntry(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.JSONEq(t, `{
+		"status": "error",
+		"code": "CONFLICT",
+		"message": "Продукт с таким штрих-кодом уже существует",
+		"details": {"field": "barcode"}
+	}`, w.Body.String())
+	assert.NoError(t, sqlMock.ExpectationsWereMet())
+}
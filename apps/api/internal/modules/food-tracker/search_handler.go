@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"regexp"
 	"strings"
 
 	"github.com/burcev/api/internal/shared/response"
 	"github.com/gin-gonic/gin"
 )
 
+// eanPattern matches EAN-8, UPC-A, EAN-13 and GTIN-14 codes
+var eanPattern = regexp.MustCompile(`^[0-9]{8,14}$`)
+
 // Note: Search service methods are defined in ServiceInterface in handler.go
 // The Handler struct uses the same service instance for all operations
 
@@ -115,6 +119,33 @@ func (h *Handler) LookupBarcode(c *gin.Context) {
 	response.Success(c, http.StatusOK, result)
 }
 
+// GetFoodByBarcode handles GET /api/v1/foods/barcode/:ean
+// Returns the food for an EAN/UPC code, looking it up in Open Food Facts when
+// it is not in the local database. Unknown codes yield 404, an unavailable
+// upstream 502.
+func (h *Handler) GetFoodByBarcode(c *gin.Context) {
+	barcode := strings.TrimSpace(c.Param("ean"))
+	if !eanPattern.MatchString(barcode) {
+		response.ValidationError(c, "Штрих-код должен содержать от 8 до 14 цифр",
+			map[string]string{"ean": "Неверный формат"})
+		return
+	}
+
+	food, err := h.search.FindByBarcode(c.Request.Context(), barcode)
+	switch {
+	case err == nil:
+		response.Success(c, http.StatusOK, food)
+	case errors.Is(err, ErrProductNotFound):
+		response.ErrorCode(c, http.StatusNotFound, response.CodeNotFound, "Продукт не найден", nil)
+	case errors.Is(err, ErrLookupFailed):
+		response.ErrorCode(c, http.StatusBadGateway, response.CodeUpstreamUnavailable,
+			"Сервис поиска по штрих-коду временно недоступен", nil)
+	default:
+		h.log.Errorw("Не удалось найти продукт по штрих-коду", "error", err, "barcode", barcode)
+		response.InternalError(c, "Не удалось выполнить поиск по штрих-коду")
+	}
+}
+
 // GetRecentFoods handles GET /api/food-tracker/recent
 // Retrieves recently used foods for the authenticated user
 // Query parameters:
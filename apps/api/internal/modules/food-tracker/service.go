@@ -3,6 +3,7 @@ package foodtracker
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"strings"
@@ -19,7 +20,8 @@ import (
 type Service struct {
 	db        *database.DB
 	log       *logger.Logger
-	offClient *openfoodfacts.Client
+	offClient FoodLookup
+	misses    *missCache
 }
 
 // NewService creates a new food tracker service
@@ -28,6 +30,7 @@ func NewService(db *database.DB, log *logger.Logger) *Service {
 		db:        db,
 		log:       log,
 		offClient: openfoodfacts.NewClient(),
+		misses:    newMissCache(missCacheTTL),
 	}
 }
 
@@ -36,8 +39,10 @@ func NewService(db *database.DB, log *logger.Logger) *Service {
 // ============================================================================
 
 // GetEntriesByDate retrieves food entries for a user on a specific date
-// Returns entries grouped by meal type
-func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.Time) (*GetEntriesResponse, error) {
+// Returns entries grouped by meal type. With excludeUnconfirmed, entries
+// flagged as anomalous and not yet confirmed are listed but left out of the
+// daily totals.
+func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.Time, excludeUnconfirmed bool) (*GetEntriesResponse, error) {
 	startTime := time.Now()
 
 	// Format date as YYYY-MM-DD
@@ -45,7 +50,7 @@ func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.
 
 	query := `
 		SELECT id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
-		       calories, protein, fat, carbs, time, date, created_at, updated_at
+		       calories, protein, fat, carbs, time, date, created_at, updated_at, needs_confirmation
 		FROM food_entries
 		WHERE user_id = $1 AND date = $2
 		ORDER BY time ASC
@@ -87,6 +92,7 @@ func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.
 			&entry.Date,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
+			&entry.NeedsConfirmation,
 		)
 		if err != nil {
 			s.log.Error("Failed to scan food entry", "error", err)
@@ -110,7 +116,12 @@ func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.
 	})
 
 	// Calculate daily totals
-	dailyTotals := s.calculateTotalsFromEntries(entries)
+	totalsSource := entries
+	excluded := 0
+	if excludeUnconfirmed {
+		totalsSource, excluded = withoutUnconfirmed(entries)
+	}
+	dailyTotals := s.calculateTotalsFromEntries(totalsSource)
 
 	// Get user goals
 	targetGoals, err := s.GetUserGoals(ctx, userID)
@@ -120,9 +131,10 @@ func (s *Service) GetEntriesByDate(ctx context.Context, userID int64, date time.
 	}
 
 	return &GetEntriesResponse{
-		Entries:     entries,
-		DailyTotals: dailyTotals,
-		TargetGoals: targetGoals,
+		Entries:         entries,
+		DailyTotals:     dailyTotals,
+		TargetGoals:     targetGoals,
+		ExcludedEntries: excluded,
 	}, nil
 }
 
@@ -214,14 +226,21 @@ func (s *Service) CreateEntry(ctx context.Context, userID int64, req *CreateEntr
 	// Generate UUID for new entry
 	entryID := uuid.New().String()
 
+	// Flag implausible values; the entry is still saved
+	warnings := s.detectEntryAnomalies(ctx, userID, entryID, foodItemID, foodName, EntryCandidate{
+		Nutrition:     nutrition,
+		PortionType:   req.PortionType,
+		PortionAmount: req.PortionAmount,
+	})
+
 	// Insert entry
 	query := `
 		INSERT INTO food_entries (
 			id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
-			calories, protein, fat, carbs, time, date, created_at, updated_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW(), NOW())
+			calories, protein, fat, carbs, time, date, needs_confirmation, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, NOW(), NOW())
 		RETURNING id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
-		          calories, protein, fat, carbs, time, date, created_at, updated_at
+		          calories, protein, fat, carbs, time, date, created_at, updated_at, needs_confirmation
 	`
 
 	var entry FoodEntry
@@ -241,6 +260,7 @@ func (s *Service) CreateEntry(ctx context.Context, userID int64, req *CreateEntr
 		nutrition.Carbs,
 		req.Time,
 		req.Date,
+		len(warnings) > 0,
 	).Scan(
 		&entry.ID,
 		&entry.UserID,
@@ -257,6 +277,7 @@ func (s *Service) CreateEntry(ctx context.Context, userID int64, req *CreateEntr
 		&entry.Date,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&entry.NeedsConfirmation,
 	)
 
 	if insertErr != nil {
@@ -282,7 +303,9 @@ func (s *Service) CreateEntry(ctx context.Context, userID int64, req *CreateEntr
 		"food_id":   req.FoodID,
 		"meal_type": req.MealType,
 		"calories":  nutrition.Calories,
+		"flagged":   len(warnings) > 0,
 	})
+	entry.Warnings = warnings
 
 	// Sync nutrition totals to daily_metrics for dashboard
 	s.syncNutritionToDailyMetrics(ctx, userID, entry.Date)
@@ -325,6 +348,7 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 	}
 
 	// Handle nutrition: direct overrides take priority, otherwise recalculate if portion changed
+	nutritionChanged := false
 	if req.Calories != nil || req.Protein != nil || req.Fat != nil || req.Carbs != nil {
 		// Direct nutrition overrides
 		if req.Calories != nil {
@@ -342,6 +366,7 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 		if req.PortionAmount != nil {
 			existing.PortionAmount = *req.PortionAmount
 		}
+		nutritionChanged = true
 	} else if req.PortionAmount != nil && *req.PortionAmount != existing.PortionAmount {
 		// No direct overrides — recalculate from DB if portion changed
 		existing.PortionAmount = *req.PortionAmount
@@ -358,16 +383,30 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 		existing.Protein = nutrition.Protein
 		existing.Fat = nutrition.Fat
 		existing.Carbs = nutrition.Carbs
+		nutritionChanged = true
+	}
+
+	// Re-check changed nutrition; an edit back into range clears the flag,
+	// while unrelated edits keep the entry's current state
+	var warnings []EntryWarning
+	if nutritionChanged {
+		warnings = s.detectEntryAnomalies(ctx, userID, entryID, existing.FoodID, existing.FoodName, EntryCandidate{
+			Nutrition:     KBZHU{Calories: existing.Calories, Protein: existing.Protein, Fat: existing.Fat, Carbs: existing.Carbs},
+			PortionType:   existing.PortionType,
+			PortionAmount: existing.PortionAmount,
+		})
+		existing.NeedsConfirmation = len(warnings) > 0
 	}
 
 	// Update entry
 	query := `
 		UPDATE food_entries
 		SET meal_type = $1, portion_type = $2, portion_amount = $3,
-		    calories = $4, protein = $5, fat = $6, carbs = $7, time = $8, food_name = $9, updated_at = NOW()
+		    calories = $4, protein = $5, fat = $6, carbs = $7, time = $8, food_name = $9,
+		    needs_confirmation = $12, updated_at = NOW()
 		WHERE id = $10 AND user_id = $11
 		RETURNING id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
-		          calories, protein, fat, carbs, time, date, created_at, updated_at
+		          calories, protein, fat, carbs, time, date, created_at, updated_at, needs_confirmation
 	`
 
 	var entry FoodEntry
@@ -385,6 +424,7 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 		existing.FoodName,
 		entryID,
 		userID,
+		existing.NeedsConfirmation,
 	).Scan(
 		&entry.ID,
 		&entry.UserID,
@@ -401,6 +441,7 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 		&entry.Date,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&entry.NeedsConfirmation,
 	)
 
 	if err != nil {
@@ -422,7 +463,9 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 	s.log.LogBusinessEvent("food_entry_updated", map[string]interface{}{
 		"entry_id": entry.ID,
 		"user_id":  userID,
+		"flagged":  entry.NeedsConfirmation,
 	})
+	entry.Warnings = warnings
 
 	// Sync nutrition totals to daily_metrics for dashboard
 	s.syncNutritionToDailyMetrics(ctx, userID, entry.Date)
@@ -430,6 +473,125 @@ func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string,
 	return &entry, nil
 }
 
+// ConfirmEntry marks a flagged entry as intended by the user, so it counts in
+// totals again and is used as history for future checks
+func (s *Service) ConfirmEntry(ctx context.Context, userID int64, entryID string) (*FoodEntry, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, fmt.Errorf("неверный формат идентификатора записи")
+	}
+
+	query := `
+		UPDATE food_entries
+		SET needs_confirmation = false, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
+		          calories, protein, fat, carbs, time, date, created_at, updated_at, needs_confirmation
+	`
+
+	var entry FoodEntry
+	err := s.db.QueryRowContext(ctx, query, entryID, userID).Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.FoodID,
+		&entry.FoodName,
+		&entry.MealType,
+		&entry.PortionType,
+		&entry.PortionAmount,
+		&entry.Calories,
+		&entry.Protein,
+		&entry.Fat,
+		&entry.Carbs,
+		&entry.Time,
+		&entry.Date,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+		&entry.NeedsConfirmation,
+	)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("запись не найдена")
+		}
+		return nil, fmt.Errorf("ошибка при подтверждении записи: %w", err)
+	}
+
+	entry.PopulateNutrition()
+
+	s.log.LogBusinessEvent("food_entry_confirmed", map[string]interface{}{
+		"entry_id": entry.ID,
+		"user_id":  userID,
+	})
+
+	return &entry, nil
+}
+
+// detectEntryAnomalies runs DetectAnomalies against the user's recent
+// confirmed entries of the same food. History is best-effort: if it cannot be
+// loaded, only the absolute bounds are checked.
+func (s *Service) detectEntryAnomalies(ctx context.Context, userID int64, entryID, foodID, foodName string, candidate EntryCandidate) []EntryWarning {
+	history, err := s.getFoodHistory(ctx, userID, entryID, foodID, foodName)
+	if err != nil {
+		s.log.Warn("Failed to load food history for anomaly check", "error", err, "user_id", userID)
+		history = nil
+	}
+
+	warnings := DetectAnomalies(candidate, history)
+	if len(warnings) > 0 {
+		codes := make([]string, len(warnings))
+		for i, w := range warnings {
+			codes[i] = w.Code
+		}
+		s.log.LogBusinessEvent("food_entry_flagged", map[string]interface{}{
+			"entry_id": entryID,
+			"user_id":  userID,
+			"warnings": codes,
+		})
+	}
+	return warnings
+}
+
+// getFoodHistory returns the user's recent entries of the same food (by id or
+// name), excluding the entry being checked and unconfirmed outliers
+func (s *Service) getFoodHistory(ctx context.Context, userID int64, entryID, foodID, foodName string) ([]HistoricalEntry, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT calories, portion_type, portion_amount
+		FROM food_entries
+		WHERE user_id = $1
+		  AND (food_id = $2 OR LOWER(food_name) = LOWER($3))
+		  AND id <> $4
+		  AND NOT needs_confirmation
+		ORDER BY created_at DESC
+		LIMIT $5
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, foodID, foodName, entryID, HistoryLimit)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"food_id": foodID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []HistoricalEntry
+	for rows.Next() {
+		var h HistoricalEntry
+		if err := rows.Scan(&h.Calories, &h.PortionType, &h.PortionAmount); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
 // DeleteEntry deletes a food entry with ownership check
 func (s *Service) DeleteEntry(ctx context.Context, userID int64, entryID string) error {
 	startTime := time.Now()
@@ -806,7 +968,8 @@ func (s *Service) ensureFoodItemExists(ctx context.Context, foodID string, item
 		false,
 	)
 	if err != nil {
-		return "", fmt.Errorf("ошибка при копировании продукта в food_items: %w", err)
+		// Another food item may already hold the product's barcode
+		return "", fmt.Errorf("ошибка при копировании продукта в food_items: %w", database.ConstraintError(err))
 	}
 
 	s.log.LogBusinessEvent("product_copied_to_food_items", map[string]interface{}{
@@ -828,7 +991,7 @@ func (s *Service) getEntryByID(ctx context.Context, entryID string) (*FoodEntry,
 
 	query := `
 		SELECT id, user_id, food_id, food_name, meal_type, portion_type, portion_amount,
-		       calories, protein, fat, carbs, time, date, created_at, updated_at
+		       calories, protein, fat, carbs, time, date, created_at, updated_at, needs_confirmation
 		FROM food_entries
 		WHERE id = $1
 	`
@@ -850,6 +1013,7 @@ func (s *Service) getEntryByID(ctx context.Context, entryID string) (*FoodEntry,
 		&entry.Date,
 		&entry.CreatedAt,
 		&entry.UpdatedAt,
+		&entry.NeedsConfirmation,
 	)
 
 	if err != nil {
@@ -876,6 +1040,23 @@ func (s *Service) getEntryByID(ctx context.Context, entryID string) (*FoodEntry,
 	return &entry, nil
 }
 
+// withoutUnconfirmed returns the entries that count towards totals and how
+// many flagged, unconfirmed entries were left out
+func withoutUnconfirmed(entries map[MealType][]FoodEntry) (map[MealType][]FoodEntry, int) {
+	kept := make(map[MealType][]FoodEntry, len(entries))
+	excluded := 0
+	for meal, mealEntries := range entries {
+		for _, entry := range mealEntries {
+			if entry.NeedsConfirmation {
+				excluded++
+				continue
+			}
+			kept[meal] = append(kept[meal], entry)
+		}
+	}
+	return kept, excluded
+}
+
 // calculateTotalsFromEntries calculates daily totals from entries map
 func (s *Service) calculateTotalsFromEntries(entries map[MealType][]FoodEntry) KBZHU {
 	var totals KBZHU
@@ -1158,28 +1339,19 @@ func (s *Service) SearchFoods(ctx context.Context, userID int64, query string, l
 }
 
 // LookupBarcode looks up a food item by barcode with cascade:
-// local DB (food_items with 3M+ products) → OpenFoodFacts API fallback
+// local DB (food_items with 3M+ products) → OpenFoodFacts API fallback.
+// Failures degrade to a "not found" response.
 func (s *Service) LookupBarcode(ctx context.Context, barcode string) (*BarcodeResponse, error) {
 	if barcode == "" {
 		return nil, fmt.Errorf("штрих-код обязателен")
 	}
 
-	// 1. Check local food_items table (fast — indexed, includes 3M+ imported products)
-	food, err := s.getFoodByBarcode(ctx, barcode)
+	food, err := s.FindByBarcode(ctx, barcode)
 	if err == nil {
 		return &BarcodeResponse{Found: true, Food: food, Cached: false}, nil
 	}
-
-	// 2. Fallback: Call OpenFoodFacts API
-	product, err := s.offClient.LookupBarcode(ctx, barcode)
-	if err != nil {
-		s.log.Warn("OpenFoodFacts lookup failed", "error", err, "barcode", barcode)
-	}
-	if product != nil {
-		foodItem := s.saveOFFProduct(ctx, barcode, product)
-		if foodItem != nil {
-			return &BarcodeResponse{Found: true, Food: foodItem, Cached: false}, nil
-		}
+	if !errors.Is(err, ErrProductNotFound) {
+		s.log.Warn("Barcode lookup failed", "error", err, "barcode", barcode)
 	}
 
 	message := "Продукт не найден. Попробуйте ввести вручную."
@@ -1187,7 +1359,7 @@ func (s *Service) LookupBarcode(ctx context.Context, barcode string) (*BarcodeRe
 }
 
 // saveOFFProduct saves an OpenFoodFacts product to the food_items table
-func (s *Service) saveOFFProduct(ctx context.Context, barcode string, product *openfoodfacts.Product) *FoodItem {
+func (s *Service) saveOFFProduct(ctx context.Context, barcode string, product *openfoodfacts.Product) (*FoodItem, error) {
 	id := uuid.New().String()
 	query := `
 		INSERT INTO food_items (id, name, brand, category, serving_size, serving_unit,
@@ -1218,11 +1390,10 @@ func (s *Service) saveOFFProduct(ctx context.Context, barcode string, product *o
 		&item.CreatedAt, &item.UpdatedAt,
 	)
 	if err != nil {
-		s.log.Warn("Failed to save OFF product", "error", err, "barcode", barcode)
-		return nil
+		return nil, fmt.Errorf("не удалось сохранить продукт: %w", err)
 	}
 	item.PopulateNutrition()
-	return &item
+	return &item, nil
 }
 
 // getFoodByBarcode retrieves a food item by barcode
@@ -1292,7 +1463,7 @@ func (s *Service) getFoodByBarcode(ctx context.Context, barcode string) (*FoodIt
 			s.log.LogDatabaseQuery(pQuery, time.Since(startTime), err, map[string]interface{}{
 				"barcode": barcode,
 			})
-			return nil, fmt.Errorf("продукт не найден")
+			return nil, errBarcodeNotFound
 		}
 		s.log.LogDatabaseQuery(pQuery, time.Since(startTime), err, map[string]interface{}{
 			"barcode": barcode,
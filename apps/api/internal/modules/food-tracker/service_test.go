@@ -23,6 +23,8 @@ func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, func()) {
 	log := logger.New()
 
 	service := NewService(db, log)
+	// Never reach the real Open Food Facts API from tests
+	service.offClient = &stubLookup{}
 
 	cleanup := func() {
 		mockDB.Close()
@@ -378,7 +380,7 @@ func TestLookupBarcode(t *testing.T) {
 			WithArgs(barcode).
 			WillReturnError(sql.ErrNoRows)
 
-		// Step 3: OFF API will be called but returns 404 for fake barcode → falls through to "not found"
+		// Step 3: OFF API does not know the barcode → falls through to "not found"
 
 		// Execute
 		result, err := service.LookupBarcode(ctx, barcode)
@@ -455,8 +457,7 @@ func TestLookupBarcode(t *testing.T) {
 			WithArgs(barcode).
 			WillReturnError(sql.ErrConnDone)
 
-		// getFoodByBarcode returns error on non-ErrNoRows, LookupBarcode falls through to OFF API
-		// Step 2: OFF API called, returns 404 for fake barcode → not found
+		// getFoodByBarcode returns error on non-ErrNoRows; OFF API is not consulted
 
 		// Execute — graceful degradation, returns not found instead of error
 		result, err := service.LookupBarcode(ctx, barcode)
@@ -535,7 +536,7 @@ func TestLookupBarcode(t *testing.T) {
 					WithArgs(tc.barcode).
 					WillReturnError(sql.ErrNoRows)
 
-				// Step 3: OFF API called, returns 404 for unknown barcode → not found
+				// Step 3: OFF API does not know the barcode → not found
 
 				// Execute
 				result, err := service.LookupBarcode(ctx, tc.barcode)
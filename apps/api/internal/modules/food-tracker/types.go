@@ -259,6 +259,10 @@ type FoodEntry struct {
 	Date          string      `json:"date" db:"date"`
 	CreatedAt     time.Time   `json:"createdAt" db:"created_at"`
 	UpdatedAt     time.Time   `json:"updatedAt" db:"updated_at"`
+	// NeedsConfirmation is set while an entry flagged as anomalous awaits the user's confirmation
+	NeedsConfirmation bool `json:"needsConfirmation" db:"needs_confirmation"`
+	// Warnings explain why the entry was flagged; only returned on create/update
+	Warnings []EntryWarning `json:"warnings,omitempty" db:"-"`
 }
 
 // Validate validates the food entry fields
@@ -566,6 +570,8 @@ func (r *SearchFoodsRequest) Validate() error {
 // GetEntriesRequest represents the request to get food entries
 type GetEntriesRequest struct {
 	Date string `form:"date" binding:"required"`
+	// ExcludeUnconfirmed leaves flagged, unconfirmed entries out of the daily totals
+	ExcludeUnconfirmed bool `form:"exclude_unconfirmed"`
 }
 
 // Validate validates the get entries request
@@ -766,6 +772,8 @@ type GetEntriesResponse struct {
 	Entries     map[MealType][]FoodEntry `json:"entries"`
 	DailyTotals KBZHU                    `json:"dailyTotals"`
 	TargetGoals *KBZHU                   `json:"targetGoals,omitempty"`
+	// ExcludedEntries counts unconfirmed entries left out of DailyTotals
+	ExcludedEntries int `json:"excludedEntries,omitempty"`
 }
 
 // SearchFoodsResponse represents the response for searching foods
@@ -0,0 +1,248 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/burcev/api/internal/modules/notifications"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+)
+
+// RunDetection looks for stale targets on start and then every DetectionInterval
+// until ctx is cancelled. Repeated runs are safe: users with a pending or
+// recently resolved suggestion are skipped.
+func (s *Service) RunDetection(ctx context.Context) {
+	ticker := time.NewTicker(DetectionInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Goal suggestion detection started")
+
+	for {
+		if _, err := s.DetectStaleTargets(ctx); err != nil {
+			s.log.Error("Failed to detect stale nutrition targets", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			s.log.Info("Goal suggestion detection stopped")
+			return
+		}
+	}
+}
+
+// DetectStaleTargets creates a pending suggestion for every active plan whose
+// user's weight changed by more than WeightChangeThreshold since the plan
+// targets were set. Returns the number of suggestions created.
+func (s *Service) DetectStaleTargets(ctx context.Context) (int, error) {
+	candidates, err := s.findCandidates(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, c := range candidates {
+		targets, err := s.calc.SuggestTargets(ctx, c.UserID, c.CurrentWeight)
+		if err != nil {
+			s.log.Error("Failed to calculate suggested targets", "error", err, "user_id", c.UserID)
+			continue
+		}
+		if targets == nil {
+			// Incomplete profile, nothing to base a suggestion on
+			continue
+		}
+
+		sg, err := s.createSuggestion(ctx, c, targets)
+		if err != nil {
+			s.log.Error("Failed to create goal suggestion", "error", err, "user_id", c.UserID)
+			continue
+		}
+		if sg == nil {
+			// Lost the race against another pending suggestion
+			continue
+		}
+		created++
+
+		s.notify(ctx, sg)
+
+		s.log.LogBusinessEvent("goal_suggestion_created", map[string]interface{}{
+			"suggestion_id":   sg.ID,
+			"user_id":         sg.UserID,
+			"previous_weight": sg.PreviousWeight,
+			"current_weight":  sg.CurrentWeight,
+			"calories_from":   sg.Current.Calories,
+			"calories_to":     sg.Suggested.Calories,
+		})
+	}
+
+	return created, nil
+}
+
+// findCandidates returns active plans with a weight change past the threshold.
+// The weight the targets were set for is the latest weigh-in on or before the
+// plan's last update.
+func (s *Service) findCandidates(ctx context.Context) ([]candidate, error) {
+	startTime := time.Now()
+
+	query := `
+		WITH plans AS (
+			SELECT DISTINCT ON (wp.user_id)
+				wp.id, wp.user_id, wp.curator_id, wp.calories_goal, wp.protein_goal, wp.fat_goal, wp.carbs_goal, wp.updated_at
+			FROM weekly_plans wp
+			WHERE wp.is_active = true AND wp.end_date >= CURRENT_DATE
+			ORDER BY wp.user_id, wp.start_date DESC
+		)
+		SELECT p.id, p.user_id, p.curator_id, p.calories_goal, p.protein_goal, p.fat_goal, p.carbs_goal,
+		       base.weight, latest.weight
+		FROM plans p
+		JOIN LATERAL (
+			SELECT weight FROM daily_metrics
+			WHERE user_id = p.user_id AND weight IS NOT NULL AND date <= p.updated_at::date
+			ORDER BY date DESC LIMIT 1
+		) base ON true
+		JOIN LATERAL (
+			SELECT weight FROM daily_metrics
+			WHERE user_id = p.user_id AND weight IS NOT NULL
+			ORDER BY date DESC LIMIT 1
+		) latest ON true
+		WHERE base.weight > 0
+		  AND ABS(latest.weight - base.weight) / base.weight > $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM nutrition_goal_suggestions gs
+			WHERE gs.user_id = p.user_id AND gs.status = 'pending'
+		  )
+		  AND NOT EXISTS (
+			SELECT 1 FROM nutrition_goal_suggestions gs
+			WHERE gs.plan_id = p.id AND gs.resolved_at > NOW() - $2 * INTERVAL '1 second'
+		  )
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, WeightChangeThreshold, int(ResolvedCooldown.Seconds()))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find stale targets: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		var curatorID, fat, carbs sql.NullInt64
+		if err := rows.Scan(&c.PlanID, &c.UserID, &curatorID, &c.Current.Calories, &c.Current.Protein, &fat, &carbs,
+			&c.PreviousWeight, &c.CurrentWeight); err != nil {
+			return nil, fmt.Errorf("failed to scan stale target: %w", err)
+		}
+		if curatorID.Valid {
+			c.CuratorID = &curatorID.Int64
+		}
+		c.Current.Fat = nullableInt(fat)
+		c.Current.Carbs = nullableInt(carbs)
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating stale targets: %w", err)
+	}
+
+	return candidates, nil
+}
+
+// createSuggestion stores a pending suggestion. Returns nil without error if
+// the user already has one pending.
+func (s *Service) createSuggestion(ctx context.Context, c candidate, targets *nutritioncalc.CalculatedTargets) (*Suggestion, error) {
+	startTime := time.Now()
+
+	fat := int(math.Round(targets.Fat))
+	carbs := int(math.Round(targets.Carbs))
+	suggested := Macros{
+		Calories: int(math.Round(targets.Calories)),
+		Protein:  int(math.Round(targets.Protein)),
+		Fat:      &fat,
+		Carbs:    &carbs,
+	}
+	reason := buildReason(c.PreviousWeight, c.CurrentWeight, c.Current, suggested)
+
+	query := `
+		INSERT INTO nutrition_goal_suggestions (
+			user_id, plan_id, curator_id, previous_weight, current_weight,
+			current_calories, current_protein, current_fat, current_carbs,
+			suggested_calories, suggested_protein, suggested_fat, suggested_carbs, reason
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+		RETURNING ` + suggestionColumns
+
+	sg, err := scanSuggestion(s.db.QueryRowContext(ctx, query,
+		c.UserID, c.PlanID, c.CuratorID, c.PreviousWeight, c.CurrentWeight,
+		c.Current.Calories, c.Current.Protein, c.Current.Fat, c.Current.Carbs,
+		suggested.Calories, suggested.Protein, fat, carbs, reason,
+	))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": c.UserID,
+		"plan_id": c.PlanID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert goal suggestion: %w", err)
+	}
+
+	return sg, nil
+}
+
+// notify tells the user and, for curator plans, the curator about a new suggestion
+func (s *Service) notify(ctx context.Context, sg *Suggestion) {
+	if s.notifier == nil {
+		return
+	}
+
+	type recipient struct {
+		userID  int64
+		content string
+	}
+	recipients := []recipient{{
+		userID: sg.UserID,
+		content: fmt.Sprintf("Ваш вес изменился с %.1f до %.1f кг. Предлагаем обновить цели: %d ккал вместо %d ккал.",
+			sg.PreviousWeight, sg.CurrentWeight, sg.Suggested.Calories, sg.Current.Calories),
+	}}
+	if sg.CuratorID != nil {
+		recipients = append(recipients, recipient{
+			userID: *sg.CuratorID,
+			content: fmt.Sprintf("Вес клиента изменился с %.1f до %.1f кг. Предложено обновить цели: %d ккал вместо %d ккал.",
+				sg.PreviousWeight, sg.CurrentWeight, sg.Suggested.Calories, sg.Current.Calories),
+		})
+	}
+
+	actionURL := "/nutrition/goals/suggestions"
+	for _, r := range recipients {
+		notification := &notifications.Notification{
+			UserID:    r.userID,
+			Category:  notifications.CategoryMain,
+			Type:      notifications.TypeGoalSuggestion,
+			Title:     "Пора обновить цели по питанию",
+			Content:   r.content,
+			ActionURL: &actionURL,
+		}
+		if err := s.notifier.CreateNotification(ctx, notification); err != nil {
+			s.log.Error("Failed to send goal_suggestion notification", "error", err, "user_id", r.userID, "suggestion_id", sg.ID)
+		}
+	}
+}
+
+// buildReason explains the suggestion in the user's terms
+func buildReason(previousWeight, currentWeight float64, current, suggested Macros) string {
+	change := (currentWeight - previousWeight) / previousWeight * 100
+	direction := "снизился"
+	if change > 0 {
+		direction = "вырос"
+	}
+	return fmt.Sprintf(
+		"Вес %s с %.1f до %.1f кг (%+.1f%%) с момента установки целей. "+
+			"Пересчёт по формуле Миффлина-Сан Жеора для текущего веса: %d ккал и %d г белка вместо %d ккал и %d г белка.",
+		direction, previousWeight, currentWeight, change,
+		suggested.Calories, suggested.Protein, current.Calories, current.Protein,
+	)
+}
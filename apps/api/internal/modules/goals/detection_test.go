@@ -0,0 +1,92 @@
+package goals
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/notifications"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var candidateColumns = []string{"id", "user_id", "curator_id", "calories_goal", "protein_goal", "fat_goal", "carbs_goal",
+	"base_weight", "latest_weight"}
+
+func suggestedTargets() *nutritioncalc.CalculatedTargets {
+	return &nutritioncalc.CalculatedTargets{Calories: 2049.6, Protein: 140.2, Fat: 64.8, Carbs: 219.5}
+}
+
+func TestDetectStaleTargets(t *testing.T) {
+	calc := &fakeCalc{targets: suggestedTargets()}
+	notifier := &fakeNotifier{}
+	service, mock, cleanup := setupService(t, calc, notifier)
+	defer cleanup()
+
+	mock.ExpectQuery("FROM weekly_plans").
+		WithArgs(WeightChangeThreshold, int(ResolvedCooldown.Seconds())).
+		WillReturnRows(sqlmock.NewRows(candidateColumns).
+			AddRow("plan-1", int64(5), int64(7), int64(2200), int64(150), nil, nil, 80.0, 76.0))
+	mock.ExpectQuery("INSERT INTO nutrition_goal_suggestions").
+		WithArgs(int64(5), "plan-1", sqlmock.AnyArg(), 80.0, 76.0, 2200, 150, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			2050, 140, 65, 220, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusPending, int64(7))...))
+
+	created, err := service.DetectStaleTargets(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+	assert.Equal(t, []float64{76.0}, calc.weights)
+
+	require.Len(t, notifier.sent, 2)
+	assert.Equal(t, int64(5), notifier.sent[0].UserID)
+	assert.Equal(t, int64(7), notifier.sent[1].UserID)
+	for _, n := range notifier.sent {
+		assert.Equal(t, notifications.TypeGoalSuggestion, n.Type)
+		assert.Equal(t, notifications.CategoryMain, n.Category)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDetectStaleTargetsAlreadyPending(t *testing.T) {
+	calc := &fakeCalc{targets: suggestedTargets()}
+	notifier := &fakeNotifier{}
+	service, mock, cleanup := setupService(t, calc, notifier)
+	defer cleanup()
+
+	// A suggestion was created concurrently: the partial unique index skips the insert
+	mock.ExpectQuery("FROM weekly_plans").
+		WillReturnRows(sqlmock.NewRows(candidateColumns).
+			AddRow("plan-1", int64(5), nil, int64(2200), int64(150), int64(70), int64(250), 80.0, 76.0))
+	mock.ExpectQuery("INSERT INTO nutrition_goal_suggestions").
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns))
+
+	created, err := service.DetectStaleTargets(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Empty(t, notifier.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDetectStaleTargetsIncompleteProfile(t *testing.T) {
+	notifier := &fakeNotifier{}
+	service, mock, cleanup := setupService(t, &fakeCalc{}, notifier)
+	defer cleanup()
+
+	mock.ExpectQuery("FROM weekly_plans").
+		WillReturnRows(sqlmock.NewRows(candidateColumns).
+			AddRow("plan-1", int64(5), nil, int64(2200), int64(150), nil, nil, 80.0, 76.0))
+
+	created, err := service.DetectStaleTargets(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, created)
+	assert.Empty(t, notifier.sent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildReason(t *testing.T) {
+	fat := 65
+	reason := buildReason(80, 76, Macros{Calories: 2200, Protein: 150}, Macros{Calories: 2050, Protein: 140, Fat: &fat})
+	assert.Contains(t, reason, "снизился с 80.0 до 76.0 кг (-5.0%)")
+	assert.Contains(t, reason, "2050 ккал и 140 г белка вместо 2200 ккал и 150 г белка")
+}
@@ -0,0 +1,188 @@
+package goals
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles goal and goal suggestion requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new goals handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// ListSuggestions handles GET /api/v1/nutrition/goals/suggestions
+func (h *Handler) ListSuggestions(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	suggestions, err := h.service.ListSuggestions(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to list goal suggestions", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить предложения")
+		return
+	}
+
+	response.Success(c, http.StatusOK, suggestions)
+}
+
+// Accept handles POST /api/v1/nutrition/goals/suggestions/:id/accept
+// Applies the suggested targets to the plan. Available to the user and their curator.
+func (h *Handler) Accept(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	suggestion, err := h.service.Accept(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.respondResolveError(c, err, userID)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Цели обновлены", suggestion)
+}
+
+// Dismiss handles POST /api/v1/nutrition/goals/suggestions/:id/dismiss
+func (h *Handler) Dismiss(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	suggestion, err := h.service.Dismiss(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.respondResolveError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, suggestion)
+}
+
+func (h *Handler) respondResolveError(c *gin.Context, err error, userID int64) {
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, "Предложение не найдено")
+	case errors.Is(err, ErrNotPending):
+		response.Error(c, http.StatusConflict, "Предложение уже рассмотрено")
+	case errors.Is(err, ErrPlanInactive):
+		response.Error(c, http.StatusConflict, "План, для которого сделано предложение, больше не активен")
+	default:
+		h.log.Error("Failed to resolve goal suggestion", "error", err, "user_id", userID, "suggestion_id", c.Param("id"))
+		response.InternalError(c, "Не удалось обработать предложение")
+	}
+}
+
+// CreateGoal handles POST /api/v1/goals
+func (h *Handler) CreateGoal(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateGoalRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	goal, err := h.service.CreateGoal(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.respondGoalError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, goal)
+}
+
+// ListGoals handles GET /api/v1/goals
+// Returns active and completed goals with their progress.
+func (h *Handler) ListGoals(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	goals, err := h.service.ListGoals(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to list goals", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить цели")
+		return
+	}
+
+	response.Success(c, http.StatusOK, goals)
+}
+
+// UpdateGoal handles PATCH /api/v1/goals/:id
+// Changes the target of an active goal or abandons it.
+func (h *Handler) UpdateGoal(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateGoalRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	goal, err := h.service.UpdateGoal(c.Request.Context(), userID, c.Param("id"), &req)
+	if err != nil {
+		h.respondGoalError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, goal)
+}
+
+func (h *Handler) respondGoalError(c *gin.Context, err error, userID int64) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		validation.Respond(c, err)
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, "Цель не найдена")
+	case errors.Is(err, ErrActiveGoalExists):
+		response.Error(c, http.StatusConflict, "Активная цель этого типа уже есть")
+	case errors.Is(err, ErrGoalNotActive):
+		response.Error(c, http.StatusConflict, "Цель уже завершена или отменена")
+	default:
+		h.log.Error("Failed to save goal", "error", err, "user_id", userID, "goal_id", c.Param("id"))
+		response.InternalError(c, "Не удалось сохранить цель")
+	}
+}
@@ -0,0 +1,207 @@
+package goals
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	actorID int64
+	err     error
+}
+
+func (m *mockService) ListSuggestions(ctx context.Context, userID int64) ([]Suggestion, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Suggestion{{ID: testSuggestionID, UserID: userID, Status: StatusPending}}, nil
+}
+
+func (m *mockService) Accept(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error) {
+	m.actorID = actorID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Suggestion{ID: suggestionID, Status: StatusAccepted}, nil
+}
+
+func (m *mockService) Dismiss(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error) {
+	m.actorID = actorID
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Suggestion{ID: suggestionID, Status: StatusDismissed}, nil
+}
+
+func (m *mockService) CreateGoal(ctx context.Context, userID int64, req *CreateGoalRequest) (*Goal, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Goal{ID: testGoalID, UserID: userID, Type: req.Type, Status: GoalActive, TargetValue: *req.TargetValue}, nil
+}
+
+func (m *mockService) ListGoals(ctx context.Context, userID int64) ([]Goal, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Goal{{ID: testGoalID, UserID: userID, Type: TypeWeight, Status: GoalActive}}, nil
+}
+
+func (m *mockService) UpdateGoal(ctx context.Context, userID int64, goalID string, req *UpdateGoalRequest) (*Goal, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Goal{ID: goalID, UserID: userID, Type: TypeWeight, Status: *req.Status}, nil
+}
+
+func newTestContext(method, path string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, nil)
+	c.Params = gin.Params{{Key: "id", Value: testSuggestionID}}
+	c.Set("user_id", int64(5))
+	return c, w
+}
+
+func TestHandlerListSuggestions(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	c, w := newTestContext(http.MethodGet, "/nutrition/goals/suggestions")
+
+	handler.ListSuggestions(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), testSuggestionID)
+}
+
+func TestHandlerAccept(t *testing.T) {
+	svc := &mockService{}
+	handler := NewHandler(nil, logger.New(), svc)
+	c, w := newTestContext(http.MethodPost, "/nutrition/goals/suggestions/"+testSuggestionID+"/accept")
+
+	handler.Accept(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, int64(5), svc.actorID)
+	assert.Contains(t, w.Body.String(), `"status":"accepted"`)
+}
+
+func TestHandlerResolveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"not pending", ErrNotPending, http.StatusConflict},
+		{"plan inactive", ErrPlanInactive, http.StatusConflict},
+		{"internal", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+
+			c, w := newTestContext(http.MethodPost, "/nutrition/goals/suggestions/"+testSuggestionID+"/accept")
+			handler.Accept(c)
+			assert.Equal(t, tt.code, w.Code)
+
+			c, w = newTestContext(http.MethodPost, "/nutrition/goals/suggestions/"+testSuggestionID+"/dismiss")
+			handler.Dismiss(c)
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerUnauthorized(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/nutrition/goals/suggestions", nil)
+
+	handler.ListSuggestions(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func newJSONTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := newTestContext(method, path)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: testGoalID}}
+	return c, w
+}
+
+func TestHandlerCreateGoal(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", `{"type":"weight","target_value":75,"target_date":"2027-03-01"}`, nil, http.StatusCreated},
+		{"unknown type", `{"type":"steps","target_value":10000,"target_date":"2027-03-01"}`, nil, http.StatusBadRequest},
+		{"missing target", `{"type":"weight","target_date":"2027-03-01"}`, nil, http.StatusBadRequest},
+		{"service validation", `{"type":"weight","target_value":5,"target_date":"2027-03-01"}`,
+			validation.Errors{"target_value": "Значение должно быть от 20 до 400"}, http.StatusBadRequest},
+		{"active goal exists", `{"type":"weight","target_value":75,"target_date":"2027-03-01"}`, ErrActiveGoalExists, http.StatusConflict},
+		{"internal", `{"type":"weight","target_value":75,"target_date":"2027-03-01"}`, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newJSONTestContext(http.MethodPost, "/goals", tt.body)
+
+			handler.CreateGoal(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerListGoals(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	c, w := newTestContext(http.MethodGet, "/goals")
+
+	handler.ListGoals(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), testGoalID)
+	assert.Contains(t, w.Body.String(), `"progress"`)
+}
+
+func TestHandlerUpdateGoal(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"abandoned", `{"status":"abandoned"}`, nil, http.StatusOK},
+		{"status cannot be completed", `{"status":"completed"}`, nil, http.StatusBadRequest},
+		{"not found", `{"status":"abandoned"}`, apperrors.ErrNotFound, http.StatusNotFound},
+		{"not active", `{"status":"abandoned"}`, ErrGoalNotActive, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newJSONTestContext(http.MethodPatch, "/goals/"+testGoalID, tt.body)
+
+			handler.UpdateGoal(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
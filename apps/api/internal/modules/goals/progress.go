@@ -0,0 +1,60 @@
+package goals
+
+import (
+	"math"
+	"time"
+)
+
+// ComputeProgress measures a goal against a straight line from start (on
+// startDate) to target (on targetDate). It works in either direction, so
+// losing weight and raising adherence are handled alike.
+//
+// Without a current value there is nothing to measure and the goal is not on
+// track. Without a start value the current one is used, i.e. no progress has
+// been made yet. A goal is on track when its percent is at least the
+// expected percent for now; a completed goal is always on track.
+func ComputeProgress(start, current *float64, target float64, startDate, targetDate, now time.Time) Progress {
+	p := Progress{
+		CurrentValue:    current,
+		ExpectedPercent: round1(expectedShare(startDate, targetDate, now) * 100),
+	}
+	if current == nil {
+		return p
+	}
+
+	from := *current
+	if start != nil {
+		from = *start
+	}
+
+	span := target - from
+	if span == 0 {
+		p.Percent = 100
+	} else {
+		p.Percent = round1(clamp((*current-from)/span, 0, 1) * 100)
+	}
+
+	p.Completed = p.Percent >= 100
+	p.OnTrack = p.Completed || p.Percent >= p.ExpectedPercent
+	return p
+}
+
+// expectedShare is the share of the period from startDate to targetDate
+// that has passed by now, between 0 and 1. It moves in whole days, so a goal
+// is not behind schedule hours after it was set.
+func expectedShare(startDate, targetDate, now time.Time) float64 {
+	total := targetDate.Sub(startDate)
+	if total <= 0 {
+		return 1
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return clamp(float64(today.Sub(startDate))/float64(total), 0, 1)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,63 @@
+package goals
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestComputeProgress(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	target := time.Date(2026, 12, 10, 0, 0, 0, 0, time.UTC) // 70 days
+	halfway := start.AddDate(0, 0, 35)
+
+	tests := []struct {
+		name      string
+		start     *float64
+		current   *float64
+		target    float64
+		now       time.Time
+		percent   float64
+		expected  float64
+		onTrack   bool
+		completed bool
+	}{
+		{"weight loss ahead of schedule", ptr(90), ptr(85), 84, halfway, 83.3, 50, true, false},
+		{"weight loss behind schedule", ptr(90), ptr(88), 84, halfway, 33.3, 50, false, false},
+		{"exactly on the line", ptr(90), ptr(87), 84, halfway, 50, 50, true, false},
+		{"adherence going up", ptr(40), ptr(80), 90, halfway, 80, 50, true, false},
+		{"complete", ptr(90), ptr(84), 84, halfway, 100, 50, true, true},
+		{"overshot the target", ptr(90), ptr(82), 84, halfway, 100, 50, true, true},
+		{"moved away from the target", ptr(90), ptr(92), 84, halfway, 0, 50, false, false},
+		{"target date passed", ptr(90), ptr(86), 84, target.AddDate(0, 0, 3), 66.7, 100, false, false},
+		{"complete after the target date", ptr(90), ptr(84), 84, target.AddDate(0, 0, 3), 100, 100, true, true},
+		{"first day", ptr(90), ptr(90), 84, start, 0, 0, true, false},
+		{"no start value", nil, ptr(88), 84, halfway, 0, 50, false, false},
+		{"no start value at target", nil, ptr(84), 84, halfway, 100, 50, true, true},
+		{"no data", ptr(90), nil, 84, halfway, 0, 50, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := ComputeProgress(tt.start, tt.current, tt.target, start, target, tt.now)
+
+			assert.Equal(t, tt.current, p.CurrentValue)
+			assert.Equal(t, tt.percent, p.Percent)
+			assert.Equal(t, tt.expected, p.ExpectedPercent)
+			assert.Equal(t, tt.onTrack, p.OnTrack)
+			assert.Equal(t, tt.completed, p.Completed)
+		})
+	}
+}
+
+func TestComputeProgress_TargetDateNotAfterStart(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	p := ComputeProgress(ptr(90), ptr(87), 84, day, day, day)
+
+	assert.Equal(t, float64(100), p.ExpectedPercent)
+	assert.False(t, p.OnTrack)
+}
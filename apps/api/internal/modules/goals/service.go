@@ -0,0 +1,275 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/modules/notifications"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/google/uuid"
+)
+
+// TargetCalculator computes KBJU targets (implemented by nutritioncalc.Service)
+type TargetCalculator interface {
+	SuggestTargets(ctx context.Context, userID int64, weightKg float64) (*nutritioncalc.CalculatedTargets, error)
+	RecalculateForDate(ctx context.Context, userID int64, date time.Time) (*nutritioncalc.CalculatedTargets, error)
+}
+
+// Notifier delivers in-app notifications (implemented by notifications.Service)
+type Notifier interface {
+	CreateNotification(ctx context.Context, notification *notifications.Notification) error
+}
+
+// ServiceInterface defines the interface for goal and goal suggestion operations
+type ServiceInterface interface {
+	ListSuggestions(ctx context.Context, userID int64) ([]Suggestion, error)
+	Accept(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error)
+	Dismiss(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error)
+
+	CreateGoal(ctx context.Context, userID int64, req *CreateGoalRequest) (*Goal, error)
+	ListGoals(ctx context.Context, userID int64) ([]Goal, error)
+	UpdateGoal(ctx context.Context, userID int64, goalID string, req *UpdateGoalRequest) (*Goal, error)
+}
+
+// Service tracks user goals, detects stale nutrition targets and manages
+// target suggestions
+type Service struct {
+	db       *database.DB
+	log      *logger.Logger
+	calc     TargetCalculator
+	notifier Notifier
+	now      func() time.Time
+}
+
+// NewService creates a new goals service. notifier may be nil.
+func NewService(db *database.DB, log *logger.Logger, calc TargetCalculator, notifier Notifier) *Service {
+	return &Service{
+		db:       db,
+		log:      log,
+		calc:     calc,
+		notifier: notifier,
+		now:      time.Now,
+	}
+}
+
+const suggestionColumns = `id, user_id, plan_id, curator_id, status, previous_weight, current_weight,
+		current_calories, current_protein, current_fat, current_carbs,
+		suggested_calories, suggested_protein, suggested_fat, suggested_carbs,
+		reason, created_at, resolved_at, resolved_by`
+
+// ListSuggestions returns the user's suggestions, pending first
+func (s *Service) ListSuggestions(ctx context.Context, userID int64) ([]Suggestion, error) {
+	startTime := time.Now()
+
+	query := `SELECT ` + suggestionColumns + `
+		FROM nutrition_goal_suggestions
+		WHERE user_id = $1
+		ORDER BY (status = 'pending') DESC, created_at DESC
+		LIMIT 50`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goal suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := make([]Suggestion, 0)
+	for rows.Next() {
+		sg, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan goal suggestion: %w", err)
+		}
+		suggestions = append(suggestions, *sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating goal suggestions: %w", err)
+	}
+
+	return suggestions, nil
+}
+
+// Accept applies a pending suggestion to the plan it was made for. Only the
+// user or their active curator may accept; others get apperrors.ErrNotFound.
+func (s *Service) Accept(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sg, err := s.lockPending(ctx, tx, actorID, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		UPDATE weekly_plans
+		SET calories_goal = $2, protein_goal = $3, fat_goal = $4, carbs_goal = $5, updated_at = NOW()
+		WHERE id = $1 AND is_active = true`
+	res, err := tx.ExecContext(ctx, query,
+		sg.PlanID, sg.Suggested.Calories, sg.Suggested.Protein, sg.Suggested.Fat, sg.Suggested.Carbs,
+	)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"suggestion_id": sg.ID,
+		"plan_id":       sg.PlanID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update plan targets: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, ErrPlanInactive
+	}
+
+	if err := s.resolve(ctx, tx, sg, StatusAccepted, actorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	// Today's stored targets were computed from the old plan values
+	if _, err := s.calc.RecalculateForDate(ctx, sg.UserID, time.Now()); err != nil {
+		s.log.Error("Failed to recalculate targets after accepting suggestion", "error", err, "user_id", sg.UserID)
+	}
+
+	s.log.LogBusinessEvent("goal_suggestion_accepted", map[string]interface{}{
+		"suggestion_id": sg.ID,
+		"user_id":       sg.UserID,
+		"accepted_by":   actorID,
+		"calories_from": sg.Current.Calories,
+		"calories_to":   sg.Suggested.Calories,
+	})
+
+	return sg, nil
+}
+
+// Dismiss rejects a pending suggestion without touching the plan
+func (s *Service) Dismiss(ctx context.Context, actorID int64, suggestionID string) (*Suggestion, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sg, err := s.lockPending(ctx, tx, actorID, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.resolve(ctx, tx, sg, StatusDismissed, actorID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.LogBusinessEvent("goal_suggestion_dismissed", map[string]interface{}{
+		"suggestion_id": sg.ID,
+		"user_id":       sg.UserID,
+		"dismissed_by":  actorID,
+	})
+
+	return sg, nil
+}
+
+// lockPending loads a suggestion the actor may act on and locks it.
+// Returns ErrNotPending if it was already resolved.
+func (s *Service) lockPending(ctx context.Context, tx *sql.Tx, actorID int64, suggestionID string) (*Suggestion, error) {
+	if _, err := uuid.Parse(suggestionID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	sg, err := scanSuggestion(tx.QueryRowContext(ctx, `
+		SELECT `+suggestionColumns+`
+		FROM nutrition_goal_suggestions s
+		WHERE id = $1
+		  AND (user_id = $2 OR EXISTS (
+			SELECT 1 FROM curator_client_relationships ccr
+			WHERE ccr.curator_id = $2 AND ccr.client_id = s.user_id AND ccr.status = 'active'
+		  ))
+		FOR UPDATE`,
+		suggestionID, actorID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get goal suggestion: %w", err)
+	}
+	if sg.Status != StatusPending {
+		return nil, ErrNotPending
+	}
+
+	return sg, nil
+}
+
+// resolve records the final status of a suggestion
+func (s *Service) resolve(ctx context.Context, tx *sql.Tx, sg *Suggestion, status string, actorID int64) error {
+	var resolvedAt time.Time
+	err := tx.QueryRowContext(ctx, `
+		UPDATE nutrition_goal_suggestions
+		SET status = $2, resolved_at = NOW(), resolved_by = $3
+		WHERE id = $1
+		RETURNING resolved_at`,
+		sg.ID, status, actorID,
+	).Scan(&resolvedAt)
+	if err != nil {
+		return fmt.Errorf("failed to resolve goal suggestion: %w", err)
+	}
+
+	sg.Status = status
+	sg.ResolvedAt = &resolvedAt
+	sg.ResolvedBy = &actorID
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSuggestion(row rowScanner) (*Suggestion, error) {
+	var sg Suggestion
+	var curatorID, resolvedBy, currentFat, currentCarbs sql.NullInt64
+	var suggestedFat, suggestedCarbs int
+	var resolvedAt sql.NullTime
+
+	if err := row.Scan(&sg.ID, &sg.UserID, &sg.PlanID, &curatorID, &sg.Status, &sg.PreviousWeight, &sg.CurrentWeight,
+		&sg.Current.Calories, &sg.Current.Protein, &currentFat, &currentCarbs,
+		&sg.Suggested.Calories, &sg.Suggested.Protein, &suggestedFat, &suggestedCarbs,
+		&sg.Reason, &sg.CreatedAt, &resolvedAt, &resolvedBy); err != nil {
+		return nil, err
+	}
+	if curatorID.Valid {
+		sg.CuratorID = &curatorID.Int64
+	}
+	sg.Current.Fat = nullableInt(currentFat)
+	sg.Current.Carbs = nullableInt(currentCarbs)
+	sg.Suggested.Fat = &suggestedFat
+	sg.Suggested.Carbs = &suggestedCarbs
+	if resolvedAt.Valid {
+		sg.ResolvedAt = &resolvedAt.Time
+	}
+	if resolvedBy.Valid {
+		sg.ResolvedBy = &resolvedBy.Int64
+	}
+	return &sg, nil
+}
+
+func nullableInt(v sql.NullInt64) *int {
+	if !v.Valid {
+		return nil
+	}
+	i := int(v.Int64)
+	return &i
+}
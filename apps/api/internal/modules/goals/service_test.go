@@ -0,0 +1,188 @@
+package goals
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/notifications"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSuggestionID = "6f1c2b0e-3a4d-4b5e-8f9a-0b1c2d3e4f5a"
+
+// fakeCalc returns canned targets and records recalculations
+type fakeCalc struct {
+	targets      *nutritioncalc.CalculatedTargets
+	err          error
+	weights      []float64
+	recalculated []int64
+}
+
+func (f *fakeCalc) SuggestTargets(ctx context.Context, userID int64, weightKg float64) (*nutritioncalc.CalculatedTargets, error) {
+	f.weights = append(f.weights, weightKg)
+	return f.targets, f.err
+}
+
+func (f *fakeCalc) RecalculateForDate(ctx context.Context, userID int64, date time.Time) (*nutritioncalc.CalculatedTargets, error) {
+	f.recalculated = append(f.recalculated, userID)
+	return f.targets, nil
+}
+
+// fakeNotifier records the notifications it was asked to send
+type fakeNotifier struct {
+	sent []*notifications.Notification
+}
+
+func (f *fakeNotifier) CreateNotification(ctx context.Context, n *notifications.Notification) error {
+	f.sent = append(f.sent, n)
+	return nil
+}
+
+var suggestionRowColumns = []string{"id", "user_id", "plan_id", "curator_id", "status", "previous_weight", "current_weight",
+	"current_calories", "current_protein", "current_fat", "current_carbs",
+	"suggested_calories", "suggested_protein", "suggested_fat", "suggested_carbs",
+	"reason", "created_at", "resolved_at", "resolved_by"}
+
+func suggestionRow(status string, curatorID interface{}) []driver.Value {
+	return []driver.Value{testSuggestionID, int64(5), "plan-1", curatorID, status, 80.0, 76.0,
+		int64(2200), int64(150), nil, nil,
+		int64(2050), int64(140), int64(65), int64(220),
+		"reason", time.Now(), nil, nil}
+}
+
+func setupService(t *testing.T, calc *fakeCalc, notifier *fakeNotifier) (*Service, sqlmock.Sqlmock, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), calc, notifier)
+	return service, mock, func() { mockDB.Close() }
+}
+
+func TestListSuggestions(t *testing.T) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	defer cleanup()
+
+	mock.ExpectQuery("FROM nutrition_goal_suggestions").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusPending, nil)...))
+
+	suggestions, err := service.ListSuggestions(context.Background(), 5)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, 2050, suggestions[0].Suggested.Calories)
+	assert.Nil(t, suggestions[0].Current.Fat)
+	assert.Equal(t, 65, *suggestions[0].Suggested.Fat)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAccept(t *testing.T) {
+	calc := &fakeCalc{}
+	service, mock, cleanup := setupService(t, calc, nil)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM nutrition_goal_suggestions s").
+		WithArgs(testSuggestionID, int64(5)).
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusPending, nil)...))
+	mock.ExpectExec("UPDATE weekly_plans").
+		WithArgs("plan-1", 2050, 140, 65, 220).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("UPDATE nutrition_goal_suggestions").
+		WithArgs(testSuggestionID, StatusAccepted, int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"resolved_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	sg, err := service.Accept(context.Background(), 5, testSuggestionID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusAccepted, sg.Status)
+	assert.Equal(t, int64(5), *sg.ResolvedBy)
+	assert.Equal(t, []int64{5}, calc.recalculated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptPlanInactive(t *testing.T) {
+	calc := &fakeCalc{}
+	service, mock, cleanup := setupService(t, calc, nil)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM nutrition_goal_suggestions s").
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusPending, nil)...))
+	mock.ExpectExec("UPDATE weekly_plans").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	_, err := service.Accept(context.Background(), 5, testSuggestionID)
+	assert.ErrorIs(t, err, ErrPlanInactive)
+	assert.Empty(t, calc.recalculated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAcceptAlreadyResolved(t *testing.T) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM nutrition_goal_suggestions s").
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusDismissed, nil)...))
+	mock.ExpectRollback()
+
+	_, err := service.Accept(context.Background(), 5, testSuggestionID)
+	assert.ErrorIs(t, err, ErrNotPending)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDismissNotAllowed(t *testing.T) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	defer cleanup()
+
+	// Neither the owner nor their curator: the row is filtered out
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM nutrition_goal_suggestions s").
+		WithArgs(testSuggestionID, int64(99)).
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns))
+	mock.ExpectRollback()
+
+	_, err := service.Dismiss(context.Background(), 99, testSuggestionID)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDismissByCurator(t *testing.T) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("FROM nutrition_goal_suggestions s").
+		WithArgs(testSuggestionID, int64(7)).
+		WillReturnRows(sqlmock.NewRows(suggestionRowColumns).AddRow(suggestionRow(StatusPending, int64(7))...))
+	mock.ExpectQuery("UPDATE nutrition_goal_suggestions").
+		WithArgs(testSuggestionID, StatusDismissed, int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"resolved_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	sg, err := service.Dismiss(context.Background(), 7, testSuggestionID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusDismissed, sg.Status)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInvalidSuggestionID(t *testing.T) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	_, err := service.Accept(context.Background(), 5, "not-a-uuid")
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,380 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// Accepted target values per goal type
+var targetRanges = map[string][2]float64{
+	TypeWeight:            {20, 400},
+	TypeBodyFat:           {2, 70},
+	TypeCaloriesAdherence: {1, 100},
+}
+
+const goalColumns = `id, user_id, type, status, start_value, target_value, start_date::text, target_date::text,
+		created_at, updated_at, completed_at, abandoned_at`
+
+// CreateGoal sets a new goal. The current value of the metric becomes its
+// start value. Returns ErrActiveGoalExists if the user already has an
+// active goal of the same type.
+func (s *Service) CreateGoal(ctx context.Context, userID int64, req *CreateGoalRequest) (*Goal, error) {
+	now := s.now()
+	if err := validateGoal(req.Type, req.TargetValue, &req.TargetDate, now); err != nil {
+		return nil, err
+	}
+
+	start, err := s.currentValue(ctx, userID, req.Type, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var goal *Goal
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// Serializes goal creation per user so the check below cannot race
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM users WHERE id = $1 FOR UPDATE`, userID); err != nil {
+			return fmt.Errorf("failed to lock user: %w", err)
+		}
+
+		var exists bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS (SELECT 1 FROM goals WHERE user_id = $1 AND type = $2 AND status = 'active')`,
+			userID, req.Type,
+		).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check active goals: %w", err)
+		}
+		if exists {
+			return ErrActiveGoalExists
+		}
+
+		goal, err = scanGoal(tx.QueryRowContext(ctx, `
+			INSERT INTO goals (user_id, type, start_value, target_value, start_date, target_date)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+goalColumns,
+			userID, req.Type, start, *req.TargetValue, now.Format("2006-01-02"), req.TargetDate,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to create goal: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	goal.Progress = s.progress(goal, start, now)
+
+	s.log.LogBusinessEvent("goal_created", map[string]interface{}{
+		"goal_id":      goal.ID,
+		"user_id":      userID,
+		"type":         goal.Type,
+		"target_value": goal.TargetValue,
+		"target_date":  goal.TargetDate,
+	})
+
+	return goal, nil
+}
+
+// ListGoals returns the user's active and completed goals with their
+// progress, active first. An active goal that has reached its target is
+// marked completed.
+func (s *Service) ListGoals(ctx context.Context, userID int64) ([]Goal, error) {
+	startTime := time.Now()
+	query := `SELECT ` + goalColumns + `
+		FROM goals
+		WHERE user_id = $1 AND status IN ('active', 'completed')
+		ORDER BY (status = 'active') DESC, target_date, created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list goals: %w", err)
+	}
+
+	goals := make([]Goal, 0)
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan goal: %w", err)
+		}
+		goals = append(goals, *goal)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating goals: %w", err)
+	}
+
+	now := s.now()
+	current := make(map[string]*float64)
+	for i := range goals {
+		goal := &goals[i]
+		if goal.Status != GoalActive {
+			// A completed goal stays complete whatever the metric does later
+			goal.Progress = Progress{Percent: 100, ExpectedPercent: 100, OnTrack: true, Completed: true}
+			continue
+		}
+
+		value, ok := current[goal.Type]
+		if !ok {
+			if value, err = s.currentValue(ctx, userID, goal.Type, now); err != nil {
+				return nil, err
+			}
+			current[goal.Type] = value
+		}
+
+		if goal.StartValue == nil && value != nil {
+			if err := s.setStartValue(ctx, goal, *value); err != nil {
+				return nil, err
+			}
+		}
+		goal.Progress = s.progress(goal, value, now)
+		if goal.Progress.Completed {
+			if err := s.complete(ctx, goal); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return goals, nil
+}
+
+// UpdateGoal changes the target of an active goal or abandons it. Goals of
+// other users are reported as apperrors.ErrNotFound.
+func (s *Service) UpdateGoal(ctx context.Context, userID int64, goalID string, req *UpdateGoalRequest) (*Goal, error) {
+	if _, err := uuid.Parse(goalID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	now := s.now()
+	var goal *Goal
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		goal, err = scanGoal(tx.QueryRowContext(ctx,
+			`SELECT `+goalColumns+` FROM goals WHERE id = $1 AND user_id = $2 FOR UPDATE`,
+			goalID, userID,
+		))
+		if err == sql.ErrNoRows {
+			return apperrors.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get goal: %w", err)
+		}
+		if goal.Status != GoalActive {
+			return ErrGoalNotActive
+		}
+
+		target := goal.TargetValue
+		if req.TargetValue != nil {
+			target = *req.TargetValue
+		}
+		targetDate := goal.TargetDate
+		if req.TargetDate != nil {
+			targetDate = *req.TargetDate
+		}
+		if req.TargetValue != nil || req.TargetDate != nil {
+			if err := validateGoal(goal.Type, &target, &targetDate, now); err != nil {
+				return err
+			}
+		}
+
+		status := goal.Status
+		if req.Status != nil {
+			status = *req.Status
+		}
+
+		goal, err = scanGoal(tx.QueryRowContext(ctx, `
+			UPDATE goals
+			SET target_value = $2, target_date = $3, status = $4, updated_at = NOW(),
+				abandoned_at = CASE WHEN $4 = 'abandoned' THEN NOW() ELSE abandoned_at END
+			WHERE id = $1
+			RETURNING `+goalColumns,
+			goalID, target, targetDate, status,
+		))
+		if err != nil {
+			return fmt.Errorf("failed to update goal: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if goal.Status == GoalActive {
+		current, err := s.currentValue(ctx, userID, goal.Type, now)
+		if err != nil {
+			return nil, err
+		}
+		goal.Progress = s.progress(goal, current, now)
+	}
+
+	event := "goal_updated"
+	if goal.Status == GoalAbandoned {
+		event = "goal_abandoned"
+	}
+	s.log.LogBusinessEvent(event, map[string]interface{}{
+		"goal_id": goal.ID,
+		"user_id": userID,
+		"type":    goal.Type,
+	})
+
+	return goal, nil
+}
+
+// progress computes a goal's progress from its stored dates
+func (s *Service) progress(goal *Goal, current *float64, now time.Time) Progress {
+	startDate, _ := time.Parse("2006-01-02", goal.StartDate)
+	targetDate, _ := time.Parse("2006-01-02", goal.TargetDate)
+	return ComputeProgress(goal.StartValue, current, goal.TargetValue, startDate, targetDate, now)
+}
+
+// setStartValue records the first value of a goal that was set without data
+func (s *Service) setStartValue(ctx context.Context, goal *Goal, value float64) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE goals SET start_value = $2 WHERE id = $1 AND start_value IS NULL`,
+		goal.ID, value,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set goal start value: %w", err)
+	}
+	goal.StartValue = &value
+	return nil
+}
+
+// complete marks an active goal that reached its target as completed
+func (s *Service) complete(ctx context.Context, goal *Goal) error {
+	var completedAt time.Time
+	err := s.db.QueryRowContext(ctx, `
+		UPDATE goals
+		SET status = 'completed', completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status = 'active'
+		RETURNING completed_at`,
+		goal.ID,
+	).Scan(&completedAt)
+	if err == sql.ErrNoRows {
+		// Completed or abandoned concurrently
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete goal: %w", err)
+	}
+
+	goal.Status = GoalCompleted
+	goal.CompletedAt = &completedAt
+
+	s.log.LogBusinessEvent("goal_completed", map[string]interface{}{
+		"goal_id": goal.ID,
+		"user_id": goal.UserID,
+		"type":    goal.Type,
+	})
+	return nil
+}
+
+// currentValue returns the user's current value of a goal metric, or nil if
+// there is no data: the latest weigh-in, the latest body fat estimate, or the
+// share of the last AdherenceWindowDays days (including today) that have
+// calories logged.
+func (s *Service) currentValue(ctx context.Context, userID int64, goalType string, now time.Time) (*float64, error) {
+	var query string
+	args := []interface{}{userID}
+	switch goalType {
+	case TypeWeight:
+		query = `
+			SELECT weight FROM daily_metrics
+			WHERE user_id = $1 AND weight IS NOT NULL
+			ORDER BY date DESC LIMIT 1`
+	case TypeBodyFat:
+		query = `
+			SELECT body_fat_pct FROM body_fat_estimates
+			WHERE user_id = $1 AND status = 'done' AND body_fat_pct IS NOT NULL
+			ORDER BY completed_at DESC LIMIT 1`
+	case TypeCaloriesAdherence:
+		query = `
+			SELECT ROUND(COUNT(*) * 100.0 / $3::int, 1) FROM daily_metrics
+			WHERE user_id = $1 AND calories > 0 AND date > $2::date - $3::int AND date <= $2::date`
+		args = append(args, now.Format("2006-01-02"), AdherenceWindowDays)
+	default:
+		return nil, fmt.Errorf("unknown goal type %q", goalType)
+	}
+
+	startTime := time.Now()
+	var value sql.NullFloat64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&value)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"type":    goalType,
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current %s: %w", goalType, err)
+	}
+	if !value.Valid {
+		return nil, nil
+	}
+	return &value.Float64, nil
+}
+
+// validateGoal checks a target value and date; targetDate must be in the
+// future and no more than MaxGoalHorizonYears away
+func validateGoal(goalType string, targetValue *float64, targetDate *string, now time.Time) error {
+	errs := validation.Errors{}
+
+	if r, ok := targetRanges[goalType]; ok {
+		if targetValue == nil {
+			errs["target_value"] = "Обязательное поле"
+		} else if *targetValue < r[0] || *targetValue > r[1] {
+			errs["target_value"] = fmt.Sprintf("Значение должно быть от %g до %g", r[0], r[1])
+		}
+	}
+
+	date, err := time.Parse("2006-01-02", *targetDate)
+	if err != nil {
+		errs["target_date"] = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+	} else {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		switch {
+		case !date.After(today):
+			errs["target_date"] = "Дата должна быть в будущем"
+		case date.After(today.AddDate(MaxGoalHorizonYears, 0, 0)):
+			errs["target_date"] = fmt.Sprintf("Дата не может быть позже чем через %d года", MaxGoalHorizonYears)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func scanGoal(row rowScanner) (*Goal, error) {
+	var g Goal
+	var startValue sql.NullFloat64
+	var completedAt, abandonedAt sql.NullTime
+
+	if err := row.Scan(&g.ID, &g.UserID, &g.Type, &g.Status, &startValue, &g.TargetValue, &g.StartDate, &g.TargetDate,
+		&g.CreatedAt, &g.UpdatedAt, &completedAt, &abandonedAt); err != nil {
+		return nil, err
+	}
+	if startValue.Valid {
+		g.StartValue = &startValue.Float64
+	}
+	if completedAt.Valid {
+		g.CompletedAt = &completedAt.Time
+	}
+	if abandonedAt.Valid {
+		g.AbandonedAt = &abandonedAt.Time
+	}
+	return &g, nil
+}
@@ -0,0 +1,218 @@
+package goals
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGoalID = "0b6a7c8d-1e2f-4a3b-9c4d-5e6f7a8b9c0d"
+
+var goalRowColumns = []string{"id", "user_id", "type", "status", "start_value", "target_value", "start_date", "target_date",
+	"created_at", "updated_at", "completed_at", "abandoned_at"}
+
+func goalRow(goalType, status string, start interface{}, target float64) []driver.Value {
+	return []driver.Value{testGoalID, int64(5), goalType, status, start, target, "2026-10-01", "2026-12-10",
+		time.Now(), time.Now(), nil, nil}
+}
+
+func setupGoalService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	service, mock, cleanup := setupService(t, &fakeCalc{}, nil)
+	t.Cleanup(cleanup)
+	service.now = func() time.Time { return time.Date(2026, 11, 5, 9, 0, 0, 0, time.UTC) }
+	return service, mock
+}
+
+func TestCreateGoal(t *testing.T) {
+	service, mock := setupGoalService(t)
+
+	mock.ExpectQuery("SELECT weight FROM daily_metrics").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(90.0))
+	mock.ExpectBegin()
+	mock.ExpectExec("FROM users WHERE id = \\$1 FOR UPDATE").
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(int64(5), TypeWeight).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery("INSERT INTO goals").
+		WithArgs(int64(5), TypeWeight, 90.0, 84.0, "2026-11-05", "2027-01-14").
+		WillReturnRows(sqlmock.NewRows(goalRowColumns).AddRow(
+			testGoalID, int64(5), TypeWeight, GoalActive, 90.0, 84.0, "2026-11-05", "2027-01-14",
+			time.Now(), time.Now(), nil, nil))
+	mock.ExpectCommit()
+
+	target := 84.0
+	goal, err := service.CreateGoal(context.Background(), 5, &CreateGoalRequest{
+		Type: TypeWeight, TargetValue: &target, TargetDate: "2027-01-14",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 90.0, *goal.StartValue)
+	assert.Zero(t, goal.Progress.Percent)
+	assert.True(t, goal.Progress.OnTrack)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGoal_ActiveGoalExists(t *testing.T) {
+	service, mock := setupGoalService(t)
+
+	mock.ExpectQuery("SELECT body_fat_pct FROM body_fat_estimates").
+		WillReturnRows(sqlmock.NewRows([]string{"body_fat_pct"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("FROM users WHERE id = \\$1 FOR UPDATE").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT EXISTS").
+		WithArgs(int64(5), TypeBodyFat).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	target := 15.0
+	_, err := service.CreateGoal(context.Background(), 5, &CreateGoalRequest{
+		Type: TypeBodyFat, TargetValue: &target, TargetDate: "2027-01-14",
+	})
+
+	assert.ErrorIs(t, err, ErrActiveGoalExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateGoal_Invalid(t *testing.T) {
+	service, mock := setupGoalService(t)
+
+	tests := []struct {
+		name   string
+		req    CreateGoalRequest
+		target float64
+		field  string
+	}{
+		{"weight out of range", CreateGoalRequest{Type: TypeWeight, TargetDate: "2027-01-14"}, 500, "target_value"},
+		{"adherence over 100", CreateGoalRequest{Type: TypeCaloriesAdherence, TargetDate: "2027-01-14"}, 120, "target_value"},
+		{"date in the past", CreateGoalRequest{Type: TypeWeight, TargetDate: "2026-11-01"}, 80, "target_date"},
+		{"date today", CreateGoalRequest{Type: TypeWeight, TargetDate: "2026-11-05"}, 80, "target_date"},
+		{"date too far", CreateGoalRequest{Type: TypeWeight, TargetDate: "2029-01-01"}, 80, "target_date"},
+		{"bad date", CreateGoalRequest{Type: TypeWeight, TargetDate: "14.01.2027"}, 80, "target_date"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.TargetValue = &tt.target
+
+			_, err := service.CreateGoal(context.Background(), 5, &req)
+
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Contains(t, fieldErrs, tt.field)
+		})
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListGoals(t *testing.T) {
+	service, mock := setupGoalService(t)
+
+	mock.ExpectQuery("FROM goals").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows(goalRowColumns).
+			AddRow(goalRow(TypeWeight, GoalActive, 90.0, 84.0)...).
+			AddRow(goalRow(TypeCaloriesAdherence, GoalActive, nil, 90.0)...).
+			AddRow(goalRow(TypeBodyFat, GoalCompleted, 22.0, 18.0)...))
+	mock.ExpectQuery("SELECT weight FROM daily_metrics").
+		WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(84.0))
+	mock.ExpectQuery("UPDATE goals").
+		WithArgs(testGoalID).
+		WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+	mock.ExpectQuery("FROM daily_metrics").
+		WithArgs(int64(5), "2026-11-05", AdherenceWindowDays).
+		WillReturnRows(sqlmock.NewRows([]string{"round"}).AddRow(57.1))
+	mock.ExpectExec("UPDATE goals SET start_value").
+		WithArgs(testGoalID, 57.1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	goals, err := service.ListGoals(context.Background(), 5)
+
+	require.NoError(t, err)
+	require.Len(t, goals, 3)
+
+	// Reached the target: completed on the way
+	assert.Equal(t, GoalCompleted, goals[0].Status)
+	assert.NotNil(t, goals[0].CompletedAt)
+	assert.True(t, goals[0].Progress.Completed)
+
+	// First data point since the goal was set becomes its start
+	assert.Equal(t, 57.1, *goals[1].StartValue)
+	assert.Zero(t, goals[1].Progress.Percent)
+	assert.False(t, goals[1].Progress.OnTrack)
+
+	assert.True(t, goals[2].Progress.Completed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateGoal(t *testing.T) {
+	t.Run("abandon", func(t *testing.T) {
+		service, mock := setupGoalService(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM goals WHERE id = \\$1 AND user_id = \\$2 FOR UPDATE").
+			WithArgs(testGoalID, int64(5)).
+			WillReturnRows(sqlmock.NewRows(goalRowColumns).AddRow(goalRow(TypeWeight, GoalActive, 90.0, 84.0)...))
+		mock.ExpectQuery("UPDATE goals").
+			WithArgs(testGoalID, 84.0, "2026-12-10", GoalAbandoned).
+			WillReturnRows(sqlmock.NewRows(goalRowColumns).AddRow(goalRow(TypeWeight, GoalAbandoned, 90.0, 84.0)...))
+		mock.ExpectCommit()
+
+		status := GoalAbandoned
+		goal, err := service.UpdateGoal(context.Background(), 5, testGoalID, &UpdateGoalRequest{Status: &status})
+
+		require.NoError(t, err)
+		assert.Equal(t, GoalAbandoned, goal.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not active", func(t *testing.T) {
+		service, mock := setupGoalService(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM goals").
+			WillReturnRows(sqlmock.NewRows(goalRowColumns).AddRow(goalRow(TypeWeight, GoalCompleted, 90.0, 84.0)...))
+		mock.ExpectRollback()
+
+		target := 80.0
+		_, err := service.UpdateGoal(context.Background(), 5, testGoalID, &UpdateGoalRequest{TargetValue: &target})
+
+		assert.ErrorIs(t, err, ErrGoalNotActive)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other user's goal", func(t *testing.T) {
+		service, mock := setupGoalService(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FROM goals").
+			WithArgs(testGoalID, int64(6)).
+			WillReturnRows(sqlmock.NewRows(goalRowColumns))
+		mock.ExpectRollback()
+
+		target := 80.0
+		_, err := service.UpdateGoal(context.Background(), 6, testGoalID, &UpdateGoalRequest{TargetValue: &target})
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, _ := setupGoalService(t)
+
+		_, err := service.UpdateGoal(context.Background(), 5, "not-a-uuid", &UpdateGoalRequest{})
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
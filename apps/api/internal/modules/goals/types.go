@@ -0,0 +1,137 @@
+package goals
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// WeightChangeThreshold is the relative weight change since the targets
+	// were set that makes them stale (3%)
+	WeightChangeThreshold = 0.03
+	// DetectionInterval is how often stale targets are looked for
+	DetectionInterval = 7 * 24 * time.Hour
+	// ResolvedCooldown keeps an accepted or dismissed plan from being
+	// suggested again right away
+	ResolvedCooldown = 7 * 24 * time.Hour
+)
+
+// Suggestion statuses
+const (
+	StatusPending   = "pending"
+	StatusAccepted  = "accepted"
+	StatusDismissed = "dismissed"
+)
+
+var (
+	ErrNotPending   = errors.New("suggestion is not pending")
+	ErrPlanInactive = errors.New("the plan the suggestion was made for is no longer active")
+)
+
+// Macros is a set of daily nutrition targets. Fat and carbs are optional in
+// curator plans.
+type Macros struct {
+	Calories int  `json:"calories"`
+	Protein  int  `json:"protein"`
+	Fat      *int `json:"fat"`
+	Carbs    *int `json:"carbs"`
+}
+
+// Suggestion is a proposed change of the user's plan targets. It never takes
+// effect until the user or their curator accepts it.
+type Suggestion struct {
+	ID             string     `json:"id"`
+	UserID         int64      `json:"user_id"`
+	PlanID         string     `json:"plan_id"`
+	CuratorID      *int64     `json:"curator_id,omitempty"`
+	Status         string     `json:"status"`
+	PreviousWeight float64    `json:"previous_weight"`
+	CurrentWeight  float64    `json:"current_weight"`
+	Current        Macros     `json:"current"`
+	Suggested      Macros     `json:"suggested"`
+	Reason         string     `json:"reason"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy     *int64     `json:"resolved_by,omitempty"`
+}
+
+// candidate is an active plan whose user's weight moved past the threshold
+type candidate struct {
+	PlanID         string
+	UserID         int64
+	CuratorID      *int64
+	Current        Macros
+	PreviousWeight float64
+	CurrentWeight  float64
+}
+
+// Goal types
+const (
+	TypeWeight            = "weight"
+	TypeBodyFat           = "body_fat"
+	TypeCaloriesAdherence = "calories_adherence"
+)
+
+// Goal statuses
+const (
+	GoalActive    = "active"
+	GoalCompleted = "completed"
+	GoalAbandoned = "abandoned"
+)
+
+const (
+	// AdherenceWindowDays is the period calories adherence is measured over:
+	// the share of these days that have calories logged
+	AdherenceWindowDays = 7
+	// MaxGoalHorizonYears caps how far in the future a target date may be
+	MaxGoalHorizonYears = 2
+)
+
+var (
+	ErrActiveGoalExists = errors.New("an active goal of this type already exists")
+	ErrGoalNotActive    = errors.New("goal is not active")
+)
+
+// Goal is a user's target for one metric. StartValue is the metric when the
+// goal was set and is nil if there was no data then.
+type Goal struct {
+	ID          string     `json:"id"`
+	UserID      int64      `json:"user_id"`
+	Type        string     `json:"type"`
+	Status      string     `json:"status"`
+	StartValue  *float64   `json:"start_value"`
+	TargetValue float64    `json:"target_value"`
+	StartDate   string     `json:"start_date"`
+	TargetDate  string     `json:"target_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	AbandonedAt *time.Time `json:"abandoned_at,omitempty"`
+	Progress    Progress   `json:"progress"`
+}
+
+// Progress is how far a goal has come. Percent is the share of the way from
+// the start value to the target; ExpectedPercent is where a straight line
+// from the start date to the target date would be today.
+type Progress struct {
+	CurrentValue    *float64 `json:"current_value"`
+	Percent         float64  `json:"percent"`
+	ExpectedPercent float64  `json:"expected_percent"`
+	OnTrack         bool     `json:"on_track"`
+	Completed       bool     `json:"completed"`
+}
+
+// CreateGoalRequest is the body of POST /api/v1/goals
+type CreateGoalRequest struct {
+	Type        string   `json:"type" binding:"required,oneof=weight body_fat calories_adherence"`
+	TargetValue *float64 `json:"target_value" binding:"required"`
+	TargetDate  string   `json:"target_date" binding:"required"`
+}
+
+// UpdateGoalRequest is the body of PATCH /api/v1/goals/:id. Omitted fields
+// are left unchanged; status can only be set to abandoned.
+type UpdateGoalRequest struct {
+	TargetValue *float64 `json:"target_value"`
+	TargetDate  *string  `json:"target_date"`
+	Status      *string  `json:"status" binding:"omitempty,oneof=abandoned"`
+}
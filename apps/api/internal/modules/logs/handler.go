@@ -1,26 +1,53 @@
 package logs
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
+	"unicode/utf8"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// MaxEntries is the most log entries accepted in one request
+	MaxEntries = 100
+	// MaxMessageLength is the longest accepted message, in characters
+	MaxMessageLength = 2000
+	// MaxStackLength is where longer stack traces are cut off, in characters
+	MaxStackLength = 4000
+	// MaxFieldLength is where longer URLs, user agents, session IDs and error
+	// names and messages are cut off, in characters
+	MaxFieldLength = 1000
+	// MaxContextSize is the largest context kept, in bytes of JSON; a larger
+	// one is dropped
+	MaxContextSize = 4096
+	// MaxBodySize is the largest accepted request body, in bytes. It fits
+	// MaxEntries entries with every ASCII field at its limit.
+	MaxBodySize = 2 << 20
+)
+
 // Handler handles log requests from frontend
 type Handler struct {
-	cfg *config.Config
-	log *logger.Logger
+	cfg     *config.Config
+	log     *logger.Logger
+	service *Service
 }
 
 // NewHandler creates a new logs handler
-func NewHandler(cfg *config.Config, log *logger.Logger) *Handler {
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB) *Handler {
 	return &Handler{
-		cfg: cfg,
-		log: log,
+		cfg:     cfg,
+		log:     log,
+		service: NewService(db, log),
 	}
 }
 
@@ -50,89 +77,176 @@ type ReceiveLogsRequest struct {
 	Logs []LogEntry `json:"logs" binding:"required"`
 }
 
-// ReceiveLogs receives and processes logs from frontend
+// RejectedDetails describes a batch refused for oversized entries
+type RejectedDetails struct {
+	Fields   map[string]string `json:"fields,omitempty"`
+	Rejected int               `json:"rejected"`
+}
+
+// ReceiveLogs receives and processes logs from frontend. The endpoint is
+// public: bodies over MaxBodySize and batches over MaxEntries or with
+// messages over MaxMessageLength are refused as a whole, other fields are
+// cut to their limits, and userId is kept only when it is the caller's own.
 func (h *Handler) ReceiveLogs(c *gin.Context) {
+	// Unknown fields are tolerated here: the frontend logger serializes
+	// arbitrary Error objects, and a rejected batch would lose the logs
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxBodySize)
 	var req ReceiveLogsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("Размер запроса не должен превышать %d МБ", MaxBodySize>>20))
+			return
+		}
+		validation.Respond(c, err)
 		return
 	}
 
-	// Get request metadata
-	clientIP := c.ClientIP()
-	requestID, _ := c.Get("request_id")
+	if len(req.Logs) > MaxEntries {
+		response.ErrorCode(c, http.StatusBadRequest, response.CodeValidationFailed,
+			fmt.Sprintf("Слишком много записей: не больше %d за запрос", MaxEntries),
+			RejectedDetails{
+				Fields:   map[string]string{"logs": fmt.Sprintf("Количество элементов должно быть не больше %d", MaxEntries)},
+				Rejected: len(req.Logs),
+			})
+		return
+	}
 
-	// Process each log entry
-	for _, entry := range req.Logs {
-		// Enrich log with server-side information
-		fields := map[string]interface{}{
-			"source":     "frontend",
-			"client_ip":  clientIP,
-			"session_id": entry.SessionID,
-			"url":        entry.URL,
-			"user_agent": entry.UserAgent,
+	fields := map[string]string{}
+	for i, entry := range req.Logs {
+		if utf8.RuneCountInString(entry.Message) > MaxMessageLength {
+			fields[fmt.Sprintf("logs[%d].message", i)] = fmt.Sprintf("Длина должна быть не больше %d", MaxMessageLength)
 		}
+	}
+	if len(fields) > 0 {
+		response.ErrorCode(c, http.StatusBadRequest, response.CodeValidationFailed,
+			fmt.Sprintf("Слишком длинные сообщения: отклонено %d из %d", len(fields), len(req.Logs)),
+			RejectedDetails{Fields: fields, Rejected: len(fields)})
+		return
+	}
 
-		if requestID != nil {
-			fields["request_id"] = requestID
+	userID, authenticated := c.Get("user_id")
+	for i := range req.Logs {
+		entry := &req.Logs[i]
+		limitFields(entry)
+		// An anonymous caller can claim any userId, so it is only trusted
+		// when it matches the token
+		if !authenticated || entry.UserID != strconv.FormatInt(userID.(int64), 10) {
+			entry.UserID = ""
 		}
+	}
 
-		if entry.UserID != "" {
-			fields["user_id"] = entry.UserID
+	// Every entry is stored; only errors are also forwarded to the server
+	// log, where the rest would just be noise
+	for _, entry := range req.Logs {
+		if entry.Level == "error" || entry.Level == "fatal" {
+			h.forwardError(c, entry)
 		}
+	}
 
-		// Add context fields
-		for k, v := range entry.Context {
-			fields[k] = v
-		}
+	if err := h.service.SaveLogs(c.Request.Context(), req.Logs); err != nil {
+		h.log.Errorw("Не удалось сохранить логи", "error", err, "count", len(req.Logs))
+		response.ErrorCode(c, http.StatusInternalServerError, response.CodeInternal, "Не удалось сохранить логи", nil)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"received": len(req.Logs),
+	})
+}
 
-		// Parse timestamp
-		timestamp, err := time.Parse(time.RFC3339, entry.Timestamp)
-		if err == nil {
-			fields["client_timestamp"] = timestamp
+// limitFields cuts the entry's free-form fields to their limits and drops a
+// context over MaxContextSize
+func limitFields(entry *LogEntry) {
+	entry.Stack = truncate(entry.Stack, MaxStackLength)
+	entry.URL = truncate(entry.URL, MaxFieldLength)
+	entry.UserAgent = truncate(entry.UserAgent, MaxFieldLength)
+	entry.SessionID = truncate(entry.SessionID, MaxFieldLength)
+	entry.RequestID = truncate(entry.RequestID, MaxFieldLength)
+	entry.Timestamp = truncate(entry.Timestamp, MaxFieldLength)
+	if entry.Error != nil {
+		entry.Error.Name = truncate(entry.Error.Name, MaxFieldLength)
+		entry.Error.Message = truncate(entry.Error.Message, MaxFieldLength)
+	}
+	if len(entry.Context) > 0 {
+		if raw, err := json.Marshal(entry.Context); err != nil || len(raw) > MaxContextSize {
+			entry.Context = map[string]interface{}{"context_dropped_bytes": len(raw)}
 		}
+	}
+}
 
-		// Create logger with fields
-		logWithFields := h.log.WithFields(fields)
+// truncate cuts s to at most max characters
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
 
-		// Add error if present
-		if entry.Error != nil {
-			logWithFields = logWithFields.WithField("error_message", entry.Error.Message)
-			if entry.Stack != "" {
-				logWithFields = logWithFields.WithField("stack_trace", entry.Stack)
-			}
-		}
+// serverLogFields are the forwarded error fields set by the server, which a
+// client context cannot override
+var serverLogFields = map[string]bool{
+	"source":           true,
+	"level":            true,
+	"client_ip":        true,
+	"session_id":       true,
+	"url":              true,
+	"user_agent":       true,
+	"request_id":       true,
+	"user_id":          true,
+	"client_timestamp": true,
+}
 
-		// Log based on level
-		message := entry.Message
-		switch entry.Level {
-		case "debug":
-			logWithFields.Debug(message)
-		case "info":
-			logWithFields.Info(message)
-		case "warn":
-			logWithFields.Warn(message)
-		case "error":
-			logWithFields.Error(message)
-		case "fatal":
-			logWithFields.Error(message) // Don't actually fatal on frontend errors
-		default:
-			logWithFields.Info(message)
+// forwardError writes a frontend error to the server log, enriched with
+// server-side request information
+func (h *Handler) forwardError(c *gin.Context, entry LogEntry) {
+	fields := make(map[string]interface{}, len(entry.Context)+len(serverLogFields))
+	for k, v := range entry.Context {
+		if !serverLogFields[k] {
+			fields[k] = v
 		}
 	}
 
-	response.Success(c, http.StatusOK, gin.H{
-		"received": len(req.Logs),
-	})
+	fields["source"] = "frontend"
+	fields["level"] = entry.Level
+	fields["client_ip"] = c.ClientIP()
+	fields["session_id"] = entry.SessionID
+	fields["url"] = entry.URL
+	fields["user_agent"] = entry.UserAgent
+
+	if requestID, ok := c.Get("request_id"); ok {
+		fields["request_id"] = requestID
+	}
+
+	if entry.UserID != "" {
+		fields["user_id"] = entry.UserID
+	}
+
+	// Parse timestamp
+	if timestamp, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+		fields["client_timestamp"] = timestamp
+	}
+
+	logWithFields := h.log.WithFields(fields)
+	if entry.Error != nil {
+		logWithFields = logWithFields.WithField("error_message", entry.Error.Message)
+	}
+	if entry.Stack != "" {
+		logWithFields = logWithFields.WithField("stack_trace", entry.Stack)
+	}
+
+	// Don't actually fatal on frontend errors
+	logWithFields.Error(entry.Message)
 }
 
-// GetLogStats returns logging statistics (for monitoring)
+// GetLogStats returns counts by level, the most frequent errors and active
+// sessions over the last 24 hours and 7 days
 func (h *Handler) GetLogStats(c *gin.Context) {
-	// This would typically query a database or metrics system
-	// For now, return a placeholder
-	stats := gin.H{
-		"status": "operational",
-		"info":   "Log collection is active",
+	stats, err := h.service.GetStats(c.Request.Context())
+	if err != nil {
+		h.log.Errorw("Не удалось получить статистику логов", "error", err)
+		response.ErrorCode(c, http.StatusInternalServerError, response.CodeInternal, "Не удалось получить статистику логов", nil)
+		return
 	}
 
 	response.Success(c, http.StatusOK, stats)
@@ -5,31 +5,46 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func setupTestHandler() (*Handler, *gin.Engine) {
+func setupTestHandler(t *testing.T) (*Handler, *gin.Engine, sqlmock.Sqlmock) {
+	t.Helper()
 	gin.SetMode(gin.TestMode)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
 	cfg := &config.Config{
 		Env:  "test",
 		Port: 8080,
 	}
 	log := logger.New()
-	handler := NewHandler(cfg, log)
+	handler := NewHandler(cfg, log, &database.DB{DB: mockDB})
 	router := gin.New()
-	return handler, router
+	return handler, router, mock
 }
 
 func TestReceiveLogs(t *testing.T) {
-	handler, router := setupTestHandler()
+	handler, router, mock := setupTestHandler(t)
 	router.POST("/logs", handler.ReceiveLogs)
 
 	t.Run("successful log reception", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").
+			WithArgs("info", "Test log message", nil, "session456", "https://example.com", "Mozilla/5.0",
+				nil, nil, nil, time.Date(2024, 1, 24, 12, 0, 0, 0, time.UTC)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
 		logs := ReceiveLogsRequest{
 			Logs: []LogEntry{
 				{
@@ -61,6 +76,8 @@ func TestReceiveLogs(t *testing.T) {
 	})
 
 	t.Run("multiple logs", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs (.+) VALUES \\(\\$1, (.+)\\), \\((.+)\\), \\((.+)\\$30\\)$").
+			WillReturnResult(sqlmock.NewResult(0, 3))
 		logs := ReceiveLogsRequest{
 			Logs: []LogEntry{
 				{
@@ -125,6 +142,7 @@ func TestReceiveLogs(t *testing.T) {
 	})
 
 	t.Run("log with all levels", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").WillReturnResult(sqlmock.NewResult(0, 5))
 		levels := []string{"debug", "info", "warn", "error", "fatal"}
 		var logEntries []LogEntry
 
@@ -146,13 +164,204 @@ func TestReceiveLogs(t *testing.T) {
 
 		assert.Equal(t, http.StatusOK, w.Code)
 	})
+
+	t.Run("storage failure", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").WillReturnError(assert.AnError)
+
+		body, _ := json.Marshal(ReceiveLogsRequest{Logs: []LogEntry{{Level: "warn", Message: "Slow render"}}})
+		req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "INTERNAL")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// postLogs sends entries to the /logs route and decodes the response
+func postLogs(router *gin.Engine, entries []LogEntry) (int, map[string]interface{}) {
+	body, _ := json.Marshal(ReceiveLogsRequest{Logs: entries})
+	req := httptest.NewRequest(http.MethodPost, "/logs", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func repeatEntries(n int) []LogEntry {
+	entries := make([]LogEntry, n)
+	for i := range entries {
+		entries[i] = LogEntry{Level: "info", Message: "m"}
+	}
+	return entries
+}
+
+func TestReceiveLogs_Limits(t *testing.T) {
+	handler, router, mock := setupTestHandler(t)
+	router.POST("/logs", handler.ReceiveLogs)
+
+	t.Run("accepts MaxEntries entries", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").WillReturnResult(sqlmock.NewResult(0, MaxEntries))
+
+		code, _ := postLogs(router, repeatEntries(MaxEntries))
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("rejects MaxEntries+1 entries", func(t *testing.T) {
+		code, resp := postLogs(router, repeatEntries(MaxEntries+1))
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.Equal(t, float64(MaxEntries+1), resp["details"].(map[string]interface{})["rejected"])
+	})
+
+	t.Run("accepts message of MaxMessageLength characters", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").
+			WithArgs("info", strings.Repeat("ж", MaxMessageLength), nil, nil, nil, nil, nil, nil, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		code, _ := postLogs(router, []LogEntry{{Level: "info", Message: strings.Repeat("ж", MaxMessageLength)}})
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("rejects batch with longer messages and counts them", func(t *testing.T) {
+		entries := repeatEntries(5)
+		entries[1].Message = strings.Repeat("a", MaxMessageLength+1)
+		entries[3].Message = strings.Repeat("ж", MaxMessageLength+1)
+
+		code, resp := postLogs(router, entries)
+
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, resp["message"], "отклонено 2 из 5")
+		details := resp["details"].(map[string]interface{})
+		assert.Equal(t, float64(2), details["rejected"])
+		assert.Equal(t, map[string]interface{}{
+			"logs[1].message": "Длина должна быть не больше 2000",
+			"logs[3].message": "Длина должна быть не больше 2000",
+		}, details["fields"])
+	})
+
+	t.Run("truncates long stack traces", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO frontend_logs").
+			WithArgs("warn", "Slow render", nil, nil, nil, nil, nil, nil, strings.Repeat("s", MaxStackLength), nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		code, _ := postLogs(router, []LogEntry{{Level: "warn", Message: "Slow render", Stack: strings.Repeat("s", MaxStackLength+500)}})
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("cuts other fields at MaxFieldLength", func(t *testing.T) {
+		atLimit := strings.Repeat("u", MaxFieldLength)
+		mock.ExpectExec("INSERT INTO frontend_logs").
+			WithArgs("error", "m", nil, atLimit, atLimit, atLimit, atLimit, atLimit, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		code, _ := postLogs(router, []LogEntry{{
+			Level:     "error",
+			Message:   "m",
+			SessionID: atLimit,
+			URL:       atLimit + "u",
+			UserAgent: atLimit + strings.Repeat("u", 500),
+			Error:     &ErrorInfo{Name: atLimit + "u", Message: atLimit},
+		}})
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("accepts a full batch with every field at its limit", func(t *testing.T) {
+		field := strings.Repeat("f", MaxFieldLength)
+		entries := make([]LogEntry, MaxEntries)
+		for i := range entries {
+			entries[i] = LogEntry{
+				Level:     "warn",
+				Message:   strings.Repeat("m", MaxMessageLength),
+				Stack:     strings.Repeat("s", MaxStackLength),
+				URL:       field,
+				UserAgent: field,
+				SessionID: field,
+				Error:     &ErrorInfo{Name: field, Message: field},
+				Context:   map[string]interface{}{"k": strings.Repeat("c", MaxContextSize-8)},
+			}
+		}
+		for range (MaxEntries + insertBatchSize - 1) / insertBatchSize {
+			mock.ExpectExec("INSERT INTO frontend_logs").WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+
+		code, _ := postLogs(router, entries)
+
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("rejects a body over MaxBodySize", func(t *testing.T) {
+		code, _ := postLogs(router, []LogEntry{{Level: "info", Message: "m", Stack: strings.Repeat("s", MaxBodySize)}})
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, code)
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLimitFields_Context(t *testing.T) {
+	// {"k":"..."} is 8 bytes of JSON around the value
+	atLimit := map[string]interface{}{"k": strings.Repeat("c", MaxContextSize-8)}
+	entry := LogEntry{Context: atLimit}
+	limitFields(&entry)
+	assert.Equal(t, atLimit, entry.Context, "a context of MaxContextSize bytes is kept")
+
+	entry = LogEntry{Context: map[string]interface{}{"k": strings.Repeat("c", MaxContextSize-7)}}
+	limitFields(&entry)
+	assert.Equal(t, map[string]interface{}{"context_dropped_bytes": MaxContextSize + 1}, entry.Context)
+}
+
+func TestReceiveLogs_UserID(t *testing.T) {
+	tests := []struct {
+		name     string
+		authUser int64
+		userID   string
+		want     interface{}
+	}{
+		{"anonymous caller", 0, "42", nil},
+		{"own user id", 42, "42", "42"},
+		{"someone else's user id", 42, "7", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, router, mock := setupTestHandler(t)
+			router.POST("/logs", func(c *gin.Context) {
+				if tt.authUser != 0 {
+					c.Set("user_id", tt.authUser)
+				}
+				handler.ReceiveLogs(c)
+			})
+			mock.ExpectExec("INSERT INTO frontend_logs").
+				WithArgs("info", "m", tt.want, nil, nil, nil, nil, nil, nil, nil).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			code, _ := postLogs(router, []LogEntry{{Level: "info", Message: "m", UserID: tt.userID}})
+
+			assert.Equal(t, http.StatusOK, code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
 }
 
 func TestGetLogStats(t *testing.T) {
-	handler, router := setupTestHandler()
+	handler, router, mock := setupTestHandler(t)
 	router.GET("/logs/stats", handler.GetLogStats)
 
 	t.Run("returns stats", func(t *testing.T) {
+		expectStats(mock)
 		req := httptest.NewRequest(http.MethodGet, "/logs/stats", nil)
 		w := httptest.NewRecorder()
 
@@ -161,6 +370,64 @@ func TestGetLogStats(t *testing.T) {
 		assert.Equal(t, http.StatusOK, w.Code)
 		var response map[string]interface{}
 		_ = json.Unmarshal(w.Body.Bytes(), &response)
-		assert.NotNil(t, response["data"])
+		data := response["data"].(map[string]interface{})
+		assert.Equal(t, float64(4), data["last_24h"].(map[string]interface{})["error"])
+		assert.Equal(t, float64(0), data["last_24h"].(map[string]interface{})["fatal"])
+		assert.Len(t, data["top_errors"], 2)
+		assert.Equal(t, float64(12), data["sessions_7d"])
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		mock.ExpectQuery("SELECT level").WillReturnError(assert.AnError)
+		req := httptest.NewRequest(http.MethodGet, "/logs/stats", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceiveLogs_ForwardedErrorKeepsServerFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	var buf bytes.Buffer
+	log := logger.New(logger.WithOutput(&buf), logger.WithEncoding(logger.EncodingJSON))
+	handler := NewHandler(&config.Config{Env: "test"}, log, &database.DB{DB: mockDB})
+	router := gin.New()
+	router.POST("/logs", func(c *gin.Context) {
+		c.Set("request_id", "req-1")
+		handler.ReceiveLogs(c)
+	})
+	mock.ExpectExec("INSERT INTO frontend_logs").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	code, _ := postLogs(router, []LogEntry{{
+		Level:   "error",
+		Message: "boom",
+		Context: map[string]interface{}{
+			"user_id":    "42",
+			"client_ip":  "10.0.0.1",
+			"request_id": "spoofed",
+			"source":     "backend",
+			"component":  "diary",
+		},
+	}})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// The forwarded error is the first line; saving the batch logs more
+	var line map[string]interface{}
+	require.NoError(t, json.NewDecoder(&buf).Decode(&line))
+	assert.Equal(t, "boom", line["msg"])
+	assert.NotContains(t, line, "user_id")
+	assert.Equal(t, "192.0.2.1", line["client_ip"])
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "frontend", line["source"])
+	assert.Equal(t, "diary", line["component"])
 }
@@ -0,0 +1,19 @@
+package logs
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+)
+
+type receiveLogsResponse struct {
+	Received int `json:"received"`
+}
+
+// Endpoints describes the /logs routes for the OpenAPI document
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodPost, Path: "", Summary: "Приём логов фронтенда", Auth: openapi.OptionalBearer, Request: ReceiveLogsRequest{}, Response: receiveLogsResponse{}},
+		{Method: http.MethodGet, Path: "/stats", Summary: "Статистика логов (super_admin)", Auth: openapi.Bearer, Response: Stats{}},
+	}
+}
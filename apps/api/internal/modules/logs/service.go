@@ -0,0 +1,220 @@
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// Retention is how long received frontend logs are kept
+	Retention = 30 * 24 * time.Hour
+	// TopErrorsLimit caps the most frequent error messages in Stats
+	TopErrorsLimit = 10
+
+	// insertBatchSize keeps one INSERT well under the bind parameter limit
+	insertBatchSize  = 100
+	cleanupBatchSize = 5000
+)
+
+// Levels accepted from the frontend; anything else is stored as info
+var levels = []string{"debug", "info", "warn", "error", "fatal"}
+
+// Service stores frontend logs and aggregates statistics over them
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new logs service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{
+		db:  db,
+		log: log,
+	}
+}
+
+// LevelCounts maps a log level to the number of entries
+type LevelCounts map[string]int64
+
+// ErrorCount is one of the most frequent error messages
+type ErrorCount struct {
+	Message  string    `json:"message"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
+// Stats summarizes received frontend logs
+type Stats struct {
+	Last24h       LevelCounts  `json:"last_24h"`
+	Last7d        LevelCounts  `json:"last_7d"`
+	TopErrors     []ErrorCount `json:"top_errors"`
+	Sessions24h   int64        `json:"sessions_24h"`
+	Sessions7d    int64        `json:"sessions_7d"`
+	RetentionDays int          `json:"retention_days"`
+}
+
+// normalizeLevel maps unknown levels to info, matching how they are logged
+func normalizeLevel(level string) string {
+	for _, l := range levels {
+		if level == l {
+			return l
+		}
+	}
+	return "info"
+}
+
+// nullable stores optional client fields as NULL rather than empty strings
+func nullable(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// SaveLogs stores entries in batches of insertBatchSize rows
+func (s *Service) SaveLogs(ctx context.Context, entries []LogEntry) error {
+	const columns = 10
+
+	for start := 0; start < len(entries); start += insertBatchSize {
+		batch := entries[start:min(start+insertBatchSize, len(entries))]
+		values := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*columns)
+		for i, entry := range batch {
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+				n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8, n+9, n+10)
+
+			var errorName, errorMessage string
+			if entry.Error != nil {
+				errorName, errorMessage = entry.Error.Name, entry.Error.Message
+			}
+			var clientTimestamp any
+			if ts, err := time.Parse(time.RFC3339, entry.Timestamp); err == nil {
+				clientTimestamp = ts
+			}
+			args = append(args, normalizeLevel(entry.Level), entry.Message,
+				nullable(entry.UserID), nullable(entry.SessionID), nullable(entry.URL), nullable(entry.UserAgent),
+				nullable(errorName), nullable(errorMessage), nullable(entry.Stack), clientTimestamp)
+		}
+
+		startTime := time.Now()
+		query := `INSERT INTO frontend_logs (level, message, user_id, session_id, url, user_agent,
+			error_name, error_message, stack, client_timestamp) VALUES ` + strings.Join(values, ", ")
+		_, err := s.db.ExecContext(ctx, query, args...)
+		s.log.LogDatabaseQuery("INSERT INTO frontend_logs", time.Since(startTime), err, map[string]interface{}{
+			"rows": len(batch),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save frontend logs: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetStats aggregates logs received in the last 7 days
+func (s *Service) GetStats(ctx context.Context) (*Stats, error) {
+	stats := &Stats{
+		Last24h:       LevelCounts{},
+		Last7d:        LevelCounts{},
+		TopErrors:     make([]ErrorCount, 0, TopErrorsLimit),
+		RetentionDays: int(Retention / (24 * time.Hour)),
+	}
+	for _, l := range levels {
+		stats.Last24h[l] = 0
+		stats.Last7d[l] = 0
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT level,
+			COUNT(*) FILTER (WHERE received_at >= NOW() - INTERVAL '24 hours'),
+			COUNT(*)
+		FROM frontend_logs
+		WHERE received_at >= NOW() - INTERVAL '7 days'
+		GROUP BY level`
+	rows, err := s.db.QueryContext(ctx, query)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count frontend logs: %w", err)
+	}
+	for rows.Next() {
+		var level string
+		var day, week int64
+		if err := rows.Scan(&level, &day, &week); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan log counts: %w", err)
+		}
+		stats.Last24h[level] = day
+		stats.Last7d[level] = week
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating log counts: %w", err)
+	}
+
+	startTime = time.Now()
+	query = `
+		SELECT message, COUNT(*), MAX(received_at)
+		FROM frontend_logs
+		WHERE level IN ('error', 'fatal') AND received_at >= NOW() - INTERVAL '7 days'
+		GROUP BY message
+		ORDER BY COUNT(*) DESC, MAX(received_at) DESC
+		LIMIT $1`
+	rows, err = s.db.QueryContext(ctx, query, TopErrorsLimit)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top errors: %w", err)
+	}
+	for rows.Next() {
+		var e ErrorCount
+		if err := rows.Scan(&e.Message, &e.Count, &e.LastSeen); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan top error: %w", err)
+		}
+		stats.TopErrors = append(stats.TopErrors, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top errors: %w", err)
+	}
+
+	startTime = time.Now()
+	query = `
+		SELECT
+			COUNT(DISTINCT session_id) FILTER (WHERE received_at >= NOW() - INTERVAL '24 hours'),
+			COUNT(DISTINCT session_id)
+		FROM frontend_logs
+		WHERE received_at >= NOW() - INTERVAL '7 days'`
+	err = s.db.QueryRowContext(ctx, query).Scan(&stats.Sessions24h, &stats.Sessions7d)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	return stats, nil
+}
+
+// Cleanup deletes up to cleanupBatchSize logs older than Retention and
+// returns how many it removed
+func (s *Service) Cleanup(ctx context.Context) (int64, error) {
+	startTime := time.Now()
+	query := `
+		DELETE FROM frontend_logs
+		WHERE id IN (
+			SELECT id FROM frontend_logs
+			WHERE received_at < NOW() - $1 * INTERVAL '1 second'
+			LIMIT $2
+		)`
+
+	result, err := s.db.ExecContext(ctx, query, int(Retention.Seconds()), cleanupBatchSize)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old frontend logs: %w", err)
+	}
+	return result.RowsAffected()
+}
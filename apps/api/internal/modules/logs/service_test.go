@@ -0,0 +1,111 @@
+package logs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+	return NewService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+// expectStats queues the three aggregation queries of GetStats
+func expectStats(mock sqlmock.Sqlmock) {
+	lastSeen := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT level,\\s+COUNT\\(\\*\\) FILTER (.+) FROM frontend_logs (.+) GROUP BY level").
+		WillReturnRows(sqlmock.NewRows([]string{"level", "day", "week"}).
+			AddRow("info", 120, 900).
+			AddRow("error", 4, 31))
+	mock.ExpectQuery("SELECT message, COUNT\\(\\*\\), MAX\\(received_at\\)\\s+FROM frontend_logs\\s+WHERE level IN \\('error', 'fatal'\\)").
+		WithArgs(TopErrorsLimit).
+		WillReturnRows(sqlmock.NewRows([]string{"message", "count", "last_seen"}).
+			AddRow("Failed to load dashboard", 20, lastSeen).
+			AddRow("ChunkLoadError", 11, lastSeen))
+	mock.ExpectQuery("SELECT\\s+COUNT\\(DISTINCT session_id\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"day", "week"}).AddRow(3, 12))
+}
+
+func TestService_SaveLogs(t *testing.T) {
+	t.Run("stores error details and normalizes level", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectExec("INSERT INTO frontend_logs").
+			WithArgs("info", "Custom level", nil, "session-1", nil, nil,
+				"TypeError", "x is undefined", "at render", nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := service.SaveLogs(context.Background(), []LogEntry{{
+			Level:     "trace",
+			Message:   "Custom level",
+			Timestamp: "not a timestamp",
+			SessionID: "session-1",
+			Error:     &ErrorInfo{Name: "TypeError", Message: "x is undefined"},
+			Stack:     "at render",
+		}})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("splits large payloads into batches", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		entries := make([]LogEntry, insertBatchSize+1)
+		for i := range entries {
+			entries[i] = LogEntry{Level: "info", Message: "m"}
+		}
+		mock.ExpectExec("INSERT INTO frontend_logs").WillReturnResult(sqlmock.NewResult(0, insertBatchSize))
+		mock.ExpectExec("INSERT INTO frontend_logs (.+) VALUES \\(\\$1, (.+)\\$10\\)$").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, service.SaveLogs(context.Background(), entries))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing to store", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		require.NoError(t, service.SaveLogs(context.Background(), nil))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_GetStats(t *testing.T) {
+	service, mock := setupTestService(t)
+	expectStats(mock)
+
+	stats, err := service.GetStats(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, LevelCounts{"debug": 0, "info": 120, "warn": 0, "error": 4, "fatal": 0}, stats.Last24h)
+	assert.Equal(t, int64(31), stats.Last7d["error"])
+	require.Len(t, stats.TopErrors, 2)
+	assert.Equal(t, "Failed to load dashboard", stats.TopErrors[0].Message)
+	assert.Equal(t, int64(20), stats.TopErrors[0].Count)
+	assert.Equal(t, int64(3), stats.Sessions24h)
+	assert.Equal(t, int64(12), stats.Sessions7d)
+	assert.Equal(t, 30, stats.RetentionDays)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Cleanup(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectExec("DELETE FROM frontend_logs\\s+WHERE id IN \\(\\s+SELECT id FROM frontend_logs\\s+WHERE received_at < NOW\\(\\) - \\$1 \\* INTERVAL '1 second'\\s+LIMIT \\$2").
+		WithArgs(int(Retention.Seconds()), cleanupBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, 17))
+
+	removed, err := service.Cleanup(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
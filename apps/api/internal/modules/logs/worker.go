@@ -0,0 +1,33 @@
+package logs
+
+import (
+	"context"
+	"time"
+)
+
+// RunCleanup removes logs older than Retention every hour until ctx is cancelled
+func (s *Service) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	s.log.Info("Frontend log cleanup started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				removed, err := s.Cleanup(ctx)
+				if err != nil {
+					s.log.Error("Failed to clean up frontend logs", "error", err)
+					break
+				}
+				if removed < cleanupBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Frontend log cleanup stopped")
+			return
+		}
+	}
+}
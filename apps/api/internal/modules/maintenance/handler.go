@@ -0,0 +1,137 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles maintenance scheduling requests (super_admin only)
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new maintenance handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// Schedule handles POST /api/v1/admin/maintenance/schedule
+func (h *Handler) Schedule(c *gin.Context) {
+	adminID, ok := c.Get("user_id")
+	if !ok {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := adminID.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var req ScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуются starts_at и ends_at в формате RFC 3339")
+		return
+	}
+
+	w, err := h.service.Schedule(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrWindowInPast):
+			response.Error(c, http.StatusBadRequest, "Окно обслуживания должно начинаться в будущем")
+		case errors.Is(err, ErrInvalidWindow):
+			response.Error(c, http.StatusBadRequest, "Окончание окна должно быть позже начала")
+		case errors.Is(err, ErrWindowOverlaps):
+			response.Error(c, http.StatusConflict, "Окно пересекается с уже запланированным")
+		default:
+			h.log.Error("Failed to schedule maintenance", "error", err)
+			response.InternalError(c, "Не удалось запланировать обслуживание")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, w)
+}
+
+// SetMode handles POST /api/v1/admin/maintenance, switching manual
+// maintenance mode on or off
+func (h *Handler) SetMode(c *gin.Context) {
+	adminID, ok := c.Get("user_id")
+	if !ok {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := adminID.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var req ModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуется enabled, eta в формате RFC 3339")
+		return
+	}
+
+	m, err := h.service.SetMode(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrETAInPast):
+			response.Error(c, http.StatusBadRequest, "Время окончания работ должно быть в будущем")
+		case errors.Is(err, ErrMessageTooLong):
+			response.Error(c, http.StatusBadRequest, fmt.Sprintf("Сообщение не может быть длиннее %d символов", MaxMessageLength))
+		default:
+			h.log.Error("Failed to set maintenance mode", "error", err)
+			response.InternalError(c, "Не удалось изменить режим обслуживания")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, m)
+}
+
+// List handles GET /api/v1/admin/maintenance
+func (h *Handler) List(c *gin.Context) {
+	windows, err := h.service.ListUpcoming(c.Request.Context())
+	if err != nil {
+		h.log.Error("Failed to list maintenance windows", "error", err)
+		response.InternalError(c, "Не удалось загрузить окна обслуживания")
+		return
+	}
+
+	response.Success(c, http.StatusOK, windows)
+}
+
+// Cancel handles DELETE /api/v1/admin/maintenance/:id
+func (h *Handler) Cancel(c *gin.Context) {
+	windowID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор окна")
+		return
+	}
+
+	if err := h.service.Cancel(c.Request.Context(), windowID); err != nil {
+		if errors.Is(err, ErrWindowNotFound) {
+			response.NotFound(c, "Окно обслуживания не найдено")
+			return
+		}
+		h.log.Error("Failed to cancel maintenance window", "error", err, "window_id", windowID)
+		response.InternalError(c, "Не удалось отменить обслуживание")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Обслуживание отменено", nil)
+}
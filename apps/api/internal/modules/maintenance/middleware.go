@@ -0,0 +1,102 @@
+package maintenance
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Response headers announcing an upcoming window (RFC 3339, UTC)
+const (
+	HeaderWindowStart = "X-Maintenance-Window-Start"
+	HeaderWindowEnd   = "X-Maintenance-Window-End"
+)
+
+// noticeContextKey stores the localized notice for handlers that embed it
+const noticeContextKey = "maintenance_notice"
+
+// exemptPrefixes stay reachable while maintenance mode is active so that
+// health checks and metrics keep working and admins can switch it off.
+var exemptPrefixes = []string{"/health", "/metrics", "/api/v1/admin/maintenance"}
+
+// Middleware announces upcoming windows and enforces maintenance mode.
+// During the NoticePeriod before a window every response carries the
+// X-Maintenance-Window-* headers; while the window is active, or manual
+// maintenance mode is on, requests get 503.
+func Middleware(tracker *Tracker, now func() time.Time) gin.HandlerFunc {
+	if now == nil {
+		now = time.Now
+	}
+	return func(c *gin.Context) {
+		current := now()
+		if m := tracker.Mode(); m.Enabled && !isExempt(c.Request.URL.Path) {
+			c.Header("Retry-After", strconv.Itoa(retryAfter(m, current)))
+			var details gin.H
+			if m.ETA != nil {
+				details = gin.H{"eta": m.ETA.UTC()}
+			}
+			response.ErrorCode(c, http.StatusServiceUnavailable, response.CodeMaintenance,
+				ModeMessage(m, c.GetHeader("Accept-Language")), details)
+			c.Abort()
+			return
+		}
+
+		phase, w := tracker.Status(current)
+
+		switch phase {
+		case PhaseUpcoming:
+			c.Header(HeaderWindowStart, w.StartsAt.UTC().Format(time.RFC3339))
+			c.Header(HeaderWindowEnd, w.EndsAt.UTC().Format(time.RFC3339))
+			c.Set(noticeContextKey, BuildNotice(w, c.GetHeader("Accept-Language")))
+		case PhaseActive:
+			if isExempt(c.Request.URL.Path) {
+				break
+			}
+			retryAfter := int(math.Ceil(w.EndsAt.Sub(current).Seconds()))
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.Header(HeaderWindowStart, w.StartsAt.UTC().Format(time.RFC3339))
+			c.Header(HeaderWindowEnd, w.EndsAt.UTC().Format(time.RFC3339))
+			response.Error(c, http.StatusServiceUnavailable, BuildNotice(w, c.GetHeader("Accept-Language")).Message)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// NoticeFromContext returns the upcoming maintenance notice set by Middleware
+func NoticeFromContext(c *gin.Context) (*Notice, bool) {
+	v, ok := c.Get(noticeContextKey)
+	if !ok {
+		return nil, false
+	}
+	n, ok := v.(Notice)
+	if !ok {
+		return nil, false
+	}
+	return &n, true
+}
+
+// retryAfter returns the seconds until the ETA of manual maintenance mode,
+// DefaultRetryAfter when there is no ETA or it has passed
+func retryAfter(m Mode, now time.Time) int {
+	if m.ETA == nil || !m.ETA.After(now) {
+		return int(DefaultRetryAfter.Seconds())
+	}
+	return int(math.Ceil(m.ETA.Sub(now).Seconds()))
+}
+
+func isExempt(path string) bool {
+	for _, prefix := range exemptPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
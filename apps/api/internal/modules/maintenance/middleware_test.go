@@ -0,0 +1,150 @@
+package maintenance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouter(tracker *Tracker, now time.Time) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware(tracker, func() time.Time { return now }))
+	handler := func(c *gin.Context) {
+		notice, ok := NoticeFromContext(c)
+		if ok {
+			c.JSON(http.StatusOK, gin.H{"notice": notice.Message})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{})
+	}
+	router.GET("/api/v1/dashboard/daily/:date", handler)
+	router.DELETE("/api/v1/admin/maintenance/:id", handler)
+	router.POST("/api/v1/admin/maintenance", handler)
+	router.GET("/health", handler)
+	router.GET("/health/ready", handler)
+	router.GET("/metrics", handler)
+	return router
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Run("no headers outside the notice period", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.SetNext(testWindow())
+		router := setupRouter(tracker, windowStart.Add(-25*time.Hour))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/dashboard/daily/2026-10-31", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get(HeaderWindowStart))
+	})
+
+	t.Run("announces upcoming window", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.SetNext(testWindow())
+		router := setupRouter(tracker, windowStart.Add(-2*time.Hour))
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/dashboard/daily/2026-10-31", nil)
+		req.Header.Set("Accept-Language", "en")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2026-11-01T02:00:00Z", w.Header().Get(HeaderWindowStart))
+		assert.Equal(t, "2026-11-01T04:00:00Z", w.Header().Get(HeaderWindowEnd))
+		assert.Contains(t, w.Body.String(), "The service will be unavailable")
+	})
+
+	t.Run("rejects requests while maintenance mode is active", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.SetNext(testWindow())
+		now := windowStart.Add(90 * time.Minute)
+		tracker.Tick(now)
+		router := setupRouter(tracker, now)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/dashboard/daily/2026-11-01", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "1800", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("keeps health and maintenance admin reachable", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.SetNext(testWindow())
+		now := windowStart.Add(time.Minute)
+		tracker.Tick(now)
+		router := setupRouter(tracker, now)
+
+		for _, req := range []*http.Request{
+			httptest.NewRequest(http.MethodGet, "/health", nil),
+			httptest.NewRequest(http.MethodDelete, "/api/v1/admin/maintenance/1", nil),
+		} {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, req.URL.Path)
+		}
+	})
+}
+
+func TestMiddlewareManualMode(t *testing.T) {
+	now := windowStart.Add(-48 * time.Hour)
+	get := func(router *gin.Engine, method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	t.Run("toggles on and off at runtime", func(t *testing.T) {
+		tracker := NewTracker()
+		router := setupRouter(tracker, now)
+
+		assert.Equal(t, http.StatusOK, get(router, http.MethodGet, "/api/v1/dashboard/daily/2026-10-30").Code)
+
+		eta := now.Add(10 * time.Minute)
+		tracker.SetMode(Mode{Enabled: true, Message: "Обновляем базу данных", ETA: &eta})
+		w := get(router, http.MethodGet, "/api/v1/dashboard/daily/2026-10-30")
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "600", w.Header().Get("Retry-After"))
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Body.String(), `"message":"Обновляем базу данных"`)
+		assert.Contains(t, w.Body.String(), `"code":"MAINTENANCE"`)
+		assert.Contains(t, w.Body.String(), `"eta":"2026-10-30T02:10:00Z"`)
+
+		tracker.SetMode(Mode{})
+		assert.Equal(t, http.StatusOK, get(router, http.MethodGet, "/api/v1/dashboard/daily/2026-10-30").Code)
+	})
+
+	t.Run("default retry and message without ETA", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.SetMode(Mode{Enabled: true})
+		router := setupRouter(tracker, now)
+
+		w := get(router, http.MethodGet, "/api/v1/dashboard/daily/2026-10-30")
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, "300", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), "идут технические работы")
+	})
+
+	t.Run("exempts health, metrics and the toggle", func(t *testing.T) {
+		tracker := NewTracker()
+		tracker.Force("")
+		router := setupRouter(tracker, now)
+
+		for _, route := range []struct{ method, path string }{
+			{http.MethodGet, "/health"},
+			{http.MethodGet, "/health/ready"},
+			{http.MethodGet, "/metrics"},
+			{http.MethodPost, "/api/v1/admin/maintenance"},
+			{http.MethodDelete, "/api/v1/admin/maintenance/1"},
+		} {
+			assert.Equal(t, http.StatusOK, get(router, route.method, route.path).Code, route.path)
+		}
+	})
+}
@@ -0,0 +1,302 @@
+package maintenance
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// ServiceInterface defines the interface for maintenance scheduling operations
+type ServiceInterface interface {
+	Schedule(ctx context.Context, adminID int64, req ScheduleRequest) (*Window, error)
+	ListUpcoming(ctx context.Context) ([]Window, error)
+	Cancel(ctx context.Context, windowID int64) error
+	SetMode(ctx context.Context, adminID int64, req ModeRequest) (*Mode, error)
+}
+
+// Service stores maintenance windows and manual maintenance mode and keeps
+// the tracker in sync
+type Service struct {
+	db          *database.DB
+	log         *logger.Logger
+	audit       audit.ServiceInterface
+	tracker     *Tracker
+	persistMode bool
+	now         func() time.Time
+}
+
+// NewService creates a new maintenance service. With persistMode, manual
+// maintenance mode is stored in the database and picked up by every replica
+// on refresh; otherwise it only applies to this process.
+func NewService(db *database.DB, log *logger.Logger, tracker *Tracker, persistMode bool) *Service {
+	return &Service{
+		db:          db,
+		log:         log,
+		audit:       audit.NewService(db.DB, log),
+		tracker:     tracker,
+		persistMode: persistMode,
+		now:         time.Now,
+	}
+}
+
+// Schedule records a new maintenance window. Windows must start in the future
+// and must not overlap other scheduled windows.
+func (s *Service) Schedule(ctx context.Context, adminID int64, req ScheduleRequest) (*Window, error) {
+	startTime := time.Now()
+
+	if !req.StartsAt.After(s.now()) {
+		return nil, ErrWindowInPast
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, ErrInvalidWindow
+	}
+
+	query := `
+		INSERT INTO maintenance_windows (starts_at, ends_at, created_by)
+		SELECT $1, $2, $3
+		WHERE NOT EXISTS (
+			SELECT 1 FROM maintenance_windows
+			WHERE cancelled_at IS NULL AND starts_at < $2 AND ends_at > $1
+		)
+		RETURNING id, starts_at, ends_at, created_by, created_at
+	`
+
+	var w Window
+	var createdBy sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query, req.StartsAt, req.EndsAt, adminID).
+		Scan(&w.ID, &w.StartsAt, &w.EndsAt, &createdBy, &w.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"admin_id": adminID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, ErrWindowOverlaps
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule maintenance window: %w", err)
+	}
+	if createdBy.Valid {
+		w.CreatedBy = &createdBy.Int64
+	}
+
+	s.log.LogBusinessEvent("maintenance_scheduled", map[string]interface{}{
+		"window_id": w.ID,
+		"admin_id":  adminID,
+		"starts_at": w.StartsAt,
+		"ends_at":   w.EndsAt,
+	})
+
+	if err := s.Refresh(ctx); err != nil {
+		s.log.Error("Failed to refresh maintenance state", "error", err)
+	}
+
+	return &w, nil
+}
+
+// ListUpcoming returns windows that have not ended and are not cancelled
+func (s *Service) ListUpcoming(ctx context.Context) ([]Window, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT id, starts_at, ends_at, created_by, created_at
+		FROM maintenance_windows
+		WHERE cancelled_at IS NULL AND ends_at > $1
+		ORDER BY starts_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, s.now())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list maintenance windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := make([]Window, 0)
+	for rows.Next() {
+		w, err := scanWindow(rows)
+		if err != nil {
+			return nil, err
+		}
+		windows = append(windows, *w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating maintenance windows: %w", err)
+	}
+
+	return windows, nil
+}
+
+// Cancel cancels a scheduled or active window; maintenance mode ends immediately
+func (s *Service) Cancel(ctx context.Context, windowID int64) error {
+	startTime := time.Now()
+
+	query := `UPDATE maintenance_windows SET cancelled_at = NOW() WHERE id = $1 AND cancelled_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, windowID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"window_id": windowID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to cancel maintenance window: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWindowNotFound
+	}
+
+	s.log.LogBusinessEvent("maintenance_cancelled", map[string]interface{}{
+		"window_id": windowID,
+	})
+
+	if err := s.Refresh(ctx); err != nil {
+		s.log.Error("Failed to refresh maintenance state", "error", err)
+	}
+
+	return nil
+}
+
+// SetMode switches manual maintenance mode on or off. Switching it off
+// clears the message and ETA. The change is audit-logged.
+func (s *Service) SetMode(ctx context.Context, adminID int64, req ModeRequest) (*Mode, error) {
+	now := s.now()
+	m := Mode{Enabled: *req.Enabled, UpdatedBy: &adminID, UpdatedAt: now}
+	if m.Enabled {
+		m.Message = strings.TrimSpace(req.Message)
+		if utf8.RuneCountInString(m.Message) > MaxMessageLength {
+			return nil, ErrMessageTooLong
+		}
+		if req.ETA != nil && !req.ETA.After(now) {
+			return nil, ErrETAInPast
+		}
+		m.ETA = req.ETA
+	}
+
+	if s.persistMode {
+		startTime := time.Now()
+		query := `
+			INSERT INTO maintenance_mode (id, enabled, message, eta, updated_by, updated_at)
+			VALUES (TRUE, $1, $2, $3, $4, $5)
+			ON CONFLICT (id) DO UPDATE
+			SET enabled = EXCLUDED.enabled, message = EXCLUDED.message, eta = EXCLUDED.eta,
+			    updated_by = EXCLUDED.updated_by, updated_at = EXCLUDED.updated_at
+		`
+		_, err := s.db.ExecContext(ctx, query, m.Enabled, m.Message, m.ETA, adminID, m.UpdatedAt)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"admin_id": adminID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store maintenance mode: %w", err)
+		}
+	}
+	s.tracker.SetMode(m)
+
+	s.log.LogBusinessEvent("maintenance_mode_changed", map[string]interface{}{
+		"admin_id": adminID,
+		"enabled":  m.Enabled,
+	})
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &adminID,
+		Action: audit.ActionMaintenanceModeChanged,
+		Metadata: map[string]any{
+			"enabled": m.Enabled,
+			"message": m.Message,
+			"eta":     m.ETA,
+		},
+	})
+
+	current := s.tracker.Mode()
+	return &current, nil
+}
+
+// Refresh loads the nearest window that has not ended into the tracker and
+// applies any due transition. With persistMode it also loads manual
+// maintenance mode, so a toggle on another replica applies here too.
+func (s *Service) Refresh(ctx context.Context) error {
+	startTime := time.Now()
+	now := s.now()
+
+	query := `
+		SELECT id, starts_at, ends_at, created_by, created_at
+		FROM maintenance_windows
+		WHERE cancelled_at IS NULL AND ends_at > $1
+		ORDER BY starts_at
+		LIMIT 1
+	`
+
+	w, err := scanWindow(s.db.QueryRowContext(ctx, query, now))
+	if err == sql.ErrNoRows {
+		w, err = nil, nil
+	}
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+		return fmt.Errorf("failed to load maintenance window: %w", err)
+	}
+
+	s.tracker.SetNext(w)
+	switch s.tracker.Tick(now) {
+	case TransitionActivated:
+		s.log.LogBusinessEvent("maintenance_mode_activated", map[string]interface{}{
+			"window_id": w.ID,
+			"ends_at":   w.EndsAt,
+		})
+	case TransitionDeactivated:
+		s.log.LogBusinessEvent("maintenance_mode_deactivated", nil)
+	}
+
+	if s.persistMode {
+		return s.refreshMode(ctx)
+	}
+	return nil
+}
+
+// refreshMode loads the stored manual maintenance mode into the tracker.
+// Until an admin first sets it there is nothing stored and the tracker keeps
+// its state.
+func (s *Service) refreshMode(ctx context.Context) error {
+	startTime := time.Now()
+	query := `SELECT enabled, message, eta, updated_by, updated_at FROM maintenance_mode WHERE id`
+
+	var m Mode
+	var eta sql.NullTime
+	var updatedBy sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query).Scan(&m.Enabled, &m.Message, &eta, &updatedBy, &m.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+		return fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+	if eta.Valid {
+		m.ETA = &eta.Time
+	}
+	if updatedBy.Valid {
+		m.UpdatedBy = &updatedBy.Int64
+	}
+	s.tracker.SetMode(m)
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWindow(row rowScanner) (*Window, error) {
+	var w Window
+	var createdBy sql.NullInt64
+	if err := row.Scan(&w.ID, &w.StartsAt, &w.EndsAt, &createdBy, &w.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan maintenance window: %w", err)
+	}
+	if createdBy.Valid {
+		w.CreatedBy = &createdBy.Int64
+	}
+	return &w, nil
+}
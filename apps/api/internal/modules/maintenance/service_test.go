@@ -0,0 +1,190 @@
+package maintenance
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var windowColumns = []string{"id", "starts_at", "ends_at", "created_by", "created_at"}
+
+func setupTestService(t *testing.T, now time.Time) (*Service, sqlmock.Sqlmock, *Tracker, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	tracker := NewTracker()
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), tracker, false)
+	service.now = func() time.Time { return now }
+
+	return service, mock, tracker, func() { mockDB.Close() }
+}
+
+func TestSchedule(t *testing.T) {
+	now := windowStart.Add(-6 * time.Hour)
+
+	t.Run("schedules window and refreshes tracker", func(t *testing.T) {
+		service, mock, tracker, cleanup := setupTestService(t, now)
+		defer cleanup()
+
+		mock.ExpectQuery("INSERT INTO maintenance_windows").
+			WithArgs(windowStart, windowStart.Add(2*time.Hour), int64(1)).
+			WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(int64(1), windowStart, windowStart.Add(2*time.Hour), int64(1), now))
+		mock.ExpectQuery("SELECT id, starts_at, ends_at").
+			WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(int64(1), windowStart, windowStart.Add(2*time.Hour), int64(1), now))
+
+		w, err := service.Schedule(context.Background(), 1, ScheduleRequest{StartsAt: windowStart, EndsAt: windowStart.Add(2 * time.Hour)})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), w.ID)
+		phase, _ := tracker.Status(now)
+		assert.Equal(t, PhaseUpcoming, phase)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects overlapping window", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t, now)
+		defer cleanup()
+
+		mock.ExpectQuery("INSERT INTO maintenance_windows").WillReturnRows(sqlmock.NewRows(windowColumns))
+
+		_, err := service.Schedule(context.Background(), 1, ScheduleRequest{StartsAt: windowStart, EndsAt: windowStart.Add(time.Hour)})
+
+		assert.ErrorIs(t, err, ErrWindowOverlaps)
+	})
+
+	t.Run("rejects window in the past", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t, now)
+		defer cleanup()
+
+		_, err := service.Schedule(context.Background(), 1, ScheduleRequest{StartsAt: now.Add(-time.Minute), EndsAt: now.Add(time.Hour)})
+
+		assert.ErrorIs(t, err, ErrWindowInPast)
+	})
+
+	t.Run("rejects window ending before start", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t, now)
+		defer cleanup()
+
+		_, err := service.Schedule(context.Background(), 1, ScheduleRequest{StartsAt: windowStart, EndsAt: windowStart})
+
+		assert.ErrorIs(t, err, ErrInvalidWindow)
+	})
+}
+
+func TestRefreshActivatesWindow(t *testing.T) {
+	now := windowStart.Add(time.Minute)
+	service, mock, tracker, cleanup := setupTestService(t, now)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT id, starts_at, ends_at").WithArgs(now).
+		WillReturnRows(sqlmock.NewRows(windowColumns).AddRow(int64(1), windowStart, windowStart.Add(2*time.Hour), nil, now))
+
+	require.NoError(t, service.Refresh(context.Background()))
+
+	phase, _ := tracker.Status(now)
+	assert.Equal(t, PhaseActive, phase)
+}
+
+func TestSetMode(t *testing.T) {
+	now := windowStart.Add(-time.Hour)
+	enabled, disabled := true, false
+
+	t.Run("stores mode and records audit", func(t *testing.T) {
+		service, mock, tracker, cleanup := setupTestService(t, now)
+		defer cleanup()
+		service.persistMode = true
+		eta := now.Add(30 * time.Minute)
+
+		mock.ExpectExec("INSERT INTO maintenance_mode").
+			WithArgs(true, "Обновляем базу данных", &eta, int64(1), now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(1), nil, "maintenance_mode_changed", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		m, err := service.SetMode(context.Background(), 1, ModeRequest{Enabled: &enabled, Message: " Обновляем базу данных ", ETA: &eta})
+
+		require.NoError(t, err)
+		assert.True(t, m.Enabled)
+		assert.Equal(t, "Обновляем базу данных", m.Message)
+		assert.Equal(t, &eta, tracker.Mode().ETA)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("switching off clears message", func(t *testing.T) {
+		service, mock, tracker, cleanup := setupTestService(t, now)
+		defer cleanup()
+		tracker.SetMode(Mode{Enabled: true, Message: "Обновляем базу данных"})
+
+		// Without persistMode only this process is switched
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		m, err := service.SetMode(context.Background(), 1, ModeRequest{Enabled: &disabled, Message: "ignored"})
+
+		require.NoError(t, err)
+		assert.False(t, m.Enabled)
+		assert.Empty(t, m.Message)
+		assert.False(t, tracker.Mode().Enabled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("forced mode stays on", func(t *testing.T) {
+		service, mock, tracker, cleanup := setupTestService(t, now)
+		defer cleanup()
+		tracker.Force("Переезд")
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		m, err := service.SetMode(context.Background(), 1, ModeRequest{Enabled: &disabled})
+
+		require.NoError(t, err)
+		assert.True(t, m.Enabled)
+		assert.True(t, m.Forced)
+		assert.Equal(t, "Переезд", m.Message)
+	})
+
+	t.Run("rejects ETA in the past", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t, now)
+		defer cleanup()
+		eta := now.Add(-time.Minute)
+
+		_, err := service.SetMode(context.Background(), 1, ModeRequest{Enabled: &enabled, ETA: &eta})
+
+		assert.ErrorIs(t, err, ErrETAInPast)
+	})
+
+	t.Run("rejects long message", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t, now)
+		defer cleanup()
+
+		_, err := service.SetMode(context.Background(), 1, ModeRequest{Enabled: &enabled, Message: strings.Repeat("ж", MaxMessageLength+1)})
+
+		assert.ErrorIs(t, err, ErrMessageTooLong)
+	})
+}
+
+func TestRefreshLoadsStoredMode(t *testing.T) {
+	now := windowStart.Add(-48 * time.Hour)
+	service, mock, tracker, cleanup := setupTestService(t, now)
+	defer cleanup()
+	service.persistMode = true
+
+	mock.ExpectQuery("SELECT id, starts_at, ends_at").WillReturnRows(sqlmock.NewRows(windowColumns))
+	mock.ExpectQuery("FROM maintenance_mode").
+		WillReturnRows(sqlmock.NewRows([]string{"enabled", "message", "eta", "updated_by", "updated_at"}).
+			AddRow(true, "Переезд", nil, int64(1), now))
+
+	require.NoError(t, service.Refresh(context.Background()))
+
+	m := tracker.Mode()
+	assert.True(t, m.Enabled)
+	assert.Equal(t, "Переезд", m.Message)
+	assert.Nil(t, m.ETA)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package maintenance
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tracker holds the next scheduled window and whether maintenance mode is
+// active. The scheduler feeds it from the database and flips maintenance mode
+// via Tick; the middleware only reads it. Manual maintenance mode, set by an
+// admin or forced by configuration, applies on top of any window.
+type Tracker struct {
+	mu     sync.RWMutex
+	next   *Window
+	active *Window
+
+	mode          Mode
+	forced        bool
+	forcedMessage string
+}
+
+// NewTracker creates a tracker with no scheduled window
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// SetNext replaces the next scheduled window (nil when none)
+func (t *Tracker) SetNext(w *Window) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.next = w
+}
+
+// Force keeps manual maintenance mode on whatever the admin toggle says, as
+// MAINTENANCE_MODE does. message is shown when the toggle has none.
+func (t *Tracker) Force(message string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.forced = true
+	t.forcedMessage = message
+}
+
+// SetMode replaces the manual maintenance mode set by an admin
+func (t *Tracker) SetMode(m Mode) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.mode = m
+}
+
+// Mode returns the manual maintenance mode in effect
+func (t *Tracker) Mode() Mode {
+	if t == nil {
+		return Mode{}
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	m := t.mode
+	if t.forced {
+		if !m.Enabled {
+			m = Mode{Enabled: true, Message: t.forcedMessage}
+		} else if m.Message == "" {
+			m.Message = t.forcedMessage
+		}
+		m.Forced = true
+	}
+	return m
+}
+
+// Tick applies time-based transitions: the scheduled window becomes active
+// once it starts, and maintenance mode ends when the window ends or is no
+// longer scheduled (cancelled).
+func (t *Tracker) Tick(now time.Time) Transition {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.active != nil {
+		if !now.Before(t.active.EndsAt) || t.next == nil || t.next.ID != t.active.ID {
+			t.active = nil
+			return TransitionDeactivated
+		}
+		return TransitionNone
+	}
+	if t.next != nil && !now.Before(t.next.StartsAt) && now.Before(t.next.EndsAt) {
+		t.active = t.next
+		return TransitionActivated
+	}
+	return TransitionNone
+}
+
+// Status returns the current phase and the window it refers to
+func (t *Tracker) Status(now time.Time) (Phase, *Window) {
+	if t == nil {
+		return PhaseNone, nil
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.active != nil && now.Before(t.active.EndsAt) {
+		return PhaseActive, t.active
+	}
+	if t.next != nil && now.Before(t.next.StartsAt) && t.next.StartsAt.Sub(now) <= NoticePeriod {
+		return PhaseUpcoming, t.next
+	}
+	return PhaseNone, nil
+}
+
+// BuildNotice returns the announcement of a window in the language preferred
+// by the Accept-Language header (Russian by default, English supported).
+func BuildNotice(w *Window, acceptLanguage string) Notice {
+	start := w.StartsAt.UTC().Format("02.01.2006 15:04")
+	end := w.EndsAt.UTC().Format("02.01.2006 15:04")
+
+	n := Notice{StartsAt: w.StartsAt, EndsAt: w.EndsAt, Locale: "ru"}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(acceptLanguage)), "en") {
+		n.Locale = "en"
+		n.Title = "Scheduled maintenance"
+		n.Message = fmt.Sprintf("The service will be unavailable from %s to %s UTC.", start, end)
+		return n
+	}
+	n.Title = "Плановые технические работы"
+	n.Message = fmt.Sprintf("Сервис будет недоступен с %s до %s UTC.", start, end)
+	return n
+}
+
+// ModeMessage returns the message of manual maintenance mode, or a default
+// one in the language preferred by the Accept-Language header
+func ModeMessage(m Mode, acceptLanguage string) string {
+	if m.Message != "" {
+		return m.Message
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(acceptLanguage)), "en") {
+		return "The service is temporarily unavailable for maintenance."
+	}
+	return "Сервис временно недоступен: идут технические работы."
+}
@@ -0,0 +1,113 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var windowStart = time.Date(2026, 11, 1, 2, 0, 0, 0, time.UTC)
+
+func testWindow() *Window {
+	return &Window{ID: 1, StartsAt: windowStart, EndsAt: windowStart.Add(2 * time.Hour)}
+}
+
+func TestTrackerTransitions(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetNext(testWindow())
+
+	steps := []struct {
+		name       string
+		now        time.Time
+		transition Transition
+		phase      Phase
+	}{
+		{"two days before", windowStart.Add(-48 * time.Hour), TransitionNone, PhaseNone},
+		{"notice period begins", windowStart.Add(-NoticePeriod), TransitionNone, PhaseUpcoming},
+		{"one minute before", windowStart.Add(-time.Minute), TransitionNone, PhaseUpcoming},
+		{"window starts", windowStart, TransitionActivated, PhaseActive},
+		{"during window", windowStart.Add(time.Hour), TransitionNone, PhaseActive},
+		{"window ends", windowStart.Add(2 * time.Hour), TransitionDeactivated, PhaseNone},
+		{"after window", windowStart.Add(3 * time.Hour), TransitionNone, PhaseNone},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			assert.Equal(t, step.transition, tracker.Tick(step.now))
+			phase, _ := tracker.Status(step.now)
+			assert.Equal(t, step.phase, phase)
+		})
+	}
+}
+
+func TestTrackerActivationRequiresTick(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetNext(testWindow())
+
+	// Until the scheduler ticks, the started window is neither upcoming nor active
+	phase, _ := tracker.Status(windowStart.Add(time.Minute))
+	assert.Equal(t, PhaseNone, phase)
+
+	tracker.Tick(windowStart.Add(time.Minute))
+	phase, w := tracker.Status(windowStart.Add(time.Minute))
+	assert.Equal(t, PhaseActive, phase)
+	assert.Equal(t, int64(1), w.ID)
+}
+
+func TestTrackerCancelDuringWindow(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetNext(testWindow())
+	now := windowStart.Add(30 * time.Minute)
+	assert.Equal(t, TransitionActivated, tracker.Tick(now))
+
+	tracker.SetNext(nil)
+
+	assert.Equal(t, TransitionDeactivated, tracker.Tick(now))
+	phase, _ := tracker.Status(now)
+	assert.Equal(t, PhaseNone, phase)
+}
+
+func TestTrackerMissedWindow(t *testing.T) {
+	tracker := NewTracker()
+	tracker.SetNext(testWindow())
+
+	// A scheduler that first ticks after the window ended never activates it
+	assert.Equal(t, TransitionNone, tracker.Tick(windowStart.Add(3*time.Hour)))
+}
+
+func TestTrackerMode(t *testing.T) {
+	tracker := NewTracker()
+	assert.False(t, tracker.Mode().Enabled)
+
+	tracker.SetMode(Mode{Enabled: true, Message: "Обновляем базу данных"})
+	assert.Equal(t, Mode{Enabled: true, Message: "Обновляем базу данных"}, tracker.Mode())
+
+	tracker.SetMode(Mode{})
+	assert.False(t, tracker.Mode().Enabled)
+
+	// Configuration keeps it on and supplies the message the toggle lacks
+	tracker.Force("Переезд")
+	assert.Equal(t, Mode{Enabled: true, Message: "Переезд", Forced: true}, tracker.Mode())
+	tracker.SetMode(Mode{Enabled: true, Message: "Почти готово"})
+	assert.Equal(t, Mode{Enabled: true, Message: "Почти готово", Forced: true}, tracker.Mode())
+}
+
+func TestModeMessage(t *testing.T) {
+	assert.Equal(t, "Переезд", ModeMessage(Mode{Message: "Переезд"}, "en"))
+	assert.Equal(t, "Сервис временно недоступен: идут технические работы.", ModeMessage(Mode{}, ""))
+	assert.Equal(t, "The service is temporarily unavailable for maintenance.", ModeMessage(Mode{}, "en-GB"))
+}
+
+func TestBuildNotice(t *testing.T) {
+	w := testWindow()
+
+	ru := BuildNotice(w, "")
+	assert.Equal(t, "ru", ru.Locale)
+	assert.Equal(t, "Сервис будет недоступен с 01.11.2026 02:00 до 01.11.2026 04:00 UTC.", ru.Message)
+
+	en := BuildNotice(w, "en-US,en;q=0.9")
+	assert.Equal(t, "en", en.Locale)
+	assert.Equal(t, "Scheduled maintenance", en.Title)
+	assert.Equal(t, w.StartsAt, en.StartsAt)
+}
@@ -0,0 +1,89 @@
+package maintenance
+
+import (
+	"errors"
+	"time"
+)
+
+// NoticePeriod is how long before a window starts clients are warned about it
+const NoticePeriod = 24 * time.Hour
+
+// Manual maintenance mode limits
+const (
+	// DefaultRetryAfter is the Retry-After of manual maintenance mode
+	// without an ETA, or with one that has passed
+	DefaultRetryAfter = 5 * time.Minute
+	// MaxMessageLength bounds the message shown while in maintenance mode
+	MaxMessageLength = 500
+)
+
+// Phase describes where "now" is relative to the scheduled window
+type Phase string
+
+const (
+	PhaseNone     Phase = ""
+	PhaseUpcoming Phase = "upcoming"
+	PhaseActive   Phase = "active"
+)
+
+// Transition is a maintenance mode change applied by the scheduler
+type Transition int
+
+const (
+	TransitionNone Transition = iota
+	TransitionActivated
+	TransitionDeactivated
+)
+
+var (
+	ErrWindowInPast   = errors.New("maintenance window must start in the future")
+	ErrInvalidWindow  = errors.New("maintenance window must end after it starts")
+	ErrWindowOverlaps = errors.New("maintenance window overlaps an existing window")
+	ErrWindowNotFound = errors.New("maintenance window not found")
+	ErrETAInPast      = errors.New("maintenance ETA must be in the future")
+	ErrMessageTooLong = errors.New("maintenance message is too long")
+)
+
+// Window is a scheduled maintenance period
+type Window struct {
+	ID        int64     `json:"id"`
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy *int64    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ScheduleRequest is the request body for POST /api/v1/admin/maintenance/schedule
+type ScheduleRequest struct {
+	StartsAt time.Time `json:"starts_at" binding:"required"`
+	EndsAt   time.Time `json:"ends_at" binding:"required"`
+}
+
+// ModeRequest is the request body for POST /api/v1/admin/maintenance. The
+// message and ETA are shown to clients while maintenance mode is on.
+type ModeRequest struct {
+	Enabled *bool      `json:"enabled" binding:"required"`
+	Message string     `json:"message"`
+	ETA     *time.Time `json:"eta"`
+}
+
+// Mode is maintenance mode switched on by hand, outside any scheduled
+// window. Forced is set when MAINTENANCE_MODE keeps it on whatever the
+// admin toggle says.
+type Mode struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message,omitempty"`
+	ETA       *time.Time `json:"eta,omitempty"`
+	Forced    bool       `json:"forced"`
+	UpdatedBy *int64     `json:"updated_by,omitempty"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Notice is the localized announcement of an upcoming window
+type Notice struct {
+	StartsAt time.Time `json:"starts_at"`
+	EndsAt   time.Time `json:"ends_at"`
+	Title    string    `json:"title"`
+	Message  string    `json:"message"`
+	Locale   string    `json:"locale"`
+}
@@ -0,0 +1,32 @@
+package maintenance
+
+import (
+	"context"
+	"time"
+)
+
+// RunScheduler keeps the tracker in sync with scheduled windows so that
+// maintenance mode switches on and off automatically. It blocks until the
+// provided context is cancelled.
+func (s *Service) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("Maintenance scheduler started")
+
+	if err := s.Refresh(ctx); err != nil {
+		s.log.Error("Failed to refresh maintenance state", "error", err)
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				s.log.Error("Failed to refresh maintenance state", "error", err)
+			}
+		case <-ctx.Done():
+			s.log.Info("Maintenance scheduler stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package mealplans
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the meal plan routes of curators and clients
+type Handler struct {
+	log     *logger.Logger
+	db      *database.DB
+	service ServiceInterface
+}
+
+// NewHandler creates a new meal plan handler. db is used for the user's
+// timezone when a client asks for today's plan.
+func NewHandler(log *logger.Logger, db *database.DB, service ServiceInterface) *Handler {
+	return &Handler{
+		log:     log,
+		db:      db,
+		service: service,
+	}
+}
+
+// CreatePlan handles POST /api/v1/curator/clients/:id/meal-plan
+func (h *Handler) CreatePlan(c *gin.Context) {
+	clientID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор клиента")
+		return
+	}
+
+	var req CreatePlanRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	plan, err := h.service.Create(c.Request.Context(), c.GetInt64("user_id"), clientID, &req)
+	if errors.Is(err, apperrors.ErrForbidden) {
+		response.Forbidden(c, "Нет активной связи с данным клиентом")
+		return
+	}
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, PlanResponse{Plan: plan})
+}
+
+// GetPlan handles GET /api/v1/nutrition/plan?date=
+// Returns the user's plan in effect on date, today by default.
+func (h *Handler) GetPlan(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	var q dayQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+	date := q.Date
+	if date.IsZero() {
+		loc, ok := middleware.RequestTimezone(c, h.db, userID)
+		if !ok {
+			return
+		}
+		date = types.DateOf(time.Now().In(loc))
+	}
+
+	plan, err := h.service.PlanFor(c.Request.Context(), userID, date)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, PlanResponse{Plan: plan})
+}
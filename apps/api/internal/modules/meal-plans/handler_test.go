@@ -0,0 +1,140 @@
+package mealplans
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err       error
+	plan      *Plan
+	curatorID int64
+	clientID  int64
+	created   *CreatePlanRequest
+	day       types.Date
+}
+
+func (m *mockService) Create(ctx context.Context, curatorID, clientID int64, req *CreatePlanRequest) (*Plan, error) {
+	m.curatorID, m.clientID, m.created = curatorID, clientID, req
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Plan{ID: "plan-1", ClientID: clientID, CuratorID: &curatorID, StartDate: req.StartDate, Meals: req.Meals}, nil
+}
+
+func (m *mockService) PlanFor(ctx context.Context, clientID int64, day types.Date) (*Plan, error) {
+	m.clientID, m.day = clientID, day
+	return m.plan, m.err
+}
+
+func serve(service ServiceInterface, userID int64, method, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	auth := func(c *gin.Context) { c.Set("user_id", userID) }
+	h := NewHandler(logger.New(), nil, service)
+	RegisterRoutes(router.Group("/nutrition", auth), h)
+	RegisterCuratorRoutes(router.Group("/curator", auth), h)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var resp map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+const planBody = `{"start_date":"2026-10-20","meals":{"breakfast":{"calories":500,"protein":40,"carbs":50,"fat":15}}}`
+
+func TestHandler_CreatePlan(t *testing.T) {
+	t.Run("created", func(t *testing.T) {
+		service := &mockService{}
+
+		w := serve(service, testCuratorID, http.MethodPost, "/curator/clients/42/meal-plan", planBody)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, testCuratorID, service.curatorID)
+		assert.Equal(t, testClientID, service.clientID)
+		assert.Equal(t, Target{Calories: 500, Protein: 40, Carbs: 50, Fat: 15}, service.created.Meals[MealBreakfast])
+		plan := decode(t, w)["data"].(map[string]any)["plan"].(map[string]any)
+		assert.Equal(t, "2026-10-20", plan["start_date"])
+	})
+
+	t.Run("not the client's curator", func(t *testing.T) {
+		w := serve(&mockService{err: apperrors.ErrForbidden}, testCuratorID, http.MethodPost, "/curator/clients/42/meal-plan", planBody)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("malformed client id", func(t *testing.T) {
+		service := &mockService{}
+
+		w := serve(service, testCuratorID, http.MethodPost, "/curator/clients/abc/meal-plan", planBody)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Nil(t, service.created)
+	})
+
+	t.Run("malformed start date", func(t *testing.T) {
+		service := &mockService{}
+
+		w := serve(service, testCuratorID, http.MethodPost, "/curator/clients/42/meal-plan",
+			`{"start_date":"20.10.2026","meals":{"lunch":{"calories":600}}}`)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Equal(t, map[string]any{"start_date": types.DateMessage}, decode(t, w)["details"].(map[string]any)["fields"])
+		assert.Nil(t, service.created)
+	})
+}
+
+func TestHandler_GetPlan(t *testing.T) {
+	t.Run("plan in effect on the date", func(t *testing.T) {
+		service := &mockService{plan: &Plan{ID: "plan-1", ClientID: testClientID, Meals: map[string]Target{MealLunch: {Calories: 600}}}}
+
+		w := serve(service, testClientID, http.MethodGet, "/nutrition/plan?date=2026-10-16", "")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, testClientID, service.clientID)
+		assert.Equal(t, "2026-10-16", service.day.String())
+		plan := decode(t, w)["data"].(map[string]any)["plan"].(map[string]any)
+		assert.Equal(t, "plan-1", plan["id"])
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		w := serve(&mockService{}, testClientID, http.MethodGet, "/nutrition/plan?date=2026-10-16", "")
+
+		require.Equal(t, http.StatusOK, w.Code)
+		data := decode(t, w)["data"].(map[string]any)
+		assert.Contains(t, data, "plan")
+		assert.Nil(t, data["plan"])
+	})
+
+	t.Run("clients cannot assign plans", func(t *testing.T) {
+		w := serve(&mockService{}, testClientID, http.MethodPost, "/nutrition/plan", planBody)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("malformed date", func(t *testing.T) {
+		w := serve(&mockService{}, testClientID, http.MethodGet, "/nutrition/plan?date=2026-02-30", "")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
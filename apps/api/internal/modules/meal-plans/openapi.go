@@ -0,0 +1,23 @@
+package mealplans
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+	"github.com/burcev/api/internal/shared/types"
+)
+
+// dayQuery selects the day the plan is in effect on; today in the tz
+// timezone when date is empty
+type dayQuery struct {
+	Date types.Date `form:"date"`
+	TZ   string     `form:"tz"`
+}
+
+// Endpoints describes the client's meal plan route, served under
+// /nutrition, for the OpenAPI document
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/plan", Summary: "План питания от куратора, действующий на день (по умолчанию сегодня): цели по калориям и БЖУ на каждый приём пищи; plan равен null, если плана нет", Auth: openapi.BearerOrAPIKey, Query: dayQuery{}, Response: PlanResponse{}},
+	}
+}
@@ -0,0 +1,15 @@
+package mealplans
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes registers the client's meal plan route on the nutrition
+// group r, which must already require authentication
+func RegisterRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/plan", h.GetPlan)
+}
+
+// RegisterCuratorRoutes registers the plan assignment route on the curator
+// group r
+func RegisterCuratorRoutes(r *gin.RouterGroup, h *Handler) {
+	r.POST("/clients/:id/meal-plan", h.CreatePlan)
+}
@@ -0,0 +1,213 @@
+// Package mealplans keeps the meal plans curators assign to their clients:
+// calorie and macro targets per meal, in effect from a start date until a
+// later plan takes over.
+package mealplans
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// ServiceInterface defines the meal plan operations used by the handler
+type ServiceInterface interface {
+	Create(ctx context.Context, curatorID, clientID int64, req *CreatePlanRequest) (*Plan, error)
+	PlanFor(ctx context.Context, clientID int64, day types.Date) (*Plan, error)
+}
+
+// Service stores meal plans and resolves the one in effect on a day.
+// Only a curator actively linked to the client assigns plans; the client
+// reads them.
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+	now func() time.Time
+}
+
+// NewService creates a new meal plan service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{
+		db:  db,
+		log: log,
+		now: time.Now,
+	}
+}
+
+const planColumns = `id, client_id, curator_id, start_date::text, meals, note, created_at`
+
+func scanPlan(row interface{ Scan(...any) error }) (*Plan, error) {
+	var p Plan
+	var startDate string
+	var meals []byte
+	if err := row.Scan(&p.ID, &p.ClientID, &p.CuratorID, &startDate, &meals, &p.Note, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	date, err := types.ParseDate(startDate)
+	if err != nil {
+		return nil, err
+	}
+	p.StartDate = date
+	if err := json.Unmarshal(meals, &p.Meals); err != nil {
+		return nil, fmt.Errorf("invalid meal targets: %w", err)
+	}
+	return &p, nil
+}
+
+// validatePlan checks a plan request and trims its note. now is used for
+// the start date window: from yesterday, which covers clients in time
+// zones behind the server, up to MaxStartDaysAhead days ahead.
+func validatePlan(req *CreatePlanRequest, now time.Time) error {
+	errs := validation.Errors{}
+
+	today := types.DateOf(now)
+	switch {
+	case req.StartDate.Before(today.AddDays(-1)):
+		errs["start_date"] = "Дата начала не может быть в прошлом"
+	case req.StartDate.After(today.AddDays(MaxStartDaysAhead)):
+		errs["start_date"] = fmt.Sprintf("Дата начала не может быть позже чем через %d дней", MaxStartDaysAhead)
+	}
+
+	if len(req.Meals) == 0 {
+		errs["meals"] = "Укажите цели хотя бы для одного приёма пищи"
+	}
+	for meal, target := range req.Meals {
+		field := "meals." + meal
+		switch meal {
+		case MealBreakfast, MealLunch, MealDinner, MealSnack:
+		default:
+			errs[field] = "Допустимые приёмы пищи: breakfast, lunch, dinner, snack"
+			continue
+		}
+		if target.Calories <= 0 || target.Calories > MaxCalories {
+			errs[field+".calories"] = fmt.Sprintf("Значение должно быть от 1 до %d", MaxCalories)
+		}
+		for name, grams := range map[string]float64{"protein": target.Protein, "carbs": target.Carbs, "fat": target.Fat} {
+			if grams < 0 || grams > MaxMacroGrams {
+				errs[field+"."+name] = fmt.Sprintf("Значение должно быть от 0 до %d", MaxMacroGrams)
+			}
+		}
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > MaxNoteLength {
+		errs["note"] = fmt.Sprintf("Не более %d символов", MaxNoteLength)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// verifyLink returns apperrors.ErrForbidden unless curatorID actively curates clientID
+func (s *Service) verifyLink(ctx context.Context, curatorID, clientID int64) error {
+	var linked bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM curator_client_relationships WHERE curator_id = $1 AND client_id = $2 AND status = 'active')`,
+		curatorID, clientID,
+	).Scan(&linked)
+	if err != nil {
+		return fmt.Errorf("failed to verify relationship: %w", err)
+	}
+	if !linked {
+		return apperrors.ErrForbidden
+	}
+	return nil
+}
+
+// Create assigns a plan to clientID from req.StartDate on, superseding
+// the plan in effect then. Invalid input is reported as validation.Errors,
+// a client curatorID does not curate as apperrors.ErrForbidden.
+func (s *Service) Create(ctx context.Context, curatorID, clientID int64, req *CreatePlanRequest) (*Plan, error) {
+	if err := validatePlan(req, s.now()); err != nil {
+		return nil, err
+	}
+	if err := s.verifyLink(ctx, curatorID, clientID); err != nil {
+		return nil, err
+	}
+
+	meals, err := json.Marshal(req.Meals)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode meal targets: %w", err)
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO meal_plans (client_id, curator_id, start_date, meals, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + planColumns
+
+	plan, err := scanPlan(s.db.QueryRowContext(ctx, query, clientID, curatorID, req.StartDate, meals, req.Note))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create meal plan: %w", err)
+	}
+
+	s.log.LogBusinessEvent("meal_plan_assigned", map[string]interface{}{
+		"curator_id": curatorID,
+		"client_id":  clientID,
+		"plan_id":    plan.ID,
+		"start_date": plan.StartDate.String(),
+	})
+	return plan, nil
+}
+
+// PlanFor returns the plan in effect for clientID on day, or nil when no
+// plan has started by then
+func (s *Service) PlanFor(ctx context.Context, clientID int64, day types.Date) (*Plan, error) {
+	startTime := time.Now()
+	query := `SELECT ` + planColumns + ` FROM meal_plans WHERE client_id = $1 AND start_date <= $2`
+
+	rows, err := s.db.QueryContext(ctx, query, clientID, day)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"client_id": clientID,
+		"date":      day.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meal plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []Plan
+	for rows.Next() {
+		p, err := scanPlan(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan meal plan: %w", err)
+		}
+		plans = append(plans, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read meal plans: %w", err)
+	}
+	return effective(plans, day), nil
+}
+
+// effective returns the plan in effect on day: of the plans started by
+// then, the one with the latest start date, and of plans starting the same
+// day the one created last. It returns nil when no plan has started.
+func effective(plans []Plan, day types.Date) *Plan {
+	var current *Plan
+	for i := range plans {
+		p := &plans[i]
+		if p.StartDate.After(day) {
+			continue
+		}
+		if current == nil || p.StartDate.After(current.StartDate) ||
+			(!p.StartDate.Before(current.StartDate) && p.CreatedAt.After(current.CreatedAt)) {
+			current = p
+		}
+	}
+	return current
+}
@@ -0,0 +1,263 @@
+package mealplans
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testClientID  int64 = 42
+	testCuratorID int64 = 7
+)
+
+var (
+	testNow         = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	planColumnNames = []string{"id", "client_id", "curator_id", "start_date", "meals", "note", "created_at"}
+)
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	service := NewService(&database.DB{DB: db}, logger.New())
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func date(t *testing.T, value string) types.Date {
+	t.Helper()
+	d, err := types.ParseDate(value)
+	require.NoError(t, err)
+	return d
+}
+
+func plan(t *testing.T, id, startDate string, created time.Time) Plan {
+	t.Helper()
+	return Plan{ID: id, StartDate: date(t, startDate), CreatedAt: created}
+}
+
+func TestEffective(t *testing.T) {
+	early := testNow.Add(-time.Hour)
+	late := testNow
+
+	// Plans as assigned over time: a plan from Oct 1, one from Oct 10, a
+	// correction to it made the same day, and one scheduled for Nov 1
+	plans := []Plan{
+		plan(t, "october", "2026-10-01", early.AddDate(0, 0, -20)),
+		plan(t, "mid-october", "2026-10-10", early),
+		plan(t, "mid-october-fixed", "2026-10-10", late),
+		plan(t, "november", "2026-11-01", late.AddDate(0, 0, 1)),
+	}
+
+	tests := []struct {
+		day  string
+		want string
+	}{
+		{"2026-09-30", ""},
+		{"2026-10-01", "october"},
+		{"2026-10-09", "october"},
+		// The later plan takes over on its start date; of the two starting
+		// that day the one created last wins
+		{"2026-10-10", "mid-october-fixed"},
+		{"2026-10-31", "mid-october-fixed"},
+		{"2026-11-01", "november"},
+		{"2027-06-01", "november"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.day, func(t *testing.T) {
+			got := effective(plans, date(t, tt.day))
+
+			if tt.want == "" {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.Equal(t, tt.want, got.ID)
+		})
+	}
+
+	t.Run("order of the plans does not matter", func(t *testing.T) {
+		reversed := []Plan{plans[3], plans[2], plans[1], plans[0]}
+
+		assert.Equal(t, "mid-october-fixed", effective(reversed, date(t, "2026-10-20")).ID)
+	})
+
+	t.Run("a plan scheduled earlier for a later day supersedes a newer one", func(t *testing.T) {
+		// The November plan was created before a fresh October one, but
+		// start dates decide, not creation order
+		overlapping := []Plan{
+			plan(t, "november", "2026-11-01", early),
+			plan(t, "october", "2026-10-15", late),
+		}
+
+		assert.Equal(t, "october", effective(overlapping, date(t, "2026-10-31")).ID)
+		assert.Equal(t, "november", effective(overlapping, date(t, "2026-11-01")).ID)
+	})
+
+	t.Run("no plans", func(t *testing.T) {
+		assert.Nil(t, effective(nil, date(t, "2026-10-16")))
+	})
+}
+
+func validRequest(t *testing.T) *CreatePlanRequest {
+	return &CreatePlanRequest{
+		StartDate: date(t, "2026-10-20"),
+		Meals: map[string]Target{
+			MealBreakfast: {Calories: 500, Protein: 40, Carbs: 50, Fat: 15},
+			MealDinner:    {Calories: 700, Protein: 50, Carbs: 60, Fat: 25},
+		},
+		Note: "  Больше белка утром  ",
+	}
+}
+
+func TestValidatePlan(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		req := validRequest(t)
+
+		require.NoError(t, validatePlan(req, testNow))
+		assert.Equal(t, "Больше белка утром", req.Note)
+	})
+
+	t.Run("start date window", func(t *testing.T) {
+		for value, ok := range map[string]bool{
+			"2026-10-14": false,
+			"2026-10-15": true,
+			"2026-10-16": true,
+			"2027-10-17": true,
+			"2027-10-18": false,
+		} {
+			req := validRequest(t)
+			req.StartDate = date(t, value)
+
+			err := validatePlan(req, testNow)
+
+			if ok {
+				assert.NoError(t, err, value)
+			} else {
+				assert.Contains(t, validation.Fields(err), "start_date", value)
+			}
+		}
+	})
+
+	t.Run("invalid meals", func(t *testing.T) {
+		req := validRequest(t)
+		req.Meals = map[string]Target{
+			"elevenses":   {Calories: 200},
+			MealBreakfast: {Calories: 0, Protein: -1},
+			MealLunch:     {Calories: 600, Fat: 1001},
+		}
+
+		err := validatePlan(req, testNow)
+
+		assert.Equal(t, map[string]string{
+			"meals.elevenses":          "Допустимые приёмы пищи: breakfast, lunch, dinner, snack",
+			"meals.breakfast.calories": "Значение должно быть от 1 до 10000",
+			"meals.breakfast.protein":  "Значение должно быть от 0 до 1000",
+			"meals.lunch.fat":          "Значение должно быть от 0 до 1000",
+		}, validation.Fields(err))
+	})
+
+	t.Run("no meals", func(t *testing.T) {
+		req := validRequest(t)
+		req.Meals = map[string]Target{}
+
+		assert.Equal(t, map[string]string{"meals": "Укажите цели хотя бы для одного приёма пищи"}, validation.Fields(validatePlan(req, testNow)))
+	})
+}
+
+func TestService_Create(t *testing.T) {
+	t.Run("assigned", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM curator_client_relationships").
+			WithArgs(testCuratorID, testClientID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("INSERT INTO meal_plans").
+			WithArgs(testClientID, testCuratorID, "2026-10-20", sqlmock.AnyArg(), "Больше белка утром").
+			WillReturnRows(sqlmock.NewRows(planColumnNames).
+				AddRow("plan-1", testClientID, testCuratorID, "2026-10-20",
+					`{"breakfast":{"calories":500,"protein":40,"carbs":50,"fat":15}}`, "Больше белка утром", testNow))
+
+		plan, err := service.Create(context.Background(), testCuratorID, testClientID, validRequest(t))
+
+		require.NoError(t, err)
+		assert.Equal(t, "plan-1", plan.ID)
+		assert.Equal(t, "2026-10-20", plan.StartDate.String())
+		assert.Equal(t, Target{Calories: 500, Protein: 40, Carbs: 50, Fat: 15}, plan.Meals[MealBreakfast])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("client of another curator", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM curator_client_relationships").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := service.Create(context.Background(), testCuratorID, testClientID, validRequest(t))
+
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid request is rejected before the database", func(t *testing.T) {
+		service, mock := setupService(t)
+		req := validRequest(t)
+		req.Meals = nil
+
+		_, err := service.Create(context.Background(), testCuratorID, testClientID, req)
+
+		assert.Contains(t, validation.Fields(err), "meals")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_PlanFor(t *testing.T) {
+	t.Run("latest plan started by the day", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery(`FROM meal_plans WHERE client_id = \$1 AND start_date <= \$2`).
+			WithArgs(testClientID, "2026-10-16").
+			WillReturnRows(sqlmock.NewRows(planColumnNames).
+				AddRow("old", testClientID, testCuratorID, "2026-09-01", `{"lunch":{"calories":600}}`, "", testNow.AddDate(0, -2, 0)).
+				AddRow("current", testClientID, nil, "2026-10-01", `{"lunch":{"calories":650}}`, "", testNow.AddDate(0, -1, 0)))
+
+		plan, err := service.PlanFor(context.Background(), testClientID, date(t, "2026-10-16"))
+
+		require.NoError(t, err)
+		require.NotNil(t, plan)
+		assert.Equal(t, "current", plan.ID)
+		assert.Nil(t, plan.CuratorID)
+		assert.Equal(t, 650.0, plan.Meals[MealLunch].Calories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no plan", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM meal_plans").WillReturnRows(sqlmock.NewRows(planColumnNames))
+
+		plan, err := service.PlanFor(context.Background(), testClientID, date(t, "2026-10-16"))
+
+		require.NoError(t, err)
+		assert.Nil(t, plan)
+	})
+
+	t.Run("query fails", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM meal_plans").WillReturnError(errors.New("connection reset"))
+
+		_, err := service.PlanFor(context.Background(), testClientID, date(t, "2026-10-16"))
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+package mealplans
+
+import (
+	"time"
+
+	"github.com/burcev/api/internal/shared/types"
+)
+
+// Meals a plan sets targets for
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnack     = "snack"
+)
+
+// Plan limits
+const (
+	MaxCalories   = 10000
+	MaxMacroGrams = 1000
+	MaxNoteLength = 500
+	// MaxStartDaysAhead bounds how far ahead a plan may be scheduled
+	MaxStartDaysAhead = 366
+)
+
+// Target is the calories and macros a plan sets for one meal
+type Target struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// Plan is a meal plan a curator assigned to a client. It is in effect from
+// StartDate until a plan with a later start date takes over. Meals only
+// holds the meals the plan sets targets for.
+type Plan struct {
+	ID        string            `json:"id"`
+	ClientID  int64             `json:"client_id"`
+	CuratorID *int64            `json:"curator_id"`
+	StartDate types.Date        `json:"start_date"`
+	Meals     map[string]Target `json:"meals"`
+	Note      string            `json:"note"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CreatePlanRequest assigns a plan to a client, superseding their current
+// plan from start_date. meals is keyed by breakfast, lunch, dinner and
+// snack; meals left out have no target.
+type CreatePlanRequest struct {
+	StartDate types.Date        `json:"start_date" binding:"required"`
+	Meals     map[string]Target `json:"meals" binding:"required"`
+	Note      string            `json:"note"`
+}
+
+// PlanResponse is one plan; Plan is nil when no plan is in effect
+type PlanResponse struct {
+	Plan *Plan `json:"plan"`
+}
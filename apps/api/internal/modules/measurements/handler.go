@@ -0,0 +1,119 @@
+package measurements
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles measurements requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	db      *database.DB
+	service ServiceInterface
+}
+
+// NewHandler creates a new measurements handler
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		db:      db,
+		service: service,
+	}
+}
+
+// GetWeightTrend handles GET /api/v1/measurements/weight-trend?days=90
+// Returns daily weights with a 7-day EMA, change, weekly rate and goal projection.
+// days is capped at 365. Weights are in the user's unit system unless
+// ?units=metric|imperial overrides it.
+func (h *Handler) GetWeightTrend(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	days := DefaultTrendDays
+	if d := c.Query("days"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 {
+			response.Error(c, http.StatusBadRequest, "Параметр days должен быть положительным числом")
+			return
+		}
+		days = parsed
+	}
+
+	userLoc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
+	today := time.Now().In(userLoc)
+
+	system, ok := middleware.RequestUnits(c, h.db, userID)
+	if !ok {
+		return
+	}
+
+	trend, err := h.service.GetWeightTrend(c.Request.Context(), userID, days, today)
+	if err != nil {
+		h.log.Error("Failed to get weight trend", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось загрузить динамику веса")
+		return
+	}
+
+	response.Success(c, http.StatusOK, trend.InUnits(system))
+}
+
+// ListMeasurements handles GET /api/v1/measurements?sort=&limit=&offset=
+// Returns a page of body measurements, newest first unless sort (date or
+// created_at, optionally :asc or :desc) says otherwise. Lengths are in the
+// user's unit system unless ?units=metric|imperial overrides it.
+func (h *Handler) ListMeasurements(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	sort, err := listing.ParseSort(c.Query("sort"), SortFields, DefaultSort)
+	if err != nil {
+		validation.Respond(c, err)
+		return
+	}
+	page := listing.ParsePage(c.Query("limit"), c.Query("offset"), DefaultPageSize, MaxPageSize)
+
+	system, ok := middleware.RequestUnits(c, h.db, userID)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.ListMeasurements(c.Request.Context(), userID, sort, page)
+	if err != nil {
+		h.log.Error("Failed to list measurements", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось загрузить замеры")
+		return
+	}
+
+	response.Success(c, http.StatusOK, list.InUnits(system))
+}
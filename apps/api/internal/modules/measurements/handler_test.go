@@ -0,0 +1,119 @@
+package measurements
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	gotDays int
+	gotSort listing.Sort
+	gotPage listing.Page
+}
+
+func (m *mockService) GetWeightTrend(ctx context.Context, userID int64, days int, today time.Time) (*WeightTrend, error) {
+	m.gotDays = days
+	return &WeightTrend{Days: days, Points: []WeightTrendPoint{}}, nil
+}
+
+func (m *mockService) ListMeasurements(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) (*MeasurementList, error) {
+	m.gotSort, m.gotPage = sort, page
+	waist := 81.0
+	return &MeasurementList{LengthUnit: "cm", Measurements: []Measurement{{Date: "2026-03-01", Waist: &waist}}}, nil
+}
+
+func TestHandlerGetWeightTrend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		wantDays int
+	}{
+		{"default window", "", http.StatusOK, DefaultTrendDays},
+		{"custom window", "?days=30", http.StatusOK, 30},
+		{"zero days", "?days=0", http.StatusBadRequest, 0},
+		{"not a number", "?days=abc", http.StatusBadRequest, 0},
+		{"imperial override", "?units=imperial", http.StatusOK, DefaultTrendDays},
+		{"unknown units", "?units=stone", http.StatusBadRequest, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{}
+			handler := NewHandler(nil, logger.New(), nil, svc)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/measurements/weight-trend"+tt.query, nil)
+			c.Set("user_id", int64(1))
+
+			handler.GetWeightTrend(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.wantDays, svc.gotDays)
+		})
+	}
+}
+
+func TestHandlerListMeasurements(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		query    string
+		status   int
+		wantSort listing.Sort
+		wantPage listing.Page
+	}{
+		{"defaults", "", http.StatusOK, DefaultSort, listing.Page{Limit: DefaultPageSize}},
+		{"sorted second page", "?sort=created_at:asc&limit=10&offset=10", http.StatusOK,
+			listing.Sort{Column: "created_at"}, listing.Page{Limit: 10, Offset: 10}},
+		{"limit capped", "?limit=1000", http.StatusOK, DefaultSort, listing.Page{Limit: MaxPageSize}},
+		{"nutrition field", "?sort=calories", http.StatusBadRequest, listing.Sort{}, listing.Page{}},
+		{"injection attempt", "?sort=date%3BDROP%20TABLE%20body_measurements", http.StatusBadRequest, listing.Sort{}, listing.Page{}},
+		{"unknown units", "?units=stone", http.StatusBadRequest, listing.Sort{}, listing.Page{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{}
+			handler := NewHandler(nil, logger.New(), nil, svc)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/measurements"+tt.query, nil)
+			c.Set("user_id", int64(1))
+
+			handler.ListMeasurements(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.wantSort, svc.gotSort)
+			assert.Equal(t, tt.wantPage, svc.gotPage)
+		})
+	}
+
+	t.Run("imperial lengths", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), nil, &mockService{})
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/measurements?units=imperial", nil)
+		c.Set("user_id", int64(1))
+
+		handler.ListMeasurements(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"length_unit":"in"`)
+		assert.Contains(t, w.Body.String(), `"waist":32`)
+	})
+}
@@ -0,0 +1,133 @@
+package measurements
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/units"
+)
+
+// Accepted body measurement range (cm)
+const (
+	minMeasurementCm = 10
+	maxMeasurementCm = 300
+)
+
+// resolveImportColumns checks the client's column mapping for an import kind
+func resolveImportColumns(kind string, mapping csvimport.Mapping, header []string) (csvimport.Columns, error) {
+	if kind == ImportKindWeight {
+		return mapping.Resolve(header, weightImportFields, "date", "weight")
+	}
+
+	cols, err := mapping.Resolve(header, measurementImportFields, "date")
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) < 2 {
+		return nil, fmt.Errorf("%w: не указана ни одна колонка с замерами", csvimport.ErrInvalidFile)
+	}
+	return cols, nil
+}
+
+// buildImportRows validates every record. Rows with a bad date or value are
+// reported as row errors; dates after today are rejected. Values are read in
+// the given unit system and converted to kg and cm before validation.
+func buildImportRows(kind string, cols csvimport.Columns, records []csvimport.Record, today time.Time, system string) ([]ImportRow, []csvimport.RowError) {
+	rows := make([]ImportRow, 0, len(records))
+	var rowErrors []csvimport.RowError
+
+	for _, record := range records {
+		row, err := buildImportRow(kind, cols, record, today, system)
+		if err != nil {
+			rowErrors = append(rowErrors, csvimport.RowError{Line: record.Line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors
+}
+
+func buildImportRow(kind string, cols csvimport.Columns, record csvimport.Record, today time.Time, system string) (ImportRow, error) {
+	dateStr := cols.Get(record, "date")
+	if dateStr == "" {
+		return ImportRow{}, errors.New("Не указана дата")
+	}
+	date, err := csvimport.ParseDate(dateStr)
+	if err != nil {
+		return ImportRow{}, fmt.Errorf("Неверная дата: %s", dateStr)
+	}
+	if date.Format("2006-01-02") > today.Format("2006-01-02") {
+		return ImportRow{}, fmt.Errorf("Дата в будущем: %s", dateStr)
+	}
+
+	row := ImportRow{Line: record.Line, Date: date.Format("2006-01-02"), Values: make(map[string]float64)}
+	for field := range cols {
+		if field == "date" {
+			continue
+		}
+		cell := cols.Get(record, field)
+		if cell == "" {
+			continue
+		}
+		value, err := csvimport.ParseNumber(cell)
+		if err != nil {
+			return ImportRow{}, fmt.Errorf("Неверное значение %s: %s", field, cell)
+		}
+		if field == "weight" {
+			value = units.WeightToKg(value, system)
+		} else {
+			value = units.LengthToCm(value, system)
+		}
+		if err := validateImportValue(field, value); err != nil {
+			return ImportRow{}, err
+		}
+		row.Values[field] = value
+	}
+
+	switch {
+	case kind == ImportKindWeight && len(row.Values) == 0:
+		return ImportRow{}, errors.New("Не указан вес")
+	case len(row.Values) == 0:
+		return ImportRow{}, errors.New("Не указан ни один замер")
+	}
+
+	return row, nil
+}
+
+func validateImportValue(field string, value float64) error {
+	if field == "weight" {
+		if value <= 0 || value > 500 {
+			return errors.New("Вес должен быть от 0 до 500 кг")
+		}
+		return nil
+	}
+	if value < minMeasurementCm || value > maxMeasurementCm {
+		return fmt.Errorf("Замер %s должен быть от %d до %d см", field, minMeasurementCm, maxMeasurementCm)
+	}
+	return nil
+}
+
+// dedupeByDate keeps one row per date. With StrategySkip the first row of a
+// date wins, with StrategyReplace the last one does, mirroring how the
+// strategy treats values already stored. Returns the kept rows in file order
+// and how many rows were dropped.
+func dedupeByDate(rows []ImportRow, strategy string) ([]ImportRow, int) {
+	kept := make([]ImportRow, 0, len(rows))
+	index := make(map[string]int, len(rows))
+
+	for _, row := range rows {
+		i, seen := index[row.Date]
+		switch {
+		case !seen:
+			index[row.Date] = len(kept)
+			kept = append(kept, row)
+		case strategy == StrategyReplace:
+			kept[i] = row
+		}
+	}
+
+	return kept, len(rows) - len(kept)
+}
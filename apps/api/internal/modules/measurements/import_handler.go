@@ -0,0 +1,169 @@
+package measurements
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/units"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles weight and body measurement history imports
+type ImportHandler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	db      *database.DB
+	service ImportServiceInterface
+}
+
+// NewImportHandler creates a new history import handler
+func NewImportHandler(cfg *config.Config, log *logger.Logger, db *database.DB, service ImportServiceInterface) *ImportHandler {
+	return &ImportHandler{
+		cfg:     cfg,
+		log:     log,
+		db:      db,
+		service: service,
+	}
+}
+
+// ImportWeight handles POST /api/v1/users/weight/import
+// Mappable fields: date and weight (both required).
+func (h *ImportHandler) ImportWeight(c *gin.Context) {
+	h.importHistory(c, ImportKindWeight)
+}
+
+// ImportMeasurements handles POST /api/v1/users/measurements/import
+// Mappable fields: date (required) and any of waist, chest, hips, thigh, arm
+// and neck in centimetres.
+func (h *ImportHandler) ImportMeasurements(c *gin.Context) {
+	h.importHistory(c, ImportKindMeasurements)
+}
+
+// importHistory reads the multipart form shared by both imports:
+//   - file: the CSV export
+//   - mapping: JSON from field to column letter or header name, e.g. {"date":"A","weight":"C"}
+//   - strategy: skip (default) keeps values already stored for a date, replace overwrites them
+//   - header: "false" when the file has no header row
+//   - units: metric or imperial; defaults to the user's unit preference
+//
+// The rows are written in the background; the response is the queued import
+// whose progress is available at GET /api/v1/users/imports/:id.
+func (h *ImportHandler) importHistory(c *gin.Context, kind string) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	strategy := c.DefaultPostForm("strategy", StrategySkip)
+	if strategy != StrategySkip && strategy != StrategyReplace {
+		response.Error(c, http.StatusBadRequest, "Параметр strategy должен быть skip или replace")
+		return
+	}
+
+	system := c.PostForm("units")
+	switch {
+	case system == "":
+		system = middleware.GetUserUnits(c.Request.Context(), h.db, userID)
+	case !units.Valid(system):
+		response.Error(c, http.StatusBadRequest, "Параметр units должен быть metric или imperial")
+		return
+	}
+
+	mapping, err := csvimport.ParseMapping(c.PostForm("mapping"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Требуется CSV-файл в поле file")
+		return
+	}
+	if header.Size > MaxImportFileSize {
+		response.Error(c, http.StatusBadRequest, "Файл слишком большой (максимум 2 МБ)")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Не удалось прочитать файл")
+		return
+	}
+	defer file.Close()
+
+	columns, records, err := csvimport.Read(file, c.DefaultPostForm("header", "true") != "false", MaxImportRows)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	imp, err := h.service.CreateImport(c.Request.Context(), userID, ImportRequest{
+		Kind:     kind,
+		Strategy: strategy,
+		Mapping:  mapping,
+		Header:   columns,
+		Records:  records,
+		Today:    time.Now().In(middleware.GetUserTimezone(c.Request.Context(), h.db, userID)),
+		Units:    system,
+	})
+	if err != nil {
+		if errors.Is(err, csvimport.ErrInvalidFile) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.log.Error("Failed to create history import", "error", err, "user_id", userID, "kind", kind)
+		response.InternalError(c, "Не удалось загрузить файл")
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, imp)
+}
+
+// GetImport handles GET /api/v1/users/imports/:id
+// Returns the import's status, progress and per-row errors.
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	importID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	imp, err := h.service.GetImport(c.Request.Context(), userID, importID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Импорт не найден")
+			return
+		}
+		h.log.Error("Failed to get history import", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось загрузить статус импорта")
+		return
+	}
+
+	response.Success(c, http.StatusOK, imp)
+}
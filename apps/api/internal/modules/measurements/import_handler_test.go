@@ -0,0 +1,125 @@
+package measurements
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockImportService implements ImportServiceInterface for handler tests
+type mockImportService struct {
+	got *ImportRequest
+}
+
+func (m *mockImportService) CreateImport(ctx context.Context, userID int64, req ImportRequest) (*HistoryImport, error) {
+	m.got = &req
+	if _, err := resolveImportColumns(req.Kind, req.Mapping, req.Header); err != nil {
+		return nil, err
+	}
+	return &HistoryImport{ID: importID1, Kind: req.Kind, Strategy: req.Strategy, Status: ImportPending}, nil
+}
+
+func (m *mockImportService) GetImport(ctx context.Context, userID int64, id string) (*HistoryImport, error) {
+	if id != importID1 {
+		return nil, apperrors.ErrNotFound
+	}
+	return &HistoryImport{ID: id, Status: ImportDone, Progress: 100}, nil
+}
+
+func importForm(t *testing.T, fields map[string]string, csv string) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	for k, v := range fields {
+		require.NoError(t, writer.WriteField(k, v))
+	}
+	if csv != "" {
+		part, err := writer.CreateFormFile("file", "weights.csv")
+		require.NoError(t, err)
+		_, err = part.Write([]byte(csv))
+		require.NoError(t, err)
+	}
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestImportHandlerImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	weights := "Дата,Вес\n01.03.2026,82\n"
+
+	tests := []struct {
+		name     string
+		kind     string
+		fields   map[string]string
+		csv      string
+		status   int
+		strategy string
+	}{
+		{"weight with default strategy", ImportKindWeight, map[string]string{"mapping": `{"date":"A","weight":"Вес"}`}, weights, http.StatusAccepted, StrategySkip},
+		{"measurements with replace", ImportKindMeasurements, map[string]string{"mapping": `{"date":"A","waist":"B"}`, "strategy": "replace"}, weights, http.StatusAccepted, StrategyReplace},
+		{"unknown strategy", ImportKindWeight, map[string]string{"mapping": `{"date":"A","weight":"B"}`, "strategy": "merge"}, weights, http.StatusBadRequest, ""},
+		{"missing mapping", ImportKindWeight, nil, weights, http.StatusBadRequest, ""},
+		{"missing file", ImportKindWeight, map[string]string{"mapping": `{"date":"A","weight":"B"}`}, "", http.StatusBadRequest, ""},
+		{"header only", ImportKindWeight, map[string]string{"mapping": `{"date":"A","weight":"B"}`}, "Дата,Вес\n", http.StatusBadRequest, ""},
+		{"unmapped column", ImportKindWeight, map[string]string{"mapping": `{"date":"A","weight":"Масса"}`}, weights, http.StatusBadRequest, StrategySkip},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockImportService{}
+			handler := NewImportHandler(nil, logger.New(), nil, svc)
+
+			body, contentType := importForm(t, tt.fields, tt.csv)
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/users/weight/import", body)
+			c.Request.Header.Set("Content-Type", contentType)
+			c.Set("user_id", int64(1))
+
+			if tt.kind == ImportKindWeight {
+				handler.ImportWeight(c)
+			} else {
+				handler.ImportMeasurements(c)
+			}
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.strategy == "" {
+				assert.Nil(t, svc.got)
+				return
+			}
+			require.NotNil(t, svc.got)
+			assert.Equal(t, tt.kind, svc.got.Kind)
+			assert.Equal(t, tt.strategy, svc.got.Strategy)
+			assert.Equal(t, []string{"Дата", "Вес"}, svc.got.Header)
+			assert.Equal(t, []csvimport.Record{{Line: 2, Fields: []string{"01.03.2026", "82"}}}, svc.got.Records)
+		})
+	}
+}
+
+func TestImportHandlerGetImport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for id, status := range map[string]int{importID1: http.StatusOK, importID2: http.StatusNotFound, "imp-1": http.StatusBadRequest} {
+		handler := NewImportHandler(nil, logger.New(), nil, &mockImportService{})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/users/imports/"+id, nil)
+		c.Params = gin.Params{{Key: "id", Value: id}}
+		c.Set("user_id", int64(1))
+
+		handler.GetImport(c)
+
+		assert.Equal(t, status, w.Code, id)
+	}
+}
@@ -0,0 +1,395 @@
+package measurements
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/ids"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/google/uuid"
+)
+
+// ImportServiceInterface defines the interface for history import operations
+type ImportServiceInterface interface {
+	CreateImport(ctx context.Context, userID int64, req ImportRequest) (*HistoryImport, error)
+	GetImport(ctx context.Context, userID int64, id string) (*HistoryImport, error)
+}
+
+// ImportService validates uploaded weight and measurement histories and
+// writes them in the background
+type ImportService struct {
+	db  *database.DB
+	log *logger.Logger
+	ids *ids.Generator
+}
+
+// NewImportService creates a new history import service
+func NewImportService(db *database.DB, log *logger.Logger) *ImportService {
+	return &ImportService{
+		db:  db,
+		log: log,
+		ids: ids.Default,
+	}
+}
+
+// Body measurement fields and their columns
+var measurementColumns = []struct{ field, column string }{
+	{"waist", "waist_cm"},
+	{"chest", "chest_cm"},
+	{"hips", "hips_cm"},
+	{"thigh", "thigh_cm"},
+	{"arm", "arm_cm"},
+	{"neck", "neck_cm"},
+}
+
+// CreateImport validates the file against the column mapping and queues the
+// valid rows. Invalid rows are reported in the import's row errors right away.
+// A mapping that cannot be applied returns csvimport.ErrInvalidFile.
+func (s *ImportService) CreateImport(ctx context.Context, userID int64, req ImportRequest) (*HistoryImport, error) {
+	cols, err := resolveImportColumns(req.Kind, req.Mapping, req.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, rowErrors := buildImportRows(req.Kind, cols, req.Records, req.Today, req.Units)
+	rows, duplicates := dedupeByDate(rows, req.Strategy)
+	if rowErrors == nil {
+		rowErrors = []csvimport.RowError{}
+	}
+
+	rowsJSON, err := json.Marshal(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import rows: %w", err)
+	}
+	errorsJSON, err := json.Marshal(rowErrors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode import row errors: %w", err)
+	}
+
+	// Nothing left to write: the import is complete as soon as it is created
+	status := ImportPending
+	var completedAt *time.Time
+	if len(rows) == 0 {
+		now := time.Now()
+		status, completedAt = ImportDone, &now
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO history_imports (
+			id, user_id, kind, strategy, status, rows, total_rows, processed_rows,
+			skipped_rows, failed_rows, row_errors, completed_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING ` + historyImportColumns
+
+	imp, err := scanHistoryImport(s.db.QueryRowContext(ctx, query,
+		s.ids.NewString(), userID, req.Kind, req.Strategy, status, rowsJSON, len(req.Records),
+		len(rowErrors)+duplicates, duplicates, len(rowErrors), errorsJSON, completedAt,
+	))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"kind":    req.Kind,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create history import: %w", err)
+	}
+
+	s.log.LogBusinessEvent("history_import_created", map[string]interface{}{
+		"user_id":    userID,
+		"import_id":  imp.ID,
+		"kind":       req.Kind,
+		"strategy":   req.Strategy,
+		"total_rows": imp.TotalRows,
+		"queued":     len(rows),
+		"failed":     imp.FailedRows,
+	})
+
+	return imp, nil
+}
+
+// GetImport returns the user's import with its progress.
+// Imports of other users are reported as apperrors.ErrNotFound.
+func (s *ImportService) GetImport(ctx context.Context, userID int64, id string) (*HistoryImport, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `SELECT ` + historyImportColumns + ` FROM history_imports WHERE id = $1 AND user_id = $2`
+
+	imp, err := scanHistoryImport(s.db.QueryRowContext(ctx, query, id, userID))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":   userID,
+		"import_id": id,
+	})
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history import: %w", err)
+	}
+
+	return imp, nil
+}
+
+// ProcessNextImport claims one queued import and writes its remaining rows
+// batch by batch, saving progress after each batch so a retried import
+// resumes where it stopped. Returns false when nothing was due.
+func (s *ImportService) ProcessNextImport(ctx context.Context) (bool, error) {
+	startTime := time.Now()
+
+	claimQuery := `
+		UPDATE history_imports
+		SET status = 'processing', attempts = attempts + 1,
+		    next_attempt_at = NOW() + $1 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM history_imports
+			WHERE status IN ('pending', 'processing') AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, kind, strategy, rows, total_rows, processed_rows, attempts
+	`
+
+	var id, kind, strategy string
+	var userID int64
+	var rowsJSON []byte
+	var total, processed, attempts int
+	err := s.db.QueryRowContext(ctx, claimQuery, int(importLease.Seconds())).
+		Scan(&id, &userID, &kind, &strategy, &rowsJSON, &total, &processed, &attempts)
+	s.log.LogDatabaseQuery(claimQuery, time.Since(startTime), err, nil)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim history import: %w", err)
+	}
+
+	// The previous attempt's lease ran out without it finishing or failing
+	if attempts > MaxImportAttempts {
+		return true, s.markImportFailed(ctx, id, fmt.Errorf("import did not finish after %d attempts", MaxImportAttempts))
+	}
+
+	var rows []ImportRow
+	if err := json.Unmarshal(rowsJSON, &rows); err != nil {
+		return true, s.markImportFailed(ctx, id, fmt.Errorf("failed to decode import rows: %w", err))
+	}
+
+	// Rows that never made it into the queue were counted as processed upfront
+	offset := processed - (total - len(rows))
+	imported, skipped := 0, 0
+	for start := max(offset, 0); start < len(rows); start += importBatchSize {
+		batch := rows[start:min(start+importBatchSize, len(rows))]
+		batchImported, err := s.writeBatch(ctx, id, userID, kind, strategy, batch)
+		if err != nil {
+			s.log.Error("History import batch failed", "error", err, "import_id", id, "attempt", attempts)
+			if attempts < MaxImportAttempts {
+				return true, err
+			}
+			return true, s.markImportFailed(ctx, id, err)
+		}
+		imported += batchImported
+		skipped += len(batch) - batchImported
+	}
+
+	startTime = time.Now()
+	query := `
+		UPDATE history_imports
+		SET status = 'done', rows = '[]', error = NULL, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = s.db.ExecContext(ctx, query, id)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"import_id": id,
+	})
+	if err != nil {
+		return true, fmt.Errorf("failed to complete history import: %w", err)
+	}
+
+	s.log.LogBusinessEvent("history_import_completed", map[string]interface{}{
+		"user_id":   userID,
+		"import_id": id,
+		"kind":      kind,
+		"imported":  imported,
+		"skipped":   skipped,
+	})
+
+	return true, nil
+}
+
+// writeBatch writes rows and advances the import's progress in one
+// transaction. Returns how many rows were written; the rest were skipped
+// because their date already had a value.
+func (s *ImportService) writeBatch(ctx context.Context, id string, userID int64, kind, strategy string, batch []ImportRow) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := importUpsertQuery(kind, strategy)
+	imported := 0
+	for _, row := range batch {
+		var args []interface{}
+		if kind == ImportKindWeight {
+			args = []interface{}{userID, row.Date, row.Values["weight"]}
+		} else {
+			// The id is only used when the date has no measurements yet
+			args = []interface{}{s.ids.NewString(), userID, row.Date}
+			for _, m := range measurementColumns {
+				if v, ok := row.Values[m.field]; ok {
+					args = append(args, v)
+				} else {
+					args = append(args, nil)
+				}
+			}
+		}
+
+		res, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return 0, fmt.Errorf("failed to write %s for line %d: %w", kind, row.Line, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			imported++
+		}
+	}
+
+	startTime := time.Now()
+	progressQuery := `
+		UPDATE history_imports
+		SET processed_rows = processed_rows + $2, imported_rows = imported_rows + $3,
+		    skipped_rows = skipped_rows + $4,
+		    next_attempt_at = NOW() + $5 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = tx.ExecContext(ctx, progressQuery, id, len(batch), imported, len(batch)-imported, int(importLease.Seconds()))
+	s.log.LogDatabaseQuery(progressQuery, time.Since(startTime), err, map[string]interface{}{
+		"import_id": id,
+		"rows":      len(batch),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save import progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit import batch: %w", err)
+	}
+
+	return imported, nil
+}
+
+// importUpsertQuery returns the per-row write for an import. With
+// StrategySkip a date that already has a value is left alone (0 rows
+// affected); with StrategyReplace the imported values overwrite it.
+// Measurement columns missing from a row keep their stored value.
+func importUpsertQuery(kind, strategy string) string {
+	if kind == ImportKindWeight {
+		query := `
+			INSERT INTO daily_metrics (user_id, date, weight)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, date) DO UPDATE
+			SET weight = EXCLUDED.weight, updated_at = NOW()`
+		if strategy == StrategySkip {
+			query += `
+			WHERE daily_metrics.weight IS NULL`
+		}
+		return query
+	}
+
+	query := `
+		INSERT INTO body_measurements (id, user_id, date, waist_cm, chest_cm, hips_cm, thigh_cm, arm_cm, neck_cm)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (user_id, date) DO `
+	if strategy == StrategySkip {
+		return query + `NOTHING`
+	}
+	query += `UPDATE SET updated_at = NOW()`
+	for _, m := range measurementColumns {
+		query += fmt.Sprintf(", %[1]s = COALESCE(EXCLUDED.%[1]s, body_measurements.%[1]s)", m.column)
+	}
+	return query
+}
+
+func (s *ImportService) markImportFailed(ctx context.Context, id string, cause error) error {
+	startTime := time.Now()
+	query := `
+		UPDATE history_imports
+		SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := s.db.ExecContext(ctx, query, id, cause.Error())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"import_id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark history import failed: %w", err)
+	}
+	return nil
+}
+
+// RunImportWorker processes queued history imports until ctx is cancelled
+func (s *ImportService) RunImportWorker(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("History import worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessNextImport(ctx)
+				if err != nil {
+					s.log.Error("Failed to process history import", "error", err)
+					break
+				}
+				if !processed || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("History import worker stopped")
+			return
+		}
+	}
+}
+
+const historyImportColumns = `id, kind, strategy, status, total_rows, processed_rows, imported_rows,
+	skipped_rows, failed_rows, row_errors, error, created_at, completed_at`
+
+func scanHistoryImport(row *sql.Row) (*HistoryImport, error) {
+	var imp HistoryImport
+	var rowErrors []byte
+	var errMsg sql.NullString
+	var completedAt sql.NullTime
+
+	err := row.Scan(&imp.ID, &imp.Kind, &imp.Strategy, &imp.Status, &imp.TotalRows, &imp.ProcessedRows,
+		&imp.ImportedRows, &imp.SkippedRows, &imp.FailedRows, &rowErrors, &errMsg, &imp.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(rowErrors, &imp.RowErrors); err != nil {
+		return nil, fmt.Errorf("failed to decode import row errors: %w", err)
+	}
+	if errMsg.Valid {
+		imp.Error = &errMsg.String
+	}
+	if completedAt.Valid {
+		imp.CompletedAt = &completedAt.Time
+	}
+	imp.Progress = 100
+	if imp.TotalRows > 0 {
+		imp.Progress = imp.ProcessedRows * 100 / imp.TotalRows
+	}
+
+	return &imp, nil
+}
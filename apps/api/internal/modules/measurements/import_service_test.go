@@ -0,0 +1,192 @@
+package measurements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Import ids as the service generates them: UUIDv7
+const (
+	importID1 = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d01"
+	importID2 = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d02"
+	importID3 = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d03"
+)
+
+func setupImportService(t *testing.T) (*ImportService, sqlmock.Sqlmock) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewImportService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+var historyImportRowColumns = []string{
+	"id", "kind", "strategy", "status", "total_rows", "processed_rows", "imported_rows",
+	"skipped_rows", "failed_rows", "row_errors", "error", "created_at", "completed_at",
+}
+
+func TestCreateImport(t *testing.T) {
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("queues valid rows and records row errors", func(t *testing.T) {
+		service, mock := setupImportService(t)
+
+		rowsJSON := `[{"line":1,"date":"2026-03-01","values":{"weight":82}},{"line":3,"date":"2026-03-02","values":{"weight":81.5}}]`
+		errorsJSON := `[{"line":4,"error":"Неверная дата: 31.02.2026"}]`
+		mock.ExpectQuery("INSERT INTO history_imports").
+			WithArgs(sqlmock.AnyArg(), int64(7), ImportKindWeight, StrategySkip, ImportPending, []byte(rowsJSON), 4, 2, 1, 1, []byte(errorsJSON), nil).
+			WillReturnRows(sqlmock.NewRows(historyImportRowColumns).AddRow(
+				importID1, ImportKindWeight, StrategySkip, ImportPending, 4, 2, 0, 1, 1, []byte(errorsJSON), nil, time.Now(), nil,
+			))
+
+		imp, err := service.CreateImport(context.Background(), 7, ImportRequest{
+			Kind:     ImportKindWeight,
+			Strategy: StrategySkip,
+			Mapping:  csvimport.Mapping{"date": "A", "weight": "B"},
+			Records: []csvimport.Record{
+				record(1, "01.03.2026", "82"),
+				record(2, "01.03.2026", "83"),
+				record(3, "02.03.2026", "81,5"),
+				record(4, "31.02.2026", "81"),
+			},
+			Today: today,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, importID1, imp.ID)
+		assert.Equal(t, 50, imp.Progress)
+		assert.Equal(t, []csvimport.RowError{{Line: 4, Error: "Неверная дата: 31.02.2026"}}, imp.RowErrors)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects a mapping without required fields", func(t *testing.T) {
+		service, mock := setupImportService(t)
+
+		_, err := service.CreateImport(context.Background(), 7, ImportRequest{
+			Kind:     ImportKindWeight,
+			Strategy: StrategySkip,
+			Mapping:  csvimport.Mapping{"date": "A"},
+			Records:  []csvimport.Record{record(1, "01.03.2026", "82")},
+			Today:    today,
+		})
+
+		assert.ErrorIs(t, err, csvimport.ErrInvalidFile)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetImport(t *testing.T) {
+	t.Run("malformed id is not found", func(t *testing.T) {
+		service, _ := setupImportService(t)
+
+		_, err := service.GetImport(context.Background(), 7, "not-a-uuid")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("other user's import is not found", func(t *testing.T) {
+		service, mock := setupImportService(t)
+		id := "0b8f6f4e-8a44-4a8b-9c59-5d8a4e0b2c11"
+
+		mock.ExpectQuery("FROM history_imports WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(id, int64(7)).
+			WillReturnRows(sqlmock.NewRows(historyImportRowColumns))
+
+		_, err := service.GetImport(context.Background(), 7, id)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestProcessNextImport(t *testing.T) {
+	claimColumns := []string{"id", "user_id", "kind", "strategy", "rows", "total_rows", "processed_rows", "attempts"}
+
+	t.Run("nothing queued", func(t *testing.T) {
+		service, mock := setupImportService(t)
+
+		mock.ExpectQuery("UPDATE history_imports").WillReturnRows(sqlmock.NewRows(claimColumns))
+
+		processed, err := service.ProcessNextImport(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+
+	t.Run("skip counts dates that already have a weight as skipped", func(t *testing.T) {
+		service, mock := setupImportService(t)
+		rows := `[{"line":2,"date":"2026-03-01","values":{"weight":82}},{"line":3,"date":"2026-03-02","values":{"weight":81.5}}]`
+
+		// One file row failed validation, so one row is already processed
+		mock.ExpectQuery("UPDATE history_imports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(importID1, int64(7), ImportKindWeight, StrategySkip, []byte(rows), 3, 1, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO daily_metrics .* WHERE daily_metrics.weight IS NULL").
+			WithArgs(int64(7), "2026-03-01", 82.0).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO daily_metrics .* WHERE daily_metrics.weight IS NULL").
+			WithArgs(int64(7), "2026-03-02", 81.5).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SET processed_rows = processed_rows").
+			WithArgs(importID1, 2, 1, 1, int(importLease.Seconds())).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("SET status = 'done'").WithArgs(importID1).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextImport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("replace resumes after the saved progress", func(t *testing.T) {
+		service, mock := setupImportService(t)
+		rows := `[{"line":2,"date":"2026-03-01","values":{"waist":84}},{"line":3,"date":"2026-03-02","values":{"hips":98.5}}]`
+
+		mock.ExpectQuery("UPDATE history_imports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(importID2, int64(7), ImportKindMeasurements, StrategyReplace, []byte(rows), 2, 1, 2))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO body_measurements .* DO UPDATE SET").
+			WithArgs(sqlmock.AnyArg(), int64(7), "2026-03-02", nil, nil, 98.5, nil, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SET processed_rows = processed_rows").
+			WithArgs(importID2, 1, 1, 0, int(importLease.Seconds())).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("SET status = 'done'").WithArgs(importID2).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextImport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("marks the import failed after the last attempt", func(t *testing.T) {
+		service, mock := setupImportService(t)
+		rows := `[{"line":2,"date":"2026-03-01","values":{"weight":82}}]`
+
+		mock.ExpectQuery("UPDATE history_imports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(importID3, int64(7), ImportKindWeight, StrategyReplace, []byte(rows), 1, 0, MaxImportAttempts))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO daily_metrics").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+		mock.ExpectExec("SET status = 'failed'").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextImport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,154 @@
+package measurements
+
+import (
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(line int, fields ...string) csvimport.Record {
+	return csvimport.Record{Line: line, Fields: fields}
+}
+
+func TestResolveImportColumns(t *testing.T) {
+	header := []string{"Дата", "Утро", "Вес", "Талия"}
+
+	t.Run("weight needs date and weight", func(t *testing.T) {
+		cols, err := resolveImportColumns(ImportKindWeight, csvimport.Mapping{"date": "A", "weight": "Вес"}, header)
+		require.NoError(t, err)
+		assert.Equal(t, csvimport.Columns{"date": 0, "weight": 2}, cols)
+
+		_, err = resolveImportColumns(ImportKindWeight, csvimport.Mapping{"date": "A"}, header)
+		assert.ErrorIs(t, err, csvimport.ErrInvalidFile)
+
+		_, err = resolveImportColumns(ImportKindWeight, csvimport.Mapping{"date": "A", "weight": "C", "waist": "D"}, header)
+		assert.ErrorIs(t, err, csvimport.ErrInvalidFile, "measurement fields are not part of a weight import")
+	})
+
+	t.Run("measurements need date and at least one measurement", func(t *testing.T) {
+		cols, err := resolveImportColumns(ImportKindMeasurements, csvimport.Mapping{"date": "дата", "waist": "D"}, header)
+		require.NoError(t, err)
+		assert.Equal(t, csvimport.Columns{"date": 0, "waist": 3}, cols)
+
+		_, err = resolveImportColumns(ImportKindMeasurements, csvimport.Mapping{"date": "A"}, header)
+		assert.ErrorIs(t, err, csvimport.ErrInvalidFile)
+	})
+}
+
+func TestBuildImportRows(t *testing.T) {
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("weight", func(t *testing.T) {
+		cols := csvimport.Columns{"date": 0, "weight": 2}
+		records := []csvimport.Record{
+			record(2, "01.03.2026", "", "82,4"),
+			record(3, "2026-03-02", "", "82.1"),
+			record(4, "вчера", "", "82"),
+			record(5, "03.03.2026", "", ""),
+			record(6, "04.03.2026", "", "600"),
+			record(7, "11.03.2026", "", "81"),
+			record(8, "05.03.2026", "", "81кг"),
+		}
+
+		rows, rowErrors := buildImportRows(ImportKindWeight, cols, records, today, "metric")
+
+		assert.Equal(t, []ImportRow{
+			{Line: 2, Date: "2026-03-01", Values: map[string]float64{"weight": 82.4}},
+			{Line: 3, Date: "2026-03-02", Values: map[string]float64{"weight": 82.1}},
+		}, rows)
+		assert.Equal(t, []csvimport.RowError{
+			{Line: 4, Error: "Неверная дата: вчера"},
+			{Line: 5, Error: "Не указан вес"},
+			{Line: 6, Error: "Вес должен быть от 0 до 500 кг"},
+			{Line: 7, Error: "Дата в будущем: 11.03.2026"},
+			{Line: 8, Error: "Неверное значение weight: 81кг"},
+		}, rowErrors)
+	})
+
+	t.Run("measurements keep only filled cells", func(t *testing.T) {
+		cols := csvimport.Columns{"date": 0, "waist": 1, "hips": 2}
+		records := []csvimport.Record{
+			record(2, "01.03.2026", "84", ""),
+			record(3, "02.03.2026", "", ""),
+			record(4, "03.03.2026", "84", "5"),
+		}
+
+		rows, rowErrors := buildImportRows(ImportKindMeasurements, cols, records, today, "metric")
+
+		assert.Equal(t, []ImportRow{{Line: 2, Date: "2026-03-01", Values: map[string]float64{"waist": 84}}}, rows)
+		assert.Equal(t, []csvimport.RowError{
+			{Line: 3, Error: "Не указан ни один замер"},
+			{Line: 4, Error: "Замер hips должен быть от 10 до 300 см"},
+		}, rowErrors)
+	})
+}
+
+func TestBuildImportRows_Imperial(t *testing.T) {
+	today := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("weight in pounds", func(t *testing.T) {
+		cols := csvimport.Columns{"date": 0, "weight": 1}
+		records := []csvimport.Record{
+			record(2, "01.03.2026", "180"),
+			record(3, "02.03.2026", "1200"),
+		}
+
+		rows, rowErrors := buildImportRows(ImportKindWeight, cols, records, today, "imperial")
+
+		assert.Equal(t, []ImportRow{{Line: 2, Date: "2026-03-01", Values: map[string]float64{"weight": 81.65}}}, rows)
+		assert.Equal(t, []csvimport.RowError{{Line: 3, Error: "Вес должен быть от 0 до 500 кг"}}, rowErrors)
+	})
+
+	t.Run("measurements in inches", func(t *testing.T) {
+		cols := csvimport.Columns{"date": 0, "waist": 1}
+		records := []csvimport.Record{record(2, "01.03.2026", "32.5")}
+
+		rows, rowErrors := buildImportRows(ImportKindMeasurements, cols, records, today, "imperial")
+
+		assert.Empty(t, rowErrors)
+		assert.Equal(t, []ImportRow{{Line: 2, Date: "2026-03-01", Values: map[string]float64{"waist": 82.6}}}, rows)
+	})
+}
+
+func TestDedupeByDate(t *testing.T) {
+	rows := []ImportRow{
+		{Line: 2, Date: "2026-03-01", Values: map[string]float64{"weight": 82}},
+		{Line: 3, Date: "2026-03-02", Values: map[string]float64{"weight": 81.8}},
+		{Line: 4, Date: "2026-03-01", Values: map[string]float64{"weight": 82.5}},
+		{Line: 5, Date: "2026-03-01", Values: map[string]float64{"weight": 82.3}},
+	}
+
+	t.Run("skip keeps the first row of a date", func(t *testing.T) {
+		kept, dropped := dedupeByDate(rows, StrategySkip)
+
+		assert.Equal(t, 2, dropped)
+		assert.Equal(t, []ImportRow{rows[0], rows[1]}, kept)
+	})
+
+	t.Run("replace keeps the last row of a date", func(t *testing.T) {
+		kept, dropped := dedupeByDate(rows, StrategyReplace)
+
+		assert.Equal(t, 2, dropped)
+		assert.Equal(t, []ImportRow{rows[3], rows[1]}, kept)
+	})
+
+	t.Run("unique dates are untouched", func(t *testing.T) {
+		kept, dropped := dedupeByDate(rows[:2], StrategyReplace)
+
+		assert.Zero(t, dropped)
+		assert.Equal(t, rows[:2], kept)
+	})
+}
+
+func TestImportUpsertQuery(t *testing.T) {
+	assert.Contains(t, importUpsertQuery(ImportKindWeight, StrategySkip), "WHERE daily_metrics.weight IS NULL")
+	assert.NotContains(t, importUpsertQuery(ImportKindWeight, StrategyReplace), "WHERE")
+
+	assert.Contains(t, importUpsertQuery(ImportKindMeasurements, StrategySkip), "DO NOTHING")
+	replace := importUpsertQuery(ImportKindMeasurements, StrategyReplace)
+	assert.Contains(t, replace, "waist_cm = COALESCE(EXCLUDED.waist_cm, body_measurements.waist_cm)")
+	assert.Contains(t, replace, "neck_cm = COALESCE(EXCLUDED.neck_cm, body_measurements.neck_cm)")
+}
@@ -0,0 +1,119 @@
+package measurements
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// ServiceInterface defines the interface for measurements service operations
+type ServiceInterface interface {
+	GetWeightTrend(ctx context.Context, userID int64, days int, today time.Time) (*WeightTrend, error)
+	ListMeasurements(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) (*MeasurementList, error)
+}
+
+// Service handles body measurement queries
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new measurements service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{
+		db:  db,
+		log: log,
+	}
+}
+
+// GetWeightTrend loads the user's weights for the last days (ending today)
+// together with the target weight and computes the trend.
+func (s *Service) GetWeightTrend(ctx context.Context, userID int64, days int, today time.Time) (*WeightTrend, error) {
+	startTime := time.Now()
+	days = clampTrendDays(days)
+	from := windowStart(today, days)
+
+	query := `
+		SELECT date, weight
+		FROM daily_metrics
+		WHERE user_id = $1 AND weight IS NOT NULL AND date >= $2 AND date <= $3
+		ORDER BY date ASC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from.Format("2006-01-02"), today.Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"days":    days,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weights: %w", err)
+	}
+	defer rows.Close()
+
+	points := make([]WeightPoint, 0)
+	for rows.Next() {
+		var p WeightPoint
+		if err := rows.Scan(&p.Date, &p.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan weight: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weights: %w", err)
+	}
+
+	var target sql.NullFloat64
+	err = s.db.QueryRowContext(ctx, `SELECT target_weight FROM user_settings WHERE user_id = $1`, userID).Scan(&target)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to query target weight: %w", err)
+	}
+
+	var targetPtr *float64
+	if target.Valid {
+		targetPtr = &target.Float64
+	}
+
+	trend := CalculateWeightTrend(points, targetPtr, days)
+	return &trend, nil
+}
+
+// ListMeasurements returns a page of the user's body measurements in the
+// given order, in cm. Ties are broken by id so pages stay consistent.
+func (s *Service) ListMeasurements(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) (*MeasurementList, error) {
+	startTime := time.Now()
+	pageClause, pageArgs := page.Clause(2)
+	query := `
+		SELECT id, date::text, waist_cm, chest_cm, hips_cm, thigh_cm, arm_cm, neck_cm, created_at
+		FROM body_measurements
+		WHERE user_id = $1
+		ORDER BY ` + sort.OrderBy("id") + pageClause
+
+	rows, err := s.db.QueryContext(ctx, query, append([]any{userID}, pageArgs...)...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"limit":   page.Limit,
+		"offset":  page.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query measurements: %w", err)
+	}
+	defer rows.Close()
+
+	list := &MeasurementList{LengthUnit: "cm", Measurements: make([]Measurement, 0)}
+	for rows.Next() {
+		var m Measurement
+		if err := rows.Scan(&m.ID, &m.Date, &m.Waist, &m.Chest, &m.Hips, &m.Thigh, &m.Arm, &m.Neck, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan measurement: %w", err)
+		}
+		list.Measurements = append(list.Measurements, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating measurements: %w", err)
+	}
+	return list, nil
+}
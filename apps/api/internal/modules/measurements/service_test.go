@@ -0,0 +1,39 @@
+package measurements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMeasurements(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	service := NewService(&database.DB{DB: mockDB}, logger.New())
+
+	createdAt := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM body_measurements\\s+WHERE user_id = \\$1\\s+ORDER BY date ASC, id ASC LIMIT \\$2 OFFSET \\$3$").
+		WithArgs(int64(1), 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date", "waist_cm", "chest_cm", "hips_cm", "thigh_cm", "arm_cm", "neck_cm", "created_at"}).
+			AddRow("0199f0b2-7c1e-7a3b-9d2e-0000000000a3", "2026-03-01", 81.5, nil, 98.0, nil, nil, nil, createdAt))
+
+	list, err := service.ListMeasurements(context.Background(), 1,
+		listing.Sort{Column: "date"}, listing.Page{Limit: 2, Offset: 2})
+
+	require.NoError(t, err)
+	require.Len(t, list.Measurements, 1)
+	m := list.Measurements[0]
+	assert.Equal(t, "2026-03-01", m.Date)
+	require.NotNil(t, m.Waist)
+	assert.Equal(t, 81.5, *m.Waist)
+	assert.Nil(t, m.Chest)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
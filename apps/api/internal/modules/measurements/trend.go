@@ -0,0 +1,118 @@
+package measurements
+
+import (
+	"math"
+	"time"
+
+	"github.com/burcev/api/internal/shared/units"
+)
+
+// maxProjectionDays bounds goal projections; slower rates are not meaningful
+const maxProjectionDays = 5 * 365
+
+// CalculateWeightTrend smooths daily weights with an exponential moving
+// average and projects when the target weight is reached at the current rate.
+//
+// points must be sorted by date ascending. The weekly rate is the total change
+// divided by the weeks between the first and last point; the projection starts
+// from the smoothed current weight. ProjectedDate is nil when there is no
+// target, no measurable rate, or the rate points away from the target.
+func CalculateWeightTrend(points []WeightPoint, target *float64, days int) WeightTrend {
+	trend := WeightTrend{
+		Days:         days,
+		Points:       make([]WeightTrendPoint, 0, len(points)),
+		TargetWeight: target,
+	}
+	if len(points) == 0 {
+		return trend
+	}
+
+	alpha := 2.0 / float64(EMAPeriod+1)
+	ema := points[0].Weight
+	for i, p := range points {
+		if i > 0 {
+			ema = alpha*p.Weight + (1-alpha)*ema
+		}
+		trend.Points = append(trend.Points, WeightTrendPoint{
+			Date:   p.Date.Format("2006-01-02"),
+			Weight: p.Weight,
+			EMA:    round2(ema),
+		})
+	}
+
+	first, last := points[0], points[len(points)-1]
+	current := round2(ema)
+	change := round2(last.Weight - first.Weight)
+	trend.CurrentWeight = &current
+	trend.TotalChange = &change
+
+	spanDays := last.Date.Sub(first.Date).Hours() / 24
+	if spanDays < 1 {
+		return trend
+	}
+	rate := (last.Weight - first.Weight) / (spanDays / 7)
+	roundedRate := round2(rate)
+	trend.WeeklyRate = &roundedRate
+
+	if target == nil || rate == 0 {
+		return trend
+	}
+	remaining := *target - ema
+	if remaining == 0 {
+		date := last.Date.Format("2006-01-02")
+		trend.ProjectedDate = &date
+		return trend
+	}
+	if (remaining > 0) != (rate > 0) {
+		return trend
+	}
+
+	daysToGoal := math.Ceil(remaining / rate * 7)
+	if daysToGoal > maxProjectionDays {
+		return trend
+	}
+	date := last.Date.AddDate(0, 0, int(daysToGoal)).Format("2006-01-02")
+	trend.ProjectedDate = &date
+
+	return trend
+}
+
+// InUnits converts the trend's weights from kg into the given unit system
+// and labels them. TargetWeight is left in kg; Target carries the converted
+// value.
+func (t WeightTrend) InUnits(system string) WeightTrend {
+	t.WeightUnit = units.WeightUnit(system)
+	t.Target = units.WeightPtr(t.TargetWeight, system)
+	t.CurrentWeight = units.WeightPtr(t.CurrentWeight, system)
+	t.TotalChange = units.WeightPtr(t.TotalChange, system)
+	t.WeeklyRate = units.WeightPtr(t.WeeklyRate, system)
+
+	points := make([]WeightTrendPoint, len(t.Points))
+	for i, p := range t.Points {
+		p.Weight = units.Weight(p.Weight, system)
+		p.EMA = units.Weight(p.EMA, system)
+		points[i] = p
+	}
+	t.Points = points
+
+	return t
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// clampTrendDays caps the requested window at MaxTrendDays
+func clampTrendDays(days int) int {
+	if days > MaxTrendDays {
+		return MaxTrendDays
+	}
+	return days
+}
+
+// windowStart returns the first day of a window of the given length ending today
+func windowStart(today time.Time, days int) time.Time {
+	day := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, today.Location())
+	return day.AddDate(0, 0, -(days - 1))
+}
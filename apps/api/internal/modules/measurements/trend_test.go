@@ -0,0 +1,165 @@
+package measurements
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(n int) time.Time {
+	return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, n)
+}
+
+func weights(values ...float64) []WeightPoint {
+	points := make([]WeightPoint, len(values))
+	for i, v := range values {
+		points[i] = WeightPoint{Date: day(i * 7), Weight: v}
+	}
+	return points
+}
+
+func ptr(v float64) *float64 { return &v }
+
+func TestCalculateWeightTrend(t *testing.T) {
+	tests := []struct {
+		name          string
+		points        []WeightPoint
+		target        *float64
+		wantChange    *float64
+		wantRate      *float64
+		wantProjected *string
+	}{
+		{
+			name:   "no data",
+			points: nil,
+			target: ptr(70),
+		},
+		{
+			name:       "single point has no rate",
+			points:     weights(80),
+			target:     ptr(70),
+			wantChange: ptr(0),
+		},
+		{
+			name:       "losing towards lower target",
+			points:     weights(80, 79, 78),
+			target:     ptr(75),
+			wantChange: ptr(-2),
+			wantRate:   ptr(-1),
+			// EMA after 80,79,78 is 79.3125; 4.3125 kg at 1 kg/week = 30.2 days
+			wantProjected: strPtr(day(14 + 31).Format("2006-01-02")),
+		},
+		{
+			name:       "gaining towards higher target",
+			points:     weights(60, 60.5, 61),
+			target:     ptr(62),
+			wantChange: ptr(1),
+			wantRate:   ptr(0.5),
+			// EMA 60.34375; 1.65625 kg at 0.5 kg/week = 23.2 days
+			wantProjected: strPtr(day(14 + 24).Format("2006-01-02")),
+		},
+		{
+			name:       "rate points away from goal",
+			points:     weights(80, 81, 82),
+			target:     ptr(75),
+			wantChange: ptr(2),
+			wantRate:   ptr(1),
+		},
+		{
+			name:       "flat weight has no projection",
+			points:     weights(80, 80, 80),
+			target:     ptr(75),
+			wantChange: ptr(0),
+			wantRate:   ptr(0),
+		},
+		{
+			name:       "no target",
+			points:     weights(80, 79),
+			target:     nil,
+			wantChange: ptr(-1),
+			wantRate:   ptr(-1),
+		},
+		{
+			name:       "projection too far away is dropped",
+			points:     []WeightPoint{{Date: day(0), Weight: 80}, {Date: day(70), Weight: 79.9}},
+			target:     ptr(60),
+			wantChange: ptr(-0.1),
+			wantRate:   ptr(-0.01),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trend := CalculateWeightTrend(tt.points, tt.target, 90)
+
+			assert.Equal(t, 90, trend.Days)
+			assert.Len(t, trend.Points, len(tt.points))
+			assert.Equal(t, tt.wantChange, trend.TotalChange)
+			assert.Equal(t, tt.wantRate, trend.WeeklyRate)
+			assert.Equal(t, tt.wantProjected, trend.ProjectedDate)
+		})
+	}
+}
+
+func TestCalculateWeightTrend_EMA(t *testing.T) {
+	points := []WeightPoint{
+		{Date: day(0), Weight: 80},
+		{Date: day(1), Weight: 84},
+		{Date: day(2), Weight: 80},
+	}
+
+	trend := CalculateWeightTrend(points, nil, 7)
+
+	require.Len(t, trend.Points, 3)
+	// alpha = 2/(7+1) = 0.25
+	assert.Equal(t, 80.0, trend.Points[0].EMA)
+	assert.Equal(t, 81.0, trend.Points[1].EMA)
+	assert.Equal(t, 80.75, trend.Points[2].EMA)
+	assert.Equal(t, "2026-01-02", trend.Points[1].Date)
+	require.NotNil(t, trend.CurrentWeight)
+	assert.Equal(t, 80.75, *trend.CurrentWeight)
+}
+
+func TestWeightTrendInUnits(t *testing.T) {
+	target := 70.0
+	trend := CalculateWeightTrend([]WeightPoint{
+		{Date: day(0), Weight: 80},
+		{Date: day(7), Weight: 79},
+	}, &target, 30)
+
+	t.Run("metric keeps kg", func(t *testing.T) {
+		got := trend.InUnits("metric")
+		assert.Equal(t, "kg", got.WeightUnit)
+		assert.Equal(t, 80.0, got.Points[0].Weight)
+		assert.Equal(t, &target, got.Target)
+	})
+
+	t.Run("imperial converts to lb", func(t *testing.T) {
+		got := trend.InUnits("imperial")
+		assert.Equal(t, "lb", got.WeightUnit)
+		assert.Equal(t, 176.4, got.Points[0].Weight)
+		assert.Equal(t, 174.2, got.Points[1].Weight)
+		require.NotNil(t, got.WeeklyRate)
+		assert.Equal(t, -2.2, *got.WeeklyRate)
+		require.NotNil(t, got.Target)
+		assert.Equal(t, 154.3, *got.Target)
+		// The kg target and the source trend are untouched
+		assert.Equal(t, 70.0, *got.TargetWeight)
+		assert.Equal(t, 80.0, trend.Points[0].Weight)
+	})
+}
+
+func TestClampTrendDays(t *testing.T) {
+	assert.Equal(t, 90, clampTrendDays(90))
+	assert.Equal(t, MaxTrendDays, clampTrendDays(1000))
+}
+
+func TestWindowStart(t *testing.T) {
+	today := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC), windowStart(today, 1))
+	assert.Equal(t, time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC), windowStart(today, 7))
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,181 @@
+package measurements
+
+import (
+	"time"
+
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/units"
+)
+
+// Weight trend window limits (days)
+const (
+	DefaultTrendDays = 90
+	MaxTrendDays     = 365
+)
+
+// EMAPeriod is the smoothing period of the weight moving average (days)
+const EMAPeriod = 7
+
+// WeightPoint is a single recorded weight
+type WeightPoint struct {
+	Date   time.Time
+	Weight float64
+}
+
+// WeightTrendPoint is a daily weight with its smoothed value
+type WeightTrendPoint struct {
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"`
+	EMA    float64 `json:"ema"`
+}
+
+// WeightTrend is the response of GET /api/v1/measurements/weight-trend.
+// Weights, changes and rates are in WeightUnit; TargetWeight stays in kg
+// for older clients.
+type WeightTrend struct {
+	Days          int                `json:"days"`
+	WeightUnit    string             `json:"weight_unit"`
+	Points        []WeightTrendPoint `json:"points"`
+	CurrentWeight *float64           `json:"current_weight"`
+	TotalChange   *float64           `json:"total_change"`
+	WeeklyRate    *float64           `json:"weekly_rate"`
+	Target        *float64           `json:"target_weight"`
+	TargetWeight  *float64           `json:"target_weight_kg"`
+	ProjectedDate *string            `json:"projected_date"`
+}
+
+// Measurement listing page sizes
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// SortFields are the sort values GET /api/v1/measurements accepts
+var SortFields = listing.Fields{
+	"date":       "date",
+	"created_at": "created_at",
+}
+
+// DefaultSort lists measurements newest first
+var DefaultSort = listing.Sort{Column: "date", Desc: true}
+
+// Measurement is a day's body measurements. Lengths are stored in cm;
+// nil means not measured that day.
+type Measurement struct {
+	ID        string    `json:"id"`
+	Date      string    `json:"date"`
+	Waist     *float64  `json:"waist"`
+	Chest     *float64  `json:"chest"`
+	Hips      *float64  `json:"hips"`
+	Thigh     *float64  `json:"thigh"`
+	Arm       *float64  `json:"arm"`
+	Neck      *float64  `json:"neck"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MeasurementList is the response of GET /api/v1/measurements. Lengths are
+// in LengthUnit.
+type MeasurementList struct {
+	LengthUnit   string        `json:"length_unit"`
+	Measurements []Measurement `json:"measurements"`
+}
+
+// InUnits converts the lengths from cm into the given unit system and
+// labels them
+func (l MeasurementList) InUnits(system string) MeasurementList {
+	l.LengthUnit = units.LengthUnit(system)
+
+	measurements := make([]Measurement, len(l.Measurements))
+	for i, m := range l.Measurements {
+		for _, v := range []**float64{&m.Waist, &m.Chest, &m.Hips, &m.Thigh, &m.Arm, &m.Neck} {
+			*v = units.LengthPtr(*v, system)
+		}
+		measurements[i] = m
+	}
+	l.Measurements = measurements
+
+	return l
+}
+
+// History import kinds
+const (
+	ImportKindWeight       = "weight"
+	ImportKindMeasurements = "measurements"
+)
+
+// What to do when an imported date already has a value
+const (
+	StrategySkip    = "skip"
+	StrategyReplace = "replace"
+)
+
+// History import statuses
+const (
+	ImportPending    = "pending"
+	ImportProcessing = "processing"
+	ImportDone       = "done"
+	ImportFailed     = "failed"
+)
+
+// History import limits
+const (
+	MaxImportFileSize = 2 << 20
+	MaxImportRows     = 5000
+	// MaxImportAttempts is how many times a worker picks up an import that
+	// keeps failing before it is marked failed
+	MaxImportAttempts = 3
+)
+
+// importBatchSize is how many rows are written per transaction; progress is
+// saved after every batch
+const importBatchSize = 200
+
+// importLease is how long a claimed import is hidden from other workers
+const importLease = 5 * time.Minute
+
+// Import column fields. Body measurements are in centimetres.
+var (
+	weightImportFields      = []string{"date", "weight"}
+	measurementImportFields = []string{"date", "waist", "chest", "hips", "thigh", "arm", "neck"}
+)
+
+// ImportRow is a validated row waiting to be written. Values holds the
+// non-empty numeric fields of the row.
+type ImportRow struct {
+	Line   int                `json:"line"`
+	Date   string             `json:"date"`
+	Values map[string]float64 `json:"values"`
+}
+
+// HistoryImport is the response of the import endpoints and of
+// GET /api/v1/users/imports/:id
+type HistoryImport struct {
+	ID            string               `json:"id"`
+	Kind          string               `json:"kind"`
+	Strategy      string               `json:"strategy"`
+	Status        string               `json:"status"`
+	TotalRows     int                  `json:"total_rows"`
+	ProcessedRows int                  `json:"processed_rows"`
+	ImportedRows  int                  `json:"imported_rows"`
+	SkippedRows   int                  `json:"skipped_rows"`
+	FailedRows    int                  `json:"failed_rows"`
+	Progress      int                  `json:"progress"`
+	RowErrors     []csvimport.RowError `json:"row_errors"`
+	Error         *string              `json:"error,omitempty"`
+	CreatedAt     time.Time            `json:"created_at"`
+	CompletedAt   *time.Time           `json:"completed_at,omitempty"`
+}
+
+// ImportRequest is an uploaded history file with the client's column mapping.
+// Today is the user's local date; later dates are rejected.
+type ImportRequest struct {
+	Kind     string
+	Strategy string
+	Mapping  csvimport.Mapping
+	Header   []string
+	Records  []csvimport.Record
+	Today    time.Time
+	// Units is the unit system the file's values are in; they are stored metric
+	Units string
+}
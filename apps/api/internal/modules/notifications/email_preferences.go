@@ -0,0 +1,175 @@
+package notifications
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// unsubscribeTokenBytes is the entropy of an unsubscribe token (256 bits)
+const unsubscribeTokenBytes = 32
+
+// hashUnsubscribeToken returns the hex SHA-256 of a plain token, the only
+// form in which tokens are stored
+func hashUnsubscribeToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// GetEmailPreferences returns the user's email preferences (all enabled when never set)
+func (s *Service) GetEmailPreferences(ctx context.Context, userID int64) (*EmailPreferences, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT password_changed_email, weekly_summary_email, coach_activity_email
+		FROM email_preferences
+		WHERE user_id = $1
+	`
+
+	prefs := &EmailPreferences{PasswordChangedEmail: true, WeeklySummaryEmail: true, CoachActivityEmail: true}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(
+		&prefs.PasswordChangedEmail, &prefs.WeeklySummaryEmail, &prefs.CoachActivityEmail,
+	)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get email preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// UpdateEmailPreferences changes the fields present in req and returns the result
+func (s *Service) UpdateEmailPreferences(ctx context.Context, userID int64, req UpdateEmailPreferencesRequest) (*EmailPreferences, error) {
+	startTime := time.Now()
+
+	query := `
+		INSERT INTO email_preferences (user_id, password_changed_email, weekly_summary_email, coach_activity_email)
+		VALUES ($1, COALESCE($2, TRUE), COALESCE($3, TRUE), COALESCE($4, TRUE))
+		ON CONFLICT (user_id) DO UPDATE SET
+			password_changed_email = COALESCE($2, email_preferences.password_changed_email),
+			weekly_summary_email   = COALESCE($3, email_preferences.weekly_summary_email),
+			coach_activity_email   = COALESCE($4, email_preferences.coach_activity_email),
+			updated_at = NOW()
+		RETURNING password_changed_email, weekly_summary_email, coach_activity_email
+	`
+
+	var prefs EmailPreferences
+	err := s.db.QueryRowContext(ctx, query, userID,
+		req.PasswordChangedEmail, req.WeeklySummaryEmail, req.CoachActivityEmail,
+	).Scan(&prefs.PasswordChangedEmail, &prefs.WeeklySummaryEmail, &prefs.CoachActivityEmail)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update email preferences: %w", err)
+	}
+
+	return &prefs, nil
+}
+
+// EmailAllowed reports whether the user accepts emails of the given kind
+func (s *Service) EmailAllowed(ctx context.Context, userID int64, kind EmailKind) (bool, error) {
+	prefs, err := s.GetEmailPreferences(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	switch kind {
+	case EmailPasswordChanged:
+		return prefs.PasswordChangedEmail, nil
+	case EmailWeeklySummary:
+		return prefs.WeeklySummaryEmail, nil
+	case EmailCoachActivity:
+		return prefs.CoachActivityEmail, nil
+	}
+	return false, fmt.Errorf("invalid email kind: %s", kind)
+}
+
+// CreateUnsubscribeToken issues a token for the unsubscribe link of one email.
+// The plain token goes into the link; only its hash is stored.
+func (s *Service) CreateUnsubscribeToken(ctx context.Context, userID int64, kind EmailKind) (string, error) {
+	if !kind.IsValid() {
+		return "", fmt.Errorf("invalid email kind: %s", kind)
+	}
+
+	raw := make([]byte, unsubscribeTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate unsubscribe token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO email_unsubscribe_tokens (token_hash, user_id, kind)
+		VALUES ($1, $2, $3)
+	`
+	_, err := s.db.ExecContext(ctx, query, hashUnsubscribeToken(token), userID, string(kind))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"kind":    kind,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to store unsubscribe token: %w", err)
+	}
+
+	return token, nil
+}
+
+// Unsubscribe turns off the email kind the token was issued for. Tokens stay
+// valid after use so a repeated click is harmless.
+func (s *Service) Unsubscribe(ctx context.Context, token string) (EmailKind, error) {
+	if token == "" {
+		return "", ErrInvalidUnsubscribeToken
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var kind EmailKind
+	err = tx.QueryRowContext(ctx, `
+		UPDATE email_unsubscribe_tokens SET used_at = COALESCE(used_at, NOW())
+		WHERE token_hash = $1
+		RETURNING user_id, kind
+	`, hashUnsubscribeToken(token)).Scan(&userID, &kind)
+	if err == sql.ErrNoRows {
+		s.log.LogSecurityEvent("invalid_unsubscribe_token", "low", nil)
+		return "", ErrInvalidUnsubscribeToken
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up unsubscribe token: %w", err)
+	}
+
+	// kind comes from a CHECK-constrained column, so it is safe as a column name
+	if !kind.IsValid() {
+		return "", fmt.Errorf("invalid email kind: %s", kind)
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO email_preferences (user_id, `+string(kind)+`)
+		VALUES ($1, FALSE)
+		ON CONFLICT (user_id) DO UPDATE SET `+string(kind)+` = FALSE, updated_at = NOW()
+	`, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to unsubscribe: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.LogBusinessEvent("email_unsubscribed", map[string]interface{}{
+		"user_id": userID,
+		"kind":    kind,
+	})
+
+	return kind, nil
+}
@@ -0,0 +1,140 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEmailPreferences(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("defaults to all enabled when never set", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT password_changed_email").
+			WithArgs(int64(1)).
+			WillReturnError(sql.ErrNoRows)
+
+		prefs, err := service.GetEmailPreferences(ctx, 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, &EmailPreferences{PasswordChangedEmail: true, WeeklySummaryEmail: true, CoachActivityEmail: true}, prefs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns stored values", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT password_changed_email").
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"password_changed_email", "weekly_summary_email", "coach_activity_email"}).
+				AddRow(true, false, false))
+
+		allowed, err := service.EmailAllowed(ctx, 1, EmailCoachActivity)
+
+		require.NoError(t, err)
+		assert.False(t, allowed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdateEmailPreferences(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+
+	off := false
+	mock.ExpectQuery("INSERT INTO email_preferences").
+		WithArgs(int64(1), nil, &off, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"password_changed_email", "weekly_summary_email", "coach_activity_email"}).
+			AddRow(true, false, true))
+
+	prefs, err := service.UpdateEmailPreferences(context.Background(), 1, UpdateEmailPreferencesRequest{WeeklySummaryEmail: &off})
+
+	require.NoError(t, err)
+	assert.False(t, prefs.WeeklySummaryEmail)
+	assert.True(t, prefs.CoachActivityEmail)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateUnsubscribeToken(t *testing.T) {
+	t.Run("stores only the token hash", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectExec("INSERT INTO email_unsubscribe_tokens").
+			WithArgs(sqlmock.AnyArg(), int64(1), string(EmailCoachActivity)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		token, err := service.CreateUnsubscribeToken(context.Background(), 1, EmailCoachActivity)
+
+		require.NoError(t, err)
+		assert.Len(t, token, unsubscribeTokenBytes*2)
+		assert.Len(t, hashUnsubscribeToken(token), 64)
+		assert.NotEqual(t, token, hashUnsubscribeToken(token))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unknown kind", func(t *testing.T) {
+		service, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.CreateUnsubscribeToken(context.Background(), 1, EmailKind("marketing"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("turns off the kind the token was issued for", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE email_unsubscribe_tokens").
+			WithArgs(hashUnsubscribeToken("plain-token")).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "kind"}).AddRow(int64(7), string(EmailWeeklySummary)))
+		mock.ExpectExec("INSERT INTO email_preferences \\(user_id, weekly_summary_email\\)").
+			WithArgs(int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		kind, err := service.Unsubscribe(ctx, "plain-token")
+
+		require.NoError(t, err)
+		assert.Equal(t, EmailWeeklySummary, kind)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE email_unsubscribe_tokens").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		_, err := service.Unsubscribe(ctx, "bogus")
+
+		assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty token", func(t *testing.T) {
+		service, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.Unsubscribe(ctx, "")
+
+		assert.ErrorIs(t, err, ErrInvalidUnsubscribeToken)
+	})
+}
@@ -2,6 +2,7 @@ package notifications
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
@@ -21,6 +22,9 @@ type ServiceInterface interface {
 	CreateNotification(ctx context.Context, notification *Notification) error
 	GetPreferences(ctx context.Context, userID int64) (*ContentNotificationPreferences, error)
 	UpdatePreferences(ctx context.Context, userID int64, req UpdatePreferencesRequest) error
+	GetEmailPreferences(ctx context.Context, userID int64) (*EmailPreferences, error)
+	UpdateEmailPreferences(ctx context.Context, userID int64, req UpdateEmailPreferencesRequest) (*EmailPreferences, error)
+	Unsubscribe(ctx context.Context, token string) (EmailKind, error)
 }
 
 // Handler handles notification requests
@@ -246,6 +250,22 @@ func (h *Handler) UpdatePreferences(c *gin.Context) {
 		return
 	}
 
+	if req.MutedChannels != nil {
+		for _, channel := range *req.MutedChannels {
+			if !NotificationChannel(channel).IsValid() {
+				response.Error(c, http.StatusBadRequest, "Неверный канал уведомлений")
+				return
+			}
+		}
+	}
+
+	if req.QuietHours != nil {
+		if err := req.QuietHours.Validate(); err != nil {
+			response.Error(c, http.StatusBadRequest, "Неверные тихие часы: укажите разные start и end в формате ЧЧ:ММ и часовой пояс")
+			return
+		}
+	}
+
 	if err := h.service.UpdatePreferences(c.Request.Context(), userID, req); err != nil {
 		h.log.Errorw("Failed to update preferences", "error", err, "user_id", userID)
 		response.InternalError(c, "Не удалось сохранить настройки уведомлений")
@@ -254,3 +274,77 @@ func (h *Handler) UpdatePreferences(c *gin.Context) {
 
 	response.Success(c, http.StatusOK, map[string]string{"status": "ok"})
 }
+
+// GetEmailPreferences handles GET /api/v1/users/notifications
+func (h *Handler) GetEmailPreferences(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		h.log.Error("Invalid user ID type", "user_id", userIDInterface)
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	prefs, err := h.service.GetEmailPreferences(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Errorw("Failed to get email preferences", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить настройки писем")
+		return
+	}
+
+	response.Success(c, http.StatusOK, prefs)
+}
+
+// UpdateEmailPreferences handles PUT /api/v1/users/notifications
+// Body: {"weekly_summary_email": false}. Omitted fields are left unchanged.
+func (h *Handler) UpdateEmailPreferences(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		h.log.Error("Invalid user ID type", "user_id", userIDInterface)
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var req UpdateEmailPreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+		return
+	}
+
+	prefs, err := h.service.UpdateEmailPreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		h.log.Errorw("Failed to update email preferences", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось сохранить настройки писем")
+		return
+	}
+
+	response.Success(c, http.StatusOK, prefs)
+}
+
+// Unsubscribe handles GET /api/v1/notifications/unsubscribe?token=...
+// Public: the link in an email is the credential, so no login is required.
+func (h *Handler) Unsubscribe(c *gin.Context) {
+	kind, err := h.service.Unsubscribe(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if errors.Is(err, ErrInvalidUnsubscribeToken) {
+			response.Error(c, http.StatusBadRequest, "Ссылка для отписки недействительна")
+			return
+		}
+		h.log.Errorw("Failed to unsubscribe", "error", err)
+		response.InternalError(c, "Не удалось отписаться от рассылки")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Вы отписались от этих писем", gin.H{"kind": kind})
+}
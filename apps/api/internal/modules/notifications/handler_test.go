@@ -71,6 +71,27 @@ func (m *MockService) UpdatePreferences(ctx context.Context, userID int64, req U
 	return args.Error(0)
 }
 
+func (m *MockService) GetEmailPreferences(ctx context.Context, userID int64) (*EmailPreferences, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*EmailPreferences), args.Error(1)
+}
+
+func (m *MockService) UpdateEmailPreferences(ctx context.Context, userID int64, req UpdateEmailPreferencesRequest) (*EmailPreferences, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*EmailPreferences), args.Error(1)
+}
+
+func (m *MockService) Unsubscribe(ctx context.Context, token string) (EmailKind, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(EmailKind), args.Error(1)
+}
+
 func setupTestHandlerWithMock() (*Handler, *MockService) {
 	gin.SetMode(gin.TestMode)
 	cfg := &config.Config{
@@ -455,3 +476,127 @@ func TestMarkAllAsRead_Unauthenticated(t *testing.T) {
 	assert.Equal(t, "error", response["status"])
 	assert.Equal(t, "Пользователь не аутентифицирован", response["message"])
 }
+
+func TestGetEmailPreferences_Success(t *testing.T) {
+	handler, mockService := setupTestHandlerWithMock()
+
+	mockService.On("GetEmailPreferences", mock.Anything, int64(1)).
+		Return(&EmailPreferences{PasswordChangedEmail: true, WeeklySummaryEmail: false, CoachActivityEmail: true}, nil)
+
+	router := gin.New()
+	router.GET("/users/notifications", func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		handler.GetEmailPreferences(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/notifications", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, false, data["weekly_summary_email"])
+	assert.Equal(t, true, data["coach_activity_email"])
+
+	mockService.AssertExpectations(t)
+}
+
+func TestUpdateEmailPreferences_PartialUpdate(t *testing.T) {
+	handler, mockService := setupTestHandlerWithMock()
+
+	off := false
+	mockService.On("UpdateEmailPreferences", mock.Anything, int64(1), UpdateEmailPreferencesRequest{CoachActivityEmail: &off}).
+		Return(&EmailPreferences{PasswordChangedEmail: true, WeeklySummaryEmail: true}, nil)
+
+	router := gin.New()
+	router.PUT("/users/notifications", func(c *gin.Context) {
+		c.Set("user_id", int64(1))
+		handler.UpdateEmailPreferences(c)
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/users/notifications", bytes.NewBufferString(`{"coach_activity_email":false}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+		mockService.On("Unsubscribe", mock.Anything, "abc").Return(EmailCoachActivity, nil)
+
+		router := gin.New()
+		router.GET("/notifications/unsubscribe", handler.Unsubscribe)
+
+		req := httptest.NewRequest(http.MethodGet, "/notifications/unsubscribe?token=abc", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), string(EmailCoachActivity))
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+		mockService.On("Unsubscribe", mock.Anything, "").Return(EmailKind(""), ErrInvalidUnsubscribeToken)
+
+		router := gin.New()
+		router.GET("/notifications/unsubscribe", handler.Unsubscribe)
+
+		req := httptest.NewRequest(http.MethodGet, "/notifications/unsubscribe", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestUpdatePreferences_QuietHours(t *testing.T) {
+	t.Run("valid quiet hours are saved", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+
+		quiet := &QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Europe/Moscow"}
+		mockService.On("UpdatePreferences", mock.Anything, int64(1), UpdatePreferencesRequest{QuietHours: quiet}).
+			Return(nil)
+
+		router := gin.New()
+		router.PUT("/notifications/preferences", func(c *gin.Context) {
+			c.Set("user_id", int64(1))
+			handler.UpdatePreferences(c)
+		})
+
+		body := `{"quietHours":{"enabled":true,"start":"22:00","end":"07:00","timezone":"Europe/Moscow"}}`
+		req := httptest.NewRequest(http.MethodPut, "/notifications/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid timezone is rejected", func(t *testing.T) {
+		handler, mockService := setupTestHandlerWithMock()
+
+		router := gin.New()
+		router.PUT("/notifications/preferences", func(c *gin.Context) {
+			c.Set("user_id", int64(1))
+			handler.UpdatePreferences(c)
+		})
+
+		body := `{"quietHours":{"enabled":true,"start":"22:00","end":"07:00","timezone":"Nowhere/City"}}`
+		req := httptest.NewRequest(http.MethodPut, "/notifications/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertNotCalled(t, "UpdatePreferences", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
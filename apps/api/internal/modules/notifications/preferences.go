@@ -3,6 +3,7 @@ package notifications
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -55,20 +56,59 @@ func (s *Service) GetPreferences(ctx context.Context, userID int64) (*ContentNot
 		return nil, fmt.Errorf("failed to query mute status: %w", err)
 	}
 
+	// Query muted delivery channels
+	channelsQuery := `
+		SELECT channel FROM notification_channel_mute
+		WHERE user_id = $1
+		ORDER BY channel
+	`
+
+	channelRows, err := s.db.QueryContext(ctx, channelsQuery, userID)
+	if err != nil {
+		s.log.LogDatabaseQuery(channelsQuery, time.Since(startTime), err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return nil, fmt.Errorf("failed to query muted channels: %w", err)
+	}
+	defer channelRows.Close()
+
+	mutedChannels := []string{}
+	for channelRows.Next() {
+		var channel string
+		if err := channelRows.Scan(&channel); err != nil {
+			return nil, fmt.Errorf("failed to scan muted channel: %w", err)
+		}
+		mutedChannels = append(mutedChannels, channel)
+	}
+
+	if err := channelRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating muted channels: %w", err)
+	}
+
+	quietHours, err := s.getQuietHours(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	s.log.LogDatabaseQuery(categoriesQuery, time.Since(startTime), nil, map[string]interface{}{
 		"user_id":          userID,
 		"muted_categories": len(mutedCategories),
 		"muted":            muted,
+		"muted_channels":   len(mutedChannels),
+		"quiet_hours":      quietHours.Enabled,
 	})
 
 	return &ContentNotificationPreferences{
 		MutedCategories: mutedCategories,
 		Muted:           muted,
+		MutedChannels:   mutedChannels,
+		QuietHours:      quietHours,
 	}, nil
 }
 
 // UpdatePreferences updates content notification preferences for a user.
-// In a transaction: replaces muted categories and toggles the global mute status.
+// In a transaction: replaces muted categories, toggles the global mute status
+// and, when present, replaces muted channels and quiet hours.
 func (s *Service) UpdatePreferences(ctx context.Context, userID int64, req UpdatePreferencesRequest) error {
 	startTime := time.Now()
 
@@ -135,6 +175,50 @@ func (s *Service) UpdatePreferences(ctx context.Context, userID int64, req Updat
 		}
 	}
 
+	// Replace muted delivery channels only when the client sent them
+	if req.MutedChannels != nil {
+		for _, channel := range *req.MutedChannels {
+			if !NotificationChannel(channel).IsValid() {
+				return fmt.Errorf("invalid channel: %s", channel)
+			}
+		}
+
+		deleteChannelsQuery := `
+			DELETE FROM notification_channel_mute WHERE user_id = $1
+		`
+		if _, err = tx.ExecContext(ctx, deleteChannelsQuery, userID); err != nil {
+			s.log.LogDatabaseQuery(deleteChannelsQuery, time.Since(startTime), err, map[string]interface{}{
+				"user_id": userID,
+			})
+			return fmt.Errorf("failed to delete existing muted channels: %w", err)
+		}
+
+		insertChannelQuery := `
+			INSERT INTO notification_channel_mute (user_id, channel, created_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (user_id, channel) DO NOTHING
+		`
+		for _, channel := range *req.MutedChannels {
+			if _, err = tx.ExecContext(ctx, insertChannelQuery, userID, channel); err != nil {
+				s.log.LogDatabaseQuery(insertChannelQuery, time.Since(startTime), err, map[string]interface{}{
+					"user_id": userID,
+					"channel": channel,
+				})
+				return fmt.Errorf("failed to insert muted channel %s: %w", channel, err)
+			}
+		}
+	}
+
+	// Replace quiet hours only when the client sent them
+	if req.QuietHours != nil {
+		if err := req.QuietHours.Validate(); err != nil {
+			return err
+		}
+		if err := s.saveQuietHours(ctx, tx, userID, *req.QuietHours); err != nil {
+			return err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
@@ -147,3 +231,57 @@ func (s *Service) UpdatePreferences(ctx context.Context, userID int64, req Updat
 
 	return nil
 }
+
+// GetMutedChannels returns the muted delivery channels for a batch of users.
+// Users without any muted channel are absent from the result.
+func (s *Service) GetMutedChannels(ctx context.Context, userIDs []int64) (map[int64]map[NotificationChannel]bool, error) {
+	result := make(map[int64]map[NotificationChannel]bool)
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	startTime := time.Now()
+
+	placeholders := make([]string, len(userIDs))
+	args := make([]interface{}, len(userIDs))
+	for i, id := range userIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := `
+		SELECT user_id, channel FROM notification_channel_mute
+		WHERE user_id IN (` + strings.Join(placeholders, ",") + `)
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"users": len(userIDs),
+		})
+		return nil, fmt.Errorf("failed to query muted channels: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userID int64
+		var channel string
+		if err := rows.Scan(&userID, &channel); err != nil {
+			return nil, fmt.Errorf("failed to scan muted channel: %w", err)
+		}
+		if result[userID] == nil {
+			result[userID] = make(map[NotificationChannel]bool)
+		}
+		result[userID][NotificationChannel(channel)] = true
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating muted channels: %w", err)
+	}
+
+	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+		"users": len(userIDs),
+	})
+
+	return result, nil
+}
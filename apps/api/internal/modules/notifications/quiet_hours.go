@@ -0,0 +1,336 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// quietHoursReleaseInterval is how often held notifications are checked for release
+const quietHoursReleaseInterval = time.Minute
+
+// parseClock parses "HH:MM" into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// formatClock formats minutes since midnight as "HH:MM"
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
+
+// Validate checks an enabled window; a disabled one needs no other fields
+func (q QuietHours) Validate() error {
+	if !q.Enabled {
+		return nil
+	}
+	start, err := parseClock(q.Start)
+	if err != nil {
+		return ErrInvalidQuietHoursTime
+	}
+	end, err := parseClock(q.End)
+	if err != nil || start == end {
+		return ErrInvalidQuietHoursTime
+	}
+	if q.Timezone == "" {
+		return ErrInvalidQuietHoursTimezone
+	}
+	if _, err := time.LoadLocation(q.Timezone); err != nil {
+		return ErrInvalidQuietHoursTimezone
+	}
+	return nil
+}
+
+// ActiveAt reports whether t falls inside the quiet window and, if so, the
+// instant the window ends. The window is evaluated in the user's timezone,
+// so it follows local midnight and DST changes.
+func (q QuietHours) ActiveAt(t time.Time) (bool, time.Time) {
+	if q.Validate() != nil || !q.Enabled {
+		return false, time.Time{}
+	}
+	loc, _ := time.LoadLocation(q.Timezone)
+	start, _ := parseClock(q.Start)
+	end, _ := parseClock(q.End)
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	y, m, d := local.Date()
+
+	if start < end {
+		if minute >= start && minute < end {
+			return true, time.Date(y, m, d, end/60, end%60, 0, 0, loc)
+		}
+		return false, time.Time{}
+	}
+
+	// Window crosses midnight: evening part ends tomorrow, morning part today
+	if minute >= start {
+		return true, time.Date(y, m, d+1, end/60, end%60, 0, 0, loc)
+	}
+	if minute < end {
+		return true, time.Date(y, m, d, end/60, end%60, 0, 0, loc)
+	}
+	return false, time.Time{}
+}
+
+// getQuietHours loads the user's quiet hours; a disabled value when none are set
+func (s *Service) getQuietHours(ctx context.Context, userID int64) (QuietHours, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT start_minute, end_minute, timezone
+		FROM notification_quiet_hours
+		WHERE user_id = $1
+	`
+
+	var start, end int
+	var timezone string
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&start, &end, &timezone)
+	if err == sql.ErrNoRows {
+		return QuietHours{}, nil
+	}
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return QuietHours{}, fmt.Errorf("failed to get quiet hours: %w", err)
+	}
+
+	return QuietHours{
+		Enabled:  true,
+		Start:    formatClock(start),
+		End:      formatClock(end),
+		Timezone: timezone,
+	}, nil
+}
+
+// InQuietHours reports whether the user is inside their quiet hours right now
+func (s *Service) InQuietHours(ctx context.Context, userID int64) (bool, error) {
+	quiet, err := s.getQuietHours(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	active, _ := quiet.ActiveAt(s.now())
+	return active, nil
+}
+
+// holdIfQuiet stores the notification for later delivery when its recipient
+// is in quiet hours right now, and reports whether it did
+func (s *Service) holdIfQuiet(ctx context.Context, notification *Notification) (bool, error) {
+	quiet, err := s.getQuietHours(ctx, notification.UserID)
+	if err != nil {
+		return false, err
+	}
+	active, releaseAt := quiet.ActiveAt(s.now())
+	if !active {
+		return false, nil
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO held_notifications (id, user_id, category, type, title, content, icon_url, action_url, content_category, created_at, release_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`
+	_, err = s.db.ExecContext(ctx, query,
+		notification.ID,
+		notification.UserID,
+		notification.Category,
+		notification.Type,
+		notification.Title,
+		notification.Content,
+		notification.IconURL,
+		notification.ActionURL,
+		notification.ContentCategory,
+		notification.CreatedAt,
+		releaseAt.UTC(),
+	)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"notification_id": notification.ID,
+		"user_id":         notification.UserID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to hold notification: %w", err)
+	}
+
+	s.log.LogBusinessEvent("notification_held", map[string]interface{}{
+		"notification_id": notification.ID,
+		"user_id":         notification.UserID,
+		"type":            notification.Type,
+		"release_at":      releaseAt,
+	})
+
+	return true, nil
+}
+
+// saveQuietHours replaces (or removes) the user's quiet hours inside tx and
+// reschedules already held notifications against the new window, so a
+// timezone change or disabling quiet hours takes effect immediately
+func (s *Service) saveQuietHours(ctx context.Context, tx *sql.Tx, userID int64, quiet QuietHours) error {
+	startTime := time.Now()
+
+	if quiet.Enabled {
+		start, _ := parseClock(quiet.Start)
+		end, _ := parseClock(quiet.End)
+		query := `
+			INSERT INTO notification_quiet_hours (user_id, start_minute, end_minute, timezone)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET
+				start_minute = EXCLUDED.start_minute,
+				end_minute = EXCLUDED.end_minute,
+				timezone = EXCLUDED.timezone,
+				updated_at = NOW()
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, start, end, quiet.Timezone); err != nil {
+			s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+				"user_id": userID,
+			})
+			return fmt.Errorf("failed to save quiet hours: %w", err)
+		}
+	} else {
+		query := `DELETE FROM notification_quiet_hours WHERE user_id = $1`
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+				"user_id": userID,
+			})
+			return fmt.Errorf("failed to delete quiet hours: %w", err)
+		}
+	}
+
+	now := s.now()
+	releaseAt := now
+	if active, end := quiet.ActiveAt(now); active {
+		releaseAt = end.UTC()
+	}
+
+	query := `UPDATE held_notifications SET release_at = $2 WHERE user_id = $1`
+	if _, err := tx.ExecContext(ctx, query, userID, releaseAt); err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"user_id": userID,
+		})
+		return fmt.Errorf("failed to reschedule held notifications: %w", err)
+	}
+
+	return nil
+}
+
+// buildDigest combines several held notifications for one user into a single notification
+func buildDigest(userID int64, held []Notification, now time.Time) *Notification {
+	lines := make([]string, 0, len(held)+1)
+	lines = append(lines, fmt.Sprintf("Пока действовали тихие часы, накопились уведомления (%d):", len(held)))
+	for _, n := range held {
+		lines = append(lines, "• "+n.Title)
+	}
+
+	return &Notification{
+		UserID:    userID,
+		Category:  CategoryMain,
+		Type:      TypeQuietHoursDigest,
+		Title:     "Уведомления за время тихих часов",
+		Content:   strings.Join(lines, "\n"),
+		CreatedAt: now,
+	}
+}
+
+// ReleaseHeld delivers every held notification whose quiet window has ended:
+// a single held notification is delivered as is, several become one digest.
+// Held rows are removed in the same transaction, so nothing is lost or
+// delivered twice if the process stops midway.
+func (s *Service) ReleaseHeld(ctx context.Context) (int, error) {
+	startTime := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		DELETE FROM held_notifications
+		WHERE release_at <= $1
+		RETURNING id, user_id, category, type, title, content, icon_url, action_url, content_category, created_at
+	`
+	rows, err := tx.QueryContext(ctx, query, s.now())
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+		return 0, fmt.Errorf("failed to claim held notifications: %w", err)
+	}
+
+	byUser := make(map[int64][]Notification)
+	var userIDs []int64
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Category, &n.Type, &n.Title, &n.Content,
+			&n.IconURL, &n.ActionURL, &n.ContentCategory, &n.CreatedAt); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan held notification: %w", err)
+		}
+		if _, ok := byUser[n.UserID]; !ok {
+			userIDs = append(userIDs, n.UserID)
+		}
+		byUser[n.UserID] = append(byUser[n.UserID], n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating held notifications: %w", err)
+	}
+
+	released := 0
+	for _, userID := range userIDs {
+		held := byUser[userID]
+		sort.Slice(held, func(i, j int) bool { return held[i].CreatedAt.Before(held[j].CreatedAt) })
+
+		notification := &held[0]
+		if len(held) > 1 {
+			notification = buildDigest(userID, held, s.now())
+			notification.ID = uuid.New().String()
+		}
+		if err := s.insertNotification(ctx, tx, notification); err != nil {
+			return 0, fmt.Errorf("failed to deliver held notifications: %w", err)
+		}
+		released += len(held)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if released > 0 {
+		s.log.LogBusinessEvent("held_notifications_released", map[string]interface{}{
+			"notifications": released,
+			"users":         len(userIDs),
+			"duration_ms":   time.Since(startTime).Milliseconds(),
+		})
+	}
+
+	return released, nil
+}
+
+// RunQuietHoursRelease delivers held notifications as quiet windows end. It
+// blocks until the provided context is cancelled.
+func (s *Service) RunQuietHoursRelease(ctx context.Context) {
+	ticker := time.NewTicker(quietHoursReleaseInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Quiet hours release worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.ReleaseHeld(ctx); err != nil {
+				s.log.Error("Failed to release held notifications", "error", err)
+			}
+		case <-ctx.Done():
+			s.log.Info("Quiet hours release worker stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package notifications
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	require.NoError(t, err)
+	return loc
+}
+
+func TestQuietHoursValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		quiet QuietHours
+		err   error
+	}{
+		{"disabled needs nothing", QuietHours{}, nil},
+		{"valid overnight", QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Europe/Moscow"}, nil},
+		{"bad start", QuietHours{Enabled: true, Start: "25:00", End: "07:00", Timezone: "UTC"}, ErrInvalidQuietHoursTime},
+		{"equal bounds", QuietHours{Enabled: true, Start: "07:00", End: "07:00", Timezone: "UTC"}, ErrInvalidQuietHoursTime},
+		{"missing timezone", QuietHours{Enabled: true, Start: "22:00", End: "07:00"}, ErrInvalidQuietHoursTimezone},
+		{"unknown timezone", QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Mars/Olympus"}, ErrInvalidQuietHoursTimezone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.err, tt.quiet.Validate())
+		})
+	}
+}
+
+func TestQuietHoursActiveAt(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	overnight := QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Europe/Moscow"}
+
+	t.Run("window crossing midnight, evening part ends next morning", func(t *testing.T) {
+		active, end := overnight.ActiveAt(time.Date(2026, 10, 16, 23, 30, 0, 0, moscow))
+
+		assert.True(t, active)
+		assert.True(t, end.Equal(time.Date(2026, 10, 17, 7, 0, 0, 0, moscow)))
+	})
+
+	t.Run("window crossing midnight, morning part ends the same day", func(t *testing.T) {
+		active, end := overnight.ActiveAt(time.Date(2026, 10, 17, 3, 0, 0, 0, moscow))
+
+		assert.True(t, active)
+		assert.True(t, end.Equal(time.Date(2026, 10, 17, 7, 0, 0, 0, moscow)))
+	})
+
+	t.Run("boundaries: start is inside, end is outside", func(t *testing.T) {
+		active, _ := overnight.ActiveAt(time.Date(2026, 10, 16, 22, 0, 0, 0, moscow))
+		assert.True(t, active)
+
+		active, _ = overnight.ActiveAt(time.Date(2026, 10, 17, 7, 0, 0, 0, moscow))
+		assert.False(t, active)
+
+		active, _ = overnight.ActiveAt(time.Date(2026, 10, 16, 12, 0, 0, 0, moscow))
+		assert.False(t, active)
+	})
+
+	t.Run("same-day window", func(t *testing.T) {
+		afternoon := QuietHours{Enabled: true, Start: "13:00", End: "15:00", Timezone: "UTC"}
+
+		active, end := afternoon.ActiveAt(time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC))
+		assert.True(t, active)
+		assert.True(t, end.Equal(time.Date(2026, 10, 16, 15, 0, 0, 0, time.UTC)))
+
+		active, _ = afternoon.ActiveAt(time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC))
+		assert.False(t, active)
+	})
+
+	t.Run("evaluated in the user's timezone, not the server's", func(t *testing.T) {
+		// 20:00 UTC is 23:00 in Moscow (quiet) but 13:00 in Los Angeles (not quiet)
+		now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+
+		active, _ := overnight.ActiveAt(now)
+		assert.True(t, active)
+
+		moved := overnight
+		moved.Timezone = "America/Los_Angeles"
+		active, _ = moved.ActiveAt(now)
+		assert.False(t, active)
+	})
+
+	t.Run("end follows a DST change overnight", func(t *testing.T) {
+		berlin := mustLocation(t, "Europe/Berlin")
+		quiet := QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "Europe/Berlin"}
+
+		// Clocks go back on 2026-10-25 at 03:00 CEST; the night is 10 hours long
+		active, end := quiet.ActiveAt(time.Date(2026, 10, 24, 22, 0, 0, 0, berlin))
+
+		assert.True(t, active)
+		assert.True(t, end.Equal(time.Date(2026, 10, 25, 7, 0, 0, 0, berlin)))
+		assert.Equal(t, 10*time.Hour, end.Sub(time.Date(2026, 10, 24, 22, 0, 0, 0, berlin)))
+	})
+
+	t.Run("disabled is never active", func(t *testing.T) {
+		active, _ := QuietHours{}.ActiveAt(time.Now())
+		assert.False(t, active)
+	})
+}
+
+func quietHoursRows(start, end int, timezone string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"start_minute", "end_minute", "timezone"}).AddRow(start, end, timezone)
+}
+
+func TestCreateNotification_QuietHours(t *testing.T) {
+	ctx := context.Background()
+	// 23:30 in Moscow
+	now := time.Date(2026, 10, 16, 20, 30, 0, 0, time.UTC)
+
+	t.Run("holds non-urgent notifications until the window ends", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.now = func() time.Time { return now }
+
+		notification := &Notification{
+			UserID: 1, Category: CategoryMain, Type: TypeTrainerFeedback,
+			Title: "Новый комментарий", Content: "Отличная работа",
+		}
+
+		mock.ExpectQuery(`FROM notification_quiet_hours`).
+			WithArgs(int64(1)).
+			WillReturnRows(quietHoursRows(22*60, 7*60, "Europe/Moscow"))
+		mock.ExpectExec(`INSERT INTO held_notifications`).
+			WithArgs(sqlmock.AnyArg(), int64(1), CategoryMain, TypeTrainerFeedback, "Новый комментарий", "Отличная работа",
+				nil, nil, nil, sqlmock.AnyArg(), time.Date(2026, 10, 17, 4, 0, 0, 0, time.UTC)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := service.CreateNotification(ctx, notification)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, notification.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delivers immediately outside the window", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.now = func() time.Time { return now }
+
+		mock.ExpectQuery(`FROM notification_quiet_hours`).
+			WillReturnRows(quietHoursRows(1*60, 6*60, "Europe/Moscow"))
+		mock.ExpectQuery(`INSERT INTO notifications`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("n-1", now))
+
+		err := service.CreateNotification(ctx, &Notification{
+			UserID: 1, Category: CategoryMain, Type: TypeReminder, Title: "Напоминание", Content: "Взвеситься",
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("security notifications bypass quiet hours", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.now = func() time.Time { return now }
+
+		// No quiet hours lookup at all
+		mock.ExpectQuery(`INSERT INTO notifications`).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("n-1", now))
+
+		err := service.CreateNotification(ctx, &Notification{
+			UserID: 1, Category: CategoryMain, Type: TypeSecurityAlert, Title: "Новый вход", Content: "Вход с нового устройства",
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+var heldColumns = []string{
+	"id", "user_id", "category", "type", "title", "content", "icon_url", "action_url", "content_category", "created_at",
+}
+
+func TestReleaseHeld(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 17, 4, 0, 0, 0, time.UTC)
+
+	t.Run("single held notification is delivered as is, several become one digest", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.now = func() time.Time { return now }
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM held_notifications`).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(heldColumns).
+				AddRow("h-1", int64(1), "main", "trainer_feedback", "Комментарий", "...", nil, nil, nil, now.Add(-3*time.Hour)).
+				AddRow("h-2", int64(2), "main", "curator_broadcast", "Сообщение", "...", nil, nil, nil, now.Add(-2*time.Hour)).
+				AddRow("h-3", int64(2), "main", "reminder", "Напоминание", "...", nil, nil, nil, now.Add(-4*time.Hour)))
+		mock.ExpectQuery(`INSERT INTO notifications`).
+			WithArgs("h-1", int64(1), CategoryMain, TypeTrainerFeedback, "Комментарий", "...",
+				nil, sqlmock.AnyArg(), nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("h-1", now))
+		mock.ExpectQuery(`INSERT INTO notifications`).
+			WithArgs(sqlmock.AnyArg(), int64(2), CategoryMain, TypeQuietHoursDigest, "Уведомления за время тихих часов",
+				"Пока действовали тихие часы, накопились уведомления (2):\n• Напоминание\n• Сообщение",
+				nil, now, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("d-1", now))
+		mock.ExpectCommit()
+
+		released, err := service.ReleaseHeld(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, released)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rolls back and keeps held rows when delivery fails", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+		service.now = func() time.Time { return now }
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`DELETE FROM held_notifications`).
+			WillReturnRows(sqlmock.NewRows(heldColumns).
+				AddRow("h-1", int64(1), "main", "reminder", "Напоминание", "...", nil, nil, nil, now))
+		mock.ExpectQuery(`INSERT INTO notifications`).
+			WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		_, err := service.ReleaseHeld(ctx)
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestUpdatePreferences_QuietHoursTimezoneChange(t *testing.T) {
+	service, mock, cleanup := setupTestService(t)
+	defer cleanup()
+	// 20:00 UTC: 23:00 in Moscow, 13:00 in Los Angeles
+	now := time.Date(2026, 10, 16, 20, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM content_notification_preferences`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DELETE FROM content_notification_mute`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO notification_quiet_hours`).
+		WithArgs(int64(1), 22*60, 7*60, "America/Los_Angeles").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Outside the window in the new timezone, so held notifications go out now
+	mock.ExpectExec(`UPDATE held_notifications SET release_at`).
+		WithArgs(int64(1), now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := service.UpdatePreferences(context.Background(), 1, UpdatePreferencesRequest{
+		QuietHours: &QuietHours{Enabled: true, Start: "22:00", End: "07:00", Timezone: "America/Los_Angeles"},
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
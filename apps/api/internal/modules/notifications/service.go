@@ -15,6 +15,7 @@ import (
 type Service struct {
 	db  *database.DB
 	log *logger.Logger
+	now func() time.Time
 }
 
 // NewService creates a new notifications service
@@ -22,6 +23,7 @@ func NewService(db *database.DB, log *logger.Logger) *Service {
 	return &Service{
 		db:  db,
 		log: log,
+		now: time.Now,
 	}
 }
 
@@ -262,7 +264,14 @@ func (s *Service) GetUnreadCounts(ctx context.Context, userID int64) (*UnreadCou
 	return counts, nil
 }
 
-// CreateNotification creates a new notification (for testing/admin use)
+// rowQuerier is satisfied by both *database.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CreateNotification creates a new notification. Unless its type bypasses
+// quiet hours, a notification for a user who is currently in quiet hours is
+// held and later delivered as part of a digest.
 func (s *Service) CreateNotification(ctx context.Context, notification *Notification) error {
 	startTime := time.Now()
 
@@ -281,30 +290,19 @@ func (s *Service) CreateNotification(ctx context.Context, notification *Notifica
 		notification.CreatedAt = time.Now()
 	}
 
-	query := `
-		INSERT INTO notifications (id, user_id, category, type, title, content, icon_url, created_at, read_at, action_url, content_category)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-		RETURNING id, created_at
-	`
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		notification.ID,
-		notification.UserID,
-		notification.Category,
-		notification.Type,
-		notification.Title,
-		notification.Content,
-		notification.IconURL,
-		notification.CreatedAt,
-		notification.ReadAt,
-		notification.ActionURL,
-		notification.ContentCategory,
-	).Scan(&notification.ID, &notification.CreatedAt)
+	if !notification.Type.BypassesQuietHours() {
+		held, err := s.holdIfQuiet(ctx, notification)
+		if err != nil {
+			return err
+		}
+		if held {
+			return nil
+		}
+	}
 
+	err := s.insertNotification(ctx, s.db, notification)
 	if err != nil {
-		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		s.log.LogDatabaseQuery(insertNotificationQuery, time.Since(startTime), err, map[string]interface{}{
 			"user_id":  notification.UserID,
 			"category": notification.Category,
 			"type":     notification.Type,
@@ -312,7 +310,7 @@ func (s *Service) CreateNotification(ctx context.Context, notification *Notifica
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
 
-	s.log.LogDatabaseQuery(query, time.Since(startTime), nil, map[string]interface{}{
+	s.log.LogDatabaseQuery(insertNotificationQuery, time.Since(startTime), nil, map[string]interface{}{
 		"notification_id": notification.ID,
 		"user_id":         notification.UserID,
 		"category":        notification.Category,
@@ -328,3 +326,28 @@ func (s *Service) CreateNotification(ctx context.Context, notification *Notifica
 
 	return nil
 }
+
+const insertNotificationQuery = `
+	INSERT INTO notifications (id, user_id, category, type, title, content, icon_url, created_at, read_at, action_url, content_category)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	RETURNING id, created_at
+`
+
+// insertNotification writes a validated notification with its ID and created_at set
+func (s *Service) insertNotification(ctx context.Context, q rowQuerier, notification *Notification) error {
+	return q.QueryRowContext(
+		ctx,
+		insertNotificationQuery,
+		notification.ID,
+		notification.UserID,
+		notification.Category,
+		notification.Type,
+		notification.Title,
+		notification.Content,
+		notification.IconURL,
+		notification.CreatedAt,
+		notification.ReadAt,
+		notification.ActionURL,
+		notification.ContentCategory,
+	).Scan(&notification.ID, &notification.CreatedAt)
+}
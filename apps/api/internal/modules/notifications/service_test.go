@@ -358,6 +358,11 @@ func TestCreateNotification(t *testing.T) {
 			CreatedAt: time.Now(),
 		}
 
+		// No quiet hours configured
+		mock.ExpectQuery(`FROM notification_quiet_hours`).
+			WithArgs(notification.UserID).
+			WillReturnError(sql.ErrNoRows)
+
 		// Mock the insert query
 		rows := sqlmock.NewRows([]string{"id", "created_at"}).
 			AddRow(uuid.New().String(), time.Now())
@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -22,6 +23,24 @@ func (c NotificationCategory) IsValid() bool {
 	return false
 }
 
+// NotificationChannel represents a delivery channel for curator-originated messages
+type NotificationChannel string
+
+const (
+	ChannelInApp NotificationChannel = "in_app"
+	ChannelEmail NotificationChannel = "email"
+	ChannelPush  NotificationChannel = "push"
+)
+
+// IsValid checks if the notification channel is valid
+func (c NotificationChannel) IsValid() bool {
+	switch c {
+	case ChannelInApp, ChannelEmail, ChannelPush:
+		return true
+	}
+	return false
+}
+
 // NotificationType represents the type of a notification
 type NotificationType string
 
@@ -37,18 +56,29 @@ const (
 	TypeTaskAssigned     NotificationType = "task_assigned"
 	TypeTaskOverdue      NotificationType = "task_overdue"
 	TypeFeedbackReceived NotificationType = "feedback_received"
+	TypeCuratorBroadcast NotificationType = "curator_broadcast"
+	TypeGoalSuggestion   NotificationType = "goal_suggestion"
+	TypeSecurityAlert    NotificationType = "security_alert"
+	TypeQuietHoursDigest NotificationType = "quiet_hours_digest"
 )
 
 // IsValid checks if the notification type is valid
 func (t NotificationType) IsValid() bool {
 	switch t {
 	case TypeTrainerFeedback, TypeAchievement, TypeReminder, TypeSystemUpdate, TypeNewFeature, TypeGeneral, TypeNewContent,
-		TypePlanUpdated, TypeTaskAssigned, TypeTaskOverdue, TypeFeedbackReceived:
+		TypePlanUpdated, TypeTaskAssigned, TypeTaskOverdue, TypeFeedbackReceived, TypeCuratorBroadcast,
+		TypeGoalSuggestion, TypeSecurityAlert, TypeQuietHoursDigest:
 		return true
 	}
 	return false
 }
 
+// BypassesQuietHours reports whether notifications of this type are delivered
+// immediately even during the recipient's quiet hours
+func (t NotificationType) BypassesQuietHours() bool {
+	return t == TypeSecurityAlert || t == TypeQuietHoursDigest
+}
+
 // Notification represents a user notification
 type Notification struct {
 	ID              string               `json:"id" db:"id"`
@@ -129,12 +159,70 @@ type UnreadCountsResponse struct {
 
 // ContentNotificationPreferences represents a user's content notification settings
 type ContentNotificationPreferences struct {
-	MutedCategories []string `json:"mutedCategories"`
-	Muted           bool     `json:"muted"`
+	MutedCategories []string   `json:"mutedCategories"`
+	Muted           bool       `json:"muted"`
+	MutedChannels   []string   `json:"mutedChannels"`
+	QuietHours      QuietHours `json:"quietHours"`
 }
 
 // UpdatePreferencesRequest is the request body for updating notification preferences
+// MutedChannels and QuietHours are optional: when omitted, they are left untouched.
 type UpdatePreferencesRequest struct {
-	MutedCategories []string `json:"mutedCategories"`
-	Muted           bool     `json:"muted"`
+	MutedCategories []string    `json:"mutedCategories"`
+	Muted           bool        `json:"muted"`
+	MutedChannels   *[]string   `json:"mutedChannels,omitempty"`
+	QuietHours      *QuietHours `json:"quietHours,omitempty"`
+}
+
+// QuietHours is a daily window in the user's timezone during which non-urgent
+// notifications are held and then delivered as a single digest.
+// Start and End are "HH:MM"; a window with Start after End crosses midnight.
+type QuietHours struct {
+	Enabled  bool   `json:"enabled"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+}
+
+var (
+	ErrInvalidQuietHoursTime     = errors.New("quiet hours start and end must be different HH:MM times")
+	ErrInvalidQuietHoursTimezone = errors.New("quiet hours timezone must be a valid IANA name")
+)
+
+// EmailKind is an optional email a user can opt out of. Security emails
+// (password reset, verification codes) are not EmailKinds and always send.
+type EmailKind string
+
+const (
+	EmailPasswordChanged EmailKind = "password_changed_email"
+	EmailWeeklySummary   EmailKind = "weekly_summary_email"
+	EmailCoachActivity   EmailKind = "coach_activity_email"
+)
+
+// IsValid checks if the email kind is valid
+func (k EmailKind) IsValid() bool {
+	switch k {
+	case EmailPasswordChanged, EmailWeeklySummary, EmailCoachActivity:
+		return true
+	}
+	return false
+}
+
+// EmailPreferences is the response of GET /api/v1/users/notifications.
+// Every email is enabled until the user turns it off.
+type EmailPreferences struct {
+	PasswordChangedEmail bool `json:"password_changed_email"`
+	WeeklySummaryEmail   bool `json:"weekly_summary_email"`
+	CoachActivityEmail   bool `json:"coach_activity_email"`
 }
+
+// UpdateEmailPreferencesRequest is the body of PUT /api/v1/users/notifications.
+// Omitted fields are left unchanged.
+type UpdateEmailPreferencesRequest struct {
+	PasswordChangedEmail *bool `json:"password_changed_email"`
+	WeeklySummaryEmail   *bool `json:"weekly_summary_email"`
+	CoachActivityEmail   *bool `json:"coach_activity_email"`
+}
+
+// ErrInvalidUnsubscribeToken is returned for unknown unsubscribe tokens
+var ErrInvalidUnsubscribeToken = errors.New("invalid unsubscribe token")
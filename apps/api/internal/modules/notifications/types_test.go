@@ -98,6 +98,16 @@ func TestNotificationType_IsValid(t *testing.T) {
 			typ:  TypeFeedbackReceived,
 			want: true,
 		},
+		{
+			name: "valid curator_broadcast type",
+			typ:  TypeCuratorBroadcast,
+			want: true,
+		},
+		{
+			name: "valid goal_suggestion type",
+			typ:  TypeGoalSuggestion,
+			want: true,
+		},
 		{
 			name: "invalid type",
 			typ:  NotificationType("invalid"),
@@ -119,6 +129,28 @@ func TestNotificationType_IsValid(t *testing.T) {
 	}
 }
 
+func TestNotificationChannel_IsValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		channel NotificationChannel
+		want    bool
+	}{
+		{name: "in_app", channel: ChannelInApp, want: true},
+		{name: "email", channel: ChannelEmail, want: true},
+		{name: "push", channel: ChannelPush, want: true},
+		{name: "invalid channel", channel: NotificationChannel("sms"), want: false},
+		{name: "empty channel", channel: NotificationChannel(""), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.channel.IsValid(); got != tt.want {
+				t.Errorf("NotificationChannel.IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNotification_Validate(t *testing.T) {
 	validIconURL := "https://example.com/icon.png"
 	longIconURL := string(make([]byte, 501))
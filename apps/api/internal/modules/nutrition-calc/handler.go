@@ -47,9 +47,12 @@ func (h *Handler) GetTargets(c *gin.Context) {
 		return
 	}
 
-	dateStr := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+	userLoc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
+	dateStr := c.DefaultQuery("date", time.Now().In(userLoc).Format("2006-01-02"))
 
-	userLoc := middleware.GetUserTimezone(c.Request.Context(), h.db, userID)
 	date, err := time.ParseInLocation("2006-01-02", dateStr, userLoc)
 	if err != nil {
 		h.log.Errorw("Invalid date format", "error", err, "date", dateStr)
@@ -140,7 +143,10 @@ func (h *Handler) Recalculate(c *gin.Context) {
 		return
 	}
 
-	userLoc := middleware.GetUserTimezone(c.Request.Context(), h.db, userID)
+	userLoc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
 	today := time.Now().In(userLoc)
 
 	targets, err := h.service.RecalculateForDate(c.Request.Context(), userID, today)
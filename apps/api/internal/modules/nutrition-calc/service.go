@@ -114,6 +114,22 @@ func (s *Service) RecalculateForDate(ctx context.Context, userID int64, date tim
 	return &targets, nil
 }
 
+// SuggestTargets calculates base (no workout) KBJU targets for the user at the
+// given weight. Returns nil if the user profile is incomplete.
+func (s *Service) SuggestTargets(ctx context.Context, userID int64, weightKg float64) (*CalculatedTargets, error) {
+	profile, err := s.getUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, nil
+	}
+	profile.WeightKg = weightKg
+
+	targets := CalculateTargets(*profile, nil)
+	return &targets, nil
+}
+
 // GetTargetsForDate returns the stored calculated targets for a date.
 func (s *Service) GetTargetsForDate(ctx context.Context, userID int64, date string) (*DailyTargetRecord, error) {
 	query := `
@@ -222,6 +238,22 @@ func (s *Service) GetHistory(ctx context.Context, userID int64, days int) ([]Tar
 	return result, nil
 }
 
+// GetFitnessGoal returns the user's fitness goal, defaulting to maintain when
+// it is unset.
+func (s *Service) GetFitnessGoal(ctx context.Context, userID int64) (FitnessGoal, error) {
+	var goal sql.NullString
+	err := s.db.QueryRowContext(ctx, `SELECT fitness_goal FROM user_settings WHERE user_id = $1`, userID).Scan(&goal)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("getting fitness goal: %w", err)
+	}
+
+	fg := FitnessGoal(goal.String)
+	if _, ok := GoalModifiers[fg]; !ok {
+		fg = GoalMaintain
+	}
+	return fg, nil
+}
+
 // getUserProfile returns profile data needed for calculation, or nil if incomplete.
 func (s *Service) getUserProfile(ctx context.Context, userID int64) (*UserProfile, error) {
 	query := `
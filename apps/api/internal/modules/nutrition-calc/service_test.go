@@ -287,3 +287,66 @@ func TestGetHistory(t *testing.T) {
 		assert.Equal(t, 500.0, history[0].Actual.Calories)
 	})
 }
+
+func TestSuggestTargets(t *testing.T) {
+	t.Run("calculates base targets at the given weight", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT birth_date, biological_sex, height").
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"birth_date", "biological_sex", "height", "activity_level", "fitness_goal"}).
+				AddRow(time.Now().AddDate(-30, 0, 0), "male", 180.0, "moderate", "loss"))
+
+		targets, err := service.SuggestTargets(context.Background(), 1, 80)
+
+		require.NoError(t, err)
+		require.NotNil(t, targets)
+		assert.Equal(t, 80.0, targets.WeightUsed)
+		assert.Equal(t, 0.0, targets.WorkoutBonus)
+		assert.Equal(t, 144.0, targets.Protein)
+	})
+
+	t.Run("returns nil for incomplete profile", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT birth_date, biological_sex, height").
+			WillReturnRows(sqlmock.NewRows([]string{"birth_date", "biological_sex", "height", "activity_level", "fitness_goal"}).
+				AddRow(nil, "male", 180.0, nil, nil))
+
+		targets, err := service.SuggestTargets(context.Background(), 1, 80)
+
+		require.NoError(t, err)
+		assert.Nil(t, targets)
+	})
+}
+
+func TestGetFitnessGoal(t *testing.T) {
+	t.Run("returns the stored goal", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT fitness_goal FROM user_settings").
+			WithArgs(int64(1)).
+			WillReturnRows(sqlmock.NewRows([]string{"fitness_goal"}).AddRow("loss"))
+
+		goal, err := service.GetFitnessGoal(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, GoalLoss, goal)
+	})
+
+	t.Run("defaults to maintain", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT fitness_goal FROM user_settings").
+			WillReturnRows(sqlmock.NewRows([]string{"fitness_goal"}).AddRow(nil))
+
+		goal, err := service.GetFitnessGoal(context.Background(), 1)
+
+		require.NoError(t, err)
+		assert.Equal(t, GoalMaintain, goal)
+	})
+}
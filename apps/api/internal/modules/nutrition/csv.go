@@ -0,0 +1,166 @@
+package nutrition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// CSV import limits
+const (
+	// MaxImportRows caps the data rows of an imported file
+	MaxImportRows = 1000
+	// MaxImportFileSize caps an imported file, in bytes
+	MaxImportFileSize = 2 << 20
+)
+
+// csvColumns are the columns of an exported file, in order. Files exported
+// before the micronutrients end at fat; they import all the same.
+var csvColumns = []string{
+	"date", "meal", "food", "calories", "protein", "carbs", "fat",
+	"fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g",
+}
+
+// csvRequiredColumns must be in the header of an imported file
+var csvRequiredColumns = []string{"date", "meal", "food", "calories"}
+
+// entryRecord returns the exported row of e. Micronutrients the entry did
+// not record are left empty rather than written as 0.
+func entryRecord(e *Entry) []string {
+	optional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return formatNumber(*v)
+	}
+	return []string{
+		e.Date, e.Meal, e.Food,
+		formatNumber(e.Calories), formatNumber(e.Protein), formatNumber(e.Carbs), formatNumber(e.Fat),
+		optional(e.FiberG), optional(e.SugarG), optional(e.SodiumMg), optional(e.SaturatedFatG),
+	}
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// importColumns finds the csvColumns in the header of an imported file by
+// name, ignoring case. Other columns, such as a spreadsheet's own notes, are
+// skipped, and any column after calories may be missing.
+func importColumns(header []string) (csvimport.Columns, error) {
+	mapping := csvimport.Mapping{}
+	for _, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		for _, column := range csvColumns {
+			if name == column {
+				mapping[column] = column
+			}
+		}
+	}
+	return mapping.Resolve(header, csvColumns, csvRequiredColumns...)
+}
+
+// parseEntryRecord builds the entry request of an imported row. An empty
+// macro cell is 0 and an empty micronutrient cell stays null; the request
+// is validated when it is created.
+func parseEntryRecord(cols csvimport.Columns, record csvimport.Record) (*CreateEntryRequest, error) {
+	errs := validation.Errors{}
+	req := &CreateEntryRequest{
+		Meal: cols.Get(record, "meal"),
+		Food: cols.Get(record, "food"),
+	}
+
+	if date, err := csvimport.ParseDate(cols.Get(record, "date")); err != nil {
+		errs["date"] = err.Error()
+	} else {
+		req.Date = types.DateOf(date)
+	}
+
+	number := func(field string) *float64 {
+		cell := cols.Get(record, field)
+		if cell == "" {
+			return nil
+		}
+		v, err := csvimport.ParseNumber(cell)
+		if err != nil {
+			errs[field] = err.Error()
+			return nil
+		}
+		return &v
+	}
+	req.Calories = number("calories")
+	for field, grams := range map[string]*float64{"protein": &req.Protein, "carbs": &req.Carbs, "fat": &req.Fat} {
+		if v := number(field); v != nil {
+			*grams = *v
+		}
+	}
+	req.FiberG = number("fiber_g")
+	req.SugarG = number("sugar_g")
+	req.SodiumMg = number("sodium_mg")
+	req.SaturatedFatG = number("saturated_fat_g")
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return req, nil
+}
+
+// importEntries creates an entry for each row through service, so imported
+// entries are validated and counted against the quota like logged ones.
+// Rows that are invalid or over the quota are reported as row errors and
+// the rest are still imported; any other failure stops the import.
+func importEntries(ctx context.Context, service ServiceInterface, userID int64, header []string, records []csvimport.Record) (*ImportEntriesResponse, error) {
+	cols, err := importColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportEntriesResponse{RowErrors: []csvimport.RowError{}}
+	for _, record := range records {
+		req, err := parseEntryRecord(cols, record)
+		if err == nil {
+			_, err = service.CreateEntry(ctx, userID, req)
+		}
+		if err != nil {
+			msg, ok := rowErrorMessage(err)
+			if !ok {
+				return nil, fmt.Errorf("failed to import line %d: %w", record.Line, err)
+			}
+			result.RowErrors = append(result.RowErrors, csvimport.RowError{Line: record.Line, Error: msg})
+			continue
+		}
+		result.Imported++
+	}
+	return result, nil
+}
+
+// rowErrorMessage returns the message for a row rejected by err, and false
+// when err is not the row's fault
+func rowErrorMessage(err error) (string, bool) {
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		fields := make([]string, 0, len(fieldErrs))
+		for field := range fieldErrs {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		parts := make([]string, len(fields))
+		for i, field := range fields {
+			parts[i] = field + ": " + fieldErrs[field]
+		}
+		return strings.Join(parts, "; "), true
+	}
+	var quota *apperrors.QuotaExceededError
+	if errors.As(err, &quota) {
+		return fmt.Sprintf("Превышена квота записей на дату (%d из %d)", quota.Used, quota.Limit), true
+	}
+	return "", false
+}
@@ -0,0 +1,264 @@
+package nutrition
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// importingService records the entries an import creates. A food listed in
+// errs fails with its error.
+type importingService struct {
+	mockService
+	created []*CreateEntryRequest
+	errs    map[string]error
+}
+
+func (s *importingService) CreateEntry(ctx context.Context, userID int64, req *CreateEntryRequest) (*Entry, error) {
+	if err := s.errs[req.Food]; err != nil {
+		return nil, err
+	}
+	s.created = append(s.created, req)
+	return &Entry{Food: req.Food}, nil
+}
+
+// readCSV reads a test file the way the import handler does
+func readCSV(t *testing.T, text string) ([]string, []csvimport.Record) {
+	t.Helper()
+	header, records, err := csvimport.Read(strings.NewReader(text), true, MaxImportRows)
+	require.NoError(t, err)
+	return header, records
+}
+
+func TestImportColumns(t *testing.T) {
+	t.Run("current format", func(t *testing.T) {
+		cols, err := importColumns(csvColumns)
+		require.NoError(t, err)
+		assert.Len(t, cols, len(csvColumns))
+	})
+
+	t.Run("old format without micronutrients", func(t *testing.T) {
+		cols, err := importColumns([]string{"date", "meal", "food", "calories", "protein", "carbs", "fat"})
+		require.NoError(t, err)
+		assert.NotContains(t, cols, "sodium_mg")
+		assert.Equal(t, 6, cols["fat"])
+	})
+
+	t.Run("matches names ignoring case and skips unknown columns", func(t *testing.T) {
+		cols, err := importColumns([]string{"Note", " Date ", "MEAL", "Food", "Calories", "Sodium_mg"})
+		require.NoError(t, err)
+		assert.Equal(t, csvimport.Columns{"date": 1, "meal": 2, "food": 3, "calories": 4, "sodium_mg": 5}, cols)
+	})
+
+	t.Run("rejects a file without a required column", func(t *testing.T) {
+		_, err := importColumns([]string{"date", "meal", "food", "protein"})
+		assert.ErrorIs(t, err, csvimport.ErrInvalidFile)
+	})
+}
+
+func TestParseEntryRecord(t *testing.T) {
+	cols, err := importColumns(csvColumns)
+	require.NoError(t, err)
+
+	t.Run("empty micronutrient cells stay null", func(t *testing.T) {
+		req, err := parseEntryRecord(cols, csvimport.Record{Line: 2, Fields: []string{
+			"2026-01-26", "breakfast", "Овсянка", "150", "5", "27", "3", "4,5", "", "", "",
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, "2026-01-26", req.Date.String())
+		assert.Equal(t, 150.0, *req.Calories)
+		assert.Equal(t, 4.5, *req.FiberG)
+		assert.Nil(t, req.SugarG)
+		assert.Nil(t, req.SodiumMg)
+		assert.Nil(t, req.SaturatedFatG)
+	})
+
+	t.Run("empty macro cells are zero", func(t *testing.T) {
+		req, err := parseEntryRecord(cols, csvimport.Record{Line: 2, Fields: []string{"26.01.2026", "обед", "Суп", "200"}})
+		require.NoError(t, err)
+		assert.Zero(t, req.Protein)
+		assert.Zero(t, req.Fat)
+		assert.Nil(t, req.FiberG)
+	})
+
+	t.Run("reports unreadable cells by field", func(t *testing.T) {
+		_, err := parseEntryRecord(cols, csvimport.Record{Line: 2, Fields: []string{
+			"вчера", "lunch", "Суп", "200", "", "", "", "", "", "много", "",
+		}})
+		var fieldErrs validation.Errors
+		require.ErrorAs(t, err, &fieldErrs)
+		assert.Contains(t, fieldErrs, "date")
+		assert.Contains(t, fieldErrs, "sodium_mg")
+	})
+}
+
+func TestImportEntries(t *testing.T) {
+	t.Run("old format file", func(t *testing.T) {
+		service := &importingService{}
+		header, records := readCSV(t, "date,meal,food,calories,protein,carbs,fat\n"+
+			"2026-01-25,breakfast,Овсянка,150,5,27,3\n"+
+			"2026-01-25,dinner,Рыба,320,40,0,17\n")
+
+		result, err := importEntries(context.Background(), service, testUserID, header, records)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Imported)
+		assert.Empty(t, result.RowErrors)
+		require.Len(t, service.created, 2)
+		assert.Equal(t, 40.0, service.created[1].Protein)
+		assert.Equal(t, Micronutrients{}, service.created[1].Micronutrients)
+	})
+
+	t.Run("current format file", func(t *testing.T) {
+		service := &importingService{}
+		header, records := readCSV(t, strings.Join(csvColumns, ",")+"\n"+
+			"2026-01-25,lunch,Суп,200,10,20,8,3,2,1200,2.5\n")
+
+		result, err := importEntries(context.Background(), service, testUserID, header, records)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Imported)
+		require.Len(t, service.created, 1)
+		assert.Equal(t, 1200.0, *service.created[0].SodiumMg)
+		assert.Equal(t, 2.5, *service.created[0].SaturatedFatG)
+	})
+
+	t.Run("rejected rows are listed and the rest imported", func(t *testing.T) {
+		service := &importingService{errs: map[string]error{
+			"Торт":  validation.Errors{"meal": "Допустимые значения: breakfast, lunch, dinner, snack", "calories": "Значение должно быть от 0 до 10000"},
+			"Салат": &apperrors.QuotaExceededError{Resource: "entries_per_day", Used: 200, Limit: 200},
+		}}
+		header, records := readCSV(t, "date,meal,food,calories\n"+
+			"2026-01-25,breakfast,Овсянка,150\n"+
+			"2026-01-25,brunch,Торт,20000\n"+
+			"2026-01-25,lunch,Салат,90\n")
+
+		result, err := importEntries(context.Background(), service, testUserID, header, records)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.Imported)
+		assert.Equal(t, []csvimport.RowError{
+			{Line: 3, Error: "calories: Значение должно быть от 0 до 10000; meal: Допустимые значения: breakfast, lunch, dinner, snack"},
+			{Line: 4, Error: "Превышена квота записей на дату (200 из 200)"},
+		}, result.RowErrors)
+	})
+
+	t.Run("a database error stops the import", func(t *testing.T) {
+		service := &importingService{errs: map[string]error{"Овсянка": errors.New("connection reset")}}
+		header, records := readCSV(t, "date,meal,food,calories\n2026-01-25,breakfast,Овсянка,150\n")
+
+		_, err := importEntries(context.Background(), service, testUserID, header, records)
+
+		assert.ErrorContains(t, err, "line 2")
+	})
+}
+
+func TestHandler_ExportEntries(t *testing.T) {
+	t.Run("writes every entry, leaving unknown micronutrients empty", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery(`SELECT (.+) FROM nutrition_entries\s+WHERE user_id = \$1\s+ORDER BY date ASC`).
+			WithArgs(testUserID).
+			WillReturnRows(sqlmock.NewRows(entryColumnNames).
+				AddRow(testEntryID, testUserID, "2026-01-25", MealBreakfast, "Овсянка", 150.0, 5.0, 27.0, 3.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil).
+				AddRow("entry-2", testUserID, "2026-01-26", MealLunch, "Суп, куриный", 200.0, 10.0, 20.0, 8.5, testNow, testNow, nil, nil, 1, 3.0, nil, 1200.0, 2.5))
+
+		w := serveWithHeaders(t, handler.ExportEntries, testUserID, http.MethodGet, "/entries/export", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "nutrition.csv")
+		assert.Equal(t, "date,meal,food,calories,protein,carbs,fat,fiber_g,sugar_g,sodium_mg,saturated_fat_g\n"+
+			"2026-01-25,breakfast,Овсянка,150,5,27,3,,,,\n"+
+			"2026-01-26,lunch,\"Суп, куриный\",200,10,20,8.5,3,,1200,2.5\n", w.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		// The export imports back as it was
+		service := &importingService{}
+		header, records := readCSV(t, w.Body.String())
+		result, err := importEntries(context.Background(), service, testUserID, header, records)
+		require.NoError(t, err)
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, "Суп, куриный", service.created[1].Food)
+		assert.Nil(t, service.created[1].SugarG)
+		assert.Equal(t, 3.0, *service.created[1].FiberG)
+	})
+
+	t.Run("a failed query is an error response", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").WillReturnError(errors.New("connection reset"))
+
+		status, resp := serve(t, handler.ExportEntries, testUserID, http.MethodGet, "/entries/export", "")
+
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, "error", resp["status"])
+	})
+}
+
+// serveImport posts content as the multipart file of an import
+func serveImport(t *testing.T, handler *Handler, content string) (int, map[string]interface{}) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "nutrition.csv")
+	require.NoError(t, err)
+	_, err = part.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	router.POST("/entries/import", func(c *gin.Context) {
+		c.Set("user_id", testUserID)
+		handler.ImportEntries(c)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/entries/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestHandler_ImportEntries(t *testing.T) {
+	t.Run("imports an old format file", func(t *testing.T) {
+		service := &importingService{}
+		handler := NewHandler(&config.Config{}, logger.New(), nil, service, nil, nil)
+
+		status, resp := serveImport(t, handler, "date;meal;food;calories;protein;carbs;fat\n25.01.2026;завтрак;Овсянка;150;5;27;3,5\n")
+
+		assert.Equal(t, http.StatusOK, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, float64(1), data["imported"])
+		assert.Empty(t, data["row_errors"])
+		require.Len(t, service.created, 1)
+		assert.Equal(t, 3.5, service.created[0].Fat)
+	})
+
+	t.Run("rejects a file without the required columns", func(t *testing.T) {
+		handler := NewHandler(&config.Config{}, logger.New(), nil, &importingService{}, nil, nil)
+
+		status, resp := serveImport(t, handler, "date,meal,food\n2026-01-25,breakfast,Овсянка\n")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, resp["message"], "calories")
+	})
+}
@@ -0,0 +1,32 @@
+package nutrition
+
+import "github.com/burcev/api/internal/shared/events"
+
+// Entry events, published in the transaction of the entry write. Their
+// JSON is the entry's, so consumers read them like the entries API.
+
+// EntryCreated reports a logged entry
+type EntryCreated struct {
+	*Entry
+}
+
+// EventType implements events.Payload
+func (EntryCreated) EventType() string { return events.NutritionEntryCreated }
+
+// EntryUpdated reports an edited entry; Previous is the entry before the
+// edit
+type EntryUpdated struct {
+	*Entry
+	Previous *Entry `json:"previous"`
+}
+
+// EventType implements events.Payload
+func (EntryUpdated) EventType() string { return events.NutritionEntryUpdated }
+
+// EntryDeleted reports a deleted entry as it was
+type EntryDeleted struct {
+	*Entry
+}
+
+// EventType implements events.Payload
+func (EntryDeleted) EventType() string { return events.NutritionEntryDeleted }
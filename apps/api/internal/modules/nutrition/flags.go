@@ -0,0 +1,236 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/tombstones"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// Day flag types
+const (
+	DayFlagRefeed = "refeed"
+	DayFlagSick   = "sick"
+	DayFlagTravel = "travel"
+)
+
+// DayFlagTypes are the accepted day flag types
+var DayFlagTypes = []string{DayFlagRefeed, DayFlagSick, DayFlagTravel}
+
+// Day flag limits
+const (
+	MaxDayFlagNoteLength = 500
+	// MaxDayFlagAheadDays bounds how far ahead a day can be flagged, so a
+	// refeed can be planned
+	MaxDayFlagAheadDays = 90
+)
+
+// FlagDayRequest marks a day as a refeed, sick or travel day
+type FlagDayRequest struct {
+	Type string `json:"type" binding:"required"`
+	Note string `json:"note"`
+}
+
+// DayFlag is a user's mark on a day. Excluded days are left out of
+// adherence statistics and recommendations.
+type DayFlag struct {
+	Date      string    `json:"date"`
+	Type      string    `json:"type"`
+	Note      string    `json:"note"`
+	Excluded  bool      `json:"excluded"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FlagService handles day flags
+type FlagService struct {
+	db       *database.DB
+	log      *logger.Logger
+	now      func() time.Time
+	excluded []string
+	dayCache cache.Cache
+}
+
+// NewFlagService creates a new day flag service. Days flagged with one of
+// the excluded types are left out of adherence statistics. Flag changes
+// remove the day from dayCache, which may be nil.
+func NewFlagService(db *database.DB, log *logger.Logger, excluded []string, dayCache cache.Cache) *FlagService {
+	return &FlagService{db: db, log: log, now: time.Now, excluded: excluded, dayCache: dayCache}
+}
+
+// Excludes reports whether days flagged with flagType are left out of
+// adherence statistics
+func (s *FlagService) Excludes(flagType string) bool {
+	return slices.Contains(s.excluded, flagType)
+}
+
+// validateFlag checks a day flag request and normalizes req.Type. Unlike
+// entries, a day may be flagged ahead of time. Invalid input is reported as
+// validation.Errors.
+func validateFlag(date types.Date, req *FlagDayRequest, now time.Time) error {
+	errs := validation.Errors{}
+
+	switch {
+	case date.IsZero() || date.Malformed():
+		errs["date"] = types.DateMessage
+	case date.Before(types.DateOf(now.AddDate(-maxEntryAgeYears, 0, 0))):
+		errs["date"] = fmt.Sprintf("Дата не может быть раньше чем %d года назад", maxEntryAgeYears)
+	case date.After(types.DateOf(now).AddDays(MaxDayFlagAheadDays)):
+		errs["date"] = fmt.Sprintf("Дату можно отметить не более чем на %d дней вперёд", MaxDayFlagAheadDays)
+	}
+
+	req.Type = strings.ToLower(strings.TrimSpace(req.Type))
+	if !slices.Contains(DayFlagTypes, req.Type) {
+		errs["type"] = "Допустимые значения: refeed, sick, travel"
+	}
+
+	req.Note = strings.TrimSpace(req.Note)
+	if utf8.RuneCountInString(req.Note) > MaxDayFlagNoteLength {
+		errs["note"] = fmt.Sprintf("Не более %d символов", MaxDayFlagNoteLength)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SetFlag flags a day, replacing the flag it already has
+func (s *FlagService) SetFlag(ctx context.Context, userID int64, date types.Date, req *FlagDayRequest) (*DayFlag, error) {
+	if err := validateFlag(date, req, s.now()); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO nutrition_day_flags (user_id, date, type, note)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, date) DO UPDATE
+		SET type = EXCLUDED.type, note = EXCLUDED.note, updated_at = NOW()
+		RETURNING date::text, type, note, created_at, updated_at`
+
+	var f DayFlag
+	err := s.db.QueryRowContext(ctx, query, userID, date, req.Type, req.Note).
+		Scan(&f.Date, &f.Type, &f.Note, &f.CreatedAt, &f.UpdatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"date":    date.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to flag day: %w", err)
+	}
+	invalidateReportDays(ctx, s.dayCache, s.log, userID, f.Date)
+	f.Excluded = s.Excludes(f.Type)
+	return &f, nil
+}
+
+// DeleteFlag removes the flag of a day. A day without a flag is reported as
+// not found.
+func (s *FlagService) DeleteFlag(ctx context.Context, userID int64, date types.Date) error {
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		startTime := time.Now()
+		query := `DELETE FROM nutrition_day_flags WHERE user_id = $1 AND date = $2`
+
+		result, err := tx.ExecContext(ctx, query, userID, date)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"user_id": userID,
+			"date":    date.String(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete day flag: %w", err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to delete day flag: %w", err)
+		}
+		if affected == 0 {
+			return apperrors.ErrNotFound
+		}
+		return tombstones.Record(ctx, tx, userID, tombstones.DayFlags, date.String())
+	})
+	if err != nil {
+		return err
+	}
+	invalidateReportDays(ctx, s.dayCache, s.log, userID, date.String())
+	return nil
+}
+
+// GetFlag returns the flag of a day, nil when the day has none
+func (s *FlagService) GetFlag(ctx context.Context, userID int64, date string) (*DayFlag, error) {
+	flags, err := s.loadFlags(ctx, userID, date, date)
+	if err != nil || len(flags) == 0 {
+		return nil, err
+	}
+	return flags[0], nil
+}
+
+// ListFlags returns the flagged days of dates, at most MaxReportDays long,
+// oldest first
+func (s *FlagService) ListFlags(ctx context.Context, userID int64, dates types.DateRange) ([]*DayFlag, error) {
+	if err := dates.Validate(MaxReportDays); err != nil {
+		return nil, err
+	}
+	return s.loadFlags(ctx, userID, dates.From.String(), dates.To.String())
+}
+
+// ExcludedDates returns the days from from to to (YYYY-MM-DD) whose flag
+// leaves them out of adherence statistics
+func (s *FlagService) ExcludedDates(ctx context.Context, userID int64, from, to string) (map[string]bool, error) {
+	flags, err := s.loadFlags(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool)
+	for _, f := range flags {
+		if f.Excluded {
+			excluded[f.Date] = true
+		}
+	}
+	return excluded, nil
+}
+
+// loadFlags returns the flagged days of the range, oldest first
+func (s *FlagService) loadFlags(ctx context.Context, userID int64, from, to string) ([]*DayFlag, error) {
+	startTime := time.Now()
+	query := `
+		SELECT date::text, type, note, created_at, updated_at
+		FROM nutrition_day_flags
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		ORDER BY date`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"from":    from,
+		"to":      to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query day flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := make([]*DayFlag, 0)
+	for rows.Next() {
+		var f DayFlag
+		if err := rows.Scan(&f.Date, &f.Type, &f.Note, &f.CreatedAt, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan day flag: %w", err)
+		}
+		f.Excluded = s.Excludes(f.Type)
+		flags = append(flags, &f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating day flags: %w", err)
+	}
+	return flags, nil
+}
@@ -0,0 +1,234 @@
+package nutrition
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/tombstones"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var dayFlagColumns = []string{"date", "type", "note", "created_at", "updated_at"}
+
+func setupFlagService(t *testing.T, excluded ...string) (*FlagService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	service := NewFlagService(&database.DB{DB: mockDB}, logger.New(), excluded, nil)
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+// serveDay routes path to handle as /days or /days/:date/flag and decodes
+// the response
+func serveDay(t *testing.T, handle gin.HandlerFunc, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	withUser := func(c *gin.Context) {
+		c.Set("user_id", testUserID)
+		handle(c)
+	}
+	router.Handle(method, "/days", withUser)
+	router.Handle(method, "/days/:date/flag", withUser)
+
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestValidateFlag(t *testing.T) {
+	tests := []struct {
+		name   string
+		date   string
+		req    FlagDayRequest
+		fields []string
+	}{
+		{"refeed today", "2026-02-01", FlagDayRequest{Type: "refeed"}, nil},
+		{"planned refeed", "2026-05-02", FlagDayRequest{Type: "refeed", Note: "Рефид перед стартом"}, nil},
+		{"upper-case type", "2026-01-26", FlagDayRequest{Type: " Sick "}, nil},
+		{"too far ahead", "2026-05-03", FlagDayRequest{Type: "travel"}, []string{"date"}},
+		{"too old", "2024-01-31", FlagDayRequest{Type: "travel"}, []string{"date"}},
+		{"bad date", "26.01.2026", FlagDayRequest{Type: "travel"}, []string{"date"}},
+		{"unknown type", "2026-01-26", FlagDayRequest{Type: "cheat"}, []string{"type"}},
+		{"long note", "2026-01-26", FlagDayRequest{Type: "sick", Note: string(bytes.Repeat([]byte("я"), MaxDayFlagNoteLength+1))}, []string{"note"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFlag(testDate(tt.date), &tt.req, testNow)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+}
+
+func TestFlagService_SetFlag(t *testing.T) {
+	service, mock := setupFlagService(t, DayFlagRefeed)
+	mock.ExpectQuery("INSERT INTO nutrition_day_flags.+ON CONFLICT \\(user_id, date\\) DO UPDATE").
+		WithArgs(testUserID, "2026-02-07", DayFlagRefeed, "Рефид").
+		WillReturnRows(sqlmock.NewRows(dayFlagColumns).AddRow("2026-02-07", DayFlagRefeed, "Рефид", testNow, testNow))
+
+	flag, err := service.SetFlag(context.Background(), testUserID, testDate("2026-02-07"), &FlagDayRequest{Type: "Refeed", Note: " Рефид "})
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02-07", flag.Date)
+	assert.True(t, flag.Excluded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFlagService_DeleteFlag(t *testing.T) {
+	t.Run("flagged day", func(t *testing.T) {
+		service, mock := setupFlagService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM nutrition_day_flags").
+			WithArgs(testUserID, "2026-01-26").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO sync_tombstones").
+			WithArgs(testUserID, tombstones.DayFlags, "2026-01-26").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteFlag(context.Background(), testUserID, testDate("2026-01-26")))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("day without a flag", func(t *testing.T) {
+		service, mock := setupFlagService(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM nutrition_day_flags").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := service.DeleteFlag(context.Background(), testUserID, testDate("2026-01-26"))
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestFlagService_ExcludedDates(t *testing.T) {
+	service, mock := setupFlagService(t, DayFlagRefeed, DayFlagSick)
+	mock.ExpectQuery("FROM nutrition_day_flags").
+		WithArgs(testUserID, "2026-01-19", "2026-02-01").
+		WillReturnRows(sqlmock.NewRows(dayFlagColumns).
+			AddRow("2026-01-20", DayFlagRefeed, "", testNow, testNow).
+			AddRow("2026-01-24", DayFlagTravel, "", testNow, testNow).
+			AddRow("2026-01-28", DayFlagSick, "", testNow, testNow))
+
+	excluded, err := service.ExcludedDates(context.Background(), testUserID, "2026-01-19", "2026-02-01")
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"2026-01-20": true, "2026-01-28": true}, excluded)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFlagDayHandler(t *testing.T) {
+	t.Run("flagged", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("INSERT INTO nutrition_day_flags").
+			WithArgs(testUserID, "2026-01-31", DayFlagRefeed, "").
+			WillReturnRows(sqlmock.NewRows(dayFlagColumns).AddRow("2026-01-31", DayFlagRefeed, "", testNow, testNow))
+
+		status, resp := serveDay(t, handler.FlagDay, http.MethodPost, "/days/2026-01-31/flag", `{"type":"refeed"}`)
+
+		assert.Equal(t, http.StatusOK, status)
+		flag := resp["data"].(map[string]interface{})["flag"].(map[string]interface{})
+		assert.Equal(t, DayFlagRefeed, flag["type"])
+		assert.Equal(t, true, flag["excluded"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serveDay(t, handler.FlagDay, http.MethodPost, "/days/2026-01-31/flag", `{"type":"cheat"}`)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, resp["details"].(map[string]interface{})["fields"], "type")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed date", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serveDay(t, handler.FlagDay, http.MethodPost, "/days/2026-02-30/flag", `{"type":"refeed"}`)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, map[string]interface{}{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"},
+			resp["details"].(map[string]interface{})["fields"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("removed", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM nutrition_day_flags").
+			WithArgs(testUserID, "2026-01-31").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		status, _ := serveDay(t, handler.UnflagDay, http.MethodDelete, "/days/2026-01-31/flag", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListDaysHandler(t *testing.T) {
+	t.Run("flags of the range", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("FROM nutrition_day_flags").
+			WithArgs(testUserID, "2026-01-01", "2026-01-31").
+			WillReturnRows(sqlmock.NewRows(dayFlagColumns).
+				AddRow("2026-01-10", DayFlagTravel, "Командировка", testNow, testNow))
+
+		status, resp := serveDay(t, handler.ListDays, http.MethodGet, "/days?from=2026-01-01&to=2026-01-31", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		flags := resp["data"].(map[string]interface{})["items"].([]interface{})
+		require.Len(t, flags, 1)
+		assert.Equal(t, "Командировка", flags[0].(map[string]interface{})["note"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing range", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serveDay(t, handler.ListDays, http.MethodGet, "/days", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, resp["details"].(map[string]interface{})["fields"], "from")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,42 +1,89 @@
 package nutrition
 
 import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/openapi"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/idempotency"
+	"github.com/burcev/api/internal/shared/listing"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
 // Handler handles nutrition requests
 type Handler struct {
-	cfg     *config.Config
-	log     *logger.Logger
-	service *Service
+	cfg      *config.Config
+	log      *logger.Logger
+	db       *database.DB
+	service  ServiceInterface
+	water    *WaterService
+	schedule *ScheduleService
+	reports  *ReportService
+	flags    *FlagService
+	search   *SearchService
+	photos   *PhotoService
 }
 
-// NewHandler creates a new nutrition handler
-func NewHandler(cfg *config.Config, log *logger.Logger) *Handler {
+// NewHandler creates a new nutrition handler. Entries go through service;
+// water, the meal schedule, day flags, reports, history search and the
+// request timezone are read from db. cfg lists the day flags reports leave
+// out and the daily sodium they warn about. Report days are cached in
+// dayCache, which may be nil; it must be the cache service writes to. Entry
+// photos are routed by photoFiles, which may be nil to disable them.
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, service ServiceInterface, dayCache cache.Cache, photoFiles *storage.Router) *Handler {
+	flags := NewFlagService(db, log, cfg.NutritionExcludedDayFlags, dayCache)
 	return &Handler{
-		cfg:     cfg,
-		log:     log,
-		service: NewService(cfg, log),
+		cfg:      cfg,
+		log:      log,
+		db:       db,
+		service:  service,
+		water:    NewWaterService(db, log),
+		schedule: NewScheduleService(db, log),
+		reports:  NewReportService(db, log, flags, dayCache, float64(cfg.NutritionSodiumWarningMg)),
+		flags:    flags,
+		search:   NewSearchService(db, log),
+		photos:   NewPhotoService(db, log, photoFiles),
 	}
 }
 
-// CreateEntryRequest represents nutrition entry creation request
+// CreateEntryRequest represents nutrition entry creation request. An entry
+// logged from a recipe sets recipe_id and portion_grams instead of the
+// macros; food defaults to the recipe name. The micronutrients are optional
+// and stay null when left out.
 type CreateEntryRequest struct {
-	Date     string  `json:"date" binding:"required"`
-	Meal     string  `json:"meal" binding:"required"`
-	Food     string  `json:"food" binding:"required"`
-	Calories float64 `json:"calories" binding:"required"`
-	Protein  float64 `json:"protein"`
-	Carbs    float64 `json:"carbs"`
-	Fat      float64 `json:"fat"`
+	Date         types.Date `json:"date" binding:"required"`
+	Meal         string     `json:"meal" binding:"required"`
+	Food         string     `json:"food"`
+	Calories     *float64   `json:"calories"`
+	Protein      float64    `json:"protein"`
+	Carbs        float64    `json:"carbs"`
+	Fat          float64    `json:"fat"`
+	RecipeID     *string    `json:"recipe_id"`
+	PortionGrams *float64   `json:"portion_grams"`
+
+	Micronutrients
 }
 
-// GetEntries returns nutrition entries
+// GetEntries returns nutrition entries: all of them, newest first, unless
+// sort (e.g. calories:desc) or limit and offset are given. With
+// Accept: application/x-ndjson or stream=true they are streamed instead.
 func (h *Handler) GetEntries(c *gin.Context) {
 	userIDInterface, _ := c.Get("user_id")
 	userID, ok := userIDInterface.(int64)
@@ -45,14 +92,178 @@ func (h *Handler) GetEntries(c *gin.Context) {
 		return
 	}
 
-	entries, err := h.service.GetEntries(c.Request.Context(), userID)
+	sort, err := listing.ParseSort(c.Query("sort"), EntrySortFields, DefaultEntrySort)
 	if err != nil {
-		h.log.Errorw("Не удалось получить записи", "error", err, "user_id", userID)
-		response.Error(c, http.StatusInternalServerError, "Не удалось получить записи")
+		validation.Respond(c, err)
+		return
+	}
+	page := listing.ParsePage(c.Query("limit"), c.Query("offset"), 0, MaxEntriesPageSize)
+
+	if wantsStream(c) {
+		h.streamEntries(c, userID, sort, page)
+		return
+	}
+
+	entries, err := h.service.GetEntries(c.Request.Context(), userID, sort, page)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.CachedList(c, http.StatusOK, EntriesResponse{response.NewList(entries, page.Limit, page.Offset)}, "entries")
+}
+
+// streamFlushEvery is how many streamed entries are written between flushes
+const streamFlushEvery = 100
+
+// wantsStream reports whether the client asked for entries as a stream
+func wantsStream(c *gin.Context) bool {
+	return c.Query("stream") == "true" || strings.Contains(c.GetHeader("Accept"), openapi.StreamContentType)
+}
+
+// streamEntries writes the entries as newline-delimited JSON while they are
+// read, without the response envelope, so a multi-year history is never
+// held in memory. A client that disconnects cancels the query. A failure
+// before the first entry is answered with the usual error envelope; a later
+// one can only end the stream early.
+func (h *Handler) streamEntries(c *gin.Context, userID int64, sort listing.Sort, page listing.Page) {
+	encoder := json.NewEncoder(c.Writer)
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", openapi.StreamContentType)
+		c.Status(http.StatusOK)
+	}
+
+	written := 0
+	for entry, err := range h.service.StreamEntries(c.Request.Context(), userID, sort, page) {
+		if err != nil {
+			if !started {
+				_ = c.Error(err)
+				return
+			}
+			if !errors.Is(err, context.Canceled) {
+				h.log.Error("Entries stream ended early", "user_id", userID, "written", written, "error", err)
+			}
+			return
+		}
+		if !started {
+			start()
+		}
+		if err := encoder.Encode(entry); err != nil {
+			// The client is gone; leaving the loop releases the rows
+			return
+		}
+		written++
+		if written%streamFlushEvery == 0 {
+			c.Writer.Flush()
+		}
+	}
+	if !started {
+		start()
+		c.Writer.WriteHeaderNow()
+	}
+	c.Writer.Flush()
+}
+
+// ExportEntries writes all of the user's entries as CSV, oldest first, in
+// the csvColumns layout that ImportEntries reads back. Like the entries
+// stream the rows are written while they are read.
+func (h *Handler) ExportEntries(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"entries": entries})
+	writer := csv.NewWriter(c.Writer)
+	started := false
+	start := func() {
+		started = true
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", `attachment; filename="nutrition.csv"`)
+		c.Status(http.StatusOK)
+		_ = writer.Write(csvColumns)
+	}
+
+	written := 0
+	for entry, err := range h.service.StreamEntries(c.Request.Context(), userID, listing.Sort{Column: "date"}, listing.Page{}) {
+		if err != nil {
+			if !started {
+				_ = c.Error(err)
+				return
+			}
+			if !errors.Is(err, context.Canceled) {
+				h.log.Error("Entries export ended early", "user_id", userID, "written", written, "error", err)
+			}
+			writer.Flush()
+			return
+		}
+		if !started {
+			start()
+		}
+		if err := writer.Write(entryRecord(entry)); err != nil {
+			return
+		}
+		written++
+		if written%streamFlushEvery == 0 {
+			writer.Flush()
+		}
+	}
+	if !started {
+		start()
+	}
+	writer.Flush()
+}
+
+// ImportEntries creates entries from a CSV file in the multipart field file.
+// The header row names the columns: date, meal, food and calories are
+// required, the macros and micronutrients optional, so files exported before
+// the micronutrients were added still import. Rejected rows are listed with
+// their line and the rest are imported.
+func (h *Handler) ImportEntries(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Требуется CSV-файл в поле file")
+		return
+	}
+	if fileHeader.Size > MaxImportFileSize {
+		response.Error(c, http.StatusBadRequest, "Файл слишком большой (максимум 2 МБ)")
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Не удалось прочитать файл")
+		return
+	}
+	defer file.Close()
+
+	header, records, err := csvimport.Read(file, true, MaxImportRows)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := importEntries(c.Request.Context(), h.service, userID, header, records)
+	if err != nil {
+		if errors.Is(err, csvimport.ErrInvalidFile) {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
 }
 
 // CreateEntry creates a new nutrition entry
@@ -65,24 +276,66 @@ func (h *Handler) CreateEntry(c *gin.Context) {
 	}
 
 	var req CreateEntryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
-	entry, err := h.service.CreateEntry(c.Request.Context(), userID, &req)
+	// Retries with the same Idempotency-Key get the entry created first
+	var entry *Entry
+	var replayed bool
+	var err error
+	if key := c.GetHeader(idempotency.HeaderKey); key != "" {
+		if idempotency.ValidateKey(key) != nil {
+			response.ErrorCode(c, http.StatusBadRequest, response.CodeValidationFailed, "Некорректный Idempotency-Key", nil)
+			return
+		}
+		entry, replayed, err = h.service.CreateEntryOnce(c.Request.Context(), userID, key, &req)
+	} else {
+		entry, err = h.service.CreateEntry(c.Request.Context(), userID, &req)
+	}
+	if errors.Is(err, idempotency.ErrInProgress) {
+		response.ErrorCode(c, http.StatusConflict, response.CodeIdempotencyInProgress, "Запрос с этим Idempotency-Key ещё выполняется", nil)
+		return
+	}
 	if err != nil {
-		h.log.Errorw("Не удалось создать запись", "error", err, "user_id", userID)
-		response.Error(c, http.StatusInternalServerError, "Не удалось создать запись")
+		// Not found here means the entry created for this key has since been deleted
+		_ = c.Error(err)
 		return
 	}
 
-	response.Success(c, http.StatusCreated, gin.H{"entry": entry})
+	if replayed {
+		c.Header(idempotency.HeaderReplay, "true")
+		response.Success(c, http.StatusOK, entryResponse(entry))
+		return
+	}
+	response.SuccessWithWarnings(c, http.StatusCreated, entryResponse(entry), h.duplicateWarnings(c, entry))
+}
+
+// duplicateWarnings warns when a new entry repeats one logged moments
+// before, so the client can offer to undo it. The entry is created either
+// way, and a failed lookup only drops the warning.
+func (h *Handler) duplicateWarnings(c *gin.Context, entry *Entry) gin.H {
+	if !h.cfg.DuplicateEntryCheck {
+		return nil
+	}
+	existingID, err := h.service.FindDuplicate(c.Request.Context(), entry, h.cfg.DuplicateEntryWindow)
+	if err != nil {
+		h.log.Warn("Failed to check for a duplicate entry", "error", err, "user_id", entry.UserID)
+		return nil
+	}
+	if existingID == "" {
+		return nil
+	}
+	return gin.H{"possible_duplicate": gin.H{"existing_entry_id": existingID}}
 }
 
 // GetEntry returns a single nutrition entry
 func (h *Handler) GetEntry(c *gin.Context) {
-	entryID := c.Param("id")
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
 	userIDInterface, _ := c.Get("user_id")
 	userID, ok := userIDInterface.(int64)
 	if !ok {
@@ -92,17 +345,22 @@ func (h *Handler) GetEntry(c *gin.Context) {
 
 	entry, err := h.service.GetEntry(c.Request.Context(), userID, entryID)
 	if err != nil {
-		h.log.Errorw("Failed to get entry", "error", err, "entry_id", entryID)
-		response.Error(c, http.StatusNotFound, "Запись не найдена")
+		h.entryError(c, err, userID, entryID)
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"entry": entry})
+	response.Success(c, http.StatusOK, EntryResponse{Entry: entry})
 }
 
-// UpdateEntry updates a nutrition entry
+// UpdateEntry updates a nutrition entry. The client sends the version it
+// edited as If-Match; when another device has changed the entry since, the
+// response is 409 VERSION_CONFLICT with the current entry. Updates without
+// If-Match overwrite blindly and are deprecated.
 func (h *Handler) UpdateEntry(c *gin.Context) {
-	entryID := c.Param("id")
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
 	userIDInterface, _ := c.Get("user_id")
 	userID, ok := userIDInterface.(int64)
 	if !ok {
@@ -110,25 +368,45 @@ func (h *Handler) UpdateEntry(c *gin.Context) {
 		return
 	}
 
+	version, ok := validation.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+	if version == nil {
+		h.log.Warnw("Deprecated: nutrition entry update without If-Match version",
+			"user_id", userID, "entry_id", entryID, "user_agent", c.Request.UserAgent())
+	}
+
 	var req CreateEntryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
-	entry, err := h.service.UpdateEntry(c.Request.Context(), userID, entryID, &req)
+	entry, err := h.service.UpdateEntry(c.Request.Context(), userID, entryID, &req, version)
 	if err != nil {
-		h.log.Errorw("Не удалось обновить запись", "error", err, "entry_id", entryID)
-		response.Error(c, http.StatusInternalServerError, "Не удалось обновить запись")
+		h.entryError(c, err, userID, entryID)
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"entry": entry})
+	response.Success(c, http.StatusOK, entryResponse(entry))
+}
+
+// entryResponse wraps a saved entry, adding a warning when its macros do not
+// add up to its calories. The entry is saved either way.
+func entryResponse(entry *Entry) EntryResponse {
+	return EntryResponse{
+		Entry:   entry,
+		Warning: macroWarning(entry.Calories, entry.Protein, entry.Carbs, entry.Fat),
+	}
 }
 
 // DeleteEntry deletes a nutrition entry
 func (h *Handler) DeleteEntry(c *gin.Context) {
-	entryID := c.Param("id")
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
 	userIDInterface, _ := c.Get("user_id")
 	userID, ok := userIDInterface.(int64)
 	if !ok {
@@ -137,10 +415,431 @@ func (h *Handler) DeleteEntry(c *gin.Context) {
 	}
 
 	if err := h.service.DeleteEntry(c.Request.Context(), userID, entryID); err != nil {
-		h.log.Errorw("Не удалось удалить запись", "error", err, "entry_id", entryID)
-		response.Error(c, http.StatusInternalServerError, "Не удалось удалить запись")
+		h.entryError(c, err, userID, entryID)
 		return
 	}
 
 	response.SuccessWithMessage(c, http.StatusOK, "Entry deleted successfully", nil)
 }
+
+// GetEntryHistory returns the change history of an entry, newest first
+func (h *Handler) GetEntryHistory(c *gin.Context) {
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	revisions, err := h.service.GetEntryHistory(c.Request.Context(), userID, entryID)
+	if err != nil {
+		h.entryError(c, err, userID, entryID)
+		return
+	}
+
+	response.List(c, http.StatusOK, RevisionsResponse{response.NewList(revisions, 0, 0)}, "revisions")
+}
+
+// multipartOverhead is the allowance for form fields and boundaries on top
+// of the file size
+const multipartOverhead = 1 << 20
+
+// UploadEntryPhoto attaches a meal photo (form file photo, JPEG or PNG up
+// to 5 MB) to an entry, replacing the previous one
+func (h *Handler) UploadEntryPhoto(c *gin.Context) {
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxEntryPhotoSize+multipartOverhead)
+	f, header, err := c.Request.FormFile("photo")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 5 МБ")
+			return
+		}
+		response.Error(c, http.StatusBadRequest, "Файл фото обязателен")
+		return
+	}
+	defer f.Close()
+	if header.Size > MaxEntryPhotoSize {
+		response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 5 МБ")
+		return
+	}
+
+	photo, err := h.photos.Upload(c.Request.Context(), userID, entryID, f)
+	if err != nil {
+		h.photoError(c, err, "Запись не найдена")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, EntryPhotoResponse{Photo: photo})
+}
+
+// GetEntryPhoto streams the meal photo of an entry, or its thumbnail with
+// ?size=thumb
+func (h *Handler) GetEntryPhoto(c *gin.Context) {
+	entryID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	size := c.DefaultQuery("size", PhotoSizeFull)
+	if size != PhotoSizeFull && size != PhotoSizeThumb {
+		response.Error(c, http.StatusBadRequest, "Параметр size должен быть full или thumb")
+		return
+	}
+
+	photo, r, err := h.photos.Open(c.Request.Context(), userID, entryID, size)
+	if err != nil {
+		h.photoError(c, err, "Фото не найдено")
+		return
+	}
+	defer r.Close()
+
+	contentType, length := photo.ContentType, photo.SizeBytes
+	if size == PhotoSizeThumb {
+		contentType, length = thumbnailContentType, photo.ThumbnailSizeBytes
+	}
+	// A new upload keeps the URL, so clients revalidate with the ETag
+	etag := fmt.Sprintf(`"%s-%d-%s"`, photo.EntryID, photo.UpdatedAt.UnixNano(), size)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.DataFromReader(http.StatusOK, int64(length), contentType, r, map[string]string{
+		"Cache-Control": "private, no-cache",
+		"ETag":          etag,
+	})
+}
+
+// photoError responds to an entry photo error; notFound describes what is
+// missing
+func (h *Handler) photoError(c *gin.Context, err error, notFound string) {
+	switch {
+	case errors.Is(err, ErrEntryPhotoTooLarge):
+		response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 5 МБ")
+	case errors.Is(err, ErrInvalidEntryPhoto):
+		response.Error(c, http.StatusBadRequest, "Фото должно быть в формате JPEG или PNG")
+	case errors.Is(err, ErrEntryPhotosUnavailable):
+		response.Error(c, http.StatusServiceUnavailable, "Фото блюд временно недоступны")
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, notFound)
+	default:
+		_ = c.Error(err)
+	}
+}
+
+// AddWater logs a water intake
+func (h *Handler) AddWater(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var req AddWaterRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	event, day, err := h.water.AddWater(c.Request.Context(), userID, &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, AddWaterResponse{Event: event, Day: day})
+}
+
+// GetWater returns the water intake of a day, today in the user's timezone
+// by default
+func (h *Handler) GetWater(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var q dayQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+	date := q.Date
+	if date.IsZero() {
+		loc, ok := middleware.RequestTimezone(c, h.db, userID)
+		if !ok {
+			return
+		}
+		date = types.DateOf(time.Now().In(loc))
+	}
+
+	day, err := h.water.GetWaterDay(c.Request.Context(), userID, date)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, day)
+}
+
+// DeleteWater removes a water intake
+func (h *Handler) DeleteWater(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	eventID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.water.DeleteWater(c.Request.Context(), userID, eventID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Water intake deleted successfully", nil)
+}
+
+// GetMealSchedule returns the user's preferred meal times
+func (h *Handler) GetMealSchedule(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	schedule, err := h.schedule.GetSchedule(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, schedule)
+}
+
+// UpdateMealSchedule replaces the user's preferred meal times. Meals left
+// out or set to null get no reminder.
+func (h *Handler) UpdateMealSchedule(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var req MealSchedule
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	schedule, err := h.schedule.UpdateSchedule(c.Request.Context(), userID, &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, schedule)
+}
+
+// GetMissingMeals returns the scheduled meals of a day, today in the user's
+// timezone by default, that are overdue and not logged yet
+func (h *Handler) GetMissingMeals(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var q dayQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+	loc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
+	date := q.Date
+	if date.IsZero() {
+		date = types.DateOf(h.schedule.now().In(loc))
+	}
+
+	missing, err := h.schedule.MissingMeals(c.Request.Context(), userID, date, loc)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, missing)
+}
+
+// GetReport returns the adherence report of the from..to range
+func (h *Handler) GetReport(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var q reportQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	report, err := h.reports.GetReport(c.Request.Context(), userID, types.DateRange{From: q.From, To: q.To})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+// SearchEntries searches the food names of the user's entries for q,
+// optionally within from..to
+func (h *Handler) SearchEntries(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var q searchQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	result, err := h.search.Search(c.Request.Context(), userID, q.Q, types.DateRange{From: q.From, To: q.To})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+// FlagDay marks a day as a refeed, sick or travel day, replacing its flag
+func (h *Handler) FlagDay(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var date types.Date
+	if !validation.Param(c, "date", &date) {
+		return
+	}
+
+	var req FlagDayRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	flag, err := h.flags.SetFlag(c.Request.Context(), userID, date, &req)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, DayFlagResponse{Flag: flag})
+}
+
+// UnflagDay removes the flag of a day
+func (h *Handler) UnflagDay(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var date types.Date
+	if !validation.Param(c, "date", &date) {
+		return
+	}
+
+	if err := h.flags.DeleteFlag(c.Request.Context(), userID, date); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Day flag deleted successfully", nil)
+}
+
+// ListDays returns the flagged days of the from..to range for the calendar
+func (h *Handler) ListDays(c *gin.Context) {
+	userIDInterface, _ := c.Get("user_id")
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	var q reportQuery
+	if err := validation.BindQuery(c, &q); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	flags, err := h.flags.ListFlags(c.Request.Context(), userID, types.DateRange{From: q.From, To: q.To})
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.List(c, http.StatusOK, DayFlagsResponse{response.NewList(flags, 0, 0)}, "flags")
+}
+
+// entryError hands a service error for a single entry to the ErrorHandler
+// middleware. Another user's entry is reported exactly like a missing one,
+// so ids cannot be probed, but the attempt is logged as a security event.
+func (h *Handler) entryError(c *gin.Context, err error, userID int64, entryID string) {
+	if errors.Is(err, errForeignEntry) {
+		h.log.LogSecurityEvent("nutrition_entry_foreign_access", "medium", map[string]interface{}{
+			"user_id":     userID,
+			"entry_id":    entryID,
+			"method":      c.Request.Method,
+			"ip":          c.ClientIP(),
+			"remote_addr": c.RemoteIP(),
+		})
+	}
+	_ = c.Error(err)
+}
@@ -1,290 +1,880 @@
 package nutrition
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/listing"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupTestHandler() *Handler {
+func setupTestHandler(t *testing.T) (*Handler, sqlmock.Sqlmock) {
+	t.Helper()
 	gin.SetMode(gin.TestMode)
+
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
 	cfg := &config.Config{
-		Env:       "test",
-		JWTSecret: "test-secret",
+		Env:                       "test",
+		JWTSecret:                 "test-secret",
+		NutritionExcludedDayFlags: DayFlagTypes,
+	}
+	db := &database.DB{DB: mockDB}
+	service := NewService(db, logger.New(), nil, nil, nil, nil)
+	service.now = func() time.Time { return testNow }
+	handler := NewHandler(cfg, logger.New(), db, service, nil, nil)
+	handler.water.now = func() time.Time { return testNow }
+	handler.schedule.now = func() time.Time { return testNow }
+	handler.flags.now = func() time.Time { return testNow }
+	return handler, mock
+}
+
+// serve runs one request through handle as the given user and decodes the body
+func serve(t *testing.T, handle gin.HandlerFunc, userID int64, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	w := serveWithHeaders(t, handle, userID, method, path, body, nil)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func serveWithHeaders(t *testing.T, handle gin.HandlerFunc, userID int64, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	router.Handle(method, "/entries/*id", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		// "/entries/" for the collection, "/entries/<id>" for one entry
+		c.Params = gin.Params{{Key: "id", Value: c.Param("id")[1:]}}
+		handle(c)
+	})
+
+	var reader *bytes.Buffer
+	if body != "" {
+		reader = bytes.NewBufferString(body)
+	} else {
+		reader = &bytes.Buffer{}
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
 	}
-	log := logger.New()
-	return NewHandler(cfg, log)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
 }
 
 func TestNewHandler(t *testing.T) {
-	handler := setupTestHandler()
-	assert.NotNil(t, handler)
+	handler, _ := setupTestHandler(t)
 	assert.NotNil(t, handler.cfg)
 	assert.NotNil(t, handler.log)
 	assert.NotNil(t, handler.service)
 }
 
 func TestGetEntries(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+	handler, mock := setupTestHandler(t)
+	mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+		WithArgs(testUserID).
+		WillReturnRows(entryRows("Овсянка", 150))
+
+	status, resp := serve(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/", "")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "success", resp["status"])
+	entries := resp["data"].(map[string]interface{})["items"].([]interface{})
+	assert.Len(t, entries, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	router.GET("/entries", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.GetEntries(c)
+func TestGetEntries_Sorted(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+	mock.ExpectQuery("ORDER BY protein ASC, created_at ASC, id ASC LIMIT \\$2 OFFSET \\$3$").
+		WithArgs(testUserID, 10, 10).
+		WillReturnRows(entryRows("Творог", 180))
+
+	status, resp := serve(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/?sort=protein:asc&limit=10&offset=10", "")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]interface{}{"limit": float64(10), "offset": float64(10), "count": float64(1)},
+		resp["data"].(map[string]interface{})["pagination"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetEntries_InvalidSort(t *testing.T) {
+	for _, sort := range []string{
+		"fat",
+		"calories:sideways",
+		"calories%3B%20DROP%20TABLE%20nutrition_entries",
+		"(SELECT%201)",
+		"date%20DESC--",
+	} {
+		t.Run(sort, func(t *testing.T) {
+			handler, mock := setupTestHandler(t)
+
+			status, resp := serve(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/?sort="+sort, "")
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			fields := resp["details"].(map[string]interface{})["fields"].(map[string]interface{})
+			assert.Contains(t, fields["sort"], "calories, created_at, date, protein")
+			assert.NoError(t, mock.ExpectationsWereMet(), "no query is run")
+		})
+	}
+}
+
+func TestGetEntries_NotModified(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+	selectRe := "SELECT (.+) FROM nutrition_entries"
+	mock.ExpectQuery(selectRe).WithArgs(testUserID).WillReturnRows(entryRows("Овсянка", 150))
+	mock.ExpectQuery(selectRe).WithArgs(testUserID).WillReturnRows(entryRows("Овсянка", 150))
+	mock.ExpectQuery(selectRe).WithArgs(testUserID).WillReturnRows(entryRows("Овсянка", 200))
+
+	first := serveWithHeaders(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/", "", nil)
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	replay := serveWithHeaders(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/", "",
+		map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, replay.Code)
+	assert.Empty(t, replay.Body.String())
+
+	changed := serveWithHeaders(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/", "",
+		map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusOK, changed.Code, "an edited entry invalidates the tag")
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// manyEntryRows returns n entries of one day, numbered by their food
+func manyEntryRows(n int) *sqlmock.Rows {
+	rows := sqlmock.NewRows(entryColumnNames)
+	for i := range n {
+		rows.AddRow(fmt.Sprintf("00000000-0000-4000-8000-%012d", i), testUserID, "2026-01-26", MealLunch, fmt.Sprintf("Блюдо %d", i),
+			100.0, 5.0, 10.0, 3.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil)
+	}
+	return rows
+}
+
+func TestGetEntries_Stream(t *testing.T) {
+	for name, ask := range map[string]struct {
+		path    string
+		headers map[string]string
+	}{
+		"accept header": {"/entries/", map[string]string{"Accept": "application/x-ndjson"}},
+		"query flag":    {"/entries/?stream=true&sort=calories:desc", nil},
+		"with JSON too": {"/entries/", map[string]string{"Accept": "application/x-ndjson, application/json;q=0.5"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler, mock := setupTestHandler(t)
+			mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+				WithArgs(testUserID).
+				WillReturnRows(manyEntryRows(250)).
+				RowsWillBeClosed()
+
+			w := serveWithHeaders(t, handler.GetEntries, testUserID, http.MethodGet, ask.path, "", ask.headers)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+			lines := strings.Split(strings.TrimSuffix(w.Body.String(), "\n"), "\n")
+			require.Len(t, lines, 250)
+			var last Entry
+			require.NoError(t, json.Unmarshal([]byte(lines[249]), &last), "each line is an entry without the envelope")
+			assert.Equal(t, "Блюдо 249", last.Food)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("no entries", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").WillReturnRows(sqlmock.NewRows(entryColumnNames))
+
+		w := serveWithHeaders(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/?stream=true", "", nil)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+		assert.Empty(t, w.Body.String())
 	})
 
-	req := httptest.NewRequest(http.MethodGet, "/entries", nil)
-	w := httptest.NewRecorder()
+	t.Run("failure before the first entry", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").WillReturnError(errors.New("connection reset"))
 
-	router.ServeHTTP(w, req)
+		status, resp := serve(t, handler.GetEntries, testUserID, http.MethodGet, "/entries/?stream=true", "")
 
-	assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, "error", resp["status"])
+	})
+}
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+// pipeResponseWriter passes the response body to a reader as it is
+// written; each write blocks until the reader takes it, like a client
+// reading slowly
+type pipeResponseWriter struct {
+	*io.PipeWriter
+	header http.Header
+}
 
-	assert.Equal(t, "success", response["status"])
-	data := response["data"].(map[string]interface{})
-	entries := data["entries"].([]interface{})
-	assert.NotEmpty(t, entries)
+func (w *pipeResponseWriter) Header() http.Header { return w.header }
+func (w *pipeResponseWriter) WriteHeader(int)     {}
+func (w *pipeResponseWriter) Flush()              {}
+
+// streamClient reads a streamed entries response as it is written
+type streamClient struct {
+	body   *bufio.Reader
+	pipe   *io.PipeReader
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
-func TestCreateEntry_Success(t *testing.T) {
-	handler := setupTestHandler()
+// streamThroughPipe serves a streamed entries request in the background;
+// done is closed once the handler returned
+func streamThroughPipe(t *testing.T, handler *Handler) *streamClient {
+	t.Helper()
 	router := gin.New()
-
-	router.POST("/entries", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.CreateEntry(c)
+	router.Use(middleware.ErrorHandler(logger.New()))
+	router.GET("/entries", func(c *gin.Context) {
+		c.Set("user_id", testUserID)
+		handler.GetEntries(c)
 	})
 
-	reqBody := CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "breakfast",
-		Food:     "Oatmeal",
-		Calories: 150,
-		Protein:  5,
-		Carbs:    27,
-		Fat:      3,
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	req := httptest.NewRequest(http.MethodGet, "/entries?stream=true", nil).WithContext(ctx)
+	pr, pw := io.Pipe()
+	t.Cleanup(func() { pr.Close() })
+
+	client := &streamClient{body: bufio.NewReader(pr), pipe: pr, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(client.done)
+		router.ServeHTTP(&pipeResponseWriter{PipeWriter: pw, header: http.Header{}}, req)
+		pw.Close()
+	}()
+	return client
+}
+
+// readEntry reads the next streamed entry
+func (sc *streamClient) readEntry(t *testing.T) *Entry {
+	t.Helper()
+	line, err := sc.body.ReadString('\n')
+	require.NoError(t, err)
+	var entry Entry
+	require.NoError(t, json.Unmarshal([]byte(line), &entry))
+	return &entry
+}
+
+// wait fails the test unless the handler returns soon
+func (sc *streamClient) wait(t *testing.T) {
+	t.Helper()
+	select {
+	case <-sc.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the handler kept streaming")
 	}
-	body, _ := json.Marshal(reqBody)
+}
 
-	req := httptest.NewRequest(http.MethodPost, "/entries", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+func TestGetEntries_StreamStopsEarly(t *testing.T) {
+	const total = 1000
 
-	router.ServeHTTP(w, req)
+	t.Run("client hangs up", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+			WillReturnRows(manyEntryRows(total)).
+			RowsWillBeClosed()
+		client := streamThroughPipe(t, handler)
 
-	assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "Блюдо 0", client.readEntry(t).Food)
+		assert.Equal(t, "Блюдо 1", client.readEntry(t).Food)
+		client.pipe.Close()
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+		client.wait(t)
+		assert.NoError(t, mock.ExpectationsWereMet(), "the rows are released")
+	})
+
+	t.Run("request context cancelled", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+			WillReturnRows(manyEntryRows(total)).
+			RowsWillBeClosed()
+		client := streamThroughPipe(t, handler)
+
+		client.readEntry(t)
+		client.cancel()
+
+		read := 1
+		for {
+			if _, err := client.body.ReadString('\n'); err != nil {
+				require.ErrorIs(t, err, io.EOF)
+				break
+			}
+			read++
+		}
+
+		client.wait(t)
+		assert.Less(t, read, total, "the query stops with the request")
+		assert.NoError(t, mock.ExpectationsWereMet(), "the rows are released")
+	})
+}
 
-	assert.Equal(t, "success", response["status"])
-	data := response["data"].(map[string]interface{})
-	entry := data["entry"].(map[string]interface{})
+func TestCreateEntry_Success(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO nutrition_entries").
+		WithArgs(newIDArg(), testUserID, "2026-01-26", MealBreakfast, "Oatmeal", 150.0, 5.0, 27.0, 3.0, nil, nil, "oatmeal", nil, nil, nil, nil).
+		WillReturnRows(entryRows("Oatmeal", 150))
+	expectRollup(mock)
+	mock.ExpectCommit()
+
+	status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+		`{"date":"2026-01-26","meal":"breakfast","food":"Oatmeal","calories":150,"protein":5,"carbs":27,"fat":3}`)
+
+	assert.Equal(t, http.StatusCreated, status)
+	entry := resp["data"].(map[string]interface{})["entry"].(map[string]interface{})
+	assert.Equal(t, testEntryID, entry["id"])
 	assert.Equal(t, "Oatmeal", entry["food"])
 	assert.Equal(t, 150.0, entry["calories"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestCreateEntry_MissingRequiredFields(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+func TestCreateEntry_PossibleDuplicate(t *testing.T) {
+	body := `{"date":"2026-01-26","meal":"breakfast","food":"Oatmeal","calories":150,"protein":5,"carbs":27,"fat":3}`
+	duplicateRows := func(createdAt time.Time) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "created_at"}).AddRow(otherEntryID, createdAt)
+	}
 
-	router.POST("/entries", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.CreateEntry(c)
+	t.Run("identical entry within the window", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		handler.cfg.DuplicateEntryCheck = true
+		handler.cfg.DuplicateEntryWindow = 2 * time.Minute
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Oatmeal", 150))
+		expectRollup(mock)
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT id, created_at\\s+FROM nutrition_entries").
+			WithArgs(testUserID, "2026-01-26", MealBreakfast, "Oatmeal", 150.0, testEntryID, testNow).
+			WillReturnRows(duplicateRows(testNow.Add(-5 * time.Second)))
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body)
+
+		assert.Equal(t, http.StatusCreated, status)
+		assert.Equal(t, testEntryID, resp["data"].(map[string]interface{})["entry"].(map[string]interface{})["id"])
+		assert.Equal(t, map[string]interface{}{"possible_duplicate": map[string]interface{}{"existing_entry_id": otherEntryID}}, resp["warnings"])
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
+	t.Run("no identical entry", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		handler.cfg.DuplicateEntryCheck = true
+		handler.cfg.DuplicateEntryWindow = 2 * time.Minute
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Oatmeal", 150))
+		expectRollup(mock)
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT id, created_at").WillReturnError(sql.ErrNoRows)
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body)
+
+		assert.Equal(t, http.StatusCreated, status)
+		assert.NotContains(t, resp, "warnings")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed lookup still creates", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		handler.cfg.DuplicateEntryCheck = true
+		handler.cfg.DuplicateEntryWindow = 2 * time.Minute
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Oatmeal", 150))
+		expectRollup(mock)
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT id, created_at").WillReturnError(errors.New("db is down"))
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body)
+
+		assert.Equal(t, http.StatusCreated, status)
+		assert.NotContains(t, resp, "warnings")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("check disabled", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Oatmeal", 150))
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body)
+
+		assert.Equal(t, http.StatusCreated, status)
+		assert.NotContains(t, resp, "warnings")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCreateEntry_MissingRequiredFields(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+
 	tests := []struct {
-		name string
-		body map[string]interface{}
+		name  string
+		body  string
+		field string
 	}{
-		{
-			name: "Missing date",
-			body: map[string]interface{}{
-				"meal":     "breakfast",
-				"food":     "Oatmeal",
-				"calories": 150,
-			},
-		},
-		{
-			name: "Missing meal",
-			body: map[string]interface{}{
-				"date":     "2026-01-26",
-				"food":     "Oatmeal",
-				"calories": 150,
-			},
-		},
-		{
-			name: "Missing food",
-			body: map[string]interface{}{
-				"date":     "2026-01-26",
-				"meal":     "breakfast",
-				"calories": 150,
-			},
-		},
-		{
-			name: "Missing calories",
-			body: map[string]interface{}{
-				"date": "2026-01-26",
-				"meal": "breakfast",
-				"food": "Oatmeal",
-			},
-		},
+		{"Missing date", `{"meal":"breakfast","food":"Oatmeal","calories":150}`, "date"},
+		{"Missing meal", `{"date":"2026-01-26","food":"Oatmeal","calories":150}`, "meal"},
+		{"Missing food", `{"date":"2026-01-26","meal":"breakfast","calories":150}`, "food"},
+		{"Missing calories", `{"date":"2026-01-26","meal":"breakfast","food":"Oatmeal"}`, "calories"},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			body, _ := json.Marshal(tt.body)
-			req := httptest.NewRequest(http.MethodPost, "/entries", bytes.NewBuffer(body))
-			req.Header.Set("Content-Type", "application/json")
-			w := httptest.NewRecorder()
-
-			router.ServeHTTP(w, req)
-
-			assert.Equal(t, http.StatusBadRequest, w.Code)
-
-			var response map[string]interface{}
-			err := json.Unmarshal(w.Body.Bytes(), &response)
-			require.NoError(t, err)
-
-			assert.Equal(t, "error", response["status"])
-			assert.Equal(t, "Неверные данные запроса", response["message"])
+			status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, "error", resp["status"])
+			assert.Equal(t, "Неверные данные запроса", resp["message"])
+			assert.Equal(t, map[string]interface{}{
+				"fields": map[string]interface{}{tt.field: "Обязательное поле"},
+			}, resp["details"])
 		})
 	}
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestCreateEntry_InvalidJSON(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+	handler, _ := setupTestHandler(t)
 
-	router.POST("/entries", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.CreateEntry(c)
+	status, _ := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", "invalid json")
+
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestCreateEntry_Validation(t *testing.T) {
+	t.Run("rejects invalid fields with details", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2020-01-26","meal":"foo","food":"Пицца","calories":-500}`)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.Equal(t, map[string]interface{}{
+			"fields": map[string]interface{}{
+				"date":     "Дата не может быть раньше чем 2 года назад",
+				"meal":     "Допустимые значения: breakfast, lunch, dinner, snack",
+				"calories": "Значение должно быть от 0 до 10000",
+			},
+		}, resp["details"])
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	req := httptest.NewRequest(http.MethodPost, "/entries", bytes.NewBufferString("invalid json"))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+	t.Run("rejects a malformed date while binding", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
 
-	router.ServeHTTP(w, req)
+		for _, date := range []string{`"garbage"`, `"2025-02-29"`, `"0000-00-00"`, `20260126`} {
+			status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+				`{"date":`+date+`,"meal":"lunch","food":"Пицца","calories":500}`)
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
-}
+			assert.Equal(t, http.StatusBadRequest, status, date)
+			assert.Equal(t, map[string]interface{}{
+				"fields": map[string]interface{}{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"},
+			}, resp["details"], date)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-func TestGetEntry(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+	t.Run("accepts zero calories", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").
+			WithArgs(newIDArg(), testUserID, "2026-01-26", MealSnack, "Вода", 0.0, 0.0, 0.0, 0.0, nil, nil, "вода", nil, nil, nil, nil).
+			WillReturnRows(entryRows("Вода", 0))
+		expectRollup(mock)
+		mock.ExpectCommit()
 
-	router.GET("/entries/:id", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.GetEntry(c)
+		status, _ := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2026-01-26","meal":"перекус","food":"Вода","calories":0}`)
+
+		assert.Equal(t, http.StatusCreated, status)
 	})
 
-	req := httptest.NewRequest(http.MethodGet, "/entries/entry-123", nil)
-	w := httptest.NewRecorder()
+	t.Run("warns when macros do not add up", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		rows := sqlmock.NewRows(entryColumnNames).
+			AddRow(testEntryID, testUserID, "2026-01-26", MealDinner, "Стейк", 100.0, 50.0, 0.0, 20.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(rows)
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2026-01-26","meal":"ужин","food":"Стейк","calories":100,"protein":50,"fat":20}`)
+
+		assert.Equal(t, http.StatusCreated, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, MealDinner, data["entry"].(map[string]interface{})["meal"])
+		assert.Contains(t, data["warning"], "не сходится с БЖУ")
+	})
 
-	router.ServeHTTP(w, req)
+	t.Run("no warning for consistent macros", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		mock.ExpectCommit()
 
-	assert.Equal(t, http.StatusOK, w.Code)
+		status, resp := serve(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2026-01-26","meal":"breakfast","food":"Овсянка","calories":150,"protein":5,"carbs":27,"fat":3}`)
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+		assert.Equal(t, http.StatusCreated, status)
+		assert.NotContains(t, resp["data"], "warning")
+	})
+}
+
+func TestGetEntry(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+	mock.ExpectQuery(entrySelectRe).
+		WithArgs(testEntryID, testUserID).
+		WillReturnRows(entryRows("Овсянка", 150))
+
+	status, resp := serve(t, handler.GetEntry, testUserID, http.MethodGet, "/entries/"+testEntryID, "")
 
-	assert.Equal(t, "success", response["status"])
-	data := response["data"].(map[string]interface{})
-	entry := data["entry"].(map[string]interface{})
-	assert.Equal(t, "entry-123", entry["id"])
+	assert.Equal(t, http.StatusOK, status)
+	entry := resp["data"].(map[string]interface{})["entry"].(map[string]interface{})
+	assert.Equal(t, testEntryID, entry["id"])
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateEntry_Success(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+	handler, mock := setupTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).WillReturnRows(entryRows("Овсянка", 150))
+	mock.ExpectQuery("UPDATE nutrition_entries").
+		WithArgs(testEntryID, testUserID, "2026-01-26", MealLunch, "Updated Food", 200.0, 10.0, 30.0, 5.0, nil, nil, "updated food", nil, nil, nil, nil, nil).
+		WillReturnRows(entryRows("Updated Food", 200))
+	expectRollup(mock)
+	mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	status, resp := serve(t, handler.UpdateEntry, testUserID, http.MethodPut, "/entries/"+testEntryID,
+		`{"date":"2026-01-26","meal":"lunch","food":"Updated Food","calories":200,"protein":10,"carbs":30,"fat":5}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	entry := resp["data"].(map[string]interface{})["entry"].(map[string]interface{})
+	assert.Equal(t, "Updated Food", entry["food"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	router.PUT("/entries/:id", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.UpdateEntry(c)
+func TestUpdateEntry_IfMatch(t *testing.T) {
+	body := `{"date":"2026-01-26","meal":"lunch","food":"Updated Food","calories":200,"protein":10,"carbs":30,"fat":5}`
+
+	t.Run("outdated version returns the current entry", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).
+			WillReturnRows(sqlmock.NewRows(entryColumnNames).
+				AddRow(testEntryID, testUserID, "2026-01-26", MealBreakfast, "Овсянка", 150.0, 5.0, 27.0, 3.0, testNow, testNow, nil, nil, 4, nil, nil, nil, nil))
+		mock.ExpectQuery("UPDATE nutrition_entries").
+			WithArgs(testEntryID, testUserID, "2026-01-26", MealLunch, "Updated Food", 200.0, 10.0, 30.0, 5.0, nil, nil, "updated food", nil, nil, nil, nil, 3).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		w := serveWithHeaders(t, handler.UpdateEntry, testUserID, http.MethodPut, "/entries/"+testEntryID, body,
+			map[string]string{"If-Match": `"3"`})
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "VERSION_CONFLICT", resp["code"])
+		current := resp["details"].(map[string]interface{})["current"].(map[string]interface{})
+		assert.Equal(t, "Овсянка", current["food"])
+		assert.Equal(t, 4.0, current["version"])
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	reqBody := CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "lunch",
-		Food:     "Updated Food",
-		Calories: 200,
-		Protein:  10,
-		Carbs:    30,
-		Fat:      5,
-	}
-	body, _ := json.Marshal(reqBody)
+	t.Run("malformed version", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
 
-	req := httptest.NewRequest(http.MethodPut, "/entries/entry-123", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+		w := serveWithHeaders(t, handler.UpdateEntry, testUserID, http.MethodPut, "/entries/"+testEntryID, body,
+			map[string]string{"If-Match": `W/"a1b2"`})
 
-	router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
 
-	assert.Equal(t, http.StatusOK, w.Code)
+func TestUpdateEntry_InvalidJSON(t *testing.T) {
+	handler, _ := setupTestHandler(t)
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+	status, _ := serve(t, handler.UpdateEntry, testUserID, http.MethodPut, "/entries/"+testEntryID, "invalid")
 
-	assert.Equal(t, "success", response["status"])
-	data := response["data"].(map[string]interface{})
-	entry := data["entry"].(map[string]interface{})
-	assert.Equal(t, "Updated Food", entry["food"])
+	assert.Equal(t, http.StatusBadRequest, status)
 }
 
-func TestUpdateEntry_InvalidJSON(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+func TestDeleteEntry(t *testing.T) {
+	handler, mock := setupTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery("DELETE FROM nutrition_entries").
+		WithArgs(testEntryID, testUserID).
+		WillReturnRows(entryRows("Овсянка", 150))
+	expectRollup(mock)
+	mock.ExpectExec("UPDATE curator_comments SET deleted_at").WithArgs(testEntryID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	status, resp := serve(t, handler.DeleteEntry, testUserID, http.MethodDelete, "/entries/"+testEntryID, "")
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "success", resp["status"])
+	assert.Equal(t, "Entry deleted successfully", resp["message"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	router.PUT("/entries/:id", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.UpdateEntry(c)
-	})
+func TestEntryOwnership(t *testing.T) {
+	updateBody := `{"date":"2026-01-26","meal":"lunch","food":"Чужая еда","calories":200}`
 
-	req := httptest.NewRequest(http.MethodPut, "/entries/entry-123", bytes.NewBufferString("invalid"))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		expect func(mock sqlmock.Sqlmock)
+		handle func(h *Handler) gin.HandlerFunc
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, otherUserID).WillReturnError(sql.ErrNoRows)
+			},
+			handle: func(h *Handler) gin.HandlerFunc { return h.GetEntry },
+		},
+		{
+			name:   "update",
+			method: http.MethodPut,
+			body:   updateBody,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, otherUserID).WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			handle: func(h *Handler) gin.HandlerFunc { return h.UpdateEntry },
+		},
+		{
+			name:   "delete",
+			method: http.MethodDelete,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery("DELETE FROM nutrition_entries").WithArgs(testEntryID, otherUserID).
+					WillReturnError(sql.ErrNoRows)
+				mock.ExpectRollback()
+			},
+			handle: func(h *Handler) gin.HandlerFunc { return h.DeleteEntry },
+		},
+	}
 
-	router.ServeHTTP(w, req)
+	for _, tt := range tests {
+		for _, owner := range []struct {
+			name   string
+			result func(mock sqlmock.Sqlmock)
+		}{
+			{"another user's entry", func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+			}},
+			{"nonexistent entry", func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).WillReturnError(sql.ErrNoRows)
+			}},
+		} {
+			t.Run(tt.name+" "+owner.name, func(t *testing.T) {
+				handler, mock := setupTestHandler(t)
+				tt.expect(mock)
+				owner.result(mock)
+
+				status, resp := serve(t, tt.handle(handler), otherUserID, tt.method, "/entries/"+testEntryID, tt.body)
+
+				// Both cases look the same so ids cannot be probed
+				assert.Equal(t, http.StatusNotFound, status)
+				assert.Equal(t, "NOT_FOUND", resp["code"])
+				assert.Equal(t, "Не найдено", resp["message"])
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	}
 
-	assert.Equal(t, http.StatusBadRequest, w.Code)
+	for _, tt := range tests {
+		t.Run(tt.name+" malformed id", func(t *testing.T) {
+			handler, mock := setupTestHandler(t)
+
+			status, resp := serve(t, tt.handle(handler), testUserID, tt.method, "/entries/entry-123", tt.body)
+
+			// Rejected before any query
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+			assert.Equal(t, map[string]interface{}{"id": "Неверный формат идентификатора"},
+				resp["details"].(map[string]interface{})["fields"])
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
 }
 
-func TestDeleteEntry(t *testing.T) {
-	handler := setupTestHandler()
-	router := gin.New()
+func TestCreateEntry_IdempotencyKey(t *testing.T) {
+	body := `{"date":"2026-01-26","meal":"breakfast","food":"Овсянка","calories":150,"protein":5,"carbs":27,"fat":3}`
+	expectClaim := func(mock sqlmock.Sqlmock, inserted int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").
+			WithArgs(testUserID, entryKeyScope, "key-1", 86400).
+			WillReturnResult(sqlmock.NewResult(0, inserted))
+	}
+
+	t.Run("first request", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		expectClaim(mock, 1)
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		mock.ExpectExec("UPDATE idempotency_keys").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		w := serveWithHeaders(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body,
+			map[string]string{"Idempotency-Key": "key-1"})
 
-	router.DELETE("/entries/:id", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.DeleteEntry(c)
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replay"))
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
-	req := httptest.NewRequest(http.MethodDelete, "/entries/entry-123", nil)
-	w := httptest.NewRecorder()
+	t.Run("replay", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		expectClaim(mock, 0)
+		mock.ExpectQuery("SELECT resource_id FROM idempotency_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"resource_id"}).AddRow(testEntryID))
+		mock.ExpectRollback()
+		mock.ExpectQuery(entrySelectRe).WillReturnRows(entryRows("Овсянка", 150))
+
+		w := serveWithHeaders(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body,
+			map[string]string{"Idempotency-Key": "key-1"})
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replay"))
+		assert.Contains(t, w.Body.String(), testEntryID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-	router.ServeHTTP(w, req)
+	t.Run("invalid key", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
 
-	assert.Equal(t, http.StatusOK, w.Code)
+		w := serveWithHeaders(t, handler.CreateEntry, testUserID, http.MethodPost, "/entries/", body,
+			map[string]string{"Idempotency-Key": "ключ"})
 
-	var response map[string]interface{}
-	err := json.Unmarshal(w.Body.Bytes(), &response)
-	require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "VALIDATION_FAILED")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// mockService implements ServiceInterface for handler tests that only care
+// how the handler maps service results to responses
+type mockService struct {
+	entry *Entry
+	err   error
+}
+
+func (m *mockService) GetEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) ([]*Entry, error) {
+	return nil, m.err
+}
+
+func (m *mockService) StreamEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		if m.err != nil {
+			yield(nil, m.err)
+		}
+	}
+}
+
+func (m *mockService) CreateEntry(ctx context.Context, userID int64, req *CreateEntryRequest) (*Entry, error) {
+	return m.entry, m.err
+}
+
+func (m *mockService) CreateEntryOnce(ctx context.Context, userID int64, key string, req *CreateEntryRequest) (*Entry, bool, error) {
+	return m.entry, false, m.err
+}
+
+func (m *mockService) GetEntry(ctx context.Context, userID int64, entryID string) (*Entry, error) {
+	return m.entry, m.err
+}
+
+func (m *mockService) UpdateEntry(ctx context.Context, userID int64, entryID string, req *CreateEntryRequest, version *int) (*Entry, error) {
+	return m.entry, m.err
+}
+
+func (m *mockService) DeleteEntry(ctx context.Context, userID int64, entryID string) error {
+	return m.err
+}
+
+func (m *mockService) GetEntryHistory(ctx context.Context, actorID int64, entryID string) ([]*Revision, error) {
+	return nil, m.err
+}
+
+func (m *mockService) FindDuplicate(ctx context.Context, entry *Entry, window time.Duration) (string, error) {
+	return "", m.err
+}
+
+func TestHandler_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"missing entry", apperrors.ErrNotFound, http.StatusNotFound},
+		{"foreign entry", errForeignEntry, http.StatusNotFound},
+		{"invalid entry", validation.Errors{"calories": "Значение должно быть не меньше 0"}, http.StatusBadRequest},
+		{"database error", errors.New("db is down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&config.Config{}, logger.New(), nil, &mockService{err: tt.err}, nil, nil)
+
+			for _, handle := range []gin.HandlerFunc{handler.GetEntry, handler.DeleteEntry, handler.GetEntryHistory} {
+				status, _ := serve(t, handle, testUserID, http.MethodGet, "/entries/"+testEntryID, "")
+				assert.Equal(t, tt.status, status)
+			}
+		})
+	}
+}
+
+func TestUpdateEntry_MacroWarning(t *testing.T) {
+	// 10 g of protein is 40 kcal, far from the 500 logged
+	entry := &Entry{ID: testEntryID, UserID: testUserID, Food: "Суп", Calories: 500, Protein: 10}
+	handler := NewHandler(&config.Config{}, logger.New(), nil, &mockService{entry: entry}, nil, nil)
+
+	status, resp := serve(t, handler.UpdateEntry, testUserID, http.MethodPut, "/entries/"+testEntryID,
+		`{"date":"2026-10-16","meal":"lunch","food":"Суп","calories":500,"protein":10}`)
 
-	assert.Equal(t, "success", response["status"])
-	assert.Equal(t, "Entry deleted successfully", response["message"])
+	assert.Equal(t, http.StatusOK, status)
+	assert.NotEmpty(t, resp["data"].(map[string]interface{})["warning"])
 }
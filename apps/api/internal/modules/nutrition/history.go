@@ -0,0 +1,183 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/google/uuid"
+)
+
+// Change types recorded in nutrition_entry_revisions
+const (
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Revision is one recorded change of a nutrition entry. OldValues holds the
+// fields an update changed, with their values before it, or the whole entry
+// before a delete.
+type Revision struct {
+	ID         int64                  `json:"id"`
+	EntryID    string                 `json:"entry_id"`
+	ChangedBy  *int64                 `json:"changed_by"`
+	ChangeType string                 `json:"change_type"`
+	OldValues  map[string]interface{} `json:"old_values"`
+	ChangedAt  time.Time              `json:"changed_at"`
+}
+
+// snapshot returns the user-editable fields of an entry. Ids, the owner and
+// timestamps are left out: they never change and are not shown in history.
+// Unknown micronutrients are nil.
+func snapshot(e *Entry) map[string]interface{} {
+	return map[string]interface{}{
+		"date":            e.Date,
+		"meal":            e.Meal,
+		"food":            e.Food,
+		"calories":        e.Calories,
+		"protein":         e.Protein,
+		"carbs":           e.Carbs,
+		"fat":             e.Fat,
+		"fiber_g":         optional(e.FiberG),
+		"sugar_g":         optional(e.SugarG),
+		"sodium_mg":       optional(e.SodiumMg),
+		"saturated_fat_g": optional(e.SaturatedFatG),
+	}
+}
+
+// optional returns the value of v, or nil, so that snapshots compare by value
+func optional(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// changedFields returns the fields of before that differ in after, with
+// their old values
+func changedFields(before, after *Entry) map[string]interface{} {
+	old, updated := snapshot(before), snapshot(after)
+	diff := make(map[string]interface{})
+	for field, value := range old {
+		if updated[field] != value {
+			diff[field] = value
+		}
+	}
+	return diff
+}
+
+// recordRevision writes a revision of entry inside the change's transaction
+func (s *Service) recordRevision(ctx context.Context, tx *sql.Tx, entry *Entry, changedBy int64, changeType string, oldValues map[string]interface{}) error {
+	startTime := time.Now()
+
+	values, err := json.Marshal(oldValues)
+	if err != nil {
+		return fmt.Errorf("failed to encode entry revision: %w", err)
+	}
+
+	query := `
+		INSERT INTO nutrition_entry_revisions (entry_id, user_id, changed_by, change_type, old_values)
+		VALUES ($1, $2, $3, $4, $5)`
+	_, err = tx.ExecContext(ctx, query, entry.ID, entry.UserID, changedBy, changeType, values)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"entry_id":    entry.ID,
+		"change_type": changeType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record entry revision: %w", err)
+	}
+	return nil
+}
+
+// GetEntryHistory returns the revisions of an entry, newest first. It is
+// available to the entry owner and their coach, also after the entry was
+// deleted. Anyone else gets a not found error, as for a missing entry.
+func (s *Service) GetEntryHistory(ctx context.Context, actorID int64, entryID string) ([]*Revision, error) {
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	ownerID, err := s.entryOwner(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if ownerID != actorID {
+		linked, err := s.isCoachOf(ctx, actorID, ownerID)
+		if err != nil {
+			return nil, err
+		}
+		if !linked {
+			return nil, errForeignEntry
+		}
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT id, entry_id, changed_by, change_type, old_values, changed_at
+		FROM nutrition_entry_revisions
+		WHERE entry_id = $1
+		ORDER BY changed_at DESC, id DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, entryID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"entry_id": entryID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query entry history: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := make([]*Revision, 0)
+	for rows.Next() {
+		var r Revision
+		var changedBy sql.NullInt64
+		var values []byte
+		if err := rows.Scan(&r.ID, &r.EntryID, &changedBy, &r.ChangeType, &values, &r.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan entry revision: %w", err)
+		}
+		if changedBy.Valid {
+			r.ChangedBy = &changedBy.Int64
+		}
+		if err := json.Unmarshal(values, &r.OldValues); err != nil {
+			return nil, fmt.Errorf("failed to decode entry revision: %w", err)
+		}
+		revisions = append(revisions, &r)
+	}
+	return revisions, rows.Err()
+}
+
+// entryOwner returns the owner of an entry, looking at its history when the
+// entry itself was deleted
+func (s *Service) entryOwner(ctx context.Context, entryID string) (int64, error) {
+	query := `
+		SELECT user_id FROM nutrition_entries WHERE id = $1
+		UNION ALL
+		SELECT user_id FROM nutrition_entry_revisions WHERE entry_id = $1
+		LIMIT 1`
+
+	var ownerID int64
+	err := s.db.QueryRowContext(ctx, query, entryID).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get entry owner: %w", err)
+	}
+	return ownerID, nil
+}
+
+// isCoachOf reports whether coachID is the active curator of clientID
+func (s *Service) isCoachOf(ctx context.Context, coachID, clientID int64) (bool, error) {
+	var linked bool
+	err := s.db.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM curator_client_relationships
+			WHERE curator_id = $1 AND client_id = $2 AND status = 'active'
+		)`, coachID, clientID).Scan(&linked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check coach link: %w", err)
+	}
+	return linked, nil
+}
@@ -0,0 +1,155 @@
+package nutrition
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	revisionOwnerRe = "SELECT user_id FROM nutrition_entries WHERE id = \\$1\\s+UNION ALL"
+	coachLinkRe     = "FROM curator_client_relationships"
+)
+
+func revisionRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "entry_id", "changed_by", "change_type", "old_values", "changed_at"}).
+		AddRow(int64(2), testEntryID, otherUserID, ChangeDelete,
+			[]byte(`{"date":"2026-01-26","meal":"lunch","food":"Гречка","calories":200,"protein":7,"carbs":40,"fat":2}`),
+			testNow.Add(time.Hour)).
+		AddRow(int64(1), testEntryID, nil, ChangeUpdate, []byte(`{"food":"Овсянка","calories":150}`), testNow)
+}
+
+func TestChangedFields(t *testing.T) {
+	before := &Entry{ID: testEntryID, UserID: testUserID, Date: "2026-01-26", Meal: MealBreakfast, Food: "Овсянка", Calories: 150, Protein: 5}
+	after := *before
+	after.Food = "Гречка"
+	after.Calories = 200
+	after.UpdatedAt = testNow
+
+	assert.Equal(t, map[string]interface{}{"food": "Овсянка", "calories": 150.0}, changedFields(before, &after))
+	assert.Empty(t, changedFields(before, before))
+
+	// Micronutrients compare by value; one newly recorded was unknown before
+	withSodium := after
+	withSodium.SodiumMg = floatPtr(900)
+	sameSodium := withSodium
+	sameSodium.SodiumMg = floatPtr(900)
+	assert.Equal(t, map[string]interface{}{"sodium_mg": nil}, changedFields(&after, &withSodium))
+	assert.Empty(t, changedFields(&withSodium, &sameSodium))
+}
+
+func TestService_GetEntryHistory(t *testing.T) {
+	t.Run("owner sees revisions newest first", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(revisionOwnerRe).WithArgs(testEntryID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+		mock.ExpectQuery("FROM nutrition_entry_revisions\\s+WHERE entry_id = \\$1\\s+ORDER BY changed_at DESC").
+			WithArgs(testEntryID).
+			WillReturnRows(revisionRows())
+
+		revisions, err := service.GetEntryHistory(context.Background(), testUserID, testEntryID)
+
+		require.NoError(t, err)
+		require.Len(t, revisions, 2)
+		assert.Equal(t, ChangeDelete, revisions[0].ChangeType)
+		assert.Equal(t, otherUserID, *revisions[0].ChangedBy)
+		assert.Equal(t, "Гречка", revisions[0].OldValues["food"])
+		assert.Nil(t, revisions[1].ChangedBy, "the editor's account was deleted")
+		assert.Equal(t, map[string]interface{}{"food": "Овсянка", "calories": 150.0}, revisions[1].OldValues)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("linked coach", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(revisionOwnerRe).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+		mock.ExpectQuery(coachLinkRe).WithArgs(otherUserID, testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("FROM nutrition_entry_revisions").WillReturnRows(revisionRows())
+
+		revisions, err := service.GetEntryHistory(context.Background(), otherUserID, testEntryID)
+
+		require.NoError(t, err)
+		assert.Len(t, revisions, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(revisionOwnerRe).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+		mock.ExpectQuery(coachLinkRe).WithArgs(otherUserID, testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := service.GetEntryHistory(context.Background(), otherUserID, testEntryID)
+
+		assert.ErrorIs(t, err, errForeignEntry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		_, err := service.GetEntryHistory(context.Background(), testUserID, "entry-123")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetEntryHistory(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery(revisionOwnerRe).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+		mock.ExpectQuery("FROM nutrition_entry_revisions").WillReturnRows(revisionRows())
+
+		status, resp := serve(t, handler.GetEntryHistory, testUserID, http.MethodGet, "/entries/"+testEntryID, "")
+
+		assert.Equal(t, http.StatusOK, status)
+		revisions := resp["data"].(map[string]interface{})["items"].([]interface{})
+		require.Len(t, revisions, 2)
+		assert.Equal(t, ChangeDelete, revisions[0].(map[string]interface{})["change_type"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nonexistent entry", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery(revisionOwnerRe).WithArgs(testEntryID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+		status, resp := serve(t, handler.GetEntryHistory, testUserID, http.MethodGet, "/entries/"+testEntryID, "")
+
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, "NOT_FOUND", resp["code"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's entry", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery(revisionOwnerRe).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+		mock.ExpectQuery(coachLinkRe).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		status, _ := serve(t, handler.GetEntryHistory, otherUserID, http.MethodGet, "/entries/"+testEntryID, "")
+
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("malformed id", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.GetEntryHistory, testUserID, http.MethodGet, "/entries/entry-123", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
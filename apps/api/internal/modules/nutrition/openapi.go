@@ -0,0 +1,81 @@
+package nutrition
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+	"github.com/burcev/api/internal/shared/types"
+)
+
+// entryPhotoQuery selects the photo size: full (default) or thumb, a JPEG
+// up to 256 px on its longest side
+type entryPhotoQuery struct {
+	Size string `form:"size"`
+}
+
+// entriesQuery sorts and pages the entries; sort is date, calories, protein
+// or created_at with an optional :asc or :desc. stream=true streams them as
+// newline-delimited JSON.
+type entriesQuery struct {
+	Sort   string `form:"sort"`
+	Limit  int    `form:"limit"`
+	Offset int    `form:"offset"`
+	Stream bool   `form:"stream"`
+}
+
+// dayQuery selects a day; today in the tz timezone when date is empty
+type dayQuery struct {
+	Date types.Date `form:"date"`
+	TZ   string     `form:"tz"`
+}
+
+// reportQuery selects the report or day flag range, both ends included, up
+// to 180 days
+type reportQuery struct {
+	From types.Date `form:"from" binding:"required"`
+	To   types.Date `form:"to" binding:"required"`
+}
+
+// searchQuery searches the food names of the user's entries, optionally
+// within from..to
+type searchQuery struct {
+	Q    string     `form:"q" binding:"required"`
+	From types.Date `form:"from"`
+	To   types.Date `form:"to"`
+}
+
+// Endpoints describes the /nutrition entry and water routes for the OpenAPI
+// document. Read-only routes also accept an API key with the
+// read:nutrition scope.
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/entries", Summary: "Записи питания (поддерживает If-None-Match); с Accept: application/x-ndjson или stream=true — поток записей по одной на строку без обёртки", Auth: openapi.BearerOrAPIKey, Query: entriesQuery{}, Response: EntriesResponse{}, Stream: Entry{}},
+		{Method: http.MethodPost, Path: "/entries", Summary: "Новая запись; повтор с тем же Idempotency-Key возвращает исходную, а похожая недавняя запись — предупреждение warnings.possible_duplicate", Auth: openapi.Bearer, Request: CreateEntryRequest{}, Response: EntryResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/entries/export", Summary: "Все записи питания в CSV, от старых к новым: date, meal, food, calories, protein, carbs, fat, fiber_g, sugar_g, sodium_mg, saturated_fat_g; пустая ячейка — нутриент не указан", Auth: openapi.BearerOrAPIKey},
+		{Method: http.MethodPost, Path: "/entries/import", Summary: "Импорт записей из CSV с заголовком (multipart-файл file, до 2 МБ и 1000 строк): обязательны date, meal, food и calories, поэтому выгрузки без микронутриентов тоже принимаются; отклонённые строки перечислены в row_errors", Auth: openapi.Bearer, Response: ImportEntriesResponse{}},
+		{Method: http.MethodGet, Path: "/entries/:id", Summary: "Запись питания", Auth: openapi.BearerOrAPIKey, Response: EntryResponse{}},
+		{Method: http.MethodPut, Path: "/entries/:id", Summary: "Изменение записи; версия из If-Match должна быть текущей, иначе 409 VERSION_CONFLICT с текущей записью", Auth: openapi.Bearer, Request: CreateEntryRequest{}, Response: EntryResponse{}},
+		{Method: http.MethodDelete, Path: "/entries/:id", Summary: "Удаление записи", Auth: openapi.Bearer},
+		{Method: http.MethodGet, Path: "/entries/:id/history", Summary: "История изменений записи", Auth: openapi.BearerOrAPIKey, Response: RevisionsResponse{}},
+		{Method: http.MethodPost, Path: "/entries/:id/photo", Summary: "Загрузка фото блюда: multipart-файл photo (JPEG или PNG, до 5 МБ); заменяет прежнее фото", Auth: openapi.Bearer, Response: EntryPhotoResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/entries/:id/photo", Summary: "Фото блюда или его миниатюра (size=thumb)", Auth: openapi.BearerOrAPIKey, Query: entryPhotoQuery{}},
+		{Method: http.MethodGet, Path: "/search", Summary: "Поиск по истории питания без учёта регистра, ё и диакритики: блюда с числом записей, средней калорийностью и датой, плюс 10 последних записей", Auth: openapi.BearerOrAPIKey, Query: searchQuery{}, Response: SearchResult{}},
+		{Method: http.MethodPost, Path: "/water", Summary: "Добавление выпитой воды", Auth: openapi.Bearer, Request: AddWaterRequest{}, Response: AddWaterResponse{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/water", Summary: "Вода за день, по умолчанию за сегодня", Auth: openapi.BearerOrAPIKey, Query: dayQuery{}, Response: WaterDay{}},
+		{Method: http.MethodDelete, Path: "/water/:id", Summary: "Удаление записи о воде", Auth: openapi.Bearer},
+		{Method: http.MethodGet, Path: "/missing", Summary: "Просроченные по расписанию приёмы пищи за день", Auth: openapi.BearerOrAPIKey, Query: dayQuery{}, Response: MissingMeals{}},
+		{Method: http.MethodGet, Path: "/days", Summary: "Отмеченные дни (рефид, болезнь, поездка) за период", Auth: openapi.BearerOrAPIKey, Query: reportQuery{}, Response: DayFlagsResponse{}},
+		{Method: http.MethodPost, Path: "/days/:date/flag", Summary: "Отметка дня; исключённые типы не учитываются в статистике и рекомендациях", Auth: openapi.Bearer, Request: FlagDayRequest{}, Response: DayFlagResponse{}},
+		{Method: http.MethodDelete, Path: "/days/:date/flag", Summary: "Снятие отметки дня", Auth: openapi.Bearer},
+		{Method: http.MethodGet, Path: "/report", Summary: "Отчёт о соблюдении целей питания за период", Auth: openapi.BearerOrAPIKey, Query: reportQuery{}, Response: Report{}},
+	}
+}
+
+// ScheduleEndpoints describes the meal schedule routes, served under /users,
+// for the OpenAPI document
+func ScheduleEndpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/meal-schedule", Summary: "Расписание приёмов пищи", Auth: openapi.Bearer, Response: MealSchedule{}},
+		{Method: http.MethodPut, Path: "/meal-schedule", Summary: "Изменение расписания приёмов пищи (ЧЧ:ММ в часовом поясе пользователя)", Auth: openapi.Bearer, Request: MealSchedule{}, Response: MealSchedule{}},
+	}
+}
@@ -0,0 +1,279 @@
+package nutrition
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/google/uuid"
+)
+
+// MaxEntryPhotoSize is the maximum accepted meal photo size (5 MB)
+const MaxEntryPhotoSize = 5 * 1024 * 1024
+
+// Entry photo sizes, selected with ?size=
+const (
+	PhotoSizeFull  = "full"
+	PhotoSizeThumb = "thumb"
+)
+
+// thumbnailContentType is the type of every thumbnail
+const thumbnailContentType = "image/jpeg"
+
+var (
+	ErrEntryPhotoTooLarge     = errors.New("photo exceeds 5 MB")
+	ErrInvalidEntryPhoto      = errors.New("photo must be a JPEG or PNG image")
+	ErrEntryPhotosUnavailable = errors.New("entry photos are not configured")
+)
+
+// entryPhotoTypes are the accepted photo content types
+var entryPhotoTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+}
+
+// EntryPhoto is the meal photo of a nutrition entry
+type EntryPhoto struct {
+	EntryID            string    `json:"entry_id"`
+	ContentType        string    `json:"content_type"`
+	SizeBytes          int       `json:"size_bytes"`
+	ThumbnailSizeBytes int       `json:"thumbnail_size_bytes"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	StorageKey         string    `json:"-"`
+	ThumbnailKey       string    `json:"-"`
+	StorageRegion      string    `json:"-"`
+}
+
+// entryPhotoKeys returns the storage keys of an entry's photo and
+// thumbnail. They are fixed per entry, so a new upload overwrites the old
+// files and deleting the entry needs no lookup.
+func entryPhotoKeys(userID int64, entryID string) (photo, thumbnail string) {
+	photo = fmt.Sprintf("nutrition/%d/%s", userID, entryID)
+	return photo, photo + "-thumb"
+}
+
+// PhotoService stores meal photos of nutrition entries
+type PhotoService struct {
+	db    *database.DB
+	log   *logger.Logger
+	files *storage.Router
+}
+
+// NewPhotoService creates a new photo service. Photos are kept in the
+// storage region of the user's organization, as routed by files; nil files
+// disable entry photos.
+func NewPhotoService(db *database.DB, log *logger.Logger, files *storage.Router) *PhotoService {
+	return &PhotoService{db: db, log: log, files: files}
+}
+
+// Upload stores the photo of a user's entry with its thumbnail in the
+// user's storage region, replacing any previous one. The content type is
+// detected from the file contents.
+func (s *PhotoService) Upload(ctx context.Context, userID int64, entryID string, data io.Reader) (*EntryPhoto, error) {
+	if s.files == nil {
+		return nil, ErrEntryPhotosUnavailable
+	}
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(data, MaxEntryPhotoSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+	if len(buf) > MaxEntryPhotoSize {
+		return nil, ErrEntryPhotoTooLarge
+	}
+	contentType := http.DetectContentType(buf)
+	if !entryPhotoTypes[contentType] {
+		return nil, ErrInvalidEntryPhoto
+	}
+	thumb, err := makeThumbnail(buf)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEntryPhoto, err)
+	}
+
+	if err := s.checkEntry(ctx, userID, entryID); err != nil {
+		return nil, err
+	}
+
+	region, store, err := s.files.ForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	photoKey, thumbKey := entryPhotoKeys(userID, entryID)
+	if err := store.Put(ctx, photoKey, bytes.NewReader(buf)); err != nil {
+		return nil, fmt.Errorf("failed to store photo: %w", err)
+	}
+	if err := store.Put(ctx, thumbKey, bytes.NewReader(thumb)); err != nil {
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	// previous is the region of the replaced photo, read before the upsert
+	startTime := time.Now()
+	query := `
+		WITH previous AS (SELECT storage_region FROM nutrition_entry_photos WHERE entry_id = $1)
+		INSERT INTO nutrition_entry_photos (entry_id, user_id, storage_key, thumbnail_key, storage_region, content_type, size_bytes, thumbnail_size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (entry_id) DO UPDATE SET
+			storage_region = EXCLUDED.storage_region,
+			content_type = EXCLUDED.content_type,
+			size_bytes = EXCLUDED.size_bytes,
+			thumbnail_size_bytes = EXCLUDED.thumbnail_size_bytes,
+			updated_at = NOW()
+		RETURNING created_at, updated_at, (SELECT storage_region FROM previous)
+	`
+	photo := &EntryPhoto{
+		EntryID:            entryID,
+		ContentType:        contentType,
+		SizeBytes:          len(buf),
+		ThumbnailSizeBytes: len(thumb),
+		StorageKey:         photoKey,
+		ThumbnailKey:       thumbKey,
+		StorageRegion:      region,
+	}
+	var previous sql.NullString
+	err = s.db.QueryRowContext(ctx, query,
+		entryID, userID, photoKey, thumbKey, region, contentType, len(buf), len(thumb),
+	).Scan(&photo.CreatedAt, &photo.UpdatedAt, &previous)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if err != nil {
+		// The files are removed with the entry
+		return nil, fmt.Errorf("failed to save photo metadata: %w", err)
+	}
+	if previous.Valid && previous.String != region {
+		// The organization changed region since the last upload
+		deleteEntryPhotoFiles(ctx, s.files, s.log, userID, entryID, previous)
+	}
+
+	s.log.LogBusinessEvent("nutrition_entry_photo_uploaded", map[string]interface{}{
+		"user_id":    userID,
+		"entry_id":   entryID,
+		"size_bytes": len(buf),
+	})
+	return photo, nil
+}
+
+// checkEntry returns apperrors.ErrNotFound unless the entry is the user's
+func (s *PhotoService) checkEntry(ctx context.Context, userID int64, entryID string) error {
+	startTime := time.Now()
+	query := `SELECT EXISTS (SELECT 1 FROM nutrition_entries WHERE id = $1 AND user_id = $2)`
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, entryID, userID).Scan(&exists)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check entry: %w", err)
+	}
+	if !exists {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// Open returns the photo metadata and the contents of the requested size,
+// PhotoSizeFull or PhotoSizeThumb. Entries of other users and entries
+// without a photo are reported as not found.
+func (s *PhotoService) Open(ctx context.Context, userID int64, entryID, size string) (*EntryPhoto, io.ReadCloser, error) {
+	if s.files == nil {
+		return nil, nil, ErrEntryPhotosUnavailable
+	}
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, nil, apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT entry_id, storage_key, thumbnail_key, storage_region, content_type, size_bytes, thumbnail_size_bytes, created_at, updated_at
+		FROM nutrition_entry_photos
+		WHERE entry_id = $1 AND user_id = $2
+	`
+	var p EntryPhoto
+	err := s.db.QueryRowContext(ctx, query, entryID, userID).Scan(&p.EntryID, &p.StorageKey, &p.ThumbnailKey,
+		&p.StorageRegion, &p.ContentType, &p.SizeBytes, &p.ThumbnailSizeBytes, &p.CreatedAt, &p.UpdatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get photo: %w", err)
+	}
+
+	key := p.StorageKey
+	if size == PhotoSizeThumb {
+		key = p.ThumbnailKey
+	}
+	store, err := s.files.ForRegion(p.StorageRegion)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := store.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			s.log.Error("Entry photo metadata points to a missing object", "entry_id", entryID, "key", key)
+			return nil, nil, apperrors.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+	return &p, r, nil
+}
+
+// entryPhotoRegion returns the storage region of an entry's photo, read
+// before the entry is deleted and its photo row with it. It is not valid for
+// an entry without a photo row.
+func entryPhotoRegion(ctx context.Context, tx *sql.Tx, userID int64, entryID string) (sql.NullString, error) {
+	var region sql.NullString
+	err := tx.QueryRowContext(ctx,
+		`SELECT storage_region FROM nutrition_entry_photos WHERE entry_id = $1 AND user_id = $2`, entryID, userID,
+	).Scan(&region)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return region, fmt.Errorf("failed to get entry photo: %w", err)
+	}
+	return region, nil
+}
+
+// deleteEntryPhotoFiles removes the photo files of an entry from region.
+// Without a region, e.g. for files left by an upload whose row was never
+// saved, they are removed from the user's current region. A failure is
+// logged: an orphaned file is only wasted space.
+func deleteEntryPhotoFiles(ctx context.Context, files *storage.Router, log *logger.Logger, userID int64, entryID string, region sql.NullString) {
+	if files == nil {
+		return
+	}
+	var store storage.Storage
+	var err error
+	if region.Valid {
+		store, err = files.ForRegion(region.String)
+	} else {
+		_, store, err = files.ForUser(ctx, userID)
+	}
+	if err != nil {
+		log.Error("Failed to delete entry photo files", "error", err, "entry_id", entryID, "region", region.String)
+		return
+	}
+	photoKey, thumbKey := entryPhotoKeys(userID, entryID)
+	for _, key := range []string{photoKey, thumbKey} {
+		if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			log.Error("Failed to delete entry photo file", "error", err, "entry_id", entryID, "key", key)
+		}
+	}
+}
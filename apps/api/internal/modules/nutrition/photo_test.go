@@ -0,0 +1,446 @@
+package nutrition
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var entryPhotoColumns = []string{"entry_id", "storage_key", "thumbnail_key", "storage_region", "content_type",
+	"size_bytes", "thumbnail_size_bytes", "created_at", "updated_at"}
+
+func setupPhotoService(t *testing.T) (*PhotoService, *storage.MemoryStorage, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	store := storage.NewMemoryStorage()
+	return NewPhotoService(&database.DB{DB: mockDB}, logger.New(), storage.NewRouter(store, nil, nil)), store, mock
+}
+
+func expectEntryOwned(mock sqlmock.Sqlmock, userID int64, owned bool) {
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM nutrition_entries WHERE id = \\$1 AND user_id = \\$2\\)").
+		WithArgs(testEntryID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(owned))
+}
+
+func TestPhotoService_Upload(t *testing.T) {
+	photo := encodeJPEG(t, quadrantImage(600, 400))
+
+	t.Run("stores the photo and thumbnail", func(t *testing.T) {
+		service, store, mock := setupPhotoService(t)
+		expectEntryOwned(mock, testUserID, true)
+		mock.ExpectQuery("INSERT INTO nutrition_entry_photos.+ON CONFLICT \\(entry_id\\) DO UPDATE").
+			WithArgs(testEntryID, testUserID, "nutrition/123/"+testEntryID, "nutrition/123/"+testEntryID+"-thumb",
+				"", "image/jpeg", len(photo), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "storage_region"}).AddRow(testNow, testNow, nil))
+
+		saved, err := service.Upload(context.Background(), testUserID, testEntryID, bytes.NewReader(photo))
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/jpeg", saved.ContentType)
+		assert.Equal(t, len(photo), saved.SizeBytes)
+		assert.Equal(t, []string{"nutrition/123/" + testEntryID, "nutrition/123/" + testEntryID + "-thumb"}, store.Keys())
+
+		r, err := store.Get(context.Background(), saved.ThumbnailKey)
+		require.NoError(t, err)
+		thumb, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Len(t, thumb, saved.ThumbnailSizeBytes)
+		assert.Equal(t, ThumbnailSize, decodeThumbnail(t, thumb).Bounds().Dx())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("entry of another user", func(t *testing.T) {
+		service, store, mock := setupPhotoService(t)
+		expectEntryOwned(mock, otherUserID, false)
+
+		_, err := service.Upload(context.Background(), otherUserID, testEntryID, bytes.NewReader(photo))
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejected before the database", func(t *testing.T) {
+		tests := []struct {
+			name string
+			data []byte
+			want error
+		}{
+			{"too large", append(photo, make([]byte, MaxEntryPhotoSize)...), ErrEntryPhotoTooLarge},
+			{"not an image", []byte("%PDF-1.7"), ErrInvalidEntryPhoto},
+			{"empty", nil, ErrInvalidEntryPhoto},
+			{"corrupt jpeg", photo[:len(photo)/3], ErrInvalidEntryPhoto},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				service, store, mock := setupPhotoService(t)
+
+				_, err := service.Upload(context.Background(), testUserID, testEntryID, bytes.NewReader(tt.data))
+
+				assert.ErrorIs(t, err, tt.want)
+				assert.Empty(t, store.Keys())
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	})
+
+	t.Run("without storage", func(t *testing.T) {
+		service := NewPhotoService(nil, logger.New(), nil)
+
+		_, err := service.Upload(context.Background(), testUserID, testEntryID, bytes.NewReader(photo))
+
+		assert.ErrorIs(t, err, ErrEntryPhotosUnavailable)
+	})
+}
+
+func TestPhotoService_Open(t *testing.T) {
+	service, store, mock := setupPhotoService(t)
+	photoKey, thumbKey := entryPhotoKeys(testUserID, testEntryID)
+	require.NoError(t, store.Put(context.Background(), photoKey, strings.NewReader("full")))
+	require.NoError(t, store.Put(context.Background(), thumbKey, strings.NewReader("thumb")))
+
+	for size, want := range map[string]string{PhotoSizeFull: "full", PhotoSizeThumb: "thumb"} {
+		mock.ExpectQuery("FROM nutrition_entry_photos\\s+WHERE entry_id = \\$1 AND user_id = \\$2").
+			WithArgs(testEntryID, testUserID).
+			WillReturnRows(sqlmock.NewRows(entryPhotoColumns).
+				AddRow(testEntryID, photoKey, thumbKey, "", "image/png", 4, 5, testNow, testNow))
+
+		photo, r, err := service.Open(context.Background(), testUserID, testEntryID, size)
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", photo.ContentType)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, want, string(data))
+		r.Close()
+	}
+
+	// An entry without a photo, or another user's
+	mock.ExpectQuery("FROM nutrition_entry_photos").
+		WithArgs(testEntryID, otherUserID).
+		WillReturnRows(sqlmock.NewRows(entryPhotoColumns))
+	_, _, err := service.Open(context.Background(), otherUserID, testEntryID, PhotoSizeFull)
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_DeleteEntryRemovesPhoto(t *testing.T) {
+	service, mock := setupTestService(t)
+	store := storage.NewMemoryStorage()
+	service.photos = storage.NewRouter(store, nil, nil)
+	photoKey, thumbKey := entryPhotoKeys(testUserID, testEntryID)
+	require.NoError(t, store.Put(context.Background(), photoKey, strings.NewReader("full")))
+	require.NoError(t, store.Put(context.Background(), thumbKey, strings.NewReader("thumb")))
+	require.NoError(t, store.Put(context.Background(), "nutrition/123/"+otherEntryID, strings.NewReader("full")))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT storage_region FROM nutrition_entry_photos").WithArgs(testEntryID, testUserID).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow(""))
+	mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+	expectRollup(mock)
+	mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+
+	assert.Equal(t, []string{"nutrition/123/" + otherEntryID}, store.Keys())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// servePhoto routes a request to the entry photo handlers as testUserID
+func servePhoto(h *Handler, req *http.Request) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(middleware.ErrorHandler(logger.New()))
+	router.Use(func(c *gin.Context) { c.Set("user_id", testUserID) })
+	router.POST("/entries/:id/photo", h.UploadEntryPhoto)
+	router.GET("/entries/:id/photo", h.GetEntryPhoto)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func photoForm(t *testing.T, field string, data []byte) (*bytes.Buffer, string) {
+	t.Helper()
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile(field, "meal.jpg")
+	require.NoError(t, err)
+	_, err = part.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, form.Close())
+	return body, form.FormDataContentType()
+}
+
+func TestUploadEntryPhotoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	photo := encodeJPEG(t, quadrantImage(300, 300))
+
+	t.Run("uploads", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		handler.photos.files = storage.NewRouter(storage.NewMemoryStorage(), nil, nil)
+		expectEntryOwned(mock, testUserID, true)
+		mock.ExpectQuery("INSERT INTO nutrition_entry_photos").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "storage_region"}).AddRow(testNow, testNow, nil))
+
+		body, contentType := photoForm(t, "photo", photo)
+		req := httptest.NewRequest(http.MethodPost, "/entries/"+testEntryID+"/photo", body)
+		req.Header.Set("Content-Type", contentType)
+		w := servePhoto(handler, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"content_type":"image/jpeg"`)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	tests := []struct {
+		name   string
+		field  string
+		data   []byte
+		status int
+	}{
+		{"missing file", "file", photo, http.StatusBadRequest},
+		{"not an image", "photo", []byte("GIF89a"), http.StatusBadRequest},
+		{"too large", "photo", make([]byte, MaxEntryPhotoSize+multipartOverhead), http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock := setupTestHandler(t)
+			handler.photos.files = storage.NewRouter(storage.NewMemoryStorage(), nil, nil)
+
+			body, contentType := photoForm(t, tt.field, tt.data)
+			req := httptest.NewRequest(http.MethodPost, "/entries/"+testEntryID+"/photo", body)
+			req.Header.Set("Content-Type", contentType)
+			w := servePhoto(handler, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+
+	t.Run("photos disabled", func(t *testing.T) {
+		handler, _ := setupTestHandler(t)
+
+		body, contentType := photoForm(t, "photo", photo)
+		req := httptest.NewRequest(http.MethodPost, "/entries/"+testEntryID+"/photo", body)
+		req.Header.Set("Content-Type", contentType)
+		w := servePhoto(handler, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	})
+}
+
+func TestGetEntryPhotoHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setup := func(t *testing.T) (*Handler, sqlmock.Sqlmock) {
+		handler, mock := setupTestHandler(t)
+		store := storage.NewMemoryStorage()
+		photoKey, thumbKey := entryPhotoKeys(testUserID, testEntryID)
+		require.NoError(t, store.Put(context.Background(), photoKey, strings.NewReader("png!")))
+		require.NoError(t, store.Put(context.Background(), thumbKey, strings.NewReader("thumb")))
+		handler.photos.files = storage.NewRouter(store, nil, nil)
+		return handler, mock
+	}
+	expectPhoto := func(mock sqlmock.Sqlmock) {
+		photoKey, thumbKey := entryPhotoKeys(testUserID, testEntryID)
+		mock.ExpectQuery("FROM nutrition_entry_photos").
+			WillReturnRows(sqlmock.NewRows(entryPhotoColumns).
+				AddRow(testEntryID, photoKey, thumbKey, "", "image/png", 4, 5, testNow, testNow))
+	}
+
+	t.Run("full size", func(t *testing.T) {
+		handler, mock := setup(t)
+		expectPhoto(mock)
+
+		w := servePhoto(handler, httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+		assert.Equal(t, "png!", w.Body.String())
+	})
+
+	t.Run("thumbnail", func(t *testing.T) {
+		handler, mock := setup(t)
+		expectPhoto(mock)
+
+		w := servePhoto(handler, httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo?size=thumb", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "5", w.Header().Get("Content-Length"))
+		assert.Equal(t, "thumb", w.Body.String())
+	})
+
+	t.Run("unchanged photo", func(t *testing.T) {
+		handler, mock := setup(t)
+		expectPhoto(mock)
+		first := servePhoto(handler, httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo", nil))
+		require.NotEmpty(t, first.Header().Get("ETag"))
+
+		expectPhoto(mock)
+		req := httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo", nil)
+		req.Header.Set("If-None-Match", first.Header().Get("ETag"))
+		w := servePhoto(handler, req)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+	})
+
+	t.Run("unknown size", func(t *testing.T) {
+		handler, _ := setup(t)
+
+		w := servePhoto(handler, httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo?size=huge", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("entry without a photo", func(t *testing.T) {
+		handler, mock := setup(t)
+		mock.ExpectQuery("FROM nutrition_entry_photos").WillReturnRows(sqlmock.NewRows(entryPhotoColumns))
+
+		w := servePhoto(handler, httptest.NewRequest(http.MethodGet, "/entries/"+testEntryID+"/photo", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+// regionStore is an in-memory storage.ObjectStore of one region
+type regionStore struct {
+	objects map[string][]byte
+}
+
+func (r *regionStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	r.objects[key] = b
+	return "https://storage.example/" + key, nil
+}
+
+func (r *regionStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return r.objects[key], nil
+}
+
+func (r *regionStore) DeleteFile(ctx context.Context, key string) error {
+	delete(r.objects, key)
+	return nil
+}
+
+func (r *regionStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + key + "?signed", nil
+}
+
+// regionRouter routes testUserID to the "eu" region and otherUserID to the
+// unconfigured "us"
+func regionRouter(local storage.Storage) (*storage.Router, *regionStore) {
+	eu := &regionStore{objects: make(map[string][]byte)}
+	regions := storage.NewRegions("ru")
+	regions.Register("eu", eu)
+	orgRegions := map[int64]string{testUserID: "eu", otherUserID: "us"}
+	return storage.NewRouter(local, regions, func(ctx context.Context, userID int64) (string, error) {
+		return orgRegions[userID], nil
+	}), eu
+}
+
+func TestPhotoService_StorageRegion(t *testing.T) {
+	photo := encodeJPEG(t, quadrantImage(600, 400))
+	photoKey, thumbKey := entryPhotoKeys(testUserID, testEntryID)
+
+	t.Run("uploads to the organization's region and removes the replaced files", func(t *testing.T) {
+		service, local, mock := setupPhotoService(t)
+		files, eu := regionRouter(local)
+		service.files = files
+		require.NoError(t, local.Put(context.Background(), photoKey, strings.NewReader("old")))
+		require.NoError(t, local.Put(context.Background(), thumbKey, strings.NewReader("old")))
+
+		expectEntryOwned(mock, testUserID, true)
+		mock.ExpectQuery("WITH previous AS .+INSERT INTO nutrition_entry_photos").
+			WithArgs(testEntryID, testUserID, photoKey, thumbKey, "eu", "image/jpeg", len(photo), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at", "updated_at", "storage_region"}).AddRow(testNow, testNow, ""))
+
+		_, err := service.Upload(context.Background(), testUserID, testEntryID, bytes.NewReader(photo))
+
+		require.NoError(t, err)
+		assert.Equal(t, photo, eu.objects[photoKey])
+		assert.Contains(t, eu.objects, thumbKey)
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails the upload for an unconfigured region", func(t *testing.T) {
+		service, local, mock := setupPhotoService(t)
+		service.files, _ = regionRouter(local)
+		expectEntryOwned(mock, otherUserID, true)
+
+		_, err := service.Upload(context.Background(), otherUserID, testEntryID, bytes.NewReader(photo))
+
+		assert.ErrorIs(t, err, storage.ErrRegionUnavailable)
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reads from the recorded region", func(t *testing.T) {
+		service, local, mock := setupPhotoService(t)
+		files, eu := regionRouter(local)
+		service.files = files
+		eu.objects[thumbKey] = []byte("thumb")
+
+		mock.ExpectQuery("FROM nutrition_entry_photos").
+			WithArgs(testEntryID, testUserID).
+			WillReturnRows(sqlmock.NewRows(entryPhotoColumns).
+				AddRow(testEntryID, photoKey, thumbKey, "eu", "image/jpeg", 4, 5, testNow, testNow))
+
+		_, r, err := service.Open(context.Background(), testUserID, testEntryID, PhotoSizeThumb)
+
+		require.NoError(t, err)
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "thumb", string(data))
+	})
+
+	t.Run("deleting the entry removes the files from the recorded region", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		files, eu := regionRouter(storage.NewMemoryStorage())
+		service.photos = files
+		eu.objects[photoKey] = []byte("full")
+		eu.objects[thumbKey] = []byte("thumb")
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_region FROM nutrition_entry_photos").WithArgs(testEntryID, testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow("eu"))
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+
+		assert.Empty(t, eu.objects)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
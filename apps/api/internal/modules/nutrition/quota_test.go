@@ -0,0 +1,86 @@
+package nutrition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testEntryQuota = 3
+
+func TestService_EntryQuota(t *testing.T) {
+	setup := func(t *testing.T) (*Service, sqlmock.Sqlmock) {
+		service, mock := setupTestService(t)
+		service.quotas = quotas.NewService(service.db, logger.New(), quotas.Limits{EntriesPerDay: testEntryQuota})
+		return service, mock
+	}
+	create := func(service *Service) (*Entry, error) {
+		return service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealLunch, Food: "Борщ", Calories: floatPtr(350),
+		})
+	}
+	expectReserve := func(mock sqlmock.Sqlmock, entries int) {
+		mock.ExpectQuery("INSERT INTO user_daily_entries").
+			WithArgs(testUserID, "2026-01-26", int64(testEntryQuota)).
+			WillReturnRows(sqlmock.NewRows([]string{"entries", "limit"}).AddRow(entries, testEntryQuota))
+	}
+
+	t.Run("create and delete count the entry on its date", func(t *testing.T) {
+		service, mock := setup(t)
+		mock.ExpectBegin()
+		expectReserve(mock, 1)
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ", 350))
+		expectRollup(mock)
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(entryRows("Борщ", 350))
+		expectRollup(mock)
+		mock.ExpectExec("UPDATE user_daily_entries SET entries = GREATEST\\(entries - 1, 0\\)").
+			WithArgs(testUserID, "2026-01-26").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		entry, err := create(service)
+		require.NoError(t, err)
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, entry.ID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the entry reaching the limit is created", func(t *testing.T) {
+		service, mock := setup(t)
+		mock.ExpectBegin()
+		expectReserve(mock, testEntryQuota)
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ", 350))
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		_, err := create(service)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an entry over the limit is rolled back", func(t *testing.T) {
+		service, mock := setup(t)
+		mock.ExpectBegin()
+		expectReserve(mock, testEntryQuota+1)
+		mock.ExpectRollback()
+
+		_, err := create(service)
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, apperrors.QuotaExceededError{Resource: quotas.EntriesPerDay, Used: testEntryQuota, Limit: testEntryQuota}, *quotaErr)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,434 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+)
+
+const (
+	// MaxReportDays bounds the from..to range of a report, both ends included
+	MaxReportDays = 180
+	// ReportTopEntries is how many of the highest-calorie entries a report lists
+	ReportTopEntries = 5
+	// TargetTolerance is how far, as a share of the target, a day's calories
+	// may be from the calorie target to count as on target
+	TargetTolerance = 0.10
+)
+
+// Calories per gram of each macronutrient, used for the macro split
+const (
+	caloriesPerGramProtein = 4
+	caloriesPerGramCarbs   = 4
+	caloriesPerGramFat     = 9
+)
+
+// Macros are calories and macronutrient grams
+type Macros struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Carbs    float64 `json:"carbs"`
+	Fat      float64 `json:"fat"`
+}
+
+// MacroSplit is the share of calories, in percent, that comes from each
+// macronutrient
+type MacroSplit struct {
+	Protein float64 `json:"protein"`
+	Carbs   float64 `json:"carbs"`
+	Fat     float64 `json:"fat"`
+}
+
+// DayTypeStats compares weekdays and weekends. Average is over logged days
+// and null when none were logged.
+type DayTypeStats struct {
+	Days       int     `json:"days"`
+	DaysLogged int     `json:"days_logged"`
+	Average    *Macros `json:"average"`
+}
+
+// Report is a user's adherence to their nutrition goals over a date range.
+// Averages, splits and the on-target share cover logged days only, leaving
+// out days flagged with an excluded type; fields without data are null, so
+// an empty range still gives a complete report.
+type Report struct {
+	From         string  `json:"from"`
+	To           string  `json:"to"`
+	Days         int     `json:"days"`
+	DaysLogged   int     `json:"days_logged"`
+	DaysExcluded int     `json:"days_excluded"`
+	Average      *Macros `json:"average"`
+
+	// DaysWithTarget are the logged days that have a calorie target;
+	// WithinTargetPercent is the share of them within TargetTolerance of it
+	DaysWithTarget      int      `json:"days_with_target"`
+	WithinTargetPercent *float64 `json:"within_target_percent"`
+
+	Split       *MacroSplit `json:"split"`
+	TargetSplit *MacroSplit `json:"target_split"`
+
+	Weekdays DayTypeStats `json:"weekdays"`
+	Weekends DayTypeStats `json:"weekends"`
+
+	// Micronutrients averages each micronutrient over the logged days that
+	// recorded it; one no day recorded is null
+	Micronutrients Micronutrients `json:"micronutrients"`
+	// SodiumWarnings are the days whose sodium went over the limit
+	SodiumWarnings []SodiumWarning `json:"sodium_warnings"`
+
+	TopEntries []*Entry `json:"top_entries"`
+}
+
+// SodiumWarning is a day whose recorded sodium went over LimitMg
+type SodiumWarning struct {
+	Date     string  `json:"date"`
+	SodiumMg float64 `json:"sodium_mg"`
+	LimitMg  float64 `json:"limit_mg"`
+}
+
+// reportDay is one day of the range with what was logged and its target.
+// Excluded days carry a flag that leaves them out of the statistics. Micros
+// are the sums of the micronutrients its entries recorded, nil where none
+// did.
+type reportDay struct {
+	Date     time.Time
+	Entries  int
+	Totals   Macros
+	Micros   Micronutrients
+	Target   *Macros
+	Excluded bool
+}
+
+// ReportService builds nutrition adherence reports
+type ReportService struct {
+	db       *database.DB
+	log      *logger.Logger
+	flags    *FlagService
+	dayCache cache.Cache
+	// sodiumLimitMg is the daily sodium above which a day gets a warning
+	sodiumLimitMg float64
+}
+
+// NewReportService creates a new report service. flags decides which
+// flagged days are left out. Loaded days are kept in dayCache, which may
+// be nil. Days with more than sodiumLimitMg of sodium get a warning.
+func NewReportService(db *database.DB, log *logger.Logger, flags *FlagService, dayCache cache.Cache, sodiumLimitMg float64) *ReportService {
+	return &ReportService{db: db, log: log, flags: flags, dayCache: dayCache, sodiumLimitMg: sodiumLimitMg}
+}
+
+// daysBetween counts the days from from to to, both included
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
+
+// GetReport returns the adherence report of the user for dates, at most
+// MaxReportDays long. An invalid range is reported as validation.Errors.
+func (s *ReportService) GetReport(ctx context.Context, userID int64, dates types.DateRange) (*Report, error) {
+	if err := dates.Validate(MaxReportDays); err != nil {
+		return nil, err
+	}
+	fromDate, toDate := dates.From.Time(), dates.To.Time()
+	from, to := dates.From.String(), dates.To.String()
+
+	days, err := s.loadDays(ctx, userID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	top, err := s.loadTopEntries(ctx, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := assembleReport(fromDate, toDate, days, top)
+	report.SodiumWarnings = sodiumWarnings(days, s.sodiumLimitMg)
+	return report, nil
+}
+
+// loadDays returns every day of the range with its entry totals, its
+// target (the active weekly plan covering the day, else the calculated one)
+// and whether its flag excludes it. The days come from the cache when all
+// of them are there; otherwise they are queried and cached.
+func (s *ReportService) loadDays(ctx context.Context, userID int64, fromDate, toDate time.Time) ([]reportDay, error) {
+	if days, ok := s.cachedDays(ctx, userID, fromDate, toDate); ok {
+		return days, nil
+	}
+	days, err := s.queryDays(ctx, userID, fromDate.Format("2006-01-02"), toDate.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	s.cacheDays(ctx, userID, days)
+	return days, nil
+}
+
+// queryDays loads the days of from..to from the database. Entry totals
+// come from the daily rollups, so a long range does not sum raw entries.
+func (s *ReportService) queryDays(ctx context.Context, userID int64, from, to string) ([]reportDay, error) {
+	startTime := time.Now()
+	query := `
+		SELECT d.date::date::text,
+		       COALESCE(r.entry_count, 0), COALESCE(r.calories, 0), COALESCE(r.protein, 0),
+		       COALESCE(r.carbs, 0), COALESCE(r.fat, 0),
+		       COALESCE(wp.calories_goal, t.calories), COALESCE(wp.protein_goal, t.protein),
+		       COALESCE(wp.carbs_goal, t.carbs), COALESCE(wp.fat_goal, t.fat),
+		       f.type,
+		       CASE WHEN r.fiber_g_count > 0 THEN r.fiber_g END,
+		       CASE WHEN r.sugar_g_count > 0 THEN r.sugar_g END,
+		       CASE WHEN r.sodium_mg_count > 0 THEN r.sodium_mg END,
+		       CASE WHEN r.saturated_fat_g_count > 0 THEN r.saturated_fat_g END
+		FROM generate_series($2::date, $3::date, '1 day'::interval) AS d(date)
+		LEFT JOIN nutrition_daily_rollups r ON r.user_id = $1 AND r.date = d.date::date
+		LEFT JOIN daily_calculated_targets t ON t.user_id = $1 AND t.date = d.date::date
+		LEFT JOIN nutrition_day_flags f ON f.user_id = $1 AND f.date = d.date::date
+		LEFT JOIN LATERAL (
+			SELECT calories_goal, protein_goal, carbs_goal, fat_goal
+			FROM weekly_plans
+			WHERE user_id = $1 AND is_active = true
+			  AND start_date <= d.date::date AND end_date >= d.date::date
+			ORDER BY start_date DESC
+			LIMIT 1
+		) wp ON true
+		ORDER BY d.date`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"from":    from,
+		"to":      to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report days: %w", err)
+	}
+	defer rows.Close()
+
+	var days []reportDay
+	for rows.Next() {
+		var d reportDay
+		var date string
+		var calories, protein, carbs, fat sql.NullFloat64
+		var flag sql.NullString
+		if err := rows.Scan(&date, &d.Entries, &d.Totals.Calories, &d.Totals.Protein, &d.Totals.Carbs, &d.Totals.Fat,
+			&calories, &protein, &carbs, &fat, &flag,
+			&d.Micros.FiberG, &d.Micros.SugarG, &d.Micros.SodiumMg, &d.Micros.SaturatedFatG); err != nil {
+			return nil, fmt.Errorf("failed to scan report day: %w", err)
+		}
+		if d.Date, err = time.Parse("2006-01-02", date); err != nil {
+			return nil, fmt.Errorf("failed to parse report day: %w", err)
+		}
+		if calories.Valid {
+			d.Target = &Macros{Calories: calories.Float64, Protein: protein.Float64, Carbs: carbs.Float64, Fat: fat.Float64}
+		}
+		d.Excluded = flag.Valid && s.flags.Excludes(flag.String)
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating report days: %w", err)
+	}
+	return days, nil
+}
+
+// loadTopEntries returns the highest-calorie entries of the range
+func (s *ReportService) loadTopEntries(ctx context.Context, userID int64, from, to string) ([]*Entry, error) {
+	startTime := time.Now()
+	query := `
+		SELECT ` + entryColumns + `
+		FROM nutrition_entries
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		ORDER BY calories DESC, date, created_at, id
+		LIMIT $4`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, from, to, ReportTopEntries)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0, ReportTopEntries)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top entries: %w", err)
+	}
+	return entries, nil
+}
+
+// assembleReport computes the report of from..to from already loaded days.
+//
+// A day is logged when it has at least one entry and is not excluded by its
+// flag; excluded days only count towards the days and DaysExcluded. A
+// logged day is on target when its calories are within TargetTolerance of a
+// positive calorie target. The target split averages the targets of the
+// logged days that have one, so it is compared with what was eaten on the
+// same days. Saturdays and Sundays are weekends.
+func assembleReport(from, to time.Time, days []reportDay, top []*Entry) *Report {
+	report := &Report{
+		From:       from.Format("2006-01-02"),
+		To:         to.Format("2006-01-02"),
+		Days:       daysBetween(from, to),
+		TopEntries: top,
+	}
+	if report.TopEntries == nil {
+		report.TopEntries = []*Entry{}
+	}
+
+	var logged, weekdays, weekends, targets []Macros
+	var micros []Micronutrients
+	withinTarget := 0
+	for _, d := range days {
+		weekend := d.Date.Weekday() == time.Saturday || d.Date.Weekday() == time.Sunday
+		if weekend {
+			report.Weekends.Days++
+		} else {
+			report.Weekdays.Days++
+		}
+		if d.Excluded {
+			report.DaysExcluded++
+			continue
+		}
+		if d.Entries == 0 {
+			continue
+		}
+
+		logged = append(logged, d.Totals)
+		micros = append(micros, d.Micros)
+		if weekend {
+			weekends = append(weekends, d.Totals)
+		} else {
+			weekdays = append(weekdays, d.Totals)
+		}
+
+		if d.Target != nil && d.Target.Calories > 0 {
+			targets = append(targets, *d.Target)
+			if math.Abs(d.Totals.Calories-d.Target.Calories) <= d.Target.Calories*TargetTolerance {
+				withinTarget++
+			}
+		}
+	}
+
+	report.DaysLogged = len(logged)
+	report.Average = averageMacros(logged)
+	report.Micronutrients = averageMicronutrients(micros)
+	report.Weekdays.DaysLogged = len(weekdays)
+	report.Weekdays.Average = averageMacros(weekdays)
+	report.Weekends.DaysLogged = len(weekends)
+	report.Weekends.Average = averageMacros(weekends)
+
+	report.DaysWithTarget = len(targets)
+	if len(targets) > 0 {
+		percent := round1(float64(withinTarget) / float64(len(targets)) * 100)
+		report.WithinTargetPercent = &percent
+	}
+
+	if report.Average != nil {
+		report.Split = splitOf(*report.Average)
+	}
+	if target := averageMacros(targets); target != nil {
+		report.TargetSplit = splitOf(*target)
+	}
+
+	return report
+}
+
+// averageMacros returns the average of days, nil when there are none
+func averageMacros(days []Macros) *Macros {
+	if len(days) == 0 {
+		return nil
+	}
+	var sum Macros
+	for _, d := range days {
+		sum.Calories += d.Calories
+		sum.Protein += d.Protein
+		sum.Carbs += d.Carbs
+		sum.Fat += d.Fat
+	}
+	n := float64(len(days))
+	return &Macros{
+		Calories: round1(sum.Calories / n),
+		Protein:  round1(sum.Protein / n),
+		Carbs:    round1(sum.Carbs / n),
+		Fat:      round1(sum.Fat / n),
+	}
+}
+
+// averageMicronutrients averages each micronutrient over the days that
+// recorded it, like SQL AVG: a nil day is skipped rather than counted as
+// zero, and a nutrient no day recorded stays nil
+func averageMicronutrients(days []Micronutrients) Micronutrients {
+	average := func(value func(Micronutrients) *float64) *float64 {
+		var sum float64
+		n := 0
+		for _, d := range days {
+			if v := value(d); v != nil {
+				sum += *v
+				n++
+			}
+		}
+		if n == 0 {
+			return nil
+		}
+		avg := round1(sum / float64(n))
+		return &avg
+	}
+	return Micronutrients{
+		FiberG:        average(func(m Micronutrients) *float64 { return m.FiberG }),
+		SugarG:        average(func(m Micronutrients) *float64 { return m.SugarG }),
+		SodiumMg:      average(func(m Micronutrients) *float64 { return m.SodiumMg }),
+		SaturatedFatG: average(func(m Micronutrients) *float64 { return m.SaturatedFatG }),
+	}
+}
+
+// sodiumWarnings returns the days whose recorded sodium is over limitMg.
+// Excluded days are warned about too: the flag keeps them out of the
+// averages, not out of the salt. A limit of 0 disables the warnings.
+func sodiumWarnings(days []reportDay, limitMg float64) []SodiumWarning {
+	warnings := make([]SodiumWarning, 0)
+	if limitMg <= 0 {
+		return warnings
+	}
+	for _, d := range days {
+		if d.Micros.SodiumMg != nil && *d.Micros.SodiumMg > limitMg {
+			warnings = append(warnings, SodiumWarning{
+				Date:     d.Date.Format("2006-01-02"),
+				SodiumMg: round1(*d.Micros.SodiumMg),
+				LimitMg:  limitMg,
+			})
+		}
+	}
+	return warnings
+}
+
+// splitOf returns the calorie split of m's macros, nil when they add up to
+// no calories
+func splitOf(m Macros) *MacroSplit {
+	protein := m.Protein * caloriesPerGramProtein
+	carbs := m.Carbs * caloriesPerGramCarbs
+	fat := m.Fat * caloriesPerGramFat
+	total := protein + carbs + fat
+	if total <= 0 {
+		return nil
+	}
+	return &MacroSplit{
+		Protein: round1(protein / total * 100),
+		Carbs:   round1(carbs / total * 100),
+		Fat:     round1(fat / total * 100),
+	}
+}
+
+// round1 rounds to one decimal place
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
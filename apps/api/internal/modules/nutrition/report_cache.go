@@ -0,0 +1,65 @@
+package nutrition
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// ReportDayTTL is how long a day of a report stays cached. Entry and day
+// flag changes remove it at once; target and weekly plan changes show
+// after at most this long.
+const ReportDayTTL = 10 * time.Minute
+
+// reportDayKey is the cache key of a user's report day (YYYY-MM-DD)
+func reportDayKey(userID int64, date string) string {
+	return fmt.Sprintf("nutrition:report-day:%d:%s", userID, date)
+}
+
+// cachedDays returns the days of from..to when every one of them is
+// cached. A cache failure counts as a miss.
+func (s *ReportService) cachedDays(ctx context.Context, userID int64, fromDate, toDate time.Time) ([]reportDay, bool) {
+	if s.dayCache == nil {
+		return nil, false
+	}
+	days := make([]reportDay, 0, daysBetween(fromDate, toDate))
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		var d reportDay
+		ok, err := cache.GetJSON(ctx, s.dayCache, reportDayKey(userID, day.Format("2006-01-02")), &d)
+		if err != nil {
+			s.log.Warnw("Failed to read cached report day", "error", err, "user_id", userID)
+			return nil, false
+		}
+		if !ok {
+			return nil, false
+		}
+		days = append(days, d)
+	}
+	return days, true
+}
+
+// cacheDays stores the loaded days of a report for ReportDayTTL
+func (s *ReportService) cacheDays(ctx context.Context, userID int64, days []reportDay) {
+	for _, d := range days {
+		if err := cache.SetJSON(ctx, s.dayCache, reportDayKey(userID, d.Date.Format("2006-01-02")), d, ReportDayTTL); err != nil {
+			s.log.Warnw("Failed to cache report day", "error", err, "user_id", userID)
+			return
+		}
+	}
+}
+
+// invalidateReportDays removes the user's cached report days of dates
+// after a write to them. A failure is logged; the days then live out
+// ReportDayTTL.
+func invalidateReportDays(ctx context.Context, c cache.Cache, log *logger.Logger, userID int64, dates ...string) {
+	keys := make([]string, 0, len(dates))
+	for _, date := range dates {
+		keys = append(keys, reportDayKey(userID, date))
+	}
+	if err := cache.Delete(ctx, c, keys...); err != nil {
+		log.Warnw("Failed to invalidate cached report days", "error", err, "user_id", userID, "dates", dates)
+	}
+}
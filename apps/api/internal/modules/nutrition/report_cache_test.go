@@ -0,0 +1,155 @@
+package nutrition
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cacheReportDays fills c with report days of testUserID for dates
+func cacheReportDays(t *testing.T, c cache.Cache, dates ...string) {
+	t.Helper()
+	for _, date := range dates {
+		require.NoError(t, cache.SetJSON(context.Background(), c, reportDayKey(testUserID, date), reportDay{Date: reportDate(date)}, ReportDayTTL))
+	}
+}
+
+func isCached(t *testing.T, c cache.Cache, date string) bool {
+	t.Helper()
+	_, ok, err := c.Get(context.Background(), reportDayKey(testUserID, date))
+	require.NoError(t, err)
+	return ok
+}
+
+func TestReportService_CachesDays(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	db := &database.DB{DB: mockDB}
+	dayCache := cache.NewMemory(0)
+	service := NewReportService(db, logger.New(), NewFlagService(db, logger.New(), nil, dayCache), dayCache, 2300)
+
+	mock.ExpectQuery("FROM generate_series").
+		WithArgs(testUserID, "2026-01-24", "2026-01-25").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns).
+			AddRow("2026-01-24", 2, 2100.0, 150.0, 200.0, 70.0, 2000.0, 150.0, 200.0, 66.7, nil, nil, nil, nil, nil).
+			AddRow("2026-01-25", 0, 0.0, 0.0, 0.0, 0.0, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery("ORDER BY calories DESC").WillReturnRows(sqlmock.NewRows(entryColumnNames))
+	first, err := service.GetReport(context.Background(), testUserID, testRange("2026-01-24", "2026-01-25"))
+	require.NoError(t, err)
+
+	// The second report reads its days from the cache; top entries are not cached
+	mock.ExpectQuery("ORDER BY calories DESC").WillReturnRows(sqlmock.NewRows(entryColumnNames))
+	second, err := service.GetReport(context.Background(), testUserID, testRange("2026-01-24", "2026-01-25"))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	// A range with an uncached day is loaded whole
+	mock.ExpectQuery("FROM generate_series").
+		WithArgs(testUserID, "2026-01-24", "2026-01-26").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns))
+	mock.ExpectQuery("ORDER BY calories DESC").WillReturnRows(sqlmock.NewRows(entryColumnNames))
+	_, err = service.GetReport(context.Background(), testUserID, testRange("2026-01-24", "2026-01-26"))
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_WritesInvalidateReportDays(t *testing.T) {
+	setup := func(t *testing.T) (*Service, sqlmock.Sqlmock, *cache.Memory) {
+		service, mock := setupTestService(t)
+		dayCache := cache.NewMemory(0)
+		service.dayCache = dayCache
+		cacheReportDays(t, dayCache, "2026-01-25", "2026-01-26", "2026-01-27")
+		return service, mock, dayCache
+	}
+
+	t.Run("create", func(t *testing.T) {
+		service, mock, dayCache := setup(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ", 350))
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealLunch, Food: "Борщ", Calories: floatPtr(350),
+		})
+
+		require.NoError(t, err)
+		assert.False(t, isCached(t, dayCache, "2026-01-26"))
+		assert.True(t, isCached(t, dayCache, "2026-01-25"))
+	})
+
+	t.Run("update moving the entry to another day", func(t *testing.T) {
+		service, mock, dayCache := setup(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").
+			WillReturnRows(sqlmock.NewRows(entryColumnNames).
+				AddRow(testEntryID, testUserID, "2026-01-27", MealBreakfast, "Овсянка", 150.0, 5.0, 27.0, 3.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil))
+		expectRollup(mock)
+		expectRollup(mock)
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, &CreateEntryRequest{
+			Date: testDate("2026-01-27"), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(150), Protein: 5, Carbs: 27, Fat: 3,
+		}, nil)
+
+		require.NoError(t, err)
+		assert.False(t, isCached(t, dayCache, "2026-01-26"), "the day the entry left")
+		assert.False(t, isCached(t, dayCache, "2026-01-27"), "the day the entry moved to")
+		assert.True(t, isCached(t, dayCache, "2026-01-25"))
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		service, mock, dayCache := setup(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+		assert.False(t, isCached(t, dayCache, "2026-01-26"))
+		assert.Equal(t, 2, dayCache.Len())
+	})
+
+	t.Run("failed write keeps the cache", func(t *testing.T) {
+		service, mock, dayCache := setup(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		assert.Error(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+		assert.Equal(t, 3, dayCache.Len())
+	})
+}
+
+func TestFlagService_InvalidatesReportDays(t *testing.T) {
+	service, mock := setupFlagService(t, DayFlagRefeed)
+	dayCache := cache.NewMemory(0)
+	service.dayCache = dayCache
+	cacheReportDays(t, dayCache, "2026-01-26", "2026-02-07")
+
+	mock.ExpectQuery("INSERT INTO nutrition_day_flags").
+		WillReturnRows(sqlmock.NewRows(dayFlagColumns).AddRow("2026-02-07", DayFlagRefeed, "", testNow, testNow))
+	_, err := service.SetFlag(context.Background(), testUserID, testDate("2026-02-07"), &FlagDayRequest{Type: DayFlagRefeed})
+	require.NoError(t, err)
+	assert.False(t, isCached(t, dayCache, "2026-02-07"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM nutrition_day_flags").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, service.DeleteFlag(context.Background(), testUserID, testDate("2026-01-26")))
+	assert.False(t, isCached(t, dayCache, "2026-01-26"))
+}
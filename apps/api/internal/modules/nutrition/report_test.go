@@ -0,0 +1,314 @@
+package nutrition
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var reportDayColumns = []string{"date", "entries", "calories", "protein", "carbs", "fat",
+	"target_calories", "target_protein", "target_carbs", "target_fat", "flag",
+	"fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}
+
+func reportDate(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func setupReportService(t *testing.T) (*ReportService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	db := &database.DB{DB: mockDB}
+	flags := NewFlagService(db, logger.New(), []string{DayFlagRefeed, DayFlagSick}, nil)
+	return NewReportService(db, logger.New(), flags, nil, 2300), mock
+}
+
+func TestReportRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		wantField string
+	}{
+		{name: "single day", from: "2026-01-26", to: "2026-01-26"},
+		{name: "longest range", from: "2026-01-01", to: "2026-06-29"},
+		{name: "too long", from: "2026-01-01", to: "2026-06-30", wantField: "to"},
+		{name: "reversed", from: "2026-01-26", to: "2026-01-25", wantField: "to"},
+		{name: "missing from", to: "2026-01-26", wantField: "from"},
+		{name: "bad to", from: "2026-01-26", to: "26.01.2026", wantField: "to"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := testRange(tt.from, tt.to).Validate(MaxReportDays)
+			if tt.wantField == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var errs validation.Errors
+			require.ErrorAs(t, err, &errs)
+			assert.Contains(t, errs, tt.wantField)
+		})
+	}
+}
+
+func TestAssembleReport(t *testing.T) {
+	// Monday 2026-01-19 to Sunday 2026-01-25
+	from, to := reportDate("2026-01-19"), reportDate("2026-01-25")
+	target := &Macros{Calories: 2000, Protein: 150, Carbs: 200, Fat: 66.7}
+
+	t.Run("week against a target", func(t *testing.T) {
+		days := []reportDay{
+			{Date: reportDate("2026-01-19"), Entries: 3, Totals: Macros{Calories: 1900, Protein: 140, Carbs: 190, Fat: 60}, Target: target},
+			{Date: reportDate("2026-01-20"), Entries: 4, Totals: Macros{Calories: 2300, Protein: 120, Carbs: 260, Fat: 80}, Target: target},
+			{Date: reportDate("2026-01-21"), Target: target},
+			{Date: reportDate("2026-01-22"), Entries: 2, Totals: Macros{Calories: 2100, Protein: 160, Carbs: 180, Fat: 75}, Target: target},
+			{Date: reportDate("2026-01-23"), Target: target},
+			{Date: reportDate("2026-01-24"), Entries: 5, Totals: Macros{Calories: 2900, Protein: 100, Carbs: 330, Fat: 120}, Target: target},
+			// Without a target the day is logged but not judged
+			{Date: reportDate("2026-01-25"), Entries: 1, Totals: Macros{Calories: 800, Protein: 40, Carbs: 90, Fat: 30}},
+		}
+		top := []*Entry{{ID: "a", Calories: 1200}}
+
+		report := assembleReport(from, to, days, top)
+
+		assert.Equal(t, "2026-01-19", report.From)
+		assert.Equal(t, "2026-01-25", report.To)
+		assert.Equal(t, 7, report.Days)
+		assert.Equal(t, 5, report.DaysLogged)
+		assert.Equal(t, &Macros{Calories: 2000, Protein: 112, Carbs: 210, Fat: 73}, report.Average)
+
+		assert.Equal(t, 4, report.DaysWithTarget)
+		require.NotNil(t, report.WithinTargetPercent)
+		assert.Equal(t, 50.0, *report.WithinTargetPercent, "1900 and 2100 are within 10% of 2000")
+
+		// 112 g protein = 448 kcal, 210 g carbs = 840 kcal, 73 g fat = 657 kcal
+		assert.Equal(t, &MacroSplit{Protein: 23, Carbs: 43.2, Fat: 33.8}, report.Split)
+		assert.Equal(t, &MacroSplit{Protein: 30, Carbs: 40, Fat: 30}, report.TargetSplit)
+
+		assert.Equal(t, DayTypeStats{Days: 5, DaysLogged: 3, Average: &Macros{Calories: 2100, Protein: 140, Carbs: 210, Fat: 71.7}}, report.Weekdays)
+		assert.Equal(t, DayTypeStats{Days: 2, DaysLogged: 2, Average: &Macros{Calories: 1850, Protein: 70, Carbs: 210, Fat: 75}}, report.Weekends)
+
+		assert.Equal(t, top, report.TopEntries)
+	})
+
+	t.Run("empty range", func(t *testing.T) {
+		days := []reportDay{
+			{Date: reportDate("2026-01-19"), Target: target},
+			{Date: reportDate("2026-01-24")},
+		}
+
+		report := assembleReport(from, to, days, nil)
+
+		assert.Equal(t, 7, report.Days)
+		assert.Zero(t, report.DaysLogged)
+		assert.Nil(t, report.Average)
+		assert.Zero(t, report.DaysWithTarget)
+		assert.Nil(t, report.WithinTargetPercent)
+		assert.Nil(t, report.Split)
+		assert.Nil(t, report.TargetSplit)
+		assert.Nil(t, report.Weekdays.Average)
+		assert.Nil(t, report.Weekends.Average)
+		assert.NotNil(t, report.TopEntries, "top entries are an empty list, not null")
+		assert.Empty(t, report.TopEntries)
+	})
+
+	t.Run("excluded days are skipped", func(t *testing.T) {
+		days := []reportDay{
+			{Date: reportDate("2026-01-19"), Entries: 3, Totals: Macros{Calories: 2000, Protein: 150, Carbs: 200, Fat: 66.7}, Target: target},
+			// A planned refeed far above target
+			{Date: reportDate("2026-01-20"), Entries: 4, Totals: Macros{Calories: 3500, Protein: 150, Carbs: 500, Fat: 80}, Target: target, Excluded: true},
+			{Date: reportDate("2026-01-21"), Excluded: true},
+		}
+
+		report := assembleReport(from, to, days, nil)
+
+		assert.Equal(t, 7, report.Days)
+		assert.Equal(t, 1, report.DaysLogged)
+		assert.Equal(t, 2, report.DaysExcluded)
+		assert.Equal(t, &Macros{Calories: 2000, Protein: 150, Carbs: 200, Fat: 66.7}, report.Average)
+		assert.Equal(t, 1, report.DaysWithTarget)
+		assert.Equal(t, 100.0, *report.WithinTargetPercent)
+		assert.Equal(t, DayTypeStats{Days: 3, DaysLogged: 1, Average: report.Average}, report.Weekdays)
+	})
+
+	t.Run("entries without calories or macros", func(t *testing.T) {
+		days := []reportDay{
+			{Date: reportDate("2026-01-19"), Entries: 1, Target: &Macros{}},
+		}
+
+		report := assembleReport(from, to, days, nil)
+
+		assert.Equal(t, 1, report.DaysLogged)
+		assert.Equal(t, &Macros{}, report.Average)
+		assert.Zero(t, report.DaysWithTarget, "a zero target is no target")
+		assert.Nil(t, report.Split)
+	})
+}
+
+func TestAveragedMicronutrients(t *testing.T) {
+	from, to := reportDate("2026-01-19"), reportDate("2026-01-21")
+	days := []reportDay{
+		// An old client logged the first day without micronutrients
+		{Date: reportDate("2026-01-19"), Entries: 2, Totals: Macros{Calories: 1800}},
+		{Date: reportDate("2026-01-20"), Entries: 3, Totals: Macros{Calories: 2000},
+			Micros: Micronutrients{FiberG: floatPtr(20), SodiumMg: floatPtr(3100)}},
+		{Date: reportDate("2026-01-21"), Entries: 1, Totals: Macros{Calories: 1500},
+			Micros: Micronutrients{FiberG: floatPtr(31), SodiumMg: floatPtr(1200), SugarG: floatPtr(0)}},
+	}
+
+	report := assembleReport(from, to, days, nil)
+
+	assert.Equal(t, floatPtr(25.5), report.Micronutrients.FiberG, "the unknown day is skipped, not averaged as 0")
+	assert.Equal(t, floatPtr(2150), report.Micronutrients.SodiumMg)
+	assert.Equal(t, floatPtr(0), report.Micronutrients.SugarG, "a recorded zero counts")
+	assert.Nil(t, report.Micronutrients.SaturatedFatG, "no day recorded it")
+
+	t.Run("excluded days are skipped", func(t *testing.T) {
+		days[1].Excluded = true
+		defer func() { days[1].Excluded = false }()
+
+		report := assembleReport(from, to, days, nil)
+
+		assert.Equal(t, floatPtr(31), report.Micronutrients.FiberG)
+	})
+}
+
+func TestSodiumWarnings(t *testing.T) {
+	days := []reportDay{
+		{Date: reportDate("2026-01-19"), Entries: 2},
+		{Date: reportDate("2026-01-20"), Entries: 3, Micros: Micronutrients{SodiumMg: floatPtr(3100)}},
+		{Date: reportDate("2026-01-21"), Entries: 1, Micros: Micronutrients{SodiumMg: floatPtr(2300)}},
+		{Date: reportDate("2026-01-22"), Entries: 1, Excluded: true, Micros: Micronutrients{SodiumMg: floatPtr(4000)}},
+	}
+
+	assert.Equal(t, []SodiumWarning{
+		{Date: "2026-01-20", SodiumMg: 3100, LimitMg: 2300},
+		{Date: "2026-01-22", SodiumMg: 4000, LimitMg: 2300},
+	}, sodiumWarnings(days, 2300), "the limit itself is fine")
+	assert.Equal(t, []SodiumWarning{}, sodiumWarnings(days, 0), "0 turns the warnings off")
+	assert.Equal(t, []SodiumWarning{}, sodiumWarnings(nil, 2300))
+}
+
+func TestReportService_GetReport(t *testing.T) {
+	service, mock := setupReportService(t)
+	mock.ExpectQuery("FROM generate_series").
+		WithArgs(testUserID, "2026-01-24", "2026-01-25").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns).
+			AddRow("2026-01-24", 2, 2100.0, 150.0, 200.0, 70.0, 2000.0, 150.0, 200.0, 66.7, nil, nil, nil, nil, nil).
+			AddRow("2026-01-25", 0, 0.0, 0.0, 0.0, 0.0, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+	mock.ExpectQuery("FROM nutrition_entries.+ORDER BY calories DESC").
+		WithArgs(testUserID, "2026-01-24", "2026-01-25", ReportTopEntries).
+		WillReturnRows(entryRows("Плов", 900))
+
+	report, err := service.GetReport(context.Background(), testUserID, testRange("2026-01-24", "2026-01-25"))
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Days)
+	assert.Equal(t, 1, report.DaysLogged)
+	assert.Equal(t, 100.0, *report.WithinTargetPercent)
+	require.Len(t, report.TopEntries, 1)
+	assert.Equal(t, "Плов", report.TopEntries[0].Food)
+	assert.Nil(t, report.Micronutrients.SodiumMg)
+	assert.Empty(t, report.SodiumWarnings)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportService_GetReportMicronutrients(t *testing.T) {
+	service, mock := setupReportService(t)
+	// A rollup leaves a nutrient NULL when none of the day's entries recorded it
+	mock.ExpectQuery("CASE WHEN r.sodium_mg_count > 0 THEN r.sodium_mg END").
+		WithArgs(testUserID, "2026-01-24", "2026-01-25").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns).
+			AddRow("2026-01-24", 2, 2100.0, 150.0, 200.0, 70.0, nil, nil, nil, nil, nil, 12.0, nil, 2900.0, nil).
+			AddRow("2026-01-25", 1, 1900.0, 120.0, 180.0, 60.0, nil, nil, nil, nil, nil, nil, nil, 1500.0, 9.5))
+	mock.ExpectQuery("ORDER BY calories DESC").WillReturnRows(sqlmock.NewRows(entryColumnNames))
+
+	report, err := service.GetReport(context.Background(), testUserID, testRange("2026-01-24", "2026-01-25"))
+
+	require.NoError(t, err)
+	assert.Equal(t, Micronutrients{FiberG: floatPtr(12), SodiumMg: floatPtr(2200), SaturatedFatG: floatPtr(9.5)}, report.Micronutrients)
+	assert.Equal(t, []SodiumWarning{{Date: "2026-01-24", SodiumMg: 2900, LimitMg: 2300}}, report.SodiumWarnings)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReportService_GetReportSkipsExcludedFlags(t *testing.T) {
+	service, mock := setupReportService(t)
+	mock.ExpectQuery("LEFT JOIN nutrition_day_flags f").
+		WithArgs(testUserID, "2026-01-23", "2026-01-25").
+		WillReturnRows(sqlmock.NewRows(reportDayColumns).
+			AddRow("2026-01-23", 2, 2000.0, 150.0, 200.0, 66.7, 2000.0, 150.0, 200.0, 66.7, nil, nil, nil, nil, nil).
+			AddRow("2026-01-24", 3, 3800.0, 150.0, 600.0, 70.0, 2000.0, 150.0, 200.0, 66.7, DayFlagRefeed, nil, nil, nil, nil).
+			// travel is not an excluded type here, so the day still counts
+			AddRow("2026-01-25", 1, 2600.0, 100.0, 300.0, 90.0, 2000.0, 150.0, 200.0, 66.7, DayFlagTravel, nil, nil, nil, nil))
+	mock.ExpectQuery("ORDER BY calories DESC").
+		WillReturnRows(sqlmock.NewRows(entryColumnNames))
+
+	report, err := service.GetReport(context.Background(), testUserID, testRange("2026-01-23", "2026-01-25"))
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, report.Days)
+	assert.Equal(t, 2, report.DaysLogged)
+	assert.Equal(t, 1, report.DaysExcluded)
+	assert.Equal(t, 2300.0, report.Average.Calories)
+	assert.Equal(t, 50.0, *report.WithinTargetPercent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReportHandler(t *testing.T) {
+	t.Run("empty range", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("FROM generate_series").
+			WithArgs(testUserID, "2026-01-26", "2026-01-26").
+			WillReturnRows(sqlmock.NewRows(reportDayColumns).
+				AddRow("2026-01-26", 0, 0.0, 0.0, 0.0, 0.0, nil, nil, nil, nil, nil, nil, nil, nil, nil))
+		mock.ExpectQuery("ORDER BY calories DESC").
+			WillReturnRows(sqlmock.NewRows(entryColumnNames))
+
+		status, resp := serve(t, handler.GetReport, testUserID, http.MethodGet, "/entries/?from=2026-01-26&to=2026-01-26", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, float64(1), data["days"])
+		assert.Equal(t, float64(0), data["days_logged"])
+		assert.Contains(t, data, "average")
+		assert.Nil(t, data["average"])
+		assert.Nil(t, data["within_target_percent"])
+		assert.Equal(t, []interface{}{}, data["top_entries"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("range too long", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.GetReport, testUserID, http.MethodGet, "/entries/?from=2025-01-01&to=2026-01-01", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, resp["details"].(map[string]interface{})["fields"], "to")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("missing and malformed dates", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.GetReport, testUserID, http.MethodGet, "/entries/?to=2026-01-26", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, map[string]interface{}{"from": "Обязательное поле"}, resp["details"].(map[string]interface{})["fields"])
+
+		status, resp = serve(t, handler.GetReport, testUserID, http.MethodGet, "/entries/?from=2026-01-01&to=26.01.2026", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, map[string]interface{}{"to": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"},
+			resp["details"].(map[string]interface{})["fields"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,51 @@
+package nutrition
+
+import (
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/response"
+)
+
+// EntriesResponse is a page of nutrition entries
+type EntriesResponse struct {
+	response.ListData[*Entry]
+}
+
+// EntryResponse is a single nutrition entry. Warning is set on a saved
+// entry whose macros do not add up to its calories.
+type EntryResponse struct {
+	Entry   *Entry `json:"entry"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// EntryPhotoResponse is the meal photo of an entry
+type EntryPhotoResponse struct {
+	Photo *EntryPhoto `json:"photo"`
+}
+
+// RevisionsResponse is the change history of an entry, newest first
+type RevisionsResponse struct {
+	response.ListData[*Revision]
+}
+
+// AddWaterResponse is the added water and the day's intake with it
+type AddWaterResponse struct {
+	Event *WaterEvent `json:"event"`
+	Day   *WaterDay   `json:"day"`
+}
+
+// DayFlagResponse is the flag of a day
+type DayFlagResponse struct {
+	Flag *DayFlag `json:"flag"`
+}
+
+// DayFlagsResponse is the flagged days of a range
+type DayFlagsResponse struct {
+	response.ListData[*DayFlag]
+}
+
+// ImportEntriesResponse is the outcome of a CSV import: how many entries
+// were created and why the other rows were not
+type ImportEntriesResponse struct {
+	Imported  int                  `json:"imported"`
+	RowErrors []csvimport.RowError `json:"row_errors"`
+}
@@ -0,0 +1,273 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// RollupReconcileInterval is how often the reconciliation job runs
+	RollupReconcileInterval = 24 * time.Hour
+	// RollupReconcileWindow is how far back the reconciliation job looks for
+	// touched days. It covers two runs, so a day a run raced with a write is
+	// recomputed again by the next one.
+	RollupReconcileWindow = 48 * time.Hour
+)
+
+// microSum is a day's sum of one micronutrient and the number of entries
+// that recorded it
+type microSum struct {
+	Sum     float64
+	Entries int
+}
+
+// add counts value, which is nil when the entry did not record it
+func (m *microSum) add(value *float64, sign float64) {
+	if value != nil {
+		m.Sum += sign * *value
+		m.Entries += int(sign)
+	}
+}
+
+// rollupDelta is a change of a day's rollup: what one write adds to it or,
+// negative, takes off it
+type rollupDelta struct {
+	Entries int
+	Totals  Macros
+
+	Fiber, Sugar, Sodium, SaturatedFat microSum
+}
+
+// entryDelta is what entry adds to its day; sign -1 takes it off
+func entryDelta(entry *Entry, sign float64) rollupDelta {
+	d := rollupDelta{
+		Entries: int(sign),
+		Totals: Macros{
+			Calories: sign * entry.Calories,
+			Protein:  sign * entry.Protein,
+			Carbs:    sign * entry.Carbs,
+			Fat:      sign * entry.Fat,
+		},
+	}
+	d.Fiber.add(entry.FiberG, sign)
+	d.Sugar.add(entry.SugarG, sign)
+	d.Sodium.add(entry.SodiumMg, sign)
+	d.SaturatedFat.add(entry.SaturatedFatG, sign)
+	return d
+}
+
+// plus returns the sum of two deltas of the same day
+func (d rollupDelta) plus(o rollupDelta) rollupDelta {
+	return rollupDelta{
+		Entries: d.Entries + o.Entries,
+		Totals: Macros{
+			Calories: d.Totals.Calories + o.Totals.Calories,
+			Protein:  d.Totals.Protein + o.Totals.Protein,
+			Carbs:    d.Totals.Carbs + o.Totals.Carbs,
+			Fat:      d.Totals.Fat + o.Totals.Fat,
+		},
+		Fiber:        microSum{d.Fiber.Sum + o.Fiber.Sum, d.Fiber.Entries + o.Fiber.Entries},
+		Sugar:        microSum{d.Sugar.Sum + o.Sugar.Sum, d.Sugar.Entries + o.Sugar.Entries},
+		Sodium:       microSum{d.Sodium.Sum + o.Sodium.Sum, d.Sodium.Entries + o.Sodium.Entries},
+		SaturatedFat: microSum{d.SaturatedFat.Sum + o.SaturatedFat.Sum, d.SaturatedFat.Entries + o.SaturatedFat.Entries},
+	}
+}
+
+// dayDelta is a rollup change of one day
+type dayDelta struct {
+	Date  string
+	Delta rollupDelta
+}
+
+// updateDeltas returns the rollup changes of an entry updated from old to
+// updated: one for an entry that stayed on its day, or one taking it off
+// the old day and one adding it to the new day for an entry that moved.
+// Days whose rollup does not change are left out. The days are in date
+// order, so concurrent moves between two days lock their rollups in the
+// same order.
+func updateDeltas(old, updated *Entry) []dayDelta {
+	if old.Date == updated.Date {
+		d := entryDelta(updated, 1).plus(entryDelta(old, -1))
+		if d == (rollupDelta{}) {
+			return nil
+		}
+		return []dayDelta{{Date: old.Date, Delta: d}}
+	}
+	deltas := []dayDelta{
+		{Date: old.Date, Delta: entryDelta(old, -1)},
+		{Date: updated.Date, Delta: entryDelta(updated, 1)},
+	}
+	if deltas[1].Date < deltas[0].Date {
+		deltas[0], deltas[1] = deltas[1], deltas[0]
+	}
+	return deltas
+}
+
+// adjustRollup adds delta to the user's rollup of date in the transaction
+// of the entry write, creating the rollup on the day's first entry. The
+// upsert locks the rollup row, so concurrent writes to a day add up.
+func (s *Service) adjustRollup(ctx context.Context, tx *sql.Tx, userID int64, date string, delta rollupDelta) error {
+	startTime := time.Now()
+	query := `
+		INSERT INTO nutrition_daily_rollups AS r (user_id, date, entry_count, calories, protein, carbs, fat,
+		                                          fiber_g, fiber_g_count, sugar_g, sugar_g_count,
+		                                          sodium_mg, sodium_mg_count, saturated_fat_g, saturated_fat_g_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (user_id, date) DO UPDATE SET
+			entry_count = r.entry_count + EXCLUDED.entry_count,
+			calories = r.calories + EXCLUDED.calories,
+			protein = r.protein + EXCLUDED.protein,
+			carbs = r.carbs + EXCLUDED.carbs,
+			fat = r.fat + EXCLUDED.fat,
+			fiber_g = r.fiber_g + EXCLUDED.fiber_g,
+			fiber_g_count = r.fiber_g_count + EXCLUDED.fiber_g_count,
+			sugar_g = r.sugar_g + EXCLUDED.sugar_g,
+			sugar_g_count = r.sugar_g_count + EXCLUDED.sugar_g_count,
+			sodium_mg = r.sodium_mg + EXCLUDED.sodium_mg,
+			sodium_mg_count = r.sodium_mg_count + EXCLUDED.sodium_mg_count,
+			saturated_fat_g = r.saturated_fat_g + EXCLUDED.saturated_fat_g,
+			saturated_fat_g_count = r.saturated_fat_g_count + EXCLUDED.saturated_fat_g_count,
+			updated_at = NOW()`
+
+	_, err := tx.ExecContext(ctx, query, userID, date, delta.Entries,
+		delta.Totals.Calories, delta.Totals.Protein, delta.Totals.Carbs, delta.Totals.Fat,
+		delta.Fiber.Sum, delta.Fiber.Entries, delta.Sugar.Sum, delta.Sugar.Entries,
+		delta.Sodium.Sum, delta.Sodium.Entries, delta.SaturatedFat.Sum, delta.SaturatedFat.Entries)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"date":    date,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to adjust daily rollup: %w", err)
+	}
+	return nil
+}
+
+// rollupSubscriber is the bus name of the rollup updater
+const rollupSubscriber = "nutrition_rollups"
+
+// updateRollups subscribes the rollups to entry events. It runs in the
+// transaction of the entry write, so a rollup never disagrees with a
+// committed entry.
+func (s *Service) updateRollups(ctx context.Context, tx *sql.Tx, event events.Event) error {
+	switch e := event.Data.(type) {
+	case EntryCreated:
+		return s.adjustRollup(ctx, tx, e.UserID, e.Date, entryDelta(e.Entry, 1))
+	case EntryUpdated:
+		for _, d := range updateDeltas(e.Previous, e.Entry) {
+			if err := s.adjustRollup(ctx, tx, e.UserID, d.Date, d.Delta); err != nil {
+				return err
+			}
+		}
+	case EntryDeleted:
+		return s.adjustRollup(ctx, tx, e.UserID, e.Date, entryDelta(e.Entry, -1))
+	}
+	return nil
+}
+
+// RollupService keeps the daily rollups in line with the entries
+type RollupService struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewRollupService creates a new rollup reconciliation service
+func NewRollupService(db *database.DB, log *logger.Logger) *RollupService {
+	return &RollupService{db: db, log: log}
+}
+
+// Reconcile recomputes from the entries every day touched within window:
+// days with an entry created or updated since, and days whose rollup a
+// write adjusted since, which covers deletes. It returns how many rollups
+// were wrong or missing.
+//
+// A write committing while a day is recomputed may be left out of it; the
+// day stays touched, so the next run corrects it.
+func (s *RollupService) Reconcile(ctx context.Context, window time.Duration) (int64, error) {
+	startTime := time.Now()
+	query := `
+		WITH touched AS (
+			SELECT user_id, date FROM nutrition_entries WHERE updated_at > NOW() - make_interval(secs => $1)
+			UNION
+			SELECT user_id, date FROM nutrition_daily_rollups WHERE updated_at > NOW() - make_interval(secs => $1)
+		)
+		INSERT INTO nutrition_daily_rollups AS r (user_id, date, entry_count, calories, protein, carbs, fat,
+		                                          fiber_g, fiber_g_count, sugar_g, sugar_g_count,
+		                                          sodium_mg, sodium_mg_count, saturated_fat_g, saturated_fat_g_count)
+		SELECT t.user_id, t.date, COUNT(e.id),
+		       COALESCE(SUM(e.calories), 0), COALESCE(SUM(e.protein), 0), COALESCE(SUM(e.carbs), 0), COALESCE(SUM(e.fat), 0),
+		       COALESCE(SUM(e.fiber_g), 0), COUNT(e.fiber_g), COALESCE(SUM(e.sugar_g), 0), COUNT(e.sugar_g),
+		       COALESCE(SUM(e.sodium_mg), 0), COUNT(e.sodium_mg), COALESCE(SUM(e.saturated_fat_g), 0), COUNT(e.saturated_fat_g)
+		FROM touched t
+		LEFT JOIN nutrition_entries e ON e.user_id = t.user_id AND e.date = t.date
+		GROUP BY t.user_id, t.date
+		ON CONFLICT (user_id, date) DO UPDATE SET
+			entry_count = EXCLUDED.entry_count,
+			calories = EXCLUDED.calories,
+			protein = EXCLUDED.protein,
+			carbs = EXCLUDED.carbs,
+			fat = EXCLUDED.fat,
+			fiber_g = EXCLUDED.fiber_g,
+			fiber_g_count = EXCLUDED.fiber_g_count,
+			sugar_g = EXCLUDED.sugar_g,
+			sugar_g_count = EXCLUDED.sugar_g_count,
+			sodium_mg = EXCLUDED.sodium_mg,
+			sodium_mg_count = EXCLUDED.sodium_mg_count,
+			saturated_fat_g = EXCLUDED.saturated_fat_g,
+			saturated_fat_g_count = EXCLUDED.saturated_fat_g_count
+		WHERE (r.entry_count, r.calories, r.protein, r.carbs, r.fat,
+		       r.fiber_g, r.fiber_g_count, r.sugar_g, r.sugar_g_count,
+		       r.sodium_mg, r.sodium_mg_count, r.saturated_fat_g, r.saturated_fat_g_count)
+		      IS DISTINCT FROM
+		      (EXCLUDED.entry_count, EXCLUDED.calories, EXCLUDED.protein, EXCLUDED.carbs, EXCLUDED.fat,
+		       EXCLUDED.fiber_g, EXCLUDED.fiber_g_count, EXCLUDED.sugar_g, EXCLUDED.sugar_g_count,
+		       EXCLUDED.sodium_mg, EXCLUDED.sodium_mg_count, EXCLUDED.saturated_fat_g, EXCLUDED.saturated_fat_g_count)`
+
+	result, err := s.db.ExecContext(ctx, query, window.Seconds())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reconcile daily rollups: %w", err)
+	}
+	fixed, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reconciled rollups: %w", err)
+	}
+	return fixed, nil
+}
+
+// RunReconciliation reconciles the rollups touched within
+// RollupReconcileWindow at start and then every RollupReconcileInterval
+// until ctx is cancelled. Corrected rollups mean a write skipped its
+// delta, so they are logged as a warning.
+func (s *RollupService) RunReconciliation(ctx context.Context) {
+	ticker := time.NewTicker(RollupReconcileInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Nutrition rollup reconciliation started")
+
+	run := func() {
+		fixed, err := s.Reconcile(ctx, RollupReconcileWindow)
+		if err != nil {
+			s.log.Error("Failed to reconcile nutrition rollups", "error", err)
+		} else if fixed > 0 {
+			s.log.Warn("Corrected drifted nutrition rollups", "count", fixed)
+		}
+	}
+
+	run()
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case <-ctx.Done():
+			s.log.Info("Nutrition rollup reconciliation stopped")
+			return
+		}
+	}
+}
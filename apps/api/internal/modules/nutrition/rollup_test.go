@@ -0,0 +1,228 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectRollup expects an entry write to adjust a daily rollup
+func expectRollup(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("INSERT INTO nutrition_daily_rollups").WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectRollupDelta expects the rollup of date to be adjusted by exactly
+// entries, the macros and the micronutrient sums and counts
+func expectRollupDelta(mock sqlmock.Sqlmock, date string, entries int, m Macros, micros ...driver.Value) {
+	if micros == nil {
+		micros = []driver.Value{0.0, 0, 0.0, 0, 0.0, 0, 0.0, 0}
+	}
+	args := append([]driver.Value{testUserID, date, entries, m.Calories, m.Protein, m.Carbs, m.Fat}, micros...)
+	mock.ExpectExec("INSERT INTO nutrition_daily_rollups").
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func testEntry(date string, calories, protein float64, fiber *float64) *Entry {
+	return &Entry{Date: date, Calories: calories, Protein: protein, Carbs: 10, Fat: 5, Micronutrients: Micronutrients{FiberG: fiber}}
+}
+
+func TestUpdateDeltas(t *testing.T) {
+	t.Run("same day changes by the difference", func(t *testing.T) {
+		deltas := updateDeltas(
+			testEntry("2026-01-26", 300, 20, floatPtr(4)),
+			testEntry("2026-01-26", 250, 25, nil))
+
+		require.Len(t, deltas, 1)
+		assert.Equal(t, "2026-01-26", deltas[0].Date)
+		assert.Equal(t, rollupDelta{
+			Totals: Macros{Calories: -50, Protein: 5},
+			Fiber:  microSum{Sum: -4, Entries: -1},
+		}, deltas[0].Delta)
+	})
+
+	t.Run("same day without a change in numbers", func(t *testing.T) {
+		old := testEntry("2026-01-26", 300, 20, floatPtr(4))
+		updated := *old
+		updated.Meal, updated.Food = MealDinner, "Гречка"
+
+		assert.Empty(t, updateDeltas(old, &updated))
+	})
+
+	t.Run("moving to a later day", func(t *testing.T) {
+		deltas := updateDeltas(
+			testEntry("2026-01-26", 300, 20, floatPtr(4)),
+			testEntry("2026-01-27", 250, 20, floatPtr(4)))
+
+		assert.Equal(t, []dayDelta{
+			{Date: "2026-01-26", Delta: rollupDelta{
+				Entries: -1,
+				Totals:  Macros{Calories: -300, Protein: -20, Carbs: -10, Fat: -5},
+				Fiber:   microSum{Sum: -4, Entries: -1},
+			}},
+			{Date: "2026-01-27", Delta: rollupDelta{
+				Entries: 1,
+				Totals:  Macros{Calories: 250, Protein: 20, Carbs: 10, Fat: 5},
+				Fiber:   microSum{Sum: 4, Entries: 1},
+			}},
+		}, deltas)
+	})
+
+	t.Run("moving to an earlier day keeps date order", func(t *testing.T) {
+		deltas := updateDeltas(
+			testEntry("2026-01-26", 300, 20, nil),
+			testEntry("2026-01-20", 300, 20, nil))
+
+		require.Len(t, deltas, 2)
+		assert.Equal(t, "2026-01-20", deltas[0].Date)
+		assert.Equal(t, 1, deltas[0].Delta.Entries)
+		assert.Equal(t, 300.0, deltas[0].Delta.Totals.Calories)
+		assert.Equal(t, "2026-01-26", deltas[1].Date)
+		assert.Equal(t, -1, deltas[1].Delta.Entries)
+		assert.Equal(t, -300.0, deltas[1].Delta.Totals.Calories)
+	})
+
+	t.Run("a delta and its reverse cancel out", func(t *testing.T) {
+		entry := testEntry("2026-01-26", 582.3, 31.7, floatPtr(2.9))
+		entry.SodiumMg = floatPtr(1200)
+
+		assert.Equal(t, rollupDelta{}, entryDelta(entry, 1).plus(entryDelta(entry, -1)))
+	})
+}
+
+func TestService_Rollups(t *testing.T) {
+	moved := func(date string) *sqlmock.Rows {
+		return sqlmock.NewRows(entryColumnNames).
+			AddRow(testEntryID, testUserID, date, MealBreakfast, "Овсянка", 180.0, 6.0, 30.0, 4.0, testNow, testNow, nil, nil, 2, 3.5, nil, nil, nil)
+	}
+	updateReq := func(date string) *CreateEntryRequest {
+		return &CreateEntryRequest{Date: testDate(date), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(180), Protein: 6, Carbs: 30, Fat: 4}
+	}
+
+	t.Run("create adds the entry to its day", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollupDelta(mock, "2026-01-26", 1, Macros{Calories: 150, Protein: 5, Carbs: 27, Fat: 3})
+		mock.ExpectCommit()
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(150), Protein: 5, Carbs: 27, Fat: 3,
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update on the same day adjusts by the difference", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(moved("2026-01-26"))
+		expectRollupDelta(mock, "2026-01-26", 0, Macros{Calories: 30, Protein: 1, Carbs: 3, Fat: 1},
+			3.5, 1, 0.0, 0, 0.0, 0, 0.0, 0)
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, updateReq("2026-01-26"), nil)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update moving the entry adjusts both days", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(moved("2026-01-24"))
+		expectRollupDelta(mock, "2026-01-24", 1, Macros{Calories: 180, Protein: 6, Carbs: 30, Fat: 4},
+			3.5, 1, 0.0, 0, 0.0, 0, 0.0, 0)
+		expectRollupDelta(mock, "2026-01-26", -1, Macros{Calories: -150, Protein: -5, Carbs: -27, Fat: -3})
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, updateReq("2026-01-24"), nil)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update without a change in numbers leaves the rollup alone", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(entryRows("Каша", 150))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealBreakfast, Food: "Каша", Calories: floatPtr(150), Protein: 5, Carbs: 27, Fat: 3,
+		}, nil)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete takes the entry off its day", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(moved("2026-01-26"))
+		expectRollupDelta(mock, "2026-01-26", -1, Macros{Calories: -180, Protein: -6, Carbs: -30, Fat: -4},
+			-3.5, -1, 0.0, 0, 0.0, 0, 0.0, 0)
+		mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed rollup fails the write", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(moved("2026-01-26"))
+		mock.ExpectExec("INSERT INTO nutrition_daily_rollups").WillReturnError(errors.New("deadlock detected"))
+		mock.ExpectRollback()
+
+		err := service.DeleteEntry(context.Background(), testUserID, testEntryID)
+
+		assert.ErrorContains(t, err, "failed to adjust daily rollup")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestRollupService_Reconcile(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+	service := NewRollupService(&database.DB{DB: mockDB}, logger.New())
+
+	t.Run("recomputes the touched days", func(t *testing.T) {
+		mock.ExpectExec("WITH touched AS (.+) INSERT INTO nutrition_daily_rollups (.+) IS DISTINCT FROM").
+			WithArgs(RollupReconcileWindow.Seconds()).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		fixed, err := service.Reconcile(context.Background(), RollupReconcileWindow)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), fixed)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		mock.ExpectExec("WITH touched AS").WillReturnError(errors.New("connection reset"))
+
+		_, err := service.Reconcile(context.Background(), RollupReconcileWindow)
+
+		assert.ErrorContains(t, err, "failed to reconcile daily rollups")
+	})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,35 @@
+package nutrition
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes registers the entry, entry CSV, entry photo, search, water,
+// missing meal, day flag and report routes on r, which must already require
+// authentication. heavy runs before the report to cap concurrent reports.
+func RegisterRoutes(r *gin.RouterGroup, h *Handler, heavy gin.HandlerFunc) {
+	r.GET("/entries", h.GetEntries)
+	r.POST("/entries", h.CreateEntry)
+	r.GET("/entries/export", h.ExportEntries)
+	r.POST("/entries/import", h.ImportEntries)
+	r.GET("/entries/:id", h.GetEntry)
+	r.PUT("/entries/:id", h.UpdateEntry)
+	r.DELETE("/entries/:id", h.DeleteEntry)
+	r.GET("/entries/:id/history", h.GetEntryHistory)
+	r.POST("/entries/:id/photo", h.UploadEntryPhoto)
+	r.GET("/entries/:id/photo", h.GetEntryPhoto)
+	r.GET("/search", h.SearchEntries)
+	r.POST("/water", h.AddWater)
+	r.GET("/water", h.GetWater)
+	r.DELETE("/water/:id", h.DeleteWater)
+	r.GET("/missing", h.GetMissingMeals)
+	r.GET("/days", h.ListDays)
+	r.POST("/days/:date/flag", h.FlagDay)
+	r.DELETE("/days/:date/flag", h.UnflagDay)
+	r.GET("/report", heavy, h.GetReport)
+}
+
+// RegisterScheduleRoutes registers the meal schedule routes, which live
+// under /users, on r
+func RegisterScheduleRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/meal-schedule", h.GetMealSchedule)
+	r.PUT("/meal-schedule", h.UpdateMealSchedule)
+}
@@ -0,0 +1,232 @@
+package nutrition
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// mealTimeLayout is the HH:MM format of scheduled meal times
+const mealTimeLayout = "15:04"
+
+// MealSchedule holds the preferred time of each meal as HH:MM in the user's
+// timezone. A nil time means no reminder for that meal.
+type MealSchedule struct {
+	Breakfast *string `json:"breakfast"`
+	Lunch     *string `json:"lunch"`
+	Dinner    *string `json:"dinner"`
+	Snack     *string `json:"snack"`
+}
+
+// mealSlot points at the time field of one meal in a MealSchedule
+type mealSlot struct {
+	meal string
+	at   **string
+}
+
+// slots returns the meals of s with their time fields
+func (s *MealSchedule) slots() []mealSlot {
+	return []mealSlot{
+		{MealBreakfast, &s.Breakfast},
+		{MealLunch, &s.Lunch},
+		{MealDinner, &s.Dinner},
+		{MealSnack, &s.Snack},
+	}
+}
+
+// OverdueMeal is a scheduled meal whose time has passed without an entry
+type OverdueMeal struct {
+	Meal  string    `json:"meal"`
+	Time  string    `json:"time"`
+	DueAt time.Time `json:"due_at"`
+}
+
+// MissingMeals lists the overdue meals of a day
+type MissingMeals struct {
+	Date     string        `json:"date"`
+	Timezone string        `json:"timezone"`
+	Overdue  []OverdueMeal `json:"overdue"`
+}
+
+// ScheduleService stores meal schedules and checks them against the log
+type ScheduleService struct {
+	db  *database.DB
+	log *logger.Logger
+	now func() time.Time
+}
+
+// NewScheduleService creates a new meal schedule service
+func NewScheduleService(db *database.DB, log *logger.Logger) *ScheduleService {
+	return &ScheduleService{db: db, log: log, now: time.Now}
+}
+
+// validateSchedule checks that every time is HH:MM and that no two meals
+// share a time, trimming the times in place. The meals may come in any order.
+func validateSchedule(schedule *MealSchedule) error {
+	errs := validation.Errors{}
+	mealAt := map[string]string{} // time → meal
+
+	for _, slot := range schedule.slots() {
+		if *slot.at == nil {
+			continue
+		}
+		at := strings.TrimSpace(**slot.at)
+		if _, err := time.Parse(mealTimeLayout, at); err != nil || len(at) != len(mealTimeLayout) {
+			errs[slot.meal] = "Неверный формат времени, ожидается ЧЧ:ММ"
+			continue
+		}
+		*slot.at = &at
+		if other, taken := mealAt[at]; taken {
+			errs[slot.meal] = fmt.Sprintf("Время совпадает с %s", other)
+			continue
+		}
+		mealAt[at] = slot.meal
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// overdueMeals returns the meals of schedule that are due on date (wall-clock
+// time in loc) before now and have no entry in logged, earliest first.
+// A meal due exactly at now is not overdue yet.
+func overdueMeals(schedule *MealSchedule, logged map[string]bool, date time.Time, loc *time.Location, now time.Time) []OverdueMeal {
+	overdue := []OverdueMeal{}
+	for _, slot := range schedule.slots() {
+		if *slot.at == nil || logged[slot.meal] {
+			continue
+		}
+		at, err := time.Parse(mealTimeLayout, **slot.at)
+		if err != nil {
+			continue
+		}
+		dueAt := time.Date(date.Year(), date.Month(), date.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+		if !now.After(dueAt) {
+			continue
+		}
+		overdue = append(overdue, OverdueMeal{Meal: slot.meal, Time: **slot.at, DueAt: dueAt})
+	}
+
+	sort.SliceStable(overdue, func(i, j int) bool { return overdue[i].DueAt.Before(overdue[j].DueAt) })
+	return overdue
+}
+
+// GetSchedule returns the user's meal schedule; meals without a time are nil
+func (s *ScheduleService) GetSchedule(ctx context.Context, userID int64) (*MealSchedule, error) {
+	startTime := time.Now()
+	query := `SELECT meal, to_char(time_of_day, 'HH24:MI') FROM meal_schedules WHERE user_id = $1`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query meal schedule: %w", err)
+	}
+	defer rows.Close()
+
+	schedule := &MealSchedule{}
+	slots := map[string]**string{}
+	for _, slot := range schedule.slots() {
+		slots[slot.meal] = slot.at
+	}
+	for rows.Next() {
+		var meal, at string
+		if err := rows.Scan(&meal, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan meal schedule: %w", err)
+		}
+		if field, ok := slots[meal]; ok {
+			*field = &at
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating meal schedule: %w", err)
+	}
+
+	return schedule, nil
+}
+
+// UpdateSchedule replaces the user's meal schedule. Invalid input is reported
+// as validation.Errors.
+func (s *ScheduleService) UpdateSchedule(ctx context.Context, userID int64, schedule *MealSchedule) (*MealSchedule, error) {
+	if err := validateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM meal_schedules WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to clear meal schedule: %w", err)
+		}
+		for _, slot := range schedule.slots() {
+			if *slot.at == nil {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO meal_schedules (user_id, meal, time_of_day) VALUES ($1, $2, $3)`,
+				userID, slot.meal, **slot.at); err != nil {
+				return fmt.Errorf("failed to save meal schedule: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.LogBusinessEvent("meal_schedule_updated", map[string]interface{}{
+		"user_id": userID,
+	})
+	return schedule, nil
+}
+
+// MissingMeals returns the scheduled meals of date that are overdue in loc
+// and have not been logged. Dates in the future have none.
+func (s *ScheduleService) MissingMeals(ctx context.Context, userID int64, date types.Date, loc *time.Location) (*MissingMeals, error) {
+	schedule, err := s.GetSchedule(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `SELECT DISTINCT meal FROM nutrition_entries WHERE user_id = $1 AND date = $2`
+	rows, err := s.db.QueryContext(ctx, query, userID, date)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"date":    date.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logged meals: %w", err)
+	}
+	defer rows.Close()
+
+	logged := map[string]bool{}
+	for rows.Next() {
+		var meal string
+		if err := rows.Scan(&meal); err != nil {
+			return nil, fmt.Errorf("failed to scan logged meal: %w", err)
+		}
+		// Entries logged before meal names were normalized may use aliases
+		if canonical, ok := normalizeMeal(meal); ok {
+			logged[canonical] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating logged meals: %w", err)
+	}
+
+	return &MissingMeals{
+		Date:     date.String(),
+		Timezone: loc.String(),
+		Overdue:  overdueMeals(schedule, logged, date.Time(), loc, s.now()),
+	}, nil
+}
@@ -0,0 +1,184 @@
+package nutrition
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scheduleRows(times map[string]string) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"meal", "time_of_day"})
+	for _, meal := range []string{MealBreakfast, MealLunch, MealDinner, MealSnack} {
+		if at, ok := times[meal]; ok {
+			rows.AddRow(meal, at)
+		}
+	}
+	return rows
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule MealSchedule
+		fields   []string
+	}{
+		{"empty", MealSchedule{}, nil},
+		{"full day", MealSchedule{Breakfast: strPtr("08:00"), Lunch: strPtr("13:00"), Dinner: strPtr("19:30"), Snack: strPtr("16:00")}, nil},
+		{"dinner before breakfast", MealSchedule{Breakfast: strPtr("21:00"), Dinner: strPtr("06:00")}, nil},
+		{"midnight snack", MealSchedule{Snack: strPtr("00:00")}, nil},
+		{"single digit hour", MealSchedule{Breakfast: strPtr("8:00")}, []string{"breakfast"}},
+		{"hour out of range", MealSchedule{Lunch: strPtr("24:00")}, []string{"lunch"}},
+		{"seconds", MealSchedule{Lunch: strPtr("13:00:00")}, []string{"lunch"}},
+		{"not a time", MealSchedule{Dinner: strPtr("вечером")}, []string{"dinner"}},
+		{"duplicate time", MealSchedule{Lunch: strPtr("13:00"), Snack: strPtr(" 13:00 ")}, []string{"snack"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSchedule(&tt.schedule)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+}
+
+func TestOverdueMeals(t *testing.T) {
+	vladivostok, err := time.LoadLocation("Asia/Vladivostok") // UTC+10
+	require.NoError(t, err)
+	schedule := &MealSchedule{Breakfast: strPtr("08:00"), Lunch: strPtr("13:00"), Dinner: strPtr("19:00"), Snack: strPtr("23:30")}
+	day := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	local := func(hour, minute int) time.Time {
+		return time.Date(2026, 2, 1, hour, minute, 0, 0, vladivostok)
+	}
+
+	tests := []struct {
+		name   string
+		date   time.Time
+		logged map[string]bool
+		now    time.Time
+		want   []string
+	}{
+		{"before the first meal", day, nil, local(7, 59), []string{}},
+		{"due exactly now", day, nil, local(8, 0), []string{}},
+		{"a minute late", day, nil, local(8, 1), []string{MealBreakfast}},
+		{"logged meals are skipped", day, map[string]bool{MealBreakfast: true}, local(14, 0), []string{MealLunch}},
+		{"late evening", day, map[string]bool{MealLunch: true}, local(23, 45), []string{MealBreakfast, MealDinner, MealSnack}},
+		// 14:10 UTC on Feb 1 is already 00:10 on Feb 2 in Vladivostok
+		{"past midnight the whole day is overdue", day, nil, time.Date(2026, 2, 1, 14, 10, 0, 0, time.UTC), []string{MealBreakfast, MealLunch, MealDinner, MealSnack}},
+		{"the new day starts empty", day.AddDate(0, 0, 1), nil, time.Date(2026, 2, 1, 14, 10, 0, 0, time.UTC), []string{}},
+		// 22:30 UTC on Feb 1 is 08:30 on Feb 2 in Vladivostok
+		{"next morning in the user's timezone", day.AddDate(0, 0, 1), nil, time.Date(2026, 2, 1, 22, 30, 0, 0, time.UTC), []string{MealBreakfast}},
+		{"future day", day.AddDate(0, 0, 2), nil, local(23, 45), []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			overdue := overdueMeals(schedule, tt.logged, tt.date, vladivostok, tt.now)
+
+			meals := []string{}
+			for _, m := range overdue {
+				meals = append(meals, m.Meal)
+			}
+			assert.Equal(t, tt.want, meals)
+		})
+	}
+
+	t.Run("sorted by time with due instant", func(t *testing.T) {
+		early := &MealSchedule{Breakfast: strPtr("09:00"), Snack: strPtr("06:15")}
+
+		overdue := overdueMeals(early, nil, day, vladivostok, local(12, 0))
+
+		require.Len(t, overdue, 2)
+		assert.Equal(t, OverdueMeal{Meal: MealSnack, Time: "06:15", DueAt: local(6, 15)}, overdue[0])
+		assert.Equal(t, MealBreakfast, overdue[1].Meal)
+		assert.True(t, overdue[0].DueAt.Equal(time.Date(2026, 1, 31, 20, 15, 0, 0, time.UTC)))
+	})
+}
+
+func TestMealScheduleHandlers(t *testing.T) {
+	t.Run("get", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("SELECT meal, to_char\\(time_of_day, 'HH24:MI'\\) FROM meal_schedules").
+			WithArgs(testUserID).
+			WillReturnRows(scheduleRows(map[string]string{MealLunch: "13:00"}))
+
+		status, resp := serve(t, handler.GetMealSchedule, testUserID, http.MethodGet, "/entries/", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, map[string]interface{}{"breakfast": nil, "lunch": "13:00", "dinner": nil, "snack": nil}, resp["data"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update replaces the schedule", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM meal_schedules").WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("INSERT INTO meal_schedules").WithArgs(testUserID, MealBreakfast, "08:00").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO meal_schedules").WithArgs(testUserID, MealLunch, "13:00").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		status, resp := serve(t, handler.UpdateMealSchedule, testUserID, http.MethodPut, "/entries/",
+			`{"breakfast":"08:00","lunch":" 13:00","dinner":null}`)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "13:00", resp["data"].(map[string]interface{})["lunch"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("update rejects duplicates", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.UpdateMealSchedule, testUserID, http.MethodPut, "/entries/",
+			`{"breakfast":"08:00","snack":"08:00"}`)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetMissingMealsHandler(t *testing.T) {
+	t.Run("today in the requested timezone", func(t *testing.T) {
+		// testNow is 12:00 UTC, 22:00 in Vladivostok
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("FROM meal_schedules").
+			WithArgs(testUserID).
+			WillReturnRows(scheduleRows(map[string]string{MealBreakfast: "08:00", MealLunch: "13:00", MealDinner: "19:00", MealSnack: "23:00"}))
+		mock.ExpectQuery("SELECT DISTINCT meal FROM nutrition_entries").
+			WithArgs(testUserID, "2026-02-01").
+			WillReturnRows(sqlmock.NewRows([]string{"meal"}).AddRow("завтрак").AddRow(MealDinner))
+
+		status, resp := serve(t, handler.GetMissingMeals, testUserID, http.MethodGet, "/entries/?tz=Asia/Vladivostok", "")
+
+		require.Equal(t, http.StatusOK, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, "2026-02-01", data["date"])
+		assert.Equal(t, "Asia/Vladivostok", data["timezone"])
+		overdue := data["overdue"].([]interface{})
+		require.Len(t, overdue, 1)
+		assert.Equal(t, MealLunch, overdue[0].(map[string]interface{})["meal"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("bad date", func(t *testing.T) {
+		handler, _ := setupTestHandler(t)
+
+		status, _ := serve(t, handler.GetMissingMeals, testUserID, http.MethodGet, "/entries/?date=today&tz=UTC", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -0,0 +1,207 @@
+package nutrition
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Search limits
+const (
+	// MaxSearchEntries caps the matching entries a search looks at
+	MaxSearchEntries = 200
+	// SearchRecentEntries is how many of the latest matches a search lists
+	SearchRecentEntries = 10
+	// MaxSearchQueryLength bounds q, in characters after normalization
+	MaxSearchQueryLength = 100
+)
+
+// FoodMatch is one food name found by a search. Food is the spelling of the
+// latest entry; AverageCalories is rounded to 0.1 kcal.
+type FoodMatch struct {
+	Food            string  `json:"food"`
+	Count           int     `json:"count"`
+	AverageCalories float64 `json:"average_calories"`
+	LastLogged      string  `json:"last_logged"`
+}
+
+// SearchResult is what a search of the user's history found. Foods are the
+// matches grouped by normalized food name, most frequent first; Truncated
+// is set when more than MaxSearchEntries entries matched and only the latest
+// ones were grouped.
+type SearchResult struct {
+	Query     string      `json:"query"`
+	Foods     []FoodMatch `json:"foods"`
+	Recent    []*Entry    `json:"recent"`
+	Truncated bool        `json:"truncated"`
+}
+
+// SearchService searches the food names of a user's entries
+type SearchService struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewSearchService creates a new nutrition history search service
+func NewSearchService(db *database.DB, log *logger.Logger) *SearchService {
+	return &SearchService{db: db, log: log}
+}
+
+// normalizeFood folds a food name for search: lowercased, ё written as е,
+// accents dropped from other letters and whitespace collapsed. й keeps its
+// breve, being a letter of its own. It fills the food_search column and is
+// applied to the query, so both sides compare alike.
+func normalizeFood(food string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(norm.NFC.String(food)) {
+		switch r {
+		case 'ё':
+			r = 'е'
+		case 'й':
+		default:
+			// The base letter of a precomposed one, e.g. é to e
+			if d := norm.NFD.String(string(r)); d != string(r) {
+				r, _ = utf8.DecodeRuneInString(d)
+			}
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// escapeLike escapes the LIKE wildcards in s, so they match literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// validateSearch checks the search parameters and returns the normalized
+// query. Either end of dates may be left open.
+func validateSearch(q string, dates types.DateRange) (string, error) {
+	errs := validation.Errors{}
+
+	query := normalizeFood(q)
+	switch {
+	case query == "":
+		errs["q"] = "Обязательное поле"
+	case utf8.RuneCountInString(query) > MaxSearchQueryLength:
+		errs["q"] = fmt.Sprintf("Не более %d символов", MaxSearchQueryLength)
+	}
+
+	if !dates.To.IsZero() && dates.To.Before(dates.From) {
+		errs["to"] = "Дата окончания раньше даты начала"
+	}
+
+	if len(errs) > 0 {
+		return "", errs
+	}
+	return query, nil
+}
+
+// Search finds the user's entries whose food name contains q, ignoring case,
+// ё and accents, optionally within dates. Invalid input is reported as
+// validation.Errors.
+func (s *SearchService) Search(ctx context.Context, userID int64, q string, dates types.DateRange) (*SearchResult, error) {
+	query, err := validateSearch(q, dates)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"user_id = $1", "food_search ILIKE $2"}
+	args := []any{userID, "%" + escapeLike(query) + "%"}
+	if !dates.From.IsZero() {
+		args = append(args, dates.From)
+		conditions = append(conditions, fmt.Sprintf("date >= $%d", len(args)))
+	}
+	if !dates.To.IsZero() {
+		args = append(args, dates.To)
+		conditions = append(conditions, fmt.Sprintf("date <= $%d", len(args)))
+	}
+	// One more than the cap tells whether the results were truncated
+	args = append(args, MaxSearchEntries+1)
+
+	startTime := time.Now()
+	sqlQuery := `
+		SELECT ` + entryColumns + `
+		FROM nutrition_entries
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY date DESC, created_at DESC, id DESC
+		LIMIT $` + fmt.Sprint(len(args))
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	s.log.LogDatabaseQuery(sqlQuery, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"from":    dates.From.String(),
+		"to":      dates.To.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*Entry, 0)
+	for rows.Next() {
+		entry, err := scanEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	result := &SearchResult{Query: query}
+	if len(entries) > MaxSearchEntries {
+		entries = entries[:MaxSearchEntries]
+		result.Truncated = true
+	}
+	result.Foods = groupByFood(entries)
+	result.Recent = entries[:min(len(entries), SearchRecentEntries)]
+	return result, nil
+}
+
+// groupByFood groups entries, latest first, by normalized food name. Groups
+// are ordered by count, then by the last logged date, latest first.
+func groupByFood(entries []*Entry) []FoodMatch {
+	type group struct {
+		match    FoodMatch
+		calories float64
+	}
+	groups := make(map[string]*group)
+	order := make([]*group, 0)
+	for _, e := range entries {
+		key := normalizeFood(e.Food)
+		g, ok := groups[key]
+		if !ok {
+			// The first entry of a group is its latest one
+			g = &group{match: FoodMatch{Food: e.Food, LastLogged: e.Date}}
+			groups[key] = g
+			order = append(order, g)
+		}
+		g.match.Count++
+		g.calories += e.Calories
+	}
+
+	foods := make([]FoodMatch, 0, len(order))
+	for _, g := range order {
+		g.match.AverageCalories = round1(g.calories / float64(g.match.Count))
+		foods = append(foods, g.match)
+	}
+	// Stable, so groups of equal count and date keep their latest-first order
+	sort.SliceStable(foods, func(i, j int) bool {
+		if foods[i].Count != foods[j].Count {
+			return foods[i].Count > foods[j].Count
+		}
+		return foods[i].LastLogged > foods[j].LastLogged
+	})
+	return foods
+}
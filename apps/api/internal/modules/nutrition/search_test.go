@@ -0,0 +1,189 @@
+package nutrition
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupSearchService(t *testing.T) (*SearchService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewSearchService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+func TestNormalizeFood(t *testing.T) {
+	tests := []struct {
+		name, food, want string
+	}{
+		{"lowercase", "Борщ", "борщ"},
+		{"ё as е", "Свёкла тушёная", "свекла тушеная"},
+		{"capital Ё", "ЁЖИКИ", "ежики"},
+		{"й is kept", "Чай зелёный", "чай зеленый"},
+		{"latin accents", "Crème Brûlée", "creme brulee"},
+		{"decomposed accents", "Café", "cafe"},
+		{"whitespace", "  борщ \t со   сметаной ", "борщ со сметаной"},
+		{"empty", "   ", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeFood(tt.food))
+		})
+	}
+}
+
+func TestValidateSearch(t *testing.T) {
+	tests := []struct {
+		name          string
+		q, from, to   string
+		wantQuery     string
+		wantErrFields []string
+	}{
+		{name: "query only", q: " Борщ ", wantQuery: "борщ"},
+		{name: "range", q: "борщ", from: "2026-01-01", to: "2026-01-31", wantQuery: "борщ"},
+		{name: "open range", q: "борщ", to: "2026-01-31", wantQuery: "борщ"},
+		{name: "empty query", q: "  ", wantErrFields: []string{"q"}},
+		{name: "long query", q: strings.Repeat("щ", MaxSearchQueryLength+1), wantErrFields: []string{"q"}},
+		{name: "reversed", q: "борщ", from: "2026-01-31", to: "2026-01-01", wantErrFields: []string{"to"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := validateSearch(tt.q, testRange(tt.from, tt.to))
+			if tt.wantErrFields == nil {
+				require.NoError(t, err)
+				assert.Equal(t, tt.wantQuery, query)
+				return
+			}
+			var errs validation.Errors
+			require.ErrorAs(t, err, &errs)
+			assert.Len(t, errs, len(tt.wantErrFields))
+			for _, field := range tt.wantErrFields {
+				assert.Contains(t, errs, field)
+			}
+		})
+	}
+}
+
+func TestGroupByFood(t *testing.T) {
+	// Latest first, as the search returns them
+	entries := []*Entry{
+		{Food: "Борщ со сметаной", Date: "2026-01-30", Calories: 400},
+		{Food: "Борщ", Date: "2026-01-28", Calories: 300},
+		{Food: "борщ  со сметаной", Date: "2026-01-20", Calories: 350},
+		{Food: "Борщ", Date: "2026-01-15", Calories: 250},
+		{Food: "Борщ", Date: "2026-01-10", Calories: 251},
+	}
+
+	foods := groupByFood(entries)
+
+	assert.Equal(t, []FoodMatch{
+		{Food: "Борщ", Count: 3, AverageCalories: 267, LastLogged: "2026-01-28"},
+		{Food: "Борщ со сметаной", Count: 2, AverageCalories: 375, LastLogged: "2026-01-30"},
+	}, foods)
+	assert.Empty(t, groupByFood(nil))
+}
+
+func TestSearchService_Search(t *testing.T) {
+	t.Run("groups matches and lists the latest", func(t *testing.T) {
+		service, mock := setupSearchService(t)
+		rows := sqlmock.NewRows(entryColumnNames)
+		for i := 0; i < SearchRecentEntries+2; i++ {
+			rows.AddRow(testEntryID, testUserID, "2026-01-26", MealLunch, "Борщ", 300.0, 10.0, 30.0, 12.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil)
+		}
+		mock.ExpectQuery(`WHERE user_id = \$1 AND food_search ILIKE \$2 AND date >= \$3 AND date <= \$4\s+ORDER BY date DESC, created_at DESC, id DESC\s+LIMIT \$5`).
+			WithArgs(testUserID, "%борщ%", "2026-01-01", "2026-01-31", MaxSearchEntries+1).
+			WillReturnRows(rows)
+
+		result, err := service.Search(context.Background(), testUserID, "БОРЩ", testRange("2026-01-01", "2026-01-31"))
+
+		require.NoError(t, err)
+		assert.Equal(t, "борщ", result.Query)
+		assert.Equal(t, []FoodMatch{{Food: "Борщ", Count: SearchRecentEntries + 2, AverageCalories: 300, LastLogged: "2026-01-26"}}, result.Foods)
+		assert.Len(t, result.Recent, SearchRecentEntries)
+		assert.False(t, result.Truncated)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("escapes wildcards and folds ё", func(t *testing.T) {
+		service, mock := setupSearchService(t)
+		mock.ExpectQuery(`food_search ILIKE \$2\s+ORDER BY`).
+			WithArgs(testUserID, `%100\% тушеная%`, MaxSearchEntries+1).
+			WillReturnRows(sqlmock.NewRows(entryColumnNames))
+
+		result, err := service.Search(context.Background(), testUserID, "100% тушёная", testRange("", ""))
+
+		require.NoError(t, err)
+		assert.NotNil(t, result.Foods)
+		assert.Empty(t, result.Foods)
+		assert.Empty(t, result.Recent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("caps the entries", func(t *testing.T) {
+		service, mock := setupSearchService(t)
+		rows := sqlmock.NewRows(entryColumnNames)
+		for i := 0; i <= MaxSearchEntries; i++ {
+			rows.AddRow(testEntryID, testUserID, "2026-01-26", MealLunch, "Борщ", 300.0, 10.0, 30.0, 12.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil)
+		}
+		mock.ExpectQuery("FROM nutrition_entries").WillReturnRows(rows)
+
+		result, err := service.Search(context.Background(), testUserID, "борщ", testRange("", ""))
+
+		require.NoError(t, err)
+		assert.True(t, result.Truncated)
+		require.Len(t, result.Foods, 1)
+		assert.Equal(t, MaxSearchEntries, result.Foods[0].Count)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSearchEntriesHandler(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("food_search ILIKE").
+			WithArgs(testUserID, "%борщ%", MaxSearchEntries+1).
+			WillReturnRows(entryRows("Борщ", 300))
+
+		status, resp := serve(t, handler.SearchEntries, testUserID, http.MethodGet, "/entries/?q=%D0%B1%D0%BE%D1%80%D1%89", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Len(t, data["foods"], 1)
+		assert.Len(t, data["recent"], 1)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("empty query", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.SearchEntries, testUserID, http.MethodGet, "/entries/?q=", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Contains(t, resp["details"].(map[string]interface{})["fields"], "q")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("malformed dates", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.SearchEntries, testUserID, http.MethodGet,
+			"/entries/?q=%D0%B1%D0%BE%D1%80%D1%89&from=01.01.2026&to=2026-13-01", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, map[string]interface{}{
+			"from": "Неверный формат даты, ожидается ГГГГ-ММ-ДД",
+			"to":   "Неверный формат даты, ожидается ГГГГ-ММ-ДД",
+		}, resp["details"].(map[string]interface{})["fields"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -2,27 +2,98 @@ package nutrition
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
 	"time"
 
-	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/comments"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/modules/recipes"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/idempotency"
+	"github.com/burcev/api/internal/shared/ids"
+	"github.com/burcev/api/internal/shared/listing"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/tombstones"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
 )
 
+// errForeignEntry is returned when an entry exists but belongs to another
+// user. It wraps apperrors.ErrNotFound so the caller cannot tell it apart
+// from a missing entry; handlers only use it to log a security event.
+var errForeignEntry = fmt.Errorf("entry belongs to another user: %w", apperrors.ErrNotFound)
+
+// errReplayed aborts the CreateEntryOnce transaction when the idempotency
+// key was already used
+var errReplayed = errors.New("idempotency key already used")
+
+// entryKeyScope namespaces idempotency keys of entry creation
+const entryKeyScope = "nutrition_entry"
+
+// ServiceInterface defines the nutrition entry operations the handler uses
+type ServiceInterface interface {
+	GetEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) ([]*Entry, error)
+	StreamEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) iter.Seq2[*Entry, error]
+	CreateEntry(ctx context.Context, userID int64, req *CreateEntryRequest) (*Entry, error)
+	CreateEntryOnce(ctx context.Context, userID int64, key string, req *CreateEntryRequest) (entry *Entry, replayed bool, err error)
+	GetEntry(ctx context.Context, userID int64, entryID string) (*Entry, error)
+	UpdateEntry(ctx context.Context, userID int64, entryID string, req *CreateEntryRequest, version *int) (*Entry, error)
+	DeleteEntry(ctx context.Context, userID int64, entryID string) error
+	GetEntryHistory(ctx context.Context, actorID int64, entryID string) ([]*Revision, error)
+	FindDuplicate(ctx context.Context, entry *Entry, window time.Duration) (string, error)
+}
+
 // Service handles nutrition business logic
 type Service struct {
-	cfg *config.Config
-	log *logger.Logger
+	db      *database.DB
+	log     *logger.Logger
+	keys    *idempotency.Store
+	recipes *recipes.Service
+	events  *events.Bus
+	ids     *ids.Generator
+	now     func() time.Time
+	// dayCache holds report days; writes remove the days they change
+	dayCache cache.Cache
+	// photos routes entry photos, deleted with their entries
+	photos *storage.Router
+	// quotas counts entries per date against the user's limit
+	quotas *quotas.Service
 }
 
-// NewService creates a new nutrition service
-func NewService(cfg *config.Config, log *logger.Logger) *Service {
-	return &Service{
-		cfg: cfg,
-		log: log,
+// NewService creates a new nutrition service. bus, dayCache, photos and
+// quota may be nil; without quota entries are not limited. The daily
+// rollups subscribe to bus; without one the service keeps a bus of its own
+// for them, and entry events go no further.
+func NewService(db *database.DB, log *logger.Logger, bus *events.Bus, dayCache cache.Cache, photos *storage.Router, quota *quotas.Service) *Service {
+	if bus == nil {
+		bus = events.NewBus(nil)
+	}
+	s := &Service{
+		db:       db,
+		log:      log,
+		keys:     idempotency.NewStore(db, log),
+		recipes:  recipes.NewService(db, log),
+		events:   bus,
+		ids:      ids.Default,
+		now:      time.Now,
+		dayCache: dayCache,
+		photos:   photos,
+		quotas:   quota,
 	}
+	bus.Subscribe(rollupSubscriber, s.updateRollups)
+	return s
 }
 
-// Entry represents a nutrition entry
+// Entry represents a nutrition entry. Entries logged from a recipe carry
+// the recipe and portion; their macros are a copy taken at log time.
 type Entry struct {
 	ID        string    `json:"id"`
 	UserID    int64     `json:"user_id"`
@@ -34,94 +105,492 @@ type Entry struct {
 	Carbs     float64   `json:"carbs"`
 	Fat       float64   `json:"fat"`
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RecipeID     *string  `json:"recipe_id,omitempty"`
+	PortionGrams *float64 `json:"portion_grams,omitempty"`
+
+	Micronutrients
+
+	// Version grows with every update; an update naming an older one is
+	// refused
+	Version int `json:"version"`
+}
+
+// Micronutrients are the optional nutrients of an entry. Older clients do
+// not send them, so each is null when unknown rather than zero.
+type Micronutrients struct {
+	FiberG        *float64 `json:"fiber_g"`
+	SugarG        *float64 `json:"sugar_g"`
+	SodiumMg      *float64 `json:"sodium_mg"`
+	SaturatedFatG *float64 `json:"saturated_fat_g"`
+}
+
+const entryColumns = `id, user_id, date::text, meal, food, calories, protein, carbs, fat, created_at, updated_at, recipe_id, portion_grams, version, fiber_g, sugar_g, sodium_mg, saturated_fat_g`
+
+func scanEntry(row interface{ Scan(dest ...any) error }) (*Entry, error) {
+	var e Entry
+	err := row.Scan(&e.ID, &e.UserID, &e.Date, &e.Meal, &e.Food,
+		&e.Calories, &e.Protein, &e.Carbs, &e.Fat, &e.CreatedAt, &e.UpdatedAt, &e.RecipeID, &e.PortionGrams, &e.Version,
+		&e.FiberG, &e.SugarG, &e.SodiumMg, &e.SaturatedFatG)
+	if err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// EntrySortFields are the sort values GET /nutrition/entries accepts
+var EntrySortFields = listing.Fields{
+	"date":       "date",
+	"calories":   "calories",
+	"protein":    "protein",
+	"created_at": "created_at",
+}
+
+// DefaultEntrySort lists entries newest first
+var DefaultEntrySort = listing.Sort{Column: "date", Desc: true}
+
+// MaxEntriesPageSize caps the limit of an entries page
+const MaxEntriesPageSize = 500
+
+// GetEntries retrieves a page of the user's nutrition entries in the given
+// order. Entries equal on the sort column are ordered by creation time, then
+// id, so consecutive pages neither repeat nor skip entries.
+func (s *Service) GetEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) ([]*Entry, error) {
+	entries := make([]*Entry, 0)
+	for entry, err := range s.StreamEntries(ctx, userID, sort, page) {
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// StreamEntries yields the entries GetEntries returns one at a time, as they
+// are read from the database, for pages too large to hold in memory. The
+// rows are released when the loop over them ends, early or not, and the
+// query stops when ctx is cancelled. A failure, ctx.Err() included, is
+// yielded once, as the last value.
+func (s *Service) StreamEntries(ctx context.Context, userID int64, sort listing.Sort, page listing.Page) iter.Seq2[*Entry, error] {
+	return func(yield func(*Entry, error) bool) {
+		startTime := time.Now()
+		pageClause, pageArgs := page.Clause(2)
+		query := `SELECT ` + entryColumns + `
+			FROM nutrition_entries
+			WHERE user_id = $1
+			ORDER BY ` + sort.OrderBy("created_at", "id") + pageClause
+
+		rows, err := s.db.QueryContext(ctx, query, append([]any{userID}, pageArgs...)...)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		if err != nil {
+			yield(nil, fmt.Errorf("failed to query entries: %w", err))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			// database/sql notices a cancelled context only eventually
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			entry, err := scanEntry(rows)
+			if err != nil {
+				yield(nil, fmt.Errorf("failed to scan entry: %w", err))
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(nil, fmt.Errorf("failed to read entries: %w", err))
+		}
+	}
 }
 
-// GetEntries retrieves nutrition entries for user
-func (s *Service) GetEntries(ctx context.Context, userID int64) ([]*Entry, error) {
-	// TODO: Implement Supabase query
-	s.log.Infow("Get entries", "user_id", userID)
-
-	// Placeholder
-	return []*Entry{
-		{
-			ID:        "entry-1",
-			UserID:    userID,
-			Date:      time.Now().Format("2006-01-02"),
-			Meal:      "breakfast",
-			Food:      "Oatmeal",
-			Calories:  150,
-			Protein:   5,
-			Carbs:     27,
-			Fat:       3,
-			CreatedAt: time.Now(),
-		},
-	}, nil
-}
-
-// CreateEntry creates a new nutrition entry
+// CreateEntry creates a new nutrition entry. Invalid input is reported as
+// validation.Errors, an entry over the user's daily quota as
+// *apperrors.QuotaExceededError.
 func (s *Service) CreateEntry(ctx context.Context, userID int64, req *CreateEntryRequest) (*Entry, error) {
-	// TODO: Implement Supabase insert
-	s.log.Infow("Create entry", "user_id", userID, "food", req.Food)
-
-	// Placeholder
-	return &Entry{
-		ID:        "entry-new",
-		UserID:    userID,
-		Date:      req.Date,
-		Meal:      req.Meal,
-		Food:      req.Food,
-		Calories:  req.Calories,
-		Protein:   req.Protein,
-		Carbs:     req.Carbs,
-		Fat:       req.Fat,
-		CreatedAt: time.Now(),
-	}, nil
-}
-
-// GetEntry retrieves a single nutrition entry
+	if err := s.applyRecipe(ctx, userID, req); err != nil {
+		return nil, err
+	}
+	if err := validateEntry(req, s.now()); err != nil {
+		return nil, err
+	}
+
+	var entry *Entry
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var err error
+		entry, err = s.insertEntry(ctx, tx, userID, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.entryCreated(ctx, entry)
+	return entry, nil
+}
+
+// CreateEntryOnce creates an entry at most once per idempotency key. A retry
+// with the same key returns the entry the first request created and
+// replayed = true; the request body of the retry is not compared.
+func (s *Service) CreateEntryOnce(ctx context.Context, userID int64, key string, req *CreateEntryRequest) (entry *Entry, replayed bool, err error) {
+	if err := s.applyRecipe(ctx, userID, req); err != nil {
+		return nil, false, err
+	}
+	if err := validateEntry(req, s.now()); err != nil {
+		return nil, false, err
+	}
+
+	var existingID string
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		existingID, err = s.keys.Claim(ctx, tx, userID, entryKeyScope, key)
+		if err != nil {
+			return err
+		}
+		if existingID != "" {
+			// Nothing to write; roll back and return the first entry
+			return errReplayed
+		}
+
+		entry, err = s.insertEntry(ctx, tx, userID, req)
+		if err != nil {
+			return err
+		}
+		return s.keys.Complete(ctx, tx, userID, entryKeyScope, key, entry.ID)
+	})
+	if errors.Is(err, errReplayed) {
+		entry, err := s.GetEntry(ctx, userID, existingID)
+		if err != nil {
+			return nil, false, err
+		}
+		return entry, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.entryCreated(ctx, entry)
+	return entry, false, nil
+}
+
+// FindDuplicate returns the id of the most recent other entry identical to
+// entry (same date, meal, food and calories) that was created no more than
+// window before it, or "" when there is none. A double submit from a lagging
+// client looks like this.
+func (s *Service) FindDuplicate(ctx context.Context, entry *Entry, window time.Duration) (string, error) {
+	startTime := time.Now()
+	query := `
+		SELECT id, created_at
+		FROM nutrition_entries
+		WHERE user_id = $1 AND date = $2 AND meal = $3 AND food = $4 AND calories = $5
+		  AND id <> $6 AND created_at <= $7
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`
+
+	var id string
+	var createdAt time.Time
+	err := s.db.QueryRowContext(ctx, query, entry.UserID, entry.Date, entry.Meal, entry.Food, entry.Calories,
+		entry.ID, entry.CreatedAt).Scan(&id, &createdAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+		"user_id":  entry.UserID,
+		"entry_id": entry.ID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up duplicate entry: %w", err)
+	}
+	if !withinDuplicateWindow(createdAt, entry.CreatedAt, window) {
+		return "", nil
+	}
+	return id, nil
+}
+
+// withinDuplicateWindow reports whether an entry created at created repeats
+// one created at existing: at most window later, the edge included
+func withinDuplicateWindow(existing, created time.Time, window time.Duration) bool {
+	return created.Sub(existing) <= window
+}
+
+// applyRecipe fills in the macros of an entry logged from a recipe. They
+// are computed from the recipe's current values and stored with the entry,
+// so later edits of the recipe do not change it; macros sent by the client
+// are ignored. Without a recipe the portion is dropped.
+func (s *Service) applyRecipe(ctx context.Context, userID int64, req *CreateEntryRequest) error {
+	if req.RecipeID == nil {
+		req.PortionGrams = nil
+		return nil
+	}
+	if req.PortionGrams == nil || !(*req.PortionGrams > 0 && *req.PortionGrams <= recipes.MaxPortionGrams) {
+		return validation.Errors{"portion_grams": fmt.Sprintf("Значение должно быть больше 0 и не больше %d", recipes.MaxPortionGrams)}
+	}
+
+	portion, err := s.recipes.Portion(ctx, userID, *req.RecipeID, *req.PortionGrams)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		return validation.Errors{"recipe_id": "Рецепт не найден"}
+	}
+	if err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(req.Food) == "" {
+		req.Food = portion.Name
+	}
+	calories := portion.Calories
+	req.Calories = &calories
+	req.Protein, req.Carbs, req.Fat = portion.Protein, portion.Carbs, portion.Fat
+	return nil
+}
+
+// insertEntry counts the entry against the user's quota, inserts it and
+// publishes EntryCreated, which adds it to its day's rollup
+func (s *Service) insertEntry(ctx context.Context, tx *sql.Tx, userID int64, req *CreateEntryRequest) (*Entry, error) {
+	if err := s.quotas.ReserveEntry(ctx, tx, userID, req.Date.String()); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO nutrition_entries (id, user_id, date, meal, food, calories, protein, carbs, fat, recipe_id, portion_grams, food_search,
+		                               fiber_g, sugar_g, sodium_mg, saturated_fat_g)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		RETURNING ` + entryColumns
+
+	entry, err := scanEntry(tx.QueryRowContext(ctx, query,
+		s.ids.NewString(), userID, req.Date, req.Meal, req.Food, *req.Calories, req.Protein, req.Carbs, req.Fat, req.RecipeID, req.PortionGrams,
+		normalizeFood(req.Food), req.FiberG, req.SugarG, req.SodiumMg, req.SaturatedFatG))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create entry: %w", err)
+	}
+	if err := s.events.Publish(ctx, tx, userID, EntryCreated{entry}); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *Service) entryCreated(ctx context.Context, entry *Entry) {
+	invalidateReportDays(ctx, s.dayCache, s.log, entry.UserID, entry.Date)
+	s.log.LogBusinessEvent("nutrition_entry_created", map[string]interface{}{
+		"user_id":  entry.UserID,
+		"entry_id": entry.ID,
+	})
+}
+
+// GetEntry retrieves a single nutrition entry owned by the user. Entries of
+// other users are reported as not found.
 func (s *Service) GetEntry(ctx context.Context, userID int64, entryID string) (*Entry, error) {
-	// TODO: Implement Supabase query
-	s.log.Infow("Get entry", "user_id", userID, "entry_id", entryID)
-
-	// Placeholder
-	return &Entry{
-		ID:        entryID,
-		UserID:    userID,
-		Date:      time.Now().Format("2006-01-02"),
-		Meal:      "lunch",
-		Food:      "Chicken Breast",
-		Calories:  165,
-		Protein:   31,
-		Carbs:     0,
-		Fat:       3.6,
-		CreatedAt: time.Now(),
-	}, nil
-}
-
-// UpdateEntry updates a nutrition entry
-func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string, req *CreateEntryRequest) (*Entry, error) {
-	// TODO: Implement Supabase update
-	s.log.Infow("Update entry", "user_id", userID, "entry_id", entryID)
-
-	// Placeholder
-	return &Entry{
-		ID:        entryID,
-		UserID:    userID,
-		Date:      req.Date,
-		Meal:      req.Meal,
-		Food:      req.Food,
-		Calories:  req.Calories,
-		Protein:   req.Protein,
-		Carbs:     req.Carbs,
-		Fat:       req.Fat,
-		CreatedAt: time.Now(),
-	}, nil
-}
-
-// DeleteEntry deletes a nutrition entry
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `SELECT ` + entryColumns + ` FROM nutrition_entries WHERE id = $1 AND user_id = $2`
+
+	entry, err := scanEntry(s.db.QueryRowContext(ctx, query, entryID, userID))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.notFound(ctx, userID, entryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	return entry, nil
+}
+
+// UpdateEntry updates a nutrition entry owned by the user and records the
+// changed fields in its history. Invalid input is reported as
+// validation.Errors. An update naming a recipe recomputes the macros from
+// its current values; one without drops the recipe reference. A non-nil
+// version must be the entry's current one, or the update fails with
+// *apperrors.VersionConflictError carrying the entry as it is. Moving the
+// entry to a date at the user's quota fails with
+// *apperrors.QuotaExceededError.
+func (s *Service) UpdateEntry(ctx context.Context, userID int64, entryID string, req *CreateEntryRequest, version *int) (*Entry, error) {
+	if err := s.applyRecipe(ctx, userID, req); err != nil {
+		return nil, err
+	}
+	if err := validateEntry(req, s.now()); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(entryID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	var entry *Entry
+	var oldDate string
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		old, err := s.lockEntry(ctx, tx, userID, entryID)
+		if err != nil {
+			return err
+		}
+		oldDate = old.Date
+
+		startTime := time.Now()
+		query := `
+			UPDATE nutrition_entries
+			SET date = $3, meal = $4, food = $5, calories = $6, protein = $7, carbs = $8, fat = $9,
+			    recipe_id = $10, portion_grams = $11, food_search = $12,
+			    fiber_g = $13, sugar_g = $14, sodium_mg = $15, saturated_fat_g = $16,
+			    updated_at = NOW(), version = version + 1
+			WHERE id = $1 AND user_id = $2 AND ($17::int IS NULL OR version = $17)
+			RETURNING ` + entryColumns
+
+		entry, err = scanEntry(tx.QueryRowContext(ctx, query,
+			entryID, userID, req.Date, req.Meal, req.Food, *req.Calories, req.Protein, req.Carbs, req.Fat, req.RecipeID, req.PortionGrams,
+			normalizeFood(req.Food), req.FiberG, req.SugarG, req.SodiumMg, req.SaturatedFatG, version))
+		s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+			"user_id":  userID,
+			"entry_id": entryID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			// The entry is locked, so only the version can have missed
+			return &apperrors.VersionConflictError{Current: old}
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update entry: %w", err)
+		}
+
+		if err := s.events.Publish(ctx, tx, userID, EntryUpdated{Entry: entry, Previous: old}); err != nil {
+			return err
+		}
+
+		// An entry moving to another day is counted on that day
+		if entry.Date != old.Date {
+			if err := s.quotas.ReleaseEntry(ctx, tx, userID, old.Date); err != nil {
+				return err
+			}
+			if err := s.quotas.ReserveEntry(ctx, tx, userID, entry.Date); err != nil {
+				return err
+			}
+		}
+
+		if diff := changedFields(old, entry); len(diff) > 0 {
+			return s.recordRevision(ctx, tx, old, userID, ChangeUpdate, diff)
+		}
+		return nil
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, s.notFound(ctx, userID, entryID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The entry may have moved to another day
+	invalidateReportDays(ctx, s.dayCache, s.log, userID, oldDate, entry.Date)
+	return entry, nil
+}
+
+// DeleteEntry deletes a nutrition entry owned by the user, keeping a copy of
+// it in the entry history. Its photo goes with it.
 func (s *Service) DeleteEntry(ctx context.Context, userID int64, entryID string) error {
-	// TODO: Implement Supabase delete
-	s.log.Infow("Delete entry", "user_id", userID, "entry_id", entryID)
+	if _, err := uuid.Parse(entryID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	var deleted *Entry
+	var photoRegion sql.NullString
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if s.photos != nil {
+			region, err := entryPhotoRegion(ctx, tx, userID, entryID)
+			if err != nil {
+				return err
+			}
+			photoRegion = region
+		}
+
+		startTime := time.Now()
+		query := `DELETE FROM nutrition_entries WHERE id = $1 AND user_id = $2 RETURNING ` + entryColumns
+
+		old, err := scanEntry(tx.QueryRowContext(ctx, query, entryID, userID))
+		deleted = old
+		s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+			"user_id":  userID,
+			"entry_id": entryID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete entry: %w", err)
+		}
 
+		if err := s.events.Publish(ctx, tx, userID, EntryDeleted{old}); err != nil {
+			return err
+		}
+		if err := s.quotas.ReleaseEntry(ctx, tx, userID, old.Date); err != nil {
+			return err
+		}
+		if err := comments.DeleteForEntry(ctx, tx, entryID); err != nil {
+			return err
+		}
+		if err := tombstones.Record(ctx, tx, userID, tombstones.NutritionEntries, entryID); err != nil {
+			return err
+		}
+		return s.recordRevision(ctx, tx, old, userID, ChangeDelete, snapshot(old))
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.notFound(ctx, userID, entryID)
+	}
+	if err != nil {
+		return err
+	}
+
+	invalidateReportDays(ctx, s.dayCache, s.log, userID, deleted.Date)
+	deleteEntryPhotoFiles(ctx, s.photos, s.log, userID, entryID, photoRegion)
+	s.log.LogBusinessEvent("nutrition_entry_deleted", map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
 	return nil
 }
+
+// lockEntry loads an entry owned by the user for update. A missing entry is
+// returned as sql.ErrNoRows.
+func (s *Service) lockEntry(ctx context.Context, tx *sql.Tx, userID int64, entryID string) (*Entry, error) {
+	startTime := time.Now()
+	query := `SELECT ` + entryColumns + ` FROM nutrition_entries WHERE id = $1 AND user_id = $2 FOR UPDATE`
+
+	entry, err := scanEntry(tx.QueryRowContext(ctx, query, entryID, userID))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+		"user_id":  userID,
+		"entry_id": entryID,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get entry: %w", err)
+	}
+	return entry, err
+}
+
+// notFound is called when an id + user_id lookup matched nothing. It checks
+// whether the entry exists under another user, so the handler can log the
+// probe; both cases look the same to the client.
+func (s *Service) notFound(ctx context.Context, userID int64, entryID string) error {
+	var ownerID int64
+	err := s.db.QueryRowContext(ctx, `SELECT user_id FROM nutrition_entries WHERE id = $1`, entryID).Scan(&ownerID)
+	switch {
+	case err == nil && ownerID != userID:
+		return errForeignEntry
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		s.log.Errorw("Failed to check nutrition entry owner", "error", err, "entry_id", entryID)
+	}
+	return apperrors.ErrNotFound
+}
+
+// ignoreNoRows hides sql.ErrNoRows from query logging, where it is not a failure
+func ignoreNoRows(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
@@ -2,228 +2,842 @@ package nutrition
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
 	"testing"
-
-	"github.com/burcev/api/internal/config"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/ids"
+	"github.com/burcev/api/internal/shared/listing"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/tombstones"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupTestService() *Service {
-	cfg := &config.Config{
-		Env:       "test",
-		JWTSecret: "test-secret",
-	}
-	log := logger.New()
-	return NewService(cfg, log)
+// testNow keeps the fixed entry dates below inside the allowed window
+var testNow = time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC)
+
+const (
+	testEntryID   = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+	otherEntryID  = "0199f0b2-6b0d-7a3b-9d2e-3f4a5b6c7d8f"
+	testUserID    = int64(123)
+	otherUserID   = int64(456)
+	entrySelectRe = "SELECT id, user_id, date::text, meal, food, calories, protein, carbs, fat, created_at, updated_at, recipe_id, portion_grams, version, fiber_g, sugar_g, sodium_mg, saturated_fat_g FROM nutrition_entries WHERE id = \\$1 AND user_id = \\$2"
+	entryOwnerRe  = "SELECT user_id FROM nutrition_entries WHERE id = \\$1"
+)
+
+var entryColumnNames = []string{"id", "user_id", "date", "meal", "food", "calories", "protein", "carbs", "fat",
+	"created_at", "updated_at", "recipe_id", "portion_grams", "version", "fiber_g", "sugar_g", "sodium_mg", "saturated_fat_g"}
+
+func floatPtr(v float64) *float64 { return &v }
+
+// testDate binds value the way a request would, so a malformed value gives
+// a malformed date
+func testDate(value string) types.Date {
+	var d types.Date
+	_ = d.UnmarshalParam(value)
+	return d
+}
+
+func testRange(from, to string) types.DateRange {
+	return types.DateRange{From: testDate(from), To: testDate(to)}
+}
+
+func strPtr(v string) *string { return &v }
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), nil, nil, nil, nil)
+	service.now = func() time.Time { return testNow }
+	service.ids = ids.NewGenerator(service.now)
+	return service, mock
+}
+
+// setupLoggedService is setupTestService with a bus that logs the events
+func setupLoggedService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	service, mock := setupTestService(t)
+	logged := NewService(service.db, logger.New(), events.NewBus(events.NewLog(service.db, logger.New())), nil, nil, nil)
+	logged.now, logged.ids = service.now, service.ids
+	return logged, mock
 }
 
-func TestNewService(t *testing.T) {
-	service := setupTestService()
-	assert.NotNil(t, service)
-	assert.NotNil(t, service.cfg)
-	assert.NotNil(t, service.log)
+// entryRows returns a result set with one entry owned by testUserID
+func entryRows(food string, calories float64) *sqlmock.Rows {
+	return sqlmock.NewRows(entryColumnNames).
+		AddRow(testEntryID, testUserID, "2026-01-26", MealBreakfast, food, calories, 5.0, 27.0, 3.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil)
 }
 
 func TestService_GetEntries(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+	service, mock := setupTestService(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM nutrition_entries\\s+WHERE user_id = \\$1\\s+ORDER BY date DESC, created_at DESC, id DESC$").
+		WithArgs(testUserID).
+		WillReturnRows(entryRows("Овсянка", 150))
 
-	entries, err := service.GetEntries(ctx, int64(123))
+	entries, err := service.GetEntries(context.Background(), testUserID, DefaultEntrySort, listing.Page{})
 
 	require.NoError(t, err)
-	assert.NotNil(t, entries)
-	assert.NotEmpty(t, entries)
-	assert.Equal(t, int64(123), entries[0].UserID)
+	require.Len(t, entries, 1)
+	assert.Equal(t, testEntryID, entries[0].ID)
+	assert.Equal(t, "2026-01-26", entries[0].Date)
+	assert.Equal(t, 150.0, entries[0].Calories)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestService_CreateEntry(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_StreamEntries(t *testing.T) {
+	t.Run("breaking out of the loop releases the rows", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+			WillReturnRows(manyEntryRows(10)).
+			RowsWillBeClosed()
 
-	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "breakfast",
-		Food:     "Oatmeal",
-		Calories: 150,
-		Protein:  5,
-		Carbs:    27,
-		Fat:      3,
-	}
+		var foods []string
+		for entry, err := range service.StreamEntries(context.Background(), testUserID, DefaultEntrySort, listing.Page{}) {
+			require.NoError(t, err)
+			foods = append(foods, entry.Food)
+			if len(foods) == 3 {
+				break
+			}
+		}
+
+		assert.Equal(t, []string{"Блюдо 0", "Блюдо 1", "Блюдо 2"}, foods)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cancelled context ends the stream", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT (.+) FROM nutrition_entries").
+			WillReturnRows(manyEntryRows(10)).
+			RowsWillBeClosed()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		read := 0
+		var last error
+		for _, err := range service.StreamEntries(ctx, testUserID, DefaultEntrySort, listing.Page{}) {
+			if err != nil {
+				last = err
+				continue
+			}
+			read++
+			cancel()
+		}
+
+		assert.Equal(t, 1, read)
+		assert.ErrorIs(t, last, context.Canceled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+func TestService_GetEntries_SortedPage(t *testing.T) {
+	service, mock := setupTestService(t)
+
+	// Ties on calories are broken by created_at and id, so page 2 continues page 1
+	mock.ExpectQuery("ORDER BY calories DESC, created_at DESC, id DESC LIMIT \\$2 OFFSET \\$3$").
+		WithArgs(testUserID, 20, 20).
+		WillReturnRows(entryRows("Плов", 650))
 
-	entry, err := service.CreateEntry(ctx, int64(123), req)
+	entries, err := service.GetEntries(context.Background(), testUserID,
+		listing.Sort{Column: "calories", Desc: true}, listing.Page{Limit: 20, Offset: 20})
 
 	require.NoError(t, err)
-	assert.NotNil(t, entry)
-	assert.Equal(t, int64(123), entry.UserID)
-	assert.Equal(t, "2026-01-26", entry.Date)
-	assert.Equal(t, "breakfast", entry.Meal)
-	assert.Equal(t, "Oatmeal", entry.Food)
-	assert.Equal(t, 150.0, entry.Calories)
-	assert.Equal(t, 5.0, entry.Protein)
-	assert.Equal(t, 27.0, entry.Carbs)
-	assert.Equal(t, 3.0, entry.Fat)
+	require.Len(t, entries, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestService_CreateEntry_WithZeroMacros(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_CreateEntry(t *testing.T) {
+	service, mock := setupTestService(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO nutrition_entries").
+		WithArgs(newIDArg(), testUserID, "2026-01-26", MealLunch, "Борщ с хлебом", 350.0, 15.0, 45.0, 12.0, nil, nil, "борщ с хлебом", nil, nil, nil, nil).
+		WillReturnRows(entryRows("Борщ с хлебом", 350))
+	expectRollup(mock)
+	mock.ExpectCommit()
 
 	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "snack",
-		Food:     "Water",
-		Calories: 0,
-		Protein:  0,
-		Carbs:    0,
-		Fat:      0,
+		Date:     testDate("2026-01-26"),
+		Meal:     "обед",
+		Food:     "Борщ с хлебом",
+		Calories: floatPtr(350),
+		Protein:  15,
+		Carbs:    45,
+		Fat:      12,
 	}
-
-	entry, err := service.CreateEntry(ctx, int64(123), req)
+	entry, err := service.CreateEntry(context.Background(), testUserID, req)
 
 	require.NoError(t, err)
-	assert.NotNil(t, entry)
-	assert.Equal(t, 0.0, entry.Calories)
+	assert.Equal(t, testEntryID, entry.ID)
+	assert.Equal(t, MealLunch, req.Meal, "Russian meal names are normalized before saving")
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestService_CreateEntry_DifferentMeals(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_CreateEntry_IDsOrderedWithinBurst(t *testing.T) {
+	// The clock does not move, as in a burst of requests within a millisecond
+	service, mock := setupTestService(t)
+
+	generated := newIDArg()
+	for range 5 {
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").
+			WithArgs(generated, testUserID, "2026-01-26", MealSnack, "Яблоко", 80.0, 0.0, 20.0, 0.0, nil, nil, "яблоко", nil, nil, nil, nil).
+			WillReturnRows(entryRows("Яблоко", 80))
+		expectRollup(mock)
+		mock.ExpectCommit()
+	}
 
-	meals := []string{"breakfast", "lunch", "dinner", "snack"}
+	for range 5 {
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealSnack, Food: "Яблоко", Calories: floatPtr(80), Carbs: 20,
+		})
+		require.NoError(t, err)
+	}
 
-	for _, meal := range meals {
-		t.Run(meal, func(t *testing.T) {
-			req := &CreateEntryRequest{
-				Date:     "2026-01-26",
-				Meal:     meal,
-				Food:     "Test Food",
-				Calories: 100,
-			}
+	require.Len(t, generated.ids, 5)
+	assert.True(t, slices.IsSorted(generated.ids), "ids are time-ordered: %v", generated.ids)
+	assert.Len(t, slices.Compact(slices.Clone(generated.ids)), 5, "ids are unique")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-			entry, err := service.CreateEntry(ctx, int64(123), req)
-			require.NoError(t, err)
-			assert.Equal(t, meal, entry.Meal)
+func TestService_PublishesEntryEvents(t *testing.T) {
+	// record subscribes to the service's bus after the rollups
+	record := func(service *Service) *[]events.Event {
+		var published []events.Event
+		service.events.Subscribe("test", func(ctx context.Context, tx *sql.Tx, event events.Event) error {
+			assert.NotNil(t, tx, "published in the write's transaction")
+			published = append(published, event)
+			return nil
 		})
+		return &published
 	}
+
+	t.Run("created", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		published := record(service)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ с хлебом", 350))
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		entry, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealLunch, Food: "Борщ с хлебом", Calories: floatPtr(350),
+		})
+
+		require.NoError(t, err)
+		require.Len(t, *published, 1)
+		assert.Equal(t, events.NutritionEntryCreated, (*published)[0].Type)
+		assert.Equal(t, testUserID, (*published)[0].UserID)
+		assert.Equal(t, EntryCreated{entry}, (*published)[0].Data)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("updated", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		published := record(service)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(entryRows("Овсянка", 180))
+		expectRollupDelta(mock, "2026-01-26", 0, Macros{Calories: 30})
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(180), Protein: 5, Carbs: 27, Fat: 3,
+		}, nil)
+
+		require.NoError(t, err)
+		require.Len(t, *published, 1)
+		updated := (*published)[0].Data.(EntryUpdated)
+		assert.Equal(t, events.NutritionEntryUpdated, (*published)[0].Type)
+		assert.Equal(t, 180.0, updated.Calories)
+		assert.Equal(t, 150.0, updated.Previous.Calories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		published := record(service)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		expectRollupDelta(mock, "2026-01-26", -1, Macros{Calories: -150, Protein: -5, Carbs: -27, Fat: -3})
+		mock.ExpectExec("UPDATE curator_comments").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO sync_tombstones").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+
+		require.Len(t, *published, 1)
+		assert.Equal(t, events.NutritionEntryDeleted, (*published)[0].Type)
+		assert.Equal(t, testEntryID, (*published)[0].Data.(EntryDeleted).ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("logged with the entry", func(t *testing.T) {
+		service, mock := setupLoggedService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ", 300))
+		expectRollup(mock)
+		mock.ExpectExec("INSERT INTO domain_events").
+			WithArgs(sqlmock.AnyArg(), events.NutritionEntryCreated, testUserID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealLunch, Food: "Борщ", Calories: floatPtr(300),
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed append rolls the entry back", func(t *testing.T) {
+		service, mock := setupLoggedService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Борщ", 300))
+		expectRollup(mock)
+		mock.ExpectExec("INSERT INTO domain_events").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealLunch, Food: "Борщ", Calories: floatPtr(300),
+		})
+
+		assert.Error(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
-func TestService_GetEntry(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestEntryEventJSON(t *testing.T) {
+	entry := &Entry{ID: testEntryID, UserID: testUserID, Date: "2026-01-26", Food: "Борщ", Calories: 300, Version: 2}
 
-	entry, err := service.GetEntry(ctx, int64(123), "entry-123")
+	created, err := json.Marshal(EntryCreated{entry})
+	require.NoError(t, err)
+	plain, err := json.Marshal(entry)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(plain), string(created), "consumers read the entry as the entries API returns it")
 
+	updated, err := json.Marshal(EntryUpdated{Entry: entry, Previous: &Entry{ID: testEntryID, Calories: 250}})
 	require.NoError(t, err)
-	assert.NotNil(t, entry)
-	assert.Equal(t, "entry-123", entry.ID)
-	assert.Equal(t, int64(123), entry.UserID)
-	assert.NotEmpty(t, entry.Food)
+	var fields map[string]interface{}
+	require.NoError(t, json.Unmarshal(updated, &fields))
+	assert.Equal(t, 300.0, fields["calories"])
+	assert.Equal(t, 250.0, fields["previous"].(map[string]interface{})["calories"])
 }
 
-func TestService_UpdateEntry(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
-
-	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "lunch",
-		Food:     "Updated Food",
-		Calories: 200,
-		Protein:  10,
-		Carbs:    30,
-		Fat:      5,
+func TestService_CreateEntry_FromRecipe(t *testing.T) {
+	const recipeID = "7c1d2e3f-4a5b-4c6d-8e9f-0a1b2c3d4e5f"
+	recipeRe := "SELECT (.+) FROM recipes WHERE id = \\$1 AND user_id = \\$2"
+	recipeRows := func(per100Calories float64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "name", "cooked_weight",
+			"calories_per_100", "protein_per_100", "fat_per_100", "carbs_per_100", "created_at", "updated_at"}).
+			AddRow(recipeID, testUserID, "Чили", 1100.0, per100Calories, 15.31, 7.98, 9.71, testNow, testNow)
 	}
 
-	entry, err := service.UpdateEntry(ctx, int64(123), "entry-123", req)
+	t.Run("macros are computed from the recipe and stored", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(recipeRe).WithArgs(recipeID, testUserID).WillReturnRows(recipeRows(166.36))
+		// 350 g of the recipe; the macros sent by the client are ignored
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").
+			WithArgs(newIDArg(), testUserID, "2026-01-26", MealDinner, "Чили", 582.26, 53.59, 33.99, 27.93, recipeID, 350.0, "чили", nil, nil, nil, nil).
+			WillReturnRows(entryRows("Чили", 582.26))
+		expectRollup(mock)
+		mock.ExpectCommit()
+
+		req := &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealDinner, Calories: floatPtr(1), Protein: 1,
+			RecipeID: strPtr(recipeID), PortionGrams: floatPtr(350),
+		}
+		_, err := service.CreateEntry(context.Background(), testUserID, req)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("recipe edits do not change logged entries", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(recipeRe).WillReturnRows(recipeRows(166.36))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnRows(entryRows("Чили", 582.26))
+		expectRollup(mock)
+		mock.ExpectCommit()
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).WillReturnRows(entryRows("Чили", 582.26))
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealDinner, RecipeID: strPtr(recipeID), PortionGrams: floatPtr(350),
+		})
+		require.NoError(t, err)
 
-	require.NoError(t, err)
-	assert.NotNil(t, entry)
-	assert.Equal(t, "entry-123", entry.ID)
-	assert.Equal(t, "Updated Food", entry.Food)
-	assert.Equal(t, 200.0, entry.Calories)
-}
+		// The entry is read from its stored macros; with no recipe query
+		// expected, a lookup of the (possibly edited) recipe would fail
+		entry, err := service.GetEntry(context.Background(), testUserID, testEntryID)
 
-func TestService_UpdateEntry_PartialUpdate(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+		require.NoError(t, err)
+		assert.Equal(t, 582.26, entry.Calories)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 
-	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "dinner",
-		Food:     "Partial Update",
-		Calories: 300,
-		// Protein, Carbs, Fat are zero values
-	}
+	t.Run("unknown recipe", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(recipeRe).WillReturnError(sql.ErrNoRows)
 
-	entry, err := service.UpdateEntry(ctx, int64(123), "entry-123", req)
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealDinner, RecipeID: strPtr(recipeID), PortionGrams: floatPtr(350),
+		})
 
-	require.NoError(t, err)
-	assert.Equal(t, "Partial Update", entry.Food)
-	assert.Equal(t, 300.0, entry.Calories)
+		assert.Equal(t, validation.Errors{"recipe_id": "Рецепт не найден"}, err)
+		assert.NoError(t, mock.ExpectationsWereMet(), "nothing is written")
+	})
+
+	t.Run("portion required", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealDinner, RecipeID: strPtr(recipeID),
+		})
+
+		var fieldErrs validation.Errors
+		require.ErrorAs(t, err, &fieldErrs)
+		assert.Contains(t, fieldErrs, "portion_grams")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
-func TestService_DeleteEntry(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_CreateEntry_InvalidInput(t *testing.T) {
+	service, mock := setupTestService(t)
 
-	err := service.DeleteEntry(ctx, int64(123), "entry-123")
+	_, err := service.CreateEntry(context.Background(), testUserID, &CreateEntryRequest{
+		Date: testDate("garbage"), Meal: MealSnack, Food: "Яблоко", Calories: floatPtr(50),
+	})
 
-	require.NoError(t, err)
+	var fieldErrs validation.Errors
+	require.ErrorAs(t, err, &fieldErrs)
+	assert.Contains(t, fieldErrs, "date")
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing is written")
 }
 
-func TestService_DeleteEntry_DifferentIDs(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_GetEntry(t *testing.T) {
+	t.Run("own entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(entrySelectRe).
+			WithArgs(testEntryID, testUserID).
+			WillReturnRows(entryRows("Овсянка", 150))
 
-	entryIDs := []string{"entry-1", "entry-999", "abc-123-def"}
+		entry, err := service.GetEntry(context.Background(), testUserID, testEntryID)
 
-	for _, entryID := range entryIDs {
-		t.Run(entryID, func(t *testing.T) {
-			err := service.DeleteEntry(ctx, int64(123), entryID)
-			require.NoError(t, err)
-		})
+		require.NoError(t, err)
+		assert.Equal(t, testUserID, entry.UserID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).WillReturnError(sql.ErrNoRows)
+
+		_, err := service.GetEntry(context.Background(), testUserID, testEntryID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NotErrorIs(t, err, errForeignEntry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, otherUserID).WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+
+		_, err := service.GetEntry(context.Background(), otherUserID, testEntryID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.ErrorIs(t, err, errForeignEntry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		_, err := service.GetEntry(context.Background(), testUserID, "entry-123")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet(), "no query for ids that cannot exist")
+	})
+
+	t.Run("database error", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).WillReturnError(errors.New("connection reset"))
+
+		_, err := service.GetEntry(context.Background(), testUserID, testEntryID)
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NotErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+// idArg matches a server-generated UUIDv7 and records the ids it saw
+type idArg struct{ ids []string }
+
+func newIDArg() *idArg { return &idArg{} }
+
+func (a *idArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if !ok {
+		return false
 	}
+	id, err := uuid.Parse(s)
+	if err != nil || id.Version() != 7 {
+		return false
+	}
+	a.ids = append(a.ids, s)
+	return true
 }
 
-func TestService_CreateEntry_HighCalories(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+// jsonArg matches a JSON argument by its decoded value
+type jsonArg map[string]interface{}
 
-	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "dinner",
-		Food:     "Large Pizza",
-		Calories: 2500,
-		Protein:  100,
-		Carbs:    300,
-		Fat:      100,
+func (a jsonArg) Match(v driver.Value) bool {
+	raw, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(raw, &got); err != nil {
+		return false
 	}
+	return reflect.DeepEqual(map[string]interface{}(a), got)
+}
 
-	entry, err := service.CreateEntry(ctx, int64(123), req)
+func TestService_UpdateEntry(t *testing.T) {
+	req := func() *CreateEntryRequest {
+		return &CreateEntryRequest{Date: testDate("2026-01-26"), Meal: MealDinner, Food: "Updated Food", Calories: floatPtr(200), Protein: 10, Carbs: 30, Fat: 5}
+	}
 
-	require.NoError(t, err)
-	assert.Equal(t, 2500.0, entry.Calories)
+	t.Run("own entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe+" FOR UPDATE").
+			WithArgs(testEntryID, testUserID).
+			WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries\\s+SET (.+)\\s+WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(testEntryID, testUserID, "2026-01-26", MealDinner, "Updated Food", 200.0, 10.0, 30.0, 5.0, nil, nil, "updated food", nil, nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows(entryColumnNames).
+				AddRow(testEntryID, testUserID, "2026-01-26", MealDinner, "Updated Food", 200.0, 10.0, 30.0, 5.0, testNow, testNow, nil, nil, 1, nil, nil, nil, nil))
+		expectRollup(mock)
+		// Exactly one revision, with the old values of the changed fields only
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").
+			WithArgs(testEntryID, testUserID, testUserID, ChangeUpdate, jsonArg{
+				"meal": MealBreakfast, "food": "Овсянка", "calories": 150.0, "protein": 5.0, "carbs": 27.0, "fat": 3.0,
+			}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		entry, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, req(), nil)
+
+		require.NoError(t, err)
+		assert.Equal(t, "Updated Food", entry.Food)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unchanged entry records no revision", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectCommit()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, &CreateEntryRequest{
+			Date: testDate("2026-01-26"), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(150), Protein: 5, Carbs: 27, Fat: 3,
+		}, nil)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("concurrent edits of the same version", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		version := 1
+		updated := func(food string, version int) *sqlmock.Rows {
+			return sqlmock.NewRows(entryColumnNames).
+				AddRow(testEntryID, testUserID, "2026-01-26", MealDinner, food, 200.0, 10.0, 30.0, 5.0, testNow, testNow, nil, nil, version, nil, nil, nil, nil)
+		}
+
+		// The first device updates version 1 to 2
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery(`SET (.+) version = version \+ 1\s+WHERE id = \$1 AND user_id = \$2 AND \(\$17::int IS NULL OR version = \$17\)`).
+			WithArgs(testEntryID, testUserID, "2026-01-26", MealDinner, "Updated Food", 200.0, 10.0, 30.0, 5.0, nil, nil, "updated food", nil, nil, nil, nil, version).
+			WillReturnRows(updated("Updated Food", 2))
+		expectRollup(mock)
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// The second one still edited version 1, so its update matches no row
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(updated("Updated Food", 2))
+		mock.ExpectQuery("UPDATE nutrition_entries").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), version).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		entry, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, req(), &version)
+		require.NoError(t, err)
+		assert.Equal(t, 2, entry.Version)
+
+		_, err = service.UpdateEntry(context.Background(), testUserID, testEntryID, req(), &version)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		var conflict *apperrors.VersionConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, 2, conflict.Current.(*Entry).Version, "the conflict carries the entry as it is now")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe+" FOR UPDATE").WithArgs(testEntryID, otherUserID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+		mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+
+		_, err := service.UpdateEntry(context.Background(), otherUserID, testEntryID, req(), nil)
+
+		assert.ErrorIs(t, err, errForeignEntry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed revision rolls back the update", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(entrySelectRe + " FOR UPDATE").WillReturnRows(entryRows("Овсянка", 150))
+		mock.ExpectQuery("UPDATE nutrition_entries").WillReturnRows(entryRows("Updated Food", 200))
+		expectRollup(mock)
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err := service.UpdateEntry(context.Background(), testUserID, testEntryID, req(), nil)
+
+		assert.ErrorContains(t, err, "connection reset")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
-func TestService_CreateEntry_CyrillicFood(t *testing.T) {
-	service := setupTestService()
-	ctx := context.Background()
+func TestService_DeleteEntry(t *testing.T) {
+	t.Run("own entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries WHERE id = \\$1 AND user_id = \\$2 RETURNING").
+			WithArgs(testEntryID, testUserID).
+			WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		// Comments on the entry are soft-deleted with it
+		mock.ExpectExec("UPDATE curator_comments SET deleted_at = NOW\\(\\) WHERE entry_id = \\$1").
+			WithArgs(testEntryID).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		// Syncing devices learn about the deletion
+		mock.ExpectExec("INSERT INTO sync_tombstones").
+			WithArgs(testUserID, tombstones.NutritionEntries, testEntryID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("INSERT INTO nutrition_entry_revisions").
+			WithArgs(testEntryID, testUserID, testUserID, ChangeDelete, jsonArg{
+				"date": "2026-01-26", "meal": MealBreakfast, "food": "Овсянка", "calories": 150.0, "protein": 5.0, "carbs": 27.0, "fat": 3.0,
+				"fiber_g": nil, "sugar_g": nil, "sodium_mg": nil, "saturated_fat_g": nil,
+			}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.DeleteEntry(context.Background(), testUserID, testEntryID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nonexistent entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WithArgs(testEntryID, testUserID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+		mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).WillReturnError(sql.ErrNoRows)
+
+		err := service.DeleteEntry(context.Background(), testUserID, testEntryID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM nutrition_entries").WithArgs(testEntryID, otherUserID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+		mock.ExpectQuery(entryOwnerRe).WithArgs(testEntryID).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(testUserID))
+
+		err := service.DeleteEntry(context.Background(), otherUserID, testEntryID)
+
+		assert.ErrorIs(t, err, errForeignEntry)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
 
-	req := &CreateEntryRequest{
-		Date:     "2026-01-26",
-		Meal:     "обед",
-		Food:     "Борщ с хлебом",
-		Calories: 350,
-		Protein:  15,
-		Carbs:    45,
-		Fat:      12,
+func TestService_CreateEntryOnce(t *testing.T) {
+	const key = "retry-key-1"
+	req := func() *CreateEntryRequest {
+		return &CreateEntryRequest{Date: testDate("2026-01-26"), Meal: MealBreakfast, Food: "Овсянка", Calories: floatPtr(150), Protein: 5, Carbs: 27, Fat: 3}
+	}
+	expectClaim := func(mock sqlmock.Sqlmock, inserted int64) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM idempotency_keys").WithArgs(testUserID, entryKeyScope, key).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO idempotency_keys").WithArgs(testUserID, entryKeyScope, key, 86400).
+			WillReturnResult(sqlmock.NewResult(0, inserted))
 	}
 
-	entry, err := service.CreateEntry(ctx, int64(123), req)
+	t.Run("first request creates the entry and records the key", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectClaim(mock, 1)
+		mock.ExpectQuery("INSERT INTO nutrition_entries").
+			WithArgs(newIDArg(), testUserID, "2026-01-26", MealBreakfast, "Овсянка", 150.0, 5.0, 27.0, 3.0, nil, nil, "овсянка", nil, nil, nil, nil).
+			WillReturnRows(entryRows("Овсянка", 150))
+		expectRollup(mock)
+		mock.ExpectExec("UPDATE idempotency_keys SET resource_id").
+			WithArgs(testUserID, entryKeyScope, key, testEntryID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		entry, replayed, err := service.CreateEntryOnce(context.Background(), testUserID, key, req())
+
+		require.NoError(t, err)
+		assert.False(t, replayed)
+		assert.Equal(t, testEntryID, entry.ID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	// The losing side of a race: its key insert waited for the winner's
+	// commit, hit the conflict and now returns the winner's entry without
+	// inserting a second one
+	t.Run("replay returns the original entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectClaim(mock, 0)
+		mock.ExpectQuery("SELECT resource_id FROM idempotency_keys").
+			WithArgs(testUserID, entryKeyScope, key).
+			WillReturnRows(sqlmock.NewRows([]string{"resource_id"}).AddRow(testEntryID))
+		mock.ExpectRollback()
+		mock.ExpectQuery(entrySelectRe).WithArgs(testEntryID, testUserID).
+			WillReturnRows(entryRows("Овсянка", 150))
+
+		entry, replayed, err := service.CreateEntryOnce(context.Background(), testUserID, key, req())
+
+		require.NoError(t, err)
+		assert.True(t, replayed)
+		assert.Equal(t, testEntryID, entry.ID)
+		assert.NoError(t, mock.ExpectationsWereMet(), "no second entry is inserted")
+	})
+
+	t.Run("failed insert releases the key", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectClaim(mock, 1)
+		mock.ExpectQuery("INSERT INTO nutrition_entries").WillReturnError(errors.New("check constraint"))
+		mock.ExpectRollback()
+
+		_, _, err := service.CreateEntryOnce(context.Background(), testUserID, key, req())
+
+		assert.ErrorContains(t, err, "check constraint")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid input does not claim the key", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		invalid := req()
+		invalid.Meal = "foo"
+
+		_, _, err := service.CreateEntryOnce(context.Background(), testUserID, key, invalid)
+
+		var fieldErrs validation.Errors
+		assert.ErrorAs(t, err, &fieldErrs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
 
-	require.NoError(t, err)
-	assert.Equal(t, "Борщ с хлебом", entry.Food)
-	assert.Equal(t, "обед", entry.Meal)
+func TestWithinDuplicateWindow(t *testing.T) {
+	window := 2 * time.Minute
+	tests := []struct {
+		name     string
+		existing time.Time
+		want     bool
+	}{
+		{"same instant", testNow, true},
+		{"seconds before", testNow.Add(-5 * time.Second), true},
+		{"exactly at the window edge", testNow.Add(-window), true},
+		{"just outside the window", testNow.Add(-window - time.Nanosecond), false},
+		{"long before", testNow.Add(-time.Hour), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, withinDuplicateWindow(tt.existing, testNow, window))
+		})
+	}
+}
+
+func TestService_FindDuplicate(t *testing.T) {
+	entry := &Entry{ID: testEntryID, UserID: testUserID, Date: "2026-01-26", Meal: MealBreakfast, Food: "Овсянка", Calories: 150, CreatedAt: testNow}
+	expectLookup := func(mock sqlmock.Sqlmock) *sqlmock.ExpectedQuery {
+		return mock.ExpectQuery("SELECT id, created_at\\s+FROM nutrition_entries\\s+WHERE user_id = \\$1 AND date = \\$2 AND meal = \\$3 AND food = \\$4 AND calories = \\$5").
+			WithArgs(testUserID, "2026-01-26", MealBreakfast, "Овсянка", 150.0, testEntryID, testNow)
+	}
+
+	t.Run("exactly at the window edge", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLookup(mock).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
+			AddRow(otherEntryID, testNow.Add(-2*time.Minute)))
+
+		id, err := service.FindDuplicate(context.Background(), entry, 2*time.Minute)
+
+		require.NoError(t, err)
+		assert.Equal(t, otherEntryID, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a second past the window", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLookup(mock).WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).
+			AddRow(otherEntryID, testNow.Add(-2*time.Minute-time.Second)))
+
+		id, err := service.FindDuplicate(context.Background(), entry, 2*time.Minute)
+
+		require.NoError(t, err)
+		assert.Empty(t, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no identical entry", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		expectLookup(mock).WillReturnError(sql.ErrNoRows)
+
+		id, err := service.FindDuplicate(context.Background(), entry, 2*time.Minute)
+
+		require.NoError(t, err)
+		assert.Empty(t, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
@@ -0,0 +1,240 @@
+package nutrition
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	red    = color.RGBA{255, 0, 0, 255}
+	green  = color.RGBA{0, 255, 0, 255}
+	blue   = color.RGBA{0, 0, 255, 255}
+	yellow = color.RGBA{255, 255, 0, 255}
+	white  = color.RGBA{255, 255, 255, 255}
+)
+
+// quadrantImage is a w x h fixture with red, green, blue and yellow
+// quadrants (top left, top right, bottom left, bottom right), so every
+// turn and mirror is told apart
+func quadrantImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := red
+			switch {
+			case x >= w/2 && y < h/2:
+				c = green
+			case x < w/2 && y >= h/2:
+				c = blue
+			case x >= w/2 && y >= h/2:
+				c = yellow
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+// withOrientation inserts an EXIF APP1 segment carrying orientation right
+// after the SOI marker, the way cameras store it
+func withOrientation(data []byte, orientation uint16, order binary.ByteOrder) []byte {
+	tiff := &bytes.Buffer{}
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+	_ = binary.Write(tiff, order, uint16(42))
+	_ = binary.Write(tiff, order, uint32(8)) // IFD0 right after the header
+	_ = binary.Write(tiff, order, uint16(2)) // entries
+	// An unrelated tag (ImageWidth, LONG) ahead of the orientation
+	_ = binary.Write(tiff, order, []uint16{0x0100, 4})
+	_ = binary.Write(tiff, order, []uint32{1, 4000})
+	_ = binary.Write(tiff, order, []uint16{0x0112, 3})
+	_ = binary.Write(tiff, order, uint32(1))
+	_ = binary.Write(tiff, order, []uint16{orientation, 0})
+	_ = binary.Write(tiff, order, uint32(0)) // no next IFD
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+	app1 := []byte{0xff, 0xe1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func decodeThumbnail(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, format, err := image.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, "jpeg", format)
+	return img
+}
+
+// nearest names the fixture color closest to the pixel, absorbing JPEG
+// artifacts
+func nearest(c color.Color) color.RGBA {
+	r, g, b, _ := c.RGBA()
+	best, bestDist := white, -1
+	for _, candidate := range []color.RGBA{red, green, blue, yellow, white} {
+		dr := int(r>>8) - int(candidate.R)
+		dg := int(g>>8) - int(candidate.G)
+		db := int(b>>8) - int(candidate.B)
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = candidate, dist
+		}
+	}
+	return best
+}
+
+// corners samples the middle of the top left and top right quadrants
+func corners(img image.Image) (topLeft, topRight color.RGBA) {
+	b := img.Bounds()
+	y := b.Min.Y + b.Dy()/4
+	return nearest(img.At(b.Min.X+b.Dx()/4, y)), nearest(img.At(b.Min.X+b.Dx()*3/4, y))
+}
+
+func TestMakeThumbnail_Orientation(t *testing.T) {
+	tests := []struct {
+		orientation       uint16
+		width, height     int
+		topLeft, topRight color.RGBA
+	}{
+		{1, 64, 32, red, green},
+		{2, 64, 32, green, red},
+		{3, 64, 32, yellow, blue},
+		{4, 64, 32, blue, yellow},
+		{5, 32, 64, red, blue},
+		{6, 32, 64, blue, red},
+		{7, 32, 64, yellow, green},
+		{8, 32, 64, green, yellow},
+	}
+	fixture := encodeJPEG(t, quadrantImage(64, 32))
+	for _, tt := range tests {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			photo := withOrientation(fixture, tt.orientation, order)
+			require.Equal(t, int(tt.orientation), exifOrientation(photo))
+
+			data, err := makeThumbnail(photo)
+
+			require.NoError(t, err)
+			thumb := decodeThumbnail(t, data)
+			assert.Equal(t, tt.width, thumb.Bounds().Dx(), "orientation %d", tt.orientation)
+			assert.Equal(t, tt.height, thumb.Bounds().Dy(), "orientation %d", tt.orientation)
+			topLeft, topRight := corners(thumb)
+			assert.Equal(t, tt.topLeft, topLeft, "orientation %d top left", tt.orientation)
+			assert.Equal(t, tt.topRight, topRight, "orientation %d top right", tt.orientation)
+		}
+	}
+}
+
+func TestMakeThumbnail_Downscales(t *testing.T) {
+	t.Run("landscape", func(t *testing.T) {
+		data, err := makeThumbnail(encodeJPEG(t, quadrantImage(1200, 800)))
+
+		require.NoError(t, err)
+		thumb := decodeThumbnail(t, data)
+		assert.Equal(t, image.Rect(0, 0, 256, 171), thumb.Bounds())
+		topLeft, topRight := corners(thumb)
+		assert.Equal(t, red, topLeft)
+		assert.Equal(t, green, topRight)
+	})
+
+	t.Run("rotated portrait", func(t *testing.T) {
+		// Stored sideways as 1000x500, shown as 500x1000
+		data, err := makeThumbnail(withOrientation(encodeJPEG(t, quadrantImage(1000, 500)), 6, binary.BigEndian))
+
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 128, 256), decodeThumbnail(t, data).Bounds())
+	})
+
+	t.Run("small images keep their size", func(t *testing.T) {
+		data, err := makeThumbnail(encodeJPEG(t, quadrantImage(100, 40)))
+
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 100, 40), decodeThumbnail(t, data).Bounds())
+	})
+
+	t.Run("thin strip keeps a pixel", func(t *testing.T) {
+		data, err := makeThumbnail(encodeJPEG(t, quadrantImage(2000, 1)))
+
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 256, 1), decodeThumbnail(t, data).Bounds())
+	})
+}
+
+func TestMakeThumbnail_PNG(t *testing.T) {
+	img := quadrantImage(600, 300)
+	// The right half is transparent
+	for y := 0; y < 300; y++ {
+		for x := 300; x < 600; x++ {
+			img.SetRGBA(x, y, color.RGBA{})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+
+	data, err := makeThumbnail(buf.Bytes())
+
+	require.NoError(t, err)
+	thumb := decodeThumbnail(t, data)
+	assert.Equal(t, image.Rect(0, 0, 256, 128), thumb.Bounds())
+	topLeft, topRight := corners(thumb)
+	assert.Equal(t, red, topLeft)
+	assert.Equal(t, white, topRight, "transparent areas become white")
+}
+
+func TestMakeThumbnail_Invalid(t *testing.T) {
+	t.Run("not an image", func(t *testing.T) {
+		_, err := makeThumbnail([]byte("not an image"))
+		assert.Error(t, err)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := encodeJPEG(t, quadrantImage(64, 32))
+		_, err := makeThumbnail(data[:len(data)/2])
+		assert.Error(t, err)
+	})
+
+	t.Run("huge dimensions", func(t *testing.T) {
+		// A valid header declaring 20000x20000 pixels
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+		data := buf.Bytes()
+		binary.BigEndian.PutUint32(data[16:], 20000)
+		binary.BigEndian.PutUint32(data[20:], 20000)
+		binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+		_, err := makeThumbnail(data)
+		assert.ErrorIs(t, err, errPhotoDimensions)
+	})
+}
+
+func TestExifOrientation(t *testing.T) {
+	fixture := encodeJPEG(t, quadrantImage(8, 8))
+
+	assert.Equal(t, 1, exifOrientation(fixture), "no EXIF")
+	assert.Equal(t, 1, exifOrientation([]byte("\x89PNG")), "not a JPEG")
+	assert.Equal(t, 1, exifOrientation(withOrientation(fixture, 9, binary.LittleEndian)), "out of range")
+
+	truncated := withOrientation(fixture, 6, binary.LittleEndian)[:20]
+	assert.Equal(t, 1, exifOrientation(truncated))
+}
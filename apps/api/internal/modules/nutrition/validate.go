@@ -0,0 +1,138 @@
+package nutrition
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// Meal types
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnack     = "snack"
+)
+
+// Entry limits
+const (
+	MaxCalories   = 10000
+	MaxMacroGrams = 1000
+	// MaxFiberGrams and MaxSodiumMg cap the micronutrients; sugar and
+	// saturated fat share MaxMacroGrams
+	MaxFiberGrams = 200
+	MaxSodiumMg   = 50000
+
+	// maxEntryAgeYears bounds how far back an entry may be logged
+	maxEntryAgeYears = 2
+	// macroTolerance is how far 4P+4C+9F may drift from the stated calories
+	// before the entry gets a warning (labels round, fibre and alcohol skew it)
+	macroTolerance = 0.3
+)
+
+// mealAliases maps accepted meal names, including Russian ones, to canonical meal types
+var mealAliases = map[string]string{
+	MealBreakfast: MealBreakfast,
+	MealLunch:     MealLunch,
+	MealDinner:    MealDinner,
+	MealSnack:     MealSnack,
+	"завтрак":     MealBreakfast,
+	"обед":        MealLunch,
+	"ужин":        MealDinner,
+	"перекус":     MealSnack,
+}
+
+// normalizeMeal returns the canonical meal type for an accepted meal name
+func normalizeMeal(meal string) (string, bool) {
+	canonical, ok := mealAliases[strings.ToLower(strings.TrimSpace(meal))]
+	return canonical, ok
+}
+
+// validateEntry checks an entry request against what binding tags cannot
+// express and normalizes req.Meal to its canonical value
+func validateEntry(req *CreateEntryRequest, now time.Time) error {
+	errs := validation.Errors{}
+
+	if msg := dateError(req.Date, now); msg != "" {
+		errs["date"] = msg
+	}
+
+	if strings.TrimSpace(req.Food) == "" {
+		errs["food"] = "Обязательное поле"
+	}
+
+	if meal, ok := normalizeMeal(req.Meal); ok {
+		req.Meal = meal
+	} else {
+		errs["meal"] = "Допустимые значения: breakfast, lunch, dinner, snack"
+	}
+
+	if req.Calories == nil {
+		errs["calories"] = "Обязательное поле"
+	} else if !inRange(*req.Calories, MaxCalories) {
+		errs["calories"] = fmt.Sprintf("Значение должно быть от 0 до %d", MaxCalories)
+	}
+
+	for field, grams := range map[string]float64{"protein": req.Protein, "carbs": req.Carbs, "fat": req.Fat} {
+		if !inRange(grams, MaxMacroGrams) {
+			errs[field] = fmt.Sprintf("Значение должно быть от 0 до %d", MaxMacroGrams)
+		}
+	}
+
+	for field, micro := range map[string]struct {
+		value *float64
+		limit float64
+	}{
+		"fiber_g":         {req.FiberG, MaxFiberGrams},
+		"sugar_g":         {req.SugarG, MaxMacroGrams},
+		"sodium_mg":       {req.SodiumMg, MaxSodiumMg},
+		"saturated_fat_g": {req.SaturatedFatG, MaxMacroGrams},
+	} {
+		if micro.value != nil && !inRange(*micro.value, micro.limit) {
+			errs[field] = fmt.Sprintf("Значение должно быть от 0 до %.0f", micro.limit)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// dateError checks a logged date, returning the message for an invalid one.
+// now is used for the date window: up to two years back and one day ahead,
+// which covers clients in time zones ahead of the server.
+func dateError(date types.Date, now time.Time) string {
+	if date.IsZero() || date.Malformed() {
+		return types.DateMessage
+	}
+	switch {
+	case date.Before(types.DateOf(now.AddDate(-maxEntryAgeYears, 0, 0))):
+		return fmt.Sprintf("Дата не может быть раньше чем %d года назад", maxEntryAgeYears)
+	case date.After(types.DateOf(now).AddDays(1)):
+		return "Дата не может быть в будущем"
+	}
+	return ""
+}
+
+func inRange(v, limit float64) bool {
+	return !math.IsNaN(v) && v >= 0 && v <= limit
+}
+
+// macroWarning returns a warning when the calories implied by the macros
+// (4 kcal/g protein and carbs, 9 kcal/g fat) differ from the stated calories
+// by more than macroTolerance. Entries without macros are not checked.
+func macroWarning(calories, protein, carbs, fat float64) string {
+	fromMacros := 4*protein + 4*carbs + 9*fat
+	if fromMacros == 0 {
+		return ""
+	}
+	if calories > 0 && math.Abs(fromMacros-calories) <= calories*macroTolerance {
+		return ""
+	}
+	return fmt.Sprintf("Калорийность (%.0f ккал) не сходится с БЖУ (≈%.0f ккал). Проверьте значения.", calories, fromMacros)
+}
@@ -0,0 +1,171 @@
+package nutrition
+
+import (
+	"math"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validEntryRequest() *CreateEntryRequest {
+	return &CreateEntryRequest{
+		Date:     testDate("2026-01-26"),
+		Meal:     MealBreakfast,
+		Food:     "Овсянка",
+		Calories: floatPtr(150),
+		Protein:  5,
+		Carbs:    27,
+		Fat:      3,
+	}
+}
+
+func TestValidateEntry(t *testing.T) {
+	// testNow is 2026-02-01
+	tests := []struct {
+		name   string
+		modify func(req *CreateEntryRequest)
+		want   validation.Errors
+	}{
+		{"valid entry", func(req *CreateEntryRequest) {}, nil},
+
+		{"date today", func(req *CreateEntryRequest) { req.Date = testDate("2026-02-01") }, nil},
+		{"date tomorrow for clients ahead of UTC", func(req *CreateEntryRequest) { req.Date = testDate("2026-02-02") }, nil},
+		{"date exactly two years ago", func(req *CreateEntryRequest) { req.Date = testDate("2024-02-01") }, nil},
+		{"date garbage", func(req *CreateEntryRequest) { req.Date = testDate("garbage") },
+			validation.Errors{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"}},
+		{"date in day-first format", func(req *CreateEntryRequest) { req.Date = testDate("26.01.2026") },
+			validation.Errors{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"}},
+		{"date with time", func(req *CreateEntryRequest) { req.Date = testDate("2026-01-26T10:00:00Z") },
+			validation.Errors{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"}},
+		{"date impossible", func(req *CreateEntryRequest) { req.Date = testDate("2026-02-30") },
+			validation.Errors{"date": "Неверный формат даты, ожидается ГГГГ-ММ-ДД"}},
+		{"date two days ahead", func(req *CreateEntryRequest) { req.Date = testDate("2026-02-03") },
+			validation.Errors{"date": "Дата не может быть в будущем"}},
+		{"date older than two years", func(req *CreateEntryRequest) { req.Date = testDate("2024-01-31") },
+			validation.Errors{"date": "Дата не может быть раньше чем 2 года назад"}},
+
+		{"meal unknown", func(req *CreateEntryRequest) { req.Meal = "foo" },
+			validation.Errors{"meal": "Допустимые значения: breakfast, lunch, dinner, snack"}},
+		{"meal empty", func(req *CreateEntryRequest) { req.Meal = "" },
+			validation.Errors{"meal": "Допустимые значения: breakfast, lunch, dinner, snack"}},
+
+		{"food blank", func(req *CreateEntryRequest) { req.Food = "  " },
+			validation.Errors{"food": "Обязательное поле"}},
+
+		{"calories missing", func(req *CreateEntryRequest) { req.Calories = nil },
+			validation.Errors{"calories": "Обязательное поле"}},
+		{"calories zero", func(req *CreateEntryRequest) { req.Calories = floatPtr(0); req.Protein, req.Carbs, req.Fat = 0, 0, 0 }, nil},
+		{"calories at limit", func(req *CreateEntryRequest) { req.Calories = floatPtr(MaxCalories) }, nil},
+		{"calories negative", func(req *CreateEntryRequest) { req.Calories = floatPtr(-500) },
+			validation.Errors{"calories": "Значение должно быть от 0 до 10000"}},
+		{"calories over limit", func(req *CreateEntryRequest) { req.Calories = floatPtr(MaxCalories + 1) },
+			validation.Errors{"calories": "Значение должно быть от 0 до 10000"}},
+		{"calories NaN", func(req *CreateEntryRequest) { req.Calories = floatPtr(math.NaN()) },
+			validation.Errors{"calories": "Значение должно быть от 0 до 10000"}},
+
+		{"macros at limit", func(req *CreateEntryRequest) {
+			req.Protein, req.Carbs, req.Fat = MaxMacroGrams, MaxMacroGrams, MaxMacroGrams
+		}, nil},
+		{"protein negative", func(req *CreateEntryRequest) { req.Protein = -1 },
+			validation.Errors{"protein": "Значение должно быть от 0 до 1000"}},
+		{"carbs over limit", func(req *CreateEntryRequest) { req.Carbs = 1000.5 },
+			validation.Errors{"carbs": "Значение должно быть от 0 до 1000"}},
+		{"fat infinite", func(req *CreateEntryRequest) { req.Fat = math.Inf(1) },
+			validation.Errors{"fat": "Значение должно быть от 0 до 1000"}},
+
+		{"micronutrients left out", func(req *CreateEntryRequest) { req.Micronutrients = Micronutrients{} }, nil},
+		{"micronutrients at limit", func(req *CreateEntryRequest) {
+			req.Micronutrients = Micronutrients{
+				FiberG: floatPtr(MaxFiberGrams), SugarG: floatPtr(MaxMacroGrams),
+				SodiumMg: floatPtr(MaxSodiumMg), SaturatedFatG: floatPtr(0),
+			}
+		}, nil},
+		{"fiber negative", func(req *CreateEntryRequest) { req.FiberG = floatPtr(-1) },
+			validation.Errors{"fiber_g": "Значение должно быть от 0 до 200"}},
+		{"sodium over limit", func(req *CreateEntryRequest) { req.SodiumMg = floatPtr(MaxSodiumMg + 1) },
+			validation.Errors{"sodium_mg": "Значение должно быть от 0 до 50000"}},
+		{"saturated fat NaN", func(req *CreateEntryRequest) { req.SaturatedFatG = floatPtr(math.NaN()) },
+			validation.Errors{"saturated_fat_g": "Значение должно быть от 0 до 1000"}},
+
+		{"several fields at once", func(req *CreateEntryRequest) {
+			req.Date = testDate("garbage")
+			req.Meal = "foo"
+			req.Calories = floatPtr(-500)
+			req.Protein, req.Fat = -1, -2
+		}, validation.Errors{
+			"date":     "Неверный формат даты, ожидается ГГГГ-ММ-ДД",
+			"meal":     "Допустимые значения: breakfast, lunch, dinner, snack",
+			"calories": "Значение должно быть от 0 до 10000",
+			"protein":  "Значение должно быть от 0 до 1000",
+			"fat":      "Значение должно быть от 0 до 1000",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validEntryRequest()
+			tt.modify(req)
+
+			err := validateEntry(req, testNow)
+
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Equal(t, tt.want, fieldErrs)
+		})
+	}
+}
+
+func TestValidateEntryNormalizesMeal(t *testing.T) {
+	tests := map[string]string{
+		"breakfast": MealBreakfast,
+		"Lunch":     MealLunch,
+		" dinner ":  MealDinner,
+		"SNACK":     MealSnack,
+		"завтрак":   MealBreakfast,
+		"Обед":      MealLunch,
+		"УЖИН":      MealDinner,
+		"перекус":   MealSnack,
+	}
+
+	for meal, want := range tests {
+		t.Run(meal, func(t *testing.T) {
+			req := validEntryRequest()
+			req.Meal = meal
+
+			require.NoError(t, validateEntry(req, testNow))
+			assert.Equal(t, want, req.Meal)
+		})
+	}
+}
+
+func TestMacroWarning(t *testing.T) {
+	tests := []struct {
+		name                          string
+		calories, protein, carbs, fat float64
+		warn                          bool
+	}{
+		{"exact match", 150, 5, 27, 3, false},               // 155 kcal from macros
+		{"no macros given", 300, 0, 0, 0, false},            // nothing to compare
+		{"within 30 percent above", 100, 0, 32, 0, false},   // 128 kcal
+		{"within 30 percent below", 100, 0, 18, 0, false},   // 72 kcal
+		{"more than 30 percent above", 100, 0, 33, 0, true}, // 132 kcal
+		{"more than 30 percent below", 100, 0, 17, 0, true}, // 68 kcal
+		{"zero calories with macros", 0, 10, 0, 0, true},
+		{"fat counts 9 kcal per gram", 90, 0, 0, 10, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warning := macroWarning(tt.calories, tt.protein, tt.carbs, tt.fat)
+			assert.Equal(t, tt.warn, warning != "", warning)
+		})
+	}
+
+	assert.Equal(t, "Калорийность (100 ккал) не сходится с БЖУ (≈200 ккал). Проверьте значения.", macroWarning(100, 25, 25, 0))
+}
@@ -0,0 +1,179 @@
+package nutrition
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// Water intake limits
+const (
+	MinWaterML = 10
+	MaxWaterML = 3000
+	// WaterWarningML is the daily total above which the day gets a warning
+	WaterWarningML = 8000
+)
+
+// AddWaterRequest represents a water intake to log
+type AddWaterRequest struct {
+	Date     types.Date `json:"date" binding:"required"`
+	AmountML *int       `json:"amount_ml" binding:"required"`
+}
+
+// WaterEvent is one logged water intake
+type WaterEvent struct {
+	ID        string    `json:"id"`
+	Date      string    `json:"date"`
+	AmountML  int       `json:"amount_ml"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WaterDay is the water intake of one day
+type WaterDay struct {
+	Date    string        `json:"date"`
+	TotalML int           `json:"total_ml"`
+	Events  []*WaterEvent `json:"events"`
+	Warning string        `json:"warning,omitempty"`
+}
+
+// WaterService handles water intake logging
+type WaterService struct {
+	db  *database.DB
+	log *logger.Logger
+	now func() time.Time
+}
+
+// NewWaterService creates a new water intake service
+func NewWaterService(db *database.DB, log *logger.Logger) *WaterService {
+	return &WaterService{db: db, log: log, now: time.Now}
+}
+
+// validateWater checks a water intake request. Invalid input is reported as
+// validation.Errors.
+func validateWater(req *AddWaterRequest, now time.Time) error {
+	errs := validation.Errors{}
+
+	if msg := dateError(req.Date, now); msg != "" {
+		errs["date"] = msg
+	}
+	if req.AmountML == nil {
+		errs["amount_ml"] = "Обязательное поле"
+	} else if *req.AmountML < MinWaterML || *req.AmountML > MaxWaterML {
+		errs["amount_ml"] = fmt.Sprintf("Значение должно быть от %d до %d мл", MinWaterML, MaxWaterML)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// summarizeWater adds up the events of a day. A total above WaterWarningML
+// gets a warning; the intakes are recorded either way.
+func summarizeWater(date string, events []*WaterEvent) *WaterDay {
+	day := &WaterDay{Date: date, Events: events}
+	for _, e := range events {
+		day.TotalML += e.AmountML
+	}
+	if day.TotalML > WaterWarningML {
+		day.Warning = fmt.Sprintf("За день выпито больше %d мл воды. Проверьте записи.", WaterWarningML)
+	}
+	return day
+}
+
+// AddWater logs a water intake and returns it with the updated day
+func (s *WaterService) AddWater(ctx context.Context, userID int64, req *AddWaterRequest) (*WaterEvent, *WaterDay, error) {
+	if err := validateWater(req, s.now()); err != nil {
+		return nil, nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO water_intake_events (user_id, date, amount_ml)
+		VALUES ($1, $2, $3)
+		RETURNING id, date::text, amount_ml, created_at`
+
+	var e WaterEvent
+	err := s.db.QueryRowContext(ctx, query, userID, req.Date, *req.AmountML).
+		Scan(&e.ID, &e.Date, &e.AmountML, &e.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to add water intake: %w", err)
+	}
+
+	day, err := s.GetWaterDay(ctx, userID, req.Date)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &e, day, nil
+}
+
+// GetWaterDay returns the water intake events of a day, oldest first, and
+// their total
+func (s *WaterService) GetWaterDay(ctx context.Context, userID int64, date types.Date) (*WaterDay, error) {
+	startTime := time.Now()
+	query := `
+		SELECT id, date::text, amount_ml, created_at
+		FROM water_intake_events
+		WHERE user_id = $1 AND date = $2
+		ORDER BY created_at, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, date)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"date":    date.String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query water intake: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]*WaterEvent, 0)
+	for rows.Next() {
+		var e WaterEvent
+		if err := rows.Scan(&e.ID, &e.Date, &e.AmountML, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan water intake: %w", err)
+		}
+		events = append(events, &e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating water intake: %w", err)
+	}
+
+	return summarizeWater(date.String(), events), nil
+}
+
+// DeleteWater removes a water intake event owned by the user. Events of other
+// users are reported as not found.
+func (s *WaterService) DeleteWater(ctx context.Context, userID int64, eventID string) error {
+	if _, err := uuid.Parse(eventID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `DELETE FROM water_intake_events WHERE id = $1 AND user_id = $2`
+
+	result, err := s.db.ExecContext(ctx, query, eventID, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  userID,
+		"event_id": eventID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete water intake: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to delete water intake: %w", err)
+	}
+	if affected == 0 {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package nutrition
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWaterID = "9c1d2e3f-4a5b-4c6d-8e7f-0a1b2c3d4e5f"
+
+func intPtr(v int) *int { return &v }
+
+func waterRows(amounts ...int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "date", "amount_ml", "created_at"})
+	for _, ml := range amounts {
+		rows.AddRow(testWaterID, "2026-01-26", ml, testNow)
+	}
+	return rows
+}
+
+func setupWaterService(t *testing.T) (*WaterService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	service := NewWaterService(&database.DB{DB: mockDB}, logger.New())
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func TestValidateWater(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    AddWaterRequest
+		fields []string
+	}{
+		{"minimum", AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(MinWaterML)}, nil},
+		{"maximum", AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(MaxWaterML)}, nil},
+		{"below minimum", AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(MinWaterML - 1)}, []string{"amount_ml"}},
+		{"above maximum", AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(MaxWaterML + 1)}, []string{"amount_ml"}},
+		{"negative", AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(-250)}, []string{"amount_ml"}},
+		{"missing amount", AddWaterRequest{Date: testDate("2026-01-26")}, []string{"amount_ml"}},
+		{"future date", AddWaterRequest{Date: testDate("2026-02-05"), AmountML: intPtr(250)}, []string{"date"}},
+		{"bad date", AddWaterRequest{Date: testDate("26.01.2026"), AmountML: intPtr(0)}, []string{"date", "amount_ml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWater(&tt.req, testNow)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+}
+
+func TestSummarizeWater(t *testing.T) {
+	t.Run("no events", func(t *testing.T) {
+		day := summarizeWater("2026-01-26", []*WaterEvent{})
+
+		assert.Zero(t, day.TotalML)
+		assert.Empty(t, day.Events)
+		assert.Empty(t, day.Warning)
+	})
+
+	t.Run("sums events", func(t *testing.T) {
+		day := summarizeWater("2026-01-26", []*WaterEvent{{AmountML: 250}, {AmountML: 500}, {AmountML: 330}})
+
+		assert.Equal(t, 1080, day.TotalML)
+		assert.Len(t, day.Events, 3)
+		assert.Empty(t, day.Warning)
+	})
+
+	t.Run("at the warning threshold", func(t *testing.T) {
+		day := summarizeWater("2026-01-26", []*WaterEvent{{AmountML: 3000}, {AmountML: 3000}, {AmountML: 2000}})
+
+		assert.Equal(t, WaterWarningML, day.TotalML)
+		assert.Empty(t, day.Warning)
+	})
+
+	t.Run("above the warning threshold", func(t *testing.T) {
+		day := summarizeWater("2026-01-26", []*WaterEvent{{AmountML: 3000}, {AmountML: 3000}, {AmountML: 2010}})
+
+		assert.Equal(t, 8010, day.TotalML)
+		assert.NotEmpty(t, day.Warning)
+	})
+}
+
+func TestWaterService_AddWater(t *testing.T) {
+	service, mock := setupWaterService(t)
+	mock.ExpectQuery("INSERT INTO water_intake_events").
+		WithArgs(testUserID, "2026-01-26", 250).
+		WillReturnRows(waterRows(250))
+	mock.ExpectQuery("FROM water_intake_events").
+		WithArgs(testUserID, "2026-01-26").
+		WillReturnRows(waterRows(500, 250))
+
+	event, day, err := service.AddWater(context.Background(), testUserID, &AddWaterRequest{Date: testDate("2026-01-26"), AmountML: intPtr(250)})
+
+	require.NoError(t, err)
+	assert.Equal(t, 250, event.AmountML)
+	assert.Equal(t, 750, day.TotalML)
+	assert.Len(t, day.Events, 2)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWaterService_DeleteWater(t *testing.T) {
+	t.Run("own event", func(t *testing.T) {
+		service, mock := setupWaterService(t)
+		mock.ExpectExec("DELETE FROM water_intake_events WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(testWaterID, testUserID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, service.DeleteWater(context.Background(), testUserID, testWaterID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's or missing event", func(t *testing.T) {
+		service, mock := setupWaterService(t)
+		mock.ExpectExec("DELETE FROM water_intake_events").
+			WithArgs(testWaterID, otherUserID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := service.DeleteWater(context.Background(), otherUserID, testWaterID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, mock := setupWaterService(t)
+
+		err := service.DeleteWater(context.Background(), testUserID, "water-1")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteWaterHandler(t *testing.T) {
+	t.Run("deleted", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectExec("DELETE FROM water_intake_events").WithArgs(testWaterID, testUserID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		status, _ := serve(t, handler.DeleteWater, testUserID, http.MethodDelete, "/entries/"+testWaterID, "")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.DeleteWater, testUserID, http.MethodDelete, "/entries/water-1", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAddWaterHandler(t *testing.T) {
+	t.Run("created", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("INSERT INTO water_intake_events").WillReturnRows(waterRows(3000))
+		mock.ExpectQuery("FROM water_intake_events").WillReturnRows(waterRows(3000, 3000, 3000))
+
+		status, resp := serve(t, handler.AddWater, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2026-01-26","amount_ml":3000}`)
+
+		assert.Equal(t, http.StatusCreated, status)
+		day := resp["data"].(map[string]interface{})["day"].(map[string]interface{})
+		assert.Equal(t, float64(9000), day["total_ml"])
+		assert.NotEmpty(t, day["warning"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("amount out of range", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+
+		status, resp := serve(t, handler.AddWater, testUserID, http.MethodPost, "/entries/",
+			`{"date":"2026-01-26","amount_ml":5000}`)
+
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "VALIDATION_FAILED", resp["code"])
+		assert.Contains(t, resp["details"].(map[string]interface{})["fields"], "amount_ml")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestGetWaterHandler(t *testing.T) {
+	t.Run("given date", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		mock.ExpectQuery("FROM water_intake_events").
+			WithArgs(testUserID, "2026-01-26").
+			WillReturnRows(waterRows(250, 500))
+
+		status, resp := serve(t, handler.GetWater, testUserID, http.MethodGet, "/entries/?date=2026-01-26", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		data := resp["data"].(map[string]interface{})
+		assert.Equal(t, float64(750), data["total_ml"])
+		assert.Len(t, data["events"], 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("today in the requested timezone", func(t *testing.T) {
+		handler, mock := setupTestHandler(t)
+		today := time.Now().In(time.FixedZone("", 14*60*60)).Format("2006-01-02")
+		mock.ExpectQuery("FROM water_intake_events").
+			WithArgs(testUserID, today).
+			WillReturnRows(waterRows())
+
+		status, resp := serve(t, handler.GetWater, testUserID, http.MethodGet, "/entries/?tz=Pacific/Kiritimati", "")
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, float64(0), resp["data"].(map[string]interface{})["total_ml"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("bad date", func(t *testing.T) {
+		handler, _ := setupTestHandler(t)
+
+		status, _ := serve(t, handler.GetWater, testUserID, http.MethodGet, "/entries/?date=yesterday", "")
+
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
@@ -0,0 +1,97 @@
+package organizations
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles organization management requests (super_admin only)
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new organizations handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// ListOrganizations handles GET /api/v1/admin/organizations
+func (h *Handler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.service.ListOrganizations(c.Request.Context())
+	if err != nil {
+		h.log.Error("Failed to list organizations", "error", err)
+		response.InternalError(c, "Не удалось загрузить организации")
+		return
+	}
+
+	response.Success(c, http.StatusOK, orgs)
+}
+
+// CreateOrganization handles POST /api/v1/admin/organizations
+func (h *Handler) CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуется name")
+		return
+	}
+
+	org, err := h.service.CreateOrganization(c.Request.Context(), req)
+	if err != nil {
+		if errors.Is(err, ErrUnknownRegion) {
+			response.Error(c, http.StatusBadRequest, "Неизвестный регион хранения")
+			return
+		}
+		h.log.Error("Failed to create organization", "error", err)
+		response.InternalError(c, "Не удалось создать организацию")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, org)
+}
+
+// ChangeStorageRegion handles PUT /api/v1/admin/organizations/:id/storage-region
+// Switches new writes immediately and returns 202 while existing files are moved in the background.
+func (h *Handler) ChangeStorageRegion(c *gin.Context) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор организации")
+		return
+	}
+
+	var req ChangeRegionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуется storage_region")
+		return
+	}
+
+	migration, err := h.service.ChangeStorageRegion(c.Request.Context(), orgID, req.StorageRegion)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnknownRegion):
+			response.Error(c, http.StatusBadRequest, "Неизвестный регион хранения")
+		case errors.Is(err, ErrSameRegion):
+			response.Error(c, http.StatusConflict, "Организация уже использует этот регион")
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Организация не найдена")
+		default:
+			h.log.Error("Failed to change storage region", "error", err, "organization_id", orgID)
+			response.InternalError(c, "Не удалось изменить регион хранения")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, migration)
+}
@@ -0,0 +1,141 @@
+package organizations
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	changeErr error
+}
+
+func (m *mockService) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	return []Organization{}, nil
+}
+
+func (m *mockService) CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	return &Organization{ID: 1, Name: req.Name, StorageRegion: req.StorageRegion}, nil
+}
+
+func (m *mockService) ChangeStorageRegion(ctx context.Context, orgID int64, region string) (*RegionMigration, error) {
+	if m.changeErr != nil {
+		return nil, m.changeErr
+	}
+	return &RegionMigration{ID: 9, OrganizationID: orgID, ToRegion: region, Status: MigrationPending}, nil
+}
+
+func TestHandlerChangeStorageRegion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		err    error
+		status int
+	}{
+		{"accepts region change", "3", `{"storage_region":"eu"}`, nil, http.StatusAccepted},
+		{"invalid id", "abc", `{"storage_region":"eu"}`, nil, http.StatusBadRequest},
+		{"missing region", "3", `{}`, nil, http.StatusBadRequest},
+		{"unknown region", "3", `{"storage_region":"us"}`, ErrUnknownRegion, http.StatusBadRequest},
+		{"same region", "3", `{"storage_region":"eu"}`, ErrSameRegion, http.StatusConflict},
+		{"unknown organization", "3", `{"storage_region":"eu"}`, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{changeErr: tt.err})
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPut, "/admin/organizations/"+tt.id+"/storage-region", bytes.NewBufferString(tt.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler.ChangeStorageRegion(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+// mockImportService implements ImportServiceInterface for handler tests
+type mockImportService struct {
+	rows []ImportRow
+}
+
+func (m *mockImportService) ImportMembers(ctx context.Context, orgID int64, rows []ImportRow) (*ImportResult, error) {
+	m.rows = rows
+	return &ImportResult{Created: len(rows), Rows: []ImportRowResult{}}, nil
+}
+
+func (m *mockImportService) AcceptMembership(ctx context.Context, token, ipAddress string) (*JoinResult, error) {
+	if token != "valid" {
+		return nil, ErrInvalidJoinToken
+	}
+	return &JoinResult{OrganizationID: 3, OrganizationName: "Iron Gym"}, nil
+}
+
+func TestImportHandlerImportMembers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	upload := func(service *mockImportService, content string) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "members.csv")
+		part.Write([]byte(content))
+		writer.Close()
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/admin/organizations/3/import", body)
+		c.Request.Header.Set("Content-Type", writer.FormDataContentType())
+		c.Params = gin.Params{{Key: "id", Value: "3"}}
+
+		NewImportHandler(nil, logger.New(), service).ImportMembers(c)
+		return w
+	}
+
+	t.Run("imports uploaded rows", func(t *testing.T) {
+		service := &mockImportService{}
+
+		w := upload(service, "email,name\nanna@gym.ru,Анна\nivan@gym.ru,Иван\n")
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Len(t, service.rows, 2)
+	})
+
+	t.Run("rejects a file without an email column", func(t *testing.T) {
+		service := &mockImportService{}
+
+		w := upload(service, "name\nАнна\n")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "нет колонки email")
+		assert.Nil(t, service.rows)
+	})
+}
+
+func TestImportHandlerAcceptMembership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for token, status := range map[string]int{"valid": http.StatusOK, "stale": http.StatusBadRequest} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/organizations/join?token="+token, nil)
+
+		NewImportHandler(nil, logger.New(), &mockImportService{}).AcceptMembership(c)
+
+		assert.Equal(t, status, w.Code, token)
+	}
+}
@@ -0,0 +1,131 @@
+package organizations
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+)
+
+// Roles that can be assigned by an import. Admins are never created in bulk.
+var importRoles = map[string]bool{
+	"client":      true,
+	"coordinator": true,
+}
+
+// ParseImportCSV reads an import file with a header row. The email column is
+// required; name, role (client by default) and curator_email are optional.
+// Blank lines are skipped.
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: файл пуст", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("%w: нет колонки email", ErrInvalidImport)
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var rows []ImportRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if len(rows) == MaxImportRows {
+			return nil, fmt.Errorf("%w: больше %d строк", ErrInvalidImport, MaxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, ImportRow{
+			Line:         line,
+			Email:        field(record, "email"),
+			Name:         field(record, "name"),
+			Role:         field(record, "role"),
+			CuratorEmail: field(record, "curator_email"),
+		})
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: нет ни одной строки с участниками", ErrInvalidImport)
+	}
+
+	return rows, nil
+}
+
+// normalizeImportRows lowercases emails, defaults the role and validates every
+// row. It returns a result per row; rows that failed validation are already
+// marked ImportFailed.
+func normalizeImportRows(rows []ImportRow) []ImportRowResult {
+	results := make([]ImportRowResult, len(rows))
+	seen := make(map[string]int, len(rows))
+
+	for i := range rows {
+		row := &rows[i]
+		row.Email = strings.ToLower(row.Email)
+		row.CuratorEmail = strings.ToLower(row.CuratorEmail)
+		row.Role = strings.ToLower(row.Role)
+		if row.Role == "" {
+			row.Role = "client"
+		}
+
+		results[i] = ImportRowResult{Line: row.Line, Email: row.Email}
+		fail := func(msg string) {
+			results[i].Status = ImportFailed
+			results[i].Error = msg
+		}
+
+		switch {
+		case !validEmail(row.Email):
+			fail("Неверный email")
+		case !importRoles[row.Role]:
+			fail("Недопустимая роль: " + row.Role)
+		case row.CuratorEmail != "" && row.Role != "client":
+			fail("Куратора можно назначить только клиенту")
+		case row.CuratorEmail != "" && !validEmail(row.CuratorEmail):
+			fail("Неверный email куратора")
+		case row.CuratorEmail == row.Email:
+			fail("Участник не может быть своим куратором")
+		default:
+			if line, ok := seen[row.Email]; ok {
+				fail(fmt.Sprintf("Email уже указан в строке %d", line))
+				continue
+			}
+			seen[row.Email] = row.Line
+		}
+	}
+
+	return results
+}
+
+func validEmail(s string) bool {
+	addr, err := mail.ParseAddress(s)
+	return err == nil && addr.Address == s
+}
@@ -0,0 +1,93 @@
+package organizations
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles bulk member imports and the join links they send
+type ImportHandler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ImportServiceInterface
+}
+
+// NewImportHandler creates a new member import handler
+func NewImportHandler(cfg *config.Config, log *logger.Logger, service ImportServiceInterface) *ImportHandler {
+	return &ImportHandler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// ImportMembers handles POST /api/v1/admin/organizations/:id/import
+// Expects a multipart CSV upload in the "file" field with the columns
+// email, name, role and curator_email. Returns a result per row.
+func (h *ImportHandler) ImportMembers(c *gin.Context) {
+	orgID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор организации")
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Требуется CSV-файл в поле file")
+		return
+	}
+	if header.Size > MaxImportFileSize {
+		response.Error(c, http.StatusBadRequest, "Файл слишком большой (максимум 1 МБ)")
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Не удалось прочитать файл")
+		return
+	}
+	defer file.Close()
+
+	rows, err := ParseImportCSV(file)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := h.service.ImportMembers(c.Request.Context(), orgID, rows)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Организация не найдена")
+			return
+		}
+		h.log.Error("Failed to import organization members", "error", err, "organization_id", orgID)
+		response.InternalError(c, "Не удалось импортировать участников")
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+// AcceptMembership handles GET /api/v1/organizations/join?token=...
+// Opened from the join request email; public because the token identifies the user.
+func (h *ImportHandler) AcceptMembership(c *gin.Context) {
+	result, err := h.service.AcceptMembership(c.Request.Context(), c.Query("token"), c.ClientIP())
+	if err != nil {
+		if errors.Is(err, ErrInvalidJoinToken) {
+			response.Error(c, http.StatusBadRequest, "Ссылка недействительна или срок её действия истёк")
+			return
+		}
+		h.log.Error("Failed to accept organization membership", "error", err)
+		response.InternalError(c, "Не удалось подтвердить вступление в организацию")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Вы вступили в организацию "+result.OrganizationName, result)
+}
@@ -0,0 +1,648 @@
+package organizations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// inviteUserAgent marks set-password tokens issued by an import
+const inviteUserAgent = "organization-import"
+
+// Mailer sends the emails of a member import
+type Mailer interface {
+	SendOrganizationInviteEmail(ctx context.Context, data email.OrganizationInviteEmailData) error
+	SendOrganizationJoinEmail(ctx context.Context, data email.OrganizationJoinEmailData) error
+}
+
+// ImportServiceInterface defines the interface for member import operations
+type ImportServiceInterface interface {
+	ImportMembers(ctx context.Context, orgID int64, rows []ImportRow) (*ImportResult, error)
+	AcceptMembership(ctx context.Context, token, ipAddress string) (*JoinResult, error)
+}
+
+// ImportService onboards organization members in bulk. New members get an
+// invited account with a set-password link (a regular reset token); existing
+// users are only linked after they accept an emailed join request.
+type ImportService struct {
+	db     *database.DB
+	cfg    *config.Config
+	log    *logger.Logger
+	mailer Mailer
+	tokens *auth.TokenGenerator
+	audit  audit.ServiceInterface
+}
+
+// NewImportService creates a new member import service
+func NewImportService(db *database.DB, cfg *config.Config, log *logger.Logger, mailer Mailer) *ImportService {
+	return &ImportService{
+		db:     db,
+		cfg:    cfg,
+		log:    log,
+		mailer: mailer,
+		tokens: auth.NewTokenGenerator(auth.WithTokenBytes(cfg.ResetTokenBytes)),
+		audit:  audit.NewService(db.DB, log),
+	}
+}
+
+// existingUser is a user already registered with an email from the import
+type existingUser struct {
+	id             int64
+	role           string
+	organizationID *int64
+}
+
+// pendingEmail is an invitation or join request to send once the import is committed
+type pendingEmail struct {
+	index int
+	token string
+}
+
+// ImportMembers imports rows into the organization and returns a result per
+// row. Re-importing the same file is safe: members are not recreated and
+// outstanding join requests are not re-sent.
+func (s *ImportService) ImportMembers(ctx context.Context, orgID int64, rows []ImportRow) (*ImportResult, error) {
+	startTime := time.Now()
+
+	var orgName string
+	err := s.db.QueryRowContext(ctx, `SELECT name FROM organizations WHERE id = $1`, orgID).Scan(&orgName)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	results := normalizeImportRows(rows)
+
+	emails := make([]string, 0, len(rows))
+	for i, row := range rows {
+		if results[i].Status == ImportFailed {
+			continue
+		}
+		emails = append(emails, row.Email)
+		if row.CuratorEmail != "" {
+			emails = append(emails, row.CuratorEmail)
+		}
+	}
+
+	existing, err := s.lookupUsers(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	// Curators must already be coordinators or be created by this import
+	newCoordinators := make(map[string]bool)
+	for i, row := range rows {
+		if _, ok := existing[row.Email]; !ok && results[i].Status != ImportFailed && row.Role == "coordinator" {
+			newCoordinators[row.Email] = true
+		}
+	}
+
+	var creates, joins []int
+	for i, row := range rows {
+		if results[i].Status == ImportFailed {
+			continue
+		}
+		if row.CuratorEmail != "" {
+			if curator, ok := existing[row.CuratorEmail]; ok && curator.role != "coordinator" {
+				results[i].Status = ImportFailed
+				results[i].Error = "Пользователь " + row.CuratorEmail + " не является куратором"
+				continue
+			} else if !ok && !newCoordinators[row.CuratorEmail] {
+				results[i].Status = ImportFailed
+				results[i].Error = "Куратор " + row.CuratorEmail + " не найден"
+				continue
+			}
+		}
+
+		user, ok := existing[row.Email]
+		if !ok {
+			creates = append(creates, i)
+			continue
+		}
+		results[i].UserID = &user.id
+		if user.organizationID != nil && *user.organizationID == orgID {
+			results[i].Status = ImportAlreadyMember
+			continue
+		}
+		joins = append(joins, i)
+	}
+
+	expiresAt := time.Now().Add(InviteTTL)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created, err := s.insertUsers(ctx, tx, orgID, rows, creates)
+	if err != nil {
+		return nil, err
+	}
+
+	var invites []pendingEmail
+	for _, i := range creates {
+		id, ok := created[rows[i].Email]
+		if !ok {
+			// Registered between the lookup and the insert
+			results[i].Status = ImportFailed
+			results[i].Error = "Пользователь зарегистрировался во время импорта, повторите импорт"
+			continue
+		}
+		results[i].UserID = &id
+		results[i].Status = ImportCreated
+		invites = append(invites, pendingEmail{index: i})
+	}
+
+	userID := func(email string) (int64, bool) {
+		if u, ok := existing[email]; ok {
+			return u.id, true
+		}
+		id, ok := created[email]
+		return id, ok
+	}
+
+	if err := s.insertInviteTokens(ctx, tx, invites, results, expiresAt); err != nil {
+		return nil, err
+	}
+
+	var curatorLinks [][2]int64
+	for _, invite := range invites {
+		row := rows[invite.index]
+		if row.CuratorEmail == "" {
+			continue
+		}
+		curatorID, ok := userID(row.CuratorEmail)
+		if !ok {
+			results[invite.index].Error = "Куратор не назначен: его аккаунт не был создан"
+			continue
+		}
+		curatorLinks = append(curatorLinks, [2]int64{curatorID, *results[invite.index].UserID})
+	}
+	if err := s.linkCurators(ctx, tx, curatorLinks); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.upsertJoinRequests(ctx, tx, orgID, rows, joins, results, userID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, invite := range invites {
+		row := rows[invite.index]
+		err := s.mailer.SendOrganizationInviteEmail(ctx, email.OrganizationInviteEmailData{
+			UserEmail:        row.Email,
+			UserName:         row.Name,
+			OrganizationName: orgName,
+			SetPasswordURL:   auth.ResetURL(s.cfg.ResetPasswordURL, invite.token),
+			ExpiresAt:        expiresAt,
+		})
+		if err != nil {
+			s.log.Error("Failed to send organization invite", "error", err, "organization_id", orgID, "user_id", *results[invite.index].UserID)
+			results[invite.index].Error = "Аккаунт создан, но письмо не отправлено: пароль можно задать через «Забыли пароль?»"
+		}
+	}
+
+	for _, request := range requests {
+		row := rows[request.index]
+		err := s.mailer.SendOrganizationJoinEmail(ctx, email.OrganizationJoinEmailData{
+			UserEmail:        row.Email,
+			OrganizationName: orgName,
+			AcceptURL:        s.cfg.OrganizationJoinURL + "?" + url.Values{"token": {request.token}}.Encode(),
+			ExpiresAt:        expiresAt,
+		})
+		if err != nil {
+			s.log.Error("Failed to send organization join request", "error", err, "organization_id", orgID, "user_id", *results[request.index].UserID)
+			// Expire the unsent request so the next import sends a fresh one
+			if _, expireErr := s.db.ExecContext(ctx,
+				`UPDATE organization_membership_requests SET expires_at = NOW() WHERE organization_id = $1 AND user_id = $2`,
+				orgID, *results[request.index].UserID,
+			); expireErr != nil {
+				s.log.Error("Failed to expire unsent join request", "error", expireErr, "organization_id", orgID)
+			}
+			results[request.index].Status = ImportFailed
+			results[request.index].Error = "Не удалось отправить письмо, повторите импорт"
+		}
+	}
+
+	result := &ImportResult{Rows: results}
+	for _, r := range results {
+		switch r.Status {
+		case ImportCreated:
+			result.Created++
+		case ImportConsentRequested:
+			result.ConsentRequested++
+		case ImportConsentPending:
+			result.ConsentPending++
+		case ImportAlreadyMember:
+			result.AlreadyMember++
+		case ImportFailed:
+			result.Failed++
+		}
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		Action: audit.ActionMembersImported,
+		Metadata: map[string]any{
+			"organization_id":   orgID,
+			"rows":              len(rows),
+			"created":           result.Created,
+			"consent_requested": result.ConsentRequested,
+			"failed":            result.Failed,
+		},
+	})
+	s.log.LogBusinessEvent("organization_members_imported", map[string]interface{}{
+		"organization_id":   orgID,
+		"rows":              len(rows),
+		"created":           result.Created,
+		"consent_requested": result.ConsentRequested,
+		"consent_pending":   result.ConsentPending,
+		"already_member":    result.AlreadyMember,
+		"failed":            result.Failed,
+		"duration":          time.Since(startTime).String(),
+	})
+
+	return result, nil
+}
+
+// lookupUsers returns registered users by lowercased email
+func (s *ImportService) lookupUsers(ctx context.Context, emails []string) (map[string]existingUser, error) {
+	users := make(map[string]existingUser, len(emails))
+
+	for start := 0; start < len(emails); start += importBatchSize {
+		batch := emails[start:min(start+importBatchSize, len(emails))]
+		placeholders := make([]string, len(batch))
+		args := make([]any, len(batch))
+		for i, e := range batch {
+			placeholders[i] = fmt.Sprintf("$%d", i+1)
+			args[i] = e
+		}
+
+		startTime := time.Now()
+		query := `SELECT id, LOWER(email), role, organization_id FROM users WHERE LOWER(email) IN (` + strings.Join(placeholders, ", ") + `)`
+		rows, err := s.db.QueryContext(ctx, query, args...)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"emails": len(batch),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up users: %w", err)
+		}
+
+		for rows.Next() {
+			var u existingUser
+			var e string
+			if err := rows.Scan(&u.id, &e, &u.role, &u.organizationID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan user: %w", err)
+			}
+			users[e] = u
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate users: %w", err)
+		}
+	}
+
+	return users, nil
+}
+
+// insertUsers creates invited accounts in batches and returns their IDs by
+// email. Emails registered concurrently are skipped and missing from the map.
+// Invited accounts get a random password nobody knows until they set their own.
+func (s *ImportService) insertUsers(ctx context.Context, tx *sql.Tx, orgID int64, rows []ImportRow, indexes []int) (map[string]int64, error) {
+	created := make(map[string]int64, len(indexes))
+
+	for start := 0; start < len(indexes); start += importBatchSize {
+		batch := indexes[start:min(start+importBatchSize, len(indexes))]
+		values := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*5)
+		for i, idx := range batch {
+			password, err := s.unusablePassword()
+			if err != nil {
+				return nil, err
+			}
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+			args = append(args, rows[idx].Email, password, rows[idx].Name, rows[idx].Role, orgID)
+		}
+
+		startTime := time.Now()
+		query := `INSERT INTO users (email, password, name, role, organization_id) VALUES ` +
+			strings.Join(values, ", ") + ` ON CONFLICT (email) DO NOTHING RETURNING id, email`
+		result, err := tx.QueryContext(ctx, query, args...)
+		s.log.LogDatabaseQuery("INSERT INTO users (import)", time.Since(startTime), err, map[string]interface{}{
+			"organization_id": orgID,
+			"rows":            len(batch),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create users: %w", err)
+		}
+
+		for result.Next() {
+			var id int64
+			var e string
+			if err := result.Scan(&id, &e); err != nil {
+				result.Close()
+				return nil, fmt.Errorf("failed to scan created user: %w", err)
+			}
+			created[e] = id
+		}
+		err = result.Err()
+		result.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate created users: %w", err)
+		}
+	}
+
+	return created, nil
+}
+
+// unusablePassword returns a bcrypt hash of a random secret that is never shown to anyone
+func (s *ImportService) unusablePassword() (string, error) {
+	secret, _, err := s.tokens.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+	// MinCost is enough for a 256-bit random secret and keeps large imports fast
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.MinCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash placeholder password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// insertInviteTokens stores a set-password reset token per invite and fills in
+// the plain tokens to email
+func (s *ImportService) insertInviteTokens(ctx context.Context, tx *sql.Tx, invites []pendingEmail, results []ImportRowResult, expiresAt time.Time) error {
+	ipAddress := audit.ActorIPFromContext(ctx)
+
+	for start := 0; start < len(invites); start += importBatchSize {
+		batch := invites[start:min(start+importBatchSize, len(invites))]
+		values := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*5)
+		for i := range batch {
+			plain, hashed, err := s.tokens.GenerateToken()
+			if err != nil {
+				return fmt.Errorf("failed to generate invite token: %w", err)
+			}
+			batch[i].token = plain
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+			args = append(args, *results[batch[i].index].UserID, hashed, expiresAt, ipAddress, inviteUserAgent)
+		}
+
+		startTime := time.Now()
+		query := `INSERT INTO reset_tokens (user_id, token_hash, expires_at, ip_address, user_agent) VALUES ` + strings.Join(values, ", ")
+		_, err := tx.ExecContext(ctx, query, args...)
+		s.log.LogDatabaseQuery("INSERT INTO reset_tokens (import)", time.Since(startTime), err, map[string]interface{}{
+			"rows": len(batch),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to store invite tokens: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// linkCurators assigns curators to newly created clients. New clients have no
+// previous relationship, so nothing needs to be deactivated.
+func (s *ImportService) linkCurators(ctx context.Context, tx *sql.Tx, links [][2]int64) error {
+	for start := 0; start < len(links); start += importBatchSize {
+		batch := links[start:min(start+importBatchSize, len(links))]
+		relationships := make([]string, len(batch))
+		conversations := make([]string, len(batch))
+		relationshipArgs := make([]any, 0, len(batch)*2)
+		conversationArgs := make([]any, 0, len(batch)*2)
+		for i, link := range batch {
+			relationships[i] = fmt.Sprintf("($%d, $%d, 'active')", 2*i+1, 2*i+2)
+			conversations[i] = fmt.Sprintf("($%d, $%d)", 2*i+1, 2*i+2)
+			relationshipArgs = append(relationshipArgs, link[0], link[1])
+			conversationArgs = append(conversationArgs, link[1], link[0])
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO curator_client_relationships (curator_id, client_id, status) VALUES `+strings.Join(relationships, ", ")+`
+			ON CONFLICT (curator_id, client_id) DO UPDATE SET status = 'active'
+		`, relationshipArgs...); err != nil {
+			return fmt.Errorf("failed to assign curators: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO conversations (client_id, curator_id) VALUES `+strings.Join(conversations, ", ")+`
+			ON CONFLICT (client_id, curator_id) DO NOTHING
+		`, conversationArgs...); err != nil {
+			return fmt.Errorf("failed to create conversations: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// upsertJoinRequests creates join requests for existing users. A request that
+// is still outstanding is left alone (no new email); expired or previously
+// accepted ones are replaced with a fresh token. It returns the requests to email.
+func (s *ImportService) upsertJoinRequests(
+	ctx context.Context,
+	tx *sql.Tx,
+	orgID int64,
+	rows []ImportRow,
+	joins []int,
+	results []ImportRowResult,
+	userID func(email string) (int64, bool),
+	expiresAt time.Time,
+) ([]pendingEmail, error) {
+	var toSend []pendingEmail
+
+	for start := 0; start < len(joins); start += importBatchSize {
+		batch := joins[start:min(start+importBatchSize, len(joins))]
+		values := make([]string, len(batch))
+		args := make([]any, 0, len(batch)*5)
+		tokens := make(map[int64]pendingEmail, len(batch))
+		for i, idx := range batch {
+			plain, hashed, err := s.tokens.GenerateToken()
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate join token: %w", err)
+			}
+			var curatorID *int64
+			if rows[idx].CuratorEmail != "" {
+				if id, ok := userID(rows[idx].CuratorEmail); ok {
+					curatorID = &id
+				}
+			}
+			n := len(args)
+			values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5)
+			args = append(args, orgID, *results[idx].UserID, curatorID, hashed, expiresAt)
+			tokens[*results[idx].UserID] = pendingEmail{index: idx, token: plain}
+			results[idx].Status = ImportConsentPending
+		}
+
+		startTime := time.Now()
+		query := `
+			INSERT INTO organization_membership_requests (organization_id, user_id, curator_id, token_hash, expires_at)
+			VALUES ` + strings.Join(values, ", ") + `
+			ON CONFLICT (organization_id, user_id) DO UPDATE
+			SET curator_id = EXCLUDED.curator_id, token_hash = EXCLUDED.token_hash, expires_at = EXCLUDED.expires_at,
+				accepted_at = NULL, accepted_ip = NULL, created_at = NOW()
+			WHERE organization_membership_requests.accepted_at IS NOT NULL
+				OR organization_membership_requests.expires_at < NOW()
+			RETURNING user_id
+		`
+		result, err := tx.QueryContext(ctx, query, args...)
+		s.log.LogDatabaseQuery("INSERT INTO organization_membership_requests (import)", time.Since(startTime), err, map[string]interface{}{
+			"organization_id": orgID,
+			"rows":            len(batch),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create join requests: %w", err)
+		}
+
+		for result.Next() {
+			var id int64
+			if err := result.Scan(&id); err != nil {
+				result.Close()
+				return nil, fmt.Errorf("failed to scan join request: %w", err)
+			}
+			request := tokens[id]
+			results[request.index].Status = ImportConsentRequested
+			toSend = append(toSend, request)
+		}
+		err = result.Err()
+		result.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to iterate join requests: %w", err)
+		}
+	}
+
+	return toSend, nil
+}
+
+// AcceptMembership records the user's consent from an emailed join link and
+// links them to the organization (and curator, for clients). Opening an
+// already accepted link again is a no-op.
+func (s *ImportService) AcceptMembership(ctx context.Context, token, ipAddress string) (*JoinResult, error) {
+	startTime := time.Now()
+
+	if token == "" {
+		return nil, ErrInvalidJoinToken
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT r.id, r.user_id, r.curator_id, r.expires_at, r.accepted_at, u.role, o.id, o.name
+		FROM organization_membership_requests r
+		JOIN users u ON u.id = r.user_id
+		JOIN organizations o ON o.id = r.organization_id
+		WHERE r.token_hash = $1
+		FOR UPDATE OF r
+	`
+	var (
+		requestID  int64
+		userID     int64
+		curatorID  *int64
+		expiresAt  time.Time
+		acceptedAt *time.Time
+		role       string
+		result     JoinResult
+	)
+	err = tx.QueryRowContext(ctx, query, s.tokens.HashToken(token)).Scan(
+		&requestID, &userID, &curatorID, &expiresAt, &acceptedAt, &role, &result.OrganizationID, &result.OrganizationName,
+	)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidJoinToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get join request: %w", err)
+	}
+	if acceptedAt != nil {
+		return &result, nil
+	}
+	if time.Now().After(expiresAt) {
+		return nil, ErrInvalidJoinToken
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE users SET organization_id = $1, updated_at = NOW() WHERE id = $2`, result.OrganizationID, userID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to link user to organization: %w", err)
+	}
+
+	if curatorID != nil && role == "client" {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE curator_client_relationships SET status = 'inactive'
+			WHERE client_id = $1 AND status = 'active' AND curator_id <> $2
+		`, userID, *curatorID); err != nil {
+			return nil, fmt.Errorf("failed to deactivate old relationship: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO curator_client_relationships (curator_id, client_id, status)
+			VALUES ($1, $2, 'active')
+			ON CONFLICT (curator_id, client_id) DO UPDATE SET status = 'active'
+		`, *curatorID, userID); err != nil {
+			return nil, fmt.Errorf("failed to assign curator: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO conversations (client_id, curator_id)
+			VALUES ($1, $2)
+			ON CONFLICT (client_id, curator_id) DO NOTHING
+		`, userID, *curatorID); err != nil {
+			return nil, fmt.Errorf("failed to create conversation: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE organization_membership_requests SET accepted_at = NOW(), accepted_ip = $1 WHERE id = $2`, ipAddress, requestID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to record consent: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	metadata := map[string]any{
+		"organization_id": result.OrganizationID,
+		"request_id":      requestID,
+	}
+	if curatorID != nil && role == "client" {
+		metadata["curator_id"] = *curatorID
+	}
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &userID,
+		ActorIP:  ipAddress,
+		Action:   audit.ActionOrganizationJoined,
+		Metadata: metadata,
+	})
+	s.log.LogBusinessEvent("organization_joined", map[string]interface{}{
+		"organization_id": result.OrganizationID,
+		"user_id":         userID,
+		"request_id":      requestID,
+	})
+
+	return &result, nil
+}
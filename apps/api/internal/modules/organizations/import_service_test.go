@@ -0,0 +1,296 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMailer records import emails
+type fakeMailer struct {
+	invites []email.OrganizationInviteEmailData
+	joins   []email.OrganizationJoinEmailData
+	joinErr error
+}
+
+func (m *fakeMailer) SendOrganizationInviteEmail(ctx context.Context, data email.OrganizationInviteEmailData) error {
+	m.invites = append(m.invites, data)
+	return nil
+}
+
+func (m *fakeMailer) SendOrganizationJoinEmail(ctx context.Context, data email.OrganizationJoinEmailData) error {
+	m.joins = append(m.joins, data)
+	return m.joinErr
+}
+
+func setupImportService(t *testing.T) (*ImportService, sqlmock.Sqlmock, *fakeMailer, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	mailer := &fakeMailer{}
+	cfg := &config.Config{
+		ResetPasswordURL:    "https://burcev.team/reset-password",
+		OrganizationJoinURL: "https://burcev.team/api/v1/organizations/join",
+	}
+	service := NewImportService(&database.DB{DB: mockDB}, cfg, logger.New(), mailer)
+
+	return service, mock, mailer, func() { mockDB.Close() }
+}
+
+var userColumns = []string{"id", "email", "role", "organization_id"}
+
+func statuses(result *ImportResult) map[string]string {
+	byEmail := make(map[string]string, len(result.Rows))
+	for _, r := range result.Rows {
+		byEmail[r.Email] = r.Status
+	}
+	return byEmail
+}
+
+func TestImportMembers(t *testing.T) {
+	t.Run("creates new members and asks existing users for consent", func(t *testing.T) {
+		service, mock, mailer, cleanup := setupImportService(t)
+		defer cleanup()
+
+		rows := []ImportRow{
+			{Line: 2, Email: "coach@gym.ru", Name: "Coach", Role: "coordinator"},
+			{Line: 3, Email: "anna@gym.ru", Name: "Анна", CuratorEmail: "coach@gym.ru"},
+			{Line: 4, Email: "Old@Example.com", CuratorEmail: "coach@gym.ru"},
+			{Line: 5, Email: "member@gym.ru"},
+			{Line: 6, Email: "pending@gym.ru"},
+			{Line: 7, Email: "bad-email"},
+			{Line: 8, Email: "ivan@gym.ru", CuratorEmail: "client@gym.ru"},
+		}
+
+		mock.ExpectQuery("SELECT name FROM organizations").WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Iron Gym"))
+		mock.ExpectQuery(`SELECT id, LOWER\(email\), role, organization_id FROM users WHERE LOWER\(email\) IN \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)`).
+			WillReturnRows(sqlmock.NewRows(userColumns).
+				AddRow(int64(7), "old@example.com", "client", nil).
+				AddRow(int64(8), "member@gym.ru", "client", int64(3)).
+				AddRow(int64(9), "pending@gym.ru", "client", int64(4)).
+				AddRow(int64(10), "client@gym.ru", "client", nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(`INSERT INTO users \(email, password, name, role, organization_id\) VALUES \(\$1, \$2, \$3, \$4, \$5\), \(\$6, \$7, \$8, \$9, \$10\) ON CONFLICT \(email\) DO NOTHING`).
+			WithArgs("coach@gym.ru", sqlmock.AnyArg(), "Coach", "coordinator", int64(3),
+				"anna@gym.ru", sqlmock.AnyArg(), "Анна", "client", int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).
+				AddRow(int64(20), "coach@gym.ru").
+				AddRow(int64(21), "anna@gym.ru"))
+		mock.ExpectExec("INSERT INTO reset_tokens").
+			WithArgs(int64(20), sqlmock.AnyArg(), sqlmock.AnyArg(), "", inviteUserAgent,
+				int64(21), sqlmock.AnyArg(), sqlmock.AnyArg(), "", inviteUserAgent).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO curator_client_relationships").WithArgs(int64(20), int64(21)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO conversations").WithArgs(int64(21), int64(20)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO organization_membership_requests").
+			WithArgs(int64(3), int64(7), int64(20), sqlmock.AnyArg(), sqlmock.AnyArg(),
+				int64(3), int64(9), nil, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+		mock.ExpectCommit()
+
+		result, err := service.ImportMembers(context.Background(), 3, rows)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"coach@gym.ru":    ImportCreated,
+			"anna@gym.ru":     ImportCreated,
+			"old@example.com": ImportConsentRequested,
+			"member@gym.ru":   ImportAlreadyMember,
+			"pending@gym.ru":  ImportConsentPending,
+			"bad-email":       ImportFailed,
+			"ivan@gym.ru":     ImportFailed,
+		}, statuses(result))
+		assert.Equal(t, 2, result.Created)
+		assert.Equal(t, 1, result.ConsentRequested)
+		assert.Equal(t, 1, result.ConsentPending)
+		assert.Equal(t, 1, result.AlreadyMember)
+		assert.Equal(t, 2, result.Failed)
+		assert.Equal(t, "Пользователь client@gym.ru не является куратором", result.Rows[6].Error)
+		assert.Equal(t, int64(21), *result.Rows[1].UserID)
+
+		require.Len(t, mailer.invites, 2)
+		assert.Equal(t, "anna@gym.ru", mailer.invites[1].UserEmail)
+		assert.Equal(t, "Iron Gym", mailer.invites[1].OrganizationName)
+		assert.True(t, strings.HasPrefix(mailer.invites[1].SetPasswordURL, "https://burcev.team/reset-password?token="))
+		require.Len(t, mailer.joins, 1)
+		assert.Equal(t, "old@example.com", mailer.joins[0].UserEmail)
+		assert.True(t, strings.HasPrefix(mailer.joins[0].AcceptURL, "https://burcev.team/api/v1/organizations/join?token="))
+		assert.NotEqual(t, mailer.invites[0].SetPasswordURL, mailer.invites[1].SetPasswordURL)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("re-importing the same file changes nothing", func(t *testing.T) {
+		service, mock, mailer, cleanup := setupImportService(t)
+		defer cleanup()
+
+		rows := []ImportRow{
+			{Line: 2, Email: "anna@gym.ru"},
+			{Line: 3, Email: "old@example.com"},
+		}
+
+		mock.ExpectQuery("SELECT name FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Iron Gym"))
+		mock.ExpectQuery("SELECT id, LOWER\\(email\\)").
+			WillReturnRows(sqlmock.NewRows(userColumns).
+				AddRow(int64(21), "anna@gym.ru", "client", int64(3)).
+				AddRow(int64(7), "old@example.com", "client", nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO organization_membership_requests").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+		mock.ExpectCommit()
+
+		result, err := service.ImportMembers(context.Background(), 3, rows)
+
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{
+			"anna@gym.ru":     ImportAlreadyMember,
+			"old@example.com": ImportConsentPending,
+		}, statuses(result))
+		assert.Empty(t, mailer.invites)
+		assert.Empty(t, mailer.joins)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsent join request is expired so the next import retries", func(t *testing.T) {
+		service, mock, mailer, cleanup := setupImportService(t)
+		defer cleanup()
+		mailer.joinErr = errors.New("smtp down")
+
+		mock.ExpectQuery("SELECT name FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Iron Gym"))
+		mock.ExpectQuery("SELECT id, LOWER\\(email\\)").
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(int64(7), "old@example.com", "client", nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO organization_membership_requests").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE organization_membership_requests SET expires_at = NOW()").
+			WithArgs(int64(3), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		result, err := service.ImportMembers(context.Background(), 3, []ImportRow{{Line: 2, Email: "old@example.com"}})
+
+		require.NoError(t, err)
+		assert.Equal(t, ImportFailed, result.Rows[0].Status)
+		assert.Equal(t, 1, result.Failed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown curator fails the row", func(t *testing.T) {
+		service, mock, mailer, cleanup := setupImportService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT name FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Iron Gym"))
+		mock.ExpectQuery("SELECT id, LOWER\\(email\\)").
+			WillReturnRows(sqlmock.NewRows(userColumns))
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		result, err := service.ImportMembers(context.Background(), 3, []ImportRow{
+			{Line: 2, Email: "anna@gym.ru", CuratorEmail: "nobody@gym.ru"},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, ImportFailed, result.Rows[0].Status)
+		assert.Equal(t, "Куратор nobody@gym.ru не найден", result.Rows[0].Error)
+		assert.Empty(t, mailer.invites)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown organization", func(t *testing.T) {
+		service, mock, _, cleanup := setupImportService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT name FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		_, err := service.ImportMembers(context.Background(), 3, []ImportRow{{Line: 2, Email: "anna@gym.ru"}})
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestAcceptMembership(t *testing.T) {
+	columns := []string{"id", "user_id", "curator_id", "expires_at", "accepted_at", "role", "org_id", "name"}
+	future := time.Now().Add(time.Hour)
+
+	t.Run("links the user and their curator", func(t *testing.T) {
+		service, mock, _, cleanup := setupImportService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM organization_membership_requests").
+			WithArgs(service.tokens.HashToken("join-token")).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(5), int64(7), int64(20), future, nil, "client", int64(3), "Iron Gym"))
+		mock.ExpectExec("UPDATE users SET organization_id").WithArgs(int64(3), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE curator_client_relationships SET status = 'inactive'").WithArgs(int64(7), int64(20)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO curator_client_relationships").WithArgs(int64(20), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO conversations").WithArgs(int64(7), int64(20)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE organization_membership_requests SET accepted_at").WithArgs("198.51.100.4", int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		result, err := service.AcceptMembership(context.Background(), "join-token", "198.51.100.4")
+
+		require.NoError(t, err)
+		assert.Equal(t, &JoinResult{OrganizationID: 3, OrganizationName: "Iron Gym"}, result)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("opening an accepted link again is a no-op", func(t *testing.T) {
+		service, mock, _, cleanup := setupImportService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM organization_membership_requests").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(5), int64(7), nil, future, time.Now(), "client", int64(3), "Iron Gym"))
+		mock.ExpectRollback()
+
+		result, err := service.AcceptMembership(context.Background(), "join-token", "198.51.100.4")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), result.OrganizationID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects expired and unknown links", func(t *testing.T) {
+		service, mock, _, cleanup := setupImportService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM organization_membership_requests").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(int64(5), int64(7), nil, time.Now().Add(-time.Minute), nil, "client", int64(3), "Iron Gym"))
+		mock.ExpectRollback()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT (.+) FROM organization_membership_requests").
+			WillReturnRows(sqlmock.NewRows(columns))
+		mock.ExpectRollback()
+
+		_, err := service.AcceptMembership(context.Background(), "expired", "198.51.100.4")
+		assert.ErrorIs(t, err, ErrInvalidJoinToken)
+		_, err = service.AcceptMembership(context.Background(), "unknown", "198.51.100.4")
+		assert.ErrorIs(t, err, ErrInvalidJoinToken)
+		_, err = service.AcceptMembership(context.Background(), "", "198.51.100.4")
+		assert.ErrorIs(t, err, ErrInvalidJoinToken)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
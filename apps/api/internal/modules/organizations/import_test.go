@@ -0,0 +1,79 @@
+package organizations
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImportCSV(t *testing.T) {
+	t.Run("maps columns by header name and skips blank lines", func(t *testing.T) {
+		csv := "\ufeffName,Email,Curator_Email,Role\n" +
+			"Анна,Anna@Gym.ru,coach@gym.ru,\n" +
+			"\n" +
+			"\"Coach, Head\",coach@gym.ru,,coordinator\n"
+
+		rows, err := ParseImportCSV(strings.NewReader(csv))
+
+		require.NoError(t, err)
+		assert.Equal(t, []ImportRow{
+			{Line: 2, Email: "Anna@Gym.ru", Name: "Анна", CuratorEmail: "coach@gym.ru"},
+			{Line: 4, Email: "coach@gym.ru", Name: "Coach, Head", Role: "coordinator"},
+		}, rows)
+	})
+
+	t.Run("only email is required", func(t *testing.T) {
+		rows, err := ParseImportCSV(strings.NewReader("email\nnew@gym.ru\n"))
+
+		require.NoError(t, err)
+		assert.Equal(t, []ImportRow{{Line: 2, Email: "new@gym.ru"}}, rows)
+	})
+
+	t.Run("rejects unusable files", func(t *testing.T) {
+		tooMany := "email\n" + strings.Repeat("a@gym.ru\n", MaxImportRows+1)
+
+		for name, csv := range map[string]string{
+			"empty":         "",
+			"no email":      "name,role\nАнна,client\n",
+			"header only":   "email,name\n",
+			"bad quoting":   "email\n\"broken@gym.ru\n",
+			"too many rows": tooMany,
+		} {
+			_, err := ParseImportCSV(strings.NewReader(csv))
+			assert.ErrorIs(t, err, ErrInvalidImport, name)
+		}
+	})
+}
+
+func TestNormalizeImportRows(t *testing.T) {
+	rows := []ImportRow{
+		{Line: 2, Email: "Anna@Gym.ru", CuratorEmail: "Coach@Gym.ru"},
+		{Line: 3, Email: "not-an-email"},
+		{Line: 4, Email: "admin@gym.ru", Role: "super_admin"},
+		{Line: 5, Email: "coach@gym.ru", Role: "Coordinator", CuratorEmail: "boss@gym.ru"},
+		{Line: 6, Email: "anna@gym.ru"},
+		{Line: 7, Email: "self@gym.ru", CuratorEmail: "self@gym.ru"},
+		{Line: 8, Email: "Ivan <ivan@gym.ru>"},
+	}
+
+	results := normalizeImportRows(rows)
+
+	assert.Equal(t, ImportRow{Line: 2, Email: "anna@gym.ru", Role: "client", CuratorEmail: "coach@gym.ru"}, rows[0])
+	assert.Empty(t, results[0].Status, "valid rows are left for the import to decide")
+	assert.Equal(t, "coordinator", rows[3].Role)
+
+	for i, want := range map[int]string{
+		1: "Неверный email",
+		2: "Недопустимая роль: super_admin",
+		3: "Куратора можно назначить только клиенту",
+		4: "Email уже указан в строке 2",
+		5: "Участник не может быть своим куратором",
+		6: "Неверный email",
+	} {
+		assert.Equal(t, ImportFailed, results[i].Status, fmt.Sprint(rows[i].Line))
+		assert.Equal(t, want, results[i].Error, fmt.Sprint(rows[i].Line))
+	}
+}
@@ -0,0 +1,257 @@
+package organizations
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// staleMigrationAfter is how long a migration may stay "running" before
+// another run picks it up again (e.g. after a crash mid-copy). Moving an
+// object is idempotent, so re-running a migration is safe.
+const staleMigrationAfter = 30 * time.Minute
+
+// RunRegionMigrations starts the storage region migration loop. Every tick it
+// processes pending migrations one by one. It blocks until the provided
+// context is cancelled.
+func (s *Service) RunRegionMigrations(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	s.log.Info("Storage region migration job started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessNextMigration(ctx)
+				if err != nil {
+					s.log.Error("Failed to process storage region migration", "error", err)
+					break
+				}
+				if !processed || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Storage region migration job stopped")
+			return
+		}
+	}
+}
+
+// ProcessNextMigration claims the oldest pending migration and moves every
+// object of the organization that is not yet in the target region. Each
+// object is copied, read back and compared before the original is deleted;
+// objects that fail verification stay in the source region and the migration
+// is marked failed. Returns false when there was nothing to do.
+func (s *Service) ProcessNextMigration(ctx context.Context) (bool, error) {
+	startTime := time.Now()
+
+	claimQuery := `
+		UPDATE storage_region_migrations
+		SET status = 'running', started_at = NOW()
+		WHERE id = (
+			SELECT id FROM storage_region_migrations
+			WHERE status = 'pending'
+			   OR (status = 'running' AND started_at < NOW() - make_interval(secs => $1))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, organization_id, to_region
+	`
+
+	var migrationID, orgID int64
+	var toRegion string
+	err := s.db.QueryRowContext(ctx, claimQuery, staleMigrationAfter.Seconds()).Scan(&migrationID, &orgID, &toRegion)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	s.log.LogDatabaseQuery(claimQuery, time.Since(startTime), err, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim region migration: %w", err)
+	}
+
+	objects, err := s.objectsOutsideRegion(ctx, orgID, toRegion)
+	if err != nil {
+		return true, s.finishMigration(ctx, migrationID, 0, 0, err)
+	}
+
+	var moved, failed int
+	var lastErr error
+	for _, obj := range objects {
+		if ctx.Err() != nil {
+			// Leave the migration running; it is picked up again once stale
+			return true, ctx.Err()
+		}
+		if err := s.moveObject(ctx, obj, toRegion); err != nil {
+			failed++
+			lastErr = err
+			s.log.Error("Failed to move object to new storage region",
+				"error", err,
+				"migration_id", migrationID,
+				"photo_id", obj.photoID,
+				"from_region", obj.region,
+				"to_region", toRegion,
+			)
+			continue
+		}
+		moved++
+	}
+
+	s.log.LogBusinessEvent("storage_region_migration_finished", map[string]interface{}{
+		"migration_id":    migrationID,
+		"organization_id": orgID,
+		"to_region":       toRegion,
+		"moved":           moved,
+		"failed":          failed,
+	})
+
+	return true, s.finishMigration(ctx, migrationID, moved, failed, lastErr)
+}
+
+// objectsOutsideRegion lists the organization's photos stored outside the target region
+func (s *Service) objectsOutsideRegion(ctx context.Context, orgID int64, region string) ([]storedObject, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT wp.id, wp.storage_key, wp.storage_region, wp.mime_type
+		FROM weekly_photos wp
+		JOIN users u ON u.id = wp.user_id
+		WHERE u.organization_id = $1
+		  AND wp.storage_region <> $2
+		  AND wp.storage_key <> ''
+		ORDER BY wp.uploaded_at
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, orgID, region)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"organization_id": orgID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization objects: %w", err)
+	}
+	defer rows.Close()
+
+	var objects []storedObject
+	for rows.Next() {
+		var obj storedObject
+		if err := rows.Scan(&obj.photoID, &obj.key, &obj.region, &obj.mimeType); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, rows.Err()
+}
+
+// moveObject copies one object to the target region, verifies the copy and
+// only then repoints the row and deletes the original.
+func (s *Service) moveObject(ctx context.Context, obj storedObject, toRegion string) error {
+	dst, ok := s.regions.Store(toRegion)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownRegion, toRegion)
+	}
+	srcRegion, src := s.regions.Resolve(obj.region)
+	if src == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownRegion, obj.region)
+	}
+
+	// The source already resolves to the target bucket: only the row is stale
+	if srcRegion == toRegion {
+		_, err := s.repointObject(ctx, obj, toRegion, "")
+		return err
+	}
+
+	data, err := src.GetFile(ctx, obj.key)
+	if err != nil {
+		return fmt.Errorf("failed to read source object: %w", err)
+	}
+
+	url, err := dst.UploadFile(ctx, obj.key, bytes.NewReader(data), obj.mimeType, int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to copy object: %w", err)
+	}
+
+	copied, err := dst.GetFile(ctx, obj.key)
+	if err != nil {
+		return fmt.Errorf("failed to verify copied object: %w", err)
+	}
+	if !bytes.Equal(copied, data) {
+		return errCopyMismatch
+	}
+
+	updated, err := s.repointObject(ctx, obj, toRegion, url)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		// The photo was replaced or deleted meanwhile; keep the original as is
+		return nil
+	}
+
+	if err := src.DeleteFile(ctx, obj.key); err != nil {
+		// Data is safe in the new region; the original is only left orphaned
+		s.log.Warn("Failed to delete original object after migration",
+			"error", err,
+			"photo_id", obj.photoID,
+			"region", srcRegion,
+		)
+	}
+	return nil
+}
+
+// repointObject records the new location of a photo. An empty url keeps the stored one.
+func (s *Service) repointObject(ctx context.Context, obj storedObject, toRegion, url string) (bool, error) {
+	startTime := time.Now()
+
+	query := `
+		UPDATE weekly_photos
+		SET storage_region = $1, photo_url = COALESCE(NULLIF($2, ''), photo_url)
+		WHERE id = $3 AND storage_region = $4 AND storage_key = $5
+	`
+
+	result, err := s.db.ExecContext(ctx, query, toRegion, url, obj.photoID, obj.region, obj.key)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"photo_id": obj.photoID,
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update object location: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to update object location: %w", err)
+	}
+	return n > 0, nil
+}
+
+// finishMigration records the migration outcome
+func (s *Service) finishMigration(ctx context.Context, migrationID int64, moved, failed int, migrationErr error) error {
+	startTime := time.Now()
+
+	status := MigrationCompleted
+	var errMsg *string
+	if migrationErr != nil {
+		status = MigrationFailed
+		msg := migrationErr.Error()
+		errMsg = &msg
+	}
+
+	query := `
+		UPDATE storage_region_migrations
+		SET status = $2, moved = $3, failed = $4, error = $5, completed_at = NOW()
+		WHERE id = $1
+	`
+
+	_, err := s.db.ExecContext(ctx, query, migrationID, status, moved, failed, errMsg)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"migration_id": migrationID,
+		"status":       status,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish region migration: %w", err)
+	}
+	return nil
+}
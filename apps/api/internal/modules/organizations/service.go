@@ -0,0 +1,199 @@
+package organizations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+)
+
+// ServiceInterface defines the interface for organization service operations
+type ServiceInterface interface {
+	ListOrganizations(ctx context.Context) ([]Organization, error)
+	CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error)
+	ChangeStorageRegion(ctx context.Context, orgID int64, region string) (*RegionMigration, error)
+}
+
+// Service manages organizations and their storage regions
+type Service struct {
+	db      *database.DB
+	log     *logger.Logger
+	regions *storage.Regions
+}
+
+// NewService creates a new organizations service
+func NewService(db *database.DB, log *logger.Logger, regions *storage.Regions) *Service {
+	return &Service{
+		db:      db,
+		log:     log,
+		regions: regions,
+	}
+}
+
+// ListOrganizations returns all organizations with member counts
+func (s *Service) ListOrganizations(ctx context.Context) ([]Organization, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT o.id, o.name, o.storage_region, COUNT(u.id) AS member_count, o.created_at, o.updated_at
+		FROM organizations o
+		LEFT JOIN users u ON u.organization_id = o.id
+		GROUP BY o.id
+		ORDER BY o.name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := make([]Organization, 0)
+	for rows.Next() {
+		var o Organization
+		if err := rows.Scan(&o.ID, &o.Name, &o.StorageRegion, &o.MemberCount, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+// CreateOrganization creates an organization. An empty storage region uses the default region.
+func (s *Service) CreateOrganization(ctx context.Context, req CreateOrganizationRequest) (*Organization, error) {
+	startTime := time.Now()
+
+	region := strings.TrimSpace(req.StorageRegion)
+	if region == "" {
+		region = s.regions.Default()
+	}
+	if !s.regions.Has(region) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+
+	query := `
+		INSERT INTO organizations (name, storage_region)
+		VALUES ($1, $2)
+		RETURNING id, name, storage_region, created_at, updated_at
+	`
+
+	var o Organization
+	err := s.db.QueryRowContext(ctx, query, strings.TrimSpace(req.Name), region).
+		Scan(&o.ID, &o.Name, &o.StorageRegion, &o.CreatedAt, &o.UpdatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"storage_region": region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	s.log.LogBusinessEvent("organization_created", map[string]interface{}{
+		"organization_id": o.ID,
+		"storage_region":  o.StorageRegion,
+	})
+
+	return &o, nil
+}
+
+// ChangeStorageRegion switches the organization to a new region. New writes go
+// to the new region immediately; existing objects are moved by the background
+// migration job recorded here.
+func (s *Service) ChangeStorageRegion(ctx context.Context, orgID int64, region string) (*RegionMigration, error) {
+	startTime := time.Now()
+
+	region = strings.TrimSpace(region)
+	if !s.regions.Has(region) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownRegion, region)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx,
+		`SELECT storage_region FROM organizations WHERE id = $1 FOR UPDATE`, orgID,
+	).Scan(&current)
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+	if current == region {
+		return nil, ErrSameRegion
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE organizations SET storage_region = $1, updated_at = NOW() WHERE id = $2`, region, orgID,
+	); err != nil {
+		return nil, fmt.Errorf("failed to update organization region: %w", err)
+	}
+
+	query := `
+		INSERT INTO storage_region_migrations (organization_id, from_region, to_region)
+		VALUES ($1, $2, $3)
+		RETURNING id, status, created_at
+	`
+	m := RegionMigration{OrganizationID: orgID, FromRegion: current, ToRegion: region}
+	err = tx.QueryRowContext(ctx, query, orgID, current, region).Scan(&m.ID, &m.Status, &m.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"organization_id": orgID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create region migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.log.LogBusinessEvent("organization_region_changed", map[string]interface{}{
+		"organization_id": orgID,
+		"from_region":     current,
+		"to_region":       region,
+		"migration_id":    m.ID,
+	})
+
+	return &m, nil
+}
+
+// UserRegion returns the storage region of the user's organization, or ""
+// for a user without one. It is the storage.RegionLookup of the files kept
+// per user.
+func (s *Service) UserRegion(ctx context.Context, userID int64) (string, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT COALESCE(o.storage_region, '')
+		FROM users u
+		LEFT JOIN organizations o ON o.id = u.organization_id
+		WHERE u.id = $1
+	`
+
+	var region string
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&region)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err == sql.ErrNoRows {
+		return "", apperrors.ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get user storage region: %w", err)
+	}
+	return region, nil
+}
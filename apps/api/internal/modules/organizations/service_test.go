@@ -0,0 +1,280 @@
+package organizations
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeStore is an in-memory storage.ObjectStore
+type fakeStore struct {
+	name      string
+	objects   map[string][]byte
+	corrupt   bool // store a truncated copy on upload
+	deleteErr error
+	deleted   []string
+}
+
+func newFakeStore(name string) *fakeStore {
+	return &fakeStore{name: name, objects: make(map[string][]byte)}
+}
+
+func (f *fakeStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	if f.corrupt && len(b) > 0 {
+		b = b[:len(b)-1]
+	}
+	f.objects[key] = b
+	return "https://storage.example/" + f.name + "/" + key, nil
+}
+
+func (f *fakeStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	b, ok := f.objects[key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+	return b, nil
+}
+
+func (f *fakeStore) DeleteFile(ctx context.Context, key string) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	delete(f.objects, key)
+	f.deleted = append(f.deleted, key)
+	return nil
+}
+
+func (f *fakeStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + f.name + "/" + key + "?signed", nil
+}
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *fakeStore, *fakeStore, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	ru := newFakeStore("photos-ru")
+	eu := newFakeStore("photos-eu")
+	regions := storage.NewRegions(storage.DefaultRegion)
+	regions.Register(storage.DefaultRegion, ru)
+	regions.Register("eu", eu)
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), regions)
+
+	return service, mock, ru, eu, func() { mockDB.Close() }
+}
+
+func expectClaim(mock sqlmock.Sqlmock, orgID int64, toRegion string) {
+	mock.ExpectQuery("UPDATE storage_region_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "to_region"}).AddRow(int64(9), orgID, toRegion))
+}
+
+func expectObjects(mock sqlmock.Sqlmock, orgID int64, toRegion string, keys ...string) {
+	rows := sqlmock.NewRows([]string{"id", "storage_key", "storage_region", "mime_type"})
+	for i, key := range keys {
+		rows.AddRow("photo-"+string(rune('a'+i)), key, storage.DefaultRegion, "image/jpeg")
+	}
+	mock.ExpectQuery("SELECT wp.id, wp.storage_key").WithArgs(orgID, toRegion).WillReturnRows(rows)
+}
+
+func TestProcessNextMigration(t *testing.T) {
+	const key = "weekly-photos/5/2026-W40/a.jpg"
+
+	t.Run("copies, verifies and only then deletes the original", func(t *testing.T) {
+		service, mock, ru, eu, cleanup := setupTestService(t)
+		defer cleanup()
+		ru.objects[key] = []byte("jpeg-bytes")
+
+		expectClaim(mock, 3, "eu")
+		expectObjects(mock, 3, "eu", key)
+		mock.ExpectExec("UPDATE weekly_photos").
+			WithArgs("eu", "https://storage.example/photos-eu/"+key, "photo-a", storage.DefaultRegion, key).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE storage_region_migrations").
+			WithArgs(int64(9), MigrationCompleted, 1, 0, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextMigration(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.Equal(t, []byte("jpeg-bytes"), eu.objects[key])
+		assert.Equal(t, []string{key}, ru.deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the original when the copy does not verify", func(t *testing.T) {
+		service, mock, ru, eu, cleanup := setupTestService(t)
+		defer cleanup()
+		ru.objects[key] = []byte("jpeg-bytes")
+		eu.corrupt = true
+
+		expectClaim(mock, 3, "eu")
+		expectObjects(mock, 3, "eu", key)
+		mock.ExpectExec("UPDATE storage_region_migrations").
+			WithArgs(int64(9), MigrationFailed, 0, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextMigration(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.Empty(t, ru.deleted)
+		assert.Equal(t, []byte("jpeg-bytes"), ru.objects[key])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the original when the photo changed during the copy", func(t *testing.T) {
+		service, mock, ru, _, cleanup := setupTestService(t)
+		defer cleanup()
+		ru.objects[key] = []byte("jpeg-bytes")
+
+		expectClaim(mock, 3, "eu")
+		expectObjects(mock, 3, "eu", key)
+		mock.ExpectExec("UPDATE weekly_photos").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("UPDATE storage_region_migrations").
+			WithArgs(int64(9), MigrationCompleted, 1, 0, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessNextMigration(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, ru.deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("continues after a failed object and reports partial failure", func(t *testing.T) {
+		service, mock, ru, eu, cleanup := setupTestService(t)
+		defer cleanup()
+		const missing = "weekly-photos/5/2026-W39/missing.jpg"
+		ru.objects[key] = []byte("jpeg-bytes")
+
+		expectClaim(mock, 3, "eu")
+		expectObjects(mock, 3, "eu", missing, key)
+		mock.ExpectExec("UPDATE weekly_photos").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE storage_region_migrations").
+			WithArgs(int64(9), MigrationFailed, 1, 1, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessNextMigration(context.Background())
+
+		require.NoError(t, err)
+		assert.Contains(t, eu.objects, key)
+		assert.Equal(t, []string{key}, ru.deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns false when nothing is pending", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("UPDATE storage_region_migrations").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "organization_id", "to_region"}))
+
+		processed, err := service.ProcessNextMigration(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+}
+
+func TestChangeStorageRegion(t *testing.T) {
+	t.Run("switches region and queues a migration", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_region FROM organizations").WithArgs(int64(3)).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow(storage.DefaultRegion))
+		mock.ExpectExec("UPDATE organizations SET storage_region").WithArgs("eu", int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO storage_region_migrations").WithArgs(int64(3), storage.DefaultRegion, "eu").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow(int64(9), MigrationPending, time.Now()))
+		mock.ExpectCommit()
+
+		m, err := service.ChangeStorageRegion(context.Background(), 3, "eu")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), m.ID)
+		assert.Equal(t, storage.DefaultRegion, m.FromRegion)
+		assert.Equal(t, "eu", m.ToRegion)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unconfigured region", func(t *testing.T) {
+		service, _, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.ChangeStorageRegion(context.Background(), 3, "us")
+
+		assert.ErrorIs(t, err, ErrUnknownRegion)
+	})
+
+	t.Run("rejects the current region", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_region FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow("eu"))
+		mock.ExpectRollback()
+
+		_, err := service.ChangeStorageRegion(context.Background(), 3, "eu")
+
+		assert.ErrorIs(t, err, ErrSameRegion)
+	})
+
+	t.Run("returns not found for unknown organization", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT storage_region FROM organizations").
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}))
+		mock.ExpectRollback()
+
+		_, err := service.ChangeStorageRegion(context.Background(), 3, "eu")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestUserRegion(t *testing.T) {
+	t.Run("returns the organization's region", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT COALESCE\\(o.storage_region, ''\\)").WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}).AddRow("eu"))
+
+		region, err := service.UserRegion(context.Background(), 7)
+
+		require.NoError(t, err)
+		assert.Equal(t, "eu", region)
+	})
+
+	t.Run("returns not found for unknown user", func(t *testing.T) {
+		service, mock, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT COALESCE\\(o.storage_region, ''\\)").WithArgs(int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_region"}))
+
+		_, err := service.UserRegion(context.Background(), 7)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
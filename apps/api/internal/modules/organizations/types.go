@@ -0,0 +1,122 @@
+package organizations
+
+import (
+	"errors"
+	"time"
+)
+
+// Region migration statuses
+const (
+	MigrationPending   = "pending"
+	MigrationRunning   = "running"
+	MigrationCompleted = "completed"
+	MigrationFailed    = "failed"
+)
+
+// Member import row statuses
+const (
+	ImportCreated          = "created"
+	ImportConsentRequested = "consent_requested"
+	ImportConsentPending   = "consent_pending"
+	ImportAlreadyMember    = "already_member"
+	ImportFailed           = "failed"
+)
+
+// Member import limits
+const (
+	// MaxImportRows caps the number of members in one import file
+	MaxImportRows = 1000
+	// MaxImportFileSize caps the size of the uploaded CSV
+	MaxImportFileSize = 1 << 20
+	// InviteTTL is how long set-password and join links stay valid
+	InviteTTL = 7 * 24 * time.Hour
+	// importBatchSize is how many rows go into one multi-row INSERT
+	importBatchSize = 100
+)
+
+var (
+	ErrUnknownRegion = errors.New("unknown storage region")
+	ErrSameRegion    = errors.New("organization already uses this storage region")
+	errCopyMismatch  = errors.New("copied object does not match source")
+
+	// ErrInvalidImport is shown to the admin, so its details are in Russian
+	ErrInvalidImport    = errors.New("неверный CSV-файл")
+	ErrInvalidJoinToken = errors.New("invalid or expired join link")
+)
+
+// Organization is a gym or team whose members share data residency settings
+type Organization struct {
+	ID            int64     `json:"id"`
+	Name          string    `json:"name"`
+	StorageRegion string    `json:"storage_region"`
+	MemberCount   int       `json:"member_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// RegionMigration tracks moving an organization's objects between regions
+type RegionMigration struct {
+	ID             int64      `json:"id"`
+	OrganizationID int64      `json:"organization_id"`
+	FromRegion     string     `json:"from_region"`
+	ToRegion       string     `json:"to_region"`
+	Status         string     `json:"status"`
+	Moved          int        `json:"moved"`
+	Failed         int        `json:"failed"`
+	Error          *string    `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
+
+// CreateOrganizationRequest is the request body for POST /api/v1/admin/organizations
+type CreateOrganizationRequest struct {
+	Name          string `json:"name" binding:"required"`
+	StorageRegion string `json:"storage_region"`
+}
+
+// ChangeRegionRequest is the request body for PUT /api/v1/admin/organizations/:id/storage-region
+type ChangeRegionRequest struct {
+	StorageRegion string `json:"storage_region" binding:"required"`
+}
+
+// ImportRow is a member listed in an import file. Line is the CSV line number.
+type ImportRow struct {
+	Line         int
+	Email        string
+	Name         string
+	Role         string
+	CuratorEmail string
+}
+
+// ImportRowResult is the outcome of importing a single row
+type ImportRowResult struct {
+	Line   int    `json:"line"`
+	Email  string `json:"email"`
+	Status string `json:"status"`
+	UserID *int64 `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ImportResult is the response of POST /api/v1/admin/organizations/:id/import
+type ImportResult struct {
+	Created          int               `json:"created"`
+	ConsentRequested int               `json:"consent_requested"`
+	ConsentPending   int               `json:"consent_pending"`
+	AlreadyMember    int               `json:"already_member"`
+	Failed           int               `json:"failed"`
+	Rows             []ImportRowResult `json:"rows"`
+}
+
+// JoinResult is the response of GET /api/v1/organizations/join
+type JoinResult struct {
+	OrganizationID   int64  `json:"organization_id"`
+	OrganizationName string `json:"organization_name"`
+}
+
+// storedObject is a photo object that has to be moved to another region
+type storedObject struct {
+	photoID  string
+	key      string
+	region   string
+	mimeType string
+}
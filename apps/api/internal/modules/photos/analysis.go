@@ -0,0 +1,419 @@
+package photos
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// analysisLease is how long a claimed estimate stays processing before
+// another worker may reclaim it (covers crashes mid-analysis)
+const analysisLease = 10 * time.Minute
+
+// analysisCallTimeout bounds a single analyzer call, including image upload
+const analysisCallTimeout = 2 * time.Minute
+
+const estimateColumns = `id, user_id, week_identifier, status, attempts, body_fat_pct, confidence,
+		raw_response, error, created_at, updated_at, completed_at`
+
+// ParseISOWeek parses an ISO week identifier (e.g. 2026-W42) and returns the
+// Monday the week starts on
+func ParseISOWeek(week string) (time.Time, error) {
+	var year, num int
+	if n, err := fmt.Sscanf(week, "%4d-W%2d", &year, &num); err != nil || n != 2 || len(week) != 8 {
+		return time.Time{}, ErrInvalidWeek
+	}
+	if num < 1 || num > 53 {
+		return time.Time{}, ErrInvalidWeek
+	}
+
+	// January 4th is always in week 1
+	jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	offset := (int(jan4.Weekday()) + 6) % 7
+	monday := jan4.AddDate(0, 0, -offset+(num-1)*7)
+
+	if y, w := monday.ISOWeek(); y != year || w != num {
+		// Week 53 in a year that only has 52
+		return time.Time{}, ErrInvalidWeek
+	}
+
+	return monday, nil
+}
+
+// RequestAnalysis enqueues body fat estimation for the user's photo set of the
+// given ISO week. All three projections must be present. An estimate that is
+// already queued or running is returned as is; a finished one is re-queued.
+func (s *Service) RequestAnalysis(ctx context.Context, userID int64, week string) (*BodyFatEstimate, error) {
+	if s.analyzer == nil {
+		return nil, ErrAnalysisUnavailable
+	}
+
+	monday, err := ParseISOWeek(week)
+	if err != nil {
+		return nil, err
+	}
+
+	present, err := s.weekProjections(ctx, userID, monday)
+	if err != nil {
+		return nil, err
+	}
+	if missing := missingProjections(present); len(missing) > 0 {
+		return nil, &IncompleteSetError{Missing: missing}
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO body_fat_estimates (user_id, week_identifier)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, week_identifier) DO UPDATE
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(),
+		    body_fat_pct = NULL, confidence = NULL, raw_response = NULL, error = NULL,
+		    completed_at = NULL, updated_at = NOW()
+		WHERE body_fat_estimates.status IN ('done', 'failed')
+		RETURNING ` + estimateColumns
+
+	estimate, err := scanEstimate(s.db.QueryRowContext(ctx, query, userID, week))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"week":    week,
+	})
+	if err == sql.ErrNoRows {
+		// Already pending or processing
+		return s.getEstimate(ctx, userID, week)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue body fat analysis: %w", err)
+	}
+
+	s.log.LogBusinessEvent("body_fat_analysis_requested", map[string]interface{}{
+		"user_id":     userID,
+		"week":        week,
+		"estimate_id": estimate.ID,
+	})
+
+	return estimate, nil
+}
+
+// ListEstimates returns the user's estimates, newest week first
+func (s *Service) ListEstimates(ctx context.Context, userID int64) ([]BodyFatEstimate, error) {
+	startTime := time.Now()
+
+	query := `SELECT ` + estimateColumns + `
+		FROM body_fat_estimates
+		WHERE user_id = $1
+		ORDER BY week_identifier DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list body fat estimates: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := make([]BodyFatEstimate, 0)
+	for rows.Next() {
+		e, err := scanEstimate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan body fat estimate: %w", err)
+		}
+		estimates = append(estimates, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating body fat estimates: %w", err)
+	}
+
+	return estimates, nil
+}
+
+// ProcessNextAnalysis claims one due estimate and runs the analyzer on it.
+// Transient analyzer errors are retried with backoff up to MaxAnalysisAttempts.
+// Returns false when nothing was due.
+func (s *Service) ProcessNextAnalysis(ctx context.Context) (bool, error) {
+	startTime := time.Now()
+
+	claimQuery := `
+		UPDATE body_fat_estimates
+		SET status = 'processing', attempts = attempts + 1,
+		    next_attempt_at = NOW() + $1 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM body_fat_estimates
+			WHERE status IN ('pending', 'processing') AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, week_identifier, attempts
+	`
+
+	var id, week string
+	var userID int64
+	var attempts int
+	err := s.db.QueryRowContext(ctx, claimQuery, int(analysisLease.Seconds())).Scan(&id, &userID, &week, &attempts)
+	s.log.LogDatabaseQuery(claimQuery, time.Since(startTime), err, nil)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim body fat estimate: %w", err)
+	}
+
+	result, err := s.runAnalysis(ctx, userID, week)
+	if err != nil {
+		retry := !errors.Is(err, ErrAnalysisRejected) && !errors.As(err, new(*IncompleteSetError)) && attempts < MaxAnalysisAttempts
+		s.log.Error("Body fat analysis failed", "error", err, "estimate_id", id, "attempt", attempts, "will_retry", retry)
+		if retry {
+			return true, s.markEstimateRetry(ctx, id, err, time.Duration(attempts*attempts)*time.Minute)
+		}
+		return true, s.markEstimateFailed(ctx, id, err)
+	}
+
+	startTime = time.Now()
+	query := `
+		UPDATE body_fat_estimates
+		SET status = 'done', body_fat_pct = $2, confidence = $3, raw_response = $4,
+		    error = NULL, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err = s.db.ExecContext(ctx, query, id, result.BodyFatPct, result.Confidence, []byte(result.Raw))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"estimate_id": id,
+	})
+	if err != nil {
+		return true, fmt.Errorf("failed to save body fat estimate: %w", err)
+	}
+
+	s.log.LogBusinessEvent("body_fat_estimated", map[string]interface{}{
+		"user_id":      userID,
+		"week":         week,
+		"estimate_id":  id,
+		"body_fat_pct": result.BodyFatPct,
+		"confidence":   result.Confidence,
+	})
+
+	return true, nil
+}
+
+// runAnalysis loads the latest photo of each projection for the week and
+// calls the analyzer
+func (s *Service) runAnalysis(ctx context.Context, userID int64, week string) (*AnalysisResult, error) {
+	monday, err := ParseISOWeek(week)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT DISTINCT ON (projection) projection, storage_key, storage_region, content_type
+		FROM progress_photos
+		WHERE user_id = $1 AND taken_on >= $2::date AND taken_on < $3::date
+		ORDER BY projection, taken_on DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, monday.Format("2006-01-02"), monday.AddDate(0, 0, 7).Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"week":    week,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load week photos: %w", err)
+	}
+	type weekPhoto struct{ projection, key, region, contentType string }
+	var found []weekPhoto
+	present := make(map[string]bool)
+	for rows.Next() {
+		var p weekPhoto
+		if err := rows.Scan(&p.projection, &p.key, &p.region, &p.contentType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan week photo: %w", err)
+		}
+		found = append(found, p)
+		present[p.projection] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating week photos: %w", err)
+	}
+	if missing := missingProjections(present); len(missing) > 0 {
+		// Photos were deleted after the analysis was requested
+		return nil, &IncompleteSetError{Missing: missing}
+	}
+
+	set := make([]AnalysisPhoto, 0, len(found))
+	for _, p := range found {
+		store, err := s.files.ForRegion(p.region)
+		if err != nil {
+			return nil, err
+		}
+		r, err := store.Get(ctx, p.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read photo %s: %w", p.key, err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read photo %s: %w", p.key, err)
+		}
+		set = append(set, AnalysisPhoto{Projection: p.projection, ContentType: p.contentType, Data: data})
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, analysisCallTimeout)
+	defer cancel()
+
+	return s.analyzer.Analyze(callCtx, set)
+}
+
+// markEstimateRetry returns the estimate to the queue after a delay
+func (s *Service) markEstimateRetry(ctx context.Context, id string, cause error, delay time.Duration) error {
+	startTime := time.Now()
+	query := `
+		UPDATE body_fat_estimates
+		SET status = 'pending', error = $2, next_attempt_at = NOW() + $3 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := s.db.ExecContext(ctx, query, id, cause.Error(), int(delay.Seconds()))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"estimate_id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to reschedule body fat estimate: %w", err)
+	}
+	return nil
+}
+
+// markEstimateFailed records a permanent failure
+func (s *Service) markEstimateFailed(ctx context.Context, id string, cause error) error {
+	startTime := time.Now()
+	query := `
+		UPDATE body_fat_estimates
+		SET status = 'failed', error = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`
+	_, err := s.db.ExecContext(ctx, query, id, cause.Error())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"estimate_id": id,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark body fat estimate failed: %w", err)
+	}
+	return nil
+}
+
+// weekProjections returns which projections have at least one photo in the week
+func (s *Service) weekProjections(ctx context.Context, userID int64, monday time.Time) (map[string]bool, error) {
+	startTime := time.Now()
+	query := `
+		SELECT DISTINCT projection FROM progress_photos
+		WHERE user_id = $1 AND taken_on >= $2::date AND taken_on < $3::date
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, monday.Format("2006-01-02"), monday.AddDate(0, 0, 7).Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check week photos: %w", err)
+	}
+	defer rows.Close()
+
+	present := make(map[string]bool)
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, fmt.Errorf("failed to scan projection: %w", err)
+		}
+		present[p] = true
+	}
+	return present, rows.Err()
+}
+
+// missingProjections lists the projections absent from present
+func missingProjections(present map[string]bool) []string {
+	var missing []string
+	for _, p := range []string{ProjectionFront, ProjectionSide, ProjectionBack} {
+		if !present[p] {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
+// getEstimate loads the user's estimate for a week
+func (s *Service) getEstimate(ctx context.Context, userID int64, week string) (*BodyFatEstimate, error) {
+	startTime := time.Now()
+	query := `SELECT ` + estimateColumns + ` FROM body_fat_estimates WHERE user_id = $1 AND week_identifier = $2`
+
+	e, err := scanEstimate(s.db.QueryRowContext(ctx, query, userID, week))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"week":    week,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get body fat estimate: %w", err)
+	}
+	return e, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanEstimate(row rowScanner) (*BodyFatEstimate, error) {
+	var e BodyFatEstimate
+	var bodyFat, confidence sql.NullFloat64
+	var raw []byte
+	var errMsg sql.NullString
+	var completedAt sql.NullTime
+
+	if err := row.Scan(&e.ID, &e.UserID, &e.Week, &e.Status, &e.Attempts, &bodyFat, &confidence,
+		&raw, &errMsg, &e.CreatedAt, &e.UpdatedAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if bodyFat.Valid {
+		e.BodyFatPct = &bodyFat.Float64
+	}
+	if confidence.Valid {
+		e.Confidence = &confidence.Float64
+	}
+	if len(raw) > 0 {
+		e.RawResponse = raw
+	}
+	if errMsg.Valid {
+		e.Error = &errMsg.String
+	}
+	if completedAt.Valid {
+		e.CompletedAt = &completedAt.Time
+	}
+	return &e, nil
+}
+
+// RunAnalysisWorker processes queued body fat estimates until ctx is cancelled
+func (s *Service) RunAnalysisWorker(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("Body fat analysis worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessNextAnalysis(ctx)
+				if err != nil {
+					s.log.Error("Failed to process body fat analysis", "error", err)
+					break
+				}
+				if !processed || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Body fat analysis worker stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package photos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAnalyzer records the photo set it was given and returns a canned result
+type fakeAnalyzer struct {
+	got    []AnalysisPhoto
+	result *AnalysisResult
+	err    error
+}
+
+func (f *fakeAnalyzer) Analyze(ctx context.Context, photos []AnalysisPhoto) (*AnalysisResult, error) {
+	f.got = photos
+	return f.result, f.err
+}
+
+var estimateRowColumns = []string{"id", "user_id", "week_identifier", "status", "attempts", "body_fat_pct", "confidence",
+	"raw_response", "error", "created_at", "updated_at", "completed_at"}
+
+func setupAnalysisService(t *testing.T, analyzer Analyzer) (*Service, sqlmock.Sqlmock, *storage.MemoryStorage, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), storage.NewRouter(store, nil, nil), analyzer, nil)
+
+	return service, mock, store, func() { mockDB.Close() }
+}
+
+func TestParseISOWeek(t *testing.T) {
+	tests := []struct {
+		week    string
+		monday  string
+		wantErr bool
+	}{
+		{"2026-W42", "2026-10-12", false},
+		{"2026-W01", "2025-12-29", false},
+		{"2026-W53", "2026-12-28", false},
+		{"2025-W53", "", true},
+		{"2026-W00", "", true},
+		{"2026-42", "", true},
+		{"2026-W4", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.week, func(t *testing.T) {
+			monday, err := ParseISOWeek(tt.week)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidWeek)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.monday, monday.Format("2006-01-02"))
+		})
+	}
+}
+
+func TestRequestAnalysis(t *testing.T) {
+	now := time.Now()
+
+	t.Run("queues estimate when the set is complete", func(t *testing.T) {
+		service, mock, _, cleanup := setupAnalysisService(t, &fakeAnalyzer{})
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT DISTINCT projection FROM progress_photos").
+			WithArgs(int64(5), "2026-10-12", "2026-10-19").
+			WillReturnRows(sqlmock.NewRows([]string{"projection"}).AddRow("front").AddRow("side").AddRow("back"))
+		mock.ExpectQuery("INSERT INTO body_fat_estimates").
+			WithArgs(int64(5), "2026-W42").
+			WillReturnRows(sqlmock.NewRows(estimateRowColumns).
+				AddRow("e-1", int64(5), "2026-W42", "pending", 0, nil, nil, nil, nil, now, now, nil))
+
+		estimate, err := service.RequestAnalysis(context.Background(), 5, "2026-W42")
+
+		require.NoError(t, err)
+		assert.Equal(t, EstimateStatusPending, estimate.Status)
+		assert.Nil(t, estimate.BodyFatPct)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reports missing projections", func(t *testing.T) {
+		service, mock, _, cleanup := setupAnalysisService(t, &fakeAnalyzer{})
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT DISTINCT projection FROM progress_photos").
+			WillReturnRows(sqlmock.NewRows([]string{"projection"}).AddRow("front"))
+
+		_, err := service.RequestAnalysis(context.Background(), 5, "2026-W42")
+
+		var incomplete *IncompleteSetError
+		require.True(t, errors.As(err, &incomplete))
+		assert.Equal(t, []string{"side", "back"}, incomplete.Missing)
+	})
+
+	t.Run("returns in-flight estimate unchanged", func(t *testing.T) {
+		service, mock, _, cleanup := setupAnalysisService(t, &fakeAnalyzer{})
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT DISTINCT projection FROM progress_photos").
+			WillReturnRows(sqlmock.NewRows([]string{"projection"}).AddRow("front").AddRow("side").AddRow("back"))
+		mock.ExpectQuery("INSERT INTO body_fat_estimates").WillReturnRows(sqlmock.NewRows(estimateRowColumns))
+		mock.ExpectQuery("FROM body_fat_estimates WHERE user_id").
+			WithArgs(int64(5), "2026-W42").
+			WillReturnRows(sqlmock.NewRows(estimateRowColumns).
+				AddRow("e-1", int64(5), "2026-W42", "processing", 1, nil, nil, nil, nil, now, now, nil))
+
+		estimate, err := service.RequestAnalysis(context.Background(), 5, "2026-W42")
+
+		require.NoError(t, err)
+		assert.Equal(t, EstimateStatusProcessing, estimate.Status)
+	})
+
+	t.Run("disabled without analyzer", func(t *testing.T) {
+		service, _, _, cleanup := setupAnalysisService(t, nil)
+		defer cleanup()
+
+		_, err := service.RequestAnalysis(context.Background(), 5, "2026-W42")
+
+		assert.ErrorIs(t, err, ErrAnalysisUnavailable)
+	})
+}
+
+func expectClaimAndPhotos(t *testing.T, mock sqlmock.Sqlmock, store *storage.MemoryStorage, attempts int) {
+	mock.ExpectQuery("UPDATE body_fat_estimates").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "week_identifier", "attempts"}).
+			AddRow("e-1", int64(5), "2026-W42", attempts))
+
+	rows := sqlmock.NewRows([]string{"projection", "storage_key", "storage_region", "content_type"})
+	for _, p := range []string{"back", "front", "side"} {
+		key := "photos/5/" + p + ".png"
+		require.NoError(t, store.Put(context.Background(), key, bytesReader(p)))
+		rows.AddRow(p, key, "", "image/png")
+	}
+	mock.ExpectQuery("SELECT DISTINCT ON \\(projection\\)").
+		WithArgs(int64(5), "2026-10-12", "2026-10-19").
+		WillReturnRows(rows)
+}
+
+func TestProcessNextAnalysis(t *testing.T) {
+	t.Run("stores the estimate", func(t *testing.T) {
+		analyzer := &fakeAnalyzer{result: &AnalysisResult{BodyFatPct: 18.5, Confidence: 0.8, Raw: json.RawMessage(`{"body_fat_pct":18.5}`)}}
+		service, mock, store, cleanup := setupAnalysisService(t, analyzer)
+		defer cleanup()
+
+		expectClaimAndPhotos(t, mock, store, 1)
+		mock.ExpectExec("SET status = 'done'").
+			WithArgs("e-1", 18.5, 0.8, []byte(`{"body_fat_pct":18.5}`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextAnalysis(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		require.Len(t, analyzer.got, 3)
+		assert.Equal(t, []byte("back"), analyzer.got[0].Data)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("retries transient errors with backoff", func(t *testing.T) {
+		service, mock, store, cleanup := setupAnalysisService(t, &fakeAnalyzer{err: errors.New("timeout")})
+		defer cleanup()
+
+		expectClaimAndPhotos(t, mock, store, 2)
+		mock.ExpectExec("SET status = 'pending'").
+			WithArgs("e-1", "timeout", 240).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessNextAnalysis(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails after the last attempt", func(t *testing.T) {
+		service, mock, store, cleanup := setupAnalysisService(t, &fakeAnalyzer{err: errors.New("timeout")})
+		defer cleanup()
+
+		expectClaimAndPhotos(t, mock, store, MaxAnalysisAttempts)
+		mock.ExpectExec("SET status = 'failed'").WithArgs("e-1", "timeout").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessNextAnalysis(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails immediately when the analyzer rejects the set", func(t *testing.T) {
+		service, mock, store, cleanup := setupAnalysisService(t, &fakeAnalyzer{err: ErrAnalysisRejected})
+		defer cleanup()
+
+		expectClaimAndPhotos(t, mock, store, 1)
+		mock.ExpectExec("SET status = 'failed'").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessNextAnalysis(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing due", func(t *testing.T) {
+		service, mock, _, cleanup := setupAnalysisService(t, &fakeAnalyzer{})
+		defer cleanup()
+
+		mock.ExpectQuery("UPDATE body_fat_estimates").WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "week_identifier", "attempts"}))
+
+		processed, err := service.ProcessNextAnalysis(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+}
@@ -0,0 +1,130 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ErrAnalysisRejected marks analyzer errors that will not succeed on retry
+// (e.g. the API rejected the images). Other errors are retried.
+var ErrAnalysisRejected = errors.New("analysis rejected")
+
+// AnalysisPhoto is one projection of a weekly photo set
+type AnalysisPhoto struct {
+	Projection  string
+	ContentType string
+	Data        []byte
+}
+
+// AnalysisResult is the body fat estimate returned by an Analyzer
+type AnalysisResult struct {
+	BodyFatPct float64
+	Confidence float64
+	Raw        json.RawMessage
+}
+
+// Analyzer estimates body fat from a front/side/back photo set
+type Analyzer interface {
+	Analyze(ctx context.Context, photos []AnalysisPhoto) (*AnalysisResult, error)
+}
+
+// HTTPAnalyzer calls an external vision API.
+//
+// Request:  POST {url} {"images":[{"projection":"front","content_type":"image/jpeg","data":"<base64>"}, ...]}
+// Response: {"body_fat_pct": 18.5, "confidence": 0.8, ...}
+type HTTPAnalyzer struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewHTTPAnalyzer creates an analyzer for the vision API at url
+func NewHTTPAnalyzer(url, apiKey string, timeout time.Duration) *HTTPAnalyzer {
+	return &HTTPAnalyzer{
+		url:        url,
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type analyzeImage struct {
+	Projection  string `json:"projection"`
+	ContentType string `json:"content_type"`
+	Data        string `json:"data"`
+}
+
+type analyzeResponse struct {
+	BodyFatPct *float64 `json:"body_fat_pct"`
+	Confidence float64  `json:"confidence"`
+}
+
+// Analyze sends the photo set and parses the estimate
+func (a *HTTPAnalyzer) Analyze(ctx context.Context, photos []AnalysisPhoto) (*AnalysisResult, error) {
+	images := make([]analyzeImage, 0, len(photos))
+	for _, p := range photos {
+		images = append(images, analyzeImage{
+			Projection:  p.Projection,
+			ContentType: p.ContentType,
+			Data:        base64.StdEncoding.EncodeToString(p.Data),
+		})
+	}
+	body, err := json.Marshal(map[string]interface{}{"images": images})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+a.apiKey)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("analyzer request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read analyzer response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, fmt.Errorf("analyzer returned status %d", resp.StatusCode)
+	case resp.StatusCode >= 400:
+		return nil, fmt.Errorf("%w: status %d: %s", ErrAnalysisRejected, resp.StatusCode, truncate(string(raw), 200))
+	}
+
+	var parsed analyzeResponse
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrAnalysisRejected, err)
+	}
+	if parsed.BodyFatPct == nil || *parsed.BodyFatPct <= 0 || *parsed.BodyFatPct >= 70 {
+		return nil, fmt.Errorf("%w: response has no plausible body_fat_pct", ErrAnalysisRejected)
+	}
+
+	return &AnalysisResult{
+		BodyFatPct: *parsed.BodyFatPct,
+		Confidence: parsed.Confidence,
+		Raw:        json.RawMessage(raw),
+	}, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,78 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func bytesReader(s string) io.Reader {
+	return bytes.NewReader([]byte(s))
+}
+
+func TestHTTPAnalyzer(t *testing.T) {
+	set := []AnalysisPhoto{{Projection: "front", ContentType: "image/png", Data: []byte("png")}}
+
+	t.Run("sends images and parses the estimate", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+			var body struct {
+				Images []analyzeImage `json:"images"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "cG5n", body.Images[0].Data)
+			_, _ = w.Write([]byte(`{"body_fat_pct":21.3,"confidence":0.7}`))
+		}))
+		defer server.Close()
+
+		result, err := NewHTTPAnalyzer(server.URL, "secret", time.Second).Analyze(context.Background(), set)
+
+		require.NoError(t, err)
+		assert.Equal(t, 21.3, result.BodyFatPct)
+		assert.Equal(t, 0.7, result.Confidence)
+		assert.JSONEq(t, `{"body_fat_pct":21.3,"confidence":0.7}`, string(result.Raw))
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPAnalyzer(server.URL, "", time.Second).Analyze(context.Background(), set)
+
+		assert.ErrorIs(t, err, ErrAnalysisRejected)
+	})
+
+	t.Run("server errors are retryable", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPAnalyzer(server.URL, "", time.Second).Analyze(context.Background(), set)
+
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrAnalysisRejected)
+	})
+
+	t.Run("times out", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		_, err := NewHTTPAnalyzer(server.URL, "", 50*time.Millisecond).Analyze(context.Background(), set)
+
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrAnalysisRejected)
+	})
+}
@@ -0,0 +1,410 @@
+package photos
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/uploads"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the allowance for form fields and boundaries on top of the file size
+const multipartOverhead = 1 << 20
+
+// Handler handles progress photo requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+	uploads uploads.ChunkedSource
+}
+
+// NewHandler creates a new photos handler. uploads may be nil, in which case
+// only inline multipart uploads are accepted.
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface, uploadSource uploads.ChunkedSource) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+		uploads: uploadSource,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// Upload handles POST /api/v1/photos
+// Form: projection (front|side|back), date (YYYY-MM-DD) and either photo (jpeg/png,
+// max 10 MB) or upload_id of a completed resumable upload.
+func (h *Handler) Upload(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxPhotoSize+multipartOverhead)
+
+	projection := c.PostForm("projection")
+	if !IsValidProjection(projection) {
+		response.Error(c, http.StatusBadRequest, "Ракурс должен быть front, side или back")
+		return
+	}
+
+	takenOn, err := time.Parse("2006-01-02", c.PostForm("date"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверный формат даты. Используйте YYYY-MM-DD")
+		return
+	}
+
+	var file io.Reader
+	if uploadID := c.PostForm("upload_id"); uploadID != "" {
+		rc, ok := h.takeUpload(c, userID, uploadID)
+		if !ok {
+			return
+		}
+		defer rc.Close()
+		file = rc
+	} else {
+		f, header, err := c.Request.FormFile("photo")
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 10 МБ")
+				return
+			}
+			response.Error(c, http.StatusBadRequest, "Файл фото обязателен")
+			return
+		}
+		defer f.Close()
+
+		if header.Size > MaxPhotoSize {
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 10 МБ")
+			return
+		}
+		file = f
+	}
+
+	h.createPhoto(c, userID, UploadInput{Projection: projection, TakenOn: takenOn}, file)
+}
+
+// createPhoto stores file as a progress photo and responds with it
+func (h *Handler) createPhoto(c *gin.Context, userID int64, input UploadInput, file io.Reader) {
+	photo, err := h.service.Upload(c.Request.Context(), userID, input, file)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrPhotoTooLarge):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 10 МБ")
+		case errors.Is(err, ErrInvalidContentType), errors.Is(err, ErrEmptyPhoto):
+			response.Error(c, http.StatusBadRequest, "Фото должно быть в формате JPEG или PNG")
+		case errors.Is(err, ErrInvalidProjection):
+			response.Error(c, http.StatusBadRequest, "Ракурс должен быть front, side или back")
+		case errors.As(err, new(*apperrors.QuotaExceededError)):
+			_ = c.Error(err)
+		default:
+			h.log.Error("Failed to upload progress photo", "error", err, "user_id", userID)
+			response.InternalError(c, "Не удалось загрузить фото")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, photo)
+}
+
+// takeUpload consumes a completed resumable upload and opens its contents
+func (h *Handler) takeUpload(c *gin.Context, userID int64, uploadID string) (io.ReadCloser, bool) {
+	if h.uploads == nil {
+		response.Error(c, http.StatusBadRequest, "Загрузка по upload_id недоступна")
+		return nil, false
+	}
+
+	upload, rc, err := h.uploads.Take(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Загрузка не найдена")
+		case errors.Is(err, uploads.ErrNotCompleted):
+			response.Error(c, http.StatusConflict, "Загрузка ещё не завершена")
+		default:
+			h.log.Error("Failed to take upload", "error", err, "user_id", userID, "upload_id", uploadID)
+			response.InternalError(c, "Не удалось загрузить фото")
+		}
+		return nil, false
+	}
+	if upload.Size > MaxPhotoSize {
+		rc.Close()
+		response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 10 МБ")
+		return nil, false
+	}
+
+	return rc, true
+}
+
+// CreateUpload handles POST /api/v1/photos/uploads
+// Body: {"size": 3145728, "content_type": "image/jpeg"}. Starts a chunked
+// upload of a photo; the response carries its id and chunk_size. Chunk n
+// (bytes n*chunk_size onwards) is sent with PUT /photos/uploads/:id/chunks/:n,
+// in any order, and GET /photos/uploads/:id lists the chunks still missing.
+func (h *Handler) CreateUpload(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	if h.uploads == nil {
+		response.Error(c, http.StatusBadRequest, "Загрузка по частям недоступна")
+		return
+	}
+
+	var req uploads.CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуются size и content_type")
+		return
+	}
+	if _, ok := allowedContentTypes[req.ContentType]; !ok {
+		response.Error(c, http.StatusBadRequest, "Фото должно быть в формате JPEG или PNG")
+		return
+	}
+	if req.Size > MaxPhotoSize {
+		response.Error(c, http.StatusRequestEntityTooLarge, "Размер фото не должен превышать 10 МБ")
+		return
+	}
+
+	upload, err := h.uploads.CreateChunked(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, uploads.ErrInvalidSize) {
+			response.Error(c, http.StatusBadRequest, "Размер фото должен быть больше нуля")
+			return
+		}
+		h.log.Error("Failed to create photo upload", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось начать загрузку")
+		return
+	}
+
+	c.Header("Location", "/api/v1/photos/uploads/"+upload.ID)
+	response.Success(c, http.StatusCreated, upload)
+}
+
+// CompleteUpload handles POST /api/v1/photos/uploads/:id/complete
+// Body: {"projection": "front", "date": "2026-10-01", "checksum": "sha256 <base64>"}.
+// Assembles the received chunks, verifies the photo against checksum and
+// creates the progress photo.
+func (h *Handler) CompleteUpload(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	if h.uploads == nil {
+		response.Error(c, http.StatusBadRequest, "Загрузка по частям недоступна")
+		return
+	}
+
+	var req CompleteUploadRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+	if !IsValidProjection(req.Projection) {
+		response.Error(c, http.StatusBadRequest, "Ракурс должен быть front, side или back")
+		return
+	}
+	checksum, err := uploads.ParseChecksum(req.Checksum)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Контрольная сумма должна иметь вид \"sha256 <base64>\"")
+		return
+	}
+
+	uploadID := c.Param("id")
+	if _, err := h.uploads.Complete(c.Request.Context(), userID, uploadID, checksum); err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Загрузка не найдена")
+		case errors.Is(err, uploads.ErrIncomplete):
+			response.Error(c, http.StatusConflict, "Получены не все части файла")
+		case errors.Is(err, uploads.ErrWrongProtocol), errors.Is(err, uploads.ErrNotActive):
+			response.Error(c, http.StatusConflict, "Загрузку нельзя завершить")
+		case errors.Is(err, uploads.ErrFileChecksumMismatch):
+			response.Error(c, http.StatusUnprocessableEntity, "Контрольная сумма файла не совпадает, начните загрузку заново")
+		case errors.Is(err, uploads.ErrContentTypeMismatch):
+			response.Error(c, http.StatusBadRequest, "Фото должно быть в формате JPEG или PNG")
+		default:
+			h.log.Error("Failed to complete photo upload", "error", err, "user_id", userID, "upload_id", uploadID)
+			response.InternalError(c, "Не удалось загрузить фото")
+		}
+		return
+	}
+
+	rc, ok := h.takeUpload(c, userID, uploadID)
+	if !ok {
+		return
+	}
+	defer rc.Close()
+
+	h.createPhoto(c, userID, UploadInput{Projection: req.Projection, TakenOn: req.Date.Time()}, rc)
+}
+
+// List handles GET /api/v1/photos?from=YYYY-MM-DD&to=YYYY-MM-DD
+func (h *Handler) List(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var from, to *time.Time
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &from}, {"to", &to}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "Неверный формат даты. Используйте YYYY-MM-DD")
+			return
+		}
+		*p.dst = &t
+	}
+	if from != nil && to != nil && from.After(*to) {
+		response.Error(c, http.StatusBadRequest, "Дата from не может быть позже to")
+		return
+	}
+
+	photos, err := h.service.List(c.Request.Context(), userID, from, to)
+	if err != nil {
+		h.log.Error("Failed to list progress photos", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось загрузить фото")
+		return
+	}
+
+	response.Success(c, http.StatusOK, photos)
+}
+
+// Get handles GET /api/v1/photos/:id
+// Streams the image with its stored Content-Type; only the owner can read it.
+func (h *Handler) Get(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	photoID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	photo, r, err := h.service.Open(c.Request.Context(), userID, photoID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Фото не найдено")
+			return
+		}
+		h.log.Error("Failed to open progress photo", "error", err, "user_id", userID, "photo_id", photoID)
+		response.InternalError(c, "Не удалось загрузить фото")
+		return
+	}
+	defer r.Close()
+
+	c.DataFromReader(http.StatusOK, int64(photo.SizeBytes), photo.ContentType, r, map[string]string{
+		"Cache-Control": "private, max-age=3600",
+	})
+}
+
+// Delete handles DELETE /api/v1/photos/:id
+func (h *Handler) Delete(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	photoID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.Delete(c.Request.Context(), userID, photoID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Фото не найдено")
+			return
+		}
+		h.log.Error("Failed to delete progress photo", "error", err, "user_id", userID, "photo_id", photoID)
+		response.InternalError(c, "Не удалось удалить фото")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Фото удалено", nil)
+}
+
+// Analyze handles POST /api/v1/photos/analyze
+// Body: {"week": "2026-W42"}. Queues body fat estimation for the week's
+// front/side/back set; poll GET /api/v1/photos/estimates for the result.
+func (h *Handler) Analyze(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req AnalyzeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Укажите неделю в формате 2026-W42")
+		return
+	}
+
+	estimate, err := h.service.RequestAnalysis(c.Request.Context(), userID, req.Week)
+	if err != nil {
+		var incomplete *IncompleteSetError
+		switch {
+		case errors.Is(err, ErrInvalidWeek):
+			response.Error(c, http.StatusBadRequest, "Неверный формат недели. Используйте 2026-W42")
+		case errors.As(err, &incomplete):
+			c.JSON(http.StatusUnprocessableEntity, response.Response{
+				Status:  "error",
+				Message: "Для анализа нужны фото спереди, сбоку и сзади за эту неделю",
+				Data:    gin.H{"missing": incomplete.Missing},
+			})
+		case errors.Is(err, ErrAnalysisUnavailable):
+			response.Error(c, http.StatusServiceUnavailable, "Анализ фото временно недоступен")
+		default:
+			h.log.Error("Failed to request body fat analysis", "error", err, "user_id", userID, "week", req.Week)
+			response.InternalError(c, "Не удалось запустить анализ")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusAccepted, estimate)
+}
+
+// ListEstimates handles GET /api/v1/photos/estimates
+func (h *Handler) ListEstimates(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	estimates, err := h.service.ListEstimates(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to list body fat estimates", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось загрузить результаты анализа")
+		return
+	}
+
+	response.Success(c, http.StatusOK, estimates)
+}
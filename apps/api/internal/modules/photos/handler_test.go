@@ -0,0 +1,396 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/modules/uploads"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPhotoID = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+
+// errUnexpectedCall fails a test that expects the service not to be called
+var errUnexpectedCall = errors.New("service must not be called")
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	uploaded *UploadInput
+	from, to *time.Time
+	photo    *Photo
+	content  string
+	week     string
+	estimate *BodyFatEstimate
+	err      error
+}
+
+func (m *mockService) Upload(ctx context.Context, userID int64, in UploadInput, data io.Reader) (*Photo, error) {
+	m.uploaded = &in
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Photo{ID: testPhotoID, Projection: in.Projection}, nil
+}
+
+func (m *mockService) List(ctx context.Context, userID int64, from, to *time.Time) ([]Photo, error) {
+	m.from, m.to = from, to
+	return []Photo{}, m.err
+}
+
+func (m *mockService) Open(ctx context.Context, userID int64, photoID string) (*Photo, io.ReadCloser, error) {
+	if m.err != nil {
+		return nil, nil, m.err
+	}
+	return m.photo, io.NopCloser(strings.NewReader(m.content)), nil
+}
+
+func (m *mockService) Delete(ctx context.Context, userID int64, photoID string) error {
+	return m.err
+}
+
+func (m *mockService) RequestAnalysis(ctx context.Context, userID int64, week string) (*BodyFatEstimate, error) {
+	m.week = week
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.estimate, nil
+}
+
+func (m *mockService) ListEstimates(ctx context.Context, userID int64) ([]BodyFatEstimate, error) {
+	return []BodyFatEstimate{}, m.err
+}
+
+func newUploadRequest(t *testing.T, fields map[string]string, withFile bool) *http.Request {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for k, v := range fields {
+		require.NoError(t, w.WriteField(k, v))
+	}
+	if withFile {
+		part, err := w.CreateFormFile("photo", "front.png")
+		require.NoError(t, err)
+		_, _ = part.Write([]byte("png-data"))
+	}
+	require.NoError(t, w.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/photos", &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+// newPhotoContext is newTestContext for /photos/:id
+func newPhotoContext(method, id string) (*gin.Context, *httptest.ResponseRecorder) {
+	c, w := newTestContext(httptest.NewRequest(method, "/photos/"+id, nil))
+	c.Params = gin.Params{{Key: "id", Value: id}}
+	return c, w
+}
+
+func newTestContext(req *http.Request) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Set("user_id", int64(5))
+	return c, w
+}
+
+func TestHandlerUpload(t *testing.T) {
+	tests := []struct {
+		name     string
+		fields   map[string]string
+		withFile bool
+		err      error
+		status   int
+	}{
+		{"valid upload", map[string]string{"projection": "side", "date": "2026-10-01"}, true, nil, http.StatusCreated},
+		{"invalid projection", map[string]string{"projection": "top", "date": "2026-10-01"}, true, nil, http.StatusBadRequest},
+		{"invalid date", map[string]string{"projection": "front", "date": "01.10.2026"}, true, nil, http.StatusBadRequest},
+		{"missing file", map[string]string{"projection": "front", "date": "2026-10-01"}, false, nil, http.StatusBadRequest},
+		{"unsupported content", map[string]string{"projection": "front", "date": "2026-10-01"}, true, ErrInvalidContentType, http.StatusBadRequest},
+		{"too large", map[string]string{"projection": "front", "date": "2026-10-01"}, true, ErrPhotoTooLarge, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{err: tt.err}
+			handler := NewHandler(nil, logger.New(), svc, nil)
+			c, w := newTestContext(newUploadRequest(t, tt.fields, tt.withFile))
+
+			handler.Upload(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestHandlerList(t *testing.T) {
+	t.Run("parses date range", func(t *testing.T) {
+		svc := &mockService{}
+		handler := NewHandler(nil, logger.New(), svc, nil)
+		c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/photos?from=2026-09-01&to=2026-09-30", nil))
+
+		handler.List(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		require.NotNil(t, svc.from)
+		require.NotNil(t, svc.to)
+		assert.Equal(t, "2026-09-30", svc.to.Format("2006-01-02"))
+	})
+
+	t.Run("rejects inverted range", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{}, nil)
+		c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/photos?from=2026-09-30&to=2026-09-01", nil))
+
+		handler.List(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandlerGet(t *testing.T) {
+	t.Run("streams image with stored content type", func(t *testing.T) {
+		svc := &mockService{photo: &Photo{ContentType: "image/jpeg", SizeBytes: 4}, content: "jpeg"}
+		handler := NewHandler(nil, logger.New(), svc, nil)
+		c, w := newPhotoContext(http.MethodGet, testPhotoID)
+
+		handler.Get(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+		assert.Equal(t, "jpeg", w.Body.String())
+	})
+
+	t.Run("returns 404 for foreign photo", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{err: apperrors.ErrNotFound}, nil)
+		c, w := newPhotoContext(http.MethodGet, testPhotoID)
+
+		handler.Get(c)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("rejects malformed id", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{err: errUnexpectedCall}, nil)
+		c, w := newPhotoContext(http.MethodGet, "p-1")
+
+		handler.Get(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "Неверный формат идентификатора")
+	})
+}
+
+func TestHandlerDelete(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		err    error
+		status int
+	}{
+		{"deleted", testPhotoID, nil, http.StatusOK},
+		{"foreign photo", testPhotoID, apperrors.ErrNotFound, http.StatusNotFound},
+		{"malformed id", "p-1", errUnexpectedCall, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err}, nil)
+			c, w := newPhotoContext(http.MethodDelete, tt.id)
+
+			handler.Delete(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestHandlerAnalyze(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		err    error
+		status int
+	}{
+		{"queued", `{"week":"2026-W42"}`, nil, http.StatusAccepted},
+		{"missing week", `{}`, nil, http.StatusBadRequest},
+		{"invalid week", `{"week":"2026-42"}`, ErrInvalidWeek, http.StatusBadRequest},
+		{"incomplete set", `{"week":"2026-W42"}`, &IncompleteSetError{Missing: []string{"back"}}, http.StatusUnprocessableEntity},
+		{"analyzer not configured", `{"week":"2026-W42"}`, ErrAnalysisUnavailable, http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{err: tt.err, estimate: &BodyFatEstimate{ID: "e-1", Status: EstimateStatusPending}}
+			handler := NewHandler(nil, logger.New(), svc, nil)
+			req := httptest.NewRequest(http.MethodPost, "/photos/analyze", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c, w := newTestContext(req)
+
+			handler.Analyze(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusUnprocessableEntity {
+				assert.Contains(t, w.Body.String(), `"missing":["back"]`)
+			}
+		})
+	}
+}
+
+// fakeUploads implements uploads.ChunkedSource for handler tests
+type fakeUploads struct {
+	upload      *uploads.Upload
+	data        []byte
+	err         error
+	completeErr error
+	checksum    []byte
+}
+
+func (f *fakeUploads) CreateChunked(ctx context.Context, userID int64, req uploads.CreateRequest) (*uploads.Upload, error) {
+	chunkSize := int64(uploads.ChunkSize)
+	return &uploads.Upload{ID: "u-1", ContentType: req.ContentType, Size: req.Size, ChunkSize: &chunkSize}, f.err
+}
+
+func (f *fakeUploads) Complete(ctx context.Context, userID int64, uploadID string, checksum []byte) (*uploads.Upload, error) {
+	f.checksum = checksum
+	return f.upload, f.completeErr
+}
+
+func (f *fakeUploads) Take(ctx context.Context, userID int64, uploadID string) (*uploads.Upload, io.ReadCloser, error) {
+	if f.err != nil {
+		return nil, nil, f.err
+	}
+	return f.upload, io.NopCloser(bytes.NewReader(f.data)), nil
+}
+
+func TestHandlerUploadFromResumableUpload(t *testing.T) {
+	fields := map[string]string{"projection": "front", "date": "2026-10-01", "upload_id": "u-1"}
+
+	t.Run("uses completed upload", func(t *testing.T) {
+		svc := &mockService{}
+		src := &fakeUploads{upload: &uploads.Upload{ID: "u-1", Size: 8}, data: []byte("png-data")}
+		handler := NewHandler(nil, logger.New(), svc, src)
+		c, w := newTestContext(newUploadRequest(t, fields, false))
+
+		handler.Upload(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		require.NotNil(t, svc.uploaded)
+	})
+
+	t.Run("upload not completed", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{}, &fakeUploads{err: uploads.ErrNotCompleted})
+		c, w := newTestContext(newUploadRequest(t, fields, false))
+
+		handler.Upload(c)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("resumable uploads disabled", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), &mockService{}, nil)
+		c, w := newTestContext(newUploadRequest(t, fields, false))
+
+		handler.Upload(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandlerCreateUpload(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"starts chunked upload", `{"size":3145728,"content_type":"image/jpeg"}`, http.StatusCreated},
+		{"rejects non-photo type", `{"size":3145728,"content_type":"text/csv"}`, http.StatusBadRequest},
+		{"rejects oversized photo", `{"size":10485761,"content_type":"image/png"}`, http.StatusRequestEntityTooLarge},
+		{"requires size", `{"content_type":"image/png"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{}, &fakeUploads{})
+			req := httptest.NewRequest(http.MethodPost, "/photos/uploads", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			c, w := newTestContext(req)
+
+			handler.CreateUpload(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.status == http.StatusCreated {
+				assert.Equal(t, "/api/v1/photos/uploads/u-1", w.Header().Get("Location"))
+				assert.Contains(t, w.Body.String(), `"chunk_size":1048576`)
+			}
+		})
+	}
+}
+
+func TestHandlerCompleteUpload(t *testing.T) {
+	sum := sha256.Sum256([]byte("png-data"))
+	checksum := "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+	body := `{"projection":"side","date":"2026-10-01","checksum":"` + checksum + `"}`
+
+	newRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/photos/uploads/u-1/complete", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	t.Run("creates the photo from the assembled upload", func(t *testing.T) {
+		svc := &mockService{}
+		src := &fakeUploads{upload: &uploads.Upload{ID: "u-1", Size: 8}, data: []byte("png-data")}
+		handler := NewHandler(nil, logger.New(), svc, src)
+		c, w := newTestContext(newRequest(body))
+		c.Params = gin.Params{{Key: "id", Value: "u-1"}}
+
+		handler.CompleteUpload(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, sum[:], src.checksum)
+		require.NotNil(t, svc.uploaded)
+		assert.Equal(t, "side", svc.uploaded.Projection)
+		assert.Equal(t, "2026-10-01", svc.uploaded.TakenOn.Format("2006-01-02"))
+	})
+
+	tests := []struct {
+		name        string
+		body        string
+		completeErr error
+		status      int
+	}{
+		{"invalid projection", `{"projection":"top","date":"2026-10-01","checksum":"` + checksum + `"}`, nil, http.StatusBadRequest},
+		{"malformed checksum", `{"projection":"side","date":"2026-10-01","checksum":"md5 abc"}`, nil, http.StatusBadRequest},
+		{"missing chunks", body, uploads.ErrIncomplete, http.StatusConflict},
+		{"file checksum mismatch", body, uploads.ErrFileChecksumMismatch, http.StatusUnprocessableEntity},
+		{"unknown upload", body, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{err: errUnexpectedCall}
+			src := &fakeUploads{upload: &uploads.Upload{ID: "u-1", Size: 8}, data: []byte("png-data"), completeErr: tt.completeErr}
+			handler := NewHandler(nil, logger.New(), svc, src)
+			c, w := newTestContext(newRequest(tt.body))
+
+			handler.CompleteUpload(c)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Nil(t, svc.uploaded)
+		})
+	}
+}
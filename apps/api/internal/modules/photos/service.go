@@ -0,0 +1,297 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/ids"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/google/uuid"
+)
+
+// ServiceInterface defines the interface for progress photo operations
+type ServiceInterface interface {
+	Upload(ctx context.Context, userID int64, in UploadInput, data io.Reader) (*Photo, error)
+	List(ctx context.Context, userID int64, from, to *time.Time) ([]Photo, error)
+	Open(ctx context.Context, userID int64, photoID string) (*Photo, io.ReadCloser, error)
+	Delete(ctx context.Context, userID int64, photoID string) error
+	RequestAnalysis(ctx context.Context, userID int64, week string) (*BodyFatEstimate, error)
+	ListEstimates(ctx context.Context, userID int64) ([]BodyFatEstimate, error)
+}
+
+// Service handles progress photo storage and metadata
+type Service struct {
+	db       *database.DB
+	log      *logger.Logger
+	files    *storage.Router
+	analyzer Analyzer
+	quotas   *quotas.Service
+	ids      *ids.Generator
+}
+
+// NewService creates a new photos service. Photos are kept in the storage
+// region of the user's organization, as routed by files. A nil analyzer
+// disables body fat estimation; nil quotas do not limit the total size of a
+// user's photos.
+func NewService(db *database.DB, log *logger.Logger, files *storage.Router, analyzer Analyzer, quota *quotas.Service) *Service {
+	return &Service{
+		db:       db,
+		log:      log,
+		files:    files,
+		analyzer: analyzer,
+		quotas:   quota,
+		ids:      ids.Default,
+	}
+}
+
+// Upload validates the image, stores it under photos/{userID}/ in the user's
+// storage region and records its metadata. The content type is detected from
+// the file contents, not taken from the client.
+// Photos taking the user over their photo quota fail with
+// *apperrors.QuotaExceededError.
+func (s *Service) Upload(ctx context.Context, userID int64, in UploadInput, data io.Reader) (*Photo, error) {
+	startTime := time.Now()
+
+	if !IsValidProjection(in.Projection) {
+		return nil, ErrInvalidProjection
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(data, MaxPhotoSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+	if len(buf) == 0 {
+		return nil, ErrEmptyPhoto
+	}
+	if len(buf) > MaxPhotoSize {
+		return nil, ErrPhotoTooLarge
+	}
+
+	contentType := http.DetectContentType(buf)
+	ext, ok := allowedContentTypes[contentType]
+	if !ok {
+		return nil, ErrInvalidContentType
+	}
+
+	region, store, err := s.files.ForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	id := s.ids.NewString()
+	key := fmt.Sprintf("photos/%d/%s%s", userID, id, ext)
+
+	if err := store.Put(ctx, key, bytes.NewReader(buf)); err != nil {
+		return nil, fmt.Errorf("failed to store photo: %w", err)
+	}
+
+	query := `
+		INSERT INTO progress_photos (id, user_id, projection, taken_on, storage_key, storage_region, content_type, size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING created_at
+	`
+
+	photo := &Photo{
+		ID:            id,
+		UserID:        userID,
+		Projection:    in.Projection,
+		TakenOn:       in.TakenOn.Format("2006-01-02"),
+		ContentType:   contentType,
+		SizeBytes:     len(buf),
+		StorageKey:    key,
+		StorageRegion: region,
+	}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.quotas.ReservePhotoBytes(ctx, tx, userID, int64(len(buf))); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx, query,
+			id, userID, in.Projection, photo.TakenOn, key, region, contentType, len(buf),
+		).Scan(&photo.CreatedAt)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"user_id":  userID,
+			"photo_id": id,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save photo metadata: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		if delErr := store.Delete(ctx, key); delErr != nil {
+			s.log.Error("Failed to cleanup stored photo after database error", "error", delErr, "key", key)
+		}
+		return nil, err
+	}
+
+	s.log.LogBusinessEvent("progress_photo_uploaded", map[string]interface{}{
+		"user_id":    userID,
+		"photo_id":   id,
+		"projection": in.Projection,
+		"size_bytes": len(buf),
+	})
+
+	return photo, nil
+}
+
+// List returns the user's photo metadata, optionally bounded by date (inclusive)
+func (s *Service) List(ctx context.Context, userID int64, from, to *time.Time) ([]Photo, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT id, user_id, projection, taken_on, content_type, size_bytes, created_at
+		FROM progress_photos
+		WHERE user_id = $1
+		  AND ($2::date IS NULL OR taken_on >= $2::date)
+		  AND ($3::date IS NULL OR taken_on <= $3::date)
+		ORDER BY taken_on DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, formatDate(from), formatDate(to))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list photos: %w", err)
+	}
+	defer rows.Close()
+
+	photos := make([]Photo, 0)
+	for rows.Next() {
+		var p Photo
+		var takenOn time.Time
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Projection, &takenOn, &p.ContentType, &p.SizeBytes, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan photo: %w", err)
+		}
+		p.TakenOn = takenOn.Format("2006-01-02")
+		photos = append(photos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating photos: %w", err)
+	}
+
+	return photos, nil
+}
+
+// Open returns the photo metadata and its contents. Photos of other users are
+// reported as not found.
+func (s *Service) Open(ctx context.Context, userID int64, photoID string) (*Photo, io.ReadCloser, error) {
+	photo, err := s.getOwned(ctx, userID, photoID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	store, err := s.files.ForRegion(photo.StorageRegion)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := store.Get(ctx, photo.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrObjectNotFound) {
+			s.log.Error("Photo metadata points to a missing object", "photo_id", photoID, "key", photo.StorageKey)
+			return nil, nil, apperrors.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to read photo: %w", err)
+	}
+
+	return photo, r, nil
+}
+
+// Delete removes the photo row and its stored file and frees its size in
+// the user's photo quota
+func (s *Service) Delete(ctx context.Context, userID int64, photoID string) error {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(photoID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	query := `DELETE FROM progress_photos WHERE id = $1 AND user_id = $2 RETURNING storage_key, storage_region, size_bytes`
+
+	var key, region string
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var size int64
+		err := tx.QueryRowContext(ctx, query, photoID, userID).Scan(&key, &region, &size)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"user_id":  userID,
+			"photo_id": photoID,
+		})
+		if err == sql.ErrNoRows {
+			return apperrors.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete photo: %w", err)
+		}
+		return s.quotas.ReleasePhotoBytes(ctx, tx, userID, size)
+	})
+	if err != nil {
+		return err
+	}
+
+	// The row is gone; an orphaned file is only wasted space
+	store, err := s.files.ForRegion(region)
+	if err == nil {
+		err = store.Delete(ctx, key)
+	}
+	if err != nil {
+		s.log.Error("Failed to delete photo file", "error", err, "photo_id", photoID, "key", key, "region", region)
+	}
+
+	s.log.LogBusinessEvent("progress_photo_deleted", map[string]interface{}{
+		"user_id":  userID,
+		"photo_id": photoID,
+	})
+
+	return nil
+}
+
+// getOwned loads photo metadata belonging to the user
+func (s *Service) getOwned(ctx context.Context, userID int64, photoID string) (*Photo, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(photoID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	query := `
+		SELECT id, user_id, projection, taken_on, storage_key, storage_region, content_type, size_bytes, created_at
+		FROM progress_photos
+		WHERE id = $1 AND user_id = $2
+	`
+
+	var p Photo
+	var takenOn time.Time
+	err := s.db.QueryRowContext(ctx, query, photoID, userID).
+		Scan(&p.ID, &p.UserID, &p.Projection, &takenOn, &p.StorageKey, &p.StorageRegion, &p.ContentType, &p.SizeBytes, &p.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  userID,
+		"photo_id": photoID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get photo: %w", err)
+	}
+	p.TakenOn = takenOn.Format("2006-01-02")
+
+	return &p, nil
+}
+
+// formatDate converts an optional date into a query argument
+func formatDate(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
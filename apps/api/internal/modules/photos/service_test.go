@@ -0,0 +1,349 @@
+package photos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testPhotoQuota is the photo quota of users in service tests
+const testPhotoQuota = 1 << 20
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *storage.MemoryStorage, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	db := &database.DB{DB: mockDB}
+	quota := quotas.NewService(db, logger.New(), quotas.Limits{PhotoBytes: testPhotoQuota})
+	service := NewService(db, logger.New(), storage.NewRouter(store, nil, nil), nil, quota)
+
+	return service, mock, store, func() { mockDB.Close() }
+}
+
+// pngBytes returns a small valid PNG image
+func pngBytes(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))))
+	return buf.Bytes()
+}
+
+func TestUpload(t *testing.T) {
+	takenOn := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("stores file under the user path and records metadata", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		img := pngBytes(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WithArgs(int64(5), int64(len(img)), int64(testPhotoQuota)).
+			WillReturnRows(sqlmock.NewRows([]string{"photo_bytes", "limit"}).AddRow(len(img), testPhotoQuota))
+		mock.ExpectQuery("INSERT INTO progress_photos").
+			WithArgs(sqlmock.AnyArg(), int64(5), "front", "2026-10-01", sqlmock.AnyArg(), "", "image/png", len(img)).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		photo, err := service.Upload(context.Background(), 5, UploadInput{Projection: "front", TakenOn: takenOn}, bytes.NewReader(img))
+
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", photo.ContentType)
+		assert.Equal(t, uuid.Version(7), uuid.MustParse(photo.ID).Version())
+		assert.Equal(t, "photos/5/"+photo.ID+".png", photo.StorageKey)
+		assert.Equal(t, []string{photo.StorageKey}, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects non-image content regardless of extension", func(t *testing.T) {
+		service, _, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "front", TakenOn: takenOn}, strings.NewReader("<html>not an image</html>"))
+
+		assert.ErrorIs(t, err, ErrInvalidContentType)
+		assert.Empty(t, store.Keys())
+	})
+
+	t.Run("rejects files over 10 MB", func(t *testing.T) {
+		service, _, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		data := io.MultiReader(bytes.NewReader(pngBytes(t)), bytes.NewReader(make([]byte, MaxPhotoSize)))
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "front", TakenOn: takenOn}, data)
+
+		assert.ErrorIs(t, err, ErrPhotoTooLarge)
+		assert.Empty(t, store.Keys())
+	})
+
+	t.Run("rejects unknown projection", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "top", TakenOn: takenOn}, bytes.NewReader(pngBytes(t)))
+
+		assert.ErrorIs(t, err, ErrInvalidProjection)
+	})
+
+	t.Run("removes stored file when metadata insert fails", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WillReturnRows(sqlmock.NewRows([]string{"photo_bytes", "limit"}).AddRow(100, testPhotoQuota))
+		mock.ExpectQuery("INSERT INTO progress_photos").WillReturnError(errors.New("db down"))
+		mock.ExpectRollback()
+
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "side", TakenOn: takenOn}, bytes.NewReader(pngBytes(t)))
+
+		assert.Error(t, err)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fits exactly at the quota", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+		img := pngBytes(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WillReturnRows(sqlmock.NewRows([]string{"photo_bytes", "limit"}).AddRow(testPhotoQuota, testPhotoQuota))
+		mock.ExpectQuery("INSERT INTO progress_photos").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "back", TakenOn: takenOn}, bytes.NewReader(img))
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects photos over the quota and removes the stored file", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		img := pngBytes(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WillReturnRows(sqlmock.NewRows([]string{"photo_bytes", "limit"}).AddRow(testPhotoQuota+1, testPhotoQuota))
+		mock.ExpectRollback()
+
+		_, err := service.Upload(context.Background(), 5, UploadInput{Projection: "back", TakenOn: takenOn}, bytes.NewReader(img))
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, quotas.PhotoBytes, quotaErr.Resource)
+		assert.Equal(t, int64(testPhotoQuota+1-len(img)), quotaErr.Used)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestOpen(t *testing.T) {
+	const photoID = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+	columns := []string{"id", "user_id", "projection", "taken_on", "storage_key", "storage_region", "content_type", "size_bytes", "created_at"}
+
+	t.Run("returns contents to the owner", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		key := "photos/5/" + photoID + ".png"
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("img")))
+
+		mock.ExpectQuery("SELECT id, user_id, projection").WithArgs(photoID, int64(5)).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(photoID, int64(5), "front", time.Now(), key, "", "image/png", 3, time.Now()))
+
+		photo, r, err := service.Open(context.Background(), 5, photoID)
+
+		require.NoError(t, err)
+		defer r.Close()
+		data, _ := io.ReadAll(r)
+		assert.Equal(t, "img", string(data))
+		assert.Equal(t, "image/png", photo.ContentType)
+	})
+
+	t.Run("hides photos of other users", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT id, user_id, projection").WithArgs(photoID, int64(6)).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, _, err := service.Open(context.Background(), 6, photoID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+
+	t.Run("treats malformed ids as not found", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, _, err := service.Open(context.Background(), 5, "../../etc/passwd")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	const photoID = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+
+	t.Run("removes row and file", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		key := "photos/5/" + photoID + ".jpg"
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("img")))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM progress_photos").WithArgs(photoID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region", "size_bytes"}).AddRow(key, "", 3))
+		mock.ExpectExec("UPDATE user_quotas SET photo_bytes = GREATEST").
+			WithArgs(int64(5), int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.Delete(context.Background(), 5, photoID))
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns not found for other users", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM progress_photos").WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region", "size_bytes"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, service.Delete(context.Background(), 6, photoID), apperrors.ErrNotFound)
+	})
+}
+
+// fakeObjectStore is an in-memory storage.ObjectStore of one region
+type fakeObjectStore struct {
+	objects map[string][]byte
+}
+
+func newFakeObjectStore() *fakeObjectStore {
+	return &fakeObjectStore{objects: make(map[string][]byte)}
+}
+
+func (f *fakeObjectStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	f.objects[key] = b
+	return "https://storage.example/" + key, nil
+}
+
+func (f *fakeObjectStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return f.objects[key], nil
+}
+
+func (f *fakeObjectStore) DeleteFile(ctx context.Context, key string) error {
+	delete(f.objects, key)
+	return nil
+}
+
+func (f *fakeObjectStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + key + "?signed", nil
+}
+
+func TestStorageRegion(t *testing.T) {
+	const photoID = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+	takenOn := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// setup routes user 5 to the "eu" region and user 6 to the unconfigured "us"
+	setup := func(t *testing.T) (*Service, sqlmock.Sqlmock, *storage.MemoryStorage, *fakeObjectStore) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { mockDB.Close() })
+
+		local, eu := storage.NewMemoryStorage(), newFakeObjectStore()
+		regions := storage.NewRegions("ru")
+		regions.Register("eu", eu)
+		orgRegions := map[int64]string{5: "eu", 6: "us"}
+		files := storage.NewRouter(local, regions, func(ctx context.Context, userID int64) (string, error) {
+			return orgRegions[userID], nil
+		})
+		return NewService(&database.DB{DB: mockDB}, logger.New(), files, nil, nil), mock, local, eu
+	}
+
+	t.Run("uploads to the organization's region and records it", func(t *testing.T) {
+		service, mock, local, eu := setup(t)
+		img := pngBytes(t)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO progress_photos").
+			WithArgs(sqlmock.AnyArg(), int64(5), "front", "2026-10-01", sqlmock.AnyArg(), "eu", "image/png", len(img)).
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		photo, err := service.Upload(context.Background(), 5, UploadInput{Projection: "front", TakenOn: takenOn}, bytes.NewReader(img))
+
+		require.NoError(t, err)
+		assert.Equal(t, img, eu.objects[photo.StorageKey])
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails the upload for an unconfigured region", func(t *testing.T) {
+		service, mock, local, _ := setup(t)
+
+		_, err := service.Upload(context.Background(), 6, UploadInput{Projection: "front", TakenOn: takenOn}, bytes.NewReader(pngBytes(t)))
+
+		assert.ErrorIs(t, err, storage.ErrRegionUnavailable)
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reads from the recorded region", func(t *testing.T) {
+		service, mock, _, eu := setup(t)
+		key := "photos/5/" + photoID + ".png"
+		eu.objects[key] = []byte("img")
+
+		mock.ExpectQuery("SELECT id, user_id, projection").WithArgs(photoID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "projection", "taken_on", "storage_key", "storage_region", "content_type", "size_bytes", "created_at"}).
+				AddRow(photoID, int64(5), "front", time.Now(), key, "eu", "image/png", 3, time.Now()))
+
+		_, r, err := service.Open(context.Background(), 5, photoID)
+
+		require.NoError(t, err)
+		defer r.Close()
+		data, _ := io.ReadAll(r)
+		assert.Equal(t, "img", string(data))
+	})
+
+	t.Run("deletes from the recorded region", func(t *testing.T) {
+		service, mock, local, eu := setup(t)
+		key := "photos/5/" + photoID + ".png"
+		eu.objects[key] = []byte("img")
+		require.NoError(t, local.Put(context.Background(), key, strings.NewReader("img")))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("DELETE FROM progress_photos").WithArgs(photoID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region", "size_bytes"}).AddRow(key, "eu", 3))
+		mock.ExpectCommit()
+
+		require.NoError(t, service.Delete(context.Background(), 5, photoID))
+		assert.Empty(t, eu.objects)
+		assert.Equal(t, []string{key}, local.Keys())
+	})
+}
@@ -0,0 +1,112 @@
+package photos
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/types"
+)
+
+// MaxPhotoSize is the maximum accepted upload size (10 MB)
+const MaxPhotoSize = 10 * 1024 * 1024
+
+// Photo projections
+const (
+	ProjectionFront = "front"
+	ProjectionSide  = "side"
+	ProjectionBack  = "back"
+)
+
+var (
+	ErrInvalidProjection  = errors.New("projection must be front, side or back")
+	ErrInvalidContentType = errors.New("photo must be image/jpeg or image/png")
+	ErrPhotoTooLarge      = errors.New("photo exceeds 10 MB")
+	ErrEmptyPhoto         = errors.New("photo is empty")
+)
+
+// allowedContentTypes maps accepted content types to file extensions
+var allowedContentTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// IsValidProjection reports whether p is a known projection
+func IsValidProjection(p string) bool {
+	return p == ProjectionFront || p == ProjectionSide || p == ProjectionBack
+}
+
+// Photo is progress photo metadata
+type Photo struct {
+	ID            string    `json:"id"`
+	UserID        int64     `json:"user_id"`
+	Projection    string    `json:"projection"`
+	TakenOn       string    `json:"date"`
+	ContentType   string    `json:"content_type"`
+	SizeBytes     int       `json:"size_bytes"`
+	CreatedAt     time.Time `json:"created_at"`
+	StorageKey    string    `json:"-"`
+	StorageRegion string    `json:"-"`
+}
+
+// UploadInput describes a validated upload request
+type UploadInput struct {
+	Projection string
+	TakenOn    time.Time
+}
+
+// CompleteUploadRequest is the body of POST /api/v1/photos/uploads/:id/complete.
+// Checksum is the SHA-256 of the whole photo as "sha256 <base64>".
+type CompleteUploadRequest struct {
+	Projection string     `json:"projection" binding:"required"`
+	Date       types.Date `json:"date" binding:"required"`
+	Checksum   string     `json:"checksum" binding:"required"`
+}
+
+// Body fat estimate statuses
+const (
+	EstimateStatusPending    = "pending"
+	EstimateStatusProcessing = "processing"
+	EstimateStatusDone       = "done"
+	EstimateStatusFailed     = "failed"
+)
+
+// MaxAnalysisAttempts is how many times a failing analyzer call is tried
+// before the estimate is marked failed
+const MaxAnalysisAttempts = 3
+
+var (
+	ErrInvalidWeek         = errors.New("week must be an ISO week like 2026-W42")
+	ErrAnalysisUnavailable = errors.New("body fat analysis is not configured")
+)
+
+// IncompleteSetError reports projections missing from the requested week
+type IncompleteSetError struct {
+	Missing []string
+}
+
+func (e *IncompleteSetError) Error() string {
+	return "photo set is incomplete: missing " + strings.Join(e.Missing, ", ")
+}
+
+// BodyFatEstimate is an analysis job and, once done, its result
+type BodyFatEstimate struct {
+	ID          string          `json:"id"`
+	UserID      int64           `json:"user_id"`
+	Week        string          `json:"week"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	BodyFatPct  *float64        `json:"body_fat_pct"`
+	Confidence  *float64        `json:"confidence"`
+	RawResponse json.RawMessage `json:"raw_response,omitempty"`
+	Error       *string         `json:"error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// AnalyzeRequest is the body of POST /api/v1/photos/analyze
+type AnalyzeRequest struct {
+	Week string `json:"week" binding:"required"`
+}
@@ -0,0 +1,109 @@
+package quotas
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves quota usage to users and limit overrides to admins
+type Handler struct {
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new quota handler
+func NewHandler(log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		log:     log,
+		service: service,
+	}
+}
+
+// GetUsage handles GET /api/v1/users/me/usage?date=YYYY-MM-DD
+// Entries are counted on date, by default today in the user's timezone.
+func (h *Handler) GetUsage(c *gin.Context) {
+	userID := c.GetInt64("user_id")
+
+	date := c.Query("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			response.Error(c, http.StatusBadRequest, "Неверный формат даты. Используйте YYYY-MM-DD")
+			return
+		}
+	}
+
+	usage, err := h.service.Usage(c.Request.Context(), userID, date)
+	if err != nil {
+		h.log.Errorw("Failed to get quota usage", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить использование квот")
+		return
+	}
+
+	response.Success(c, http.StatusOK, usage)
+}
+
+// GetUserQuotas handles GET /api/v1/admin/users/:id/quotas
+func (h *Handler) GetUserQuotas(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.service.Usage(c.Request.Context(), userID, "")
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, usage)
+}
+
+// SetUserQuotas handles PUT /api/v1/admin/users/:id/quotas
+// The body replaces all of the user's overrides; a limit left out or null
+// goes back to the configured one.
+func (h *Handler) SetUserQuotas(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req Overrides
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	usage, err := h.service.SetOverrides(c.Request.Context(), c.GetInt64("user_id"), userID, req)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, usage)
+}
+
+func (h *Handler) respondError(c *gin.Context, err error, userID int64) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		response.NotFound(c, "Пользователь не найден")
+		return
+	}
+	h.log.Errorw("Failed to handle user quotas", "error", err, "user_id", userID)
+	response.InternalError(c, "Не удалось обработать квоты пользователя")
+}
+
+func userIDParam(c *gin.Context) (int64, bool) {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		response.Error(c, http.StatusBadRequest, "Неверный идентификатор пользователя")
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,133 @@
+package quotas
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err       error
+	date      string
+	adminID   int64
+	userID    int64
+	overrides Overrides
+}
+
+func (m *mockService) Usage(ctx context.Context, userID int64, date string) (*Usage, error) {
+	m.userID, m.date = userID, date
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Usage{EntriesPerDay: DailyAmount{Date: "2026-10-16", Amount: Amount{Used: 3, Limit: 200}}}, nil
+}
+
+func (m *mockService) SetOverrides(ctx context.Context, adminID, userID int64, overrides Overrides) (*Usage, error) {
+	m.adminID, m.userID, m.overrides = adminID, userID, overrides
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Usage{}, nil
+}
+
+func serve(handler gin.HandlerFunc, method, route, path, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("user_id", testAdminID)
+		handler(c)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestHandler_GetUsage(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		err      error
+		code     int
+		wantDate string
+	}{
+		{"today", "", nil, http.StatusOK, ""},
+		{"given date", "?date=2026-10-01", nil, http.StatusOK, "2026-10-01"},
+		{"invalid date", "?date=01.10.2026", nil, http.StatusBadRequest, ""},
+		{"internal", "", assert.AnError, http.StatusInternalServerError, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockService{err: tt.err}
+			handler := NewHandler(logger.New(), service)
+
+			w := serve(handler.GetUsage, http.MethodGet, "/me/usage", "/me/usage"+tt.query, "")
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.Equal(t, tt.wantDate, service.date)
+		})
+	}
+
+	t.Run("reports used and limit", func(t *testing.T) {
+		handler := NewHandler(logger.New(), &mockService{})
+
+		w := serve(handler.GetUsage, http.MethodGet, "/me/usage", "/me/usage", "")
+
+		var resp struct {
+			Data Usage `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, DailyAmount{Date: "2026-10-16", Amount: Amount{Used: 3, Limit: 200}}, resp.Data.EntriesPerDay)
+	})
+}
+
+func TestHandler_SetUserQuotas(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		err  error
+		code int
+	}{
+		{"set", "/users/42/quotas", `{"entries_per_day":500,"webhooks":0}`, nil, http.StatusOK},
+		{"reset to configured", "/users/42/quotas", `{}`, nil, http.StatusOK},
+		{"negative limit", "/users/42/quotas", `{"photo_bytes":-1}`, nil, http.StatusBadRequest},
+		{"invalid user id", "/users/abc/quotas", `{}`, nil, http.StatusBadRequest},
+		{"unknown user", "/users/42/quotas", `{}`, apperrors.ErrNotFound, http.StatusNotFound},
+		{"internal", "/users/42/quotas", `{}`, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &mockService{err: tt.err}
+			handler := NewHandler(logger.New(), service)
+
+			w := serve(handler.SetUserQuotas, http.MethodPut, "/users/:id/quotas", tt.path, tt.body)
+
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("passes the admin and the limits", func(t *testing.T) {
+		service := &mockService{}
+		handler := NewHandler(logger.New(), service)
+
+		serve(handler.SetUserQuotas, http.MethodPut, "/users/:id/quotas", "/users/42/quotas", `{"entries_per_day":500}`)
+
+		assert.Equal(t, testAdminID, service.adminID)
+		assert.Equal(t, testUserID, service.userID)
+		require.NotNil(t, service.overrides.EntriesPerDay)
+		assert.Equal(t, int64(500), *service.overrides.EntriesPerDay)
+		assert.Nil(t, service.overrides.Webhooks)
+	})
+}
@@ -0,0 +1,19 @@
+package quotas
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+)
+
+type usageQuery struct {
+	Date string `form:"date"`
+}
+
+// Endpoints describes the usage route, served under /users, for the
+// OpenAPI document
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/me/usage", Summary: "Использование квот: записи за день (date, по умолчанию сегодня в часовом поясе пользователя), объём фото прогресса, активные вебхуки", Auth: openapi.Bearer, Query: usageQuery{}, Response: Usage{}},
+	}
+}
@@ -0,0 +1,15 @@
+package quotas
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes registers the usage route, which lives under /users, on r
+func RegisterRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/me/usage", h.GetUsage)
+}
+
+// RegisterAdminRoutes registers the limit override routes on the admin
+// group r
+func RegisterAdminRoutes(r *gin.RouterGroup, h *Handler) {
+	r.GET("/users/:id/quotas", h.GetUserQuotas)
+	r.PUT("/users/:id/quotas", h.SetUserQuotas)
+}
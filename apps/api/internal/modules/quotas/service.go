@@ -0,0 +1,264 @@
+// Package quotas limits how much data a single user can store: nutrition
+// entries per date, the total size of progress photos and active webhooks.
+// Usage is kept in counters that the writing services update in the
+// transaction of the write, so a check never has to count rows.
+package quotas
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/types"
+)
+
+// Querier is satisfied by *sql.Tx; counters are only changed inside the
+// transaction writing the counted row
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ServiceInterface defines the quota operations the handler uses
+type ServiceInterface interface {
+	Usage(ctx context.Context, userID int64, date string) (*Usage, error)
+	SetOverrides(ctx context.Context, adminID, userID int64, overrides Overrides) (*Usage, error)
+}
+
+// Service enforces and reports the per-user quotas. A nil *Service
+// enforces nothing, so writing services work without quotas in tests and
+// tools.
+type Service struct {
+	db       *database.DB
+	log      *logger.Logger
+	defaults Limits
+	audit    *audit.Service
+	now      func() time.Time
+}
+
+// NewService creates a new quota service with the limits of users without
+// an override
+func NewService(db *database.DB, log *logger.Logger, defaults Limits) *Service {
+	return &Service{
+		db:       db,
+		log:      log,
+		defaults: defaults,
+		audit:    audit.NewService(db.DB, log),
+		now:      time.Now,
+	}
+}
+
+// ReserveEntry counts a new nutrition entry of the user on date, failing
+// with *apperrors.QuotaExceededError when the date already has as many
+// entries as the user's limit
+func (s *Service) ReserveEntry(ctx context.Context, tx Querier, userID int64, date string) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		INSERT INTO user_daily_entries (user_id, date, entries)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (user_id, date) DO UPDATE SET entries = user_daily_entries.entries + 1
+		RETURNING entries, COALESCE((SELECT entries_per_day_limit FROM user_quotas WHERE user_id = $1), $3)`
+	return s.reserve(ctx, tx, EntriesPerDay, 1, query, userID, date, s.defaults.EntriesPerDay)
+}
+
+// ReleaseEntry uncounts a deleted nutrition entry of the user on date
+func (s *Service) ReleaseEntry(ctx context.Context, tx Querier, userID int64, date string) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		UPDATE user_daily_entries SET entries = GREATEST(entries - 1, 0)
+		WHERE user_id = $1 AND date = $2`
+	return s.release(ctx, tx, EntriesPerDay, query, userID, date)
+}
+
+// ReservePhotoBytes counts size more bytes of progress photos, failing with
+// *apperrors.QuotaExceededError when they would take the user over their
+// limit
+func (s *Service) ReservePhotoBytes(ctx context.Context, tx Querier, userID, size int64) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		INSERT INTO user_quotas (user_id, photo_bytes)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET photo_bytes = user_quotas.photo_bytes + EXCLUDED.photo_bytes, updated_at = NOW()
+		RETURNING photo_bytes, COALESCE(photo_bytes_limit, $3)`
+	return s.reserve(ctx, tx, PhotoBytes, size, query, userID, size, s.defaults.PhotoBytes)
+}
+
+// ReleasePhotoBytes uncounts size bytes of deleted progress photos
+func (s *Service) ReleasePhotoBytes(ctx context.Context, tx Querier, userID, size int64) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		UPDATE user_quotas SET photo_bytes = GREATEST(photo_bytes - $2, 0), updated_at = NOW()
+		WHERE user_id = $1`
+	return s.release(ctx, tx, PhotoBytes, query, userID, size)
+}
+
+// ReserveWebhook counts a new active webhook, failing with
+// *apperrors.QuotaExceededError when the user has as many as their limit
+func (s *Service) ReserveWebhook(ctx context.Context, tx Querier, userID int64) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		INSERT INTO user_quotas (user_id, webhooks)
+		VALUES ($1, 1)
+		ON CONFLICT (user_id) DO UPDATE SET webhooks = user_quotas.webhooks + 1, updated_at = NOW()
+		RETURNING webhooks, COALESCE(webhooks_limit, $2)`
+	return s.reserve(ctx, tx, Webhooks, 1, query, userID, s.defaults.Webhooks)
+}
+
+// ReleaseWebhook uncounts a webhook that stopped being active
+func (s *Service) ReleaseWebhook(ctx context.Context, tx Querier, userID int64) error {
+	if s == nil {
+		return nil
+	}
+	query := `
+		UPDATE user_quotas SET webhooks = GREATEST(webhooks - 1, 0), updated_at = NOW()
+		WHERE user_id = $1`
+	return s.release(ctx, tx, Webhooks, query, userID)
+}
+
+// reserve runs an upsert adding amount to a counter and returning the new
+// usage and the user's limit. The upsert locks the counter row, so
+// concurrent writes of one user are counted one after the other. Going
+// over the limit fails the caller's transaction, which undoes the count.
+func (s *Service) reserve(ctx context.Context, tx Querier, resource string, amount int64, query string, args ...any) error {
+	startTime := time.Now()
+	var used, limit int64
+	err := tx.QueryRowContext(ctx, query, args...).Scan(&used, &limit)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  args[0],
+		"resource": resource,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count %s quota: %w", resource, err)
+	}
+
+	if used > limit {
+		s.log.LogBusinessEvent("quota_exceeded", map[string]interface{}{
+			"user_id":  args[0],
+			"resource": resource,
+			"limit":    limit,
+		})
+		return &apperrors.QuotaExceededError{Resource: resource, Used: used - amount, Limit: limit}
+	}
+	return nil
+}
+
+// release runs an update taking a deleted amount off a counter. Counters
+// do not go below zero, so a release without its reservation is harmless.
+func (s *Service) release(ctx context.Context, tx Querier, resource, query string, args ...any) error {
+	startTime := time.Now()
+	_, err := tx.ExecContext(ctx, query, args...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":  args[0],
+		"resource": resource,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release %s quota: %w", resource, err)
+	}
+	return nil
+}
+
+// Usage returns the user's usage and limit of each quota. Entries are
+// counted on date (YYYY-MM-DD), when empty the user's today in their
+// timezone, the day entries are logged on.
+func (s *Service) Usage(ctx context.Context, userID int64, date string) (*Usage, error) {
+	if date == "" {
+		date = types.DateOf(s.now().In(middleware.GetUserTimezone(ctx, s.db, userID))).String()
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT COALESCE(d.entries, 0), COALESCE(q.photo_bytes, 0), COALESCE(q.webhooks, 0),
+		       q.entries_per_day_limit, q.photo_bytes_limit, q.webhooks_limit
+		FROM users u
+		LEFT JOIN user_quotas q ON q.user_id = u.id
+		LEFT JOIN user_daily_entries d ON d.user_id = u.id AND d.date = $2
+		WHERE u.id = $1`
+
+	var entries, photoBytes, webhooks int64
+	var entriesLimit, photoBytesLimit, webhooksLimit sql.NullInt64
+	err := s.db.QueryRowContext(ctx, query, userID, date).Scan(
+		&entries, &photoBytes, &webhooks, &entriesLimit, &photoBytesLimit, &webhooksLimit)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota usage: %w", err)
+	}
+
+	return &Usage{
+		EntriesPerDay: DailyAmount{Date: date, Amount: amount(entries, entriesLimit, s.defaults.EntriesPerDay)},
+		PhotoBytes:    amount(photoBytes, photoBytesLimit, s.defaults.PhotoBytes),
+		Webhooks:      amount(webhooks, webhooksLimit, s.defaults.Webhooks),
+	}, nil
+}
+
+func amount(used int64, override sql.NullInt64, defaultLimit int64) Amount {
+	if override.Valid {
+		return Amount{Used: used, Limit: override.Int64, Overridden: true}
+	}
+	return Amount{Used: used, Limit: defaultLimit}
+}
+
+// SetOverrides replaces the user's own limits, set by adminID, and returns
+// the usage under them. Lowering a limit below the usage keeps the stored
+// data but refuses new writes until usage is under it again.
+func (s *Service) SetOverrides(ctx context.Context, adminID, userID int64, overrides Overrides) (*Usage, error) {
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists); err != nil {
+			return fmt.Errorf("failed to look up user: %w", err)
+		}
+		if !exists {
+			return apperrors.ErrNotFound
+		}
+
+		startTime := time.Now()
+		query := `
+			INSERT INTO user_quotas (user_id, entries_per_day_limit, photo_bytes_limit, webhooks_limit)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id) DO UPDATE SET
+				entries_per_day_limit = EXCLUDED.entries_per_day_limit,
+				photo_bytes_limit = EXCLUDED.photo_bytes_limit,
+				webhooks_limit = EXCLUDED.webhooks_limit,
+				updated_at = NOW()`
+		_, err := tx.ExecContext(ctx, query, userID, overrides.EntriesPerDay, overrides.PhotoBytes, overrides.Webhooks)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to set quota limits: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &userID,
+		Action: audit.ActionQuotaLimitsChanged,
+		Metadata: map[string]any{
+			"entries_per_day": overrides.EntriesPerDay,
+			"photo_bytes":     overrides.PhotoBytes,
+			"webhooks":        overrides.Webhooks,
+			"changed_by":      adminID,
+		},
+	})
+	return s.Usage(ctx, userID, "")
+}
@@ -0,0 +1,216 @@
+package quotas
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserID  int64 = 42
+	testAdminID int64 = 1
+)
+
+var (
+	testNow    = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	testLimits = Limits{EntriesPerDay: 200, PhotoBytes: 1 << 30, Webhooks: 5}
+)
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	service := NewService(&database.DB{DB: db}, logger.New(), testLimits)
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func TestService_Reserve(t *testing.T) {
+	usedRows := func(used, limit int64) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"used", "limit"}).AddRow(used, limit)
+	}
+
+	t.Run("entry reaching the limit is counted", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO user_daily_entries").
+			WithArgs(testUserID, "2026-10-16", testLimits.EntriesPerDay).
+			WillReturnRows(usedRows(200, 200))
+
+		err := service.ReserveEntry(context.Background(), service.db, testUserID, "2026-10-16")
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("entry over the limit reports the usage before it", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO user_daily_entries").WillReturnRows(usedRows(201, 200))
+
+		err := service.ReserveEntry(context.Background(), service.db, testUserID, "2026-10-16")
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, apperrors.QuotaExceededError{Resource: EntriesPerDay, Used: 200, Limit: 200}, *quotaErr)
+		assert.ErrorIs(t, err, apperrors.ErrForbidden)
+	})
+
+	t.Run("photo filling the quota exactly is counted", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WithArgs(testUserID, int64(1000), testLimits.PhotoBytes).
+			WillReturnRows(usedRows(5000, 5000))
+
+		err := service.ReservePhotoBytes(context.Background(), service.db, testUserID, 1000)
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("photo over the quota", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO user_quotas").WillReturnRows(usedRows(5001, 5000))
+
+		err := service.ReservePhotoBytes(context.Background(), service.db, testUserID, 1000)
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, apperrors.QuotaExceededError{Resource: PhotoBytes, Used: 4001, Limit: 5000}, *quotaErr)
+	})
+
+	t.Run("webhook over an overridden limit", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WithArgs(testUserID, testLimits.Webhooks).
+			WillReturnRows(usedRows(2, 1))
+
+		err := service.ReserveWebhook(context.Background(), service.db, testUserID)
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, apperrors.QuotaExceededError{Resource: Webhooks, Used: 1, Limit: 1}, *quotaErr)
+	})
+
+	t.Run("releases take the amount off the counter", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectExec("UPDATE user_daily_entries SET entries = GREATEST\\(entries - 1, 0\\)").
+			WithArgs(testUserID, "2026-10-16").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE user_quotas SET photo_bytes = GREATEST\\(photo_bytes - \\$2, 0\\)").
+			WithArgs(testUserID, int64(1000)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE user_quotas SET webhooks = GREATEST\\(webhooks - 1, 0\\)").
+			WithArgs(testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		ctx := context.Background()
+		require.NoError(t, service.ReleaseEntry(ctx, service.db, testUserID, "2026-10-16"))
+		require.NoError(t, service.ReleasePhotoBytes(ctx, service.db, testUserID, 1000))
+		require.NoError(t, service.ReleaseWebhook(ctx, service.db, testUserID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nil service counts nothing", func(t *testing.T) {
+		var service *Service
+		ctx := context.Background()
+
+		assert.NoError(t, service.ReserveEntry(ctx, nil, testUserID, "2026-10-16"))
+		assert.NoError(t, service.ReservePhotoBytes(ctx, nil, testUserID, 1000))
+		assert.NoError(t, service.ReserveWebhook(ctx, nil, testUserID))
+		assert.NoError(t, service.ReleaseEntry(ctx, nil, testUserID, "2026-10-16"))
+	})
+}
+
+func TestService_Usage(t *testing.T) {
+	columns := []string{"entries", "photo_bytes", "webhooks", "entries_per_day_limit", "photo_bytes_limit", "webhooks_limit"}
+
+	t.Run("configured limits, entries counted today", func(t *testing.T) {
+		service, mock := setupService(t)
+		// 12:00 UTC is already the next day in the user's timezone
+		mock.ExpectQuery("SELECT COALESCE\\(timezone").
+			WithArgs(testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Pacific/Kiritimati"))
+		mock.ExpectQuery("SELECT (.+) FROM users u").
+			WithArgs(testUserID, "2026-10-17").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(12, 2048, 1, nil, nil, nil))
+
+		usage, err := service.Usage(context.Background(), testUserID, "")
+
+		require.NoError(t, err)
+		assert.Equal(t, &Usage{
+			EntriesPerDay: DailyAmount{Date: "2026-10-17", Amount: Amount{Used: 12, Limit: 200}},
+			PhotoBytes:    Amount{Used: 2048, Limit: 1 << 30},
+			Webhooks:      Amount{Used: 1, Limit: 5},
+		}, usage)
+	})
+
+	t.Run("overridden limit", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("SELECT (.+) FROM users u").
+			WithArgs(testUserID, "2026-10-01").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(0, 0, 0, 500, nil, 0))
+
+		usage, err := service.Usage(context.Background(), testUserID, "2026-10-01")
+
+		require.NoError(t, err)
+		assert.Equal(t, Amount{Limit: 500, Overridden: true}, usage.EntriesPerDay.Amount)
+		assert.Equal(t, Amount{Limit: 0, Overridden: true}, usage.Webhooks)
+		assert.False(t, usage.PhotoBytes.Overridden)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("SELECT (.+) FROM users u").WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := service.Usage(context.Background(), testUserID, "2026-10-16")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestService_SetOverrides(t *testing.T) {
+	t.Run("replaces the limits and audits the change", func(t *testing.T) {
+		service, mock := setupService(t)
+		entries := int64(500)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(testUserID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO user_quotas").
+			WithArgs(testUserID, entries, nil, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(testUserID, sqlmock.AnyArg(), "quota_limits_changed", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT COALESCE\\(timezone").
+			WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Europe/Moscow"))
+		mock.ExpectQuery("SELECT (.+) FROM users u").
+			WithArgs(testUserID, "2026-10-16").
+			WillReturnRows(sqlmock.NewRows([]string{"entries", "photo_bytes", "webhooks", "a", "b", "c"}).
+				AddRow(3, 0, 0, 500, nil, nil))
+
+		usage, err := service.SetOverrides(context.Background(), testAdminID, testUserID, Overrides{EntriesPerDay: &entries})
+
+		require.NoError(t, err)
+		assert.Equal(t, Amount{Used: 3, Limit: 500, Overridden: true}, usage.EntriesPerDay.Amount)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT EXISTS").
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		_, err := service.SetOverrides(context.Background(), testAdminID, testUserID, Overrides{})
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
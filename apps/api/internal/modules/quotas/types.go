@@ -0,0 +1,59 @@
+package quotas
+
+import "github.com/burcev/api/internal/config"
+
+// Resources with a per-user quota, as reported in QUOTA_EXCEEDED responses
+const (
+	// EntriesPerDay counts nutrition entries per entry date
+	EntriesPerDay = "entries_per_day"
+	// PhotoBytes is the total size of the user's progress photos
+	PhotoBytes = "photo_bytes"
+	// Webhooks counts active webhooks; disabled ones are not counted
+	Webhooks = "webhooks"
+)
+
+// Limits are the quota limits of a user
+type Limits struct {
+	EntriesPerDay int64
+	PhotoBytes    int64
+	Webhooks      int64
+}
+
+// LimitsFrom returns the configured limits, which apply to every user
+// without an override
+func LimitsFrom(cfg *config.Config) Limits {
+	return Limits{
+		EntriesPerDay: int64(cfg.QuotaEntriesPerDay),
+		PhotoBytes:    int64(cfg.QuotaPhotoBytes),
+		Webhooks:      int64(cfg.QuotaWebhooks),
+	}
+}
+
+// Amount is the usage of one quota. Overridden is set when an admin gave
+// the user their own limit.
+type Amount struct {
+	Used       int64 `json:"used"`
+	Limit      int64 `json:"limit"`
+	Overridden bool  `json:"overridden"`
+}
+
+// DailyAmount is the usage of a per-day quota on Date
+type DailyAmount struct {
+	Date string `json:"date"`
+	Amount
+}
+
+// Usage is a user's consumption of each quota
+type Usage struct {
+	EntriesPerDay DailyAmount `json:"entries_per_day"`
+	PhotoBytes    Amount      `json:"photo_bytes"`
+	Webhooks      Amount      `json:"webhooks"`
+}
+
+// Overrides are a user's own limits set by an admin. A nil limit uses the
+// configured one.
+type Overrides struct {
+	EntriesPerDay *int64 `json:"entries_per_day" binding:"omitempty,min=0"`
+	PhotoBytes    *int64 `json:"photo_bytes" binding:"omitempty,min=0"`
+	Webhooks      *int64 `json:"webhooks" binding:"omitempty,min=0"`
+}
@@ -0,0 +1,166 @@
+package recipes
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles recipe requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new recipes handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateRecipe handles POST /api/v1/recipes
+func (h *Handler) CreateRecipe(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req RecipeRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	recipe, err := h.service.CreateRecipe(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, recipe)
+}
+
+// ListRecipes handles GET /api/v1/recipes?search=&limit=&offset=
+func (h *Handler) ListRecipes(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	filter := ListFilter{
+		Search: strings.TrimSpace(c.Query("search")),
+		Limit:  DefaultPageSize,
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err := strconv.Atoi(v); err == nil && limit > 0 {
+			filter.Limit = min(limit, MaxPageSize)
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if offset, err := strconv.Atoi(v); err == nil && offset >= 0 {
+			filter.Offset = offset
+		}
+	}
+
+	list, err := h.service.ListRecipes(c.Request.Context(), userID, filter)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, list)
+}
+
+// GetRecipe handles GET /api/v1/recipes/:id
+func (h *Handler) GetRecipe(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	recipe, err := h.service.GetRecipe(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, recipe)
+}
+
+// UpdateRecipe handles PUT /api/v1/recipes/:id
+// Entries already logged from the recipe keep their macros.
+func (h *Handler) UpdateRecipe(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req RecipeRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	recipe, err := h.service.UpdateRecipe(c.Request.Context(), userID, c.Param("id"), &req)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, recipe)
+}
+
+// DeleteRecipe handles DELETE /api/v1/recipes/:id
+func (h *Handler) DeleteRecipe(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteRecipe(c.Request.Context(), userID, c.Param("id")); err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Рецепт удалён", nil)
+}
+
+func (h *Handler) respondError(c *gin.Context, err error, userID int64) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		validation.Respond(c, err)
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, "Рецепт не найден")
+	default:
+		h.log.Error("Failed to process recipe request", "error", err, "user_id", userID, "recipe_id", c.Param("id"))
+		response.InternalError(c, "Не удалось обработать запрос")
+	}
+}
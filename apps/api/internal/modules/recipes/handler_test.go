@@ -0,0 +1,134 @@
+package recipes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	filter ListFilter
+	err    error
+}
+
+func (m *mockService) CreateRecipe(ctx context.Context, userID int64, req *RecipeRequest) (*Recipe, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Recipe{ID: testRecipeID, UserID: userID, Name: req.Name}, nil
+}
+
+func (m *mockService) UpdateRecipe(ctx context.Context, userID int64, recipeID string, req *RecipeRequest) (*Recipe, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Recipe{ID: recipeID, UserID: userID, Name: req.Name}, nil
+}
+
+func (m *mockService) GetRecipe(ctx context.Context, userID int64, recipeID string) (*Recipe, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Recipe{ID: recipeID, UserID: userID}, nil
+}
+
+func (m *mockService) ListRecipes(ctx context.Context, userID int64, filter ListFilter) (*RecipeList, error) {
+	m.filter = filter
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &RecipeList{Recipes: []Recipe{}}, nil
+}
+
+func (m *mockService) DeleteRecipe(ctx context.Context, userID int64, recipeID string) error {
+	return m.err
+}
+
+func newTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: testRecipeID}}
+	c.Set("user_id", testUserID)
+	return c, w
+}
+
+func TestHandlerCreateRecipe(t *testing.T) {
+	body := `{"name":"Чили","cooked_weight":1100,"ingredients":[{"food_id":"` + beefID + `","grams":500}]}`
+
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", body, nil, http.StatusCreated},
+		{"missing ingredients", `{"name":"Чили","cooked_weight":1100}`, nil, http.StatusBadRequest},
+		{"unknown food", body, validation.Errors{"ingredients[0].food_id": "Продукт не найден"}, http.StatusBadRequest},
+		{"internal", body, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/recipes", tt.body)
+
+			handler.CreateRecipe(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerListRecipes(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  ListFilter
+	}{
+		{"defaults", "", ListFilter{Limit: DefaultPageSize}},
+		{"search and page", "?search=%20чили%20&limit=10&offset=20", ListFilter{Search: "чили", Limit: 10, Offset: 20}},
+		{"limit capped", "?limit=1000", ListFilter{Limit: MaxPageSize}},
+		{"invalid paging ignored", "?limit=-1&offset=abc", ListFilter{Limit: DefaultPageSize}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockService{}
+			handler := NewHandler(nil, logger.New(), svc)
+			c, w := newTestContext(http.MethodGet, "/recipes"+tt.query, "")
+
+			handler.ListRecipes(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, svc.filter)
+		})
+	}
+}
+
+func TestHandlerRecipeNotFound(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{err: apperrors.ErrNotFound})
+
+	for name, call := range map[string]func(*gin.Context){
+		"get":    handler.GetRecipe,
+		"delete": handler.DeleteRecipe,
+	} {
+		t.Run(name, func(t *testing.T) {
+			c, w := newTestContext(http.MethodGet, "/recipes/"+testRecipeID, "")
+
+			call(c)
+
+			assert.Equal(t, http.StatusNotFound, w.Code)
+		})
+	}
+}
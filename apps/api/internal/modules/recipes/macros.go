@@ -0,0 +1,56 @@
+package recipes
+
+import "math"
+
+// per100g returns the macros of 100 g of the cooked dish. The ingredients'
+// macros are summed and spread over the cooked weight, so cooking changes the
+// density of the dish but not its totals. Values are rounded to two decimal
+// places, as stored.
+func per100g(ingredients []Ingredient, cookedWeight float64) Macros {
+	if cookedWeight <= 0 {
+		return Macros{}
+	}
+
+	var total Macros
+	for _, ing := range ingredients {
+		total = total.add(ing.per100.scale(ing.Grams / 100))
+	}
+	return total.scale(100 / cookedWeight).rounded()
+}
+
+// ForGrams returns the macros of grams of a food given its macros per 100 g
+func (m Macros) ForGrams(grams float64) Macros {
+	return m.scale(grams / 100).rounded()
+}
+
+func (m Macros) add(o Macros) Macros {
+	return Macros{
+		Calories: m.Calories + o.Calories,
+		Protein:  m.Protein + o.Protein,
+		Fat:      m.Fat + o.Fat,
+		Carbs:    m.Carbs + o.Carbs,
+	}
+}
+
+func (m Macros) scale(factor float64) Macros {
+	return Macros{
+		Calories: m.Calories * factor,
+		Protein:  m.Protein * factor,
+		Fat:      m.Fat * factor,
+		Carbs:    m.Carbs * factor,
+	}
+}
+
+func (m Macros) rounded() Macros {
+	return Macros{
+		Calories: round2(m.Calories),
+		Protein:  round2(m.Protein),
+		Fat:      round2(m.Fat),
+		Carbs:    round2(m.Carbs),
+	}
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
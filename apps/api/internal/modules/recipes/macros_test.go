@@ -0,0 +1,79 @@
+package recipes
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	beef     = Macros{Calories: 250, Protein: 26, Fat: 17}
+	beans    = Macros{Calories: 127, Protein: 8.7, Fat: 0.5, Carbs: 22.8}
+	tomatoes = Macros{Calories: 18, Protein: 0.9, Fat: 0.2, Carbs: 3.9}
+)
+
+func ingredient(per100 Macros, grams float64) Ingredient {
+	return Ingredient{Grams: grams, per100: per100}
+}
+
+func TestPer100g(t *testing.T) {
+	chili := []Ingredient{ingredient(beef, 500), ingredient(beans, 400), ingredient(tomatoes, 400)}
+
+	tests := []struct {
+		name         string
+		ingredients  []Ingredient
+		cookedWeight float64
+		want         Macros
+	}{
+		{
+			name:         "single ingredient, weight unchanged",
+			ingredients:  []Ingredient{ingredient(beans, 250)},
+			cookedWeight: 250,
+			want:         beans,
+		},
+		{
+			name:         "water absorbed dilutes the dish",
+			ingredients:  []Ingredient{ingredient(Macros{Calories: 350, Protein: 12, Fat: 1, Carbs: 72}, 100)},
+			cookedWeight: 250,
+			want:         Macros{Calories: 140, Protein: 4.8, Fat: 0.4, Carbs: 28.8},
+		},
+		{
+			// 1830 kcal, 168.4 P, 87.8 F, 106.8 C spread over 1100 g
+			name:         "several ingredients, water evaporated",
+			ingredients:  chili,
+			cookedWeight: 1100,
+			want:         Macros{Calories: 166.36, Protein: 15.31, Fat: 7.98, Carbs: 9.71},
+		},
+		{
+			name:         "repeated food counts twice",
+			ingredients:  []Ingredient{ingredient(beef, 100), ingredient(beef, 100)},
+			cookedWeight: 200,
+			want:         beef,
+		},
+		{
+			name:         "zero cooked weight",
+			ingredients:  chili,
+			cookedWeight: 0,
+			want:         Macros{},
+		},
+		{
+			name:         "no ingredients",
+			cookedWeight: 500,
+			want:         Macros{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, per100g(tt.ingredients, tt.cookedWeight))
+		})
+	}
+}
+
+func TestMacrosForGrams(t *testing.T) {
+	per100 := Macros{Calories: 166.36, Protein: 15.31, Fat: 7.98, Carbs: 9.71}
+
+	assert.Equal(t, Macros{Calories: 582.26, Protein: 53.59, Fat: 27.93, Carbs: 33.99}, per100.ForGrams(350))
+	assert.Equal(t, per100, per100.ForGrams(100))
+	assert.Equal(t, Macros{}, per100.ForGrams(0))
+}
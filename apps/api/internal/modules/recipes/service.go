@@ -0,0 +1,400 @@
+package recipes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// ServiceInterface defines the interface for recipe operations
+type ServiceInterface interface {
+	CreateRecipe(ctx context.Context, userID int64, req *RecipeRequest) (*Recipe, error)
+	UpdateRecipe(ctx context.Context, userID int64, recipeID string, req *RecipeRequest) (*Recipe, error)
+	GetRecipe(ctx context.Context, userID int64, recipeID string) (*Recipe, error)
+	ListRecipes(ctx context.Context, userID int64, filter ListFilter) (*RecipeList, error)
+	DeleteRecipe(ctx context.Context, userID int64, recipeID string) error
+}
+
+// Service manages recipes
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new recipes service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{db: db, log: log}
+}
+
+const recipeColumns = `id, user_id, name, cooked_weight, calories_per_100, protein_per_100, fat_per_100, carbs_per_100, created_at, updated_at`
+
+// likeEscaper escapes LIKE wildcards in user-supplied search text
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// validateRecipe checks a recipe request and trims its name. Invalid input
+// is reported as validation.Errors; ingredient fields are keyed by index,
+// e.g. ingredients[2].grams.
+func validateRecipe(req *RecipeRequest) error {
+	errs := validation.Errors{}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		errs["name"] = "Обязательное поле"
+	}
+	if !inRange(req.CookedWeight, MaxCookedWeight) {
+		errs["cooked_weight"] = fmt.Sprintf("Значение должно быть больше 0 и не больше %d", MaxCookedWeight)
+	}
+
+	switch {
+	case len(req.Ingredients) == 0:
+		errs["ingredients"] = "Добавьте хотя бы один ингредиент"
+	case len(req.Ingredients) > MaxIngredients:
+		errs["ingredients"] = fmt.Sprintf("Не больше %d ингредиентов", MaxIngredients)
+	}
+	for i, ing := range req.Ingredients {
+		if _, err := uuid.Parse(ing.FoodID); err != nil {
+			errs[fmt.Sprintf("ingredients[%d].food_id", i)] = "Неверный ID продукта"
+		}
+		if !inRange(ing.Grams, MaxIngredientGrams) {
+			errs[fmt.Sprintf("ingredients[%d].grams", i)] = fmt.Sprintf("Значение должно быть больше 0 и не больше %d", MaxIngredientGrams)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// inRange reports whether v is in (0, limit]
+func inRange(v, limit float64) bool {
+	return !math.IsNaN(v) && v > 0 && v <= limit
+}
+
+// CreateRecipe saves a recipe and computes its macros per 100 g
+func (s *Service) CreateRecipe(ctx context.Context, userID int64, req *RecipeRequest) (*Recipe, error) {
+	if err := validateRecipe(req); err != nil {
+		return nil, err
+	}
+	ingredients, err := s.loadIngredients(ctx, req.Ingredients)
+	if err != nil {
+		return nil, err
+	}
+	per100 := per100g(ingredients, req.CookedWeight)
+
+	var recipe *Recipe
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		startTime := time.Now()
+		query := `
+			INSERT INTO recipes (user_id, name, cooked_weight, calories_per_100, protein_per_100, fat_per_100, carbs_per_100)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING ` + recipeColumns
+
+		recipe, err = scanRecipe(tx.QueryRowContext(ctx, query,
+			userID, req.Name, req.CookedWeight, per100.Calories, per100.Protein, per100.Fat, per100.Carbs))
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to create recipe: %w", err)
+		}
+		return s.insertIngredients(ctx, tx, recipe.ID, ingredients)
+	})
+	if err != nil {
+		return nil, err
+	}
+	recipe.Ingredients = ingredients
+
+	s.log.LogBusinessEvent("recipe_created", map[string]interface{}{
+		"recipe_id":   recipe.ID,
+		"user_id":     userID,
+		"ingredients": len(ingredients),
+	})
+
+	return recipe, nil
+}
+
+// UpdateRecipe replaces a recipe's name, cooked weight and ingredients and
+// recomputes its macros. Nutrition entries already logged from the recipe
+// keep the macros they were logged with.
+func (s *Service) UpdateRecipe(ctx context.Context, userID int64, recipeID string, req *RecipeRequest) (*Recipe, error) {
+	if err := validateRecipe(req); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(recipeID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+	ingredients, err := s.loadIngredients(ctx, req.Ingredients)
+	if err != nil {
+		return nil, err
+	}
+	per100 := per100g(ingredients, req.CookedWeight)
+
+	var recipe *Recipe
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		startTime := time.Now()
+		query := `
+			UPDATE recipes
+			SET name = $3, cooked_weight = $4, calories_per_100 = $5, protein_per_100 = $6,
+			    fat_per_100 = $7, carbs_per_100 = $8, updated_at = NOW()
+			WHERE id = $1 AND user_id = $2
+			RETURNING ` + recipeColumns
+
+		recipe, err = scanRecipe(tx.QueryRowContext(ctx, query,
+			recipeID, userID, req.Name, req.CookedWeight, per100.Calories, per100.Protein, per100.Fat, per100.Carbs))
+		s.log.LogDatabaseQuery(query, time.Since(startTime), ignoreNoRows(err), map[string]interface{}{
+			"user_id":   userID,
+			"recipe_id": recipeID,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update recipe: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM recipe_ingredients WHERE recipe_id = $1`, recipeID); err != nil {
+			return fmt.Errorf("failed to clear recipe ingredients: %w", err)
+		}
+		return s.insertIngredients(ctx, tx, recipeID, ingredients)
+	})
+	if err != nil {
+		return nil, err
+	}
+	recipe.Ingredients = ingredients
+
+	return recipe, nil
+}
+
+// GetRecipe returns one of the user's recipes with its ingredients
+func (s *Service) GetRecipe(ctx context.Context, userID int64, recipeID string) (*Recipe, error) {
+	recipe, err := s.getOwned(ctx, userID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT ri.food_id, f.name, ri.grams
+		FROM recipe_ingredients ri
+		JOIN food_items f ON f.id = ri.food_id
+		WHERE ri.recipe_id = $1
+		ORDER BY ri.position`
+
+	rows, err := s.db.QueryContext(ctx, query, recipeID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"recipe_id": recipeID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recipe ingredients: %w", err)
+	}
+	defer rows.Close()
+
+	recipe.Ingredients = make([]Ingredient, 0)
+	for rows.Next() {
+		var ing Ingredient
+		if err := rows.Scan(&ing.FoodID, &ing.Name, &ing.Grams); err != nil {
+			return nil, fmt.Errorf("failed to scan recipe ingredient: %w", err)
+		}
+		recipe.Ingredients = append(recipe.Ingredients, ing)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recipe ingredients: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// ListRecipes returns a page of the user's recipes ordered by name, without
+// ingredients. Search matches any part of the name, case-insensitively.
+func (s *Service) ListRecipes(ctx context.Context, userID int64, filter ListFilter) (*RecipeList, error) {
+	startTime := time.Now()
+
+	where := "WHERE user_id = $1"
+	args := []interface{}{userID}
+	if filter.Search != "" {
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(filter.Search))+"%")
+		where += fmt.Sprintf(" AND LOWER(name) LIKE $%d", len(args))
+	}
+
+	var total int
+	countQuery := `SELECT COUNT(*) FROM recipes ` + where
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		s.log.LogDatabaseQuery(countQuery, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		return nil, fmt.Errorf("failed to count recipes: %w", err)
+	}
+
+	query := fmt.Sprintf(`SELECT %s FROM recipes %s ORDER BY LOWER(name), id LIMIT $%d OFFSET $%d`,
+		recipeColumns, where, len(args)+1, len(args)+2)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recipes: %w", err)
+	}
+	defer rows.Close()
+
+	list := &RecipeList{Recipes: make([]Recipe, 0), Total: total}
+	for rows.Next() {
+		recipe, err := scanRecipe(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recipe: %w", err)
+		}
+		list.Recipes = append(list.Recipes, *recipe)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recipes: %w", err)
+	}
+	list.HasMore = filter.Offset+len(list.Recipes) < total
+
+	return list, nil
+}
+
+// DeleteRecipe deletes one of the user's recipes. Entries logged from it
+// keep their macros and lose the reference.
+func (s *Service) DeleteRecipe(ctx context.Context, userID int64, recipeID string) error {
+	if _, err := uuid.Parse(recipeID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `DELETE FROM recipes WHERE id = $1 AND user_id = $2`
+	result, err := s.db.ExecContext(ctx, query, recipeID, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":   userID,
+		"recipe_id": recipeID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete recipe: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// Portion returns the macros of grams of one of the user's recipes, computed
+// from its current per-100 g values. Callers store the result, so later
+// edits of the recipe do not change it.
+func (s *Service) Portion(ctx context.Context, userID int64, recipeID string, grams float64) (*Portion, error) {
+	recipe, err := s.getOwned(ctx, userID, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	return &Portion{
+		RecipeID: recipe.ID,
+		Name:     recipe.Name,
+		Grams:    grams,
+		Macros:   recipe.Per100g.ForGrams(grams),
+	}, nil
+}
+
+func (s *Service) getOwned(ctx context.Context, userID int64, recipeID string) (*Recipe, error) {
+	if _, err := uuid.Parse(recipeID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	recipe, err := scanRecipe(s.db.QueryRowContext(ctx,
+		`SELECT `+recipeColumns+` FROM recipes WHERE id = $1 AND user_id = $2`,
+		recipeID, userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipe: %w", err)
+	}
+	return recipe, nil
+}
+
+// loadIngredients looks up the requested foods in food_items. Unknown foods
+// are reported as validation.Errors keyed by ingredient index.
+func (s *Service) loadIngredients(ctx context.Context, reqs []IngredientRequest) ([]Ingredient, error) {
+	ids := make([]string, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for _, r := range reqs {
+		if !seen[r.FoodID] {
+			seen[r.FoodID] = true
+			ids = append(ids, r.FoodID)
+		}
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT id, name, calories_per_100, protein_per_100, fat_per_100, carbs_per_100
+		FROM food_items
+		WHERE id = ANY($1)`
+
+	// The IDs were validated as UUIDs, so the array literal is safe
+	rows, err := s.db.QueryContext(ctx, query, "{"+strings.Join(ids, ",")+"}")
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"foods": len(ids)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recipe foods: %w", err)
+	}
+	defer rows.Close()
+
+	foods := make(map[string]Ingredient, len(ids))
+	for rows.Next() {
+		var food Ingredient
+		err := rows.Scan(&food.FoodID, &food.Name,
+			&food.per100.Calories, &food.per100.Protein, &food.per100.Fat, &food.per100.Carbs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan recipe food: %w", err)
+		}
+		foods[food.FoodID] = food
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read recipe foods: %w", err)
+	}
+
+	ingredients := make([]Ingredient, len(reqs))
+	errs := validation.Errors{}
+	for i, r := range reqs {
+		food, ok := foods[r.FoodID]
+		if !ok {
+			errs[fmt.Sprintf("ingredients[%d].food_id", i)] = "Продукт не найден"
+			continue
+		}
+		food.Grams = r.Grams
+		ingredients[i] = food
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return ingredients, nil
+}
+
+func (s *Service) insertIngredients(ctx context.Context, tx *sql.Tx, recipeID string, ingredients []Ingredient) error {
+	query := `INSERT INTO recipe_ingredients (recipe_id, position, food_id, grams) VALUES ($1, $2, $3, $4)`
+	for i, ing := range ingredients {
+		if _, err := tx.ExecContext(ctx, query, recipeID, i, ing.FoodID, ing.Grams); err != nil {
+			return fmt.Errorf("failed to add recipe ingredient: %w", err)
+		}
+	}
+	return nil
+}
+
+func scanRecipe(row interface{ Scan(dest ...any) error }) (*Recipe, error) {
+	var r Recipe
+	err := row.Scan(&r.ID, &r.UserID, &r.Name, &r.CookedWeight,
+		&r.Per100g.Calories, &r.Per100g.Protein, &r.Per100g.Fat, &r.Per100g.Carbs,
+		&r.CreatedAt, &r.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func ignoreNoRows(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	return err
+}
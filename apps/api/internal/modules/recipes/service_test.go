@@ -0,0 +1,228 @@
+package recipes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testRecipeID = "7c1d2e3f-4a5b-4c6d-8e9f-0a1b2c3d4e5f"
+	beefID       = "11111111-1111-4111-8111-111111111111"
+	beansID      = "22222222-2222-4222-8222-222222222222"
+	testUserID   = int64(42)
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+func foodRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "calories_per_100", "protein_per_100", "fat_per_100", "carbs_per_100"}).
+		AddRow(beefID, "Говяжий фарш", beef.Calories, beef.Protein, beef.Fat, beef.Carbs).
+		AddRow(beansID, "Фасоль", beans.Calories, beans.Protein, beans.Fat, beans.Carbs)
+}
+
+func recipeRows(name string, per100 Macros) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "name", "cooked_weight",
+		"calories_per_100", "protein_per_100", "fat_per_100", "carbs_per_100", "created_at", "updated_at"}).
+		AddRow(testRecipeID, testUserID, name, 800.0, per100.Calories, per100.Protein, per100.Fat, per100.Carbs, testNow, testNow)
+}
+
+func chiliRequest() *RecipeRequest {
+	return &RecipeRequest{
+		Name:         " Чили ",
+		CookedWeight: 800,
+		Ingredients: []IngredientRequest{
+			{FoodID: beefID, Grams: 500},
+			{FoodID: beansID, Grams: 400},
+		},
+	}
+}
+
+func TestValidateRecipe(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    RecipeRequest
+		fields []string
+	}{
+		{"valid", *chiliRequest(), nil},
+		{"blank name", RecipeRequest{Name: " ", CookedWeight: 100, Ingredients: []IngredientRequest{{FoodID: beefID, Grams: 100}}}, []string{"name"}},
+		{"no ingredients", RecipeRequest{Name: "Чили", CookedWeight: 100}, []string{"ingredients"}},
+		{"cooked weight out of range", RecipeRequest{Name: "Чили", CookedWeight: MaxCookedWeight + 1, Ingredients: []IngredientRequest{{FoodID: beefID, Grams: 100}}}, []string{"cooked_weight"}},
+		{"bad ingredient", RecipeRequest{Name: "Чили", CookedWeight: 100, Ingredients: []IngredientRequest{{FoodID: beefID, Grams: 100}, {FoodID: "42", Grams: -1}}},
+			[]string{"ingredients[1].food_id", "ingredients[1].grams"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRecipe(&tt.req)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				assert.Equal(t, "Чили", tt.req.Name)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+}
+
+func TestService_CreateRecipe(t *testing.T) {
+	// 500 g beef + 400 g beans = 1758 kcal, 164.8 P, 87 F, 91.2 C over 800 g
+	per100 := Macros{Calories: 219.75, Protein: 20.6, Fat: 10.88, Carbs: 11.4}
+
+	t.Run("macros computed from ingredients", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT (.+) FROM food_items WHERE id = ANY").
+			WithArgs("{" + beefID + "," + beansID + "}").
+			WillReturnRows(foodRows())
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO recipes").
+			WithArgs(testUserID, "Чили", 800.0, per100.Calories, per100.Protein, per100.Fat, per100.Carbs).
+			WillReturnRows(recipeRows("Чили", per100))
+		mock.ExpectExec("INSERT INTO recipe_ingredients").WithArgs(testRecipeID, 0, beefID, 500.0).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO recipe_ingredients").WithArgs(testRecipeID, 1, beansID, 400.0).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		recipe, err := service.CreateRecipe(context.Background(), testUserID, chiliRequest())
+
+		require.NoError(t, err)
+		assert.Equal(t, per100, recipe.Per100g)
+		require.Len(t, recipe.Ingredients, 2)
+		assert.Equal(t, "Фасоль", recipe.Ingredients[1].Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown food", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT (.+) FROM food_items").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "calories_per_100", "protein_per_100", "fat_per_100", "carbs_per_100"}).
+				AddRow(beefID, "Говяжий фарш", 250.0, 26.0, 17.0, 0.0))
+
+		_, err := service.CreateRecipe(context.Background(), testUserID, chiliRequest())
+
+		assert.Equal(t, validation.Errors{"ingredients[1].food_id": "Продукт не найден"}, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_UpdateRecipe(t *testing.T) {
+	t.Run("recomputes the recipe only", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		per100 := Macros{Calories: 219.75, Protein: 20.6, Fat: 10.88, Carbs: 11.4}
+		mock.ExpectQuery("SELECT (.+) FROM food_items").WillReturnRows(foodRows())
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE recipes").
+			WithArgs(testRecipeID, testUserID, "Чили", 800.0, per100.Calories, per100.Protein, per100.Fat, per100.Carbs).
+			WillReturnRows(recipeRows("Чили", per100))
+		mock.ExpectExec("DELETE FROM recipe_ingredients").WithArgs(testRecipeID).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("INSERT INTO recipe_ingredients").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO recipe_ingredients").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		recipe, err := service.UpdateRecipe(context.Background(), testUserID, testRecipeID, chiliRequest())
+
+		require.NoError(t, err)
+		assert.Equal(t, per100, recipe.Per100g)
+		// Any statement touching nutrition_entries would fail as unexpected
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("foreign recipe", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("SELECT (.+) FROM food_items").WillReturnRows(foodRows())
+		mock.ExpectBegin()
+		mock.ExpectQuery("UPDATE recipes").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		_, err := service.UpdateRecipe(context.Background(), testUserID, testRecipeID, chiliRequest())
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_GetRecipe(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("SELECT (.+) FROM recipes WHERE id = \\$1 AND user_id = \\$2").
+		WithArgs(testRecipeID, testUserID).
+		WillReturnRows(recipeRows("Чили", beef))
+	mock.ExpectQuery("SELECT ri.food_id, f.name, ri.grams FROM recipe_ingredients").
+		WithArgs(testRecipeID).
+		WillReturnRows(sqlmock.NewRows([]string{"food_id", "name", "grams"}).AddRow(beefID, "Говяжий фарш", 500.0))
+
+	recipe, err := service.GetRecipe(context.Background(), testUserID, testRecipeID)
+
+	require.NoError(t, err)
+	assert.Equal(t, []Ingredient{{FoodID: beefID, Name: "Говяжий фарш", Grams: 500}}, recipe.Ingredients)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_ListRecipes(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM recipes WHERE user_id = \\$1 AND LOWER\\(name\\) LIKE \\$2").
+		WithArgs(testUserID, `%100\%%`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT (.+) FROM recipes WHERE (.+) ORDER BY LOWER\\(name\\), id LIMIT \\$3 OFFSET \\$4").
+		WithArgs(testUserID, `%100\%%`, 1, 1).
+		WillReturnRows(recipeRows("Чили 100%", beef))
+
+	list, err := service.ListRecipes(context.Background(), testUserID, ListFilter{Search: "100%", Limit: 1, Offset: 1})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, list.Total)
+	assert.True(t, list.HasMore)
+	require.Len(t, list.Recipes, 1)
+	assert.Nil(t, list.Recipes[0].Ingredients)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_DeleteRecipe(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectExec("DELETE FROM recipes").WithArgs(testRecipeID, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := service.DeleteRecipe(context.Background(), testUserID, testRecipeID)
+
+	assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	assert.ErrorIs(t, service.DeleteRecipe(context.Background(), testUserID, "not-a-uuid"), apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Portion(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("SELECT (.+) FROM recipes").
+		WithArgs(testRecipeID, testUserID).
+		WillReturnRows(recipeRows("Чили", Macros{Calories: 166.36, Protein: 15.31, Fat: 7.98, Carbs: 9.71}))
+
+	portion, err := service.Portion(context.Background(), testUserID, testRecipeID, 350)
+
+	require.NoError(t, err)
+	assert.Equal(t, &Portion{
+		RecipeID: testRecipeID,
+		Name:     "Чили",
+		Grams:    350,
+		Macros:   Macros{Calories: 582.26, Protein: 53.59, Fat: 27.93, Carbs: 33.99},
+	}, portion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
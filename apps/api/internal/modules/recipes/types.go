@@ -0,0 +1,86 @@
+package recipes
+
+import "time"
+
+// Recipe limits
+const (
+	MaxIngredients     = 50
+	MaxIngredientGrams = 10000
+	MaxCookedWeight    = 20000
+	// MaxPortionGrams bounds a logged portion of a recipe
+	MaxPortionGrams = 5000
+)
+
+// List page sizes
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// Macros are calories (kcal) and protein, fat and carbs (g)
+type Macros struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+}
+
+// IngredientRequest is one ingredient of a recipe: a food item and its raw
+// weight in grams
+type IngredientRequest struct {
+	FoodID string  `json:"food_id"`
+	Grams  float64 `json:"grams"`
+}
+
+// RecipeRequest represents a recipe to create or replace
+type RecipeRequest struct {
+	Name         string              `json:"name" binding:"required,max=255"`
+	CookedWeight float64             `json:"cooked_weight" binding:"required"`
+	Ingredients  []IngredientRequest `json:"ingredients" binding:"required"`
+}
+
+// Ingredient is a food item used in a recipe
+type Ingredient struct {
+	FoodID string  `json:"food_id"`
+	Name   string  `json:"name"`
+	Grams  float64 `json:"grams"`
+	// per100 is the food's macros per 100 g, used to compute the recipe
+	per100 Macros
+}
+
+// Recipe is a dish made of food items. Per100g is computed from the
+// ingredients and the cooked weight when the recipe is saved. Ingredients
+// are only filled in when a single recipe is requested.
+type Recipe struct {
+	ID           string       `json:"id"`
+	UserID       int64        `json:"user_id"`
+	Name         string       `json:"name"`
+	CookedWeight float64      `json:"cooked_weight"`
+	Per100g      Macros       `json:"per_100g"`
+	Ingredients  []Ingredient `json:"ingredients,omitempty"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// ListFilter selects a page of the user's recipes. Search matches the name.
+type ListFilter struct {
+	Search string
+	Limit  int
+	Offset int
+}
+
+// RecipeList is a page of recipes
+type RecipeList struct {
+	Recipes []Recipe `json:"recipes"`
+	Total   int      `json:"total"`
+	HasMore bool     `json:"hasMore"`
+}
+
+// Portion is the macros of a portion of a recipe, as logged in a nutrition
+// entry
+type Portion struct {
+	RecipeID string  `json:"recipe_id"`
+	Name     string  `json:"name"`
+	Grams    float64 `json:"grams"`
+	Macros
+}
@@ -0,0 +1,197 @@
+package recommendations
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/measurements"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/modules/photos"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// NutritionSource provides targets, intake and the fitness goal (implemented by nutritioncalc.Service)
+type NutritionSource interface {
+	GetHistory(ctx context.Context, userID int64, days int) ([]nutritioncalc.TargetVsActual, error)
+	GetFitnessGoal(ctx context.Context, userID int64) (nutritioncalc.FitnessGoal, error)
+}
+
+// WeightSource provides the weight trend (implemented by measurements.Service)
+type WeightSource interface {
+	GetWeightTrend(ctx context.Context, userID int64, days int, today time.Time) (*measurements.WeightTrend, error)
+}
+
+// BodyFatSource provides body-fat estimates (implemented by photos.Service)
+type BodyFatSource interface {
+	ListEstimates(ctx context.Context, userID int64) ([]photos.BodyFatEstimate, error)
+}
+
+// DayFlagSource provides the days whose flag (a refeed, sick or travel day)
+// leaves them out (implemented by nutrition.FlagService)
+type DayFlagSource interface {
+	ExcludedDates(ctx context.Context, userID int64, from, to string) (map[string]bool, error)
+}
+
+// Handler handles recommendation requests
+type Handler struct {
+	cfg       *config.Config
+	log       *logger.Logger
+	db        *database.DB
+	nutrition NutritionSource
+	weights   WeightSource
+	bodyFat   BodyFatSource
+	dayFlags  DayFlagSource
+}
+
+// NewHandler creates a new recommendations handler. bodyFat may be nil when
+// progress photos are not configured; dayFlags may be nil to count every day.
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, nutrition NutritionSource, weights WeightSource, bodyFat BodyFatSource, dayFlags DayFlagSource) *Handler {
+	return &Handler{
+		cfg:       cfg,
+		log:       log,
+		db:        db,
+		nutrition: nutrition,
+		weights:   weights,
+		bodyFat:   bodyFat,
+		dayFlags:  dayFlags,
+	}
+}
+
+// GetNutrition handles GET /api/v1/recommendations/nutrition
+// Compares the last two weeks of intake with the weight (and body-fat) change
+// and suggests a calorie adjustment. Not enough history is reported with
+// data_sufficiency=false, not as an error.
+func (h *Handler) GetNutrition(c *gin.Context) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return
+	}
+
+	ctx := c.Request.Context()
+	userLoc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return
+	}
+	today := time.Now().In(userLoc)
+
+	goal, err := h.nutrition.GetFitnessGoal(ctx, userID)
+	if err != nil {
+		h.log.Error("Failed to get fitness goal", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось сформировать рекомендацию")
+		return
+	}
+
+	history, err := h.nutrition.GetHistory(ctx, userID, WindowDays)
+	if err != nil {
+		h.log.Error("Failed to get nutrition history", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось сформировать рекомендацию")
+		return
+	}
+
+	// A planned refeed must not read as overeating, so a failure here is fatal
+	var excluded map[string]bool
+	if h.dayFlags != nil && len(history) > 0 {
+		excluded, err = h.dayFlags.ExcludedDates(ctx, userID, history[0].Date, history[len(history)-1].Date)
+		if err != nil {
+			h.log.Error("Failed to get day flags", "error", err, "user_id", userID)
+			response.InternalError(c, "Не удалось сформировать рекомендацию")
+			return
+		}
+	}
+
+	trend, err := h.weights.GetWeightTrend(ctx, userID, WindowDays, today)
+	if err != nil {
+		h.log.Error("Failed to get weight trend", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось сформировать рекомендацию")
+		return
+	}
+
+	in := Input{Goal: goal}
+	applyHistory(&in, history, excluded)
+	applyTrend(&in, trend)
+
+	// Body fat only refines the decision, so a failure here is not fatal
+	if h.bodyFat != nil {
+		estimates, err := h.bodyFat.ListEstimates(ctx, userID)
+		if err != nil {
+			h.log.Error("Failed to list body fat estimates", "error", err, "user_id", userID)
+		} else {
+			in.BodyFatChange = bodyFatChange(estimates)
+		}
+	}
+
+	response.Success(c, http.StatusOK, Recommend(in))
+}
+
+// applyHistory fills the current target (the latest day that has one) and
+// the average intake over the days with food entries. The intake of
+// excluded days (YYYY-MM-DD) is left out.
+func applyHistory(in *Input, history []nutritioncalc.TargetVsActual, excluded map[string]bool) {
+	var total float64
+	for _, day := range history {
+		if day.Target != nil {
+			calories := day.Target.Calories
+			in.TargetCalories = &calories
+		}
+		if excluded[day.Date] {
+			in.ExcludedDays++
+			continue
+		}
+		if day.Actual != nil && day.Actual.Calories > 0 {
+			total += day.Actual.Calories
+			in.LoggedDays++
+		}
+	}
+	if in.LoggedDays > 0 {
+		in.AverageIntake = total / float64(in.LoggedDays)
+	}
+}
+
+// applyTrend fills the weigh-in coverage and the weekly weight change
+func applyTrend(in *Input, trend *measurements.WeightTrend) {
+	in.WeighIns = len(trend.Points)
+	if in.WeighIns == 0 {
+		return
+	}
+
+	first, errFirst := time.Parse("2006-01-02", trend.Points[0].Date)
+	last, errLast := time.Parse("2006-01-02", trend.Points[in.WeighIns-1].Date)
+	if errFirst == nil && errLast == nil {
+		in.WeighInSpan = int(last.Sub(first).Hours() / 24)
+	}
+	if trend.CurrentWeight != nil {
+		in.CurrentWeight = *trend.CurrentWeight
+	}
+	if trend.WeeklyRate != nil {
+		in.WeeklyChange = *trend.WeeklyRate
+	}
+}
+
+// bodyFatChange is the difference between the two most recent completed
+// estimates in percentage points, or nil if there are fewer than two.
+// Estimates are listed newest week first.
+func bodyFatChange(estimates []photos.BodyFatEstimate) *float64 {
+	var values []float64
+	for _, e := range estimates {
+		if e.Status == photos.EstimateStatusDone && e.BodyFatPct != nil {
+			values = append(values, *e.BodyFatPct)
+			if len(values) == 2 {
+				change := values[0] - values[1]
+				return &change
+			}
+		}
+	}
+	return nil
+}
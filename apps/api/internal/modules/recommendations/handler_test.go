@@ -0,0 +1,195 @@
+package recommendations
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/modules/measurements"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/modules/photos"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockNutrition struct {
+	history []nutritioncalc.TargetVsActual
+	err     error
+}
+
+func (m *mockNutrition) GetHistory(ctx context.Context, userID int64, days int) ([]nutritioncalc.TargetVsActual, error) {
+	return m.history, m.err
+}
+
+func (m *mockNutrition) GetFitnessGoal(ctx context.Context, userID int64) (nutritioncalc.FitnessGoal, error) {
+	return nutritioncalc.GoalLoss, nil
+}
+
+type mockWeights struct {
+	trend *measurements.WeightTrend
+}
+
+func (m *mockWeights) GetWeightTrend(ctx context.Context, userID int64, days int, today time.Time) (*measurements.WeightTrend, error) {
+	return m.trend, nil
+}
+
+type mockDayFlags struct {
+	excluded map[string]bool
+	err      error
+}
+
+func (m *mockDayFlags) ExcludedDates(ctx context.Context, userID int64, from, to string) (map[string]bool, error) {
+	return m.excluded, m.err
+}
+
+type mockBodyFat struct {
+	estimates []photos.BodyFatEstimate
+}
+
+func (m *mockBodyFat) ListEstimates(ctx context.Context, userID int64) ([]photos.BodyFatEstimate, error) {
+	return m.estimates, nil
+}
+
+// history returns days of logged intake against a 2000 kcal target
+func history(days int, intake float64) []nutritioncalc.TargetVsActual {
+	result := make([]nutritioncalc.TargetVsActual, 0, days)
+	for i := 0; i < days; i++ {
+		result = append(result, nutritioncalc.TargetVsActual{
+			Date:   time.Date(2026, 10, 3+i, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			Target: &nutritioncalc.CalculatedTargets{Calories: 2000},
+			Actual: &nutritioncalc.ActualIntake{Calories: intake},
+		})
+	}
+	return result
+}
+
+// stalledTrend is a flat weight over two weeks
+func stalledTrend() *measurements.WeightTrend {
+	current, rate := 80.0, 0.0
+	points := make([]measurements.WeightTrendPoint, 0)
+	for _, day := range []int{3, 6, 9, 12, 16} {
+		points = append(points, measurements.WeightTrendPoint{
+			Date:   time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC).Format("2006-01-02"),
+			Weight: 80,
+			EMA:    80,
+		})
+	}
+	return &measurements.WeightTrend{Days: WindowDays, Points: points, CurrentWeight: &current, WeeklyRate: &rate}
+}
+
+func doRequest(t *testing.T, handler *Handler) (*httptest.ResponseRecorder, Recommendation) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/recommendations/nutrition", nil)
+	c.Set("user_id", int64(5))
+
+	handler.GetNutrition(c)
+
+	var body struct {
+		Data Recommendation `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w, body.Data
+}
+
+func TestHandlerGetNutrition(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: history(14, 1990)}, &mockWeights{trend: stalledTrend()}, nil, nil)
+
+	w, rec := doRequest(t, handler)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, rec.DataSufficiency)
+	assert.Equal(t, 2000, *rec.CurrentTarget)
+	assert.Equal(t, 2000-CalorieStep, *rec.SuggestedTarget)
+	assert.Equal(t, 14, rec.Inputs.LoggedDays)
+	assert.Equal(t, 5, rec.Inputs.WeighIns)
+	assert.Equal(t, 13, rec.Inputs.WeighInSpan)
+}
+
+func TestHandlerGetNutritionInsufficientData(t *testing.T) {
+	// Only the last 5 days are logged
+	days := history(14, 0)
+	for i := 9; i < 14; i++ {
+		days[i].Actual.Calories = 1990
+	}
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: days}, &mockWeights{trend: stalledTrend()}, nil, nil)
+
+	w, rec := doRequest(t, handler)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.False(t, rec.DataSufficiency)
+	assert.Equal(t, 5, rec.Inputs.LoggedDays)
+	assert.Equal(t, rec.CurrentTarget, rec.SuggestedTarget)
+}
+
+func TestHandlerGetNutritionBodyFat(t *testing.T) {
+	estimates := &mockBodyFat{estimates: []photos.BodyFatEstimate{
+		{Week: "2026-W42", Status: photos.EstimateStatusDone, BodyFatPct: floatPtr(19.2)},
+		{Week: "2026-W41", Status: photos.EstimateStatusFailed},
+		{Week: "2026-W40", Status: photos.EstimateStatusDone, BodyFatPct: floatPtr(20.0)},
+	}}
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: history(14, 1990)}, &mockWeights{trend: stalledTrend()}, estimates, nil)
+
+	w, rec := doRequest(t, handler)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, rec.Inputs.BodyFatChange)
+	assert.InDelta(t, -0.8, *rec.Inputs.BodyFatChange, 0.001)
+	assert.Zero(t, rec.Adjustment)
+}
+
+func TestHandlerGetNutritionExcludedDays(t *testing.T) {
+	// A planned refeed at twice the target would otherwise look like the
+	// target is not being followed
+	days := history(14, 1990)
+	days[6].Actual.Calories = 5000
+	flags := &mockDayFlags{excluded: map[string]bool{days[6].Date: true}}
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: days}, &mockWeights{trend: stalledTrend()}, nil, flags)
+
+	w, rec := doRequest(t, handler)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 13, rec.Inputs.LoggedDays)
+	assert.Equal(t, 1, rec.Inputs.ExcludedDays)
+	assert.Equal(t, 1990.0, rec.Inputs.AverageIntake)
+	assert.Equal(t, 2000-CalorieStep, *rec.SuggestedTarget)
+
+	t.Run("counted without flags", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: days}, &mockWeights{trend: stalledTrend()}, nil, nil)
+
+		_, rec := doRequest(t, handler)
+
+		assert.Equal(t, 14, rec.Inputs.LoggedDays)
+		assert.Equal(t, 2205.0, rec.Inputs.AverageIntake)
+		assert.Zero(t, rec.Adjustment)
+	})
+}
+
+func TestHandlerGetNutritionDayFlagsError(t *testing.T) {
+	flags := &mockDayFlags{err: assert.AnError}
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{history: history(14, 1990)}, &mockWeights{trend: stalledTrend()}, nil, flags)
+
+	w, _ := doRequest(t, handler)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandlerGetNutritionError(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), nil, &mockNutrition{err: assert.AnError}, &mockWeights{trend: stalledTrend()}, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/recommendations/nutrition", nil)
+	c.Set("user_id", int64(5))
+
+	handler.GetNutrition(c)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
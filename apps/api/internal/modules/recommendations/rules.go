@@ -0,0 +1,125 @@
+package recommendations
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+)
+
+// Recommend decides whether the calorie target should change. It is pure:
+// the same input always gives the same recommendation.
+//
+// The checks run in order: enough data, then adherence (a target that is not
+// being followed cannot be judged), then the weight rate for the user's goal,
+// with body-fat change overriding the weight signal where they disagree.
+func Recommend(in Input) Recommendation {
+	rec := Recommendation{
+		WindowDays: WindowDays,
+		Inputs:     in,
+	}
+
+	if missing := insufficientData(in); len(missing) > 0 {
+		if in.TargetCalories != nil {
+			current := int(math.Round(*in.TargetCalories))
+			rec.CurrentTarget = &current
+			rec.SuggestedTarget = &current
+		}
+		rec.Reason = "Недостаточно данных для рекомендации: " + strings.Join(missing, "; ") + "."
+		return rec
+	}
+
+	rec.DataSufficiency = true
+	current := int(math.Round(*in.TargetCalories))
+	rec.CurrentTarget = &current
+
+	adjustment, reason := decide(in, current)
+
+	suggested := current + adjustment
+	if adjustment < 0 && suggested < MinCalories {
+		// Never cut below the floor, and never raise a target already under it
+		suggested = min(current, MinCalories)
+	}
+	rec.SuggestedTarget = &suggested
+	rec.Adjustment = suggested - current
+	rec.Reason = reason
+	if adjustment != 0 && rec.Adjustment == 0 {
+		rec.Reason += fmt.Sprintf(" Цель уже на минимуме %d ккал, дальнейшее снижение не рекомендуется.", MinCalories)
+	}
+
+	return rec
+}
+
+// insufficientData lists what is missing for a recommendation
+func insufficientData(in Input) []string {
+	var missing []string
+	if in.TargetCalories == nil || *in.TargetCalories <= 0 {
+		missing = append(missing, "не задана цель по калориям")
+	}
+	if in.LoggedDays < MinLoggedDays {
+		missing = append(missing, fmt.Sprintf("питание записано за %d из %d необходимых дней", in.LoggedDays, MinLoggedDays))
+	}
+	if in.WeighIns < MinWeighIns || in.WeighInSpan < MinWeighInSpanDays || in.CurrentWeight <= 0 {
+		missing = append(missing, fmt.Sprintf("нужно не менее %d взвешиваний за %d дней", MinWeighIns, MinWeighInSpanDays))
+	}
+	return missing
+}
+
+// decide returns the calorie adjustment and its explanation
+func decide(in Input, current int) (int, string) {
+	intake := int(math.Round(in.AverageIntake))
+	if math.Abs(in.AverageIntake-float64(current))/float64(current) > AdherenceTolerance {
+		return 0, fmt.Sprintf(
+			"Среднее потребление %d ккал отличается от цели %d ккал более чем на %.0f%%. "+
+				"Сначала стоит придерживаться текущей цели, чтобы оценить её эффект.",
+			intake, current, AdherenceTolerance*100)
+	}
+
+	rate := in.WeeklyChange / in.CurrentWeight
+	ratePct := rate * 100
+	fatFalling := in.BodyFatChange != nil && *in.BodyFatChange <= -BodyFatChangeThreshold
+	fatRising := in.BodyFatChange != nil && *in.BodyFatChange >= BodyFatChangeThreshold
+
+	switch in.Goal {
+	case nutritioncalc.GoalLoss:
+		switch {
+		case rate > LossStallRate && fatFalling:
+			return 0, fmt.Sprintf(
+				"Вес почти не меняется (%+.2f%% в неделю), но процент жира снизился на %.1f п.п. — "+
+					"идёт рекомпозиция, цель менять не нужно.", ratePct, -*in.BodyFatChange)
+		case rate > LossStallRate:
+			return -CalorieStep, fmt.Sprintf(
+				"Снижение веса остановилось: %+.2f%% в неделю при потреблении %d ккал. "+
+					"Рекомендуем снизить цель на %d ккал.", ratePct, intake, CalorieStep)
+		case rate < LossMaxRate:
+			return CalorieStep, fmt.Sprintf(
+				"Вес снижается слишком быстро: %.2f%% в неделю. "+
+					"Рекомендуем повысить цель на %d ккал, чтобы сохранить мышечную массу.", ratePct, CalorieStep)
+		}
+		return 0, fmt.Sprintf("Вес снижается в рекомендуемом темпе (%.2f%% в неделю), цель менять не нужно.", ratePct)
+
+	case nutritioncalc.GoalGain:
+		switch {
+		case rate > GainMaxRate || fatRising:
+			return -CalorieStep, fmt.Sprintf(
+				"Набор идёт слишком быстро (%+.2f%% в неделю) или за счёт жира. "+
+					"Рекомендуем снизить цель на %d ккал.", ratePct, CalorieStep)
+		case rate < GainMinRate:
+			return CalorieStep, fmt.Sprintf(
+				"Набор веса остановился: %+.2f%% в неделю при потреблении %d ккал. "+
+					"Рекомендуем повысить цель на %d ккал.", ratePct, intake, CalorieStep)
+		}
+		return 0, fmt.Sprintf("Вес растёт в рекомендуемом темпе (%+.2f%% в неделю), цель менять не нужно.", ratePct)
+	}
+
+	switch {
+	case rate > MaintainTolerance:
+		return -CalorieStep, fmt.Sprintf(
+			"Вес растёт (%+.2f%% в неделю) при цели поддержания. Рекомендуем снизить цель на %d ккал.", ratePct, CalorieStep)
+	case rate < -MaintainTolerance:
+		return CalorieStep, fmt.Sprintf(
+			"Вес снижается (%+.2f%% в неделю) при цели поддержания. Рекомендуем повысить цель на %d ккал.", ratePct, CalorieStep)
+	}
+	return 0, fmt.Sprintf("Вес стабилен (%+.2f%% в неделю), цель менять не нужно.", ratePct)
+}
@@ -0,0 +1,144 @@
+package recommendations
+
+import (
+	"testing"
+
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+// sufficientInput is two weeks of well-logged data at an 80 kg body weight
+func sufficientInput(goal nutritioncalc.FitnessGoal, weeklyChange float64) Input {
+	return Input{
+		Goal:           goal,
+		TargetCalories: floatPtr(2000),
+		AverageIntake:  1980,
+		LoggedDays:     13,
+		WeighIns:       8,
+		WeighInSpan:    13,
+		CurrentWeight:  80,
+		WeeklyChange:   weeklyChange,
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      Input
+		adjustment int
+	}{
+		{"cut stalled", sufficientInput(nutritioncalc.GoalLoss, -0.1), -CalorieStep},
+		{"cut gaining", sufficientInput(nutritioncalc.GoalLoss, 0.2), -CalorieStep},
+		{"cut on pace", sufficientInput(nutritioncalc.GoalLoss, -0.5), 0},
+		{"cut too fast", sufficientInput(nutritioncalc.GoalLoss, -1.0), CalorieStep},
+		{"bulk stalled", sufficientInput(nutritioncalc.GoalGain, 0.0), CalorieStep},
+		{"bulk on pace", sufficientInput(nutritioncalc.GoalGain, 0.2), 0},
+		{"bulk too fast", sufficientInput(nutritioncalc.GoalGain, 0.6), -CalorieStep},
+		{"maintain stable", sufficientInput(nutritioncalc.GoalMaintain, 0.1), 0},
+		{"maintain drifting up", sufficientInput(nutritioncalc.GoalMaintain, 0.3), -CalorieStep},
+		{"maintain drifting down", sufficientInput(nutritioncalc.GoalMaintain, -0.3), CalorieStep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := Recommend(tt.input)
+
+			assert.True(t, rec.DataSufficiency)
+			require.NotNil(t, rec.CurrentTarget)
+			require.NotNil(t, rec.SuggestedTarget)
+			assert.Equal(t, 2000, *rec.CurrentTarget)
+			assert.Equal(t, tt.adjustment, rec.Adjustment)
+			assert.Equal(t, 2000+tt.adjustment, *rec.SuggestedTarget)
+			assert.NotEmpty(t, rec.Reason)
+		})
+	}
+}
+
+func TestRecommendInsufficientData(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(in *Input)
+	}{
+		{"no target", func(in *Input) { in.TargetCalories = nil }},
+		{"few logged days", func(in *Input) { in.LoggedDays = MinLoggedDays - 1 }},
+		{"few weigh-ins", func(in *Input) { in.WeighIns = MinWeighIns - 1 }},
+		{"weigh-ins too close together", func(in *Input) { in.WeighInSpan = MinWeighInSpanDays - 1 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := sufficientInput(nutritioncalc.GoalLoss, 0)
+			tt.modify(&in)
+
+			rec := Recommend(in)
+
+			assert.False(t, rec.DataSufficiency)
+			assert.Zero(t, rec.Adjustment)
+			assert.Equal(t, rec.CurrentTarget, rec.SuggestedTarget)
+			assert.Contains(t, rec.Reason, "Недостаточно данных")
+		})
+	}
+}
+
+func TestRecommendPoorAdherence(t *testing.T) {
+	// Stalled on a cut, but eating 15% over target: fix adherence first
+	in := sufficientInput(nutritioncalc.GoalLoss, 0)
+	in.AverageIntake = 2300
+
+	rec := Recommend(in)
+
+	assert.True(t, rec.DataSufficiency)
+	assert.Zero(t, rec.Adjustment)
+	assert.Contains(t, rec.Reason, "2300 ккал")
+}
+
+func TestRecommendBodyFat(t *testing.T) {
+	t.Run("recomposition on a cut keeps the target", func(t *testing.T) {
+		in := sufficientInput(nutritioncalc.GoalLoss, 0)
+		in.BodyFatChange = floatPtr(-0.8)
+
+		assert.Zero(t, Recommend(in).Adjustment)
+	})
+
+	t.Run("small body-fat drop does not override a stall", func(t *testing.T) {
+		in := sufficientInput(nutritioncalc.GoalLoss, 0)
+		in.BodyFatChange = floatPtr(-0.2)
+
+		assert.Equal(t, -CalorieStep, Recommend(in).Adjustment)
+	})
+
+	t.Run("fat gain on a bulk lowers the target", func(t *testing.T) {
+		in := sufficientInput(nutritioncalc.GoalGain, 0.2)
+		in.BodyFatChange = floatPtr(1.0)
+
+		assert.Equal(t, -CalorieStep, Recommend(in).Adjustment)
+	})
+}
+
+func TestRecommendCalorieFloor(t *testing.T) {
+	t.Run("cut is clamped to the floor", func(t *testing.T) {
+		in := sufficientInput(nutritioncalc.GoalLoss, 0)
+		in.TargetCalories = floatPtr(1300)
+		in.AverageIntake = 1290
+
+		rec := Recommend(in)
+
+		assert.Equal(t, MinCalories, *rec.SuggestedTarget)
+		assert.Equal(t, -100, rec.Adjustment)
+	})
+
+	t.Run("target below the floor is not raised by a cut", func(t *testing.T) {
+		in := sufficientInput(nutritioncalc.GoalLoss, 0)
+		in.TargetCalories = floatPtr(1100)
+		in.AverageIntake = 1100
+
+		rec := Recommend(in)
+
+		assert.Equal(t, 1100, *rec.SuggestedTarget)
+		assert.Zero(t, rec.Adjustment)
+		assert.Contains(t, rec.Reason, "минимуме")
+	})
+}
@@ -0,0 +1,68 @@
+package recommendations
+
+import nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+
+// WindowDays is how much history a recommendation is based on
+const WindowDays = 14
+
+// Data sufficiency thresholds
+const (
+	// MinLoggedDays is the number of days with food entries in the window
+	MinLoggedDays = 10
+	// MinWeighIns is the number of weight entries in the window
+	MinWeighIns = 4
+	// MinWeighInSpanDays is the minimum distance between the first and last weigh-in
+	MinWeighInSpanDays = 10
+)
+
+// Decision thresholds. Weight rates are weekly changes relative to body weight.
+const (
+	// AdherenceTolerance is how far average intake may stray from the target
+	// before the target itself is judged (10%)
+	AdherenceTolerance = 0.10
+	// LossStallRate: losing slower than 0.25%/week on a cut counts as a stall
+	LossStallRate = -0.0025
+	// LossMaxRate: losing faster than 1%/week risks muscle loss
+	LossMaxRate = -0.01
+	// MaintainTolerance: drifting more than 0.25%/week either way on maintenance
+	MaintainTolerance = 0.0025
+	// GainMinRate: gaining slower than 0.1%/week on a bulk counts as a stall
+	GainMinRate = 0.001
+	// GainMaxRate: gaining faster than 0.5%/week is mostly fat
+	GainMaxRate = 0.005
+	// BodyFatChangeThreshold is a meaningful body-fat change between two
+	// estimates, in percentage points
+	BodyFatChangeThreshold = 0.5
+	// CalorieStep is the size of a single adjustment (kcal)
+	CalorieStep = 150
+	// MinCalories is the lowest target ever suggested (kcal)
+	MinCalories = 1200
+)
+
+// Input is everything the rules engine looks at. The handler assembles it
+// from nutrition history, the weight trend and body-fat estimates.
+// ExcludedDays are flagged days of the window whose intake is not counted.
+type Input struct {
+	Goal           nutritioncalc.FitnessGoal `json:"goal"`
+	TargetCalories *float64                  `json:"-"`
+	AverageIntake  float64                   `json:"average_intake"`
+	LoggedDays     int                       `json:"logged_days"`
+	ExcludedDays   int                       `json:"excluded_days"`
+	WeighIns       int                       `json:"weigh_ins"`
+	WeighInSpan    int                       `json:"weigh_in_span_days"`
+	CurrentWeight  float64                   `json:"current_weight"`
+	WeeklyChange   float64                   `json:"weekly_weight_change"`
+	BodyFatChange  *float64                  `json:"body_fat_change"`
+}
+
+// Recommendation is the response of GET /api/v1/recommendations/nutrition.
+// When DataSufficiency is false the suggested target equals the current one.
+type Recommendation struct {
+	CurrentTarget   *int   `json:"current_target"`
+	SuggestedTarget *int   `json:"suggested_target"`
+	Adjustment      int    `json:"adjustment"`
+	Reason          string `json:"reason"`
+	DataSufficiency bool   `json:"data_sufficiency"`
+	WindowDays      int    `json:"window_days"`
+	Inputs          Input  `json:"inputs"`
+}
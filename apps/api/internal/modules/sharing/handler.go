@@ -0,0 +1,126 @@
+package sharing
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles share link requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new sharing handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateShare handles POST /api/v1/share. The response carries the token
+// and the public URL; neither can be fetched again.
+func (h *Handler) CreateShare(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateShareRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	link, err := h.service.CreateShare(c.Request.Context(), userID, &req)
+	if errors.Is(err, ErrTooManyLinks) {
+		response.Conflict(c, "Слишком много активных ссылок, отзовите ненужные", "")
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to create share link", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось создать ссылку")
+		return
+	}
+
+	response.Success(c, http.StatusCreated, link)
+}
+
+// ListShares handles GET /api/v1/share
+func (h *Handler) ListShares(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.service.ListShares(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Error("Failed to list share links", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось получить ссылки")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"shares": links})
+}
+
+// RevokeShare handles DELETE /api/v1/share/:id
+func (h *Handler) RevokeShare(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	linkID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	err := h.service.RevokeShare(c.Request.Context(), userID, linkID)
+	if errors.Is(err, apperrors.ErrNotFound) {
+		response.NotFound(c, "Ссылка не найдена")
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to revoke share link", "error", err, "user_id", userID)
+		response.InternalError(c, "Не удалось отозвать ссылку")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Ссылка отозвана", nil)
+}
+
+// GetPublic handles GET /api/v1/public/share/:token without authentication.
+// Unknown, expired and revoked tokens all answer 404.
+func (h *Handler) GetPublic(c *gin.Context) {
+	share, err := h.service.GetPublicShare(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, share)
+}
@@ -0,0 +1,127 @@
+package sharing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err error
+}
+
+func (m *mockService) CreateShare(ctx context.Context, userID int64, req *CreateShareRequest) (*ShareLink, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &ShareLink{ID: testLinkID, Scope: req.Scope, Token: "token", URL: "https://app.test/share/token"}, nil
+}
+
+func (m *mockService) ListShares(ctx context.Context, userID int64) ([]ShareLink, error) {
+	return []ShareLink{{ID: testLinkID, Scope: ScopeWeightTrend}}, m.err
+}
+
+func (m *mockService) RevokeShare(ctx context.Context, userID int64, linkID string) error {
+	return m.err
+}
+
+func (m *mockService) GetPublicShare(ctx context.Context, token string) (*PublicShare, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &PublicShare{Scope: ScopeWeightTrend}, nil
+}
+
+func newTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Set("user_id", testUserID)
+	return c, w
+}
+
+func TestHandlerCreateShare(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", `{"scope":"weight_trend","expires_in_days":30}`, nil, http.StatusCreated},
+		{"unknown scope", `{"scope":"everything"}`, nil, http.StatusBadRequest},
+		{"expiry too long", `{"scope":"monthly_summary","expires_in_days":1000}`, nil, http.StatusBadRequest},
+		{"too many links", `{"scope":"weight_trend"}`, ErrTooManyLinks, http.StatusConflict},
+		{"internal", `{"scope":"weight_trend"}`, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&config.Config{}, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/api/v1/share", tt.body)
+
+			handler.CreateShare(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerRevokeShare(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		err  error
+		code int
+	}{
+		{"revoked", testLinkID, nil, http.StatusOK},
+		{"invalid id", "not-a-uuid", nil, http.StatusBadRequest},
+		{"not found", testLinkID, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(&config.Config{}, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodDelete, "/api/v1/share/"+tt.id, "")
+			c.Params = gin.Params{{Key: "id", Value: tt.id}}
+
+			handler.RevokeShare(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerGetPublic(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"found", nil, http.StatusOK},
+		{"expired or revoked", apperrors.ErrNotFound, http.StatusNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			handler := NewHandler(&config.Config{}, logger.New(), &mockService{err: tt.err})
+			router := gin.New()
+			router.Use(middleware.ErrorHandler(logger.New()))
+			router.GET("/api/v1/public/share/:token", handler.GetPublic)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/public/share/abc", nil))
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package sharing
+
+import "math"
+
+// PublicShare is the payload of a public share page. It is built field by
+// field from the owner's progress, so nothing identifying the owner (email,
+// name, ids) and none of their notes can reach it.
+type PublicShare struct {
+	Scope          string                `json:"scope"`
+	From           string                `json:"from"`
+	To             string                `json:"to"`
+	WeightTrend    *PublicWeightTrend    `json:"weight_trend,omitempty"`
+	MonthlySummary *PublicMonthlySummary `json:"monthly_summary,omitempty"`
+}
+
+// PublicWeightTrend is the weigh-ins of the range, oldest first. Change is
+// null with fewer than two weigh-ins.
+type PublicWeightTrend struct {
+	Points []PublicWeighIn `json:"points"`
+	Change *float64        `json:"change"`
+}
+
+// PublicWeighIn is a single recorded weight in kg
+type PublicWeighIn struct {
+	Date   string  `json:"date"`
+	Weight float64 `json:"weight"`
+}
+
+// PublicMonthlySummary is the nutrition of the logged days of the range and
+// the weight change over it. Averages are null when no day was logged.
+type PublicMonthlySummary struct {
+	LoggedDays   int      `json:"logged_days"`
+	FlaggedDays  int      `json:"flagged_days"`
+	AvgCalories  *float64 `json:"avg_calories"`
+	AvgProtein   *float64 `json:"avg_protein"`
+	AvgFat       *float64 `json:"avg_fat"`
+	AvgCarbs     *float64 `json:"avg_carbs"`
+	WeightChange *float64 `json:"weight_change"`
+}
+
+// publicShare shapes the progress of a share for strangers
+func publicShare(p *progress) *PublicShare {
+	share := &PublicShare{
+		Scope: p.Scope,
+		From:  p.From.Format("2006-01-02"),
+		To:    p.To.Format("2006-01-02"),
+	}
+
+	switch p.Scope {
+	case ScopeWeightTrend:
+		trend := &PublicWeightTrend{Points: make([]PublicWeighIn, len(p.WeighIns)), Change: weightChange(p.WeighIns)}
+		for i, w := range p.WeighIns {
+			trend.Points[i] = PublicWeighIn{Date: w.Date.Format("2006-01-02"), Weight: w.Weight}
+		}
+		share.WeightTrend = trend
+	case ScopeMonthlySummary:
+		summary := &PublicMonthlySummary{
+			LoggedDays:   len(p.Days),
+			FlaggedDays:  len(p.Flags),
+			WeightChange: weightChange(p.WeighIns),
+		}
+		if n := float64(len(p.Days)); n > 0 {
+			var calories, protein, fat, carbs float64
+			for _, d := range p.Days {
+				calories += d.Calories
+				protein += d.Protein
+				fat += d.Fat
+				carbs += d.Carbs
+			}
+			summary.AvgCalories = round1Ptr(calories / n)
+			summary.AvgProtein = round1Ptr(protein / n)
+			summary.AvgFat = round1Ptr(fat / n)
+			summary.AvgCarbs = round1Ptr(carbs / n)
+		}
+		share.MonthlySummary = summary
+	}
+	return share
+}
+
+// weightChange is the last weigh-in less the first, nil with fewer than two
+func weightChange(weighIns []weighIn) *float64 {
+	if len(weighIns) < 2 {
+		return nil
+	}
+	return round1Ptr(weighIns[len(weighIns)-1].Weight - weighIns[0].Weight)
+}
+
+func round1Ptr(v float64) *float64 {
+	r := math.Round(v*10) / 10
+	return &r
+}
@@ -0,0 +1,102 @@
+package sharing
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(d int) time.Time {
+	return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+}
+
+func testProgress(scope string) *progress {
+	return &progress{
+		UserID: testUserID,
+		Scope:  scope,
+		From:   day(1),
+		To:     day(16),
+		WeighIns: []weighIn{
+			{Date: day(2), Weight: 82.4},
+			{Date: day(9), Weight: 81.9},
+			{Date: day(15), Weight: 81.1},
+		},
+		Days: []dayTotals{
+			{Date: day(2), Calories: 2100, Protein: 120, Fat: 70, Carbs: 230},
+			{Date: day(3), Calories: 1900, Protein: 110, Fat: 60, Carbs: 210},
+		},
+		Flags: []dayFlag{{Date: "2026-10-04", Type: "sick", Note: "секретная заметка"}},
+	}
+}
+
+// forbiddenKeys must never appear anywhere in a public payload
+var forbiddenKeys = []string{"email", "name", "note", "notes", "type", "user_id", "userId", "id", "token", "token_hash"}
+
+// collectKeys returns every object key of a decoded JSON value
+func collectKeys(v any, keys map[string]bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			keys[k] = true
+			collectKeys(child, keys)
+		}
+	case []any:
+		for _, child := range v {
+			collectKeys(child, keys)
+		}
+	}
+}
+
+func TestPublicShare_StripsPrivateData(t *testing.T) {
+	for _, scope := range []string{ScopeWeightTrend, ScopeMonthlySummary} {
+		t.Run(scope, func(t *testing.T) {
+			data, err := json.Marshal(publicShare(testProgress(scope)))
+			require.NoError(t, err)
+
+			var decoded any
+			require.NoError(t, json.Unmarshal(data, &decoded))
+			keys := map[string]bool{}
+			collectKeys(decoded, keys)
+			for _, key := range forbiddenKeys {
+				assert.False(t, keys[key], "public payload has %q", key)
+			}
+			assert.NotContains(t, string(data), "секретная заметка")
+			assert.NotContains(t, string(data), "sick")
+		})
+	}
+}
+
+func TestPublicShare_WeightTrend(t *testing.T) {
+	share := publicShare(testProgress(ScopeWeightTrend))
+
+	assert.Equal(t, "2026-10-01", share.From)
+	assert.Equal(t, "2026-10-16", share.To)
+	assert.Nil(t, share.MonthlySummary, "a weight trend share shows no nutrition")
+	require.NotNil(t, share.WeightTrend)
+	assert.Equal(t, PublicWeighIn{Date: "2026-10-02", Weight: 82.4}, share.WeightTrend.Points[0])
+	assert.Len(t, share.WeightTrend.Points, 3)
+	assert.InDelta(t, -1.3, *share.WeightTrend.Change, 1e-9)
+}
+
+func TestPublicShare_MonthlySummary(t *testing.T) {
+	share := publicShare(testProgress(ScopeMonthlySummary))
+
+	assert.Nil(t, share.WeightTrend)
+	summary := share.MonthlySummary
+	require.NotNil(t, summary)
+	assert.Equal(t, 2, summary.LoggedDays)
+	assert.Equal(t, 1, summary.FlaggedDays)
+	assert.Equal(t, 2000.0, *summary.AvgCalories)
+	assert.Equal(t, 115.0, *summary.AvgProtein)
+	assert.Equal(t, 65.0, *summary.AvgFat)
+	assert.Equal(t, 220.0, *summary.AvgCarbs)
+	assert.InDelta(t, -1.3, *summary.WeightChange, 1e-9)
+
+	empty := publicShare(&progress{Scope: ScopeMonthlySummary, From: day(1), To: day(16)}).MonthlySummary
+	assert.Zero(t, empty.LoggedDays)
+	assert.Nil(t, empty.AvgCalories)
+	assert.Nil(t, empty.WeightChange)
+}
@@ -0,0 +1,280 @@
+package sharing
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// ErrTooManyLinks is returned when a user already has MaxActiveLinks
+// active share links
+var ErrTooManyLinks = errors.New("too many active share links")
+
+// ServiceInterface defines the share link operations the handler uses
+type ServiceInterface interface {
+	CreateShare(ctx context.Context, userID int64, req *CreateShareRequest) (*ShareLink, error)
+	ListShares(ctx context.Context, userID int64) ([]ShareLink, error)
+	RevokeShare(ctx context.Context, userID int64, linkID string) error
+	GetPublicShare(ctx context.Context, token string) (*PublicShare, error)
+}
+
+// Service manages share links and builds the public pages behind them
+type Service struct {
+	db     *database.DB
+	log    *logger.Logger
+	cfg    *config.Config
+	tokens *auth.TokenGenerator
+	now    func() time.Time
+}
+
+// NewService creates a new sharing service. Share URLs are built from
+// cfg.ShareURL.
+func NewService(db *database.DB, log *logger.Logger, cfg *config.Config) *Service {
+	return &Service{db: db, log: log, cfg: cfg, tokens: auth.NewTokenGenerator(), now: time.Now}
+}
+
+// activeLink is the condition selecting links that still work at $2
+const activeLink = `revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)`
+
+// CreateShare creates a share link. The returned token is the only time the
+// plain token is available; it is stored hashed.
+func (s *Service) CreateShare(ctx context.Context, userID int64, req *CreateShareRequest) (*ShareLink, error) {
+	now := s.now()
+
+	var active int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM share_links WHERE user_id = $1 AND `+activeLink, userID, now,
+	).Scan(&active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count share links: %w", err)
+	}
+	if active >= MaxActiveLinks {
+		return nil, ErrTooManyLinks
+	}
+
+	plain, hashed, err := s.tokens.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := &ShareLink{Scope: req.Scope, Token: plain, URL: s.cfg.ShareURL + "/" + url.PathEscape(plain)}
+	if req.ExpiresInDays != nil {
+		expiresAt := now.AddDate(0, 0, *req.ExpiresInDays)
+		link.ExpiresAt = &expiresAt
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO share_links (user_id, token_hash, scope, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`
+
+	err = s.db.QueryRowContext(ctx, query, userID, hashed, link.Scope, link.ExpiresAt).Scan(&link.ID, &link.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	s.log.LogSecurityEvent("share_link_created", "info", map[string]interface{}{
+		"user_id": userID,
+		"link_id": link.ID,
+		"scope":   link.Scope,
+	})
+
+	return link, nil
+}
+
+// ListShares returns the user's active share links, newest first
+func (s *Service) ListShares(ctx context.Context, userID int64) ([]ShareLink, error) {
+	startTime := time.Now()
+	query := `
+		SELECT id, scope, expires_at, created_at
+		FROM share_links
+		WHERE user_id = $1 AND ` + activeLink + `
+		ORDER BY created_at DESC, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, s.now())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]ShareLink, 0)
+	for rows.Next() {
+		var link ShareLink
+		if err := rows.Scan(&link.ID, &link.Scope, &link.ExpiresAt, &link.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating share links: %w", err)
+	}
+	return links, nil
+}
+
+// RevokeShare stops one of the user's share links from working. A link
+// that is missing, another user's or already revoked is apperrors.ErrNotFound.
+func (s *Service) RevokeShare(ctx context.Context, userID int64, linkID string) error {
+	startTime := time.Now()
+	query := `UPDATE share_links SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := s.db.ExecContext(ctx, query, linkID, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"link_id": linkID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+	if n == 0 {
+		return apperrors.ErrNotFound
+	}
+
+	s.log.LogSecurityEvent("share_link_revoked", "info", map[string]interface{}{
+		"user_id": userID,
+		"link_id": linkID,
+	})
+	return nil
+}
+
+// GetPublicShare returns the public page of a share token. Unknown,
+// expired and revoked tokens are all apperrors.ErrNotFound.
+func (s *Service) GetPublicShare(ctx context.Context, token string) (*PublicShare, error) {
+	if _, err := hex.DecodeString(token); err != nil || len(token) != 2*auth.DefaultTokenBytes {
+		return nil, apperrors.ErrNotFound
+	}
+
+	now := s.now()
+	p := &progress{To: now.UTC().Truncate(24 * time.Hour)}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT user_id, scope FROM share_links WHERE token_hash = $1 AND `+activeLink,
+		s.tokens.HashToken(token), now,
+	).Scan(&p.UserID, &p.Scope)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+
+	switch p.Scope {
+	case ScopeWeightTrend:
+		p.From = p.To.AddDate(0, 0, -(WeightTrendDays - 1))
+		if p.WeighIns, err = s.loadWeighIns(ctx, p); err != nil {
+			return nil, err
+		}
+	case ScopeMonthlySummary:
+		p.From = p.To.AddDate(0, 0, -(MonthlySummaryDays - 1))
+		if p.WeighIns, err = s.loadWeighIns(ctx, p); err != nil {
+			return nil, err
+		}
+		if p.Days, err = s.loadDays(ctx, p); err != nil {
+			return nil, err
+		}
+		if p.Flags, err = s.loadFlags(ctx, p); err != nil {
+			return nil, err
+		}
+	}
+
+	return publicShare(p), nil
+}
+
+// loadWeighIns returns the recorded weights of the range, oldest first
+func (s *Service) loadWeighIns(ctx context.Context, p *progress) ([]weighIn, error) {
+	query := `
+		SELECT date, weight FROM daily_metrics
+		WHERE user_id = $1 AND weight IS NOT NULL AND date >= $2 AND date <= $3
+		ORDER BY date`
+
+	rows, err := s.db.QueryContext(ctx, query, p.UserID, p.From, p.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weigh-ins: %w", err)
+	}
+	defer rows.Close()
+
+	var weighIns []weighIn
+	for rows.Next() {
+		var w weighIn
+		if err := rows.Scan(&w.Date, &w.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan weigh-in: %w", err)
+		}
+		weighIns = append(weighIns, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weigh-ins: %w", err)
+	}
+	return weighIns, nil
+}
+
+// loadDays returns per-day nutrition totals for days with at least one food entry
+func (s *Service) loadDays(ctx context.Context, p *progress) ([]dayTotals, error) {
+	query := `
+		SELECT date, COALESCE(SUM(calories), 0), COALESCE(SUM(protein), 0),
+		       COALESCE(SUM(fat), 0), COALESCE(SUM(carbs), 0)
+		FROM food_entries
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		GROUP BY date
+		ORDER BY date`
+
+	rows, err := s.db.QueryContext(ctx, query, p.UserID, p.From, p.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food totals: %w", err)
+	}
+	defer rows.Close()
+
+	var days []dayTotals
+	for rows.Next() {
+		var d dayTotals
+		if err := rows.Scan(&d.Date, &d.Calories, &d.Protein, &d.Fat, &d.Carbs); err != nil {
+			return nil, fmt.Errorf("failed to scan food totals: %w", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating food totals: %w", err)
+	}
+	return days, nil
+}
+
+// loadFlags returns the flagged days of the range
+func (s *Service) loadFlags(ctx context.Context, p *progress) ([]dayFlag, error) {
+	query := `
+		SELECT date::text, type, note FROM nutrition_day_flags
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		ORDER BY date`
+
+	rows, err := s.db.QueryContext(ctx, query, p.UserID, p.From, p.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query day flags: %w", err)
+	}
+	defer rows.Close()
+
+	var flags []dayFlag
+	for rows.Next() {
+		var f dayFlag
+		if err := rows.Scan(&f.Date, &f.Type, &f.Note); err != nil {
+			return nil, fmt.Errorf("failed to scan day flag: %w", err)
+		}
+		flags = append(flags, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating day flags: %w", err)
+	}
+	return flags, nil
+}
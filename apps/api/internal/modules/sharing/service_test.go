@@ -0,0 +1,120 @@
+package sharing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testUserID int64 = 42
+	testLinkID       = "5f0c6d3e-8a51-4a8e-9d3b-2f6a1c7e9b40"
+)
+
+var testNow = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func setupService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	service := NewService(&database.DB{DB: db}, logger.New(), &config.Config{ShareURL: "https://app.test/share"})
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func TestService_CreateShare(t *testing.T) {
+	t.Run("stores the token hashed", func(t *testing.T) {
+		service, mock := setupService(t)
+		days := 7
+		mock.ExpectQuery("SELECT COUNT").WithArgs(testUserID, testNow).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectQuery("INSERT INTO share_links").
+			WithArgs(testUserID, sqlmock.AnyArg(), ScopeWeightTrend, testNow.AddDate(0, 0, 7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(testLinkID, testNow))
+
+		link, err := service.CreateShare(context.Background(), testUserID, &CreateShareRequest{Scope: ScopeWeightTrend, ExpiresInDays: &days})
+
+		require.NoError(t, err)
+		assert.Equal(t, testLinkID, link.ID)
+		assert.Len(t, link.Token, 64)
+		assert.Equal(t, "https://app.test/share/"+link.Token, link.URL)
+		assert.Equal(t, testNow.AddDate(0, 0, 7), *link.ExpiresAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("too many active links", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("SELECT COUNT").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(MaxActiveLinks))
+
+		_, err := service.CreateShare(context.Background(), testUserID, &CreateShareRequest{Scope: ScopeMonthlySummary})
+
+		assert.ErrorIs(t, err, ErrTooManyLinks)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_RevokeShare(t *testing.T) {
+	service, mock := setupService(t)
+	mock.ExpectExec("UPDATE share_links SET revoked_at").
+		WithArgs(testLinkID, testUserID).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE share_links SET revoked_at").
+		WithArgs(testLinkID, testUserID).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, service.RevokeShare(context.Background(), testUserID, testLinkID))
+	assert.ErrorIs(t, service.RevokeShare(context.Background(), testUserID, testLinkID), apperrors.ErrNotFound,
+		"a revoked link cannot be revoked again")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GetPublicShare(t *testing.T) {
+	token := "a1b2c3d4e5f60718293a4b5c6d7e8f90a1b2c3d4e5f60718293a4b5c6d7e8f90"
+
+	t.Run("weight trend", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM share_links").
+			WithArgs(service.tokens.HashToken(token), testNow).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "scope"}).AddRow(testUserID, ScopeWeightTrend))
+		mock.ExpectQuery("FROM daily_metrics").
+			WithArgs(testUserID, day(16).AddDate(0, 0, -(WeightTrendDays-1)), day(16)).
+			WillReturnRows(sqlmock.NewRows([]string{"date", "weight"}).AddRow(day(2), 82.4).AddRow(day(15), 81.1))
+
+		share, err := service.GetPublicShare(context.Background(), token)
+
+		require.NoError(t, err)
+		assert.Equal(t, ScopeWeightTrend, share.Scope)
+		assert.Len(t, share.WeightTrend.Points, 2)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("expired or revoked", func(t *testing.T) {
+		service, mock := setupService(t)
+		mock.ExpectQuery("FROM share_links").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "scope"}))
+
+		_, err := service.GetPublicShare(context.Background(), token)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		service, mock := setupService(t)
+
+		for _, bad := range []string{"", "short", token[:62] + "zz"} {
+			_, err := service.GetPublicShare(context.Background(), bad)
+			assert.ErrorIs(t, err, apperrors.ErrNotFound, bad)
+		}
+		assert.NoError(t, mock.ExpectationsWereMet(), "malformed tokens are rejected without a query")
+	})
+}
@@ -0,0 +1,69 @@
+package sharing
+
+import "time"
+
+// Scopes of a share link: the slice of progress it shows
+const (
+	ScopeWeightTrend    = "weight_trend"
+	ScopeMonthlySummary = "monthly_summary"
+)
+
+const (
+	// WeightTrendDays is how far back a weight trend share reaches
+	WeightTrendDays = 90
+	// MonthlySummaryDays is the length of a monthly summary share
+	MonthlySummaryDays = 30
+	// MaxActiveLinks is the number of active share links a user may have
+	MaxActiveLinks = 20
+)
+
+// CreateShareRequest represents a share link to create. Without
+// ExpiresInDays the link works until it is revoked.
+type CreateShareRequest struct {
+	Scope         string `json:"scope" binding:"required,oneof=weight_trend monthly_summary"`
+	ExpiresInDays *int   `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// ShareLink is a share link as listed to its owner. Token and URL are only
+// set in the response to its creation; afterwards only the hash is known.
+type ShareLink struct {
+	ID        string     `json:"id"`
+	Scope     string     `json:"scope"`
+	Token     string     `json:"token,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// progress is what a share is built from, as loaded for its owner. It is
+// never sent as is: publicShare picks the fields strangers may see.
+type progress struct {
+	UserID   int64
+	Scope    string
+	From, To time.Time
+	WeighIns []weighIn
+	Days     []dayTotals
+	Flags    []dayFlag
+}
+
+type weighIn struct {
+	Date   time.Time
+	Weight float64
+}
+
+// dayTotals is one day of logged nutrition
+type dayTotals struct {
+	Date     time.Time
+	Calories float64
+	Protein  float64
+	Fat      float64
+	Carbs    float64
+}
+
+// dayFlag is a flagged day. Its type and note are the owner's health
+// notes and stay private.
+type dayFlag struct {
+	Date string
+	Type string
+	Note string
+}
@@ -0,0 +1,60 @@
+package status
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the public status page data
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+
+	// mu guards the cached payload; holding it while rebuilding keeps a burst
+	// of requests from running the probes more than once per CacheTTL
+	mu       sync.Mutex
+	cached   *PublicStatus
+	cachedAt time.Time
+	now      func() time.Time
+}
+
+// NewHandler creates a new status handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+		now:     time.Now,
+	}
+}
+
+// GetStatus handles GET /api/v1/public/status
+func (h *Handler) GetStatus(c *gin.Context) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached == nil || h.now().Sub(h.cachedAt) >= CacheTTL {
+		status, err := h.service.GetStatus(c.Request.Context())
+		if err != nil {
+			h.log.Error("Failed to build public status", "error", err)
+			response.InternalError(c, "Не удалось получить статус сервиса")
+			return
+		}
+		h.cached = status
+		h.cachedAt = h.now()
+	}
+
+	// Overrides the global no-store header: this payload is public and safe to cache
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(CacheTTL.Seconds())))
+	c.Header("Pragma", "")
+	c.Header("Expires", "")
+	response.Success(c, http.StatusOK, h.cached)
+}
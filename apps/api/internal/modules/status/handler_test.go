@@ -0,0 +1,76 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStatusService struct {
+	calls int
+	err   error
+}
+
+func (m *mockStatusService) GetStatus(ctx context.Context) (*PublicStatus, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &PublicStatus{
+		Status:     HealthOperational,
+		Components: []ComponentStatus{{Name: ComponentAPI, Status: HealthOperational}},
+		Uptime:     []DayUptime{},
+	}, nil
+}
+
+func setupHandler(service ServiceInterface) (*Handler, *gin.Engine) {
+	gin.SetMode(gin.TestMode)
+	handler := NewHandler(&config.Config{}, logger.New(), service)
+	router := gin.New()
+	router.GET("/public/status", handler.GetStatus)
+	return handler, router
+}
+
+func get(router *gin.Engine) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/public/status", nil))
+	return w
+}
+
+func TestGetStatus_CachesForTTL(t *testing.T) {
+	service := &mockStatusService{}
+	handler, router := setupHandler(service)
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	handler.now = func() time.Time { return now }
+
+	w := get(router)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=30", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), `"status":"operational"`)
+
+	now = now.Add(CacheTTL - time.Second)
+	get(router)
+	assert.Equal(t, 1, service.calls)
+
+	now = now.Add(time.Second)
+	get(router)
+	assert.Equal(t, 2, service.calls)
+}
+
+func TestGetStatus_Error(t *testing.T) {
+	_, router := setupHandler(&mockStatusService{err: errors.New("db gone")})
+
+	w := get(router)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, w.Body.String(), "db gone")
+}
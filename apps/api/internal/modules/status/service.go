@@ -0,0 +1,212 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// componentOrder is the display order of components on the status page
+var componentOrder = []string{ComponentAPI, ComponentDatabase, ComponentEmail, ComponentStorage}
+
+// ServiceInterface defines the interface for status service operations
+type ServiceInterface interface {
+	GetStatus(ctx context.Context) (*PublicStatus, error)
+}
+
+// Service computes public component health and uptime history
+type Service struct {
+	db     *database.DB
+	log    *logger.Logger
+	probes Probes
+}
+
+// NewService creates a new status service. Components without a probe
+// (e.g. storage that is not configured) are left off the status page.
+func NewService(db *database.DB, log *logger.Logger, probes Probes) *Service {
+	return &Service{
+		db:     db,
+		log:    log,
+		probes: probes,
+	}
+}
+
+// CheckComponents runs every probe and returns the coarse health of each component
+func (s *Service) CheckComponents(ctx context.Context) []ComponentStatus {
+	results := make(map[string]Health, len(s.probes))
+	for name, probe := range s.probes {
+		results[name] = s.runProbe(ctx, name, probe)
+	}
+
+	// The API answers this request, so it is up; without a database it can do little
+	results[ComponentAPI] = HealthOperational
+	if results[ComponentDatabase] == HealthDown {
+		results[ComponentAPI] = HealthDegraded
+	}
+
+	components := make([]ComponentStatus, 0, len(results))
+	for _, name := range componentOrder {
+		if health, ok := results[name]; ok {
+			components = append(components, ComponentStatus{Name: name, Status: health})
+		}
+	}
+	return components
+}
+
+// runProbe classifies a single check; details are only logged, never exposed
+func (s *Service) runProbe(ctx context.Context, name string, probe Probe) Health {
+	probeCtx, cancel := context.WithTimeout(ctx, ProbeTimeout)
+	defer cancel()
+
+	startTime := time.Now()
+	err := probe(probeCtx)
+	elapsed := time.Since(startTime)
+
+	if err != nil {
+		s.log.Warn("Status probe failed", "component", name, "error", err)
+		return HealthDown
+	}
+	if elapsed > DegradedAfter {
+		s.log.Warn("Status probe slow", "component", name, "duration_ms", elapsed.Milliseconds())
+		return HealthDegraded
+	}
+	return HealthOperational
+}
+
+// overallHealth is the worst health among components
+func overallHealth(components []ComponentStatus) Health {
+	overall := HealthOperational
+	for _, c := range components {
+		switch c.Status {
+		case HealthDown:
+			return HealthDown
+		case HealthDegraded:
+			overall = HealthDegraded
+		}
+	}
+	return overall
+}
+
+// GetStatus returns current component health together with daily uptime history
+func (s *Service) GetStatus(ctx context.Context) (*PublicStatus, error) {
+	components := s.CheckComponents(ctx)
+
+	uptime, err := s.DailyUptime(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &PublicStatus{
+		Status:     overallHealth(components),
+		Components: components,
+		Uptime:     uptime,
+		UpdatedAt:  time.Now().UTC(),
+	}, nil
+}
+
+// RecordSample probes every component once and stores the results, pruning
+// samples that have fallen out of the history window
+func (s *Service) RecordSample(ctx context.Context) error {
+	components := s.CheckComponents(ctx)
+
+	placeholders := make([]string, 0, len(components))
+	args := make([]interface{}, 0, len(components)*2)
+	for i, c := range components {
+		placeholders = append(placeholders, fmt.Sprintf("($%d, $%d)", i*2+1, i*2+2))
+		args = append(args, c.Name, string(c.Status))
+	}
+
+	startTime := time.Now()
+	query := `INSERT INTO uptime_samples (component, status) VALUES ` + strings.Join(placeholders, ", ")
+	_, err := s.db.ExecContext(ctx, query, args...)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"components": len(components),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record uptime sample: %w", err)
+	}
+
+	startTime = time.Now()
+	query = `DELETE FROM uptime_samples WHERE sampled_at < $1`
+	_, err = s.db.ExecContext(ctx, query, time.Now().AddDate(0, 0, -HistoryDays-1))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return fmt.Errorf("failed to prune uptime samples: %w", err)
+	}
+
+	return nil
+}
+
+// DailyUptime returns uptime for the HistoryDays UTC days ending with now's day
+func (s *Service) DailyUptime(ctx context.Context, now time.Time) ([]DayUptime, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	since := today.AddDate(0, 0, -(HistoryDays - 1))
+
+	startTime := time.Now()
+	query := `
+		SELECT date_trunc('day', sampled_at AT TIME ZONE 'UTC') AS day, component,
+		       COUNT(*) AS total,
+		       COUNT(*) FILTER (WHERE status <> 'down') AS up
+		FROM uptime_samples
+		WHERE sampled_at >= $1
+		GROUP BY day, component
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, since)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uptime samples: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []sampleCount
+	for rows.Next() {
+		var c sampleCount
+		if err := rows.Scan(&c.day, &c.component, &c.total, &c.up); err != nil {
+			return nil, fmt.Errorf("failed to scan uptime samples: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating uptime samples: %w", err)
+	}
+
+	return aggregateDailyUptime(counts, today, HistoryDays), nil
+}
+
+// aggregateDailyUptime turns per-day, per-component sample counts into one
+// uptime percentage per day, oldest first. A day's uptime is that of its
+// least available component; degraded samples count as up. Days without
+// samples have a nil percentage rather than being reported as 0% or 100%.
+func aggregateDailyUptime(counts []sampleCount, today time.Time, days int) []DayUptime {
+	today = today.UTC().Truncate(24 * time.Hour)
+
+	byDay := make(map[string]float64)
+	for _, c := range counts {
+		if c.total <= 0 {
+			continue
+		}
+		key := c.day.UTC().Format("2006-01-02")
+		pct := float64(c.up) / float64(c.total) * 100
+		if current, ok := byDay[key]; !ok || pct < current {
+			byDay[key] = pct
+		}
+	}
+
+	result := make([]DayUptime, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		date := today.AddDate(0, 0, -i).Format("2006-01-02")
+		day := DayUptime{Date: date}
+		if pct, ok := byDay[date]; ok {
+			rounded := math.Round(pct*100) / 100
+			day.UptimePercent = &rounded
+		}
+		result = append(result, day)
+	}
+	return result
+}
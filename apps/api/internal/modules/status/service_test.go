@@ -0,0 +1,183 @@
+package status
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTestService(t *testing.T, probes Probes) (*Service, sqlmock.Sqlmock, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), probes)
+
+	return service, mock, func() { mockDB.Close() }
+}
+
+func okProbe(ctx context.Context) error { return nil }
+
+func failingProbe(ctx context.Context) error {
+	return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+}
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestAggregateDailyUptime(t *testing.T) {
+	today := time.Date(2026, 10, 16, 15, 30, 0, 0, time.UTC)
+
+	t.Run("covers every day oldest first, nil when no samples", func(t *testing.T) {
+		result := aggregateDailyUptime(nil, today, 3)
+
+		require.Len(t, result, 3)
+		assert.Equal(t, "2026-10-14", result[0].Date)
+		assert.Equal(t, "2026-10-16", result[2].Date)
+		for _, d := range result {
+			assert.Nil(t, d.UptimePercent)
+		}
+	})
+
+	t.Run("day uptime is that of the least available component", func(t *testing.T) {
+		counts := []sampleCount{
+			{day: day("2026-10-15"), component: ComponentAPI, total: 1440, up: 1440},
+			{day: day("2026-10-15"), component: ComponentDatabase, total: 1440, up: 1368},
+			{day: day("2026-10-15"), component: ComponentEmail, total: 1440, up: 1430},
+			{day: day("2026-10-16"), component: ComponentAPI, total: 930, up: 930},
+		}
+
+		result := aggregateDailyUptime(counts, today, 2)
+
+		require.Len(t, result, 2)
+		require.NotNil(t, result[0].UptimePercent)
+		assert.Equal(t, 95.0, *result[0].UptimePercent)
+		require.NotNil(t, result[1].UptimePercent)
+		assert.Equal(t, 100.0, *result[1].UptimePercent)
+	})
+
+	t.Run("rounds to two decimals", func(t *testing.T) {
+		counts := []sampleCount{
+			{day: day("2026-10-16"), component: ComponentDatabase, total: 3, up: 2},
+		}
+
+		result := aggregateDailyUptime(counts, today, 1)
+
+		require.NotNil(t, result[0].UptimePercent)
+		assert.Equal(t, 66.67, *result[0].UptimePercent)
+	})
+
+	t.Run("ignores days outside the window and empty counts", func(t *testing.T) {
+		counts := []sampleCount{
+			{day: day("2026-01-01"), component: ComponentAPI, total: 10, up: 0},
+			{day: day("2026-10-16"), component: ComponentStorage, total: 0, up: 0},
+		}
+
+		result := aggregateDailyUptime(counts, today, 1)
+
+		require.Len(t, result, 1)
+		assert.Nil(t, result[0].UptimePercent)
+	})
+
+	t.Run("a fully down day is 0 percent", func(t *testing.T) {
+		counts := []sampleCount{
+			{day: day("2026-10-16"), component: ComponentDatabase, total: 60, up: 0},
+		}
+
+		result := aggregateDailyUptime(counts, today, 1)
+
+		require.NotNil(t, result[0].UptimePercent)
+		assert.Equal(t, 0.0, *result[0].UptimePercent)
+	})
+}
+
+func TestCheckComponents(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("all healthy", func(t *testing.T) {
+		service, _, cleanup := setupTestService(t, Probes{
+			ComponentStorage:  okProbe,
+			ComponentDatabase: okProbe,
+			ComponentEmail:    okProbe,
+		})
+		defer cleanup()
+
+		components := service.CheckComponents(ctx)
+
+		assert.Equal(t, []ComponentStatus{
+			{Name: ComponentAPI, Status: HealthOperational},
+			{Name: ComponentDatabase, Status: HealthOperational},
+			{Name: ComponentEmail, Status: HealthOperational},
+			{Name: ComponentStorage, Status: HealthOperational},
+		}, components)
+		assert.Equal(t, HealthOperational, overallHealth(components))
+	})
+
+	t.Run("database down degrades the api and leaks no details", func(t *testing.T) {
+		service, _, cleanup := setupTestService(t, Probes{
+			ComponentDatabase: failingProbe,
+			ComponentEmail:    okProbe,
+		})
+		defer cleanup()
+
+		components := service.CheckComponents(ctx)
+
+		assert.Equal(t, []ComponentStatus{
+			{Name: ComponentAPI, Status: HealthDegraded},
+			{Name: ComponentDatabase, Status: HealthDown},
+			{Name: ComponentEmail, Status: HealthOperational},
+		}, components)
+		assert.Equal(t, HealthDown, overallHealth(components))
+	})
+}
+
+func TestRecordSample(t *testing.T) {
+	service, mock, cleanup := setupTestService(t, Probes{
+		ComponentDatabase: okProbe,
+		ComponentEmail:    failingProbe,
+	})
+	defer cleanup()
+
+	mock.ExpectExec("INSERT INTO uptime_samples").
+		WithArgs(ComponentAPI, "operational", ComponentDatabase, "operational", ComponentEmail, "down").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM uptime_samples").
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := service.RecordSample(context.Background())
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDailyUptime(t *testing.T) {
+	service, mock, cleanup := setupTestService(t, Probes{})
+	defer cleanup()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM uptime_samples").
+		WithArgs(time.Date(2026, 7, 19, 0, 0, 0, 0, time.UTC)).
+		WillReturnRows(sqlmock.NewRows([]string{"day", "component", "total", "up"}).
+			AddRow(day("2026-10-16"), ComponentAPI, 720, 720).
+			AddRow(day("2026-10-16"), ComponentDatabase, 720, 684))
+
+	uptime, err := service.DailyUptime(context.Background(), now)
+
+	require.NoError(t, err)
+	require.Len(t, uptime, HistoryDays)
+	assert.Equal(t, "2026-07-19", uptime[0].Date)
+	last := uptime[HistoryDays-1]
+	assert.Equal(t, "2026-10-16", last.Date)
+	require.NotNil(t, last.UptimePercent)
+	assert.Equal(t, 95.0, *last.UptimePercent)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,74 @@
+package status
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// ProbeInterval is how often the self-probe records uptime samples
+	ProbeInterval = time.Minute
+	// ProbeTimeout bounds a single component check
+	ProbeTimeout = 5 * time.Second
+	// DegradedAfter is the check duration above which a healthy component is reported degraded
+	DegradedAfter = 2 * time.Second
+	// HistoryDays is how many days of daily uptime the status page shows (and samples are kept)
+	HistoryDays = 90
+	// CacheTTL is how long a computed status page is served before it is rebuilt
+	CacheTTL = 30 * time.Second
+)
+
+// Health is the coarse health of a component as shown publicly
+type Health string
+
+const (
+	HealthOperational Health = "operational"
+	HealthDegraded    Health = "degraded"
+	HealthDown        Health = "down"
+)
+
+// Component names exposed on the status page
+const (
+	ComponentAPI      = "api"
+	ComponentDatabase = "database"
+	ComponentEmail    = "email"
+	ComponentStorage  = "storage"
+)
+
+// Probe checks a single dependency; any error means the component is down
+type Probe func(ctx context.Context) error
+
+// Probes maps component names to their checks. The API component is implicit:
+// it is up whenever the server answers, and degraded when the database is down.
+type Probes map[string]Probe
+
+// ComponentStatus is the public health of one component
+type ComponentStatus struct {
+	Name   string `json:"name"`
+	Status Health `json:"status"`
+}
+
+// DayUptime is the uptime of one UTC day. UptimePercent is nil when no samples
+// were recorded that day.
+type DayUptime struct {
+	Date          string   `json:"date"`
+	UptimePercent *float64 `json:"uptime_percent"`
+}
+
+// PublicStatus is the status page payload. It deliberately carries no
+// latencies, hostnames or error messages.
+type PublicStatus struct {
+	Status     Health            `json:"status"`
+	Components []ComponentStatus `json:"components"`
+	Uptime     []DayUptime       `json:"uptime"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// sampleCount is the number of samples (and non-down samples) of one
+// component on one UTC day
+type sampleCount struct {
+	day       time.Time
+	component string
+	total     int
+	up        int
+}
@@ -0,0 +1,28 @@
+package status
+
+import (
+	"context"
+	"time"
+)
+
+// RunProbe records an uptime sample every ProbeInterval until the context is
+// cancelled. Minutes in which the database itself is unreachable cannot be
+// stored and are simply absent from the history.
+func (s *Service) RunProbe(ctx context.Context) {
+	ticker := time.NewTicker(ProbeInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Status self-probe started")
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.RecordSample(ctx); err != nil {
+				s.log.Error("Failed to record uptime sample", "error", err)
+			}
+		case <-ctx.Done():
+			s.log.Info("Status self-probe stopped")
+			return
+		}
+	}
+}
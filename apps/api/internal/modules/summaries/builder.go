@@ -0,0 +1,219 @@
+package summaries
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// SummaryBuilder loads a user's week of nutrition and weight data and
+// assembles it into a WeeklySummary
+type SummaryBuilder struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewSummaryBuilder creates a new summary builder
+func NewSummaryBuilder(db *database.DB, log *logger.Logger) *SummaryBuilder {
+	return &SummaryBuilder{
+		db:  db,
+		log: log,
+	}
+}
+
+// Build returns the summary of the week starting on weekStart (a Monday)
+func (b *SummaryBuilder) Build(ctx context.Context, userID int64, weekStart time.Time) (*WeeklySummary, error) {
+	weekEnd := weekStart.AddDate(0, 0, DaysPerWeek-1)
+
+	days, err := b.loadDays(ctx, userID, weekStart, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+	weighIns, err := b.loadWeighIns(ctx, userID, weekStart, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+	target, err := b.loadCalorieTarget(ctx, userID, weekStart, weekEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	return assembleSummary(weekStart, days, weighIns, target), nil
+}
+
+// loadDays returns per-day nutrition totals for days with at least one food entry
+func (b *SummaryBuilder) loadDays(ctx context.Context, userID int64, from, to time.Time) ([]DayTotals, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT date, COALESCE(SUM(calories), 0), COALESCE(SUM(protein), 0),
+		       COALESCE(SUM(fat), 0), COALESCE(SUM(carbs), 0)
+		FROM food_entries
+		WHERE user_id = $1 AND date >= $2 AND date <= $3
+		GROUP BY date
+		ORDER BY date
+	`
+
+	rows, err := b.db.QueryContext(ctx, query, userID, from, to)
+	b.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query food totals: %w", err)
+	}
+	defer rows.Close()
+
+	var days []DayTotals
+	for rows.Next() {
+		var d DayTotals
+		if err := rows.Scan(&d.Date, &d.Calories, &d.Protein, &d.Fat, &d.Carbs); err != nil {
+			return nil, fmt.Errorf("failed to scan food totals: %w", err)
+		}
+		days = append(days, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating food totals: %w", err)
+	}
+
+	return days, nil
+}
+
+// loadWeighIns returns the week's recorded weights, oldest first
+func (b *SummaryBuilder) loadWeighIns(ctx context.Context, userID int64, from, to time.Time) ([]WeighIn, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT date, weight FROM daily_metrics
+		WHERE user_id = $1 AND weight IS NOT NULL AND date >= $2 AND date <= $3
+		ORDER BY date
+	`
+
+	rows, err := b.db.QueryContext(ctx, query, userID, from, to)
+	b.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weigh-ins: %w", err)
+	}
+	defer rows.Close()
+
+	var weighIns []WeighIn
+	for rows.Next() {
+		var w WeighIn
+		if err := rows.Scan(&w.Date, &w.Weight); err != nil {
+			return nil, fmt.Errorf("failed to scan weigh-in: %w", err)
+		}
+		weighIns = append(weighIns, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating weigh-ins: %w", err)
+	}
+
+	return weighIns, nil
+}
+
+// loadCalorieTarget returns the calorie goal of the active plan covering the week, if any
+func (b *SummaryBuilder) loadCalorieTarget(ctx context.Context, userID int64, from, to time.Time) (*float64, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT calories_goal FROM weekly_plans
+		WHERE user_id = $1 AND is_active = true AND start_date <= $3 AND end_date >= $2
+		ORDER BY start_date DESC
+		LIMIT 1
+	`
+
+	var target float64
+	err := b.db.QueryRowContext(ctx, query, userID, from, to).Scan(&target)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	b.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query calorie target: %w", err)
+	}
+
+	return &target, nil
+}
+
+// assembleSummary computes the weekly summary from already loaded data.
+//
+// Averages are over logged days (days with calories > 0); adherence is the
+// share of the week's days that were logged. Weight change compares the
+// first and last weigh-in of the week and needs at least two. The best and
+// worst days are the logged days closest to and furthest from the calorie
+// target (or the week's average when there is no target); they need at
+// least two logged days.
+func assembleSummary(weekStart time.Time, days []DayTotals, weighIns []WeighIn, calorieTarget *float64) *WeeklySummary {
+	summary := &WeeklySummary{
+		WeekStart:     weekStart,
+		WeekEnd:       weekStart.AddDate(0, 0, DaysPerWeek-1),
+		CalorieTarget: calorieTarget,
+	}
+
+	var logged []DayTotals
+	for _, d := range days {
+		if d.Calories > 0 {
+			logged = append(logged, d)
+		}
+	}
+
+	summary.LoggedDays = len(logged)
+	summary.AdherencePercent = int(math.Round(float64(len(logged)) / DaysPerWeek * 100))
+
+	if len(logged) > 0 {
+		for _, d := range logged {
+			summary.AvgCalories += d.Calories
+			summary.AvgProtein += d.Protein
+			summary.AvgFat += d.Fat
+			summary.AvgCarbs += d.Carbs
+		}
+		n := float64(len(logged))
+		summary.AvgCalories = round1(summary.AvgCalories / n)
+		summary.AvgProtein = round1(summary.AvgProtein / n)
+		summary.AvgFat = round1(summary.AvgFat / n)
+		summary.AvgCarbs = round1(summary.AvgCarbs / n)
+	}
+
+	if len(logged) >= 2 {
+		reference := summary.AvgCalories
+		if calorieTarget != nil {
+			reference = *calorieTarget
+		}
+		best, worst := logged[0], logged[0]
+		for _, d := range logged[1:] {
+			deviation := math.Abs(d.Calories - reference)
+			if deviation < math.Abs(best.Calories-reference) {
+				best = d
+			}
+			if deviation > math.Abs(worst.Calories-reference) {
+				worst = d
+			}
+		}
+		summary.BestDay = &DayHighlight{Date: best.Date, Calories: best.Calories}
+		summary.WorstDay = &DayHighlight{Date: worst.Date, Calories: worst.Calories}
+	}
+
+	if len(weighIns) > 0 {
+		current := weighIns[len(weighIns)-1].Weight
+		summary.CurrentWeight = &current
+	}
+	if len(weighIns) >= 2 {
+		change := round1(weighIns[len(weighIns)-1].Weight - weighIns[0].Weight)
+		summary.WeightChange = &change
+	}
+
+	return summary
+}
+
+// round1 rounds to one decimal place
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
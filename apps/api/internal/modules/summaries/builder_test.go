@@ -0,0 +1,146 @@
+package summaries
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestAssembleSummary(t *testing.T) {
+	weekStart := day("2026-10-05")
+
+	t.Run("full week against a calorie target", func(t *testing.T) {
+		days := []DayTotals{
+			{Date: day("2026-10-05"), Calories: 2000, Protein: 120, Fat: 70, Carbs: 200},
+			{Date: day("2026-10-06"), Calories: 2600, Protein: 110, Fat: 90, Carbs: 300},
+			{Date: day("2026-10-07"), Calories: 2050, Protein: 130, Fat: 60, Carbs: 220},
+			{Date: day("2026-10-09"), Calories: 1500, Protein: 100, Fat: 50, Carbs: 150},
+		}
+		weighIns := []WeighIn{
+			{Date: day("2026-10-05"), Weight: 82.4},
+			{Date: day("2026-10-08"), Weight: 82.0},
+			{Date: day("2026-10-11"), Weight: 81.7},
+		}
+
+		summary := assembleSummary(weekStart, days, weighIns, floatPtr(2100))
+
+		assert.Equal(t, day("2026-10-11"), summary.WeekEnd)
+		assert.Equal(t, 4, summary.LoggedDays)
+		assert.Equal(t, 57, summary.AdherencePercent)
+		assert.Equal(t, 2037.5, summary.AvgCalories)
+		assert.Equal(t, 115.0, summary.AvgProtein)
+		assert.Equal(t, 67.5, summary.AvgFat)
+		assert.Equal(t, 217.5, summary.AvgCarbs)
+		require.NotNil(t, summary.BestDay)
+		assert.Equal(t, day("2026-10-07"), summary.BestDay.Date)
+		require.NotNil(t, summary.WorstDay)
+		assert.Equal(t, day("2026-10-09"), summary.WorstDay.Date)
+		assert.Equal(t, 81.7, *summary.CurrentWeight)
+		assert.Equal(t, -0.7, *summary.WeightChange)
+		assert.True(t, summary.HasData())
+	})
+
+	t.Run("days without calories do not count as logged", func(t *testing.T) {
+		days := []DayTotals{
+			{Date: day("2026-10-05"), Calories: 0},
+			{Date: day("2026-10-06"), Calories: 1800, Protein: 90},
+		}
+
+		summary := assembleSummary(weekStart, days, nil, nil)
+
+		assert.Equal(t, 1, summary.LoggedDays)
+		assert.Equal(t, 14, summary.AdherencePercent)
+		assert.Equal(t, 1800.0, summary.AvgCalories)
+		assert.Nil(t, summary.BestDay, "a single day is not compared")
+		assert.Nil(t, summary.WorstDay)
+		assert.Nil(t, summary.CurrentWeight)
+		assert.Nil(t, summary.WeightChange)
+	})
+
+	t.Run("without a target days are compared to the average", func(t *testing.T) {
+		days := []DayTotals{
+			{Date: day("2026-10-05"), Calories: 1000},
+			{Date: day("2026-10-06"), Calories: 2000},
+			{Date: day("2026-10-07"), Calories: 2100},
+		}
+
+		summary := assembleSummary(weekStart, days, nil, nil)
+
+		assert.Equal(t, day("2026-10-06"), summary.BestDay.Date)
+		assert.Equal(t, day("2026-10-05"), summary.WorstDay.Date)
+	})
+
+	t.Run("ties keep the earlier day", func(t *testing.T) {
+		days := []DayTotals{
+			{Date: day("2026-10-05"), Calories: 1900},
+			{Date: day("2026-10-06"), Calories: 2100},
+		}
+
+		summary := assembleSummary(weekStart, days, nil, floatPtr(2000))
+
+		assert.Equal(t, day("2026-10-05"), summary.BestDay.Date)
+		assert.Equal(t, day("2026-10-05"), summary.WorstDay.Date)
+	})
+
+	t.Run("single weigh-in has no change", func(t *testing.T) {
+		summary := assembleSummary(weekStart, nil, []WeighIn{{Date: day("2026-10-07"), Weight: 70.2}}, nil)
+
+		assert.Equal(t, 0, summary.LoggedDays)
+		assert.Equal(t, 70.2, *summary.CurrentWeight)
+		assert.Nil(t, summary.WeightChange)
+		assert.True(t, summary.HasData())
+	})
+
+	t.Run("empty week has no data", func(t *testing.T) {
+		summary := assembleSummary(weekStart, nil, nil, nil)
+
+		assert.Equal(t, 0, summary.AdherencePercent)
+		assert.False(t, summary.HasData())
+	})
+}
+
+func TestBuild(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	builder := NewSummaryBuilder(&database.DB{DB: mockDB}, logger.New())
+	weekStart := day("2026-10-05")
+	weekEnd := day("2026-10-11")
+
+	mock.ExpectQuery("FROM food_entries").
+		WithArgs(int64(1), weekStart, weekEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "calories", "protein", "fat", "carbs"}).
+			AddRow(day("2026-10-05"), 2000.0, 100.0, 60.0, 250.0).
+			AddRow(day("2026-10-06"), 2200.0, 120.0, 70.0, 230.0))
+	mock.ExpectQuery("FROM daily_metrics").
+		WithArgs(int64(1), weekStart, weekEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "weight"}).
+			AddRow(day("2026-10-05"), 75.0).
+			AddRow(day("2026-10-10"), 74.6))
+	mock.ExpectQuery("FROM weekly_plans").
+		WithArgs(int64(1), weekStart, weekEnd).
+		WillReturnRows(sqlmock.NewRows([]string{"calories_goal"}))
+
+	summary, err := builder.Build(context.Background(), 1, weekStart)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, summary.LoggedDays)
+	assert.Equal(t, 2100.0, summary.AvgCalories)
+	assert.Nil(t, summary.CalorieTarget)
+	assert.Equal(t, -0.4, *summary.WeightChange)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
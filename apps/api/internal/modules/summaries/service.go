@@ -0,0 +1,294 @@
+package summaries
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/shared/clock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// Mailer sends weekly summary emails
+type Mailer interface {
+	SendWeeklySummaryEmail(ctx context.Context, data email.WeeklySummaryEmailData) error
+}
+
+// TokenIssuer creates unsubscribe tokens for email links
+type TokenIssuer interface {
+	CreateUnsubscribeToken(ctx context.Context, userID int64, kind notifications.EmailKind) (string, error)
+}
+
+// Service runs the weekly summary email job
+type Service struct {
+	db       *database.DB
+	log      *logger.Logger
+	builder  *SummaryBuilder
+	mailer   Mailer
+	tokens   TokenIssuer
+	pageSize int
+	now      func() time.Time
+}
+
+// NewService creates a new weekly summary service
+func NewService(db *database.DB, log *logger.Logger, mailer Mailer, tokens TokenIssuer) *Service {
+	return &Service{
+		db:       db,
+		log:      log,
+		builder:  NewSummaryBuilder(db, log),
+		mailer:   mailer,
+		tokens:   tokens,
+		pageSize: DefaultPageSize,
+		now:      time.Now,
+	}
+}
+
+// PreviousWeekStart returns the Monday of the full week before now (UTC dates)
+func PreviousWeekStart(now time.Time) time.Time {
+	today := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	daysSinceMonday := (int(today.Weekday()) + 6) % 7
+	return today.AddDate(0, 0, -daysSinceMonday-DaysPerWeek)
+}
+
+// SendWeek emails the summary of the week starting on weekStart to every
+// opted-in client who has not received it yet. Users are loaded page by page
+// in id order, so memory use does not grow with the user count.
+func (s *Service) SendWeek(ctx context.Context, weekStart time.Time) (SendStats, error) {
+	startTime := time.Now()
+
+	var stats SendStats
+	var cursor int64
+	for {
+		page, err := s.listRecipients(ctx, weekStart, cursor)
+		if err != nil {
+			return stats, err
+		}
+		for _, r := range page {
+			s.sendOne(ctx, r, weekStart, &stats)
+		}
+		if len(page) < s.pageSize {
+			break
+		}
+		cursor = page[len(page)-1].id
+	}
+
+	s.log.LogBusinessEvent("weekly_summaries_processed", map[string]interface{}{
+		"week_start":  weekStart.Format("2006-01-02"),
+		"sent":        stats.Sent,
+		"skipped":     stats.Skipped,
+		"failed":      stats.Failed,
+		"duration_ms": time.Since(startTime).Milliseconds(),
+	})
+
+	return stats, nil
+}
+
+// listRecipients returns the next page of opted-in clients after cursor that
+// have no send record for the week
+func (s *Service) listRecipients(ctx context.Context, weekStart time.Time, cursor int64) ([]recipient, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT u.id, u.email, COALESCE(u.name, ''), COALESCE(us.timezone, '')
+		FROM users u
+		LEFT JOIN email_preferences ep ON ep.user_id = u.id
+		LEFT JOIN user_settings us ON us.user_id = u.id
+		WHERE u.id > $1
+		  AND u.role = 'client'
+		  AND COALESCE(ep.weekly_summary_email, TRUE)
+		  AND NOT EXISTS (
+			SELECT 1 FROM weekly_summary_sends ws
+			WHERE ws.user_id = u.id AND ws.week_start = $2
+		  )
+		ORDER BY u.id
+		LIMIT $3
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, cursor, weekStart, s.pageSize)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"cursor": cursor,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list summary recipients: %w", err)
+	}
+	defer rows.Close()
+
+	var page []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.id, &r.email, &r.name, &r.timezone); err != nil {
+			return nil, fmt.Errorf("failed to scan summary recipient: %w", err)
+		}
+		page = append(page, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating summary recipients: %w", err)
+	}
+
+	return page, nil
+}
+
+// sendOne claims the (user, week) pair and sends the summary. The claim is
+// the idempotency guard: a concurrent or repeated run cannot claim it again.
+// A failed send releases the claim so a later run retries; a crash after
+// claiming leaves it in "sending", preferring a missed email to a duplicate.
+// Users whose local week has not ended yet are left for a later run.
+func (s *Service) sendOne(ctx context.Context, r recipient, weekStart time.Time, stats *SendStats) {
+	if clock.UserDay(r.timezone, s.now()).Before(weekStart.AddDate(0, 0, DaysPerWeek)) {
+		return
+	}
+
+	claimed, err := s.claim(ctx, r.id, weekStart)
+	if err != nil {
+		s.log.Error("Failed to claim weekly summary", "user_id", r.id, "error", err)
+		stats.Failed++
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	summary, err := s.builder.Build(ctx, r.id, weekStart)
+	if err != nil {
+		s.log.Error("Failed to build weekly summary", "user_id", r.id, "error", err)
+		s.release(ctx, r.id, weekStart)
+		stats.Failed++
+		return
+	}
+
+	if !summary.HasData() {
+		s.finish(ctx, r.id, weekStart, SendStatusSkipped)
+		stats.Skipped++
+		return
+	}
+
+	token, err := s.tokens.CreateUnsubscribeToken(ctx, r.id, notifications.EmailWeeklySummary)
+	if err != nil {
+		s.log.Warn("Failed to create unsubscribe token for weekly summary", "user_id", r.id, "error", err)
+	}
+
+	if err := s.mailer.SendWeeklySummaryEmail(ctx, emailData(r, summary, token)); err != nil {
+		s.log.Warn("Weekly summary email failed", "user_id", r.id, "error", err)
+		s.release(ctx, r.id, weekStart)
+		stats.Failed++
+		return
+	}
+
+	s.finish(ctx, r.id, weekStart, SendStatusSent)
+	stats.Sent++
+}
+
+// claim records the send attempt; false means the week was already claimed
+func (s *Service) claim(ctx context.Context, userID int64, weekStart time.Time) (bool, error) {
+	startTime := time.Now()
+
+	query := `
+		INSERT INTO weekly_summary_sends (user_id, week_start, status)
+		VALUES ($1, $2, 'sending')
+		ON CONFLICT (user_id, week_start) DO NOTHING
+	`
+	result, err := s.db.ExecContext(ctx, query, userID, weekStart)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// finish marks a claimed week as sent or skipped
+func (s *Service) finish(ctx context.Context, userID int64, weekStart time.Time, status string) {
+	startTime := time.Now()
+
+	query := `
+		UPDATE weekly_summary_sends
+		SET status = $3, sent_at = CASE WHEN $3 = 'sent' THEN NOW() END
+		WHERE user_id = $1 AND week_start = $2
+	`
+	_, err := s.db.ExecContext(ctx, query, userID, weekStart, status)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"status":  status,
+	})
+}
+
+// release drops a claim after a failure so the next run can retry
+func (s *Service) release(ctx context.Context, userID int64, weekStart time.Time) {
+	startTime := time.Now()
+
+	query := `DELETE FROM weekly_summary_sends WHERE user_id = $1 AND week_start = $2 AND status = 'sending'`
+	_, err := s.db.ExecContext(ctx, query, userID, weekStart)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+}
+
+// emailData maps a summary to the email template data
+func emailData(r recipient, summary *WeeklySummary, token string) email.WeeklySummaryEmailData {
+	data := email.WeeklySummaryEmailData{
+		UserEmail:        r.email,
+		UserName:         r.name,
+		WeekStart:        summary.WeekStart,
+		WeekEnd:          summary.WeekEnd,
+		LoggedDays:       summary.LoggedDays,
+		AdherencePercent: summary.AdherencePercent,
+		AvgCalories:      summary.AvgCalories,
+		AvgProtein:       summary.AvgProtein,
+		AvgFat:           summary.AvgFat,
+		AvgCarbs:         summary.AvgCarbs,
+		CalorieTarget:    summary.CalorieTarget,
+		CurrentWeight:    summary.CurrentWeight,
+		WeightChange:     summary.WeightChange,
+		UnsubscribeToken: token,
+	}
+	if summary.BestDay != nil {
+		data.BestDay = &email.WeeklySummaryDay{Date: summary.BestDay.Date, Calories: summary.BestDay.Calories}
+	}
+	if summary.WorstDay != nil {
+		data.WorstDay = &email.WeeklySummaryDay{Date: summary.WorstDay.Date, Calories: summary.WorstDay.Calories}
+	}
+	return data
+}
+
+// RunScheduler sends the previous week's summaries once it is Monday in the
+// user's timezone. Monday starts 14 hours before UTC in the easternmost zones
+// and ends a day later in the westernmost, so runs are due while it is Monday
+// or Tuesday at UTC+14. It checks hourly, so failed sends are retried and
+// restarts are harmless thanks to the per-week send log. It blocks until ctx
+// is cancelled.
+func (s *Service) RunScheduler(ctx context.Context) {
+	ticker := time.NewTicker(SchedulerInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Weekly summary scheduler started")
+
+	run := func() {
+		ahead := s.now().UTC().Add(clock.MaxUTCOffset)
+		if ahead.Weekday() != time.Monday && ahead.Weekday() != time.Tuesday {
+			return
+		}
+		if _, err := s.SendWeek(ctx, PreviousWeekStart(ahead)); err != nil {
+			s.log.Error("Weekly summary run failed", "error", err)
+		}
+	}
+
+	run()
+	for {
+		select {
+		case <-ticker.C:
+			run()
+		case <-ctx.Done():
+			s.log.Info("Weekly summary scheduler stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package summaries
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeMailer struct {
+	sent []email.WeeklySummaryEmailData
+	err  error
+}
+
+func (m *fakeMailer) SendWeeklySummaryEmail(ctx context.Context, data email.WeeklySummaryEmailData) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, data)
+	return nil
+}
+
+type fakeTokens struct{}
+
+func (fakeTokens) CreateUnsubscribeToken(ctx context.Context, userID int64, kind notifications.EmailKind) (string, error) {
+	return "token", nil
+}
+
+func setupTestService(t *testing.T, mailer Mailer) (*Service, sqlmock.Sqlmock, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), mailer, fakeTokens{})
+
+	return service, mock, func() { mockDB.Close() }
+}
+
+func recipientRows(ids ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "email", "name", "timezone"})
+	for _, id := range ids {
+		rows.AddRow(id, "user@example.com", "Анна", "Europe/Moscow")
+	}
+	return rows
+}
+
+// expectWeek expects the builder queries for a user with one logged day or none
+func expectWeek(mock sqlmock.Sqlmock, userID int64, logged bool) {
+	days := sqlmock.NewRows([]string{"date", "calories", "protein", "fat", "carbs"})
+	if logged {
+		days.AddRow(day("2026-10-05"), 1800.0, 100.0, 60.0, 200.0)
+	}
+	mock.ExpectQuery("FROM food_entries").WithArgs(userID, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnRows(days)
+	mock.ExpectQuery("FROM daily_metrics").WithArgs(userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"date", "weight"}))
+	mock.ExpectQuery("FROM weekly_plans").WithArgs(userID, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"calories_goal"}))
+}
+
+func TestPreviousWeekStart(t *testing.T) {
+	assert.Equal(t, day("2026-10-05"), PreviousWeekStart(time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC)))
+	assert.Equal(t, day("2026-10-05"), PreviousWeekStart(time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC)))
+}
+
+func TestSendWeek(t *testing.T) {
+	weekStart := day("2026-10-05")
+
+	t.Run("pages through recipients and records each send", func(t *testing.T) {
+		mailer := &fakeMailer{}
+		service, mock, cleanup := setupTestService(t, mailer)
+		defer cleanup()
+		service.pageSize = 2
+
+		mock.ExpectQuery("FROM users u").WithArgs(int64(0), weekStart, 2).WillReturnRows(recipientRows(1, 2))
+		for _, id := range []int64{1, 2} {
+			mock.ExpectExec("INSERT INTO weekly_summary_sends").WithArgs(id, weekStart).WillReturnResult(sqlmock.NewResult(0, 1))
+			expectWeek(mock, id, true)
+			mock.ExpectExec("UPDATE weekly_summary_sends").WithArgs(id, weekStart, SendStatusSent).WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectQuery("FROM users u").WithArgs(int64(2), weekStart, 2).WillReturnRows(recipientRows(3))
+		mock.ExpectExec("INSERT INTO weekly_summary_sends").WithArgs(int64(3), weekStart).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectWeek(mock, 3, false)
+		mock.ExpectExec("UPDATE weekly_summary_sends").WithArgs(int64(3), weekStart, SendStatusSkipped).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		stats, err := service.SendWeek(context.Background(), weekStart)
+
+		require.NoError(t, err)
+		assert.Equal(t, SendStats{Sent: 2, Skipped: 1}, stats)
+		require.Len(t, mailer.sent, 2)
+		assert.Equal(t, "token", mailer.sent[0].UnsubscribeToken)
+		assert.Equal(t, day("2026-10-11"), mailer.sent[0].WeekEnd)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already claimed week is not sent again", func(t *testing.T) {
+		mailer := &fakeMailer{}
+		service, mock, cleanup := setupTestService(t, mailer)
+		defer cleanup()
+
+		mock.ExpectQuery("FROM users u").WillReturnRows(recipientRows(5))
+		mock.ExpectExec("INSERT INTO weekly_summary_sends").WithArgs(int64(5), weekStart).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		stats, err := service.SendWeek(context.Background(), weekStart)
+
+		require.NoError(t, err)
+		assert.Equal(t, SendStats{}, stats)
+		assert.Empty(t, mailer.sent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed send releases the claim for a retry", func(t *testing.T) {
+		service, mock, cleanup := setupTestService(t, &fakeMailer{err: errors.New("smtp: 451 try again later")})
+		defer cleanup()
+
+		mock.ExpectQuery("FROM users u").WillReturnRows(recipientRows(7))
+		mock.ExpectExec("INSERT INTO weekly_summary_sends").WithArgs(int64(7), weekStart).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectWeek(mock, 7, true)
+		mock.ExpectExec("DELETE FROM weekly_summary_sends").WithArgs(int64(7), weekStart).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		stats, err := service.SendWeek(context.Background(), weekStart)
+
+		require.NoError(t, err)
+		assert.Equal(t, SendStats{Failed: 1}, stats)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("waits for the end of the week in the user's timezone", func(t *testing.T) {
+		mailer := &fakeMailer{}
+		service, mock, cleanup := setupTestService(t, mailer)
+		defer cleanup()
+		// Monday 01:00 in Vladivostok, still Sunday in Moscow
+		service.now = func() time.Time { return time.Date(2026, 10, 11, 15, 0, 0, 0, time.UTC) }
+
+		mock.ExpectQuery("FROM users u").WillReturnRows(sqlmock.NewRows([]string{"id", "email", "name", "timezone"}).
+			AddRow(int64(1), "vl@example.com", "Иван", "Asia/Vladivostok").
+			AddRow(int64(2), "msk@example.com", "Анна", "Europe/Moscow"))
+		mock.ExpectExec("INSERT INTO weekly_summary_sends").WithArgs(int64(1), weekStart).WillReturnResult(sqlmock.NewResult(0, 1))
+		expectWeek(mock, 1, true)
+		mock.ExpectExec("UPDATE weekly_summary_sends").WithArgs(int64(1), weekStart, SendStatusSent).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		stats, err := service.SendWeek(context.Background(), weekStart)
+
+		require.NoError(t, err)
+		assert.Equal(t, SendStats{Sent: 1}, stats)
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, "vl@example.com", mailer.sent[0].UserEmail)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
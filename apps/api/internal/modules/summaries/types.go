@@ -0,0 +1,77 @@
+package summaries
+
+import "time"
+
+const (
+	// DaysPerWeek is the length of a summarised week (Monday to Sunday)
+	DaysPerWeek = 7
+	// DefaultPageSize is how many users the weekly job loads at a time
+	DefaultPageSize = 200
+	// SchedulerInterval is how often the scheduler checks whether a summary run is due
+	SchedulerInterval = time.Hour
+)
+
+// Send statuses recorded in weekly_summary_sends
+const (
+	SendStatusSending = "sending"
+	SendStatusSent    = "sent"
+	SendStatusSkipped = "skipped"
+)
+
+// DayTotals is one day of logged nutrition
+type DayTotals struct {
+	Date     time.Time
+	Calories float64
+	Protein  float64
+	Fat      float64
+	Carbs    float64
+}
+
+// WeighIn is a single recorded body weight
+type WeighIn struct {
+	Date   time.Time
+	Weight float64
+}
+
+// DayHighlight is a day singled out in the summary
+type DayHighlight struct {
+	Date     time.Time
+	Calories float64
+}
+
+// WeeklySummary is the assembled weekly progress of one user
+type WeeklySummary struct {
+	WeekStart        time.Time
+	WeekEnd          time.Time
+	LoggedDays       int
+	AdherencePercent int
+	AvgCalories      float64
+	AvgProtein       float64
+	AvgFat           float64
+	AvgCarbs         float64
+	CalorieTarget    *float64
+	CurrentWeight    *float64
+	WeightChange     *float64
+	BestDay          *DayHighlight
+	WorstDay         *DayHighlight
+}
+
+// HasData reports whether there is anything worth emailing
+func (s *WeeklySummary) HasData() bool {
+	return s.LoggedDays > 0 || s.CurrentWeight != nil
+}
+
+// recipient is an opted-in user who has not yet received the week's summary
+type recipient struct {
+	id       int64
+	email    string
+	name     string
+	timezone string
+}
+
+// SendStats counts the outcome of one weekly run
+type SendStats struct {
+	Sent    int
+	Skipped int
+	Failed  int
+}
@@ -0,0 +1,164 @@
+package supplements
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/clock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles supplement requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	db      *database.DB
+	service ServiceInterface
+}
+
+// NewHandler creates a new supplements handler. db is used to look up the
+// user's timezone.
+func NewHandler(cfg *config.Config, log *logger.Logger, db *database.DB, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		db:      db,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// today returns the current day in the user's timezone, honouring ?tz=
+func (h *Handler) today(c *gin.Context, userID int64) (time.Time, bool) {
+	loc, ok := middleware.RequestTimezone(c, h.db, userID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return clock.Day(time.Now().In(loc)), true
+}
+
+// CreateSupplement handles POST /api/v1/supplements
+func (h *Handler) CreateSupplement(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateSupplementRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	today, ok := h.today(c, userID)
+	if !ok {
+		return
+	}
+
+	supplement, err := h.service.CreateSupplement(c.Request.Context(), userID, &req, today)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, supplement)
+}
+
+// GetSupplement handles GET /api/v1/supplements/:id
+// Includes adherence over the last 30 days.
+func (h *Handler) GetSupplement(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	today, ok := h.today(c, userID)
+	if !ok {
+		return
+	}
+
+	supplement, err := h.service.GetSupplement(c.Request.Context(), userID, c.Param("id"), today)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, supplement)
+}
+
+// GetToday handles GET /api/v1/supplements/today
+func (h *Handler) GetToday(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	today, ok := h.today(c, userID)
+	if !ok {
+		return
+	}
+
+	checklist, err := h.service.GetChecklist(c.Request.Context(), userID, today)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, checklist)
+}
+
+// Take handles POST /api/v1/supplements/:id/take
+// Marks today's dose taken; repeating the request changes nothing.
+func (h *Handler) Take(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+	today, ok := h.today(c, userID)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.Take(c.Request.Context(), userID, c.Param("id"), today)
+	if err != nil {
+		h.respondError(c, err, userID)
+		return
+	}
+
+	response.Success(c, http.StatusOK, item)
+}
+
+func (h *Handler) respondError(c *gin.Context, err error, userID int64) {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		validation.Respond(c, err)
+	case errors.Is(err, apperrors.ErrNotFound):
+		response.NotFound(c, "Добавка не найдена")
+	case errors.Is(err, ErrNotScheduled):
+		response.Error(c, http.StatusConflict, "На сегодня приём этой добавки не запланирован")
+	default:
+		h.log.Error("Failed to process supplement request", "error", err, "user_id", userID, "supplement_id", c.Param("id"))
+		response.InternalError(c, "Не удалось обработать запрос")
+	}
+}
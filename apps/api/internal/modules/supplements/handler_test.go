@@ -0,0 +1,159 @@
+package supplements
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	today time.Time
+	err   error
+}
+
+func (m *mockService) CreateSupplement(ctx context.Context, userID int64, req *CreateSupplementRequest, today time.Time) (*Supplement, error) {
+	m.today = today
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Supplement{ID: testSupplementID, UserID: userID, Name: req.Name, Schedule: Schedule{Type: req.Schedule}}, nil
+}
+
+func (m *mockService) GetSupplement(ctx context.Context, userID int64, supplementID string, today time.Time) (*Supplement, error) {
+	m.today = today
+	if m.err != nil {
+		return nil, m.err
+	}
+	percent := 90.0
+	return &Supplement{ID: supplementID, UserID: userID, Adherence: &Adherence{Scheduled: 10, Taken: 9, Percent: &percent}}, nil
+}
+
+func (m *mockService) GetChecklist(ctx context.Context, userID int64, today time.Time) (*Checklist, error) {
+	m.today = today
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Checklist{Date: today.Format("2006-01-02"), Items: []ChecklistItem{{SupplementID: testSupplementID}}}, nil
+}
+
+func (m *mockService) Take(ctx context.Context, userID int64, supplementID string, today time.Time) (*ChecklistItem, error) {
+	m.today = today
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &ChecklistItem{SupplementID: supplementID, Taken: true}, nil
+}
+
+func newTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: testSupplementID}}
+	c.Set("user_id", int64(5))
+	return c, w
+}
+
+func TestHandlerCreateSupplement(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", `{"name":"Креатин","dose":"5 г","schedule":"daily"}`, nil, http.StatusCreated},
+		{"unknown schedule", `{"name":"Креатин","dose":"5 г","schedule":"monthly"}`, nil, http.StatusBadRequest},
+		{"missing dose", `{"name":"Креатин","schedule":"daily"}`, nil, http.StatusBadRequest},
+		{"internal", `{"name":"Креатин","dose":"5 г","schedule":"daily"}`, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), nil, &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/supplements?tz=UTC", tt.body)
+
+			handler.CreateSupplement(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerGetToday(t *testing.T) {
+	t.Run("today in the requested timezone", func(t *testing.T) {
+		svc := &mockService{}
+		handler := NewHandler(nil, logger.New(), nil, svc)
+		c, w := newTestContext(http.MethodGet, "/supplements/today?tz=Pacific/Kiritimati", "")
+
+		handler.GetToday(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		today := time.Now().In(time.FixedZone("", 14*60*60)).Format("2006-01-02")
+		assert.Equal(t, today, svc.today.Format("2006-01-02"))
+		assert.Contains(t, w.Body.String(), testSupplementID)
+	})
+
+	t.Run("unknown timezone", func(t *testing.T) {
+		handler := NewHandler(nil, logger.New(), nil, &mockService{})
+		c, w := newTestContext(http.MethodGet, "/supplements/today?tz=Mars/Olympus_Mons", "")
+
+		handler.GetToday(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandlerGetSupplement(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), nil, &mockService{})
+	c, w := newTestContext(http.MethodGet, "/supplements/"+testSupplementID+"?tz=UTC", "")
+
+	handler.GetSupplement(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"percent":90`)
+}
+
+func TestHandlerTake(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"taken", nil, http.StatusOK},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"not scheduled", ErrNotScheduled, http.StatusConflict},
+		{"internal", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), nil, &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/supplements/"+testSupplementID+"/take?tz=UTC", "")
+
+			handler.Take(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerUnauthorized(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), nil, &mockService{})
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/supplements/today", nil)
+
+	handler.GetToday(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
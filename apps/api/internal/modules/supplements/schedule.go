@@ -0,0 +1,62 @@
+package supplements
+
+import (
+	"math"
+	"time"
+)
+
+// IsScheduled reports whether the schedule requires a dose on day
+func (s Schedule) IsScheduled(day time.Time) bool {
+	if s.Type == ScheduleDaily {
+		return true
+	}
+	weekday := int(day.Weekday())
+	for _, d := range s.Weekdays {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// ScheduledDays expands the schedule into the days between from and to,
+// both inclusive, that require a dose. Days are calendar dates at midnight
+// UTC, as returned by clock.Day.
+func ScheduledDays(s Schedule, from, to time.Time) []time.Time {
+	days := make([]time.Time, 0)
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if s.IsScheduled(day) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// computeAdherence measures the doses taken over the AdherenceWindowDays
+// ending today, starting no earlier than the day the supplement was added.
+// Today counts as scheduled only once its dose is taken, so the percentage
+// does not drop in the morning before the user had a chance to take it.
+func computeAdherence(s Schedule, startDate, today time.Time, taken map[string]bool) *Adherence {
+	from := today.AddDate(0, 0, -(AdherenceWindowDays - 1))
+	if startDate.After(from) {
+		from = startDate
+	}
+
+	a := &Adherence{From: from.Format("2006-01-02"), To: today.Format("2006-01-02")}
+	for _, day := range ScheduledDays(s, from, today) {
+		done := taken[day.Format("2006-01-02")]
+		if day.Equal(today) && !done {
+			continue
+		}
+		a.Scheduled++
+		if done {
+			a.Taken++
+		}
+	}
+
+	if a.Scheduled > 0 {
+		percent := math.Round(float64(a.Taken)/float64(a.Scheduled)*1000) / 10
+		a.Percent = &percent
+	}
+	return a
+}
@@ -0,0 +1,138 @@
+package supplements
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func dates(days []time.Time) []string {
+	out := make([]string, len(days))
+	for i, d := range days {
+		out[i] = d.Format("2006-01-02")
+	}
+	return out
+}
+
+func TestScheduledDays(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule Schedule
+		from, to string
+		want     []string
+	}{
+		{
+			name:     "daily",
+			schedule: Schedule{Type: ScheduleDaily},
+			from:     "2026-01-30", to: "2026-02-02",
+			want: []string{"2026-01-30", "2026-01-31", "2026-02-01", "2026-02-02"},
+		},
+		{
+			name:     "mondays and thursdays across a month boundary",
+			schedule: Schedule{Type: ScheduleWeekdays, Weekdays: []int{1, 4}},
+			from:     "2026-01-26", to: "2026-02-09",
+			want: []string{"2026-01-26", "2026-01-29", "2026-02-02", "2026-02-05", "2026-02-09"},
+		},
+		{
+			name:     "weekend across a year boundary",
+			schedule: Schedule{Type: ScheduleWeekdays, Weekdays: []int{0, 6}},
+			from:     "2026-12-26", to: "2027-01-03",
+			want: []string{"2026-12-26", "2026-12-27", "2027-01-02", "2027-01-03"},
+		},
+		{
+			name:     "end of a leap February",
+			schedule: Schedule{Type: ScheduleWeekdays, Weekdays: []int{2}},
+			from:     "2028-02-22", to: "2028-03-07",
+			want: []string{"2028-02-22", "2028-02-29", "2028-03-07"},
+		},
+		{
+			name:     "no matching weekday in range",
+			schedule: Schedule{Type: ScheduleWeekdays, Weekdays: []int{3}},
+			from:     "2026-02-26", to: "2026-03-03",
+			want: []string{},
+		},
+		{
+			name:     "single day",
+			schedule: Schedule{Type: ScheduleDaily},
+			from:     "2026-03-31", to: "2026-03-31",
+			want: []string{"2026-03-31"},
+		},
+		{
+			name:     "empty range",
+			schedule: Schedule{Type: ScheduleDaily},
+			from:     "2026-04-01", to: "2026-03-31",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ScheduledDays(tt.schedule, day(tt.from), day(tt.to))
+			assert.Equal(t, tt.want, dates(got))
+		})
+	}
+}
+
+func TestComputeAdherence(t *testing.T) {
+	daily := Schedule{Type: ScheduleDaily}
+
+	t.Run("window is the last 30 days", func(t *testing.T) {
+		taken := map[string]bool{"2026-02-15": true, "2026-03-01": true, "2026-03-16": true, "2026-03-17": true}
+
+		a := computeAdherence(daily, day("2026-01-01"), day("2026-03-17"), taken)
+
+		assert.Equal(t, "2026-02-16", a.From)
+		assert.Equal(t, "2026-03-17", a.To)
+		assert.Equal(t, 30, a.Scheduled)
+		assert.Equal(t, 3, a.Taken, "2026-02-15 is outside the window")
+		require.NotNil(t, a.Percent)
+		assert.Equal(t, 10.0, *a.Percent)
+	})
+
+	t.Run("starts when the supplement was added", func(t *testing.T) {
+		taken := map[string]bool{"2026-03-10": true, "2026-03-12": true}
+
+		a := computeAdherence(daily, day("2026-03-10"), day("2026-03-12"), taken)
+
+		assert.Equal(t, "2026-03-10", a.From)
+		assert.Equal(t, 3, a.Scheduled)
+		assert.Equal(t, 2, a.Taken)
+		assert.Equal(t, 66.7, *a.Percent)
+	})
+
+	t.Run("today does not count until taken", func(t *testing.T) {
+		a := computeAdherence(daily, day("2026-03-10"), day("2026-03-11"), map[string]bool{"2026-03-10": true})
+
+		assert.Equal(t, 1, a.Scheduled)
+		assert.Equal(t, 100.0, *a.Percent)
+	})
+
+	t.Run("weekday schedule across a month boundary", func(t *testing.T) {
+		// Mondays and Thursdays from 2026-01-26 to 2026-02-06: four doses
+		schedule := Schedule{Type: ScheduleWeekdays, Weekdays: []int{1, 4}}
+		taken := map[string]bool{"2026-01-26": true, "2026-01-29": true, "2026-02-02": true, "2026-01-31": true}
+
+		a := computeAdherence(schedule, day("2026-01-26"), day("2026-02-06"), taken)
+
+		assert.Equal(t, 4, a.Scheduled)
+		assert.Equal(t, 3, a.Taken, "a dose on an unscheduled day does not count")
+		assert.Equal(t, 75.0, *a.Percent)
+	})
+
+	t.Run("nothing scheduled yet", func(t *testing.T) {
+		a := computeAdherence(daily, day("2026-03-10"), day("2026-03-10"), map[string]bool{})
+
+		assert.Zero(t, a.Scheduled)
+		assert.Nil(t, a.Percent)
+	})
+}
@@ -0,0 +1,296 @@
+package supplements
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// ServiceInterface defines the interface for supplement operations. today is
+// the user's current calendar day at midnight UTC (see clock.Day).
+type ServiceInterface interface {
+	CreateSupplement(ctx context.Context, userID int64, req *CreateSupplementRequest, today time.Time) (*Supplement, error)
+	GetSupplement(ctx context.Context, userID int64, supplementID string, today time.Time) (*Supplement, error)
+	GetChecklist(ctx context.Context, userID int64, today time.Time) (*Checklist, error)
+	Take(ctx context.Context, userID int64, supplementID string, today time.Time) (*ChecklistItem, error)
+}
+
+// Service manages supplements and their daily intakes
+type Service struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewService creates a new supplements service
+func NewService(db *database.DB, log *logger.Logger) *Service {
+	return &Service{db: db, log: log}
+}
+
+const supplementColumns = `id, user_id, name, dose, schedule, weekdays, start_date, created_at`
+
+// validateSupplement checks a supplement request and normalizes its name,
+// dose and weekdays. Invalid input is reported as validation.Errors.
+func validateSupplement(req *CreateSupplementRequest) error {
+	errs := validation.Errors{}
+
+	req.Name = strings.TrimSpace(req.Name)
+	req.Dose = strings.TrimSpace(req.Dose)
+	if req.Name == "" {
+		errs["name"] = "Обязательное поле"
+	}
+	if req.Dose == "" {
+		errs["dose"] = "Обязательное поле"
+	}
+
+	if req.Schedule == ScheduleDaily {
+		req.Weekdays = nil
+	} else {
+		seen := make(map[int]bool, len(req.Weekdays))
+		for _, d := range req.Weekdays {
+			if d < 0 || d > 6 {
+				errs["weekdays"] = "Дни недели задаются числами от 0 (воскресенье) до 6 (суббота)"
+				break
+			}
+			if seen[d] {
+				errs["weekdays"] = "Дни недели не должны повторяться"
+				break
+			}
+			seen[d] = true
+		}
+		if len(req.Weekdays) == 0 {
+			errs["weekdays"] = "Укажите хотя бы один день недели"
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CreateSupplement defines a supplement starting today
+func (s *Service) CreateSupplement(ctx context.Context, userID int64, req *CreateSupplementRequest, today time.Time) (*Supplement, error) {
+	if err := validateSupplement(req); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO supplements (user_id, name, dose, schedule, weekdays, start_date)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + supplementColumns
+
+	supplement, err := scanSupplement(s.db.QueryRowContext(ctx, query,
+		userID, req.Name, req.Dose, req.Schedule, intArrayParam(req.Weekdays), today.Format("2006-01-02"),
+	))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create supplement: %w", err)
+	}
+
+	s.log.LogBusinessEvent("supplement_created", map[string]interface{}{
+		"supplement_id": supplement.ID,
+		"user_id":       userID,
+		"schedule":      supplement.Type,
+	})
+
+	return supplement, nil
+}
+
+// GetSupplement returns one of the user's supplements with its adherence
+// over the last AdherenceWindowDays
+func (s *Service) GetSupplement(ctx context.Context, userID int64, supplementID string, today time.Time) (*Supplement, error) {
+	supplement, err := s.getOwned(ctx, userID, supplementID)
+	if err != nil {
+		return nil, err
+	}
+
+	startDate, err := time.Parse("2006-01-02", supplement.StartDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse supplement start date: %w", err)
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT date::text FROM supplement_intakes
+		WHERE supplement_id = $1 AND date BETWEEN $2 AND $3`
+
+	rows, err := s.db.QueryContext(ctx, query, supplementID,
+		today.AddDate(0, 0, -(AdherenceWindowDays-1)).Format("2006-01-02"), today.Format("2006-01-02"))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"supplement_id": supplementID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query supplement intakes: %w", err)
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var date string
+		if err := rows.Scan(&date); err != nil {
+			return nil, fmt.Errorf("failed to scan supplement intake: %w", err)
+		}
+		taken[date] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating supplement intakes: %w", err)
+	}
+
+	supplement.Adherence = computeAdherence(supplement.Schedule, startDate, today, taken)
+	return supplement, nil
+}
+
+// GetChecklist returns the user's supplements due today, in the order they
+// were added, with whether today's dose was taken
+func (s *Service) GetChecklist(ctx context.Context, userID int64, today time.Time) (*Checklist, error) {
+	startTime := time.Now()
+	query := `
+		SELECT s.id, s.name, s.dose, s.schedule, s.weekdays, i.taken_at
+		FROM supplements s
+		LEFT JOIN supplement_intakes i ON i.supplement_id = s.id AND i.date = $2
+		WHERE s.user_id = $1 AND s.start_date <= $2
+		ORDER BY s.created_at, s.id`
+
+	date := today.Format("2006-01-02")
+	rows, err := s.db.QueryContext(ctx, query, userID, date)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"date":    date,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query supplements: %w", err)
+	}
+	defer rows.Close()
+
+	checklist := &Checklist{Date: date, Items: make([]ChecklistItem, 0)}
+	for rows.Next() {
+		var item ChecklistItem
+		var schedule Schedule
+		var weekdays sql.NullString
+		var takenAt sql.NullTime
+		if err := rows.Scan(&item.SupplementID, &item.Name, &item.Dose, &schedule.Type, &weekdays, &takenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan supplement: %w", err)
+		}
+		schedule.Weekdays = parseIntArray(weekdays.String)
+		if !schedule.IsScheduled(today) {
+			continue
+		}
+		if takenAt.Valid {
+			item.Taken = true
+			item.TakenAt = &takenAt.Time
+		}
+		checklist.Items = append(checklist.Items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating supplements: %w", err)
+	}
+
+	return checklist, nil
+}
+
+// Take marks today's dose of a supplement taken. Marking it again returns
+// the dose already recorded.
+func (s *Service) Take(ctx context.Context, userID int64, supplementID string, today time.Time) (*ChecklistItem, error) {
+	supplement, err := s.getOwned(ctx, userID, supplementID)
+	if err != nil {
+		return nil, err
+	}
+	if supplement.StartDate > today.Format("2006-01-02") || !supplement.IsScheduled(today) {
+		return nil, ErrNotScheduled
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO supplement_intakes (supplement_id, date)
+		VALUES ($1, $2)
+		ON CONFLICT (supplement_id, date) DO UPDATE SET taken_at = supplement_intakes.taken_at
+		RETURNING taken_at`
+
+	var takenAt time.Time
+	err = s.db.QueryRowContext(ctx, query, supplementID, today.Format("2006-01-02")).Scan(&takenAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":       userID,
+		"supplement_id": supplementID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record supplement intake: %w", err)
+	}
+
+	return &ChecklistItem{
+		SupplementID: supplement.ID,
+		Name:         supplement.Name,
+		Dose:         supplement.Dose,
+		Taken:        true,
+		TakenAt:      &takenAt,
+	}, nil
+}
+
+// getOwned loads a supplement of the user. Supplements of other users are
+// reported as not found.
+func (s *Service) getOwned(ctx context.Context, userID int64, supplementID string) (*Supplement, error) {
+	if _, err := uuid.Parse(supplementID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	supplement, err := scanSupplement(s.db.QueryRowContext(ctx,
+		`SELECT `+supplementColumns+` FROM supplements WHERE id = $1 AND user_id = $2`,
+		supplementID, userID,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get supplement: %w", err)
+	}
+	return supplement, nil
+}
+
+func scanSupplement(row *sql.Row) (*Supplement, error) {
+	var sup Supplement
+	var weekdays sql.NullString
+	var startDate time.Time
+	err := row.Scan(&sup.ID, &sup.UserID, &sup.Name, &sup.Dose, &sup.Type, &weekdays, &startDate, &sup.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	sup.Weekdays = parseIntArray(weekdays.String)
+	sup.StartDate = startDate.Format("2006-01-02")
+	return &sup, nil
+}
+
+// intArrayParam formats ints as a PostgreSQL array literal, or NULL when empty
+func intArrayParam(values []int) any {
+	if len(values) == 0 {
+		return nil
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// parseIntArray parses a PostgreSQL array literal like "{1,3,5}"
+func parseIntArray(s string) []int {
+	s = strings.Trim(strings.TrimSpace(s), "{}")
+	if s == "" || s == "NULL" {
+		return nil
+	}
+	parts := strings.Split(s, ",")
+	result := make([]int, 0, len(parts))
+	for _, p := range parts {
+		if v, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+			result = append(result, v)
+		}
+	}
+	return result
+}
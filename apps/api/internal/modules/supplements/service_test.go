@@ -0,0 +1,197 @@
+package supplements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSupplementID = "4b6f1c2d-8e9a-4b3c-9d1e-2f3a4b5c6d7e"
+
+var (
+	testNow   = time.Date(2026, 2, 2, 9, 30, 0, 0, time.UTC)
+	testToday = day("2026-02-02") // Monday
+)
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	return NewService(&database.DB{DB: mockDB}, logger.New()), mock
+}
+
+func supplementRows(schedule string, weekdays interface{}, startDate string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "name", "dose", "schedule", "weekdays", "start_date", "created_at"}).
+		AddRow(testSupplementID, int64(5), "Креатин", "5 г", schedule, weekdays, day(startDate), testNow)
+}
+
+func TestValidateSupplement(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    CreateSupplementRequest
+		fields []string
+	}{
+		{"daily", CreateSupplementRequest{Name: "Витамин D", Dose: "2000 МЕ", Schedule: ScheduleDaily}, nil},
+		{"weekdays", CreateSupplementRequest{Name: "Омега-3", Dose: "1 капсула", Schedule: ScheduleWeekdays, Weekdays: []int{0, 6}}, nil},
+		{"blank name", CreateSupplementRequest{Name: "  ", Dose: "5 г", Schedule: ScheduleDaily}, []string{"name"}},
+		{"no weekdays", CreateSupplementRequest{Name: "Креатин", Dose: "5 г", Schedule: ScheduleWeekdays}, []string{"weekdays"}},
+		{"weekday out of range", CreateSupplementRequest{Name: "Креатин", Dose: "5 г", Schedule: ScheduleWeekdays, Weekdays: []int{1, 7}}, []string{"weekdays"}},
+		{"repeated weekday", CreateSupplementRequest{Name: "Креатин", Dose: "5 г", Schedule: ScheduleWeekdays, Weekdays: []int{1, 1}}, []string{"weekdays"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSupplement(&tt.req)
+
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Len(t, fieldErrs, len(tt.fields))
+			for _, field := range tt.fields {
+				assert.Contains(t, fieldErrs, field)
+			}
+		})
+	}
+
+	t.Run("daily schedule drops weekdays", func(t *testing.T) {
+		req := CreateSupplementRequest{Name: " Креатин ", Dose: "5 г", Schedule: ScheduleDaily, Weekdays: []int{9}}
+
+		require.NoError(t, validateSupplement(&req))
+		assert.Equal(t, "Креатин", req.Name)
+		assert.Nil(t, req.Weekdays)
+	})
+}
+
+func TestService_CreateSupplement(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("INSERT INTO supplements").
+		WithArgs(int64(5), "Креатин", "5 г", ScheduleWeekdays, "{1,4}", "2026-02-02").
+		WillReturnRows(supplementRows(ScheduleWeekdays, "{1,4}", "2026-02-02"))
+
+	supplement, err := service.CreateSupplement(context.Background(), 5, &CreateSupplementRequest{
+		Name: "Креатин", Dose: "5 г", Schedule: ScheduleWeekdays, Weekdays: []int{1, 4},
+	}, testToday)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 4}, supplement.Weekdays)
+	assert.Equal(t, "2026-02-02", supplement.StartDate)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_GetSupplement(t *testing.T) {
+	t.Run("with adherence", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM supplements WHERE id = \\$1 AND user_id = \\$2").
+			WithArgs(testSupplementID, int64(5)).
+			WillReturnRows(supplementRows(ScheduleDaily, nil, "2026-01-30"))
+		mock.ExpectQuery("FROM supplement_intakes").
+			WithArgs(testSupplementID, "2026-01-04", "2026-02-02").
+			WillReturnRows(sqlmock.NewRows([]string{"date"}).AddRow("2026-01-30").AddRow("2026-02-01"))
+
+		supplement, err := service.GetSupplement(context.Background(), 5, testSupplementID, testToday)
+
+		require.NoError(t, err)
+		require.NotNil(t, supplement.Adherence)
+		assert.Equal(t, "2026-01-30", supplement.Adherence.From)
+		assert.Equal(t, 3, supplement.Adherence.Scheduled)
+		assert.Equal(t, 2, supplement.Adherence.Taken)
+		assert.Equal(t, 66.7, *supplement.Adherence.Percent)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's supplement", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM supplements WHERE id").
+			WithArgs(testSupplementID, int64(7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := service.GetSupplement(context.Background(), 7, testSupplementID, testToday)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, mock := setupTestService(t)
+
+		_, err := service.GetSupplement(context.Background(), 5, "creatine", testToday)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestService_GetChecklist(t *testing.T) {
+	service, mock := setupTestService(t)
+	mock.ExpectQuery("FROM supplements s\\s+LEFT JOIN supplement_intakes").
+		WithArgs(int64(5), "2026-02-02").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "dose", "schedule", "weekdays", "taken_at"}).
+			AddRow("a0000000-0000-4000-8000-000000000001", "Креатин", "5 г", ScheduleDaily, nil, testNow).
+			AddRow("a0000000-0000-4000-8000-000000000002", "Омега-3", "1 капсула", ScheduleWeekdays, "{0,6}", nil).
+			AddRow("a0000000-0000-4000-8000-000000000003", "Витамин D", "2000 МЕ", ScheduleWeekdays, "{1,3,5}", nil))
+
+	checklist, err := service.GetChecklist(context.Background(), 5, testToday)
+
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02-02", checklist.Date)
+	require.Len(t, checklist.Items, 2, "the weekend-only supplement is not due on Monday")
+	assert.Equal(t, "Креатин", checklist.Items[0].Name)
+	assert.True(t, checklist.Items[0].Taken)
+	assert.Equal(t, "Витамин D", checklist.Items[1].Name)
+	assert.False(t, checklist.Items[1].Taken)
+	assert.Nil(t, checklist.Items[1].TakenAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestService_Take(t *testing.T) {
+	t.Run("records today's dose", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM supplements WHERE id").
+			WillReturnRows(supplementRows(ScheduleWeekdays, "{1,4}", "2026-01-26"))
+		mock.ExpectQuery("INSERT INTO supplement_intakes .* ON CONFLICT \\(supplement_id, date\\)").
+			WithArgs(testSupplementID, "2026-02-02").
+			WillReturnRows(sqlmock.NewRows([]string{"taken_at"}).AddRow(testNow))
+
+		item, err := service.Take(context.Background(), 5, testSupplementID, testToday)
+
+		require.NoError(t, err)
+		assert.True(t, item.Taken)
+		assert.Equal(t, testNow, *item.TakenAt)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not scheduled today", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM supplements WHERE id").
+			WillReturnRows(supplementRows(ScheduleWeekdays, "{0,6}", "2026-01-26"))
+
+		_, err := service.Take(context.Background(), 5, testSupplementID, testToday)
+
+		assert.ErrorIs(t, err, ErrNotScheduled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("before the start date", func(t *testing.T) {
+		service, mock := setupTestService(t)
+		mock.ExpectQuery("FROM supplements WHERE id").
+			WillReturnRows(supplementRows(ScheduleDaily, nil, "2026-02-03"))
+
+		_, err := service.Take(context.Background(), 5, testSupplementID, testToday)
+
+		assert.ErrorIs(t, err, ErrNotScheduled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
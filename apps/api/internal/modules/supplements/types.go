@@ -0,0 +1,74 @@
+package supplements
+
+import (
+	"errors"
+	"time"
+)
+
+// Schedules
+const (
+	ScheduleDaily    = "daily"
+	ScheduleWeekdays = "weekdays"
+)
+
+// AdherenceWindowDays is the number of days, today included, adherence is
+// measured over
+const AdherenceWindowDays = 30
+
+// ErrNotScheduled is returned when a dose is marked taken on a day the
+// supplement's schedule does not require one
+var ErrNotScheduled = errors.New("supplement is not scheduled for this day")
+
+// Schedule describes which days require a dose. Weekdays is used by the
+// weekdays schedule only: 0 = Sunday .. 6 = Saturday, as in task
+// recurrence_days.
+type Schedule struct {
+	Type     string `json:"schedule"`
+	Weekdays []int  `json:"weekdays,omitempty"`
+}
+
+// CreateSupplementRequest represents a supplement to define
+type CreateSupplementRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	Dose     string `json:"dose" binding:"required,max=50"`
+	Schedule string `json:"schedule" binding:"required,oneof=daily weekdays"`
+	Weekdays []int  `json:"weekdays"`
+}
+
+// Supplement is a supplement or medication the user takes on a schedule.
+// Adherence is only filled in when a single supplement is requested.
+type Supplement struct {
+	ID     string `json:"id"`
+	UserID int64  `json:"user_id"`
+	Name   string `json:"name"`
+	Dose   string `json:"dose"`
+	Schedule
+	StartDate string     `json:"start_date"`
+	CreatedAt time.Time  `json:"created_at"`
+	Adherence *Adherence `json:"adherence,omitempty"`
+}
+
+// Adherence is the share of scheduled doses taken between From and To.
+// Percent is nil when no dose was scheduled in the window.
+type Adherence struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Scheduled int      `json:"scheduled"`
+	Taken     int      `json:"taken"`
+	Percent   *float64 `json:"percent"`
+}
+
+// ChecklistItem is a supplement due on a day and whether its dose was taken
+type ChecklistItem struct {
+	SupplementID string     `json:"supplement_id"`
+	Name         string     `json:"name"`
+	Dose         string     `json:"dose"`
+	Taken        bool       `json:"taken"`
+	TakenAt      *time.Time `json:"taken_at,omitempty"`
+}
+
+// Checklist is the list of supplements due on a day
+type Checklist struct {
+	Date  string          `json:"date"`
+	Items []ChecklistItem `json:"items"`
+}
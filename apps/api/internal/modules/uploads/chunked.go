@@ -0,0 +1,280 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/google/uuid"
+)
+
+// Chunked uploads split the file into numbered chunks of ChunkSize bytes
+// that may arrive in any order and are assembled when the client asks for
+// it, once all of them are stored. Each chunk carries its own checksum and
+// the completion request the checksum of the whole file.
+
+// CreateChunked starts a chunked upload of the declared size and type
+func (s *Service) CreateChunked(ctx context.Context, userID int64, req CreateRequest) (*Upload, error) {
+	chunkSize := int64(ChunkSize)
+	return s.create(ctx, userID, req, &chunkSize)
+}
+
+// chunkCount is the number of chunks of a chunked upload
+func chunkCount(upload *Upload) int {
+	return int((upload.Size + *upload.ChunkSize - 1) / *upload.ChunkSize)
+}
+
+// chunkRange is the byte range chunk n of a chunked upload must cover
+func chunkRange(upload *Upload, n int) ContentRange {
+	start := int64(n) * *upload.ChunkSize
+	end := start + *upload.ChunkSize
+	if end > upload.Size {
+		end = upload.Size
+	}
+	return ContentRange{Start: start, End: end - 1, Total: upload.Size}
+}
+
+// newProgress lists which chunks of upload are stored at offsets
+func newProgress(upload *Upload, offsets []int64) *Progress {
+	count := chunkCount(upload)
+	received := make([]bool, count)
+	for _, off := range offsets {
+		if n := int(off / *upload.ChunkSize); n < count {
+			received[n] = true
+		}
+	}
+
+	p := &Progress{Upload: upload, ChunkCount: count, Received: []int{}, Missing: []int{}}
+	for n, ok := range received {
+		if ok {
+			p.Received = append(p.Received, n)
+		} else {
+			p.Missing = append(p.Missing, n)
+		}
+	}
+	return p
+}
+
+// lockChunked loads the user's chunked upload in tx and locks it
+func lockChunked(ctx context.Context, tx *sql.Tx, userID int64, uploadID string) (*Upload, error) {
+	upload, err := scanUpload(tx.QueryRowContext(ctx,
+		`SELECT `+uploadColumns+` FROM uploads WHERE id = $1 AND user_id = $2 AND expires_at > NOW() FOR UPDATE`,
+		uploadID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	if upload.ChunkSize == nil {
+		return nil, ErrWrongProtocol
+	}
+	return upload, nil
+}
+
+// PutChunk stores chunk n of a chunked upload. r is the range the client
+// says the chunk covers and must be exactly chunk n's; checksum is the
+// required SHA-256 of the chunk. A chunk that was already stored with the
+// same contents is acknowledged without changes, so retries are safe; a
+// different chunk under the same number is refused.
+func (s *Service) PutChunk(ctx context.Context, userID int64, uploadID string, n int, r ContentRange, checksum []byte, data io.Reader) (*Progress, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(data, MaxChunkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if len(chunk) == 0 {
+		return nil, ErrEmptyChunk
+	}
+	if len(chunk) > MaxChunkSize {
+		return nil, ErrChunkTooLarge
+	}
+	sum := sha256.Sum256(chunk)
+	if !bytes.Equal(checksum, sum[:]) {
+		return nil, ErrChecksumMismatch
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upload, err := lockChunked(ctx, tx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if n < 0 || n >= chunkCount(upload) {
+		return nil, ErrChunkOutOfRange
+	}
+	want := chunkRange(upload, n)
+	if r != want || int64(len(chunk)) != want.End-want.Start+1 {
+		return nil, ErrRangeMismatch
+	}
+
+	var storedSum []byte
+	err = tx.QueryRowContext(ctx,
+		`SELECT sha256 FROM upload_chunks WHERE upload_id = $1 AND offset_bytes = $2`,
+		uploadID, want.Start,
+	).Scan(&storedSum)
+	switch {
+	case err == nil:
+		// A retry of a chunk whose response was lost
+		if !bytes.Equal(storedSum, sum[:]) {
+			return nil, ErrChunkConflict
+		}
+	case errors.Is(err, sql.ErrNoRows):
+		if upload.Status != StatusUploading {
+			return nil, ErrNotActive
+		}
+		if err := s.store.Put(ctx, chunkKey(uploadID, want.Start), bytes.NewReader(chunk)); err != nil {
+			return nil, fmt.Errorf("failed to store chunk: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO upload_chunks (upload_id, offset_bytes, size_bytes, sha256) VALUES ($1, $2, $3, $4)`,
+			uploadID, want.Start, len(chunk), sum[:],
+		); err != nil {
+			return nil, fmt.Errorf("failed to record chunk: %w", err)
+		}
+
+		query := `
+			UPDATE uploads SET offset_bytes = offset_bytes + $2, updated_at = NOW()
+			WHERE id = $1
+			RETURNING offset_bytes
+		`
+		err = tx.QueryRowContext(ctx, query, uploadID, len(chunk)).Scan(&upload.Offset)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+			"upload_id": uploadID,
+			"chunk":     n,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to update upload: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("failed to get upload chunk: %w", err)
+	}
+
+	offsets, err := chunkOffsets(ctx, tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return newProgress(upload, offsets), nil
+}
+
+// Progress returns the user's chunked upload with the chunks received so far
+func (s *Service) Progress(ctx context.Context, userID int64, uploadID string) (*Progress, error) {
+	upload, err := s.Get(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.ChunkSize == nil {
+		return nil, ErrWrongProtocol
+	}
+
+	offsets, err := chunkOffsets(ctx, s.db, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	return newProgress(upload, offsets), nil
+}
+
+// Complete assembles a chunked upload once all its chunks are stored and
+// checks the file against the declared type and checksum, its SHA-256. A
+// file that fails either check fails the upload, which has to be started
+// over. Completing a completed upload again returns it unchanged.
+func (s *Service) Complete(ctx context.Context, userID int64, uploadID string, checksum []byte) (*Upload, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upload, err := lockChunked(ctx, tx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Status == StatusCompleted {
+		return upload, nil
+	}
+	if upload.Status != StatusUploading {
+		return nil, ErrNotActive
+	}
+
+	offsets, err := chunkOffsets(ctx, tx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if len(offsets) < chunkCount(upload) {
+		return nil, ErrIncomplete
+	}
+
+	sum, assembleErr := s.assemble(ctx, upload, offsets)
+	if assembleErr != nil && !errors.Is(assembleErr, ErrContentTypeMismatch) {
+		return nil, assembleErr
+	}
+	if assembleErr == nil && !bytes.Equal(sum, checksum) {
+		s.log.Warn("Upload checksum mismatch", "upload_id", uploadID)
+		if err := s.store.Delete(ctx, dataKey(uploadID)); err != nil {
+			s.log.Error("Failed to delete rejected upload", "error", err, "upload_id", uploadID)
+		}
+		assembleErr = ErrFileChecksumMismatch
+	}
+	if assembleErr == nil {
+		upload.Status = StatusCompleted
+	} else {
+		upload.Status = StatusFailed
+		msg := assembleErr.Error()
+		upload.Error = &msg
+	}
+
+	query := `
+		UPDATE uploads
+		SET status = $2, error = $3, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING completed_at
+	`
+	var completedAt time.Time
+	err = tx.QueryRowContext(ctx, query, uploadID, upload.Status, upload.Error).Scan(&completedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"upload_id": uploadID,
+		"status":    upload.Status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", err)
+	}
+	upload.CompletedAt = &completedAt
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.release(ctx, upload, offsets)
+
+	if assembleErr != nil {
+		return upload, assembleErr
+	}
+	return upload, nil
+}
@@ -0,0 +1,325 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChunkSize keeps chunked test uploads small; the service takes the
+// chunk size from the upload row
+const testChunkSize = 20
+
+func chunkedRow(contentType string, size, received int64, status string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(uploadRowColumns).
+		AddRow(testUploadID, int64(5), contentType, size, received, int64(testChunkSize), status, nil, now, now.Add(UploadTTL), nil)
+}
+
+func offsetRows(offsets ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"offset_bytes"})
+	for _, off := range offsets {
+		rows.AddRow(off)
+	}
+	return rows
+}
+
+// chunkOf returns chunk n of data and its Content-Range
+func chunkOf(data []byte, n int) ([]byte, ContentRange) {
+	start := n * testChunkSize
+	end := min(start+testChunkSize, len(data))
+	return data[start:end], ContentRange{Start: int64(start), End: int64(end - 1), Total: int64(len(data))}
+}
+
+func TestCreateChunked(t *testing.T) {
+	service, mock, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	mock.ExpectQuery("INSERT INTO uploads").
+		WithArgs(int64(5), "image/png", int64(3000000), int64(ChunkSize), int(UploadTTL.Seconds())).
+		WillReturnRows(chunkedRow("image/png", 3000000, 0, StatusUploading))
+
+	upload, err := service.CreateChunked(context.Background(), 5, CreateRequest{Size: 3000000, ContentType: "image/png"})
+
+	require.NoError(t, err)
+	require.NotNil(t, upload.ChunkSize)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChunkedUpload(t *testing.T) {
+	img := pngBytes(t)
+	size := int64(len(img))
+	require.Greater(t, size, int64(2*testChunkSize))
+	count := int((size + testChunkSize - 1) / testChunkSize)
+	last := count - 1
+
+	// put expects the queries of storing a chunk that was not received yet
+	put := func(mock sqlmock.Sqlmock, n int, received int64, offsets ...int64) {
+		chunk, r := chunkOf(img, n)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, received, StatusUploading))
+		mock.ExpectQuery("SELECT sha256 FROM upload_chunks").
+			WithArgs(testUploadID, r.Start).
+			WillReturnRows(sqlmock.NewRows([]string{"sha256"}))
+		mock.ExpectExec("INSERT INTO upload_chunks").
+			WithArgs(testUploadID, r.Start, len(chunk), sha(chunk)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE uploads SET offset_bytes = offset_bytes").
+			WithArgs(testUploadID, len(chunk)).
+			WillReturnRows(sqlmock.NewRows([]string{"offset_bytes"}).AddRow(received + int64(len(chunk))))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(offsets...))
+		mock.ExpectCommit()
+	}
+
+	t.Run("assembles chunks received out of order and twice", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		ctx := context.Background()
+
+		// The last chunk first, then the rest backwards; chunk 0 is resent
+		// after a lost response
+		var offsets []int64
+		var received int64
+		for n := last; n >= 0; n-- {
+			chunk, r := chunkOf(img, n)
+			offsets = append([]int64{r.Start}, offsets...)
+			put(mock, n, received, offsets...)
+			received += int64(len(chunk))
+		}
+		first, _ := chunkOf(img, 0)
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, size, StatusUploading))
+		mock.ExpectQuery("SELECT sha256 FROM upload_chunks").WillReturnRows(sqlmock.NewRows([]string{"sha256"}).AddRow(sha(first)))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(offsets...))
+		mock.ExpectCommit()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, size, StatusUploading))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(offsets...))
+		mock.ExpectQuery("UPDATE uploads").
+			WithArgs(testUploadID, StatusCompleted, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		send := func(n int) (*Progress, error) {
+			chunk, r := chunkOf(img, n)
+			return service.PutChunk(ctx, 5, testUploadID, n, r, sha(chunk), bytes.NewReader(chunk))
+		}
+
+		progress, err := send(last)
+		require.NoError(t, err)
+		assert.Equal(t, []int{last}, progress.Received)
+		assert.Len(t, progress.Missing, last)
+
+		for n := last - 1; n >= 0; n-- {
+			progress, err = send(n)
+			require.NoError(t, err)
+		}
+		assert.Empty(t, progress.Missing)
+
+		progress, err = send(0)
+		require.NoError(t, err, "a resent chunk is acknowledged")
+		assert.Equal(t, count, len(progress.Received))
+
+		upload, err := service.Complete(ctx, 5, testUploadID, sha(img))
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, upload.Status)
+		assert.Equal(t, []string{dataKey(testUploadID)}, store.Keys())
+
+		r, err := store.Get(ctx, dataKey(testUploadID))
+		require.NoError(t, err)
+		assembled, _ := io.ReadAll(r)
+		assert.Equal(t, img, assembled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("refuses a different chunk under a received number", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		chunk, r := chunkOf(img, 1)
+		other := bytes.Repeat([]byte{1}, len(chunk))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, testChunkSize, StatusUploading))
+		mock.ExpectQuery("SELECT sha256 FROM upload_chunks").WillReturnRows(sqlmock.NewRows([]string{"sha256"}).AddRow(sha(chunk)))
+		mock.ExpectRollback()
+
+		_, err := service.PutChunk(context.Background(), 5, testUploadID, 1, r, sha(other), bytes.NewReader(other))
+
+		assert.ErrorIs(t, err, ErrChunkConflict)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("checks the range against the chunk number", func(t *testing.T) {
+		chunk, r := chunkOf(img, 1)
+		tests := []struct {
+			name string
+			n    int
+			r    ContentRange
+			data []byte
+			err  error
+		}{
+			{"range of another chunk", 2, r, chunk, ErrRangeMismatch},
+			{"wrong total", 1, ContentRange{Start: r.Start, End: r.End, Total: size + 1}, chunk, ErrRangeMismatch},
+			{"short body", 1, r, chunk[:5], ErrRangeMismatch},
+			{"past the last chunk", count, r, chunk, ErrChunkOutOfRange},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				service, mock, _, cleanup := setupTestService(t)
+				defer cleanup()
+
+				mock.ExpectBegin()
+				mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, 0, StatusUploading))
+				mock.ExpectRollback()
+
+				_, err := service.PutChunk(context.Background(), 5, testUploadID, tt.n, tt.r, sha(tt.data), bytes.NewReader(tt.data))
+
+				assert.ErrorIs(t, err, tt.err)
+				assert.NoError(t, mock.ExpectationsWereMet())
+			})
+		}
+	})
+
+	t.Run("rejects a chunk that does not match its checksum", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+		chunk, r := chunkOf(img, 0)
+
+		_, err := service.PutChunk(context.Background(), 5, testUploadID, 0, r, sha([]byte("other")), bytes.NewReader(chunk))
+
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+	})
+
+	t.Run("rejects numbered chunks for an offset upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+		chunk, r := chunkOf(img, 0)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, 0, StatusUploading))
+		mock.ExpectRollback()
+
+		_, err := service.PutChunk(context.Background(), 5, testUploadID, 0, r, sha(chunk), bytes.NewReader(chunk))
+
+		assert.ErrorIs(t, err, ErrWrongProtocol)
+	})
+
+	t.Run("rejects an offset chunk for a chunked upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, 0, StatusUploading))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(img[:testChunkSize]))
+
+		assert.ErrorIs(t, err, ErrWrongProtocol)
+	})
+}
+
+func TestProgress(t *testing.T) {
+	service, mock, _, cleanup := setupTestService(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .+ FROM uploads WHERE id = \\$1").WillReturnRows(chunkedRow("image/png", 70, 30, StatusUploading))
+	mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(20, 60))
+
+	progress, err := service.Progress(context.Background(), 5, testUploadID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, progress.ChunkCount)
+	assert.Equal(t, []int{1, 3}, progress.Received)
+	assert.Equal(t, []int{0, 2}, progress.Missing)
+	assert.Equal(t, int64(30), progress.Offset)
+}
+
+func TestComplete(t *testing.T) {
+	img := pngBytes(t)
+	size := int64(len(img))
+	count := int((size + testChunkSize - 1) / testChunkSize)
+
+	// storeChunks puts every chunk of data in store and returns their offsets
+	storeChunks := func(t *testing.T, service *Service, data []byte) []int64 {
+		var offsets []int64
+		for n := 0; n < count; n++ {
+			chunk, r := chunkOf(data, n)
+			require.NoError(t, service.store.Put(context.Background(), chunkKey(testUploadID, r.Start), bytes.NewReader(chunk)))
+			offsets = append(offsets, r.Start)
+		}
+		return offsets
+	}
+
+	t.Run("fails the upload when the file checksum does not match", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		offsets := storeChunks(t, service, img)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, size, StatusUploading))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(offsets...))
+		mock.ExpectQuery("UPDATE uploads").
+			WithArgs(testUploadID, StatusFailed, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		upload, err := service.Complete(context.Background(), 5, testUploadID, sha([]byte("other")))
+
+		assert.ErrorIs(t, err, ErrFileChecksumMismatch)
+		assert.Equal(t, StatusFailed, upload.Status)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("requires every chunk", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, testChunkSize, StatusUploading))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").WillReturnRows(offsetRows(0))
+		mock.ExpectRollback()
+
+		_, err := service.Complete(context.Background(), 5, testUploadID, sha(img))
+
+		assert.ErrorIs(t, err, ErrIncomplete)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("returns a completed upload unchanged", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(chunkedRow("image/png", size, size, StatusCompleted))
+		mock.ExpectRollback()
+
+		upload, err := service.Complete(context.Background(), 5, testUploadID, sha(img))
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, upload.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(sqlmock.NewRows(uploadRowColumns))
+		mock.ExpectRollback()
+
+		_, err := service.Complete(context.Background(), 5, testUploadID, sha(img))
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
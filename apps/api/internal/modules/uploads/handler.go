@@ -0,0 +1,302 @@
+package uploads
+
+import (
+	"encoding/base64"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// statusChecksumMismatch is the tus checksum extension's status for a chunk
+// whose checksum does not match its contents
+const statusChecksumMismatch = 460
+
+// Handler handles resumable upload requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new uploads handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// Create handles POST /api/v1/uploads
+// Body: {"size": 1048576, "content_type": "image/jpeg"}
+func (h *Handler) Create(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, "Неверные данные: требуются size и content_type")
+		return
+	}
+
+	upload, err := h.service.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrUnsupportedType):
+			response.Error(c, http.StatusBadRequest, "Допустимы только JPEG, PNG, WebP и CSV")
+		case errors.Is(err, ErrInvalidSize):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер файла должен быть от 1 байта до 100 МБ")
+		default:
+			h.log.Error("Failed to create upload", "error", err, "user_id", userID)
+			response.InternalError(c, "Не удалось начать загрузку")
+		}
+		return
+	}
+
+	c.Header("Location", "/api/v1/uploads/"+upload.ID)
+	c.Header(HeaderOffset, "0")
+	response.Success(c, http.StatusCreated, upload)
+}
+
+// Get handles GET /api/v1/uploads/:id
+// Returns the upload with its current offset so an interrupted client can resume.
+func (h *Handler) Get(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.service.Get(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Загрузка не найдена")
+			return
+		}
+		h.log.Error("Failed to get upload", "error", err, "user_id", userID, "upload_id", c.Param("id"))
+		response.InternalError(c, "Не удалось получить загрузку")
+		return
+	}
+
+	c.Header(HeaderOffset, strconv.FormatInt(upload.Offset, 10))
+	c.Header("Cache-Control", "no-store")
+	response.Success(c, http.StatusOK, upload)
+}
+
+// Patch handles PATCH /api/v1/uploads/:id
+// Headers: Content-Type: application/offset+octet-stream, Upload-Offset (required),
+// Upload-Checksum: sha256 <base64> (optional). Body: raw chunk bytes.
+func (h *Handler) Patch(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if mediaType != "application/offset+octet-stream" && mediaType != "application/octet-stream" {
+		response.Error(c, http.StatusUnsupportedMediaType, "Content-Type должен быть application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader(HeaderOffset), 10, 64)
+	if err != nil || offset < 0 {
+		response.Error(c, http.StatusBadRequest, "Заголовок Upload-Offset обязателен")
+		return
+	}
+
+	checksum, err := ParseChecksum(c.GetHeader(HeaderChecksum))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Заголовок Upload-Checksum должен иметь вид \"sha256 <base64>\"")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxChunkSize+1)
+
+	upload, err := h.service.AppendChunk(c.Request.Context(), userID, c.Param("id"), offset, checksum, c.Request.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Загрузка не найдена")
+		case errors.Is(err, ErrOffsetMismatch):
+			response.Error(c, http.StatusConflict, "Смещение не совпадает с принятыми данными")
+		case errors.Is(err, ErrWrongProtocol):
+			response.Error(c, http.StatusConflict, "Загрузка принимает только нумерованные части")
+		case errors.Is(err, ErrNotActive):
+			response.Error(c, http.StatusConflict, "Загрузка уже завершена")
+		case errors.Is(err, ErrChecksumMismatch):
+			response.Error(c, statusChecksumMismatch, "Контрольная сумма части не совпадает")
+		case errors.Is(err, ErrChunkTooLarge), errors.As(err, &maxErr):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер части не должен превышать 8 МБ")
+		case errors.Is(err, ErrSizeExceeded):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Часть выходит за объявленный размер файла")
+		case errors.Is(err, ErrEmptyChunk):
+			response.Error(c, http.StatusBadRequest, "Пустая часть файла")
+		case errors.Is(err, ErrContentTypeMismatch):
+			response.Error(c, http.StatusUnsupportedMediaType, "Содержимое файла не соответствует заявленному типу")
+		default:
+			h.log.Error("Failed to append upload chunk", "error", err, "user_id", userID, "upload_id", c.Param("id"))
+			response.InternalError(c, "Не удалось сохранить часть файла")
+		}
+		return
+	}
+
+	c.Header(HeaderOffset, strconv.FormatInt(upload.Offset, 10))
+	response.Success(c, http.StatusOK, upload)
+}
+
+// PutChunk handles PUT /api/v1/photos/uploads/:id/chunks/:n
+// Headers: Content-Range: bytes <start>-<end>/<size> (required),
+// Upload-Checksum: sha256 <base64> (required). Body: raw bytes of chunk n,
+// which starts at n * chunk_size. Returns the upload progress.
+func (h *Handler) PutChunk(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	n, err := strconv.Atoi(c.Param("n"))
+	if err != nil || n < 0 {
+		response.Error(c, http.StatusBadRequest, "Неверный номер части")
+		return
+	}
+
+	r, err := ParseContentRange(c.GetHeader("Content-Range"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "Заголовок Content-Range должен иметь вид \"bytes <start>-<end>/<size>\"")
+		return
+	}
+
+	checksum, err := ParseChecksum(c.GetHeader(HeaderChecksum))
+	if err != nil || checksum == nil {
+		response.Error(c, http.StatusBadRequest, "Заголовок Upload-Checksum должен иметь вид \"sha256 <base64>\"")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxChunkSize+1)
+
+	progress, err := h.service.PutChunk(c.Request.Context(), userID, c.Param("id"), n, r, checksum, c.Request.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Загрузка не найдена")
+		case errors.Is(err, ErrWrongProtocol):
+			response.Error(c, http.StatusConflict, "Загрузка не принимает нумерованные части")
+		case errors.Is(err, ErrChunkOutOfRange):
+			response.Error(c, http.StatusBadRequest, "Номер части выходит за размер файла")
+		case errors.Is(err, ErrRangeMismatch):
+			response.Error(c, http.StatusRequestedRangeNotSatisfiable, "Content-Range не совпадает с границами части")
+		case errors.Is(err, ErrChunkConflict):
+			response.Error(c, http.StatusConflict, "Часть с этим номером уже получена с другим содержимым")
+		case errors.Is(err, ErrNotActive):
+			response.Error(c, http.StatusConflict, "Загрузка уже завершена")
+		case errors.Is(err, ErrChecksumMismatch):
+			response.Error(c, statusChecksumMismatch, "Контрольная сумма части не совпадает")
+		case errors.Is(err, ErrChunkTooLarge), errors.As(err, &maxErr):
+			response.Error(c, http.StatusRequestEntityTooLarge, "Размер части не должен превышать 8 МБ")
+		case errors.Is(err, ErrEmptyChunk):
+			response.Error(c, http.StatusBadRequest, "Пустая часть файла")
+		default:
+			h.log.Error("Failed to put upload chunk", "error", err, "user_id", userID, "upload_id", c.Param("id"), "chunk", n)
+			response.InternalError(c, "Не удалось сохранить часть файла")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusOK, progress)
+}
+
+// Progress handles GET /api/v1/photos/uploads/:id
+// Returns the chunks received and missing so an interrupted client resends
+// only the missing ones.
+func (h *Handler) Progress(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	progress, err := h.service.Progress(c.Request.Context(), userID, c.Param("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Загрузка не найдена")
+		case errors.Is(err, ErrWrongProtocol):
+			response.Error(c, http.StatusConflict, "Загрузка не принимает нумерованные части")
+		default:
+			h.log.Error("Failed to get upload progress", "error", err, "user_id", userID, "upload_id", c.Param("id"))
+			response.InternalError(c, "Не удалось получить загрузку")
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	response.Success(c, http.StatusOK, progress)
+}
+
+// ParseContentRange decodes a "bytes <start>-<end>/<total>" Content-Range header
+func ParseContentRange(header string) (ContentRange, error) {
+	unit, spec, ok := strings.Cut(header, " ")
+	if !ok || unit != "bytes" {
+		return ContentRange{}, ErrRangeMismatch
+	}
+	span, total, ok := strings.Cut(spec, "/")
+	if !ok {
+		return ContentRange{}, ErrRangeMismatch
+	}
+	first, last, ok := strings.Cut(span, "-")
+	if !ok {
+		return ContentRange{}, ErrRangeMismatch
+	}
+
+	var r ContentRange
+	var errs [3]error
+	r.Start, errs[0] = strconv.ParseInt(first, 10, 64)
+	r.End, errs[1] = strconv.ParseInt(last, 10, 64)
+	r.Total, errs[2] = strconv.ParseInt(total, 10, 64)
+	if err := errors.Join(errs[:]...); err != nil || r.Start < 0 || r.End < r.Start || r.Total <= r.End {
+		return ContentRange{}, ErrRangeMismatch
+	}
+	return r, nil
+}
+
+// ParseChecksum decodes an optional "sha256 <base64>" checksum, as sent in
+// the Upload-Checksum header
+func ParseChecksum(header string) ([]byte, error) {
+	if header == "" {
+		return nil, nil
+	}
+	algo, value, ok := strings.Cut(header, " ")
+	if !ok || algo != "sha256" {
+		return nil, ErrInvalidChecksum
+	}
+	sum, err := base64.StdEncoding.DecodeString(value)
+	if err != nil || len(sum) != 32 {
+		return nil, ErrInvalidChecksum
+	}
+	return sum, nil
+}
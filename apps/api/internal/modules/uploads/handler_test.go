@@ -0,0 +1,239 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	offset   int64
+	checksum []byte
+	chunk    int
+	r        ContentRange
+	err      error
+}
+
+func (m *mockService) Create(ctx context.Context, userID int64, req CreateRequest) (*Upload, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Upload{ID: testUploadID, ContentType: req.ContentType, Size: req.Size, Status: StatusUploading}, nil
+}
+
+func (m *mockService) Get(ctx context.Context, userID int64, uploadID string) (*Upload, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Upload{ID: uploadID, Offset: 42, Status: StatusUploading}, nil
+}
+
+func (m *mockService) AppendChunk(ctx context.Context, userID int64, uploadID string, offset int64, checksum []byte, data io.Reader) (*Upload, error) {
+	m.offset, m.checksum = offset, checksum
+	if m.err != nil {
+		return nil, m.err
+	}
+	n, _ := io.Copy(io.Discard, data)
+	return &Upload{ID: uploadID, Offset: offset + n, Status: StatusUploading}, nil
+}
+
+func (m *mockService) PutChunk(ctx context.Context, userID int64, uploadID string, n int, r ContentRange, checksum []byte, data io.Reader) (*Progress, error) {
+	m.chunk, m.r, m.checksum = n, r, checksum
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Progress{Upload: &Upload{ID: uploadID}, ChunkCount: 2, Received: []int{n}, Missing: []int{1 - n}}, nil
+}
+
+func (m *mockService) Progress(ctx context.Context, userID int64, uploadID string) (*Progress, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Progress{Upload: &Upload{ID: uploadID}, ChunkCount: 2, Received: []int{1}, Missing: []int{0}}, nil
+}
+
+func (m *mockService) Take(ctx context.Context, userID int64, uploadID string) (*Upload, io.ReadCloser, error) {
+	return nil, nil, m.err
+}
+
+func newTestContext(req *http.Request) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: testUploadID}}
+	c.Set("user_id", int64(5))
+	return c, w
+}
+
+func newPatchRequest(body string, headers map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/uploads/"+testUploadID, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func TestHandlerCreate(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	req := httptest.NewRequest(http.MethodPost, "/uploads", bytes.NewBufferString(`{"size":1000,"content_type":"image/jpeg"}`))
+	req.Header.Set("Content-Type", "application/json")
+	c, w := newTestContext(req)
+
+	handler.Create(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/api/v1/uploads/"+testUploadID, w.Header().Get("Location"))
+	assert.Equal(t, "0", w.Header().Get(HeaderOffset))
+}
+
+func TestHandlerGet(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/uploads/"+testUploadID, nil))
+
+	handler.Get(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "42", w.Header().Get(HeaderOffset))
+}
+
+func TestHandlerPatch(t *testing.T) {
+	sum := sha256.Sum256([]byte("chunk"))
+
+	t.Run("passes offset and checksum to the service", func(t *testing.T) {
+		svc := &mockService{}
+		handler := NewHandler(nil, logger.New(), svc)
+		c, w := newTestContext(newPatchRequest("chunk", map[string]string{
+			HeaderOffset:   "100",
+			HeaderChecksum: "sha256 " + base64.StdEncoding.EncodeToString(sum[:]),
+		}))
+
+		handler.Patch(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, int64(100), svc.offset)
+		assert.Equal(t, sum[:], svc.checksum)
+		assert.Equal(t, "105", w.Header().Get(HeaderOffset))
+	})
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		err     error
+		status  int
+	}{
+		{"missing offset", map[string]string{}, nil, http.StatusBadRequest},
+		{"wrong content type", map[string]string{HeaderOffset: "0", "Content-Type": "image/png"}, nil, http.StatusUnsupportedMediaType},
+		{"unsupported checksum algorithm", map[string]string{HeaderOffset: "0", HeaderChecksum: "md5 abc"}, nil, http.StatusBadRequest},
+		{"offset mismatch", map[string]string{HeaderOffset: "0"}, ErrOffsetMismatch, http.StatusConflict},
+		{"checksum mismatch", map[string]string{HeaderOffset: "0"}, ErrChecksumMismatch, statusChecksumMismatch},
+		{"content type mismatch on completion", map[string]string{HeaderOffset: "0"}, ErrContentTypeMismatch, http.StatusUnsupportedMediaType},
+		{"unknown upload", map[string]string{HeaderOffset: "0"}, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(newPatchRequest("chunk", tt.headers))
+
+			handler.Patch(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func newChunkRequest(n string, body string, headers map[string]string) (*gin.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPut, "/photos/uploads/"+testUploadID+"/chunks/"+n, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	c, w := newTestContext(req)
+	c.Params = append(c.Params, gin.Param{Key: "n", Value: n})
+	return c, w
+}
+
+func TestHandlerPutChunk(t *testing.T) {
+	sum := sha256.Sum256([]byte("chunk"))
+	checksum := "sha256 " + base64.StdEncoding.EncodeToString(sum[:])
+
+	t.Run("passes number, range and checksum to the service", func(t *testing.T) {
+		svc := &mockService{}
+		handler := NewHandler(nil, logger.New(), svc)
+		c, w := newChunkRequest("1", "chunk", map[string]string{
+			"Content-Range": "bytes 1048576-1048580/1048581",
+			HeaderChecksum:  checksum,
+		})
+
+		handler.PutChunk(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, 1, svc.chunk)
+		assert.Equal(t, ContentRange{Start: 1048576, End: 1048580, Total: 1048581}, svc.r)
+		assert.Equal(t, sum[:], svc.checksum)
+		assert.Contains(t, w.Body.String(), `"received_chunks":[1]`)
+	})
+
+	valid := map[string]string{"Content-Range": "bytes 0-4/5", HeaderChecksum: checksum}
+	tests := []struct {
+		name    string
+		n       string
+		headers map[string]string
+		err     error
+		status  int
+	}{
+		{"bad chunk number", "x", valid, nil, http.StatusBadRequest},
+		{"missing range", "0", map[string]string{HeaderChecksum: checksum}, nil, http.StatusBadRequest},
+		{"missing checksum", "0", map[string]string{"Content-Range": "bytes 0-4/5"}, nil, http.StatusBadRequest},
+		{"range mismatch", "0", valid, ErrRangeMismatch, http.StatusRequestedRangeNotSatisfiable},
+		{"conflicting duplicate", "0", valid, ErrChunkConflict, http.StatusConflict},
+		{"checksum mismatch", "0", valid, ErrChecksumMismatch, statusChecksumMismatch},
+		{"offset upload", "0", valid, ErrWrongProtocol, http.StatusConflict},
+		{"unknown upload", "0", valid, apperrors.ErrNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newChunkRequest(tt.n, "chunk", tt.headers)
+
+			handler.PutChunk(c)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestHandlerProgress(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/photos/uploads/"+testUploadID, nil))
+
+	handler.Progress(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"missing_chunks":[0]`)
+}
+
+func TestParseContentRange(t *testing.T) {
+	r, err := ParseContentRange("bytes 0-1048575/3000000")
+	assert.NoError(t, err)
+	assert.Equal(t, ContentRange{Start: 0, End: 1048575, Total: 3000000}, r)
+
+	for _, header := range []string{"", "0-4/5", "items 0-4/5", "bytes 0-4/*", "bytes 4-0/5", "bytes 0-5/5", "bytes -1-4/5"} {
+		_, err := ParseContentRange(header)
+		assert.Error(t, err, header)
+	}
+}
@@ -0,0 +1,472 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/google/uuid"
+)
+
+// sniffLen is how many leading bytes http.DetectContentType looks at
+const sniffLen = 512
+
+const uploadColumns = `id, user_id, content_type, size_bytes, offset_bytes, chunk_size, status, error,
+		created_at, expires_at, completed_at`
+
+// Source hands completed uploads to the endpoints that consume them
+// (progress photos, avatars, imports)
+type Source interface {
+	// Take marks a completed upload as consumed and opens its contents.
+	// An upload can only be taken once.
+	Take(ctx context.Context, userID int64, uploadID string) (*Upload, io.ReadCloser, error)
+}
+
+// ChunkedSource is what consumers of chunked uploads use: they start an
+// upload of their own kind of file and complete it before taking it
+type ChunkedSource interface {
+	Source
+	CreateChunked(ctx context.Context, userID int64, req CreateRequest) (*Upload, error)
+	Complete(ctx context.Context, userID int64, uploadID string, checksum []byte) (*Upload, error)
+}
+
+// ServiceInterface defines the interface for resumable upload operations
+type ServiceInterface interface {
+	Source
+	Create(ctx context.Context, userID int64, req CreateRequest) (*Upload, error)
+	Get(ctx context.Context, userID int64, uploadID string) (*Upload, error)
+	AppendChunk(ctx context.Context, userID int64, uploadID string, offset int64, checksum []byte, data io.Reader) (*Upload, error)
+	PutChunk(ctx context.Context, userID int64, uploadID string, n int, r ContentRange, checksum []byte, data io.Reader) (*Progress, error)
+	Progress(ctx context.Context, userID int64, uploadID string) (*Progress, error)
+}
+
+// Service stores upload chunks and assembles them once all bytes arrived
+type Service struct {
+	db    *database.DB
+	log   *logger.Logger
+	store storage.Storage
+}
+
+// NewService creates a new uploads service
+func NewService(db *database.DB, log *logger.Logger, store storage.Storage) *Service {
+	return &Service{
+		db:    db,
+		log:   log,
+		store: store,
+	}
+}
+
+// chunkKey is the storage key of the chunk starting at offset
+func chunkKey(uploadID string, offset int64) string {
+	return fmt.Sprintf("uploads/%s/chunks/%012d", uploadID, offset)
+}
+
+// dataKey is the storage key of the assembled file
+func dataKey(uploadID string) string {
+	return fmt.Sprintf("uploads/%s/data", uploadID)
+}
+
+// Create starts an upload of the declared size and type
+func (s *Service) Create(ctx context.Context, userID int64, req CreateRequest) (*Upload, error) {
+	return s.create(ctx, userID, req, nil)
+}
+
+// create starts an upload; chunkSize is set for chunked uploads
+func (s *Service) create(ctx context.Context, userID int64, req CreateRequest, chunkSize *int64) (*Upload, error) {
+	startTime := time.Now()
+
+	if _, ok := allowedContentTypes[req.ContentType]; !ok {
+		return nil, ErrUnsupportedType
+	}
+	if req.Size <= 0 || req.Size > MaxUploadSize {
+		return nil, ErrInvalidSize
+	}
+
+	query := `
+		INSERT INTO uploads (user_id, content_type, size_bytes, chunk_size, expires_at)
+		VALUES ($1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second')
+		RETURNING ` + uploadColumns
+
+	upload, err := scanUpload(s.db.QueryRowContext(ctx, query, userID, req.ContentType, req.Size, chunkSize, int(UploadTTL.Seconds())))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+
+	s.log.LogBusinessEvent("upload_created", map[string]interface{}{
+		"user_id":      userID,
+		"upload_id":    upload.ID,
+		"content_type": req.ContentType,
+		"size_bytes":   req.Size,
+	})
+
+	return upload, nil
+}
+
+// Get returns the user's upload; clients use it to find the offset to resume from
+func (s *Service) Get(ctx context.Context, userID int64, uploadID string) (*Upload, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	query := `SELECT ` + uploadColumns + ` FROM uploads WHERE id = $1 AND user_id = $2 AND expires_at > NOW()`
+
+	upload, err := scanUpload(s.db.QueryRowContext(ctx, query, uploadID, userID))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":   userID,
+		"upload_id": uploadID,
+	})
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+
+	return upload, nil
+}
+
+// AppendChunk stores the chunk starting at offset. The offset must equal the
+// bytes received so far; a retried chunk that was already stored (same offset,
+// size and checksum) is acknowledged without changes. checksum is the optional
+// client-provided SHA-256 of the chunk. When the last byte arrives the chunks
+// are assembled and the content is checked against the declared type.
+func (s *Service) AppendChunk(ctx context.Context, userID int64, uploadID string, offset int64, checksum []byte, data io.Reader) (*Upload, error) {
+	startTime := time.Now()
+
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	chunk, err := io.ReadAll(io.LimitReader(data, MaxChunkSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if len(chunk) == 0 {
+		return nil, ErrEmptyChunk
+	}
+	if len(chunk) > MaxChunkSize {
+		return nil, ErrChunkTooLarge
+	}
+	sum := sha256.Sum256(chunk)
+	if checksum != nil && !bytes.Equal(checksum, sum[:]) {
+		return nil, ErrChecksumMismatch
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upload, err := scanUpload(tx.QueryRowContext(ctx,
+		`SELECT `+uploadColumns+` FROM uploads WHERE id = $1 AND user_id = $2 AND expires_at > NOW() FOR UPDATE`,
+		uploadID, userID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	if upload.ChunkSize != nil {
+		return nil, ErrWrongProtocol
+	}
+
+	if offset < upload.Offset {
+		// A retry of a chunk whose response was lost
+		var storedSize int64
+		var storedSum []byte
+		err := tx.QueryRowContext(ctx,
+			`SELECT size_bytes, sha256 FROM upload_chunks WHERE upload_id = $1 AND offset_bytes = $2`,
+			uploadID, offset,
+		).Scan(&storedSize, &storedSum)
+		if err == sql.ErrNoRows {
+			return nil, ErrOffsetMismatch
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get upload chunk: %w", err)
+		}
+		if storedSize != int64(len(chunk)) || !bytes.Equal(storedSum, sum[:]) {
+			return nil, ErrOffsetMismatch
+		}
+		return upload, nil
+	}
+	if upload.Status != StatusUploading {
+		return nil, ErrNotActive
+	}
+	if offset > upload.Offset {
+		return nil, ErrOffsetMismatch
+	}
+	if offset+int64(len(chunk)) > upload.Size {
+		return nil, ErrSizeExceeded
+	}
+
+	if err := s.store.Put(ctx, chunkKey(uploadID, offset), bytes.NewReader(chunk)); err != nil {
+		return nil, fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO upload_chunks (upload_id, offset_bytes, size_bytes, sha256) VALUES ($1, $2, $3, $4)`,
+		uploadID, offset, len(chunk), sum[:],
+	); err != nil {
+		return nil, fmt.Errorf("failed to record chunk: %w", err)
+	}
+	upload.Offset = offset + int64(len(chunk))
+
+	var offsets []int64
+	var assembleErr error
+	if upload.Offset == upload.Size {
+		offsets, err = chunkOffsets(ctx, tx, uploadID)
+		if err != nil {
+			return nil, err
+		}
+		_, assembleErr = s.assemble(ctx, upload, offsets)
+		if assembleErr != nil && !errors.Is(assembleErr, ErrContentTypeMismatch) {
+			return nil, assembleErr
+		}
+		if assembleErr == nil {
+			upload.Status = StatusCompleted
+		} else {
+			upload.Status = StatusFailed
+			msg := assembleErr.Error()
+			upload.Error = &msg
+		}
+	}
+
+	query := `
+		UPDATE uploads
+		SET offset_bytes = $2, status = $3, error = $4, updated_at = NOW(),
+		    completed_at = CASE WHEN $3 = 'uploading' THEN NULL ELSE NOW() END
+		WHERE id = $1
+		RETURNING completed_at
+	`
+	var completedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, query, uploadID, upload.Offset, upload.Status, upload.Error).Scan(&completedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"upload_id": uploadID,
+		"offset":    upload.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", err)
+	}
+	if completedAt.Valid {
+		upload.CompletedAt = &completedAt.Time
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if upload.Status != StatusUploading {
+		s.release(ctx, upload, offsets)
+	}
+
+	if assembleErr != nil {
+		return upload, assembleErr
+	}
+	return upload, nil
+}
+
+// release deletes the chunks of an assembled (or rejected) upload, which
+// are no longer needed, and records the outcome
+func (s *Service) release(ctx context.Context, upload *Upload, offsets []int64) {
+	for _, off := range offsets {
+		if err := s.store.Delete(ctx, chunkKey(upload.ID, off)); err != nil {
+			s.log.Error("Failed to delete upload chunk", "error", err, "upload_id", upload.ID, "offset", off)
+		}
+	}
+	s.log.LogBusinessEvent("upload_"+upload.Status, map[string]interface{}{
+		"user_id":    upload.UserID,
+		"upload_id":  upload.ID,
+		"size_bytes": upload.Size,
+	})
+}
+
+// assemble concatenates the chunks into the data object, verifies the
+// detected content type and returns the SHA-256 of the file. On mismatch
+// the data object is removed and ErrContentTypeMismatch is returned.
+func (s *Service) assemble(ctx context.Context, upload *Upload, offsets []int64) ([]byte, error) {
+	keys := make([]string, len(offsets))
+	for i, off := range offsets {
+		keys[i] = chunkKey(upload.ID, off)
+	}
+
+	head := &headBuffer{limit: sniffLen}
+	hash := sha256.New()
+	r := &chunkReader{ctx: ctx, store: s.store, keys: keys}
+	defer r.Close()
+
+	if err := s.store.Put(ctx, dataKey(upload.ID), io.TeeReader(r, io.MultiWriter(head, hash))); err != nil {
+		return nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+
+	detected := http.DetectContentType(head.buf)
+	for _, ok := range allowedContentTypes[upload.ContentType] {
+		if detected == ok {
+			return hash.Sum(nil), nil
+		}
+	}
+
+	s.log.Warn("Upload content does not match declared type",
+		"upload_id", upload.ID, "declared", upload.ContentType, "detected", detected)
+	if err := s.store.Delete(ctx, dataKey(upload.ID)); err != nil {
+		s.log.Error("Failed to delete rejected upload", "error", err, "upload_id", upload.ID)
+	}
+	return nil, ErrContentTypeMismatch
+}
+
+// Take marks a completed upload as consumed and opens the assembled file
+func (s *Service) Take(ctx context.Context, userID int64, uploadID string) (*Upload, io.ReadCloser, error) {
+	upload, err := s.Get(ctx, userID, uploadID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if upload.Status != StatusCompleted {
+		return nil, nil, ErrNotCompleted
+	}
+
+	startTime := time.Now()
+	query := `UPDATE uploads SET status = 'consumed', updated_at = NOW() WHERE id = $1 AND status = 'completed'`
+	res, err := s.db.ExecContext(ctx, query, uploadID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":   userID,
+		"upload_id": uploadID,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to consume upload: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		// Taken concurrently
+		return nil, nil, ErrNotCompleted
+	}
+	upload.Status = StatusConsumed
+
+	r, err := s.store.Get(ctx, dataKey(uploadID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open upload: %w", err)
+	}
+
+	return upload, r, nil
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// chunkOffsets lists the upload's chunk offsets in order
+func chunkOffsets(ctx context.Context, q querier, uploadID string) ([]int64, error) {
+	rows, err := q.QueryContext(ctx,
+		`SELECT offset_bytes FROM upload_chunks WHERE upload_id = $1 ORDER BY offset_bytes`, uploadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var offsets []int64
+	for rows.Next() {
+		var off int64
+		if err := rows.Scan(&off); err != nil {
+			return nil, fmt.Errorf("failed to scan upload chunk: %w", err)
+		}
+		offsets = append(offsets, off)
+	}
+	return offsets, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanUpload(row rowScanner) (*Upload, error) {
+	var u Upload
+	var errMsg sql.NullString
+	var chunkSize sql.NullInt64
+	var completedAt sql.NullTime
+
+	if err := row.Scan(&u.ID, &u.UserID, &u.ContentType, &u.Size, &u.Offset, &chunkSize, &u.Status, &errMsg,
+		&u.CreatedAt, &u.ExpiresAt, &completedAt); err != nil {
+		return nil, err
+	}
+	if chunkSize.Valid {
+		u.ChunkSize = &chunkSize.Int64
+	}
+	if errMsg.Valid {
+		u.Error = &errMsg.String
+	}
+	if completedAt.Valid {
+		u.CompletedAt = &completedAt.Time
+	}
+	return &u, nil
+}
+
+// chunkReader reads stored chunks back to back, opening each one lazily
+type chunkReader struct {
+	ctx     context.Context
+	store   storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.keys) == 0 {
+				return 0, io.EOF
+			}
+			rc, err := r.store.Get(r.ctx, r.keys[0])
+			if err != nil {
+				return 0, fmt.Errorf("failed to open chunk %s: %w", r.keys[0], err)
+			}
+			r.current, r.keys = rc, r.keys[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		return n, err
+	}
+}
+
+func (r *chunkReader) Close() error {
+	if r.current != nil {
+		return r.current.Close()
+	}
+	return nil
+}
+
+// headBuffer keeps the first limit bytes written to it
+type headBuffer struct {
+	buf   []byte
+	limit int
+}
+
+func (h *headBuffer) Write(p []byte) (int, error) {
+	if room := h.limit - len(h.buf); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		h.buf = append(h.buf, p[:room]...)
+	}
+	return len(p), nil
+}
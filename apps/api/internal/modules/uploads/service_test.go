@@ -0,0 +1,361 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"image"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUploadID = "7b0e3a52-5d0f-4a4e-9a55-0d6f5b1c2a10"
+
+var uploadRowColumns = []string{"id", "user_id", "content_type", "size_bytes", "offset_bytes", "chunk_size", "status", "error",
+	"created_at", "expires_at", "completed_at"}
+
+func setupTestService(t *testing.T) (*Service, sqlmock.Sqlmock, *storage.MemoryStorage, func()) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	store := storage.NewMemoryStorage()
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), store)
+
+	return service, mock, store, func() { mockDB.Close() }
+}
+
+// pngBytes returns a small valid PNG image
+func pngBytes(t *testing.T) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))))
+	return buf.Bytes()
+}
+
+func uploadRow(contentType string, size, offset int64, status string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(uploadRowColumns).
+		AddRow(testUploadID, int64(5), contentType, size, offset, nil, status, nil, now, now.Add(UploadTTL), nil)
+}
+
+func sha(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("creates upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("INSERT INTO uploads").
+			WithArgs(int64(5), "image/jpeg", int64(1000), nil, int(UploadTTL.Seconds())).
+			WillReturnRows(uploadRow("image/jpeg", 1000, 0, StatusUploading))
+
+		upload, err := service.Create(context.Background(), 5, CreateRequest{Size: 1000, ContentType: "image/jpeg"})
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), upload.Offset)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects unsupported type", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.Create(context.Background(), 5, CreateRequest{Size: 1000, ContentType: "application/zip"})
+
+		assert.ErrorIs(t, err, ErrUnsupportedType)
+	})
+
+	t.Run("rejects oversized upload", func(t *testing.T) {
+		service, _, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.Create(context.Background(), 5, CreateRequest{Size: MaxUploadSize + 1, ContentType: "image/png"})
+
+		assert.ErrorIs(t, err, ErrInvalidSize)
+	})
+}
+
+func TestAppendChunk(t *testing.T) {
+	img := pngBytes(t)
+	size := int64(len(img))
+	first, rest := img[:20], img[20:]
+
+	t.Run("appends chunk at current offset", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .+ FROM uploads WHERE id = \\$1 AND user_id = \\$2 .+ FOR UPDATE").
+			WithArgs(testUploadID, int64(5)).
+			WillReturnRows(uploadRow("image/png", size, 0, StatusUploading))
+		mock.ExpectExec("INSERT INTO upload_chunks").
+			WithArgs(testUploadID, int64(0), len(first), sha(first)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE uploads").
+			WithArgs(testUploadID, int64(20), StatusUploading, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(nil))
+		mock.ExpectCommit()
+
+		upload, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, sha(first), bytes.NewReader(first))
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), upload.Offset)
+		assert.Equal(t, StatusUploading, upload.Status)
+		assert.Equal(t, []string{chunkKey(testUploadID, 0)}, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects out-of-order chunk", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, 0, StatusUploading))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 20, nil, bytes.NewReader(rest))
+
+		assert.ErrorIs(t, err, ErrOffsetMismatch)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("acknowledges duplicate chunk without storing it again", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, 20, StatusUploading))
+		mock.ExpectQuery("SELECT size_bytes, sha256 FROM upload_chunks").
+			WithArgs(testUploadID, int64(0)).
+			WillReturnRows(sqlmock.NewRows([]string{"size_bytes", "sha256"}).AddRow(int64(20), sha(first)))
+		mock.ExpectRollback()
+
+		upload, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(first))
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(20), upload.Offset)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects different data at an already received offset", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, 20, StatusUploading))
+		mock.ExpectQuery("SELECT size_bytes, sha256 FROM upload_chunks").
+			WillReturnRows(sqlmock.NewRows([]string{"size_bytes", "sha256"}).AddRow(int64(20), sha(first)))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(rest[:20]))
+
+		assert.ErrorIs(t, err, ErrOffsetMismatch)
+	})
+
+	t.Run("rejects checksum mismatch before touching the upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, sha(rest), bytes.NewReader(first))
+
+		assert.ErrorIs(t, err, ErrChecksumMismatch)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects chunk past the declared size", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", 10, 0, StatusUploading))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(first))
+
+		assert.ErrorIs(t, err, ErrSizeExceeded)
+	})
+
+	t.Run("assembles and verifies the last chunk", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		require.NoError(t, store.Put(context.Background(), chunkKey(testUploadID, 0), bytes.NewReader(first)))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, 20, StatusUploading))
+		mock.ExpectExec("INSERT INTO upload_chunks").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").
+			WillReturnRows(sqlmock.NewRows([]string{"offset_bytes"}).AddRow(int64(0)).AddRow(int64(20)))
+		mock.ExpectQuery("UPDATE uploads").
+			WithArgs(testUploadID, size, StatusCompleted, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		upload, err := service.AppendChunk(context.Background(), 5, testUploadID, 20, nil, bytes.NewReader(rest))
+
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, upload.Status)
+		assert.NotNil(t, upload.CompletedAt)
+		assert.Equal(t, []string{dataKey(testUploadID)}, store.Keys())
+
+		r, err := store.Get(context.Background(), dataKey(testUploadID))
+		require.NoError(t, err)
+		assembled, _ := io.ReadAll(r)
+		assert.Equal(t, img, assembled)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fails upload whose content does not match the declared type", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/jpeg", size, 0, StatusUploading))
+		mock.ExpectExec("INSERT INTO upload_chunks").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").
+			WillReturnRows(sqlmock.NewRows([]string{"offset_bytes"}).AddRow(int64(0)))
+		mock.ExpectQuery("UPDATE uploads").
+			WithArgs(testUploadID, size, StatusFailed, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"completed_at"}).AddRow(time.Now()))
+		mock.ExpectCommit()
+
+		upload, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(img))
+
+		assert.ErrorIs(t, err, ErrContentTypeMismatch)
+		assert.Equal(t, StatusFailed, upload.Status)
+		assert.Empty(t, store.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects chunks for a completed upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(uploadRow("image/png", size, size, StatusCompleted))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, size, nil, bytes.NewReader(first))
+
+		assert.ErrorIs(t, err, ErrNotActive)
+	})
+
+	t.Run("unknown upload", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery("FOR UPDATE").WillReturnRows(sqlmock.NewRows(uploadRowColumns))
+		mock.ExpectRollback()
+
+		_, err := service.AppendChunk(context.Background(), 5, testUploadID, 0, nil, bytes.NewReader(first))
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+func TestTake(t *testing.T) {
+	t.Run("consumes completed upload once", func(t *testing.T) {
+		service, mock, store, cleanup := setupTestService(t)
+		defer cleanup()
+		require.NoError(t, store.Put(context.Background(), dataKey(testUploadID), bytes.NewReader([]byte("data"))))
+
+		mock.ExpectQuery("SELECT .+ FROM uploads").WillReturnRows(uploadRow("image/png", 4, 4, StatusCompleted))
+		mock.ExpectExec("UPDATE uploads SET status = 'consumed'").
+			WithArgs(testUploadID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		upload, r, err := service.Take(context.Background(), 5, testUploadID)
+
+		require.NoError(t, err)
+		defer r.Close()
+		assert.Equal(t, StatusConsumed, upload.Status)
+		data, _ := io.ReadAll(r)
+		assert.Equal(t, "data", string(data))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rejects upload still in progress", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .+ FROM uploads").WillReturnRows(uploadRow("image/png", 4, 2, StatusUploading))
+
+		_, _, err := service.Take(context.Background(), 5, testUploadID)
+
+		assert.ErrorIs(t, err, ErrNotCompleted)
+	})
+
+	t.Run("rejects upload taken concurrently", func(t *testing.T) {
+		service, mock, _, cleanup := setupTestService(t)
+		defer cleanup()
+
+		mock.ExpectQuery("SELECT .+ FROM uploads").WillReturnRows(uploadRow("image/png", 4, 4, StatusCompleted))
+		mock.ExpectExec("UPDATE uploads SET status = 'consumed'").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		_, _, err := service.Take(context.Background(), 5, testUploadID)
+
+		assert.ErrorIs(t, err, ErrNotCompleted)
+	})
+}
+
+func TestCleanupExpired(t *testing.T) {
+	service, mock, store, cleanup := setupTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, chunkKey(testUploadID, 0), bytes.NewReader([]byte("a"))))
+	require.NoError(t, store.Put(ctx, chunkKey(testUploadID, 1), bytes.NewReader([]byte("b"))))
+	require.NoError(t, store.Put(ctx, "uploads/other/data", bytes.NewReader([]byte("keep"))))
+
+	mock.ExpectQuery("SELECT id FROM uploads").
+		WithArgs(int(consumedRetention.Seconds()), cleanupBatchSize).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(testUploadID))
+	mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").
+		WithArgs(testUploadID).
+		WillReturnRows(sqlmock.NewRows([]string{"offset_bytes"}).AddRow(int64(0)).AddRow(int64(1)))
+	mock.ExpectExec("DELETE FROM uploads").WithArgs(testUploadID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	removed, err := service.CleanupExpired(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []string{"uploads/other/data"}, store.Keys())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeUser(t *testing.T) {
+	service, mock, store, cleanup := setupTestService(t)
+	defer cleanup()
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, dataKey(testUploadID), bytes.NewReader([]byte("jpeg"))))
+	require.NoError(t, store.Put(ctx, chunkKey(testUploadID, 0), bytes.NewReader([]byte("a"))))
+	require.NoError(t, store.Put(ctx, "uploads/other/data", bytes.NewReader([]byte("keep"))))
+
+	mock.ExpectQuery("SELECT id FROM uploads WHERE user_id = \\$1").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(testUploadID))
+	mock.ExpectQuery("SELECT offset_bytes FROM upload_chunks").
+		WithArgs(testUploadID).
+		WillReturnRows(sqlmock.NewRows([]string{"offset_bytes"}).AddRow(int64(0)))
+	mock.ExpectExec("DELETE FROM uploads").WithArgs(testUploadID).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	removed, err := service.PurgeUser(ctx, 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Equal(t, []string{"uploads/other/data"}, store.Keys())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
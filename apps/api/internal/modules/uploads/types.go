@@ -0,0 +1,99 @@
+package uploads
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// MaxUploadSize is the largest upload that can be declared (100 MB)
+	MaxUploadSize = 100 * 1024 * 1024
+	// MaxChunkSize is the largest chunk accepted by a single PATCH (8 MB)
+	MaxChunkSize = 8 * 1024 * 1024
+	// ChunkSize is the size of the numbered chunks of a chunked upload (1 MB);
+	// only the last chunk may be shorter
+	ChunkSize = 1024 * 1024
+	// UploadTTL is how long an upload lives before the cleanup job removes it
+	UploadTTL = 24 * time.Hour
+)
+
+// Upload statuses
+const (
+	StatusUploading = "uploading"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusConsumed  = "consumed"
+)
+
+// Protocol headers (tus core + checksum extension)
+const (
+	HeaderOffset   = "Upload-Offset"
+	HeaderChecksum = "Upload-Checksum"
+)
+
+var (
+	ErrUnsupportedType      = errors.New("unsupported content type")
+	ErrInvalidSize          = errors.New("upload size must be between 1 byte and 100 MB")
+	ErrOffsetMismatch       = errors.New("chunk offset does not match upload offset")
+	ErrChunkTooLarge        = errors.New("chunk exceeds 8 MB")
+	ErrEmptyChunk           = errors.New("chunk is empty")
+	ErrSizeExceeded         = errors.New("chunk extends past the declared upload size")
+	ErrChecksumMismatch     = errors.New("chunk checksum mismatch")
+	ErrInvalidChecksum      = errors.New("checksum must be \"sha256 <base64>\"")
+	ErrContentTypeMismatch  = errors.New("uploaded content does not match the declared type")
+	ErrNotActive            = errors.New("upload is not accepting chunks")
+	ErrNotCompleted         = errors.New("upload is not completed")
+	ErrWrongProtocol        = errors.New("upload does not take chunks this way")
+	ErrChunkOutOfRange      = errors.New("chunk number is out of range")
+	ErrRangeMismatch        = errors.New("content range does not match the chunk")
+	ErrChunkConflict        = errors.New("a different chunk with this number was already received")
+	ErrIncomplete           = errors.New("upload is missing chunks")
+	ErrFileChecksumMismatch = errors.New("assembled file checksum mismatch")
+)
+
+// allowedContentTypes maps declared content types to the types
+// http.DetectContentType must report for the assembled file
+var allowedContentTypes = map[string][]string{
+	"image/jpeg": {"image/jpeg"},
+	"image/png":  {"image/png"},
+	"image/webp": {"image/webp"},
+	"text/csv":   {"text/plain; charset=utf-8"},
+}
+
+// Upload is a resumable upload session
+type Upload struct {
+	ID          string     `json:"id"`
+	UserID      int64      `json:"user_id"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	Offset      int64      `json:"offset"`
+	ChunkSize   *int64     `json:"chunk_size,omitempty"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// CreateRequest is the body of POST /api/v1/uploads
+type CreateRequest struct {
+	Size        int64  `json:"size" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+}
+
+// ContentRange is the byte range a chunk claims to cover, from its
+// "bytes start-end/total" Content-Range header; End is inclusive
+type ContentRange struct {
+	Start int64
+	End   int64
+	Total int64
+}
+
+// Progress is a chunked upload with the numbers of the chunks received so
+// far, so an interrupted client resends only the missing ones
+type Progress struct {
+	*Upload
+	ChunkCount int   `json:"chunk_count"`
+	Received   []int `json:"received_chunks"`
+	Missing    []int `json:"missing_chunks"`
+}
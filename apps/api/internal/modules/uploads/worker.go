@@ -0,0 +1,150 @@
+package uploads
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// cleanupBatchSize bounds how many uploads one cleanup pass removes
+const cleanupBatchSize = 100
+
+// consumedRetention keeps consumed and failed uploads around briefly so a
+// consumer still reading the file is not cut off
+const consumedRetention = time.Hour
+
+// RunCleanup removes expired, consumed and failed uploads until ctx is cancelled
+func (s *Service) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	s.log.Info("Upload cleanup started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				removed, err := s.CleanupExpired(ctx)
+				if err != nil {
+					s.log.Error("Failed to clean up uploads", "error", err)
+					break
+				}
+				if removed < cleanupBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Upload cleanup stopped")
+			return
+		}
+	}
+}
+
+// CleanupExpired deletes the stored objects and rows of up to cleanupBatchSize
+// uploads that expired (partial uploads after 24h) or were consumed/failed
+// more than consumedRetention ago. Returns the number of uploads removed.
+func (s *Service) CleanupExpired(ctx context.Context) (int, error) {
+	startTime := time.Now()
+
+	query := `
+		SELECT id FROM uploads
+		WHERE expires_at <= NOW()
+		   OR (status IN ('consumed', 'failed') AND updated_at <= NOW() - $1 * INTERVAL '1 second')
+		LIMIT $2
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, int(consumedRetention.Seconds()), cleanupBatchSize)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired uploads: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating expired uploads: %w", err)
+	}
+
+	removed, err := s.removeUploads(ctx, ids)
+	if removed > 0 {
+		s.log.LogBusinessEvent("uploads_cleaned_up", map[string]interface{}{
+			"count": removed,
+		})
+	}
+
+	return removed, err
+}
+
+// PurgeUser deletes the stored objects and rows of all the user's uploads,
+// for an account being purged. Returns the number of uploads removed.
+func (s *Service) PurgeUser(ctx context.Context, userID int64) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM uploads WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list user uploads: %w", err)
+	}
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user upload: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating user uploads: %w", err)
+	}
+
+	removed, err := s.removeUploads(ctx, ids)
+	if err != nil {
+		return removed, err
+	}
+	if removed < len(ids) {
+		return removed, fmt.Errorf("failed to delete the objects of %d uploads", len(ids)-removed)
+	}
+	return removed, nil
+}
+
+// removeUploads deletes the stored objects of the uploads and then their
+// rows. An upload whose objects could not all be deleted keeps its row, so
+// they are retried. Returns the number of uploads removed.
+func (s *Service) removeUploads(ctx context.Context, ids []string) (int, error) {
+	removed := 0
+	for _, id := range ids {
+		offsets, err := chunkOffsets(ctx, s.db, id)
+		if err != nil {
+			return removed, err
+		}
+		keys := []string{dataKey(id)}
+		for _, off := range offsets {
+			keys = append(keys, chunkKey(id, off))
+		}
+		failed := false
+		for _, key := range keys {
+			if err := s.store.Delete(ctx, key); err != nil {
+				s.log.Error("Failed to delete upload object", "error", err, "upload_id", id, "key", key)
+				failed = true
+			}
+		}
+		if failed {
+			// Keep the row so the objects are retried on the next pass
+			continue
+		}
+
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM uploads WHERE id = $1`, id); err != nil {
+			return removed, fmt.Errorf("failed to delete upload: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
@@ -0,0 +1,250 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxAPIKeys is the number of active API keys a user may have
+	MaxAPIKeys = 10
+	// APIKeyPrefixLength is the number of leading key characters kept in
+	// plain text to identify the key
+	APIKeyPrefixLength = 8
+	// APIKeyTouchInterval throttles last_used_at updates, so a dashboard
+	// polling every few seconds does not write on every request
+	APIKeyTouchInterval = time.Minute
+)
+
+// ErrTooManyAPIKeys is returned when a user already has MaxAPIKeys active keys
+var ErrTooManyAPIKeys = errors.New("too many active API keys")
+
+// CreateAPIKeyRequest represents an API key to generate
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" binding:"max=100"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKey is an API key as listed to its owner. Key is only set in the
+// response to its creation; afterwards only the prefix is known.
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Key        string     `json:"key,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// APIKeyService manages API keys and resolves them for middleware.RequireAuth
+type APIKeyService struct {
+	db     *sql.DB
+	log    *logger.Logger
+	tokens *auth.TokenGenerator
+	now    func() time.Time
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(db *sql.DB, log *logger.Logger) *APIKeyService {
+	return &APIKeyService{db: db, log: log, tokens: auth.NewTokenGenerator(), now: time.Now}
+}
+
+// validateScopes checks that scopes is a non-empty set of known scopes.
+// Invalid input is reported as validation.Errors.
+func validateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return validation.Errors{"scopes": "Укажите хотя бы одну область доступа"}
+	}
+	for i, scope := range scopes {
+		if !slices.Contains(middleware.Scopes, scope) {
+			return validation.Errors{"scopes": "Допустимые области доступа: " + strings.Join(middleware.Scopes, ", ")}
+		}
+		if slices.Contains(scopes[:i], scope) {
+			return validation.Errors{"scopes": "Области доступа не должны повторяться"}
+		}
+	}
+	return nil
+}
+
+// CreateAPIKey generates a key for the user. The returned key is the only
+// time the plain key is available; it is stored hashed.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID int64, req *CreateAPIKeyRequest) (*APIKey, error) {
+	if err := validateScopes(req.Scopes); err != nil {
+		return nil, err
+	}
+
+	var active int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM api_keys WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+	).Scan(&active)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if active >= MaxAPIKeys {
+		return nil, ErrTooManyAPIKeys
+	}
+
+	plain, hashed, err := s.tokens.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at`
+
+	key := &APIKey{
+		Name:   strings.TrimSpace(req.Name),
+		Prefix: plain[:APIKeyPrefixLength],
+		Key:    plain,
+		Scopes: req.Scopes,
+	}
+	err = s.db.QueryRowContext(ctx, query, userID, key.Name, key.Prefix, hashed, "{"+strings.Join(req.Scopes, ",")+"}").
+		Scan(&key.ID, &key.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.log.LogSecurityEvent("api_key_created", "info", map[string]interface{}{
+		"user_id": userID,
+		"key_id":  key.ID,
+		"scopes":  req.Scopes,
+	})
+
+	return key, nil
+}
+
+// ListAPIKeys returns the user's active keys, newest first
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID int64) ([]APIKey, error) {
+	startTime := time.Now()
+	query := `
+		SELECT id, name, prefix, scopes, created_at, last_used_at
+		FROM api_keys
+		WHERE user_id = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC, id`
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := make([]APIKey, 0)
+	for rows.Next() {
+		var key APIKey
+		var scopes string
+		var lastUsedAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &lastUsedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+		key.Scopes = parseTextArray(scopes)
+		if lastUsedAt.Valid {
+			key.LastUsedAt = &lastUsedAt.Time
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating API keys: %w", err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey revokes one of the user's keys. Keys of other users and keys
+// already revoked are reported as not found.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID int64, keyID string) error {
+	if _, err := uuid.Parse(keyID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`,
+		keyID, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if affected == 0 {
+		return apperrors.ErrNotFound
+	}
+
+	s.log.LogSecurityEvent("api_key_revoked", "info", map[string]interface{}{
+		"user_id": userID,
+		"key_id":  keyID,
+	})
+	return nil
+}
+
+// ResolveAPIKey returns the owner and scopes of an active key; keys of a
+// deactivated account stop working until it is reactivated. It
+// implements middleware.APIKeyResolver.
+func (s *APIKeyService) ResolveAPIKey(ctx context.Context, key string) (*middleware.APIKeyPrincipal, error) {
+	var keyID, scopes string
+	var lastUsedAt sql.NullTime
+	var principal middleware.APIKeyPrincipal
+	err := s.db.QueryRowContext(ctx, `
+		SELECT k.id, k.scopes, k.last_used_at, u.id, u.email, u.role
+		FROM api_keys k
+		JOIN users u ON u.id = k.user_id
+		WHERE k.key_hash = $1 AND k.revoked_at IS NULL AND u.deactivated_at IS NULL`,
+		s.tokens.HashToken(key),
+	).Scan(&keyID, &scopes, &lastUsedAt, &principal.UserID, &principal.Email, &principal.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, middleware.ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve API key: %w", err)
+	}
+	principal.Scopes = parseTextArray(scopes)
+
+	if !lastUsedAt.Valid || s.now().Sub(lastUsedAt.Time) >= APIKeyTouchInterval {
+		s.touch(ctx, keyID)
+	}
+
+	return &principal, nil
+}
+
+// touch records that a key was used. The condition keeps concurrent
+// requests from writing more than once per APIKeyTouchInterval; a failure
+// only loses the timestamp and does not fail the request.
+func (s *APIKeyService) touch(ctx context.Context, keyID string) {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - $2 * INTERVAL '1 second')`,
+		keyID, int(APIKeyTouchInterval.Seconds()),
+	)
+	if err != nil {
+		s.log.Warn("Failed to update API key last use", "error", err, "key_id", keyID)
+	}
+}
+
+// parseTextArray parses a PostgreSQL array literal of simple values like
+// "{read:nutrition,read:measurements}"
+func parseTextArray(s string) []string {
+	s = strings.Trim(strings.TrimSpace(s), "{}")
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
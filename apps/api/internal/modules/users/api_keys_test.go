@@ -0,0 +1,170 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKeyID = "7d1e2f3a-4b5c-4d6e-8f9a-0b1c2d3e4f5a"
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func setupAPIKeyService(t *testing.T) (*APIKeyService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	service := NewAPIKeyService(db, logger.New())
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func TestValidateScopes(t *testing.T) {
+	assert.NoError(t, validateScopes([]string{middleware.ScopeReadNutrition, middleware.ScopeReadMeasurements}))
+
+	for name, scopes := range map[string][]string{
+		"empty":    nil,
+		"unknown":  {"write:nutrition"},
+		"repeated": {middleware.ScopeReadNutrition, middleware.ScopeReadNutrition},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, validateScopes(scopes), &fieldErrs)
+			assert.Contains(t, fieldErrs, "scopes")
+		})
+	}
+}
+
+func TestAPIKeyService_CreateAPIKey(t *testing.T) {
+	t.Run("stores the hash and returns the key once", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM api_keys").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("INSERT INTO api_keys").
+			WithArgs(int64(5), "Grafana", sqlmock.AnyArg(), sqlmock.AnyArg(), "{read:nutrition}").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(testKeyID, testNow))
+
+		key, err := service.CreateAPIKey(context.Background(), 5, &CreateAPIKeyRequest{
+			Name: " Grafana ", Scopes: []string{middleware.ScopeReadNutrition},
+		})
+
+		require.NoError(t, err)
+		assert.Len(t, key.Key, 64)
+		assert.Equal(t, key.Key[:APIKeyPrefixLength], key.Prefix)
+		assert.Equal(t, "Grafana", key.Name)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("too many keys", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM api_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(MaxAPIKeys))
+
+		_, err := service.CreateAPIKey(context.Background(), 5, &CreateAPIKeyRequest{Scopes: []string{middleware.ScopeReadNutrition}})
+
+		assert.ErrorIs(t, err, ErrTooManyAPIKeys)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAPIKeyService_ListAPIKeys(t *testing.T) {
+	service, mock := setupAPIKeyService(t)
+	mock.ExpectQuery("FROM api_keys\\s+WHERE user_id = \\$1 AND revoked_at IS NULL").WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "prefix", "scopes", "created_at", "last_used_at"}).
+			AddRow(testKeyID, "Grafana", "a1b2c3d4", "{read:nutrition,read:measurements}", testNow, testNow).
+			AddRow("8e2f3a4b-5c6d-4e7f-9a0b-1c2d3e4f5a6b", "", "e5f6a7b8", "{read:measurements}", testNow, nil))
+
+	keys, err := service.ListAPIKeys(context.Background(), 5)
+
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, []string{middleware.ScopeReadNutrition, middleware.ScopeReadMeasurements}, keys[0].Scopes)
+	assert.Empty(t, keys[0].Key)
+	assert.Nil(t, keys[1].LastUsedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyService_RevokeAPIKey(t *testing.T) {
+	t.Run("revoked", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectExec("UPDATE api_keys SET revoked_at = NOW\\(\\)").WithArgs(testKeyID, int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		require.NoError(t, service.RevokeAPIKey(context.Background(), 5, testKeyID))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's or already revoked key", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectExec("UPDATE api_keys SET revoked_at").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := service.RevokeAPIKey(context.Background(), 7, testKeyID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAPIKeyService_ResolveAPIKey(t *testing.T) {
+	const resolveRe = "FROM api_keys k\\s+JOIN users u ON u.id = k.user_id\\s+WHERE k.key_hash = \\$1 AND k.revoked_at IS NULL"
+	keyRows := func(lastUsedAt interface{}) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "scopes", "last_used_at", "id", "email", "role"}).
+			AddRow(testKeyID, "{read:nutrition}", lastUsedAt, int64(5), "user@example.com", "client")
+	}
+
+	t.Run("recently used key is not written", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery(resolveRe).WithArgs(service.tokens.HashToken("plain-key")).
+			WillReturnRows(keyRows(testNow.Add(-30 * time.Second)))
+
+		principal, err := service.ResolveAPIKey(context.Background(), "plain-key")
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), principal.UserID)
+		assert.Equal(t, []string{middleware.ScopeReadNutrition}, principal.Scopes)
+		assert.NoError(t, mock.ExpectationsWereMet(), "no last_used_at update within a minute")
+	})
+
+	t.Run("last use is recorded after a minute", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery(resolveRe).WillReturnRows(keyRows(testNow.Add(-2 * time.Minute)))
+		mock.ExpectExec("UPDATE api_keys SET last_used_at = NOW\\(\\)").WithArgs(testKeyID, 60).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ResolveAPIKey(context.Background(), "plain-key")
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("first use", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery(resolveRe).WillReturnRows(keyRows(nil))
+		mock.ExpectExec("UPDATE api_keys SET last_used_at").WillReturnError(assert.AnError)
+
+		_, err := service.ResolveAPIKey(context.Background(), "plain-key")
+
+		assert.NoError(t, err, "a failed timestamp update does not fail the request")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("revoked or unknown key", func(t *testing.T) {
+		service, mock := setupAPIKeyService(t)
+		mock.ExpectQuery(resolveRe).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err := service.ResolveAPIKey(context.Background(), "revoked-key")
+
+		assert.ErrorIs(t, err, middleware.ErrInvalidAPIKey)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
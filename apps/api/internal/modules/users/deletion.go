@@ -0,0 +1,439 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/storage"
+)
+
+const (
+	// PurgeInterval is how often the purge job looks for accounts whose
+	// grace period is over
+	PurgeInterval = time.Hour
+	// purgeBatchSize is the number of rows deleted per transaction, so
+	// purging a long history does not hold locks for long
+	purgeBatchSize = 500
+	// purgeUsersPerRun caps the accounts purged in one run
+	purgeUsersPerRun = 100
+)
+
+// ErrStaffAccount is returned when a coordinator or admin tries to delete
+// their own account: staff own content other users depend on, so their
+// accounts are removed by an administrator
+var ErrStaffAccount = errors.New("staff accounts cannot be deleted by their owner")
+
+// DeleteAccountRequest confirms an account deletion with the password
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// AccountDeletion is the outcome of a deletion request
+type AccountDeletion struct {
+	PurgeAfter time.Time `json:"purge_after"`
+}
+
+// purgeStep is a table holding a user's rows, deleted in batches by key
+type purgeStep struct {
+	table string
+	key   string
+	// storageKeys name columns with stored files to delete with the row
+	storageKeys []string
+	// regionKey names the column holding the storage region of the files;
+	// such files live in the region stores, or in store for region ''
+	regionKey string
+}
+
+// purgedFile is a stored file whose row has been purged
+type purgedFile struct {
+	key    string
+	region string
+}
+
+// purgeSteps are purged in order before the users row, dependent rows
+// first. Anything not listed goes with the users row via ON DELETE CASCADE.
+var purgeSteps = []purgeStep{
+	{table: "nutrition_entry_revisions", key: "id"},
+	{table: "nutrition_entry_photos", key: "entry_id", storageKeys: []string{"storage_key", "thumbnail_key"}, regionKey: "storage_region"},
+	{table: "nutrition_entries", key: "id"},
+	{table: "food_entries", key: "id"},
+	{table: "daily_metrics", key: "id"},
+	{table: "body_fat_estimates", key: "id"},
+	{table: "body_measurements", key: "id"},
+	{table: "progress_photos", key: "id", storageKeys: []string{"storage_key"}, regionKey: "storage_region"},
+	{table: "weekly_photos", key: "id", storageKeys: []string{"storage_key"}, regionKey: "storage_region"},
+	{table: "reset_tokens", key: "id"},
+}
+
+// FilePurger deletes the files a module stores for a user in its own
+// storage, together with the rows pointing to them. It returns the number
+// of files or uploads removed.
+type FilePurger interface {
+	PurgeUser(ctx context.Context, userID int64) (int, error)
+}
+
+// AvatarPurger deletes the avatar of an account being purged from the
+// profile photos bucket. It implements FilePurger.
+type AvatarPurger struct {
+	db    *sql.DB
+	log   *logger.Logger
+	store storage.ObjectStore
+}
+
+// NewAvatarPurger creates an avatar purger for the profile photos store
+func NewAvatarPurger(db *sql.DB, log *logger.Logger, store storage.ObjectStore) *AvatarPurger {
+	return &AvatarPurger{db: db, log: log, store: store}
+}
+
+// PurgeUser deletes the user's avatar object and clears avatar_url. A
+// failed delete is returned, so the purge keeps the users row and retries.
+func (p *AvatarPurger) PurgeUser(ctx context.Context, userID int64) (int, error) {
+	var avatarURL sql.NullString
+	startTime := time.Now()
+	err := p.db.QueryRowContext(ctx, `SELECT avatar_url FROM users WHERE id = $1`, userID).Scan(&avatarURL)
+	p.log.LogDatabaseQuery("Purge.Avatar", time.Since(startTime), err, map[string]any{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up avatar: %w", err)
+	}
+	key := avatarKey(avatarURL.String)
+	if key == "" {
+		return 0, nil
+	}
+
+	if err := p.store.DeleteFile(ctx, key); err != nil {
+		return 0, fmt.Errorf("failed to delete avatar: %w", err)
+	}
+	if _, err := p.db.ExecContext(ctx, `UPDATE users SET avatar_url = NULL WHERE id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to clear avatar: %w", err)
+	}
+	return 1, nil
+}
+
+// DeletionService deletes accounts at their owner's request. A deleted
+// account is deactivated at once and purged by RunPurge once
+// auth.DeletionGracePeriod is over; until then it can be reactivated.
+type DeletionService struct {
+	db    *sql.DB
+	log   *logger.Logger
+	store storage.Storage
+	// regions hold weekly photos and regional progress and meal photos,
+	// each in the region recorded on its row
+	regions *storage.Regions
+	// purgers delete files kept outside store, before any row is purged
+	purgers []FilePurger
+	audit   audit.ServiceInterface
+	now     func() time.Time
+}
+
+// NewDeletionService creates a new deletion service. store holds the local
+// progress and meal photo files and may be nil when photos are disabled;
+// regions hold weekly photos and the photos of organizations in other
+// regions; purgers delete the files of modules with storages of their own.
+func NewDeletionService(db *sql.DB, log *logger.Logger, store storage.Storage, regions *storage.Regions, purgers ...FilePurger) *DeletionService {
+	return &DeletionService{
+		db:      db,
+		log:     log,
+		store:   store,
+		regions: regions,
+		purgers: purgers,
+		audit:   audit.NewService(db, log),
+		now:     time.Now,
+	}
+}
+
+// DeleteAccount deactivates the user's account after checking the password
+// and signs out every session. Deleting an already deleted account returns
+// the original purge date.
+func (s *DeletionService) DeleteAccount(ctx context.Context, userID int64, plainPassword string) (*AccountDeletion, error) {
+	var hashedPassword, role string
+	var purgeAfter sql.NullTime
+	startTime := time.Now()
+	err := s.db.QueryRowContext(ctx,
+		`SELECT password, role, purge_after FROM users WHERE id = $1`, userID,
+	).Scan(&hashedPassword, &role, &purgeAfter)
+	s.log.LogDatabaseQuery("DeleteAccount.LookupUser", time.Since(startTime), err, map[string]any{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if err := password.Compare(hashedPassword, plainPassword); err != nil {
+		return nil, fmt.Errorf("DeleteAccount.VerifyPassword: %w", apperrors.ErrInvalidCredentials)
+	}
+	if role != "client" {
+		return nil, ErrStaffAccount
+	}
+	if purgeAfter.Valid {
+		return &AccountDeletion{PurgeAfter: purgeAfter.Time}, nil
+	}
+
+	now := s.now()
+	deletion := &AccountDeletion{PurgeAfter: now.Add(auth.DeletionGracePeriod)}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users SET deactivated_at = $2, purge_after = $3, updated_at = NOW() WHERE id = $1`,
+			userID, now, deletion.PurgeAfter,
+		); err != nil {
+			return fmt.Errorf("failed to deactivate user: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID,
+		); err != nil {
+			return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &userID,
+		Action:   audit.ActionAccountDeleted,
+		Metadata: map[string]any{"purge_after": deletion.PurgeAfter},
+	})
+	s.log.LogBusinessEvent("account_deleted", map[string]any{
+		"user_id":     userID,
+		"purge_after": deletion.PurgeAfter,
+	})
+
+	return deletion, nil
+}
+
+// Purge removes the accounts whose grace period is over and returns how
+// many were removed. A failed account is logged and retried on the next run.
+func (s *DeletionService) Purge(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM users WHERE purge_after <= $1 ORDER BY purge_after LIMIT $2`,
+		s.now(), purgeUsersPerRun,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list accounts to purge: %w", err)
+	}
+	var userIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan account to purge: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list accounts to purge: %w", err)
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := s.purgeUser(ctx, userID)
+		if err != nil {
+			s.log.Error("Failed to purge account", "error", err, "user_id", userID)
+			continue
+		}
+		if ok {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purgeUser deletes the user's rows table by table and then the user. Every
+// step only deletes what is left, so a purge interrupted halfway simply
+// continues on the next run. It reports whether the users row was removed
+// by this call.
+func (s *DeletionService) purgeUser(ctx context.Context, userID int64) (bool, error) {
+	deleted := 0
+	// Files in other storages go first: the rows naming them would otherwise be
+	// dropped by the users row cascade, leaving the files behind
+	for _, purger := range s.purgers {
+		if _, err := purger.PurgeUser(ctx, userID); err != nil {
+			return false, fmt.Errorf("failed to purge files: %w", err)
+		}
+	}
+	for _, step := range purgeSteps {
+		n, err := s.purgeTable(ctx, step, userID)
+		deleted += n
+		if err != nil {
+			return false, err
+		}
+	}
+
+	var removed bool
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		// Entries the user logged for someone else stay with their owner
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE food_entries SET created_by = NULL WHERE created_by = $1`, userID,
+		); err != nil {
+			return fmt.Errorf("failed to detach food entries: %w", err)
+		}
+		// The grace period condition keeps a reactivated account alive
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM users WHERE id = $1 AND purge_after <= $2`, userID, s.now(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		n, err := result.RowsAffected()
+		removed = n == 1
+		return err
+	})
+	if err != nil || !removed {
+		return false, err
+	}
+
+	// The users row is gone, so the record keeps the ID in its metadata
+	s.audit.Record(ctx, audit.Entry{
+		Action:   audit.ActionAccountPurged,
+		Metadata: map[string]any{"purged_user_id": userID, "deleted_rows": deleted},
+	})
+	s.log.LogBusinessEvent("account_purged", map[string]any{
+		"user_id":      userID,
+		"deleted_rows": deleted,
+	})
+	return true, nil
+}
+
+// purgeTable deletes the user's rows of one table in batches, each in its
+// own transaction, and returns the number of rows deleted
+func (s *DeletionService) purgeTable(ctx context.Context, step purgeStep, userID int64) (int, error) {
+	returning := ""
+	if len(step.storageKeys) > 0 {
+		columns := step.storageKeys
+		if step.regionKey != "" {
+			columns = append(columns[:len(columns):len(columns)], step.regionKey)
+		}
+		returning = " RETURNING " + strings.Join(columns, ", ")
+	}
+	query := fmt.Sprintf(
+		`DELETE FROM %[1]s WHERE %[2]s IN (SELECT %[2]s FROM %[1]s WHERE user_id = $1 LIMIT $2)%[3]s`,
+		step.table, step.key, returning,
+	)
+
+	total := 0
+	for {
+		var n int
+		var files []purgedFile
+		startTime := time.Now()
+		err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+			if returning == "" {
+				result, err := tx.ExecContext(ctx, query, userID, purgeBatchSize)
+				if err != nil {
+					return err
+				}
+				affected, err := result.RowsAffected()
+				n = int(affected)
+				return err
+			}
+
+			rows, err := tx.QueryContext(ctx, query, userID, purgeBatchSize)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+			for rows.Next() {
+				keys := make([]string, len(step.storageKeys))
+				dest := make([]any, len(keys), len(keys)+1)
+				for i := range keys {
+					dest[i] = &keys[i]
+				}
+				var region string
+				if step.regionKey != "" {
+					dest = append(dest, &region)
+				}
+				if err := rows.Scan(dest...); err != nil {
+					return err
+				}
+				for _, key := range keys {
+					files = append(files, purgedFile{key: key, region: region})
+				}
+				n++
+			}
+			return rows.Err()
+		})
+		s.log.LogDatabaseQuery("Purge."+step.table, time.Since(startTime), err, map[string]any{"user_id": userID})
+		if err != nil {
+			return total, fmt.Errorf("failed to purge %s: %w", step.table, err)
+		}
+		total += n
+
+		// Files go only after their rows are committed; an orphaned file
+		// is only wasted space
+		for _, file := range files {
+			s.deleteFile(ctx, step, file)
+		}
+
+		if n < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// deleteFile removes a purged row's file from the store it was kept in. A
+// file that cannot be deleted is logged and left behind.
+func (s *DeletionService) deleteFile(ctx context.Context, step purgeStep, file purgedFile) {
+	// Weekly photos uploaded before keys were recorded have none
+	if file.key == "" {
+		return
+	}
+	if file.region == "" {
+		if s.store == nil {
+			return
+		}
+		if err := s.store.Delete(ctx, file.key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+			s.log.Error("Failed to delete purged file", "error", err, "key", file.key)
+		}
+		return
+	}
+
+	store, ok := s.regions.Store(file.region)
+	if !ok {
+		s.log.Error("Purged file is in an unconfigured storage region", "key", file.key, "storage_region", file.region)
+		return
+	}
+	if err := store.DeleteFile(ctx, file.key); err != nil {
+		s.log.Error("Failed to delete purged file", "error", err, "key", file.key, "storage_region", file.region)
+	}
+}
+
+// RunPurge purges accounts past their grace period every PurgeInterval
+// until ctx is cancelled
+func (s *DeletionService) RunPurge(ctx context.Context) {
+	ticker := time.NewTicker(PurgeInterval)
+	defer ticker.Stop()
+
+	s.log.Info("Account purge started")
+
+	for {
+		select {
+		case <-ticker.C:
+			purged, err := s.Purge(ctx)
+			if err != nil {
+				s.log.Error("Failed to purge accounts", "error", err)
+			} else if purged > 0 {
+				s.log.Info("Purged deleted accounts", "count", purged)
+			}
+		case <-ctx.Done():
+			s.log.Info("Account purge stopped")
+			return
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package users
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func setupDeletionService(t *testing.T, store storage.Storage) (*DeletionService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	service := NewDeletionService(db, logger.New(), store, nil)
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func userRows(t *testing.T, role string, purgeAfter any) *sqlmock.Rows {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return sqlmock.NewRows([]string{"password", "role", "purge_after"}).AddRow(string(hash), role, purgeAfter)
+}
+
+func TestDeletionService_DeleteAccount(t *testing.T) {
+	t.Run("deactivates and signs out", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		purgeAfter := testNow.Add(auth.DeletionGracePeriod)
+
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").WithArgs(int64(5)).
+			WillReturnRows(userRows(t, "client", nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE users SET deactivated_at = \\$2, purge_after = \\$3").
+			WithArgs(int64(5), testNow, purgeAfter).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE refresh_tokens SET revoked_at = NOW\\(\\) WHERE user_id = \\$1").
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(5), sqlmock.AnyArg(), audit.ActionAccountDeleted, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		deletion, err := service.DeleteAccount(context.Background(), 5, "password123")
+
+		require.NoError(t, err)
+		assert.Equal(t, purgeAfter, deletion.PurgeAfter)
+		assert.Equal(t, 14*24*time.Hour, deletion.PurgeAfter.Sub(testNow))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+			WillReturnRows(userRows(t, "client", nil))
+
+		_, err := service.DeleteAccount(context.Background(), 5, "wrong")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("staff account", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+			WillReturnRows(userRows(t, "coordinator", nil))
+
+		_, err := service.DeleteAccount(context.Background(), 5, "password123")
+
+		assert.ErrorIs(t, err, ErrStaffAccount)
+	})
+
+	t.Run("already deleted keeps the original date", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		purgeAfter := testNow.Add(3 * 24 * time.Hour)
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+			WillReturnRows(userRows(t, "client", purgeAfter))
+
+		deletion, err := service.DeleteAccount(context.Background(), 5, "password123")
+
+		require.NoError(t, err)
+		assert.Equal(t, purgeAfter, deletion.PurgeAfter)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+			WillReturnRows(sqlmock.NewRows([]string{"password", "role", "purge_after"}))
+
+		_, err := service.DeleteAccount(context.Background(), 5, "password123")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+	})
+}
+
+// expectPurgeTables expects one batch per purge step, deleting rows[table]
+// rows (none when missing); tables with files return files[table], the
+// storage keys of each deleted row followed by its region when the table
+// has one
+func expectPurgeTables(mock sqlmock.Sqlmock, userID int64, rows map[string]int, files map[string][][]string) {
+	for _, step := range purgeSteps {
+		query := regexp.QuoteMeta("DELETE FROM " + step.table + " WHERE " + step.key + " IN")
+		mock.ExpectBegin()
+		if len(step.storageKeys) > 0 {
+			columns := step.storageKeys
+			if step.regionKey != "" {
+				columns = append(columns[:len(columns):len(columns)], step.regionKey)
+			}
+			result := sqlmock.NewRows(columns)
+			for _, keys := range files[step.table] {
+				row := make([]driver.Value, len(keys))
+				for i, key := range keys {
+					row[i] = key
+				}
+				result.AddRow(row...)
+			}
+			mock.ExpectQuery(query).WithArgs(userID, purgeBatchSize).WillReturnRows(result)
+		} else {
+			mock.ExpectExec(query).WithArgs(userID, purgeBatchSize).
+				WillReturnResult(sqlmock.NewResult(0, int64(rows[step.table])))
+		}
+		mock.ExpectCommit()
+	}
+}
+
+func expectDeleteUser(mock sqlmock.Sqlmock, userID int64, affected int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE food_entries SET created_by = NULL WHERE created_by = \\$1").
+		WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM users WHERE id = \\$1 AND purge_after <= \\$2").
+		WithArgs(userID, testNow).WillReturnResult(sqlmock.NewResult(0, affected))
+	mock.ExpectCommit()
+}
+
+// fakeObjectStore is an in-memory storage.ObjectStore for a storage region
+type fakeObjectStore struct {
+	objects   map[string]bool
+	deleteErr error
+}
+
+func newFakeObjectStore(keys ...string) *fakeObjectStore {
+	f := &fakeObjectStore{objects: make(map[string]bool)}
+	for _, key := range keys {
+		f.objects[key] = true
+	}
+	return f
+}
+
+func (f *fakeObjectStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	f.objects[key] = true
+	return "https://storage.example/" + key, nil
+}
+
+func (f *fakeObjectStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	return nil, nil
+}
+
+func (f *fakeObjectStore) DeleteFile(ctx context.Context, key string) error {
+	if f.deleteErr != nil {
+		return f.deleteErr
+	}
+	delete(f.objects, key)
+	return nil
+}
+
+func (f *fakeObjectStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + key + "?signed", nil
+}
+
+// purgedMetadata matches the audit metadata of a purge
+type purgedMetadata struct {
+	userID  int64
+	deleted int
+}
+
+func (m purgedMetadata) Match(v driver.Value) bool {
+	raw, ok := v.([]byte)
+	if !ok {
+		return false
+	}
+	var metadata struct {
+		PurgedUserID int64 `json:"purged_user_id"`
+		DeletedRows  int   `json:"deleted_rows"`
+	}
+	return json.Unmarshal(raw, &metadata) == nil &&
+		metadata.PurgedUserID == m.userID && metadata.DeletedRows == m.deleted
+}
+
+func TestDeletionService_Purge(t *testing.T) {
+	t.Run("removes data, files and the user", func(t *testing.T) {
+		store := storage.NewMemoryStorage()
+		ctx := context.Background()
+		require.NoError(t, store.Put(ctx, "progress/5/front.jpg", strings.NewReader("jpeg")))
+		require.NoError(t, store.Put(ctx, "progress/9/front.jpg", strings.NewReader("jpeg")))
+		require.NoError(t, store.Put(ctx, "nutrition/5/meal", strings.NewReader("jpeg")))
+		require.NoError(t, store.Put(ctx, "nutrition/5/meal-thumb", strings.NewReader("jpeg")))
+		ru := newFakeObjectStore("weekly-photos/5/2026-W40/a.jpg", "weekly-photos/9/2026-W40/b.jpg")
+		eu := newFakeObjectStore("weekly-photos/5/2026-W41/c.jpg", "progress/5/side.jpg", "nutrition/5/dinner", "nutrition/5/dinner-thumb")
+		avatars := newFakeObjectStore("avatars/5/avatar.jpg", "avatars/9/avatar.jpg")
+		service, mock := setupDeletionService(t, store)
+		service.regions = storage.NewRegions(storage.DefaultRegion)
+		service.regions.Register(storage.DefaultRegion, ru)
+		service.regions.Register("eu", eu)
+		service.purgers = []FilePurger{NewAvatarPurger(service.db, service.log, avatars)}
+
+		mock.ExpectQuery("SELECT id FROM users WHERE purge_after <= \\$1").
+			WithArgs(testNow, purgeUsersPerRun).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectQuery("SELECT avatar_url FROM users WHERE id = \\$1").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"avatar_url"}).AddRow("https://storage.example/profile/avatars/5/avatar.jpg"))
+		mock.ExpectExec("UPDATE users SET avatar_url = NULL WHERE id = \\$1").WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectPurgeTables(mock, 5, map[string]int{"nutrition_entries": 40, "daily_metrics": 12, "body_measurements": 6, "reset_tokens": 1}, map[string][][]string{
+			"progress_photos": {{"progress/5/front.jpg", ""}, {"progress/5/side.jpg", "eu"}},
+			"nutrition_entry_photos": {
+				{"nutrition/5/meal", "nutrition/5/meal-thumb", ""},
+				{"nutrition/5/dinner", "nutrition/5/dinner-thumb", "eu"},
+			},
+			"weekly_photos": {
+				{"weekly-photos/5/2026-W40/a.jpg", storage.DefaultRegion},
+				{"weekly-photos/5/2026-W41/c.jpg", "eu"},
+				{"", storage.DefaultRegion},
+			},
+		})
+		expectDeleteUser(mock, 5, 1)
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(nil, sqlmock.AnyArg(), audit.ActionAccountPurged, purgedMetadata{userID: 5, deleted: 66}).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		purged, err := service.Purge(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		assert.Equal(t, []string{"progress/9/front.jpg"}, store.Keys())
+		assert.Equal(t, map[string]bool{"weekly-photos/9/2026-W40/b.jpg": true}, ru.objects)
+		assert.Empty(t, eu.objects)
+		assert.Equal(t, map[string]bool{"avatars/9/avatar.jpg": true}, avatars.objects)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing due", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		mock.ExpectQuery("SELECT id FROM users WHERE purge_after <= \\$1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		purged, err := service.Purge(context.Background())
+
+		require.NoError(t, err)
+		assert.Zero(t, purged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed account does not stop the others", func(t *testing.T) {
+		service, mock := setupDeletionService(t, nil)
+		mock.ExpectQuery("SELECT id FROM users WHERE purge_after <= \\$1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE FROM nutrition_entry_revisions").WillReturnError(assert.AnError)
+		mock.ExpectRollback()
+		expectPurgeTables(mock, 6, nil, nil)
+		expectDeleteUser(mock, 6, 1)
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		purged, err := service.Purge(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeletionService_PurgeIsIdempotent(t *testing.T) {
+	service, mock := setupDeletionService(t, storage.NewMemoryStorage())
+	ctx := context.Background()
+
+	// First run: data left over from an interrupted purge is removed
+	expectPurgeTables(mock, 5, map[string]int{"food_entries": 3}, nil)
+	expectDeleteUser(mock, 5, 1)
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs(nil, sqlmock.AnyArg(), audit.ActionAccountPurged, purgedMetadata{userID: 5, deleted: 3}).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	// Second run for the same user: nothing is left, nothing is audited
+	expectPurgeTables(mock, 5, nil, nil)
+	expectDeleteUser(mock, 5, 0)
+
+	removed, err := service.purgeUser(ctx, 5)
+	require.NoError(t, err)
+	assert.True(t, removed)
+
+	removed, err = service.purgeUser(ctx, 5)
+	require.NoError(t, err)
+	assert.False(t, removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// fakePurger records the users whose files it was asked to delete
+type fakePurger struct {
+	users []int64
+	err   error
+}
+
+func (p *fakePurger) PurgeUser(ctx context.Context, userID int64) (int, error) {
+	p.users = append(p.users, userID)
+	return 1, p.err
+}
+
+func TestDeletionService_PurgeRunsFilePurgers(t *testing.T) {
+	t.Run("files go before the rows", func(t *testing.T) {
+		exports, uploads := &fakePurger{}, &fakePurger{}
+		service, mock := setupDeletionService(t, nil)
+		service.purgers = []FilePurger{exports, uploads}
+
+		expectPurgeTables(mock, 5, nil, nil)
+		expectDeleteUser(mock, 5, 1)
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		removed, err := service.purgeUser(context.Background(), 5)
+
+		require.NoError(t, err)
+		assert.True(t, removed)
+		assert.Equal(t, []int64{5}, exports.users)
+		assert.Equal(t, []int64{5}, uploads.users)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed purger keeps the rows", func(t *testing.T) {
+		uploads := &fakePurger{err: assert.AnError}
+		service, mock := setupDeletionService(t, nil)
+		service.purgers = []FilePurger{uploads}
+
+		removed, err := service.purgeUser(context.Background(), 5)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.False(t, removed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeletionService_PurgeTableBatches(t *testing.T) {
+	service, mock := setupDeletionService(t, nil)
+	step := purgeStep{table: "daily_metrics", key: "id"}
+	query := regexp.QuoteMeta("DELETE FROM daily_metrics WHERE id IN (SELECT id FROM daily_metrics WHERE user_id = $1 LIMIT $2)")
+
+	// Each batch commits on its own; a short batch ends the table
+	for _, n := range []int64{purgeBatchSize, purgeBatchSize, 7} {
+		mock.ExpectBegin()
+		mock.ExpectExec(query).WithArgs(int64(5), purgeBatchSize).WillReturnResult(sqlmock.NewResult(0, n))
+		mock.ExpectCommit()
+	}
+
+	deleted, err := service.purgeTable(context.Background(), step, 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2*purgeBatchSize+7, deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandler_DeleteAccount(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, tc := range []struct {
+		name       string
+		body       string
+		role       string
+		wantStatus int
+	}{
+		{"missing password", `{}`, "client", http.StatusBadRequest},
+		{"wrong password", `{"password":"wrong"}`, "client", http.StatusUnauthorized},
+		{"staff account", `{"password":"password123"}`, "super_admin", http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			deletion, mock := setupDeletionService(t, nil)
+			mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+				WillReturnRows(userRows(t, tc.role, nil))
+			handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, deletion, nil, nil)
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", bytes.NewBufferString(tc.body))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("user_id", int64(5))
+
+			handler.DeleteAccount(c)
+
+			assert.Equal(t, tc.wantStatus, w.Code)
+		})
+	}
+
+	t.Run("already deleted", func(t *testing.T) {
+		deletion, mock := setupDeletionService(t, nil)
+		purgeAfter := testNow.Add(auth.DeletionGracePeriod)
+		mock.ExpectQuery("SELECT password, role, purge_after FROM users").
+			WillReturnRows(userRows(t, "client", purgeAfter))
+		handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, deletion, nil, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/v1/users/me", bytes.NewBufferString(`{"password":"password123"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", int64(5))
+
+		handler.DeleteAccount(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Message string          `json:"message"`
+			Data    AccountDeletion `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, purgeAfter.Equal(resp.Data.PurgeAfter))
+		assert.Contains(t, resp.Message, "15.03.2026")
+	})
+}
+
+func TestAvatarPurger_PurgeUser(t *testing.T) {
+	setup := func(t *testing.T, store *fakeObjectStore) (*AvatarPurger, sqlmock.Sqlmock) {
+		t.Helper()
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		return NewAvatarPurger(db, logger.New(), store), mock
+	}
+
+	t.Run("no avatar", func(t *testing.T) {
+		purger, mock := setup(t, newFakeObjectStore())
+		mock.ExpectQuery("SELECT avatar_url FROM users").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"avatar_url"}).AddRow(nil))
+
+		n, err := purger.PurgeUser(context.Background(), 5)
+
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failed delete keeps the avatar for the next run", func(t *testing.T) {
+		store := newFakeObjectStore("avatars/5/avatar.png")
+		store.deleteErr = assert.AnError
+		purger, mock := setup(t, store)
+		mock.ExpectQuery("SELECT avatar_url FROM users").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"avatar_url"}).AddRow("https://storage.example/profile/avatars/5/avatar.png"))
+
+		_, err := purger.PurgeUser(context.Background(), 5)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet(), "avatar_url is not cleared")
+	})
+}
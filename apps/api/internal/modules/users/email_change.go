@@ -0,0 +1,376 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/password"
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+const (
+	// EmailChangeTTL is how long the confirmation link of an email change
+	// stays valid
+	EmailChangeTTL = time.Hour
+	// MaxEmailChangesPerHour caps the email change requests of one user per
+	// hour; every request sends two emails
+	MaxEmailChangesPerHour = 3
+)
+
+// errEmailTaken is returned when the new email belongs to another account
+var errEmailTaken = &apperrors.ConflictError{Constraint: "users_email_key", Field: "email"}
+
+// pendingChange is the SQL condition selecting email changes that can
+// still be confirmed at $2
+const pendingChange = `confirmed_at IS NULL AND cancelled_at IS NULL AND expires_at > $2`
+
+// EmailChangeMailer sends the emails of an email change
+type EmailChangeMailer interface {
+	SendEmailChangeConfirmEmail(ctx context.Context, data email.EmailChangeConfirmEmailData) error
+	SendEmailChangeNoticeEmail(ctx context.Context, data email.EmailChangeNoticeEmailData) error
+}
+
+// ChangeEmailRequest starts an email change, confirmed with the password
+type ChangeEmailRequest struct {
+	Password string `json:"password" binding:"required"`
+	NewEmail string `json:"new_email" binding:"required,email,max=255"`
+}
+
+// PendingEmailChange is an email change waiting for confirmation from the
+// new address
+type PendingEmailChange struct {
+	NewEmail  string    `json:"new_email"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// EmailChangeService changes account emails. users.email only changes when
+// the link sent to the new address is opened; the old address is notified
+// of the request so a hijacked session cannot move the account unnoticed.
+type EmailChangeService struct {
+	db     *sql.DB
+	cfg    *config.Config
+	log    *logger.Logger
+	mailer EmailChangeMailer
+	// sessions rejects the access tokens of the sessions signed out by a
+	// confirmed change
+	sessions *auth.SessionBlacklist
+	tokens   *auth.TokenGenerator
+	audit    audit.ServiceInterface
+	now      func() time.Time
+}
+
+// NewEmailChangeService creates a new email change service. Confirmation
+// links point to cfg.EmailChangeConfirmURL.
+func NewEmailChangeService(db *sql.DB, cfg *config.Config, log *logger.Logger, mailer EmailChangeMailer, sessions *auth.SessionBlacklist) *EmailChangeService {
+	return &EmailChangeService{
+		db:       db,
+		cfg:      cfg,
+		log:      log,
+		mailer:   mailer,
+		sessions: sessions,
+		tokens:   auth.NewTokenGenerator(),
+		audit:    audit.NewService(db, log),
+		now:      time.Now,
+	}
+}
+
+// RequestChange checks the password and sends a confirmation link to
+// newEmail, replacing any change still pending. It fails with a
+// *apperrors.ConflictError when newEmail belongs to another account and
+// with a *apperrors.RateLimitError after MaxEmailChangesPerHour requests.
+func (s *EmailChangeService) RequestChange(ctx context.Context, userID int64, plainPassword, newEmail string) (*PendingEmailChange, error) {
+	newEmail = validation.NormalizeEmail(newEmail)
+
+	var currentEmail, hashedPassword string
+	startTime := time.Now()
+	err := s.db.QueryRowContext(ctx,
+		`SELECT email, password FROM users WHERE id = $1`, userID,
+	).Scan(&currentEmail, &hashedPassword)
+	s.log.LogDatabaseQuery("RequestEmailChange.LookupUser", time.Since(startTime), err, map[string]any{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	}
+
+	if err := password.Compare(hashedPassword, plainPassword); err != nil {
+		return nil, fmt.Errorf("RequestEmailChange.VerifyPassword: %w", apperrors.ErrInvalidCredentials)
+	}
+	if newEmail == validation.NormalizeEmail(currentEmail) {
+		return nil, validation.Errors{"new_email": "Это ваш текущий email"}
+	}
+
+	now := s.now()
+	if err := s.checkRateLimit(ctx, userID, now); err != nil {
+		return nil, err
+	}
+	taken, err := emailTaken(ctx, s.db, newEmail, userID)
+	if err != nil {
+		return nil, err
+	}
+	if taken {
+		return nil, errEmailTaken
+	}
+
+	plainToken, hashedToken, err := s.tokens.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	var changeID string
+	change := &PendingEmailChange{NewEmail: newEmail, ExpiresAt: now.Add(EmailChangeTTL)}
+	err = database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE email_changes SET cancelled_at = NOW() WHERE user_id = $1 AND `+pendingChange,
+			userID, now,
+		); err != nil {
+			return fmt.Errorf("failed to cancel pending email change: %w", err)
+		}
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO email_changes (user_id, new_email, token_hash, expires_at)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at`,
+			userID, newEmail, hashedToken, change.ExpiresAt,
+		).Scan(&changeID, &change.CreatedAt); err != nil {
+			return fmt.Errorf("failed to store email change: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mailer.SendEmailChangeConfirmEmail(ctx, email.EmailChangeConfirmEmailData{
+		UserEmail:  newEmail,
+		ConfirmURL: s.cfg.EmailChangeConfirmURL + "?token=" + url.QueryEscape(plainToken),
+		ExpiresAt:  change.ExpiresAt,
+	}); err != nil {
+		// A change nobody can confirm would only block the next request
+		if _, delErr := s.db.ExecContext(ctx, `DELETE FROM email_changes WHERE id = $1`, changeID); delErr != nil {
+			s.log.Errorw("Failed to delete unsent email change", "error", delErr, "user_id", userID)
+		}
+		return nil, fmt.Errorf("failed to send confirmation email: %w", err)
+	}
+
+	// The change is safe to go ahead without the notice: it still needs the
+	// new address
+	if err := s.mailer.SendEmailChangeNoticeEmail(ctx, email.EmailChangeNoticeEmailData{
+		UserEmail:    currentEmail,
+		NewEmail:     newEmail,
+		RequestedAt:  change.CreatedAt,
+		IPAddress:    audit.ActorIPFromContext(ctx),
+		SupportEmail: "support@burcev.team",
+	}); err != nil {
+		s.log.Warnw("Failed to notify old address of email change", "error", err, "user_id", userID)
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &userID,
+		Action:   audit.ActionEmailChangeRequested,
+		Metadata: map[string]any{"new_email": newEmail},
+	})
+	s.log.LogBusinessEvent("email_change_requested", map[string]any{"user_id": userID})
+
+	return change, nil
+}
+
+// checkRateLimit fails with a *apperrors.RateLimitError when the user made
+// MaxEmailChangesPerHour requests in the hour before now
+func (s *EmailChangeService) checkRateLimit(ctx context.Context, userID int64, now time.Time) error {
+	var count int
+	var oldest sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), MIN(created_at) FROM email_changes WHERE user_id = $1 AND created_at > $2`,
+		userID, now.Add(-time.Hour),
+	).Scan(&count, &oldest)
+	if err != nil {
+		return fmt.Errorf("failed to count email changes: %w", err)
+	}
+	if count < MaxEmailChangesPerHour {
+		return nil
+	}
+	s.log.LogSecurityEvent("email_change_rate_limited", "medium", map[string]any{"user_id": userID})
+	return &apperrors.RateLimitError{RetryAfter: oldest.Time.Add(time.Hour).Sub(now)}
+}
+
+// ConfirmChange applies the email change of token. The new email is checked
+// again under the transaction, since another account may have registered
+// it after the request. Every session of the user is signed out, and its
+// access tokens stop working once the change is committed.
+func (s *EmailChangeService) ConfirmChange(ctx context.Context, token string) error {
+	if _, err := hex.DecodeString(token); err != nil || len(token) != 2*auth.DefaultTokenBytes {
+		return apperrors.ErrTokenInvalid
+	}
+
+	now := s.now()
+	var userID int64
+	var oldEmail, newEmail string
+	var revoked []string
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var changeID string
+		var expiresAt time.Time
+		var confirmedAt, cancelledAt sql.NullTime
+		err := tx.QueryRowContext(ctx, `
+			SELECT c.id, c.user_id, c.new_email, c.expires_at, c.confirmed_at, c.cancelled_at, u.email
+			FROM email_changes c
+			JOIN users u ON u.id = c.user_id
+			WHERE c.token_hash = $1
+			FOR UPDATE`,
+			s.tokens.HashToken(token),
+		).Scan(&changeID, &userID, &newEmail, &expiresAt, &confirmedAt, &cancelledAt, &oldEmail)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("lookup: %w", apperrors.ErrTokenInvalid)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to look up email change: %w", err)
+		}
+		if confirmedAt.Valid || cancelledAt.Valid {
+			return fmt.Errorf("already used: %w", apperrors.ErrTokenInvalid)
+		}
+		if now.After(expiresAt) {
+			return fmt.Errorf("expired: %w", apperrors.ErrTokenExpired)
+		}
+
+		taken, err := emailTaken(ctx, tx, newEmail, userID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return errEmailTaken
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE users SET email = $2, token_version = token_version + 1, updated_at = NOW() WHERE id = $1`,
+			userID, newEmail,
+		); err != nil {
+			// The email was registered between the check and the update
+			return database.ConstraintError(err)
+		}
+		revoked, err = revokeSessions(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE email_changes SET confirmed_at = $2 WHERE id = $1`, changeID, now,
+		); err != nil {
+			return fmt.Errorf("failed to mark email change confirmed: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if s.sessions != nil {
+		s.sessions.Revoke(revoked...)
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &userID,
+		Action:   audit.ActionEmailChanged,
+		Metadata: map[string]any{"old_email": oldEmail, "new_email": newEmail},
+	})
+	s.log.LogBusinessEvent("email_changed", map[string]any{"user_id": userID})
+
+	return nil
+}
+
+// revokeSessions revokes the user's refresh tokens and returns their
+// sessions, each once
+func revokeSessions(ctx context.Context, tx *sql.Tx, userID int64) ([]string, error) {
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL RETURNING family_id`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var revoked []string
+	seen := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked session: %w", err)
+		}
+		if !seen[id] {
+			seen[id] = true
+			revoked = append(revoked, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return revoked, nil
+}
+
+// CancelChange cancels the user's pending email change. It returns
+// apperrors.ErrNotFound when there is none.
+func (s *EmailChangeService) CancelChange(ctx context.Context, userID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE email_changes SET cancelled_at = NOW() WHERE user_id = $1 AND `+pendingChange,
+		userID, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel email change: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to cancel email change: %w", err)
+	} else if n == 0 {
+		return apperrors.ErrNotFound
+	}
+	return nil
+}
+
+// Pending returns the user's email change waiting for confirmation, or nil
+// when there is none
+func (s *EmailChangeService) Pending(ctx context.Context, userID int64) (*PendingEmailChange, error) {
+	var change PendingEmailChange
+	err := s.db.QueryRowContext(ctx, `
+		SELECT new_email, expires_at, created_at
+		FROM email_changes
+		WHERE user_id = $1 AND `+pendingChange+`
+		ORDER BY created_at DESC
+		LIMIT 1`,
+		userID, s.now(),
+	).Scan(&change.NewEmail, &change.ExpiresAt, &change.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending email change: %w", err)
+	}
+	return &change, nil
+}
+
+// queryRower is a *sql.DB or *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// emailTaken reports whether an account other than userID uses the
+// normalized email
+func emailTaken(ctx context.Context, q queryRower, normalizedEmail string, userID int64) (bool, error) {
+	var taken bool
+	err := q.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = $1 AND id <> $2)`,
+		normalizedEmail, userID,
+	).Scan(&taken)
+	if err != nil {
+		return false, fmt.Errorf("failed to check email: %w", err)
+	}
+	return taken, nil
+}
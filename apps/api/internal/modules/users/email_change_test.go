@@ -0,0 +1,396 @@
+package users
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	changeID       = "5b0f8a3e-2c1d-4e6f-9a7b-1c2d3e4f5a6b"
+	emailLookupRe  = "SELECT EXISTS \\(SELECT 1 FROM users WHERE LOWER\\(email\\) = \\$1 AND id <> \\$2\\)"
+	oldSessionID   = "7c9e6679-7425-40de-944b-e07fc1f90ae7"
+	changeLookupRe = "FROM email_changes c\\s+JOIN users u ON u.id = c.user_id\\s+WHERE c.token_hash = \\$1\\s+FOR UPDATE"
+)
+
+// fakeEmailChangeMailer records the emails of an email change
+type fakeEmailChangeMailer struct {
+	confirms   []email.EmailChangeConfirmEmailData
+	notices    []email.EmailChangeNoticeEmailData
+	confirmErr error
+}
+
+func (m *fakeEmailChangeMailer) SendEmailChangeConfirmEmail(ctx context.Context, data email.EmailChangeConfirmEmailData) error {
+	m.confirms = append(m.confirms, data)
+	return m.confirmErr
+}
+
+func (m *fakeEmailChangeMailer) SendEmailChangeNoticeEmail(ctx context.Context, data email.EmailChangeNoticeEmailData) error {
+	m.notices = append(m.notices, data)
+	return nil
+}
+
+func setupEmailChangeService(t *testing.T) (*EmailChangeService, sqlmock.Sqlmock, *fakeEmailChangeMailer) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	mailer := &fakeEmailChangeMailer{}
+	cfg := &config.Config{EmailChangeConfirmURL: "https://burcev.team/api/v1/users/confirm-email"}
+	service := NewEmailChangeService(db, cfg, logger.New(), mailer, auth.NewSessionBlacklist(db, logger.New(), 15*time.Minute))
+	service.now = func() time.Time { return testNow }
+	return service, mock, mailer
+}
+
+func emailUserRows(t *testing.T, currentEmail string) *sqlmock.Rows {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return sqlmock.NewRows([]string{"email", "password"}).AddRow(currentEmail, string(hash))
+}
+
+// expectChangeChecks expects the lookups RequestChange makes before storing
+// a change of userID: the user, the hourly count and whether the email is
+// taken
+func expectChangeChecks(t *testing.T, mock sqlmock.Sqlmock, userID int64, recent int, taken bool) {
+	t.Helper()
+	mock.ExpectQuery("SELECT email, password FROM users WHERE id = \\$1").WithArgs(userID).
+		WillReturnRows(emailUserRows(t, "old@example.com"))
+	var oldest any
+	if recent > 0 {
+		oldest = testNow.Add(-40 * time.Minute)
+	}
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\), MIN\\(created_at\\) FROM email_changes").
+		WithArgs(userID, testNow.Add(-time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "min"}).AddRow(recent, oldest))
+	if recent >= MaxEmailChangesPerHour {
+		return
+	}
+	mock.ExpectQuery(emailLookupRe).WithArgs("new@example.com", userID).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(taken))
+}
+
+// confirmToken returns the token of the link in a confirmation email
+func confirmToken(t *testing.T, data email.EmailChangeConfirmEmailData) string {
+	t.Helper()
+	link, err := url.Parse(data.ConfirmURL)
+	require.NoError(t, err)
+	return link.Query().Get("token")
+}
+
+func TestEmailChangeService_RequestChange(t *testing.T) {
+	t.Run("emails both addresses", func(t *testing.T) {
+		service, mock, mailer := setupEmailChangeService(t)
+		expiresAt := testNow.Add(EmailChangeTTL)
+
+		expectChangeChecks(t, mock, 5, 1, false)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE email_changes SET cancelled_at = NOW\\(\\) WHERE user_id = \\$1").
+			WithArgs(int64(5), testNow).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("INSERT INTO email_changes").
+			WithArgs(int64(5), "new@example.com", sqlmock.AnyArg(), expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(changeID, testNow))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(5), sqlmock.AnyArg(), audit.ActionEmailChangeRequested, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		change, err := service.RequestChange(context.Background(), 5, "password123", "  New@Example.com ")
+
+		require.NoError(t, err)
+		assert.Equal(t, &PendingEmailChange{NewEmail: "new@example.com", ExpiresAt: expiresAt, CreatedAt: testNow}, change)
+		require.Len(t, mailer.confirms, 1)
+		assert.Equal(t, "new@example.com", mailer.confirms[0].UserEmail)
+		assert.True(t, strings.HasPrefix(mailer.confirms[0].ConfirmURL, "https://burcev.team/api/v1/users/confirm-email?token="))
+		assert.Len(t, confirmToken(t, mailer.confirms[0]), 2*auth.DefaultTokenBytes)
+		require.Len(t, mailer.notices, 1)
+		assert.Equal(t, "old@example.com", mailer.notices[0].UserEmail)
+		assert.Equal(t, "new@example.com", mailer.notices[0].NewEmail)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service, mock, mailer := setupEmailChangeService(t)
+		mock.ExpectQuery("SELECT email, password FROM users").
+			WillReturnRows(emailUserRows(t, "old@example.com"))
+
+		_, err := service.RequestChange(context.Background(), 5, "wrong", "new@example.com")
+
+		assert.ErrorIs(t, err, apperrors.ErrInvalidCredentials)
+		assert.Empty(t, mailer.confirms)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("current email in another case", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectQuery("SELECT email, password FROM users").
+			WillReturnRows(emailUserRows(t, "old@example.com"))
+
+		_, err := service.RequestChange(context.Background(), 5, "password123", "OLD@example.com")
+
+		var fields validation.Errors
+		require.ErrorAs(t, err, &fields)
+		assert.Contains(t, fields, "new_email")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("email of another account", func(t *testing.T) {
+		service, mock, mailer := setupEmailChangeService(t)
+		expectChangeChecks(t, mock, 5, 0, true)
+
+		_, err := service.RequestChange(context.Background(), 5, "password123", "new@example.com")
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.Empty(t, mailer.confirms)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rate limited", func(t *testing.T) {
+		service, mock, mailer := setupEmailChangeService(t)
+		expectChangeChecks(t, mock, 5, MaxEmailChangesPerHour, false)
+
+		_, err := service.RequestChange(context.Background(), 5, "password123", "new@example.com")
+
+		var limited *apperrors.RateLimitError
+		require.ErrorAs(t, err, &limited)
+		assert.Equal(t, 20*time.Minute, limited.RetryAfter, "the oldest request leaves the window")
+		assert.Empty(t, mailer.confirms)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unsent confirmation is removed", func(t *testing.T) {
+		service, mock, mailer := setupEmailChangeService(t)
+		mailer.confirmErr = errors.New("smtp down")
+
+		expectChangeChecks(t, mock, 5, 0, false)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE email_changes SET cancelled_at").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("INSERT INTO email_changes").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(changeID, testNow))
+		mock.ExpectCommit()
+		mock.ExpectExec("DELETE FROM email_changes WHERE id = \\$1").WithArgs(changeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.RequestChange(context.Background(), 5, "password123", "new@example.com")
+
+		assert.Error(t, err)
+		assert.Empty(t, mailer.notices, "the old address is only told about a change that can happen")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// expectChangeLookup expects the locked lookup of token's change
+func expectChangeLookup(mock sqlmock.Sqlmock, token string, expiresAt time.Time, confirmedAt any) {
+	mock.ExpectQuery(changeLookupRe).
+		WithArgs(auth.NewTokenGenerator().HashToken(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "new_email", "expires_at", "confirmed_at", "cancelled_at", "email"}).
+			AddRow(changeID, int64(5), "new@example.com", expiresAt, confirmedAt, nil, "old@example.com"))
+}
+
+func TestEmailChangeService_ConfirmChange(t *testing.T) {
+	token := strings.Repeat("ab", auth.DefaultTokenBytes)
+
+	t.Run("switches the email and signs out", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(30*time.Minute), nil)
+		mock.ExpectQuery(emailLookupRe).WithArgs("new@example.com", int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec("UPDATE users SET email = \\$2, token_version = token_version \\+ 1").
+			WithArgs(int64(5), "new@example.com").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE refresh_tokens SET revoked_at = NOW\\(\\) WHERE user_id = \\$1 AND revoked_at IS NULL RETURNING family_id").
+			WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"family_id"}).AddRow(oldSessionID).AddRow(oldSessionID))
+		mock.ExpectExec("UPDATE email_changes SET confirmed_at = \\$2 WHERE id = \\$1").
+			WithArgs(changeID, testNow).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(5), sqlmock.AnyArg(), audit.ActionEmailChanged, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		cfg := &config.Config{JWTSecret: "test-secret"}
+		router := gin.New()
+		router.Use(middleware.RejectRevokedSessions(cfg, service.sessions))
+		router.GET("/users/profile", func(c *gin.Context) { c.Status(http.StatusOK) })
+		oldToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.UserClaims{
+			UserID:           5,
+			Email:            "old@example.com",
+			Role:             "client",
+			SessionID:        oldSessionID,
+			RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(10 * time.Minute))},
+		}).SignedString([]byte(cfg.JWTSecret))
+		require.NoError(t, err)
+		getProfile := func() int {
+			req := httptest.NewRequest(http.MethodGet, "/users/profile", nil)
+			req.Header.Set("Authorization", "Bearer "+oldToken)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w.Code
+		}
+		require.Equal(t, http.StatusOK, getProfile())
+
+		require.NoError(t, service.ConfirmChange(context.Background(), token))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, http.StatusUnauthorized, getProfile(), "access tokens issued before the change are rejected")
+	})
+
+	t.Run("email registered by another account after the request", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(30*time.Minute), nil)
+		mock.ExpectQuery(emailLookupRe).WithArgs("new@example.com", int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+
+		err := service.ConfirmChange(context.Background(), token)
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		assert.NoError(t, mock.ExpectationsWereMet(), "the email is not touched")
+	})
+
+	t.Run("email registered between the check and the update", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(30*time.Minute), nil)
+		mock.ExpectQuery(emailLookupRe).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectExec("UPDATE users SET email").
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+		mock.ExpectRollback()
+
+		err := service.ConfirmChange(context.Background(), token)
+
+		var conflict *apperrors.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "email", conflict.Field)
+		assert.NoError(t, mock.ExpectationsWereMet(), "sessions are kept and the change stays unconfirmed")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(-time.Minute), nil)
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, service.ConfirmChange(context.Background(), token), apperrors.ErrTokenExpired)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("already confirmed", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(30*time.Minute), testNow.Add(-time.Minute))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, service.ConfirmChange(context.Background(), token), apperrors.ErrTokenInvalid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+
+		assert.ErrorIs(t, service.ConfirmChange(context.Background(), "abc"), apperrors.ErrTokenInvalid)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEmailChangeService_CancelChange(t *testing.T) {
+	service, mock, _ := setupEmailChangeService(t)
+	mock.ExpectExec("UPDATE email_changes SET cancelled_at = NOW\\(\\) WHERE user_id = \\$1 AND confirmed_at IS NULL").
+		WithArgs(int64(5), testNow).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE email_changes SET cancelled_at").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, service.CancelChange(context.Background(), 5))
+	assert.ErrorIs(t, service.CancelChange(context.Background(), 5), apperrors.ErrNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHandler_EmailChange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("wrong password", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectQuery("SELECT email, password FROM users").
+			WillReturnRows(emailUserRows(t, "old@example.com"))
+		handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, nil, nil, service)
+
+		status, resp := serveAs(t, handler.ChangeEmail, http.MethodPost, `{"password":"wrong","new_email":"new@example.com"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Equal(t, "AUTH_WRONG_PASSWORD", resp["code"])
+	})
+
+	t.Run("email taken", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		expectChangeChecks(t, mock, 123, 0, true)
+		handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, nil, nil, service)
+
+		status, resp := serveAs(t, handler.ChangeEmail, http.MethodPost, `{"password":"password123","new_email":"new@example.com"}`)
+
+		assert.Equal(t, http.StatusConflict, status)
+		assert.Equal(t, "new_email", resp["details"].(map[string]interface{})["field"])
+	})
+
+	t.Run("confirm link of a taken email", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		token := strings.Repeat("cd", auth.DefaultTokenBytes)
+		mock.ExpectBegin()
+		expectChangeLookup(mock, token, testNow.Add(30*time.Minute), nil)
+		mock.ExpectQuery(emailLookupRe).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectRollback()
+		handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, nil, nil, service)
+
+		router := gin.New()
+		router.GET("/api/v1/users/confirm-email", handler.ConfirmEmail)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/confirm-email?token="+token, nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("profile shows the pending change", func(t *testing.T) {
+		service, mock, _ := setupEmailChangeService(t)
+		mock.ExpectQuery("SELECT new_email, expires_at, created_at\\s+FROM email_changes").
+			WithArgs(int64(123), testNow).
+			WillReturnRows(sqlmock.NewRows([]string{"new_email", "expires_at", "created_at"}).
+				AddRow("new@example.com", testNow.Add(time.Hour), testNow))
+		handler := NewHandler(&config.Config{}, logger.New(), &mockService{profile: &FullProfile{ID: 123, Email: "old@example.com"}}, nil, nil, nil, nil, nil, service)
+
+		status, resp := serveAs(t, handler.GetProfile, http.MethodGet, "")
+
+		assert.Equal(t, http.StatusOK, status)
+		profile := resp["data"].(map[string]interface{})["profile"].(map[string]interface{})
+		assert.Equal(t, "new@example.com", profile["pending_email_change"].(map[string]interface{})["new_email"])
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,628 @@
+package users
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+)
+
+const (
+	// ExportLinkTTL is how long a finished export can be downloaded
+	ExportLinkTTL = 48 * time.Hour
+	// MaxExportAttempts caps how often the worker tries to build an export
+	MaxExportAttempts = 3
+	// ExportCleanupInterval is how often expired archives are removed
+	ExportCleanupInterval = time.Hour
+	// exportLease is how long a claimed export is hidden from other workers;
+	// an export still processing after it is picked up again
+	exportLease = 10 * time.Minute
+	// exportFetchSize is the number of rows fetched from a cursor at a time,
+	// which bounds the memory an export needs regardless of history length
+	exportFetchSize = 500
+	// exportCleanupBatch caps the archives removed in one cleanup run
+	exportCleanupBatch = 100
+)
+
+var (
+	// ErrExportInProgress is returned when the user already has an export
+	// waiting or being built
+	ErrExportInProgress = errors.New("data export already in progress")
+	// ErrExportExpired is returned for a download link past its expiry
+	ErrExportExpired = errors.New("data export expired")
+)
+
+// ExportMailer sends the download link of a finished export
+type ExportMailer interface {
+	SendDataExportEmail(ctx context.Context, data email.DataExportEmailData) error
+}
+
+// DataExport is a requested archive of the user's data
+type DataExport struct {
+	ID        string    `json:"id"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportArchive is a finished export opened for download
+type ExportArchive struct {
+	FileName  string
+	SizeBytes int64
+}
+
+// exportFile is a JSON file of the archive. query selects one jsonb document
+// per row for the user $1; single files hold one object instead of an array.
+type exportFile struct {
+	name   string
+	query  string
+	single bool
+}
+
+// exportFiles are the files of an export archive, in archive order.
+// Credentials and storage internals are stripped from the documents.
+var exportFiles = []exportFile{
+	{name: "profile.json", single: true,
+		query: `SELECT to_jsonb(u) - 'password' FROM users u WHERE id = $1`},
+	{name: "nutrition_entries.json",
+		query: `SELECT to_jsonb(e) - 'user_id' - 'food_search' FROM nutrition_entries e WHERE user_id = $1 ORDER BY date, created_at`},
+	{name: "food_entries.json",
+		query: `SELECT to_jsonb(e) - 'user_id' FROM food_entries e WHERE user_id = $1 ORDER BY date, created_at`},
+	{name: "daily_metrics.json",
+		query: `SELECT to_jsonb(m) - 'user_id' FROM daily_metrics m WHERE user_id = $1 ORDER BY date`},
+	{name: "body_measurements.json",
+		query: `SELECT to_jsonb(m) - 'user_id' FROM body_measurements m WHERE user_id = $1 ORDER BY date`},
+	{name: "progress_photos.json",
+		query: `SELECT to_jsonb(p) - 'user_id' - 'storage_key' - 'storage_region' FROM progress_photos p WHERE user_id = $1 ORDER BY taken_on, created_at`},
+	{name: "audit_events.json",
+		query: `SELECT to_jsonb(a) - 'user_id' FROM audit_log a WHERE user_id = $1 ORDER BY created_at`},
+}
+
+// ExportService builds archives of everything stored about a user. A
+// request only queues the export; RunWorker builds the archive, stores it
+// and emails a signed link that is valid for ExportLinkTTL.
+type ExportService struct {
+	db     *sql.DB
+	cfg    *config.Config
+	log    *logger.Logger
+	files  *storage.Router
+	mailer ExportMailer
+	tokens *auth.TokenGenerator
+	audit  audit.ServiceInterface
+	now    func() time.Time
+}
+
+// NewExportService creates a new data export service. Archives are kept in
+// the storage region of the user's organization, as routed by files.
+func NewExportService(db *sql.DB, cfg *config.Config, log *logger.Logger, files *storage.Router, mailer ExportMailer) *ExportService {
+	return &ExportService{
+		db:     db,
+		cfg:    cfg,
+		log:    log,
+		files:  files,
+		mailer: mailer,
+		tokens: auth.NewTokenGenerator(),
+		audit:  audit.NewService(db, log),
+		now:    time.Now,
+	}
+}
+
+// RequestExport queues an export of the user's data. It fails with
+// ErrExportInProgress while a previous export is still being built.
+func (s *ExportService) RequestExport(ctx context.Context, userID int64) (*DataExport, error) {
+	token, _, err := s.tokens.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO data_exports (user_id, token)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) WHERE status IN ('pending', 'processing') DO NOTHING
+		RETURNING id, status, created_at`
+
+	var export DataExport
+	err = s.db.QueryRowContext(ctx, query, userID, token).Scan(&export.ID, &export.Status, &export.CreatedAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportInProgress
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue data export: %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID:   &userID,
+		Action:   audit.ActionDataExportRequested,
+		Metadata: map[string]any{"export_id": export.ID},
+	})
+
+	return &export, nil
+}
+
+// ProcessNextExport builds the oldest queued export and reports whether
+// there was one. A failed attempt is retried after exportLease until
+// MaxExportAttempts is reached.
+func (s *ExportService) ProcessNextExport(ctx context.Context) (bool, error) {
+	startTime := time.Now()
+	claimQuery := `
+		UPDATE data_exports
+		SET status = 'processing', attempts = attempts + 1,
+		    next_attempt_at = NOW() + $1 * INTERVAL '1 second'
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status IN ('pending', 'processing') AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id, token, attempts`
+
+	var id, token string
+	var userID int64
+	var attempts int
+	err := s.db.QueryRowContext(ctx, claimQuery, int(exportLease.Seconds())).Scan(&id, &userID, &token, &attempts)
+	s.log.LogDatabaseQuery(claimQuery, time.Since(startTime), err, nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim data export: %w", err)
+	}
+
+	// The previous attempt's lease ran out without it finishing or failing
+	if attempts > MaxExportAttempts {
+		return true, s.markExportFailed(ctx, id, fmt.Errorf("export did not finish after %d attempts", MaxExportAttempts))
+	}
+
+	key := exportStorageKey(token)
+	region, store, err := s.files.ForUser(ctx, userID)
+	var size int64
+	if err == nil {
+		size, err = s.buildArchive(ctx, store, userID, key)
+	}
+	if err != nil {
+		s.log.Error("Data export attempt failed", "error", err, "export_id", id, "attempt", attempts)
+		if attempts < MaxExportAttempts {
+			return true, err
+		}
+		return true, s.markExportFailed(ctx, id, err)
+	}
+
+	expiresAt := s.now().Add(ExportLinkTTL)
+	startTime = time.Now()
+	query := `
+		UPDATE data_exports e
+		SET status = 'ready', storage_key = $2, storage_region = $3, size_bytes = $4, expires_at = $5, completed_at = NOW()
+		FROM users u
+		WHERE e.id = $1 AND u.id = e.user_id
+		RETURNING u.email`
+
+	var userEmail string
+	err = s.db.QueryRowContext(ctx, query, id, key, region, size, expiresAt).Scan(&userEmail)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"export_id": id})
+	if err != nil {
+		return true, fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	// The archive stays downloadable when the email fails; it is only logged
+	if err := s.mailer.SendDataExportEmail(ctx, email.DataExportEmailData{
+		UserEmail:   userEmail,
+		DownloadURL: s.DownloadURL(token, userID, expiresAt),
+		ExpiresAt:   expiresAt,
+	}); err != nil {
+		s.log.Error("Failed to send data export email", "error", err, "export_id", id, "user_id", userID)
+	}
+
+	s.log.LogBusinessEvent("data_export_ready", map[string]any{
+		"export_id":  id,
+		"user_id":    userID,
+		"size_bytes": size,
+	})
+	return true, nil
+}
+
+func (s *ExportService) markExportFailed(ctx context.Context, id string, cause error) error {
+	startTime := time.Now()
+	query := `UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW() WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id, cause.Error())
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"export_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to mark data export failed: %w", err)
+	}
+	return nil
+}
+
+// buildArchive writes the user's archive to key in store and returns its
+// size. The archive is streamed into the store while it is assembled, so
+// neither the rows nor the ZIP are ever held in memory as a whole.
+func (s *ExportService) buildArchive(ctx context.Context, store storage.Storage, userID int64, key string) (int64, error) {
+	pr, pw := io.Pipe()
+	counter := &countingWriter{w: pw}
+	done := make(chan error, 1)
+	go func() {
+		err := s.writeUserArchive(ctx, counter, userID)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+
+	putErr := store.Put(ctx, key, pr)
+	// Unblocks the writer if the store gave up before reading everything
+	pr.CloseWithError(io.ErrClosedPipe)
+	writeErr := <-done
+
+	if err := errors.Join(writeErr, putErr); err != nil {
+		if delErr := store.Delete(ctx, key); delErr != nil && !errors.Is(delErr, storage.ErrObjectNotFound) {
+			s.log.Error("Failed to delete incomplete data export", "error", delErr, "key", key)
+		}
+		return 0, err
+	}
+	return counter.n, nil
+}
+
+// writeUserArchive writes the archive of the user's data to w. All files are
+// read in one read-only transaction so the archive is a consistent snapshot.
+func (s *ExportService) writeUserArchive(ctx context.Context, w io.Writer, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("failed to begin export transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	return writeArchive(w, exportFiles, s.now(), func(f exportFile, emit func(json.RawMessage) error) error {
+		startTime := time.Now()
+		err := streamRows(ctx, tx, f.query, userID, emit)
+		s.log.LogDatabaseQuery("Export."+f.name, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		return err
+	})
+}
+
+// streamRows runs query through a server-side cursor and passes each
+// document to emit, holding at most exportFetchSize rows at a time
+func streamRows(ctx context.Context, tx *sql.Tx, query string, userID int64, emit func(json.RawMessage) error) error {
+	if _, err := tx.ExecContext(ctx, `DECLARE export_rows NO SCROLL CURSOR FOR `+query, userID); err != nil {
+		return fmt.Errorf("failed to open export cursor: %w", err)
+	}
+
+	fetch := fmt.Sprintf(`FETCH %d FROM export_rows`, exportFetchSize)
+	for {
+		rows, err := tx.QueryContext(ctx, fetch)
+		if err != nil {
+			return fmt.Errorf("failed to fetch export rows: %w", err)
+		}
+		n := 0
+		for rows.Next() {
+			var doc []byte
+			if err := rows.Scan(&doc); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan export row: %w", err)
+			}
+			n++
+			if err := emit(doc); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to fetch export rows: %w", err)
+		}
+		if n < exportFetchSize {
+			break
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `CLOSE export_rows`); err != nil {
+		return fmt.Errorf("failed to close export cursor: %w", err)
+	}
+	return nil
+}
+
+// writeArchive writes a ZIP with one JSON file per entry of files to w.
+// fetch streams a file's documents; each is written as soon as it arrives.
+func writeArchive(w io.Writer, files []exportFile, modified time.Time, fetch func(f exportFile, emit func(json.RawMessage) error) error) error {
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", f.name, err)
+		}
+		if err := writeJSONFile(fw, f, fetch); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeJSONFile writes the documents of f as a JSON array, or as a single
+// document (null when there is none) for single files
+func writeJSONFile(w io.Writer, f exportFile, fetch func(f exportFile, emit func(json.RawMessage) error) error) error {
+	count := 0
+	err := fetch(f, func(doc json.RawMessage) error {
+		if !json.Valid(doc) {
+			return errors.New("row is not a JSON document")
+		}
+		sep := ",\n  "
+		switch {
+		case f.single && count > 0:
+			return errors.New("more than one document for a single-object file")
+		case f.single:
+			sep = ""
+		case count == 0:
+			sep = "[\n  "
+		}
+		count++
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		_, err := w.Write(doc)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	end := "\n]\n"
+	switch {
+	case f.single && count == 0:
+		end = "null\n"
+	case f.single:
+		end = "\n"
+	case count == 0:
+		end = "[]\n"
+	}
+	_, err = io.WriteString(w, end)
+	return err
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// exportStorageKey is where the archive with token is stored. The random
+// token keeps archive names unguessable.
+func exportStorageKey(token string) string {
+	return "exports/" + token + ".zip"
+}
+
+// signExportLink returns the signature of a download link: the hex
+// HMAC-SHA256, keyed with secret, of the token, owner and expiry
+func signExportLink(secret, token string, userID int64, expiresAt time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("data-export." + token + "." + strconv.FormatInt(userID, 10) + "." + strconv.FormatInt(expiresAt.Unix(), 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyExportLink reports whether signature signs the link of token for userID
+func verifyExportLink(secret, token string, userID int64, expiresAt time.Time, signature string) bool {
+	return hmac.Equal([]byte(signExportLink(secret, token, userID, expiresAt)), []byte(signature))
+}
+
+// DownloadURL returns the signed link emailed for the export with token
+func (s *ExportService) DownloadURL(token string, userID int64, expiresAt time.Time) string {
+	return s.cfg.DataExportURL + "/" + url.PathEscape(token) + "?" + url.Values{
+		"expires":   {strconv.FormatInt(expiresAt.Unix(), 10)},
+		"signature": {signExportLink(s.cfg.JWTSecret, token, userID, expiresAt)},
+	}.Encode()
+}
+
+// OpenExport opens the archive with token for download by its owner.
+// expires and signature are the query parameters of the emailed link. A
+// link signed for another user fails with apperrors.ErrForbidden.
+func (s *ExportService) OpenExport(ctx context.Context, userID int64, token string, expires int64, signature string) (*ExportArchive, io.ReadCloser, error) {
+	expiresAt := time.Unix(expires, 0)
+	if !verifyExportLink(s.cfg.JWTSecret, token, userID, expiresAt, signature) {
+		return nil, nil, apperrors.ErrForbidden
+	}
+	if !s.now().Before(expiresAt) {
+		return nil, nil, ErrExportExpired
+	}
+
+	var key sql.NullString
+	var region string
+	var size sql.NullInt64
+	var storedExpiry sql.NullTime
+	var createdAt time.Time
+	startTime := time.Now()
+	err := s.db.QueryRowContext(ctx,
+		`SELECT storage_key, storage_region, size_bytes, expires_at, created_at FROM data_exports
+		 WHERE token = $1 AND user_id = $2 AND status = 'ready'`,
+		token, userID,
+	).Scan(&key, &region, &size, &storedExpiry, &createdAt)
+	s.log.LogDatabaseQuery("OpenExport", time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, apperrors.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up data export: %w", err)
+	}
+	if !key.Valid || !storedExpiry.Valid || !s.now().Before(storedExpiry.Time) {
+		return nil, nil, ErrExportExpired
+	}
+
+	store, err := s.files.ForRegion(region)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := store.Get(ctx, key.String)
+	if errors.Is(err, storage.ErrObjectNotFound) {
+		return nil, nil, ErrExportExpired
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open data export: %w", err)
+	}
+
+	s.audit.Record(ctx, audit.Entry{
+		UserID: &userID,
+		Action: audit.ActionDataExportDownloaded,
+	})
+
+	return &ExportArchive{
+		FileName:  "burcev-export-" + createdAt.Format("2006-01-02") + ".zip",
+		SizeBytes: size.Int64,
+	}, r, nil
+}
+
+// CleanupExpired deletes expired archives and returns how many were removed.
+// Export rows are kept so the in-flight check and history stay intact.
+func (s *ExportService) CleanupExpired(ctx context.Context) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, storage_key, storage_region FROM data_exports
+		 WHERE storage_key IS NOT NULL AND expires_at <= $1
+		 ORDER BY expires_at LIMIT $2`,
+		s.now(), exportCleanupBatch,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+	type expired struct{ id, key, region string }
+	var exports []expired
+	for rows.Next() {
+		var e expired
+		if err := rows.Scan(&e.id, &e.key, &e.region); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan expired data export: %w", err)
+		}
+		exports = append(exports, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list expired data exports: %w", err)
+	}
+
+	removed := 0
+	for _, e := range exports {
+		if err := s.deleteArchive(ctx, e.region, e.key); err != nil {
+			s.log.Error("Failed to delete expired data export", "error", err, "export_id", e.id, "region", e.region)
+			continue
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE data_exports SET storage_key = NULL WHERE id = $1`, e.id); err != nil {
+			return removed, fmt.Errorf("failed to mark data export removed: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// PurgeUser deletes the user's archives and then their export rows, for an
+// account being purged. Returns the number of archives deleted.
+func (s *ExportService) PurgeUser(ctx context.Context, userID int64) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT storage_key, storage_region FROM data_exports WHERE user_id = $1 AND storage_key IS NOT NULL`, userID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list user data exports: %w", err)
+	}
+	type archive struct{ key, region string }
+	var archives []archive
+	for rows.Next() {
+		var a archive
+		if err := rows.Scan(&a.key, &a.region); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan user data export: %w", err)
+		}
+		archives = append(archives, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to list user data exports: %w", err)
+	}
+
+	// The rows stay until every archive is gone, so a failure is retried
+	for _, a := range archives {
+		if err := s.deleteArchive(ctx, a.region, a.key); err != nil {
+			return 0, fmt.Errorf("failed to delete data export: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM data_exports WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to delete user data exports: %w", err)
+	}
+	return len(archives), nil
+}
+
+// deleteArchive removes an archive from the region it was written to. A
+// missing archive is not an error.
+func (s *ExportService) deleteArchive(ctx context.Context, region, key string) error {
+	store, err := s.files.ForRegion(region)
+	if err != nil {
+		return err
+	}
+	if err := store.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrObjectNotFound) {
+		return err
+	}
+	return nil
+}
+
+// RunWorker builds queued exports until ctx is cancelled
+func (s *ExportService) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("Data export worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessNextExport(ctx)
+				if err != nil {
+					s.log.Error("Failed to process data export", "error", err)
+					break
+				}
+				if !processed || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Data export worker stopped")
+			return
+		}
+	}
+}
+
+// RunCleanup removes expired archives every ExportCleanupInterval until ctx
+// is cancelled. Archives still within their lifetime when an account is
+// purged are removed by PurgeUser, which the purge runs before the rows go.
+func (s *ExportService) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(ExportCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			removed, err := s.CleanupExpired(ctx)
+			if err != nil {
+				s.log.Error("Failed to clean up data exports", "error", err)
+			} else if removed > 0 {
+				s.log.Info("Removed expired data exports", "count", removed)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
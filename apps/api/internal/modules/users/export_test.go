@@ -0,0 +1,533 @@
+package users
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportToken = "0f8c2d1e"
+
+func setupExportService(t *testing.T, store storage.Storage) (*ExportService, sqlmock.Sqlmock, *email.MemorySender) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	sender := email.NewMemorySender()
+	mailer, err := email.NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+
+	cfg := &config.Config{JWTSecret: "test-secret", DataExportURL: "https://burcev.team/data-export"}
+	service := NewExportService(db, cfg, logger.New(), storage.NewRouter(store, nil, nil), mailer)
+	service.now = func() time.Time { return testNow }
+	return service, mock, sender
+}
+
+// readArchive returns the files of a ZIP archive by name
+func readArchive(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(r)
+		require.NoError(t, err)
+		r.Close()
+		files[f.Name] = string(b)
+	}
+	return files
+}
+
+func TestWriteArchive(t *testing.T) {
+	files := []exportFile{
+		{name: "profile.json", single: true},
+		{name: "entries.json"},
+		{name: "empty.json"},
+		{name: "missing.json", single: true},
+	}
+	docs := map[string][]string{
+		"profile.json": {`{"id": 5, "email": "user@example.com"}`},
+		"entries.json": {`{"food": "Овсянка"}`, `{"food": "Яблоко"}`},
+	}
+
+	var buf bytes.Buffer
+	err := writeArchive(&buf, files, testNow, func(f exportFile, emit func(json.RawMessage) error) error {
+		for _, doc := range docs[f.name] {
+			if err := emit(json.RawMessage(doc)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	archive := readArchive(t, buf.Bytes())
+	require.Len(t, archive, 4)
+
+	var profile map[string]any
+	require.NoError(t, json.Unmarshal([]byte(archive["profile.json"]), &profile))
+	assert.Equal(t, "user@example.com", profile["email"])
+
+	var entries []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(archive["entries.json"]), &entries))
+	assert.Equal(t, []map[string]string{{"food": "Овсянка"}, {"food": "Яблоко"}}, entries)
+
+	assert.JSONEq(t, `[]`, archive["empty.json"])
+	assert.JSONEq(t, `null`, archive["missing.json"])
+}
+
+func TestWriteArchive_Errors(t *testing.T) {
+	t.Run("fetch failure", func(t *testing.T) {
+		err := writeArchive(io.Discard, []exportFile{{name: "entries.json"}}, testNow,
+			func(exportFile, func(json.RawMessage) error) error { return errors.New("connection reset") })
+		assert.ErrorContains(t, err, "entries.json")
+	})
+
+	t.Run("invalid document", func(t *testing.T) {
+		err := writeArchive(io.Discard, []exportFile{{name: "entries.json"}}, testNow,
+			func(_ exportFile, emit func(json.RawMessage) error) error { return emit(json.RawMessage(`{"food":`)) })
+		assert.Error(t, err)
+	})
+
+	t.Run("second document in a single file", func(t *testing.T) {
+		err := writeArchive(io.Discard, []exportFile{{name: "profile.json", single: true}}, testNow,
+			func(_ exportFile, emit func(json.RawMessage) error) error {
+				if err := emit(json.RawMessage(`{}`)); err != nil {
+					return err
+				}
+				return emit(json.RawMessage(`{}`))
+			})
+		assert.Error(t, err)
+	})
+}
+
+func TestExportLinkSigning(t *testing.T) {
+	expiresAt := testNow.Add(ExportLinkTTL)
+	signature := signExportLink("secret", exportToken, 5, expiresAt)
+
+	assert.True(t, verifyExportLink("secret", exportToken, 5, expiresAt, signature))
+	assert.False(t, verifyExportLink("other-secret", exportToken, 5, expiresAt, signature), "other key")
+	assert.False(t, verifyExportLink("secret", "0f8c2d1f", 5, expiresAt, signature), "other token")
+	assert.False(t, verifyExportLink("secret", exportToken, 6, expiresAt, signature), "other user")
+	assert.False(t, verifyExportLink("secret", exportToken, 5, expiresAt.Add(time.Hour), signature), "extended expiry")
+	assert.False(t, verifyExportLink("secret", exportToken, 5, expiresAt, ""), "no signature")
+
+	service, _, _ := setupExportService(t, nil)
+	link, err := url.Parse(service.DownloadURL(exportToken, 5, expiresAt))
+	require.NoError(t, err)
+	assert.Equal(t, "/data-export/"+exportToken, link.Path)
+	assert.Equal(t, strconv.FormatInt(expiresAt.Unix(), 10), link.Query().Get("expires"))
+	assert.True(t, verifyExportLink("test-secret", exportToken, 5, expiresAt, link.Query().Get("signature")))
+}
+
+func TestExportService_RequestExport(t *testing.T) {
+	t.Run("queues an export", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, nil)
+		mock.ExpectQuery("INSERT INTO data_exports").
+			WithArgs(int64(5), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}).AddRow("exp-1", "pending", testNow))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(5), nil, audit.ActionDataExportRequested, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		export, err := service.RequestExport(context.Background(), 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, "exp-1", export.ID)
+		assert.Equal(t, "pending", export.Status)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("one export in flight", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, nil)
+		mock.ExpectQuery("INSERT INTO data_exports").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}))
+
+		_, err := service.RequestExport(context.Background(), 5)
+
+		assert.ErrorIs(t, err, ErrExportInProgress)
+	})
+}
+
+// expectExportTables expects every export file to be streamed through a
+// cursor, returning docs for the files listed there and nothing otherwise
+func expectExportTables(mock sqlmock.Sqlmock, docs map[string][]string) {
+	mock.ExpectBegin()
+	for _, f := range exportFiles {
+		mock.ExpectExec("DECLARE export_rows NO SCROLL CURSOR FOR " + regexp.QuoteMeta(f.query)).
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		rows := sqlmock.NewRows([]string{"to_jsonb"})
+		for _, doc := range docs[f.name] {
+			rows.AddRow([]byte(doc))
+		}
+		mock.ExpectQuery("FETCH 500 FROM export_rows").WillReturnRows(rows)
+		mock.ExpectExec("CLOSE export_rows").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectRollback()
+}
+
+func TestExportService_ProcessNextExport(t *testing.T) {
+	claimColumns := []string{"id", "user_id", "token", "attempts"}
+
+	t.Run("builds the archive and emails the link", func(t *testing.T) {
+		store := storage.NewMemoryStorage()
+		service, mock, sender := setupExportService(t, store)
+		expiresAt := testNow.Add(ExportLinkTTL)
+
+		mock.ExpectQuery("UPDATE data_exports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow("exp-1", int64(5), exportToken, 1))
+		expectExportTables(mock, map[string][]string{
+			"profile.json":           {`{"id": 5, "email": "user@example.com"}`},
+			"nutrition_entries.json": {`{"food": "Овсянка", "calories": 350}`},
+			"audit_events.json":      {`{"action": "login"}`, `{"action": "password_changed"}`},
+		})
+		mock.ExpectQuery("UPDATE data_exports e\\s+SET status = 'ready'").
+			WithArgs("exp-1", "exports/"+exportToken+".zip", "", sqlmock.AnyArg(), expiresAt).
+			WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+
+		processed, err := service.ProcessNextExport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+
+		require.Equal(t, []string{"exports/" + exportToken + ".zip"}, store.Keys())
+		r, err := store.Get(context.Background(), "exports/"+exportToken+".zip")
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		archive := readArchive(t, data)
+		assert.Len(t, archive, len(exportFiles))
+		assert.Contains(t, archive["profile.json"], "user@example.com")
+		assert.Contains(t, archive["nutrition_entries.json"], "Овсянка")
+		assert.JSONEq(t, `[]`, archive["daily_metrics.json"])
+		var events []map[string]string
+		require.NoError(t, json.Unmarshal([]byte(archive["audit_events.json"]), &events))
+		assert.Len(t, events, 2)
+
+		messages := sender.Messages()
+		require.Len(t, messages, 1)
+		assert.Equal(t, "user@example.com", messages[0].To)
+		assert.Contains(t, messages[0].HTMLBody, "https://burcev.team/data-export/"+exportToken)
+	})
+
+	t.Run("failed attempt leaves no file and is retried", func(t *testing.T) {
+		store := storage.NewMemoryStorage()
+		service, mock, sender := setupExportService(t, store)
+
+		mock.ExpectQuery("UPDATE data_exports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow("exp-1", int64(5), exportToken, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE export_rows").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		processed, err := service.ProcessNextExport(context.Background())
+
+		assert.True(t, processed)
+		assert.ErrorContains(t, err, "connection reset")
+		assert.Empty(t, store.Keys())
+		assert.Empty(t, sender.Messages())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("gives up after the last attempt", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, storage.NewMemoryStorage())
+
+		mock.ExpectQuery("UPDATE data_exports").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow("exp-1", int64(5), exportToken, MaxExportAttempts+1))
+		mock.ExpectExec("UPDATE data_exports SET status = 'failed'").
+			WithArgs("exp-1", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := service.ProcessNextExport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nothing queued", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, nil)
+		mock.ExpectQuery("UPDATE data_exports").WillReturnRows(sqlmock.NewRows(claimColumns))
+
+		processed, err := service.ProcessNextExport(context.Background())
+
+		require.NoError(t, err)
+		assert.False(t, processed)
+	})
+}
+
+func TestStreamRows_FetchesInBatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	full := sqlmock.NewRows([]string{"to_jsonb"})
+	for i := 0; i < exportFetchSize; i++ {
+		full.AddRow([]byte(`{}`))
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DECLARE export_rows").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("FETCH 500 FROM export_rows").WillReturnRows(full)
+	mock.ExpectQuery("FETCH 500 FROM export_rows").WillReturnRows(sqlmock.NewRows([]string{"to_jsonb"}).AddRow([]byte(`{}`)))
+	mock.ExpectExec("CLOSE export_rows").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+	count := 0
+	err = streamRows(context.Background(), tx, exportFiles[1].query, 5, func(json.RawMessage) error {
+		count++
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, exportFetchSize+1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportService_CleanupExpired(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	require.NoError(t, store.Put(context.Background(), "exports/old.zip", strings.NewReader("zip")))
+	service, mock, _ := setupExportService(t, store)
+
+	mock.ExpectQuery("SELECT id, storage_key, storage_region FROM data_exports").
+		WithArgs(testNow, exportCleanupBatch).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage_key", "storage_region"}).AddRow("exp-1", "exports/old.zip", ""))
+	mock.ExpectExec("UPDATE data_exports SET storage_key = NULL").
+		WithArgs("exp-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	removed, err := service.CleanupExpired(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.Empty(t, store.Keys())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportService_PurgeUser(t *testing.T) {
+	store := storage.NewMemoryStorage()
+	ctx := context.Background()
+	require.NoError(t, store.Put(ctx, "exports/mine.zip", strings.NewReader("zip")))
+	require.NoError(t, store.Put(ctx, "exports/other.zip", strings.NewReader("zip")))
+	service, mock, _ := setupExportService(t, store)
+
+	mock.ExpectQuery("SELECT storage_key, storage_region FROM data_exports WHERE user_id = \\$1").
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region"}).
+			AddRow("exports/mine.zip", "").AddRow("exports/gone.zip", ""))
+	mock.ExpectExec("DELETE FROM data_exports WHERE user_id = \\$1").
+		WithArgs(int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	removed, err := service.PurgeUser(ctx, 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, removed)
+	assert.Equal(t, []string{"exports/other.zip"}, store.Keys())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportService_StorageRegion(t *testing.T) {
+	key := exportStorageKey(exportToken)
+
+	// setup routes user 5 to the "eu" region and user 6 to the unconfigured "us"
+	setup := func(t *testing.T) (*ExportService, sqlmock.Sqlmock, *storage.MemoryStorage, *fakeObjectStore) {
+		local, eu := storage.NewMemoryStorage(), newFakeObjectStore()
+		service, mock, _ := setupExportService(t, local)
+		regions := storage.NewRegions(storage.DefaultRegion)
+		regions.Register("eu", eu)
+		orgRegions := map[int64]string{5: "eu", 6: "us"}
+		service.files = storage.NewRouter(local, regions, func(ctx context.Context, userID int64) (string, error) {
+			return orgRegions[userID], nil
+		})
+		return service, mock, local, eu
+	}
+
+	t.Run("builds the archive in the organization's region", func(t *testing.T) {
+		service, mock, local, eu := setup(t)
+		mock.ExpectQuery("UPDATE data_exports").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "attempts"}).AddRow("exp-1", int64(5), exportToken, 1))
+		expectExportTables(mock, nil)
+		mock.ExpectQuery("UPDATE data_exports e\\s+SET status = 'ready'").
+			WithArgs("exp-1", key, "eu", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"email"}).AddRow("user@example.com"))
+
+		_, err := service.ProcessNextExport(context.Background())
+
+		require.NoError(t, err)
+		assert.True(t, eu.objects[key])
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an unconfigured region fails the attempt", func(t *testing.T) {
+		service, mock, local, _ := setup(t)
+		mock.ExpectQuery("UPDATE data_exports").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token", "attempts"}).AddRow("exp-1", int64(6), exportToken, 1))
+
+		processed, err := service.ProcessNextExport(context.Background())
+
+		assert.True(t, processed)
+		assert.ErrorIs(t, err, storage.ErrRegionUnavailable)
+		assert.Empty(t, local.Keys())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reads and deletes in the recorded region", func(t *testing.T) {
+		service, mock, _, eu := setup(t)
+		eu.objects[key] = true
+		expiresAt := testNow.Add(time.Hour)
+		signature := signExportLink(service.cfg.JWTSecret, exportToken, 5, expiresAt)
+
+		mock.ExpectQuery("SELECT storage_key, storage_region, size_bytes, expires_at, created_at FROM data_exports").
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region", "size_bytes", "expires_at", "created_at"}).
+				AddRow(key, "eu", int64(10), expiresAt, testNow))
+		mock.ExpectExec("INSERT INTO audit_log").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		_, r, err := service.OpenExport(context.Background(), 5, exportToken, expiresAt.Unix(), signature)
+		require.NoError(t, err)
+		r.Close()
+
+		mock.ExpectQuery("SELECT storage_key, storage_region FROM data_exports WHERE user_id = \\$1").
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key", "storage_region"}).AddRow(key, "eu"))
+		mock.ExpectExec("DELETE FROM data_exports WHERE user_id = \\$1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		removed, err := service.PurgeUser(context.Background(), 5)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, removed)
+		assert.Empty(t, eu.objects)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestHandler_DownloadExport(t *testing.T) {
+	expiresAt := testNow.Add(time.Hour)
+	key := exportStorageKey(exportToken)
+	exportColumns := []string{"storage_key", "storage_region", "size_bytes", "expires_at", "created_at"}
+
+	download := func(t *testing.T, service *ExportService, userID int64, query string) *httptest.ResponseRecorder {
+		t.Helper()
+		handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, nil, service, nil)
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/me/export/"+exportToken+"?"+query, nil)
+		c.Params = gin.Params{{Key: "token", Value: exportToken}}
+		c.Set("user_id", userID)
+		handler.DownloadExport(c)
+		return w
+	}
+	linkQuery := func(service *ExportService, userID int64, expiresAt time.Time) string {
+		link, _ := url.Parse(service.DownloadURL(exportToken, userID, expiresAt))
+		return link.RawQuery
+	}
+
+	t.Run("owner downloads the archive", func(t *testing.T) {
+		store := storage.NewMemoryStorage()
+		require.NoError(t, store.Put(context.Background(), key, strings.NewReader("PK-archive")))
+		service, mock, _ := setupExportService(t, store)
+		mock.ExpectQuery("SELECT storage_key, storage_region, size_bytes, expires_at, created_at FROM data_exports").
+			WithArgs(exportToken, int64(5)).
+			WillReturnRows(sqlmock.NewRows(exportColumns).AddRow(key, "", int64(10), expiresAt, testNow))
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(int64(5), nil, audit.ActionDataExportDownloaded, []byte("{}")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		w := download(t, service, 5, linkQuery(service, 5, expiresAt))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "burcev-export-")
+		assert.Equal(t, "PK-archive", w.Body.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("link of another user", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, storage.NewMemoryStorage())
+
+		w := download(t, service, 6, linkQuery(service, 5, expiresAt))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("tampered expiry", func(t *testing.T) {
+		service, _, _ := setupExportService(t, storage.NewMemoryStorage())
+		query := strings.Replace(linkQuery(service, 5, expiresAt),
+			strconv.FormatInt(expiresAt.Unix(), 10), strconv.FormatInt(expiresAt.Add(ExportLinkTTL).Unix(), 10), 1)
+
+		w := download(t, service, 5, query)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("expired link", func(t *testing.T) {
+		service, _, _ := setupExportService(t, storage.NewMemoryStorage())
+
+		w := download(t, service, 5, linkQuery(service, 5, testNow.Add(-time.Minute)))
+
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("archive already removed", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, storage.NewMemoryStorage())
+		mock.ExpectQuery("SELECT storage_key, storage_region, size_bytes, expires_at, created_at FROM data_exports").
+			WillReturnRows(sqlmock.NewRows(exportColumns).AddRow(nil, "", int64(10), expiresAt, testNow))
+
+		w := download(t, service, 5, linkQuery(service, 5, expiresAt))
+
+		assert.Equal(t, http.StatusGone, w.Code)
+	})
+
+	t.Run("unknown export", func(t *testing.T) {
+		service, mock, _ := setupExportService(t, storage.NewMemoryStorage())
+		mock.ExpectQuery("SELECT storage_key, storage_region, size_bytes, expires_at, created_at FROM data_exports").
+			WillReturnRows(sqlmock.NewRows(exportColumns))
+
+		w := download(t, service, 5, linkQuery(service, 5, expiresAt))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestHandler_RequestExport_InProgress(t *testing.T) {
+	service, mock, _ := setupExportService(t, nil)
+	mock.ExpectQuery("INSERT INTO data_exports").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "created_at"}))
+	handler := NewHandler(&config.Config{}, logger.New(), nil, nil, nil, nil, nil, service, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/users/me/export", nil)
+	c.Set("user_id", int64(5))
+
+	handler.RequestExport(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
@@ -2,16 +2,21 @@ package users
 
 import (
 	"context"
-	"database/sql"
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/burcev/api/internal/config"
 	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/modules/uploads"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/clock"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
-	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/units"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
@@ -19,17 +24,29 @@ import (
 type Handler struct {
 	cfg              *config.Config
 	log              *logger.Logger
-	service          *Service
+	service          ServiceInterface
+	apiKeys          *APIKeyService
+	deletion         *DeletionService
+	exports          *ExportService
+	emailChanges     *EmailChangeService
 	nutritionCalcSvc *nutritioncalc.Service
+	uploads          uploads.Source
 }
 
-// NewHandler creates a new users handler
-func NewHandler(db *sql.DB, s3 *storage.S3Client, cfg *config.Config, log *logger.Logger, nutritionCalcSvc *nutritioncalc.Service) *Handler {
+// NewHandler creates a new users handler. nutritionCalcSvc may be nil, then
+// settings changes do not recalculate KBJU targets; exports may be nil when
+// data exports are disabled.
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface, apiKeys *APIKeyService, nutritionCalcSvc *nutritioncalc.Service, uploadSource uploads.Source, deletion *DeletionService, exports *ExportService, emailChanges *EmailChangeService) *Handler {
 	return &Handler{
 		cfg:              cfg,
 		log:              log,
-		service:          NewService(db, s3, cfg, log),
+		service:          service,
+		apiKeys:          apiKeys,
+		deletion:         deletion,
+		exports:          exports,
+		emailChanges:     emailChanges,
 		nutritionCalcSvc: nutritionCalcSvc,
+		uploads:          uploadSource,
 	}
 }
 
@@ -49,15 +66,30 @@ func (h *Handler) GetProfile(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"profile": profile})
+	if h.emailChanges != nil {
+		pending, err := h.emailChanges.Pending(c.Request.Context(), userID)
+		if err != nil {
+			h.log.Errorw("Не удалось получить профиль", "error", err, "user_id", userID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось получить профиль")
+			return
+		}
+		profile.PendingEmailChange = pending
+	}
+
+	profile.Settings = profile.Settings.InUnits()
+	response.CachedJSON(c, http.StatusOK, ProfileResponse{Profile: profile})
 }
 
 // UpdateProfileRequest represents profile update request
 type UpdateProfileRequest struct {
-	Name string `json:"name"`
+	Name     string  `json:"name"`
+	Timezone *string `json:"timezone,omitempty"`
 }
 
-// UpdateProfile updates user profile
+// UpdateProfile updates user profile. The client sends the profile version
+// it edited as If-Match; when another device has changed the profile since,
+// the response is 409 VERSION_CONFLICT with the current profile. Updates
+// without If-Match overwrite blindly and are deprecated.
 func (h *Handler) UpdateProfile(c *gin.Context) {
 	userIDInterface, _ := c.Get("user_id")
 	userID, ok := userIDInterface.(int64)
@@ -66,20 +98,41 @@ func (h *Handler) UpdateProfile(c *gin.Context) {
 		return
 	}
 
+	version, ok := validation.IfMatchVersion(c)
+	if !ok {
+		return
+	}
+	if version == nil {
+		h.log.Warnw("Deprecated: profile update without If-Match version",
+			"user_id", userID, "user_agent", c.Request.UserAgent())
+	}
+
 	var req UpdateProfileRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
-	profile, err := h.service.UpdateProfile(c.Request.Context(), userID, req.Name)
+	if req.Timezone != nil {
+		if err := clock.ValidateTimezone(*req.Timezone); err != nil {
+			validation.Respond(c, validation.Errors{"timezone": "Неизвестный часовой пояс"})
+			return
+		}
+	}
+
+	profile, err := h.service.UpdateProfile(c.Request.Context(), userID, req.Name, req.Timezone, version)
+	var stale *apperrors.VersionConflictError
+	if errors.As(err, &stale) {
+		response.VersionConflict(c, "Профиль изменён на другом устройстве", stale.Current)
+		return
+	}
 	if err != nil {
 		h.log.Errorw("Не удалось обновить профиль", "error", err, "user_id", userID)
 		response.Error(c, http.StatusInternalServerError, "Не удалось обновить профиль")
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"profile": profile})
+	response.Success(c, http.StatusOK, ProfileResponse{Profile: profile})
 }
 
 // getUserID extracts user_id from gin context
@@ -103,6 +156,26 @@ type UpdateSettingsRequest struct {
 	BiologicalSex      *string  `json:"biological_sex"`
 	ActivityLevel      *string  `json:"activity_level"`
 	FitnessGoal        *string  `json:"fitness_goal"`
+	// InputUnits is the unit system of target_weight and height when it
+	// differs from units; by default they are read in units
+	InputUnits string `json:"input_units"`
+}
+
+// toMetric converts target_weight and height from the request's unit
+// system to kg and cm
+func (r *UpdateSettingsRequest) toMetric() {
+	system := r.Units
+	if r.InputUnits != "" {
+		system = r.InputUnits
+	}
+	if r.TargetWeight != nil {
+		kg := units.WeightToKg(*r.TargetWeight, system)
+		r.TargetWeight = &kg
+	}
+	if r.Height != nil {
+		cm := units.LengthToCm(*r.Height, system)
+		r.Height = &cm
+	}
 }
 
 // UpdateSettings updates user settings
@@ -110,19 +183,25 @@ func (h *Handler) UpdateSettings(c *gin.Context) {
 	userID := getUserID(c)
 
 	var req UpdateSettingsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, http.StatusBadRequest, "Неверные данные запроса")
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
 		return
 	}
 
 	// Validate timezone if provided
 	if req.Timezone != "" {
-		if _, err := time.LoadLocation(req.Timezone); err != nil {
+		if err := clock.ValidateTimezone(req.Timezone); err != nil {
 			response.Error(c, http.StatusBadRequest, "Неверный часовой пояс")
 			return
 		}
 	}
 
+	if req.InputUnits != "" && !units.Valid(req.InputUnits) {
+		validation.Respond(c, validation.Errors{"input_units": "Допустимы metric и imperial"})
+		return
+	}
+	req.toMetric()
+
 	// Validate height if provided
 	if req.Height != nil && (*req.Height <= 0 || *req.Height > 300) {
 		response.Error(c, http.StatusBadRequest, "Рост должен быть от 1 до 300 см")
@@ -217,13 +296,19 @@ func (h *Handler) UpdateSettings(c *gin.Context) {
 		}()
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"settings": settings})
+	response.Success(c, http.StatusOK, SettingsResponse{Settings: settings.InUnits()})
 }
 
-// UploadAvatar handles avatar file upload
+// UploadAvatar handles avatar file upload: either a multipart "avatar" file or
+// upload_id of a completed resumable upload
 func (h *Handler) UploadAvatar(c *gin.Context) {
 	userID := getUserID(c)
 
+	if uploadID := c.PostForm("upload_id"); uploadID != "" {
+		h.uploadAvatarFromUpload(c, userID, uploadID)
+		return
+	}
+
 	file, header, err := c.Request.FormFile("avatar")
 	if err != nil {
 		response.Error(c, http.StatusBadRequest, "Файл не найден")
@@ -249,7 +334,49 @@ func (h *Handler) UploadAvatar(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"avatar_url": url})
+	response.Success(c, http.StatusOK, AvatarResponse{AvatarURL: url})
+}
+
+// uploadAvatarFromUpload sets the avatar from a completed resumable upload
+func (h *Handler) uploadAvatarFromUpload(c *gin.Context, userID int64, uploadID string) {
+	if h.uploads == nil {
+		response.Error(c, http.StatusBadRequest, "Загрузка по upload_id недоступна")
+		return
+	}
+
+	upload, file, err := h.uploads.Take(c.Request.Context(), userID, uploadID)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.Error(c, http.StatusNotFound, "Загрузка не найдена")
+		case errors.Is(err, uploads.ErrNotCompleted):
+			response.Error(c, http.StatusConflict, "Загрузка ещё не завершена")
+		default:
+			h.log.Errorw("Не удалось получить загрузку", "error", err, "user_id", userID, "upload_id", uploadID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось загрузить фото")
+		}
+		return
+	}
+	defer file.Close()
+
+	if !strings.HasPrefix(upload.ContentType, "image/") {
+		response.Error(c, http.StatusBadRequest, "Допустимы только изображения")
+		return
+	}
+
+	if upload.Size > 5*1024*1024 {
+		response.Error(c, http.StatusBadRequest, "Максимальный размер файла 5 МБ")
+		return
+	}
+
+	url, err := h.service.UploadAvatar(c.Request.Context(), userID, file, upload.ContentType, upload.Size)
+	if err != nil {
+		h.log.Errorw("Не удалось загрузить фото", "error", err, "user_id", userID)
+		response.Error(c, http.StatusInternalServerError, "Не удалось загрузить фото")
+		return
+	}
+
+	response.Success(c, http.StatusOK, AvatarResponse{AvatarURL: url})
 }
 
 // DeleteAvatar removes the user's avatar
@@ -262,7 +389,7 @@ func (h *Handler) DeleteAvatar(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"message": "Фото удалено"})
+	response.Success(c, http.StatusOK, MessageResponse{Message: "Фото удалено"})
 }
 
 // CompleteOnboarding marks user onboarding as complete
@@ -275,5 +402,224 @@ func (h *Handler) CompleteOnboarding(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, http.StatusOK, gin.H{"message": "Онбординг завершён"})
+	response.Success(c, http.StatusOK, MessageResponse{Message: "Онбординг завершён"})
+}
+
+// CreateAPIKey generates an API key for read-only integrations. The key is
+// shown only in this response.
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	userID := getUserID(c)
+
+	var req CreateAPIKeyRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	key, err := h.apiKeys.CreateAPIKey(c.Request.Context(), userID, &req)
+	if err != nil {
+		var fieldErrs validation.Errors
+		switch {
+		case errors.As(err, &fieldErrs):
+			validation.Respond(c, err)
+		case errors.Is(err, ErrTooManyAPIKeys):
+			response.Error(c, http.StatusConflict, "Достигнуто максимальное количество API-ключей")
+		default:
+			h.log.Errorw("Не удалось создать API-ключ", "error", err, "user_id", userID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось создать API-ключ")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, key)
+}
+
+// ListAPIKeys returns the user's active API keys
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	userID := getUserID(c)
+
+	keys, err := h.apiKeys.ListAPIKeys(c.Request.Context(), userID)
+	if err != nil {
+		h.log.Errorw("Не удалось получить API-ключи", "error", err, "user_id", userID)
+		response.Error(c, http.StatusInternalServerError, "Не удалось получить API-ключи")
+		return
+	}
+
+	response.List(c, http.StatusOK, APIKeysResponse{response.NewList(keys, 0, 0)}, "keys")
+}
+
+// RevokeAPIKey revokes one of the user's API keys
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	userID := getUserID(c)
+
+	if err := h.apiKeys.RevokeAPIKey(c.Request.Context(), userID, c.Param("id")); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "API-ключ не найден")
+			return
+		}
+		h.log.Errorw("Не удалось отозвать API-ключ", "error", err, "user_id", userID)
+		response.Error(c, http.StatusInternalServerError, "Не удалось отозвать API-ключ")
+		return
+	}
+
+	response.Success(c, http.StatusOK, MessageResponse{Message: "API-ключ отозван"})
+}
+
+// DeleteAccount deletes the user's account after confirming the password.
+// The account is deactivated now and purged after the grace period.
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	userID := getUserID(c)
+
+	var req DeleteAccountRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	deletion, err := h.deletion.DeleteAccount(c.Request.Context(), userID, req.Password)
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrInvalidCredentials):
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthWrongPassword, "Неверный пароль", nil)
+		case errors.Is(err, ErrStaffAccount):
+			response.Forbidden(c, "Аккаунт сотрудника может удалить только администратор")
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Пользователь не найден")
+		default:
+			h.log.Errorw("Не удалось удалить аккаунт", "error", err, "user_id", userID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось удалить аккаунт")
+		}
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK,
+		"Аккаунт удалён. До "+deletion.PurgeAfter.Format("02.01.2006")+" его можно восстановить с тем же email и паролем", deletion)
+}
+
+// ChangeEmail starts an email change: the new address gets a confirmation
+// link and the current one a notice
+func (h *Handler) ChangeEmail(c *gin.Context) {
+	userID := getUserID(c)
+
+	var req ChangeEmailRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	change, err := h.emailChanges.RequestChange(c.Request.Context(), userID, req.Password, req.NewEmail)
+	if err != nil {
+		var fields validation.Errors
+		var limited *apperrors.RateLimitError
+		switch {
+		case errors.As(err, &fields):
+			validation.Respond(c, fields)
+		case errors.Is(err, apperrors.ErrInvalidCredentials):
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeAuthWrongPassword, "Неверный пароль", nil)
+		case errors.Is(err, apperrors.ErrConflict):
+			response.Conflict(c, "Этот email уже используется", "new_email")
+		case errors.As(err, &limited):
+			response.RateLimited(c, "Слишком много запросов на смену email. Попробуйте позже.", limited.RetryAfter)
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Пользователь не найден")
+		default:
+			h.log.Errorw("Не удалось запросить смену email", "error", err, "user_id", userID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось запросить смену email")
+		}
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusAccepted,
+		"Мы отправили ссылку для подтверждения на "+change.NewEmail+". Email сменится после перехода по ней", change)
+}
+
+// CancelEmailChange cancels the pending email change
+func (h *Handler) CancelEmailChange(c *gin.Context) {
+	userID := getUserID(c)
+
+	if err := h.emailChanges.CancelChange(c.Request.Context(), userID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Нет ожидающей смены email")
+			return
+		}
+		h.log.Errorw("Не удалось отменить смену email", "error", err, "user_id", userID)
+		response.Error(c, http.StatusInternalServerError, "Не удалось отменить смену email")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Смена email отменена", nil)
+}
+
+// ConfirmEmail applies an email change; it is opened without a session from
+// the link sent to the new address
+func (h *Handler) ConfirmEmail(c *gin.Context) {
+	if err := h.emailChanges.ConfirmChange(c.Request.Context(), c.Query("token")); err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrTokenExpired):
+			response.Error(c, http.StatusBadRequest, "Срок действия ссылки истёк. Запросите смену email ещё раз")
+		case errors.Is(err, apperrors.ErrTokenInvalid):
+			response.Error(c, http.StatusBadRequest, "Ссылка для подтверждения недействительна")
+		case errors.Is(err, apperrors.ErrConflict):
+			response.Conflict(c, "Этот email уже используется другим аккаунтом", "new_email")
+		default:
+			h.log.Errorw("Не удалось подтвердить смену email", "error", err)
+			response.InternalError(c, "Не удалось подтвердить смену email")
+		}
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusOK, "Email изменён. Войдите заново с новым адресом", nil)
+}
+
+// RequestExport starts building an archive of everything stored about the
+// user; the download link is emailed when it is ready
+func (h *Handler) RequestExport(c *gin.Context) {
+	userID := getUserID(c)
+
+	export, err := h.exports.RequestExport(c.Request.Context(), userID)
+	if err != nil {
+		if errors.Is(err, ErrExportInProgress) {
+			response.Error(c, http.StatusConflict, "Архив с вашими данными уже готовится")
+			return
+		}
+		h.log.Errorw("Не удалось запросить выгрузку данных", "error", err, "user_id", userID)
+		response.Error(c, http.StatusInternalServerError, "Не удалось запросить выгрузку данных")
+		return
+	}
+
+	response.SuccessWithMessage(c, http.StatusAccepted, "Готовим архив с вашими данными. Ссылка для скачивания придёт на почту", export)
+}
+
+// DownloadExport streams a finished export archive to its owner. The
+// expires and signature query parameters come from the emailed link.
+func (h *Handler) DownloadExport(c *gin.Context) {
+	userID := getUserID(c)
+
+	expires, err := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if err != nil || c.Query("signature") == "" {
+		response.Forbidden(c, "Недействительная ссылка для скачивания")
+		return
+	}
+
+	archive, r, err := h.exports.OpenExport(c.Request.Context(), userID, c.Param("token"), expires, c.Query("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, apperrors.ErrForbidden):
+			response.Forbidden(c, "Недействительная ссылка для скачивания")
+		case errors.Is(err, ErrExportExpired):
+			response.Error(c, http.StatusGone, "Срок действия ссылки истёк, запросите выгрузку заново")
+		case errors.Is(err, apperrors.ErrNotFound):
+			response.NotFound(c, "Архив не найден")
+		default:
+			h.log.Errorw("Не удалось открыть выгрузку данных", "error", err, "user_id", userID)
+			response.Error(c, http.StatusInternalServerError, "Не удалось скачать архив")
+		}
+		return
+	}
+	defer r.Close()
+
+	c.DataFromReader(http.StatusOK, archive.SizeBytes, "application/zip", r, map[string]string{
+		"Content-Disposition": `attachment; filename="` + archive.FileName + `"`,
+		"Cache-Control":       "private, no-store",
+	})
 }
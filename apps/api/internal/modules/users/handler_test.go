@@ -2,82 +2,190 @@ package users
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupTestHandler() *Handler {
+// mockService implements ServiceInterface for handler tests, recording
+// what the handler passed in
+type mockService struct {
+	profile *FullProfile
+	err     error
+
+	name     string
+	timezone *string
+	version  *int
+	settings Settings
+}
+
+func (m *mockService) GetProfile(ctx context.Context, userID int64) (*FullProfile, error) {
+	return m.profile, m.err
+}
+
+func (m *mockService) UpdateProfile(ctx context.Context, userID int64, name string, timezone *string, version *int) (*FullProfile, error) {
+	m.name, m.timezone, m.version = name, timezone, version
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &FullProfile{ID: userID, Name: name}, nil
+}
+
+func (m *mockService) UpdateSettings(ctx context.Context, userID int64, settings Settings) (*Settings, error) {
+	m.settings = settings
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &settings, nil
+}
+
+func (m *mockService) UploadAvatar(ctx context.Context, userID int64, file io.Reader, contentType string, size int64) (string, error) {
+	return "", m.err
+}
+
+func (m *mockService) DeleteAvatar(ctx context.Context, userID int64) error {
+	return m.err
+}
+
+func (m *mockService) CompleteOnboarding(ctx context.Context, userID int64) error {
+	return m.err
+}
+
+func setupTestHandler(service ServiceInterface) *Handler {
 	gin.SetMode(gin.TestMode)
 	cfg := &config.Config{
 		Env:       "test",
 		JWTSecret: "test-secret",
 	}
 	log := logger.New()
-	return NewHandler(nil, nil, cfg, log, nil)
+	return NewHandler(cfg, log, service, nil, nil, nil, nil, nil, nil)
+}
+
+// serveAs runs one request through handle as user 123 and decodes the body
+func serveAs(t *testing.T, handle gin.HandlerFunc, method, body string) (int, map[string]interface{}) {
+	t.Helper()
+	router := gin.New()
+	router.Handle(method, "/users", func(c *gin.Context) {
+		c.Set("user_id", int64(123))
+		handle(c)
+	})
+
+	req := httptest.NewRequest(method, "/users", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
 }
 
 func TestNewHandler(t *testing.T) {
-	handler := setupTestHandler()
+	handler := setupTestHandler(&mockService{})
 	assert.NotNil(t, handler)
 	assert.NotNil(t, handler.cfg)
 	assert.NotNil(t, handler.log)
 	assert.NotNil(t, handler.service)
 }
 
-func TestGetProfile_NilDB(t *testing.T) {
-	// With nil DB, GetProfile panics → Recovery returns 500
-	handler := setupTestHandler()
-	router := gin.New()
-	router.Use(gin.Recovery())
+func TestGetProfile(t *testing.T) {
+	t.Run("settings come back in the user's units", func(t *testing.T) {
+		weight := 70.0
+		handler := setupTestHandler(&mockService{profile: &FullProfile{
+			ID:       123,
+			Name:     "Анна",
+			Settings: Settings{Units: "imperial", TargetWeight: &weight},
+		}})
 
-	router.GET("/profile", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.GetProfile(c)
+		status, resp := serveAs(t, handler.GetProfile, http.MethodGet, "")
+
+		assert.Equal(t, http.StatusOK, status)
+		settings := resp["data"].(map[string]interface{})["profile"].(map[string]interface{})["settings"].(map[string]interface{})
+		assert.Equal(t, "lb", settings["weight_unit"])
+		assert.Equal(t, 154.3, settings["target_weight"])
 	})
 
-	req := httptest.NewRequest(http.MethodGet, "/profile", nil)
-	w := httptest.NewRecorder()
+	t.Run("service error", func(t *testing.T) {
+		handler := setupTestHandler(&mockService{err: errors.New("db is down")})
 
-	router.ServeHTTP(w, req)
+		status, resp := serveAs(t, handler.GetProfile, http.MethodGet, "")
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusInternalServerError, status)
+		assert.Equal(t, "Не удалось получить профиль", resp["message"])
+	})
 }
 
-func TestUpdateProfile_NilDB(t *testing.T) {
-	// With nil DB, UpdateProfile panics → Recovery returns 500
-	handler := setupTestHandler()
-	router := gin.New()
-	router.Use(gin.Recovery())
+func TestUpdateProfile(t *testing.T) {
+	t.Run("passes name and timezone", func(t *testing.T) {
+		service := &mockService{}
+		handler := setupTestHandler(service)
 
-	router.PUT("/profile", func(c *gin.Context) {
-		c.Set("user_id", int64(123))
-		handler.UpdateProfile(c)
+		status, _ := serveAs(t, handler.UpdateProfile, http.MethodPut, `{"name":"Анна","timezone":"Europe/Moscow"}`)
+
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "Анна", service.name)
+		require.NotNil(t, service.timezone)
+		assert.Equal(t, "Europe/Moscow", *service.timezone)
 	})
 
-	reqBody := UpdateProfileRequest{
-		Name: "Updated Name",
-	}
-	body, _ := json.Marshal(reqBody)
+	t.Run("service error", func(t *testing.T) {
+		handler := setupTestHandler(&mockService{err: errors.New("db is down")})
 
-	req := httptest.NewRequest(http.MethodPut, "/profile", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+		status, _ := serveAs(t, handler.UpdateProfile, http.MethodPut, `{"name":""}`)
 
-	router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusInternalServerError, status)
+	})
+
+	t.Run("outdated version returns the current profile", func(t *testing.T) {
+		current := &FullProfile{ID: 123, Name: "Анна", Version: 4}
+		service := &mockService{err: &apperrors.VersionConflictError{Current: current}}
+		handler := setupTestHandler(service)
+		router := gin.New()
+		router.PUT("/users", func(c *gin.Context) {
+			c.Set("user_id", int64(123))
+			handler.UpdateProfile(c)
+		})
+
+		req := httptest.NewRequest(http.MethodPut, "/users", bytes.NewBufferString(`{"name":"Аня"}`))
+		req.Header.Set("If-Match", `"3"`)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		require.NotNil(t, service.version)
+		assert.Equal(t, 3, *service.version)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "VERSION_CONFLICT", resp["code"])
+		profile := resp["details"].(map[string]interface{})["current"].(map[string]interface{})
+		assert.Equal(t, 4.0, profile["version"])
+	})
+}
+
+func TestCompleteOnboarding_ServiceError(t *testing.T) {
+	handler := setupTestHandler(&mockService{err: errors.New("db is down")})
+
+	status, resp := serveAs(t, handler.CompleteOnboarding, http.MethodPut, "")
+
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Equal(t, "Не удалось завершить онбординг", resp["message"])
 }
 
 func TestUpdateProfile_InvalidJSON(t *testing.T) {
-	handler := setupTestHandler()
+	handler := setupTestHandler(&mockService{})
 	router := gin.New()
 
 	router.PUT("/profile", func(c *gin.Context) {
@@ -101,27 +209,146 @@ func TestUpdateProfile_InvalidJSON(t *testing.T) {
 	assert.Equal(t, "Неверные данные запроса", response["message"])
 }
 
-func TestUpdateProfile_EmptyName_NilDB(t *testing.T) {
-	// With nil DB, UpdateProfile panics → Recovery returns 500
-	handler := setupTestHandler()
+func TestUpdateProfile_InvalidTimezone(t *testing.T) {
+	handler := setupTestHandler(&mockService{})
 	router := gin.New()
-	router.Use(gin.Recovery())
 
 	router.PUT("/profile", func(c *gin.Context) {
 		c.Set("user_id", int64(123))
 		handler.UpdateProfile(c)
 	})
 
-	reqBody := UpdateProfileRequest{
-		Name: "",
+	for _, tz := range []string{"Mars/Olympus_Mons", "Local", ""} {
+		body, _ := json.Marshal(UpdateProfileRequest{Name: "Анна", Timezone: &tz})
+		req := httptest.NewRequest(http.MethodPut, "/profile", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, tz)
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, map[string]interface{}{
+			"fields": map[string]interface{}{"timezone": "Неизвестный часовой пояс"},
+		}, response["details"], tz)
 	}
-	body, _ := json.Marshal(reqBody)
+}
 
-	req := httptest.NewRequest(http.MethodPut, "/profile", bytes.NewBuffer(body))
-	req.Header.Set("Content-Type", "application/json")
-	w := httptest.NewRecorder()
+func TestUpdateSettings_Units(t *testing.T) {
+	handler := setupTestHandler(&mockService{})
+	router := gin.New()
 
-	router.ServeHTTP(w, req)
+	router.PUT("/settings", func(c *gin.Context) {
+		c.Set("user_id", int64(123))
+		handler.UpdateSettings(c)
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		// 130 in is 330.2 cm, above the metric limit
+		{"height checked after conversion", `{"units":"imperial","height":130}`},
+		{"unknown input units", `{"units":"metric","input_units":"stone","height":170}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/settings", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+}
+
+func TestUpdateSettings_StoresMetric(t *testing.T) {
+	service := &mockService{}
+	handler := setupTestHandler(service)
 
-	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	status, resp := serveAs(t, handler.UpdateSettings, http.MethodPut, `{"units":"imperial","height":70}`)
+
+	assert.Equal(t, http.StatusOK, status)
+	require.NotNil(t, service.settings.Height)
+	assert.Equal(t, 177.8, *service.settings.Height)
+	settings := resp["data"].(map[string]interface{})["settings"].(map[string]interface{})
+	assert.Equal(t, 70.0, settings["height"])
+}
+
+func TestUpdateSettingsRequest_ToMetric(t *testing.T) {
+	weight, height := 154.3, 70.0
+
+	t.Run("values follow units", func(t *testing.T) {
+		req := UpdateSettingsRequest{Units: "imperial", TargetWeight: &weight, Height: &height}
+		req.toMetric()
+		assert.Equal(t, 69.99, *req.TargetWeight)
+		assert.Equal(t, 177.8, *req.Height)
+	})
+
+	t.Run("input units override units", func(t *testing.T) {
+		req := UpdateSettingsRequest{Units: "imperial", InputUnits: "metric", TargetWeight: &weight, Height: &height}
+		req.toMetric()
+		assert.Equal(t, 154.3, *req.TargetWeight)
+		assert.Equal(t, 70.0, *req.Height)
+	})
+}
+
+func TestSettingsInUnits(t *testing.T) {
+	weight, height := 70.0, 178.0
+
+	metric := Settings{Units: "metric", TargetWeight: &weight, Height: &height}.InUnits()
+	assert.Equal(t, "kg", metric.WeightUnit)
+	assert.Equal(t, "cm", metric.LengthUnit)
+	assert.Equal(t, 70.0, *metric.TargetWeight)
+
+	imperial := Settings{Units: "imperial", TargetWeight: &weight, Height: &height}.InUnits()
+	assert.Equal(t, "lb", imperial.WeightUnit)
+	assert.Equal(t, "in", imperial.LengthUnit)
+	assert.Equal(t, 154.3, *imperial.TargetWeight)
+	assert.Equal(t, 70.0, *imperial.Height)
+	assert.Nil(t, Settings{Units: "imperial"}.InUnits().Height)
+}
+
+func TestListAPIKeys(t *testing.T) {
+	list := func(t *testing.T, compat bool) map[string]interface{} {
+		t.Helper()
+		apiKeys, mock := setupAPIKeyService(t)
+		mock.ExpectQuery("FROM api_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "prefix", "scopes", "created_at", "last_used_at"}).
+				AddRow(testKeyID, "Grafana", "a1b2c3d4", "{read:nutrition}", testNow, nil))
+		handler := NewHandler(&config.Config{}, logger.New(), &mockService{}, apiKeys, nil, nil, nil, nil, nil)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.GET("/api-keys", response.Compat(compat), func(c *gin.Context) {
+			c.Set("user_id", int64(123))
+			handler.ListAPIKeys(c)
+		})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api-keys", nil))
+
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp["data"].(map[string]interface{})
+	}
+
+	t.Run("items and pagination", func(t *testing.T) {
+		data := list(t, false)
+
+		require.Len(t, data["items"], 1)
+		assert.Equal(t, "Grafana", data["items"].([]interface{})[0].(map[string]interface{})["name"])
+		assert.Equal(t, float64(1), data["pagination"].(map[string]interface{})["count"])
+		assert.NotContains(t, data, "keys")
+	})
+
+	t.Run("compatible responses keep the keys", func(t *testing.T) {
+		data := list(t, true)
+
+		assert.Equal(t, data["items"], data["keys"])
+	})
 }
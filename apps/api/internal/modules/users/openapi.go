@@ -0,0 +1,37 @@
+package users
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/openapi"
+)
+
+type exportLinkQuery struct {
+	Expires   int64  `form:"expires" binding:"required"`
+	Signature string `form:"signature" binding:"required"`
+}
+
+type confirmEmailQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// Endpoints describes the /users routes for the OpenAPI document
+func Endpoints() []openapi.Endpoint {
+	return []openapi.Endpoint{
+		{Method: http.MethodGet, Path: "/profile", Summary: "Профиль (поддерживает If-None-Match)", Auth: openapi.Bearer, Response: ProfileResponse{}},
+		{Method: http.MethodPut, Path: "/profile", Summary: "Изменение профиля; версия из If-Match должна быть текущей, иначе 409 VERSION_CONFLICT с текущим профилем", Auth: openapi.Bearer, Request: UpdateProfileRequest{}, Response: ProfileResponse{}},
+		{Method: http.MethodPut, Path: "/settings", Summary: "Изменение настроек", Auth: openapi.Bearer, Request: UpdateSettingsRequest{}, Response: SettingsResponse{}},
+		{Method: http.MethodPost, Path: "/avatar", Summary: "Загрузка аватара: multipart-файл avatar или upload_id", Auth: openapi.Bearer, Response: AvatarResponse{}},
+		{Method: http.MethodDelete, Path: "/avatar", Summary: "Удаление аватара", Auth: openapi.Bearer, Response: MessageResponse{}},
+		{Method: http.MethodPut, Path: "/onboarding/complete", Summary: "Завершение онбординга", Auth: openapi.Bearer, Response: MessageResponse{}},
+		{Method: http.MethodPost, Path: "/api-keys", Summary: "Создание API-ключа; ключ показывается один раз", Auth: openapi.Bearer, Request: CreateAPIKeyRequest{}, Response: APIKey{}, Status: http.StatusCreated},
+		{Method: http.MethodGet, Path: "/api-keys", Summary: "Активные API-ключи", Auth: openapi.Bearer, Response: APIKeysResponse{}},
+		{Method: http.MethodDelete, Path: "/api-keys/:id", Summary: "Отзыв API-ключа", Auth: openapi.Bearer, Response: MessageResponse{}},
+		{Method: http.MethodGet, Path: "/me/export", Summary: "Выгрузка всех данных: архив собирается в фоне, ссылка приходит на почту", Auth: openapi.Bearer, Response: DataExport{}, Status: http.StatusAccepted},
+		{Method: http.MethodGet, Path: "/me/export/:token", Summary: "Скачивание ZIP-архива по ссылке из письма (действует 48 часов)", Auth: openapi.Bearer, Query: exportLinkQuery{}},
+		{Method: http.MethodPost, Path: "/change-email", Summary: "Смена email: ссылка для подтверждения (действует час) уходит на новый адрес, уведомление — на текущий", Auth: openapi.Bearer, Request: ChangeEmailRequest{}, Response: PendingEmailChange{}, Status: http.StatusAccepted},
+		{Method: http.MethodDelete, Path: "/change-email", Summary: "Отмена ожидающей смены email", Auth: openapi.Bearer, Response: MessageResponse{}},
+		{Method: http.MethodGet, Path: "/confirm-email", Summary: "Подтверждение смены email по ссылке из письма; завершает все сессии", Auth: openapi.Public, Query: confirmEmailQuery{}, Response: MessageResponse{}},
+		{Method: http.MethodDelete, Path: "/me", Summary: "Удаление аккаунта; данные стираются после 14 дней", Auth: openapi.Bearer, Request: DeleteAccountRequest{}, Response: AccountDeletion{}},
+	}
+}
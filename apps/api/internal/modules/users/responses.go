@@ -0,0 +1,28 @@
+package users
+
+import "github.com/burcev/api/internal/shared/response"
+
+// ProfileResponse is the user's profile
+type ProfileResponse struct {
+	Profile *FullProfile `json:"profile"`
+}
+
+// SettingsResponse is the user's settings in their unit system
+type SettingsResponse struct {
+	Settings Settings `json:"settings"`
+}
+
+// AvatarResponse is the URL of the user's new avatar
+type AvatarResponse struct {
+	AvatarURL string `json:"avatar_url"`
+}
+
+// MessageResponse confirms an action that has nothing else to return
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// APIKeysResponse is the user's active API keys
+type APIKeysResponse struct {
+	response.ListData[APIKey]
+}
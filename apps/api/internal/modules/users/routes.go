@@ -0,0 +1,30 @@
+package users
+
+import "github.com/gin-gonic/gin"
+
+// RegisterRoutes registers the profile, settings, API key and account
+// routes on r, which must already require authentication. The export
+// routes are only registered when h has an export service; heavy runs
+// before them to cap concurrent exports. The email change routes are only
+// registered when h has an email change service; the confirmation link is
+// public and registered by the caller.
+func RegisterRoutes(r *gin.RouterGroup, h *Handler, heavy gin.HandlerFunc) {
+	r.GET("/profile", h.GetProfile)
+	r.PUT("/profile", h.UpdateProfile)
+	r.PUT("/settings", h.UpdateSettings)
+	r.POST("/avatar", h.UploadAvatar)
+	r.DELETE("/avatar", h.DeleteAvatar)
+	r.PUT("/onboarding/complete", h.CompleteOnboarding)
+	r.POST("/api-keys", h.CreateAPIKey)
+	r.GET("/api-keys", h.ListAPIKeys)
+	r.DELETE("/api-keys/:id", h.RevokeAPIKey)
+	r.DELETE("/me", h.DeleteAccount)
+	if h.emailChanges != nil {
+		r.POST("/change-email", h.ChangeEmail)
+		r.DELETE("/change-email", h.CancelEmailChange)
+	}
+	if h.exports != nil {
+		r.GET("/me/export", heavy, h.RequestExport)
+		r.GET("/me/export/:token", heavy, h.DownloadExport)
+	}
+}
@@ -3,15 +3,22 @@ package users
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/units"
 )
 
+// errStaleProfile is an UpdateProfile version that matched no row
+var errStaleProfile = errors.New("profile version is not current")
+
 // FullProfile is the complete profile response
 type FullProfile struct {
 	ID                  int64    `json:"id"`
@@ -21,12 +28,21 @@ type FullProfile struct {
 	AvatarURL           string   `json:"avatar_url,omitempty"`
 	OnboardingCompleted bool     `json:"onboarding_completed"`
 	Settings            Settings `json:"settings"`
+	// Version grows with every UpdateProfile; an update naming an older
+	// one is refused
+	Version int `json:"version"`
+	// PendingEmailChange is set while a new email awaits confirmation
+	PendingEmailChange *PendingEmailChange `json:"pending_email_change,omitempty"`
 }
 
-// Settings represents user preferences
+// Settings represents user preferences. TargetWeight and Height are stored
+// in kg and cm; responses carry them in Units, labelled by WeightUnit and
+// LengthUnit.
 type Settings struct {
 	Language           string   `json:"language"`
 	Units              string   `json:"units"`
+	WeightUnit         string   `json:"weight_unit,omitempty"`
+	LengthUnit         string   `json:"length_unit,omitempty"`
 	Timezone           string   `json:"timezone"`
 	TelegramUsername   string   `json:"telegram_username,omitempty"`
 	InstagramUsername  string   `json:"instagram_username,omitempty"`
@@ -39,6 +55,27 @@ type Settings struct {
 	FitnessGoal        *string  `json:"fitness_goal,omitempty"`
 }
 
+// InUnits returns the settings with stored metric values converted to the
+// user's unit system
+func (s Settings) InUnits() Settings {
+	s.WeightUnit = units.WeightUnit(s.Units)
+	s.LengthUnit = units.LengthUnit(s.Units)
+	s.TargetWeight = units.WeightPtr(s.TargetWeight, s.Units)
+	s.Height = units.LengthPtr(s.Height, s.Units)
+	return s
+}
+
+// ServiceInterface defines the profile, settings and avatar operations the
+// handler uses
+type ServiceInterface interface {
+	GetProfile(ctx context.Context, userID int64) (*FullProfile, error)
+	UpdateProfile(ctx context.Context, userID int64, name string, timezone *string, version *int) (*FullProfile, error)
+	UpdateSettings(ctx context.Context, userID int64, settings Settings) (*Settings, error)
+	UploadAvatar(ctx context.Context, userID int64, file io.Reader, contentType string, size int64) (string, error)
+	DeleteAvatar(ctx context.Context, userID int64) error
+	CompleteOnboarding(ctx context.Context, userID int64) error
+}
+
 // Service handles users business logic
 type Service struct {
 	db  *sql.DB
@@ -67,7 +104,7 @@ func (s *Service) GetProfile(ctx context.Context, userID int64) (*FullProfile, e
 		       COALESCE(s.language, 'ru'), COALESCE(s.units, 'metric'), COALESCE(s.timezone, 'Europe/Moscow'),
 		       COALESCE(s.telegram_username, ''), COALESCE(s.instagram_username, ''), COALESCE(s.apple_health_enabled, false),
 		       s.target_weight, s.height,
-		       s.birth_date, s.biological_sex, s.activity_level, s.fitness_goal
+		       s.birth_date, s.biological_sex, s.activity_level, s.fitness_goal, u.version
 		FROM users u
 		LEFT JOIN user_settings s ON s.user_id = u.id
 		WHERE u.id = $1
@@ -96,6 +133,7 @@ func (s *Service) GetProfile(ctx context.Context, userID int64) (*FullProfile, e
 		&biologicalSex,
 		&activityLevel,
 		&fitnessGoal,
+		&profile.Version,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -126,24 +164,62 @@ func (s *Service) GetProfile(ctx context.Context, userID int64) (*FullProfile, e
 	return &profile, nil
 }
 
-// UpdateProfile updates the user's name and returns the fresh full profile
-func (s *Service) UpdateProfile(ctx context.Context, userID int64, name string) (*FullProfile, error) {
+// UpdateProfile updates the user's name and, when timezone is not nil, their
+// timezone, and returns the fresh full profile. timezone must already be
+// validated. A non-nil version must be the profile's current one, or the
+// update fails with *apperrors.VersionConflictError carrying the profile as
+// it is.
+func (s *Service) UpdateProfile(ctx context.Context, userID int64, name string, timezone *string, version *int) (*FullProfile, error) {
 	if s.db == nil {
 		return nil, fmt.Errorf("database connection not available")
 	}
-	query := `UPDATE users SET name = $1, updated_at = NOW() WHERE id = $2`
 
-	result, err := s.db.ExecContext(ctx, query, name, userID)
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при обновлении профиля: %w", err)
-	}
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := `
+			UPDATE users SET name = $1, updated_at = NOW(), version = version + 1
+			WHERE id = $2 AND ($3::int IS NULL OR version = $3)
+		`
 
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, fmt.Errorf("ошибка при проверке обновления: %w", err)
+		result, err := tx.ExecContext(ctx, query, name, userID, version)
+		if err != nil {
+			return fmt.Errorf("ошибка при обновлении профиля: %w", err)
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("ошибка при проверке обновления: %w", err)
+		}
+		if rowsAffected == 0 && version != nil {
+			return errStaleProfile
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("пользователь не найден")
+		}
+
+		if timezone == nil {
+			return nil
+		}
+		query = `
+			INSERT INTO user_settings (user_id, timezone, updated_at)
+			VALUES ($1, $2, NOW())
+			ON CONFLICT (user_id) DO UPDATE SET timezone = EXCLUDED.timezone, updated_at = NOW()
+		`
+		if _, err := tx.ExecContext(ctx, query, userID, *timezone); err != nil {
+			return fmt.Errorf("ошибка при обновлении часового пояса: %w", err)
+		}
+		return nil
+	})
+	if errors.Is(err, errStaleProfile) {
+		// Either another update got there first or the user is gone; the
+		// profile read tells which
+		current, err := s.GetProfile(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &apperrors.VersionConflictError{Current: current}
 	}
-	if rowsAffected == 0 {
-		return nil, fmt.Errorf("пользователь не найден")
+	if err != nil {
+		return nil, err
 	}
 
 	return s.GetProfile(ctx, userID)
@@ -288,15 +364,8 @@ func (s *Service) DeleteAvatar(ctx context.Context, userID int64) error {
 		return nil // Nothing to delete
 	}
 
-	// Extract S3 key from URL
-	// URL format: https://endpoint/bucket/key
-	// We need to extract the key part
 	if s.s3 != nil {
-		urlStr := avatarURL.String
-		// Find the key by looking for "avatars/" in the URL
-		idx := strings.Index(urlStr, "avatars/")
-		if idx >= 0 {
-			key := urlStr[idx:]
+		if key := avatarKey(avatarURL.String); key != "" {
 			if err := s.s3.DeleteFile(ctx, key); err != nil {
 				s.log.Errorw("Не удалось удалить аватар из S3", "error", err, "key", key)
 				// Continue to clear the URL even if S3 delete fails
@@ -314,6 +383,16 @@ func (s *Service) DeleteAvatar(ctx context.Context, userID int64) error {
 	return nil
 }
 
+// avatarKey extracts the S3 key from an avatar URL
+// (https://endpoint/bucket/avatars/...), or returns "" when there is none
+func avatarKey(avatarURL string) string {
+	idx := strings.Index(avatarURL, "avatars/")
+	if idx < 0 {
+		return ""
+	}
+	return avatarURL[idx:]
+}
+
 // CompleteOnboarding marks the user's onboarding as completed
 func (s *Service) CompleteOnboarding(ctx context.Context, userID int64) error {
 	if s.db == nil {
@@ -337,6 +416,23 @@ func (s *Service) CompleteOnboarding(ctx context.Context, userID int64) error {
 	return nil
 }
 
+// GetLanguage returns the language preference of a user, empty when the
+// user has no settings yet
+func (s *Service) GetLanguage(ctx context.Context, userID int64) (string, error) {
+	if s.db == nil {
+		return "", fmt.Errorf("database connection not available")
+	}
+	var language string
+	err := s.db.QueryRowContext(ctx, `SELECT language FROM user_settings WHERE user_id = $1`, userID).Scan(&language)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("ошибка при получении языка: %w", err)
+	}
+	return language, nil
+}
+
 // EnsureSettingsExist creates default settings for a user if they don't exist
 func (s *Service) EnsureSettingsExist(ctx context.Context, userID int64) error {
 	if s.db == nil {
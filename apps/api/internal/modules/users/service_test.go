@@ -2,11 +2,15 @@ package users
 
 import (
 	"context"
+	"database/sql"
 	"testing"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupTestService() *Service {
@@ -37,10 +41,51 @@ func TestService_UpdateProfile_NilDB(t *testing.T) {
 	service := setupTestService()
 	ctx := context.Background()
 
-	_, err := service.UpdateProfile(ctx, int64(123), "New Name")
+	_, err := service.UpdateProfile(ctx, int64(123), "New Name", nil, nil)
 	assert.Error(t, err, "UpdateProfile should fail with nil DB")
 }
 
+func TestService_UpdateProfile_Version(t *testing.T) {
+	profileRows := func(name string, version int) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "email", "name", "role", "avatar_url", "onboarding_completed",
+			"language", "units", "timezone", "telegram_username", "instagram_username", "apple_health_enabled",
+			"target_weight", "height", "birth_date", "biological_sex", "activity_level", "fitness_goal", "version"}).
+			AddRow(int64(123), "anna@example.com", name, "client", "", true,
+				"ru", "metric", "Europe/Moscow", "", "", false, nil, nil, nil, nil, nil, nil, version)
+	}
+	updateRe := `UPDATE users SET name = \$1, updated_at = NOW\(\), version = version \+ 1\s+WHERE id = \$2 AND \(\$3::int IS NULL OR version = \$3\)`
+
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	service := NewService(db, nil, &config.Config{}, logger.New())
+	version := 1
+
+	// Two devices edited version 1; the first update bumps it to 2
+	mock.ExpectBegin()
+	mock.ExpectExec(updateRe).WithArgs("Анна", int64(123), version).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("FROM users u").WithArgs(int64(123)).WillReturnRows(profileRows("Анна", 2))
+	// so the second one matches no row and gets the current profile back
+	mock.ExpectBegin()
+	mock.ExpectExec(updateRe).WithArgs("Аня", int64(123), version).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectQuery("FROM users u").WithArgs(int64(123)).WillReturnRows(profileRows("Анна", 2))
+
+	profile, err := service.UpdateProfile(context.Background(), 123, "Анна", nil, &version)
+	require.NoError(t, err)
+	assert.Equal(t, 2, profile.Version)
+
+	_, err = service.UpdateProfile(context.Background(), 123, "Аня", nil, &version)
+
+	var conflict *apperrors.VersionConflictError
+	require.ErrorAs(t, err, &conflict)
+	current := conflict.Current.(*FullProfile)
+	assert.Equal(t, "Анна", current.Name)
+	assert.Equal(t, 2, current.Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestService_CompleteOnboarding_NilDB(t *testing.T) {
 	service := setupTestService()
 	ctx := context.Background()
@@ -49,6 +94,30 @@ func TestService_CompleteOnboarding_NilDB(t *testing.T) {
 	assert.Error(t, err, "CompleteOnboarding should fail with nil DB")
 }
 
+func TestService_GetLanguage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	service := NewService(db, nil, &config.Config{}, logger.New())
+
+	mock.ExpectQuery("SELECT language FROM user_settings").
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"language"}).AddRow("en"))
+	mock.ExpectQuery("SELECT language FROM user_settings").
+		WithArgs(int64(2)).
+		WillReturnError(sql.ErrNoRows)
+
+	lang, err := service.GetLanguage(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, "en", lang)
+
+	// Users without settings have no preference
+	lang, err = service.GetLanguage(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Empty(t, lang)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestService_UploadAvatar_NilS3(t *testing.T) {
 	service := setupTestService()
 	ctx := context.Background()
@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/shared/events"
+)
+
+// Consumer is the name of the webhooks consumer of the event log
+const Consumer = "webhooks"
+
+// Consume registers the service as the webhooks consumer of log
+func (s *Service) Consume(log *events.Log) {
+	log.Register(Consumer, s.HandleEvent)
+}
+
+// HandleEvent queues one delivery per active webhook of the event's user
+// that subscribed to its type and existed when the event occurred. An
+// event handed again queues nothing new: the first attempt of an event is
+// unique per webhook.
+func (s *Service) HandleEvent(ctx context.Context, event events.Event) error {
+	startTime := time.Now()
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload)
+		SELECT id, $2, $3, $4 FROM webhooks
+		WHERE user_id = $1 AND active AND $3 = ANY(events) AND created_at <= $5
+		ON CONFLICT (webhook_id, event_id) WHERE attempt = 1 DO NOTHING`
+
+	result, err := s.db.ExecContext(ctx, query, event.UserID, event.ID, event.Type, payload, event.OccurredAt)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":    event.UserID,
+		"event_type": event.Type,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+
+	if queued, _ := result.RowsAffected(); queued > 0 {
+		s.log.LogBusinessEvent("webhook_deliveries_queued", map[string]interface{}{
+			"user_id":    event.UserID,
+			"event_id":   event.ID,
+			"event_type": event.Type,
+			"count":      queued,
+		})
+	}
+	return nil
+}
+
+// backoff returns how long to wait before retrying a delivery after its
+// attempt-th attempt failed: 1, 5 and 25 minutes
+func backoff(attempt int) time.Duration {
+	delay := time.Minute
+	for i := 1; i < attempt; i++ {
+		delay *= 5
+	}
+	return delay
+}
+
+// send POSTs a delivery to its webhook and returns the response status code.
+// Any response other than 2xx is an error.
+func (s *Service) send(ctx context.Context, d pendingDelivery) (int, error) {
+	timestamp := s.now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "BurcevFitnessApp-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.eventType)
+	req.Header.Set(HeaderDelivery, d.eventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(d.secret, timestamp, d.payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,270 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testWebhookID  = "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e"
+	testDeliveryID = "6a5b4c3d-2e1f-4a0b-9c8d-7e6f5a4b3c2d"
+	testEventID    = "1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d"
+	testSecret     = "whsec-0123456789abcdef"
+)
+
+var testNow = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fakeClient records delivery requests and answers with a fixed status
+type fakeClient struct {
+	status   int
+	err      error
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (f *fakeClient) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	f.requests = append(f.requests, req)
+	f.bodies = append(f.bodies, body)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &http.Response{StatusCode: f.status, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+}
+
+func setupTestService(t *testing.T, client HTTPDoer) (*Service, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	service := NewService(&database.DB{DB: mockDB}, logger.New(), nil)
+	service.client = client
+	service.now = func() time.Time { return testNow }
+	return service, mock
+}
+
+func claimedRows(attempt int, active bool) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "webhook_id", "event_id", "event_type", "payload", "attempt", "url", "secret", "active"}).
+		AddRow(testDeliveryID, testWebhookID, testEventID, events.NutritionEntryCreated,
+			[]byte(`{"id":"`+testEventID+`","type":"nutrition.entry.created"}`), attempt,
+			"https://bot.example.com/hook", testSecret, active)
+}
+
+func TestHandleEvent(t *testing.T) {
+	// An event as the log hands it to consumers
+	event := events.Event{
+		ID:         testEventID,
+		Type:       events.MeasurementCreated,
+		UserID:     5,
+		OccurredAt: testNow,
+		Data:       json.RawMessage(`{"type":"weight","value":81.5}`),
+	}
+	query := "INSERT INTO webhook_deliveries .* FROM webhooks\\s+WHERE user_id = \\$1 AND active AND \\$3 = ANY\\(events\\) AND created_at <= \\$5\\s+" +
+		"ON CONFLICT \\(webhook_id, event_id\\) WHERE attempt = 1 DO NOTHING"
+
+	t.Run("queues a delivery per subscribed webhook", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectExec(query).
+			WithArgs(int64(5), testEventID, events.MeasurementCreated,
+				[]byte(`{"id":"`+testEventID+`","type":"measurement.created","user_id":5,"occurred_at":"2026-03-01T12:00:00Z","data":{"type":"weight","value":81.5}}`),
+				testNow).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		require.NoError(t, service.HandleEvent(context.Background(), event))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("an event handed again queues nothing new", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		require.NoError(t, service.HandleEvent(context.Background(), event))
+		require.NoError(t, service.HandleEvent(context.Background(), event))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failure is returned for the log to hand the event again", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectExec("INSERT INTO webhook_deliveries").WillReturnError(assert.AnError)
+
+		err := service.HandleEvent(context.Background(), event)
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("registered as the log's webhooks consumer", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		log := events.NewLog(service.db, logger.New())
+		service.Consume(log)
+		mock.ExpectQuery("FROM domain_events").
+			WillReturnRows(sqlmock.NewRows([]string{"xid", "seq", "id", "type", "user_id", "occurred_at", "data"}).
+				AddRow(int64(100), int64(1), testEventID, events.MeasurementCreated, int64(5), testNow, []byte(`{"type":"weight","value":81.5}`)))
+		mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		replayed, err := log.Replay(context.Background(), Consumer, time.Time{})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, backoff(1))
+	assert.Equal(t, 5*time.Minute, backoff(2))
+	assert.Equal(t, 25*time.Minute, backoff(3))
+}
+
+func TestProcessPending(t *testing.T) {
+	t.Run("delivered with a signature", func(t *testing.T) {
+		client := &fakeClient{status: http.StatusNoContent}
+		service, mock := setupTestService(t, client)
+		mock.ExpectQuery("WITH claimed AS").WillReturnRows(claimedRows(1, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE webhook_deliveries").
+			WithArgs(testDeliveryID, DeliveryDelivered, http.StatusNoContent, "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE webhooks SET consecutive_failures = 0").
+			WithArgs(testWebhookID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		processed, err := service.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		require.Len(t, client.requests, 1)
+		req := client.requests[0]
+		assert.Equal(t, http.MethodPost, req.Method)
+		assert.Equal(t, events.NutritionEntryCreated, req.Header.Get(HeaderEvent))
+		assert.Equal(t, testEventID, req.Header.Get(HeaderDelivery))
+		timestamp, err := strconv.ParseInt(req.Header.Get(HeaderTimestamp), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, testNow.Unix(), timestamp)
+		assert.True(t, Verify(testSecret, timestamp, client.bodies[0], req.Header.Get(HeaderSignature)))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failure schedules a retry with backoff", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{status: http.StatusBadGateway})
+		mock.ExpectQuery("WITH claimed AS").WillReturnRows(claimedRows(2, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE webhook_deliveries").
+			WithArgs(testDeliveryID, DeliveryFailed, http.StatusBadGateway, "unexpected status: 502").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE webhooks\\s+SET consecutive_failures = consecutive_failures \\+ 1").
+			WithArgs(testWebhookID, MaxConsecutiveFailures).
+			WillReturnRows(sqlmock.NewRows([]string{"active", "disabled", "user_id"}).AddRow(true, false, int64(5)))
+		mock.ExpectExec("INSERT INTO webhook_deliveries").
+			WithArgs(testWebhookID, testEventID, events.NutritionEntryCreated, sqlmock.AnyArg(), 3, testNow.Add(5*time.Minute)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := service.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no retry after the last one", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{err: assert.AnError})
+		mock.ExpectQuery("WITH claimed AS").WillReturnRows(claimedRows(MaxRetries+1, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE webhook_deliveries").
+			WithArgs(testDeliveryID, DeliveryFailed, 0, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE webhooks").
+			WillReturnRows(sqlmock.NewRows([]string{"active", "disabled", "user_id"}).AddRow(true, false, int64(5)))
+		mock.ExpectCommit()
+
+		_, err := service.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("the failure that disables the webhook is not retried", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{status: http.StatusInternalServerError})
+		service.quotas = quotas.NewService(service.db, logger.New(), quotas.Limits{Webhooks: 5})
+		mock.ExpectQuery("WITH claimed AS").WillReturnRows(claimedRows(1, true))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE webhook_deliveries").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("UPDATE webhooks").
+			WillReturnRows(sqlmock.NewRows([]string{"active", "disabled", "user_id"}).AddRow(false, true, int64(5)))
+		// The disabled webhook stops counting against the owner's quota
+		mock.ExpectExec("UPDATE user_quotas SET webhooks = GREATEST\\(webhooks - 1, 0\\)").
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		_, err := service.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("disabled webhook is not called", func(t *testing.T) {
+		client := &fakeClient{status: http.StatusOK}
+		service, mock := setupTestService(t, client)
+		mock.ExpectQuery("WITH claimed AS").WillReturnRows(claimedRows(1, false))
+		mock.ExpectExec("UPDATE webhook_deliveries").
+			WithArgs(testDeliveryID, DeliveryFailed, 0, errWebhookDisabled.Error()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := service.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, client.requests)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestEventPayload(t *testing.T) {
+	service, mock := setupTestService(t, &fakeClient{})
+	var captured []byte
+	mock.ExpectExec("INSERT INTO webhook_deliveries").
+		WithArgs(int64(5), testEventID, events.NutritionEntryCreated, payloadArg{&captured}, testNow).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.HandleEvent(context.Background(), events.Event{
+		ID: testEventID, Type: events.NutritionEntryCreated, UserID: 5, OccurredAt: testNow,
+		Data: json.RawMessage(`{"food":"Гречка","calories":200}`),
+	})
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	var payload map[string]interface{}
+	require.NoError(t, json.Unmarshal(captured, &payload))
+	assert.Equal(t, testEventID, payload["id"])
+	assert.Equal(t, events.NutritionEntryCreated, payload["type"])
+	assert.Equal(t, "Гречка", payload["data"].(map[string]interface{})["food"])
+}
+
+// payloadArg captures the JSON payload argument of a query
+type payloadArg struct {
+	dst *[]byte
+}
+
+func (p payloadArg) Match(v driver.Value) bool {
+	b, ok := v.([]byte)
+	*p.dst = b
+	return ok
+}
@@ -0,0 +1,127 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// Handler handles webhook requests
+type Handler struct {
+	cfg     *config.Config
+	log     *logger.Logger
+	service ServiceInterface
+}
+
+// NewHandler creates a new webhooks handler
+func NewHandler(cfg *config.Config, log *logger.Logger, service ServiceInterface) *Handler {
+	return &Handler{
+		cfg:     cfg,
+		log:     log,
+		service: service,
+	}
+}
+
+// getUserID extracts the authenticated user ID from the Gin context
+func (h *Handler) getUserID(c *gin.Context) (int64, bool) {
+	userIDInterface, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "Пользователь не аутентифицирован")
+		return 0, false
+	}
+	userID, ok := userIDInterface.(int64)
+	if !ok {
+		response.Error(c, http.StatusBadRequest, "Неверный ID пользователя")
+		return 0, false
+	}
+	return userID, true
+}
+
+// CreateWebhook handles POST /api/v1/webhooks
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := validation.BindJSON(c, &req); err != nil {
+		validation.Respond(c, err)
+		return
+	}
+
+	webhook, err := h.service.CreateWebhook(c.Request.Context(), userID, &req)
+	if err != nil {
+		var fieldErrs validation.Errors
+		switch {
+		case errors.As(err, &fieldErrs):
+			validation.Respond(c, err)
+		case errors.As(err, new(*apperrors.QuotaExceededError)):
+			_ = c.Error(err)
+		default:
+			h.log.Error("Failed to create webhook", "error", err, "user_id", userID)
+			response.InternalError(c, "Не удалось создать вебхук")
+		}
+		return
+	}
+
+	response.Success(c, http.StatusCreated, webhook)
+}
+
+// ListDeliveries handles GET /api/v1/webhooks/:id/deliveries
+// Returns the most recent delivery attempts with their status codes.
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	webhookID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), userID, webhookID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Вебхук не найден")
+			return
+		}
+		h.log.Error("Failed to list webhook deliveries", "error", err, "user_id", userID, "webhook_id", webhookID)
+		response.InternalError(c, "Не удалось получить доставки")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// DeleteWebhook handles DELETE /api/v1/webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	userID, ok := h.getUserID(c)
+	if !ok {
+		return
+	}
+
+	webhookID, ok := validation.IDParam(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(c.Request.Context(), userID, webhookID); err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.NotFound(c, "Вебхук не найден")
+			return
+		}
+		h.log.Error("Failed to delete webhook", "error", err, "user_id", userID, "webhook_id", webhookID)
+		response.InternalError(c, "Не удалось удалить вебхук")
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"message": "Вебхук удалён"})
+}
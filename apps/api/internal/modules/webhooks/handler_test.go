@@ -0,0 +1,167 @@
+package webhooks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockService implements ServiceInterface for handler tests
+type mockService struct {
+	err error
+}
+
+func (m *mockService) CreateWebhook(ctx context.Context, userID int64, req *CreateWebhookRequest) (*Webhook, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &Webhook{ID: testWebhookID, UserID: userID, URL: req.URL, Events: req.Events, Active: true}, nil
+}
+
+func (m *mockService) ListDeliveries(ctx context.Context, userID int64, webhookID string) ([]Delivery, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return []Delivery{{ID: testDeliveryID, EventID: testEventID, Attempt: 1, Status: DeliveryDelivered}}, nil
+}
+
+func (m *mockService) DeleteWebhook(ctx context.Context, userID int64, webhookID string) error {
+	return m.err
+}
+
+func newTestContext(method, path, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Params = gin.Params{{Key: "id", Value: testWebhookID}}
+	c.Set("user_id", int64(5))
+	return c, w
+}
+
+func TestHandlerCreateWebhook(t *testing.T) {
+	body := `{"url":"https://bot.example.com/hook","secret":"whsec-0123456789abcdef","events":["measurement.created"]}`
+	tests := []struct {
+		name string
+		body string
+		err  error
+		code int
+	}{
+		{"created", body, nil, http.StatusCreated},
+		{"missing secret", `{"url":"https://bot.example.com/hook","events":["measurement.created"]}`, nil, http.StatusBadRequest},
+		{"invalid", body, validation.Errors{"url": "Укажите адрес, начинающийся с https://"}, http.StatusBadRequest},
+		{"internal", body, assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodPost, "/webhooks", tt.body)
+
+			handler.CreateWebhook(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			assert.NotContains(t, w.Body.String(), "whsec-")
+		})
+	}
+
+	t.Run("quota exceeded", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		quotaErr := &apperrors.QuotaExceededError{Resource: quotas.Webhooks, Used: 5, Limit: 5}
+		handler := NewHandler(nil, logger.New(), &mockService{err: quotaErr})
+		router := gin.New()
+		router.Use(middleware.ErrorHandler(logger.New()))
+		router.POST("/webhooks", func(c *gin.Context) {
+			c.Set("user_id", int64(5))
+			handler.CreateWebhook(c)
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"QUOTA_EXCEEDED"`)
+	})
+}
+
+func TestHandlerListDeliveries(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"listed", nil, http.StatusOK},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"internal", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodGet, "/webhooks/"+testWebhookID+"/deliveries", "")
+
+			handler.ListDeliveries(c)
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.err == nil {
+				assert.Contains(t, w.Body.String(), testDeliveryID)
+			}
+		})
+	}
+}
+
+func TestHandlerDeleteWebhook(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"deleted", nil, http.StatusOK},
+		{"not found", apperrors.ErrNotFound, http.StatusNotFound},
+		{"internal", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHandler(nil, logger.New(), &mockService{err: tt.err})
+			c, w := newTestContext(http.MethodDelete, "/webhooks/"+testWebhookID, "")
+
+			handler.DeleteWebhook(c)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestHandlerListDeliveriesMalformedID(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{err: assert.AnError})
+	c, w := newTestContext(http.MethodGet, "/webhooks/wh-1/deliveries", "")
+	c.Params = gin.Params{{Key: "id", Value: "wh-1"}}
+
+	handler.ListDeliveries(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Неверный формат идентификатора")
+}
+
+func TestHandlerUnauthorized(t *testing.T) {
+	handler := NewHandler(nil, logger.New(), &mockService{})
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/webhooks/"+testWebhookID+"/deliveries", nil)
+
+	handler.ListDeliveries(c)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,285 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/ids"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+)
+
+// defaultBatchSize is the number of deliveries claimed per worker iteration
+const defaultBatchSize = 20
+
+// staleProcessingAfter is how long a claimed delivery may stay in
+// "processing" before another worker run picks it up again
+const staleProcessingAfter = 10 * time.Minute
+
+// HTTPDoer sends delivery requests (implemented by *http.Client)
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ServiceInterface defines the interface for webhook operations
+type ServiceInterface interface {
+	CreateWebhook(ctx context.Context, userID int64, req *CreateWebhookRequest) (*Webhook, error)
+	ListDeliveries(ctx context.Context, userID int64, webhookID string) ([]Delivery, error)
+	DeleteWebhook(ctx context.Context, userID int64, webhookID string) error
+}
+
+// Service registers webhooks, queues deliveries for published events and
+// sends them in the background worker
+type Service struct {
+	db        *database.DB
+	log       *logger.Logger
+	client    HTTPDoer
+	quotas    *quotas.Service
+	batchSize int
+	ids       *ids.Generator
+	now       func() time.Time
+}
+
+// NewService creates a new webhooks service. Deliveries are sent with an
+// HTTP client that does not follow redirects and refuses to connect to
+// loopback and private addresses. Nil quotas do not limit the number of
+// active webhooks.
+func NewService(db *database.DB, log *logger.Logger, quota *quotas.Service) *Service {
+	return &Service{
+		db:        db,
+		log:       log,
+		client:    newDeliveryClient(),
+		quotas:    quota,
+		batchSize: defaultBatchSize,
+		ids:       ids.Default,
+		now:       time.Now,
+	}
+}
+
+func newDeliveryClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: DeliveryTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("refusing to connect to %s", addrPort.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Timeout:   DeliveryTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// publicAddr reports whether addr may receive deliveries: loopback, private,
+// link-local and unspecified addresses belong to us or the receiver's LAN
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsValid() && !addr.IsLoopback() && !addr.IsPrivate() &&
+		!addr.IsLinkLocalUnicast() && !addr.IsLinkLocalMulticast() && !addr.IsUnspecified()
+}
+
+// validateWebhook checks a webhook request. Invalid input is reported as
+// validation.Errors.
+func validateWebhook(req *CreateWebhookRequest) error {
+	errs := validation.Errors{}
+
+	if u, err := url.Parse(req.URL); err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		errs["url"] = "Укажите адрес, начинающийся с https://"
+	} else if host := u.Hostname(); host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		errs["url"] = "Адрес должен быть доступен из интернета"
+	} else if addr, err := netip.ParseAddr(host); err == nil && !publicAddr(addr) {
+		errs["url"] = "Адрес должен быть доступен из интернета"
+	}
+
+	if n := len(req.Secret); n < MinSecretLength || n > MaxSecretLength {
+		errs["secret"] = fmt.Sprintf("Секрет должен содержать от %d до %d символов", MinSecretLength, MaxSecretLength)
+	}
+
+	if len(req.Events) == 0 {
+		errs["events"] = "Укажите хотя бы одно событие"
+	}
+	for i, event := range req.Events {
+		if !slices.Contains(events.Types, event) {
+			errs["events"] = "Допустимые события: " + strings.Join(events.Types, ", ")
+			break
+		}
+		if slices.Contains(req.Events[:i], event) {
+			errs["events"] = "События не должны повторяться"
+			break
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// CreateWebhook registers a webhook for the user's events. Users at their
+// webhook quota get *apperrors.QuotaExceededError.
+func (s *Service) CreateWebhook(ctx context.Context, userID int64, req *CreateWebhookRequest) (*Webhook, error) {
+	if err := validateWebhook(req); err != nil {
+		return nil, err
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO webhooks (id, user_id, url, secret, events)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at`
+
+	webhook := &Webhook{ID: s.ids.NewString(), UserID: userID, URL: req.URL, Events: req.Events, Active: true}
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.quotas.ReserveWebhook(ctx, tx, userID); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx, query, webhook.ID, userID, req.URL, req.Secret, "{"+strings.Join(req.Events, ",")+"}").
+			Scan(&webhook.CreatedAt)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"user_id": userID})
+		if err != nil {
+			return fmt.Errorf("failed to create webhook: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.LogBusinessEvent("webhook_created", map[string]interface{}{
+		"webhook_id": webhook.ID,
+		"user_id":    userID,
+		"events":     req.Events,
+	})
+
+	return webhook, nil
+}
+
+// DeleteWebhook removes one of the user's webhooks with its deliveries. An
+// active webhook is released from the user's webhook quota; a disabled one
+// was released when it was disabled. Webhooks of other users are reported
+// as not found.
+func (s *Service) DeleteWebhook(ctx context.Context, userID int64, webhookID string) error {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `DELETE FROM webhooks WHERE id = $1 AND user_id = $2 RETURNING active`
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		var active bool
+		err := tx.QueryRowContext(ctx, query, webhookID, userID).Scan(&active)
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"webhook_id": webhookID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return apperrors.ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete webhook: %w", err)
+		}
+		if active {
+			return s.quotas.ReleaseWebhook(ctx, tx, userID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.log.LogBusinessEvent("webhook_deleted", map[string]interface{}{
+		"webhook_id": webhookID,
+		"user_id":    userID,
+	})
+	return nil
+}
+
+// ListDeliveries returns the most recent delivery attempts of one of the
+// user's webhooks, newest first. Webhooks of other users are reported as
+// not found.
+func (s *Service) ListDeliveries(ctx context.Context, userID int64, webhookID string) ([]Delivery, error) {
+	if _, err := uuid.Parse(webhookID); err != nil {
+		return nil, apperrors.ErrNotFound
+	}
+
+	var owned bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1 AND user_id = $2)`, webhookID, userID,
+	).Scan(&owned)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	if !owned {
+		return nil, apperrors.ErrNotFound
+	}
+
+	startTime := time.Now()
+	query := `
+		SELECT id, event_id, event_type, attempt, status, status_code, error,
+		       next_attempt_at, attempted_at, created_at
+		FROM webhook_deliveries
+		WHERE webhook_id = $1
+		ORDER BY created_at DESC, attempt DESC
+		LIMIT $2`
+
+	rows, err := s.db.QueryContext(ctx, query, webhookID, DeliveriesListLimit)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"webhook_id": webhookID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]Delivery, 0)
+	for rows.Next() {
+		var d Delivery
+		var statusCode sql.NullInt64
+		var errMsg sql.NullString
+		var nextAttempt, attemptedAt sql.NullTime
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.Attempt, &d.Status, &statusCode, &errMsg,
+			&nextAttempt, &attemptedAt, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		if statusCode.Valid {
+			code := int(statusCode.Int64)
+			d.StatusCode = &code
+		}
+		d.Error = errMsg.String
+		if d.Status == DeliveryPending && nextAttempt.Valid {
+			d.NextAttempt = &nextAttempt.Time
+		}
+		if attemptedAt.Valid {
+			d.AttemptedAt = &attemptedAt.Time
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
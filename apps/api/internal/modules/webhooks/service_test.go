@@ -0,0 +1,238 @@
+package webhooks
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validRequest() *CreateWebhookRequest {
+	return &CreateWebhookRequest{
+		URL:    "https://bot.example.com/hook",
+		Secret: testSecret,
+		Events: []string{events.NutritionEntryCreated},
+	}
+}
+
+func TestValidateWebhook(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *CreateWebhookRequest)
+		field  string
+	}{
+		{"valid", func(r *CreateWebhookRequest) {}, ""},
+		{"all events", func(r *CreateWebhookRequest) { r.Events = events.Types }, ""},
+		{"plain http", func(r *CreateWebhookRequest) { r.URL = "http://bot.example.com/hook" }, "url"},
+		{"not a url", func(r *CreateWebhookRequest) { r.URL = "bot.example.com" }, "url"},
+		{"localhost", func(r *CreateWebhookRequest) { r.URL = "https://localhost:8080/hook" }, "url"},
+		{"private address", func(r *CreateWebhookRequest) { r.URL = "https://10.0.0.1/hook" }, "url"},
+		{"loopback v6", func(r *CreateWebhookRequest) { r.URL = "https://[::1]/hook" }, "url"},
+		{"short secret", func(r *CreateWebhookRequest) { r.Secret = "short" }, "secret"},
+		{"no events", func(r *CreateWebhookRequest) { r.Events = nil }, "events"},
+		{"unknown event", func(r *CreateWebhookRequest) { r.Events = []string{"user.deleted"} }, "events"},
+		{"duplicate event", func(r *CreateWebhookRequest) {
+			r.Events = []string{events.MeasurementCreated, events.MeasurementCreated}
+		}, "events"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := validRequest()
+			tt.modify(req)
+
+			err := validateWebhook(req)
+
+			if tt.field == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Contains(t, fieldErrs, tt.field)
+		})
+	}
+}
+
+func TestPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":   true,
+		"127.0.0.1":       false,
+		"192.168.1.10":    false,
+		"169.254.169.254": false,
+		"0.0.0.0":         false,
+		"::ffff:10.0.0.1": false,
+		"2606:4700::1111": true,
+	} {
+		assert.Equal(t, public, publicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	withQuota := func(service *Service) {
+		service.quotas = quotas.NewService(service.db, logger.New(), quotas.Limits{Webhooks: 5})
+	}
+
+	t.Run("created", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		withQuota(service)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WithArgs(int64(5), int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"webhooks", "limit"}).AddRow(2, 5))
+		mock.ExpectQuery("INSERT INTO webhooks").
+			WithArgs(sqlmock.AnyArg(), int64(5), "https://bot.example.com/hook", testSecret, "{nutrition.entry.created,measurement.created}").
+			WillReturnRows(sqlmock.NewRows([]string{"created_at"}).AddRow(testNow))
+		mock.ExpectCommit()
+
+		req := validRequest()
+		req.Events = []string{events.NutritionEntryCreated, events.MeasurementCreated}
+		webhook, err := service.CreateWebhook(context.Background(), 5, req)
+
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), uuid.MustParse(webhook.ID).Version())
+		assert.True(t, webhook.Active)
+		assert.Equal(t, req.Events, webhook.Events)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("quota exceeded rolls back", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		withQuota(service)
+		mock.ExpectBegin()
+		mock.ExpectQuery("INSERT INTO user_quotas").
+			WillReturnRows(sqlmock.NewRows([]string{"webhooks", "limit"}).AddRow(6, 5))
+		mock.ExpectRollback()
+
+		_, err := service.CreateWebhook(context.Background(), 5, validRequest())
+
+		var quotaErr *apperrors.QuotaExceededError
+		require.ErrorAs(t, err, &quotaErr)
+		assert.Equal(t, apperrors.QuotaExceededError{Resource: quotas.Webhooks, Used: 5, Limit: 5}, *quotaErr)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid request does not touch the database", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		req := validRequest()
+		req.URL = "http://bot.example.com/hook"
+
+		_, err := service.CreateWebhook(context.Background(), 5, req)
+
+		var fieldErrs validation.Errors
+		assert.ErrorAs(t, err, &fieldErrs)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestDeleteWebhook(t *testing.T) {
+	deleteQuery := "DELETE FROM webhooks WHERE id = \\$1 AND user_id = \\$2"
+
+	t.Run("active webhook releases the quota", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		service.quotas = quotas.NewService(service.db, logger.New(), quotas.Limits{Webhooks: 5})
+		mock.ExpectBegin()
+		mock.ExpectQuery(deleteQuery).
+			WithArgs(testWebhookID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+		mock.ExpectExec("UPDATE user_quotas SET webhooks = GREATEST\\(webhooks - 1, 0\\)").
+			WithArgs(int64(5)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := service.DeleteWebhook(context.Background(), 5, testWebhookID)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("disabled webhook was already released", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		service.quotas = quotas.NewService(service.db, logger.New(), quotas.Limits{Webhooks: 5})
+		mock.ExpectBegin()
+		mock.ExpectQuery(deleteQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(false))
+		mock.ExpectCommit()
+
+		err := service.DeleteWebhook(context.Background(), 5, testWebhookID)
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's webhook", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectBegin()
+		mock.ExpectQuery(deleteQuery).
+			WithArgs(testWebhookID, int64(6)).
+			WillReturnRows(sqlmock.NewRows([]string{"active"}))
+		mock.ExpectRollback()
+
+		err := service.DeleteWebhook(context.Background(), 6, testWebhookID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestListDeliveries(t *testing.T) {
+	columns := []string{"id", "event_id", "event_type", "attempt", "status", "status_code", "error",
+		"next_attempt_at", "attempted_at", "created_at"}
+
+	t.Run("owner sees attempts", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(testWebhookID, int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		retryAt := testNow.Add(5 * time.Minute)
+		mock.ExpectQuery("FROM webhook_deliveries").
+			WithArgs(testWebhookID, DeliveriesListLimit).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("d3", testEventID, events.NutritionEntryCreated, 3, DeliveryPending, nil, nil, retryAt, nil, testNow).
+				AddRow("d2", testEventID, events.NutritionEntryCreated, 2, DeliveryFailed, 502, "unexpected status: 502", testNow, testNow, testNow).
+				AddRow("d1", "e0", events.MeasurementCreated, 1, DeliveryDelivered, 200, nil, testNow, testNow, testNow))
+
+		deliveries, err := service.ListDeliveries(context.Background(), 5, testWebhookID)
+
+		require.NoError(t, err)
+		require.Len(t, deliveries, 3)
+		assert.Nil(t, deliveries[0].StatusCode)
+		assert.Equal(t, retryAt, *deliveries[0].NextAttempt)
+		assert.Equal(t, 502, *deliveries[1].StatusCode)
+		assert.Equal(t, "unexpected status: 502", deliveries[1].Error)
+		assert.Nil(t, deliveries[1].NextAttempt)
+		assert.Equal(t, 200, *deliveries[2].StatusCode)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("another user's webhook", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+		mock.ExpectQuery("SELECT EXISTS").
+			WithArgs(testWebhookID, int64(6)).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := service.ListDeliveries(context.Background(), 6, testWebhookID)
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("malformed id", func(t *testing.T) {
+		service, mock := setupTestService(t, &fakeClient{})
+
+		_, err := service.ListDeliveries(context.Background(), 5, "not-a-uuid")
+
+		assert.ErrorIs(t, err, apperrors.ErrNotFound)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,28 @@
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"nutrition.entry.created"}`)
+
+	// echo -n '1767225600.{"type":"nutrition.entry.created"}' | openssl dgst -sha256 -hmac 'whsec-0123456789abcdef'
+	assert.Equal(t,
+		"sha256=8857dc3974281db54fecfc0ba52ba32ec69d769fe8284332f10b794627d0d840",
+		Sign("whsec-0123456789abcdef", 1767225600, body),
+	)
+}
+
+func TestVerify(t *testing.T) {
+	secret := "whsec-0123456789abcdef"
+	body := []byte(`{"type":"measurement.created"}`)
+	signature := Sign(secret, 1767225600, body)
+
+	assert.True(t, Verify(secret, 1767225600, body, signature))
+	assert.False(t, Verify("another-secret-value", 1767225600, body, signature), "wrong secret")
+	assert.False(t, Verify(secret, 1767225601, body, signature), "replayed with another timestamp")
+	assert.False(t, Verify(secret, 1767225600, []byte(`{"type":"measurement.deleted"}`), signature), "tampered body")
+}
@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"errors"
+	"time"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending    = "pending"
+	DeliveryProcessing = "processing"
+	DeliveryDelivered  = "delivered"
+	DeliveryFailed     = "failed"
+)
+
+const (
+	// MaxRetries is the number of times a failed delivery is retried
+	MaxRetries = 3
+	// MaxConsecutiveFailures disables a webhook whose attempts keep failing
+	MaxConsecutiveFailures = 20
+	// DeliveryTimeout bounds a single delivery request
+	DeliveryTimeout = 10 * time.Second
+	// DeliveriesListLimit is the number of recent attempts listed
+	DeliveriesListLimit = 50
+
+	// MinSecretLength and MaxSecretLength bound the signing secret
+	MinSecretLength = 16
+	MaxSecretLength = 256
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+var (
+	errWebhookDisabled = errors.New("webhook is disabled")
+)
+
+// CreateWebhookRequest represents a webhook to register
+type CreateWebhookRequest struct {
+	URL    string   `json:"url" binding:"required,max=2000"`
+	Secret string   `json:"secret" binding:"required"`
+	Events []string `json:"events" binding:"required"`
+}
+
+// Webhook is a registered webhook. The secret is never returned.
+type Webhook struct {
+	ID                  string     `json:"id"`
+	UserID              int64      `json:"user_id"`
+	URL                 string     `json:"url"`
+	Events              []string   `json:"events"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// Delivery is one attempt to deliver an event to a webhook
+type Delivery struct {
+	ID          string     `json:"id"`
+	EventID     string     `json:"event_id"`
+	EventType   string     `json:"event_type"`
+	Attempt     int        `json:"attempt"`
+	Status      string     `json:"status"`
+	StatusCode  *int       `json:"status_code"`
+	Error       string     `json:"error,omitempty"`
+	NextAttempt *time.Time `json:"next_attempt_at,omitempty"`
+	AttemptedAt *time.Time `json:"attempted_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// pendingDelivery is a claimed attempt with what is needed to send it
+type pendingDelivery struct {
+	id        string
+	webhookID string
+	eventID   string
+	eventType string
+	payload   []byte
+	attempt   int
+	url       string
+	secret    string
+	active    bool
+}
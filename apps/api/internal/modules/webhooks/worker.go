@@ -0,0 +1,225 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+)
+
+// maxErrorLength caps the error text stored with a failed attempt
+const maxErrorLength = 500
+
+// RunWorker starts the webhook delivery loop. Every tick it drains due
+// deliveries batch by batch. It blocks until the provided context is cancelled.
+func (s *Service) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	s.log.Info("Webhook worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := s.ProcessPending(ctx)
+				if err != nil {
+					s.log.Error("Failed to process webhook deliveries", "error", err)
+					break
+				}
+				if processed < s.batchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Webhook worker stopped")
+			return
+		}
+	}
+}
+
+// ProcessPending claims one batch of due deliveries, sends them and records
+// each outcome. Returns the number of deliveries processed.
+func (s *Service) ProcessPending(ctx context.Context) (int, error) {
+	deliveries, err := s.claimBatch(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, d := range deliveries {
+		if !d.active {
+			// Queued before the webhook was disabled; not an attempt
+			if err := s.finish(ctx, s.db, d.id, DeliveryFailed, 0, errWebhookDisabled.Error()); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		code, sendErr := s.send(ctx, d)
+		if sendErr == nil {
+			err = s.recordSuccess(ctx, d, code)
+		} else {
+			s.log.Warn("Webhook delivery failed",
+				"webhook_id", d.webhookID,
+				"event_id", d.eventID,
+				"attempt", d.attempt,
+				"error", sendErr,
+			)
+			err = s.recordFailure(ctx, d, code, sendErr)
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// claimBatch atomically marks a batch of due (or stale processing)
+// deliveries as processing and returns them with their webhook
+func (s *Service) claimBatch(ctx context.Context) ([]pendingDelivery, error) {
+	startTime := time.Now()
+
+	query := `
+		WITH claimed AS (
+			UPDATE webhook_deliveries
+			SET status = 'processing', attempted_at = NOW()
+			WHERE id IN (
+				SELECT id FROM webhook_deliveries
+				WHERE (status = 'pending' AND next_attempt_at <= NOW())
+				   OR (status = 'processing' AND attempted_at < $2)
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, webhook_id, event_id, event_type, payload, attempt
+		)
+		SELECT c.id, c.webhook_id, c.event_id, c.event_type, c.payload, c.attempt,
+		       w.url, w.secret, w.active
+		FROM claimed c
+		JOIN webhooks w ON w.id = c.webhook_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, s.batchSize, s.now().Add(-staleProcessingAfter))
+	if err != nil {
+		s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []pendingDelivery
+	for rows.Next() {
+		var d pendingDelivery
+		if err := rows.Scan(&d.id, &d.webhookID, &d.eventID, &d.eventType, &d.payload, &d.attempt,
+			&d.url, &d.secret, &d.active); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating webhook deliveries: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// execer is satisfied by both *database.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// finish records the outcome of an attempt. code 0 means no response.
+func (s *Service) finish(ctx context.Context, db execer, id, status string, code int, errMsg string) error {
+	if len(errMsg) > maxErrorLength {
+		errMsg = errMsg[:maxErrorLength]
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, status_code = NULLIF($3, 0), error = NULLIF($4, '')
+		WHERE id = $1`,
+		id, status, code, errMsg,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update webhook delivery %s: %w", id, err)
+	}
+	return nil
+}
+
+// recordSuccess marks the attempt delivered and resets the webhook's
+// failure count
+func (s *Service) recordSuccess(ctx context.Context, d pendingDelivery, code int) error {
+	return database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.finish(ctx, tx, d.id, DeliveryDelivered, code, ""); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx,
+			`UPDATE webhooks SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0`, d.webhookID)
+		if err != nil {
+			return fmt.Errorf("failed to reset webhook failures: %w", err)
+		}
+		return nil
+	})
+}
+
+// recordFailure marks the attempt failed, counts it against the webhook,
+// disabling it at MaxConsecutiveFailures, and schedules a retry while
+// retries are left and the webhook is still active. A disabled webhook no
+// longer counts against its owner's webhook quota.
+func (s *Service) recordFailure(ctx context.Context, d pendingDelivery, code int, sendErr error) error {
+	var active, disabled bool
+	err := database.WithTx(ctx, s.db, func(tx *sql.Tx) error {
+		if err := s.finish(ctx, tx, d.id, DeliveryFailed, code, sendErr.Error()); err != nil {
+			return err
+		}
+
+		var userID int64
+		err := tx.QueryRowContext(ctx, `
+			UPDATE webhooks
+			SET consecutive_failures = consecutive_failures + 1,
+			    active = active AND consecutive_failures + 1 < $2,
+			    disabled_at = CASE WHEN active AND consecutive_failures + 1 >= $2 THEN NOW() ELSE disabled_at END
+			WHERE id = $1
+			RETURNING active, consecutive_failures = $2, user_id`,
+			d.webhookID, MaxConsecutiveFailures,
+		).Scan(&active, &disabled, &userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			// Deleted together with its account meanwhile
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to count webhook failure: %w", err)
+		}
+		if disabled {
+			if err := s.quotas.ReleaseWebhook(ctx, tx, userID); err != nil {
+				return err
+			}
+		}
+
+		if !active || d.attempt > MaxRetries {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, attempt, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			d.webhookID, d.eventID, d.eventType, d.payload, d.attempt+1, s.now().Add(backoff(d.attempt)),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to schedule webhook retry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		s.log.LogBusinessEvent("webhook_disabled", map[string]interface{}{
+			"webhook_id": d.webhookID,
+			"reason":     "consecutive_failures",
+		})
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package openapi
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler serves the document for the engine's routes. It is built and
+// validated on the first request, when every route has been registered; an
+// invalid document is a 500 rather than a spec clients cannot load.
+func (d *Document) Handler(engine *gin.Engine) gin.HandlerFunc {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+	return func(c *gin.Context) {
+		once.Do(func() {
+			doc := d.Build(engine.Routes())
+			if err = Validate(doc); err != nil {
+				return
+			}
+			body, err = json.Marshal(doc)
+		})
+		if err != nil {
+			_ = c.Error(err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+	}
+}
+
+var uiPage = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: {{.SpecURL}}, dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`))
+
+// UI serves a Swagger UI page for the document at specURL
+func (d *Document) UI(specURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		if err := uiPage.Execute(c.Writer, map[string]string{"Title": d.title, "SpecURL": specURL}); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
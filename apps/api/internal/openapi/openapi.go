@@ -0,0 +1,253 @@
+// Package openapi builds the OpenAPI 3 document of the API from endpoint
+// descriptors that modules register next to their routes. Request and
+// response schemas are derived from the handlers' own structs.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI specification version of the generated document
+const Version = "3.0.3"
+
+// Auth is how an endpoint authenticates its caller
+type Auth int
+
+const (
+	// Public endpoints need no credentials
+	Public Auth = iota
+	// Bearer endpoints need a JWT access token
+	Bearer
+	// BearerOrAPIKey endpoints also accept a scoped read-only API key
+	BearerOrAPIKey
+	// OptionalBearer endpoints use the access token when one is sent
+	OptionalBearer
+)
+
+// Endpoint describes one route. Request, Query and Response are zero values
+// of the handler's structs (or nil): Request is the JSON body, Query a
+// struct whose form tags name the query parameters, and Response the data
+// field of the success envelope.
+type Endpoint struct {
+	Method   string
+	Path     string // Gin route pattern relative to the group, e.g. "/entries/:id"
+	Summary  string
+	Auth     Auth
+	Query    any
+	Request  any
+	Response any
+	// Status is the success status code; 200 when zero
+	Status int
+	// Stream, when set, is one item of the newline-delimited JSON the
+	// endpoint streams instead on request: StreamContentType objects
+	// without the envelope
+	Stream any
+}
+
+// StreamContentType is the media type of streamed responses
+const StreamContentType = "application/x-ndjson"
+
+type group struct {
+	basePath  string
+	tag       string
+	endpoints []Endpoint
+}
+
+// Document collects endpoint descriptors and builds the OpenAPI document
+type Document struct {
+	title   string
+	version string
+	groups  []group
+}
+
+// New creates an empty document
+func New(title, version string) *Document {
+	return &Document{title: title, version: version}
+}
+
+// Add registers endpoints of the route group at basePath under tag
+func (d *Document) Add(basePath, tag string, endpoints ...Endpoint) {
+	d.groups = append(d.groups, group{basePath: basePath, tag: tag, endpoints: endpoints})
+}
+
+// Build returns the document for routes. Every route appears in it: routes
+// without a descriptor get an operation with only their path parameters, so
+// the document never hides an endpoint.
+func (d *Document) Build(routes gin.RoutesInfo) map[string]any {
+	s := newSchemas()
+	paths := map[string]map[string]any{}
+
+	addOperation := func(method, ginPath string, op map[string]any) {
+		path, params := convertPath(ginPath)
+		if len(params) > 0 {
+			op["parameters"] = append(params, asSlice(op["parameters"])...)
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = op
+	}
+
+	described := map[string]bool{}
+	for _, g := range d.groups {
+		for _, e := range g.endpoints {
+			fullPath := joinPath(g.basePath, e.Path)
+			described[e.Method+" "+fullPath] = true
+			addOperation(e.Method, fullPath, s.operation(g.tag, e))
+		}
+	}
+
+	sorted := append(gin.RoutesInfo(nil), routes...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	for _, r := range sorted {
+		if described[r.Method+" "+r.Path] {
+			continue
+		}
+		addOperation(r.Method, r.Path, map[string]any{
+			"tags":      []string{routeTag(r.Path)},
+			"responses": map[string]any{"default": s.errorResponse("Ответ не описан")},
+		})
+	}
+
+	return map[string]any{
+		"openapi": Version,
+		"info":    map[string]any{"title": d.title, "version": d.version},
+		"servers": []any{map[string]any{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": s.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+				"apiKey":     map[string]any{"type": "apiKey", "in": "header", "name": middleware.APIKeyHeader},
+			},
+		},
+	}
+}
+
+// operation builds the operation object of a described endpoint
+func (s *schemas) operation(tag string, e Endpoint) map[string]any {
+	op := map[string]any{"tags": []string{tag}}
+	if e.Summary != "" {
+		op["summary"] = e.Summary
+	}
+
+	switch e.Auth {
+	case Bearer:
+		op["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+	case BearerOrAPIKey:
+		op["security"] = []any{map[string]any{"bearerAuth": []string{}}, map[string]any{"apiKey": []string{}}}
+	case OptionalBearer:
+		op["security"] = []any{map[string]any{}, map[string]any{"bearerAuth": []string{}}}
+	}
+
+	if e.Query != nil {
+		op["parameters"] = s.queryParameters(reflect.TypeOf(e.Query))
+	}
+	if e.Request != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": s.of(reflect.TypeOf(e.Request))},
+			},
+		}
+	}
+
+	status := e.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	data := map[string]any{
+		"status":  map[string]any{"type": "string", "enum": []string{"success"}},
+		"message": map[string]any{"type": "string"},
+	}
+	if e.Response != nil {
+		data["data"] = s.of(reflect.TypeOf(e.Response))
+	}
+	content := map[string]any{
+		"application/json": map[string]any{"schema": map[string]any{
+			"type":       "object",
+			"required":   []string{"status"},
+			"properties": data,
+		}},
+	}
+	if e.Stream != nil {
+		content[StreamContentType] = map[string]any{"schema": s.of(reflect.TypeOf(e.Stream))}
+	}
+	responses := map[string]any{
+		strconv.Itoa(status): map[string]any{
+			"description": http.StatusText(status),
+			"content":     content,
+		},
+		"default": s.errorResponse("Ошибка"),
+	}
+	if e.Request != nil || e.Query != nil {
+		responses["400"] = s.errorResponse("Некорректный запрос")
+	}
+	if e.Auth == Bearer || e.Auth == BearerOrAPIKey {
+		responses["401"] = s.errorResponse("Нужна аутентификация")
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// errorResponse is a response with the standard error envelope
+func (s *schemas) errorResponse(description string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": s.of(reflect.TypeOf(response.Response{}))},
+		},
+	}
+}
+
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// convertPath turns a Gin pattern into an OpenAPI path template and its
+// path parameters: "/entries/:id" becomes "/entries/{id}"
+func convertPath(ginPath string) (string, []any) {
+	var params []any
+	path := ginParam.ReplaceAllStringFunc(ginPath, func(m string) string {
+		name := m[1:]
+		params = append(params, map[string]any{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]any{"type": "string"},
+		})
+		return "{" + name + "}"
+	})
+	return path, params
+}
+
+// routeTag groups an undescribed route by its first segment after the
+// version prefix, e.g. "food-tracker"
+func routeTag(path string) string {
+	path = strings.TrimPrefix(path, "/api/v1")
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	if segment == "" {
+		return "default"
+	}
+	return segment
+}
+
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+func asSlice(v any) []any {
+	s, _ := v.([]any)
+	return s
+}
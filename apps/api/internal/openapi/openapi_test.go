@@ -0,0 +1,338 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixtureEntry struct {
+	ID        string        `json:"id"`
+	Food      string        `json:"food"`
+	Calories  float64       `json:"calories"`
+	Meal      string        `json:"meal"`
+	Date      types.Date    `json:"date"`
+	Parent    *fixtureEntry `json:"parent,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	Secret    string        `json:"-"`
+}
+
+type fixtureCreateRequest struct {
+	Food     string   `json:"food" binding:"required,min=1,max=100"`
+	Meal     string   `json:"meal" binding:"required,oneof=breakfast lunch dinner"`
+	Calories *float64 `json:"calories" binding:"omitempty,gte=0,lte=5000"`
+	Email    string   `json:"email" binding:"omitempty,email"`
+	Tags     []string `json:"tags" binding:"max=5,dive,min=1"`
+}
+
+type fixtureQuery struct {
+	Date  string `form:"date" binding:"required"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type fixtureList struct {
+	Entries []fixtureEntry `json:"entries"`
+	Total   int            `json:"total"`
+}
+
+func fixtureRouter() (*gin.Engine, *Document) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	noop := func(c *gin.Context) {}
+
+	doc := New("Test API", "1.0")
+	entries := router.Group("/api/v1/entries")
+	entries.GET("", noop)
+	entries.POST("", noop)
+	entries.DELETE("/:id", noop)
+	doc.Add(entries.BasePath(), "entries",
+		Endpoint{Method: http.MethodGet, Path: "", Summary: "Список", Auth: BearerOrAPIKey, Query: fixtureQuery{}, Response: fixtureList{}, Stream: fixtureEntry{}},
+		Endpoint{Method: http.MethodPost, Path: "", Auth: Bearer, Request: fixtureCreateRequest{}, Response: fixtureEntry{}, Status: http.StatusCreated},
+		Endpoint{Method: http.MethodDelete, Path: "/:id", Auth: Bearer},
+	)
+
+	// Routes without descriptors
+	router.GET("/api/v1/food-tracker/items/:itemId/photos/*path", noop)
+	router.GET("/health", noop)
+	router.POST("/api/v1/logs", noop)
+	return router, doc
+}
+
+// roundTrip returns the document as clients decode it
+func roundTrip(t *testing.T, doc map[string]any) map[string]any {
+	t.Helper()
+	raw, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(raw, &decoded))
+	return decoded
+}
+
+func TestBuild_ValidDocument(t *testing.T) {
+	router, doc := fixtureRouter()
+
+	built := doc.Build(router.Routes())
+
+	require.NoError(t, Validate(built))
+	decoded := roundTrip(t, built)
+	assert.Equal(t, Version, decoded["openapi"])
+	assert.Equal(t, "Test API", decoded["info"].(map[string]any)["title"])
+}
+
+func TestBuild_EveryRouteAppears(t *testing.T) {
+	router, doc := fixtureRouter()
+
+	paths := roundTrip(t, doc.Build(router.Routes()))["paths"].(map[string]any)
+
+	for _, r := range router.Routes() {
+		path, _ := convertPath(r.Path)
+		item, ok := paths[path].(map[string]any)
+		require.True(t, ok, "path %s is missing", path)
+		assert.Contains(t, item, strings.ToLower(r.Method), "%s %s is missing", r.Method, r.Path)
+	}
+}
+
+func TestBuild_DescribedOperation(t *testing.T) {
+	router, doc := fixtureRouter()
+
+	decoded := roundTrip(t, doc.Build(router.Routes()))
+	paths := decoded["paths"].(map[string]any)
+
+	t.Run("query parameters and API key security", func(t *testing.T) {
+		op := paths["/api/v1/entries"].(map[string]any)["get"].(map[string]any)
+
+		assert.Equal(t, "Список", op["summary"])
+		assert.Equal(t, []any{"entries"}, op["tags"])
+		assert.Len(t, op["security"], 2)
+		params := op["parameters"].([]any)
+		require.Len(t, params, 2)
+		date := params[0].(map[string]any)
+		assert.Equal(t, "date", date["name"])
+		assert.Equal(t, "query", date["in"])
+		assert.Equal(t, true, date["required"])
+		limit := params[1].(map[string]any)
+		assert.NotContains(t, limit, "required")
+		assert.Equal(t, map[string]any{"type": "integer", "format": "int64", "minimum": 1.0, "maximum": 100.0}, limit["schema"])
+
+		responses := op["responses"].(map[string]any)
+		assert.Contains(t, responses, "200")
+		assert.Contains(t, responses, "400")
+		assert.Contains(t, responses, "401")
+		assert.Contains(t, responses, "default")
+	})
+
+	t.Run("streamed alternative without the envelope", func(t *testing.T) {
+		op := paths["/api/v1/entries"].(map[string]any)["get"].(map[string]any)
+
+		content := op["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+		assert.Contains(t, content, "application/json")
+		stream := content[StreamContentType].(map[string]any)
+		assert.Equal(t, map[string]any{"$ref": "#/components/schemas/openapi.fixtureEntry"}, stream["schema"])
+
+		post := paths["/api/v1/entries"].(map[string]any)["post"].(map[string]any)
+		assert.NotContains(t, post["responses"].(map[string]any)["201"].(map[string]any)["content"], StreamContentType)
+	})
+
+	t.Run("request body and created status", func(t *testing.T) {
+		op := paths["/api/v1/entries"].(map[string]any)["post"].(map[string]any)
+
+		body := op["requestBody"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)
+		assert.Equal(t, "#/components/schemas/openapi.fixtureCreateRequest", body["schema"].(map[string]any)["$ref"])
+		responses := op["responses"].(map[string]any)
+		assert.Contains(t, responses, "201")
+		assert.NotContains(t, responses, "200")
+		data := responses["201"].(map[string]any)["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)["properties"].(map[string]any)["data"]
+		assert.Equal(t, map[string]any{"$ref": "#/components/schemas/openapi.fixtureEntry"}, data)
+	})
+
+	t.Run("path parameters", func(t *testing.T) {
+		op := paths["/api/v1/entries/{id}"].(map[string]any)["delete"].(map[string]any)
+
+		params := op["parameters"].([]any)
+		require.Len(t, params, 1)
+		assert.Equal(t, map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string"}}, params[0])
+		assert.NotContains(t, op, "requestBody")
+	})
+}
+
+func TestBuild_UndescribedRoutes(t *testing.T) {
+	router, doc := fixtureRouter()
+
+	paths := roundTrip(t, doc.Build(router.Routes()))["paths"].(map[string]any)
+
+	op := paths["/api/v1/food-tracker/items/{itemId}/photos/{path}"].(map[string]any)["get"].(map[string]any)
+	assert.Equal(t, []any{"food-tracker"}, op["tags"])
+	assert.Len(t, op["parameters"], 2)
+	assert.Contains(t, op["responses"], "default")
+
+	health := paths["/health"].(map[string]any)["get"].(map[string]any)
+	assert.Equal(t, []any{"health"}, health["tags"])
+	logs := paths["/api/v1/logs"].(map[string]any)["post"].(map[string]any)
+	assert.Equal(t, []any{"logs"}, logs["tags"])
+}
+
+func TestSchemas(t *testing.T) {
+	router, doc := fixtureRouter()
+
+	schemas := roundTrip(t, doc.Build(router.Routes()))["components"].(map[string]any)["schemas"].(map[string]any)
+
+	t.Run("struct fields follow encoding/json", func(t *testing.T) {
+		entry := schemas["openapi.fixtureEntry"].(map[string]any)
+		props := entry["properties"].(map[string]any)
+
+		assert.Equal(t, map[string]any{"type": "string", "format": "date-time"}, props["created_at"])
+		assert.Equal(t, map[string]any{"type": "string", "format": "date"}, props["date"])
+		assert.Equal(t, map[string]any{"type": "number", "format": "double"}, props["calories"])
+		assert.NotContains(t, props, "Secret")
+		assert.NotContains(t, props, "-")
+		// Recursive pointers become a nullable reference
+		assert.Equal(t, map[string]any{
+			"allOf":    []any{map[string]any{"$ref": "#/components/schemas/openapi.fixtureEntry"}},
+			"nullable": true,
+		}, props["parent"])
+	})
+
+	t.Run("binding rules become constraints", func(t *testing.T) {
+		req := schemas["openapi.fixtureCreateRequest"].(map[string]any)
+		props := req["properties"].(map[string]any)
+
+		assert.ElementsMatch(t, []any{"food", "meal"}, req["required"])
+		assert.Equal(t, map[string]any{"type": "string", "minLength": 1.0, "maxLength": 100.0}, props["food"])
+		assert.Equal(t, []any{"breakfast", "lunch", "dinner"}, props["meal"].(map[string]any)["enum"])
+		assert.Equal(t, map[string]any{"type": "number", "format": "double", "nullable": true, "minimum": 0.0, "maximum": 5000.0}, props["calories"])
+		assert.Equal(t, "email", props["email"].(map[string]any)["format"])
+		// Rules after dive apply to the elements
+		assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "maxItems": 5.0}, props["tags"])
+	})
+
+	t.Run("error envelope", func(t *testing.T) {
+		assert.Contains(t, schemas, "response.Response")
+	})
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() map[string]any {
+		return map[string]any{
+			"openapi": "3.0.3",
+			"info":    map[string]any{"title": "API", "version": "1"},
+			"paths": map[string]any{
+				"/items/{id}": map[string]any{
+					"get": map[string]any{
+						"parameters": []any{map[string]any{"name": "id", "in": "path", "required": true, "schema": map[string]any{"type": "string"}}},
+						"security":   []any{map[string]any{"bearerAuth": []any{}}},
+						"responses": map[string]any{"200": map[string]any{
+							"description": "OK",
+							"content": map[string]any{"application/json": map[string]any{
+								"schema": map[string]any{"$ref": "#/components/schemas/Item"},
+							}},
+						}},
+					},
+				},
+			},
+			"components": map[string]any{
+				"schemas": map[string]any{"Item": map[string]any{
+					"type":       "object",
+					"required":   []any{"id"},
+					"properties": map[string]any{"id": map[string]any{"type": "string"}},
+				}},
+				"securitySchemes": map[string]any{"bearerAuth": map[string]any{"type": "http", "scheme": "bearer"}},
+			},
+		}
+	}
+	operation := func(doc map[string]any) map[string]any {
+		return doc["paths"].(map[string]any)["/items/{id}"].(map[string]any)["get"].(map[string]any)
+	}
+
+	require.NoError(t, Validate(valid()))
+
+	tests := []struct {
+		name   string
+		mutate func(doc map[string]any)
+		want   string
+	}{
+		{"swagger 2 version", func(doc map[string]any) { doc["openapi"] = "2.0" }, "unsupported version"},
+		{"missing title", func(doc map[string]any) { doc["info"] = map[string]any{"version": "1"} }, "info.title"},
+		{"undeclared path parameter", func(doc map[string]any) { delete(operation(doc), "parameters") }, `"id" is not declared`},
+		{"optional path parameter", func(doc map[string]any) {
+			operation(doc)["parameters"].([]any)[0].(map[string]any)["required"] = false
+		}, "must be required"},
+		{"no responses", func(doc map[string]any) { operation(doc)["responses"] = map[string]any{} }, "responses are required"},
+		{"invalid status code", func(doc map[string]any) {
+			operation(doc)["responses"] = map[string]any{"ok": map[string]any{"description": "OK"}}
+		}, "invalid response code"},
+		{"missing description", func(doc map[string]any) {
+			operation(doc)["responses"] = map[string]any{"200": map[string]any{}}
+		}, "description is required"},
+		{"unresolved ref", func(doc map[string]any) {
+			delete(doc["components"].(map[string]any)["schemas"].(map[string]any), "Item")
+		}, "unresolved $ref"},
+		{"unknown security scheme", func(doc map[string]any) {
+			operation(doc)["security"] = []any{map[string]any{"oauth": []any{}}}
+		}, "unknown security scheme"},
+		{"invalid schema type", func(doc map[string]any) {
+			item := doc["components"].(map[string]any)["schemas"].(map[string]any)["Item"].(map[string]any)
+			item["properties"].(map[string]any)["id"] = map[string]any{"type": "uuid"}
+		}, "invalid type"},
+		{"undefined required property", func(doc map[string]any) {
+			item := doc["components"].(map[string]any)["schemas"].(map[string]any)["Item"].(map[string]any)
+			item["required"] = []any{"name"}
+		}, "required property name"},
+		{"array without items", func(doc map[string]any) {
+			item := doc["components"].(map[string]any)["schemas"].(map[string]any)["Item"].(map[string]any)
+			item["properties"].(map[string]any)["id"] = map[string]any{"type": "array"}
+		}, "schema must be an object"},
+		{"unknown method", func(doc map[string]any) {
+			doc["paths"].(map[string]any)["/items/{id}"].(map[string]any)["fetch"] = map[string]any{}
+		}, "unknown operation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := valid()
+			tt.mutate(doc)
+
+			err := Validate(doc)
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	router, doc := fixtureRouter()
+	router.GET("/api/v1/openapi.json", doc.Handler(router))
+	router.GET("/docs", doc.UI("/api/v1/openapi.json"))
+
+	t.Run("serves the document", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		var decoded map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &decoded))
+		require.NoError(t, Validate(decoded))
+		// Routes registered after the handler are included too
+		assert.Contains(t, decoded["paths"], "/api/v1/openapi.json")
+		assert.Contains(t, decoded["paths"], "/docs")
+	})
+
+	t.Run("serves Swagger UI", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+		assert.Contains(t, w.Body.String(), "SwaggerUIBundle")
+		assert.Contains(t, w.Body.String(), `url: "/api/v1/openapi.json"`)
+		assert.Contains(t, w.Body.String(), "<title>Test API</title>")
+	})
+}
@@ -0,0 +1,272 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/types"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	dateType       = reflect.TypeOf(types.Date{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	// Component names may only use these characters
+	invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+)
+
+// schemas derives JSON schemas from Go types. Named structs become
+// components referenced by $ref, which also keeps recursive types finite.
+type schemas struct {
+	components map[string]any
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{components: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+// of returns the schema of values of type t as encoding/json marshals them
+func (s *schemas) of(t reflect.Type) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == dateType:
+		return map[string]any{"type": "string", "format": "date"}
+	case t == rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.of(t.Elem())
+		if _, ref := schema["$ref"]; ref {
+			// $ref siblings are ignored in OpenAPI 3.0
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32:
+		return map[string]any{"type": "number", "format": "float"}
+	case reflect.Float64:
+		return map[string]any{"type": "number", "format": "double"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + s.component(t)}
+	}
+
+	// interface{} and anything else accept any value
+	return map[string]any{}
+}
+
+// component registers a named struct once and returns its component name,
+// qualified with the package ("nutrition.Entry")
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	name := invalidNameChars.ReplaceAllString(pkg+"."+t.Name(), "_")
+	for i := 2; s.components[name] != nil; i++ {
+		name = invalidNameChars.ReplaceAllString(pkg+"."+t.Name(), "_") + strconv.Itoa(i)
+	}
+
+	s.names[t] = name
+	s.components[name] = map[string]any{} // placeholder while fields are resolved
+	s.components[name] = s.object(t)
+	return name
+}
+
+// object returns the schema of a struct's JSON object
+func (s *schemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.fields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// fields adds the JSON fields of struct t, flattening embedded structs the
+// way encoding/json does
+func (s *schemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, omitted := jsonName(f)
+		if omitted {
+			continue
+		}
+
+		if f.Anonymous && name == "" {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.fields(embedded, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		schema := s.of(f.Type)
+		if applyBinding(schema, f.Type, f.Tag.Get("binding")) {
+			*required = append(*required, name)
+		}
+		properties[name] = schema
+	}
+}
+
+// queryParameters returns query parameters for the form-tagged fields of t
+func (s *schemas) queryParameters(t reflect.Type) []any {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []any
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("form"), ",")
+		if name == "" || name == "-" || !f.IsExported() {
+			continue
+		}
+
+		schema := s.of(f.Type)
+		param := map[string]any{"name": name, "in": "query", "schema": schema}
+		if applyBinding(schema, f.Type, f.Tag.Get("binding")) {
+			param["required"] = true
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+// jsonName returns the name from a field's json tag; omitted is true for
+// fields tagged "-"
+func jsonName(f reflect.StructField) (name string, omitted bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, false
+}
+
+// applyBinding adds the constraints of validator binding rules to schema
+// and reports whether the field is required. Rules after "dive" apply to
+// elements and are left out.
+func applyBinding(schema map[string]any, t reflect.Type, binding string) (required bool) {
+	if binding == "" {
+		return false
+	}
+	if _, ref := schema["$ref"]; ref {
+		return strings.Contains(","+binding+",", ",required,")
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for _, rule := range strings.Split(binding, ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema["format"] = "email"
+		case "url":
+			schema["format"] = "uri"
+		case "uuid":
+			schema["format"] = "uuid"
+		case "oneof":
+			schema["enum"] = enumValues(t, strings.Fields(value))
+		case "min", "gte", "max", "lte":
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			setBound(schema, t, key == "min" || key == "gte", n)
+		}
+	}
+	return required
+}
+
+// setBound sets a lower or upper bound: a length for strings, an item
+// count for slices and a value for numbers
+func setBound(schema map[string]any, t reflect.Type, lower bool, n float64) {
+	var keyword string
+	switch t.Kind() {
+	case reflect.String:
+		keyword = "Length"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		keyword = "Items"
+		if t.Kind() == reflect.Map {
+			keyword = "Properties"
+		}
+	default:
+		if lower {
+			schema["minimum"] = n
+		} else {
+			schema["maximum"] = n
+		}
+		return
+	}
+	if lower {
+		schema["min"+keyword] = int(n)
+	} else {
+		schema["max"+keyword] = int(n)
+	}
+}
+
+// enumValues converts oneof values to the field's JSON type
+func enumValues(t reflect.Type, values []string) []any {
+	enum := make([]any, 0, len(values))
+	for _, v := range values {
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+				enum = append(enum, n)
+				continue
+			}
+		}
+		enum = append(enum, v)
+	}
+	return enum
+}
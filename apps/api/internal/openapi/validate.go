@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+var (
+	versionPattern   = regexp.MustCompile(`^3\.0\.\d+$`)
+	componentPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+	statusPattern    = regexp.MustCompile(`^[1-5]\d\d$`)
+	templatePattern  = regexp.MustCompile(`\{([^}]+)\}`)
+
+	operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	schemaTypes      = []string{"string", "number", "integer", "boolean", "array", "object"}
+)
+
+// Validate checks a document against the structural rules of the OpenAPI
+// 3.0 specification that a generated document can break: path templates
+// and their parameters, operations and responses, schema types and that
+// every $ref and security requirement resolves.
+func Validate(doc map[string]any) error {
+	// Validate what clients receive, not the Go values
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("document is not JSON: %w", err)
+	}
+	var d map[string]any
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return err
+	}
+
+	v := &validator{}
+	if version, _ := d["openapi"].(string); !versionPattern.MatchString(version) {
+		v.fail("openapi: unsupported version %q", d["openapi"])
+	}
+	info, _ := d["info"].(map[string]any)
+	if title, _ := info["title"].(string); title == "" {
+		v.fail("info.title is required")
+	}
+	if version, _ := info["version"].(string); version == "" {
+		v.fail("info.version is required")
+	}
+
+	components, _ := d["components"].(map[string]any)
+	v.schemas, _ = components["schemas"].(map[string]any)
+	v.securitySchemes, _ = components["securitySchemes"].(map[string]any)
+	for _, name := range sortedKeys(v.schemas) {
+		if !componentPattern.MatchString(name) {
+			v.fail("components.schemas: invalid name %q", name)
+		}
+		v.schema("components.schemas."+name, v.schemas[name])
+	}
+
+	paths, ok := d["paths"].(map[string]any)
+	if !ok {
+		v.fail("paths is required")
+	}
+	for _, path := range sortedKeys(paths) {
+		if !strings.HasPrefix(path, "/") {
+			v.fail("paths: %q must start with /", path)
+		}
+		item, _ := paths[path].(map[string]any)
+		for _, method := range sortedKeys(item) {
+			if !slices.Contains(operationMethods, method) {
+				v.fail("%s: unknown operation %q", path, method)
+				continue
+			}
+			op, _ := item[method].(map[string]any)
+			v.operation(strings.ToUpper(method)+" "+path, path, op)
+		}
+	}
+
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	schemas         map[string]any
+	securitySchemes map[string]any
+	errs            []error
+}
+
+func (v *validator) fail(format string, args ...any) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+func (v *validator) operation(at, path string, op map[string]any) {
+	// Every template variable needs exactly one required path parameter and
+	// every path parameter a template variable
+	templated := map[string]bool{}
+	for _, m := range templatePattern.FindAllStringSubmatch(path, -1) {
+		templated[m[1]] = true
+	}
+	declared := map[string]bool{}
+	params, _ := op["parameters"].([]any)
+	for _, p := range params {
+		param, _ := p.(map[string]any)
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		if name == "" || !slices.Contains([]string{"path", "query", "header", "cookie"}, in) {
+			v.fail("%s: parameter needs a name and a valid location", at)
+			continue
+		}
+		if in == "path" {
+			if declared[name] {
+				v.fail("%s: path parameter %q declared twice", at, name)
+			}
+			declared[name] = true
+			if required, _ := param["required"].(bool); !required {
+				v.fail("%s: path parameter %q must be required", at, name)
+			}
+			if !templated[name] {
+				v.fail("%s: path parameter %q is not in the path", at, name)
+			}
+		}
+		v.schema(at+" parameter "+name, param["schema"])
+	}
+	for name := range templated {
+		if !declared[name] {
+			v.fail("%s: path parameter %q is not declared", at, name)
+		}
+	}
+
+	if body, ok := op["requestBody"].(map[string]any); ok {
+		v.content(at+" requestBody", body["content"])
+	}
+
+	responses, _ := op["responses"].(map[string]any)
+	if len(responses) == 0 {
+		v.fail("%s: responses are required", at)
+	}
+	for _, code := range sortedKeys(responses) {
+		if code != "default" && !statusPattern.MatchString(code) {
+			v.fail("%s: invalid response code %q", at, code)
+		}
+		resp, _ := responses[code].(map[string]any)
+		if _, ok := resp["description"].(string); !ok {
+			v.fail("%s %s: response description is required", at, code)
+		}
+		if content, ok := resp["content"]; ok {
+			v.content(at+" "+code, content)
+		}
+	}
+
+	security, _ := op["security"].([]any)
+	for _, s := range security {
+		requirement, _ := s.(map[string]any)
+		for name := range requirement {
+			if _, ok := v.securitySchemes[name]; !ok {
+				v.fail("%s: unknown security scheme %q", at, name)
+			}
+		}
+	}
+}
+
+func (v *validator) content(at string, c any) {
+	content, _ := c.(map[string]any)
+	for _, mediaType := range sortedKeys(content) {
+		media, _ := content[mediaType].(map[string]any)
+		v.schema(at+" "+mediaType, media["schema"])
+	}
+}
+
+func (v *validator) schema(at string, s any) {
+	schema, ok := s.(map[string]any)
+	if !ok {
+		v.fail("%s: schema must be an object", at)
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		name, found := strings.CutPrefix(ref, "#/components/schemas/")
+		if _, exists := v.schemas[name]; !found || !exists {
+			v.fail("%s: unresolved $ref %q", at, ref)
+		}
+		return
+	}
+
+	if t, ok := schema["type"]; ok {
+		name, _ := t.(string)
+		if !slices.Contains(schemaTypes, name) {
+			v.fail("%s: invalid type %v", at, t)
+		}
+		if name == "array" {
+			v.schema(at+"[]", schema["items"])
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]any)
+	for _, name := range sortedKeys(properties) {
+		v.schema(at+"."+name, properties[name])
+	}
+	required, _ := schema["required"].([]any)
+	for _, r := range required {
+		if name, _ := r.(string); properties[name] == nil {
+			v.fail("%s: required property %v is not defined", at, r)
+		}
+	}
+	if additional, ok := schema["additionalProperties"]; ok {
+		if _, isBool := additional.(bool); !isBool {
+			v.schema(at+".*", additional)
+		}
+	}
+	allOf, _ := schema["allOf"].([]any)
+	for i, sub := range allOf {
+		v.schema(fmt.Sprintf("%s.allOf[%d]", at, i), sub)
+	}
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,167 @@
+package seed
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Profile describes a demo client the generator invents data for
+type Profile struct {
+	// DailyCalories is the weekday intake the meals scatter around
+	DailyCalories float64
+	StartWeight   float64
+	// WeeklyWeightChange is the weight trend in kg per week, negative when losing
+	WeeklyWeightChange float64
+	StartWaist         float64
+	StartHips          float64
+}
+
+// Meal is one generated nutrition entry
+type Meal struct {
+	Meal     string
+	Food     string
+	Calories float64
+	Protein  float64
+	Carbs    float64
+	Fat      float64
+}
+
+// Day is the generated data of one day. Weight is nil on days the client
+// skipped the scales; Waist and Hips are set once a week.
+type Day struct {
+	Date   time.Time
+	Meals  []Meal
+	Weight *float64
+	Waist  *float64
+	Hips   *float64
+}
+
+// food is a catalogue dish with the macros of one regular portion
+type food struct {
+	name                          string
+	calories, protein, carbs, fat float64
+}
+
+var menu = map[string][]food{
+	"breakfast": {
+		{"Овсянка с бананом", 320, 9, 58, 6},
+		{"Омлет из двух яиц", 260, 17, 3, 20},
+		{"Творог 5% с ягодами", 230, 25, 14, 8},
+		{"Сырники со сметаной", 410, 21, 38, 19},
+	},
+	"lunch": {
+		{"Гречка с куриной грудкой", 480, 42, 55, 9},
+		{"Борщ со сметаной", 310, 11, 30, 16},
+		{"Паста с тунцом", 560, 33, 72, 14},
+		{"Плов с говядиной", 650, 27, 78, 25},
+	},
+	"dinner": {
+		{"Запечённый лосось с рисом", 540, 36, 48, 21},
+		{"Салат с курицей и овощами", 350, 31, 14, 18},
+		{"Тушёная индейка с овощами", 390, 38, 20, 16},
+		{"Пельмени", 620, 26, 64, 28},
+	},
+	"snack": {
+		{"Яблоко", 80, 0, 20, 0},
+		{"Греческий йогурт", 150, 14, 8, 7},
+		{"Горсть миндаля", 175, 6, 6, 15},
+		{"Протеиновый батончик", 210, 20, 22, 6},
+	},
+}
+
+// weekendExtras are what weekends add on top of the regular meals
+var weekendExtras = []food{
+	{"Пицца, 2 куска", 540, 22, 62, 22},
+	{"Бургер с картофелем фри", 850, 30, 84, 42},
+	{"Торт, кусок", 420, 5, 52, 21},
+}
+
+// Generate returns days of data for p ending on end, oldest first, drawing
+// from rng only: an rng seeded the same way always yields the same data, so
+// a bug report can name its dataset. Weekdays stay close to DailyCalories;
+// weekends spike 25–45% higher with an extra treat.
+func Generate(rng *rand.Rand, p Profile, end time.Time, days int) []Day {
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -(days - 1))
+
+	out := make([]Day, days)
+	for i := range out {
+		date := start.AddDate(0, 0, i)
+		weekend := date.Weekday() == time.Saturday || date.Weekday() == time.Sunday
+
+		// Portions are scaled so the day lands near its target
+		target := p.DailyCalories * (0.9 + 0.2*rng.Float64())
+		if weekend {
+			target *= 1.25 + 0.2*rng.Float64()
+		}
+
+		meals := []Meal{
+			pick(rng, "breakfast", menu["breakfast"]),
+			pick(rng, "lunch", menu["lunch"]),
+			pick(rng, "dinner", menu["dinner"]),
+		}
+		if rng.IntN(3) > 0 {
+			meals = append(meals, pick(rng, "snack", menu["snack"]))
+		}
+		if weekend {
+			meals = append(meals, pick(rng, "snack", weekendExtras))
+		}
+		scaleMeals(meals, target)
+
+		day := Day{Date: date, Meals: meals}
+
+		// The trend plus daily water-weight noise of about ±0.4 kg
+		trend := p.StartWeight + p.WeeklyWeightChange*float64(i)/7
+		if rng.IntN(10) > 0 {
+			day.Weight = ptr(round1(trend + 0.8*(rng.Float64()-0.5)))
+		}
+		if i%7 == 0 {
+			shrink := p.WeeklyWeightChange * float64(i) / 7
+			day.Waist = ptr(round1(p.StartWaist + shrink*0.8))
+			day.Hips = ptr(round1(p.StartHips + shrink*0.6))
+		}
+
+		out[i] = day
+	}
+	return out
+}
+
+func pick(rng *rand.Rand, meal string, foods []food) Meal {
+	f := foods[rng.IntN(len(foods))]
+	return Meal{Meal: meal, Food: f.name, Calories: f.calories, Protein: f.protein, Carbs: f.carbs, Fat: f.fat}
+}
+
+// scaleMeals scales the portions so the meals add up to about target kcal
+func scaleMeals(meals []Meal, target float64) {
+	var total float64
+	for _, m := range meals {
+		total += m.Calories
+	}
+	factor := target / total
+	for i := range meals {
+		meals[i].Calories = math.Round(meals[i].Calories * factor)
+		meals[i].Protein = round1(meals[i].Protein * factor)
+		meals[i].Carbs = round1(meals[i].Carbs * factor)
+		meals[i].Fat = round1(meals[i].Fat * factor)
+	}
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
+
+// Password returns a password that passes the registration policy
+func Password(rng *rand.Rand) string {
+	const letters = "abcdefghjkmnpqrstuvwxyz"
+	b := []byte("Demo-")
+	for range 6 {
+		b = append(b, letters[rng.IntN(len(letters))])
+	}
+	return fmt.Sprintf("%s%02d!", b, rng.IntN(100))
+}
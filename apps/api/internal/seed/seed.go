@@ -0,0 +1,313 @@
+// Package seed fills a development database with demo accounts and a month
+// of realistic client data. Everything goes through the services the API
+// uses, so passwords are hashed and entries validated as in production.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/admin"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/modules/measurements"
+	"github.com/burcev/api/internal/modules/nutrition"
+	"github.com/burcev/api/internal/modules/users"
+	"github.com/burcev/api/internal/shared/csvimport"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/listing"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/types"
+	"github.com/burcev/api/internal/shared/units"
+)
+
+// ErrProduction is returned when the seed is run against a production config
+var ErrProduction = errors.New("seed refuses to run with ENV=production")
+
+// DefaultDays is how many days of data each demo client gets
+const DefaultDays = 30
+
+// Options controls a seed run. The same Seed always produces the same
+// passwords and data.
+type Options struct {
+	Seed  uint64
+	Days  int
+	Today time.Time
+}
+
+// Account is a seeded login
+type Account struct {
+	ID       int64
+	Role     string
+	Email    string
+	Password string
+	Name     string
+}
+
+// Result reports the seeded accounts and how much data they got
+type Result struct {
+	Accounts     []Account
+	Entries      int
+	Weights      int
+	Measurements int
+}
+
+// demoAccount is a fixed demo user; clients carry the profile their data is
+// generated from. Accounts are found by email on later runs.
+type demoAccount struct {
+	email   string
+	name    string
+	role    string
+	profile *Profile
+}
+
+// Accounts are created in this order: the admin and the curator exist
+// before the clients register, so the clients are not auto-assigned to
+// some other coordinator first.
+var demoAccounts = []demoAccount{
+	{email: "demo-admin@example.com", name: "Демо Администратор", role: admin.RoleSuperAdmin},
+	{email: "demo-curator@example.com", name: "Демо Куратор", role: admin.RoleCoordinator},
+	{email: "demo-client1@example.com", name: "Анна Демо", role: admin.RoleClient, profile: &Profile{
+		DailyCalories: 1700, StartWeight: 72, WeeklyWeightChange: -0.4, StartWaist: 82, StartHips: 104,
+	}},
+	{email: "demo-client2@example.com", name: "Иван Демо", role: admin.RoleClient, profile: &Profile{
+		DailyCalories: 2600, StartWeight: 91, WeeklyWeightChange: -0.6, StartWaist: 98, StartHips: 106,
+	}},
+}
+
+// Seeder writes the demo data
+type Seeder struct {
+	db        *database.DB
+	cfg       *config.Config
+	log       *logger.Logger
+	auth      *auth.Service
+	admin     *admin.Service
+	users     *users.Service
+	nutrition *nutrition.Service
+	imports   *measurements.ImportService
+}
+
+// New creates a Seeder
+func New(db *database.DB, cfg *config.Config, log *logger.Logger) *Seeder {
+	return &Seeder{
+		db:        db,
+		cfg:       cfg,
+		log:       log,
+		auth:      auth.NewService(db.DB, cfg, log),
+		admin:     admin.NewService(db, log),
+		users:     users.NewService(db.DB, nil, cfg, log),
+		nutrition: nutrition.NewService(db, log, nil, nil, nil, nil),
+		imports:   measurements.NewImportService(db, log),
+	}
+}
+
+// Seed creates or updates the demo accounts and their data. Running it
+// again updates the same accounts and days instead of adding new ones.
+func (s *Seeder) Seed(ctx context.Context, opts Options) (*Result, error) {
+	if s.cfg.Env == "production" {
+		return nil, ErrProduction
+	}
+	if opts.Days < 1 {
+		opts.Days = DefaultDays
+	}
+
+	result := &Result{}
+	var adminID, curatorID int64
+	for i, demo := range demoAccounts {
+		rng := rand.New(rand.NewPCG(opts.Seed, uint64(i)))
+		account, err := s.ensureAccount(ctx, demo, Password(rng))
+		if err != nil {
+			return nil, fmt.Errorf("seed %s: %w", demo.email, err)
+		}
+
+		// The first admin promotes themselves; everyone else is promoted by them
+		actorID := adminID
+		if demo.role == admin.RoleSuperAdmin {
+			actorID = account.ID
+		}
+		if err := s.admin.ChangeRole(ctx, actorID, account.ID, demo.role); err != nil {
+			return nil, fmt.Errorf("seed %s role: %w", demo.email, err)
+		}
+
+		switch demo.role {
+		case admin.RoleSuperAdmin:
+			adminID = account.ID
+		case admin.RoleCoordinator:
+			curatorID = account.ID
+		case admin.RoleClient:
+			if err := s.admin.AssignCurator(ctx, account.ID, curatorID); err != nil {
+				return nil, fmt.Errorf("seed %s curator: %w", demo.email, err)
+			}
+			days := Generate(rng, *demo.profile, opts.Today, opts.Days)
+			if err := s.seedClient(ctx, account.ID, days, opts.Today, result); err != nil {
+				return nil, fmt.Errorf("seed %s data: %w", demo.email, err)
+			}
+		}
+
+		result.Accounts = append(result.Accounts, *account)
+	}
+
+	// Run the queued measurement imports now rather than waiting for a worker
+	for {
+		processed, err := s.imports.ProcessNextImport(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("seed measurements: %w", err)
+		}
+		if !processed {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+// ensureAccount registers the demo user, or finds them by email and resets
+// their name and password, and marks onboarding done
+func (s *Seeder) ensureAccount(ctx context.Context, demo demoAccount, password string) (*Account, error) {
+	account := &Account{Role: demo.role, Email: demo.email, Password: password, Name: demo.name}
+
+	err := s.db.QueryRowContext(ctx, `SELECT id FROM users WHERE LOWER(email) = $1`, demo.email).Scan(&account.ID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		registered, err := s.auth.Register(ctx, demo.email, password, demo.name, "127.0.0.1", "seed", nil)
+		if err != nil {
+			return nil, err
+		}
+		account.ID = registered.User.ID
+	case err != nil:
+		return nil, fmt.Errorf("failed to look up user: %w", err)
+	default:
+		if err := s.auth.SetPassword(ctx, account.ID, password); err != nil {
+			return nil, err
+		}
+		if _, err := s.users.UpdateProfile(ctx, account.ID, demo.name, nil, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.users.CompleteOnboarding(ctx, account.ID); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// seedClient writes a client's days: nutrition entries through the
+// nutrition service, weights and measurements as history imports
+func (s *Seeder) seedClient(ctx context.Context, userID int64, days []Day, today time.Time, result *Result) error {
+	if err := s.syncEntries(ctx, userID, days, result); err != nil {
+		return err
+	}
+
+	var weights, bodies []csvimport.Record
+	for i, day := range days {
+		date := day.Date.Format("2006-01-02")
+		if day.Weight != nil {
+			weights = append(weights, csvimport.Record{Line: i + 1, Fields: []string{date, formatNumber(*day.Weight)}})
+		}
+		if day.Waist != nil {
+			bodies = append(bodies, csvimport.Record{Line: i + 1, Fields: []string{date, formatNumber(*day.Waist), formatNumber(*day.Hips)}})
+		}
+	}
+
+	imports := []struct {
+		kind    string
+		mapping csvimport.Mapping
+		records []csvimport.Record
+		count   *int
+	}{
+		{measurements.ImportKindWeight, csvimport.Mapping{"date": "A", "weight": "B"}, weights, &result.Weights},
+		{measurements.ImportKindMeasurements, csvimport.Mapping{"date": "A", "waist": "B", "hips": "C"}, bodies, &result.Measurements},
+	}
+	for _, imp := range imports {
+		created, err := s.imports.CreateImport(ctx, userID, measurements.ImportRequest{
+			Kind:     imp.kind,
+			Strategy: measurements.StrategyReplace,
+			Mapping:  imp.mapping,
+			Records:  imp.records,
+			Today:    today,
+			Units:    units.Metric,
+		})
+		if err != nil {
+			return err
+		}
+		if created.FailedRows > 0 {
+			return fmt.Errorf("%d invalid %s rows", created.FailedRows, imp.kind)
+		}
+		*imp.count += len(imp.records)
+	}
+	return nil
+}
+
+// syncEntries makes the client's entries on the seeded days match the
+// generated meals. Entries are matched by date, meal and position within
+// the meal; unchanged ones are left alone and leftovers deleted, so a rerun
+// neither duplicates entries nor piles up revisions.
+func (s *Seeder) syncEntries(ctx context.Context, userID int64, days []Day, result *Result) error {
+	existing, err := s.nutrition.GetEntries(ctx, userID,
+		listing.Sort{Column: "created_at"}, listing.Page{})
+	if err != nil {
+		return err
+	}
+
+	seeded := make(map[string]bool, len(days))
+	for _, day := range days {
+		seeded[day.Date.Format("2006-01-02")] = true
+	}
+	current := make(map[string][]*nutrition.Entry)
+	for _, entry := range existing {
+		if seeded[entry.Date] {
+			key := entry.Date + "/" + entry.Meal
+			current[key] = append(current[key], entry)
+		}
+	}
+
+	for _, day := range days {
+		date := day.Date.Format("2006-01-02")
+		for _, meal := range day.Meals {
+			req := &nutrition.CreateEntryRequest{
+				Date: types.DateOf(day.Date), Meal: meal.Meal, Food: meal.Food,
+				Calories: &meal.Calories, Protein: meal.Protein, Carbs: meal.Carbs, Fat: meal.Fat,
+			}
+
+			key := date + "/" + meal.Meal
+			if len(current[key]) == 0 {
+				if _, err := s.nutrition.CreateEntry(ctx, userID, req); err != nil {
+					return err
+				}
+			} else {
+				entry := current[key][0]
+				current[key] = current[key][1:]
+				if !sameMeal(entry, meal) {
+					if _, err := s.nutrition.UpdateEntry(ctx, userID, entry.ID, req, nil); err != nil {
+						return err
+					}
+				}
+			}
+			result.Entries++
+		}
+	}
+
+	for _, leftovers := range current {
+		for _, entry := range leftovers {
+			if err := s.nutrition.DeleteEntry(ctx, userID, entry.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sameMeal(entry *nutrition.Entry, meal Meal) bool {
+	return entry.Food == meal.Food && entry.Calories == meal.Calories &&
+		entry.Protein == meal.Protein && entry.Carbs == meal.Carbs && entry.Fat == meal.Fat &&
+		entry.RecipeID == nil
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,125 @@
+package seed
+
+import (
+	"context"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testProfile = Profile{DailyCalories: 2000, StartWeight: 80, WeeklyWeightChange: -0.5, StartWaist: 90, StartHips: 100}
+
+// 2026-03-01 is a Sunday
+var testEnd = time.Date(2026, 3, 1, 18, 30, 0, 0, time.UTC)
+
+func generate(seed uint64) []Day {
+	return Generate(rand.New(rand.NewPCG(seed, 0)), testProfile, testEnd, 28)
+}
+
+func TestGenerate_Deterministic(t *testing.T) {
+	assert.Equal(t, generate(42), generate(42))
+	assert.NotEqual(t, generate(42), generate(43))
+}
+
+func TestGenerate_Days(t *testing.T) {
+	days := generate(1)
+
+	require.Len(t, days, 28)
+	assert.Equal(t, "2026-02-02", days[0].Date.Format("2006-01-02"))
+	assert.Equal(t, "2026-03-01", days[27].Date.Format("2006-01-02"))
+
+	measured := 0
+	for _, day := range days {
+		meals := map[string]bool{}
+		for _, m := range day.Meals {
+			meals[m.Meal] = true
+			assert.Greater(t, m.Calories, 0.0)
+		}
+		assert.True(t, meals["breakfast"] && meals["lunch"] && meals["dinner"], day.Date)
+		if day.Waist != nil {
+			measured++
+		}
+	}
+	assert.Equal(t, 4, measured, "measured once a week")
+}
+
+func TestGenerate_WeekendSpike(t *testing.T) {
+	var weekday, weekend []float64
+	for seed := range uint64(20) {
+		for _, day := range generate(seed) {
+			var total float64
+			for _, m := range day.Meals {
+				total += m.Calories
+			}
+			if day.Date.Weekday() == time.Saturday || day.Date.Weekday() == time.Sunday {
+				weekend = append(weekend, total)
+			} else {
+				weekday = append(weekday, total)
+			}
+		}
+	}
+
+	assert.InDelta(t, 2000, mean(weekday), 60)
+	assert.Greater(t, mean(weekend), mean(weekday)*1.2)
+}
+
+func TestGenerate_WeightTrend(t *testing.T) {
+	days := generate(1)
+
+	first, last := firstWeight(days), firstWeight(reverse(days))
+	assert.InDelta(t, -0.5*27/7, last-first, 0.9)
+}
+
+func TestPassword_PassesPolicy(t *testing.T) {
+	validator := auth.NewPasswordValidator()
+	for seed := range uint64(50) {
+		password := Password(rand.New(rand.NewPCG(seed, 0)))
+		result := validator.Validate(password, auth.UserContext{Email: "demo-client1@example.com"})
+		assert.True(t, result.Valid, "%s: %v", password, result.Errors)
+	}
+}
+
+func TestSeed_RefusesProduction(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	seeder := New(&database.DB{DB: mockDB}, &config.Config{Env: "production"}, logger.New())
+
+	_, err = seeder.Seed(context.Background(), Options{Seed: 1, Today: testEnd})
+
+	assert.ErrorIs(t, err, ErrProduction)
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing touches the database")
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func firstWeight(days []Day) float64 {
+	for _, day := range days {
+		if day.Weight != nil {
+			return *day.Weight
+		}
+	}
+	return 0
+}
+
+func reverse(days []Day) []Day {
+	out := make([]Day, len(days))
+	for i, day := range days {
+		out[len(days)-1-i] = day
+	}
+	return out
+}
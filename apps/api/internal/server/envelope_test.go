@@ -0,0 +1,94 @@
+package server
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// typedEnvelopeModules send typed response DTOs. Modules move here as their
+// handlers stop building success bodies out of gin.H.
+var typedEnvelopeModules = []string{"auth", "features", "meal-plans", "nutrition", "users"}
+
+// successDataArg is the index of the data argument of each response
+// function sending a success body
+var successDataArg = map[string]int{
+	"Success":             2,
+	"SuccessWithNotice":   2,
+	"SuccessWithWarnings": 2,
+	"SuccessWithMessage":  3,
+	"LocalizedSuccess":    3,
+	"CachedJSON":          2,
+}
+
+// TestHandlersSendTypedSuccessBodies keeps raw gin.H out of the success
+// responses of migrated modules: clients generate their types from the
+// documented DTOs, and a gin.H body has none.
+func TestHandlersSendTypedSuccessBodies(t *testing.T) {
+	for _, module := range typedEnvelopeModules {
+		files, err := filepath.Glob(filepath.Join("..", "modules", module, "*.go"))
+		require.NoError(t, err)
+		require.NotEmpty(t, files, module)
+
+		for _, file := range files {
+			if strings.HasSuffix(file, "_test.go") {
+				continue
+			}
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, file, nil, 0)
+			require.NoError(t, err)
+
+			ast.Inspect(f, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				for _, arg := range successArgs(call) {
+					if isGinH(arg) {
+						t.Errorf("%s: success body built from gin.H; send a response DTO", fset.Position(arg.Pos()))
+					}
+				}
+				return true
+			})
+		}
+	}
+}
+
+// successArgs returns the body arguments of response.<success function>
+// and c.JSON calls
+func successArgs(call *ast.CallExpr) []ast.Expr {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return nil
+	}
+	if sel.Sel.Name == "JSON" && len(call.Args) == 2 {
+		return call.Args[1:]
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok || pkg.Name != "response" {
+		return nil
+	}
+	if i, ok := successDataArg[sel.Sel.Name]; ok && i < len(call.Args) {
+		return call.Args[i : i+1]
+	}
+	return nil
+}
+
+// isGinH reports whether expr is a gin.H literal
+func isGinH(expr ast.Expr) bool {
+	lit, ok := expr.(*ast.CompositeLit)
+	if !ok {
+		return false
+	}
+	sel, ok := lit.Type.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "gin" && sel.Sel.Name == "H"
+}
@@ -0,0 +1,957 @@
+// Package server assembles the API: the shared services, the HTTP router
+// and the background jobs. cmd/server runs it; integration tests build it
+// over a mock database.
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/modules/activity"
+	"github.com/burcev/api/internal/modules/admin"
+	"github.com/burcev/api/internal/modules/audit"
+	"github.com/burcev/api/internal/modules/auth"
+	"github.com/burcev/api/internal/modules/broadcast"
+	"github.com/burcev/api/internal/modules/chat"
+	"github.com/burcev/api/internal/modules/comments"
+	"github.com/burcev/api/internal/modules/content"
+	"github.com/burcev/api/internal/modules/curator"
+	"github.com/burcev/api/internal/modules/dashboard"
+	"github.com/burcev/api/internal/modules/devicesync"
+	"github.com/burcev/api/internal/modules/features"
+	foodtracker "github.com/burcev/api/internal/modules/food-tracker"
+	"github.com/burcev/api/internal/modules/goals"
+	"github.com/burcev/api/internal/modules/logs"
+	"github.com/burcev/api/internal/modules/maintenance"
+	mealplans "github.com/burcev/api/internal/modules/meal-plans"
+	"github.com/burcev/api/internal/modules/measurements"
+	"github.com/burcev/api/internal/modules/notifications"
+	"github.com/burcev/api/internal/modules/nutrition"
+	nutritioncalc "github.com/burcev/api/internal/modules/nutrition-calc"
+	"github.com/burcev/api/internal/modules/organizations"
+	"github.com/burcev/api/internal/modules/photos"
+	"github.com/burcev/api/internal/modules/quotas"
+	"github.com/burcev/api/internal/modules/recipes"
+	"github.com/burcev/api/internal/modules/recommendations"
+	"github.com/burcev/api/internal/modules/sharing"
+	"github.com/burcev/api/internal/modules/status"
+	"github.com/burcev/api/internal/modules/summaries"
+	"github.com/burcev/api/internal/modules/supplements"
+	"github.com/burcev/api/internal/modules/uploads"
+	"github.com/burcev/api/internal/modules/users"
+	"github.com/burcev/api/internal/modules/webhooks"
+	"github.com/burcev/api/internal/openapi"
+	"github.com/burcev/api/internal/shared/cache"
+	"github.com/burcev/api/internal/shared/cors"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/events"
+	"github.com/burcev/api/internal/shared/idempotency"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/middleware"
+	"github.com/burcev/api/internal/shared/openrouter"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/securityevents"
+	"github.com/burcev/api/internal/shared/storage"
+	"github.com/burcev/api/internal/shared/ws"
+	"github.com/gin-gonic/gin"
+)
+
+// Deps are the clients and services shared by the routes and the
+// background jobs. Optional components are nil when not configured.
+type Deps struct {
+	db    *database.DB
+	log   *logger.Logger
+	email *email.Service
+	cache cache.Cache
+	// securityEvents counts the security events of log
+	securityEvents *securityevents.Registry
+
+	weeklyPhotosS3  *storage.S3Client
+	profilePhotosS3 *storage.S3Client
+	chatS3          *storage.S3Client
+	contentS3       *storage.S3Client
+	foodPhotosS3    *storage.S3Client
+	storageRegions  *storage.Regions
+	quotas          *quotas.Service
+	features        *features.Service
+
+	organizations       *organizations.Service
+	organizationImports *organizations.ImportService
+	historyImports      *measurements.ImportService
+	maintenanceTracker  *maintenance.Tracker
+	maintenance         *maintenance.Service
+	uploads             *uploads.Service
+	bodyFatAnalyzer     photos.Analyzer
+	photosStore         storage.Storage
+	photoFiles          *storage.Router
+	photos              *photos.Service
+	accountDeletion     *users.DeletionService
+	dataExports         *users.ExportService
+	emailChanges        *users.EmailChangeService
+	openRouter          *openrouter.Client
+
+	authRateLimiter *middleware.AuthRateLimiter
+	audit           *audit.Service
+	emailOutbox     *email.Outbox
+	reset           *auth.ResetService
+	sessions        *auth.SessionBlacklist
+	status          *status.Service
+
+	wsHub     *ws.Hub
+	broadcast *broadcast.Service
+	events    *events.Bus
+	eventLog  *events.Log
+	webhooks  *webhooks.Service
+	goals     *goals.Service
+	content   *content.Service
+}
+
+// NewDeps creates the shared clients and services. Storage that fails to
+// initialize is logged and left out, disabling the routes that need it.
+// Nothing is queried or sent until the router or the jobs run, so tests can
+// pass a mock database and an email service over a MemorySender.
+func NewDeps(cfg *config.Config, log *logger.Logger, db *database.DB, emailService *email.Service) *Deps {
+	d := &Deps{db: db, log: log, email: emailService}
+
+	// Security events are counted for alerting (/metrics) and the latest
+	// kept for the admin panel, next to the log
+	d.securityEvents = securityevents.NewRegistry(securityevents.DefaultCapacity)
+	log.AddSecuritySink(d.securityEvents)
+
+	// Cache of computed values (nutrition report days); nil when
+	// CACHE_DRIVER is unset, and the values are then always recomputed
+	cacheStore, err := cache.New(cache.Config{
+		Driver:        cfg.CacheDriver,
+		MemoryEntries: cfg.CacheMemoryEntries,
+		RedisURL:      cfg.RedisURL,
+	})
+	if err != nil {
+		log.Error("Failed to initialize cache, caching disabled", "error", err, "driver", cfg.CacheDriver)
+	}
+	d.cache = cacheStore
+
+	// Per-user quotas, counted by the services writing entries, progress
+	// photos and webhooks
+	d.quotas = quotas.NewService(db, log, quotas.LimitsFrom(cfg))
+
+	// S3 buckets are optional; each needs its own credentials
+	d.weeklyPhotosS3 = newS3Client(log, "weekly photos", cfg.WeeklyPhotosS3AccessKeyID, &storage.S3Config{
+		AccessKeyID:     cfg.WeeklyPhotosS3AccessKeyID,
+		SecretAccessKey: cfg.WeeklyPhotosS3SecretAccessKey,
+		Bucket:          cfg.WeeklyPhotosS3Bucket,
+		Region:          cfg.WeeklyPhotosS3Region,
+		Endpoint:        cfg.WeeklyPhotosS3Endpoint,
+		PathPrefix:      cfg.S3PathPrefix,
+	})
+	d.profilePhotosS3 = newS3Client(log, "profile photos", cfg.ProfilePhotosS3AccessKeyID, &storage.S3Config{
+		AccessKeyID:     cfg.ProfilePhotosS3AccessKeyID,
+		SecretAccessKey: cfg.ProfilePhotosS3SecretAccessKey,
+		Bucket:          cfg.ProfilePhotosS3Bucket,
+		Region:          cfg.ProfilePhotosS3Region,
+		Endpoint:        cfg.ProfilePhotosS3Endpoint,
+		PathPrefix:      cfg.S3PathPrefix,
+	})
+	d.chatS3 = newS3Client(log, "chat", cfg.ChatS3AccessKeyID, &storage.S3Config{
+		AccessKeyID:     cfg.ChatS3AccessKeyID,
+		SecretAccessKey: cfg.ChatS3SecretAccessKey,
+		Bucket:          cfg.ChatS3Bucket,
+		Region:          cfg.ChatS3Region,
+		Endpoint:        cfg.ChatS3Endpoint,
+		PathPrefix:      cfg.S3PathPrefix,
+	})
+	d.contentS3 = newS3Client(log, "content", cfg.ContentS3AccessKeyID, &storage.S3Config{
+		AccessKeyID:     cfg.ContentS3AccessKeyID,
+		SecretAccessKey: cfg.ContentS3SecretAccessKey,
+		Bucket:          cfg.ContentS3Bucket,
+		Region:          cfg.ContentS3Region,
+		Endpoint:        cfg.ContentS3Endpoint,
+		PathPrefix:      cfg.ContentS3PathPrefix,
+	})
+	d.foodPhotosS3 = newS3Client(log, "food photos", cfg.FoodPhotosS3AccessKeyID, &storage.S3Config{
+		AccessKeyID:     cfg.FoodPhotosS3AccessKeyID,
+		SecretAccessKey: cfg.FoodPhotosS3SecretAccessKey,
+		Bucket:          cfg.FoodPhotosS3Bucket,
+		Region:          cfg.FoodPhotosS3Region,
+		Endpoint:        cfg.FoodPhotosS3Endpoint,
+		PathPrefix:      cfg.S3PathPrefix,
+	})
+
+	// Storage regions (data residency): the weekly photos bucket serves the
+	// default region, organizations may be pinned to additional regions
+	d.storageRegions = storage.NewRegions(cfg.StorageDefaultRegion)
+	if d.weeklyPhotosS3 != nil {
+		d.storageRegions.Register(d.storageRegions.Default(), d.weeklyPhotosS3)
+	}
+	for _, rc := range cfg.StorageRegions {
+		regionClient, err := storage.NewS3Client(&storage.S3Config{
+			AccessKeyID:     rc.AccessKeyID,
+			SecretAccessKey: rc.SecretAccessKey,
+			Bucket:          rc.Bucket,
+			Region:          rc.Region,
+			Endpoint:        rc.Endpoint,
+			PathPrefix:      cfg.S3PathPrefix,
+		}, log)
+		if err != nil {
+			log.Error("Failed to initialize storage region", "error", err, "region", rc.Name)
+			continue
+		}
+		d.storageRegions.Register(rc.Name, regionClient)
+		log.Info("Storage region initialized", "region", rc.Name, "bucket", rc.Bucket)
+	}
+	d.organizations = organizations.NewService(db, log, d.storageRegions)
+	d.organizationImports = organizations.NewImportService(db, cfg, log, emailService)
+
+	// Weight and body measurement history imports (written by a background worker)
+	d.historyImports = measurements.NewImportService(db, log)
+
+	// Scheduled maintenance windows (notice headers + automatic maintenance
+	// mode) and manual maintenance mode, forced on by MAINTENANCE_MODE
+	d.maintenanceTracker = maintenance.NewTracker()
+	if cfg.MaintenanceMode {
+		d.maintenanceTracker.Force(cfg.MaintenanceMessage)
+		log.Warn("MAINTENANCE_MODE is set, the API answers 503 until it is unset")
+	}
+	d.maintenance = maintenance.NewService(db, log, d.maintenanceTracker, cfg.MaintenanceModePersist)
+
+	// Sessions signed out within the access token lifetime, whose access
+	// tokens are rejected (refreshed from the database by a background job)
+	d.sessions = auth.NewSessionBlacklist(db.DB, log, cfg.AccessTokenTTL)
+
+	// Progress and meal photos storage (local disk, or the storage region
+	// of the user's organization)
+	if localStore, err := storage.NewLocalStorage(cfg.PhotosStorageDir); err != nil {
+		log.Error("Failed to initialize photos storage", "error", err, "dir", cfg.PhotosStorageDir)
+	} else {
+		d.photosStore = localStore
+		d.photoFiles = storage.NewRouter(localStore, d.storageRegions, d.organizations.UserRegion)
+		log.Info("Photos storage initialized", "dir", cfg.PhotosStorageDir)
+	}
+
+	// Resumable uploads storage (local disk)
+	if uploadsStore, err := storage.NewLocalStorage(cfg.UploadsStorageDir); err != nil {
+		log.Error("Failed to initialize uploads storage", "error", err, "dir", cfg.UploadsStorageDir)
+	} else {
+		d.uploads = uploads.NewService(db, log, uploadsStore)
+		log.Info("Uploads storage initialized", "dir", cfg.UploadsStorageDir)
+	}
+
+	// Body fat estimation analyzer (external vision API)
+	if cfg.BodyFatAnalyzerURL != "" {
+		d.bodyFatAnalyzer = photos.NewHTTPAnalyzer(cfg.BodyFatAnalyzerURL, cfg.BodyFatAnalyzerAPIKey,
+			time.Duration(cfg.BodyFatAnalyzerTimeoutSeconds)*time.Second)
+		log.Info("Body fat analyzer initialized", "url", cfg.BodyFatAnalyzerURL)
+	} else {
+		log.Warn("BODY_FAT_ANALYZER_URL not set, body fat estimation disabled")
+	}
+
+	if d.photoFiles != nil {
+		d.photos = photos.NewService(db, log, d.photoFiles, d.bodyFatAnalyzer, d.quotas)
+	}
+
+	// Feature flags rolling out AI analysis and recommendations to a share
+	// of the users
+	d.features = features.NewService(db, log)
+
+	// Data exports (archives built by a background worker, local disk or
+	// the storage region of the user's organization)
+	if exportsStore, err := storage.NewLocalStorage(cfg.ExportsStorageDir); err != nil {
+		log.Error("Failed to initialize exports storage", "error", err, "dir", cfg.ExportsStorageDir)
+	} else {
+		exportFiles := storage.NewRouter(exportsStore, d.storageRegions, d.organizations.UserRegion)
+		d.dataExports = users.NewExportService(db.DB, cfg, log, exportFiles, emailService)
+		log.Info("Exports storage initialized", "dir", cfg.ExportsStorageDir)
+	}
+
+	// Deleted accounts are purged in the background, photo, avatar, export
+	// and upload files included
+	var purgers []users.FilePurger
+	if d.dataExports != nil {
+		purgers = append(purgers, d.dataExports)
+	}
+	if d.uploads != nil {
+		purgers = append(purgers, d.uploads)
+	}
+	if d.profilePhotosS3 != nil {
+		purgers = append(purgers, users.NewAvatarPurger(db.DB, log, d.profilePhotosS3))
+	}
+	d.accountDeletion = users.NewDeletionService(db.DB, log, d.photosStore, d.storageRegions, purgers...)
+
+	// Email changes, confirmed from the new address
+	d.emailChanges = users.NewEmailChangeService(db.DB, cfg, log, emailService, d.sessions)
+
+	// Initialize OpenRouter client (for AI food recognition)
+	if cfg.OpenRouterAPIKey != "" {
+		d.openRouter = openrouter.NewClient(cfg.OpenRouterAPIKey, cfg.OpenRouterModel, log)
+		log.Info("OpenRouter client initialized", "model", cfg.OpenRouterModel)
+	}
+
+	// Initialize rate limiter (DB-backed, for password reset)
+	rateLimiter := middleware.NewRateLimiter(db, log, middleware.RateLimitConfigFrom(cfg))
+
+	// Initialize auth rate limiter (in-memory sliding window, for login/register)
+	d.authRateLimiter = middleware.NewAuthRateLimiter()
+
+	// Audit log for sensitive operations; login lockouts are recorded by IP
+	// and reported as security events
+	d.audit = audit.NewService(db.DB, log)
+	d.authRateLimiter.OnLimited(func(c *gin.Context, endpoint string) {
+		if endpoint != "login" {
+			return
+		}
+		log.LogSecurityEvent("login_lockout", "high", map[string]interface{}{
+			"ip_address":  c.ClientIP(),
+			"remote_addr": c.RemoteIP(),
+		})
+		d.audit.Record(c.Request.Context(), audit.Entry{
+			ActorIP: c.ClientIP(),
+			Action:  audit.ActionLoginLockout,
+		})
+	})
+
+	// Initialize reset service; its emails are sent by the outbox worker
+	d.emailOutbox = email.NewOutbox(db.DB, log)
+	d.reset = auth.NewResetService(db, cfg, log, emailService, rateLimiter, d.emailOutbox)
+
+	// Public status page: coarse component health from the same checks as /health
+	statusProbes := status.Probes{
+		status.ComponentDatabase: db.Health,
+		status.ComponentEmail:    emailService.Ping,
+	}
+	if d.weeklyPhotosS3 != nil {
+		statusProbes[status.ComponentStorage] = d.weeklyPhotosS3.Ping
+	}
+	d.status = status.NewService(db, log, statusProbes)
+
+	// WebSocket hub (shared between chat handler for REST and WS)
+	d.wsHub = ws.NewHub()
+
+	// Curator broadcasts are fanned out by a background worker
+	d.broadcast = broadcast.NewService(db, log, notifications.NewService(db, log), emailService, d.wsHub)
+
+	// Services publish domain events in the transaction of their changes;
+	// the events are logged, and log consumers such as the webhooks queue
+	// read them in a background worker
+	d.webhooks = webhooks.NewService(db, log, d.quotas)
+	d.eventLog = NewEventLog(db, log, d.webhooks)
+	d.events = events.NewBus(d.eventLog)
+
+	// Stale nutrition targets are detected weekly by a background job
+	d.goals = goals.NewService(db, log, nutritioncalc.NewService(db, log), notifications.NewService(db, log))
+
+	// Content management; the scheduler job publishes scheduled articles
+	var contentS3Uploader content.S3Uploader
+	if d.contentS3 != nil {
+		contentS3Uploader = d.contentS3
+	}
+	d.content = content.NewService(db, log, contentS3Uploader, d.wsHub)
+
+	return d
+}
+
+// NewEventLog returns the domain event log with its consumers registered.
+// The replay command builds it without the rest of Deps.
+func NewEventLog(db *database.DB, log *logger.Logger, hooks *webhooks.Service) *events.Log {
+	eventLog := events.NewLog(db, log)
+	hooks.Consume(eventLog)
+	return eventLog
+}
+
+// EmailOutbox is the queue of emails sent by the outbox worker, one of the
+// Jobs. Tests drain it instead of running the worker.
+func (d *Deps) EmailOutbox() *email.Outbox {
+	return d.emailOutbox
+}
+
+// Jobs returns the background jobs, which run until their context is
+// cancelled. Jobs of disabled components are left out.
+func (d *Deps) Jobs() []func(ctx context.Context) {
+	db, log := d.db, d.log
+
+	// The content scheduler uses the same content service as the routes
+	jobs := []func(ctx context.Context){
+		d.content.RunScheduler,
+		d.broadcast.RunWorker,
+		d.webhooks.RunWorker,
+		d.eventLog.RunWorker,
+		d.emailOutbox.RunWorker,
+		d.historyImports.RunImportWorker,
+		d.organizations.RunRegionMigrations,
+		d.maintenance.RunScheduler,
+		d.sessions.Run,
+		d.goals.RunDetection,
+		d.status.RunProbe,
+		d.accountDeletion.RunPurge,
+		d.features.RunRefresh,
+		idempotency.NewStore(db, log).RunCleanup,
+		logs.NewService(db, log).RunCleanup,
+		nutrition.NewRollupService(db, log).RunReconciliation,
+		notifications.NewService(db, log).RunQuietHoursRelease,
+		summaries.NewService(db, log, d.email, notifications.NewService(db, log)).RunScheduler,
+	}
+	if d.uploads != nil {
+		jobs = append(jobs, d.uploads.RunCleanup)
+	}
+	if d.dataExports != nil {
+		jobs = append(jobs, d.dataExports.RunWorker, d.dataExports.RunCleanup)
+	}
+	if d.photos != nil && d.bodyFatAnalyzer != nil {
+		jobs = append(jobs, d.photos.RunAnalysisWorker)
+	}
+	return jobs
+}
+
+// newS3Client connects to a bucket when its access key is set; it returns
+// nil for an unconfigured bucket or one that fails to initialize
+func newS3Client(log *logger.Logger, name, accessKeyID string, s3cfg *storage.S3Config) *storage.S3Client {
+	if accessKeyID == "" || s3cfg.SecretAccessKey == "" {
+		return nil
+	}
+	client, err := storage.NewS3Client(s3cfg, log)
+	if err != nil {
+		log.Error("Failed to initialize S3 client", "error", err, "client", name)
+		return nil
+	}
+	log.Info("S3 client initialized", "client", name, "bucket", s3cfg.Bucket)
+	return client
+}
+
+// NewRouter creates the HTTP router with every route and its middleware.
+// Routes under /api/v1 require authentication unless they are listed in
+// publicRoutes in server_test.go.
+func NewRouter(cfg *config.Config, d *Deps) *gin.Engine {
+	db, log := d.db, d.log
+
+	// Create Gin router
+	router := gin.New()
+	// Only the configured proxies (nginx, traefik) may set X-Forwarded-For;
+	// any other client is identified by its peer address, so it cannot
+	// spoof its IP past the rate limiters. The list was validated by
+	// config.Load.
+	_ = router.SetTrustedProxies(cfg.TrustedProxies)
+
+	// Global middleware
+	// Panics become JSON 500s; no external error tracker is wired in yet
+	router.Use(middleware.Recovery(log, middleware.NopReporter{}))
+	router.Use(middleware.NoCacheAPI())
+	router.Use(middleware.SecurityHeaders())
+	router.Use(middleware.Logger(log, middleware.RequestLogConfigFrom(cfg)))
+	// Inside Logger, so the logged body size is what went over the wire;
+	// progress and meal photos are streamed from storage as they are
+	router.Use(middleware.Compress(cfg.GzipMinSize, "/api/v1/photos/:id", "/api/v1/nutrition/entries/:id/photo"))
+	router.Use(middleware.ErrorHandler(log))
+	router.Use(response.Compat(cfg.CompatResponses))
+	// Access tokens of revoked sessions stop working on every route
+	router.Use(middleware.RejectRevokedSessions(cfg, d.sessions))
+	router.Use(audit.CaptureActorIP())
+	// Localized responses use the profile language of authenticated users,
+	// otherwise Accept-Language
+	usersService := users.NewService(db.DB, d.profilePhotosS3, cfg, log)
+	router.Use(middleware.Locale(usersService.GetLanguage))
+	router.Use(features.Middleware(d.features))
+
+	// CORS: origins come from CORS_ORIGINS. When none are configured every
+	// origin is allowed, since the API normally sits behind the Next.js proxy.
+	router.Use(cors.New(cfg.CORSOrigins,
+		[]string{uploads.HeaderOffset, uploads.HeaderChecksum, "Content-Range", idempotency.HeaderKey},
+		[]string{maintenance.HeaderWindowStart, maintenance.HeaderWindowEnd, uploads.HeaderOffset, idempotency.HeaderReplay},
+	))
+
+	// Maintenance notices and maintenance mode (after CORS so 503s stay readable in browsers)
+	router.Use(maintenance.Middleware(d.maintenanceTracker, nil))
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		// Check database health
+		dbStatus := "ok"
+		if err := db.Health(c.Request.Context()); err != nil {
+			dbStatus = "unhealthy"
+			log.Error("Database health check failed", "error", err)
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":      "ok",
+			"timestamp":   time.Now().Format(time.RFC3339),
+			"environment": cfg.Env,
+			"database":    dbStatus,
+		})
+	})
+
+	// Prometheus scrape endpoint for the security event counters
+	router.GET("/metrics", func(c *gin.Context) {
+		c.Header("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := d.securityEvents.WritePrometheus(c.Writer); err != nil {
+			log.Warn("Failed to write metrics", "error", err)
+		}
+	})
+
+	// Chat handler (used for both REST routes and WebSocket)
+	chatHandler := chat.NewHandler(cfg, log, db, d.chatS3, d.wsHub)
+
+	// Consumers take completed uploads through these; left nil when uploads are disabled
+	var uploadSource uploads.Source
+	var chunkedUploads uploads.ChunkedSource
+	if d.uploads != nil {
+		uploadSource, chunkedUploads = d.uploads, d.uploads
+	}
+
+	// Read-only integrations authenticate with API keys on the nutrition and
+	// measurements routes
+	apiKeys := users.NewAPIKeyService(db.DB, log)
+
+	// Exports, imports and trends are expensive: a user may run two of them
+	// at once, across all of these routes
+	heavy := middleware.ConcurrencyLimit(2, middleware.UserKey)
+
+	// Role-gated routes re-check the token version so a role change applies
+	// before the access token expires
+	tokenVersions := auth.NewTokenVersions(db.DB)
+
+	// OpenAPI document: modules describe their routes next to the route groups
+	apiDocs := openapi.New("BURCEV API", "1.0")
+
+	// API v1 routes
+	v1 := router.Group("/api/v1")
+	{
+		v1.GET("/openapi.json", apiDocs.Handler(router))
+		apiDocs.Add(v1.BasePath(), "docs", openapi.Endpoint{Method: http.MethodGet, Path: "/openapi.json", Summary: "Этот документ"})
+		if cfg.Env != "production" {
+			v1.GET("/docs", apiDocs.UI("/api/v1/openapi.json"))
+		}
+
+		// Auth routes
+		verificationService := auth.NewVerificationService(db.DB, log, d.email)
+		authHandler := auth.NewHandler(cfg, log, auth.NewService(db.DB, cfg, log), verificationService)
+		resetHandler := auth.NewResetHandler(cfg, log, d.reset)
+		authGroup := v1.Group("/auth")
+		apiDocs.Add(authGroup.BasePath(), "auth", auth.Endpoints()...)
+		auth.RegisterRoutes(authGroup, auth.RouteDeps{Handler: authHandler, Reset: resetHandler, RateLimiter: d.authRateLimiter})
+
+		// Shared nutrition-calc service (used by multiple handlers for KBJU recalculation)
+		nutritionCalcSvc := nutritioncalc.NewService(db, log)
+
+		nutritionHandler := nutrition.NewHandler(cfg, log, db, nutrition.NewService(db, log, d.events, d.cache, d.photoFiles, d.quotas), d.cache, d.photoFiles)
+		commentsHandler := comments.NewHandler(cfg, log, comments.NewService(db, log))
+		mealPlansHandler := mealplans.NewHandler(log, db, mealplans.NewService(db, log))
+
+		// Users routes (protected)
+		usersHandler := users.NewHandler(cfg, log, usersService, apiKeys, nutritionCalcSvc, uploadSource, d.accountDeletion, d.dataExports, d.emailChanges)
+		usersGroup := v1.Group("/users")
+		usersGroup.Use(middleware.RequireAuth(cfg))
+		apiDocs.Add(usersGroup.BasePath(), "users", users.Endpoints()...)
+		apiDocs.Add(usersGroup.BasePath(), "users", nutrition.ScheduleEndpoints()...)
+		users.RegisterRoutes(usersGroup, usersHandler, heavy)
+		// Confirmation links are opened from the new address's mailbox, often
+		// on a device without a session
+		v1.GET("/users/confirm-email", d.authRateLimiter.Limit("confirm_email"), usersHandler.ConfirmEmail)
+		nutrition.RegisterScheduleRoutes(usersGroup, nutritionHandler)
+		sessionHandler := auth.NewSessionHandler(log, auth.NewSessionService(db.DB, log, d.sessions))
+		apiDocs.Add(usersGroup.BasePath(), "users", auth.SessionEndpoints()...)
+		auth.RegisterSessionRoutes(usersGroup, sessionHandler)
+		quotasHandler := quotas.NewHandler(log, d.quotas)
+		apiDocs.Add(usersGroup.BasePath(), "users", quotas.Endpoints()...)
+		quotas.RegisterRoutes(usersGroup, quotasHandler)
+
+		// Nutrition routes (protected)
+		goalsHandler := goals.NewHandler(cfg, log, d.goals)
+		nutritionGroup := v1.Group("/nutrition")
+		nutritionGroup.Use(middleware.RequireAuth(cfg, apiKeys), middleware.RequireScope(middleware.ScopeReadNutrition))
+		apiDocs.Add(nutritionGroup.BasePath(), "nutrition", nutrition.Endpoints()...)
+		nutrition.RegisterRoutes(nutritionGroup, nutritionHandler, heavy)
+		apiDocs.Add(nutritionGroup.BasePath(), "nutrition", mealplans.Endpoints()...)
+		mealplans.RegisterRoutes(nutritionGroup, mealPlansHandler)
+		{
+			nutritionGroup.GET("/comments", commentsHandler.ListComments)
+			nutritionGroup.POST("/comments/:id/read", commentsHandler.MarkRead)
+
+			nutritionGroup.GET("/goals/suggestions", goalsHandler.ListSuggestions)
+			nutritionGroup.POST("/goals/suggestions/:id/accept", goalsHandler.Accept)
+			nutritionGroup.POST("/goals/suggestions/:id/dismiss", goalsHandler.Dismiss)
+		}
+
+		// Goals routes (protected)
+		goalsGroup := v1.Group("/goals")
+		goalsGroup.Use(middleware.RequireAuth(cfg))
+		{
+			goalsGroup.POST("", goalsHandler.CreateGoal)
+			goalsGroup.GET("", goalsHandler.ListGoals)
+			goalsGroup.PATCH("/:id", goalsHandler.UpdateGoal)
+		}
+
+		// Supplements routes (protected)
+		supplementsHandler := supplements.NewHandler(cfg, log, db, supplements.NewService(db, log))
+		supplementsGroup := v1.Group("/supplements")
+		supplementsGroup.Use(middleware.RequireAuth(cfg))
+		{
+			supplementsGroup.POST("", supplementsHandler.CreateSupplement)
+			supplementsGroup.GET("/today", supplementsHandler.GetToday)
+			supplementsGroup.GET("/:id", supplementsHandler.GetSupplement)
+			supplementsGroup.POST("/:id/take", supplementsHandler.Take)
+		}
+
+		// Activity routes (protected)
+		activityHandler := activity.NewHandler(cfg, log, activity.NewService(db, log))
+		activityGroup := v1.Group("/activity")
+		activityGroup.Use(middleware.RequireAuth(cfg))
+		{
+			activityGroup.POST("", activityHandler.CreateActivity)
+			activityGroup.GET("", activityHandler.ListActivities)
+		}
+
+		// Share link routes (protected); the shared pages are public and
+		// rate limited per IP
+		shareHandler := sharing.NewHandler(cfg, log, sharing.NewService(db, log, cfg))
+		shareGroup := v1.Group("/share")
+		shareGroup.Use(middleware.RequireAuth(cfg))
+		{
+			shareGroup.POST("", shareHandler.CreateShare)
+			shareGroup.GET("", shareHandler.ListShares)
+			shareGroup.DELETE("/:id", shareHandler.RevokeShare)
+		}
+		v1.GET("/public/share/:token", d.authRateLimiter.Limit("public_share"), shareHandler.GetPublic)
+
+		// Recipes routes (protected)
+		recipesHandler := recipes.NewHandler(cfg, log, recipes.NewService(db, log))
+		recipesGroup := v1.Group("/recipes")
+		recipesGroup.Use(middleware.RequireAuth(cfg))
+		{
+			recipesGroup.POST("", recipesHandler.CreateRecipe)
+			recipesGroup.GET("", recipesHandler.ListRecipes)
+			recipesGroup.GET("/:id", recipesHandler.GetRecipe)
+			recipesGroup.PUT("/:id", recipesHandler.UpdateRecipe)
+			recipesGroup.DELETE("/:id", recipesHandler.DeleteRecipe)
+		}
+
+		// Webhooks routes (protected)
+		webhooksHandler := webhooks.NewHandler(cfg, log, d.webhooks)
+		webhooksGroup := v1.Group("/webhooks")
+		webhooksGroup.Use(middleware.RequireAuth(cfg))
+		{
+			webhooksGroup.POST("", webhooksHandler.CreateWebhook)
+			webhooksGroup.GET("/:id/deliveries", webhooksHandler.ListDeliveries)
+			webhooksGroup.DELETE("/:id", webhooksHandler.DeleteWebhook)
+		}
+
+		// Notifications routes (protected)
+		notificationsHandler := notifications.NewHandler(cfg, log, db)
+		notificationsGroup := v1.Group("/notifications")
+		notificationsGroup.Use(middleware.RequireAuth(cfg))
+		{
+			notificationsGroup.GET("", notificationsHandler.GetNotifications)
+			notificationsGroup.POST("/:id/read", notificationsHandler.MarkAsRead)
+			notificationsGroup.GET("/unread-counts", notificationsHandler.GetUnreadCounts)
+			notificationsGroup.POST("/mark-all-read", notificationsHandler.MarkAllAsRead)
+			notificationsGroup.GET("/preferences", notificationsHandler.GetPreferences)
+			notificationsGroup.PUT("/preferences", notificationsHandler.UpdatePreferences)
+		}
+
+		// Email preferences live under /users; unsubscribe links are opened from
+		// emails without a session, so that route is public (token-authenticated)
+		usersGroup.GET("/notifications", notificationsHandler.GetEmailPreferences)
+		usersGroup.PUT("/notifications", notificationsHandler.UpdateEmailPreferences)
+		v1.GET("/notifications/unsubscribe", notificationsHandler.Unsubscribe)
+
+		// History backfill from spreadsheets; imports run in the background
+		historyImportHandler := measurements.NewImportHandler(cfg, log, db, d.historyImports)
+		usersGroup.POST("/weight/import", heavy, historyImportHandler.ImportWeight)
+		usersGroup.POST("/measurements/import", heavy, historyImportHandler.ImportMeasurements)
+		usersGroup.GET("/imports/:id", historyImportHandler.GetImport)
+
+		// Join requests from organization imports are accepted from the email
+		// link without a session (token-authenticated)
+		organizationImportHandler := organizations.NewImportHandler(cfg, log, d.organizationImports)
+		v1.GET("/organizations/join", organizationImportHandler.AcceptMembership)
+
+		// Public status page data (unauthenticated, cached and rate limited)
+		statusHandler := status.NewHandler(cfg, log, d.status)
+		v1.GET("/public/status", d.authRateLimiter.Limit("public_status"), statusHandler.GetStatus)
+
+		// Logs routes (public for frontend logging)
+		logsHandler := logs.NewHandler(cfg, log, db)
+		logsGroup := v1.Group("/logs")
+		apiDocs.Add(logsGroup.BasePath(), "logs", logs.Endpoints()...)
+		{
+			logsGroup.POST("", d.authRateLimiter.Limit("frontend_logs"), middleware.OptionalAuth(cfg), logsHandler.ReceiveLogs)
+			// Protected stats endpoint
+			logsGroup.GET("/stats", middleware.RequireAuth(cfg), middleware.RequireTokenVersion(tokenVersions), middleware.RequireRole("super_admin"), logsHandler.GetLogStats)
+		}
+
+		// Food tracker routes (protected)
+		foodTrackerHandler := foodtracker.NewHandler(cfg, log, db, d.foodPhotosS3, d.openRouter)
+		ftGroup := v1.Group("/food-tracker")
+		ftGroup.Use(middleware.RequireAuth(cfg))
+		{
+			// Food entries
+			ftGroup.GET("/entries", foodTrackerHandler.GetEntries)
+			ftGroup.POST("/entries", foodTrackerHandler.CreateEntry)
+			ftGroup.PUT("/entries/:id", foodTrackerHandler.UpdateEntry)
+			ftGroup.POST("/entries/:id/confirm", foodTrackerHandler.ConfirmEntry)
+			ftGroup.DELETE("/entries/:id", foodTrackerHandler.DeleteEntry)
+
+			// AI food recognition
+			ftGroup.POST("/recognize", foodTrackerHandler.RecognizeFood)
+
+			// Food search
+			ftGroup.GET("/search", foodTrackerHandler.SearchFoods)
+			ftGroup.GET("/barcode/:code", foodTrackerHandler.LookupBarcode)
+			ftGroup.GET("/recent", foodTrackerHandler.GetRecentFoods)
+			ftGroup.GET("/favorites", foodTrackerHandler.GetFavoriteFoods)
+			ftGroup.POST("/favorites/:foodId", foodTrackerHandler.AddToFavorites)
+			ftGroup.DELETE("/favorites/:foodId", foodTrackerHandler.RemoveFromFavorites)
+
+			// User foods
+			ftGroup.POST("/user-foods", foodTrackerHandler.CreateUserFood)
+			ftGroup.POST("/user-foods/clone", foodTrackerHandler.CloneUserFood)
+			ftGroup.GET("/user-foods", foodTrackerHandler.GetUserFoods)
+			ftGroup.PUT("/user-foods/:id", foodTrackerHandler.UpdateUserFood)
+			ftGroup.DELETE("/user-foods/:id", foodTrackerHandler.DeleteUserFood)
+
+			// Water tracking
+			ftGroup.GET("/water", foodTrackerHandler.GetWaterIntake)
+			ftGroup.POST("/water", foodTrackerHandler.AddWater)
+
+			// Recommendations
+			ftGroup.GET("/recommendations", foodTrackerHandler.GetRecommendations)
+			ftGroup.GET("/recommendations/:id", foodTrackerHandler.GetRecommendationDetail)
+			ftGroup.PUT("/recommendations/preferences", foodTrackerHandler.UpdatePreferences)
+			ftGroup.POST("/recommendations/custom", foodTrackerHandler.CreateCustomRecommendation)
+		}
+
+		// Food catalogue (protected)
+		foodsGroup := v1.Group("/foods")
+		foodsGroup.Use(middleware.RequireAuth(cfg))
+		{
+			foodsGroup.GET("/barcode/:ean", foodTrackerHandler.GetFoodByBarcode)
+		}
+
+		// Nutrition calculator routes (protected)
+		nutritionCalcHandler := nutritioncalc.NewHandler(cfg, log, db)
+		ncGroup := v1.Group("/nutrition-calc")
+		ncGroup.Use(middleware.RequireAuth(cfg))
+		{
+			ncGroup.GET("/targets", nutritionCalcHandler.GetTargets)
+			ncGroup.GET("/history", nutritionCalcHandler.GetHistory)
+			ncGroup.POST("/recalculate", nutritionCalcHandler.Recalculate)
+		}
+
+		// Measurements routes (protected)
+		measurementsService := measurements.NewService(db, log)
+		measurementsHandler := measurements.NewHandler(cfg, log, db, measurementsService)
+		measurementsGroup := v1.Group("/measurements")
+		measurementsGroup.Use(middleware.RequireAuth(cfg, apiKeys), middleware.RequireScope(middleware.ScopeReadMeasurements))
+		{
+			measurementsGroup.GET("", measurementsHandler.ListMeasurements)
+			measurementsGroup.GET("/weight-trend", heavy, measurementsHandler.GetWeightTrend)
+		}
+
+		// Resumable uploads routes (protected)
+		if d.uploads != nil {
+			uploadsHandler := uploads.NewHandler(cfg, log, d.uploads)
+			uploadsGroup := v1.Group("/uploads")
+			uploadsGroup.Use(middleware.RequireAuth(cfg))
+			{
+				uploadsGroup.POST("", uploadsHandler.Create)
+				uploadsGroup.GET("/:id", uploadsHandler.Get)
+				uploadsGroup.PATCH("/:id", uploadsHandler.Patch)
+			}
+		}
+
+		// Progress photos routes (protected)
+		if d.photos != nil {
+			photosHandler := photos.NewHandler(cfg, log, d.photos, chunkedUploads)
+			photosGroup := v1.Group("/photos")
+			photosGroup.Use(middleware.RequireAuth(cfg))
+			{
+				photosGroup.POST("", photosHandler.Upload)
+				photosGroup.GET("", photosHandler.List)
+				photosGroup.POST("/analyze", features.Require(features.AIAnalysis), photosHandler.Analyze)
+				photosGroup.GET("/estimates", features.Require(features.AIAnalysis), photosHandler.ListEstimates)
+				// Chunked uploads for photos sent over unreliable connections
+				if d.uploads != nil {
+					chunksHandler := uploads.NewHandler(cfg, log, d.uploads)
+					photosGroup.POST("/uploads", photosHandler.CreateUpload)
+					photosGroup.GET("/uploads/:id", chunksHandler.Progress)
+					photosGroup.PUT("/uploads/:id/chunks/:n", chunksHandler.PutChunk)
+					photosGroup.POST("/uploads/:id/complete", photosHandler.CompleteUpload)
+				}
+				photosGroup.GET("/:id", photosHandler.Get)
+				photosGroup.DELETE("/:id", photosHandler.Delete)
+			}
+		}
+
+		// Nutrition recommendation routes (protected)
+		var bodyFatSource recommendations.BodyFatSource
+		if d.photos != nil {
+			bodyFatSource = d.photos
+		}
+		dayFlags := nutrition.NewFlagService(db, log, cfg.NutritionExcludedDayFlags, d.cache)
+		recommendationsHandler := recommendations.NewHandler(cfg, log, db, nutritionCalcSvc, measurementsService, bodyFatSource, dayFlags)
+		recommendationsGroup := v1.Group("/recommendations")
+		recommendationsGroup.Use(middleware.RequireAuth(cfg), features.Require(features.Recommendations))
+		{
+			recommendationsGroup.GET("/nutrition", recommendationsHandler.GetNutrition)
+		}
+
+		// Device sync routes (protected)
+		syncHandler := devicesync.NewHandler(log, devicesync.NewService(db, log, dayFlags))
+		syncGroup := v1.Group("/sync")
+		syncGroup.Use(middleware.RequireAuth(cfg))
+		{
+			syncGroup.GET("", syncHandler.Sync)
+		}
+
+		// Dashboard routes (protected)
+		notificationsSvc := notifications.NewService(db, log)
+		dashboardHandler := dashboard.NewHandler(cfg, log, db, d.storageRegions, notificationsSvc, nutritionCalcSvc, d.events, dayFlags)
+		dashGroup := v1.Group("/dashboard")
+		dashGroup.Use(middleware.RequireAuth(cfg))
+		{
+			dashGroup.GET("/daily/:date", dashboardHandler.GetDailyMetrics)
+			dashGroup.POST("/daily", dashboardHandler.SaveMetric)
+			dashGroup.GET("/week", dashboardHandler.GetWeekMetrics)
+			dashGroup.GET("/progress", dashboardHandler.GetProgress)
+			dashGroup.GET("/weekly-plan", dashboardHandler.GetWeeklyPlan)
+			dashGroup.POST("/weekly-plan", dashboardHandler.CreateWeeklyPlan)
+			dashGroup.GET("/tasks", dashboardHandler.GetTasks)
+			dashGroup.POST("/tasks", dashboardHandler.CreateTask)
+			dashGroup.PUT("/tasks/:id", dashboardHandler.UpdateTaskStatus)
+			dashGroup.POST("/tasks/:id/complete", dashboardHandler.CompleteTaskForDate)
+			dashGroup.GET("/weekly-reports/:reportId/feedback", dashboardHandler.GetReportFeedback)
+			dashGroup.POST("/weekly-report", dashboardHandler.SubmitWeeklyReport)
+			dashGroup.POST("/photo-upload", dashboardHandler.UploadPhoto)
+		}
+
+		// Chat routes (protected, both roles)
+		convGroup := v1.Group("/conversations")
+		convGroup.Use(middleware.RequireAuth(cfg))
+		{
+			convGroup.GET("", chatHandler.GetConversations)
+			convGroup.GET("/unread", chatHandler.GetUnreadCount)
+			convGroup.GET("/:id/messages", chatHandler.GetMessages)
+			convGroup.POST("/:id/messages", chatHandler.SendMessage)
+			convGroup.POST("/:id/upload", chatHandler.UploadAttachment)
+			convGroup.POST("/:id/read", chatHandler.MarkAsRead)
+			convGroup.POST("/:id/messages/:msgId/food-entry", chatHandler.CreateFoodEntry)
+		}
+
+		// Curator routes (coordinator role only)
+		curatorHandler := curator.NewHandler(cfg, log, db, notificationsSvc)
+		broadcastHandler := broadcast.NewHandler(cfg, log, d.broadcast)
+		curatorGroup := v1.Group("/curator")
+		curatorGroup.Use(middleware.RequireAuth(cfg))
+		curatorGroup.Use(middleware.RequireTokenVersion(tokenVersions))
+		curatorGroup.Use(middleware.RequireRole("coordinator"))
+		{
+			curatorGroup.GET("/analytics", curatorHandler.GetAnalytics)
+			curatorGroup.GET("/analytics/history", curatorHandler.GetAnalyticsHistory)
+			curatorGroup.GET("/analytics/benchmark", curatorHandler.GetBenchmark)
+			curatorGroup.GET("/attention", curatorHandler.GetAttentionList)
+			curatorGroup.GET("/clients", curatorHandler.GetClients)
+			curatorGroup.GET("/clients/:id", curatorHandler.GetClientDetail)
+			curatorGroup.PUT("/clients/:id/target-weight", curatorHandler.SetTargetWeight)
+			curatorGroup.PUT("/clients/:id/water-goal", curatorHandler.SetWaterGoal)
+			curatorGroup.POST("/clients/:id/weekly-plan", curatorHandler.CreateWeeklyPlan)
+			curatorGroup.PUT("/clients/:id/weekly-plan/:planId", curatorHandler.UpdateWeeklyPlan)
+			curatorGroup.DELETE("/clients/:id/weekly-plan/:planId", curatorHandler.DeleteWeeklyPlan)
+			curatorGroup.GET("/clients/:id/weekly-plans", curatorHandler.GetWeeklyPlans)
+			curatorGroup.POST("/clients/:id/tasks", curatorHandler.CreateTask)
+			curatorGroup.PUT("/clients/:id/tasks/:taskId", curatorHandler.UpdateTask)
+			curatorGroup.DELETE("/clients/:id/tasks/:taskId", curatorHandler.DeleteTask)
+			curatorGroup.GET("/clients/:id/tasks", curatorHandler.GetTasks)
+			curatorGroup.PUT("/clients/:id/weekly-reports/:reportId/feedback", curatorHandler.SubmitFeedback)
+			curatorGroup.GET("/clients/:id/weekly-reports", curatorHandler.GetWeeklyReports)
+			curatorGroup.GET("/clients/:id/targets/history", nutritionCalcHandler.GetClientHistory)
+			curatorGroup.GET("/clients/:id/nutrition", curatorHandler.GetClientNutrition)
+			curatorGroup.POST("/clients/:id/comments", commentsHandler.CreateComment)
+			mealplans.RegisterCuratorRoutes(curatorGroup, mealPlansHandler)
+			curatorGroup.POST("/invites", curatorHandler.CreateInvite)
+			curatorGroup.POST("/broadcast", broadcastHandler.CreateBroadcast)
+			curatorGroup.GET("/broadcasts", broadcastHandler.ListBroadcasts)
+		}
+
+		// Client side of curator invites (protected)
+		curatorLinkGroup := v1.Group("/users")
+		curatorLinkGroup.Use(middleware.RequireAuth(cfg))
+		{
+			curatorLinkGroup.POST("/link-curator", curatorHandler.LinkCurator)
+			curatorLinkGroup.DELETE("/link-curator", curatorHandler.UnlinkCurator)
+		}
+
+		// Admin routes (super_admin role only)
+		adminHandler := admin.NewHandler(cfg, log, db, d.email, d.cache, d.securityEvents)
+		auditHandler := audit.NewHandler(cfg, log, d.audit)
+		organizationsHandler := organizations.NewHandler(cfg, log, d.organizations)
+		maintenanceHandler := maintenance.NewHandler(cfg, log, d.maintenance)
+		adminGroup := v1.Group("/admin")
+		adminGroup.Use(middleware.RequireAuth(cfg))
+		adminGroup.Use(middleware.RequireTokenVersion(tokenVersions))
+		adminGroup.Use(middleware.RequireRole("super_admin"))
+		{
+			adminGroup.GET("/stats", adminHandler.GetStats)
+			adminGroup.GET("/security-events", adminHandler.GetSecurityEvents)
+			adminGroup.GET("/users", adminHandler.GetUsers)
+			adminGroup.GET("/curators", adminHandler.GetCurators)
+			adminGroup.PUT("/users/:id/role", adminHandler.ChangeRole)
+			adminGroup.POST("/users/:id/role", adminHandler.ChangeRole)
+			quotas.RegisterAdminRoutes(adminGroup, quotasHandler)
+			features.RegisterAdminRoutes(adminGroup, features.NewHandler(log, d.features))
+			adminGroup.POST("/assignments", adminHandler.AssignCurator)
+			adminGroup.GET("/conversations", adminHandler.GetConversations)
+			adminGroup.GET("/conversations/:id/messages", adminHandler.GetConversationMessages)
+			adminGroup.GET("/organizations", organizationsHandler.ListOrganizations)
+			adminGroup.POST("/organizations", organizationsHandler.CreateOrganization)
+			adminGroup.PUT("/organizations/:id/storage-region", organizationsHandler.ChangeStorageRegion)
+			adminGroup.POST("/organizations/:id/import", heavy, organizationImportHandler.ImportMembers)
+			adminGroup.GET("/maintenance", maintenanceHandler.List)
+			adminGroup.POST("/maintenance", maintenanceHandler.SetMode)
+			adminGroup.GET("/audit", auditHandler.List)
+			adminGroup.GET("/email-preview/:template", adminHandler.PreviewEmail)
+			adminGroup.POST("/maintenance/schedule", maintenanceHandler.Schedule)
+			adminGroup.DELETE("/maintenance/:id", maintenanceHandler.Cancel)
+		}
+	}
+
+	// Content management routes (coordinator + super_admin)
+	contentHandler := content.NewHandler(cfg, log, d.content)
+
+	// Public content routes (no auth required)
+	publicContentGroup := v1.Group("/public/content")
+	{
+		publicContentGroup.GET("", contentHandler.GetPublicFeed)
+		publicContentGroup.GET("/:id", contentHandler.GetPublicArticle)
+	}
+
+	contentManageGroup := v1.Group("/content/articles")
+	contentManageGroup.Use(middleware.RequireAuth(cfg))
+	contentManageGroup.Use(middleware.RequireTokenVersion(tokenVersions))
+	contentManageGroup.Use(middleware.RequireRole("coordinator", "super_admin"))
+	{
+		contentManageGroup.POST("", contentHandler.CreateArticle)
+		contentManageGroup.GET("", contentHandler.ListArticles)
+		contentManageGroup.GET("/:id", contentHandler.GetArticle)
+		contentManageGroup.PUT("/:id", contentHandler.UpdateArticle)
+		contentManageGroup.DELETE("/:id", contentHandler.DeleteArticle)
+		contentManageGroup.POST("/:id/publish", contentHandler.PublishArticle)
+		contentManageGroup.POST("/:id/schedule", contentHandler.ScheduleArticle)
+		contentManageGroup.POST("/:id/unpublish", contentHandler.UnpublishArticle)
+		contentManageGroup.POST("/:id/media", contentHandler.UploadMedia)
+		contentManageGroup.POST("/upload", contentHandler.UploadMarkdownFile)
+		contentManageGroup.POST("/cover", contentHandler.UploadCoverImage)
+	}
+
+	// Client content feed
+	contentFeedGroup := v1.Group("/content/feed")
+	contentFeedGroup.Use(middleware.RequireAuth(cfg))
+	{
+		contentFeedGroup.GET("", contentHandler.GetFeed)
+		contentFeedGroup.GET("/:id", contentHandler.GetFeedArticle)
+	}
+
+	// WebSocket endpoint (JWT checked in handler via query param)
+	router.GET("/ws", chatHandler.HandleWebSocket)
+
+	return router
+}
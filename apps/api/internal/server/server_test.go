@@ -0,0 +1,98 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/email"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// publicRoutes are the routes that answer without a token. Every other route
+// must reject anonymous requests with 401: adding a route here is a
+// deliberate decision, forgetting RequireAuth is a test failure.
+var publicRoutes = map[string]string{
+	"GET /health":  "load balancer health check",
+	"GET /metrics": "Prometheus scrape, event counters only",
+
+	"GET /api/v1/openapi.json": "API description",
+	"GET /api/v1/docs":         "API docs UI, not served in production",
+
+	"POST /api/v1/auth/register":            "registration",
+	"POST /api/v1/auth/login":               "login",
+	"POST /api/v1/auth/refresh":             "refresh cookie",
+	"POST /api/v1/auth/logout":              "refresh cookie",
+	"POST /api/v1/auth/reactivate":          "credentials in the body",
+	"POST /api/v1/auth/forgot-password":     "password reset",
+	"POST /api/v1/auth/reset-password":      "reset token",
+	"GET /api/v1/auth/validate-reset-token": "reset token",
+	"GET /api/v1/notifications/unsubscribe": "unsubscribe token from emails",
+	"GET /api/v1/organizations/join":        "join token from emails",
+	"GET /api/v1/public/status":             "public status page",
+	"POST /api/v1/logs":                     "frontend logs, auth optional",
+	"GET /api/v1/public/content":            "public articles",
+	"GET /api/v1/public/content/:id":        "public articles",
+	"GET /api/v1/public/share/:token":       "share token",
+	"GET /api/v1/users/confirm-email":       "email change token from emails",
+}
+
+// pathParam matches the :name parameters of a Gin route
+var pathParam = regexp.MustCompile(`:[A-Za-z]+`)
+
+// newTestRouter builds the production router over a database that expects
+// no queries: a request that gets past authentication fails loudly
+func newTestRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	t.Setenv("ENV", "test")
+	t.Setenv("DATABASE_URL", "postgres://test@localhost/test")
+	t.Setenv("PHOTOS_STORAGE_DIR", t.TempDir())
+	t.Setenv("UPLOADS_STORAGE_DIR", t.TempDir())
+	t.Setenv("EXPORTS_STORAGE_DIR", t.TempDir())
+	cfg, err := config.Load()
+	require.NoError(t, err)
+
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	log := logger.New()
+	emailService, err := email.NewServiceWithSender(email.NewMemorySender(), log)
+	require.NoError(t, err)
+
+	return NewRouter(cfg, NewDeps(cfg, log, &database.DB{DB: mockDB}, emailService))
+}
+
+func TestRoutesRequireAuth(t *testing.T) {
+	router := newTestRouter(t)
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, public := publicRoutes[key]; public {
+			continue
+		}
+
+		t.Run(key, func(t *testing.T) {
+			path := pathParam.ReplaceAllString(route.Path, "0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(route.Method, path, nil))
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code,
+				"%s answers without a token: add middleware.RequireAuth, or list it in publicRoutes if it is public on purpose", key)
+		})
+	}
+
+	// A stale entry would let a new route reuse it unnoticed
+	for key := range publicRoutes {
+		assert.True(t, registered[key], "publicRoutes lists %s, which is not registered", key)
+	}
+}
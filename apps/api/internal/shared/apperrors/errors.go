@@ -1,6 +1,10 @@
 package apperrors
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 var (
 	ErrNotFound           = errors.New("not found")
@@ -12,4 +16,73 @@ var (
 	ErrCodeExpired        = errors.New("code expired")
 	ErrTooManyAttempts    = errors.New("too many attempts")
 	ErrRateLimited        = errors.New("rate limit exceeded")
+	ErrConflict           = errors.New("conflict")
 )
+
+// RateLimitError is ErrRateLimited with the time after which a retry may
+// succeed. errors.Is(err, ErrRateLimited) holds for it.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return ErrRateLimited.Error() + ", retry after " + e.RetryAfter.String()
+}
+
+// Is reports RateLimitError as ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// ConflictError is ErrConflict for a value that violates a unique
+// constraint. Field is the request field the constraint covers, empty when
+// the constraint has no user-facing field. errors.Is(err, ErrConflict) holds
+// for it.
+type ConflictError struct {
+	Constraint string
+	Field      string
+}
+
+func (e *ConflictError) Error() string {
+	return ErrConflict.Error() + " on " + e.Constraint
+}
+
+// Is reports ConflictError as ErrConflict
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// VersionConflictError is ErrConflict for an update of a record version
+// that is no longer current: another client changed the record first.
+// Current is the record as it is now. errors.Is(err, ErrConflict) holds
+// for it.
+type VersionConflictError struct {
+	Current any
+}
+
+func (e *VersionConflictError) Error() string {
+	return ErrConflict.Error() + ": record version is not current"
+}
+
+// Is reports VersionConflictError as ErrConflict
+func (e *VersionConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// QuotaExceededError is ErrForbidden for a write that would take the user
+// over one of their quotas. Used is the usage before the write, Limit the
+// user's limit of Resource. errors.Is(err, ErrForbidden) holds for it.
+type QuotaExceededError struct {
+	Resource string
+	Used     int64
+	Limit    int64
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s: %s quota exceeded (%d of %d used)", ErrForbidden, e.Resource, e.Used, e.Limit)
+}
+
+// Is reports QuotaExceededError as ErrForbidden
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrForbidden
+}
@@ -0,0 +1,98 @@
+// Package cache keeps computed values for a while so they are not
+// recomputed on every request. Values are bytes; GetJSON and SetJSON store
+// structs. A nil Cache is a valid, empty cache: the helpers treat it as
+// always missing and ignore writes, so callers need not check whether
+// caching is configured.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Cache drivers selectable via CACHE_DRIVER; empty disables caching
+const (
+	DriverMemory = "memory"
+	DriverRedis  = "redis"
+)
+
+// DefaultMemoryEntries is how many values the memory driver keeps
+const DefaultMemoryEntries = 10000
+
+// Cache stores values under string keys until their TTL runs out
+type Cache interface {
+	// Get returns the value stored under key; ok is false when there is
+	// none or it has expired
+	Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+	// Set stores value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the keys; missing keys are not an error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Config selects and configures the cache driver
+type Config struct {
+	Driver string
+	// MemoryEntries bounds the memory driver, DefaultMemoryEntries when 0
+	MemoryEntries int
+	// RedisURL is the redis://[:password@]host:port[/db] address of the
+	// redis driver
+	RedisURL string
+}
+
+// New creates the cache selected by cfg.Driver, or returns nil when
+// caching is disabled
+func New(cfg Config) (Cache, error) {
+	switch cfg.Driver {
+	case "":
+		return nil, nil
+	case DriverMemory:
+		return NewMemory(cfg.MemoryEntries), nil
+	case DriverRedis:
+		r, err := NewRedis(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver: %s", cfg.Driver)
+	}
+}
+
+// GetJSON decodes the value under key into dest and reports whether there
+// was one. A nil c always misses.
+func GetJSON(ctx context.Context, c Cache, key string, dest any) (bool, error) {
+	if c == nil {
+		return false, nil
+	}
+	value, ok, err := c.Get(ctx, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(value, dest); err != nil {
+		return false, fmt.Errorf("failed to decode cached %s: %w", key, err)
+	}
+	return true, nil
+}
+
+// SetJSON stores value under key, JSON encoded, for ttl. A nil c ignores it.
+func SetJSON(ctx context.Context, c Cache, key string, value any, ttl time.Duration) error {
+	if c == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s for the cache: %w", key, err)
+	}
+	return c.Set(ctx, key, data, ttl)
+}
+
+// Delete removes keys from c. A nil c has nothing to remove.
+func Delete(ctx context.Context, c Cache, keys ...string) error {
+	if c == nil || len(keys) == 0 {
+		return nil
+	}
+	return c.Delete(ctx, keys...)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	c, err := New(Config{})
+	require.NoError(t, err)
+	assert.Nil(t, c)
+
+	c, err = New(Config{Driver: DriverMemory})
+	require.NoError(t, err)
+	assert.IsType(t, &Memory{}, c)
+
+	c, err = New(Config{Driver: DriverRedis, RedisURL: "redis://:secret@cache:6380/2"})
+	require.NoError(t, err)
+	r := c.(*Redis)
+	assert.Equal(t, "cache:6380", r.addr)
+	assert.Equal(t, "secret", r.password)
+	assert.Equal(t, 2, r.db)
+
+	c, err = New(Config{Driver: DriverRedis, RedisURL: "http://cache"})
+	assert.Error(t, err)
+	assert.Nil(t, c)
+	_, err = New(Config{Driver: "memcached"})
+	assert.Error(t, err)
+}
+
+func TestJSONHelpers(t *testing.T) {
+	ctx := context.Background()
+	type summary struct {
+		Calories float64 `json:"calories"`
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		c := NewMemory(0)
+		require.NoError(t, SetJSON(ctx, c, "k", summary{Calories: 1800}, time.Hour))
+
+		var got summary
+		ok, err := GetJSON(ctx, c, "k", &got)
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, summary{Calories: 1800}, got)
+
+		require.NoError(t, Delete(ctx, c, "k"))
+		ok, err = GetJSON(ctx, c, "k", &got)
+		require.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	t.Run("nil cache is a no-op", func(t *testing.T) {
+		var c Cache
+		require.NoError(t, SetJSON(ctx, c, "k", summary{Calories: 1800}, time.Hour))
+		var got summary
+		ok, err := GetJSON(ctx, c, "k", &got)
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.NoError(t, Delete(ctx, c, "k"))
+	})
+}
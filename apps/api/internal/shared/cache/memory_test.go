@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemory_TTL(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	m := NewMemory(10)
+	m.now = func() time.Time { return now }
+
+	require.NoError(t, m.Set(ctx, "k", []byte("v"), time.Minute))
+
+	now = now.Add(59 * time.Second)
+	value, ok, err := m.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("v"), value)
+
+	now = now.Add(time.Second)
+	_, ok, err = m.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, ok, "expired at exactly the TTL")
+	assert.Zero(t, m.Len(), "expired values are dropped when read")
+}
+
+func TestMemory_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(2)
+
+	require.NoError(t, m.Set(ctx, "a", []byte("1"), time.Hour))
+	require.NoError(t, m.Set(ctx, "b", []byte("2"), time.Hour))
+	// Reading a makes b the least recently used
+	_, ok, _ := m.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, m.Set(ctx, "c", []byte("3"), time.Hour))
+
+	_, ok, _ = m.Get(ctx, "b")
+	assert.False(t, ok)
+	_, ok, _ = m.Get(ctx, "a")
+	assert.True(t, ok)
+	_, ok, _ = m.Get(ctx, "c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, m.Len())
+
+	// Overwriting an existing key evicts nothing
+	require.NoError(t, m.Set(ctx, "a", []byte("4"), time.Hour))
+	assert.Equal(t, 2, m.Len())
+	value, _, _ := m.Get(ctx, "a")
+	assert.Equal(t, []byte("4"), value)
+}
+
+func TestMemory_Delete(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(0)
+
+	require.NoError(t, m.Set(ctx, "a", []byte("1"), time.Hour))
+	require.NoError(t, m.Set(ctx, "b", []byte("2"), time.Hour))
+	require.NoError(t, m.Delete(ctx, "a", "missing"))
+
+	_, ok, _ := m.Get(ctx, "a")
+	assert.False(t, ok)
+	_, ok, _ = m.Get(ctx, "b")
+	assert.True(t, ok)
+}
+
+func TestMemory_CopiesValues(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory(0)
+	value := []byte("abc")
+
+	require.NoError(t, m.Set(ctx, "k", value, time.Hour))
+	value[0] = 'x'
+	got, _, _ := m.Get(ctx, "k")
+	got[1] = 'y'
+
+	again, _, _ := m.Get(ctx, "k")
+	assert.Equal(t, []byte("abc"), again)
+}
@@ -0,0 +1,139 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis serves GET, SET and DEL from a map and records the commands
+type fakeRedis struct {
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{values: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, "redis://" + ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		reply, err := readReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range reply.([]any) {
+			args = append(args, string(arg.([]byte)))
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, strings.Join(args, " "))
+		var out string
+		switch args[0] {
+		case "GET":
+			if v, ok := f.values[args[1]]; ok {
+				out = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			} else {
+				out = "$-1\r\n"
+			}
+		case "SET":
+			f.values[args[1]] = args[2]
+			out = "+OK\r\n"
+		case "DEL":
+			for _, key := range args[1:] {
+				delete(f.values, key)
+			}
+			out = ":1\r\n"
+		default:
+			out = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := conn.Write([]byte(out)); err != nil {
+			return
+		}
+	}
+}
+
+func TestRedis(t *testing.T) {
+	ctx := context.Background()
+	server, url := startFakeRedis(t)
+	r, err := NewRedis(url)
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, ok, err := r.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, r.Set(ctx, "k", []byte("a b\r\nc"), 90*time.Second))
+	value, ok, err := r.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("a b\r\nc"), value)
+
+	require.NoError(t, r.Delete(ctx, "k", "other"))
+	_, ok, err = r.Get(ctx, "k")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	assert.Equal(t, "SET k a b\r\nc PX 90000", server.commands[1])
+	assert.Equal(t, "DEL k other", server.commands[3])
+}
+
+func TestRedis_ErrorReplyKeepsConnection(t *testing.T) {
+	ctx := context.Background()
+	_, url := startFakeRedis(t)
+	r, err := NewRedis(url)
+	require.NoError(t, err)
+	defer r.Close()
+
+	_, err = r.do(ctx, "PING")
+	var replyErr redisError
+	require.ErrorAs(t, err, &replyErr)
+	assert.Len(t, r.idle, 1)
+
+	_, _, err = r.Get(ctx, "k")
+	assert.NoError(t, err)
+}
+
+func TestRedis_Unreachable(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	r, err := NewRedis("redis://" + addr)
+	require.NoError(t, err)
+	_, _, err = r.Get(context.Background(), "k")
+	assert.ErrorContains(t, err, "redis unreachable")
+}
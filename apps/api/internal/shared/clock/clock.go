@@ -0,0 +1,58 @@
+// Package clock maps instants to the calendar days of a user's timezone.
+// Entries, summaries and streaks are bucketed by the user's local day, so a
+// late dinner in Vladivostok stays on the day it was eaten.
+package clock
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultTimezone is used for users without a (valid) timezone
+const DefaultTimezone = "Europe/Moscow"
+
+// MaxUTCOffset is the largest offset of any timezone from UTC (Pacific/Kiritimati)
+const MaxUTCOffset = 14 * time.Hour
+
+// ErrInvalidTimezone is returned for names that are not IANA timezones
+var ErrInvalidTimezone = errors.New("timezone must be a valid IANA name")
+
+// ValidateTimezone checks that tz is an IANA timezone name such as
+// Asia/Vladivostok. "Local" and the empty string, which time.LoadLocation
+// accepts, are rejected: they do not name a zone.
+func ValidateTimezone(tz string) error {
+	if tz == "" || tz == "Local" {
+		return ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
+// Location loads tz, falling back to DefaultTimezone when it is empty or invalid
+func Location(tz string) *time.Location {
+	if ValidateTimezone(tz) == nil {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	loc, err := time.LoadLocation(DefaultTimezone)
+	if err != nil {
+		// No tzdata at all; Moscow has had no DST since 2014
+		return time.FixedZone(DefaultTimezone, 3*60*60)
+	}
+	return loc
+}
+
+// UserDay returns the calendar day t falls on in timezone userTZ, as
+// midnight UTC of that date: the form DATE columns are compared with.
+// An empty or invalid userTZ means DefaultTimezone.
+func UserDay(userTZ string, t time.Time) time.Time {
+	return Day(t.In(Location(userTZ)))
+}
+
+// Day returns the calendar date of t in its own location as midnight UTC
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,76 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func TestUserDay(t *testing.T) {
+	tests := []struct {
+		name string
+		tz   string
+		at   string // RFC 3339, UTC
+		want string
+	}{
+		{"late dinner in Vladivostok", "Asia/Vladivostok", "2026-10-16T12:30:00Z", "2026-10-16"},
+		{"after midnight in Vladivostok", "Asia/Vladivostok", "2026-10-16T14:30:00Z", "2026-10-17"},
+		{"Moscow", "Europe/Moscow", "2026-10-16T20:59:00Z", "2026-10-16"},
+		{"Moscow after midnight", "Europe/Moscow", "2026-10-16T21:00:00Z", "2026-10-17"},
+
+		// UTC+12 without DST: the day starts at 12:00 UTC the day before
+		{"Kamchatka before midnight", "Asia/Kamchatka", "2026-10-16T11:59:59Z", "2026-10-16"},
+		{"Kamchatka at midnight", "Asia/Kamchatka", "2026-10-16T12:00:00Z", "2026-10-17"},
+		// UTC+12 in winter, UTC+13 in (southern) summer
+		{"Auckland in winter", "Pacific/Auckland", "2026-07-01T11:59:00Z", "2026-07-01"},
+		{"Auckland in summer", "Pacific/Auckland", "2026-12-31T11:30:00Z", "2027-01-01"},
+		// UTC+14: a day ahead of UTC for most of the UTC day
+		{"Kiritimati", "Pacific/Kiritimati", "2026-10-16T10:00:00Z", "2026-10-17"},
+		// UTC-12-ish side: still the previous day
+		{"Pago Pago", "Pacific/Pago_Pago", "2026-10-16T10:00:00Z", "2026-10-15"},
+
+		// DST spring forward in New York: 2026-03-08 02:00 EST -> 03:00 EDT
+		{"before spring forward", "America/New_York", "2026-03-08T06:59:00Z", "2026-03-08"},
+		{"after spring forward", "America/New_York", "2026-03-08T07:00:00Z", "2026-03-08"},
+		{"end of the 23-hour day", "America/New_York", "2026-03-09T03:59:00Z", "2026-03-08"},
+		{"day after spring forward", "America/New_York", "2026-03-09T04:00:00Z", "2026-03-09"},
+		// DST fall back in Berlin: 2026-10-25 03:00 CEST -> 02:00 CET
+		{"end of the 25-hour day", "Europe/Berlin", "2026-10-25T22:59:00Z", "2026-10-25"},
+		{"day after fall back", "Europe/Berlin", "2026-10-25T23:00:00Z", "2026-10-26"},
+
+		{"empty falls back to Moscow", "", "2026-10-16T21:00:00Z", "2026-10-17"},
+		{"invalid falls back to Moscow", "Mars/Olympus_Mons", "2026-10-16T21:00:00Z", "2026-10-17"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.at)
+			assert.NoError(t, err)
+
+			got := UserDay(tt.tz, at)
+
+			assert.Equal(t, date(tt.want), got)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
+
+func TestValidateTimezone(t *testing.T) {
+	for _, tz := range []string{"Europe/Moscow", "Asia/Vladivostok", "Pacific/Kiritimati", "UTC"} {
+		assert.NoError(t, ValidateTimezone(tz), tz)
+	}
+	for _, tz := range []string{"", "Local", "Mars/Olympus_Mons", "+03:00", "../etc/passwd"} {
+		assert.ErrorIs(t, ValidateTimezone(tz), ErrInvalidTimezone, tz)
+	}
+}
+
+func TestDay(t *testing.T) {
+	loc := time.FixedZone("UTC+12", 12*60*60)
+	assert.Equal(t, date("2026-10-17"), Day(time.Date(2026, 10, 17, 0, 30, 0, 0, loc)))
+}
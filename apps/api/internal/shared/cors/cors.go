@@ -0,0 +1,105 @@
+// Package cors configures which browser origins may call the API.
+package cors
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// Headers every API route accepts and exposes; New appends route-specific ones
+var (
+	baseAllowHeaders  = []string{"Origin", "Content-Type", "Authorization", "Accept", "X-Requested-With", "If-None-Match", "If-Match"}
+	baseExposeHeaders = []string{"Content-Length", "Retry-After", "Location", "ETag"}
+)
+
+// Matcher decides whether an Origin header is allowed. Patterns are exact
+// origins (https://burcev.team) or wildcard-subdomain origins
+// (https://*.burcev.team), which match any subdomain but not the bare domain.
+// The scheme and port always have to match.
+type Matcher struct {
+	exact     map[string]bool
+	wildcards []wildcard
+	any       bool
+}
+
+type wildcard struct {
+	scheme string
+	suffix string // ".burcev.team" or ".burcev.team:8443"
+}
+
+// NewMatcher builds a matcher from configured origins. An empty list or "*"
+// allows every origin.
+func NewMatcher(origins []string) *Matcher {
+	m := &Matcher{exact: make(map[string]bool), any: len(origins) == 0}
+
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == "*" {
+			m.any = true
+			continue
+		}
+		scheme, host, ok := strings.Cut(origin, "://")
+		if ok && strings.HasPrefix(host, "*.") {
+			m.wildcards = append(m.wildcards, wildcard{scheme: scheme, suffix: host[1:]})
+			continue
+		}
+		m.exact[origin] = true
+	}
+
+	return m
+}
+
+// AllowsAll reports whether every origin is allowed
+func (m *Matcher) AllowsAll() bool {
+	return m.any
+}
+
+// Allowed reports whether the request origin matches a configured pattern
+func (m *Matcher) Allowed(origin string) bool {
+	if m.any {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	if m.exact[origin] {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, w := range m.wildcards {
+		// The wildcard stands for at least one non-empty label
+		if u.Scheme == w.scheme && len(u.Host) > len(w.suffix) && strings.HasSuffix(u.Host, w.suffix) &&
+			!strings.HasPrefix(u.Host, ".") && !strings.Contains(u.Host, "..") {
+			return true
+		}
+	}
+	return false
+}
+
+// New returns the CORS middleware for the configured origins. allowHeaders
+// and exposeHeaders are added to the headers every route uses.
+func New(origins []string, allowHeaders, exposeHeaders []string) gin.HandlerFunc {
+	cfg := cors.Config{
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     append(append([]string{}, baseAllowHeaders...), allowHeaders...),
+		ExposeHeaders:    append(append([]string{}, baseExposeHeaders...), exposeHeaders...),
+		AllowCredentials: false,
+		MaxAge:           12 * time.Hour,
+	}
+
+	matcher := NewMatcher(origins)
+	if matcher.AllowsAll() {
+		cfg.AllowAllOrigins = true
+	} else {
+		cfg.AllowOriginFunc = matcher.Allowed
+	}
+
+	return cors.New(cfg)
+}
@@ -0,0 +1,85 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatcherAllowed(t *testing.T) {
+	m := NewMatcher([]string{"https://burcev.team", "https://*.burcev.team", "http://localhost:3000", "https://*.staging.example.com:8443"})
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://burcev.team", true},
+		{"HTTPS://Burcev.Team", true},
+		{"https://dev.burcev.team", true},
+		{"https://a.b.burcev.team", true},
+		{"http://localhost:3000", true},
+		{"https://app.staging.example.com:8443", true},
+
+		{"http://dev.burcev.team", false},
+		{"https://dev.burcev.team:8443", false},
+		{"https://evilburcev.team", false},
+		{"https://burcev.team.evil.com", false},
+		{"https://.burcev.team", false},
+		{"http://localhost:3069", false},
+		{"https://app.staging.example.com", false},
+		{"null", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, m.Allowed(tt.origin), tt.origin)
+	}
+}
+
+func TestMatcherAllowsAll(t *testing.T) {
+	assert.True(t, NewMatcher(nil).AllowsAll())
+	assert.True(t, NewMatcher([]string{"https://burcev.team", "*"}).Allowed("https://other.example"))
+	assert.False(t, NewMatcher([]string{"https://burcev.team"}).AllowsAll())
+}
+
+func TestNew(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	preflight := func(origins []string, origin string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(New(origins, []string{"Upload-Offset"}, nil))
+		router.POST("/api/v1/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodOptions, "/api/v1/test", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("allows a matching wildcard origin", func(t *testing.T) {
+		w := preflight([]string{"https://*.burcev.team"}, "https://dev.burcev.team")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "https://dev.burcev.team", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, w.Header().Get("Access-Control-Allow-Headers"), "Upload-Offset")
+	})
+
+	t.Run("rejects other origins", func(t *testing.T) {
+		w := preflight([]string{"https://*.burcev.team"}, "https://evil.example")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("allows any origin when none are configured", func(t *testing.T) {
+		w := preflight(nil, "https://evil.example")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
@@ -0,0 +1,203 @@
+// Package csvimport reads user-supplied CSV exports (typically saved from
+// Excel or Google Sheets) whose columns are mapped to fields by the caller,
+// and carries per-row errors back to the client.
+package csvimport
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidFile is returned when the file or the mapping cannot be used at all.
+// Problems with individual rows are reported as RowError instead.
+var ErrInvalidFile = errors.New("неверный CSV-файл")
+
+// Record is a non-blank data row of the file
+type Record struct {
+	Line   int
+	Fields []string
+}
+
+// RowError describes why a row was rejected
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// Mapping maps a field name to a column. A column is referenced either by its
+// spreadsheet letter ("A", "C", "AA") or by its header name.
+type Mapping map[string]string
+
+// ParseMapping decodes a mapping such as {"date":"A","weight":"C"}
+func ParseMapping(raw string) (Mapping, error) {
+	var m Mapping
+	if err := json.Unmarshal([]byte(raw), &m); err != nil || len(m) == 0 {
+		return nil, fmt.Errorf("%w: неверное сопоставление колонок", ErrInvalidFile)
+	}
+	return m, nil
+}
+
+// Columns is a resolved mapping: field name to zero-based column index
+type Columns map[string]int
+
+// Get returns the trimmed value of field in record, or "" when the field is
+// not mapped or the row is short.
+func (c Columns) Get(record Record, field string) string {
+	i, ok := c[field]
+	if !ok || i >= len(record.Fields) {
+		return ""
+	}
+	return strings.TrimSpace(record.Fields[i])
+}
+
+// Resolve turns a mapping into column indexes. header is nil when the file
+// has no header row. A reference matching a header name (case-insensitive)
+// wins over a column letter, so a column titled "A" can still be addressed.
+// Fields outside allowed and missing required fields are rejected.
+func (m Mapping) Resolve(header []string, allowed []string, required ...string) (Columns, error) {
+	known := make(map[string]bool, len(allowed))
+	for _, f := range allowed {
+		known[f] = true
+	}
+
+	byName := make(map[string]int, len(header))
+	for i, name := range header {
+		byName[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	cols := make(Columns, len(m))
+	for field, ref := range m {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !known[field] {
+			return nil, fmt.Errorf("%w: неизвестное поле %q", ErrInvalidFile, field)
+		}
+		ref = strings.TrimSpace(ref)
+		if i, ok := byName[strings.ToLower(ref)]; ok && ref != "" {
+			cols[field] = i
+			continue
+		}
+		i, ok := columnIndex(ref)
+		if !ok {
+			return nil, fmt.Errorf("%w: колонка %q не найдена", ErrInvalidFile, ref)
+		}
+		cols[field] = i
+	}
+
+	for _, f := range required {
+		if _, ok := cols[f]; !ok {
+			return nil, fmt.Errorf("%w: не указана колонка для поля %s", ErrInvalidFile, f)
+		}
+	}
+
+	return cols, nil
+}
+
+// columnIndex converts a spreadsheet column letter to a zero-based index
+func columnIndex(ref string) (int, bool) {
+	if ref == "" || len(ref) > 3 {
+		return 0, false
+	}
+	n := 0
+	for _, r := range strings.ToUpper(ref) {
+		if r < 'A' || r > 'Z' {
+			return 0, false
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1, true
+}
+
+// Read reads the whole file. When hasHeader is set the first row is returned
+// separately as the header. Blank lines are skipped, and more than maxRows
+// data rows is an error. Both comma and semicolon (Excel in the Russian locale)
+// separated files are accepted.
+func Read(r io.Reader, hasHeader bool, maxRows int) (header []string, records []Record, err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	text := strings.TrimPrefix(string(data), "\ufeff")
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	reader.Comma = detectComma(text)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		if hasHeader && header == nil {
+			header = record
+			continue
+		}
+		if len(records) == maxRows {
+			return nil, nil, fmt.Errorf("%w: больше %d строк", ErrInvalidFile, maxRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		records = append(records, Record{Line: line, Fields: record})
+	}
+
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%w: нет ни одной строки с данными", ErrInvalidFile)
+	}
+
+	return header, records, nil
+}
+
+// detectComma picks ';' when the first line has at least as many semicolons
+// as commas (a comma may be a decimal separator there)
+func detectComma(text string) rune {
+	first, _, _ := strings.Cut(text, "\n")
+	if semicolons := strings.Count(first, ";"); semicolons > 0 && semicolons >= strings.Count(first, ",") {
+		return ';'
+	}
+	return ','
+}
+
+// Date formats accepted in date columns. Slashed and dotted dates are read
+// day first, as Russian spreadsheets write them.
+var dateLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"2.1.2006",
+	"02/01/2006",
+	"2/1/2006",
+	"2006-01-02 15:04:05",
+	"02.01.2006 15:04:05",
+	"02.01.06",
+}
+
+// ParseDate parses a spreadsheet date cell
+func ParseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("неверная дата %q", s)
+}
+
+// ParseNumber parses a decimal number written with either a dot or a comma
+func ParseNumber(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.ReplaceAll(strings.ReplaceAll(s, " ", ""), ",", "."), 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("неверное число %q", s)
+	}
+	return v, nil
+}
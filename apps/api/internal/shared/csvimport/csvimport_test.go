@@ -0,0 +1,132 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMappingResolve(t *testing.T) {
+	allowed := []string{"date", "weight"}
+
+	t.Run("resolves column letters", func(t *testing.T) {
+		cols, err := Mapping{"date": "A", "weight": "c"}.Resolve(nil, allowed, "date", "weight")
+
+		require.NoError(t, err)
+		assert.Equal(t, Columns{"date": 0, "weight": 2}, cols)
+	})
+
+	t.Run("resolves multi-letter columns", func(t *testing.T) {
+		cols, err := Mapping{"date": "Z", "weight": "AB"}.Resolve(nil, allowed)
+
+		require.NoError(t, err)
+		assert.Equal(t, Columns{"date": 25, "weight": 27}, cols)
+	})
+
+	t.Run("resolves header names case-insensitively", func(t *testing.T) {
+		header := []string{"Дата", "Comment", " Вес, кг "}
+
+		cols, err := Mapping{"date": "дата", "weight": "ВЕС, КГ"}.Resolve(header, allowed, "date", "weight")
+
+		require.NoError(t, err)
+		assert.Equal(t, Columns{"date": 0, "weight": 2}, cols)
+	})
+
+	t.Run("header name wins over a column letter", func(t *testing.T) {
+		header := []string{"date", "B", "A"}
+
+		cols, err := Mapping{"date": "date", "weight": "A"}.Resolve(header, allowed)
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, cols["weight"])
+	})
+
+	t.Run("rejects unusable mappings", func(t *testing.T) {
+		header := []string{"date", "weight"}
+
+		for name, tc := range map[string]struct {
+			mapping  Mapping
+			required []string
+		}{
+			"unknown field":    {Mapping{"date": "A", "steps": "B"}, nil},
+			"unknown column":   {Mapping{"date": "A", "weight": "Вес"}, nil},
+			"missing required": {Mapping{"date": "A"}, []string{"date", "weight"}},
+			"empty reference":  {Mapping{"date": ""}, nil},
+		} {
+			_, err := tc.mapping.Resolve(header, allowed, tc.required...)
+			assert.ErrorIs(t, err, ErrInvalidFile, name)
+		}
+	})
+}
+
+func TestParseMapping(t *testing.T) {
+	m, err := ParseMapping(`{"date":"A","weight":"C"}`)
+	require.NoError(t, err)
+	assert.Equal(t, Mapping{"date": "A", "weight": "C"}, m)
+
+	for _, raw := range []string{"", "{}", "[1]", `{"date":1}`} {
+		_, err := ParseMapping(raw)
+		assert.ErrorIs(t, err, ErrInvalidFile, raw)
+	}
+}
+
+func TestRead(t *testing.T) {
+	t.Run("splits header and skips blank lines", func(t *testing.T) {
+		header, records, err := Read(strings.NewReader("\ufeffДата,Вес\n01.02.2025,82.5\n\n02.02.2025,82.1\n"), true, 10)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Дата", "Вес"}, header)
+		assert.Equal(t, []Record{
+			{Line: 2, Fields: []string{"01.02.2025", "82.5"}},
+			{Line: 4, Fields: []string{"02.02.2025", "82.1"}},
+		}, records)
+	})
+
+	t.Run("detects semicolon separated files", func(t *testing.T) {
+		header, records, err := Read(strings.NewReader("01.02.2025;82,5\n"), false, 10)
+
+		require.NoError(t, err)
+		assert.Nil(t, header)
+		assert.Equal(t, []Record{{Line: 1, Fields: []string{"01.02.2025", "82,5"}}}, records)
+	})
+
+	t.Run("rejects unusable files", func(t *testing.T) {
+		for name, csv := range map[string]string{
+			"empty":         "",
+			"header only":   "date,weight\n",
+			"bad quoting":   "date,weight\n\"01.02.2025,82\n",
+			"too many rows": "date,weight\n" + strings.Repeat("01.02.2025,82\n", 3),
+		} {
+			_, _, err := Read(strings.NewReader(csv), true, 2)
+			assert.ErrorIs(t, err, ErrInvalidFile, name)
+		}
+	})
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2025-02-03", "03.02.2025", "3.2.2025", "03/02/2025", "03.02.25"} {
+		got, err := ParseDate(s)
+		require.NoError(t, err, s)
+		assert.True(t, want.Equal(got), s)
+	}
+
+	_, err := ParseDate("Feb 3")
+	assert.Error(t, err)
+}
+
+func TestParseNumber(t *testing.T) {
+	for s, want := range map[string]float64{"82.5": 82.5, "82,5": 82.5, "1 082": 1082, "80": 80} {
+		got, err := ParseNumber(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, want, got, s)
+	}
+
+	for _, s := range []string{"", "abc", "82kg", "NaN", "Inf"} {
+		_, err := ParseNumber(s)
+		assert.Error(t, err, s)
+	}
+}
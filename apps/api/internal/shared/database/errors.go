@@ -0,0 +1,27 @@
+package database
+
+import (
+	"errors"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/migrations"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// uniqueViolation is the SQLSTATE of a unique constraint violation
+const uniqueViolation = "23505"
+
+// ConstraintError converts a unique violation into an
+// *apperrors.ConflictError naming the field of the violated constraint, as
+// listed in migrations.ConstraintFields. Any other error, nil included, is
+// returned as is.
+func ConstraintError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != uniqueViolation {
+		return err
+	}
+	return &apperrors.ConflictError{
+		Constraint: pgErr.ConstraintName,
+		Field:      migrations.ConstraintFields[pgErr.ConstraintName],
+	}
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraintError(t *testing.T) {
+	t.Run("unique violation through the driver", func(t *testing.T) {
+		db, mock := newTxTestDB(t)
+		mock.ExpectExec("INSERT INTO users").
+			WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "users_email_key"})
+
+		_, err := db.ExecContext(context.Background(), "INSERT INTO users (email) VALUES ($1)", "anna@example.com")
+		err = ConstraintError(fmt.Errorf("register: %w", err))
+
+		assert.ErrorIs(t, err, apperrors.ErrConflict)
+		var conflict *apperrors.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Equal(t, "users_email_key", conflict.Constraint)
+		assert.Equal(t, "email", conflict.Field)
+	})
+
+	t.Run("unmapped constraint", func(t *testing.T) {
+		err := ConstraintError(&pgconn.PgError{Code: "23505", ConstraintName: "api_keys_key_hash_key"})
+
+		var conflict *apperrors.ConflictError
+		require.ErrorAs(t, err, &conflict)
+		assert.Empty(t, conflict.Field)
+	})
+
+	t.Run("other errors pass through", func(t *testing.T) {
+		fkErr := &pgconn.PgError{Code: "23503", ConstraintName: "food_entries_food_id_fkey"}
+		assert.Same(t, fkErr, ConstraintError(fkErr))
+
+		plain := errors.New("connection refused")
+		assert.Equal(t, plain, ConstraintError(plain))
+		assert.NoError(t, ConstraintError(nil))
+	})
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// DefaultSlowQueryThreshold is used when Instrument is given no threshold
+const DefaultSlowQueryThreshold = 500 * time.Millisecond
+
+// QueryObserver receives the sanitized query, duration and error of every
+// instrumented call, e.g. to feed a latency histogram
+type QueryObserver func(query string, duration time.Duration, err error)
+
+// instrumentation is the optional state behind QueryCtx, QueryRowCtx and
+// ExecCtx; without it they behave like their *Context counterparts
+type instrumentation struct {
+	log           *logger.Logger
+	slowThreshold time.Duration
+	observer      QueryObserver
+	now           func() time.Time
+}
+
+// Instrument makes QueryCtx, QueryRowCtx and ExecCtx time every call and log
+// it: failures and calls slower than slowThreshold (0 for the default) are
+// logged as such, the rest at debug level. observer may be nil.
+func (db *DB) Instrument(log *logger.Logger, slowThreshold time.Duration, observer QueryObserver) {
+	if slowThreshold <= 0 {
+		slowThreshold = DefaultSlowQueryThreshold
+	}
+	db.inst = &instrumentation{
+		log:           log,
+		slowThreshold: slowThreshold,
+		observer:      observer,
+		now:           time.Now,
+	}
+}
+
+// QueryCtx is QueryContext with instrumentation
+func (db *DB) QueryCtx(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := db.start()
+	rows, err := db.QueryContext(ctx, query, args...)
+	db.record(query, start, err)
+	return rows, err
+}
+
+// ExecCtx is ExecContext with instrumentation
+func (db *DB) ExecCtx(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := db.start()
+	result, err := db.ExecContext(ctx, query, args...)
+	db.record(query, start, err)
+	return result, err
+}
+
+// Row is a *sql.Row that records its query when scanned, since a row's
+// error is only known then
+type Row struct {
+	*sql.Row
+	db    *DB
+	query string
+	start time.Time
+}
+
+// QueryRowCtx is QueryRowContext with instrumentation. sql.ErrNoRows is not
+// recorded as a failure.
+func (db *DB) QueryRowCtx(ctx context.Context, query string, args ...interface{}) *Row {
+	start := db.start()
+	return &Row{
+		Row:   db.QueryRowContext(ctx, query, args...),
+		db:    db,
+		query: query,
+		start: start,
+	}
+}
+
+// Scan copies the row into dest and records the query
+func (r *Row) Scan(dest ...any) error {
+	err := r.Row.Scan(dest...)
+	recorded := err
+	if errors.Is(err, sql.ErrNoRows) {
+		recorded = nil
+	}
+	r.db.record(r.query, r.start, recorded)
+	return err
+}
+
+func (db *DB) start() time.Time {
+	if db.inst == nil {
+		return time.Time{}
+	}
+	return db.inst.now()
+}
+
+func (db *DB) record(query string, start time.Time, err error) {
+	if db.inst == nil {
+		return
+	}
+	duration := db.inst.now().Sub(start)
+	query = SanitizeQuery(query)
+
+	if db.inst.observer != nil {
+		db.inst.observer(query, duration, err)
+	}
+	if db.inst.log == nil {
+		return
+	}
+	if err == nil && duration >= db.inst.slowThreshold {
+		db.inst.log.Warn("Slow database query",
+			"query", query,
+			"duration_ms", duration.Milliseconds(),
+			"threshold_ms", db.inst.slowThreshold.Milliseconds(),
+		)
+		return
+	}
+	db.inst.log.LogDatabaseQuery(query, duration, err, nil)
+}
+
+var (
+	stringLiteral = regexp.MustCompile(`'(?:[^']|'')*'`)
+	whitespace    = regexp.MustCompile(`\s+`)
+)
+
+// SanitizeQuery prepares a query for logging: string literals are replaced
+// with '?' and whitespace is collapsed. Argument values are never part of
+// the query text, since queries use $n placeholders.
+func SanitizeQuery(query string) string {
+	query = stringLiteral.ReplaceAllString(query, "'?'")
+	return strings.TrimSpace(whitespace.ReplaceAllString(query, " "))
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// observed is one call seen by a QueryObserver
+type observed struct {
+	query    string
+	duration time.Duration
+	err      error
+}
+
+// newInstrumentedDB returns a sqlmock-backed DB whose clock advances by step
+// on every reading, so each call appears to take exactly step
+func newInstrumentedDB(t *testing.T, step time.Duration) (*DB, sqlmock.Sqlmock, *bytes.Buffer, *[]observed) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	var buf bytes.Buffer
+	var calls []observed
+	db := &DB{DB: mockDB}
+	db.Instrument(
+		logger.New(logger.WithOutput(&buf), logger.WithEncoding(logger.EncodingJSON), logger.WithLevel(logger.DebugLevel)),
+		100*time.Millisecond,
+		func(query string, d time.Duration, err error) {
+			calls = append(calls, observed{query, d, err})
+		},
+	)
+
+	clock := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	db.inst.now = func() time.Time {
+		clock = clock.Add(step)
+		return clock
+	}
+	return db, mock, &buf, &calls
+}
+
+func TestExecCtx_SlowQuery(t *testing.T) {
+	db, mock, buf, calls := newInstrumentedDB(t, 150*time.Millisecond)
+	mock.ExpectExec("UPDATE users").WithArgs("secret@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := db.ExecCtx(context.Background(), "UPDATE users\n\t\tSET name = 'x' WHERE email = $1", "secret@example.com")
+
+	require.NoError(t, err)
+	require.Len(t, *calls, 1)
+	assert.Equal(t, "UPDATE users SET name = '?' WHERE email = $1", (*calls)[0].query)
+	assert.Equal(t, 150*time.Millisecond, (*calls)[0].duration)
+	assert.Contains(t, buf.String(), "Slow database query")
+	assert.Contains(t, buf.String(), `"threshold_ms":100`)
+	assert.NotContains(t, buf.String(), "secret@example.com")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExecCtx_FastQuery(t *testing.T) {
+	db, mock, buf, calls := newInstrumentedDB(t, 10*time.Millisecond)
+	mock.ExpectExec("DELETE FROM sessions").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	_, err := db.ExecCtx(context.Background(), "DELETE FROM sessions")
+
+	require.NoError(t, err)
+	require.Len(t, *calls, 1)
+	assert.Contains(t, buf.String(), "Database query executed")
+	assert.NotContains(t, buf.String(), "Slow database query")
+}
+
+func TestQueryCtx_Failure(t *testing.T) {
+	db, mock, buf, calls := newInstrumentedDB(t, 150*time.Millisecond)
+	mock.ExpectQuery("SELECT id FROM users").WillReturnError(errors.New("connection reset"))
+
+	_, err := db.QueryCtx(context.Background(), "SELECT id FROM users")
+
+	require.Error(t, err)
+	require.Len(t, *calls, 1)
+	assert.Error(t, (*calls)[0].err)
+	// A failed query is reported as a failure even when it was slow
+	assert.Contains(t, buf.String(), "Database query failed")
+	assert.NotContains(t, buf.String(), "Slow database query")
+}
+
+func TestQueryRowCtx(t *testing.T) {
+	t.Run("records when scanned", func(t *testing.T) {
+		db, mock, _, calls := newInstrumentedDB(t, 10*time.Millisecond)
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		row := db.QueryRowCtx(context.Background(), "SELECT COUNT(*) FROM users")
+		assert.Empty(t, *calls)
+
+		var count int
+		require.NoError(t, row.Scan(&count))
+		assert.Equal(t, 2, count)
+		assert.Len(t, *calls, 1)
+	})
+
+	t.Run("no rows is not a failure", func(t *testing.T) {
+		db, mock, buf, calls := newInstrumentedDB(t, 10*time.Millisecond)
+		mock.ExpectQuery("SELECT id").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var id int
+		err := db.QueryRowCtx(context.Background(), "SELECT id FROM users WHERE email = $1", "a@b.c").Scan(&id)
+
+		assert.ErrorIs(t, err, sql.ErrNoRows)
+		require.Len(t, *calls, 1)
+		assert.NoError(t, (*calls)[0].err)
+		assert.NotContains(t, buf.String(), "Database query failed")
+	})
+}
+
+func TestCtxHelpers_WithoutInstrument(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+	db := &DB{DB: mockDB}
+
+	mock.ExpectExec("DELETE FROM sessions").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT 1").WillReturnRows(sqlmock.NewRows([]string{"one"}).AddRow(1))
+
+	_, err = db.ExecCtx(context.Background(), "DELETE FROM sessions")
+	require.NoError(t, err)
+	var one int
+	require.NoError(t, db.QueryRowCtx(context.Background(), "SELECT 1").Scan(&one))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSanitizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT id FROM users WHERE email = $1", "SELECT id FROM users WHERE email = $1"},
+		{"SELECT *\n\t\tFROM logs\n\t\tWHERE level IN ('error', 'fatal')", "SELECT * FROM logs WHERE level IN ('?', '?')"},
+		{"UPDATE notes SET text = 'it''s' WHERE id = $1", "UPDATE notes SET text = '?' WHERE id = $1"},
+		{"  DELETE FROM sessions  ", "DELETE FROM sessions"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, SanitizeQuery(tt.query))
+	}
+}
+
+func TestInstrument_DefaultThreshold(t *testing.T) {
+	db := &DB{}
+	db.Instrument(logger.Nop(), 0, nil)
+	assert.Equal(t, DefaultSlowQueryThreshold, db.inst.slowThreshold)
+}
@@ -2,6 +2,10 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io/fs"
 	"sort"
@@ -10,6 +14,10 @@ import (
 	"time"
 )
 
+// ErrChecksumMismatch is returned when an applied migration file was edited
+// after it ran. Applied migrations are immutable: write a new one instead.
+var ErrChecksumMismatch = errors.New("applied migration file was modified")
+
 // migrationLogger is the subset of logger methods used by Migrator.
 type migrationLogger interface {
 	Infow(msg string, keysAndValues ...any)
@@ -19,9 +27,27 @@ type migrationLogger interface {
 
 // migration holds a parsed migration file ready to apply.
 type migration struct {
-	Version int
-	Name    string
-	SQL     string
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// appliedMigration is a schema_migrations row. Checksum is empty for rows
+// recorded before checksums were tracked.
+type appliedMigration struct {
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// MigrationStatus describes one migration file for the status command.
+// Modified is set when the file no longer matches the applied checksum.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	Modified  bool
 }
 
 // Migrator applies SQL migration files in version order and tracks applied
@@ -38,8 +64,9 @@ func NewMigrator(db *DB, migrationsFS fs.FS, log migrationLogger) *Migrator {
 	return &Migrator{db: db, fs: migrationsFS, log: log}
 }
 
-// Run ensures schema_migrations exists, optionally seeds it with a baseline,
-// then applies all pending up-migrations in version order.
+// Run ensures schema_migrations exists, verifies the checksums of applied
+// migrations, optionally seeds a baseline, then applies all pending
+// up-migrations in version order, each in its own transaction.
 //
 // baseline: if schema_migrations is empty and baseline > 0, all versions up to
 // and including baseline are recorded as already applied without running their
@@ -49,35 +76,37 @@ func (m *Migrator) Run(ctx context.Context, baseline int) error {
 		return err
 	}
 
-	applied, seeded, err := m.loadApplied(ctx)
+	all, err := m.loadMigrations()
 	if err != nil {
 		return err
 	}
 
-	pending, err := m.loadPending(applied)
+	applied, err := m.loadApplied(ctx)
 	if err != nil {
 		return err
 	}
 
+	if err := m.verifyChecksums(ctx, all, applied); err != nil {
+		return err
+	}
+
+	pending := pendingMigrations(all, applied)
 	if len(pending) == 0 {
 		m.log.Infow("Migrations: all up to date", "applied", len(applied))
 		return nil
 	}
 
 	// On first run with an empty table, seed baseline versions without executing SQL.
-	if !seeded && baseline > 0 {
+	if len(applied) == 0 && baseline > 0 {
 		if err := m.seedBaseline(ctx, pending, baseline); err != nil {
 			return err
 		}
 		// Reload so we only apply truly pending migrations.
-		applied, _, err = m.loadApplied(ctx)
-		if err != nil {
-			return err
-		}
-		pending, err = m.loadPending(applied)
+		applied, err = m.loadApplied(ctx)
 		if err != nil {
 			return err
 		}
+		pending = pendingMigrations(all, applied)
 	}
 
 	if len(pending) == 0 {
@@ -100,7 +129,7 @@ func (m *Migrator) Run(ctx context.Context, baseline int) error {
 
 // Applied returns all versions recorded in schema_migrations.
 func (m *Migrator) Applied(ctx context.Context) ([]int, error) {
-	applied, _, err := m.loadApplied(ctx)
+	applied, err := m.loadApplied(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -112,7 +141,39 @@ func (m *Migrator) Applied(ctx context.Context) ([]int, error) {
 	return versions, nil
 }
 
-// ensureMigrationsTable creates schema_migrations if it does not exist.
+// Status lists every migration file in version order with whether it has
+// been applied and whether it was modified since. It changes nothing but
+// creates schema_migrations if it does not exist yet.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.ensureMigrationsTable(ctx); err != nil {
+		return nil, err
+	}
+
+	all, err := m.loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := m.loadApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(all))
+	for i, mg := range all {
+		statuses[i] = MigrationStatus{Version: mg.Version, Name: mg.Name}
+		if a, ok := applied[mg.Version]; ok {
+			appliedAt := a.AppliedAt
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = &appliedAt
+			statuses[i].Modified = a.Checksum != "" && a.Checksum != mg.Checksum
+		}
+	}
+	return statuses, nil
+}
+
+// ensureMigrationsTable creates schema_migrations if it does not exist and
+// adds the checksum column to tables created before it was tracked.
 func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
 	_, err := m.db.DB.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
@@ -123,38 +184,76 @@ func (m *Migrator) ensureMigrationsTable(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("create schema_migrations: %w", err)
 	}
+
+	_, err = m.db.DB.ExecContext(ctx,
+		`ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT`)
+	if err != nil {
+		return fmt.Errorf("add schema_migrations checksum: %w", err)
+	}
 	return nil
 }
 
-// loadApplied returns the set of applied versions and whether the table had
-// any rows (seeded = true means the table was not brand new / empty).
-func (m *Migrator) loadApplied(ctx context.Context) (map[int]struct{}, bool, error) {
+// loadApplied returns the applied migrations by version.
+func (m *Migrator) loadApplied(ctx context.Context) (map[int]appliedMigration, error) {
 	rows, err := m.db.DB.QueryContext(ctx,
-		`SELECT version FROM schema_migrations ORDER BY version`)
+		`SELECT version, checksum, applied_at FROM schema_migrations ORDER BY version`)
 	if err != nil {
-		return nil, false, fmt.Errorf("query schema_migrations: %w", err)
+		return nil, fmt.Errorf("query schema_migrations: %w", err)
 	}
 	defer rows.Close()
 
-	applied := make(map[int]struct{})
+	applied := make(map[int]appliedMigration)
 	for rows.Next() {
 		var v int
-		if err := rows.Scan(&v); err != nil {
-			return nil, false, err
+		var checksum sql.NullString
+		var a appliedMigration
+		if err := rows.Scan(&v, &checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		a.Checksum = checksum.String
+		applied[v] = a
+	}
+	return applied, rows.Err()
+}
+
+// verifyChecksums fails with ErrChecksumMismatch if any applied migration
+// file changed since it ran. Rows recorded before checksums were tracked
+// adopt the current file's checksum.
+func (m *Migrator) verifyChecksums(ctx context.Context, all []migration, applied map[int]appliedMigration) error {
+	var modified []string
+	for _, mg := range all {
+		a, ok := applied[mg.Version]
+		if !ok {
+			continue
+		}
+		if a.Checksum == "" {
+			if _, err := m.db.DB.ExecContext(ctx,
+				`UPDATE schema_migrations SET checksum = $1 WHERE version = $2 AND checksum IS NULL`,
+				mg.Checksum, mg.Version); err != nil {
+				return fmt.Errorf("record checksum of version %d: %w", mg.Version, err)
+			}
+			continue
+		}
+		if a.Checksum != mg.Checksum {
+			modified = append(modified, fmt.Sprintf("%03d_%s", mg.Version, mg.Name))
 		}
-		applied[v] = struct{}{}
 	}
-	return applied, len(applied) > 0, rows.Err()
+
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return nil
 }
 
-// loadPending returns all up-migrations not yet in applied, sorted by version.
-func (m *Migrator) loadPending(applied map[int]struct{}) ([]migration, error) {
+// loadMigrations parses all up-migration files, sorted by version. Two files
+// with the same version are an error.
+func (m *Migrator) loadMigrations() ([]migration, error) {
 	entries, err := fs.ReadDir(m.fs, ".")
 	if err != nil {
 		return nil, fmt.Errorf("read migrations dir: %w", err)
 	}
 
-	var pending []migration
+	var all []migration
 	for _, e := range entries {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), "_up.sql") {
 			continue
@@ -164,15 +263,37 @@ func (m *Migrator) loadPending(applied map[int]struct{}) ([]migration, error) {
 			m.log.Warnw("Skipping unparseable migration file", "file", e.Name(), "error", err)
 			continue
 		}
+		all = append(all, mg)
+	}
+
+	return sortMigrations(all)
+}
+
+// sortMigrations orders migrations by numeric version and rejects duplicate
+// versions.
+func sortMigrations(all []migration) ([]migration, error) {
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Version < all[j].Version
+	})
+	for i := 1; i < len(all); i++ {
+		if all[i].Version == all[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s",
+				all[i].Version, all[i-1].Name, all[i].Name)
+		}
+	}
+	return all, nil
+}
+
+// pendingMigrations returns the migrations of all not yet in applied, in
+// version order.
+func pendingMigrations(all []migration, applied map[int]appliedMigration) []migration {
+	var pending []migration
+	for _, mg := range all {
 		if _, ok := applied[mg.Version]; !ok {
 			pending = append(pending, mg)
 		}
 	}
-
-	sort.Slice(pending, func(i, j int) bool {
-		return pending[i].Version < pending[j].Version
-	})
-	return pending, nil
+	return pending
 }
 
 // seedBaseline records all pending migrations with version ≤ baseline as
@@ -192,9 +313,9 @@ func (m *Migrator) seedBaseline(ctx context.Context, pending []migration, baseli
 			continue
 		}
 		_, err := tx.ExecContext(ctx,
-			`INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)
+			`INSERT INTO schema_migrations (version, name, applied_at, checksum) VALUES ($1, $2, $3, $4)
 			 ON CONFLICT (version) DO NOTHING`,
-			mg.Version, mg.Name, time.Now())
+			mg.Version, mg.Name, time.Now(), mg.Checksum)
 		if err != nil {
 			return fmt.Errorf("seed baseline version %d: %w", mg.Version, err)
 		}
@@ -222,8 +343,8 @@ func (m *Migrator) apply(ctx context.Context, mg migration) error {
 	}
 
 	if _, err := tx.ExecContext(ctx,
-		`INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`,
-		mg.Version, mg.Name); err != nil {
+		`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		mg.Version, mg.Name, mg.Checksum); err != nil {
 		return fmt.Errorf("record migration: %w", err)
 	}
 
@@ -245,14 +366,21 @@ func parseMigrationFile(migrationsFS fs.FS, filename string) (migration, error)
 		return migration{}, fmt.Errorf("non-numeric version prefix in %s", filename)
 	}
 
-	sql, err := fs.ReadFile(migrationsFS, filename)
+	content, err := fs.ReadFile(migrationsFS, filename)
 	if err != nil {
 		return migration{}, fmt.Errorf("read %s: %w", filename, err)
 	}
 
 	return migration{
-		Version: version,
-		Name:    parts[1],
-		SQL:     string(sql),
+		Version:  version,
+		Name:     parts[1],
+		SQL:      string(content),
+		Checksum: checksum(content),
 	}, nil
 }
+
+// checksum is the hex SHA-256 of a migration file's contents.
+func checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
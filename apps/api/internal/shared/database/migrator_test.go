@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testMigrations = fstest.MapFS{
+	"010_add_goals_up.sql":   {Data: []byte("CREATE TABLE goals (id INT);")},
+	"010_add_goals_down.sql": {Data: []byte("DROP TABLE goals;")},
+	"002_add_users_up.sql":   {Data: []byte("CREATE TABLE users (id INT);")},
+	"001_init_up.sql":        {Data: []byte("CREATE TABLE init (id INT);")},
+	"README.md":              {Data: []byte("not a migration")},
+}
+
+var appliedColumns = []string{"version", "checksum", "applied_at"}
+
+func newMigratorTest(t *testing.T, files fstest.MapFS) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+	return NewMigrator(&DB{DB: mockDB}, files, logger.New()), mock
+}
+
+func expectMigrationsTable(mock sqlmock.Sqlmock) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func fileChecksum(name string) string {
+	return checksum(testMigrations[name].Data)
+}
+
+func TestLoadMigrations_Order(t *testing.T) {
+	m, _ := newMigratorTest(t, testMigrations)
+
+	all, err := m.loadMigrations()
+
+	require.NoError(t, err)
+	require.Len(t, all, 3, "down migrations and other files are ignored")
+	assert.Equal(t, []int{1, 2, 10}, []int{all[0].Version, all[1].Version, all[2].Version},
+		"numeric, not lexical, order")
+	assert.Equal(t, "add_goals", all[2].Name)
+}
+
+func TestSortMigrations_DuplicateVersion(t *testing.T) {
+	_, err := sortMigrations([]migration{{Version: 3, Name: "a"}, {Version: 1, Name: "b"}, {Version: 3, Name: "c"}})
+	assert.ErrorContains(t, err, "duplicate migration version 3")
+}
+
+func TestChecksum(t *testing.T) {
+	a := checksum([]byte("CREATE TABLE goals (id INT);"))
+	assert.Len(t, a, 64)
+	assert.Equal(t, a, checksum([]byte("CREATE TABLE goals (id INT);")))
+	assert.NotEqual(t, a, checksum([]byte("CREATE TABLE goals (id BIGINT);")))
+}
+
+func TestMigratorRun_AppliesPendingInOrder(t *testing.T) {
+	m, mock := newMigratorTest(t, testMigrations)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(appliedColumns).AddRow(1, fileChecksum("001_init_up.sql"), time.Now()))
+
+	for _, file := range []string{"002_add_users_up.sql", "010_add_goals_up.sql"} {
+		mock.ExpectBegin()
+		mock.ExpectExec("CREATE TABLE").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO schema_migrations \\(version, name, checksum\\)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), fileChecksum(file)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
+	require.NoError(t, m.Run(context.Background(), 0))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorRun_FailedMigrationRollsBack(t *testing.T) {
+	m, mock := newMigratorTest(t, testMigrations)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(appliedColumns))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE init").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+
+	err := m.Run(context.Background(), 0)
+
+	assert.ErrorContains(t, err, "migration 001_init failed")
+	assert.NoError(t, mock.ExpectationsWereMet(), "later migrations are not attempted")
+}
+
+func TestMigratorRun_ModifiedFile(t *testing.T) {
+	m, mock := newMigratorTest(t, testMigrations)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(appliedColumns).
+			AddRow(1, fileChecksum("001_init_up.sql"), time.Now()).
+			AddRow(2, checksum([]byte("the original file")), time.Now()))
+
+	err := m.Run(context.Background(), 0)
+
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.ErrorContains(t, err, "002_add_users")
+	assert.NoError(t, mock.ExpectationsWereMet(), "nothing is applied")
+}
+
+func TestMigratorRun_BackfillsMissingChecksums(t *testing.T) {
+	m, mock := newMigratorTest(t, testMigrations)
+	expectMigrationsTable(mock)
+	// Rows recorded before checksums were tracked
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(appliedColumns).
+			AddRow(1, nil, time.Now()).
+			AddRow(2, nil, time.Now()).
+			AddRow(10, nil, time.Now()))
+	for _, file := range []string{"001_init_up.sql", "002_add_users_up.sql", "010_add_goals_up.sql"} {
+		mock.ExpectExec("UPDATE schema_migrations SET checksum = \\$1 WHERE version = \\$2 AND checksum IS NULL").
+			WithArgs(fileChecksum(file), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	require.NoError(t, m.Run(context.Background(), 0))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigratorStatus(t *testing.T) {
+	m, mock := newMigratorTest(t, testMigrations)
+	appliedAt := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	expectMigrationsTable(mock)
+	mock.ExpectQuery("SELECT version, checksum, applied_at FROM schema_migrations").
+		WillReturnRows(sqlmock.NewRows(appliedColumns).
+			AddRow(1, fileChecksum("001_init_up.sql"), appliedAt).
+			AddRow(2, "edited", appliedAt))
+
+	statuses, err := m.Status(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, MigrationStatus{Version: 1, Name: "init", Applied: true, AppliedAt: &appliedAt}, statuses[0])
+	assert.True(t, statuses[1].Modified)
+	assert.Equal(t, MigrationStatus{Version: 10, Name: "add_goals"}, statuses[2])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
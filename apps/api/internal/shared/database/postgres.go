@@ -29,6 +29,9 @@ type PostgresConfig struct {
 // DB wraps sql.DB with additional functionality
 type DB struct {
 	*sql.DB
+
+	// inst is set by Instrument
+	inst *instrumentation
 }
 
 // NewPostgres creates a new PostgreSQL connection for Yandex Cloud
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// TxBeginner starts transactions; *sql.DB and *DB satisfy it
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// TxOption configures the transaction started by WithTx
+type TxOption func(*sql.TxOptions)
+
+// WithIsolation sets the isolation level, e.g. sql.LevelSerializable
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *sql.TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction
+func ReadOnly() TxOption {
+	return func(o *sql.TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// WithTx runs fn in a transaction. The transaction is committed when fn
+// returns nil and rolled back when it returns an error or panics; a panic is
+// re-raised after the rollback. fn's error is returned as is, joined with
+// the rollback error if that fails too, so errors.Is still matches it.
+func WithTx(ctx context.Context, db TxBeginner, fn func(tx *sql.Tx) error, opts ...TxOption) error {
+	var txOpts *sql.TxOptions
+	if len(opts) > 0 {
+		txOpts = &sql.TxOptions{}
+		for _, opt := range opts {
+			opt(txOpts)
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, txOpts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
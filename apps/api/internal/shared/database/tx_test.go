@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTxTestDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+	return &DB{DB: mockDB}, mock
+}
+
+func TestWithTx_Commit(t *testing.T) {
+	db, mock := newTxTestDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE users").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(context.Background(), "UPDATE users SET name = $1", "Anna")
+		return err
+	})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTx_CallbackError(t *testing.T) {
+	errFailed := errors.New("update failed")
+
+	t.Run("rolls back and returns the error", func(t *testing.T) {
+		db, mock := newTxTestDB(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		err := WithTx(context.Background(), db, func(tx *sql.Tx) error { return errFailed })
+
+		assert.Equal(t, errFailed, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("keeps the rollback error", func(t *testing.T) {
+		db, mock := newTxTestDB(t)
+		mock.ExpectBegin()
+		mock.ExpectRollback().WillReturnError(errors.New("connection lost"))
+
+		err := WithTx(context.Background(), db, func(tx *sql.Tx) error { return errFailed })
+
+		assert.ErrorIs(t, err, errFailed)
+		assert.ErrorContains(t, err, "failed to roll back transaction: connection lost")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestWithTx_CommitError(t *testing.T) {
+	db, mock := newTxTestDB(t)
+	errCommit := errors.New("serialization failure")
+	mock.ExpectBegin()
+	mock.ExpectCommit().WillReturnError(errCommit)
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error { return nil })
+
+	assert.ErrorIs(t, err, errCommit)
+	assert.ErrorContains(t, err, "failed to commit transaction")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTx_BeginError(t *testing.T) {
+	db, mock := newTxTestDB(t)
+	mock.ExpectBegin().WillReturnError(errors.New("too many connections"))
+	called := false
+
+	err := WithTx(context.Background(), db, func(tx *sql.Tx) error {
+		called = true
+		return nil
+	})
+
+	assert.ErrorContains(t, err, "failed to begin transaction: too many connections")
+	assert.False(t, called)
+}
+
+func TestWithTx_PanicRollsBack(t *testing.T) {
+	db, mock := newTxTestDB(t)
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = WithTx(context.Background(), db, func(tx *sql.Tx) error {
+			panic("boom")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithTx_Options(t *testing.T) {
+	var got *sql.TxOptions
+	beginner := txBeginnerFunc(func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+		got = opts
+		return nil, errors.New("not connected")
+	})
+
+	_ = WithTx(context.Background(), beginner, func(tx *sql.Tx) error { return nil })
+	assert.Nil(t, got)
+
+	_ = WithTx(context.Background(), beginner, func(tx *sql.Tx) error { return nil },
+		WithIsolation(sql.LevelSerializable), ReadOnly())
+	require.NotNil(t, got)
+	assert.Equal(t, sql.LevelSerializable, got.Isolation)
+	assert.True(t, got.ReadOnly)
+}
+
+// txBeginnerFunc records the options WithTx passes to BeginTx
+type txBeginnerFunc func(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+
+func (f txBeginnerFunc) BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error) {
+	return f(ctx, opts)
+}
@@ -0,0 +1,154 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFrom = mail.Address{Name: "BURCEV", Address: "noreply@burcev.team"}
+
+// mimePart is a decoded part of a multipart message
+type mimePart struct {
+	contentType string
+	body        string
+}
+
+// parseMessage parses raw with net/mail and decodes its multipart/alternative body
+func parseMessage(t *testing.T, raw []byte) (*mail.Message, []mimePart) {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	var parts []mimePart
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		require.Equal(t, "quoted-printable", part.Header.Get("Content-Transfer-Encoding"))
+
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		// Line breaks travel as CRLF; compare them with the rendered LF text
+		parts = append(parts, mimePart{
+			contentType: part.Header.Get("Content-Type"),
+			body:        strings.ReplaceAll(string(body), "\r\n", "\n"),
+		})
+	}
+	return msg, parts
+}
+
+// renderedMessage returns the message the service hands to the sender for send
+func renderedMessage(t *testing.T, unsubscribeURL string, send func(svc *Service) error) Message {
+	t.Helper()
+	sender := NewMemorySender()
+	svc, err := NewServiceWithSender(sender, logger.Nop())
+	require.NoError(t, err)
+	svc.unsubscribeURL = unsubscribeURL
+
+	require.NoError(t, send(svc))
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	return messages[0]
+}
+
+func TestBuildMessage_PasswordReset(t *testing.T) {
+	data := ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset-password?token=abc123",
+		ExpirationTime: time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC),
+		SupportEmail:   "support@burcev.team",
+	}
+	rendered := renderedMessage(t, "", func(svc *Service) error {
+		return svc.SendPasswordResetEmail(context.Background(), data)
+	})
+
+	raw, err := buildMessage(testFrom, rendered, time.Now())
+	require.NoError(t, err)
+
+	// Cyrillic content is encoded, so the message is 7-bit clean
+	for _, b := range raw {
+		require.Less(t, b, byte(0x80), "message must be 7-bit")
+	}
+
+	msg, parts := parseMessage(t, raw)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Запрос на сброс пароля - BURCEV", subject)
+	assert.Equal(t, "user@example.com", msg.Header.Get("To"))
+	assert.Equal(t, `"BURCEV" <noreply@burcev.team>`, msg.Header.Get("From"))
+	assert.Equal(t, "1.0", msg.Header.Get("MIME-Version"))
+	assert.Regexp(t, `^<[a-z0-9]+@burcev\.team>$`, msg.Header.Get("Message-ID"))
+	assert.Empty(t, msg.Header.Get("List-Unsubscribe"), "transactional emails cannot be unsubscribed from")
+
+	require.Len(t, parts, 2)
+	assert.Equal(t, "text/plain; charset=UTF-8", parts[0].contentType)
+	assert.Equal(t, rendered.TextBody, parts[0].body)
+	assert.Contains(t, parts[0].body, "Чтобы сбросить пароль, откройте ссылку:\n"+data.ResetURL)
+	assert.NotContains(t, parts[0].body, "<")
+
+	assert.Equal(t, "text/html; charset=UTF-8", parts[1].contentType)
+	assert.Equal(t, rendered.HTMLBody, parts[1].body)
+	assert.Contains(t, parts[1].body, `<a href="https://burcev.team/reset-password?token=abc123"`)
+}
+
+func TestBuildMessage_ListUnsubscribe(t *testing.T) {
+	rendered := renderedMessage(t, "https://burcev.team/api/v1/notifications/unsubscribe", func(svc *Service) error {
+		return svc.SendWeeklySummaryEmail(context.Background(), WeeklySummaryEmailData{
+			UserEmail:        "user@example.com",
+			UserName:         "Анна",
+			WeekStart:        time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			WeekEnd:          time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC),
+			LoggedDays:       5,
+			AdherencePercent: 71,
+			AvgCalories:      1850,
+			UnsubscribeToken: "tok",
+		})
+	})
+
+	raw, err := buildMessage(testFrom, rendered, time.Now())
+	require.NoError(t, err)
+	msg, parts := parseMessage(t, raw)
+
+	unsubscribeURL := "https://burcev.team/api/v1/notifications/unsubscribe?token=tok"
+	assert.Equal(t, "<"+unsubscribeURL+">", msg.Header.Get("List-Unsubscribe"))
+
+	require.Len(t, parts, 2)
+	assert.Contains(t, parts[0].body, "Здравствуйте, Анна!")
+	assert.Contains(t, parts[0].body, "Дней с записями питания: 5 из 7 (71%)")
+	assert.Contains(t, parts[0].body, "Средняя калорийность: 1850 ккал")
+	assert.Contains(t, parts[0].body, "Отписаться: "+unsubscribeURL)
+	assert.Contains(t, parts[1].body, `href="`+unsubscribeURL+`"`)
+}
+
+func TestBuildMessage_UniqueMessageID(t *testing.T) {
+	msg := Message{To: "user@example.com", Subject: "Тема", HTMLBody: "<p>Тело</p>", TextBody: "Тело"}
+
+	first, err := buildMessage(testFrom, msg, time.Now())
+	require.NoError(t, err)
+	second, err := buildMessage(testFrom, msg, time.Now())
+	require.NoError(t, err)
+
+	firstMsg, _ := parseMessage(t, first)
+	secondMsg, _ := parseMessage(t, second)
+	assert.NotEqual(t, firstMsg.Header.Get("Message-ID"), secondMsg.Header.Get("Message-ID"))
+}
@@ -0,0 +1,213 @@
+package email
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// MaxOutboxAttempts caps how often a queued email is tried before it is
+	// left as failed
+	MaxOutboxAttempts = 5
+	// outboxLease is how long a claimed email is hidden from other workers;
+	// an email still processing after it is picked up again
+	outboxLease = 5 * time.Minute
+	// outboxBatchSize caps the emails of one kind claimed at a time
+	outboxBatchSize = 20
+	// maxOutboxErrorLength caps the error text stored with a failed email
+	maxOutboxErrorLength = 500
+)
+
+// OutboxHandler delivers one queued email from its payload
+type OutboxHandler func(ctx context.Context, payload json.RawMessage) error
+
+// outboxEntry is a claimed email_outbox row
+type outboxEntry struct {
+	id       int64
+	payload  json.RawMessage
+	attempts int
+}
+
+// Outbox queues emails whose preparation must not delay the request that
+// triggers them. Each kind of email is delivered by the handler registered
+// for it; RunWorker drains the queue.
+type Outbox struct {
+	db       *sql.DB
+	log      *logger.Logger
+	handlers map[string]OutboxHandler
+	kinds    []string
+}
+
+// NewOutbox creates an email outbox stored in the email_outbox table
+func NewOutbox(db *sql.DB, log *logger.Logger) *Outbox {
+	return &Outbox{
+		db:       db,
+		log:      log,
+		handlers: make(map[string]OutboxHandler),
+	}
+}
+
+// Handle registers the handler delivering emails of kind. Handlers must be
+// registered before the worker starts.
+func (o *Outbox) Handle(kind string, handler OutboxHandler) {
+	if _, ok := o.handlers[kind]; !ok {
+		o.kinds = append(o.kinds, kind)
+	}
+	o.handlers[kind] = handler
+}
+
+// Enqueue queues an email of kind. payload is stored as JSON and passed to
+// the kind's handler as is.
+func (o *Outbox) Enqueue(ctx context.Context, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s email: %w", kind, err)
+	}
+
+	startTime := time.Now()
+	query := `INSERT INTO email_outbox (kind, payload) VALUES ($1, $2)`
+	_, err = o.db.ExecContext(ctx, query, kind, data)
+	o.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"kind": kind})
+	if err != nil {
+		return fmt.Errorf("failed to queue %s email: %w", kind, err)
+	}
+	return nil
+}
+
+// ProcessPending claims one batch of due emails of every registered kind and
+// hands them to their handlers. Returns the number of emails processed.
+func (o *Outbox) ProcessPending(ctx context.Context) (int, error) {
+	processed := 0
+	for _, kind := range o.kinds {
+		entries, err := o.claimBatch(ctx, kind)
+		if err != nil {
+			return processed, err
+		}
+
+		for _, e := range entries {
+			if err := o.deliver(ctx, kind, e); err != nil {
+				return processed, err
+			}
+			processed++
+		}
+	}
+	return processed, nil
+}
+
+// claimBatch marks a batch of due emails of kind as processing for
+// outboxLease and returns them
+func (o *Outbox) claimBatch(ctx context.Context, kind string) ([]outboxEntry, error) {
+	startTime := time.Now()
+	query := `
+		UPDATE email_outbox
+		SET status = 'processing', attempts = attempts + 1,
+		    next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE kind = $1 AND status IN ('pending', 'processing') AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, payload, attempts`
+
+	rows, err := o.db.QueryContext(ctx, query, kind, outboxBatchSize, int(outboxLease.Seconds()))
+	if err != nil {
+		o.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"kind": kind})
+		return nil, fmt.Errorf("failed to claim %s emails: %w", kind, err)
+	}
+	defer rows.Close()
+
+	var entries []outboxEntry
+	for rows.Next() {
+		var e outboxEntry
+		if err := rows.Scan(&e.id, &e.payload, &e.attempts); err != nil {
+			return nil, fmt.Errorf("failed to scan outbox email: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating outbox emails: %w", err)
+	}
+	return entries, nil
+}
+
+// deliver runs the handler for a claimed email and records the outcome.
+// Delivered emails are deleted; failed ones are retried with a linear
+// backoff until MaxOutboxAttempts is reached.
+func (o *Outbox) deliver(ctx context.Context, kind string, e outboxEntry) error {
+	sendErr := o.handlers[kind](ctx, e.payload)
+	if sendErr == nil {
+		_, err := o.db.ExecContext(ctx, `DELETE FROM email_outbox WHERE id = $1`, e.id)
+		if err != nil {
+			return fmt.Errorf("failed to remove delivered email %d: %w", e.id, err)
+		}
+		return nil
+	}
+	if errors.Is(sendErr, context.Canceled) {
+		// Shutting down; the lease hands the email to the next worker
+		return sendErr
+	}
+
+	errMsg := sendErr.Error()
+	if len(errMsg) > maxOutboxErrorLength {
+		errMsg = errMsg[:maxOutboxErrorLength]
+	}
+
+	status := "pending"
+	if e.attempts >= MaxOutboxAttempts {
+		status = "failed"
+	}
+	o.log.Warn("Outbox email delivery failed",
+		"kind", kind,
+		"outbox_id", e.id,
+		"attempt", e.attempts,
+		"error", sendErr,
+	)
+
+	retryAfter := time.Duration(e.attempts) * time.Minute
+	_, err := o.db.ExecContext(ctx, `
+		UPDATE email_outbox
+		SET status = $2, error = $3, next_attempt_at = NOW() + $4 * INTERVAL '1 second'
+		WHERE id = $1`,
+		e.id, status, errMsg, int(retryAfter.Seconds()),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record outbox email %d failure: %w", e.id, err)
+	}
+	return nil
+}
+
+// RunWorker delivers queued emails until ctx is cancelled. Every tick it
+// drains due emails batch by batch.
+func (o *Outbox) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	o.log.Info("Email outbox worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				processed, err := o.ProcessPending(ctx)
+				if err != nil {
+					o.log.Error("Failed to process email outbox", "error", err)
+					break
+				}
+				if processed == 0 || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			o.log.Info("Email outbox worker stopped")
+			return
+		}
+	}
+}
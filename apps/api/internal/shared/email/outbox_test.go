@@ -0,0 +1,119 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupOutboxTest(t *testing.T) (*Outbox, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return NewOutbox(db, logger.New()), mock
+}
+
+func TestOutboxEnqueue(t *testing.T) {
+	outbox, mock := setupOutboxTest(t)
+
+	mock.ExpectExec("INSERT INTO email_outbox").
+		WithArgs("welcome", []byte(`{"email":"user@example.com"}`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	err := outbox.Enqueue(context.Background(), "welcome", map[string]string{"email": "user@example.com"})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestOutboxProcessPending(t *testing.T) {
+	claimColumns := []string{"id", "payload", "attempts"}
+
+	t.Run("delivered emails are removed", func(t *testing.T) {
+		outbox, mock := setupOutboxTest(t)
+
+		var got []string
+		outbox.Handle("welcome", func(ctx context.Context, payload json.RawMessage) error {
+			got = append(got, string(payload))
+			return nil
+		})
+
+		mock.ExpectQuery("UPDATE email_outbox").
+			WithArgs("welcome", outboxBatchSize, int(outboxLease.Seconds())).
+			WillReturnRows(sqlmock.NewRows(claimColumns).
+				AddRow(1, []byte(`{"n":1}`), 1).
+				AddRow(2, []byte(`{"n":2}`), 1))
+		mock.ExpectExec("DELETE FROM email_outbox").WithArgs(int64(1)).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM email_outbox").WithArgs(int64(2)).WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := outbox.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, processed)
+		assert.Equal(t, []string{`{"n":1}`, `{"n":2}`}, got)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed email is retried", func(t *testing.T) {
+		outbox, mock := setupOutboxTest(t)
+		outbox.Handle("welcome", func(ctx context.Context, payload json.RawMessage) error {
+			return errors.New("smtp unavailable")
+		})
+
+		mock.ExpectQuery("UPDATE email_outbox").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(1, []byte(`{}`), 2))
+		mock.ExpectExec("UPDATE email_outbox").
+			WithArgs(int64(1), "pending", "smtp unavailable", 120).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		processed, err := outbox.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("last attempt marks email failed", func(t *testing.T) {
+		outbox, mock := setupOutboxTest(t)
+		outbox.Handle("welcome", func(ctx context.Context, payload json.RawMessage) error {
+			return errors.New("smtp unavailable")
+		})
+
+		mock.ExpectQuery("UPDATE email_outbox").
+			WillReturnRows(sqlmock.NewRows(claimColumns).AddRow(1, []byte(`{}`), MaxOutboxAttempts))
+		mock.ExpectExec("UPDATE email_outbox").
+			WithArgs(int64(1), "failed", "smtp unavailable", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := outbox.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("each kind is claimed separately", func(t *testing.T) {
+		outbox, mock := setupOutboxTest(t)
+		noop := func(ctx context.Context, payload json.RawMessage) error { return nil }
+		outbox.Handle("welcome", noop)
+		outbox.Handle("digest", noop)
+
+		mock.ExpectQuery("UPDATE email_outbox").
+			WithArgs("welcome", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(claimColumns))
+		mock.ExpectQuery("UPDATE email_outbox").
+			WithArgs("digest", sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(claimColumns))
+
+		processed, err := outbox.ProcessPending(context.Background())
+
+		require.NoError(t, err)
+		assert.Zero(t, processed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,97 @@
+package email
+
+import (
+	"context"
+	"sync"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// Email drivers selectable via EMAIL_DRIVER
+const (
+	DriverSMTP   = "smtp"
+	DriverLog    = "log"
+	DriverMemory = "memory"
+)
+
+// Sender delivers a rendered email message
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Message is a rendered email with an HTML body and its plain-text alternative
+type Message struct {
+	To       string
+	Subject  string
+	HTMLBody string
+	TextBody string
+
+	// UnsubscribeURL, when set, is advertised in the List-Unsubscribe header
+	UnsubscribeURL string
+}
+
+// LogSender writes messages to the log instead of sending them (local development)
+type LogSender struct {
+	log *logger.Logger
+}
+
+// NewLogSender creates a sender that only logs messages
+func NewLogSender(log *logger.Logger) *LogSender {
+	return &LogSender{log: log}
+}
+
+// Send logs the recipient and subject and always succeeds. The body is left
+// out: it carries password reset and email change links.
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.log.Info("Email not sent (log driver)",
+		"to", msg.To,
+		"subject", msg.Subject,
+	)
+	return nil
+}
+
+// MemorySender records messages in memory; intended for tests
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemorySender creates an empty in-memory sender
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+// Send records the message, or returns the error configured with FailWith
+func (s *MemorySender) Send(ctx context.Context, msg Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.messages = append(s.messages, msg)
+	return nil
+}
+
+// FailWith makes subsequent sends return err (nil restores normal behaviour)
+func (s *MemorySender) FailWith(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Messages returns a copy of all recorded messages
+func (s *MemorySender) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Message, len(s.messages))
+	copy(result, s.messages)
+	return result
+}
+
+// Reset clears recorded messages
+func (s *MemorySender) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = nil
+}
@@ -0,0 +1,28 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogSender_Send(t *testing.T) {
+	var buf bytes.Buffer
+	sender := NewLogSender(logger.New(logger.WithOutput(&buf), logger.WithEncoding(logger.EncodingJSON)))
+
+	err := sender.Send(context.Background(), Message{
+		To:       "user@example.com",
+		Subject:  "Сброс пароля",
+		HTMLBody: `<a href="https://app.example/reset?token=secret-token">`,
+		TextBody: "https://app.example/reset?token=secret-token",
+	})
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "user@example.com")
+	assert.Contains(t, buf.String(), "Сброс пароля")
+	assert.NotContains(t, buf.String(), "secret-token")
+}
@@ -3,36 +3,56 @@ package email
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
 	"fmt"
 	"html/template"
-	"mime"
-	"net/smtp"
+	"net/url"
+	texttemplate "text/template"
 	"time"
 
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/burcev/api/internal/shared/logger"
 )
 
-// Service handles email sending operations
+// maxSendAttempts is how often retried emails (reset, verification) are tried
+const maxSendAttempts = 3
+
+// Service renders email templates and hands messages to a Sender. Every
+// template has an HTML and a plain-text version of the same name.
 type Service struct {
-	smtpHost     string
-	smtpPort     int
-	smtpUsername string
-	smtpPassword string
-	fromAddress  string
-	fromName     string
-	log          *logger.Logger
-	templates    *template.Template
+	sender        Sender
+	log           *logger.Logger
+	templates     *template.Template
+	textTemplates *texttemplate.Template
+
+	// retryDelay is the wait after the first failed attempt; it grows linearly
+	retryDelay time.Duration
+
+	// unsubscribeURL is the public endpoint unsubscribe tokens are appended to
+	unsubscribeURL string
 }
 
-// Config holds email service configuration
+// Config holds email service configuration.
+// SMTP fields are only required for the smtp driver.
 type Config struct {
+	Driver       string
 	SMTPHost     string
 	SMTPPort     int
 	SMTPUsername string
 	SMTPPassword string
 	FromAddress  string
 	FromName     string
+
+	// SMTPPoolSize caps the open SMTP connections; SMTPIdleTimeout is how
+	// long an unused one is kept. Zero means the defaults.
+	SMTPPoolSize    int
+	SMTPIdleTimeout time.Duration
+
+	// UnsubscribeURL is the public unsubscribe endpoint; optional emails link to it
+	UnsubscribeURL string
+
+	// TemplateDir optionally holds {name}.html and {name}.txt files that
+	// replace the built-in templates of the same name
+	TemplateDir string
 }
 
 // ResetEmailData contains data for password reset email template
@@ -41,6 +61,9 @@ type ResetEmailData struct {
 	ResetURL       string
 	ExpirationTime time.Time
 	SupportEmail   string
+
+	// Locale selects the language of the email; empty means the default
+	Locale i18n.Locale
 }
 
 // PasswordChangedEmailData contains data for password changed confirmation email
@@ -49,6 +72,9 @@ type PasswordChangedEmailData struct {
 	ChangedAt    time.Time
 	IPAddress    string
 	SupportEmail string
+
+	// Locale selects the language of the email; empty means the default
+	Locale i18n.Locale
 }
 
 // VerificationEmailData contains data for the email verification template
@@ -58,99 +84,213 @@ type VerificationEmailData struct {
 	ExpiresAt time.Time
 }
 
-// NewService creates a new email service instance
+// CuratorBroadcastEmailData contains data for a curator broadcast announcement
+type CuratorBroadcastEmailData struct {
+	UserEmail   string
+	CuratorName string
+	Message     string
+
+	// UnsubscribeToken, when set, adds a one-click unsubscribe link to the email
+	UnsubscribeToken string
+}
+
+// curatorBroadcastView is what the curator broadcast template renders
+type curatorBroadcastView struct {
+	CuratorBroadcastEmailData
+	UnsubscribeURL string
+}
+
+// OrganizationInviteEmailData contains data for the invitation sent to a member
+// whose account was created by an organization import
+type OrganizationInviteEmailData struct {
+	UserEmail        string
+	UserName         string
+	OrganizationName string
+	SetPasswordURL   string
+	ExpiresAt        time.Time
+}
+
+// OrganizationJoinEmailData contains data for the request sent to an existing
+// user who was listed in an organization import
+type OrganizationJoinEmailData struct {
+	UserEmail        string
+	OrganizationName string
+	AcceptURL        string
+	ExpiresAt        time.Time
+}
+
+// DataExportEmailData contains data for the notice that a data export is ready
+type DataExportEmailData struct {
+	UserEmail   string
+	DownloadURL string
+	ExpiresAt   time.Time
+}
+
+// EmailChangeConfirmEmailData contains data for the confirmation link sent
+// to the new address of an email change
+type EmailChangeConfirmEmailData struct {
+	UserEmail  string
+	ConfirmURL string
+	ExpiresAt  time.Time
+}
+
+// EmailChangeNoticeEmailData contains data for the notice sent to the old
+// address of an email change
+type EmailChangeNoticeEmailData struct {
+	UserEmail    string
+	NewEmail     string
+	RequestedAt  time.Time
+	IPAddress    string
+	SupportEmail string
+}
+
+// WeeklySummaryDay is a single day highlighted in the weekly summary
+type WeeklySummaryDay struct {
+	Date     time.Time
+	Calories float64
+}
+
+// WeeklySummaryEmailData contains data for the weekly progress summary.
+// Optional figures are nil when there was not enough data to compute them.
+type WeeklySummaryEmailData struct {
+	UserEmail        string
+	UserName         string
+	WeekStart        time.Time
+	WeekEnd          time.Time
+	LoggedDays       int
+	AdherencePercent int
+	AvgCalories      float64
+	AvgProtein       float64
+	AvgFat           float64
+	AvgCarbs         float64
+	CalorieTarget    *float64
+	CurrentWeight    *float64
+	WeightChange     *float64
+	BestDay          *WeeklySummaryDay
+	WorstDay         *WeeklySummaryDay
+
+	// UnsubscribeToken, when set, adds a one-click unsubscribe link to the email
+	UnsubscribeToken string
+}
+
+// weeklySummaryView is what the weekly summary template renders
+type weeklySummaryView struct {
+	WeeklySummaryEmailData
+	UnsubscribeURL string
+}
+
+// NewService creates a new email service instance with the sender selected by cfg.Driver
+// (smtp when empty)
 func NewService(cfg Config, log *logger.Logger) (*Service, error) {
-	if cfg.SMTPHost == "" {
-		return nil, fmt.Errorf("SMTP host is required")
-	}
-	if cfg.SMTPUsername == "" {
-		return nil, fmt.Errorf("SMTP username is required")
+	var sender Sender
+	switch cfg.Driver {
+	case "", DriverSMTP:
+		smtpSender, err := NewSMTPSender(cfg)
+		if err != nil {
+			return nil, err
+		}
+		sender = smtpSender
+	case DriverLog:
+		sender = NewLogSender(log)
+	case DriverMemory:
+		sender = NewMemorySender()
+	default:
+		return nil, fmt.Errorf("unknown email driver: %s", cfg.Driver)
 	}
-	if cfg.SMTPPassword == "" {
-		return nil, fmt.Errorf("SMTP password is required")
+
+	svc, err := NewServiceWithSender(sender, log)
+	if err != nil {
+		return nil, err
 	}
-	if cfg.FromAddress == "" {
-		cfg.FromAddress = cfg.SMTPUsername
+	svc.unsubscribeURL = cfg.UnsubscribeURL
+
+	if cfg.TemplateDir != "" {
+		svc.templates, svc.textTemplates, err = parseTemplates(cfg.TemplateDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load email templates from %s: %w", cfg.TemplateDir, err)
+		}
+		log.Info("Email templates loaded", "dir", cfg.TemplateDir)
 	}
+	return svc, nil
+}
 
-	// Parse email templates
-	templates, err := parseTemplates()
+// NewServiceWithSender creates an email service that delivers through the given sender
+func NewServiceWithSender(sender Sender, log *logger.Logger) (*Service, error) {
+	// Parse the built-in email templates
+	templates, textTemplates, err := parseTemplates("")
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse templates: %w", err)
 	}
 
 	return &Service{
-		smtpHost:     cfg.SMTPHost,
-		smtpPort:     cfg.SMTPPort,
-		smtpUsername: cfg.SMTPUsername,
-		smtpPassword: cfg.SMTPPassword,
-		fromAddress:  cfg.FromAddress,
-		fromName:     cfg.FromName,
-		log:          log,
-		templates:    templates,
+		sender:        sender,
+		log:           log,
+		templates:     templates,
+		textTemplates: textTemplates,
+		retryDelay:    time.Second,
 	}, nil
 }
 
+// Sender returns the underlying message sender
+func (s *Service) Sender() Sender {
+	return s.sender
+}
+
+// Ping checks that the underlying sender can reach its server.
+// Senders without a remote server (log, memory) are always healthy.
+func (s *Service) Ping(ctx context.Context) error {
+	if pinger, ok := s.sender.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// Close releases the connections the sender keeps open. Senders without
+// connections (log, memory) have nothing to close.
+func (s *Service) Close(ctx context.Context) error {
+	if closer, ok := s.sender.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
 // SendPasswordResetEmail sends a password reset email with retry logic
 func (s *Service) SendPasswordResetEmail(ctx context.Context, data ResetEmailData) error {
-	subject := "Запрос на сброс пароля - BURCEV"
+	subject := i18n.T(data.Locale, i18n.EmailPasswordResetSubject)
 
 	// Render email template
-	body, err := s.renderTemplate("password_reset", data)
+	html, text, err := s.renderBoth(s.localizedTemplate("password_reset", data.Locale), data)
 	if err != nil {
 		s.log.WithError(err).Error("Failed to render password reset email template")
 		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	// Send email with retry logic
-	maxRetries := 3
-	var lastErr error
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err := s.sendEmail(ctx, data.UserEmail, subject, body)
-		if err == nil {
-			s.log.Info("Password reset email sent successfully",
-				"email", data.UserEmail,
-				"attempt", attempt,
-			)
-			return nil
-		}
-
-		lastErr = err
-		s.log.WithError(err).Warn("Failed to send password reset email",
-			"email", data.UserEmail,
-			"attempt", attempt,
-			"max_retries", maxRetries,
-		)
-
-		// Wait before retry (exponential backoff)
-		if attempt < maxRetries {
-			backoff := time.Duration(attempt) * time.Second
-			time.Sleep(backoff)
-		}
-	}
-
-	s.log.WithError(lastErr).Error("Failed to send password reset email after retries",
-		"email", data.UserEmail,
-		"attempts", maxRetries,
-	)
-
-	return fmt.Errorf("failed to send email after %d attempts: %w", maxRetries, lastErr)
+	return s.sendWithRetry(ctx, "password reset", Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
 }
 
 // SendPasswordChangedEmail sends a confirmation email after password change
 func (s *Service) SendPasswordChangedEmail(ctx context.Context, data PasswordChangedEmailData) error {
-	subject := "Пароль изменен - BURCEV"
+	subject := i18n.T(data.Locale, i18n.EmailPasswordChangedSubject)
 
 	// Render email template
-	body, err := s.renderTemplate("password_changed", data)
+	html, text, err := s.renderBoth(s.localizedTemplate("password_changed", data.Locale), data)
 	if err != nil {
 		s.log.WithError(err).Error("Failed to render password changed email template")
 		return fmt.Errorf("failed to render template: %w", err)
 	}
 
 	// Send email (no retry for confirmation emails)
-	err = s.sendEmail(ctx, data.UserEmail, subject, body)
+	err = s.sender.Send(ctx, Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
 	if err != nil {
 		s.log.WithError(err).Error("Failed to send password changed email",
 			"email", data.UserEmail,
@@ -169,163 +309,263 @@ func (s *Service) SendPasswordChangedEmail(ctx context.Context, data PasswordCha
 func (s *Service) SendVerificationEmail(ctx context.Context, data VerificationEmailData) error {
 	subject := "Код подтверждения — BURCEV"
 
-	body, err := s.renderTemplate("email_verification", data)
+	html, text, err := s.renderBoth("email_verification", data)
 	if err != nil {
 		s.log.WithError(err).Error("Failed to render verification email template")
 		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	maxRetries := 3
+	return s.sendWithRetry(ctx, "verification", Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
+}
+
+// sendWithRetry sends a message up to maxSendAttempts times with a growing
+// delay. It gives up early on permanent SMTP errors and returns ctx.Err()
+// as soon as ctx ends, including while waiting between attempts.
+func (s *Service) sendWithRetry(ctx context.Context, kind string, msg Message) error {
+	to := msg.To
 	var lastErr error
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		err := s.sendEmail(ctx, data.UserEmail, subject, body)
+	for attempt := 1; attempt <= maxSendAttempts; attempt++ {
+		err := s.sender.Send(ctx, msg)
 		if err == nil {
-			s.log.Info("Verification email sent successfully",
-				"email", data.UserEmail,
+			s.log.Info("Email sent successfully",
+				"kind", kind,
+				"email", to,
 				"attempt", attempt,
 			)
 			return nil
 		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 
 		lastErr = err
-		s.log.WithError(err).Warn("Failed to send verification email",
-			"email", data.UserEmail,
+		s.log.WithError(err).Warn("Failed to send email",
+			"kind", kind,
+			"email", to,
 			"attempt", attempt,
-			"max_retries", maxRetries,
+			"max_retries", maxSendAttempts,
 		)
+		if IsPermanent(err) {
+			break
+		}
 
-		if attempt < maxRetries {
-			backoff := time.Duration(attempt) * time.Second
-			time.Sleep(backoff)
+		// Wait before retry (linear backoff)
+		if attempt < maxSendAttempts {
+			timer := time.NewTimer(time.Duration(attempt) * s.retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
 		}
 	}
 
-	return fmt.Errorf("failed to send email after %d attempts: %w", maxRetries, lastErr)
-}
-
-// sendEmail sends an email via SMTP
-func (s *Service) sendEmail(ctx context.Context, to, subject, body string) error {
-	// Build email message
-	from := fmt.Sprintf("%s <%s>", s.fromName, s.fromAddress)
-
-	headers := make(map[string]string)
-	headers["From"] = from
-	headers["To"] = to
-	headers["Subject"] = mime.QEncoding.Encode("utf-8", subject)
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-	headers["Date"] = time.Now().Format(time.RFC1123Z)
+	s.log.WithError(lastErr).Error("Failed to send email after retries",
+		"kind", kind,
+		"email", to,
+	)
 
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+	if IsPermanent(lastErr) {
+		return fmt.Errorf("failed to send email: %w", lastErr)
 	}
-	message += "\r\n" + body
+	return fmt.Errorf("failed to send email after %d attempts: %w", maxSendAttempts, lastErr)
+}
 
-	// Connect to SMTP server
-	addr := fmt.Sprintf("%s:%d", s.smtpHost, s.smtpPort)
+// SendCuratorBroadcastEmail sends a curator announcement to a single client
+func (s *Service) SendCuratorBroadcastEmail(ctx context.Context, data CuratorBroadcastEmailData) error {
+	subject := "Сообщение от куратора - BURCEV"
 
-	// Setup authentication
-	auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
+	unsubscribeURL := s.buildUnsubscribeURL(data.UnsubscribeToken)
+	html, text, err := s.renderBoth("curator_broadcast", curatorBroadcastView{data, unsubscribeURL})
+	if err != nil {
+		s.log.WithError(err).Error("Failed to render curator broadcast email template")
+		return fmt.Errorf("failed to render template: %w", err)
+	}
 
-	// For port 465 (SSL/TLS), use TLS connection
-	if s.smtpPort == 465 {
-		return s.sendEmailTLS(addr, auth, to, []byte(message))
+	// No retry here: the broadcast worker records failures per recipient
+	err = s.sender.Send(ctx, Message{
+		To:             data.UserEmail,
+		Subject:        subject,
+		HTMLBody:       html,
+		TextBody:       text,
+		UnsubscribeURL: unsubscribeURL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	// For port 587 (STARTTLS), use standard SMTP with STARTTLS
-	return smtp.SendMail(addr, auth, s.fromAddress, []string{to}, []byte(message))
+	return nil
 }
 
-// sendEmailTLS sends email using TLS connection (for port 465)
-func (s *Service) sendEmailTLS(addr string, auth smtp.Auth, to string, message []byte) error {
-	// Setup TLS config
-	tlsConfig := &tls.Config{
-		ServerName: s.smtpHost,
-		MinVersion: tls.VersionTLS12,
-	}
+// SendWeeklySummaryEmail sends the weekly progress summary to a single user
+func (s *Service) SendWeeklySummaryEmail(ctx context.Context, data WeeklySummaryEmailData) error {
+	subject := "Ваша неделя в цифрах - BURCEV"
 
-	// Connect with TLS
-	conn, err := tls.Dial("tcp", addr, tlsConfig)
+	unsubscribeURL := s.buildUnsubscribeURL(data.UnsubscribeToken)
+	html, text, err := s.renderBoth("weekly_summary", weeklySummaryView{data, unsubscribeURL})
 	if err != nil {
-		return fmt.Errorf("failed to connect: %w", err)
+		s.log.WithError(err).Error("Failed to render weekly summary email template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
-	defer conn.Close()
 
-	// Create SMTP client
-	client, err := smtp.NewClient(conn, s.smtpHost)
+	// No retry here: the weekly summary job retries users whose send failed
+	err = s.sender.Send(ctx, Message{
+		To:             data.UserEmail,
+		Subject:        subject,
+		HTMLBody:       html,
+		TextBody:       text,
+		UnsubscribeURL: unsubscribeURL,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to create SMTP client: %w", err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
-	defer client.Close()
 
-	// Authenticate
-	if err := client.Auth(auth); err != nil {
-		return fmt.Errorf("authentication failed: %w", err)
-	}
+	return nil
+}
 
-	// Set sender
-	if err := client.Mail(s.fromAddress); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
-	}
+// SendOrganizationInviteEmail sends the set-password invitation to an imported member
+func (s *Service) SendOrganizationInviteEmail(ctx context.Context, data OrganizationInviteEmailData) error {
+	subject := "Приглашение в BURCEV"
 
-	// Set recipient
-	if err := client.Rcpt(to); err != nil {
-		return fmt.Errorf("failed to set recipient: %w", err)
+	html, text, err := s.renderBoth("organization_invite", data)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to render organization invite email template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	// Send message
-	w, err := client.Data()
+	// No retry here: the import reports unsent invitations per row
+	err = s.sender.Send(ctx, Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get data writer: %w", err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	_, err = w.Write(message)
+	return nil
+}
+
+// SendOrganizationJoinEmail asks an existing user to confirm joining an organization
+func (s *Service) SendOrganizationJoinEmail(ctx context.Context, data OrganizationJoinEmailData) error {
+	subject := "Подтвердите вступление в организацию - BURCEV"
+
+	html, text, err := s.renderBoth("organization_join", data)
 	if err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
+		s.log.WithError(err).Error("Failed to render organization join email template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	err = w.Close()
+	// No retry here: the import reports unsent requests per row
+	err = s.sender.Send(ctx, Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to close writer: %w", err)
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	return client.Quit()
+	return nil
 }
 
-// renderTemplate renders an email template with data
-func (s *Service) renderTemplate(templateName string, data interface{}) (string, error) {
-	var buf bytes.Buffer
-	err := s.templates.ExecuteTemplate(&buf, templateName, data)
+// SendDataExportEmail sends the download link of a finished data export with retry logic
+func (s *Service) SendDataExportEmail(ctx context.Context, data DataExportEmailData) error {
+	subject := "Ваши данные готовы к скачиванию - BURCEV"
+
+	html, text, err := s.renderBoth("data_export", data)
 	if err != nil {
-		return "", err
+		s.log.WithError(err).Error("Failed to render data export email template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
-	return buf.String(), nil
+
+	return s.sendWithRetry(ctx, "data export", Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
 }
 
-// parseTemplates parses email templates
-func parseTemplates() (*template.Template, error) {
-	tmpl := template.New("email")
+// SendEmailChangeConfirmEmail sends the confirmation link of an email change
+// to the new address with retry logic
+func (s *Service) SendEmailChangeConfirmEmail(ctx context.Context, data EmailChangeConfirmEmailData) error {
+	subject := "Подтвердите новый email - BURCEV"
 
-	// Password reset email template
-	_, err := tmpl.New("password_reset").Parse(passwordResetTemplate)
+	html, text, err := s.renderBoth("email_change_confirm", data)
 	if err != nil {
-		return nil, err
+		s.log.WithError(err).Error("Failed to render email change confirmation template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	// Password changed email template
-	_, err = tmpl.New("password_changed").Parse(passwordChangedTemplate)
+	return s.sendWithRetry(ctx, "email change confirmation", Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
+}
+
+// SendEmailChangeNoticeEmail tells the old address that an email change was
+// requested, with retry logic
+func (s *Service) SendEmailChangeNoticeEmail(ctx context.Context, data EmailChangeNoticeEmailData) error {
+	subject := "Запрошена смена email - BURCEV"
+
+	html, text, err := s.renderBoth("email_change_notice", data)
 	if err != nil {
-		return nil, err
+		s.log.WithError(err).Error("Failed to render email change notice template")
+		return fmt.Errorf("failed to render template: %w", err)
 	}
 
-	_, err = tmpl.New("email_verification").Parse(emailVerificationTemplate)
-	if err != nil {
-		return nil, err
+	return s.sendWithRetry(ctx, "email change notice", Message{
+		To:       data.UserEmail,
+		Subject:  subject,
+		HTMLBody: html,
+		TextBody: text,
+	})
+}
+
+// buildUnsubscribeURL returns the unsubscribe link for token, or "" when
+// either the token or the endpoint is not configured
+func (s *Service) buildUnsubscribeURL(token string) string {
+	if token == "" || s.unsubscribeURL == "" {
+		return ""
+	}
+	return s.unsubscribeURL + "?" + url.Values{"token": {token}}.Encode()
+}
+
+// localizedTemplate returns the variant of template name for locale, named
+// {name}.{locale}, or name itself when the template has no such variant
+func (s *Service) localizedTemplate(name string, locale i18n.Locale) string {
+	if locale == "" || locale == i18n.Default {
+		return name
 	}
+	variant := name + "." + string(locale)
+	if s.templates.Lookup(variant) == nil {
+		return name
+	}
+	return variant
+}
 
-	return tmpl, nil
+// renderBoth renders the HTML and the plain-text version of an email template with data
+func (s *Service) renderBoth(templateName string, data interface{}) (html, text string, err error) {
+	var htmlBuf, textBuf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&htmlBuf, templateName, data); err != nil {
+		return "", "", err
+	}
+	if err := s.textTemplates.ExecuteTemplate(&textBuf, templateName, data); err != nil {
+		return "", "", err
+	}
+	return htmlBuf.String(), textBuf.String(), nil
 }
 
 // Email templates
@@ -443,3 +683,559 @@ const emailVerificationTemplate = `
 </body>
 </html>
 `
+
+const curatorBroadcastTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Сообщение от куратора</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Сообщение от куратора</h2>
+
+        <p>Здравствуйте,</p>
+
+        <p>Ваш куратор <strong>{{.CuratorName}}</strong> отправил сообщение всем своим клиентам:</p>
+
+        <div style="background-color: #e9ecef; padding: 15px; border-radius: 5px; margin: 20px 0; white-space: pre-wrap;">{{.Message}}</div>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+        {{if .UnsubscribeURL}}
+        <p style="color: #999; font-size: 12px;">
+            Не хотите получать сообщения куратора по почте? <a href="{{.UnsubscribeURL}}" style="color: #999;">Отписаться</a>
+        </p>
+        {{end}}
+    </div>
+</body>
+</html>
+`
+
+const organizationInviteTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Приглашение в BURCEV</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Добро пожаловать в BURCEV</h2>
+
+        <p>Здравствуйте{{if .UserName}}, {{.UserName}}{{end}}!</p>
+
+        <p><strong>{{.OrganizationName}}</strong> создал для вас аккаунт BURCEV с адресом <strong>{{.UserEmail}}</strong>.</p>
+
+        <p>Чтобы начать пользоваться приложением, задайте пароль:</p>
+
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.SetPasswordURL}}" style="background-color: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">Задать пароль</a>
+        </div>
+
+        <p>Или скопируйте и вставьте эту ссылку в браузер:</p>
+        <p style="word-break: break-all; color: #007bff;">{{.SetPasswordURL}}</p>
+
+        <p><strong>Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}.</strong> Позже пароль можно задать через «Забыли пароль?».</p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const organizationJoinTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Вступление в организацию</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Вступление в организацию</h2>
+
+        <p>Здравствуйте,</p>
+
+        <p><strong>{{.OrganizationName}}</strong> добавил ваш аккаунт BURCEV <strong>{{.UserEmail}}</strong> в список участников.</p>
+
+        <p>Если вы согласны вступить в организацию и открыть ей доступ к своему аккаунту, подтвердите это:</p>
+
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.AcceptURL}}" style="background-color: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">Вступить</a>
+        </div>
+
+        <p><strong>Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}.</strong></p>
+
+        <p style="color: #666; font-size: 14px;">
+            Если вы не ожидали этого письма, просто проигнорируйте его — без подтверждения ничего не изменится.
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const dataExportTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Ваши данные готовы</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Ваши данные готовы</h2>
+
+        <p>Здравствуйте,</p>
+
+        <p>Мы подготовили архив со всеми данными вашего аккаунта BURCEV <strong>{{.UserEmail}}</strong>: профиль, дневник питания, замеры, сведения о фото и журнал действий.</p>
+
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DownloadURL}}" style="background-color: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">Скачать архив</a>
+        </div>
+
+        <p>Или скопируйте и вставьте эту ссылку в браузер:</p>
+        <p style="word-break: break-all; color: #007bff;">{{.DownloadURL}}</p>
+
+        <p><strong>Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}.</strong> Скачать архив можно, только войдя в свой аккаунт.</p>
+
+        <p style="color: #666; font-size: 14px;">
+            Если вы не запрашивали выгрузку данных, смените пароль.
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const emailChangeConfirmTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Подтвердите новый email</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Подтвердите новый email</h2>
+
+        <p>Здравствуйте,</p>
+
+        <p>В аккаунте BURCEV запрошена смена email на <strong>{{.UserEmail}}</strong>. Чтобы завершить смену, подтвердите адрес:</p>
+
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.ConfirmURL}}" style="background-color: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">Подтвердить email</a>
+        </div>
+
+        <p>Или скопируйте и вставьте эту ссылку в браузер:</p>
+        <p style="word-break: break-all; color: #007bff;">{{.ConfirmURL}}</p>
+
+        <p><strong>Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}.</strong> После подтверждения нужно будет войти заново на всех устройствах.</p>
+
+        <p style="color: #666; font-size: 14px;">
+            Если вы не запрашивали смену email, просто проигнорируйте это письмо.
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const emailChangeNoticeTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Запрошена смена email</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Запрошена смена email</h2>
+
+        <p>Здравствуйте,</p>
+
+        <p>{{.RequestedAt.Format "02.01.2006 в 15:04 MST"}} в аккаунте BURCEV <strong>{{.UserEmail}}</strong> запрошена смена email на <strong>{{.NewEmail}}</strong>{{if .IPAddress}} (IP-адрес: {{.IPAddress}}){{end}}.</p>
+
+        <p>Email сменится, только когда владелец нового адреса подтвердит его по ссылке из письма. До этого вы можете отменить смену в настройках профиля.</p>
+
+        <p style="color: #666; font-size: 14px;">
+            Если это были не вы, отмените смену, смените пароль и напишите нам: {{.SupportEmail}}
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const weeklySummaryTemplate = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Ваша неделя в цифрах</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Ваша неделя в цифрах</h2>
+
+        <p>Здравствуйте{{if .UserName}}, {{.UserName}}{{end}}!</p>
+
+        <p>Итоги недели {{shortDay .WeekStart}}–{{shortDay .WeekEnd}}:</p>
+
+        <table style="width: 100%; border-collapse: collapse; margin: 20px 0;">
+            <tr>
+                <td style="padding: 6px 0;">Дней с записями питания</td>
+                <td style="padding: 6px 0; text-align: right;"><strong>{{.LoggedDays}} из 7 ({{.AdherencePercent}}%)</strong></td>
+            </tr>
+            {{if .LoggedDays}}
+            <tr>
+                <td style="padding: 6px 0;">Средняя калорийность</td>
+                <td style="padding: 6px 0; text-align: right;"><strong>{{printf "%.0f" .AvgCalories}} ккал</strong>{{with .CalorieTarget}} (цель {{printf "%.0f" (deref .)}}){{end}}</td>
+            </tr>
+            <tr>
+                <td style="padding: 6px 0;">Белки / жиры / углеводы</td>
+                <td style="padding: 6px 0; text-align: right;"><strong>{{printf "%.0f" .AvgProtein}} / {{printf "%.0f" .AvgFat}} / {{printf "%.0f" .AvgCarbs}} г</strong></td>
+            </tr>
+            {{end}}
+            {{with .WeightChange}}
+            <tr>
+                <td style="padding: 6px 0;">Изменение веса</td>
+                <td style="padding: 6px 0; text-align: right;"><strong>{{printf "%+.1f" (deref .)}} кг</strong></td>
+            </tr>
+            {{end}}
+            {{with .CurrentWeight}}
+            <tr>
+                <td style="padding: 6px 0;">Текущий вес</td>
+                <td style="padding: 6px 0; text-align: right;"><strong>{{printf "%.1f" (deref .)}} кг</strong></td>
+            </tr>
+            {{end}}
+        </table>
+
+        {{if and .BestDay .WorstDay}}
+        <p>Лучший день: <strong>{{shortDay .BestDay.Date}}</strong> ({{printf "%.0f" .BestDay.Calories}} ккал).<br>
+        День, которому стоит уделить внимание: <strong>{{shortDay .WorstDay.Date}}</strong> ({{printf "%.0f" .WorstDay.Calories}} ккал).</p>
+        {{end}}
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+        </p>
+        {{if .UnsubscribeURL}}
+        <p style="color: #999; font-size: 12px;">
+            Не хотите получать еженедельные итоги? <a href="{{.UnsubscribeURL}}" style="color: #999;">Отписаться</a>
+        </p>
+        {{end}}
+    </div>
+</body>
+</html>
+`
+
+// Plain-text versions of the email templates, sent as the text/plain
+// alternative for spam filters and clients that do not render HTML
+const passwordResetTextTemplate = `Запрос на сброс пароля
+
+Здравствуйте,
+
+Мы получили запрос на сброс пароля для вашего аккаунта BURCEV, связанного с {{.UserEmail}}.
+
+Чтобы сбросить пароль, откройте ссылку:
+{{.ResetURL}}
+
+Срок действия ссылки истекает {{.ExpirationTime.Format "02.01.2006 в 15:04 MST"}}.
+
+Уведомление о безопасности: если вы не запрашивали сброс пароля, проигнорируйте это письмо. Ваш пароль останется без изменений. По вопросам безопасности свяжитесь с нами по адресу {{.SupportEmail}}.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const passwordChangedTextTemplate = `Пароль успешно изменен
+
+Здравствуйте,
+
+Это письмо подтверждает, что пароль для вашего аккаунта BURCEV {{.UserEmail}} был успешно изменен.
+
+Изменено: {{.ChangedAt.Format "02.01.2006 в 15:04 MST"}}
+IP адрес: {{.IPAddress}}
+
+Теперь вы можете использовать новый пароль для входа в аккаунт.
+
+Это были не вы? Если вы не меняли пароль, ваш аккаунт может быть скомпрометирован. Пожалуйста, немедленно свяжитесь с нами по адресу {{.SupportEmail}} и измените пароль как можно скорее.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const emailVerificationTextTemplate = `Код подтверждения
+
+Здравствуйте,
+
+Ваш код подтверждения для аккаунта BURCEV: {{.Code}}
+
+Код действителен в течение 10 минут.
+
+Если вы не запрашивали этот код, проигнорируйте это письмо.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const curatorBroadcastTextTemplate = `Сообщение от куратора
+
+Здравствуйте,
+
+Ваш куратор {{.CuratorName}} отправил сообщение всем своим клиентам:
+
+{{.Message}}
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+{{- if .UnsubscribeURL}}
+Не хотите получать сообщения куратора по почте? Отписаться: {{.UnsubscribeURL}}
+{{- end}}
+`
+
+const organizationInviteTextTemplate = `Добро пожаловать в BURCEV
+
+Здравствуйте{{if .UserName}}, {{.UserName}}{{end}}!
+
+{{.OrganizationName}} создал для вас аккаунт BURCEV с адресом {{.UserEmail}}.
+
+Чтобы начать пользоваться приложением, задайте пароль по ссылке:
+{{.SetPasswordURL}}
+
+Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}. Позже пароль можно задать через «Забыли пароль?».
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const organizationJoinTextTemplate = `Вступление в организацию
+
+Здравствуйте,
+
+{{.OrganizationName}} добавил ваш аккаунт BURCEV {{.UserEmail}} в список участников.
+
+Если вы согласны вступить в организацию и открыть ей доступ к своему аккаунту, подтвердите это по ссылке:
+{{.AcceptURL}}
+
+Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}.
+
+Если вы не ожидали этого письма, просто проигнорируйте его — без подтверждения ничего не изменится.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const dataExportTextTemplate = `Ваши данные готовы
+
+Здравствуйте,
+
+Мы подготовили архив со всеми данными вашего аккаунта BURCEV {{.UserEmail}}: профиль, дневник питания, замеры, сведения о фото и журнал действий.
+
+Скачать архив:
+{{.DownloadURL}}
+
+Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}. Скачать архив можно, только войдя в свой аккаунт.
+
+Если вы не запрашивали выгрузку данных, смените пароль.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const emailChangeConfirmTextTemplate = `Подтвердите новый email
+
+Здравствуйте,
+
+В аккаунте BURCEV запрошена смена email на {{.UserEmail}}. Чтобы завершить смену, подтвердите адрес по ссылке:
+{{.ConfirmURL}}
+
+Ссылка действительна до {{.ExpiresAt.Format "02.01.2006 в 15:04 MST"}}. После подтверждения нужно будет войти заново на всех устройствах.
+
+Если вы не запрашивали смену email, просто проигнорируйте это письмо.
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const emailChangeNoticeTextTemplate = `Запрошена смена email
+
+Здравствуйте,
+
+{{.RequestedAt.Format "02.01.2006 в 15:04 MST"}} в аккаунте BURCEV {{.UserEmail}} запрошена смена email на {{.NewEmail}}{{if .IPAddress}} (IP-адрес: {{.IPAddress}}){{end}}.
+
+Email сменится, только когда владелец нового адреса подтвердит его по ссылке из письма. До этого вы можете отменить смену в настройках профиля.
+
+Если это были не вы, отмените смену, смените пароль и напишите нам: {{.SupportEmail}}
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+`
+
+const weeklySummaryTextTemplate = `Ваша неделя в цифрах
+
+Здравствуйте{{if .UserName}}, {{.UserName}}{{end}}!
+
+Итоги недели {{shortDay .WeekStart}}–{{shortDay .WeekEnd}}:
+
+Дней с записями питания: {{.LoggedDays}} из 7 ({{.AdherencePercent}}%)
+{{- if .LoggedDays}}
+Средняя калорийность: {{printf "%.0f" .AvgCalories}} ккал{{with .CalorieTarget}} (цель {{printf "%.0f" (deref .)}}){{end}}
+Белки / жиры / углеводы: {{printf "%.0f" .AvgProtein}} / {{printf "%.0f" .AvgFat}} / {{printf "%.0f" .AvgCarbs}} г
+{{- end}}
+{{- with .WeightChange}}
+Изменение веса: {{printf "%+.1f" (deref .)}} кг
+{{- end}}
+{{- with .CurrentWeight}}
+Текущий вес: {{printf "%.1f" (deref .)}} кг
+{{- end}}
+{{- if and .BestDay .WorstDay}}
+
+Лучший день: {{shortDay .BestDay.Date}} ({{printf "%.0f" .BestDay.Calories}} ккал).
+День, которому стоит уделить внимание: {{shortDay .WorstDay.Date}} ({{printf "%.0f" .WorstDay.Calories}} ккал).
+{{- end}}
+
+--
+Это автоматическое сообщение от BURCEV. Пожалуйста, не отвечайте на это письмо.
+{{- if .UnsubscribeURL}}
+Не хотите получать еженедельные итоги? Отписаться: {{.UnsubscribeURL}}
+{{- end}}
+`
+
+// English variants, sent to recipients whose language is en
+const passwordResetTemplateEN = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Password reset request</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #2c3e50; margin-top: 0;">Password reset request</h2>
+
+        <p>Hello,</p>
+
+        <p>We received a request to reset the password of the BURCEV account associated with <strong>{{.UserEmail}}</strong>.</p>
+
+        <p>To reset your password, click the button below:</p>
+
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.ResetURL}}" style="background-color: #007bff; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; display: inline-block;">Reset password</a>
+        </div>
+
+        <p>Or copy and paste this link into your browser:</p>
+        <p style="word-break: break-all; color: #007bff;">{{.ResetURL}}</p>
+
+        <p><strong>The link expires on {{.ExpirationTime.Format "Jan 2, 2006 at 15:04 MST"}}.</strong></p>
+
+        <hr style="border: none; border-top: 1px solid #ddd; margin: 30px 0;">
+
+        <p style="color: #666; font-size: 14px;">
+            <strong>Security notice:</strong> If you did not request a password reset, ignore this email. Your password will stay the same. For security questions contact us at {{.SupportEmail}}.
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            This is an automated message from BURCEV. Please do not reply to this email.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const passwordChangedTemplateEN = `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Password changed</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333; max-width: 600px; margin: 0 auto; padding: 20px;">
+    <div style="background-color: #f8f9fa; padding: 20px; border-radius: 5px;">
+        <h2 style="color: #28a745; margin-top: 0;">✓ Your password has been changed</h2>
+
+        <p>Hello,</p>
+
+        <p>This email confirms that the password of your BURCEV account <strong>{{.UserEmail}}</strong> has been changed.</p>
+
+        <div style="background-color: #e9ecef; padding: 15px; border-radius: 5px; margin: 20px 0;">
+            <p style="margin: 5px 0;"><strong>Changed:</strong> {{.ChangedAt.Format "Jan 2, 2006 at 15:04 MST"}}</p>
+            <p style="margin: 5px 0;"><strong>IP address:</strong> {{.IPAddress}}</p>
+        </div>
+
+        <p>You can now sign in with your new password.</p>
+
+        <hr style="border: none; border-top: 1px solid #ddd; margin: 30px 0;">
+
+        <p style="color: #dc3545; font-size: 14px;">
+            <strong>⚠ Wasn't you?</strong><br>
+            If you did not change your password, your account may be compromised. Please contact us at {{.SupportEmail}} immediately and change your password as soon as possible.
+        </p>
+
+        <p style="color: #999; font-size: 12px; margin-top: 30px;">
+            This is an automated message from BURCEV. Please do not reply to this email.
+        </p>
+    </div>
+</body>
+</html>
+`
+
+const passwordResetTextTemplateEN = `Password reset request
+
+Hello,
+
+We received a request to reset the password of the BURCEV account associated with {{.UserEmail}}.
+
+To reset your password, open the link:
+{{.ResetURL}}
+
+The link expires on {{.ExpirationTime.Format "Jan 2, 2006 at 15:04 MST"}}.
+
+Security notice: if you did not request a password reset, ignore this email. Your password will stay the same. For security questions contact us at {{.SupportEmail}}.
+
+--
+This is an automated message from BURCEV. Please do not reply to this email.
+`
+
+const passwordChangedTextTemplateEN = `Your password has been changed
+
+Hello,
+
+This email confirms that the password of your BURCEV account {{.UserEmail}} has been changed.
+
+Changed: {{.ChangedAt.Format "Jan 2, 2006 at 15:04 MST"}}
+IP address: {{.IPAddress}}
+
+You can now sign in with your new password.
+
+Wasn't you? If you did not change your password, your account may be compromised. Please contact us at {{.SupportEmail}} immediately and change your password as soon as possible.
+
+--
+This is an automated message from BURCEV. Please do not reply to this email.
+`
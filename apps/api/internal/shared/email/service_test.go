@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -87,19 +88,133 @@ func TestNewService(t *testing.T) {
 			} else {
 				assert.NoError(t, err)
 				assert.NotNil(t, service)
-				assert.Equal(t, tt.config.SMTPHost, service.smtpHost)
-				assert.Equal(t, tt.config.SMTPPort, service.smtpPort)
-				assert.Equal(t, tt.config.SMTPUsername, service.smtpUsername)
+				smtpSender, ok := service.Sender().(*SMTPSender)
+				require.True(t, ok, "default driver should be smtp")
+				assert.Equal(t, tt.config.SMTPHost, smtpSender.smtpHost)
+				assert.Equal(t, tt.config.SMTPPort, smtpSender.smtpPort)
+				assert.Equal(t, tt.config.SMTPUsername, smtpSender.smtpUsername)
 				expectedFrom := tt.config.FromAddress
 				if expectedFrom == "" {
 					expectedFrom = tt.config.SMTPUsername
 				}
-				assert.Equal(t, expectedFrom, service.fromAddress)
+				assert.Equal(t, expectedFrom, smtpSender.fromAddress)
 			}
 		})
 	}
 }
 
+func TestNewService_Drivers(t *testing.T) {
+	log := logger.New()
+
+	t.Run("log driver does not require SMTP credentials", func(t *testing.T) {
+		service, err := NewService(Config{Driver: DriverLog}, log)
+		require.NoError(t, err)
+		assert.IsType(t, &LogSender{}, service.Sender())
+	})
+
+	t.Run("memory driver does not require SMTP credentials", func(t *testing.T) {
+		service, err := NewService(Config{Driver: DriverMemory}, log)
+		require.NoError(t, err)
+		assert.IsType(t, &MemorySender{}, service.Sender())
+	})
+
+	t.Run("explicit smtp driver still validates credentials", func(t *testing.T) {
+		service, err := NewService(Config{Driver: DriverSMTP}, log)
+		assert.Error(t, err)
+		assert.Nil(t, service)
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		service, err := NewService(Config{Driver: "sendgrid"}, log)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unknown email driver")
+		assert.Nil(t, service)
+	})
+}
+
+func TestSendEmails_MemorySender(t *testing.T) {
+	log := logger.New()
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, log)
+	require.NoError(t, err)
+
+	err = service.SendPasswordResetEmail(context.Background(), ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset-password?token=abc123",
+		ExpirationTime: time.Now().Add(time.Hour),
+		SupportEmail:   "support@burcev.team",
+	})
+	require.NoError(t, err)
+
+	err = service.SendVerificationEmail(context.Background(), VerificationEmailData{
+		UserEmail: "user@example.com",
+		Code:      "123456",
+		ExpiresAt: time.Now().Add(10 * time.Minute),
+	})
+	require.NoError(t, err)
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "user@example.com", messages[0].To)
+	assert.Equal(t, "Запрос на сброс пароля - BURCEV", messages[0].Subject)
+	assert.Contains(t, messages[0].HTMLBody, "https://burcev.team/reset-password?token=abc123")
+	assert.Contains(t, messages[1].HTMLBody, "123456")
+
+	sender.Reset()
+	assert.Empty(t, sender.Messages())
+}
+
+func TestSendEmails_Locale(t *testing.T) {
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+
+	for _, locale := range []i18n.Locale{i18n.EN, i18n.Locale("de"), ""} {
+		require.NoError(t, service.SendPasswordResetEmail(context.Background(), ResetEmailData{
+			UserEmail:      "user@example.com",
+			ResetURL:       "https://burcev.team/reset-password?token=abc123",
+			ExpirationTime: time.Now().Add(time.Hour),
+			Locale:         locale,
+		}))
+		require.NoError(t, service.SendPasswordChangedEmail(context.Background(), PasswordChangedEmailData{
+			UserEmail: "user@example.com",
+			ChangedAt: time.Now(),
+			Locale:    locale,
+		}))
+	}
+
+	messages := sender.Messages()
+	require.Len(t, messages, 6)
+	assert.Equal(t, "Password reset request - BURCEV", messages[0].Subject)
+	assert.Contains(t, messages[0].HTMLBody, "Reset password")
+	assert.Contains(t, messages[0].TextBody, "To reset your password")
+	assert.Equal(t, "Your password was changed - BURCEV", messages[1].Subject)
+	assert.Contains(t, messages[1].HTMLBody, "Your password has been changed")
+
+	// Locales without a variant get the Russian emails
+	for _, m := range messages[2:] {
+		assert.NotContains(t, m.HTMLBody, "Hello,")
+	}
+	assert.Equal(t, "Запрос на сброс пароля - BURCEV", messages[2].Subject)
+	assert.Equal(t, "Пароль изменен - BURCEV", messages[5].Subject)
+}
+
+func TestSendPasswordChangedEmail_SenderFailure(t *testing.T) {
+	log := logger.New()
+	sender := NewMemorySender()
+	sender.FailWith(assert.AnError)
+	service, err := NewServiceWithSender(sender, log)
+	require.NoError(t, err)
+
+	err = service.SendPasswordChangedEmail(context.Background(), PasswordChangedEmailData{
+		UserEmail: "user@example.com",
+		ChangedAt: time.Now(),
+	})
+
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Empty(t, sender.Messages())
+}
+
 func TestRenderTemplate(t *testing.T) {
 	log := logger.New()
 	config := Config{
@@ -122,7 +237,7 @@ func TestRenderTemplate(t *testing.T) {
 			SupportEmail:   "support@burcev.team",
 		}
 
-		body, err := service.renderTemplate("password_reset", data)
+		body, text, err := service.renderBoth("password_reset", data)
 
 		assert.NoError(t, err)
 		assert.Contains(t, body, "Запрос на сброс пароля")
@@ -130,6 +245,11 @@ func TestRenderTemplate(t *testing.T) {
 		assert.Contains(t, body, data.ResetURL)
 		assert.Contains(t, body, data.SupportEmail)
 		assert.Contains(t, body, "<!DOCTYPE html>")
+
+		assert.Contains(t, text, "Запрос на сброс пароля")
+		assert.Contains(t, text, data.ResetURL)
+		assert.Contains(t, text, data.SupportEmail)
+		assert.NotContains(t, text, "<")
 	})
 
 	t.Run("Render password changed template", func(t *testing.T) {
@@ -140,7 +260,7 @@ func TestRenderTemplate(t *testing.T) {
 			SupportEmail: "support@burcev.team",
 		}
 
-		body, err := service.renderTemplate("password_changed", data)
+		body, text, err := service.renderBoth("password_changed", data)
 
 		assert.NoError(t, err)
 		assert.Contains(t, body, "Пароль успешно изменен")
@@ -148,10 +268,14 @@ func TestRenderTemplate(t *testing.T) {
 		assert.Contains(t, body, data.IPAddress)
 		assert.Contains(t, body, data.SupportEmail)
 		assert.Contains(t, body, "<!DOCTYPE html>")
+
+		assert.Contains(t, text, "Пароль успешно изменен")
+		assert.Contains(t, text, data.IPAddress)
+		assert.NotContains(t, text, "<")
 	})
 
 	t.Run("Invalid template name", func(t *testing.T) {
-		_, err := service.renderTemplate("nonexistent", nil)
+		_, _, err := service.renderBoth("nonexistent", nil)
 		assert.Error(t, err)
 	})
 }
@@ -177,7 +301,7 @@ func TestPasswordResetEmailContent(t *testing.T) {
 		SupportEmail:   "support@burcev.team",
 	}
 
-	body, err := service.renderTemplate("password_reset", data)
+	body, _, err := service.renderBoth("password_reset", data)
 	require.NoError(t, err)
 
 	// Verify all required content is present
@@ -218,7 +342,7 @@ func TestPasswordChangedEmailContent(t *testing.T) {
 		SupportEmail: "support@burcev.team",
 	}
 
-	body, err := service.renderTemplate("password_changed", data)
+	body, _, err := service.renderBoth("password_changed", data)
 	require.NoError(t, err)
 
 	// Verify all required content is present
@@ -237,15 +361,64 @@ func TestPasswordChangedEmailContent(t *testing.T) {
 	}
 }
 
+func TestSendDataExportEmail(t *testing.T) {
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+
+	require.NoError(t, service.SendDataExportEmail(context.Background(), DataExportEmailData{
+		UserEmail:   "user@example.com",
+		DownloadURL: "https://burcev.team/data-export/abc?signature=def",
+		ExpiresAt:   time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+	}))
+
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, "user@example.com", messages[0].To)
+	assert.Contains(t, messages[0].HTMLBody, "https://burcev.team/data-export/abc?signature=def")
+	assert.Contains(t, messages[0].HTMLBody, "18.10.2026")
+}
+
+func TestSendEmailChangeEmails(t *testing.T) {
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+
+	require.NoError(t, service.SendEmailChangeConfirmEmail(context.Background(), EmailChangeConfirmEmailData{
+		UserEmail:  "new@example.com",
+		ConfirmURL: "https://burcev.team/api/v1/users/confirm-email?token=abc",
+		ExpiresAt:  time.Date(2026, 10, 16, 13, 0, 0, 0, time.UTC),
+	}))
+	require.NoError(t, service.SendEmailChangeNoticeEmail(context.Background(), EmailChangeNoticeEmailData{
+		UserEmail:    "old@example.com",
+		NewEmail:     "new@example.com",
+		RequestedAt:  time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		SupportEmail: "support@burcev.team",
+	}))
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "new@example.com", messages[0].To)
+	assert.Contains(t, messages[0].HTMLBody, "confirm-email?token=abc")
+	assert.Contains(t, messages[0].TextBody, "16.10.2026 в 13:00")
+	assert.Equal(t, "old@example.com", messages[1].To)
+	assert.Contains(t, messages[1].TextBody, "new@example.com")
+	assert.NotContains(t, messages[1].TextBody, "token=", "the old address never receives the link")
+	assert.NotContains(t, messages[1].TextBody, "IP-адрес")
+}
+
 func TestParseTemplates(t *testing.T) {
-	templates, err := parseTemplates()
+	templates, textTemplates, err := parseTemplates("")
 
 	assert.NoError(t, err)
-	assert.NotNil(t, templates)
+	require.NotNil(t, templates)
+	require.NotNil(t, textTemplates)
 
-	// Verify both templates are available
-	assert.NotNil(t, templates.Lookup("password_reset"))
-	assert.NotNil(t, templates.Lookup("password_changed"))
+	// Every template has an HTML and a text version
+	for _, name := range TemplateNames() {
+		assert.NotNil(t, templates.Lookup(name), name)
+		assert.NotNil(t, textTemplates.Lookup(name), name)
+	}
 }
 
 // Note: Actual SMTP sending tests are skipped as they require a real SMTP server
@@ -303,3 +476,119 @@ func TestSendPasswordChangedEmail_Integration(t *testing.T) {
 	err = service.SendPasswordChangedEmail(context.Background(), data)
 	assert.NoError(t, err)
 }
+
+func TestSendCuratorBroadcastEmail_UnsubscribeLink(t *testing.T) {
+	log := logger.New()
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, log)
+	require.NoError(t, err)
+	service.unsubscribeURL = "https://burcev.team/api/v1/notifications/unsubscribe"
+
+	data := CuratorBroadcastEmailData{
+		UserEmail:   "client@example.com",
+		CuratorName: "Coach",
+		Message:     "В отпуске до понедельника",
+	}
+
+	require.NoError(t, service.SendCuratorBroadcastEmail(context.Background(), data))
+
+	data.UnsubscribeToken = "abc123"
+	require.NoError(t, service.SendCuratorBroadcastEmail(context.Background(), data))
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+	assert.NotContains(t, messages[0].HTMLBody, "Отписаться")
+	assert.Contains(t, messages[1].HTMLBody, "https://burcev.team/api/v1/notifications/unsubscribe?token=abc123")
+}
+
+func TestSendWeeklySummaryEmail(t *testing.T) {
+	log := logger.New()
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, log)
+	require.NoError(t, err)
+	service.unsubscribeURL = "https://burcev.team/api/v1/notifications/unsubscribe"
+
+	target := 2000.0
+	change := -0.6
+	weight := 81.4
+	err = service.SendWeeklySummaryEmail(context.Background(), WeeklySummaryEmailData{
+		UserEmail:        "client@example.com",
+		UserName:         "Анна",
+		WeekStart:        time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC),
+		WeekEnd:          time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC),
+		LoggedDays:       5,
+		AdherencePercent: 71,
+		AvgCalories:      1950.4,
+		AvgProtein:       120,
+		AvgFat:           60,
+		AvgCarbs:         210,
+		CalorieTarget:    &target,
+		CurrentWeight:    &weight,
+		WeightChange:     &change,
+		BestDay:          &WeeklySummaryDay{Date: time.Date(2026, 10, 7, 0, 0, 0, 0, time.UTC), Calories: 2010},
+		WorstDay:         &WeeklySummaryDay{Date: time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC), Calories: 2900},
+		UnsubscribeToken: "tok",
+	})
+	require.NoError(t, err)
+
+	messages := sender.Messages()
+	require.Len(t, messages, 1)
+	body := messages[0].HTMLBody
+	assert.Equal(t, "Ваша неделя в цифрах - BURCEV", messages[0].Subject)
+	assert.Contains(t, body, "05.10–11.10")
+	assert.Contains(t, body, "5 из 7 (71%)")
+	assert.Contains(t, body, "1950 ккал")
+	assert.Contains(t, body, "цель 2000")
+	assert.Contains(t, body, "-0.6 кг")
+	assert.Contains(t, body, "07.10")
+	assert.Contains(t, body, "unsubscribe?token=tok")
+}
+
+func TestSendWeeklySummaryEmail_SparseData(t *testing.T) {
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+
+	err = service.SendWeeklySummaryEmail(context.Background(), WeeklySummaryEmailData{
+		UserEmail: "client@example.com",
+		WeekStart: time.Date(2026, 10, 5, 0, 0, 0, 0, time.UTC),
+		WeekEnd:   time.Date(2026, 10, 11, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	body := sender.Messages()[0].HTMLBody
+	assert.Contains(t, body, "0 из 7 (0%)")
+	assert.NotContains(t, body, "Изменение веса")
+	assert.NotContains(t, body, "Лучший день")
+	assert.NotContains(t, body, "Отписаться")
+}
+
+func TestSendOrganizationEmails(t *testing.T) {
+	sender := NewMemorySender()
+	service, err := NewServiceWithSender(sender, logger.New())
+	require.NoError(t, err)
+	expiresAt := time.Date(2026, 10, 23, 12, 0, 0, 0, time.UTC)
+
+	require.NoError(t, service.SendOrganizationInviteEmail(context.Background(), OrganizationInviteEmailData{
+		UserEmail:        "new@example.com",
+		UserName:         "Анна",
+		OrganizationName: "Iron Gym",
+		SetPasswordURL:   "https://burcev.team/reset-password?token=abc",
+		ExpiresAt:        expiresAt,
+	}))
+	require.NoError(t, service.SendOrganizationJoinEmail(context.Background(), OrganizationJoinEmailData{
+		UserEmail:        "old@example.com",
+		OrganizationName: "Iron Gym",
+		AcceptURL:        "https://burcev.team/api/v1/organizations/join?token=def",
+		ExpiresAt:        expiresAt,
+	}))
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, "new@example.com", messages[0].To)
+	assert.Contains(t, messages[0].HTMLBody, "Анна")
+	assert.Contains(t, messages[0].HTMLBody, "https://burcev.team/reset-password?token=abc")
+	assert.Contains(t, messages[0].HTMLBody, "23.10.2026")
+	assert.Equal(t, "old@example.com", messages[1].To)
+	assert.Contains(t, messages[1].HTMLBody, "https://burcev.team/api/v1/organizations/join?token=def")
+}
@@ -0,0 +1,397 @@
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+// Defaults of the SMTP connection pool
+const (
+	// DefaultSMTPPoolSize is how many connections may be open at once
+	DefaultSMTPPoolSize = 3
+	// DefaultSMTPIdleTimeout is how long an unused connection is kept
+	DefaultSMTPIdleTimeout = 30 * time.Second
+
+	// noopTimeout bounds the NOOP check of an idle connection and the QUIT
+	// of a closed one
+	noopTimeout = 5 * time.Second
+)
+
+// SMTPSender delivers email through an SMTP server. It keeps up to poolSize
+// authenticated connections and reuses them across sends, so bulk mailings
+// do not pay for a TLS and SMTP handshake per message.
+type SMTPSender struct {
+	smtpHost     string
+	smtpPort     int
+	smtpUsername string
+	smtpPassword string
+	fromAddress  string
+	fromName     string
+
+	idleTimeout time.Duration
+
+	// slots holds a token per connection in use, capping them at poolSize
+	slots chan struct{}
+
+	mu     sync.Mutex
+	idle   []*smtpConn // most recently used last
+	reaper *time.Timer // closes expired idle connections
+	closed bool        // after Close connections are not kept
+}
+
+// smtpConn is an open, authenticated SMTP session
+type smtpConn struct {
+	conn     net.Conn // the TCP connection; deadlines set here cover TLS too
+	client   *smtp.Client
+	lastUsed time.Time
+	broken   bool
+}
+
+// NewSMTPSender creates an SMTP sender; host, username and password are
+// required, the pool settings default to DefaultSMTPPoolSize and
+// DefaultSMTPIdleTimeout
+func NewSMTPSender(cfg Config) (*SMTPSender, error) {
+	if cfg.SMTPHost == "" {
+		return nil, fmt.Errorf("SMTP host is required")
+	}
+	if cfg.SMTPUsername == "" {
+		return nil, fmt.Errorf("SMTP username is required")
+	}
+	if cfg.SMTPPassword == "" {
+		return nil, fmt.Errorf("SMTP password is required")
+	}
+	if cfg.FromAddress == "" {
+		cfg.FromAddress = cfg.SMTPUsername
+	}
+	if cfg.SMTPPoolSize <= 0 {
+		cfg.SMTPPoolSize = DefaultSMTPPoolSize
+	}
+	if cfg.SMTPIdleTimeout <= 0 {
+		cfg.SMTPIdleTimeout = DefaultSMTPIdleTimeout
+	}
+
+	return &SMTPSender{
+		smtpHost:     cfg.SMTPHost,
+		smtpPort:     cfg.SMTPPort,
+		smtpUsername: cfg.SMTPUsername,
+		smtpPassword: cfg.SMTPPassword,
+		fromAddress:  cfg.FromAddress,
+		fromName:     cfg.FromName,
+		idleTimeout:  cfg.SMTPIdleTimeout,
+		slots:        make(chan struct{}, cfg.SMTPPoolSize),
+	}, nil
+}
+
+// Ping checks that the SMTP server accepts TCP connections
+func (s *SMTPSender) Ping(ctx context.Context) error {
+	addr := fmt.Sprintf("%s:%d", s.smtpHost, s.smtpPort)
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("smtp unreachable: %w", err)
+	}
+	return conn.Close()
+}
+
+// Send sends msg via SMTP as a multipart message with HTML and text parts
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	from := mail.Address{Name: s.fromName, Address: s.fromAddress}
+	message, err := buildMessage(from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = s.send(ctx, msg.To, message)
+	if ctxErr := contextErr(ctx); err != nil && ctxErr != nil {
+		// The connection was cut because ctx ended; report that instead
+		// of the resulting i/o error
+		return fmt.Errorf("smtp send aborted: %w", ctxErr)
+	}
+	return err
+}
+
+// send delivers message over a pooled connection, waiting for a free slot
+// when poolSize sends are already running
+func (s *SMTPSender) send(ctx context.Context, to string, message []byte) error {
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.slots }()
+
+	c, reused, err := s.take(ctx)
+	if err != nil {
+		return err
+	}
+	retry, err := s.deliver(ctx, c, to, message)
+	if retry && reused && contextErr(ctx) == nil {
+		// The server dropped the connection after the NOOP check and
+		// before taking the message: reconnect once
+		c.conn.Close()
+		if c, err = s.dial(ctx); err != nil {
+			return err
+		}
+		_, err = s.deliver(ctx, c, to, message)
+	}
+	s.release(c, err)
+	return err
+}
+
+// take returns an idle connection that still answers NOOP or, when there is
+// none, dials a new one. reused tells which it was.
+func (s *SMTPSender) take(ctx context.Context) (c *smtpConn, reused bool, err error) {
+	for {
+		s.mu.Lock()
+		n := len(s.idle)
+		if n == 0 {
+			s.mu.Unlock()
+			break
+		}
+		c = s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+
+		if time.Since(c.lastUsed) < s.idleTimeout && c.alive() {
+			return c, true, nil
+		}
+		c.conn.Close()
+	}
+
+	c, err = s.dial(ctx)
+	return c, false, err
+}
+
+// release returns c to the pool after a send that ended with err. A
+// connection that failed below the SMTP level is closed; after an SMTP
+// error reply the transaction is reset and the connection kept.
+func (s *SMTPSender) release(c *smtpConn, err error) {
+	healthy := err == nil && !c.broken
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) && !c.broken {
+		healthy = c.client.Reset() == nil
+	}
+	if !healthy {
+		c.conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		c.quit()
+		return
+	}
+	c.lastUsed = time.Now()
+	s.idle = append(s.idle, c)
+	if s.reaper == nil {
+		s.reaper = time.AfterFunc(s.idleTimeout, s.reapIdle)
+	}
+	s.mu.Unlock()
+}
+
+// reapIdle closes the connections idle for idleTimeout and reschedules
+// itself while others remain
+func (s *SMTPSender) reapIdle() {
+	s.mu.Lock()
+	var expired, kept []*smtpConn
+	for _, c := range s.idle {
+		if time.Since(c.lastUsed) >= s.idleTimeout {
+			expired = append(expired, c)
+		} else {
+			kept = append(kept, c)
+		}
+	}
+	s.idle = kept
+	s.reaper = nil
+	if len(kept) > 0 && !s.closed {
+		s.reaper = time.AfterFunc(time.Until(kept[0].lastUsed.Add(s.idleTimeout)), s.reapIdle)
+	}
+	s.mu.Unlock()
+
+	for _, c := range expired {
+		c.quit()
+	}
+}
+
+// Close says QUIT on the idle connections. Sends still running finish and
+// close their connections; later sends dial one connection each.
+func (s *SMTPSender) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	idle := s.idle
+	s.idle = nil
+	if s.reaper != nil {
+		s.reaper.Stop()
+		s.reaper = nil
+	}
+	s.mu.Unlock()
+
+	for _, c := range idle {
+		c.quit()
+	}
+	return nil
+}
+
+// contextErr is ctx.Err(), except that a passed deadline counts even if
+// ctx has not noticed yet: the connection deadline may fire first.
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// watch applies ctx to conn: its deadline becomes the connection deadline,
+// and cancellation moves the deadline to now, so a hanging server cannot
+// block the caller. The returned stop clears the deadline again and reports
+// false when ctx already cut the connection.
+func watch(ctx context.Context, conn net.Conn) (stop func() bool) {
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stopCut := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	return func() bool {
+		if !stopCut() {
+			return false
+		}
+		_ = conn.SetDeadline(time.Time{})
+		return true
+	}
+}
+
+// dial opens and authenticates a new connection
+func (s *SMTPSender) dial(ctx context.Context) (*smtpConn, error) {
+	addr := fmt.Sprintf("%s:%d", s.smtpHost, s.smtpPort)
+	tlsConfig := &tls.Config{
+		ServerName: s.smtpHost,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	stop := watch(ctx, conn)
+	client, err := s.handshake(ctx, conn, tlsConfig)
+	if !stop() && err == nil {
+		err = fmt.Errorf("failed to connect: %w", contextErr(ctx))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &smtpConn{conn: conn, client: client}, nil
+}
+
+// handshake runs the SMTP greeting, TLS and authentication on conn
+func (s *SMTPSender) handshake(ctx context.Context, conn net.Conn, tlsConfig *tls.Config) (*smtp.Client, error) {
+	// For port 465 (SSL/TLS), the whole session runs over TLS
+	var session net.Conn = conn
+	if s.smtpPort == 465 {
+		tlsConn := tls.Client(conn, tlsConfig)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		session = tlsConn
+	}
+
+	// Create SMTP client
+	client, err := smtp.NewClient(session, s.smtpHost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+
+	// For port 587, upgrade with STARTTLS when offered (as smtp.SendMail does)
+	if s.smtpPort != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return nil, fmt.Errorf("failed to start TLS: %w", err)
+			}
+		}
+	}
+
+	// Authenticate
+	if ok, _ := client.Extension("AUTH"); ok || s.smtpPort == 465 {
+		auth := smtp.PlainAuth("", s.smtpUsername, s.smtpPassword, s.smtpHost)
+		if err := client.Auth(auth); err != nil {
+			return nil, fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// deliver runs one mail transaction on c. retry reports that the
+// connection failed before the server took anything, so the message may be
+// sent again on a new one.
+func (s *SMTPSender) deliver(ctx context.Context, c *smtpConn, to string, message []byte) (retry bool, err error) {
+	stop := watch(ctx, c.conn)
+	defer func() {
+		if !stop() {
+			// ctx ended mid-transaction; the deadline is poisoned
+			c.broken = true
+		}
+	}()
+
+	// Set sender
+	if err := c.client.Mail(s.fromAddress); err != nil {
+		var protoErr *textproto.Error
+		return !errors.As(err, &protoErr), fmt.Errorf("failed to set sender: %w", err)
+	}
+
+	// Set recipient
+	if err := c.client.Rcpt(to); err != nil {
+		return false, fmt.Errorf("failed to set recipient: %w", err)
+	}
+
+	// Send message
+	w, err := c.client.Data()
+	if err != nil {
+		return false, fmt.Errorf("failed to get data writer: %w", err)
+	}
+
+	_, err = w.Write(message)
+	if err != nil {
+		return false, fmt.Errorf("failed to write message: %w", err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to close writer: %w", err)
+	}
+	return false, nil
+}
+
+// alive checks an idle connection with NOOP
+func (c *smtpConn) alive() bool {
+	_ = c.conn.SetDeadline(time.Now().Add(noopTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+	return c.client.Noop() == nil
+}
+
+// quit ends the session politely and closes the connection
+func (c *smtpConn) quit() {
+	_ = c.conn.SetDeadline(time.Now().Add(noopTimeout))
+	_ = c.client.Quit()
+	c.conn.Close()
+}
+
+// IsPermanent reports whether err is an SMTP 5xx reply (bad recipient,
+// rejected credentials, ...), which a retry cannot fix
+func IsPermanent(err error) bool {
+	var protoErr *textproto.Error
+	return errors.As(err, &protoErr) && protoErr.Code >= 500 && protoErr.Code < 600
+}
@@ -0,0 +1,310 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts connections on a local port and hands each one to serve
+func fakeSMTPServer(t *testing.T, serve func(conn net.Conn)) *SMTPSender {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				serve(conn)
+			}()
+		}
+	}()
+
+	sender, err := NewSMTPSender(Config{
+		SMTPHost:     "127.0.0.1",
+		SMTPPort:     ln.Addr().(*net.TCPAddr).Port,
+		SMTPUsername: "noreply",
+		SMTPPassword: "secret",
+	})
+	require.NoError(t, err)
+	return sender
+}
+
+// hang never sends the SMTP greeting
+func hang(conn net.Conn) {
+	_, _ = bufio.NewReader(conn).ReadString(0)
+}
+
+func TestSMTPSender_Send_HangingServer(t *testing.T) {
+	t.Run("returns when the context is cancelled", func(t *testing.T) {
+		sender := fakeSMTPServer(t, hang)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := sender.Send(ctx, Message{To: "user@example.com", Subject: "Тема", HTMLBody: "<p>Тело</p>"})
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("returns at the context deadline", func(t *testing.T) {
+		sender := fakeSMTPServer(t, hang)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := sender.Send(ctx, Message{To: "user@example.com", Subject: "Тема", HTMLBody: "<p>Тело</p>"})
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+}
+
+func TestSMTPSender_Send_PermanentError(t *testing.T) {
+	sender := fakeSMTPServer(t, func(conn net.Conn) {
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				_ = tp.PrintfLine("250 fake")
+			case strings.HasPrefix(line, "MAIL"):
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(line, "RCPT"):
+				_ = tp.PrintfLine("550 No such user")
+			default:
+				_ = tp.PrintfLine("221 Bye")
+				return
+			}
+		}
+	})
+
+	err := sender.Send(context.Background(), Message{To: "missing@example.com", Subject: "Тема", HTMLBody: "<p>Тело</p>"})
+
+	require.Error(t, err)
+	assert.True(t, IsPermanent(err))
+}
+
+// countingSender fails every Send with err and counts the attempts
+type countingSender struct {
+	err      error
+	attempts atomic.Int32
+}
+
+func (s *countingSender) Send(ctx context.Context, msg Message) error {
+	s.attempts.Add(1)
+	return s.err
+}
+
+func newRetryService(t *testing.T, sender Sender, delay time.Duration) *Service {
+	t.Helper()
+	svc, err := NewServiceWithSender(sender, logger.Nop())
+	require.NoError(t, err)
+	svc.retryDelay = delay
+	return svc
+}
+
+func TestSendPasswordResetEmail_Retries(t *testing.T) {
+	data := ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset?token=abc",
+		ExpirationTime: time.Now().Add(time.Hour),
+		SupportEmail:   "support@burcev.team",
+	}
+
+	t.Run("temporary errors use all attempts", func(t *testing.T) {
+		sender := &countingSender{err: &textproto.Error{Code: 421, Msg: "Service not available"}}
+		svc := newRetryService(t, sender, time.Millisecond)
+
+		err := svc.SendPasswordResetEmail(context.Background(), data)
+
+		assert.ErrorContains(t, err, "after 3 attempts")
+		assert.Equal(t, int32(maxSendAttempts), sender.attempts.Load())
+	})
+
+	t.Run("permanent error is not retried", func(t *testing.T) {
+		sender := &countingSender{err: &textproto.Error{Code: 550, Msg: "No such user"}}
+		svc := newRetryService(t, sender, time.Millisecond)
+
+		err := svc.SendPasswordResetEmail(context.Background(), data)
+
+		assert.True(t, IsPermanent(err))
+		assert.Equal(t, int32(1), sender.attempts.Load())
+	})
+
+	t.Run("cancellation during backoff stops retrying", func(t *testing.T) {
+		sender := &countingSender{err: errors.New("connection reset")}
+		svc := newRetryService(t, sender, time.Hour)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		start := time.Now()
+		err := svc.SendPasswordResetEmail(ctx, data)
+
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(1), sender.attempts.Load())
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("hanging server with cancelled context", func(t *testing.T) {
+		svc := newRetryService(t, fakeSMTPServer(t, hang), time.Hour)
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := svc.SendPasswordResetEmail(ctx, data)
+
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func TestIsPermanent(t *testing.T) {
+	assert.True(t, IsPermanent(&textproto.Error{Code: 535, Msg: "Authentication failed"}))
+	assert.False(t, IsPermanent(&textproto.Error{Code: 451, Msg: "Try again later"}))
+	assert.False(t, IsPermanent(errors.New("connection refused")))
+	assert.False(t, IsPermanent(nil))
+}
+
+// mailStats counts what a mailServer saw
+type mailStats struct {
+	conns    atomic.Int32
+	messages atomic.Int32
+	noops    atomic.Int32
+	quits    atomic.Int32
+
+	// dropAfterMessage closes the connection after each message without QUIT
+	dropAfterMessage bool
+}
+
+// mailServer accepts every message and counts connections, messages, NOOPs
+// and QUITs in stats
+func mailServer(stats *mailStats) func(conn net.Conn) {
+	return func(conn net.Conn) {
+		stats.conns.Add(1)
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 fake ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				_ = tp.PrintfLine("250 fake")
+			case strings.HasPrefix(line, "MAIL"), strings.HasPrefix(line, "RCPT"), strings.HasPrefix(line, "RSET"):
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(line, "NOOP"):
+				stats.noops.Add(1)
+				_ = tp.PrintfLine("250 OK")
+			case strings.HasPrefix(line, "DATA"):
+				_ = tp.PrintfLine("354 Go ahead")
+				if _, err := tp.ReadDotLines(); err != nil {
+					return
+				}
+				stats.messages.Add(1)
+				_ = tp.PrintfLine("250 Queued")
+				if stats.dropAfterMessage {
+					return
+				}
+			case strings.HasPrefix(line, "QUIT"):
+				stats.quits.Add(1)
+				_ = tp.PrintfLine("221 Bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 Not implemented")
+			}
+		}
+	}
+}
+
+func sendN(t *testing.T, sender *SMTPSender, n int) {
+	t.Helper()
+	for range n {
+		require.NoError(t, sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Итоги недели", HTMLBody: "<p>Тело</p>"}))
+	}
+}
+
+func TestSMTPSender_ReusesConnection(t *testing.T) {
+	stats := &mailStats{}
+	sender := fakeSMTPServer(t, mailServer(stats))
+
+	sendN(t, sender, 10)
+
+	assert.Equal(t, int32(1), stats.conns.Load())
+	assert.Equal(t, int32(10), stats.messages.Load())
+	// Every reuse is preceded by a keepalive check
+	assert.Equal(t, int32(9), stats.noops.Load())
+}
+
+func TestSMTPSender_ReconnectsDroppedConnection(t *testing.T) {
+	stats := &mailStats{dropAfterMessage: true}
+	sender := fakeSMTPServer(t, mailServer(stats))
+
+	sendN(t, sender, 3)
+
+	assert.Equal(t, int32(3), stats.conns.Load())
+	assert.Equal(t, int32(3), stats.messages.Load())
+}
+
+func TestSMTPSender_ClosesIdleConnections(t *testing.T) {
+	stats := &mailStats{}
+	sender := fakeSMTPServer(t, mailServer(stats))
+	sender.idleTimeout = 20 * time.Millisecond
+
+	sendN(t, sender, 1)
+	assert.Eventually(t, func() bool { return stats.quits.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	sendN(t, sender, 1)
+	assert.Equal(t, int32(2), stats.conns.Load())
+}
+
+func TestSMTPSender_ConcurrentSends(t *testing.T) {
+	stats := &mailStats{}
+	sender := fakeSMTPServer(t, mailServer(stats))
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Go(func() {
+			assert.NoError(t, sender.Send(context.Background(), Message{To: "user@example.com", Subject: "Тема", HTMLBody: "<p>Тело</p>"}))
+		})
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(20), stats.messages.Load())
+	assert.LessOrEqual(t, stats.conns.Load(), int32(DefaultSMTPPoolSize))
+}
+
+func TestSMTPSender_Close(t *testing.T) {
+	stats := &mailStats{}
+	sender := fakeSMTPServer(t, mailServer(stats))
+	sendN(t, sender, 2)
+
+	require.NoError(t, sender.Close(context.Background()))
+	assert.Eventually(t, func() bool { return stats.quits.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	// Sends after shutdown still work but keep no connection open
+	sendN(t, sender, 1)
+	assert.Eventually(t, func() bool { return stats.quits.Load() == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(2), stats.conns.Load())
+}
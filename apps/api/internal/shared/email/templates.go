@@ -0,0 +1,242 @@
+package email
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/burcev/api/internal/shared/i18n"
+)
+
+// ErrUnknownTemplate is returned when previewing a template that does not exist
+var ErrUnknownTemplate = errors.New("unknown email template")
+
+// Extensions of the override files in a template directory
+const (
+	htmlTemplateExt = ".html"
+	textTemplateExt = ".txt"
+)
+
+// emailTemplate is a built-in template with the sample data it is checked
+// and previewed with. Samples fill every optional field so that conditional
+// sections are rendered as well. Variants in other languages are named
+// {name}.{locale}; the plain name is the Russian default.
+type emailTemplate struct {
+	name   string
+	html   string
+	text   string
+	sample any
+}
+
+// sampleTime is the fixed moment sample data refers to
+var sampleTime = time.Date(2026, 1, 27, 15, 0, 0, 0, time.UTC)
+
+var emailTemplates = []emailTemplate{
+	{"password_reset", passwordResetTemplate, passwordResetTextTemplate, ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset-password?token=sample",
+		ExpirationTime: sampleTime.Add(time.Hour),
+		SupportEmail:   "support@burcev.team",
+	}},
+	{"password_changed", passwordChangedTemplate, passwordChangedTextTemplate, PasswordChangedEmailData{
+		UserEmail:    "user@example.com",
+		ChangedAt:    sampleTime,
+		IPAddress:    "203.0.113.10",
+		SupportEmail: "support@burcev.team",
+	}},
+	{"password_reset.en", passwordResetTemplateEN, passwordResetTextTemplateEN, ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset-password?token=sample",
+		ExpirationTime: sampleTime.Add(time.Hour),
+		SupportEmail:   "support@burcev.team",
+		Locale:         i18n.EN,
+	}},
+	{"password_changed.en", passwordChangedTemplateEN, passwordChangedTextTemplateEN, PasswordChangedEmailData{
+		UserEmail:    "user@example.com",
+		ChangedAt:    sampleTime,
+		IPAddress:    "203.0.113.10",
+		SupportEmail: "support@burcev.team",
+		Locale:       i18n.EN,
+	}},
+	{"email_verification", emailVerificationTemplate, emailVerificationTextTemplate, VerificationEmailData{
+		UserEmail: "user@example.com",
+		Code:      "123456",
+		ExpiresAt: sampleTime.Add(10 * time.Minute),
+	}},
+	{"curator_broadcast", curatorBroadcastTemplate, curatorBroadcastTextTemplate, curatorBroadcastView{
+		CuratorBroadcastEmailData: CuratorBroadcastEmailData{
+			UserEmail:        "user@example.com",
+			CuratorName:      "Мария Иванова",
+			Message:          "Напоминаю: в пятницу присылаем замеры и фото.",
+			UnsubscribeToken: "sample",
+		},
+		UnsubscribeURL: "https://burcev.team/api/v1/notifications/unsubscribe?token=sample",
+	}},
+	{"organization_invite", organizationInviteTemplate, organizationInviteTextTemplate, OrganizationInviteEmailData{
+		UserEmail:        "user@example.com",
+		UserName:         "Анна",
+		OrganizationName: "Фитнес-клуб «Пример»",
+		SetPasswordURL:   "https://burcev.team/reset-password?token=sample",
+		ExpiresAt:        sampleTime.Add(72 * time.Hour),
+	}},
+	{"organization_join", organizationJoinTemplate, organizationJoinTextTemplate, OrganizationJoinEmailData{
+		UserEmail:        "user@example.com",
+		OrganizationName: "Фитнес-клуб «Пример»",
+		AcceptURL:        "https://burcev.team/organizations/join?token=sample",
+		ExpiresAt:        sampleTime.Add(72 * time.Hour),
+	}},
+	{"data_export", dataExportTemplate, dataExportTextTemplate, DataExportEmailData{
+		UserEmail:   "user@example.com",
+		DownloadURL: "https://burcev.team/data-export/sample",
+		ExpiresAt:   sampleTime.Add(24 * time.Hour),
+	}},
+	{"email_change_confirm", emailChangeConfirmTemplate, emailChangeConfirmTextTemplate, EmailChangeConfirmEmailData{
+		UserEmail:  "new@example.com",
+		ConfirmURL: "https://burcev.team/api/v1/users/confirm-email?token=sample",
+		ExpiresAt:  sampleTime.Add(time.Hour),
+	}},
+	{"email_change_notice", emailChangeNoticeTemplate, emailChangeNoticeTextTemplate, EmailChangeNoticeEmailData{
+		UserEmail:    "user@example.com",
+		NewEmail:     "new@example.com",
+		RequestedAt:  sampleTime,
+		IPAddress:    "203.0.113.10",
+		SupportEmail: "support@burcev.team",
+	}},
+	{"weekly_summary", weeklySummaryTemplate, weeklySummaryTextTemplate, weeklySummaryView{
+		WeeklySummaryEmailData: WeeklySummaryEmailData{
+			UserEmail:        "user@example.com",
+			UserName:         "Анна",
+			WeekStart:        sampleTime.AddDate(0, 0, -7),
+			WeekEnd:          sampleTime.AddDate(0, 0, -1),
+			LoggedDays:       6,
+			AdherencePercent: 86,
+			AvgCalories:      1840,
+			AvgProtein:       110,
+			AvgFat:           60,
+			AvgCarbs:         190,
+			CalorieTarget:    floatPtr(1900),
+			CurrentWeight:    floatPtr(68.4),
+			WeightChange:     floatPtr(-0.6),
+			BestDay:          &WeeklySummaryDay{Date: sampleTime.AddDate(0, 0, -5), Calories: 1895},
+			WorstDay:         &WeeklySummaryDay{Date: sampleTime.AddDate(0, 0, -2), Calories: 2430},
+			UnsubscribeToken: "sample",
+		},
+		UnsubscribeURL: "https://burcev.team/api/v1/notifications/unsubscribe?token=sample",
+	}},
+}
+
+// templateFuncs are the helpers available to every template
+var templateFuncs = map[string]any{
+	"deref":    func(v *float64) float64 { return *v },
+	"shortDay": func(t time.Time) string { return t.Format("02.01") },
+}
+
+// parseTemplates parses the HTML and text email templates. Files named
+// {name}.html and {name}.txt in dir, when dir is set, replace the built-in
+// versions. Every template is rendered with its sample data, so an override
+// that does not parse or references a field its data does not have fails
+// here rather than when the email is sent.
+func parseTemplates(dir string) (*template.Template, *texttemplate.Template, error) {
+	overrides, err := readOverrides(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	htmlSet := template.New("email").Funcs(templateFuncs)
+	textSet := texttemplate.New("email").Funcs(templateFuncs)
+	for _, t := range emailTemplates {
+		html, text := t.html, t.text
+		if source, ok := overrides[t.name+htmlTemplateExt]; ok {
+			html = source
+		}
+		if source, ok := overrides[t.name+textTemplateExt]; ok {
+			text = source
+		}
+
+		if _, err := htmlSet.New(t.name).Parse(html); err != nil {
+			return nil, nil, fmt.Errorf("%s%s: %w", t.name, htmlTemplateExt, err)
+		}
+		if _, err := textSet.New(t.name).Parse(text); err != nil {
+			return nil, nil, fmt.Errorf("%s%s: %w", t.name, textTemplateExt, err)
+		}
+	}
+
+	for _, t := range emailTemplates {
+		if err := htmlSet.ExecuteTemplate(io.Discard, t.name, t.sample); err != nil {
+			return nil, nil, fmt.Errorf("%s%s: %w", t.name, htmlTemplateExt, err)
+		}
+		if err := textSet.ExecuteTemplate(io.Discard, t.name, t.sample); err != nil {
+			return nil, nil, fmt.Errorf("%s%s: %w", t.name, textTemplateExt, err)
+		}
+	}
+
+	return htmlSet, textSet, nil
+}
+
+// readOverrides returns the template files in dir by file name. Files with
+// a template extension must be named after a built-in template, so a typo
+// is not silently ignored; other files are skipped.
+func readOverrides(dir string) (map[string]string, error) {
+	if dir == "" {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	overrides := make(map[string]string)
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != htmlTemplateExt && ext != textTemplateExt) {
+			continue
+		}
+		if !isTemplateName(strings.TrimSuffix(entry.Name(), ext)) {
+			return nil, fmt.Errorf("%s does not match any email template", entry.Name())
+		}
+
+		source, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		overrides[entry.Name()] = string(source)
+	}
+	return overrides, nil
+}
+
+func floatPtr(v float64) *float64 { return &v }
+
+func isTemplateName(name string) bool {
+	for _, t := range emailTemplates {
+		if t.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TemplateNames returns the names of the email templates
+func TemplateNames() []string {
+	names := make([]string, len(emailTemplates))
+	for i, t := range emailTemplates {
+		names[i] = t.name
+	}
+	return names
+}
+
+// Preview renders both versions of the named template with its sample data.
+// It returns ErrUnknownTemplate when there is no such template.
+func (s *Service) Preview(name string) (html, text string, err error) {
+	for _, t := range emailTemplates {
+		if t.name == name {
+			return s.renderBoth(name, t.sample)
+		}
+	}
+	return "", "", ErrUnknownTemplate
+}
@@ -0,0 +1,125 @@
+package email
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// templateDir writes files (name to content) into a temporary directory
+func templateDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	return dir
+}
+
+func newTemplateService(dir string) (*Service, error) {
+	return NewService(Config{Driver: DriverMemory, TemplateDir: dir}, logger.Nop())
+}
+
+func TestTemplateDir_OverridesBuiltins(t *testing.T) {
+	dir := templateDir(t, map[string]string{
+		"password_reset.html": `<p>Свой бренд: <a href="{{.ResetURL}}">сбросить</a></p>`,
+		"data_export.txt":     "Архив: {{.DownloadURL}}\n",
+		"README.md":           "not a template",
+	})
+	svc, err := newTemplateService(dir)
+	require.NoError(t, err)
+	sender := svc.Sender().(*MemorySender)
+
+	require.NoError(t, svc.SendPasswordResetEmail(context.Background(), ResetEmailData{
+		UserEmail:      "user@example.com",
+		ResetURL:       "https://burcev.team/reset-password?token=abc",
+		ExpirationTime: time.Now().Add(time.Hour),
+	}))
+	require.NoError(t, svc.SendDataExportEmail(context.Background(), DataExportEmailData{
+		UserEmail:   "user@example.com",
+		DownloadURL: "https://burcev.team/data-export/abc",
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}))
+
+	messages := sender.Messages()
+	require.Len(t, messages, 2)
+
+	// Only the overridden version of a template changes
+	assert.Equal(t, `<p>Свой бренд: <a href="https://burcev.team/reset-password?token=abc">сбросить</a></p>`, messages[0].HTMLBody)
+	assert.Contains(t, messages[0].TextBody, "Чтобы сбросить пароль, откройте ссылку:")
+
+	assert.Contains(t, messages[1].HTMLBody, "<!DOCTYPE html>")
+	assert.Equal(t, "Архив: https://burcev.team/data-export/abc\n", messages[1].TextBody)
+
+	// Templates without overrides keep the built-in versions
+	html, text, err := svc.Preview("email_verification")
+	require.NoError(t, err)
+	assert.Contains(t, html, "Код подтверждения")
+	assert.Contains(t, text, "Ваш код подтверждения для аккаунта BURCEV: 123456")
+}
+
+func TestTemplateDir_FailsFast(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name:    "missing field",
+			files:   map[string]string{"password_changed.html": `<p>{{.UserEmail}} {{.NewPassword}}</p>`},
+			wantErr: []string{"password_changed.html", "NewPassword"},
+		},
+		{
+			name:    "missing field in a conditional section",
+			files:   map[string]string{"weekly_summary.txt": `{{with .BestDay}}{{.Protein}}{{end}}`},
+			wantErr: []string{"weekly_summary.txt", "Protein"},
+		},
+		{
+			name:    "syntax error",
+			files:   map[string]string{"data_export.txt": `{{.DownloadURL`},
+			wantErr: []string{"data_export.txt"},
+		},
+		{
+			name:    "unknown template",
+			files:   map[string]string{"pasword_reset.html": `<p>{{.ResetURL}}</p>`},
+			wantErr: []string{"pasword_reset.html does not match any email template"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTemplateService(templateDir(t, tt.files))
+
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+
+	t.Run("missing directory", func(t *testing.T) {
+		_, err := newTemplateService(filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+}
+
+func TestPreview(t *testing.T) {
+	svc, err := NewServiceWithSender(NewMemorySender(), logger.Nop())
+	require.NoError(t, err)
+
+	for _, name := range TemplateNames() {
+		html, text, err := svc.Preview(name)
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, html, name)
+		assert.NotEmpty(t, text, name)
+	}
+
+	_, _, err = svc.Preview("unknown")
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+}
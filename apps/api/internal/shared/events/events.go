@@ -0,0 +1,119 @@
+// Package events carries domain events: typed records of changes to a
+// user's data. Services publish them on a Bus inside the transaction of the
+// change. In-process subscribers, such as the nutrition rollups, run in
+// that transaction; the event is also appended to the Log, from which
+// consumers such as the webhooks module read it afterwards, at least once.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types
+const (
+	NutritionEntryCreated = "nutrition.entry.created"
+	NutritionEntryUpdated = "nutrition.entry.updated"
+	NutritionEntryDeleted = "nutrition.entry.deleted"
+	MeasurementCreated    = "measurement.created"
+)
+
+// Types lists the event types subscribers can ask for
+var Types = []string{NutritionEntryCreated, NutritionEntryUpdated, NutritionEntryDeleted, MeasurementCreated}
+
+// Payload is the typed data of an event. The publishing module defines it;
+// EventType names the event it is the data of.
+type Payload interface {
+	EventType() string
+}
+
+// Event is something that happened to a user's data. Data is serialized to
+// JSON for the log and external consumers. Subscribers get the published
+// Payload; consumers, which read the event back from the log, get its JSON
+// as a json.RawMessage.
+type Event struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	UserID     int64       `json:"user_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Handler is an in-process subscriber. It runs in tx, the transaction of
+// the change that raised the event, and an error fails the change. tx is
+// nil for events published without a transaction.
+type Handler func(ctx context.Context, tx *sql.Tx, event Event) error
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// Bus delivers published events to its subscribers and appends them to its
+// log. A nil *Bus is valid and drops all events, so services can be built
+// without one.
+type Bus struct {
+	log *Log
+
+	mu          sync.RWMutex
+	subscribers []subscriber
+}
+
+// NewBus creates a new event bus without subscribers. Events are appended
+// to log; a nil log keeps them in-process.
+func NewBus(log *Log) *Bus {
+	return &Bus{log: log}
+}
+
+// Subscribe registers h under name for all events published after the
+// call. Subscribing a name again replaces its handler in place.
+func (b *Bus) Subscribe(name string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.subscribers {
+		if b.subscribers[i].name == name {
+			b.subscribers[i].handler = h
+			return
+		}
+	}
+	b.subscribers = append(b.subscribers, subscriber{name: name, handler: h})
+}
+
+// Publish stamps the event with an id and time, hands it to every
+// subscriber in subscription order and appends it to the log, all in tx.
+// The first failure is returned and the change's transaction should roll
+// back. A nil tx appends the event on its own, after a change stored in a
+// single statement.
+func (b *Bus) Publish(ctx context.Context, tx *sql.Tx, userID int64, data Payload) error {
+	if b == nil {
+		return nil
+	}
+
+	event := Event{
+		ID:         uuid.NewString(),
+		Type:       data.EventType(),
+		UserID:     userID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	}
+
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+
+	for _, s := range subscribers {
+		if err := s.handler(ctx, tx, event); err != nil {
+			return fmt.Errorf("%s failed on %s: %w", s.name, event.Type, err)
+		}
+	}
+
+	if b.log == nil {
+		return nil
+	}
+	return b.log.Append(ctx, tx, event)
+}
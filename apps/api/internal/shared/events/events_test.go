@@ -0,0 +1,314 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// weighed is a test payload
+type weighed struct {
+	Value float64 `json:"value"`
+}
+
+func (weighed) EventType() string { return MeasurementCreated }
+
+var (
+	testNow      = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	eventColumns = []string{"xid", "seq", "id", "type", "user_id", "occurred_at", "data"}
+)
+
+func setupLog(t *testing.T) (*Log, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	return NewLog(&database.DB{DB: db}, logger.New()), mock
+}
+
+// eventRows returns logged events at positions (xid, seq) with ids e<seq>
+func eventRows(positions ...Cursor) *sqlmock.Rows {
+	rows := sqlmock.NewRows(eventColumns)
+	for _, p := range positions {
+		rows.AddRow(p.XID, p.Seq, eventID(p.Seq), NutritionEntryCreated, int64(5), testNow, []byte(`{"calories":300}`))
+	}
+	return rows
+}
+
+func eventID(seq int64) string {
+	return fmt.Sprintf("e%d", seq)
+}
+
+// recorder is a consumer that records the ids it was handed and fails on
+// the ids in failOn
+type recorder struct {
+	ids    []string
+	failOn map[string]bool
+}
+
+func (r *recorder) consume(ctx context.Context, event Event) error {
+	r.ids = append(r.ids, event.ID)
+	if r.failOn[event.ID] {
+		return errors.New("downstream unavailable")
+	}
+	return nil
+}
+
+func expectCursor(mock sqlmock.Sqlmock, name string, c *Cursor) {
+	q := mock.ExpectQuery(`SELECT xid, seq FROM event_consumers WHERE consumer = \$1`).WithArgs(name)
+	if c == nil {
+		q.WillReturnError(sql.ErrNoRows)
+		return
+	}
+	q.WillReturnRows(sqlmock.NewRows([]string{"xid", "seq"}).AddRow(c.XID, c.Seq))
+}
+
+func TestBus_Publish(t *testing.T) {
+	t.Run("subscribers in order", func(t *testing.T) {
+		bus := NewBus(nil)
+		var order []string
+		var received []Event
+		bus.Subscribe("first", func(ctx context.Context, tx *sql.Tx, e Event) error {
+			order = append(order, "first")
+			received = append(received, e)
+			return nil
+		})
+		bus.Subscribe("second", func(ctx context.Context, tx *sql.Tx, e Event) error {
+			order = append(order, "second")
+			return nil
+		})
+
+		err := bus.Publish(context.Background(), nil, 5, weighed{Value: 81.5})
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{"first", "second"}, order)
+		require.Len(t, received, 1)
+		assert.Equal(t, MeasurementCreated, received[0].Type)
+		assert.Equal(t, int64(5), received[0].UserID)
+		assert.Equal(t, weighed{Value: 81.5}, received[0].Data)
+		assert.NotEmpty(t, received[0].ID)
+		assert.False(t, received[0].OccurredAt.IsZero())
+	})
+
+	t.Run("subscribing a name again replaces it", func(t *testing.T) {
+		bus := NewBus(nil)
+		var calls []string
+		bus.Subscribe("rollups", func(context.Context, *sql.Tx, Event) error { calls = append(calls, "old"); return nil })
+		bus.Subscribe("rollups", func(context.Context, *sql.Tx, Event) error { calls = append(calls, "new"); return nil })
+
+		require.NoError(t, bus.Publish(context.Background(), nil, 5, weighed{}))
+
+		assert.Equal(t, []string{"new"}, calls)
+	})
+
+	t.Run("a failing subscriber fails the publish", func(t *testing.T) {
+		log, mock := setupLog(t)
+		bus := NewBus(log)
+		called := false
+		bus.Subscribe("failing", func(context.Context, *sql.Tx, Event) error { return assert.AnError })
+		bus.Subscribe("after", func(context.Context, *sql.Tx, Event) error { called = true; return nil })
+
+		err := bus.Publish(context.Background(), nil, 5, weighed{})
+
+		assert.ErrorIs(t, err, assert.AnError)
+		assert.False(t, called)
+		// Nothing is appended for a change that rolls back
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("appends to the log in the transaction", func(t *testing.T) {
+		log, mock := setupLog(t)
+		bus := NewBus(log)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO domain_events").
+			WithArgs(sqlmock.AnyArg(), MeasurementCreated, int64(5), sqlmock.AnyArg(), []byte(`{"value":81.5}`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := database.WithTx(context.Background(), log.db, func(tx *sql.Tx) error {
+			return bus.Publish(context.Background(), tx, 5, weighed{Value: 81.5})
+		})
+
+		require.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("nil bus drops events", func(t *testing.T) {
+		var bus *Bus
+
+		assert.NoError(t, bus.Publish(context.Background(), nil, 5, weighed{}))
+	})
+}
+
+func TestLog_Process(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new consumer starts at the beginning", func(t *testing.T) {
+		log, mock := setupLog(t)
+		rec := &recorder{}
+		log.Register("webhooks", rec.consume)
+		expectCursor(mock, "webhooks", nil)
+		mock.ExpectQuery(`FROM domain_events\s+WHERE \(xid, seq\) > \(\$1, \$2\) AND occurred_at >= \$3\s+AND xid < pg_snapshot_xmin`).
+			WithArgs(int64(0), int64(0), time.Time{}, defaultBatchSize).
+			WillReturnRows(eventRows(Cursor{100, 2}, Cursor{101, 1}))
+		mock.ExpectExec("INSERT INTO event_consumers").
+			WithArgs("webhooks", int64(101), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		handled, err := log.Process(ctx, "webhooks")
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, handled)
+		assert.Equal(t, []string{"e2", "e1"}, rec.ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("reads after the saved cursor", func(t *testing.T) {
+		log, mock := setupLog(t)
+		rec := &recorder{}
+		log.Register("webhooks", rec.consume)
+		expectCursor(mock, "webhooks", &Cursor{101, 1})
+		mock.ExpectQuery("FROM domain_events").
+			WithArgs(int64(101), int64(1), time.Time{}, defaultBatchSize).
+			WillReturnRows(eventRows())
+
+		handled, err := log.Process(ctx, "webhooks")
+
+		require.NoError(t, err)
+		assert.Zero(t, handled)
+		// Nothing handled, nothing saved
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("a failure keeps the cursor before the failed event", func(t *testing.T) {
+		log, mock := setupLog(t)
+		rec := &recorder{failOn: map[string]bool{"e2": true}}
+		log.Register("webhooks", rec.consume)
+		expectCursor(mock, "webhooks", nil)
+		mock.ExpectQuery("FROM domain_events").
+			WillReturnRows(eventRows(Cursor{100, 1}, Cursor{100, 2}, Cursor{100, 3}))
+		mock.ExpectExec("INSERT INTO event_consumers").
+			WithArgs("webhooks", int64(100), int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		handled, err := log.Process(ctx, "webhooks")
+
+		assert.ErrorContains(t, err, "webhooks failed on event e2")
+		assert.Equal(t, 1, handled)
+		assert.Equal(t, []string{"e1", "e2"}, rec.ids, "events after the failed one wait")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown consumer", func(t *testing.T) {
+		log, mock := setupLog(t)
+
+		_, err := log.Process(ctx, "search-index")
+
+		assert.ErrorIs(t, err, ErrUnknownConsumer)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// TestLog_AtLeastOnce shows why consumers must be idempotent: a batch whose
+// cursor could not be saved is handed again in full
+func TestLog_AtLeastOnce(t *testing.T) {
+	log, mock := setupLog(t)
+	seen := map[string]int{}
+	log.Register("webhooks", func(ctx context.Context, e Event) error {
+		seen[e.ID]++
+		return nil
+	})
+
+	expectCursor(mock, "webhooks", &Cursor{99, 9})
+	mock.ExpectQuery("FROM domain_events").WillReturnRows(eventRows(Cursor{100, 1}, Cursor{100, 2}))
+	mock.ExpectExec("INSERT INTO event_consumers").WillReturnError(errors.New("connection reset"))
+
+	expectCursor(mock, "webhooks", &Cursor{99, 9})
+	mock.ExpectQuery("FROM domain_events").
+		WithArgs(int64(99), int64(9), time.Time{}, defaultBatchSize).
+		WillReturnRows(eventRows(Cursor{100, 1}, Cursor{100, 2}))
+	mock.ExpectExec("INSERT INTO event_consumers").
+		WithArgs("webhooks", int64(100), int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := log.Process(context.Background(), "webhooks")
+	require.Error(t, err)
+	handled, err := log.Process(context.Background(), "webhooks")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, handled)
+	assert.Equal(t, map[string]int{"e1": 2, "e2": 2}, seen)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLog_Replay(t *testing.T) {
+	ctx := context.Background()
+	since := testNow.AddDate(0, 0, -7)
+
+	t.Run("pages through the log without moving the cursor", func(t *testing.T) {
+		log, mock := setupLog(t)
+		log.batchSize = 2
+		rec := &recorder{}
+		log.Register("webhooks", rec.consume)
+		mock.ExpectQuery("FROM domain_events").
+			WithArgs(int64(0), int64(0), since, 2).
+			WillReturnRows(eventRows(Cursor{100, 1}, Cursor{100, 2}))
+		mock.ExpectQuery("FROM domain_events").
+			WithArgs(int64(100), int64(2), since, 2).
+			WillReturnRows(eventRows(Cursor{102, 3}))
+
+		replayed, err := log.Replay(ctx, "webhooks", since)
+
+		require.NoError(t, err)
+		assert.Equal(t, 3, replayed)
+		assert.Equal(t, []string{"e1", "e2", "e3"}, rec.ids)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("decoded data is the logged JSON", func(t *testing.T) {
+		log, mock := setupLog(t)
+		var data []json.RawMessage
+		log.Register("webhooks", func(ctx context.Context, e Event) error {
+			data = append(data, e.Data.(json.RawMessage))
+			return nil
+		})
+		mock.ExpectQuery("FROM domain_events").WillReturnRows(eventRows(Cursor{100, 1}))
+
+		_, err := log.Replay(ctx, "webhooks", time.Time{})
+
+		require.NoError(t, err)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`{"calories":300}`)}, data)
+	})
+
+	t.Run("stops at a failure", func(t *testing.T) {
+		log, mock := setupLog(t)
+		rec := &recorder{failOn: map[string]bool{"e2": true}}
+		log.Register("webhooks", rec.consume)
+		mock.ExpectQuery("FROM domain_events").WillReturnRows(eventRows(Cursor{100, 1}, Cursor{100, 2}, Cursor{100, 3}))
+
+		replayed, err := log.Replay(ctx, "webhooks", since)
+
+		assert.Error(t, err)
+		assert.Equal(t, 1, replayed)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown consumer", func(t *testing.T) {
+		log, _ := setupLog(t)
+
+		_, err := log.Replay(ctx, "webhooks", since)
+
+		assert.ErrorIs(t, err, ErrUnknownConsumer)
+	})
+}
@@ -0,0 +1,293 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+const (
+	// defaultBatchSize is how many events a consumer is handed per batch
+	defaultBatchSize = 100
+	// workerInterval is how often the worker feeds consumers new events
+	workerInterval = 5 * time.Second
+)
+
+// ErrUnknownConsumer is returned for a consumer name nothing registered
+var ErrUnknownConsumer = errors.New("unknown event consumer")
+
+// Consumer handles events read back from the log. Delivery is at least
+// once: an event is handed again after a failure or crash before the
+// consumer's cursor moved past it, and replays hand out old events, so a
+// consumer must be idempotent, keyed by Event.ID.
+type Consumer func(ctx context.Context, event Event) error
+
+// Cursor is a consumer's position in the log. Events are ordered by the
+// transaction that wrote them, then by sequence number; a consumer has
+// handled every event up to and including its cursor.
+type Cursor struct {
+	XID int64
+	Seq int64
+}
+
+// logged is an event read back from the log with its position
+type logged struct {
+	Event
+	cursor Cursor
+}
+
+// Log is the append-only table of domain events and the consumers that
+// read it. Consumers are registered at startup; each keeps its own cursor.
+type Log struct {
+	db        *database.DB
+	log       *logger.Logger
+	batchSize int
+
+	mu        sync.RWMutex
+	names     []string
+	consumers map[string]Consumer
+}
+
+// NewLog creates a new event log without consumers
+func NewLog(db *database.DB, log *logger.Logger) *Log {
+	return &Log{
+		db:        db,
+		log:       log,
+		batchSize: defaultBatchSize,
+		consumers: make(map[string]Consumer),
+	}
+}
+
+// Register adds a consumer under name. Its cursor is kept by name, so a
+// renamed consumer starts over from the beginning of the log.
+func (l *Log) Register(name string, c Consumer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.consumers[name]; !ok {
+		l.names = append(l.names, name)
+	}
+	l.consumers[name] = c
+}
+
+func (l *Log) consumer(name string) (Consumer, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	c, ok := l.consumers[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownConsumer, name)
+	}
+	return c, nil
+}
+
+// Append writes event to the log in tx, or on its own when tx is nil
+func (l *Log) Append(ctx context.Context, tx *sql.Tx, event Event) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	startTime := time.Now()
+	query := `
+		INSERT INTO domain_events (id, type, user_id, occurred_at, data)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	args := []any{event.ID, event.Type, event.UserID, event.OccurredAt, data}
+	if tx != nil {
+		_, err = tx.ExecContext(ctx, query, args...)
+	} else {
+		_, err = l.db.ExecContext(ctx, query, args...)
+	}
+	l.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id":    event.UserID,
+		"event_type": event.Type,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	return nil
+}
+
+// read returns up to batchSize events after the cursor that occurred at or
+// after since, in log order. Only events whose xid is below that of every
+// transaction still running are read: a running transaction may yet commit
+// events, and they must not land behind a cursor that already moved on.
+func (l *Log) read(ctx context.Context, after Cursor, since time.Time) ([]logged, error) {
+	startTime := time.Now()
+	query := `
+		SELECT xid, seq, id, type, user_id, occurred_at, data
+		FROM domain_events
+		WHERE (xid, seq) > ($1, $2) AND occurred_at >= $3
+		  AND xid < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+		ORDER BY xid, seq
+		LIMIT $4`
+
+	rows, err := l.db.QueryContext(ctx, query, after.XID, after.Seq, since, l.batchSize)
+	l.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	defer rows.Close()
+
+	var batch []logged
+	for rows.Next() {
+		var e logged
+		var data []byte
+		if err := rows.Scan(&e.cursor.XID, &e.cursor.Seq, &e.ID, &e.Type, &e.UserID, &e.OccurredAt, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		e.Data = json.RawMessage(data)
+		batch = append(batch, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+	return batch, nil
+}
+
+// Cursor returns the position of the named consumer; a consumer that has
+// not handled any event is at the zero Cursor
+func (l *Log) Cursor(ctx context.Context, name string) (Cursor, error) {
+	var c Cursor
+	err := l.db.QueryRowContext(ctx,
+		`SELECT xid, seq FROM event_consumers WHERE consumer = $1`, name,
+	).Scan(&c.XID, &c.Seq)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return Cursor{}, fmt.Errorf("failed to get cursor of %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// saveCursor moves the named consumer to c. A cursor only moves forward,
+// so a worker that fell behind another cannot move it back.
+func (l *Log) saveCursor(ctx context.Context, name string, c Cursor) error {
+	startTime := time.Now()
+	query := `
+		INSERT INTO event_consumers AS ec (consumer, xid, seq)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (consumer) DO UPDATE SET
+			xid = EXCLUDED.xid,
+			seq = EXCLUDED.seq,
+			updated_at = NOW()
+		WHERE (ec.xid, ec.seq) < (EXCLUDED.xid, EXCLUDED.seq)`
+
+	_, err := l.db.ExecContext(ctx, query, name, c.XID, c.Seq)
+	l.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{"consumer": name})
+	if err != nil {
+		return fmt.Errorf("failed to save cursor of %s: %w", name, err)
+	}
+	return nil
+}
+
+// Process hands the named consumer the next batch of events after its
+// cursor, in log order, and moves the cursor past the ones it handled. It
+// stops at the first event the consumer fails on; that event is handed
+// again by the next call. Returns the number of events handled.
+func (l *Log) Process(ctx context.Context, name string) (int, error) {
+	consume, err := l.consumer(name)
+	if err != nil {
+		return 0, err
+	}
+	cursor, err := l.Cursor(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	batch, err := l.read(ctx, cursor, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	handled := 0
+	var consumeErr error
+	for _, e := range batch {
+		if err := consume(ctx, e.Event); err != nil {
+			consumeErr = fmt.Errorf("%s failed on event %s: %w", name, e.ID, err)
+			break
+		}
+		cursor = e.cursor
+		handled++
+	}
+
+	if handled > 0 {
+		if err := l.saveCursor(ctx, name, cursor); err != nil {
+			return handled, err
+		}
+	}
+	return handled, consumeErr
+}
+
+// Replay hands the named consumer every event that occurred at or after
+// since, in log order, to rebuild what it derives from them. The consumer's
+// cursor is left as it is. It stops at the first event the consumer fails
+// on and returns the number of events replayed.
+func (l *Log) Replay(ctx context.Context, name string, since time.Time) (int, error) {
+	consume, err := l.consumer(name)
+	if err != nil {
+		return 0, err
+	}
+
+	var after Cursor
+	replayed := 0
+	for {
+		batch, err := l.read(ctx, after, since)
+		if err != nil {
+			return replayed, err
+		}
+		for _, e := range batch {
+			if err := consume(ctx, e.Event); err != nil {
+				return replayed, fmt.Errorf("%s failed on event %s: %w", name, e.ID, err)
+			}
+			after = e.cursor
+			replayed++
+		}
+		if len(batch) < l.batchSize || ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+	}
+}
+
+// RunWorker starts the consumer loop. Every tick it drains each consumer's
+// new events batch by batch. It blocks until the provided context is
+// cancelled.
+func (l *Log) RunWorker(ctx context.Context) {
+	ticker := time.NewTicker(workerInterval)
+	defer ticker.Stop()
+
+	l.log.Info("Event log worker started")
+
+	for {
+		select {
+		case <-ticker.C:
+			l.mu.RLock()
+			names := l.names
+			l.mu.RUnlock()
+
+			for _, name := range names {
+				l.drain(ctx, name)
+			}
+		case <-ctx.Done():
+			l.log.Info("Event log worker stopped")
+			return
+		}
+	}
+}
+
+// drain processes the named consumer's batches until it is caught up
+func (l *Log) drain(ctx context.Context, name string) {
+	for {
+		handled, err := l.Process(ctx, name)
+		if err != nil {
+			l.log.Error("Failed to process events", "consumer", name, "error", err)
+			return
+		}
+		if handled < l.batchSize || ctx.Err() != nil {
+			return
+		}
+	}
+}
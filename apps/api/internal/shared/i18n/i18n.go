@@ -0,0 +1,117 @@
+// Package i18n translates user-facing messages. Messages are identified by
+// stable keys and looked up in a catalog per locale; Russian is the default
+// and the fallback for anything missing in another catalog.
+package i18n
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Locale is a supported language
+type Locale string
+
+// Supported locales
+const (
+	RU Locale = "ru"
+	EN Locale = "en"
+)
+
+// Default is used when no supported locale was requested
+const Default = RU
+
+// Key identifies a translatable message
+type Key string
+
+// Message is a key with the arguments its translation is formatted with
+type Message struct {
+	Key  Key
+	Args []any
+}
+
+// In returns m translated to locale
+func (m Message) In(locale Locale) string {
+	return T(locale, m.Key, m.Args...)
+}
+
+// T returns the message of key in locale, formatted with args when given.
+// Keys missing from the locale fall back to the default catalog, and to the
+// key itself when no catalog has them.
+func T(locale Locale, key Key, args ...any) string {
+	text, ok := catalogs[locale][key]
+	if !ok {
+		if text, ok = catalogs[Default][key]; !ok {
+			text = string(key)
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Parse returns the locale of a language tag such as "en" or "en-US", or
+// Default when the language is not supported
+func Parse(tag string) Locale {
+	if locale, ok := Match(tag); ok {
+		return locale
+	}
+	return Default
+}
+
+// Match returns the locale of a language tag and whether it is supported
+func Match(tag string) (Locale, bool) {
+	lang, _, _ := strings.Cut(strings.TrimSpace(tag), "-")
+	locale := Locale(strings.ToLower(lang))
+	_, ok := catalogs[locale]
+	return locale, ok
+}
+
+// FromAcceptLanguage returns the supported locale the Accept-Language header
+// gives the highest weight, or Default when it names none of them
+func FromAcceptLanguage(header string) Locale {
+	best, bestQ := Default, 0.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		// Ties go to the earlier language, as listed by the client
+		if locale, ok := Match(tag); ok && q > bestQ {
+			best, bestQ = locale, q
+		}
+	}
+	return best
+}
+
+// ginKey is the gin context key the locale of a request is stored under
+const ginKey = "locale"
+
+// SetResolver makes resolve decide the locale of the request. It runs on
+// first use, so it can rely on what later middleware, such as
+// authentication, stores in c.
+func SetResolver(c *gin.Context, resolve func() Locale) {
+	c.Set(ginKey, resolve)
+}
+
+// Of returns the locale of the request, Default when no middleware set one
+func Of(c *gin.Context) Locale {
+	value, _ := c.Get(ginKey)
+	switch v := value.(type) {
+	case Locale:
+		return v
+	case func() Locale:
+		locale := v()
+		c.Set(ginKey, locale)
+		return locale
+	}
+	return Default
+}
@@ -0,0 +1,77 @@
+package i18n
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogsHaveTheSameKeys(t *testing.T) {
+	for locale, catalog := range catalogs {
+		for key := range catalogs[Default] {
+			assert.Contains(t, catalog, key, "%s catalog misses %s", locale, key)
+		}
+		for key := range catalog {
+			assert.Contains(t, catalogs[Default], key, "%s is only in the %s catalog", key, locale)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Слишком много запросов. Попробуйте позже.", T(RU, ResetRateLimited))
+	assert.Equal(t, "Too many requests. Please try again later.", T(EN, ResetRateLimited))
+	assert.Equal(t, "The password must be at least 8 characters long", T(EN, PasswordTooShort, 8))
+
+	// Unknown locales fall back to Russian, unknown keys to the key itself
+	assert.Equal(t, T(RU, ResetRequested), T(Locale("de"), ResetRequested))
+	assert.Equal(t, "no.such.key", T(EN, Key("no.such.key")))
+}
+
+func TestParse(t *testing.T) {
+	tests := map[string]Locale{
+		"ru":    RU,
+		"en":    EN,
+		"en-US": EN,
+		" EN ":  EN,
+		"de":    RU,
+		"":      RU,
+	}
+	for tag, want := range tests {
+		assert.Equal(t, want, Parse(tag), "tag %q", tag)
+	}
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := map[string]Locale{
+		"":                            RU,
+		"en":                          EN,
+		"en-GB,en;q=0.9":              EN,
+		"ru-RU,ru;q=0.9,en;q=0.8":     RU,
+		"de-DE,de;q=0.9,en;q=0.5":     EN,
+		"de,fr;q=0.8":                 RU,
+		"ru;q=0.5,en;q=0.7":           EN,
+		"en;q=0":                      RU,
+		"*":                           RU,
+		"en;q=abc,ru;q=0.1":           RU,
+		"fr-CA, en-US;q=0.8, ru;q=.7": EN,
+	}
+	for header, want := range tests {
+		assert.Equal(t, want, FromAcceptLanguage(header), "header %q", header)
+	}
+}
+
+func TestOf(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.Equal(t, Default, Of(c))
+
+	calls := 0
+	SetResolver(c, func() Locale {
+		calls++
+		return EN
+	})
+	assert.Equal(t, EN, Of(c))
+	assert.Equal(t, EN, Of(c))
+	assert.Equal(t, 1, calls, "the resolver runs once per request")
+}
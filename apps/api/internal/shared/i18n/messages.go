@@ -0,0 +1,87 @@
+package i18n
+
+// Password reset responses
+const (
+	ResetRateLimited     Key = "reset.rate_limited"
+	ResetRequested       Key = "reset.requested"
+	ResetCompleted       Key = "reset.completed"
+	ResetFailed          Key = "reset.failed"
+	ResetTokenInvalid    Key = "reset.token_invalid"
+	ResetTokenExpired    Key = "reset.token_expired"
+	ResetLinkInvalid     Key = "reset.link_invalid"
+	ResetLinkExpired     Key = "reset.link_expired"
+	ResetLinkCheckFailed Key = "reset.link_check_failed"
+)
+
+// Password requirements
+const (
+	PasswordTooWeak         Key = "password.too_weak"
+	PasswordTooShort        Key = "password.too_short" // arg: minimum length
+	PasswordTooLong         Key = "password.too_long"  // arg: maximum length
+	PasswordNoUppercase     Key = "password.no_uppercase"
+	PasswordNoLowercase     Key = "password.no_lowercase"
+	PasswordNoDigit         Key = "password.no_digit"
+	PasswordNoSpecial       Key = "password.no_special"
+	PasswordCommon          Key = "password.common"
+	PasswordContainsEmail   Key = "password.contains_email"
+	PasswordFoundInBreaches Key = "password.found_in_breaches"
+)
+
+// Email subjects
+const (
+	EmailPasswordResetSubject   Key = "email.password_reset.subject"
+	EmailPasswordChangedSubject Key = "email.password_changed.subject"
+)
+
+var catalogs = map[Locale]map[Key]string{
+	RU: {
+		ResetRateLimited:     "Слишком много запросов. Попробуйте позже.",
+		ResetRequested:       "Если аккаунт с этим email существует, вы получите инструкции по сбросу пароля.",
+		ResetCompleted:       "Пароль успешно изменен. Теперь вы можете войти с новым паролем.",
+		ResetFailed:          "Не удалось сбросить пароль. Попробуйте снова.",
+		ResetTokenInvalid:    "Неверная или истекшая ссылка для сброса. Запросите новую.",
+		ResetTokenExpired:    "Срок действия ссылки истек. Запросите новую.",
+		ResetLinkInvalid:     "Неверная ссылка для сброса.",
+		ResetLinkExpired:     "Срок действия ссылки истек.",
+		ResetLinkCheckFailed: "Не удалось проверить токен.",
+
+		PasswordTooWeak:         "Пароль не соответствует требованиям",
+		PasswordTooShort:        "Пароль должен содержать минимум %d символов",
+		PasswordTooLong:         "Пароль не должен превышать %d символов",
+		PasswordNoUppercase:     "Пароль должен содержать хотя бы одну заглавную букву",
+		PasswordNoLowercase:     "Пароль должен содержать хотя бы одну строчную букву",
+		PasswordNoDigit:         "Пароль должен содержать хотя бы одну цифру",
+		PasswordNoSpecial:       "Пароль должен содержать хотя бы один специальный символ",
+		PasswordCommon:          "Пароль слишком распространён, придумайте другой",
+		PasswordContainsEmail:   "Пароль не должен содержать имя из вашего email",
+		PasswordFoundInBreaches: "Этот пароль встречался в утечках данных, придумайте другой",
+
+		EmailPasswordResetSubject:   "Запрос на сброс пароля - BURCEV",
+		EmailPasswordChangedSubject: "Пароль изменен - BURCEV",
+	},
+	EN: {
+		ResetRateLimited:     "Too many requests. Please try again later.",
+		ResetRequested:       "If an account with this email exists, you will receive password reset instructions.",
+		ResetCompleted:       "Your password has been changed. You can now sign in with the new password.",
+		ResetFailed:          "Could not reset the password. Please try again.",
+		ResetTokenInvalid:    "The reset link is invalid or has expired. Please request a new one.",
+		ResetTokenExpired:    "The reset link has expired. Please request a new one.",
+		ResetLinkInvalid:     "Invalid reset link.",
+		ResetLinkExpired:     "The reset link has expired.",
+		ResetLinkCheckFailed: "Could not verify the reset link.",
+
+		PasswordTooWeak:         "The password does not meet the requirements",
+		PasswordTooShort:        "The password must be at least %d characters long",
+		PasswordTooLong:         "The password must not be longer than %d characters",
+		PasswordNoUppercase:     "The password must contain at least one uppercase letter",
+		PasswordNoLowercase:     "The password must contain at least one lowercase letter",
+		PasswordNoDigit:         "The password must contain at least one digit",
+		PasswordNoSpecial:       "The password must contain at least one special character",
+		PasswordCommon:          "This password is too common, please choose another one",
+		PasswordContainsEmail:   "The password must not contain the name from your email",
+		PasswordFoundInBreaches: "This password has appeared in data breaches, please choose another one",
+
+		EmailPasswordResetSubject:   "Password reset request - BURCEV",
+		EmailPasswordChangedSubject: "Your password was changed - BURCEV",
+	},
+}
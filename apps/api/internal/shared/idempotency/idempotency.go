@@ -0,0 +1,179 @@
+// Package idempotency lets clients retry create requests safely. A request
+// carrying an Idempotency-Key header runs once per user, scope and key; a
+// retry within TTL gets the resource the first request created.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// Headers
+const (
+	HeaderKey    = "Idempotency-Key"
+	HeaderReplay = "Idempotent-Replay"
+)
+
+const (
+	// TTL is how long a key is remembered
+	TTL = 24 * time.Hour
+	// MaxKeyLength matches the key column
+	MaxKeyLength = 255
+
+	cleanupBatchSize = 1000
+)
+
+var (
+	// ErrInvalidKey is returned for keys that are empty, too long or not printable ASCII
+	ErrInvalidKey = errors.New("invalid idempotency key")
+	// ErrInProgress is returned when a key is claimed but has no resource yet
+	ErrInProgress = errors.New("idempotent request still in progress")
+)
+
+// ValidateKey checks a client-supplied key. Clients typically send a UUID.
+func ValidateKey(key string) error {
+	if strings.TrimSpace(key) == "" || len(key) > MaxKeyLength {
+		return ErrInvalidKey
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x21 || key[i] > 0x7e {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
+
+// Store keeps claimed keys in the idempotency_keys table
+type Store struct {
+	db  *database.DB
+	log *logger.Logger
+}
+
+// NewStore creates a new idempotency key store
+func NewStore(db *database.DB, log *logger.Logger) *Store {
+	return &Store{
+		db:  db,
+		log: log,
+	}
+}
+
+// Claim reserves key inside tx. It returns "" when the key is new: the
+// caller then creates the resource in the same tx and calls Complete before
+// committing. Otherwise it returns the id of the resource created by the
+// earlier request with this key.
+//
+// A concurrent request with the same key blocks on the insert until the
+// first transaction ends. If that one committed, the insert is skipped
+// (ON CONFLICT DO NOTHING) and the committed resource id is returned; if it
+// rolled back, the waiting request claims the key itself.
+func (s *Store) Claim(ctx context.Context, tx *sql.Tx, userID int64, scope, key string) (string, error) {
+	startTime := time.Now()
+
+	// An expired key may be reused; remove it so the insert below can claim it
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND scope = $2 AND key = $3 AND expires_at <= NOW()`,
+		userID, scope, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to remove expired idempotency key: %w", err)
+	}
+
+	query := `
+		INSERT INTO idempotency_keys (user_id, scope, key, expires_at)
+		VALUES ($1, $2, $3, NOW() + $4 * INTERVAL '1 second')
+		ON CONFLICT (user_id, scope, key) DO NOTHING`
+
+	result, err := tx.ExecContext(ctx, query, userID, scope, key, int(TTL.Seconds()))
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, map[string]interface{}{
+		"user_id": userID,
+		"scope":   scope,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return "", fmt.Errorf("failed to claim idempotency key: %w", err)
+	}
+	if claimed == 1 {
+		return "", nil
+	}
+
+	var resourceID sql.NullString
+	err = tx.QueryRowContext(ctx, `
+		SELECT resource_id FROM idempotency_keys
+		WHERE user_id = $1 AND scope = $2 AND key = $3`,
+		userID, scope, key).Scan(&resourceID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load idempotency key: %w", err)
+	}
+	if !resourceID.Valid {
+		return "", ErrInProgress
+	}
+	return resourceID.String, nil
+}
+
+// Complete records the resource created for a claimed key
+func (s *Store) Complete(ctx context.Context, tx *sql.Tx, userID int64, scope, key, resourceID string) error {
+	_, err := tx.ExecContext(ctx, `
+		UPDATE idempotency_keys SET resource_id = $4
+		WHERE user_id = $1 AND scope = $2 AND key = $3`,
+		userID, scope, key, resourceID)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Cleanup deletes up to cleanupBatchSize expired keys and returns how many it removed
+func (s *Store) Cleanup(ctx context.Context) (int64, error) {
+	startTime := time.Now()
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE (user_id, scope, key) IN (
+			SELECT user_id, scope, key FROM idempotency_keys
+			WHERE expires_at <= NOW()
+			LIMIT $1
+		)`
+
+	result, err := s.db.ExecContext(ctx, query, cleanupBatchSize)
+	s.log.LogDatabaseQuery(query, time.Since(startTime), err, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// RunCleanup removes expired keys every hour until ctx is cancelled
+func (s *Store) RunCleanup(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	s.log.Info("Idempotency key cleanup started")
+
+	for {
+		select {
+		case <-ticker.C:
+			for {
+				removed, err := s.Cleanup(ctx)
+				if err != nil {
+					s.log.Error("Failed to clean up idempotency keys", "error", err)
+					break
+				}
+				if removed < cleanupBatchSize || ctx.Err() != nil {
+					break
+				}
+			}
+		case <-ctx.Done():
+			s.log.Info("Idempotency key cleanup stopped")
+			return
+		}
+	}
+}
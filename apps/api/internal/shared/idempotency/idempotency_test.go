@@ -0,0 +1,124 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupStore(t *testing.T) (*Store, *sql.DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+	return NewStore(&database.DB{DB: mockDB}, logger.New()), mockDB, mock
+}
+
+func TestValidateKey(t *testing.T) {
+	valid := []string{"a", "3f2b8c1e-8a4d-4c5e-9b7a-1d2e3f4a5b6c", strings.Repeat("k", MaxKeyLength)}
+	for _, key := range valid {
+		assert.NoError(t, ValidateKey(key), key)
+	}
+
+	invalid := []string{"", "   ", "with space", "ключ", "tab\tkey", strings.Repeat("k", MaxKeyLength+1)}
+	for _, key := range invalid {
+		assert.ErrorIs(t, ValidateKey(key), ErrInvalidKey, key)
+	}
+}
+
+func TestClaim(t *testing.T) {
+	ctx := context.Background()
+
+	expectDeleteExpired := func(mock sqlmock.Sqlmock) {
+		mock.ExpectExec("DELETE FROM idempotency_keys\\s+WHERE user_id = \\$1 AND scope = \\$2 AND key = \\$3 AND expires_at <= NOW\\(\\)").
+			WithArgs(int64(7), "entry", "key-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	expectInsert := func(mock sqlmock.Sqlmock, inserted int64) {
+		mock.ExpectExec("INSERT INTO idempotency_keys (.+) ON CONFLICT \\(user_id, scope, key\\) DO NOTHING").
+			WithArgs(int64(7), "entry", "key-1", 86400).
+			WillReturnResult(sqlmock.NewResult(0, inserted))
+	}
+
+	t.Run("new key is claimed", func(t *testing.T) {
+		store, db, mock := setupStore(t)
+		mock.ExpectBegin()
+		expectDeleteExpired(mock)
+		expectInsert(mock, 1)
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		existingID, err := store.Claim(ctx, tx, 7, "entry", "key-1")
+
+		require.NoError(t, err)
+		assert.Empty(t, existingID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("used key returns the earlier resource", func(t *testing.T) {
+		store, db, mock := setupStore(t)
+		mock.ExpectBegin()
+		expectDeleteExpired(mock)
+		expectInsert(mock, 0)
+		mock.ExpectQuery("SELECT resource_id FROM idempotency_keys").
+			WithArgs(int64(7), "entry", "key-1").
+			WillReturnRows(sqlmock.NewRows([]string{"resource_id"}).AddRow("entry-1"))
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		existingID, err := store.Claim(ctx, tx, 7, "entry", "key-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, "entry-1", existingID)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claimed key without resource", func(t *testing.T) {
+		store, db, mock := setupStore(t)
+		mock.ExpectBegin()
+		expectDeleteExpired(mock)
+		expectInsert(mock, 0)
+		mock.ExpectQuery("SELECT resource_id FROM idempotency_keys").
+			WillReturnRows(sqlmock.NewRows([]string{"resource_id"}).AddRow(nil))
+
+		tx, err := db.Begin()
+		require.NoError(t, err)
+		_, err = store.Claim(ctx, tx, 7, "entry", "key-1")
+
+		assert.ErrorIs(t, err, ErrInProgress)
+	})
+}
+
+func TestComplete(t *testing.T) {
+	store, db, mock := setupStore(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE idempotency_keys SET resource_id = \\$4").
+		WithArgs(int64(7), "entry", "key-1", "entry-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	require.NoError(t, store.Complete(context.Background(), tx, 7, "entry", "key-1", "entry-1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCleanup(t *testing.T) {
+	store, _, mock := setupStore(t)
+	mock.ExpectExec("DELETE FROM idempotency_keys\\s+WHERE \\(user_id, scope, key\\) IN \\(\\s+SELECT (.+) WHERE expires_at <= NOW\\(\\)\\s+LIMIT \\$1").
+		WithArgs(cleanupBatchSize).
+		WillReturnResult(sqlmock.NewResult(0, 42))
+
+	removed, err := store.Cleanup(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,79 @@
+// Package ids generates the ids of new rows: time-ordered UUIDv7s, so rows
+// created later sort after earlier ones and index inserts stay append-only.
+package ids
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxSequence is the largest value of the 12-bit counter in rand_a
+const maxSequence = 0xFFF
+
+// Generator makes UUIDv7s (RFC 9562) from its clock. Ids from one
+// Generator strictly increase even within a millisecond or when the clock
+// steps back: the 12 bits after the timestamp count up, and once they run
+// out the timestamp is advanced past the clock.
+type Generator struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	rand io.Reader
+
+	lastMs int64
+	seq    uint16
+}
+
+// NewGenerator creates a Generator reading the time from now
+func NewGenerator(now func() time.Time) *Generator {
+	return &Generator{now: now, rand: rand.Reader}
+}
+
+// Default is the process-wide generator the services use, so ids of all
+// tables come from one clock and counter
+var Default = NewGenerator(time.Now)
+
+// NewString returns the next id as a string, the form the services store
+func (g *Generator) NewString() string {
+	return g.Next().String()
+}
+
+// Next returns the next id
+func (g *Generator) Next() uuid.UUID {
+	g.mu.Lock()
+	ms := g.now().UnixMilli()
+	if ms > g.lastMs {
+		g.lastMs, g.seq = ms, 0
+	} else if g.seq < maxSequence {
+		g.seq++
+	} else {
+		g.lastMs, g.seq = g.lastMs+1, 0
+	}
+	ms, seq := g.lastMs, g.seq
+	g.mu.Unlock()
+
+	var id uuid.UUID
+	if _, err := io.ReadFull(g.rand, id[8:]); err != nil {
+		// crypto/rand does not fail on supported platforms
+		panic("ids: failed to read random bytes: " + err.Error())
+	}
+
+	var stamp [8]byte
+	binary.BigEndian.PutUint64(stamp[:], uint64(ms))
+	copy(id[:6], stamp[2:])
+	id[6] = 0x70 | byte(seq>>8)
+	id[7] = byte(seq)
+	id[8] = 0x80 | id[8]&0x3F
+	return id
+}
+
+// Time returns the creation time encoded in a UUIDv7, to the millisecond
+func Time(id uuid.UUID) time.Time {
+	var stamp [8]byte
+	copy(stamp[2:], id[:6])
+	return time.UnixMilli(int64(binary.BigEndian.Uint64(stamp[:])))
+}
@@ -0,0 +1,100 @@
+package ids
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var start = time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestNext_IsV7(t *testing.T) {
+	g := NewGenerator(fixedClock(start))
+
+	id := g.Next()
+
+	assert.Equal(t, uuid.Version(7), id.Version())
+	assert.Equal(t, uuid.RFC4122, id.Variant())
+	assert.Equal(t, start, Time(id).UTC())
+
+	parsed, err := uuid.Parse(id.String())
+	require.NoError(t, err)
+	assert.Equal(t, id, parsed)
+}
+
+func TestNext_MonotonicWithinBurst(t *testing.T) {
+	// The clock does not move: a burst of requests within one millisecond
+	g := NewGenerator(fixedClock(start))
+
+	prev := g.Next().String()
+	for range 2 * maxSequence {
+		next := g.Next().String()
+		require.Greater(t, next, prev)
+		prev = next
+	}
+}
+
+func TestNext_SequenceOverflowAdvancesTime(t *testing.T) {
+	g := NewGenerator(fixedClock(start))
+
+	for range maxSequence + 1 {
+		assert.Equal(t, start, Time(g.Next()).UTC())
+	}
+	assert.Equal(t, start.Add(time.Millisecond), Time(g.Next()).UTC())
+}
+
+func TestNext_ClockStepsBack(t *testing.T) {
+	now := start
+	g := NewGenerator(func() time.Time { return now })
+
+	before := g.Next().String()
+	now = start.Add(-time.Second)
+	after := g.Next().String()
+
+	assert.Greater(t, after, before)
+}
+
+func TestNext_NewMillisecondResetsSequence(t *testing.T) {
+	now := start
+	g := NewGenerator(func() time.Time { return now })
+
+	g.Next()
+	g.Next()
+	now = start.Add(time.Millisecond)
+	id := g.Next()
+
+	assert.Equal(t, byte(0x70), id[6])
+	assert.Equal(t, byte(0), id[7])
+	assert.Equal(t, now, Time(id).UTC())
+}
+
+func TestNext_Concurrent(t *testing.T) {
+	g := NewGenerator(fixedClock(start))
+
+	const workers, perWorker = 8, 200
+	var mu sync.Mutex
+	seen := make(map[uuid.UUID]bool, workers*perWorker)
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			for range perWorker {
+				id := g.Next()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+
+	assert.Len(t, seen, workers*perWorker)
+}
@@ -0,0 +1,181 @@
+// Package lifecycle starts the server's components and shuts them down in
+// order on SIGTERM, so background jobs are not killed mid-transaction and the
+// database is closed last.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// DefaultStopTimeout bounds a component's Stop when it sets no Timeout
+const DefaultStopTimeout = 5 * time.Second
+
+// Component is a part of the server with a lifetime. Start must not block;
+// Stop should return once the component has finished its work or when ctx
+// expires. Either func may be nil.
+type Component struct {
+	Name    string
+	Start   func(ctx context.Context) error
+	Stop    func(ctx context.Context) error
+	Timeout time.Duration
+}
+
+// Manager runs registered components. They start in registration order and
+// stop in reverse, so register dependencies (database, logger) before the
+// components that use them (jobs, HTTP server).
+type Manager struct {
+	log        *logger.Logger
+	components []Component
+	started    int
+}
+
+// New creates a new lifecycle manager
+func New(log *logger.Logger) *Manager {
+	return &Manager{log: log}
+}
+
+// Register adds a component
+func (m *Manager) Register(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Start starts the components in registration order. If one fails, the
+// components already started are stopped and the error is returned.
+func (m *Manager) Start(ctx context.Context) error {
+	for _, c := range m.components[m.started:] {
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				stopErr := m.Stop()
+				return errors.Join(fmt.Errorf("failed to start %s: %w", c.Name, err), stopErr)
+			}
+		}
+		m.started++
+	}
+	return nil
+}
+
+// Stop stops the started components in reverse order. Each Stop gets its own
+// timeout; a component that does not return in time is abandoned and the
+// shutdown moves on to the next one.
+func (m *Manager) Stop() error {
+	var errs []error
+	for i := m.started - 1; i >= 0; i-- {
+		if err := m.stop(m.components[i]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	m.started = 0
+	return errors.Join(errs...)
+}
+
+func (m *Manager) stop(c Component) error {
+	if c.Stop == nil {
+		return nil
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultStopTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	startTime := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.Stop(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			m.log.Error("Component stopped with error", "component", c.Name, "error", err, "duration_ms", time.Since(startTime).Milliseconds())
+			return fmt.Errorf("failed to stop %s: %w", c.Name, err)
+		}
+		m.log.Info("Component stopped", "component", c.Name, "duration_ms", time.Since(startTime).Milliseconds())
+		return nil
+	case <-ctx.Done():
+		m.log.Error("Component stop timed out", "component", c.Name, "timeout", timeout.String())
+		return fmt.Errorf("stopping %s timed out after %s", c.Name, timeout)
+	}
+}
+
+// Run starts the components, blocks until ctx is cancelled (typically by a
+// signal) and then stops them.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	m.log.Info("Shutting down")
+	return m.Stop()
+}
+
+// Workers returns a component for background loops that block until their
+// context is cancelled (RunWorker, RunScheduler, ...). Stop cancels them and
+// waits for every loop to return, so a job finishes its current batch.
+func Workers(name string, timeout time.Duration, loops ...func(ctx context.Context)) Component {
+	var wg sync.WaitGroup
+	var cancel context.CancelFunc = func() {}
+
+	return Component{
+		Name:    name,
+		Timeout: timeout,
+		Start: func(ctx context.Context) error {
+			// Workers outlive the start context; they are cancelled by Stop only
+			var runCtx context.Context
+			runCtx, cancel = context.WithCancel(context.WithoutCancel(ctx))
+			for _, loop := range loops {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					loop(runCtx)
+				}()
+			}
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+// HTTPServer returns a component that serves srv. Start binds the port
+// synchronously so a taken port fails startup; Stop stops accepting
+// connections and waits for in-flight requests.
+func HTTPServer(srv *http.Server, timeout time.Duration, log *logger.Logger) Component {
+	return Component{
+		Name:    "http server",
+		Timeout: timeout,
+		Start: func(ctx context.Context) error {
+			ln, err := net.Listen("tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Error("HTTP server stopped unexpectedly", "error", err)
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
+}
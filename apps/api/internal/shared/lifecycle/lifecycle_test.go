@@ -0,0 +1,185 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder collects start/stop events from fake components
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.events...)
+}
+
+func (r *recorder) component(name string) Component {
+	return Component{
+		Name:  name,
+		Start: func(context.Context) error { r.add("start " + name); return nil },
+		Stop:  func(context.Context) error { r.add("stop " + name); return nil },
+	}
+}
+
+func TestManagerStopsInReverseOrder(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New())
+	for _, name := range []string{"database", "logger", "jobs", "http"} {
+		m.Register(rec.component(name))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, m.Run(ctx))
+
+	assert.Equal(t, []string{
+		"start database", "start logger", "start jobs", "start http",
+		"stop http", "stop jobs", "stop logger", "stop database",
+	}, rec.list())
+}
+
+func TestManagerCutsOffHangingComponent(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New())
+	m.Register(rec.component("database"))
+	m.Register(Component{
+		Name:    "stuck job",
+		Timeout: 50 * time.Millisecond,
+		Stop: func(context.Context) error {
+			// Ignores its context, like a job blocked on a slow query
+			time.Sleep(time.Second)
+			rec.add("stop stuck job")
+			return nil
+		},
+	})
+	m.Register(rec.component("http"))
+	require.NoError(t, m.Start(context.Background()))
+
+	startTime := time.Now()
+	err := m.Stop()
+
+	assert.ErrorContains(t, err, "stopping stuck job timed out after 50ms")
+	assert.Less(t, time.Since(startTime), 500*time.Millisecond)
+	assert.Equal(t, []string{"start database", "start http", "stop http", "stop database"}, rec.list())
+}
+
+func TestManagerStopsStartedComponentsWhenStartFails(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New())
+	m.Register(rec.component("database"))
+	m.Register(Component{
+		Name:  "http",
+		Start: func(context.Context) error { return errors.New("address already in use") },
+		Stop:  func(context.Context) error { rec.add("stop http"); return nil },
+	})
+	m.Register(rec.component("never started"))
+
+	err := m.Start(context.Background())
+
+	assert.ErrorContains(t, err, "failed to start http: address already in use")
+	assert.Equal(t, []string{"start database", "stop database"}, rec.list())
+}
+
+func TestManagerReportsStopErrors(t *testing.T) {
+	rec := &recorder{}
+	m := New(logger.New())
+	m.Register(rec.component("database"))
+	m.Register(Component{Name: "jobs", Stop: func(context.Context) error { return errors.New("boom") }})
+	require.NoError(t, m.Start(context.Background()))
+
+	err := m.Stop()
+
+	assert.ErrorContains(t, err, "failed to stop jobs: boom")
+	assert.Equal(t, []string{"start database", "stop database"}, rec.list(), "later components still stop")
+}
+
+func TestWorkers(t *testing.T) {
+	t.Run("stop waits for loops to finish", func(t *testing.T) {
+		rec := &recorder{}
+		loop := func(name string) func(ctx context.Context) {
+			return func(ctx context.Context) {
+				<-ctx.Done()
+				time.Sleep(10 * time.Millisecond) // finish the current batch
+				rec.add(name + " done")
+			}
+		}
+		w := Workers("jobs", time.Second, loop("broadcast"), loop("imports"))
+
+		// Cancelling the start context (the signal) must not stop the loops
+		ctx, cancel := context.WithCancel(context.Background())
+		require.NoError(t, w.Start(ctx))
+		cancel()
+		time.Sleep(20 * time.Millisecond)
+		assert.Empty(t, rec.list())
+
+		require.NoError(t, w.Stop(context.Background()))
+		assert.ElementsMatch(t, []string{"broadcast done", "imports done"}, rec.list())
+	})
+
+	t.Run("stop gives up at the deadline", func(t *testing.T) {
+		block := make(chan struct{})
+		defer close(block)
+		w := Workers("jobs", time.Second, func(context.Context) { <-block })
+		require.NoError(t, w.Start(context.Background()))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, w.Stop(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestHTTPServer(t *testing.T) {
+	// Reserve a free port for the server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	started := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	})
+	c := HTTPServer(&http.Server{Addr: addr, Handler: mux}, time.Second, logger.New())
+	require.NoError(t, c.Start(context.Background()))
+
+	status := make(chan int, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			status <- 0
+			return
+		}
+		resp.Body.Close()
+		status <- resp.StatusCode
+	}()
+	<-started
+
+	require.NoError(t, c.Stop(context.Background()))
+	assert.Equal(t, http.StatusOK, <-status, "in-flight request completes before stop returns")
+
+	// The port is bound in Start, so a bad address fails startup
+	assert.Error(t, HTTPServer(&http.Server{Addr: "127.0.0.1:-1"}, time.Second, logger.New()).Start(context.Background()))
+}
@@ -0,0 +1,108 @@
+// Package listing parses the sort and pagination query parameters of list
+// endpoints into SQL clauses. Column names always come from the endpoint's
+// whitelist; request values are never interpolated into SQL.
+package listing
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// Fields maps the sort names a listing accepts to their SQL columns
+type Fields map[string]string
+
+// Names returns the accepted sort names in alphabetical order
+func (f Fields) Names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Sort is an ordering of a listing by one whitelisted column
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// ParseSort parses a sort query value of the form field or field:asc or
+// field:desc (ascending when the direction is omitted). An empty value
+// returns def. Unknown fields and directions are reported as
+// validation.Errors on "sort" listing the accepted fields.
+func ParseSort(raw string, fields Fields, def Sort) (Sort, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return def, nil
+	}
+
+	name, dir, _ := strings.Cut(raw, ":")
+	column, ok := fields[name]
+	if !ok || (dir != "" && dir != "asc" && dir != "desc") {
+		return Sort{}, validation.Errors{"sort": fmt.Sprintf(
+			"Недопустимая сортировка: допустимы %s, с необязательным :asc или :desc",
+			strings.Join(fields.Names(), ", "))}
+	}
+	return Sort{Column: column, Desc: dir == "desc"}, nil
+}
+
+// OrderBy returns the ORDER BY list: the sort column followed by the
+// tie-breaker columns, all in the sort direction. The last tie-breaker
+// should be unique (the id) so rows with equal values keep the same order
+// from page to page. Tie-breakers equal to the sort column are skipped.
+func (s Sort) OrderBy(tieBreakers ...string) string {
+	dir := " ASC"
+	if s.Desc {
+		dir = " DESC"
+	}
+
+	parts := []string{s.Column + dir}
+	for _, column := range tieBreakers {
+		if column != s.Column {
+			parts = append(parts, column+dir)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Page is a limit/offset window of a listing. A zero Limit means no limit.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// ParsePage reads limit and offset query values. Like the older list
+// endpoints it is lenient: a missing or invalid limit becomes defaultLimit,
+// a missing or invalid offset 0, and limit is capped at maxLimit.
+func ParsePage(limit, offset string, defaultLimit, maxLimit int) Page {
+	page := Page{Limit: defaultLimit}
+	if v, err := strconv.Atoi(limit); err == nil && v > 0 {
+		page.Limit = min(v, maxLimit)
+	}
+	if v, err := strconv.Atoi(offset); err == nil && v >= 0 {
+		page.Offset = v
+	}
+	return page
+}
+
+// Clause returns the LIMIT/OFFSET clause with placeholders numbered from
+// next, and its arguments. Both are empty for the whole listing.
+func (p Page) Clause(next int) (string, []any) {
+	var clause string
+	var args []any
+	if p.Limit > 0 {
+		clause += fmt.Sprintf(" LIMIT $%d", next)
+		args = append(args, p.Limit)
+		next++
+	}
+	if p.Offset > 0 {
+		clause += fmt.Sprintf(" OFFSET $%d", next)
+		args = append(args, p.Offset)
+	}
+	return clause, args
+}
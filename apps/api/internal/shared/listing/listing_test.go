@@ -0,0 +1,87 @@
+package listing
+
+import (
+	"testing"
+
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFields = Fields{"date": "date", "calories": "calories", "created_at": "created_at"}
+
+func TestParseSort(t *testing.T) {
+	def := Sort{Column: "date", Desc: true}
+
+	tests := []struct {
+		raw  string
+		want Sort
+	}{
+		{"", def},
+		{"calories", Sort{Column: "calories"}},
+		{"calories:asc", Sort{Column: "calories"}},
+		{"calories:desc", Sort{Column: "calories", Desc: true}},
+		{" created_at:desc ", Sort{Column: "created_at", Desc: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseSort(tt.raw, testFields, def)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseSort_Rejected(t *testing.T) {
+	for _, raw := range []string{
+		"fat",
+		"calories:up",
+		"Calories",
+		"calories:desc:asc",
+		"calories; DROP TABLE nutrition_entries",
+		"calories DESC, (SELECT 1)",
+		"date:desc--",
+		"1",
+	} {
+		t.Run(raw, func(t *testing.T) {
+			_, err := ParseSort(raw, testFields, Sort{Column: "date"})
+
+			var fieldErrs validation.Errors
+			require.ErrorAs(t, err, &fieldErrs)
+			assert.Contains(t, fieldErrs["sort"], "calories, created_at, date")
+			assert.NotContains(t, fieldErrs["sort"], raw, "the raw value is not echoed")
+		})
+	}
+}
+
+func TestSort_OrderBy(t *testing.T) {
+	assert.Equal(t, "calories DESC, id DESC", Sort{Column: "calories", Desc: true}.OrderBy("id"))
+	assert.Equal(t, "date ASC, created_at ASC, id ASC", Sort{Column: "date"}.OrderBy("created_at", "id"))
+	assert.Equal(t, "created_at DESC, id DESC", Sort{Column: "created_at", Desc: true}.OrderBy("created_at", "id"))
+}
+
+func TestParsePage(t *testing.T) {
+	assert.Equal(t, Page{Limit: 20}, ParsePage("", "", 20, 100))
+	assert.Equal(t, Page{Limit: 50, Offset: 100}, ParsePage("50", "100", 20, 100))
+	assert.Equal(t, Page{Limit: 100}, ParsePage("500", "", 20, 100))
+	assert.Equal(t, Page{Limit: 20}, ParsePage("-1", "abc", 20, 100))
+	assert.Equal(t, Page{}, ParsePage("", "", 0, 100))
+}
+
+func TestPage_Clause(t *testing.T) {
+	clause, args := Page{Limit: 20, Offset: 40}.Clause(2)
+	assert.Equal(t, " LIMIT $2 OFFSET $3", clause)
+	assert.Equal(t, []any{20, 40}, args)
+
+	clause, args = Page{Limit: 20}.Clause(2)
+	assert.Equal(t, " LIMIT $2", clause)
+	assert.Equal(t, []any{20}, args)
+
+	clause, args = Page{Offset: 10}.Clause(4)
+	assert.Equal(t, " OFFSET $4", clause)
+	assert.Equal(t, []any{10}, args)
+
+	clause, args = Page{}.Clause(2)
+	assert.Empty(t, clause)
+	assert.Empty(t, args)
+}
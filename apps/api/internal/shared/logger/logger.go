@@ -2,7 +2,10 @@ package logger
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
@@ -12,7 +15,8 @@ import (
 // Logger wraps zap.SugaredLogger with additional context
 type Logger struct {
 	*zap.SugaredLogger
-	fields map[string]interface{}
+	fields   map[string]interface{}
+	security *securitySinks
 }
 
 // LogLevel represents log severity levels
@@ -26,42 +30,136 @@ const (
 	FatalLevel LogLevel = "fatal"
 )
 
-// New creates a new logger instance
-func New() *Logger {
-	var config zap.Config
+// Encodings supported by WithEncoding
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// Option configures a logger built by New
+type Option func(*options)
+
+type options struct {
+	level    LogLevel
+	encoding string
+	output   io.Writer
+}
 
-	env := os.Getenv("NODE_ENV")
-	if env == "production" {
-		config = zap.NewProductionConfig()
+// WithLevel sets the minimum level that is written. An empty or unknown
+// level keeps the default.
+func WithLevel(level LogLevel) Option {
+	return func(o *options) {
+		if _, err := zapLevel(level); err == nil {
+			o.level = level
+		}
+	}
+}
+
+// WithEncoding selects EncodingJSON or EncodingConsole
+func WithEncoding(encoding string) Option {
+	return func(o *options) {
+		if encoding == EncodingJSON || encoding == EncodingConsole {
+			o.encoding = encoding
+		}
+	}
+}
+
+// WithOutput writes log lines to w instead of stderr, e.g. to capture them in tests
+func WithOutput(w io.Writer) Option {
+	return func(o *options) {
+		o.output = w
+	}
+}
+
+// New creates a new logger instance. Without options it logs JSON at info
+// level in production (NODE_ENV=production) and colored console lines at
+// debug level otherwise, to stderr.
+func New(opts ...Option) *Logger {
+	o := options{level: DebugLevel, encoding: EncodingConsole, output: os.Stderr}
+	production := os.Getenv("NODE_ENV") == "production"
+	if production {
+		o.level, o.encoding = InfoLevel, EncodingJSON
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var encoderConfig zapcore.EncoderConfig
+	if o.encoding == EncodingJSON {
 		// JSON format for production (machine-readable)
-		config.Encoding = "json"
+		encoderConfig = zap.NewProductionEncoderConfig()
 	} else {
-		config = zap.NewDevelopmentConfig()
 		// Console format for development (human-readable)
-		config.Encoding = "console"
-		config.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-		config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+		encoderConfig = zap.NewDevelopmentEncoderConfig()
+		encoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	}
 
 	// Add caller information
-	config.EncoderConfig.CallerKey = "caller"
-	config.EncoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+	encoderConfig.CallerKey = "caller"
+	encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
 
 	// Add stack trace for errors
-	config.EncoderConfig.StacktraceKey = "stacktrace"
+	encoderConfig.StacktraceKey = "stacktrace"
+
+	encoder := zapcore.NewConsoleEncoder(encoderConfig)
+	if o.encoding == EncodingJSON {
+		encoder = zapcore.NewJSONEncoder(encoderConfig)
+	}
 
-	logger, err := config.Build(
+	level, _ := zapLevel(o.level)
+	core := zapcore.NewCore(encoder, zapcore.Lock(zapcore.AddSync(o.output)), level)
+	if production {
+		// Same sampling as zap's production config
+		core = zapcore.NewSamplerWithOptions(core, time.Second, 100, 100)
+	}
+
+	logger := zap.New(core,
 		zap.AddCaller(),
 		zap.AddCallerSkip(1),
 		zap.AddStacktrace(zapcore.ErrorLevel),
 	)
-	if err != nil {
-		panic(err)
-	}
 
 	return &Logger{
 		SugaredLogger: logger.Sugar(),
 		fields:        make(map[string]interface{}),
+		security:      &securitySinks{},
+	}
+}
+
+// Nop returns a logger that discards everything, for tests that should not print
+func Nop() *Logger {
+	return &Logger{
+		SugaredLogger: zap.NewNop().Sugar(),
+		fields:        make(map[string]interface{}),
+		security:      &securitySinks{},
+	}
+}
+
+// ParseLevel reads a configured level name such as "info". Case is ignored,
+// deploy env files use "INFO".
+func ParseLevel(level string) (LogLevel, error) {
+	parsed := LogLevel(strings.ToLower(strings.TrimSpace(level)))
+	if _, err := zapLevel(parsed); err != nil {
+		return "", err
+	}
+	return parsed, nil
+}
+
+func zapLevel(level LogLevel) (zapcore.Level, error) {
+	switch level {
+	case DebugLevel:
+		return zapcore.DebugLevel, nil
+	case InfoLevel:
+		return zapcore.InfoLevel, nil
+	case WarnLevel:
+		return zapcore.WarnLevel, nil
+	case ErrorLevel:
+		return zapcore.ErrorLevel, nil
+	case FatalLevel:
+		return zapcore.FatalLevel, nil
+	default:
+		return zapcore.InfoLevel, fmt.Errorf("unknown log level %q", level)
 	}
 }
 
@@ -70,6 +168,7 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 	newLogger := &Logger{
 		SugaredLogger: l.SugaredLogger,
 		fields:        make(map[string]interface{}),
+		security:      l.security,
 	}
 
 	// Copy existing fields
@@ -96,6 +195,7 @@ func (l *Logger) WithFields(fields map[string]interface{}) *Logger {
 	newLogger := &Logger{
 		SugaredLogger: l.SugaredLogger,
 		fields:        make(map[string]interface{}),
+		security:      l.security,
 	}
 
 	// Copy existing fields
@@ -215,12 +315,16 @@ func (l *Logger) LogBusinessEvent(event string, fields map[string]interface{}) {
 	l.WithFields(logFields).Info("Business event")
 }
 
-// LogSecurityEvent logs security-related events
+// LogSecurityEvent logs security-related events and hands them to the
+// registered security sinks
 func (l *Logger) LogSecurityEvent(event string, severity string, fields map[string]interface{}) {
+	now := time.Now().UTC()
+	l.recordSecurityEvent(SecurityEvent{Event: event, Severity: severity, Fields: fields, Time: now})
+
 	logFields := map[string]interface{}{
 		"event":     event,
 		"severity":  severity,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"timestamp": now.Format(time.RFC3339),
 		"category":  "security",
 	}
 
@@ -1,12 +1,15 @@
 package logger
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNew(t *testing.T) {
@@ -18,6 +21,77 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNew_Level(t *testing.T) {
+	t.Run("debug is suppressed at info level", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := New(WithLevel(InfoLevel), WithOutput(&buf))
+
+		log.Debug("debug line")
+		log.Info("info line")
+
+		assert.NotContains(t, buf.String(), "debug line")
+		assert.Contains(t, buf.String(), "info line")
+	})
+
+	t.Run("debug is written at debug level", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := New(WithLevel(DebugLevel), WithOutput(&buf))
+
+		log.Debug("debug line")
+
+		assert.Contains(t, buf.String(), "debug line")
+	})
+
+	t.Run("unknown level keeps the default", func(t *testing.T) {
+		var buf bytes.Buffer
+		log := New(WithLevel("verbose"), WithLevel(WarnLevel), WithLevel("loud"), WithOutput(&buf))
+
+		log.Info("info line")
+		log.Warn("warn line")
+
+		assert.NotContains(t, buf.String(), "info line")
+		assert.Contains(t, buf.String(), "warn line")
+	})
+}
+
+func TestNew_JSONEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	log := New(WithEncoding(EncodingJSON), WithOutput(&buf))
+
+	log.WithField("user_id", 42).Info("json line")
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &line))
+	assert.Equal(t, "json line", line["msg"])
+	assert.Equal(t, float64(42), line["user_id"])
+}
+
+func TestNop(t *testing.T) {
+	log := Nop()
+
+	assert.NotPanics(t, func() {
+		log.WithField("k", "v").Error("discarded")
+		log.LogSecurityEvent("event", "critical", nil)
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, level := range []string{"debug", "info", "warn", "error", "fatal"} {
+		parsed, err := ParseLevel(level)
+		require.NoError(t, err)
+		assert.Equal(t, LogLevel(level), parsed)
+	}
+
+	parsed, err := ParseLevel(" INFO ")
+	require.NoError(t, err)
+	assert.Equal(t, InfoLevel, parsed)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+	_, err = ParseLevel("")
+	assert.Error(t, err)
+}
+
 type contextKey string
 
 const (
@@ -219,6 +293,29 @@ func TestLogSecurityEvent(t *testing.T) {
 	})
 }
 
+func TestLogSecurityEvent_Sinks(t *testing.T) {
+	log := Nop()
+	derived := log.WithField("module", "auth").WithContext(context.Background())
+	// Sinks added later still reach loggers derived earlier
+	recorder := &SecurityRecorder{}
+	log.AddSecuritySink(recorder)
+
+	derived.LogSecurityEvent("ip_rate_limit_exceeded", "high", map[string]interface{}{"ip": "203.0.113.9"})
+	log.LogSecurityEvent("session_revoked", "info", nil)
+
+	events := recorder.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "ip_rate_limit_exceeded", events[0].Event)
+	assert.Equal(t, "high", events[0].Severity)
+	assert.Equal(t, "203.0.113.9", events[0].Fields["ip"])
+	assert.WithinDuration(t, time.Now(), events[0].Time, time.Minute)
+	assert.Equal(t, []string{"ip_rate_limit_exceeded", "session_revoked"}, recorder.Names())
+
+	// Separate loggers do not share sinks
+	New(WithOutput(&bytes.Buffer{})).LogSecurityEvent("other", "low", nil)
+	assert.Len(t, recorder.Events(), 2)
+}
+
 func TestSync(t *testing.T) {
 	log := New()
 
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// SecurityEvent is an event passed to LogSecurityEvent
+type SecurityEvent struct {
+	Event    string                 `json:"event"`
+	Severity string                 `json:"severity"`
+	Fields   map[string]interface{} `json:"fields,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// SecuritySink receives every security event next to the log, e.g. to count
+// them for alerting. RecordSecurityEvent is called synchronously from
+// request paths and must be cheap and safe for concurrent use.
+type SecuritySink interface {
+	RecordSecurityEvent(event SecurityEvent)
+}
+
+// securitySinks is shared by a logger and every logger derived from it, so
+// sinks added at startup also reach loggers handed out earlier
+type securitySinks struct {
+	mu    sync.RWMutex
+	sinks []SecuritySink
+}
+
+// AddSecuritySink registers sink for the security events of this logger and
+// of every logger derived from the same New or Nop call
+func (l *Logger) AddSecuritySink(sink SecuritySink) {
+	if l.security == nil {
+		return
+	}
+	l.security.mu.Lock()
+	defer l.security.mu.Unlock()
+	l.security.sinks = append(l.security.sinks, sink)
+}
+
+// recordSecurityEvent fans event out to the registered sinks
+func (l *Logger) recordSecurityEvent(event SecurityEvent) {
+	if l.security == nil {
+		return
+	}
+	l.security.mu.RLock()
+	defer l.security.mu.RUnlock()
+	for _, sink := range l.security.sinks {
+		sink.RecordSecurityEvent(event)
+	}
+}
+
+// SecurityRecorder is a SecuritySink that keeps every event, for tests that
+// assert which security events a code path emits
+type SecurityRecorder struct {
+	mu     sync.Mutex
+	events []SecurityEvent
+}
+
+// RecordSecurityEvent implements SecuritySink
+func (r *SecurityRecorder) RecordSecurityEvent(event SecurityEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events, oldest first
+func (r *SecurityRecorder) Events() []SecurityEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SecurityEvent(nil), r.events...)
+}
+
+// Names returns the names of the recorded events, oldest first, or nil
+func (r *SecurityRecorder) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var names []string
+	for _, e := range r.events {
+		names = append(names, e.Event)
+	}
+	return names
+}
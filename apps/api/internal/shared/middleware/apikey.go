@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries an API key for read-only integrations
+const APIKeyHeader = "X-API-Key"
+
+// API key scopes
+const (
+	ScopeReadNutrition    = "read:nutrition"
+	ScopeReadMeasurements = "read:measurements"
+)
+
+// Scopes lists the scopes an API key can be granted
+var Scopes = []string{ScopeReadNutrition, ScopeReadMeasurements}
+
+// ErrInvalidAPIKey is returned by an APIKeyResolver for unknown and revoked keys
+var ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+
+// APIKeyPrincipal is the user an API key acts for and what it may read
+type APIKeyPrincipal struct {
+	UserID int64
+	Email  string
+	Role   string
+	Scopes []string
+}
+
+// APIKeyResolver looks up the owner of an API key (implemented by
+// users.APIKeyService)
+type APIKeyResolver interface {
+	ResolveAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error)
+}
+
+// authenticateAPIKey sets the user of an X-API-Key request. Keys are
+// read-only: they authenticate GET and HEAD requests only. It reports false
+// after responding with an error.
+func authenticateAPIKey(c *gin.Context, keys APIKeyResolver, key string) bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		response.Error(c, http.StatusForbidden, "API-ключ даёт доступ только на чтение")
+		c.Abort()
+		return false
+	}
+
+	principal, err := keys.ResolveAPIKey(c.Request.Context(), key)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		response.Error(c, http.StatusUnauthorized, "Неверный или отозванный API-ключ")
+		c.Abort()
+		return false
+	}
+	if err != nil {
+		response.InternalError(c, "Не удалось проверить API-ключ")
+		c.Abort()
+		return false
+	}
+
+	c.Set("user_id", principal.UserID)
+	c.Set("user_email", principal.Email)
+	c.Set("user_role", principal.Role)
+	c.Set("auth_scopes", principal.Scopes)
+	return true
+}
+
+// RequireScope middleware restricts API key requests to keys granted scope.
+// Requests authenticated with a session token are not restricted.
+// Must be applied AFTER RequireAuth middleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scopes, isKey := c.Get("auth_scopes")
+		if !isKey {
+			c.Next()
+			return
+		}
+
+		granted, _ := scopes.([]string)
+		if !slices.Contains(granted, scope) {
+			response.Error(c, http.StatusForbidden, "У API-ключа нет доступа к этим данным")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,102 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeKeys resolves "nutrition-key" to a key with read:nutrition; any other
+// key is treated as unknown or revoked
+type fakeKeys struct {
+	err error
+}
+
+func (f *fakeKeys) ResolveAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if key != "nutrition-key" {
+		return nil, ErrInvalidAPIKey
+	}
+	return &APIKeyPrincipal{UserID: 42, Email: "grafana@example.com", Role: "client", Scopes: []string{ScopeReadNutrition}}, nil
+}
+
+func TestRequireAuth_APIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := "test-secret"
+	cfg := &config.Config{JWTSecret: secret}
+
+	claims := jwt.MapClaims{
+		"user_id": int64(123),
+		"email":   "test@example.com",
+		"role":    "client",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+
+	serve := func(keys APIKeyResolver, scope, method string, headers map[string]string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		auth := RequireAuth(cfg)
+		if keys != nil {
+			auth = RequireAuth(cfg, keys)
+		}
+		r.Use(auth, RequireScope(scope))
+		r.Handle(method, "/data", func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{"user_id": c.GetInt64("user_id")})
+		})
+
+		req := httptest.NewRequest(method, "/data", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name    string
+		keys    APIKeyResolver
+		scope   string
+		method  string
+		headers map[string]string
+		status  int
+	}{
+		{"key with the scope", &fakeKeys{}, ScopeReadNutrition, http.MethodGet,
+			map[string]string{APIKeyHeader: "nutrition-key"}, http.StatusOK},
+		{"key without the scope", &fakeKeys{}, ScopeReadMeasurements, http.MethodGet,
+			map[string]string{APIKeyHeader: "nutrition-key"}, http.StatusForbidden},
+		{"revoked key", &fakeKeys{}, ScopeReadNutrition, http.MethodGet,
+			map[string]string{APIKeyHeader: "revoked-key"}, http.StatusUnauthorized},
+		{"key on a write request", &fakeKeys{}, ScopeReadNutrition, http.MethodPost,
+			map[string]string{APIKeyHeader: "nutrition-key"}, http.StatusForbidden},
+		{"key lookup fails", &fakeKeys{err: assert.AnError}, ScopeReadNutrition, http.MethodGet,
+			map[string]string{APIKeyHeader: "nutrition-key"}, http.StatusInternalServerError},
+		{"route without key support", nil, ScopeReadNutrition, http.MethodGet,
+			map[string]string{APIKeyHeader: "nutrition-key"}, http.StatusUnauthorized},
+		{"session token is not limited by scopes", &fakeKeys{}, ScopeReadMeasurements, http.MethodPost,
+			map[string]string{"Authorization": "Bearer " + token}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(tt.keys, tt.scope, tt.method, tt.headers)
+
+			assert.Equal(t, tt.status, w.Code)
+		})
+	}
+
+	t.Run("key acts as its owner", func(t *testing.T) {
+		w := serve(&fakeKeys{}, ScopeReadNutrition, http.MethodGet, map[string]string{APIKeyHeader: "nutrition-key"})
+
+		assert.JSONEq(t, `{"user_id":42}`, w.Body.String())
+	})
+}
@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// defaultAttemptCounterCapacity is how many emails and IPs the in-process
+// fallback of the password reset limiter tracks
+const defaultAttemptCounterCapacity = 10000
+
+// attemptCounter counts recent attempts per key in memory. It keeps at most
+// capacity keys, evicting the least recently used one, and forgets attempts
+// older than window. It is safe for concurrent use.
+type attemptCounter struct {
+	mu       sync.Mutex
+	capacity int
+	window   time.Duration
+	entries  map[string]*list.Element
+	order    *list.List // front is the most recently used key
+	now      func() time.Time
+}
+
+type attemptEntry struct {
+	key      string
+	attempts []time.Time // oldest first
+}
+
+func newAttemptCounter(capacity int, window time.Duration) *attemptCounter {
+	return &attemptCounter{
+		capacity: capacity,
+		window:   window,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// add records an attempt for key
+func (c *attemptCounter) add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*attemptEntry)
+		entry.attempts = append(c.prune(entry.attempts, now), now)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	// Keys are ordered by their latest attempt, so expired keys collect at
+	// the back and are dropped here without scanning
+	for back := c.order.Back(); back != nil; back = c.order.Back() {
+		entry := back.Value.(*attemptEntry)
+		if len(c.prune(entry.attempts, now)) > 0 {
+			break
+		}
+		c.order.Remove(back)
+		delete(c.entries, entry.key)
+	}
+
+	if c.order.Len() >= c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*attemptEntry).key)
+	}
+	c.entries[key] = c.order.PushFront(&attemptEntry{key: key, attempts: []time.Time{now}})
+}
+
+// count returns the attempts for key within the window. It does not count
+// as a use of the key for eviction.
+func (c *attemptCounter) count(key string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return 0
+	}
+	entry := el.Value.(*attemptEntry)
+	entry.attempts = c.prune(entry.attempts, c.now())
+	if len(entry.attempts) == 0 {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return 0
+	}
+	return len(entry.attempts)
+}
+
+// oldest returns the time of the oldest attempt for key within the window
+func (c *attemptCounter) oldest(key string) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return time.Time{}, false
+	}
+	attempts := c.prune(el.Value.(*attemptEntry).attempts, c.now())
+	if len(attempts) == 0 {
+		return time.Time{}, false
+	}
+	return attempts[0], true
+}
+
+// len returns the number of tracked keys
+func (c *attemptCounter) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// prune drops the attempts that fell out of the window
+func (c *attemptCounter) prune(attempts []time.Time, now time.Time) []time.Time {
+	cutoff := now.Add(-c.window)
+	i := 0
+	for i < len(attempts) && !attempts[i].After(cutoff) {
+		i++
+	}
+	return attempts[i:]
+}
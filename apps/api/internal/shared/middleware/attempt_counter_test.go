@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttemptCounter(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	newCounter := func(capacity int) *attemptCounter {
+		c := newAttemptCounter(capacity, time.Hour)
+		c.now = func() time.Time { return now }
+		return c
+	}
+
+	t.Run("counts attempts per key", func(t *testing.T) {
+		c := newCounter(10)
+		c.add("email:a@example.com")
+		c.add("email:a@example.com")
+		c.add("ip:10.0.0.1")
+
+		assert.Equal(t, 2, c.count("email:a@example.com"))
+		assert.Equal(t, 1, c.count("ip:10.0.0.1"))
+		assert.Equal(t, 0, c.count("email:b@example.com"))
+	})
+
+	t.Run("forgets attempts after the window", func(t *testing.T) {
+		c := newCounter(10)
+		c.add("ip:10.0.0.1")
+		c.now = func() time.Time { return now.Add(30 * time.Minute) }
+		c.add("ip:10.0.0.1")
+
+		c.now = func() time.Time { return now.Add(time.Hour) }
+		assert.Equal(t, 1, c.count("ip:10.0.0.1"))
+
+		c.now = func() time.Time { return now.Add(2 * time.Hour) }
+		assert.Equal(t, 0, c.count("ip:10.0.0.1"))
+		assert.Equal(t, 0, c.len(), "expired keys are dropped")
+	})
+
+	t.Run("drops expired keys when adding", func(t *testing.T) {
+		c := newCounter(10)
+		c.add("ip:10.0.0.1")
+		c.add("ip:10.0.0.2")
+
+		c.now = func() time.Time { return now.Add(2 * time.Hour) }
+		c.add("ip:10.0.0.3")
+
+		assert.Equal(t, 1, c.len())
+	})
+
+	t.Run("evicts the least recently used key", func(t *testing.T) {
+		c := newCounter(2)
+		c.add("ip:10.0.0.1")
+		c.add("ip:10.0.0.2")
+		c.add("ip:10.0.0.1")
+		c.add("ip:10.0.0.3")
+
+		assert.Equal(t, 2, c.len())
+		assert.Equal(t, 2, c.count("ip:10.0.0.1"))
+		assert.Equal(t, 0, c.count("ip:10.0.0.2"))
+		assert.Equal(t, 1, c.count("ip:10.0.0.3"))
+	})
+
+	t.Run("concurrent use", func(t *testing.T) {
+		c := newAttemptCounter(100, time.Hour)
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					c.add(fmt.Sprintf("ip:10.0.0.%d", i%5))
+					c.count(fmt.Sprintf("ip:10.0.0.%d", j%5))
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		total := 0
+		for i := 0; i < 5; i++ {
+			total += c.count(fmt.Sprintf("ip:10.0.0.%d", i))
+		}
+		assert.Equal(t, 1000, total)
+	})
+}
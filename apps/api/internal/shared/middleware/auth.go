@@ -15,12 +15,27 @@ type UserClaims struct {
 	UserID int64  `json:"user_id"`
 	Email  string `json:"email"`
 	Role   string `json:"role"`
+	// TokenVersion is checked against the database by RequireTokenVersion
+	TokenVersion int `json:"token_version"`
+	// SessionID is the refresh token session the token was issued in; tokens
+	// from before sessions were tracked have none
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// RequireAuth middleware validates JWT token
-func RequireAuth(cfg *config.Config) gin.HandlerFunc {
+// RequireAuth middleware validates JWT token. When an APIKeyResolver is
+// given, requests may authenticate with an X-API-Key header instead; such
+// requests are read-only and carry the key's scopes, so routes accepting
+// keys must also apply RequireScope.
+func RequireAuth(cfg *config.Config, keys ...APIKeyResolver) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if key := c.GetHeader(APIKeyHeader); key != "" && len(keys) > 0 {
+			if authenticateAPIKey(c, keys[0], key) {
+				c.Next()
+			}
+			return
+		}
+
 		// Get token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -52,9 +67,7 @@ func RequireAuth(cfg *config.Config) gin.HandlerFunc {
 
 		// Extract claims
 		if claims, ok := token.Claims.(*UserClaims); ok {
-			c.Set("user_id", claims.UserID)
-			c.Set("user_email", claims.Email)
-			c.Set("user_role", claims.Role)
+			setClaims(c, claims)
 		} else {
 			response.Error(c, http.StatusUnauthorized, "Неверные данные токена")
 			c.Abort()
@@ -65,6 +78,40 @@ func RequireAuth(cfg *config.Config) gin.HandlerFunc {
 	}
 }
 
+// OptionalAuth sets the user like RequireAuth when the request carries a
+// valid bearer token. Requests without one, or with an invalid one, continue
+// anonymously; handlers tell them apart by the absence of "user_id".
+func OptionalAuth(cfg *config.Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			c.Next()
+			return
+		}
+
+		token, err := jwt.ParseWithClaims(tokenString, &UserClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(cfg.JWTSecret), nil
+		})
+		if err == nil && token.Valid {
+			if claims, ok := token.Claims.(*UserClaims); ok {
+				setClaims(c, claims)
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func setClaims(c *gin.Context, claims *UserClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.Email)
+	c.Set("user_role", claims.Role)
+	c.Set("token_version", claims.TokenVersion)
+	if claims.SessionID != "" {
+		c.Set("session_id", claims.SessionID)
+	}
+}
+
 // RequireRole middleware checks user role
 func RequireRole(roles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
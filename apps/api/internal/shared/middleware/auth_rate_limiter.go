@@ -1,10 +1,10 @@
 package middleware
 
 import (
-	"net/http"
 	"sync"
 	"time"
 
+	"github.com/burcev/api/internal/shared/response"
 	"github.com/gin-gonic/gin"
 )
 
@@ -17,6 +17,15 @@ type authLimitConfig struct {
 var authLimitConfigs = map[string]authLimitConfig{
 	"login":    {maxRequests: 10, window: 15 * time.Minute},
 	"register": {maxRequests: 5, window: time.Hour},
+	// Public, unauthenticated status page data; responses are cached for 30s anyway
+	"public_status": {maxRequests: 20, window: time.Minute},
+	// Public frontend log collection; the frontend batches entries, so a
+	// healthy client sends far fewer requests than this
+	"frontend_logs": {maxRequests: 60, window: time.Minute},
+	// Public progress shares opened by token; also slows token guessing
+	"public_share": {maxRequests: 30, window: time.Minute},
+	// Email change confirmations opened from the emailed link
+	"confirm_email": {maxRequests: 10, window: 15 * time.Minute},
 }
 
 // AuthRateLimiter is an in-memory sliding window rate limiter for auth endpoints.
@@ -24,6 +33,11 @@ type AuthRateLimiter struct {
 	// mu protects the map of per-endpoint maps.
 	mu      sync.Mutex
 	buckets map[string]*sync.Map // endpoint -> *sync.Map{ip -> []time.Time}
+
+	// onLimited, if set, is called once per lockout: on the first rejected
+	// request, not on every retry while the IP stays blocked.
+	onLimited func(c *gin.Context, endpoint string)
+	lockouts  sync.Map // endpoint + "|" + ip -> time.Time the lockout ends
 }
 
 // NewAuthRateLimiter creates a new AuthRateLimiter.
@@ -38,8 +52,15 @@ func NewAuthRateLimiter() *AuthRateLimiter {
 	return rl
 }
 
+// OnLimited registers a callback for the start of each lockout, e.g. to
+// write an audit record. It must be called before serving requests.
+func (rl *AuthRateLimiter) OnLimited(fn func(c *gin.Context, endpoint string)) {
+	rl.onLimited = fn
+}
+
 // Limit returns a Gin middleware that enforces rate limiting for the given endpoint.
-// Supported endpoints: "login", "register".
+// Supported endpoints: "login", "register", "public_status", "frontend_logs",
+// "public_share", "confirm_email".
 func (rl *AuthRateLimiter) Limit(endpoint string) gin.HandlerFunc {
 	cfg, ok := authLimitConfigs[endpoint]
 	if !ok {
@@ -75,10 +96,9 @@ func (rl *AuthRateLimiter) Limit(endpoint string) gin.HandlerFunc {
 		if len(valid) >= cfg.maxRequests {
 			// Store pruned slice (without the new request) and reject.
 			bucket.Store(ip, valid)
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
-				"status":  "error",
-				"message": "Слишком много попыток. Попробуйте позже.",
-			})
+			until := valid[0].Add(cfg.window)
+			rl.reportLockout(c, endpoint, ip, now, until)
+			response.RateLimited(c, "Слишком много попыток. Попробуйте позже.", until.Sub(now))
 			return
 		}
 
@@ -89,3 +109,16 @@ func (rl *AuthRateLimiter) Limit(endpoint string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// reportLockout calls onLimited unless this lockout was already reported
+func (rl *AuthRateLimiter) reportLockout(c *gin.Context, endpoint, ip string, now, until time.Time) {
+	if rl.onLimited == nil {
+		return
+	}
+	key := endpoint + "|" + ip
+	if prev, ok := rl.lockouts.Load(key); ok && now.Before(prev.(time.Time)) {
+		return
+	}
+	rl.lockouts.Store(key, until)
+	rl.onLimited(c, endpoint)
+}
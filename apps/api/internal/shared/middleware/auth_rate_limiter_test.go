@@ -87,3 +87,66 @@ func TestRegisterRateLimit_BlocksAfterMaxRequests(t *testing.T) {
 		t.Fatalf("request 6: expected 429 got %d", code)
 	}
 }
+
+// TestPublicStatusRateLimit_BlocksAfterMaxRequests fires 21 requests and asserts
+// the 21st is rejected (public status limit is 20 per minute).
+func TestPublicStatusRateLimit_BlocksAfterMaxRequests(t *testing.T) {
+	router := newTestRouter("public_status")
+	const max = 20
+
+	for i := range max {
+		code := fireRequest(router, "172.16.0.2")
+		if code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 got %d", i+1, code)
+		}
+	}
+
+	code := fireRequest(router, "172.16.0.2")
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("request 21: expected 429 got %d", code)
+	}
+}
+
+// TestFrontendLogsRateLimit_BlocksAfterMaxRequests fires 61 requests and
+// asserts the 61st is rejected (frontend log limit is 60 per minute).
+func TestFrontendLogsRateLimit_BlocksAfterMaxRequests(t *testing.T) {
+	router := newTestRouter("frontend_logs")
+	const max = 60
+
+	for i := range max {
+		code := fireRequest(router, "172.16.0.3")
+		if code != http.StatusOK {
+			t.Fatalf("request %d: expected 200 got %d", i+1, code)
+		}
+	}
+
+	code := fireRequest(router, "172.16.0.3")
+	if code != http.StatusTooManyRequests {
+		t.Fatalf("request 61: expected 429 got %d", code)
+	}
+	if code := fireRequest(router, "172.16.0.4"); code != http.StatusOK {
+		t.Fatalf("other IP: expected 200 got %d", code)
+	}
+}
+
+// TestLoginRateLimit_ReportsLockoutOnce checks that OnLimited fires on the
+// first rejected request only, not on every retry while blocked.
+func TestLoginRateLimit_ReportsLockoutOnce(t *testing.T) {
+	rl := NewAuthRateLimiter()
+	var reported []string
+	rl.OnLimited(func(c *gin.Context, endpoint string) {
+		reported = append(reported, endpoint+" "+c.ClientIP())
+	})
+	router := gin.New()
+	router.POST("/test", rl.Limit("login"), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	for i := 0; i < 13; i++ {
+		fireRequest(router, "10.0.0.5")
+	}
+
+	if len(reported) != 1 || reported[0] != "login 10.0.0.5" {
+		t.Fatalf("expected one lockout report for 10.0.0.5, got %v", reported)
+	}
+}
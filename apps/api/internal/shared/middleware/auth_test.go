@@ -113,6 +113,55 @@ func TestAuthMiddleware(t *testing.T) {
 	}
 }
 
+func TestOptionalAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	secret := "test-secret"
+	cfg := &config.Config{JWTSecret: secret}
+
+	claims := jwt.MapClaims{
+		"user_id": int64(123),
+		"email":   "test@example.com",
+		"role":    "client",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	validToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	foreignToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("other-secret"))
+
+	tests := []struct {
+		name       string
+		header     string
+		wantUserID interface{}
+	}{
+		{"valid token", "Bearer " + validToken, int64(123)},
+		{"no header", "", nil},
+		{"not a bearer token", "Basic dXNlcjpwYXNz", nil},
+		{"invalid signature", "Bearer " + foreignToken, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+
+			var userID interface{}
+			r.Use(OptionalAuth(cfg))
+			r.GET("/test", func(c *gin.Context) {
+				userID, _ = c.Get("user_id")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code, "anonymous requests are not rejected")
+			assert.Equal(t, tt.wantUserID, userID)
+		})
+	}
+}
+
 func TestRoleMiddleware(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipWriters reuses compressors between responses; a gzip.Writer holds
+// several hundred kilobytes of state
+var gzipWriters = sync.Pool{
+	New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	},
+}
+
+// Compress gzips responses of at least minSize bytes for clients that send
+// Accept-Encoding: gzip. Smaller responses, responses without a body (304)
+// and already compressed content such as images are sent as they are.
+// Routes listed in skip (by their Gin route pattern, e.g.
+// "/api/v1/photos/:id") are never touched, so streamed files keep their
+// Content-Length.
+//
+// The response is buffered until minSize bytes are written, so register
+// Compress inside Logger: Logger then reports the bytes actually sent.
+func Compress(minSize int, skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(skip, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		// The body depends on Accept-Encoding whether or not this one is compressed
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &gzipResponseWriter{ResponseWriter: original, minSize: minSize}
+		c.Writer = w
+
+		defer func() {
+			c.Writer = original
+			if r := recover(); r != nil {
+				// Drop the buffered half-response so Recovery can answer 500
+				w.release()
+				panic(r)
+			}
+			w.finish()
+		}()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			continue
+		}
+		// "gzip;q=0" explicitly refuses it
+		for _, param := range strings.Split(params, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(name, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil && q <= 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressible reports whether a content type benefits from gzip. Images,
+// video, audio and archives are already compressed.
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	switch {
+	case mediaType == "":
+		return false
+	case strings.HasPrefix(mediaType, "text/"):
+		// Server-sent events have to reach the client as they are written
+		return mediaType != "text/event-stream"
+	case mediaType == "application/json", strings.HasSuffix(mediaType, "+json"),
+		mediaType == "application/xml", strings.HasSuffix(mediaType, "+xml"),
+		mediaType == "application/javascript", mediaType == "image/svg+xml",
+		// Newline-delimited JSON streams are flushed through the compressor
+		mediaType == "application/x-ndjson":
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it knows whether
+// the response is large enough to compress, then either streams it through
+// a gzip.Writer or passes it on unchanged
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize int
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+// start decides how the response is sent and writes out what was buffered
+func (w *gzipResponseWriter) start() error {
+	w.decided = true
+
+	status := w.ResponseWriter.Status()
+	header := w.ResponseWriter.Header()
+	if len(w.buf) > 0 && status != http.StatusNoContent && status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" && compressible(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.gz != nil {
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	switch {
+	case w.gz != nil:
+		return w.gz.Write(p)
+	case w.decided:
+		return w.ResponseWriter.Write(p)
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.minSize {
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports true as soon as the handler wrote a body, even while it
+// is still buffered
+func (w *gzipResponseWriter) Written() bool {
+	return len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// WriteHeaderNow sends the header of a response without a body as is
+func (w *gzipResponseWriter) WriteHeaderNow() {
+	if !w.decided {
+		_ = w.start()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+// Flush sends what has been written so far, compressing it if the threshold
+// was not reached yet
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		_ = w.start()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish sends a response that stayed below the threshold and closes the
+// compressor of one that did not
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		w.decided = true
+		if len(w.buf) > 0 {
+			_, _ = w.ResponseWriter.Write(w.buf)
+			w.buf = nil
+		}
+		return
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+		w.release()
+	}
+}
+
+// release returns the compressor to the pool and drops buffered output
+func (w *gzipResponseWriter) release() {
+	w.buf = nil
+	if w.gz != nil {
+		w.gz.Reset(nil)
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
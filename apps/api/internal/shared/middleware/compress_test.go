@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testGzipMinSize = 1024
+
+// historyPayload resembles a nutrition history response with n entries
+func historyPayload(n int) gin.H {
+	entries := make([]gin.H, n)
+	for i := range entries {
+		entries[i] = gin.H{
+			"id":       fmt.Sprintf("00000000-0000-4000-8000-%012d", i),
+			"date":     "2026-01-26",
+			"meal":     "lunch",
+			"food":     "Борщ с хлебом",
+			"calories": 350, "protein": 15, "carbs": 45, "fat": 12,
+		}
+	}
+	return gin.H{"entries": entries}
+}
+
+func setupCompressRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(testGzipMinSize, "/photos/:id"))
+	router.Use(ErrorHandler(logger.New()))
+	router.GET("/history", func(c *gin.Context) {
+		response.Success(c, http.StatusOK, historyPayload(50))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		response.Success(c, http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/cached", func(c *gin.Context) {
+		response.CachedJSON(c, http.StatusOK, historyPayload(50))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/x-ndjson")
+		for i := range 100 {
+			_, _ = fmt.Fprintf(c.Writer, "{\"n\":%d}\n", i)
+			if i%10 == 9 {
+				c.Writer.Flush()
+			}
+		}
+	})
+	router.GET("/photos/:id", func(c *gin.Context) {
+		body := strings.Repeat("x", 4096)
+		c.DataFromReader(http.StatusOK, int64(len(body)), "image/jpeg", strings.NewReader(body), nil)
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(strings.Repeat("x", 4096)))
+	})
+	return router
+}
+
+func get(router http.Handler, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func gunzip(t *testing.T, body []byte) string {
+	t.Helper()
+	zr, err := gzip.NewReader(strings.NewReader(string(body)))
+	require.NoError(t, err)
+	plain, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	return string(plain)
+}
+
+func TestCompress(t *testing.T) {
+	router := setupCompressRouter()
+	acceptGzip := map[string]string{"Accept-Encoding": "gzip, deflate, br"}
+
+	t.Run("large JSON is gzipped", func(t *testing.T) {
+		w := get(router, "/history", acceptGzip)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Empty(t, w.Header().Get("Content-Length"))
+		plain := gunzip(t, w.Body.Bytes())
+		assert.Contains(t, plain, `"status":"success"`)
+		assert.Contains(t, plain, "Борщ с хлебом")
+	})
+
+	t.Run("small response stays uncompressed", func(t *testing.T) {
+		w := get(router, "/small", acceptGzip)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.JSONEq(t, `{"status":"success","data":{"ok":true}}`, w.Body.String())
+	})
+
+	t.Run("NDJSON stream is gzipped across flushes", func(t *testing.T) {
+		w := get(router, "/stream", acceptGzip)
+
+		assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+		lines := strings.Split(strings.TrimSuffix(gunzip(t, w.Body.Bytes()), "\n"), "\n")
+		assert.Len(t, lines, 100)
+		assert.Equal(t, `{"n":99}`, lines[99])
+	})
+
+	t.Run("client without gzip", func(t *testing.T) {
+		w := get(router, "/history", nil)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		assert.Contains(t, w.Body.String(), "Борщ с хлебом")
+	})
+
+	t.Run("gzip refused with q=0", func(t *testing.T) {
+		w := get(router, "/history", map[string]string{"Accept-Encoding": "br, gzip;q=0"})
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+	})
+
+	t.Run("already compressed content type", func(t *testing.T) {
+		w := get(router, "/image", acceptGzip)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Equal(t, 4096, w.Body.Len())
+	})
+
+	t.Run("streamed photo is skipped", func(t *testing.T) {
+		w := get(router, "/photos/1", acceptGzip)
+
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Empty(t, w.Header().Get("Vary"))
+		assert.Equal(t, "4096", w.Header().Get("Content-Length"))
+	})
+
+	t.Run("ETag revalidation still answers 304", func(t *testing.T) {
+		first := get(router, "/cached", acceptGzip)
+		require.Equal(t, "gzip", first.Header().Get("Content-Encoding"))
+		etag := first.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+
+		w := get(router, "/cached", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.Empty(t, w.Body.Bytes())
+	})
+
+	t.Run("panic drops the buffered response", func(t *testing.T) {
+		recovered := gin.New()
+		recovered.Use(Recovery(logger.New(), nil))
+		recovered.Use(Compress(testGzipMinSize))
+		recovered.GET("/panic", func(c *gin.Context) {
+			_, _ = c.Writer.WriteString(strings.Repeat("x", 100))
+			panic("boom")
+		})
+
+		w := get(recovered, "/panic", acceptGzip)
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+		assert.NotContains(t, w.Body.String(), "xxx")
+		assert.Contains(t, w.Body.String(), "INTERNAL_ERROR")
+	})
+}
+
+func TestCompress_LoggedSizeIsSentSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	var loggedSize int
+	router.Use(func(c *gin.Context) {
+		c.Next()
+		loggedSize = c.Writer.Size()
+	})
+	router.Use(Compress(testGzipMinSize))
+	router.GET("/history", func(c *gin.Context) {
+		response.Success(c, http.StatusOK, historyPayload(50))
+	})
+
+	w := get(router, "/history", map[string]string{"Accept-Encoding": "gzip"})
+
+	assert.Equal(t, w.Body.Len(), loggedSize)
+}
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                  false,
+		"gzip":              true,
+		"GZIP":              true,
+		"deflate, gzip":     true,
+		"gzip;q=0.5":        true,
+		"gzip; q=0":         false,
+		"gzip;q=0.000":      false,
+		"br, deflate":       false,
+		"x-gzip-like, br":   false,
+		"identity, gzip;q=": true,
+	} {
+		assert.Equal(t, want, acceptsGzip(header), header)
+	}
+}
+
+// BenchmarkCompress reports the response size with and without gzip for a
+// nutrition history of increasing length
+func BenchmarkCompress(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	for _, entries := range []int{1, 30, 365} {
+		router := gin.New()
+		router.Use(Compress(testGzipMinSize))
+		router.GET("/history", func(c *gin.Context) {
+			response.Success(c, http.StatusOK, historyPayload(entries))
+		})
+
+		for _, encoding := range []string{"identity", "gzip"} {
+			b.Run(fmt.Sprintf("entries=%d/%s", entries, encoding), func(b *testing.B) {
+				headers := map[string]string{"Accept-Encoding": encoding}
+				var size int
+				for b.Loop() {
+					size = get(router, "/history", headers).Body.Len()
+				}
+				b.ReportMetric(float64(size), "bytes/response")
+			})
+		}
+	}
+}
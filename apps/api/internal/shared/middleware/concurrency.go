@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// KeyFunc returns the key concurrent requests are counted under
+type KeyFunc func(c *gin.Context) string
+
+// UserKey counts requests per authenticated user and per client IP for
+// anonymous requests. It must run after RequireAuth to see the user.
+func UserKey(c *gin.Context) string {
+	if userID, ok := c.Get("user_id"); ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// concurrencyRetryAfter is the Retry-After of requests rejected by a
+// concurrency limit. When a slot frees up is unknown; the expensive
+// endpoints behind the limit take about this long.
+const concurrencyRetryAfter = 2 * time.Second
+
+// concurrencyLimiter counts the requests in flight per key
+type concurrencyLimiter struct {
+	limit int
+	key   KeyFunc
+
+	mu       sync.Mutex
+	inFlight map[string]int // keys are removed when their count drops to 0
+}
+
+// ConcurrencyLimit returns a middleware that lets at most n requests with
+// the same key run at once and rejects the rest with 429. Expensive
+// endpoints share one limiter, so a user with several tabs open cannot hold
+// more than n database connections between them.
+func ConcurrencyLimit(n int, key KeyFunc) gin.HandlerFunc {
+	return newConcurrencyLimiter(n, key).handle
+}
+
+func newConcurrencyLimiter(n int, key KeyFunc) *concurrencyLimiter {
+	return &concurrencyLimiter{limit: n, key: key, inFlight: make(map[string]int)}
+}
+
+func (l *concurrencyLimiter) handle(c *gin.Context) {
+	key := l.key(c)
+	if !l.acquire(key) {
+		response.RateLimited(c, fmt.Sprintf("Одновременно можно выполнять не больше %d таких запросов. Дождитесь завершения предыдущих.", l.limit), concurrencyRetryAfter)
+		return
+	}
+	// Deferred so a panicking handler still frees its slot
+	defer l.release(key)
+
+	c.Next()
+}
+
+// acquire takes a slot for key unless all of them are taken
+func (l *concurrencyLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[key] >= l.limit {
+		return false
+	}
+	l.inFlight[key]++
+	return true
+}
+
+// release frees a slot of key
+func (l *concurrencyLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight[key] <= 1 {
+		delete(l.inFlight, key)
+		return
+	}
+	l.inFlight[key]--
+}
+
+// count returns the requests of key in flight
+func (l *concurrencyLimiter) count(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight[key]
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newConcurrencyRouter serves GET /heavy through l. Requests with ?block
+// signal started and wait for release; ?panic makes the handler panic.
+// X-User sets the authenticated user.
+func newConcurrencyRouter(l *concurrencyLimiter, started chan<- struct{}, release <-chan struct{}) *gin.Engine {
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.GET("/heavy", func(c *gin.Context) {
+		if user := c.GetHeader("X-User"); user != "" {
+			id, _ := strconv.ParseInt(user, 10, 64)
+			c.Set("user_id", id)
+		}
+		c.Next()
+	}, l.handle, func(c *gin.Context) {
+		if _, ok := c.GetQuery("panic"); ok {
+			panic("handler failed")
+		}
+		if _, ok := c.GetQuery("block"); ok {
+			started <- struct{}{}
+			<-release
+		}
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func getHeavy(router *gin.Engine, user, query string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/heavy"+query, nil)
+	req.RemoteAddr = "192.168.1.1:1234"
+	if user != "" {
+		req.Header.Set("X-User", user)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestConcurrencyLimit_RejectsOverLimit(t *testing.T) {
+	l := newConcurrencyLimiter(2, UserKey)
+	started, release := make(chan struct{}), make(chan struct{})
+	router := newConcurrencyRouter(l, started, release)
+
+	// Two requests of user 7 are held open
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Go(func() { codes[i] = getHeavy(router, "7", "?block").Code })
+		<-started
+	}
+	require.Equal(t, 2, l.count("user:7"))
+
+	third := getHeavy(router, "7", "")
+	assert.Equal(t, http.StatusTooManyRequests, third.Code)
+	assert.Contains(t, third.Body.String(), "RATE_LIMITED")
+	assert.Equal(t, "2", third.Header().Get("Retry-After"))
+	assert.Contains(t, third.Body.String(), `"retry_after_seconds":2`)
+
+	// Other users and anonymous clients have their own slots
+	assert.Equal(t, http.StatusOK, getHeavy(router, "8", "").Code)
+	assert.Equal(t, http.StatusOK, getHeavy(router, "", "").Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, codes)
+
+	// The counter drains once the requests complete
+	assert.Equal(t, 0, l.count("user:7"))
+	assert.Empty(t, l.inFlight)
+	assert.Equal(t, http.StatusOK, getHeavy(router, "7", "").Code)
+}
+
+func TestConcurrencyLimit_ReleasesOnPanic(t *testing.T) {
+	l := newConcurrencyLimiter(1, UserKey)
+	router := newConcurrencyRouter(l, nil, nil)
+
+	assert.Equal(t, http.StatusInternalServerError, getHeavy(router, "7", "?panic").Code)
+	assert.Empty(t, l.inFlight)
+	assert.Equal(t, http.StatusOK, getHeavy(router, "7", "").Code)
+}
+
+func TestConcurrencyLimit_ReleasesOnAbort(t *testing.T) {
+	router := gin.New()
+	router.GET("/heavy", ConcurrencyLimit(1, UserKey), func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusBadRequest)
+	})
+
+	for range 3 {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/heavy", nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
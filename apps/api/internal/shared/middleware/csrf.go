@@ -0,0 +1,27 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestedWithHeader must accompany requests authenticated by a cookie.
+// Browsers only let same-origin scripts (or origins allowed by CORS) set
+// custom headers, so a forged cross-site form post cannot carry it.
+const RequestedWithHeader = "X-Requested-With"
+
+// RequireRequestedWith rejects requests that carry the named cookie without
+// an X-Requested-With header. Requests without the cookie, such as mobile
+// clients sending tokens in the body, pass through.
+func RequireRequestedWith(cookieName string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, err := c.Cookie(cookieName); err == nil && c.GetHeader(RequestedWithHeader) == "" {
+			response.ErrorCode(c, http.StatusForbidden, response.CodeForbidden, "Отсутствует заголовок "+RequestedWithHeader, nil)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequireRequestedWith(t *testing.T) {
+	router := gin.New()
+	router.POST("/refresh", RequireRequestedWith("refresh_token"), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		cookie bool
+		header string
+		want   int
+	}{
+		{name: "cookie without header", cookie: true, want: http.StatusForbidden},
+		{name: "cookie with header", cookie: true, header: "XMLHttpRequest", want: http.StatusOK},
+		{name: "no cookie", want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/refresh", nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "token"})
+			}
+			if tt.header != "" {
+				req.Header.Set(RequestedWithHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
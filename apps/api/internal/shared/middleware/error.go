@@ -1,32 +1,130 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/burcev/api/internal/shared/response"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 )
 
-// ErrorHandler middleware handles errors
+// ErrorHandler turns the last error a handler attached with c.Error into a
+// response, so handlers can simply `_ = c.Error(err); return`:
+//
+//   - validation.Errors: 400 VALIDATION_FAILED with the fields
+//   - apperrors.ErrNotFound: 404
+//   - apperrors.ErrConflict: 409 CONFLICT with the field of an
+//     *apperrors.ConflictError, or 409 VERSION_CONFLICT with the current
+//     copy of an *apperrors.VersionConflictError
+//   - apperrors.ErrRateLimited (*apperrors.RateLimitError for Retry-After): 429
+//   - apperrors.ErrForbidden: 403, or 403 QUOTA_EXCEEDED with the usage of
+//     an *apperrors.QuotaExceededError
+//   - apperrors.ErrUnauthorized and the credential/token errors: 401
+//   - anything else: 500, without the error text
+//
+// 5xx are logged at error level, 4xx at warn. A response the handler already
+// wrote is left alone.
 func ErrorHandler(log *logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
-		// Check if there are any errors
-		if len(c.Errors) > 0 {
-			err := c.Errors.Last()
+		if len(c.Errors) == 0 {
+			return
+		}
+		err := c.Errors.Last().Err
+		status := errorStatus(err)
+
+		fields := []interface{}{
+			"error", err.Error(),
+			"status", status,
+			"path", c.Request.URL.Path,
+			"method", c.Request.Method,
+			"request_id", c.GetString("request_id"),
+		}
+		if status >= http.StatusInternalServerError {
+			log.Errorw("Request error", fields...)
+		} else {
+			log.Warnw("Request error", fields...)
+		}
+
+		if c.Writer.Written() {
+			return
+		}
+		respondError(c, status, err)
+	}
+}
 
-			log.Errorw("Request error",
-				"error", err.Error(),
-				"path", c.Request.URL.Path,
-				"method", c.Request.Method,
-			)
+// errorStatus maps an error to the HTTP status it is reported with
+func errorStatus(err error) int {
+	var fieldErrs validation.Errors
+	switch {
+	case errors.As(err, &fieldErrs):
+		return http.StatusBadRequest
+	case errors.Is(err, apperrors.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperrors.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperrors.ErrRateLimited), errors.Is(err, apperrors.ErrTooManyAttempts):
+		return http.StatusTooManyRequests
+	case errors.Is(err, apperrors.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperrors.ErrUnauthorized),
+		errors.Is(err, apperrors.ErrInvalidCredentials),
+		errors.Is(err, apperrors.ErrTokenInvalid),
+		errors.Is(err, apperrors.ErrTokenExpired):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
 
-			// If response not already written
-			if !c.Writer.Written() {
-				response.Error(c, http.StatusInternalServerError, "Внутренняя ошибка сервера")
-			}
+func respondError(c *gin.Context, status int, err error) {
+	switch status {
+	case http.StatusBadRequest:
+		validation.Respond(c, err)
+	case http.StatusNotFound:
+		response.ErrorCode(c, status, response.CodeNotFound, "Не найдено", nil)
+	case http.StatusConflict:
+		var stale *apperrors.VersionConflictError
+		if errors.As(err, &stale) {
+			response.VersionConflict(c, "Запись изменена на другом устройстве", stale.Current)
+			return
+		}
+		var field string
+		var conflict *apperrors.ConflictError
+		if errors.As(err, &conflict) {
+			field = conflict.Field
+		}
+		response.Conflict(c, "Такое значение уже существует", field)
+	case http.StatusTooManyRequests:
+		var retryAfter time.Duration
+		var rateErr *apperrors.RateLimitError
+		if errors.As(err, &rateErr) {
+			retryAfter = rateErr.RetryAfter
+		}
+		response.RateLimited(c, "Слишком много запросов. Попробуйте позже.", retryAfter)
+	case http.StatusForbidden:
+		var quota *apperrors.QuotaExceededError
+		if errors.As(err, &quota) {
+			response.QuotaExceeded(c, "Превышена квота", response.QuotaDetails{
+				Resource: quota.Resource,
+				Used:     quota.Used,
+				Limit:    quota.Limit,
+			})
+			return
+		}
+		response.ErrorCode(c, status, response.CodeForbidden, "Доступ запрещён", nil)
+	case http.StatusUnauthorized:
+		if errors.Is(err, apperrors.ErrInvalidCredentials) {
+			response.ErrorCode(c, status, response.CodeAuthInvalidCredentials, "Неверные учетные данные", nil)
+			return
 		}
+		response.ErrorCode(c, status, response.CodeUnauthorized, "Требуется авторизация", nil)
+	default:
+		response.ErrorCode(c, status, response.CodeInternal, "Внутренняя ошибка сервера", nil)
 	}
 }
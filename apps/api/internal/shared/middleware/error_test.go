@@ -1,14 +1,20 @@
 package middleware
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/burcev/api/internal/shared/apperrors"
 	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/validation"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestErrorHandlerMiddleware(t *testing.T) {
@@ -29,6 +35,7 @@ func TestErrorHandlerMiddleware(t *testing.T) {
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "Внутренняя ошибка сервера")
+		assert.NotContains(t, w.Body.String(), "test error", "error text is not exposed")
 	})
 
 	t.Run("does not interfere with successful requests", func(t *testing.T) {
@@ -80,3 +87,155 @@ func TestErrorHandlerMiddleware(t *testing.T) {
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 	})
 }
+
+func TestErrorHandlerMiddleware_TypedErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	log := logger.New()
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"validation", validation.Errors{"meal": "Обязательное поле"}, http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"wrapped not found", fmt.Errorf("load entry: %w", apperrors.ErrNotFound), http.StatusNotFound, "NOT_FOUND"},
+		{"conflict", &apperrors.ConflictError{Constraint: "users_email_key", Field: "email"}, http.StatusConflict, "CONFLICT"},
+		{"version conflict", &apperrors.VersionConflictError{Current: map[string]int{"version": 3}}, http.StatusConflict, "VERSION_CONFLICT"},
+		{"rate limited", apperrors.ErrRateLimited, http.StatusTooManyRequests, "RATE_LIMITED"},
+		{"too many attempts", apperrors.ErrTooManyAttempts, http.StatusTooManyRequests, "RATE_LIMITED"},
+		{"forbidden", apperrors.ErrForbidden, http.StatusForbidden, "FORBIDDEN"},
+		{"quota exceeded", &apperrors.QuotaExceededError{Resource: "webhooks", Used: 5, Limit: 5}, http.StatusForbidden, "QUOTA_EXCEEDED"},
+		{"unauthorized", apperrors.ErrUnauthorized, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"expired token", apperrors.ErrTokenExpired, http.StatusUnauthorized, "UNAUTHORIZED"},
+		{"invalid credentials", apperrors.ErrInvalidCredentials, http.StatusUnauthorized, "AUTH_INVALID_CREDENTIALS"},
+		{"unknown", errors.New("pq: connection refused"), http.StatusInternalServerError, "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			_, r := gin.CreateTestContext(w)
+
+			r.Use(func(c *gin.Context) { c.Set("request_id", "req-42") })
+			r.Use(ErrorHandler(log))
+			r.GET("/test", func(c *gin.Context) {
+				_ = c.Error(tt.err)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			var resp map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, "error", resp["status"])
+			assert.Equal(t, tt.wantCode, resp["code"])
+			assert.Equal(t, "req-42", resp["request_id"])
+			assert.NotContains(t, resp["message"], tt.err.Error())
+		})
+	}
+
+	t.Run("validation details", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.GET("/test", func(c *gin.Context) {
+			_ = c.Error(validation.Errors{"meal": "Обязательное поле"})
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]interface{}{
+			"fields": map[string]interface{}{"meal": "Обязательное поле"},
+		}, resp["details"])
+	})
+
+	t.Run("conflict field", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.GET("/test", func(c *gin.Context) {
+			_ = c.Error(fmt.Errorf("save food: %w", &apperrors.ConflictError{Constraint: "idx_food_items_barcode_unique", Field: "barcode"}))
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]interface{}{"field": "barcode"}, resp["details"])
+	})
+
+	t.Run("version conflict current copy", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.PUT("/test", func(c *gin.Context) {
+			_ = c.Error(&apperrors.VersionConflictError{Current: map[string]interface{}{"food": "Суп", "version": 3}})
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/test", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]interface{}{
+			"current": map[string]interface{}{"food": "Суп", "version": 3.0},
+		}, resp["details"])
+	})
+
+	t.Run("quota usage", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.POST("/test", func(c *gin.Context) {
+			_ = c.Error(fmt.Errorf("create entry: %w", &apperrors.QuotaExceededError{Resource: "entries_per_day", Used: 200, Limit: 200}))
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/test", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, map[string]interface{}{
+			"resource": "entries_per_day", "used": 200.0, "limit": 200.0,
+		}, resp["details"])
+	})
+
+	t.Run("retry after from RateLimitError", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.GET("/test", func(c *gin.Context) {
+			_ = c.Error(fmt.Errorf("send code: %w", &apperrors.RateLimitError{RetryAfter: 90 * time.Second}))
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "90", w.Header().Get("Retry-After"))
+	})
+
+	t.Run("last error decides", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+
+		r.Use(ErrorHandler(log))
+		r.GET("/test", func(c *gin.Context) {
+			_ = c.Error(errors.New("first error"))
+			_ = c.Error(apperrors.ErrNotFound)
+		})
+
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
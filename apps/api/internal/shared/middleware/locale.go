@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/burcev/api/internal/shared/i18n"
+	"github.com/gin-gonic/gin"
+)
+
+// LocalePreference returns the language saved in a user's profile
+type LocalePreference func(ctx context.Context, userID int64) (string, error)
+
+// Locale decides the language of localized responses. Authenticated users
+// get the language of their profile; everyone else, and users whose profile
+// has no supported language or cannot be read, the one Accept-Language
+// prefers, Russian by default. The locale is resolved when a handler first
+// asks for it, after authentication, so the profile is only read for
+// localized responses.
+func Locale(preference LocalePreference) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		i18n.SetResolver(c, func() i18n.Locale {
+			if locale, ok := profileLocale(c, preference); ok {
+				return locale
+			}
+			return i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+		})
+		c.Next()
+	}
+}
+
+// profileLocale returns the locale saved in the profile of the authenticated user
+func profileLocale(c *gin.Context, preference LocalePreference) (i18n.Locale, bool) {
+	userID, ok := c.Get("user_id")
+	if !ok || preference == nil {
+		return "", false
+	}
+	id, ok := userID.(int64)
+	if !ok {
+		return "", false
+	}
+	lang, err := preference(c.Request.Context(), id)
+	if err != nil {
+		return "", false
+	}
+	return i18n.Match(lang)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/i18n"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocale(t *testing.T) {
+	profiles := map[int64]string{1: "en", 2: "ru", 3: "de"}
+	preference := func(ctx context.Context, userID int64) (string, error) {
+		if lang, ok := profiles[userID]; ok {
+			return lang, nil
+		}
+		return "", errors.New("settings unavailable")
+	}
+
+	tests := []struct {
+		name           string
+		userID         int64
+		acceptLanguage string
+		want           i18n.Locale
+	}{
+		{name: "anonymous without header", want: i18n.RU},
+		{name: "anonymous with header", acceptLanguage: "en-US,en;q=0.9", want: i18n.EN},
+		{name: "anonymous with unsupported language", acceptLanguage: "de", want: i18n.RU},
+		{name: "profile wins over header", userID: 2, acceptLanguage: "en", want: i18n.RU},
+		{name: "profile language", userID: 1, want: i18n.EN},
+		{name: "unsupported profile language", userID: 3, acceptLanguage: "en", want: i18n.EN},
+		{name: "unreadable profile", userID: 4, acceptLanguage: "en", want: i18n.EN},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got i18n.Locale
+			router := gin.New()
+			router.Use(Locale(preference))
+			router.GET("/", func(c *gin.Context) {
+				if tt.userID != 0 {
+					c.Set("user_id", tt.userID)
+				}
+				got = i18n.Of(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
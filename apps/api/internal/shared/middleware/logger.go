@@ -1,15 +1,50 @@
 package middleware
 
 import (
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
+	"github.com/burcev/api/internal/config"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// Logger middleware logs HTTP requests with detailed information
-func Logger(log *logger.Logger) gin.HandlerFunc {
+// Redacted replaces the values of sensitive query parameters and headers in
+// request logs
+const Redacted = "[REDACTED]"
+
+// RequestLogConfig controls what the Logger middleware writes. The zero
+// value logs every request and redacts nothing.
+type RequestLogConfig struct {
+	// RedactKeys are matched case-insensitively against query parameter and
+	// header names; a name containing one of them has its value redacted
+	RedactKeys []string
+	// SampleRate logs 1 in SampleRate successful requests, picked by request
+	// id; 0 and 1 log them all. Errors and slow requests are always logged.
+	SampleRate int
+	// SlowThreshold is how long a request may take before it is logged
+	// regardless of sampling; 0 disables it
+	SlowThreshold time.Duration
+}
+
+// RequestLogConfigFrom returns the request logging settings of the
+// application config
+func RequestLogConfigFrom(cfg *config.Config) RequestLogConfig {
+	return RequestLogConfig{
+		RedactKeys:    cfg.LogRedactKeys,
+		SampleRate:    cfg.LogSampleRate,
+		SlowThreshold: cfg.LogSlowRequest,
+	}
+}
+
+// Logger middleware logs HTTP requests with detailed information. Sensitive
+// query values are redacted; server errors also log the request headers,
+// redacted the same way.
+func Logger(log *logger.Logger, opts RequestLogConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Use client-provided request ID for cross-layer tracing, or generate one
 		requestID := c.GetHeader("X-Request-Id")
@@ -39,6 +74,10 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 		statusCode := c.Writer.Status()
 		bodySize := c.Writer.Size()
 
+		if !shouldLog(requestID, statusCode, duration, opts) {
+			return
+		}
+
 		// Get user ID if authenticated
 		userID, _ := c.Get("user_id")
 
@@ -49,6 +88,9 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 			"user_agent": userAgent,
 			"body_size":  bodySize,
 		}
+		if opts.SampleRate > 1 {
+			fields["sample_rate"] = opts.SampleRate
+		}
 
 		// Preserve client-generated request ID for cross-proxy tracing
 		if clientReqID := c.GetHeader("X-Client-Request-Id"); clientReqID != "" {
@@ -56,13 +98,17 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 		}
 
 		if query != "" {
-			fields["query"] = query
+			fields["query"] = RedactQuery(query, opts.RedactKeys)
 		}
 
 		if userID != nil {
 			fields["user_id"] = userID
 		}
 
+		if statusCode >= http.StatusInternalServerError {
+			fields["headers"] = RedactHeaders(c.Request.Header, opts.RedactKeys)
+		}
+
 		// Check for errors
 		if len(c.Errors) > 0 {
 			fields["errors"] = c.Errors.String()
@@ -72,3 +118,63 @@ func Logger(log *logger.Logger) gin.HandlerFunc {
 		log.LogHTTPRequest(method, path, statusCode, duration, fields)
 	}
 }
+
+// shouldLog decides whether a finished request is logged. Errors and slow
+// requests always are; other requests are sampled by a hash of the request
+// id, so the same request id always gets the same decision.
+func shouldLog(requestID string, statusCode int, duration time.Duration, opts RequestLogConfig) bool {
+	if opts.SampleRate <= 1 || statusCode >= http.StatusBadRequest {
+		return true
+	}
+	if opts.SlowThreshold > 0 && duration >= opts.SlowThreshold {
+		return true
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(requestID))
+	return h.Sum32()%uint32(opts.SampleRate) == 0
+}
+
+// RedactQuery returns rawQuery with the values of parameters whose name
+// matches keys replaced by Redacted. Parameter order and encoding are kept.
+func RedactQuery(rawQuery string, keys []string) string {
+	if len(keys) == 0 || rawQuery == "" {
+		return rawQuery
+	}
+	params := strings.Split(rawQuery, "&")
+	for i, param := range params {
+		rawName, _, hasValue := strings.Cut(param, "=")
+		name, err := url.QueryUnescape(rawName)
+		if err != nil {
+			name = rawName
+		}
+		if hasValue && isRedacted(name, keys) {
+			params[i] = rawName + "=" + Redacted
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// RedactHeaders returns the headers as name to comma-joined values, with the
+// values of headers whose name matches keys replaced by Redacted
+func RedactHeaders(headers http.Header, keys []string) map[string]string {
+	redacted := make(map[string]string, len(headers))
+	for name, values := range headers {
+		if isRedacted(name, keys) {
+			redacted[name] = Redacted
+			continue
+		}
+		redacted[name] = strings.Join(values, ", ")
+	}
+	return redacted
+}
+
+// isRedacted reports whether name contains one of keys, ignoring case
+func isRedacted(name string, keys []string) bool {
+	name = strings.ToLower(name)
+	for _, key := range keys {
+		if key != "" && strings.Contains(name, strings.ToLower(key)) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package middleware
 
 import (
+	"bytes"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/gin-gonic/gin"
@@ -18,7 +21,7 @@ func TestLoggerMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		_, r := gin.CreateTestContext(w)
 
-		r.Use(Logger(log))
+		r.Use(Logger(log, RequestLogConfig{}))
 		r.GET("/test", func(c *gin.Context) {
 			// Verify request_id was set in context
 			requestID, exists := c.Get("request_id")
@@ -38,7 +41,7 @@ func TestLoggerMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		_, r := gin.CreateTestContext(w)
 
-		r.Use(Logger(log))
+		r.Use(Logger(log, RequestLogConfig{}))
 		r.GET("/test", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{"message": "success"})
 		})
@@ -53,7 +56,7 @@ func TestLoggerMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		_, r := gin.CreateTestContext(w)
 
-		r.Use(Logger(log))
+		r.Use(Logger(log, RequestLogConfig{}))
 		r.Use(func(c *gin.Context) {
 			c.Set("user_id", "user-123")
 			c.Next()
@@ -72,7 +75,7 @@ func TestLoggerMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		_, r := gin.CreateTestContext(w)
 
-		r.Use(Logger(log))
+		r.Use(Logger(log, RequestLogConfig{}))
 		r.GET("/test", func(c *gin.Context) {
 			_ = c.Error(assert.AnError)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "something went wrong"})
@@ -91,7 +94,7 @@ func TestLoggerMiddleware(t *testing.T) {
 			w := httptest.NewRecorder()
 			_, r := gin.CreateTestContext(w)
 
-			r.Use(Logger(log))
+			r.Use(Logger(log, RequestLogConfig{}))
 			r.Handle(method, "/test", func(c *gin.Context) {
 				c.JSON(http.StatusOK, gin.H{"message": "success"})
 			})
@@ -110,7 +113,7 @@ func TestLoggerMiddleware(t *testing.T) {
 			w := httptest.NewRecorder()
 			_, r := gin.CreateTestContext(w)
 
-			r.Use(Logger(log))
+			r.Use(Logger(log, RequestLogConfig{}))
 			r.GET("/test", func(c *gin.Context) {
 				c.JSON(statusCode, gin.H{"message": "test"})
 			})
@@ -122,3 +125,111 @@ func TestLoggerMiddleware(t *testing.T) {
 		}
 	})
 }
+
+func TestLoggerRedactionAndSampling(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	opts := RequestLogConfig{RedactKeys: []string{"token", "authorization"}, SampleRate: 1000, SlowThreshold: time.Hour}
+
+	serve := func(t *testing.T, status int, target string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		log := logger.New(logger.WithOutput(&buf), logger.WithEncoding(logger.EncodingJSON))
+		_, r := gin.CreateTestContext(httptest.NewRecorder())
+		r.Use(Logger(log, opts))
+		r.GET("/test", func(c *gin.Context) { c.Status(status) })
+
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("X-Request-Id", "req-1")
+		req.Header.Set("Authorization", "Bearer secret-jwt")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		return buf.String()
+	}
+
+	t.Run("redacts the query and headers of a failed request", func(t *testing.T) {
+		out := serve(t, http.StatusInternalServerError, "/test?token=abc123&page=2")
+
+		assert.Contains(t, out, `"query":"token=[REDACTED]&page=2"`)
+		assert.Contains(t, out, `"Authorization":"[REDACTED]"`)
+		assert.NotContains(t, out, "abc123")
+		assert.NotContains(t, out, "secret-jwt")
+	})
+
+	t.Run("samples successful requests by request id", func(t *testing.T) {
+		// fnv32a("req-1") is not a multiple of 1000
+		assert.Empty(t, serve(t, http.StatusOK, "/test"))
+		assert.Contains(t, serve(t, http.StatusNotFound, "/test"), "HTTP request client error")
+	})
+}
+
+func TestShouldLog(t *testing.T) {
+	sampled := RequestLogConfig{SampleRate: 4, SlowThreshold: time.Second}
+
+	t.Run("deterministic per request id", func(t *testing.T) {
+		logged := 0
+		for i := range 400 {
+			id := fmt.Sprintf("req-%d", i)
+			first := shouldLog(id, http.StatusOK, time.Millisecond, sampled)
+			assert.Equal(t, first, shouldLog(id, http.StatusOK, time.Millisecond, sampled), id)
+			if first {
+				logged++
+			}
+		}
+		assert.InDelta(t, 100, logged, 40, "about 1 in 4 requests are logged")
+	})
+
+	t.Run("errors and slow requests are always logged", func(t *testing.T) {
+		for i := range 50 {
+			id := fmt.Sprintf("req-%d", i)
+			assert.True(t, shouldLog(id, http.StatusBadRequest, time.Millisecond, sampled))
+			assert.True(t, shouldLog(id, http.StatusBadGateway, time.Millisecond, sampled))
+			assert.True(t, shouldLog(id, http.StatusOK, time.Second, sampled))
+		}
+	})
+
+	t.Run("no sampling logs everything", func(t *testing.T) {
+		assert.True(t, shouldLog("req-1", http.StatusOK, 0, RequestLogConfig{}))
+		assert.True(t, shouldLog("req-1", http.StatusOK, 0, RequestLogConfig{SampleRate: 1}))
+	})
+}
+
+func TestRedactQuery(t *testing.T) {
+	keys := []string{"token", "password"}
+	tests := []struct {
+		name, query, want string
+	}{
+		{"reset token", "token=abc123", "token=[REDACTED]"},
+		{"keeps order and other values", "page=2&refresh_token=xyz&limit=10", "page=2&refresh_token=[REDACTED]&limit=10"},
+		{"case-insensitive", "Token=abc&NEW_PASSWORD=hunter2", "Token=[REDACTED]&NEW_PASSWORD=[REDACTED]"},
+		{"encoded name", "reset%5Ftoken=abc", "reset%5Ftoken=[REDACTED]"},
+		{"repeated parameter", "token=a&token=b", "token=[REDACTED]&token=[REDACTED]"},
+		{"flag without value", "token&page=1", "token&page=1"},
+		{"nothing sensitive", "q=%D0%B1%D0%BE%D1%80%D1%89", "q=%D0%B1%D0%BE%D1%80%D1%89"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RedactQuery(tt.query, keys))
+		})
+	}
+
+	assert.Equal(t, "token=abc", RedactQuery("token=abc", nil), "no keys redact nothing")
+}
+
+func TestRedactHeaders(t *testing.T) {
+	headers := http.Header{
+		"Authorization":   {"Bearer jwt"},
+		"Cookie":          {"refresh_token=abc"},
+		"X-Api-Key":       {"key"},
+		"Accept-Language": {"ru", "en"},
+	}
+
+	redacted := RedactHeaders(headers, []string{"authorization", "cookie", "api-key"})
+
+	assert.Equal(t, map[string]string{
+		"Authorization":   Redacted,
+		"Cookie":          Redacted,
+		"X-Api-Key":       Redacted,
+		"Accept-Language": "ru, en",
+	}, redacted)
+	assert.Equal(t, "Bearer jwt", headers.Get("Authorization"), "the request headers are not changed")
+}
@@ -3,116 +3,231 @@ package middleware
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 )
 
-// RateLimiter handles rate limiting for password reset requests
+// FailurePolicy decides what the password reset limiter does when the
+// database check fails and the in-memory counters are under the limit
+type FailurePolicy string
+
+const (
+	// FailClosed rejects requests while the database cannot be checked
+	FailClosed FailurePolicy = "closed"
+	// FailOpen admits requests the in-memory counters allow
+	FailOpen FailurePolicy = "open"
+)
+
+// ErrRateLimitUnavailable is returned under FailClosed when the database
+// check fails. Callers must not treat it as a pass.
+var ErrRateLimitUnavailable = errors.New("failed to check rate limit")
+
+// RateLimiter handles rate limiting for password reset requests. The
+// database is the source of truth; attempts are also counted in memory,
+// and those counters decide when the database check fails. They are per
+// instance, so with several instances the fallback is only approximate.
 type RateLimiter struct {
-	db  *sql.DB
-	log *logger.Logger
+	db     *database.DB
+	log    *logger.Logger
+	limits RateLimitConfig
+
+	memory    *attemptCounter
+	fallbacks atomic.Int64
+	now       func() time.Time
 }
 
 // RateLimitConfig defines rate limiting parameters
 type RateLimitConfig struct {
-	EmailLimit    int // 3 requests per email
-	IPLimit       int // 10 requests per IP
-	WindowMinutes int // 60 minutes
+	EmailLimit    int           // requests per email within Window
+	IPLimit       int           // requests per IP within Window
+	Window        time.Duration // sliding window the limits apply to
+	FailurePolicy FailurePolicy // what to do when the database check fails
 }
 
 // DefaultRateLimitConfig returns the default rate limit configuration
 func DefaultRateLimitConfig() RateLimitConfig {
 	return RateLimitConfig{
-		EmailLimit:    3,
-		IPLimit:       10,
-		WindowMinutes: 60,
+		EmailLimit: config.DefaultResetEmailLimit,
+		IPLimit:    config.DefaultResetIPLimit,
+		Window:     config.DefaultResetLimitWindow,
+
+		FailurePolicy: FailurePolicy(config.DefaultResetLimitFailurePolicy),
+	}
+}
+
+// RateLimitConfigFrom returns the password reset limits of the application config
+func RateLimitConfigFrom(cfg *config.Config) RateLimitConfig {
+	return RateLimitConfig{
+		EmailLimit: cfg.ResetEmailLimit,
+		IPLimit:    cfg.ResetIPLimit,
+		Window:     cfg.ResetLimitWindow,
+
+		FailurePolicy: FailurePolicy(cfg.ResetLimitFailurePolicy),
 	}
 }
 
 // NewRateLimiter creates a new rate limiter instance
-func NewRateLimiter(db *sql.DB, log *logger.Logger) *RateLimiter {
+func NewRateLimiter(db *database.DB, log *logger.Logger, limits RateLimitConfig) *RateLimiter {
 	return &RateLimiter{
-		db:  db,
-		log: log,
+		db:     db,
+		log:    log,
+		limits: limits,
+		memory: newAttemptCounter(defaultAttemptCounterCapacity, limits.Window),
+		now:    time.Now,
 	}
 }
 
-// CheckEmailRateLimit checks if the email has exceeded the rate limit
-// Returns error if rate limit is exceeded
-func (rl *RateLimiter) CheckEmailRateLimit(ctx context.Context, email string) error {
-	config := DefaultRateLimitConfig()
+// FallbackCount returns how often a database check failed and the
+// in-memory counters were used instead
+func (rl *RateLimiter) FallbackCount() int64 {
+	return rl.fallbacks.Load()
+}
 
+// CheckEmailRateLimit checks if the email has exceeded the rate limit.
+// Returns *apperrors.RateLimitError if it has.
+func (rl *RateLimiter) CheckEmailRateLimit(ctx context.Context, email string) error {
 	query := `
 		SELECT COUNT(*)
 		FROM password_reset_attempts
 		WHERE email = $1
-		AND attempted_at > NOW() - INTERVAL '1 hour'
+		AND attempted_at > NOW() - make_interval(secs => $2)
 	`
 
 	var count int
-	err := rl.db.QueryRowContext(ctx, query, email).Scan(&count)
+	err := rl.db.QueryRowCtx(ctx, query, email, rl.limits.Window.Seconds()).Scan(&count)
 	if err != nil {
 		rl.log.WithError(err).Error("Failed to check email rate limit",
 			"email", email,
 		)
-		return fmt.Errorf("failed to check rate limit: %w", err)
+		return rl.fallback("email", email, rl.limits.EmailLimit, err)
 	}
 
-	if count >= config.EmailLimit {
+	if count >= rl.limits.EmailLimit {
 		rl.log.LogSecurityEvent("email_rate_limit_exceeded", "high", map[string]interface{}{
 			"email":         email,
 			"attempt_count": count,
-			"limit":         config.EmailLimit,
+			"limit":         rl.limits.EmailLimit,
 		})
-		return fmt.Errorf("rate limit exceeded")
+		return rl.limited(ctx, `
+			SELECT MIN(attempted_at)
+			FROM password_reset_attempts
+			WHERE email = $1
+			AND attempted_at > NOW() - make_interval(secs => $2)
+		`, email)
 	}
 
 	return nil
 }
 
-// CheckIPRateLimit checks if the IP address has exceeded the rate limit
-// Returns error if rate limit is exceeded
+// CheckIPRateLimit checks if the IP address has exceeded the rate limit.
+// Returns *apperrors.RateLimitError if it has.
 func (rl *RateLimiter) CheckIPRateLimit(ctx context.Context, ipAddress string) error {
-	config := DefaultRateLimitConfig()
-
 	query := `
 		SELECT COUNT(*)
 		FROM password_reset_attempts
 		WHERE ip_address = $1
-		AND attempted_at > NOW() - INTERVAL '1 hour'
+		AND attempted_at > NOW() - make_interval(secs => $2)
 	`
 
 	var count int
-	err := rl.db.QueryRowContext(ctx, query, ipAddress).Scan(&count)
+	err := rl.db.QueryRowCtx(ctx, query, ipAddress, rl.limits.Window.Seconds()).Scan(&count)
 	if err != nil {
 		rl.log.WithError(err).Error("Failed to check IP rate limit",
 			"ip_address", ipAddress,
 		)
-		return fmt.Errorf("failed to check rate limit: %w", err)
+		return rl.fallback("ip", ipAddress, rl.limits.IPLimit, err)
 	}
 
-	if count >= config.IPLimit {
+	if count >= rl.limits.IPLimit {
 		rl.log.LogSecurityEvent("ip_rate_limit_exceeded", "high", map[string]interface{}{
 			"ip_address":    ipAddress,
 			"attempt_count": count,
-			"limit":         config.IPLimit,
+			"limit":         rl.limits.IPLimit,
 		})
-		return fmt.Errorf("rate limit exceeded")
+		return rl.limited(ctx, `
+			SELECT MIN(attempted_at)
+			FROM password_reset_attempts
+			WHERE ip_address = $1
+			AND attempted_at > NOW() - make_interval(secs => $2)
+		`, ipAddress)
 	}
 
 	return nil
 }
 
-// RecordResetAttempt records a password reset attempt for rate limiting
+// limited returns the rate limit error of an email or IP over its limit.
+// The window frees up when the oldest attempt in it expires; query selects
+// that attempt. Without it the client is told to wait the whole window.
+func (rl *RateLimiter) limited(ctx context.Context, query, value string) error {
+	var oldest sql.NullTime
+	err := rl.db.QueryRowCtx(ctx, query, value, rl.limits.Window.Seconds()).Scan(&oldest)
+	if err != nil || !oldest.Valid {
+		if err != nil {
+			rl.log.WithError(err).Warn("Failed to get oldest reset attempt")
+		}
+		return &apperrors.RateLimitError{RetryAfter: rl.limits.Window}
+	}
+	return &apperrors.RateLimitError{RetryAfter: rl.retryAfter(oldest.Time)}
+}
+
+// retryAfter returns how long until an attempt made at oldest leaves the
+// window, at least a second so clients never retry at once
+func (rl *RateLimiter) retryAfter(oldest time.Time) time.Duration {
+	return max(oldest.Add(rl.limits.Window).Sub(rl.now()), time.Second)
+}
+
+// fallback decides a check whose database query failed from the in-memory
+// counters: over the limit is rejected, otherwise the failure policy applies
+func (rl *RateLimiter) fallback(kind, value string, limit int, cause error) error {
+	rl.fallbacks.Add(1)
+	key := kind + ":" + value
+	count := rl.memory.count(key)
+
+	decision := "rejected"
+	var err error
+	switch {
+	case count >= limit:
+		retryAfter := rl.limits.Window
+		if oldest, ok := rl.memory.oldest(key); ok {
+			retryAfter = rl.retryAfter(oldest)
+		}
+		err = &apperrors.RateLimitError{RetryAfter: retryAfter}
+	case rl.limits.FailurePolicy == FailOpen:
+		decision = "allowed"
+	default:
+		err = fmt.Errorf("%w: %w", ErrRateLimitUnavailable, cause)
+	}
+
+	rl.log.LogSecurityEvent("rate_limit_fallback", "medium", map[string]interface{}{
+		"check":          kind,
+		"policy":         string(rl.limits.FailurePolicy),
+		"local_count":    count,
+		"limit":          limit,
+		"decision":       decision,
+		"fallback_total": rl.fallbacks.Load(),
+	})
+	return err
+}
+
+// RecordResetAttempt records a password reset attempt for rate limiting.
+// The attempt is counted in memory even when the database write fails.
 func (rl *RateLimiter) RecordResetAttempt(ctx context.Context, email string, ipAddress string) error {
+	rl.memory.add("email:" + email)
+	rl.memory.add("ip:" + ipAddress)
+
 	query := `
 		INSERT INTO password_reset_attempts (email, ip_address, attempted_at)
 		VALUES ($1, $2, NOW())
 	`
 
-	_, err := rl.db.ExecContext(ctx, query, email, ipAddress)
+	_, err := rl.db.ExecCtx(ctx, query, email, ipAddress)
 	if err != nil {
 		rl.log.WithError(err).Error("Failed to record reset attempt",
 			"email", email,
@@ -137,7 +252,7 @@ func (rl *RateLimiter) CleanupOldAttempts(ctx context.Context) (int, error) {
 		WHERE attempted_at < NOW() - INTERVAL '24 hours'
 	`
 
-	result, err := rl.db.ExecContext(ctx, query)
+	result, err := rl.db.ExecCtx(ctx, query)
 	if err != nil {
 		rl.log.WithError(err).Error("Failed to cleanup old reset attempts")
 		return 0, fmt.Errorf("failed to cleanup: %w", err)
@@ -157,16 +272,18 @@ func (rl *RateLimiter) CleanupOldAttempts(ctx context.Context) (int, error) {
 	return int(rowsAffected), nil
 }
 
-// GetAttemptCount returns the number of attempts for an email or IP in the last hour
+// GetAttemptCount returns the number of attempts for an email or IP within the rate limit window
 func (rl *RateLimiter) GetAttemptCount(ctx context.Context, email string, ipAddress string) (emailCount int, ipCount int, err error) {
+	window := rl.limits.Window.Seconds()
+
 	// Get email count
 	emailQuery := `
 		SELECT COUNT(*)
 		FROM password_reset_attempts
 		WHERE email = $1
-		AND attempted_at > NOW() - INTERVAL '1 hour'
+		AND attempted_at > NOW() - make_interval(secs => $2)
 	`
-	err = rl.db.QueryRowContext(ctx, emailQuery, email).Scan(&emailCount)
+	err = rl.db.QueryRowCtx(ctx, emailQuery, email, window).Scan(&emailCount)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to get email count: %w", err)
 	}
@@ -176,9 +293,9 @@ func (rl *RateLimiter) GetAttemptCount(ctx context.Context, email string, ipAddr
 		SELECT COUNT(*)
 		FROM password_reset_attempts
 		WHERE ip_address = $1
-		AND attempted_at > NOW() - INTERVAL '1 hour'
+		AND attempted_at > NOW() - make_interval(secs => $2)
 	`
-	err = rl.db.QueryRowContext(ctx, ipQuery, ipAddress).Scan(&ipCount)
+	err = rl.db.QueryRowCtx(ctx, ipQuery, ipAddress, window).Scan(&ipCount)
 	if err != nil {
 		return 0, 0, fmt.Errorf("failed to get IP count: %w", err)
 	}
@@ -195,7 +312,7 @@ func (rl *RateLimiter) GetRecentAttempts(ctx context.Context, limit int) ([]Rese
 		LIMIT $1
 	`
 
-	rows, err := rl.db.QueryContext(ctx, query, limit)
+	rows, err := rl.db.QueryCtx(ctx, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recent attempts: %w", err)
 	}
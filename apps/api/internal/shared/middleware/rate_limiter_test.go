@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/database"
 	"github.com/burcev/api/internal/shared/logger"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -17,7 +19,7 @@ func setupRateLimiterTest(t *testing.T) (*RateLimiter, sqlmock.Sqlmock, func())
 	require.NoError(t, err)
 
 	log := logger.New()
-	rl := NewRateLimiter(db, log)
+	rl := NewRateLimiter(&database.DB{DB: db}, log, DefaultRateLimitConfig())
 
 	cleanup := func() {
 		db.Close()
@@ -89,13 +91,17 @@ func TestCheckEmailRateLimit(t *testing.T) {
 			defer cleanup()
 
 			expectation := mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-				WithArgs(tt.email)
+				WithArgs(tt.email, float64(3600))
 
 			if tt.mockError != nil {
 				expectation.WillReturnError(tt.mockError)
 			} else {
 				expectation.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.attemptCount))
 			}
+			if tt.expectError && tt.mockError == nil {
+				mock.ExpectQuery("SELECT MIN\\(attempted_at\\) FROM password_reset_attempts").
+					WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now().Add(-10 * time.Minute)))
+			}
 
 			err := rl.CheckEmailRateLimit(context.Background(), tt.email)
 
@@ -167,13 +173,17 @@ func TestCheckIPRateLimit(t *testing.T) {
 			defer cleanup()
 
 			expectation := mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-				WithArgs(tt.ipAddress)
+				WithArgs(tt.ipAddress, float64(3600))
 
 			if tt.mockError != nil {
 				expectation.WillReturnError(tt.mockError)
 			} else {
 				expectation.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.attemptCount))
 			}
+			if tt.expectError && tt.mockError == nil {
+				mock.ExpectQuery("SELECT MIN\\(attempted_at\\) FROM password_reset_attempts").
+					WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now().Add(-10 * time.Minute)))
+			}
 
 			err := rl.CheckIPRateLimit(context.Background(), tt.ipAddress)
 
@@ -189,6 +199,31 @@ func TestCheckIPRateLimit(t *testing.T) {
 	}
 }
 
+func TestCheckIPRateLimit_SecurityEvents(t *testing.T) {
+	rl, mock, cleanup := setupRateLimiterTest(t)
+	defer cleanup()
+	recorder := &logger.SecurityRecorder{}
+	rl.log.AddSecuritySink(recorder)
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+	mock.ExpectQuery("SELECT MIN\\(attempted_at\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now()))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WillReturnError(sql.ErrConnDone)
+
+	assert.ErrorIs(t, rl.CheckIPRateLimit(context.Background(), "192.168.1.1"), apperrors.ErrRateLimited)
+	assert.Error(t, rl.CheckIPRateLimit(context.Background(), "192.168.1.1"))
+
+	events := recorder.Events()
+	require.Len(t, events, 2)
+	assert.Equal(t, "ip_rate_limit_exceeded", events[0].Event)
+	assert.Equal(t, "high", events[0].Severity)
+	assert.Equal(t, "192.168.1.1", events[0].Fields["ip_address"])
+	assert.Equal(t, "rate_limit_fallback", events[1].Event)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRecordResetAttempt(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -353,7 +388,7 @@ func TestGetAttemptCount(t *testing.T) {
 			defer cleanup()
 
 			emailExpectation := mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-				WithArgs(tt.email)
+				WithArgs(tt.email, float64(3600))
 
 			if tt.emailError != nil {
 				emailExpectation.WillReturnError(tt.emailError)
@@ -361,7 +396,7 @@ func TestGetAttemptCount(t *testing.T) {
 				emailExpectation.WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(tt.emailCount))
 
 				ipExpectation := mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
-					WithArgs(tt.ipAddress)
+					WithArgs(tt.ipAddress, float64(3600))
 
 				if tt.ipError != nil {
 					ipExpectation.WillReturnError(tt.ipError)
@@ -469,5 +504,172 @@ func TestDefaultRateLimitConfig(t *testing.T) {
 
 	assert.Equal(t, 3, config.EmailLimit)
 	assert.Equal(t, 10, config.IPLimit)
-	assert.Equal(t, 60, config.WindowMinutes)
+	assert.Equal(t, time.Hour, config.Window)
+	assert.Equal(t, FailClosed, config.FailurePolicy)
+}
+
+func TestRateLimiterUsesConfiguredLimits(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	rl := NewRateLimiter(&database.DB{DB: db}, logger.New(), RateLimitConfig{EmailLimit: 1, IPLimit: 5, Window: 15 * time.Minute})
+
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WithArgs("user@example.com", float64(900)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT MIN\\(attempted_at\\)").
+		WithArgs("user@example.com", float64(900)).
+		WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(time.Now()))
+	mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+		WithArgs("192.168.1.1", float64(900)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+
+	assert.Error(t, rl.CheckEmailRateLimit(context.Background(), "user@example.com"))
+	assert.NoError(t, rl.CheckIPRateLimit(context.Background(), "192.168.1.1"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRateLimiterRetryAfter(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	setup := func(t *testing.T) (*RateLimiter, sqlmock.Sqlmock) {
+		t.Helper()
+		rl, mock, cleanup := setupRateLimiterTest(t)
+		t.Cleanup(cleanup)
+		rl.now = func() time.Time { return now }
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		return rl, mock
+	}
+	retryAfter := func(t *testing.T, err error) time.Duration {
+		t.Helper()
+		var rateErr *apperrors.RateLimitError
+		require.ErrorAs(t, err, &rateErr)
+		return rateErr.RetryAfter
+	}
+
+	t.Run("until the oldest attempt leaves the window", func(t *testing.T) {
+		rl, mock := setup(t)
+		mock.ExpectQuery("SELECT MIN\\(attempted_at\\) FROM password_reset_attempts\\s+WHERE email = \\$1").
+			WithArgs("user@example.com", float64(3600)).
+			WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(now.Add(-42*time.Minute - 30*time.Second)))
+
+		err := rl.CheckEmailRateLimit(context.Background(), "user@example.com")
+
+		assert.Equal(t, 17*time.Minute+30*time.Second, retryAfter(t, err))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("at least a second", func(t *testing.T) {
+		rl, mock := setup(t)
+		mock.ExpectQuery("SELECT MIN\\(attempted_at\\)").
+			WillReturnRows(sqlmock.NewRows([]string{"min"}).AddRow(now.Add(-time.Hour)))
+
+		assert.Equal(t, time.Second, retryAfter(t, rl.CheckEmailRateLimit(context.Background(), "user@example.com")))
+	})
+
+	t.Run("the whole window when the oldest attempt is unknown", func(t *testing.T) {
+		rl, mock := setup(t)
+		mock.ExpectQuery("SELECT MIN\\(attempted_at\\)").
+			WillReturnError(sql.ErrConnDone)
+
+		assert.Equal(t, time.Hour, retryAfter(t, rl.CheckEmailRateLimit(context.Background(), "user@example.com")))
+	})
+}
+
+func TestRateLimiterFallback(t *testing.T) {
+	setup := func(t *testing.T, policy FailurePolicy) (*RateLimiter, sqlmock.Sqlmock) {
+		t.Helper()
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		rl := NewRateLimiter(&database.DB{DB: db}, logger.New(), RateLimitConfig{
+			EmailLimit: 2, IPLimit: 3, Window: time.Hour, FailurePolicy: policy,
+		})
+		return rl, mock
+	}
+	failCheck := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").WillReturnError(sql.ErrConnDone)
+	}
+	// recordWhileDown records an attempt whose database write fails too
+	recordWhileDown := func(t *testing.T, rl *RateLimiter, mock sqlmock.Sqlmock, email, ip string) {
+		t.Helper()
+		mock.ExpectExec("INSERT INTO password_reset_attempts").WillReturnError(sql.ErrConnDone)
+		assert.Error(t, rl.RecordResetAttempt(context.Background(), email, ip))
+	}
+
+	t.Run("fail open admits under the local limit", func(t *testing.T) {
+		rl, mock := setup(t, FailOpen)
+
+		failCheck(mock)
+		assert.NoError(t, rl.CheckEmailRateLimit(context.Background(), "user@example.com"))
+		recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.1")
+		failCheck(mock)
+		assert.NoError(t, rl.CheckIPRateLimit(context.Background(), "10.0.0.1"))
+
+		assert.Equal(t, int64(2), rl.FallbackCount())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("fail open still enforces the local limit", func(t *testing.T) {
+		rl, mock := setup(t, FailOpen)
+		now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+		rl.memory.now = func() time.Time { return now }
+		rl.now = rl.memory.now
+		recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.1")
+		now = now.Add(10 * time.Minute)
+		recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.2")
+
+		failCheck(mock)
+		err := rl.CheckEmailRateLimit(context.Background(), "user@example.com")
+
+		assert.ErrorIs(t, err, apperrors.ErrRateLimited)
+		assert.NotErrorIs(t, err, ErrRateLimitUnavailable)
+		assert.Equal(t, int64(1), rl.FallbackCount())
+		// The first of the two attempts leaves the hour-long window first
+		var rateErr *apperrors.RateLimitError
+		require.ErrorAs(t, err, &rateErr)
+		assert.Equal(t, 50*time.Minute, rateErr.RetryAfter)
+	})
+
+	t.Run("fail closed rejects under the local limit", func(t *testing.T) {
+		rl, mock := setup(t, FailClosed)
+
+		failCheck(mock)
+		err := rl.CheckEmailRateLimit(context.Background(), "user@example.com")
+
+		assert.ErrorIs(t, err, ErrRateLimitUnavailable)
+		assert.ErrorIs(t, err, sql.ErrConnDone)
+		assert.NotErrorIs(t, err, apperrors.ErrRateLimited)
+		assert.Equal(t, int64(1), rl.FallbackCount())
+	})
+
+	t.Run("fail closed reports the local limit as exceeded", func(t *testing.T) {
+		rl, mock := setup(t, FailClosed)
+		for i := 0; i < 3; i++ {
+			recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.1")
+		}
+
+		failCheck(mock)
+		assert.ErrorIs(t, rl.CheckIPRateLimit(context.Background(), "10.0.0.1"), apperrors.ErrRateLimited)
+	})
+
+	t.Run("unset policy fails closed", func(t *testing.T) {
+		rl, mock := setup(t, "")
+
+		failCheck(mock)
+		assert.ErrorIs(t, rl.CheckIPRateLimit(context.Background(), "10.0.0.1"), ErrRateLimitUnavailable)
+	})
+
+	t.Run("healthy database does not engage the fallback", func(t *testing.T) {
+		rl, mock := setup(t, FailOpen)
+		recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.1")
+		recordWhileDown(t, rl, mock, "user@example.com", "10.0.0.1")
+
+		// The database is the source of truth once it answers again
+		mock.ExpectQuery("SELECT COUNT\\(\\*\\) FROM password_reset_attempts").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		assert.NoError(t, rl.CheckEmailRateLimit(context.Background(), "user@example.com"))
+		assert.Zero(t, rl.FallbackCount())
+	})
 }
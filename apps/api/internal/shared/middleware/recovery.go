@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Reporter sends errors to an external error tracker. It has the shape of
+// Sentry's CaptureException, so a tracker can be wired in without this
+// package importing its SDK.
+type Reporter interface {
+	CaptureException(err error, tags map[string]string)
+}
+
+// NopReporter drops every error, for when no error tracker is configured
+type NopReporter struct{}
+
+// CaptureException does nothing
+func (NopReporter) CaptureException(error, map[string]string) {}
+
+// CapturedError is an error kept by MemoryReporter
+type CapturedError struct {
+	Err  error
+	Tags map[string]string
+}
+
+// MemoryReporter keeps the errors it is sent, for tests
+type MemoryReporter struct {
+	mu     sync.Mutex
+	events []CapturedError
+}
+
+// CaptureException records err with its tags
+func (r *MemoryReporter) CaptureException(err error, tags map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, CapturedError{Err: err, Tags: tags})
+}
+
+// Events returns the errors captured so far, oldest first
+func (r *MemoryReporter) Events() []CapturedError {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]CapturedError(nil), r.events...)
+}
+
+// panics counts the panics Recovery recovered from since the process started
+var panics atomic.Int64
+
+// PanicCount returns how many handler panics Recovery has recovered from
+func PanicCount() int64 {
+	return panics.Load()
+}
+
+// Recovery turns a panic in a later handler into a 500 with the standard
+// JSON error envelope, request_id included. The panic is logged with its
+// stack, request id, route and user id, counted and sent to reporter (nil
+// for none). A client that hung up is not reported, and a response already
+// under way is left as it is.
+func Recovery(log *logger.Logger, reporter Reporter) gin.HandlerFunc {
+	if reporter == nil {
+		reporter = NopReporter{}
+	}
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("panic: %v", recovered)
+			}
+			if errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET) {
+				log.Warnw("Client connection lost", "error", err, "path", c.Request.URL.Path)
+				c.Abort()
+				return
+			}
+
+			tags := map[string]string{
+				"request_id": c.GetString("request_id"),
+				"route":      c.FullPath(),
+				"method":     c.Request.Method,
+			}
+			fields := []interface{}{
+				"error", err.Error(),
+				"stack", string(debug.Stack()),
+				"request_id", tags["request_id"],
+				"route", tags["route"],
+				"method", tags["method"],
+				"panic_total", panics.Add(1),
+			}
+			if userID, ok := c.Get("user_id"); ok {
+				tags["user_id"] = fmt.Sprint(userID)
+				fields = append(fields, "user_id", userID)
+			}
+			log.Errorw("Panic recovered", fields...)
+			reporter.CaptureException(err, tags)
+
+			c.Abort()
+			if c.Writer.Written() {
+				return
+			}
+			response.ErrorCode(c, http.StatusInternalServerError, response.CodeInternal, "Внутренняя ошибка сервера", nil)
+		}()
+
+		c.Next()
+	}
+}
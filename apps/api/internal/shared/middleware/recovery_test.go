@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"syscall"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRecoveryRouter(reporter Reporter, handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Recovery(logger.New(), reporter))
+	router.Use(func(c *gin.Context) {
+		c.Set("request_id", "req-1")
+		c.Set("user_id", int64(42))
+		c.Next()
+	})
+	router.GET("/entries/:id", handler)
+	return router
+}
+
+func TestRecovery(t *testing.T) {
+	t.Run("panic yields the JSON envelope and one captured event", func(t *testing.T) {
+		reporter := &MemoryReporter{}
+		router := setupRecoveryRouter(reporter, func(c *gin.Context) {
+			var entries map[string]int
+			entries["x"]++
+		})
+		before := PanicCount()
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/entries/7", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Equal(t, "application/json; charset=utf-8", w.Header().Get("Content-Type"))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "error", body["status"])
+		assert.Equal(t, "INTERNAL_ERROR", body["code"])
+		assert.Equal(t, "req-1", body["request_id"])
+		assert.NotContains(t, w.Body.String(), "nil map", "panic text is not exposed")
+
+		events := reporter.Events()
+		require.Len(t, events, 1)
+		assert.ErrorContains(t, events[0].Err, "assignment to entry in nil map")
+		assert.Equal(t, map[string]string{
+			"request_id": "req-1",
+			"route":      "/entries/:id",
+			"method":     http.MethodGet,
+			"user_id":    "42",
+		}, events[0].Tags)
+		assert.Equal(t, before+1, PanicCount())
+	})
+
+	t.Run("non-error panic values are wrapped", func(t *testing.T) {
+		reporter := &MemoryReporter{}
+		router := setupRecoveryRouter(reporter, func(c *gin.Context) { panic("boom") })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/entries/7", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		require.Len(t, reporter.Events(), 1)
+		assert.EqualError(t, reporter.Events()[0].Err, "panic: boom")
+	})
+
+	t.Run("response already written is kept", func(t *testing.T) {
+		reporter := &MemoryReporter{}
+		router := setupRecoveryRouter(reporter, func(c *gin.Context) {
+			c.String(http.StatusOK, "partial")
+			panic(errors.New("late failure"))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/entries/7", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "partial", w.Body.String())
+		assert.Len(t, reporter.Events(), 1)
+	})
+
+	t.Run("lost client connection is not reported", func(t *testing.T) {
+		reporter := &MemoryReporter{}
+		router := setupRecoveryRouter(reporter, func(c *gin.Context) {
+			panic(fmt.Errorf("write response: %w", syscall.EPIPE))
+		})
+		before := PanicCount()
+
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/entries/7", nil))
+
+		assert.Empty(t, reporter.Events())
+		assert.Equal(t, before, PanicCount())
+	})
+
+	t.Run("nil reporter", func(t *testing.T) {
+		router := setupRecoveryRouter(nil, func(c *gin.Context) { panic("boom") })
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/entries/7", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+	})
+}
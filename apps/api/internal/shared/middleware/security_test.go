@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(SecurityHeaders())
+	router.GET("/ok", func(c *gin.Context) {
+		response.Success(c, http.StatusOK, gin.H{"ok": true})
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		response.InternalError(c, "Ошибка")
+	})
+	router.GET("/abort", func(c *gin.Context) {
+		c.AbortWithStatus(http.StatusUnauthorized)
+	})
+
+	for _, path := range []string{"/ok", "/fail", "/abort", "/missing"} {
+		t.Run(path[1:], func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+			assert.Equal(t, "SAMEORIGIN", w.Header().Get("X-Frame-Options"))
+			assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+			assert.Len(t, w.Header().Values("X-Frame-Options"), 1, "set once, not appended")
+		})
+	}
+}
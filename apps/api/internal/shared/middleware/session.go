@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// RevokedSessions reports whether a refresh token session was revoked
+// (implemented by auth.SessionBlacklist)
+type RevokedSessions interface {
+	IsRevoked(sessionID string) bool
+}
+
+// RejectRevokedSessions rejects bearer tokens issued in a session that was
+// revoked, so signing out a device takes effect before its access token
+// expires. It runs on every route, ahead of RequireAuth and OptionalAuth;
+// invalid tokens are left for them to reject.
+func RejectRevokedSessions(cfg *config.Config, sessions RevokedSessions) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || tokenString == "" {
+			c.Next()
+			return
+		}
+
+		var claims UserClaims
+		token, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+			return []byte(cfg.JWTSecret), nil
+		})
+		if err == nil && token.Valid && claims.SessionID != "" && sessions.IsRevoked(claims.SessionID) {
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeUnauthorized, "Сеанс завершён, войдите заново", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
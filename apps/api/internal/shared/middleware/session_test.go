@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type revokedSet map[string]bool
+
+func (s revokedSet) IsRevoked(sessionID string) bool { return s[sessionID] }
+
+func TestRejectRevokedSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.Config{JWTSecret: "test-secret"}
+
+	token := func(secret string, claims jwt.MapClaims) string {
+		claims["user_id"] = 1
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+		signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+		return signed
+	}
+
+	router := gin.New()
+	router.Use(RejectRevokedSessions(cfg, revokedSet{"revoked": true}))
+	router.GET("/test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"revoked session", "Bearer " + token(cfg.JWTSecret, jwt.MapClaims{"sid": "revoked"}), http.StatusUnauthorized},
+		{"active session", "Bearer " + token(cfg.JWTSecret, jwt.MapClaims{"sid": "active"}), http.StatusOK},
+		{"token without session", "Bearer " + token(cfg.JWTSecret, jwt.MapClaims{}), http.StatusOK},
+		{"forged token is left to RequireAuth", "Bearer " + token("other-secret", jwt.MapClaims{"sid": "revoked"}), http.StatusOK},
+		{"anonymous", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -4,18 +4,23 @@ import (
 	"context"
 	"time"
 
+	"github.com/burcev/api/internal/shared/clock"
 	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
 )
 
 // DefaultTimezone is used when user has no timezone set
-const DefaultTimezone = "Europe/Moscow"
+const DefaultTimezone = clock.DefaultTimezone
+
+// TimezoneQueryParam overrides the stored timezone for a single request
+const TimezoneQueryParam = "tz"
 
 // GetUserTimezone loads the user's timezone from user_settings.
 // Returns the timezone Location, falling back to Europe/Moscow.
 func GetUserTimezone(ctx context.Context, db *database.DB, userID int64) *time.Location {
 	if db == nil {
-		loc, _ := time.LoadLocation(DefaultTimezone)
-		return loc
+		return clock.Location(DefaultTimezone)
 	}
 
 	var tz string
@@ -24,14 +29,23 @@ func GetUserTimezone(ctx context.Context, db *database.DB, userID int64) *time.L
 		userID,
 	).Scan(&tz)
 
-	if err != nil || tz == "" {
+	if err != nil {
 		tz = DefaultTimezone
 	}
 
-	loc, err := time.LoadLocation(tz)
-	if err != nil {
-		loc, _ = time.LoadLocation(DefaultTimezone)
-	}
+	return clock.Location(tz)
+}
 
-	return loc
+// RequestTimezone returns the timezone to interpret "today" in for a request:
+// the tz query parameter if present, otherwise the user's stored timezone.
+// An invalid tz is answered with 400 VALIDATION_FAILED and ok is false.
+func RequestTimezone(c *gin.Context, db *database.DB, userID int64) (loc *time.Location, ok bool) {
+	if tz, set := c.GetQuery(TimezoneQueryParam); set {
+		if err := clock.ValidateTimezone(tz); err != nil {
+			validation.Respond(c, validation.Errors{TimezoneQueryParam: "Неизвестный часовой пояс"})
+			return nil, false
+		}
+		return clock.Location(tz), true
+	}
+	return GetUserTimezone(c.Request.Context(), db, userID), true
 }
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestTimezone(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(t *testing.T, db *database.DB, target string) (*time.Location, *httptest.ResponseRecorder) {
+		var loc *time.Location
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.GET("/today", func(c *gin.Context) {
+			var ok bool
+			if loc, ok = RequestTimezone(c, db, 5); ok {
+				c.Status(http.StatusNoContent)
+			}
+		})
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return loc, w
+	}
+
+	t.Run("stored timezone", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		mock.ExpectQuery("FROM user_settings").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Asia/Vladivostok"))
+
+		loc, w := serve(t, &database.DB{DB: mockDB}, "/today")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "Asia/Vladivostok", loc.String())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query parameter overrides the stored timezone", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		loc, w := serve(t, &database.DB{DB: mockDB}, "/today?tz=Pacific/Auckland")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "Pacific/Auckland", loc.String())
+		assert.NoError(t, mock.ExpectationsWereMet(), "no lookup with an explicit tz")
+	})
+
+	t.Run("invalid query parameter", func(t *testing.T) {
+		loc, w := serve(t, nil, "/today?tz=Mars/Olympus_Mons")
+
+		assert.Nil(t, loc)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"tz":"Неизвестный часовой пояс"`)
+	})
+
+	t.Run("unknown stored timezone falls back to the default", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		mock.ExpectQuery("FROM user_settings").
+			WillReturnRows(sqlmock.NewRows([]string{"timezone"}).AddRow("Mars/Olympus_Mons"))
+
+		loc, _ := serve(t, &database.DB{DB: mockDB}, "/today")
+
+		assert.Equal(t, DefaultTimezone, loc.String())
+	})
+}
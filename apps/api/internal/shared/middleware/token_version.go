@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+)
+
+// TokenVersionSource returns the current token version of a user
+// (implemented by auth.TokenVersions)
+type TokenVersionSource interface {
+	TokenVersion(ctx context.Context, userID int64) (int, error)
+}
+
+// RequireTokenVersion rejects access tokens issued before the user's token
+// version was bumped, so a role change takes effect before the token
+// expires. It must run after RequireAuth; API key requests are not checked.
+func RequireTokenVersion(versions TokenVersionSource) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimed, ok := c.Get("token_version")
+		if !ok {
+			c.Next()
+			return
+		}
+
+		current, err := versions.TokenVersion(c.Request.Context(), c.GetInt64("user_id"))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			response.Error(c, http.StatusUnauthorized, "Пользователь не найден")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "Не удалось проверить токен")
+			c.Abort()
+			return
+		}
+		if claimed.(int) != current {
+			response.ErrorCode(c, http.StatusUnauthorized, response.CodeUnauthorized, "Токен устарел, войдите заново", nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
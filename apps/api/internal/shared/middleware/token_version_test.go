@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/config"
+	"github.com/burcev/api/internal/shared/apperrors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTokenVersions struct {
+	version int
+	err     error
+}
+
+func (f fakeTokenVersions) TokenVersion(ctx context.Context, userID int64) (int, error) {
+	return f.version, f.err
+}
+
+func TestRequireTokenVersion(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret"}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, UserClaims{
+		UserID:       7,
+		Role:         "super_admin",
+		TokenVersion: 2,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(cfg.JWTSecret))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		versions fakeTokenVersions
+		want     int
+	}{
+		{name: "current version", versions: fakeTokenVersions{version: 2}, want: http.StatusOK},
+		{name: "version bumped by role change", versions: fakeTokenVersions{version: 3}, want: http.StatusUnauthorized},
+		{name: "user gone", versions: fakeTokenVersions{err: apperrors.ErrNotFound}, want: http.StatusUnauthorized},
+		{name: "lookup failure", versions: fakeTokenVersions{err: errors.New("db down")}, want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/admin", RequireAuth(cfg), RequireTokenVersion(tt.versions), RequireRole("super_admin"), func(c *gin.Context) {
+				c.Status(http.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
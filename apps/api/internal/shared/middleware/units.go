@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/units"
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+)
+
+// UnitsQueryParam overrides the stored unit system for a single request
+const UnitsQueryParam = "units"
+
+// GetUserUnits loads the user's unit system from user_settings, falling
+// back to metric
+func GetUserUnits(ctx context.Context, db *database.DB, userID int64) string {
+	if db == nil {
+		return units.Metric
+	}
+
+	var system string
+	err := db.QueryRowContext(ctx,
+		"SELECT COALESCE(units, 'metric') FROM user_settings WHERE user_id = $1",
+		userID,
+	).Scan(&system)
+	if err != nil || !units.Valid(system) {
+		return units.Metric
+	}
+
+	return system
+}
+
+// RequestUnits returns the unit system for a request: the units query
+// parameter if present, otherwise the user's stored preference. An invalid
+// value is answered with 400 VALIDATION_FAILED and ok is false.
+func RequestUnits(c *gin.Context, db *database.DB, userID int64) (system string, ok bool) {
+	if system, set := c.GetQuery(UnitsQueryParam); set {
+		if !units.Valid(system) {
+			validation.Respond(c, validation.Errors{UnitsQueryParam: "Допустимы metric и imperial"})
+			return "", false
+		}
+		return system, true
+	}
+	return GetUserUnits(c.Request.Context(), db, userID), true
+}
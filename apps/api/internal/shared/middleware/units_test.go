@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/burcev/api/internal/shared/database"
+	"github.com/burcev/api/internal/shared/units"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestUnits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	serve := func(t *testing.T, db *database.DB, target string) (string, *httptest.ResponseRecorder) {
+		var system string
+		w := httptest.NewRecorder()
+		_, r := gin.CreateTestContext(w)
+		r.GET("/trend", func(c *gin.Context) {
+			var ok bool
+			if system, ok = RequestUnits(c, db, 5); ok {
+				c.Status(http.StatusNoContent)
+			}
+		})
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		return system, w
+	}
+
+	t.Run("stored preference", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		mock.ExpectQuery("FROM user_settings").WithArgs(int64(5)).
+			WillReturnRows(sqlmock.NewRows([]string{"units"}).AddRow(units.Imperial))
+
+		system, w := serve(t, &database.DB{DB: mockDB}, "/trend")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, units.Imperial, system)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query parameter overrides the preference", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+
+		system, w := serve(t, &database.DB{DB: mockDB}, "/trend?units=metric")
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, units.Metric, system)
+		assert.NoError(t, mock.ExpectationsWereMet(), "no lookup with explicit units")
+	})
+
+	t.Run("invalid query parameter", func(t *testing.T) {
+		_, w := serve(t, nil, "/trend?units=furlongs")
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"units":"Допустимы metric и imperial"`)
+	})
+
+	t.Run("no settings row falls back to metric", func(t *testing.T) {
+		mockDB, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer mockDB.Close()
+		mock.ExpectQuery("FROM user_settings").WillReturnRows(sqlmock.NewRows([]string{"units"}))
+
+		system, _ := serve(t, &database.DB{DB: mockDB}, "/trend")
+
+		assert.Equal(t, units.Metric, system)
+	})
+}
@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,8 +16,12 @@ const (
 	BaseURL   = "https://world.openfoodfacts.org/api/v2/product"
 	UserAgent = "BurcevFitnessApp/1.0 (https://burcev.team)"
 	Timeout   = 10 * time.Second
+
+	// kJPerKcal converts energy reported only in kilojoules
+	kJPerKcal = 4.184
 )
 
+// Product is a product with its macros per 100 g
 type Product struct {
 	Code     string
 	Name     string
@@ -28,31 +35,65 @@ type Product struct {
 type apiResponse struct {
 	Status  int `json:"status"`
 	Product struct {
-		ProductName string `json:"product_name"`
-		Brands      string `json:"brands"`
-		Nutriments  struct {
-			EnergyKcal100g float64 `json:"energy-kcal_100g"`
-			Proteins100g   float64 `json:"proteins_100g"`
-			Fat100g        float64 `json:"fat_100g"`
-			Carbs100g      float64 `json:"carbohydrates_100g"`
-		} `json:"nutriments"`
+		ProductName     string     `json:"product_name"`
+		Brands          string     `json:"brands"`
+		ServingQuantity number     `json:"serving_quantity"`
+		Nutriments      nutriments `json:"nutriments"`
 	} `json:"product"`
 }
 
+// nutriments holds the values OFF reports per 100 g and per serving. Any of
+// them may be missing.
+type nutriments struct {
+	EnergyKcal100g     number `json:"energy-kcal_100g"`
+	EnergyKJ100g       number `json:"energy-kj_100g"`
+	Energy100g         number `json:"energy_100g"` // kJ
+	Proteins100g       number `json:"proteins_100g"`
+	Fat100g            number `json:"fat_100g"`
+	Carbs100g          number `json:"carbohydrates_100g"`
+	EnergyKcalServing  number `json:"energy-kcal_serving"`
+	EnergyKJServing    number `json:"energy-kj_serving"`
+	ProteinsServing    number `json:"proteins_serving"`
+	FatServing         number `json:"fat_serving"`
+	CarbohydratesServe number `json:"carbohydrates_serving"`
+}
+
+// number decodes a JSON number or a numeric string; OFF uses both.
+// Unparseable values are treated as missing.
+type number struct {
+	value float64
+	set   bool
+}
+
+func (n *number) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	n.value, n.set = v, true
+	return nil
+}
+
 type Client struct {
 	httpClient *http.Client
+	baseURL    string
 }
 
 func NewClient() *Client {
 	return &Client{
 		httpClient: &http.Client{Timeout: Timeout},
+		baseURL:    BaseURL,
 	}
 }
 
 // LookupBarcode queries OpenFoodFacts API for a product by barcode.
-// Returns nil, nil if product is not found.
+// Returns nil, nil if product is not found or has no energy value.
 func (c *Client) LookupBarcode(ctx context.Context, barcode string) (*Product, error) {
-	url := fmt.Sprintf("%s/%s?fields=product_name,brands,nutriments", BaseURL, barcode)
+	url := fmt.Sprintf("%s/%s?fields=product_name,brands,serving_quantity,nutriments", c.baseURL, barcode)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -66,6 +107,10 @@ func (c *Client) LookupBarcode(ctx context.Context, barcode string) (*Product, e
 	}
 	defer resp.Body.Close()
 
+	// Unknown barcodes are answered with 404
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected status: %d", resp.StatusCode)
 	}
@@ -84,13 +129,63 @@ func (c *Client) LookupBarcode(ctx context.Context, barcode string) (*Product, e
 		return nil, nil // not found
 	}
 
+	product, ok := apiResp.Product.Nutriments.per100g(apiResp.Product.ServingQuantity)
+	if !ok {
+		return nil, nil // nothing to log without calories
+	}
+	product.Code = barcode
+	product.Name = apiResp.Product.ProductName
+	product.Brand = apiResp.Product.Brands
+	return product, nil
+}
+
+// per100g returns the macros per 100 g. Values reported per 100 g are used
+// as is; missing ones are scaled from per-serving values when the serving
+// quantity (g) is known. Energy reported only in kJ is converted to kcal.
+// ok is false when the energy cannot be determined.
+func (n nutriments) per100g(servingQuantity number) (product *Product, ok bool) {
+	factor := 0.0
+	if servingQuantity.set && servingQuantity.value > 0 {
+		factor = 100 / servingQuantity.value
+	}
+	pick := func(per100, perServing number) (float64, bool) {
+		switch {
+		case per100.set:
+			return per100.value, true
+		case perServing.set && factor > 0:
+			return perServing.value * factor, true
+		}
+		return 0, false
+	}
+
+	calories, ok := pick(n.EnergyKcal100g, n.EnergyKcalServing)
+	if !ok {
+		kJ := n.EnergyKJ100g
+		if !kJ.set {
+			kJ = n.Energy100g
+		}
+		var energy float64
+		if energy, ok = pick(kJ, n.EnergyKJServing); ok {
+			calories = energy / kJPerKcal
+		}
+	}
+	if !ok || calories < 0 {
+		return nil, false
+	}
+
+	protein, _ := pick(n.Proteins100g, n.ProteinsServing)
+	fat, _ := pick(n.Fat100g, n.FatServing)
+	carbs, _ := pick(n.Carbs100g, n.CarbohydratesServe)
+
 	return &Product{
-		Code:     barcode,
-		Name:     apiResp.Product.ProductName,
-		Brand:    apiResp.Product.Brands,
-		Calories: apiResp.Product.Nutriments.EnergyKcal100g,
-		Protein:  apiResp.Product.Nutriments.Proteins100g,
-		Fat:      apiResp.Product.Nutriments.Fat100g,
-		Carbs:    apiResp.Product.Nutriments.Carbs100g,
-	}, nil
+		Calories: round2(calories),
+		Protein:  round2(protein),
+		Fat:      round2(fat),
+		Carbs:    round2(carbs),
+	}, true
+}
+
+// round2 rounds to two decimal places
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
 }
@@ -0,0 +1,109 @@
+package openfoodfacts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, status int, body string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/4600000000017", r.URL.Path)
+		assert.Equal(t, UserAgent, r.Header.Get("User-Agent"))
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return &Client{httpClient: server.Client(), baseURL: server.URL}
+}
+
+func TestLookupBarcode(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		want    *Product
+		wantErr bool
+	}{
+		{
+			name:   "per 100 g values",
+			status: http.StatusOK,
+			body: `{"status":1,"product":{"product_name":"Кефир","brands":"Савушкин","nutriments":{
+				"energy-kcal_100g":40,"proteins_100g":3,"fat_100g":1,"carbohydrates_100g":4.1}}}`,
+			want: &Product{Calories: 40, Protein: 3, Fat: 1, Carbs: 4.1},
+		},
+		{
+			name:   "per serving values scaled to 100 g",
+			status: http.StatusOK,
+			body: `{"status":1,"product":{"product_name":"Кефир","brands":"Савушкин","serving_quantity":"250","nutriments":{
+				"energy-kcal_serving":100,"proteins_serving":7.5,"fat_serving":2.5,"carbohydrates_serving":10}}}`,
+			want: &Product{Calories: 40, Protein: 3, Fat: 1, Carbs: 4},
+		},
+		{
+			name:   "energy in kJ only",
+			status: http.StatusOK,
+			body: `{"status":1,"product":{"product_name":"Кефир","brands":"Савушкин","nutriments":{
+				"energy_100g":167.36,"proteins_100g":"3","fat_100g":1}}}`,
+			want: &Product{Calories: 40, Protein: 3, Fat: 1},
+		},
+		{
+			name:   "no energy",
+			status: http.StatusOK,
+			body:   `{"status":1,"product":{"product_name":"Кефир","nutriments":{"proteins_100g":3}}}`,
+		},
+		{
+			name:   "serving values without serving quantity",
+			status: http.StatusOK,
+			body:   `{"status":1,"product":{"product_name":"Кефир","nutriments":{"energy-kcal_serving":100}}}`,
+		},
+		{
+			name:   "unknown product",
+			status: http.StatusOK,
+			body:   `{"status":0,"status_verbose":"product not found"}`,
+		},
+		{
+			name:   "unknown product with 404",
+			status: http.StatusNotFound,
+			body:   `{"status":0,"status_verbose":"product not found"}`,
+		},
+		{
+			name:    "upstream error",
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+		{
+			name:    "malformed body",
+			status:  http.StatusOK,
+			body:    `<html>`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, tt.status, tt.body)
+
+			product, err := client.LookupBarcode(context.Background(), "4600000000017")
+
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.Nil(t, product)
+				return
+			}
+			tt.want.Code = "4600000000017"
+			tt.want.Name = "Кефир"
+			tt.want.Brand = "Савушкин"
+			assert.Equal(t, tt.want, product)
+		})
+	}
+}
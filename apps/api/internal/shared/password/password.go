@@ -0,0 +1,63 @@
+// Package password hashes account passwords with bcrypt. The cost is
+// configured once (BCRYPT_COST) and passed in; hashes made at a lower cost
+// still verify and are upgraded on the next login.
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Accepted bcrypt costs. Each step doubles the hashing time: 10 takes
+// about 50 ms, 15 over a second per login.
+const (
+	DefaultCost = bcrypt.DefaultCost
+	MinCost     = 10
+	MaxCost     = 15
+)
+
+// ErrMismatch is returned by Compare when the password does not match
+var ErrMismatch = bcrypt.ErrMismatchedHashAndPassword
+
+// Hash returns the bcrypt hash of password at cost; a cost of 0 means
+// DefaultCost
+func Hash(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), effectiveCost(cost))
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// Compare checks password against a bcrypt hash of any cost. It returns
+// ErrMismatch for a wrong password and another error for a malformed hash.
+func Compare(hash, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrMismatch
+	}
+	return err
+}
+
+// Cost returns the cost a bcrypt hash was made with
+func Cost(hash string) (int, error) {
+	return bcrypt.Cost([]byte(hash))
+}
+
+// NeedsRehash reports whether hash was made at a lower cost than cost.
+// Malformed hashes are left alone: Compare already rejects them.
+func NeedsRehash(hash string, cost int) bool {
+	hashCost, err := Cost(hash)
+	if err != nil {
+		return false
+	}
+	return hashCost < effectiveCost(cost)
+}
+
+func effectiveCost(cost int) int {
+	if cost == 0 {
+		return DefaultCost
+	}
+	return cost
+}
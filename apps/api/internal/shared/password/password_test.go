@@ -0,0 +1,72 @@
+package password
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// lowCostHash hashes "Kettlebell#Row42" at bcrypt.MinCost (4), below every
+// configurable cost
+func lowCostHash(t *testing.T) string {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("Kettlebell#Row42"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+func TestHash(t *testing.T) {
+	hash, err := Hash("Kettlebell#Row42", 11)
+
+	require.NoError(t, err)
+	cost, err := Cost(hash)
+	require.NoError(t, err)
+	assert.Equal(t, 11, cost)
+	assert.NoError(t, Compare(hash, "Kettlebell#Row42"))
+
+	t.Run("zero cost means the default", func(t *testing.T) {
+		hash, err := Hash("Kettlebell#Row42", 0)
+
+		require.NoError(t, err)
+		cost, err := Cost(hash)
+		require.NoError(t, err)
+		assert.Equal(t, DefaultCost, cost)
+	})
+}
+
+func TestCompare(t *testing.T) {
+	hash := lowCostHash(t)
+
+	assert.NoError(t, Compare(hash, "Kettlebell#Row42"), "hashes of any cost verify")
+	assert.ErrorIs(t, Compare(hash, "wrong"), ErrMismatch)
+
+	err := Compare("plaintext", "plaintext")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrMismatch)
+}
+
+func TestCost(t *testing.T) {
+	cost, err := Cost(lowCostHash(t))
+	require.NoError(t, err)
+	assert.Equal(t, bcrypt.MinCost, cost)
+
+	// A fixed hash, cost 12
+	cost, err = Cost("$2a$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW")
+	require.NoError(t, err)
+	assert.Equal(t, 12, cost)
+
+	_, err = Cost("not a hash")
+	assert.Error(t, err)
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash := lowCostHash(t)
+
+	assert.True(t, NeedsRehash(hash, MinCost))
+	assert.True(t, NeedsRehash(hash, 0), "zero cost means the default")
+	assert.False(t, NeedsRehash(hash, bcrypt.MinCost))
+	assert.False(t, NeedsRehash("$2a$12$R9h/cIPz0gi.URNNX3kh2OPST9/PgBkqquzi.Ss7KIUgO2t0jWMUW", 11), "higher costs are kept")
+	assert.False(t, NeedsRehash("plaintext", MinCost))
+}
@@ -0,0 +1,43 @@
+package response
+
+// Error codes returned in Response.Code. They are part of the API contract:
+// the frontend branches on them, so never change an existing value.
+const (
+	// Generic
+	CodeValidationFailed = "VALIDATION_FAILED"
+	CodeRateLimited      = "RATE_LIMITED"
+	CodeUnauthorized     = "UNAUTHORIZED"
+	CodeForbidden        = "FORBIDDEN"
+	CodeNotFound         = "NOT_FOUND"
+	CodeConflict         = "CONFLICT"
+	CodeInternal         = "INTERNAL_ERROR"
+	// An external service the request depends on failed or timed out
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	// The API is in maintenance mode switched on by an admin
+	CodeMaintenance = "MAINTENANCE"
+
+	// An update named a record version another client has changed since
+	CodeVersionConflict = "VERSION_CONFLICT"
+
+	// A write would take the user over one of their quotas
+	CodeQuotaExceeded = "QUOTA_EXCEEDED"
+
+	// A request with the same Idempotency-Key has not finished yet
+	CodeIdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS"
+
+	// Authentication
+	CodeAuthInvalidCredentials  = "AUTH_INVALID_CREDENTIALS"
+	CodeAuthRefreshInvalid      = "AUTH_REFRESH_TOKEN_INVALID"
+	CodeAuthWrongPassword       = "AUTH_WRONG_PASSWORD"
+	CodePasswordTooWeak         = "PASSWORD_TOO_WEAK"
+	CodeVerificationCodeInvalid = "VERIFICATION_CODE_INVALID"
+	CodeVerificationCodeExpired = "VERIFICATION_CODE_EXPIRED"
+	// Too many wrong codes: the code is burned, a new one has to be requested
+	CodeVerificationAttemptsExceeded = "VERIFICATION_ATTEMPTS_EXCEEDED"
+	// The owner deleted the account; it can be reactivated until the purge
+	CodeAccountDeactivated = "ACCOUNT_DEACTIVATED"
+
+	// Password reset
+	CodeResetTokenInvalid = "RESET_TOKEN_INVALID"
+	CodeResetTokenExpired = "RESET_TOKEN_EXPIRED"
+)
@@ -0,0 +1,59 @@
+package response
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CachedJSON sends a success response like Success, with an ETag computed
+// from the serialized envelope. When the request's If-None-Match already
+// names that tag it answers 304 Not Modified without a body instead.
+//
+// The tag is weak: it identifies the JSON document, not its bytes on the
+// wire, so it stays valid when the response is compressed.
+func CachedJSON(c *gin.Context, statusCode int, data interface{}) {
+	body, err := json.Marshal(Response{
+		Status: "success",
+		Data:   data,
+	})
+	if err != nil {
+		InternalError(c, "Не удалось сформировать ответ")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("ETag", etag)
+	// Clients may keep the response but have to revalidate it every time
+	c.Header("Cache-Control", "private, no-cache")
+
+	if statusCode == http.StatusOK && (c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead) &&
+		etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Data(statusCode, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header names etag, using
+// the weak comparison RFC 9110 prescribes for If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
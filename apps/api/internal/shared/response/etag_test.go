@@ -0,0 +1,83 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedJSON(t *testing.T) {
+	name := "Иван"
+	router := setupTestRouter()
+	router.GET("/profile", func(c *gin.Context) {
+		CachedJSON(c, http.StatusOK, gin.H{"profile": gin.H{"name": name}})
+	})
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := get("")
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.JSONEq(t, `{"status":"success","data":{"profile":{"name":"Иван"}}}`, first.Body.String())
+	assert.Equal(t, "private, no-cache", first.Header().Get("Cache-Control"))
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	t.Run("replay is not modified", func(t *testing.T) {
+		w := get(etag)
+
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, etag, w.Header().Get("ETag"))
+	})
+
+	t.Run("tag in a list", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotModified, get(`W/"stale", `+etag).Code)
+	})
+
+	t.Run("changed data gets a new tag", func(t *testing.T) {
+		name = "Пётр"
+		t.Cleanup(func() { name = "Иван" })
+
+		w := get(etag)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Пётр")
+		assert.NotEqual(t, etag, w.Header().Get("ETag"))
+	})
+}
+
+func TestCachedJSON_OnlyConditionalForReads(t *testing.T) {
+	router := setupTestRouter()
+	router.PUT("/profile", func(c *gin.Context) {
+		CachedJSON(c, http.StatusOK, gin.H{"ok": true})
+	})
+
+	req := httptest.NewRequest(http.MethodPut, "/profile", nil)
+	req.Header.Set("If-None-Match", "*")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.String())
+}
+
+func TestEtagMatches(t *testing.T) {
+	assert.True(t, etagMatches(`W/"abc"`, `W/"abc"`))
+	assert.True(t, etagMatches(`"abc"`, `W/"abc"`), "weak comparison")
+	assert.True(t, etagMatches(`*`, `W/"abc"`))
+	assert.True(t, etagMatches(`"x", W/"abc"`, `W/"abc"`))
+	assert.False(t, etagMatches(``, `W/"abc"`))
+	assert.False(t, etagMatches(`W/"abd"`, `W/"abc"`))
+}
@@ -0,0 +1,75 @@
+package response
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+// compatKey is the context key of the compatible responses switch
+const compatKey = "compat_responses"
+
+// Compat turns compatible responses on or off for the requests it
+// handles. With them on, list responses also carry their items under the
+// key the endpoint used before the items/pagination envelope, so clients
+// have a release to move over.
+func Compat(on bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(compatKey, on)
+		c.Next()
+	}
+}
+
+// Pagination places a list response in the whole listing. Limit is 0 when
+// the whole listing is returned; Count is the number of items sent.
+type Pagination struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+// ListData is the data of every list response. Modules embed it in their
+// list DTOs, which keeps the response shape the same across endpoints.
+type ListData[T any] struct {
+	Items      []T        `json:"items"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// NewList returns the list data of a page of items read with limit and
+// offset (both 0 for a whole listing). Items are never sent as null.
+func NewList[T any](items []T, limit, offset int) ListData[T] {
+	if items == nil {
+		items = []T{}
+	}
+	return ListData[T]{
+		Items:      items,
+		Pagination: Pagination{Limit: limit, Offset: offset, Count: len(items)},
+	}
+}
+
+// withLegacyKey returns the list data with its items also under legacyKey
+func (l ListData[T]) withLegacyKey(legacyKey string) gin.H {
+	return gin.H{"items": l.Items, "pagination": l.Pagination, legacyKey: l.Items}
+}
+
+// Lister is list data: ListData or a DTO embedding it
+type Lister interface {
+	withLegacyKey(legacyKey string) gin.H
+}
+
+// List sends a success response with list data. legacyKey is the key the
+// endpoint sent its items under before; it is only added while compatible
+// responses are on.
+func List(c *gin.Context, statusCode int, data Lister, legacyKey string) {
+	Success(c, statusCode, listBody(c, data, legacyKey))
+}
+
+// CachedList sends list data like List, with an ETag like CachedJSON
+func CachedList(c *gin.Context, statusCode int, data Lister, legacyKey string) {
+	CachedJSON(c, statusCode, listBody(c, data, legacyKey))
+}
+
+func listBody(c *gin.Context, data Lister, legacyKey string) interface{} {
+	if c.GetBool(compatKey) {
+		return data.withLegacyKey(legacyKey)
+	}
+	return data
+}
@@ -0,0 +1,69 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testItem struct {
+	Name string `json:"name"`
+}
+
+// testItemsResponse is a module list DTO
+type testItemsResponse struct {
+	ListData[testItem]
+}
+
+func TestNewList(t *testing.T) {
+	list := NewList([]testItem{{"a"}, {"b"}}, 2, 4)
+	assert.Equal(t, Pagination{Limit: 2, Offset: 4, Count: 2}, list.Pagination)
+
+	empty, err := json.Marshal(NewList[testItem](nil, 0, 0))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"items":[],"pagination":{"limit":0,"offset":0,"count":0}}`, string(empty))
+}
+
+func TestList(t *testing.T) {
+	serveList := func(t *testing.T, middleware ...gin.HandlerFunc) string {
+		t.Helper()
+		router := setupTestRouter()
+		handlers := append(middleware, func(c *gin.Context) {
+			List(c, http.StatusOK, testItemsResponse{NewList([]testItem{{"a"}}, 0, 0)}, "things")
+		})
+		router.GET("/test", handlers...)
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+	const items = `"items":[{"name":"a"}],"pagination":{"limit":0,"offset":0,"count":1}`
+
+	t.Run("items and pagination", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"success","data":{`+items+`}}`, serveList(t))
+		assert.JSONEq(t, `{"status":"success","data":{`+items+`}}`, serveList(t, Compat(false)))
+	})
+
+	t.Run("compatible responses add the legacy key", func(t *testing.T) {
+		assert.JSONEq(t, `{"status":"success","data":{`+items+`,"things":[{"name":"a"}]}}`, serveList(t, Compat(true)))
+	})
+}
+
+func TestCachedList(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/test", Compat(true), func(c *gin.Context) {
+		CachedList(c, http.StatusOK, testItemsResponse{NewList([]testItem{{"a"}}, 0, 0)}, "things")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+
+	assert.NotEmpty(t, w.Header().Get("ETag"))
+	assert.Contains(t, w.Body.String(), `"things":[{"name":"a"}]`)
+}
@@ -1,14 +1,30 @@
 package response
 
 import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/burcev/api/internal/shared/i18n"
 	"github.com/gin-gonic/gin"
 )
 
-// Response represents API response structure
+// Response represents API response structure.
+// Code and Details are only set on errors the client is expected to handle
+// programmatically; the message stays human-readable. Error responses carry
+// the request id so a user report can be matched to the server logs.
+// Warnings flag something about a successful request the client may act on,
+// keyed by warning name.
 type Response struct {
-	Status  string      `json:"status"`
-	Data    interface{} `json:"data,omitempty"`
-	Message string      `json:"message,omitempty"`
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	Message   string      `json:"message,omitempty"`
+	Code      string      `json:"code,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	Notice    interface{} `json:"notice,omitempty"`
+	Warnings  gin.H       `json:"warnings,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
 }
 
 // Success sends success response
@@ -19,11 +35,134 @@ func Success(c *gin.Context, statusCode int, data interface{}) {
 	})
 }
 
+// SuccessWithNotice sends success response with a service notice (e.g. upcoming maintenance)
+func SuccessWithNotice(c *gin.Context, statusCode int, data interface{}, notice interface{}) {
+	c.JSON(statusCode, Response{
+		Status: "success",
+		Data:   data,
+		Notice: notice,
+	})
+}
+
+// SuccessWithWarnings sends success response with warnings about the
+// request (e.g. a possible duplicate). Empty warnings are left out.
+func SuccessWithWarnings(c *gin.Context, statusCode int, data interface{}, warnings gin.H) {
+	c.JSON(statusCode, Response{
+		Status:   "success",
+		Data:     data,
+		Warnings: warnings,
+	})
+}
+
 // Error sends error response
 func Error(c *gin.Context, statusCode int, message string) {
 	c.JSON(statusCode, Response{
-		Status:  "error",
-		Message: message,
+		Status:    "error",
+		Message:   message,
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// ErrorCode sends an error response with a machine-readable code (see codes.go)
+// and optional details
+func ErrorCode(c *gin.Context, statusCode int, code, message string, details interface{}) {
+	c.JSON(statusCode, Response{
+		Status:    "error",
+		Message:   message,
+		Code:      code,
+		Details:   details,
+		RequestID: c.GetString("request_id"),
+	})
+}
+
+// T returns the message of key in the language of the request, as decided
+// by the Locale middleware
+func T(c *gin.Context, key i18n.Key, args ...any) string {
+	return i18n.T(i18n.Of(c), key, args...)
+}
+
+// LocalizedErrorCode sends an ErrorCode response with the message of key in
+// the language of the request
+func LocalizedErrorCode(c *gin.Context, statusCode int, code string, key i18n.Key, details interface{}) {
+	ErrorCode(c, statusCode, code, T(c, key), details)
+}
+
+// ValidationDetails lists invalid request fields with a message per field
+type ValidationDetails struct {
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// ValidationError sends a 400 VALIDATION_FAILED response. fields maps request
+// field names to what is wrong with them and may be nil.
+func ValidationError(c *gin.Context, message string, fields map[string]string) {
+	var details interface{}
+	if len(fields) > 0 {
+		details = ValidationDetails{Fields: fields}
+	}
+	ErrorCode(c, http.StatusBadRequest, CodeValidationFailed, message, details)
+}
+
+// ConflictDetails names the request field whose value is already taken
+type ConflictDetails struct {
+	Field string `json:"field"`
+}
+
+// Conflict sends a 409 CONFLICT response. field is the request field whose
+// value is taken and may be empty.
+func Conflict(c *gin.Context, message, field string) {
+	var details interface{}
+	if field != "" {
+		details = ConflictDetails{Field: field}
+	}
+	ErrorCode(c, http.StatusConflict, CodeConflict, message, details)
+}
+
+// VersionConflictDetails carries the current copy of a record whose update
+// named an outdated version
+type VersionConflictDetails struct {
+	Current interface{} `json:"current"`
+}
+
+// VersionConflict sends a 409 VERSION_CONFLICT response with the record as
+// it is now, so the client can merge and retry with its version
+func VersionConflict(c *gin.Context, message string, current interface{}) {
+	ErrorCode(c, http.StatusConflict, CodeVersionConflict, message, VersionConflictDetails{Current: current})
+}
+
+// QuotaDetails is the user's usage of the quota a write would exceed
+type QuotaDetails struct {
+	Resource string `json:"resource"`
+	Used     int64  `json:"used"`
+	Limit    int64  `json:"limit"`
+}
+
+// QuotaExceeded sends a 403 QUOTA_EXCEEDED response with the usage of the
+// quota. Unlike a rate limit, retrying only helps once usage goes down.
+func QuotaExceeded(c *gin.Context, message string, details QuotaDetails) {
+	ErrorCode(c, http.StatusForbidden, CodeQuotaExceeded, message, details)
+}
+
+// RateLimitDetails tells the client when to retry; the same value is sent
+// in the Retry-After header
+type RateLimitDetails struct {
+	RetryAfterSeconds int `json:"retry_after_seconds"`
+}
+
+// RateLimited sends a 429 RATE_LIMITED response. When retryAfter is known it
+// is also sent as the Retry-After header (rounded up to whole seconds).
+func RateLimited(c *gin.Context, message string, retryAfter time.Duration) {
+	var details interface{}
+	if retryAfter > 0 {
+		seconds := int(math.Ceil(retryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		details = RateLimitDetails{RetryAfterSeconds: seconds}
+	}
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, Response{
+		Status:    "error",
+		Message:   message,
+		Code:      CodeRateLimited,
+		Details:   details,
+		RequestID: c.GetString("request_id"),
 	})
 }
 
@@ -36,6 +175,12 @@ func SuccessWithMessage(c *gin.Context, statusCode int, message string, data int
 	})
 }
 
+// LocalizedSuccess sends a SuccessWithMessage response with the message of
+// key in the language of the request
+func LocalizedSuccess(c *gin.Context, statusCode int, key i18n.Key, data interface{}) {
+	SuccessWithMessage(c, statusCode, T(c, key), data)
+}
+
 // Unauthorized sends unauthorized response
 func Unauthorized(c *gin.Context, message string) {
 	Error(c, 401, message)
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -32,6 +33,35 @@ func TestSuccess(t *testing.T) {
 	})
 }
 
+func TestSuccessWithWarnings(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/test", func(c *gin.Context) {
+		SuccessWithWarnings(c, http.StatusCreated, gin.H{"id": "1"}, gin.H{"possible_duplicate": gin.H{"existing_entry_id": "2"}})
+	})
+	router.GET("/none", func(c *gin.Context) {
+		SuccessWithWarnings(c, http.StatusOK, gin.H{"id": "1"}, nil)
+	})
+
+	t.Run("returns warnings next to the data", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.JSONEq(t, `{"status":"success","data":{"id":"1"},"warnings":{"possible_duplicate":{"existing_entry_id":"2"}}}`, w.Body.String())
+	})
+
+	t.Run("omits empty warnings", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/none", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.NotContains(t, w.Body.String(), "warnings")
+	})
+}
+
 func TestError(t *testing.T) {
 	router := setupTestRouter()
 	router.GET("/test", func(c *gin.Context) {
@@ -117,3 +147,111 @@ func TestInternalError(t *testing.T) {
 		assert.Contains(t, w.Body.String(), "database connection failed")
 	})
 }
+
+func TestErrorCode(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/test", func(c *gin.Context) {
+		ErrorCode(c, http.StatusUnauthorized, CodeAuthInvalidCredentials, "Неверные учетные данные", nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.JSONEq(t, `{"status":"error","message":"Неверные учетные данные","code":"AUTH_INVALID_CREDENTIALS"}`, w.Body.String())
+}
+
+func TestErrorIncludesRequestID(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/test", func(c *gin.Context) {
+		c.Set("request_id", "req-42")
+		ErrorCode(c, http.StatusNotFound, CodeNotFound, "Не найдено", nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.JSONEq(t, `{"status":"error","message":"Не найдено","code":"NOT_FOUND","request_id":"req-42"}`, w.Body.String())
+}
+
+func TestErrorOmitsCode(t *testing.T) {
+	router := setupTestRouter()
+	router.GET("/test", func(c *gin.Context) {
+		Error(c, http.StatusBadRequest, "validation error")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	// Responses without a code keep the original shape
+	assert.JSONEq(t, `{"status":"error","message":"validation error"}`, w.Body.String())
+}
+
+func TestValidationError(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   string
+	}{
+		{"with fields", map[string]string{"email": "Некорректный email"},
+			`{"status":"error","message":"Неверные данные запроса","code":"VALIDATION_FAILED","details":{"fields":{"email":"Некорректный email"}}}`},
+		{"without fields", nil,
+			`{"status":"error","message":"Неверные данные запроса","code":"VALIDATION_FAILED"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouter()
+			router.POST("/test", func(c *gin.Context) {
+				ValidationError(c, "Неверные данные запроса", tt.fields)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, "/test", nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.JSONEq(t, tt.want, w.Body.String())
+		})
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+	t.Run("rounds retry_after_seconds up to seconds", func(t *testing.T) {
+		router := setupTestRouter()
+		router.POST("/test", func(c *gin.Context) {
+			RateLimited(c, "Слишком много запросов", 1500*time.Millisecond)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/test", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "2", w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"status":"error","message":"Слишком много запросов","code":"RATE_LIMITED","details":{"retry_after_seconds":2}}`, w.Body.String())
+	})
+
+	t.Run("omits retry_after_seconds when unknown", func(t *testing.T) {
+		router := setupTestRouter()
+		router.POST("/test", func(c *gin.Context) {
+			RateLimited(c, "Слишком много запросов", 0)
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/test", nil)
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Empty(t, w.Header().Get("Retry-After"))
+		assert.JSONEq(t, `{"status":"error","message":"Слишком много запросов","code":"RATE_LIMITED"}`, w.Body.String())
+	})
+}
@@ -0,0 +1,123 @@
+// Package securityevents counts the security events passed to
+// logger.LogSecurityEvent, so alerting can page on spikes, and keeps the
+// latest ones for triage without log access.
+package securityevents
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/burcev/api/internal/shared/logger"
+)
+
+// DefaultCapacity is how many recent events a registry keeps
+const DefaultCapacity = 200
+
+// MetricName is the name of the exported counter
+const MetricName = "security_events_total"
+
+// Count is the number of events with one name and severity since startup
+type Count struct {
+	Event    string `json:"event"`
+	Severity string `json:"severity"`
+	Count    uint64 `json:"count"`
+}
+
+type countKey struct {
+	event, severity string
+}
+
+// Registry is a logger.SecuritySink counting events by name and severity
+// and keeping the most recent ones in a ring buffer
+type Registry struct {
+	mu     sync.Mutex
+	counts map[countKey]uint64
+	recent []logger.SecurityEvent
+	// next is the ring slot the next event goes to
+	next int
+	full bool
+}
+
+// NewRegistry creates a registry keeping the last capacity events;
+// capacity below 1 means DefaultCapacity
+func NewRegistry(capacity int) *Registry {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Registry{
+		counts: make(map[countKey]uint64),
+		recent: make([]logger.SecurityEvent, capacity),
+	}
+}
+
+// RecordSecurityEvent implements logger.SecuritySink
+func (r *Registry) RecordSecurityEvent(event logger.SecurityEvent) {
+	// The caller keeps its map
+	event.Fields = maps.Clone(event.Fields)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[countKey{event.Event, event.Severity}]++
+	r.recent[r.next] = event
+	r.next = (r.next + 1) % len(r.recent)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns the kept events, newest first
+func (r *Registry) Recent() []logger.SecurityEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := r.next
+	if r.full {
+		n = len(r.recent)
+	}
+	events := make([]logger.SecurityEvent, 0, n)
+	for i := 1; i <= n; i++ {
+		events = append(events, r.recent[(r.next-i+len(r.recent))%len(r.recent)])
+	}
+	return events
+}
+
+// Counts returns the event counts sorted by event and severity
+func (r *Registry) Counts() []Count {
+	r.mu.Lock()
+	counts := make([]Count, 0, len(r.counts))
+	for key, n := range r.counts {
+		counts = append(counts, Count{Event: key.event, Severity: key.severity, Count: n})
+	}
+	r.mu.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Event != counts[j].Event {
+			return counts[i].Event < counts[j].Event
+		}
+		return counts[i].Severity < counts[j].Severity
+	})
+	return counts
+}
+
+// WritePrometheus writes the counts in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s Security events by event and severity.\n", MetricName)
+	fmt.Fprintf(&b, "# TYPE %s counter\n", MetricName)
+	for _, c := range r.Counts() {
+		fmt.Fprintf(&b, "%s{event=\"%s\",severity=\"%s\"} %d\n",
+			MetricName, escapeLabel(c.Event), escapeLabel(c.Severity), c.Count)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
@@ -0,0 +1,112 @@
+package securityevents
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(r *Registry, event, severity string) {
+	r.RecordSecurityEvent(logger.SecurityEvent{Event: event, Severity: severity})
+}
+
+func eventNames(events []logger.SecurityEvent) []string {
+	names := make([]string, len(events))
+	for i, e := range events {
+		names[i] = e.Event
+	}
+	return names
+}
+
+func TestRegistry_Recent(t *testing.T) {
+	t.Run("newest first", func(t *testing.T) {
+		r := NewRegistry(3)
+		assert.Empty(t, r.Recent())
+
+		record(r, "a", "low")
+		record(r, "b", "low")
+
+		assert.Equal(t, []string{"b", "a"}, eventNames(r.Recent()))
+	})
+
+	t.Run("keeps the last capacity events", func(t *testing.T) {
+		r := NewRegistry(3)
+		for _, name := range []string{"a", "b", "c", "d", "e"} {
+			record(r, name, "low")
+		}
+
+		assert.Equal(t, []string{"e", "d", "c"}, eventNames(r.Recent()))
+	})
+
+	t.Run("default capacity", func(t *testing.T) {
+		r := NewRegistry(0)
+		for i := 0; i < DefaultCapacity+10; i++ {
+			record(r, "ip_rate_limit_exceeded", "high")
+		}
+
+		assert.Len(t, r.Recent(), DefaultCapacity)
+	})
+
+	t.Run("fields are copied", func(t *testing.T) {
+		r := NewRegistry(3)
+		fields := map[string]interface{}{"ip": "203.0.113.9"}
+		r.RecordSecurityEvent(logger.SecurityEvent{Event: "a", Severity: "low", Fields: fields})
+		fields["ip"] = "changed"
+
+		assert.Equal(t, "203.0.113.9", r.Recent()[0].Fields["ip"])
+	})
+}
+
+func TestRegistry_Counts(t *testing.T) {
+	r := NewRegistry(2)
+	record(r, "password_reset_rate_limit", "high")
+	record(r, "ip_rate_limit_exceeded", "high")
+	record(r, "password_reset_rate_limit", "high")
+	record(r, "password_reset_rate_limit", "medium")
+
+	// Counts outlive the ring buffer
+	assert.Equal(t, []Count{
+		{Event: "ip_rate_limit_exceeded", Severity: "high", Count: 1},
+		{Event: "password_reset_rate_limit", Severity: "high", Count: 2},
+		{Event: "password_reset_rate_limit", Severity: "medium", Count: 1},
+	}, r.Counts())
+}
+
+func TestRegistry_WritePrometheus(t *testing.T) {
+	r := NewRegistry(10)
+	record(r, "login_lockout", "high")
+	record(r, "login_lockout", "high")
+	record(r, `odd"name`, "low")
+
+	var b strings.Builder
+	require.NoError(t, r.WritePrometheus(&b))
+
+	assert.Equal(t, `# HELP security_events_total Security events by event and severity.
+# TYPE security_events_total counter
+security_events_total{event="login_lockout",severity="high"} 2
+security_events_total{event="odd\"name",severity="low"} 1
+`, b.String())
+}
+
+func TestRegistry_Concurrent(t *testing.T) {
+	r := NewRegistry(5)
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				record(r, "ip_rate_limit_exceeded", "high")
+				r.Recent()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, []Count{{Event: "ip_rate_limit_exceeded", Severity: "high", Count: 1000}}, r.Counts())
+	assert.Len(t, r.Recent(), 5)
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+// DefaultRegion is the region name used when an organization has no explicit
+// storage region or names one that is not configured.
+const DefaultRegion = "default"
+
+// ErrRegionUnavailable is returned for a storage region that is not
+// configured, e.g. one an organization is pinned to or a file was written to
+var ErrRegionUnavailable = errors.New("storage region is not configured")
+
+// ObjectStore is the set of object operations used by region-aware callers.
+// *S3Client satisfies it.
+type ObjectStore interface {
+	UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error)
+	GetFile(ctx context.Context, key string) ([]byte, error)
+	DeleteFile(ctx context.Context, key string) error
+	GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error)
+}
+
+// Regions maps named storage regions (e.g. "default", "eu") to object stores
+// so that data can be kept in the bucket an organization requires.
+type Regions struct {
+	defaultRegion string
+	stores        map[string]ObjectStore
+}
+
+// NewRegions creates an empty region registry. An empty defaultRegion falls
+// back to DefaultRegion.
+func NewRegions(defaultRegion string) *Regions {
+	if defaultRegion == "" {
+		defaultRegion = DefaultRegion
+	}
+	return &Regions{
+		defaultRegion: defaultRegion,
+		stores:        make(map[string]ObjectStore),
+	}
+}
+
+// Register adds or replaces the store for a named region.
+func (r *Regions) Register(name string, store ObjectStore) {
+	r.stores[name] = store
+}
+
+// Default returns the name of the fallback region.
+func (r *Regions) Default() string {
+	return r.defaultRegion
+}
+
+// Has reports whether a store is configured for the region.
+func (r *Regions) Has(name string) bool {
+	if r == nil {
+		return false
+	}
+	_, ok := r.stores[name]
+	return ok
+}
+
+// Names returns the configured region names in sorted order.
+func (r *Regions) Names() []string {
+	if r == nil {
+		return nil
+	}
+	names := make([]string, 0, len(r.stores))
+	for name := range r.stores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Resolve returns the effective region name and store for the requested
+// region. Empty or unconfigured regions resolve to the default region; the
+// returned store is nil when the default region is not configured either.
+func (r *Regions) Resolve(name string) (string, ObjectStore) {
+	if r == nil {
+		return "", nil
+	}
+	if store, ok := r.stores[name]; ok {
+		return name, store
+	}
+	return r.defaultRegion, r.stores[r.defaultRegion]
+}
+
+// Store returns the store for exactly the named region, without falling back.
+// Used when the object location is known, e.g. when reading an existing file.
+func (r *Regions) Store(name string) (ObjectStore, bool) {
+	if r == nil {
+		return nil, false
+	}
+	store, ok := r.stores[name]
+	return store, ok
+}
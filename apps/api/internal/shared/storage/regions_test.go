@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegionsResolve(t *testing.T) {
+	defaultStore := &S3Client{bucket: "photos-ru"}
+	euStore := &S3Client{bucket: "photos-eu"}
+
+	regions := NewRegions("")
+	regions.Register(DefaultRegion, defaultStore)
+	regions.Register("eu", euStore)
+
+	tests := []struct {
+		name       string
+		region     string
+		wantRegion string
+		wantStore  ObjectStore
+	}{
+		{"configured region", "eu", "eu", euStore},
+		{"default region", DefaultRegion, DefaultRegion, defaultStore},
+		{"empty region falls back to default", "", DefaultRegion, defaultStore},
+		{"unknown region falls back to default", "us", DefaultRegion, defaultStore},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, store := regions.Resolve(tt.region)
+			assert.Equal(t, tt.wantRegion, region)
+			assert.Same(t, tt.wantStore, store)
+		})
+	}
+}
+
+func TestRegionsResolveWithoutDefault(t *testing.T) {
+	regions := NewRegions("ru")
+	regions.Register("eu", &S3Client{bucket: "photos-eu"})
+
+	region, store := regions.Resolve("us")
+	assert.Equal(t, "ru", region)
+	assert.Nil(t, store)
+}
+
+func TestRegionsStore(t *testing.T) {
+	regions := NewRegions("")
+	regions.Register(DefaultRegion, &S3Client{bucket: "photos-ru"})
+
+	_, ok := regions.Store("eu")
+	assert.False(t, ok, "exact lookup must not fall back to the default region")
+
+	store, ok := regions.Store(DefaultRegion)
+	assert.True(t, ok)
+	assert.NotNil(t, store)
+}
+
+func TestRegionsNames(t *testing.T) {
+	regions := NewRegions("")
+	regions.Register("eu", &S3Client{})
+	regions.Register(DefaultRegion, &S3Client{})
+
+	assert.Equal(t, []string{DefaultRegion, "eu"}, regions.Names())
+	assert.True(t, regions.Has("eu"))
+	assert.False(t, regions.Has("us"))
+
+	var nilRegions *Regions
+	assert.False(t, nilRegions.Has("eu"))
+	region, store := nilRegions.Resolve("eu")
+	assert.Empty(t, region)
+	assert.Nil(t, store)
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+// ObjectStorage adapts a regional ObjectStore to the Storage interface. Put
+// spools the object to a temp file, as uploads need its size and content
+// type up front.
+type ObjectStorage struct {
+	store ObjectStore
+}
+
+// NewObjectStorage wraps store as a Storage
+func NewObjectStorage(store ObjectStore) *ObjectStorage {
+	return &ObjectStorage{store: store}
+}
+
+// Put uploads the object with its content type detected from the data
+func (o *ObjectStorage) Put(ctx context.Context, key string, data io.Reader) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp("", "object-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, data)
+	if err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+	head := make([]byte, 512)
+	n, err := tmp.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read object: %w", err)
+	}
+
+	if _, err := o.store.UploadFile(ctx, key, tmp, http.DetectContentType(head[:n]), size); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Get downloads the object
+func (o *ObjectStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, err := o.store.GetFile(ctx, key)
+	if IsNotFound(err) {
+		return nil, ErrObjectNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+// Delete removes the object
+func (o *ObjectStorage) Delete(ctx context.Context, key string) error {
+	return o.store.DeleteFile(ctx, key)
+}
+
+// RegionLookup returns the storage region of the user's organization, or ""
+// for a user without one
+type RegionLookup func(ctx context.Context, userID int64) (string, error)
+
+// Router picks the store of a user's files (progress photos, entry photos,
+// exports) by the storage region of their organization. Users without an
+// organization and organizations in the default region keep their files in
+// the local store; other regions use their object store. The region is
+// recorded with each file as returned by ForUser, "" for the local store, so
+// that reads and deletes go to where the file was written.
+type Router struct {
+	local   Storage
+	regions *Regions
+	lookup  RegionLookup
+}
+
+// NewRouter creates a router over the local store and the configured
+// regions. A nil lookup keeps every file in the local store.
+func NewRouter(local Storage, regions *Regions, lookup RegionLookup) *Router {
+	return &Router{local: local, regions: regions, lookup: lookup}
+}
+
+// ForUser returns the region new files of the user are written to and its
+// store. An organization pinned to an unconfigured region fails with
+// ErrRegionUnavailable rather than falling back to the local store.
+func (r *Router) ForUser(ctx context.Context, userID int64) (string, Storage, error) {
+	if r.lookup == nil {
+		return "", r.local, nil
+	}
+	region, err := r.lookup(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get storage region: %w", err)
+	}
+	if r.regions != nil && region == r.regions.Default() {
+		region = ""
+	}
+	store, err := r.ForRegion(region)
+	if err != nil {
+		return "", nil, err
+	}
+	return region, store, nil
+}
+
+// ForRegion returns the store of a region recorded with a file, the local
+// store for "". An unconfigured region fails with ErrRegionUnavailable.
+func (r *Router) ForRegion(region string) (Storage, error) {
+	if region == "" {
+		return r.local, nil
+	}
+	store, ok := r.regions.Store(region)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRegionUnavailable, region)
+	}
+	return NewObjectStorage(store), nil
+}
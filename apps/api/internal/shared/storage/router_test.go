@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memObjectStore is an in-memory ObjectStore
+type memObjectStore struct {
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newMemObjectStore() *memObjectStore {
+	return &memObjectStore{objects: make(map[string][]byte), types: make(map[string]string)}
+}
+
+func (m *memObjectStore) UploadFile(ctx context.Context, key string, data io.Reader, contentType string, fileSize int64) (string, error) {
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return "", err
+	}
+	m.objects[key] = b
+	m.types[key] = contentType
+	return "https://storage.example/" + key, nil
+}
+
+func (m *memObjectStore) GetFile(ctx context.Context, key string) ([]byte, error) {
+	b, ok := m.objects[key]
+	if !ok {
+		return nil, &s3types.NoSuchKey{}
+	}
+	return b, nil
+}
+
+func (m *memObjectStore) DeleteFile(ctx context.Context, key string) error {
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memObjectStore) GetSignedURL(ctx context.Context, key string, expiration time.Duration) (string, error) {
+	return "https://storage.example/" + key + "?signed", nil
+}
+
+func TestRouterForUser(t *testing.T) {
+	local := NewMemoryStorage()
+	eu := newMemObjectStore()
+	regions := NewRegions("ru")
+	regions.Register("ru", newMemObjectStore())
+	regions.Register("eu", eu)
+
+	orgRegions := map[int64]string{1: "", 2: "ru", 3: "eu", 4: "us"}
+	router := NewRouter(local, regions, func(ctx context.Context, userID int64) (string, error) {
+		if userID == 5 {
+			return "", errors.New("connection refused")
+		}
+		return orgRegions[userID], nil
+	})
+
+	tests := []struct {
+		name       string
+		userID     int64
+		wantRegion string
+		wantStore  Storage
+		wantErr    error
+	}{
+		{"no organization uses the local store", 1, "", local, nil},
+		{"default region uses the local store", 2, "", local, nil},
+		{"configured region uses its object store", 3, "eu", NewObjectStorage(eu), nil},
+		{"unconfigured region fails", 4, "", nil, ErrRegionUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, store, err := router.ForUser(context.Background(), tt.userID)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRegion, region)
+			assert.Equal(t, tt.wantStore, store)
+		})
+	}
+
+	t.Run("lookup errors are returned", func(t *testing.T) {
+		_, _, err := router.ForUser(context.Background(), 5)
+		assert.ErrorContains(t, err, "connection refused")
+	})
+}
+
+func TestRouterWithoutLookup(t *testing.T) {
+	local := NewMemoryStorage()
+	router := NewRouter(local, nil, nil)
+
+	region, store, err := router.ForUser(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, "", region)
+	assert.Same(t, local, store)
+
+	_, err = router.ForRegion("eu")
+	assert.ErrorIs(t, err, ErrRegionUnavailable)
+}
+
+func TestObjectStorage(t *testing.T) {
+	ctx := context.Background()
+	objects := newMemObjectStore()
+	store := NewObjectStorage(objects)
+
+	require.NoError(t, store.Put(ctx, "photos/1/a.png", strings.NewReader("\x89PNG\r\n\x1a\nrest")))
+	assert.Equal(t, "image/png", objects.types["photos/1/a.png"])
+
+	r, err := store.Get(ctx, "photos/1/a.png")
+	require.NoError(t, err)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG\r\n\x1a\nrest", string(b))
+
+	require.NoError(t, store.Delete(ctx, "photos/1/a.png"))
+	_, err = store.Get(ctx, "photos/1/a.png")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+
+	assert.Error(t, store.Put(ctx, "../escape", strings.NewReader("x")))
+}
@@ -162,6 +162,16 @@ func (s *S3Client) GetFile(ctx context.Context, key string) ([]byte, error) {
 	return data, nil
 }
 
+// Ping checks that the bucket is reachable by fetching a key that is not
+// expected to exist; a NoSuchKey answer still proves the bucket responds
+func (s *S3Client) Ping(ctx context.Context) error {
+	_, err := s.GetFile(ctx, ".ping")
+	if err != nil && !IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
 // IsNotFound returns true if err is an S3 NoSuchKey (404) error.
 func IsNotFound(err error) bool {
 	if err == nil {
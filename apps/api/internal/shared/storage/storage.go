@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrObjectNotFound is returned by Storage implementations for missing keys
+var ErrObjectNotFound = errors.New("object not found")
+
+// Storage is a minimal blob store used by modules that keep user files
+// (progress photos, exports). Keys are slash-separated relative paths such as
+// "photos/42/0f8c....jpg". Implementations: LocalStorage (disk) and
+// MemoryStorage (tests).
+type Storage interface {
+	Put(ctx context.Context, key string, data io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// validateKey rejects keys that could escape the storage root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	if path.Clean(key) != key || key == "." || strings.HasPrefix(key, "../") || key == ".." {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func storageImplementations(t *testing.T) map[string]Storage {
+	local, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	return map[string]Storage{
+		"local":  local,
+		"memory": NewMemoryStorage(),
+	}
+}
+
+func TestStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range storageImplementations(t) {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, store.Put(ctx, "photos/1/a.jpg", strings.NewReader("first")))
+			require.NoError(t, store.Put(ctx, "photos/1/a.jpg", strings.NewReader("second")))
+
+			r, err := store.Get(ctx, "photos/1/a.jpg")
+			require.NoError(t, err)
+			data, err := io.ReadAll(r)
+			r.Close()
+			require.NoError(t, err)
+			assert.Equal(t, "second", string(data))
+
+			require.NoError(t, store.Delete(ctx, "photos/1/a.jpg"))
+			_, err = store.Get(ctx, "photos/1/a.jpg")
+			assert.ErrorIs(t, err, ErrObjectNotFound)
+
+			assert.NoError(t, store.Delete(ctx, "photos/1/a.jpg"), "deleting a missing object is not an error")
+		})
+	}
+}
+
+func TestStorageRejectsUnsafeKeys(t *testing.T) {
+	ctx := context.Background()
+
+	for name, store := range storageImplementations(t) {
+		for _, key := range []string{"", "/etc/passwd", "../escape", "photos/../../escape", "photos//a.jpg", `photos\a.jpg`} {
+			t.Run(name+" "+key, func(t *testing.T) {
+				assert.Error(t, store.Put(ctx, key, strings.NewReader("x")))
+			})
+		}
+	}
+}
+
+func TestNewLocalStorageRequiresDir(t *testing.T) {
+	_, err := NewLocalStorage("")
+	assert.Error(t, err)
+}
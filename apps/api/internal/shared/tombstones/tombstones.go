@@ -0,0 +1,32 @@
+// Package tombstones records deletions of the rows devices sync, so an
+// incremental sync can tell them what to drop. A tombstone is written in
+// the transaction deleting the row.
+package tombstones
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Synced collections
+const (
+	NutritionEntries = "nutrition_entries"
+	Measurements     = "measurements"
+	DayFlags         = "day_flags"
+)
+
+// Execer is satisfied by *sql.Tx, *sql.DB and *database.DB
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Record stores that the user's row itemID was deleted from collection
+func Record(ctx context.Context, exec Execer, userID int64, collection, itemID string) error {
+	if _, err := exec.ExecContext(ctx,
+		`INSERT INTO sync_tombstones (user_id, collection, item_id) VALUES ($1, $2, $3)`,
+		userID, collection, itemID); err != nil {
+		return fmt.Errorf("failed to record %s deletion: %w", collection, err)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+// Package types holds value types that requests bind directly, so their
+// format is checked once while binding instead of in every handler.
+package types
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// DateLayout is the YYYY-MM-DD format dates are exchanged in
+const DateLayout = "2006-01-02"
+
+// DateMessage is the validation message of a malformed date
+const DateMessage = "Неверный формат даты, ожидается ГГГГ-ММ-ДД"
+
+// minDate is the earliest date accepted. It keeps the zero Date free to
+// mean "not set" and rejects placeholders such as 0000-01-01.
+var minDate = NewDate(1900, time.January, 1)
+
+func init() {
+	// Validation tags see a Date as its YYYY-MM-DD string, so an unset date
+	// fails "required"
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+			return field.Interface().(Date).String()
+		}, Date{})
+	}
+}
+
+// Date is a calendar day without a time zone. The zero Date is "not set".
+//
+// A Date binds from JSON strings, query and path parameters in YYYY-MM-DD
+// form. Anything else, including impossible days such as 2025-02-29 or
+// 0000-00-00, binds as a malformed Date that the validation binders report
+// on its field, so it must be bound with validation.BindJSON, BindQuery or
+// Param.
+type Date struct {
+	// t is midnight UTC of the day
+	t time.Time
+	// malformed is the value that failed to parse, if any
+	malformed string
+}
+
+// DateError is a value that is not a YYYY-MM-DD date
+type DateError struct {
+	Value string
+}
+
+func (e *DateError) Error() string {
+	return fmt.Sprintf("invalid date %q, expected YYYY-MM-DD", e.Value)
+}
+
+// NewDate returns the given day; out-of-range values are normalized the way
+// time.Date does
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{t: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the day t falls on in its own location
+func DateOf(t time.Time) Date {
+	return NewDate(t.Date())
+}
+
+// ParseDate parses a YYYY-MM-DD date. The value has no time zone, so the
+// result is the same day wherever the server runs.
+func ParseDate(value string) (Date, error) {
+	t, err := time.Parse(DateLayout, value)
+	if err != nil || t.Before(minDate.t) {
+		return Date{}, &DateError{Value: value}
+	}
+	return Date{t: t}, nil
+}
+
+// IsZero reports whether the date is not set
+func (d Date) IsZero() bool {
+	return d.t.IsZero() && d.malformed == ""
+}
+
+// Malformed reports whether the date was bound from a value that is not a
+// YYYY-MM-DD date
+func (d Date) Malformed() bool {
+	return d.malformed != ""
+}
+
+// String returns the date as YYYY-MM-DD, "" when it is not set, or the
+// value a malformed date was bound from
+func (d Date) String() string {
+	switch {
+	case d.Malformed():
+		return d.malformed
+	case d.IsZero():
+		return ""
+	}
+	return d.t.Format(DateLayout)
+}
+
+// Time returns midnight UTC of the day
+func (d Date) Time() time.Time {
+	return d.t
+}
+
+// In returns midnight of the day in loc
+func (d Date) In(loc *time.Location) time.Time {
+	return time.Date(d.t.Year(), d.t.Month(), d.t.Day(), 0, 0, 0, 0, loc)
+}
+
+// AddDays returns the date n days later, or earlier for negative n
+func (d Date) AddDays(n int) Date {
+	return Date{t: d.t.AddDate(0, 0, n)}
+}
+
+// Before reports whether d is an earlier day than o
+func (d Date) Before(o Date) bool {
+	return d.t.Before(o.t)
+}
+
+// After reports whether d is a later day than o
+func (d Date) After(o Date) bool {
+	return d.t.After(o.t)
+}
+
+// Between reports whether d is within earliest..latest, both included. It
+// is the bounds check for dates that may only be so far back or ahead.
+func (d Date) Between(earliest, latest Date) bool {
+	return !d.Before(earliest) && !d.After(latest)
+}
+
+// MarshalText formats the date as YYYY-MM-DD
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText parses a YYYY-MM-DD date; an empty value leaves it unset
+func (d *Date) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*d = Date{}
+		return nil
+	}
+	parsed, err := ParseDate(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalJSON binds a JSON string date; null leaves it unset. Anything
+// else binds as a malformed date rather than failing the whole body, which
+// would lose the field it was meant for.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	value, err := strconv.Unquote(string(data))
+	if err != nil {
+		*d = Date{malformed: string(data)}
+		return nil
+	}
+	return d.UnmarshalParam(value)
+}
+
+// UnmarshalParam binds a query or path parameter for gin's form binding;
+// an empty value leaves the date unset and a malformed one binds as such
+func (d *Date) UnmarshalParam(param string) error {
+	if err := d.UnmarshalText([]byte(param)); err != nil {
+		*d = Date{malformed: param}
+	}
+	return nil
+}
+
+// Value passes the date to SQL as YYYY-MM-DD, or NULL when it is not set
+func (d Date) Value() (driver.Value, error) {
+	switch {
+	case d.Malformed():
+		return nil, &DateError{Value: d.malformed}
+	case d.IsZero():
+		return nil, nil
+	}
+	return d.String(), nil
+}
+
+// TypeMessage is the validation message of a value that is not a date
+func (Date) TypeMessage() string {
+	return DateMessage
+}
@@ -0,0 +1,260 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  Date
+		ok    bool
+	}{
+		{"2026-01-26", NewDate(2026, time.January, 26), true},
+		{"2024-02-29", NewDate(2024, time.February, 29), true},
+		{"2000-02-29", NewDate(2000, time.February, 29), true},
+		{"1900-01-01", NewDate(1900, time.January, 1), true},
+		// Leap days only in leap years; 1900 and 2100 are not
+		{"2025-02-29", Date{}, false},
+		{"1900-02-29", Date{}, false},
+		{"2100-02-29", Date{}, false},
+		{"2026-04-31", Date{}, false},
+		{"2026-13-01", Date{}, false},
+		// Zero placeholders are not days
+		{"0000-00-00", Date{}, false},
+		{"0000-01-01", Date{}, false},
+		{"0001-01-01", Date{}, false},
+		{"1899-12-31", Date{}, false},
+		// Only YYYY-MM-DD, with no time or zone
+		{"2026-1-26", Date{}, false},
+		{"26.01.2026", Date{}, false},
+		{"2026-01-26T00:00:00Z", Date{}, false},
+		{"2026-01-26 10:00", Date{}, false},
+		{" 2026-01-26", Date{}, false},
+		{"", Date{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseDate(tt.value)
+
+			if !tt.ok {
+				var dateErr *DateError
+				require.ErrorAs(t, err, &dateErr)
+				assert.Equal(t, tt.value, dateErr.Value)
+				assert.True(t, got.IsZero())
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.value, got.String())
+		})
+	}
+}
+
+func TestDate_TimeZones(t *testing.T) {
+	vladivostok := time.FixedZone("UTC+10", 10*60*60)
+	honolulu := time.FixedZone("UTC-10", -10*60*60)
+
+	t.Run("parsing ignores the server time zone", func(t *testing.T) {
+		local := time.Local
+		time.Local = honolulu
+		t.Cleanup(func() { time.Local = local })
+
+		d, err := ParseDate("2026-01-26")
+
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2026, time.January, 26, 0, 0, 0, 0, time.UTC), d.Time())
+	})
+
+	t.Run("the same day starts at different instants", func(t *testing.T) {
+		d := NewDate(2026, time.January, 26)
+
+		assert.Equal(t, "2026-01-25T14:00:00Z", d.In(vladivostok).UTC().Format(time.RFC3339))
+		assert.Equal(t, "2026-01-26T10:00:00Z", d.In(honolulu).UTC().Format(time.RFC3339))
+	})
+
+	t.Run("an instant falls on the day of its location", func(t *testing.T) {
+		instant := time.Date(2026, time.January, 26, 20, 0, 0, 0, time.UTC)
+
+		assert.Equal(t, "2026-01-27", DateOf(instant.In(vladivostok)).String())
+		assert.Equal(t, "2026-01-26", DateOf(instant).String())
+		assert.Equal(t, "2026-01-26", DateOf(instant.In(honolulu)).String())
+	})
+}
+
+func TestDate_Arithmetic(t *testing.T) {
+	d := NewDate(2024, time.February, 28)
+
+	assert.Equal(t, "2024-02-29", d.AddDays(1).String())
+	assert.Equal(t, "2024-03-01", d.AddDays(2).String())
+	assert.Equal(t, "2023-02-28", d.AddDays(-365).String())
+	assert.True(t, d.Before(d.AddDays(1)))
+	assert.True(t, d.After(d.AddDays(-1)))
+	assert.True(t, d.Between(d, d))
+	assert.True(t, d.Between(d.AddDays(-1), d.AddDays(1)))
+	assert.False(t, d.Between(d.AddDays(1), d.AddDays(2)))
+	assert.Equal(t, "", Date{}.String())
+}
+
+type dateRequest struct {
+	Date     Date  `json:"date" binding:"required"`
+	Optional *Date `json:"optional"`
+	Days     []struct {
+		Date Date `json:"date"`
+	} `json:"days,omitempty"`
+}
+
+func TestDate_JSON(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		var req dateRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"date":"2024-02-29","optional":null}`), &req))
+
+		assert.Equal(t, NewDate(2024, time.February, 29), req.Date)
+		assert.Nil(t, req.Optional)
+		out, err := json.Marshal(req)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"date":"2024-02-29","optional":null}`, string(out))
+	})
+
+	t.Run("invalid values bind as malformed", func(t *testing.T) {
+		var req dateRequest
+		require.NoError(t, json.Unmarshal([]byte(`{"date":"0000-00-00"}`), &req))
+
+		assert.True(t, req.Date.Malformed())
+		assert.False(t, req.Date.IsZero())
+		assert.Equal(t, "0000-00-00", req.Date.String())
+	})
+}
+
+func bindRequest(t *testing.T, body string) (*dateRequest, error) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+
+	var req dateRequest
+	err := validation.BindJSON(c, &req)
+	return &req, err
+}
+
+func TestDate_Binding(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		req, err := bindRequest(t, `{"date":"2026-01-26"}`)
+
+		require.NoError(t, err)
+		assert.Equal(t, "2026-01-26", req.Date.String())
+	})
+
+	t.Run("missing date fails required", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"date":null}`, `{"date":""}`} {
+			_, err := bindRequest(t, body)
+
+			assert.Equal(t, map[string]string{"date": "Обязательное поле"}, validation.Fields(err), body)
+		}
+	})
+
+	t.Run("malformed dates name the field", func(t *testing.T) {
+		for _, body := range []string{
+			`{"date":"2025-02-29"}`,
+			`{"date":"0000-00-00"}`,
+			`{"date":20260126}`,
+			`{"date":"2026-01-26T10:00:00+03:00"}`,
+		} {
+			_, err := bindRequest(t, body)
+
+			assert.Equal(t, map[string]string{"date": DateMessage}, validation.Fields(err), body)
+		}
+	})
+
+	t.Run("nested and optional dates", func(t *testing.T) {
+		_, err := bindRequest(t, `{"date":"2026-01-26","optional":"2026-02-30","days":[{"date":"2026-01-01"},{"date":"1-1-1"}]}`)
+
+		assert.Equal(t, map[string]string{"optional": DateMessage, "days[1].date": DateMessage}, validation.Fields(err))
+	})
+}
+
+type dayQuery struct {
+	Date Date `form:"date"`
+	DateRange
+}
+
+func TestDate_Query(t *testing.T) {
+	bind := func(query string) (dayQuery, error) {
+		gin.SetMode(gin.TestMode)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/?"+query, nil)
+		var q dayQuery
+		err := validation.BindQuery(c, &q)
+		return q, err
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		q, err := bind("date=2024-02-29&from=2024-02-01&to=2024-02-29")
+
+		require.NoError(t, err)
+		assert.Equal(t, "2024-02-29", q.Date.String())
+		assert.Equal(t, DateRange{From: NewDate(2024, time.February, 1), To: NewDate(2024, time.February, 29)}, q.DateRange)
+	})
+
+	t.Run("absent and empty values leave dates unset", func(t *testing.T) {
+		q, err := bind("date=")
+
+		require.NoError(t, err)
+		assert.True(t, q.Date.IsZero())
+		assert.True(t, q.From.IsZero())
+	})
+
+	t.Run("malformed values name the parameter", func(t *testing.T) {
+		_, err := bind("date=0000-00-00&from=2025-02-29&to=2026-01-26")
+
+		assert.Equal(t, map[string]string{"date": DateMessage, "from": DateMessage}, validation.Fields(err))
+	})
+}
+
+func TestDate_Param(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/days/:date", func(c *gin.Context) {
+		var date Date
+		if !validation.Param(c, "date", &date) {
+			return
+		}
+		c.String(http.StatusOK, date.String())
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/days/2024-02-29", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2024-02-29", w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/days/2025-02-29", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), DateMessage)
+}
+
+func TestDate_Value(t *testing.T) {
+	value, err := NewDate(2024, time.February, 29).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "2024-02-29", value)
+
+	value, err = Date{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	var malformed Date
+	require.NoError(t, malformed.UnmarshalParam("2025-02-29"))
+	_, err = malformed.Value()
+	assert.Error(t, err)
+}
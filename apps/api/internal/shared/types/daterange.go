@@ -0,0 +1,50 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/burcev/api/internal/shared/validation"
+)
+
+// DateRange is the from..to range of days, both ends included
+type DateRange struct {
+	From Date `json:"from" form:"from"`
+	To   Date `json:"to" form:"to"`
+}
+
+// Days counts the days of the range
+func (r DateRange) Days() int {
+	return int(r.To.t.Sub(r.From.t).Hours()/24) + 1
+}
+
+// Contains reports whether d is within the range
+func (r DateRange) Contains(d Date) bool {
+	return d.Between(r.From, r.To)
+}
+
+// Validate checks that both ends are set dates, that the range does not
+// end before it starts and, for a positive maxDays, that it spans at most
+// maxDays days. Problems are reported as validation.Errors on from and to.
+func (r DateRange) Validate(maxDays int) error {
+	errs := validation.Errors{}
+	for field, d := range map[string]Date{"from": r.From, "to": r.To} {
+		switch {
+		case d.Malformed():
+			errs[field] = DateMessage
+		case d.IsZero():
+			errs[field] = "Обязательное поле"
+		}
+	}
+	if len(errs) == 0 {
+		switch {
+		case r.To.Before(r.From):
+			errs["to"] = "Дата окончания раньше даты начала"
+		case maxDays > 0 && r.Days() > maxDays:
+			errs["to"] = fmt.Sprintf("Период не может быть длиннее %d дней", maxDays)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/burcev/api/internal/shared/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateRange_Validate(t *testing.T) {
+	jan := func(day int) Date { return NewDate(2026, time.January, day) }
+
+	tests := []struct {
+		name  string
+		r     DateRange
+		max   int
+		wants map[string]string
+	}{
+		{"single day", DateRange{jan(26), jan(26)}, 7, nil},
+		{"exactly the limit", DateRange{jan(1), jan(7)}, 7, nil},
+		{"no limit", DateRange{NewDate(2000, time.January, 1), jan(26)}, 0, nil},
+		{"over the limit", DateRange{jan(1), jan(8)}, 7, map[string]string{"to": "Период не может быть длиннее 7 дней"}},
+		{"ends before it starts", DateRange{jan(26), jan(25)}, 7, map[string]string{"to": "Дата окончания раньше даты начала"}},
+		{"open ends", DateRange{}, 7, map[string]string{"from": "Обязательное поле", "to": "Обязательное поле"}},
+		{"malformed end", DateRange{jan(1), Date{malformed: "2026-01-32"}}, 7, map[string]string{"to": DateMessage}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.r.Validate(tt.max)
+
+			if tt.wants == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, tt.wants, validation.Fields(err))
+		})
+	}
+}
+
+func TestDateRange_Days(t *testing.T) {
+	leap := DateRange{From: NewDate(2024, time.February, 1), To: NewDate(2024, time.March, 1)}
+
+	assert.Equal(t, 30, leap.Days())
+	assert.True(t, leap.Contains(NewDate(2024, time.February, 29)))
+	assert.False(t, leap.Contains(NewDate(2024, time.March, 2)))
+	// Days count calendar days across a DST change
+	assert.Equal(t, 2, DateRange{From: NewDate(2026, time.March, 29), To: NewDate(2026, time.March, 30)}.Days())
+}
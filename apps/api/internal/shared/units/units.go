@@ -0,0 +1,107 @@
+// Package units converts body weights and lengths between the metric values
+// the database stores and the unit system a user prefers.
+//
+// Values going out are rounded for display in imperial: weights to 0.1 lb,
+// lengths to 0.5 in. Metric values are what is stored and pass through
+// unchanged. Values coming in are converted to metric and rounded to
+// 0.01 kg / 0.1 cm so float noise does not reach the database.
+package units
+
+import "math"
+
+// Unit systems, as stored in user_settings.units
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// Unit labels returned next to converted values
+const (
+	Kilogram   = "kg"
+	Pound      = "lb"
+	Centimeter = "cm"
+	Inch       = "in"
+)
+
+const (
+	kgPerLb = 0.45359237
+	cmPerIn = 2.54
+)
+
+// Valid reports whether system is a known unit system
+func Valid(system string) bool {
+	return system == Metric || system == Imperial
+}
+
+// WeightUnit returns the weight label for a unit system
+func WeightUnit(system string) string {
+	if system == Imperial {
+		return Pound
+	}
+	return Kilogram
+}
+
+// LengthUnit returns the length label for a unit system
+func LengthUnit(system string) string {
+	if system == Imperial {
+		return Inch
+	}
+	return Centimeter
+}
+
+// Weight converts a stored weight (or weight difference) in kg for display
+func Weight(kg float64, system string) float64 {
+	if system != Imperial {
+		return kg
+	}
+	return roundTo(kg/kgPerLb, 0.1)
+}
+
+// WeightToKg converts a weight entered in system to kg for storage
+func WeightToKg(value float64, system string) float64 {
+	if system != Imperial {
+		return value
+	}
+	return roundTo(value*kgPerLb, 0.01)
+}
+
+// Length converts a stored length in cm for display
+func Length(cm float64, system string) float64 {
+	if system != Imperial {
+		return cm
+	}
+	return roundTo(cm/cmPerIn, 0.5)
+}
+
+// LengthToCm converts a length entered in system to cm for storage
+func LengthToCm(value float64, system string) float64 {
+	if system != Imperial {
+		return value
+	}
+	return roundTo(value*cmPerIn, 0.1)
+}
+
+// WeightPtr is Weight for optional values
+func WeightPtr(kg *float64, system string) *float64 {
+	if kg == nil {
+		return nil
+	}
+	v := Weight(*kg, system)
+	return &v
+}
+
+// LengthPtr is Length for optional values
+func LengthPtr(cm *float64, system string) *float64 {
+	if cm == nil {
+		return nil
+	}
+	v := Length(*cm, system)
+	return &v
+}
+
+// roundTo rounds v to the nearest multiple of step, halves away from zero
+func roundTo(v, step float64) float64 {
+	// Dividing by the step's reciprocal keeps 0.1 multiples exact in binary
+	inv := math.Round(1 / step)
+	return math.Round(v*inv) / inv
+}
@@ -0,0 +1,133 @@
+package units
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValid(t *testing.T) {
+	assert.True(t, Valid(Metric))
+	assert.True(t, Valid(Imperial))
+	assert.False(t, Valid(""))
+	assert.False(t, Valid("Imperial"))
+	assert.False(t, Valid("us"))
+}
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		system, weight, length string
+	}{
+		{Metric, Kilogram, Centimeter},
+		{Imperial, Pound, Inch},
+		// Unknown systems fall back to what is stored
+		{"", Kilogram, Centimeter},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.weight, WeightUnit(tt.system), tt.system)
+		assert.Equal(t, tt.length, LengthUnit(tt.system), tt.system)
+	}
+}
+
+func TestWeight(t *testing.T) {
+	tests := []struct {
+		name   string
+		kg     float64
+		system string
+		want   float64
+	}{
+		{"metric passes through", 80.37, Metric, 80.37},
+		{"unknown system passes through", 80.37, "", 80.37},
+		{"imperial one kilogram", 1, Imperial, 2.2},
+		{"imperial exact pound", kgPerLb * 150, Imperial, 150},
+		{"imperial rounds to 0.1", 80, Imperial, 176.4},
+		{"imperial rounds half up", kgPerLb * 100.05, Imperial, 100.1},
+		{"imperial negative change", -0.5, Imperial, -1.1},
+		{"imperial zero", 0, Imperial, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Weight(tt.kg, tt.system))
+		})
+	}
+}
+
+func TestWeightToKg(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  float64
+		system string
+		want   float64
+	}{
+		{"metric passes through", 72.345, Metric, 72.345},
+		{"imperial pounds", 150, Imperial, 68.04},
+		{"imperial rounds to 0.01", 176.4, Imperial, 80.01},
+		{"imperial zero", 0, Imperial, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WeightToKg(tt.value, tt.system))
+		})
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		name   string
+		cm     float64
+		system string
+		want   float64
+	}{
+		{"metric passes through", 178.3, Metric, 178.3},
+		{"imperial exact inches", 2.54 * 70, Imperial, 70},
+		{"imperial rounds down to 0.5", 178, Imperial, 70},
+		{"imperial rounds up to 0.5", 179, Imperial, 70.5},
+		{"imperial nearest half above", 2.54 * 30.3, Imperial, 30.5},
+		{"imperial nearest whole below", 2.54 * 30.2, Imperial, 30},
+		{"imperial zero", 0, Imperial, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Length(tt.cm, tt.system))
+		})
+	}
+}
+
+func TestLengthToCm(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  float64
+		system string
+		want   float64
+	}{
+		{"metric passes through", 92.25, Metric, 92.25},
+		{"imperial inches", 70, Imperial, 177.8},
+		{"imperial half inch", 32.5, Imperial, 82.6},
+		{"imperial zero", 0, Imperial, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LengthToCm(tt.value, tt.system))
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	// Entering a displayed imperial value again stores (nearly) the same metric value
+	for _, kg := range []float64{45.3, 60, 72.5, 99.99, 140.2} {
+		assert.InDelta(t, kg, WeightToKg(Weight(kg, Imperial), Imperial), 0.05, "kg=%v", kg)
+	}
+	for _, cm := range []float64{30, 65.5, 92.2, 180, 250} {
+		assert.InDelta(t, cm, LengthToCm(Length(cm, Imperial), Imperial), 0.64, "cm=%v", cm)
+	}
+}
+
+func TestPtrHelpers(t *testing.T) {
+	assert.Nil(t, WeightPtr(nil, Imperial))
+	assert.Nil(t, LengthPtr(nil, Imperial))
+
+	kg, cm := 80.0, 179.0
+	assert.Equal(t, 176.4, *WeightPtr(&kg, Imperial))
+	assert.Equal(t, 70.5, *LengthPtr(&cm, Imperial))
+	assert.Equal(t, 80.0, *WeightPtr(&kg, Metric))
+}
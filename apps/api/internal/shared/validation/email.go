@@ -0,0 +1,14 @@
+package validation
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeEmail returns the canonical form of an email address: trimmed,
+// Unicode NFC and lower case. Emails are normalized before they are stored
+// or looked up, so addresses differing only in case or composition match.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(norm.NFC.String(strings.TrimSpace(email)))
+}
@@ -0,0 +1,314 @@
+// Package validation turns request binding failures into per-field messages
+// for the VALIDATION_FAILED response, so the client can show which field is
+// wrong instead of a generic "Неверные данные запроса".
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// Message is the top-level message of every validation failure
+const Message = "Неверные данные запроса"
+
+// ErrEmptyBody is returned by BindJSON when the request has no body
+var ErrEmptyBody = errors.New("request body is empty")
+
+func init() {
+	// Report fields by their JSON (or query) names, not Go struct names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// Errors maps request fields to what is wrong with them. Services return it
+// for checks that binding tags cannot express; Respond sends it as is.
+type Errors map[string]string
+
+// Error implements error
+func (e Errors) Error() string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	parts := make([]string, len(fields))
+	for i, field := range fields {
+		parts[i] = field + ": " + e[field]
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// BindJSON decodes the request body into obj and validates its binding tags.
+// Unlike c.ShouldBindJSON it rejects fields obj does not declare, so typos
+// and stale clients fail loudly on write endpoints.
+func BindJSON(c *gin.Context, obj any) error {
+	if c.Request == nil || c.Request.Body == nil {
+		return ErrEmptyBody
+	}
+
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		if errors.Is(err, io.EOF) {
+			return ErrEmptyBody
+		}
+		return err
+	}
+	if errs := malformedFields(obj); len(errs) > 0 {
+		return errs
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// Respond sends a 400 VALIDATION_FAILED response for a binding or
+// validation error, with per-field details where they can be derived
+func Respond(c *gin.Context, err error) {
+	response.ValidationError(c, Message, Fields(err))
+}
+
+// Fields converts a binding error into field → message. Nested fields use
+// dotted paths (consents.marketing, logs[0].level). It returns nil for
+// errors that are not about a particular field, such as malformed JSON.
+func Fields(err error) map[string]string {
+	var fieldErrs Errors
+	if errors.As(err, &fieldErrs) {
+		return fieldErrs
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		fields := make(map[string]string, len(validationErrs))
+		for _, fe := range validationErrs {
+			path := fieldPath(fe)
+			if _, seen := fields[path]; !seen {
+				fields[path] = tagMessage(fe)
+			}
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return map[string]string{typeErr.Field: typeMessage(typeErr.Type)}
+	}
+
+	// encoding/json has no error type for unknown fields
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		if name, err := strconv.Unquote(name); err == nil {
+			return map[string]string{name: "Неизвестное поле"}
+		}
+	}
+
+	return nil
+}
+
+// fieldPath drops the struct name from the namespace: RegisterRequest.email → email
+func fieldPath(fe validator.FieldError) string {
+	_, path, ok := strings.Cut(fe.Namespace(), ".")
+	if !ok || path == "" {
+		return fe.Field()
+	}
+	return path
+}
+
+func tagMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "Обязательное поле"
+	case "email":
+		return "Некорректный email"
+	case "url", "http_url":
+		return "Некорректная ссылка"
+	case "uuid", "uuid4":
+		return "Некорректный идентификатор"
+	case "oneof":
+		return "Допустимые значения: " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "min", "gte", "max", "lte", "gt", "lt", "len":
+		return boundMessage(fe)
+	default:
+		return "Некорректное значение"
+	}
+}
+
+// boundMessage words a size or range limit by the kind of the field:
+// length for strings, element count for lists, value for numbers
+func boundMessage(fe validator.FieldError) string {
+	var subject string
+	switch fe.Kind() {
+	case reflect.String:
+		subject = "Длина должна"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		subject = "Количество элементов должно"
+	default:
+		subject = "Значение должно"
+	}
+	return fmt.Sprintf("%s быть %s %s", subject, boundRelations[fe.Tag()], fe.Param())
+}
+
+var boundRelations = map[string]string{
+	"min": "не меньше", "gte": "не меньше",
+	"max": "не больше", "lte": "не больше",
+	"gt": "больше", "lt": "меньше", "len": "равно",
+}
+
+// typeMessager is implemented by value types that parse themselves, such
+// as dates, to word what a value they reject should look like
+type typeMessager interface {
+	TypeMessage() string
+}
+
+func typeMessage(t reflect.Type) string {
+	if t == nil {
+		return "Неверный тип значения"
+	}
+	if m, ok := reflect.Zero(t).Interface().(typeMessager); ok {
+		return m.TypeMessage()
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "Ожидается строка"
+	case reflect.Bool:
+		return "Ожидается true или false"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Ожидается целое число"
+	case reflect.Float32, reflect.Float64:
+		return "Ожидается число"
+	case reflect.Slice, reflect.Array:
+		return "Ожидается массив"
+	case reflect.Struct, reflect.Map:
+		return "Ожидается объект"
+	default:
+		return "Неверный тип значения"
+	}
+}
+
+// BindQuery binds the query string to obj and validates its binding tags,
+// like c.ShouldBindQuery. A value its field's type rejects, such as a
+// malformed date, is reported as Errors on that field.
+func BindQuery(c *gin.Context, obj any) error {
+	if err := binding.MapFormWithTag(obj, c.Request.URL.Query(), "form"); err != nil {
+		return err
+	}
+	if errs := malformedFields(obj); len(errs) > 0 {
+		return errs
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// Param binds the path parameter name into dst, such as a *types.Date. A
+// malformed value sends 400 VALIDATION_FAILED and returns false.
+func Param(c *gin.Context, name string, dst binding.BindUnmarshaler) bool {
+	err := dst.UnmarshalParam(c.Param(name))
+	if m, ok := dst.(malformer); err != nil || ok && m.Malformed() {
+		response.ValidationError(c, Message, map[string]string{name: typeMessage(reflect.TypeOf(dst).Elem())})
+		return false
+	}
+	return true
+}
+
+// malformer is implemented by value types that bind a value they cannot
+// parse as malformed instead of failing, so the failure can be reported
+// on its field
+type malformer interface {
+	Malformed() bool
+}
+
+// malformedFields returns the fields of obj, by the same paths as Fields,
+// that hold malformed values
+func malformedFields(obj any) Errors {
+	errs := Errors{}
+	collectMalformed(reflect.ValueOf(obj), "", errs)
+	return errs
+}
+
+func collectMalformed(v reflect.Value, path string, errs Errors) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			collectMalformed(v.Elem(), path, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range v.Len() {
+			collectMalformed(v.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+	case reflect.Struct:
+		if m, ok := v.Interface().(malformer); ok {
+			if m.Malformed() {
+				errs[path] = typeMessage(v.Type())
+			}
+			return
+		}
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if f.Anonymous && f.Tag.Get("json") == "" && f.Tag.Get("form") == "" {
+				collectMalformed(v.Field(i), path, errs)
+				continue
+			}
+			name := fieldName(f)
+			if path != "" {
+				name = path + "." + name
+			}
+			collectMalformed(v.Field(i), name, errs)
+		}
+	}
+}
+
+// IDParam returns the path parameter name if it is a UUID. Otherwise it
+// sends 400 VALIDATION_FAILED and returns false, so a malformed id never
+// reaches the database.
+func IDParam(c *gin.Context, name string) (string, bool) {
+	id := c.Param(name)
+	if _, err := uuid.Parse(id); err != nil {
+		response.ValidationError(c, Message, map[string]string{name: "Неверный формат идентификатора"})
+		return "", false
+	}
+	return id, true
+}
+
+// IfMatchVersion returns the record version a client sends in the If-Match
+// header of an update, as "3" or 3. It returns nil when the header is
+// missing or "*", meaning any version. A malformed header sends 400
+// VALIDATION_FAILED and returns false.
+func IfMatchVersion(c *gin.Context) (*int, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil, true
+	}
+	version, err := strconv.Atoi(strings.Trim(header, `"`))
+	if err != nil || version < 1 {
+		response.ValidationError(c, Message, map[string]string{"If-Match": "Ожидается версия записи"})
+		return nil, false
+	}
+	return &version, true
+}
@@ -0,0 +1,259 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/burcev/api/internal/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConsents struct {
+	Marketing bool `json:"marketing"`
+}
+
+type testItem struct {
+	Meal string `json:"meal" binding:"required,oneof=breakfast lunch dinner snack"`
+}
+
+type testRequest struct {
+	Email    string        `json:"email" binding:"required,email"`
+	Password string        `json:"password" binding:"required,min=8,max=128"`
+	Age      int           `json:"age" binding:"omitempty,gte=14,lte=120"`
+	Tags     []string      `json:"tags" binding:"omitempty,max=2"`
+	Items    []testItem    `json:"items" binding:"omitempty,dive"`
+	Consents *testConsents `json:"consents"`
+}
+
+type testQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+// post binds body through BindJSON and returns the response status and body
+func post(t *testing.T, body string) (int, response.Response) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.POST("/test", func(c *gin.Context) {
+		var req testRequest
+		if err := BindJSON(c, &req); err != nil {
+			Respond(c, err)
+			return
+		}
+		response.Success(c, http.StatusOK, nil)
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	var resp response.Response
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestBindJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		details string // expected details JSON; empty when there are none
+	}{
+		{
+			name:    "missing required fields",
+			body:    `{}`,
+			details: `{"fields":{"email":"Обязательное поле","password":"Обязательное поле"}}`,
+		},
+		{
+			name:    "bad email and short password",
+			body:    `{"email":"not-an-email","password":"short"}`,
+			details: `{"fields":{"email":"Некорректный email","password":"Длина должна быть не меньше 8"}}`,
+		},
+		{
+			name:    "number out of range and too many elements",
+			body:    `{"email":"a@b.ru","password":"password1","age":7,"tags":["a","b","c"]}`,
+			details: `{"fields":{"age":"Значение должно быть не меньше 14","tags":"Количество элементов должно быть не больше 2"}}`,
+		},
+		{
+			name:    "nested list element",
+			body:    `{"email":"a@b.ru","password":"password1","items":[{"meal":"lunch"},{"meal":"brunch"}]}`,
+			details: `{"fields":{"items[1].meal":"Допустимые значения: breakfast, lunch, dinner, snack"}}`,
+		},
+		{
+			name:    "wrong type",
+			body:    `{"email":"a@b.ru","password":12345678}`,
+			details: `{"fields":{"password":"Ожидается строка"}}`,
+		},
+		{
+			name:    "wrong nested type",
+			body:    `{"email":"a@b.ru","password":"password1","consents":{"marketing":"yes"}}`,
+			details: `{"fields":{"consents.marketing":"Ожидается true или false"}}`,
+		},
+		{
+			name:    "unknown field",
+			body:    `{"email":"a@b.ru","password":"password1","role":"super_admin"}`,
+			details: `{"fields":{"role":"Неизвестное поле"}}`,
+		},
+		{
+			name: "malformed JSON",
+			body: `{"email":`,
+		},
+		{
+			name: "empty body",
+			body: ``,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := post(t, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, status)
+			assert.Equal(t, response.CodeValidationFailed, resp.Code)
+			assert.Equal(t, Message, resp.Message)
+			if tt.details == "" {
+				assert.Nil(t, resp.Details)
+				return
+			}
+			details, err := json.Marshal(resp.Details)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.details, string(details))
+		})
+	}
+
+	t.Run("valid body", func(t *testing.T) {
+		status, _ := post(t, `{"email":"a@b.ru","password":"password1","consents":{"marketing":true}}`)
+		assert.Equal(t, http.StatusOK, status)
+	})
+}
+
+func TestFieldsUsesFormNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/test", nil)
+
+	var q testQuery
+	err := c.ShouldBindQuery(&q)
+
+	require.Error(t, err)
+	assert.Equal(t, map[string]string{"token": "Обязательное поле"}, Fields(err))
+}
+
+func TestErrors(t *testing.T) {
+	err := error(Errors{"meal": "Неизвестный приём пищи", "date": "Неверный формат даты"})
+
+	assert.Equal(t, "validation failed: date: Неверный формат даты; meal: Неизвестный приём пищи", err.Error())
+	assert.Equal(t, map[string]string{"meal": "Неизвестный приём пищи", "date": "Неверный формат даты"},
+		Fields(errors.Join(errors.New("create entry"), err)))
+	assert.Nil(t, Fields(errors.New("db is down")))
+}
+
+func TestIDParam(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.GET("/things/:id", func(c *gin.Context) {
+		id, ok := IDParam(c, "id")
+		if !ok {
+			return
+		}
+		response.Success(c, http.StatusOK, id)
+	})
+
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8e", http.StatusOK},
+		{"not-a-uuid", http.StatusBadRequest},
+		{"1", http.StatusBadRequest},
+		{"0199f0b2-7c1e-7a3b-9d2e-3f4a5b6c7d8", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/things/"+tt.id, nil))
+
+			assert.Equal(t, tt.code, w.Code)
+			if tt.code == http.StatusBadRequest {
+				var resp response.Response
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(t, response.CodeValidationFailed, resp.Code)
+				assert.Equal(t, map[string]any{"id": "Неверный формат идентификатора"}, resp.Details.(map[string]any)["fields"])
+			}
+		})
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.PUT("/things", func(c *gin.Context) {
+		version, ok := IfMatchVersion(c)
+		if !ok {
+			return
+		}
+		response.Success(c, http.StatusOK, version)
+	})
+
+	tests := []struct {
+		header string
+		code   int
+		want   any
+	}{
+		{"", http.StatusOK, nil},
+		{"*", http.StatusOK, nil},
+		{`"3"`, http.StatusOK, 3.0},
+		{"12", http.StatusOK, 12.0},
+		{`W/"3"`, http.StatusBadRequest, nil},
+		{"0", http.StatusBadRequest, nil},
+		{"abc", http.StatusBadRequest, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/things", nil)
+			if tt.header != "" {
+				req.Header.Set("If-Match", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+			var resp response.Response
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			if tt.code == http.StatusOK {
+				assert.Equal(t, tt.want, resp.Data)
+			} else {
+				assert.Equal(t, map[string]any{"If-Match": "Ожидается версия записи"}, resp.Details.(map[string]any)["fields"])
+			}
+		})
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "already normalized", input: "user@example.com", want: "user@example.com"},
+		{name: "mixed case", input: "User@Example.COM", want: "user@example.com"},
+		{name: "surrounding whitespace", input: "  user@example.com\n", want: "user@example.com"},
+		{name: "decomposed unicode", input: "Jose\u0301@example.com", want: "jos\u00e9@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NormalizeEmail(tt.input))
+		})
+	}
+}
@@ -8,6 +8,7 @@ const (
 	EventTyping              = "typing"
 	EventUnreadCountUpdate   = "unread_count_update"
 	EventContentNotification = "content_notification"
+	EventCuratorBroadcast    = "curator_broadcast"
 )
 
 // OutgoingEvent is sent from server to client
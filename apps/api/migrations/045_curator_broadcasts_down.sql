@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS curator_broadcast_deliveries;
+DROP TABLE IF EXISTS curator_broadcasts;
+DROP TABLE IF EXISTS notification_channel_mute;
+
+-- Note: this will fail if curator_broadcast notifications exist
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN (
+        'trainer_feedback', 'achievement', 'reminder', 'system_update',
+        'new_feature', 'general', 'new_content',
+        'plan_updated', 'task_assigned', 'task_overdue', 'feedback_received'
+    ));
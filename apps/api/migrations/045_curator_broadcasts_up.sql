@@ -0,0 +1,55 @@
+-- Migration: Curator broadcasts and per-channel notification mutes
+-- Version: 045
+-- Date: 2026-10-16
+
+-- Allow curator broadcast notifications
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN (
+        'trainer_feedback', 'achievement', 'reminder', 'system_update',
+        'new_feature', 'general', 'new_content',
+        'plan_updated', 'task_assigned', 'task_overdue', 'feedback_received',
+        'curator_broadcast'
+    ));
+
+-- Delivery channel mutes (opt-out model: record = channel disabled)
+CREATE TABLE IF NOT EXISTS notification_channel_mute (
+    user_id    BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    channel    VARCHAR(20) NOT NULL CHECK (channel IN ('in_app', 'email', 'push')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (user_id, channel)
+);
+
+CREATE TABLE IF NOT EXISTS curator_broadcasts (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    curator_id   BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message      TEXT NOT NULL,
+    channels     VARCHAR(100) NOT NULL, -- comma-separated channel list
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_curator_broadcasts_curator ON curator_broadcasts(curator_id, created_at DESC);
+
+-- One row per recipient and channel; recipients are snapshotted when the broadcast is created
+CREATE TABLE IF NOT EXISTS curator_broadcast_deliveries (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    broadcast_id UUID NOT NULL REFERENCES curator_broadcasts(id) ON DELETE CASCADE,
+    client_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel      VARCHAR(20) NOT NULL CHECK (channel IN ('in_app', 'email', 'push')),
+    status       VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'sent', 'skipped', 'failed')),
+    error        TEXT,
+    attempted_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (broadcast_id, client_id, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_curator_broadcast_deliveries_broadcast ON curator_broadcast_deliveries(broadcast_id);
+CREATE INDEX IF NOT EXISTS idx_curator_broadcast_deliveries_pending ON curator_broadcast_deliveries(created_at) WHERE status IN ('pending', 'processing');
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE notification_channel_mute TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE curator_broadcasts TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE curator_broadcast_deliveries TO PUBLIC';
+END $$;
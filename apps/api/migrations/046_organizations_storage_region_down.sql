@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS storage_region_migrations;
+
+ALTER TABLE weekly_photos DROP COLUMN IF EXISTS storage_key;
+ALTER TABLE weekly_photos DROP COLUMN IF EXISTS storage_region;
+
+DROP INDEX IF EXISTS idx_users_organization;
+ALTER TABLE users DROP COLUMN IF EXISTS organization_id;
+
+DROP TABLE IF EXISTS organizations;
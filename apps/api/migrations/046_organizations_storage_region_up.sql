@@ -0,0 +1,47 @@
+-- Migration: Organizations with per-organization storage region (data residency)
+-- Version: 046
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS organizations (
+    id             BIGSERIAL PRIMARY KEY,
+    name           VARCHAR(255) NOT NULL,
+    storage_region VARCHAR(50) NOT NULL DEFAULT 'default',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS organization_id BIGINT REFERENCES organizations(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_users_organization ON users(organization_id) WHERE organization_id IS NOT NULL;
+
+-- Track where each photo object lives so reads keep working while an
+-- organization is being moved between regions.
+ALTER TABLE weekly_photos ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50) NOT NULL DEFAULT 'default';
+ALTER TABLE weekly_photos ADD COLUMN IF NOT EXISTS storage_key TEXT NOT NULL DEFAULT '';
+
+-- Backfill object keys from the stored URLs (…/weekly-photos/{user}/{week}/{file})
+UPDATE weekly_photos
+SET storage_key = substring(photo_url FROM 'weekly-photos/.*$')
+WHERE storage_key = '' AND photo_url LIKE '%weekly-photos/%';
+
+CREATE TABLE IF NOT EXISTS storage_region_migrations (
+    id              BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    from_region     VARCHAR(50) NOT NULL,
+    to_region       VARCHAR(50) NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed')),
+    moved           INTEGER NOT NULL DEFAULT 0,
+    failed          INTEGER NOT NULL DEFAULT 0,
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at      TIMESTAMPTZ,
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_storage_region_migrations_pending ON storage_region_migrations(created_at) WHERE status = 'pending';
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE organizations TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE storage_region_migrations TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE organizations_id_seq TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE storage_region_migrations_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1,20 @@
+-- Migration: Progress photos (front/side/back) stored via the Storage interface
+-- Version: 047
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS progress_photos (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    projection   VARCHAR(10) NOT NULL CHECK (projection IN ('front', 'side', 'back')),
+    taken_on     DATE NOT NULL,
+    storage_key  TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes   INTEGER NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_progress_photos_user_date ON progress_photos(user_id, taken_on DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE progress_photos TO PUBLIC';
+END $$;
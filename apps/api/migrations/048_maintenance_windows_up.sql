@@ -0,0 +1,20 @@
+-- Migration: Scheduled maintenance windows
+-- Version: 048
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS maintenance_windows (
+    id           BIGSERIAL PRIMARY KEY,
+    starts_at    TIMESTAMPTZ NOT NULL,
+    ends_at      TIMESTAMPTZ NOT NULL,
+    created_by   BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    cancelled_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_maintenance_windows_upcoming ON maintenance_windows(starts_at) WHERE cancelled_at IS NULL;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE maintenance_windows TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE maintenance_windows_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS body_fat_estimates;
@@ -0,0 +1,26 @@
+-- Migration: Body fat estimates from weekly progress photo sets
+-- Version: 049
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS body_fat_estimates (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    week_identifier VARCHAR(10) NOT NULL, -- ISO week, e.g. 2026-W42
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    body_fat_pct    NUMERIC(4, 1),
+    confidence      NUMERIC(3, 2),
+    raw_response    JSONB,
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ,
+    UNIQUE (user_id, week_identifier)
+);
+
+CREATE INDEX IF NOT EXISTS idx_body_fat_estimates_queue ON body_fat_estimates(next_attempt_at) WHERE status IN ('pending', 'processing');
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE body_fat_estimates TO PUBLIC';
+END $$;
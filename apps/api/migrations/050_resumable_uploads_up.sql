@@ -0,0 +1,35 @@
+-- Migration: Resumable (tus-style) uploads
+-- Version: 050
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS uploads (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content_type VARCHAR(100) NOT NULL,
+    size_bytes   BIGINT NOT NULL CHECK (size_bytes > 0),
+    offset_bytes BIGINT NOT NULL DEFAULT 0,
+    status       VARCHAR(20) NOT NULL DEFAULT 'uploading' CHECK (status IN ('uploading', 'completed', 'failed', 'consumed')),
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL DEFAULT NOW() + INTERVAL '24 hours',
+    completed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_user_id ON uploads(user_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads(expires_at);
+
+-- Received chunks, kept so retried (duplicate) chunks can be acknowledged
+CREATE TABLE IF NOT EXISTS upload_chunks (
+    upload_id    UUID NOT NULL REFERENCES uploads(id) ON DELETE CASCADE,
+    offset_bytes BIGINT NOT NULL,
+    size_bytes   BIGINT NOT NULL,
+    sha256       BYTEA NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (upload_id, offset_bytes)
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE uploads TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE upload_chunks TO PUBLIC';
+END $$;
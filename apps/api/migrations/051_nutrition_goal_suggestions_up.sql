@@ -0,0 +1,46 @@
+-- Migration: Nutrition goal suggestions after significant weight change
+-- Version: 051
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS nutrition_goal_suggestions (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id          BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    plan_id          UUID NOT NULL REFERENCES weekly_plans(id) ON DELETE CASCADE,
+    curator_id       BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    status           VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'dismissed')),
+
+    previous_weight  DECIMAL(5,1) NOT NULL,
+    current_weight   DECIMAL(5,1) NOT NULL,
+
+    current_calories INTEGER NOT NULL,
+    current_protein  INTEGER NOT NULL,
+    current_fat      INTEGER,
+    current_carbs    INTEGER,
+
+    suggested_calories INTEGER NOT NULL,
+    suggested_protein  INTEGER NOT NULL,
+    suggested_fat      INTEGER NOT NULL,
+    suggested_carbs    INTEGER NOT NULL,
+
+    reason           TEXT NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at      TIMESTAMPTZ,
+    resolved_by      BIGINT REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- At most one pending suggestion per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_goal_suggestions_pending ON nutrition_goal_suggestions(user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_goal_suggestions_plan ON nutrition_goal_suggestions(plan_id, resolved_at DESC);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN (
+        'trainer_feedback', 'achievement', 'reminder', 'system_update',
+        'new_feature', 'general', 'new_content',
+        'plan_updated', 'task_assigned', 'task_overdue', 'feedback_received',
+        'curator_broadcast', 'goal_suggestion'
+    ));
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_goal_suggestions TO PUBLIC';
+END $$;
@@ -0,0 +1,19 @@
+-- Migration: Curator invite codes for self-service client linking
+-- Version: 052
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS curator_invites (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code         VARCHAR(16) NOT NULL UNIQUE,
+    curator_id   BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    redeemed_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    redeemed_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_curator_invites_curator ON curator_invites(curator_id, created_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE curator_invites TO PUBLIC';
+END $$;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_unsubscribe_tokens;
+DROP TABLE IF EXISTS email_preferences;
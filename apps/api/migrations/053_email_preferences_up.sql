@@ -0,0 +1,28 @@
+-- Migration: Per-user email preferences and unsubscribe tokens
+-- Version: 053
+-- Date: 2026-10-16
+
+-- A missing row means every optional email is enabled
+CREATE TABLE IF NOT EXISTS email_preferences (
+    user_id                 BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    password_changed_email  BOOLEAN NOT NULL DEFAULT TRUE,
+    weekly_summary_email    BOOLEAN NOT NULL DEFAULT TRUE,
+    coach_activity_email    BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One token per sent email; only the SHA-256 hash is stored
+CREATE TABLE IF NOT EXISTS email_unsubscribe_tokens (
+    token_hash  VARCHAR(64) PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind        VARCHAR(32) NOT NULL CHECK (kind IN ('password_changed_email', 'weekly_summary_email', 'coach_activity_email')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    used_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_unsubscribe_tokens_user ON email_unsubscribe_tokens(user_id);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE email_preferences TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE email_unsubscribe_tokens TO PUBLIC';
+END $$;
@@ -0,0 +1,18 @@
+-- Migration: Uptime samples for the public status page
+-- Version: 054
+-- Date: 2026-10-16
+
+-- One row per component per self-probe run (every minute); kept for 90 days
+CREATE TABLE IF NOT EXISTS uptime_samples (
+    id          BIGSERIAL PRIMARY KEY,
+    sampled_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    component   VARCHAR(16) NOT NULL,
+    status      VARCHAR(16) NOT NULL CHECK (status IN ('operational', 'degraded', 'down'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_uptime_samples_sampled_at ON uptime_samples(sampled_at);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE uptime_samples TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE uptime_samples_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS held_notifications;
+DROP TABLE IF EXISTS notification_quiet_hours;
+
+-- Note: this will fail if security_alert or quiet_hours_digest notifications exist
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN (
+        'trainer_feedback', 'achievement', 'reminder', 'system_update',
+        'new_feature', 'general', 'new_content',
+        'plan_updated', 'task_assigned', 'task_overdue', 'feedback_received',
+        'curator_broadcast', 'goal_suggestion'
+    ));
@@ -0,0 +1,47 @@
+-- Migration: Notification quiet hours and held notifications
+-- Version: 055
+-- Date: 2026-10-16
+
+-- Daily quiet window in the user's timezone; minutes since local midnight.
+-- A window with start > end crosses midnight (e.g. 22:00-07:00).
+CREATE TABLE IF NOT EXISTS notification_quiet_hours (
+    user_id       BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    start_minute  SMALLINT NOT NULL CHECK (start_minute BETWEEN 0 AND 1439),
+    end_minute    SMALLINT NOT NULL CHECK (end_minute BETWEEN 0 AND 1439),
+    timezone      VARCHAR(64) NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (start_minute <> end_minute)
+);
+
+-- Notifications created during quiet hours, delivered as a digest at release_at
+CREATE TABLE IF NOT EXISTS held_notifications (
+    id                UUID PRIMARY KEY,
+    user_id           BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category          VARCHAR(20) NOT NULL,
+    type              VARCHAR(50) NOT NULL,
+    title             VARCHAR(255) NOT NULL,
+    content           TEXT NOT NULL,
+    icon_url          VARCHAR(500),
+    action_url        VARCHAR(500),
+    content_category  content_category,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    release_at        TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_held_notifications_release ON held_notifications(release_at);
+CREATE INDEX IF NOT EXISTS idx_held_notifications_user ON held_notifications(user_id);
+
+ALTER TABLE notifications DROP CONSTRAINT IF EXISTS notifications_type_check;
+ALTER TABLE notifications ADD CONSTRAINT notifications_type_check
+    CHECK (type IN (
+        'trainer_feedback', 'achievement', 'reminder', 'system_update',
+        'new_feature', 'general', 'new_content',
+        'plan_updated', 'task_assigned', 'task_overdue', 'feedback_received',
+        'curator_broadcast', 'goal_suggestion',
+        'security_alert', 'quiet_hours_digest'
+    ));
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE notification_quiet_hours TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE held_notifications TO PUBLIC';
+END $$;
@@ -0,0 +1,18 @@
+-- Migration: Weekly summary email send log
+-- Version: 056
+-- Date: 2026-10-16
+
+-- One row per user and summarised week; the primary key makes re-running the
+-- weekly job idempotent. status: sending (claimed), sent, skipped (no data).
+CREATE TABLE IF NOT EXISTS weekly_summary_sends (
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    week_start  DATE NOT NULL,
+    status      VARCHAR(16) NOT NULL DEFAULT 'sending' CHECK (status IN ('sending', 'sent', 'skipped')),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at     TIMESTAMPTZ,
+    PRIMARY KEY (user_id, week_start)
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE weekly_summary_sends TO PUBLIC';
+END $$;
@@ -0,0 +1,24 @@
+-- Migration: Audit log for sensitive operations
+-- Version: 057
+-- Date: 2026-10-16
+
+-- Durable record of security-relevant actions; rows are never updated.
+-- user_id is the account the action concerns (NULL when unknown, e.g. a
+-- login lockout by IP). metadata is sanitized before it is written.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    actor_ip    VARCHAR(45),
+    action      VARCHAR(64) NOT NULL,
+    metadata    JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_user_created ON audit_log(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action_created ON audit_log(action, created_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE audit_log TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE audit_log_id_seq TO PUBLIC';
+END $$;
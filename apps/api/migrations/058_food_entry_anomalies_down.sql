@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_food_entries_needs_confirmation;
+ALTER TABLE food_entries DROP COLUMN IF EXISTS needs_confirmation;
@@ -0,0 +1,12 @@
+-- Migration: Flag anomalous food entries for confirmation
+-- Version: 058
+-- Date: 2026-10-16
+
+-- Set when an entry looked implausible on create/update (e.g. 15000 kcal for
+-- a banana); cleared when the user confirms it or edits it into range.
+-- Daily totals can leave such entries out until they are confirmed.
+ALTER TABLE food_entries
+    ADD COLUMN IF NOT EXISTS needs_confirmation BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_food_entries_needs_confirmation
+    ON food_entries(user_id, date) WHERE needs_confirmation;
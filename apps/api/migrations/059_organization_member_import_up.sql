@@ -0,0 +1,25 @@
+-- Migration: Bulk member import for organizations
+-- Version: 059
+-- Date: 2026-10-16
+
+-- Existing users found in an import are not moved into the organization
+-- directly: they get an emailed link and are linked once they accept it.
+CREATE TABLE IF NOT EXISTS organization_membership_requests (
+    id              BIGSERIAL PRIMARY KEY,
+    organization_id BIGINT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    curator_id      BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    token_hash      VARCHAR(64) NOT NULL UNIQUE,
+    expires_at      TIMESTAMPTZ NOT NULL,
+    accepted_at     TIMESTAMPTZ,
+    accepted_ip     VARCHAR(45),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (organization_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_membership_requests_user ON organization_membership_requests(user_id);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE organization_membership_requests TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE organization_membership_requests_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS history_imports;
+DROP TABLE IF EXISTS body_measurements;
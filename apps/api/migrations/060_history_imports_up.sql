@@ -0,0 +1,49 @@
+-- Migration: Historical weight and body measurement imports
+-- Version: 060
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS body_measurements (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date       DATE NOT NULL,
+    waist_cm   DECIMAL(5,1),
+    chest_cm   DECIMAL(5,1),
+    hips_cm    DECIMAL(5,1),
+    thigh_cm   DECIMAL(5,1),
+    arm_cm     DECIMAL(5,1),
+    neck_cm    DECIMAL(5,1),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, date)
+);
+
+-- Validated rows wait in rows until the import worker writes them;
+-- processed_rows is the resume point if a worker dies mid-import.
+CREATE TABLE IF NOT EXISTS history_imports (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind            VARCHAR(20) NOT NULL CHECK (kind IN ('weight', 'measurements')),
+    strategy        VARCHAR(10) NOT NULL CHECK (strategy IN ('skip', 'replace')),
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'done', 'failed')),
+    rows            JSONB NOT NULL,
+    total_rows      INTEGER NOT NULL,
+    processed_rows  INTEGER NOT NULL DEFAULT 0,
+    imported_rows   INTEGER NOT NULL DEFAULT 0,
+    skipped_rows    INTEGER NOT NULL DEFAULT 0,
+    failed_rows     INTEGER NOT NULL DEFAULT 0,
+    row_errors      JSONB NOT NULL DEFAULT '[]',
+    error           TEXT,
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_history_imports_queue ON history_imports(next_attempt_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_history_imports_user ON history_imports(user_id, created_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE body_measurements TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE history_imports TO PUBLIC';
+END $$;
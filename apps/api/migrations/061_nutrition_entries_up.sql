@@ -0,0 +1,23 @@
+-- Migration: Nutrition entries
+-- Version: 061
+-- Date: 2026-10-16
+
+CREATE TABLE IF NOT EXISTS nutrition_entries (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date       DATE NOT NULL,
+    meal       VARCHAR(20) NOT NULL CHECK (meal IN ('breakfast', 'lunch', 'dinner', 'snack')),
+    food       TEXT NOT NULL,
+    calories   DECIMAL(7,1) NOT NULL CHECK (calories >= 0),
+    protein    DECIMAL(6,1) NOT NULL DEFAULT 0 CHECK (protein >= 0),
+    carbs      DECIMAL(6,1) NOT NULL DEFAULT 0 CHECK (carbs >= 0),
+    fat        DECIMAL(6,1) NOT NULL DEFAULT 0 CHECK (fat >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_nutrition_entries_user_date ON nutrition_entries(user_id, date DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_entries TO PUBLIC';
+END $$;
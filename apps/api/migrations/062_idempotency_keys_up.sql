@@ -0,0 +1,21 @@
+-- Migration: Idempotency keys for safely retried create requests
+-- Version: 062
+-- Date: 2026-10-16
+
+-- resource_id is set in the same transaction that claims the key, so a
+-- committed row always points at the resource the first request created.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    scope       VARCHAR(50) NOT NULL,
+    key         VARCHAR(255) NOT NULL,
+    resource_id TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, scope, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE idempotency_keys TO PUBLIC';
+END $$;
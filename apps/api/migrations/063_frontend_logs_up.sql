@@ -0,0 +1,28 @@
+-- Migration: Persist logs received from the frontend
+-- Version: 063
+-- Date: 2026-10-16
+
+-- user_id is the id the client reports and is not verified, so it is kept
+-- as text without a foreign key. Rows are removed after 30 days.
+CREATE TABLE IF NOT EXISTS frontend_logs (
+    id               BIGSERIAL PRIMARY KEY,
+    level            VARCHAR(10) NOT NULL,
+    message          TEXT NOT NULL,
+    user_id          TEXT,
+    session_id       TEXT,
+    url              TEXT,
+    user_agent       TEXT,
+    error_name       TEXT,
+    error_message    TEXT,
+    stack            TEXT,
+    client_timestamp TIMESTAMPTZ,
+    received_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_frontend_logs_received ON frontend_logs(received_at);
+CREATE INDEX IF NOT EXISTS idx_frontend_logs_level_received ON frontend_logs(level, received_at);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE frontend_logs TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT, UPDATE ON SEQUENCE frontend_logs_id_seq TO PUBLIC';
+END $$;
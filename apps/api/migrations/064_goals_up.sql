@@ -0,0 +1,26 @@
+-- Migration: User goals with progress tracking
+-- Version: 064
+-- Date: 2026-10-16
+
+-- start_value is the user's value when the goal was set; NULL when there was
+-- no data yet. One active goal per type is enforced by the service.
+CREATE TABLE IF NOT EXISTS goals (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type         VARCHAR(30) NOT NULL CHECK (type IN ('weight', 'body_fat', 'calories_adherence')),
+    status       VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'completed', 'abandoned')),
+    start_value  DECIMAL(5,1),
+    target_value DECIMAL(5,1) NOT NULL,
+    start_date   DATE NOT NULL DEFAULT CURRENT_DATE,
+    target_date  DATE NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMPTZ,
+    abandoned_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_status ON goals(user_id, status);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE goals TO PUBLIC';
+END $$;
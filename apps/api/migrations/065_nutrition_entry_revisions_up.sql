@@ -0,0 +1,25 @@
+-- Migration: Nutrition entry revisions
+-- Version: 065
+-- Date: 2026-10-16
+
+-- One row per update or delete of a nutrition entry, written in the same
+-- transaction as the change. old_values holds the changed fields before an
+-- update, or the whole entry before a delete. There is no foreign key to
+-- nutrition_entries so the history outlives a deleted entry; user_id is the
+-- entry owner and is used for access checks.
+CREATE TABLE IF NOT EXISTS nutrition_entry_revisions (
+    id          BIGSERIAL PRIMARY KEY,
+    entry_id    UUID NOT NULL,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    changed_by  BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    change_type VARCHAR(10) NOT NULL CHECK (change_type IN ('update', 'delete')),
+    old_values  JSONB NOT NULL,
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_nutrition_entry_revisions_entry ON nutrition_entry_revisions(entry_id, changed_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_entry_revisions TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT, UPDATE ON SEQUENCE nutrition_entry_revisions_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1,19 @@
+-- Migration: Water intake events
+-- Version: 066
+-- Date: 2026-10-16
+
+-- Individual water intakes logged through /api/v1/nutrition/water; the daily
+-- total is their sum. Separate from water_logs, which counts glasses.
+CREATE TABLE IF NOT EXISTS water_intake_events (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date       DATE NOT NULL,
+    amount_ml  INTEGER NOT NULL CHECK (amount_ml BETWEEN 10 AND 3000),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_water_intake_events_user_date ON water_intake_events(user_id, date);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE water_intake_events TO PUBLIC';
+END $$;
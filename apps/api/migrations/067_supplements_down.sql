@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS supplement_intakes;
+DROP TABLE IF EXISTS supplements;
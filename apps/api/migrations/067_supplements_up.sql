@@ -0,0 +1,31 @@
+-- Migration: Supplements and daily intake checklist
+-- Version: 067
+-- Date: 2026-10-16
+
+-- A supplement or medication the user takes on a schedule: every day, or on
+-- the listed weekdays (0 = Sunday .. 6 = Saturday, as in tasks.recurrence_days).
+CREATE TABLE IF NOT EXISTS supplements (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name       TEXT NOT NULL,
+    dose       TEXT NOT NULL,
+    schedule   TEXT NOT NULL CHECK (schedule IN ('daily', 'weekdays')),
+    weekdays   INTEGER[],
+    start_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_supplements_user ON supplements(user_id);
+
+-- One row per taken dose; the primary key makes marking a day taken idempotent
+CREATE TABLE IF NOT EXISTS supplement_intakes (
+    supplement_id UUID NOT NULL REFERENCES supplements(id) ON DELETE CASCADE,
+    date          DATE NOT NULL,
+    taken_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (supplement_id, date)
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE supplements TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE supplement_intakes TO PUBLIC';
+END $$;
@@ -0,0 +1,24 @@
+-- Migration: API keys for read-only integrations
+-- Version: 068
+-- Date: 2026-10-16
+
+-- Keys are shown once on creation and stored as SHA-256 hashes; prefix is
+-- the first characters of the key, for telling keys apart in the list.
+-- Revoked keys are kept, with revoked_at set.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name         TEXT NOT NULL DEFAULT '',
+    prefix       TEXT NOT NULL,
+    key_hash     TEXT NOT NULL UNIQUE,
+    scopes       TEXT[] NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id) WHERE revoked_at IS NULL;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE api_keys TO PUBLIC';
+END $$;
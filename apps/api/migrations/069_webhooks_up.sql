@@ -0,0 +1,47 @@
+-- Migration: Webhook subscriptions and deliveries
+-- Version: 069
+-- Date: 2026-10-16
+
+-- A URL that receives the user's events as signed JSON POSTs. The secret is
+-- kept in plain text because every delivery is signed with it. A webhook is
+-- disabled after too many consecutive failed attempts.
+CREATE TABLE IF NOT EXISTS webhooks (
+    id                   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id              BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    url                  TEXT NOT NULL,
+    secret               TEXT NOT NULL,
+    events               TEXT[] NOT NULL,
+    active               BOOLEAN NOT NULL DEFAULT true,
+    consecutive_failures INTEGER NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id) WHERE active;
+
+-- One row per delivery attempt; a retry is a new row with the same event_id
+-- and the next attempt number
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    webhook_id      UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    event_id        UUID NOT NULL,
+    event_type      TEXT NOT NULL,
+    payload         JSONB NOT NULL,
+    attempt         INTEGER NOT NULL DEFAULT 1,
+    status          TEXT NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'processing', 'delivered', 'failed')),
+    status_code     INTEGER,
+    error           TEXT,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempted_at    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at)
+    WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE webhooks TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE webhook_deliveries TO PUBLIC';
+END $$;
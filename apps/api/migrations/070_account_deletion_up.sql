@@ -0,0 +1,11 @@
+-- Migration: Account deletion with a grace period
+-- Version: 070
+-- Date: 2026-10-16
+
+-- A deleted account is first deactivated: login is refused and the owner
+-- may reactivate it until purge_after. After that a background job removes
+-- the user's data and finally the users row itself.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purge_after TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_users_purge_after ON users(purge_after) WHERE purge_after IS NOT NULL;
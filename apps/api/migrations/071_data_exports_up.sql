@@ -0,0 +1,30 @@
+-- Migration: Data exports
+-- Version: 071
+-- Date: 2026-10-16
+
+-- A user's request for an archive of all their data. The export worker
+-- builds the archive and stores it under storage_key; the emailed download
+-- link carries token and stays valid until expires_at. The partial unique
+-- index allows a single export in flight per user.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id         BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token           VARCHAR(64) NOT NULL UNIQUE,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'ready', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    storage_key     TEXT,
+    size_bytes      BIGINT,
+    error           TEXT,
+    expires_at      TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    completed_at    TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_in_flight ON data_exports(user_id) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_exports_next_attempt ON data_exports(next_attempt_at) WHERE status IN ('pending', 'processing');
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires_at ON data_exports(expires_at) WHERE storage_key IS NOT NULL;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE data_exports TO PUBLIC';
+END $$;
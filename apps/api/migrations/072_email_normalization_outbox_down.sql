@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS email_outbox;
+DROP INDEX IF EXISTS idx_users_email_lower;
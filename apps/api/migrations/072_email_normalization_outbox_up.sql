@@ -0,0 +1,29 @@
+-- Migration: Case-insensitive email lookups and the email outbox
+-- Version: 072
+-- Date: 2026-10-16
+
+-- Emails are normalized (trimmed, NFC, lower case) before they are stored or
+-- looked up. Accounts created earlier may still hold mixed-case addresses,
+-- so lookups compare LOWER(email) and rely on this index.
+CREATE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
+
+-- Emails whose preparation must not delay the request that triggers them.
+-- A worker claims rows by kind; delivered rows are deleted, rows that ran
+-- out of attempts stay as failed for inspection.
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id              BIGSERIAL PRIMARY KEY,
+    kind            VARCHAR(50) NOT NULL,
+    payload         JSONB NOT NULL,
+    status          VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    error           TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_queue ON email_outbox(kind, next_attempt_at) WHERE status IN ('pending', 'processing');
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE email_outbox TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE email_outbox_id_seq TO PUBLIC';
+END $$;
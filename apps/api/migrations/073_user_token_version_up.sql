@@ -0,0 +1,8 @@
+-- Migration: Access token versions
+-- Version: 073
+-- Date: 2026-10-16
+
+-- Access tokens carry the version current when they were issued. Bumping it
+-- (e.g. on a role change) makes routes checking the version reject tokens
+-- that still carry the old role.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INTEGER NOT NULL DEFAULT 0;
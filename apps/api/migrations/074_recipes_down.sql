@@ -0,0 +1,4 @@
+ALTER TABLE nutrition_entries DROP COLUMN IF EXISTS portion_grams;
+ALTER TABLE nutrition_entries DROP COLUMN IF EXISTS recipe_id;
+DROP TABLE IF EXISTS recipe_ingredients;
+DROP TABLE IF EXISTS recipes;
@@ -0,0 +1,40 @@
+-- Migration: Recipes made of food items
+-- Version: 074
+-- Date: 2026-10-16
+
+-- A dish cooked from food_items. The per-100 g macros are computed from the
+-- ingredients when the recipe is saved: their total divided by the cooked
+-- weight, so water lost or absorbed in cooking is accounted for.
+CREATE TABLE IF NOT EXISTS recipes (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id          BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name             VARCHAR(255) NOT NULL,
+    cooked_weight    NUMERIC(10,2) NOT NULL CHECK (cooked_weight > 0),
+    calories_per_100 NUMERIC(10,2) NOT NULL DEFAULT 0,
+    protein_per_100  NUMERIC(10,2) NOT NULL DEFAULT 0,
+    fat_per_100      NUMERIC(10,2) NOT NULL DEFAULT 0,
+    carbs_per_100    NUMERIC(10,2) NOT NULL DEFAULT 0,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recipes_user_name ON recipes(user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS recipe_ingredients (
+    recipe_id UUID NOT NULL REFERENCES recipes(id) ON DELETE CASCADE,
+    position  SMALLINT NOT NULL,
+    food_id   UUID NOT NULL REFERENCES food_items(id),
+    grams     NUMERIC(10,2) NOT NULL CHECK (grams > 0),
+    PRIMARY KEY (recipe_id, position)
+);
+
+-- Entries logged from a recipe keep the macros computed at log time; the
+-- reference only records where they came from
+ALTER TABLE nutrition_entries
+    ADD COLUMN IF NOT EXISTS recipe_id UUID REFERENCES recipes(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS portion_grams NUMERIC(10,2);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE recipes TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE recipe_ingredients TO PUBLIC';
+END $$;
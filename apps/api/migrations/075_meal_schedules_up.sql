@@ -0,0 +1,17 @@
+-- Migration: Preferred meal times for logging reminders
+-- Version: 075
+-- Date: 2026-10-16
+
+-- When a user usually eats each meal, as wall-clock time in their timezone
+-- (user_settings.timezone). A meal without a row has no reminder.
+CREATE TABLE IF NOT EXISTS meal_schedules (
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    meal        VARCHAR(20) NOT NULL CHECK (meal IN ('breakfast', 'lunch', 'dinner', 'snack')),
+    time_of_day TIME NOT NULL,
+    PRIMARY KEY (user_id, meal),
+    UNIQUE (user_id, time_of_day)
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE meal_schedules TO PUBLIC';
+END $$;
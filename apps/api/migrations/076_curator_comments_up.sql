@@ -0,0 +1,26 @@
+-- Migration: Curator comments on nutrition entries and days
+-- Version: 076
+-- Date: 2026-10-16
+
+-- A comment a curator leaves on a client's nutrition entry or on a whole
+-- day. Entry comments carry the entry's date so both kinds are listed by
+-- date. entry_id has no foreign key: deleting the entry soft-deletes its
+-- comments (deleted_at) in the same transaction.
+CREATE TABLE IF NOT EXISTS curator_comments (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    curator_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    client_id  BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entry_id   UUID,
+    date       DATE NOT NULL,
+    text       VARCHAR(2000) NOT NULL,
+    read_at    TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_curator_comments_client_date ON curator_comments(client_id, date) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_curator_comments_entry ON curator_comments(entry_id) WHERE entry_id IS NOT NULL;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE curator_comments TO PUBLIC';
+END $$;
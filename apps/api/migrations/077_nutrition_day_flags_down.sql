@@ -0,0 +1 @@
+DROP TABLE IF EXISTS nutrition_day_flags;
@@ -0,0 +1,21 @@
+-- Migration: Nutrition day flags
+-- Version: 077
+-- Date: 2026-10-16
+
+-- A user's mark on a whole day of nutrition logs: a planned refeed, a sick
+-- day or travel. Flagged days of the types listed in
+-- NUTRITION_EXCLUDED_DAY_FLAGS are left out of adherence statistics and
+-- recommendations. A day has at most one flag.
+CREATE TABLE IF NOT EXISTS nutrition_day_flags (
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date       DATE NOT NULL,
+    type       VARCHAR(20) NOT NULL CHECK (type IN ('refeed', 'sick', 'travel')),
+    note       VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, date)
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_day_flags TO PUBLIC';
+END $$;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_nutrition_entries_food_search;
+ALTER TABLE nutrition_entries DROP COLUMN IF EXISTS food_search;
@@ -0,0 +1,18 @@
+-- Migration: Searchable food names of nutrition entries
+-- Version: 078
+-- Date: 2026-10-16
+
+-- The food name folded for search: lowercased, ё written as е and the
+-- accents of Latin letters dropped, with whitespace collapsed. The API
+-- writes it with every entry; this backfills the entries logged before.
+ALTER TABLE nutrition_entries ADD COLUMN IF NOT EXISTS food_search TEXT;
+
+UPDATE nutrition_entries
+SET food_search = regexp_replace(btrim(translate(lower(food),
+        'ёàáâãäåāçèéêëēìíîïīñòóôõöōùúûüūýÿ',
+        'еaaaaaaaceeeeeiiiiinoooooouuuuuyy')), '\s+', ' ', 'g')
+WHERE food_search IS NULL;
+
+-- Substring search within a user's history
+CREATE INDEX IF NOT EXISTS idx_nutrition_entries_food_search
+    ON nutrition_entries USING GIN (food_search gin_trgm_ops);
@@ -0,0 +1,18 @@
+-- Migration: Manual maintenance mode
+-- Version: 079
+-- Date: 2026-10-16
+
+-- Maintenance mode switched on by an admin outside any scheduled window. A
+-- single row, so every replica reads the same state.
+CREATE TABLE IF NOT EXISTS maintenance_mode (
+    id         BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+    enabled    BOOLEAN NOT NULL DEFAULT FALSE,
+    message    VARCHAR(500) NOT NULL DEFAULT '',
+    eta        TIMESTAMPTZ,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE maintenance_mode TO PUBLIC';
+END $$;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_refresh_tokens_revoked_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_user_active;
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS family_id;
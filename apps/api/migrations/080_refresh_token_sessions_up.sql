@@ -0,0 +1,16 @@
+-- Migration: Refresh token sessions
+-- Version: 080
+-- Date: 2026-10-16
+
+-- Every login starts a session; the refresh tokens it rotates through share
+-- its family_id, which access tokens carry as the "sid" claim. Tokens
+-- issued before this migration each become a session of their own.
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS family_id UUID NOT NULL DEFAULT gen_random_uuid();
+
+-- Active sessions of a user
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_active
+    ON refresh_tokens(user_id, family_id) WHERE revoked_at IS NULL;
+
+-- Sessions ended recently, whose access tokens are still rejected
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_revoked_at
+    ON refresh_tokens(revoked_at) WHERE revoked_at IS NOT NULL AND replaced_by_hash IS NULL;
@@ -0,0 +1,23 @@
+-- Migration: Meal photos attached to nutrition entries
+-- Version: 081
+-- Date: 2026-10-16
+
+-- One photo per entry; a new upload replaces the files in place. The
+-- thumbnail is always a JPEG.
+CREATE TABLE IF NOT EXISTS nutrition_entry_photos (
+    entry_id             UUID PRIMARY KEY REFERENCES nutrition_entries(id) ON DELETE CASCADE,
+    user_id              BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    storage_key          TEXT NOT NULL,
+    thumbnail_key        TEXT NOT NULL,
+    content_type         VARCHAR(50) NOT NULL,
+    size_bytes           INTEGER NOT NULL,
+    thumbnail_size_bytes INTEGER NOT NULL,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_nutrition_entry_photos_user ON nutrition_entry_photos(user_id);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_entry_photos TO PUBLIC';
+END $$;
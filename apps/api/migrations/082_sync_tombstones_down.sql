@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_nutrition_day_flags_sync;
+DROP INDEX IF EXISTS idx_body_measurements_sync;
+DROP INDEX IF EXISTS idx_nutrition_entries_sync;
+DROP TABLE IF EXISTS sync_tombstones;
@@ -0,0 +1,25 @@
+-- Migration: Device sync change tracking
+-- Version: 082
+-- Date: 2026-10-16
+
+-- One row per deleted synced row, written in the transaction deleting it,
+-- so GET /api/v1/sync can tell devices what to drop. item_id is the id of
+-- the deleted row (the date for day flags).
+CREATE TABLE IF NOT EXISTS sync_tombstones (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection VARCHAR(30) NOT NULL CHECK (collection IN ('nutrition_entries', 'measurements', 'day_flags')),
+    item_id    TEXT NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Sync reads each collection in change order from a (time, id) position
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_position ON sync_tombstones(user_id, collection, deleted_at, item_id);
+CREATE INDEX IF NOT EXISTS idx_nutrition_entries_sync ON nutrition_entries(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_body_measurements_sync ON body_measurements(user_id, updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_nutrition_day_flags_sync ON nutrition_day_flags(user_id, updated_at, date);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE sync_tombstones TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT, UPDATE ON SEQUENCE sync_tombstones_id_seq TO PUBLIC';
+END $$;
@@ -0,0 +1,9 @@
+-- Migration: Record versions for optimistic locking
+-- Version: 083
+-- Date: 2026-10-16
+
+-- Bumped by every update of a nutrition entry and of the profile (name and
+-- timezone). A client sends the version it edited in If-Match; an update
+-- of a newer version is refused with 409 and the current copy.
+ALTER TABLE nutrition_entries ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
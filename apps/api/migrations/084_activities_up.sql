@@ -0,0 +1,22 @@
+-- Migration: Activity energy expenditure
+-- Version: 084
+-- Date: 2026-10-16
+
+-- A workout or other activity and the calories it burned. calories_computed
+-- marks calories estimated from the MET table rather than given by the user.
+CREATE TABLE IF NOT EXISTS activities (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date              DATE NOT NULL,
+    type              TEXT NOT NULL,
+    duration_min      INTEGER NOT NULL CHECK (duration_min > 0),
+    calories_burned   NUMERIC(7, 1) NOT NULL CHECK (calories_burned >= 0),
+    calories_computed BOOLEAN NOT NULL DEFAULT false,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_activities_user_date ON activities(user_id, date);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE activities TO PUBLIC';
+END $$;
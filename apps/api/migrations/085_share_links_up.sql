@@ -0,0 +1,22 @@
+-- Migration: Public progress share links
+-- Version: 085
+-- Date: 2026-10-16
+
+-- A read-only link to a slice of a user's progress. Only the SHA-256 of the
+-- token is stored; the token itself is shown once, on creation. A link
+-- stops working when it expires or is revoked.
+CREATE TABLE IF NOT EXISTS share_links (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id    BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    scope      VARCHAR(30) NOT NULL CHECK (scope IN ('weight_trend', 'monthly_summary')),
+    expires_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_share_links_user ON share_links(user_id) WHERE revoked_at IS NULL;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE share_links TO PUBLIC';
+END $$;
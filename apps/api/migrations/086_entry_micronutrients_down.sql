@@ -0,0 +1,5 @@
+ALTER TABLE nutrition_entries
+    DROP COLUMN IF EXISTS saturated_fat_g,
+    DROP COLUMN IF EXISTS sodium_mg,
+    DROP COLUMN IF EXISTS sugar_g,
+    DROP COLUMN IF EXISTS fiber_g;
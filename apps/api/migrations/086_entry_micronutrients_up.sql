@@ -0,0 +1,11 @@
+-- Migration: Micronutrients on nutrition entries
+-- Version: 086
+-- Date: 2026-10-16
+
+-- Optional: entries from older clients leave them NULL, which reports treat
+-- as unknown rather than zero
+ALTER TABLE nutrition_entries
+    ADD COLUMN IF NOT EXISTS fiber_g         DECIMAL(6,1) CHECK (fiber_g >= 0),
+    ADD COLUMN IF NOT EXISTS sugar_g         DECIMAL(6,1) CHECK (sugar_g >= 0),
+    ADD COLUMN IF NOT EXISTS sodium_mg       DECIMAL(7,1) CHECK (sodium_mg >= 0),
+    ADD COLUMN IF NOT EXISTS saturated_fat_g DECIMAL(6,1) CHECK (saturated_fat_g >= 0);
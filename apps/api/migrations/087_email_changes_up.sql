@@ -0,0 +1,23 @@
+-- Migration: Email change requests
+-- Version: 087
+-- Date: 2026-10-16
+
+-- A pending switch of users.email, applied only when the link sent to the
+-- new address is opened. Only the SHA-256 of the token is stored. A user has
+-- at most one pending change: a new request cancels the previous one.
+CREATE TABLE IF NOT EXISTS email_changes (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    new_email    VARCHAR(255) NOT NULL,
+    token_hash   TEXT NOT NULL UNIQUE,
+    expires_at   TIMESTAMPTZ NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    cancelled_at TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_changes_user ON email_changes(user_id, created_at);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE email_changes TO PUBLIC';
+END $$;
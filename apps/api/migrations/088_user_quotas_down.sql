@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_daily_entries;
+DROP TABLE IF EXISTS user_quotas;
@@ -0,0 +1,45 @@
+-- Migration: Per-user quotas
+-- Version: 088
+-- Date: 2026-10-16
+
+-- Usage counters of the per-user quotas, kept in the transactions that
+-- write the counted rows so enforcement never has to count them. The
+-- *_limit columns are admin overrides of the configured limits; NULL uses
+-- the configured one.
+CREATE TABLE IF NOT EXISTS user_quotas (
+    user_id               BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    photo_bytes           BIGINT NOT NULL DEFAULT 0,
+    webhooks              INTEGER NOT NULL DEFAULT 0,
+    entries_per_day_limit INTEGER,
+    photo_bytes_limit     BIGINT,
+    webhooks_limit        INTEGER,
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Nutrition entries per user and entry date
+CREATE TABLE IF NOT EXISTS user_daily_entries (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date    DATE NOT NULL,
+    entries INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, date)
+);
+
+-- Counters start from the data already stored. Only active webhooks count;
+-- the delivery worker releases a webhook when it disables it.
+INSERT INTO user_quotas (user_id, photo_bytes, webhooks)
+SELECT u.id,
+       COALESCE((SELECT SUM(p.size_bytes) FROM progress_photos p WHERE p.user_id = u.id), 0),
+       (SELECT COUNT(*) FROM webhooks w WHERE w.user_id = u.id AND w.active)
+FROM users u
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO user_daily_entries (user_id, date, entries)
+SELECT user_id, date, COUNT(*)
+FROM nutrition_entries
+GROUP BY user_id, date
+ON CONFLICT (user_id, date) DO NOTHING;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE user_quotas TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE user_daily_entries TO PUBLIC';
+END $$;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_nutrition_entries_updated_at;
+DROP TABLE IF EXISTS nutrition_daily_rollups;
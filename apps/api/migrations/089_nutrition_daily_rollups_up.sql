@@ -0,0 +1,47 @@
+-- Migration: Daily nutrition rollups
+-- Version: 089
+-- Date: 2026-10-16
+
+-- Totals of each user's nutrition entries per day, adjusted in the
+-- transaction of every entry write so reports never sum raw entries.
+-- Micronutrients are optional on entries: each sum comes with the number of
+-- entries that recorded it, and a day none did reports it as unknown.
+-- updated_at marks days the writes touched; the reconciliation job
+-- recomputes recent ones from the entries.
+CREATE TABLE IF NOT EXISTS nutrition_daily_rollups (
+    user_id               BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date                  DATE NOT NULL,
+    entry_count           INTEGER NOT NULL DEFAULT 0,
+    calories              DECIMAL(10,1) NOT NULL DEFAULT 0,
+    protein               DECIMAL(9,1) NOT NULL DEFAULT 0,
+    carbs                 DECIMAL(9,1) NOT NULL DEFAULT 0,
+    fat                   DECIMAL(9,1) NOT NULL DEFAULT 0,
+    fiber_g               DECIMAL(9,1) NOT NULL DEFAULT 0,
+    fiber_g_count         INTEGER NOT NULL DEFAULT 0,
+    sugar_g               DECIMAL(9,1) NOT NULL DEFAULT 0,
+    sugar_g_count         INTEGER NOT NULL DEFAULT 0,
+    sodium_mg             DECIMAL(10,1) NOT NULL DEFAULT 0,
+    sodium_mg_count       INTEGER NOT NULL DEFAULT 0,
+    saturated_fat_g       DECIMAL(9,1) NOT NULL DEFAULT 0,
+    saturated_fat_g_count INTEGER NOT NULL DEFAULT 0,
+    updated_at            TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_nutrition_daily_rollups_updated_at ON nutrition_daily_rollups(updated_at);
+CREATE INDEX IF NOT EXISTS idx_nutrition_entries_updated_at ON nutrition_entries(updated_at);
+
+-- Rollups start from the entries already stored
+INSERT INTO nutrition_daily_rollups (user_id, date, entry_count, calories, protein, carbs, fat,
+                                     fiber_g, fiber_g_count, sugar_g, sugar_g_count,
+                                     sodium_mg, sodium_mg_count, saturated_fat_g, saturated_fat_g_count)
+SELECT user_id, date, COUNT(*), SUM(calories), SUM(protein), SUM(carbs), SUM(fat),
+       COALESCE(SUM(fiber_g), 0), COUNT(fiber_g), COALESCE(SUM(sugar_g), 0), COUNT(sugar_g),
+       COALESCE(SUM(sodium_mg), 0), COUNT(sodium_mg), COALESCE(SUM(saturated_fat_g), 0), COUNT(saturated_fat_g)
+FROM nutrition_entries
+GROUP BY user_id, date
+ON CONFLICT (user_id, date) DO NOTHING;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE nutrition_daily_rollups TO PUBLIC';
+END $$;
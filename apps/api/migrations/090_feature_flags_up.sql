@@ -0,0 +1,26 @@
+-- Migration: Feature flags
+-- Version: 090
+-- Date: 2026-10-16
+
+-- Per-user feature flags. A disabled flag is off for everyone; an enabled
+-- one is on for the users in allowlist and for rollout_percent percent of
+-- the others, picked by a hash of the flag and the user id.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    flag            VARCHAR(63) PRIMARY KEY,
+    description     TEXT NOT NULL DEFAULT '',
+    enabled         BOOLEAN NOT NULL DEFAULT false,
+    rollout_percent SMALLINT NOT NULL DEFAULT 0 CHECK (rollout_percent BETWEEN 0 AND 100),
+    allowlist       BIGINT[] NOT NULL DEFAULT '{}',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- The gated features stay on for everyone until an admin narrows them
+INSERT INTO feature_flags (flag, description, enabled, rollout_percent) VALUES
+    ('ai_analysis', 'Оценка процента жира по фото прогресса', true, 100),
+    ('recommendations', 'Персональные рекомендации по питанию', true, 100)
+ON CONFLICT (flag) DO NOTHING;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE feature_flags TO PUBLIC';
+END $$;
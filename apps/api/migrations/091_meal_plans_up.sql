@@ -0,0 +1,24 @@
+-- Migration: Curator meal plans
+-- Version: 091
+-- Date: 2026-10-16
+
+-- A meal plan a curator assigns to a client: calorie and macro targets per
+-- meal, in effect from start_date until a plan with a later start_date
+-- takes over. Plans are never updated; a new plan supersedes the old one,
+-- and of plans starting the same day the latest created wins. meals maps
+-- breakfast, lunch, dinner and snack to {calories, protein, carbs, fat}.
+CREATE TABLE IF NOT EXISTS meal_plans (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    client_id  BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    curator_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    start_date DATE NOT NULL,
+    meals      JSONB NOT NULL,
+    note       VARCHAR(500) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_meal_plans_client_start ON meal_plans(client_id, start_date DESC, created_at DESC);
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE meal_plans TO PUBLIC';
+END $$;
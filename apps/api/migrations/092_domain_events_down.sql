@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_webhook_deliveries_first_attempt;
+DROP TABLE IF EXISTS event_consumers;
+DROP TABLE IF EXISTS domain_events;
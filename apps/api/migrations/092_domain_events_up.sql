@@ -0,0 +1,41 @@
+-- Migration: Domain event log and consumer cursors
+-- Version: 092
+-- Date: 2026-10-16
+
+-- Append-only log of domain events, written in the transaction of the
+-- change they report. Consumers read it in (xid, seq) order: xid is the
+-- writing transaction, and an event is read only once its transaction and
+-- every transaction with a lower xid have ended, so a slow transaction
+-- cannot commit an event behind a consumer's cursor. data is the event
+-- payload.
+CREATE TABLE IF NOT EXISTS domain_events (
+    seq         BIGSERIAL PRIMARY KEY,
+    xid         BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    id          UUID NOT NULL UNIQUE,
+    type        TEXT NOT NULL,
+    user_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    data        JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_domain_events_position ON domain_events(xid, seq);
+
+-- Position of each consumer in the log: it has handled every event up to
+-- and including (xid, seq)
+CREATE TABLE IF NOT EXISTS event_consumers (
+    consumer   TEXT PRIMARY KEY,
+    xid        BIGINT NOT NULL,
+    seq        BIGINT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Webhook deliveries are queued by a log consumer, which may see an event
+-- more than once; the first attempt of an event per webhook is queued once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_first_attempt
+    ON webhook_deliveries(webhook_id, event_id) WHERE attempt = 1;
+
+DO $$ BEGIN
+    EXECUTE 'GRANT ALL ON TABLE domain_events TO PUBLIC';
+    EXECUTE 'GRANT USAGE, SELECT ON SEQUENCE domain_events_seq_seq TO PUBLIC';
+    EXECUTE 'GRANT ALL ON TABLE event_consumers TO PUBLIC';
+END $$;
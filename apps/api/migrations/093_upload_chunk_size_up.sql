@@ -0,0 +1,8 @@
+-- Migration: Numbered-chunk uploads
+-- Version: 093
+-- Date: 2026-10-16
+
+-- Set for uploads whose chunks are sent by number, in any order, rather
+-- than appended at the current offset. Chunk n starts at n * chunk_size;
+-- offset_bytes counts the bytes received so far.
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS chunk_size BIGINT CHECK (chunk_size > 0);
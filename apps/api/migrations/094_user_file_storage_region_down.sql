@@ -0,0 +1,3 @@
+ALTER TABLE data_exports DROP COLUMN IF EXISTS storage_region;
+ALTER TABLE nutrition_entry_photos DROP COLUMN IF EXISTS storage_region;
+ALTER TABLE progress_photos DROP COLUMN IF EXISTS storage_region;
@@ -0,0 +1,10 @@
+-- Migration: Storage region of progress photos, entry photos and exports
+-- Version: 094
+-- Date: 2026-10-16
+
+-- The region a file was written to, following the storage region of the
+-- user's organization. '' is the local store, used by users without an
+-- organization and by the default region.
+ALTER TABLE progress_photos ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE nutrition_entry_photos ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50) NOT NULL DEFAULT '';
+ALTER TABLE data_exports ADD COLUMN IF NOT EXISTS storage_region VARCHAR(50) NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+package migrations
+
+// ConstraintFields maps unique constraints and indexes on user-supplied
+// values to the request field a violation is reported on, each commented
+// with the migration that creates it. A migration that adds such a
+// constraint adds its name here.
+var ConstraintFields = map[string]string{
+	"users_email_key":                    "email",   // 000
+	"daily_metrics_user_id_date_key":     "date",    // 003
+	"idx_food_items_barcode_unique":      "barcode", // 017
+	"body_measurements_user_id_date_key": "date",    // 060
+	"feature_flags_pkey":                 "flag",    // 090
+}
@@ -18,11 +18,11 @@ describe('adminApi', () => {
     describe('getUsers', () => {
         it('calls GET /api/v1/admin/users', async () => {
             const users = [{ id: 1, name: 'Test User' }]
-            mockApiClient.get.mockResolvedValue(users)
+            mockApiClient.get.mockResolvedValue({ users, total: 1, hasMore: false })
 
             const result = await adminApi.getUsers()
 
-            expect(mockApiClient.get).toHaveBeenCalledWith('/api/v1/admin/users')
+            expect(mockApiClient.get).toHaveBeenCalledWith('/api/v1/admin/users?limit=200')
             expect(result).toEqual(users)
         })
     })
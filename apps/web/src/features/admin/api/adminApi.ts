@@ -1,10 +1,16 @@
 import { apiClient } from '@/shared/utils/api-client'
-import type { AdminUser, CuratorLoad, AdminConversation, AdminMessage } from '../types'
+import type { AdminUser, AdminUserPage, CuratorLoad, AdminConversation, AdminMessage } from '../types'
 
 const BASE = '/api/v1/admin'
 
+// Largest page the API returns for the user list
+const USERS_PAGE_SIZE = 200
+
 export const adminApi = {
-    getUsers: () => apiClient.get<AdminUser[]>(`${BASE}/users`),
+    getUsers: async (): Promise<AdminUser[]> => {
+        const page = await apiClient.get<AdminUserPage>(`${BASE}/users?limit=${USERS_PAGE_SIZE}`)
+        return page.users
+    },
 
     getCurators: () => apiClient.get<CuratorLoad[]>(`${BASE}/curators`),
 
@@ -22,6 +22,12 @@ export interface AdminUser {
     last_login_at?: string
 }
 
+export interface AdminUserPage {
+    users: AdminUser[]
+    total: number
+    hasMore: boolean
+}
+
 export interface CuratorLoad {
     id: number
     name: string